@@ -0,0 +1,71 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrExecutorBusy is returned when Config.MaxConcurrentExecutions slots
+// are all in use and the execution could not be queued, or waited longer
+// than Config.QueueTimeout for a slot.
+var ErrExecutorBusy = errors.New("code: executor busy")
+
+// admission bounds the number of concurrently running executions.
+// A nil admission admits everything.
+type admission struct {
+	slots     chan struct{}
+	maxQueued int
+	timeout   time.Duration
+	queued    atomic.Int64
+}
+
+func newAdmission(cfg *Config) *admission {
+	if cfg.MaxConcurrentExecutions <= 0 {
+		return nil
+	}
+	return &admission{
+		slots:     make(chan struct{}, cfg.MaxConcurrentExecutions),
+		maxQueued: cfg.MaxQueuedExecutions,
+		timeout:   cfg.QueueTimeout,
+	}
+}
+
+// acquire takes a slot, waiting in the queue if none is free. It returns
+// a release func, or ErrExecutorBusy when the queue is full or the wait
+// times out, or ctx.Err() when ctx ends first.
+func (a *admission) acquire(ctx context.Context) (func(), error) {
+	if a == nil {
+		return func() {}, nil
+	}
+	release := func() { <-a.slots }
+
+	select {
+	case a.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if n := a.queued.Add(1); a.maxQueued > 0 && n > int64(a.maxQueued) {
+		a.queued.Add(-1)
+		return nil, fmt.Errorf("%w: %d executions running and %d queued", ErrExecutorBusy, cap(a.slots), a.maxQueued)
+	}
+	defer a.queued.Add(-1)
+
+	var expired <-chan time.Time
+	if a.timeout > 0 {
+		timer := time.NewTimer(a.timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case a.slots <- struct{}{}:
+		return release, nil
+	case <-expired:
+		return nil, fmt.Errorf("%w: no slot free after %v", ErrExecutorBusy, a.timeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
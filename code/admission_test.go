@@ -0,0 +1,110 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingExecutor returns an executor whose engine blocks until release
+// is closed, signalling each start on started.
+func blockingExecutor(t *testing.T, cfg Config) (exec *DefaultExecutor, started chan struct{}, release chan struct{}) {
+	t.Helper()
+	started = make(chan struct{}, 10)
+	release = make(chan struct{})
+	cfg.Index, cfg.Docs, cfg.Run = &mockIndex{}, &mockStore{}, &mockRunner{}
+	cfg.Engine = engineFunc(func(context.Context, ExecuteParams, Tools) (ExecuteResult, error) {
+		started <- struct{}{}
+		<-release
+		return ExecuteResult{Value: "done"}, nil
+	})
+	exec, err := NewDefaultExecutor(cfg)
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+	return exec, started, release
+}
+
+func TestExecuteCode_MaxConcurrentExecutions_Queues(t *testing.T) {
+	exec, started, release := blockingExecutor(t, Config{MaxConcurrentExecutions: 1})
+	ctx := context.Background()
+
+	first := exec.ExecuteCodeAsync(ctx, ExecuteParams{Code: "a"})
+	<-started
+	second := exec.ExecuteCodeAsync(ctx, ExecuteParams{Code: "b"})
+
+	select {
+	case <-started:
+		t.Fatal("second execution started while the only slot was taken")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	for _, x := range []*Execution{first, second} {
+		if _, err := x.Wait(ctx); err != nil {
+			t.Errorf("Wait() error = %v", err)
+		}
+	}
+}
+
+func TestExecuteCode_MaxQueuedExecutions(t *testing.T) {
+	exec, started, release := blockingExecutor(t, Config{MaxConcurrentExecutions: 1, MaxQueuedExecutions: 1})
+	defer close(release)
+	ctx := context.Background()
+
+	exec.ExecuteCodeAsync(ctx, ExecuteParams{Code: "a"})
+	<-started
+	queued := exec.ExecuteCodeAsync(ctx, ExecuteParams{Code: "b"})
+	for exec.admission.queued.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := exec.ExecuteCode(ctx, ExecuteParams{Code: "c"}); !errors.Is(err, ErrExecutorBusy) {
+		t.Errorf("ExecuteCode() error = %v, want %v", err, ErrExecutorBusy)
+	}
+	queued.Cancel()
+}
+
+func TestExecuteCode_QueueTimeout(t *testing.T) {
+	exec, started, release := blockingExecutor(t, Config{MaxConcurrentExecutions: 1, QueueTimeout: 20 * time.Millisecond})
+	defer close(release)
+	ctx := context.Background()
+
+	exec.ExecuteCodeAsync(ctx, ExecuteParams{Code: "a"})
+	<-started
+
+	if _, err := exec.ExecuteCode(ctx, ExecuteParams{Code: "b"}); !errors.Is(err, ErrExecutorBusy) {
+		t.Errorf("ExecuteCode() error = %v, want %v", err, ErrExecutorBusy)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := exec.ExecuteCode(cctx, ExecuteParams{Code: "c"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExecuteCode() with canceled ctx error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestExecuteCode_SessionWaitHoldsNoSlot(t *testing.T) {
+	exec, started, release := blockingExecutor(t, Config{MaxConcurrentExecutions: 2, MaxQueuedExecutions: 1})
+	ctx := context.Background()
+
+	first := exec.ExecuteCodeAsync(ctx, ExecuteParams{SessionID: "s", Code: "a"})
+	<-started
+	// Waiting for the session's turn takes neither a slot nor a queue place.
+	second := exec.ExecuteCodeAsync(ctx, ExecuteParams{SessionID: "s", Code: "b"})
+	time.Sleep(20 * time.Millisecond)
+	other := exec.ExecuteCodeAsync(ctx, ExecuteParams{Code: "c"})
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("execution outside the session did not get the free slot")
+	}
+
+	close(release)
+	for _, x := range []*Execution{first, second, other} {
+		if _, err := x.Wait(ctx); err != nil {
+			t.Errorf("Wait() error = %v", err)
+		}
+	}
+}
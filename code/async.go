@@ -0,0 +1,174 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrExecutionCanceled is returned by Execution.Wait when the execution
+// was stopped with Cancel. It wraps context.Canceled.
+var ErrExecutionCanceled = errors.New("code: execution canceled")
+
+// ExecutionStatus is the lifecycle state of an asynchronous execution.
+type ExecutionStatus string
+
+const (
+	// ExecutionRunning means the snippet is still executing.
+	ExecutionRunning ExecutionStatus = "running"
+
+	// ExecutionSucceeded means the snippet finished without error.
+	ExecutionSucceeded ExecutionStatus = "succeeded"
+
+	// ExecutionFailed means the snippet finished with an error.
+	ExecutionFailed ExecutionStatus = "failed"
+
+	// ExecutionCanceled means the execution was stopped with Cancel.
+	ExecutionCanceled ExecutionStatus = "canceled"
+)
+
+// Execution is a handle to a code execution started with
+// ExecuteCodeAsync. It is safe for concurrent use.
+type Execution struct {
+	id        string
+	startedAt time.Time
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	mu         sync.Mutex
+	canceled   bool
+	status     ExecutionStatus
+	result     ExecuteResult
+	err        error
+	finishedAt time.Time
+}
+
+// ID returns the execution identifier, usable with
+// DefaultExecutor.Execution. It is the runtime execution ID the engine,
+// its backends, and the execution's tool call records and log entries
+// carry.
+func (x *Execution) ID() string {
+	return x.id
+}
+
+// StartedAt returns when the execution started.
+func (x *Execution) StartedAt() time.Time {
+	return x.startedAt
+}
+
+// FinishedAt returns when the execution finished, or the zero time while
+// it is running.
+func (x *Execution) FinishedAt() time.Time {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.finishedAt
+}
+
+// Cancel stops the execution. Cancellation propagates through the context
+// to the engine and its backend, which stop the snippet, for example by
+// killing its process or container. Cancel is a no-op once the execution
+// has finished.
+func (x *Execution) Cancel() {
+	x.mu.Lock()
+	if x.status == ExecutionRunning {
+		x.canceled = true
+	}
+	x.mu.Unlock()
+	x.cancel()
+}
+
+// Status returns the current lifecycle state.
+func (x *Execution) Status() ExecutionStatus {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.status
+}
+
+// Done returns a channel that is closed when the execution finishes.
+func (x *Execution) Done() <-chan struct{} {
+	return x.done
+}
+
+// Wait blocks until the execution finishes or ctx is done, then returns
+// the execution's result. Canceled executions return an error matching
+// ErrExecutionCanceled.
+func (x *Execution) Wait(ctx context.Context) (ExecuteResult, error) {
+	select {
+	case <-x.done:
+	case <-ctx.Done():
+		return ExecuteResult{}, ctx.Err()
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.result, x.err
+}
+
+// finish records the outcome and releases waiters.
+func (x *Execution) finish(result ExecuteResult, err error) {
+	x.mu.Lock()
+	// A snippet that succeeded before noticing Cancel keeps its outcome.
+	switch {
+	case x.canceled && err != nil:
+		x.status = ExecutionCanceled
+		if !errors.Is(err, context.Canceled) {
+			err = fmt.Errorf("%w: %w", context.Canceled, err)
+		}
+		err = fmt.Errorf("%w: %w", ErrExecutionCanceled, err)
+	case err != nil:
+		x.status = ExecutionFailed
+	default:
+		x.status = ExecutionSucceeded
+	}
+	x.result = result
+	x.err = err
+	x.finishedAt = time.Now()
+	x.mu.Unlock()
+	x.cancel()
+	close(x.done)
+}
+
+// ExecuteCodeAsync starts a code execution in the background and returns
+// a handle for cancelling it and observing its status. The execution runs
+// under ctx, so cancelling ctx also cancels it; pass
+// context.WithoutCancel(ctx) to outlive the caller's request.
+//
+// The execution's ID is the one ctx carries (see runtime.WithExecutionID),
+// or a new one. Running executions can be looked up by ID with Execution.
+func (e *DefaultExecutor) ExecuteCodeAsync(ctx context.Context, params ExecuteParams) *Execution {
+	ctx, id := withExecutionID(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	x := &Execution{
+		id:        id,
+		startedAt: time.Now(),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		status:    ExecutionRunning,
+	}
+
+	e.runningMu.Lock()
+	if e.running == nil {
+		e.running = make(map[string]*Execution)
+	}
+	e.running[x.id] = x
+	e.runningMu.Unlock()
+
+	go func() {
+		result, err := e.ExecuteCode(ctx, params)
+		e.runningMu.Lock()
+		delete(e.running, x.id)
+		e.runningMu.Unlock()
+		x.finish(result, err)
+	}()
+	return x
+}
+
+// Execution returns the running execution with the given ID. Finished
+// executions are no longer listed; their handles keep reporting status.
+func (e *DefaultExecutor) Execution(id string) (*Execution, bool) {
+	e.runningMu.Lock()
+	defer e.runningMu.Unlock()
+	x, ok := e.running[id]
+	return x, ok
+}
@@ -0,0 +1,113 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+func TestExecuteCodeAsync_Succeeds(t *testing.T) {
+	engine := &mockEngine{executeResult: ExecuteResult{Value: 42}}
+	exec, _ := NewDefaultExecutor(Config{Index: &mockIndex{}, Docs: &mockStore{}, Run: &mockRunner{}, Engine: engine})
+
+	x := exec.ExecuteCodeAsync(context.Background(), ExecuteParams{Code: "x"})
+	result, err := x.Wait(context.Background())
+	if err != nil || result.Value != 42 {
+		t.Fatalf("Wait() = %v, %v; want 42", result.Value, err)
+	}
+	if x.Status() != ExecutionSucceeded || x.FinishedAt().IsZero() {
+		t.Errorf("Status() = %v, FinishedAt() = %v", x.Status(), x.FinishedAt())
+	}
+	if _, ok := exec.Execution(x.ID()); ok {
+		t.Error("finished execution should no longer be listed")
+	}
+	x.Cancel()
+	if x.Status() != ExecutionSucceeded {
+		t.Errorf("Status() after late Cancel = %v, want %v", x.Status(), ExecutionSucceeded)
+	}
+}
+
+func TestExecuteCodeAsync_ExecutionID(t *testing.T) {
+	seen := make(chan string, 2)
+	engine := engineFunc(func(ctx context.Context, _ ExecuteParams, tools Tools) (ExecuteResult, error) {
+		seen <- runtime.ExecutionIDFromContext(ctx)
+		_, err := tools.RunTool(ctx, "ns:tool", nil)
+		return ExecuteResult{}, err
+	})
+	exec, _ := NewDefaultExecutor(Config{Index: &mockIndex{}, Docs: &mockStore{}, Run: &mockRunner{}, Engine: engine})
+
+	// A caller's ID is kept; otherwise a runtime execution ID is made.
+	for _, ctx := range []context.Context{
+		runtime.WithExecutionID(context.Background(), "exec-caller"),
+		context.Background(),
+	} {
+		x := exec.ExecuteCodeAsync(ctx, ExecuteParams{Code: "x"})
+		result, err := x.Wait(context.Background())
+		if err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+		if want := runtime.ExecutionIDFromContext(ctx); want != "" && x.ID() != want {
+			t.Errorf("ID() = %q, want %q", x.ID(), want)
+		}
+		if !strings.HasPrefix(x.ID(), "exec-") {
+			t.Errorf("ID() = %q, want a runtime execution ID", x.ID())
+		}
+		if got := <-seen; got != x.ID() {
+			t.Errorf("engine context ID = %q, want %q", got, x.ID())
+		}
+		if len(result.ToolCalls) != 1 || result.ToolCalls[0].ExecutionID != x.ID() {
+			t.Errorf("ToolCalls = %+v, want one call from %s", result.ToolCalls, x.ID())
+		}
+	}
+}
+
+func TestExecuteCodeAsync_Cancel(t *testing.T) {
+	started := make(chan struct{})
+	engine := engineFunc(func(ctx context.Context, _ ExecuteParams, _ Tools) (ExecuteResult, error) {
+		close(started)
+		<-ctx.Done()
+		return ExecuteResult{}, ctx.Err()
+	})
+	exec, _ := NewDefaultExecutor(Config{Index: &mockIndex{}, Docs: &mockStore{}, Run: &mockRunner{}, Engine: engine})
+
+	x := exec.ExecuteCodeAsync(context.Background(), ExecuteParams{Code: "for {}"})
+	<-started
+	if got, ok := exec.Execution(x.ID()); !ok || got != x {
+		t.Fatal("Execution() should find the running execution")
+	}
+	if x.Status() != ExecutionRunning {
+		t.Errorf("Status() = %v, want %v", x.Status(), ExecutionRunning)
+	}
+
+	x.Cancel()
+	select {
+	case <-x.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("execution did not stop after Cancel")
+	}
+	_, err := x.Wait(context.Background())
+	if !errors.Is(err, ErrExecutionCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() error = %v, want %v wrapping context.Canceled", err, ErrExecutionCanceled)
+	}
+	if x.Status() != ExecutionCanceled {
+		t.Errorf("Status() = %v, want %v", x.Status(), ExecutionCanceled)
+	}
+}
+
+func TestExecuteCodeAsync_Failed(t *testing.T) {
+	boom := errors.New("boom")
+	engine := &mockEngine{executeErr: boom}
+	exec, _ := NewDefaultExecutor(Config{Index: &mockIndex{}, Docs: &mockStore{}, Run: &mockRunner{}, Engine: engine})
+
+	x := exec.ExecuteCodeAsync(context.Background(), ExecuteParams{Code: "x"})
+	if _, err := x.Wait(context.Background()); !errors.Is(err, boom) {
+		t.Errorf("Wait() error = %v, want %v", err, boom)
+	}
+	if x.Status() != ExecutionFailed {
+		t.Errorf("Status() = %v, want %v", x.Status(), ExecutionFailed)
+	}
+}
@@ -0,0 +1,78 @@
+package code
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+// UnlimitedToolCalls exempts a namespace in Config.NamespaceToolCalls from
+// all tool call limits.
+const UnlimitedToolCalls = -1
+
+// namespaceOf returns the namespace of a tool ID, or "" if it has none.
+func namespaceOf(id string) string {
+	ns, _, err := model.ParseToolID(id)
+	if err != nil {
+		return ""
+	}
+	return ns
+}
+
+// checkBudget returns ErrLimitExceeded if calling every tool in ids would
+// exceed the global limit or the limit of any namespace. Tools in a
+// namespace with its own limit do not count against the global limit.
+func (t *toolsImpl) checkBudget(ids []string) error {
+	global := 0
+	perNamespace := make(map[string]int)
+	for _, id := range ids {
+		ns := namespaceOf(id)
+		if _, ok := t.namespaceLimits[ns]; ok {
+			perNamespace[ns]++
+		} else {
+			global++
+		}
+	}
+
+	if t.maxToolCalls > 0 && global > 0 && t.callCount+global > t.maxToolCalls {
+		if len(ids) == 1 {
+			return fmt.Errorf("%w: max tool calls (%d) exceeded", ErrLimitExceeded, t.maxToolCalls)
+		}
+		return fmt.Errorf("%w: max tool calls (%d) exceeded (need %d, have %d remaining)",
+			ErrLimitExceeded, t.maxToolCalls, global, t.maxToolCalls-t.callCount)
+	}
+	for _, ns := range slices.Sorted(maps.Keys(perNamespace)) {
+		limit := t.namespaceLimits[ns]
+		if limit == UnlimitedToolCalls {
+			continue
+		}
+		if need := perNamespace[ns]; t.namespaceCalls[ns]+need > limit {
+			return fmt.Errorf("%w: max tool calls for namespace %q (%d) exceeded (need %d, have %d remaining)",
+				ErrLimitExceeded, ns, limit, need, max(limit-t.namespaceCalls[ns], 0))
+		}
+	}
+	return nil
+}
+
+// charge counts one call to id against its budget. Callers hold t.mu,
+// as they do for checkBudget and refund.
+func (t *toolsImpl) charge(id string) {
+	ns := namespaceOf(id)
+	if _, ok := t.namespaceLimits[ns]; ok {
+		t.namespaceCalls[ns]++
+		return
+	}
+	t.callCount++
+}
+
+// refund returns a call reserved with charge that never ran.
+func (t *toolsImpl) refund(id string) {
+	ns := namespaceOf(id)
+	if _, ok := t.namespaceLimits[ns]; ok {
+		t.namespaceCalls[ns]--
+		return
+	}
+	t.callCount--
+}
@@ -0,0 +1,153 @@
+package code
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// ResultCache memoizes successful executions so repeated identical
+// snippets, common in agent retry loops, skip engine and sandbox startup.
+//
+// Entries are keyed by language, a hash of the code, and every parameter
+// that can change the outcome (Metadata, Env, a digest of the resolved
+// secret values, OutputSchema, MaxToolCalls, MaxStdoutBytes, Workspace,
+// Determinism). Secrets are resolved on every execution, so a rotated
+// secret misses results computed with its old value. Executions that join
+// a session or set BypassCache are never served from or stored in the
+// cache.
+//
+// Contract:
+// - Concurrency: safe for concurrent use; a cache may be shared by executors.
+// - Ownership: cached results are shallow-copied on read and write.
+type ResultCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]resultEntry
+	order      []string
+}
+
+type resultEntry struct {
+	result  ExecuteResult
+	expires time.Time
+}
+
+// NewResultCache creates a result cache whose entries expire after ttl and
+// which holds at most maxEntries results, evicting the oldest first.
+// A ttl of zero disables expiry; a maxEntries of zero disables the bound.
+func NewResultCache(ttl time.Duration, maxEntries int) *ResultCache {
+	return &ResultCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]resultEntry),
+	}
+}
+
+// InvalidateAll removes every cached result.
+func (c *ResultCache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]resultEntry)
+	c.order = nil
+}
+
+// Len returns the number of cached results, including expired entries
+// that have not yet been evicted.
+func (c *ResultCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// get returns the cached result for key when present and unexpired.
+func (c *ResultCache) get(key string) (ExecuteResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return ExecuteResult{}, false
+	}
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return ExecuteResult{}, false
+	}
+	return entry.result, true
+}
+
+// set stores result under key, evicting the oldest entries over capacity.
+func (c *ResultCache) set(key string, result ExecuteResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := resultEntry{result: result}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	if _, exists := c.entries[key]; !exists {
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = entry
+
+	for c.maxEntries > 0 && len(c.entries) > c.maxEntries && len(c.order) > 0 {
+		oldest := c.order[0]
+		c.order = c.order[1:]
+		delete(c.entries, oldest)
+	}
+	// Drop order slots for keys already removed by expiry.
+	if len(c.order) > 2*len(c.entries)+16 {
+		kept := c.order[:0]
+		for _, k := range c.order {
+			if _, ok := c.entries[k]; ok {
+				kept = append(kept, k)
+			}
+		}
+		c.order = kept
+	}
+}
+
+// resultCacheKey derives the cache key for params and the values its
+// secrets resolved to, which enter the key only as a digest. ok is false
+// when the parameters cannot be encoded, in which case the execution is
+// not cached.
+func resultCacheKey(params ExecuteParams, secrets map[string]string) (key string, ok bool) {
+	codeHash := sha256.Sum256([]byte(params.Code))
+	var secretsHash string
+	if len(secrets) > 0 {
+		data, err := json.Marshal(secrets)
+		if err != nil {
+			return "", false
+		}
+		sum := sha256.Sum256(data)
+		secretsHash = hex.EncodeToString(sum[:])
+	}
+	data, err := json.Marshal(struct {
+		Language     string            `json:"l"`
+		CodeHash     string            `json:"c"`
+		Metadata     map[string]any    `json:"m,omitempty"`
+		Env          map[string]string `json:"e,omitempty"`
+		SecretsHash  string            `json:"s,omitempty"`
+		OutputSchema any               `json:"o,omitempty"`
+		MaxToolCalls int               `json:"t,omitempty"`
+		MaxStdout    int               `json:"b,omitempty"`
+		Workspace    *Workspace        `json:"w,omitempty"`
+		Determinism  *Determinism      `json:"d,omitempty"`
+	}{
+		Language:     params.Language,
+		CodeHash:     hex.EncodeToString(codeHash[:]),
+		Metadata:     params.Metadata,
+		Env:          params.Env,
+		SecretsHash:  secretsHash,
+		OutputSchema: params.OutputSchema,
+		MaxToolCalls: params.MaxToolCalls,
+		MaxStdout:    params.MaxStdoutBytes,
+		Workspace:    params.Workspace,
+		Determinism:  params.Determinism,
+	})
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
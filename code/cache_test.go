@@ -0,0 +1,110 @@
+package code
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExecuteCode_ResultCache(t *testing.T) {
+	engine := &mockEngine{executeResult: ExecuteResult{Value: "v"}}
+	exec, err := NewDefaultExecutor(Config{
+		Index:       &mockIndex{},
+		Docs:        &mockStore{},
+		Run:         &mockRunner{},
+		Engine:      engine,
+		ResultCache: NewResultCache(time.Minute, 0),
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+	ctx := context.Background()
+	params := ExecuteParams{Code: "__out = 1", Metadata: map[string]any{"attempt": "a"}}
+
+	first, err := exec.ExecuteCode(ctx, params)
+	if err != nil || first.Cached {
+		t.Fatalf("first ExecuteCode() = %+v, %v", first, err)
+	}
+	second, err := exec.ExecuteCode(ctx, params)
+	if err != nil || !second.Cached || second.Value != "v" {
+		t.Fatalf("second ExecuteCode() = %+v, %v, want cached", second, err)
+	}
+	if len(engine.executeCalls) != 1 {
+		t.Errorf("engine calls = %d, want 1", len(engine.executeCalls))
+	}
+
+	cases := map[string]ExecuteParams{
+		"different code":     {Code: "__out = 2", Metadata: params.Metadata},
+		"different metadata": {Code: params.Code, Metadata: map[string]any{"attempt": "b"}},
+		"stdout limit":       {Code: params.Code, Metadata: params.Metadata, MaxStdoutBytes: 10},
+		"workspace":          {Code: params.Code, Metadata: params.Metadata, Workspace: &Workspace{Path: "/w"}},
+		"bypass":             {Code: params.Code, Metadata: params.Metadata, BypassCache: true},
+		"session":            {Code: params.Code, Metadata: params.Metadata, SessionID: "s"},
+	}
+	for name, p := range cases {
+		before := len(engine.executeCalls)
+		result, err := exec.ExecuteCode(ctx, p)
+		if err != nil || result.Cached || len(engine.executeCalls) != before+1 {
+			t.Errorf("%s: cached = %v, err = %v, want fresh execution", name, result.Cached, err)
+		}
+	}
+}
+
+func TestExecuteCode_ResultCacheSecretRotation(t *testing.T) {
+	engine := &mockEngine{executeResult: ExecuteResult{Value: "v"}}
+	var secret atomic.Value
+	secret.Store("old")
+	exec, err := NewDefaultExecutor(Config{
+		Index:       &mockIndex{},
+		Docs:        &mockStore{},
+		Run:         &mockRunner{},
+		Engine:      engine,
+		ResultCache: NewResultCache(time.Minute, 0),
+		SecretResolver: SecretResolverFunc(func(context.Context, string) (string, error) {
+			return secret.Load().(string), nil
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+	ctx := context.Background()
+	params := ExecuteParams{Code: "x", Secrets: map[string]string{"TOKEN": "vault://token"}}
+
+	for i, want := range []bool{false, true} {
+		result, err := exec.ExecuteCode(ctx, params)
+		if err != nil || result.Cached != want {
+			t.Fatalf("run %d: cached = %v, err = %v, want cached %v", i, result.Cached, err, want)
+		}
+	}
+
+	// The same reference now resolves to a new value.
+	secret.Store("new")
+	result, err := exec.ExecuteCode(ctx, params)
+	if err != nil || result.Cached {
+		t.Errorf("after rotation: cached = %v, err = %v, want a fresh execution", result.Cached, err)
+	}
+	if len(engine.executeCalls) != 2 {
+		t.Errorf("engine calls = %d, want 2", len(engine.executeCalls))
+	}
+}
+
+func TestResultCache_Bounds(t *testing.T) {
+	c := NewResultCache(0, 2)
+	c.set("a", ExecuteResult{Value: 1})
+	c.set("b", ExecuteResult{Value: 2})
+	c.set("c", ExecuteResult{Value: 3})
+	if _, ok := c.get("a"); ok {
+		t.Error("oldest entry not evicted")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+
+	expiring := NewResultCache(time.Millisecond, 0)
+	expiring.set("a", ExecuteResult{})
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := expiring.get("a"); ok {
+		t.Error("expired entry served")
+	}
+}
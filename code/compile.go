@@ -0,0 +1,245 @@
+package code
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrCompilationFailed is returned when a Compiler cannot build a snippet.
+var ErrCompilationFailed = errors.New("code: compilation failed")
+
+// MetadataWASMModule is the ExecuteParams.Metadata key under which the
+// executor passes a compiled WASM module to the engine. The wasm runtime
+// backend reads the module from the same key.
+const MetadataWASMModule = "wasm_module"
+
+// Compiler languages supported by ToolchainCompiler.
+const (
+	// LanguageGo compiles a Go main package with the Go toolchain
+	// (GOOS=wasip1 GOARCH=wasm), or with TinyGo when configured.
+	LanguageGo = "go"
+
+	// LanguageTinyGo compiles a Go main package with TinyGo for WASI.
+	LanguageTinyGo = "tinygo"
+
+	// LanguageAssemblyScript compiles an AssemblyScript module with asc.
+	LanguageAssemblyScript = "assemblyscript"
+)
+
+// DefaultCompilerCacheEntries is the number of compiled modules
+// ToolchainCompiler keeps when CompilerConfig.MaxCacheEntries is zero.
+const DefaultCompilerCacheEntries = 128
+
+// Compiler compiles snippets to WASM modules for sandboxes that run
+// WebAssembly. When Config.Compiler is set, the executor compiles
+// snippets in the compiler's languages before execution and passes the
+// module to the engine in ExecuteParams.Metadata under MetadataWASMModule.
+//
+// Snippets are complete programs: a WASI module that reports its result
+// by printing a "__OUT__:<json>" line to stdout.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines.
+// - Errors: build failures return an error wrapping ErrCompilationFailed;
+// unsupported languages return ErrUnsupportedLanguage.
+type Compiler interface {
+	// Languages lists the languages the compiler accepts.
+	Languages() []string
+
+	// Compile builds source into a WASM module.
+	Compile(ctx context.Context, language, source string) ([]byte, error)
+}
+
+// CompilerConfig configures a ToolchainCompiler. Empty tool paths are
+// looked up on PATH.
+type CompilerConfig struct {
+	// GoPath is the go command. Default: "go".
+	GoPath string
+
+	// TinyGoPath is the tinygo command. Default: "tinygo".
+	TinyGoPath string
+
+	// AscPath is the AssemblyScript compiler command. Default: "asc".
+	AscPath string
+
+	// PreferTinyGo compiles LanguageGo with TinyGo, which produces much
+	// smaller modules than the Go toolchain.
+	PreferTinyGo bool
+
+	// MaxCacheEntries bounds the compiled module cache.
+	// Default: DefaultCompilerCacheEntries.
+	MaxCacheEntries int
+}
+
+// ToolchainCompiler is the default Compiler. It shells out to the Go,
+// TinyGo, or AssemblyScript toolchain and caches modules by a hash of the
+// language and source, so repeated snippets compile once.
+type ToolchainCompiler struct {
+	cfg CompilerConfig
+
+	mu    sync.Mutex
+	cache map[string][]byte
+	order []string
+}
+
+// NewToolchainCompiler creates a ToolchainCompiler.
+func NewToolchainCompiler(cfg CompilerConfig) *ToolchainCompiler {
+	if cfg.GoPath == "" {
+		cfg.GoPath = "go"
+	}
+	if cfg.TinyGoPath == "" {
+		cfg.TinyGoPath = "tinygo"
+	}
+	if cfg.AscPath == "" {
+		cfg.AscPath = "asc"
+	}
+	if cfg.MaxCacheEntries <= 0 {
+		cfg.MaxCacheEntries = DefaultCompilerCacheEntries
+	}
+	return &ToolchainCompiler{cfg: cfg, cache: make(map[string][]byte)}
+}
+
+// Languages implements Compiler.
+func (c *ToolchainCompiler) Languages() []string {
+	return []string{LanguageGo, LanguageTinyGo, LanguageAssemblyScript}
+}
+
+// Compile implements Compiler.
+func (c *ToolchainCompiler) Compile(ctx context.Context, language, source string) ([]byte, error) {
+	if !slices.Contains(c.Languages(), language) {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedLanguage, language)
+	}
+	key := compileKey(language, source)
+	if module, ok := c.cached(key); ok {
+		return module, nil
+	}
+
+	dir, err := os.MkdirTemp("", "toolexec-compile-*")
+	if err != nil {
+		return nil, fmt.Errorf("code: compile workspace: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	module, err := c.build(ctx, dir, language, source)
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, module)
+	return module, nil
+}
+
+// build writes source into dir and runs the toolchain for language.
+func (c *ToolchainCompiler) build(ctx context.Context, dir, language, source string) ([]byte, error) {
+	out := filepath.Join(dir, "module.wasm")
+	var cmd *exec.Cmd
+	switch {
+	case language == LanguageAssemblyScript:
+		if err := os.WriteFile(filepath.Join(dir, "main.ts"), []byte(source), 0o600); err != nil {
+			return nil, fmt.Errorf("code: write source: %w", err)
+		}
+		cmd = exec.CommandContext(ctx, c.cfg.AscPath, "main.ts", "-o", out, "--optimize")
+	default:
+		if err := writeGoModule(dir, source); err != nil {
+			return nil, err
+		}
+		if language == LanguageTinyGo || c.cfg.PreferTinyGo {
+			cmd = exec.CommandContext(ctx, c.cfg.TinyGoPath, "build", "-target=wasi", "-o", out, ".")
+		} else {
+			cmd = exec.CommandContext(ctx, c.cfg.GoPath, "build", "-o", out, ".")
+			cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm", "GOWORK=off", "GOFLAGS=-mod=mod")
+		}
+	}
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stdout = &stderr
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("%w: %s: %s", ErrCompilationFailed, language, msg)
+	}
+
+	module, err := os.ReadFile(out)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: read module: %w", ErrCompilationFailed, language, err)
+	}
+	return module, nil
+}
+
+// writeGoModule lays out source as a standalone main module in dir.
+func writeGoModule(dir, source string) error {
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module snippet\n\ngo 1.21\n"), 0o600); err != nil {
+		return fmt.Errorf("code: write go.mod: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(source), 0o600); err != nil {
+		return fmt.Errorf("code: write source: %w", err)
+	}
+	return nil
+}
+
+func (c *ToolchainCompiler) cached(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	module, ok := c.cache[key]
+	return module, ok
+}
+
+// store caches module, evicting the oldest entry when the cache is full.
+func (c *ToolchainCompiler) store(key string, module []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.cache[key]; ok {
+		return
+	}
+	if len(c.order) >= c.cfg.MaxCacheEntries {
+		delete(c.cache, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.cache[key] = module
+	c.order = append(c.order, key)
+}
+
+// compileKey hashes the language and source of a snippet.
+func compileKey(language, source string) string {
+	sum := sha256.Sum256([]byte(language + "\x00" + source))
+	return hex.EncodeToString(sum[:])
+}
+
+// compile builds params.Code with the configured Compiler when it
+// accepts the language, returning params with the module in Metadata.
+func (e *DefaultExecutor) compile(ctx context.Context, params ExecuteParams) (ExecuteParams, error) {
+	c := e.cfg.Compiler
+	if c == nil || !slices.Contains(c.Languages(), params.Language) {
+		return params, nil
+	}
+	module, err := c.Compile(ctx, params.Language, params.Code)
+	if err != nil {
+		return params, err
+	}
+	params.Metadata = maps.Clone(params.Metadata)
+	if params.Metadata == nil {
+		params.Metadata = make(map[string]any, 1)
+	}
+	params.Metadata[MetadataWASMModule] = module
+	return params, nil
+}
+
+var _ Compiler = (*ToolchainCompiler)(nil)
@@ -0,0 +1,132 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// fakeCompiler compiles every snippet to a fixed module.
+type fakeCompiler struct {
+	languages []string
+	module    []byte
+	err       error
+	calls     int
+	block     bool // wait for the context to end
+}
+
+func (f *fakeCompiler) Languages() []string { return f.languages }
+
+func (f *fakeCompiler) Compile(ctx context.Context, _, _ string) ([]byte, error) {
+	f.calls++
+	if f.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return f.module, f.err
+}
+
+func TestExecuteCode_CompilesToWASM(t *testing.T) {
+	module := []byte("\x00asm\x01\x00\x00\x00")
+	compiler := &fakeCompiler{languages: []string{LanguageGo}, module: module}
+	engine := &mockEngine{}
+	exec, err := NewDefaultExecutor(Config{
+		Index:    &mockIndex{},
+		Docs:     &mockStore{},
+		Run:      &mockRunner{},
+		Engine:   engine,
+		Compiler: compiler,
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+	ctx := context.Background()
+
+	meta := map[string]any{"caller": "test"}
+	if _, err := exec.ExecuteCode(ctx, ExecuteParams{Language: LanguageGo, Code: "package main", Metadata: meta}); err != nil {
+		t.Fatalf("ExecuteCode() error = %v", err)
+	}
+	got := engine.executeCalls[0].params.Metadata
+	if m, _ := got[MetadataWASMModule].([]byte); string(m) != string(module) || got["caller"] != "test" {
+		t.Errorf("engine Metadata = %v, want the module alongside caller metadata", got)
+	}
+	if _, ok := meta[MetadataWASMModule]; ok {
+		t.Error("caller's Metadata was mutated")
+	}
+
+	// Languages the compiler does not accept run uncompiled.
+	if _, err := exec.ExecuteCode(ctx, ExecuteParams{Language: "go-interp", Code: "x"}); err != nil {
+		t.Fatalf("ExecuteCode() error = %v", err)
+	}
+	if compiler.calls != 1 || engine.executeCalls[1].params.Metadata != nil {
+		t.Errorf("compiler calls = %d, want 1 with uncompiled second execution", compiler.calls)
+	}
+}
+
+func TestExecuteCode_CompileErrorStopsExecution(t *testing.T) {
+	compiler := &fakeCompiler{languages: []string{LanguageGo}, err: ErrCompilationFailed}
+	engine := &mockEngine{}
+	exec, err := NewDefaultExecutor(Config{
+		Index:    &mockIndex{},
+		Docs:     &mockStore{},
+		Run:      &mockRunner{},
+		Engine:   engine,
+		Compiler: compiler,
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+
+	_, err = exec.ExecuteCode(context.Background(), ExecuteParams{Language: LanguageGo, Code: "x"})
+	if !errors.Is(err, ErrCompilationFailed) {
+		t.Errorf("ExecuteCode() error = %v, want %v", err, ErrCompilationFailed)
+	}
+
+	// The execution timeout bounds the build.
+	compiler.block = true
+	_, err = exec.ExecuteCode(context.Background(), ExecuteParams{Language: LanguageGo, Code: "x", Timeout: 10 * time.Millisecond})
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("ExecuteCode() with a slow build error = %v, want %v", err, ErrLimitExceeded)
+	}
+	if len(engine.executeCalls) != 0 {
+		t.Error("engine ran despite the compile error")
+	}
+}
+
+func TestToolchainCompiler_UnsupportedLanguage(t *testing.T) {
+	c := NewToolchainCompiler(CompilerConfig{})
+	if _, err := c.Compile(context.Background(), "cobol", "x"); !errors.Is(err, ErrUnsupportedLanguage) {
+		t.Errorf("Compile() error = %v, want %v", err, ErrUnsupportedLanguage)
+	}
+}
+
+func TestToolchainCompiler_Go(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles with the Go toolchain")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skipf("go not available: %v", err)
+	}
+	c := NewToolchainCompiler(CompilerConfig{})
+	ctx := context.Background()
+
+	src := "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(`__OUT__:\"hi\"`) }\n"
+	module, err := c.Compile(ctx, LanguageGo, src)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if len(module) < 4 || string(module[:4]) != "\x00asm" {
+		t.Fatalf("Compile() returned %d bytes without the wasm magic", len(module))
+	}
+
+	// A cached module is served without invoking the toolchain.
+	c.cfg.GoPath = "/nonexistent/go"
+	if _, err := c.Compile(ctx, LanguageGo, src); err != nil {
+		t.Errorf("Compile() cached error = %v", err)
+	}
+	if _, err := c.Compile(ctx, LanguageGo, "package main\n\nfunc main() { undefined() }\n"); !errors.Is(err, ErrCompilationFailed) {
+		t.Errorf("Compile() error = %v, want %v", err, ErrCompilationFailed)
+	}
+}
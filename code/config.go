@@ -2,12 +2,15 @@ package code
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/jonwraymond/tooldiscovery/index"
 	"github.com/jonwraymond/tooldiscovery/tooldoc"
 	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolfoundation/model"
 )
 
 // Config holds the configuration for a code executor.
@@ -22,28 +25,107 @@ type Config struct {
 
 	// Run provides tool execution capabilities.
 	// Required.
-	Run run.Runner
+	Run run.Executor
 
-	// Engine is the pluggable code execution engine.
-	// Required.
+	// Engine is the pluggable code execution engine. When Engines is also
+	// set, Engine handles every language Engines does not list.
+	// Required unless Engines is set.
 	Engine Engine
 
+	// Engines maps languages to the engine that runs them, letting one
+	// executor serve several languages. A language with no entry falls
+	// back to Engine, or fails with ErrUnsupportedLanguage when Engine
+	// is nil.
+	Engines map[string]Engine
+
 	// DefaultTimeout is the default execution timeout when not specified
 	// in ExecuteParams. If zero, no default timeout is applied.
 	DefaultTimeout time.Duration
 
 	// DefaultLanguage is the default language when not specified in
-	// ExecuteParams. Defaults to "go" if empty.
+	// ExecuteParams, and so selects among Engines. Defaults to the only
+	// key of Engines when Engine is nil and Engines has one entry,
+	// otherwise to "go".
 	DefaultLanguage string
 
 	// MaxToolCalls limits the maximum number of tool invocations per
 	// execution. Zero means unlimited.
 	MaxToolCalls int
 
+	// MaxStdoutBytes caps captured stdout per execution; output beyond it
+	// is dropped and marked. Zero means unlimited.
+	MaxStdoutBytes int
+
+	// NamespaceToolCalls sets per-namespace tool call limits per execution,
+	// keyed by namespace (the part of a tool ID before ":"). Calls to a
+	// listed namespace count only against its own limit, not MaxToolCalls;
+	// UnlimitedToolCalls exempts a namespace and zero blocks it.
+	NamespaceToolCalls map[string]int
+
 	// MaxChainSteps limits the maximum number of steps allowed in a single
 	// RunChain call. Zero means unlimited.
 	MaxChainSteps int
 
+	// PartialChains changes how RunChain handles a chain blocked by
+	// MaxChainSteps or a tool call limit: instead of rejecting it outright,
+	// the steps that fit within the limits run and their results are
+	// returned, followed by a marker StepResult for the first blocked step
+	// whose Err wraps ErrLimitExceeded. RunChain still returns an error
+	// wrapping ErrLimitExceeded so callers notice the chain was cut short.
+	// When none of the steps fit, only the marker is returned. When a step
+	// that ran fails, its error is returned as usual, without the marker.
+	PartialChains bool
+
+	// DedupToolCalls reuses the result of an earlier successful RunTool
+	// call when a snippet repeats an identical (toolID, args) call within
+	// the same execution. Reused calls do not count against MaxToolCalls.
+	DedupToolCalls bool
+
+	// Preflight, if set, checks each snippet before it reaches the Engine.
+	// Rejections are returned without executing. See NewPolicyPreflight.
+	Preflight Preflight
+
+	// Compiler, if set, compiles snippets in its languages to WASM before
+	// execution, passing the module to the Engine in ExecuteParams.Metadata
+	// under MetadataWASMModule. See NewToolchainCompiler.
+	Compiler Compiler
+
+	// SecretResolver resolves ExecuteParams.Secrets. Required only when
+	// executions request secrets.
+	SecretResolver SecretResolver
+
+	// SchemaValidator validates values against ExecuteParams.OutputSchema.
+	// Defaults to model.NewDefaultValidator().
+	SchemaValidator model.SchemaValidator
+
+	// ResultCache, if set, serves repeated identical executions from
+	// earlier successful results. See ResultCache for the key.
+	ResultCache *ResultCache
+
+	// MaxConcurrentExecutions caps how many executions run at once, so a
+	// shared executor cannot be stampeded into starting unbounded
+	// sandboxes. Further executions queue for a slot. Zero means unlimited.
+	MaxConcurrentExecutions int
+
+	// MaxQueuedExecutions caps how many executions may wait for a slot;
+	// beyond it they fail immediately with ErrExecutorBusy. Zero means
+	// no cap. Only used with MaxConcurrentExecutions.
+	MaxQueuedExecutions int
+
+	// QueueTimeout bounds how long an execution waits for a slot before
+	// failing with ErrExecutorBusy. Zero waits until the context ends.
+	// The wait does not count against the execution timeout.
+	QueueTimeout time.Duration
+
+	// SessionTTL is how long a session may sit idle before it is discarded.
+	// If zero, DefaultSessionTTL is used.
+	SessionTTL time.Duration
+
+	// MaxSessionResults caps how many results a session keeps for
+	// Session.Results, dropping the oldest first.
+	// If zero, DefaultMaxSessionResults is used.
+	MaxSessionResults int
+
 	// Logger is an optional logger for observability.
 	Logger Logger
 }
@@ -62,7 +144,7 @@ func (c *Config) Validate() error {
 	if c.Run == nil {
 		missing = append(missing, "Run")
 	}
-	if c.Engine == nil {
+	if c.Engine == nil && len(c.Engines) == 0 {
 		missing = append(missing, "Engine")
 	}
 
@@ -70,12 +152,55 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("%w: missing required fields: %s",
 			ErrConfiguration, strings.Join(missing, ", "))
 	}
+	for lang, engine := range c.Engines {
+		if engine == nil {
+			return fmt.Errorf("%w: nil engine for language %q", ErrConfiguration, lang)
+		}
+	}
+	if c.Engine == nil && c.DefaultLanguage != "" && c.Engines[c.DefaultLanguage] == nil {
+		return fmt.Errorf("%w: no engine for default language %q", ErrConfiguration, c.DefaultLanguage)
+	}
+	if c.MaxConcurrentExecutions < 0 || c.MaxQueuedExecutions < 0 || c.QueueTimeout < 0 {
+		return fmt.Errorf("%w: concurrency limits cannot be negative", ErrConfiguration)
+	}
+	for ns, limit := range c.NamespaceToolCalls {
+		if limit < UnlimitedToolCalls {
+			return fmt.Errorf("%w: invalid tool call limit %d for namespace %q",
+				ErrConfiguration, limit, ns)
+		}
+	}
 	return nil
 }
 
+// engineFor returns the engine that runs language.
+func (c *Config) engineFor(language string) (Engine, error) {
+	if engine, ok := c.Engines[language]; ok {
+		return engine, nil
+	}
+	if c.Engine != nil {
+		return c.Engine, nil
+	}
+	return nil, fmt.Errorf("%w: %q (supported: %s)", ErrUnsupportedLanguage,
+		language, strings.Join(slices.Sorted(maps.Keys(c.Engines)), ", "))
+}
+
 // applyDefaults sets default values for optional fields.
 func (c *Config) applyDefaults() {
 	if c.DefaultLanguage == "" {
 		c.DefaultLanguage = "go"
+		if c.Engine == nil && len(c.Engines) == 1 {
+			for lang := range c.Engines {
+				c.DefaultLanguage = lang
+			}
+		}
+	}
+	if c.SchemaValidator == nil {
+		c.SchemaValidator = model.NewDefaultValidator()
+	}
+	if c.SessionTTL <= 0 {
+		c.SessionTTL = DefaultSessionTTL
+	}
+	if c.MaxSessionResults <= 0 {
+		c.MaxSessionResults = DefaultMaxSessionResults
 	}
 }
@@ -196,3 +196,58 @@ func containsStr(s, substr string) bool {
 	}
 	return false
 }
+
+func TestConfig_Validate_NamespaceToolCalls(t *testing.T) {
+	cfg := Config{
+		Index:              &mockIndex{},
+		Docs:               &mockStore{},
+		Run:                &mockRunner{},
+		Engine:             &mockEngine{},
+		NamespaceToolCalls: map[string]int{"comms": -2},
+	}
+	if err := cfg.Validate(); !errors.Is(err, ErrConfiguration) {
+		t.Errorf("Validate() error = %v, want %v", err, ErrConfiguration)
+	}
+}
+
+func TestConfig_Validate_Engines(t *testing.T) {
+	base := Config{Index: &mockIndex{}, Docs: &mockStore{}, Run: &mockRunner{}}
+
+	cfg := base
+	cfg.Engines = map[string]Engine{"python": &mockEngine{}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with Engines only error = %v", err)
+	}
+
+	cfg.Engines = map[string]Engine{"python": nil}
+	if err := cfg.Validate(); !errors.Is(err, ErrConfiguration) {
+		t.Errorf("Validate() with nil engine error = %v, want %v", err, ErrConfiguration)
+	}
+
+	cfg.Engines = map[string]Engine{"python": &mockEngine{}}
+	cfg.DefaultLanguage = "go"
+	if err := cfg.Validate(); !errors.Is(err, ErrConfiguration) {
+		t.Errorf("Validate() with unserved default error = %v, want %v", err, ErrConfiguration)
+	}
+}
+
+func TestConfig_DefaultLanguage_SingleEngine(t *testing.T) {
+	cfg := Config{Engines: map[string]Engine{"javascript": &mockEngine{}}}
+	cfg.applyDefaults()
+	if cfg.DefaultLanguage != "javascript" {
+		t.Errorf("DefaultLanguage = %q, want %q", cfg.DefaultLanguage, "javascript")
+	}
+}
+
+func TestConfig_Validate_Concurrency(t *testing.T) {
+	cfg := Config{
+		Index:                   &mockIndex{},
+		Docs:                    &mockStore{},
+		Run:                     &mockRunner{},
+		Engine:                  &mockEngine{},
+		MaxConcurrentExecutions: -1,
+	}
+	if err := cfg.Validate(); !errors.Is(err, ErrConfiguration) {
+		t.Errorf("Validate() error = %v, want %v", err, ErrConfiguration)
+	}
+}
@@ -0,0 +1,163 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/run"
+)
+
+// Values the EngineContract snippets work with.
+const (
+	// ContractValue is the string contract snippets assign, print, or
+	// pass to the contract tool.
+	ContractValue = "contract"
+
+	// ContractToolID is the tool contract snippets call. It echoes its
+	// arguments back as its structured result.
+	ContractToolID = "contract:echo"
+)
+
+// DefaultContractGrace is how long an engine may take to stop after the
+// execution deadline when EngineContract.Grace is zero.
+const DefaultContractGrace = 2 * time.Second
+
+// EngineContract defines tests that any Engine implementation must pass.
+// Use RunEngineContractTests to test an implementation.
+//
+// Snippets are written in the engine's language. A snippet left empty
+// skips the tests that need it.
+type EngineContract struct {
+	// NewEngine creates a fresh engine instance for testing.
+	NewEngine func() Engine
+
+	// Language is the language passed in ExecuteParams.
+	Language string
+
+	// OutCode assigns the string ContractValue to __out.
+	OutCode string
+
+	// PrintlnCode writes ContractValue to stdout through Tools.Println,
+	// for example with the language's print function.
+	PrintlnCode string
+
+	// RunToolCode calls RunTool with ContractToolID and the arguments
+	// {"msg": ContractValue}, then assigns the result's "msg" field
+	// to __out.
+	RunToolCode string
+
+	// LoopCode runs until the execution is canceled.
+	LoopCode string
+
+	// Grace is how long the engine may take to stop after the execution
+	// deadline. Zero uses DefaultContractGrace.
+	Grace time.Duration
+}
+
+// RunEngineContractTests runs all contract tests for an Engine
+// implementation. Snippets run against a Tools that records the tool call
+// trace and captures stdout exactly as DefaultExecutor does.
+func RunEngineContractTests(t *testing.T, contract EngineContract) {
+	t.Helper()
+
+	execute := func(t *testing.T, ctx context.Context, src string) (ExecuteResult, *toolsImpl, error) {
+		t.Helper()
+		if src == "" {
+			t.Skip("snippet not provided")
+		}
+		tools := newTools(&Config{Run: run.ExecutorFunc(contractEcho)}, 0, 0)
+		params := ExecuteParams{Language: contract.Language, Code: src, Timeout: time.Minute}
+		result, err := contract.NewEngine().Execute(ctx, params, tools)
+		return result, tools, err
+	}
+
+	t.Run("Out", func(t *testing.T) {
+		t.Run("extracts __out", func(t *testing.T) {
+			result, _, err := execute(t, context.Background(), contract.OutCode)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Value != ContractValue {
+				t.Errorf("Execute() Value = %#v, want %q", result.Value, ContractValue)
+			}
+		})
+	})
+
+	t.Run("Println", func(t *testing.T) {
+		t.Run("captures stdout through Tools", func(t *testing.T) {
+			_, tools, err := execute(t, context.Background(), contract.PrintlnCode)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got := tools.GetStdout(); !strings.Contains(got, ContractValue) {
+				t.Errorf("stdout = %q, want it to contain %q", got, ContractValue)
+			}
+		})
+	})
+
+	t.Run("RunTool", func(t *testing.T) {
+		t.Run("records the tool call", func(t *testing.T) {
+			result, tools, err := execute(t, context.Background(), contract.RunToolCode)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			calls := tools.GetToolCalls()
+			if len(calls) != 1 {
+				t.Fatalf("recorded %d tool calls, want 1", len(calls))
+			}
+			if calls[0].ToolID != ContractToolID {
+				t.Errorf("recorded ToolID = %q, want %q", calls[0].ToolID, ContractToolID)
+			}
+			if calls[0].Args["msg"] != ContractValue {
+				t.Errorf("recorded Args = %v, want msg %q", calls[0].Args, ContractValue)
+			}
+			if calls[0].Error != "" {
+				t.Errorf("recorded Error = %q, want none", calls[0].Error)
+			}
+			if result.Value != ContractValue {
+				t.Errorf("Execute() Value = %#v, want %q", result.Value, ContractValue)
+			}
+		})
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		t.Run("stops at the context deadline", func(t *testing.T) {
+			grace := contract.Grace
+			if grace == 0 {
+				grace = DefaultContractGrace
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, _, err := execute(t, ctx, contract.LoopCode)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Execute() error = %v, want %v", err, context.DeadlineExceeded)
+			}
+			if elapsed := time.Since(start); elapsed > 100*time.Millisecond+grace {
+				t.Errorf("Execute() returned after %v, want within %v of the deadline", elapsed, grace)
+			}
+		})
+
+		t.Run("returns immediately when already canceled", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, _, err := execute(t, ctx, contract.OutCode)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Execute() error = %v, want %v", err, context.Canceled)
+			}
+		})
+	})
+}
+
+// contractEcho runs ContractToolID, echoing its arguments.
+func contractEcho(_ context.Context, toolID string, args map[string]any) (run.RunResult, error) {
+	if toolID != ContractToolID {
+		return run.RunResult{}, run.WrapError(toolID, nil, "run", run.ErrToolNotFound)
+	}
+	return run.RunResult{Structured: args}, nil
+}
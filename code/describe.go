@@ -0,0 +1,215 @@
+package code
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// FunctionInfo describes one function a snippet can call.
+type FunctionInfo struct {
+	// Name is the Tools method the function maps to, such as "RunTool".
+	Name string `json:"name"`
+
+	// Signature is how a snippet calls the function in the execution's
+	// language.
+	Signature string `json:"signature"`
+
+	// Description explains what the function does.
+	Description string `json:"description"`
+}
+
+// EngineInfo describes an Engine for Describe.
+type EngineInfo struct {
+	// Name identifies the engine, such as "javascript".
+	Name string `json:"name,omitempty"`
+
+	// Backend names the sandbox backend the engine runs snippets in,
+	// if any.
+	Backend string `json:"backend,omitempty"`
+
+	// SecurityProfile names the security profile applied to snippets,
+	// if any.
+	SecurityProfile string `json:"securityProfile,omitempty"`
+
+	// Signatures overrides the default Go signatures of the Tools
+	// functions, keyed by Tools method name. Functions missing from the
+	// map keep their default signature.
+	Signatures map[string]string `json:"-"`
+}
+
+// EngineDescriber is implemented by engines that can describe themselves
+// for Describe. Engines that do not implement it are described by their
+// language and the default Go signatures.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Errors: DescribeEngine must not fail; unknown fields stay empty.
+type EngineDescriber interface {
+	// DescribeEngine returns the engine's description for language.
+	DescribeEngine(language string) EngineInfo
+}
+
+// SurfaceLimits reports the limits an execution would run under.
+// Zero values mean unlimited.
+type SurfaceLimits struct {
+	// TimeoutMs is the execution timeout in milliseconds.
+	TimeoutMs int64 `json:"timeoutMs,omitempty"`
+
+	// MaxToolCalls caps tool calls outside limited namespaces.
+	MaxToolCalls int `json:"maxToolCalls,omitempty"`
+
+	// MaxChainSteps caps the steps of a single RunChain call.
+	MaxChainSteps int `json:"maxChainSteps,omitempty"`
+
+	// MaxStdoutBytes caps captured stdout.
+	MaxStdoutBytes int `json:"maxStdoutBytes,omitempty"`
+
+	// NamespaceToolCalls holds per-namespace tool call limits.
+	NamespaceToolCalls map[string]int `json:"namespaceToolCalls,omitempty"`
+}
+
+// Surface is the metatool surface an execution would see: the functions
+// it can call, the limits it runs under, and where it runs. It is meant
+// to be included in an LLM system prompt, either as JSON or via Prompt.
+type Surface struct {
+	// Language is the snippet language.
+	Language string `json:"language"`
+
+	// Engine describes the engine selected for Language.
+	Engine EngineInfo `json:"engine"`
+
+	// Functions lists the functions available to snippets.
+	Functions []FunctionInfo `json:"functions"`
+
+	// Namespaces lists the tool namespaces available for discovery.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Limits reports the limits the execution would run under.
+	Limits SurfaceLimits `json:"limits"`
+}
+
+// defaultFunctions describes the Tools methods with their Go signatures.
+var defaultFunctions = []FunctionInfo{
+	{"SearchTools", "tools.SearchTools(ctx, query string, limit int) ([]index.Summary, error)",
+		"Search for tools matching a query."},
+	{"SearchToolsFiltered", "tools.SearchToolsFiltered(ctx, query string, filter code.SearchFilter) ([]index.Summary, error)",
+		"Search for tools matching a query, restricted to namespaces and tags."},
+	{"ListNamespaces", "tools.ListNamespaces(ctx) ([]string, error)",
+		"List the available tool namespaces."},
+	{"DescribeTool", "tools.DescribeTool(ctx, id string, level tooldoc.DetailLevel) (tooldoc.ToolDoc, error)",
+		"Get a tool's documentation at a detail level (summary, schema, or full)."},
+	{"ListToolExamples", "tools.ListToolExamples(ctx, id string, max int) ([]tooldoc.ToolExample, error)",
+		"Get usage examples for a tool."},
+	{"RunTool", "tools.RunTool(ctx, id string, args map[string]any) (run.RunResult, error)",
+		"Run one tool and return its result."},
+	{"RunChain", "tools.RunChain(ctx, steps []run.ChainStep) (run.RunResult, []run.StepResult, error)",
+		"Run tools in sequence, optionally feeding each result to the next step."},
+	{"Println", "tools.Println(args ...any)",
+		"Write a line to captured stdout."},
+}
+
+// Describe reports the surface an execution with params would see,
+// without running any code. Defaults and limits are resolved exactly as
+// ExecuteCode resolves them. Returns ErrUnsupportedLanguage when no
+// engine handles the language.
+func (e *DefaultExecutor) Describe(ctx context.Context, params ExecuteParams) (Surface, error) {
+	if err := ctx.Err(); err != nil {
+		return Surface{}, err
+	}
+	if params.Language == "" {
+		params.Language = e.cfg.DefaultLanguage
+	}
+	if params.Timeout == 0 {
+		params.Timeout = e.cfg.DefaultTimeout
+	}
+	engine, err := e.cfg.engineFor(params.Language)
+	if err != nil {
+		return Surface{}, err
+	}
+
+	info := EngineInfo{Name: params.Language}
+	if d, ok := engine.(EngineDescriber); ok {
+		info = d.DescribeEngine(params.Language)
+	}
+
+	functions := make([]FunctionInfo, len(defaultFunctions))
+	for i, fn := range defaultFunctions {
+		if sig, ok := info.Signatures[fn.Name]; ok {
+			fn.Signature = sig
+		}
+		functions[i] = fn
+	}
+
+	namespaces, err := e.cfg.Index.ListNamespaces()
+	if err != nil {
+		return Surface{}, fmt.Errorf("code: list namespaces: %w", err)
+	}
+
+	return Surface{
+		Language:   params.Language,
+		Engine:     info,
+		Functions:  functions,
+		Namespaces: namespaces,
+		Limits: SurfaceLimits{
+			TimeoutMs:          params.Timeout.Milliseconds(),
+			MaxToolCalls:       capLimit(params.MaxToolCalls, e.cfg.MaxToolCalls),
+			MaxChainSteps:      e.cfg.MaxChainSteps,
+			MaxStdoutBytes:     capLimit(params.MaxStdoutBytes, e.cfg.MaxStdoutBytes),
+			NamespaceToolCalls: maps.Clone(e.cfg.NamespaceToolCalls),
+		},
+	}, nil
+}
+
+// Prompt renders the surface as plain text for an LLM system prompt.
+func (s Surface) Prompt() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Language: %s\n", s.Language)
+
+	engine := s.Engine.Name
+	var where []string
+	if s.Engine.Backend != "" {
+		where = append(where, "backend "+s.Engine.Backend)
+	}
+	if s.Engine.SecurityProfile != "" {
+		where = append(where, "security profile "+s.Engine.SecurityProfile)
+	}
+	if len(where) > 0 {
+		engine += " (" + strings.Join(where, ", ") + ")"
+	}
+	fmt.Fprintf(&b, "Engine: %s\n", engine)
+
+	b.WriteString("Functions:\n")
+	for _, fn := range s.Functions {
+		fmt.Fprintf(&b, "- %s: %s\n", fn.Signature, fn.Description)
+	}
+	if len(s.Namespaces) > 0 {
+		fmt.Fprintf(&b, "Namespaces: %s\n", strings.Join(s.Namespaces, ", "))
+	}
+
+	b.WriteString("Limits:\n")
+	limit := func(name string, v int64, unit string) {
+		if v > 0 {
+			fmt.Fprintf(&b, "- %s: %d%s\n", name, v, unit)
+		} else {
+			fmt.Fprintf(&b, "- %s: unlimited\n", name)
+		}
+	}
+	limit("timeout", s.Limits.TimeoutMs, "ms")
+	limit("tool calls", int64(s.Limits.MaxToolCalls), "")
+	limit("chain steps", int64(s.Limits.MaxChainSteps), "")
+	limit("stdout", int64(s.Limits.MaxStdoutBytes), " bytes")
+	for _, ns := range slices.Sorted(maps.Keys(s.Limits.NamespaceToolCalls)) {
+		n := s.Limits.NamespaceToolCalls[ns]
+		if n == UnlimitedToolCalls {
+			fmt.Fprintf(&b, "- tool calls in %s: unlimited\n", ns)
+		} else {
+			fmt.Fprintf(&b, "- tool calls in %s: %d\n", ns, n)
+		}
+	}
+
+	b.WriteString("Assign the final result to __out.\n")
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package code
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// describingEngine is a mockEngine that implements EngineDescriber.
+type describingEngine struct {
+	mockEngine
+	info EngineInfo
+}
+
+func (d *describingEngine) DescribeEngine(string) EngineInfo { return d.info }
+
+func TestDescribe_Surface(t *testing.T) {
+	engine := &describingEngine{info: EngineInfo{
+		Name:            "python",
+		Backend:         "docker",
+		SecurityProfile: "standard",
+		Signatures:      map[string]string{"RunTool": "tools.run_tool(id, args=None)"},
+	}}
+	exec, err := NewDefaultExecutor(Config{
+		Index:              &mockIndex{namespacesResult: []string{"comms", "weather"}},
+		Docs:               &mockStore{},
+		Run:                &mockRunner{},
+		Engines:            map[string]Engine{"python": engine},
+		DefaultTimeout:     30 * time.Second,
+		MaxToolCalls:       20,
+		MaxChainSteps:      5,
+		NamespaceToolCalls: map[string]int{"comms": 2},
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+
+	surface, err := exec.Describe(context.Background(), ExecuteParams{MaxToolCalls: 50})
+	if err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+	if len(engine.executeCalls) != 0 {
+		t.Errorf("Describe() ran the engine %d times", len(engine.executeCalls))
+	}
+	if surface.Language != "python" || surface.Engine.Backend != "docker" || surface.Engine.SecurityProfile != "standard" {
+		t.Errorf("Describe() engine = %q %+v", surface.Language, surface.Engine)
+	}
+	want := SurfaceLimits{TimeoutMs: 30000, MaxToolCalls: 20, MaxChainSteps: 5, NamespaceToolCalls: map[string]int{"comms": 2}}
+	if surface.Limits.TimeoutMs != want.TimeoutMs || surface.Limits.MaxToolCalls != want.MaxToolCalls ||
+		surface.Limits.MaxChainSteps != want.MaxChainSteps || surface.Limits.NamespaceToolCalls["comms"] != 2 {
+		t.Errorf("Describe().Limits = %+v, want %+v", surface.Limits, want)
+	}
+	if len(surface.Namespaces) != 2 {
+		t.Errorf("Describe().Namespaces = %v", surface.Namespaces)
+	}
+
+	var runTool FunctionInfo
+	for _, fn := range surface.Functions {
+		if fn.Name == "RunTool" {
+			runTool = fn
+		}
+	}
+	if runTool.Signature != "tools.run_tool(id, args=None)" || runTool.Description == "" {
+		t.Errorf("RunTool function = %+v, want engine signature with description", runTool)
+	}
+
+	prompt := surface.Prompt()
+	for _, s := range []string{"Language: python", "backend docker", "tools.run_tool(id, args=None)", "tool calls: 20", "tool calls in comms: 2", "stdout: unlimited", "__out"} {
+		if !strings.Contains(prompt, s) {
+			t.Errorf("Prompt() missing %q:\n%s", s, prompt)
+		}
+	}
+
+	data, err := json.Marshal(surface)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if strings.Contains(string(data), "Signatures") {
+		t.Errorf("JSON should not include raw signature overrides: %s", data)
+	}
+}
+
+func TestDescribe_DefaultSignatures(t *testing.T) {
+	exec, _ := NewDefaultExecutor(Config{
+		Index:  &mockIndex{},
+		Docs:   &mockStore{},
+		Run:    &mockRunner{},
+		Engine: &mockEngine{},
+	})
+
+	surface, err := exec.Describe(context.Background(), ExecuteParams{})
+	if err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+	if surface.Engine.Name != "go" {
+		t.Errorf("Engine.Name = %q, want %q", surface.Engine.Name, "go")
+	}
+	if len(surface.Functions) != len(defaultFunctions) || !strings.HasPrefix(surface.Functions[0].Signature, "tools.SearchTools(") {
+		t.Errorf("Functions = %+v, want default Go signatures", surface.Functions)
+	}
+}
+
+func TestDescribe_UnsupportedLanguage(t *testing.T) {
+	exec, _ := NewDefaultExecutor(Config{
+		Index:   &mockIndex{},
+		Docs:    &mockStore{},
+		Run:     &mockRunner{},
+		Engines: map[string]Engine{"python": &mockEngine{}},
+	})
+
+	if _, err := exec.Describe(context.Background(), ExecuteParams{Language: "ruby"}); !errors.Is(err, ErrUnsupportedLanguage) {
+		t.Errorf("Describe() error = %v, want %v", err, ErrUnsupportedLanguage)
+	}
+}
@@ -0,0 +1,44 @@
+package code
+
+import (
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrDeterminismUnsupported is returned when ExecuteParams.Determinism is
+// set but the selected engine cannot honor it.
+var ErrDeterminismUnsupported = errors.New("code: engine does not support deterministic execution")
+
+// Determinism pins the sources of nondeterminism a snippet can observe,
+// so a recorded execution can be replayed with the same clock readings
+// and random numbers.
+type Determinism struct {
+	// Now is the time every clock read inside the sandbox returns. The
+	// clock does not advance. Zero leaves the real clock in place.
+	Now time.Time `json:"now,omitzero"`
+
+	// Seed seeds the sandbox's random number generator.
+	Seed int64 `json:"seed"`
+}
+
+// Rand returns a random number generator seeded from Seed, for engines
+// that supply randomness to snippets from the host.
+func (d Determinism) Rand() *rand.Rand {
+	return rand.New(rand.NewPCG(uint64(d.Seed), 0))
+}
+
+// DeterministicEngine is implemented by engines that honor
+// ExecuteParams.Determinism. The executor rejects deterministic executions
+// for engines that do not implement it with ErrDeterminismUnsupported.
+//
+// Contract:
+//   - Concurrency: implementations must be safe for concurrent use.
+//   - Determinism: every clock read and random number a snippet can obtain
+//     from the engine's language runtime must derive from Determinism.
+type DeterministicEngine interface {
+	Engine
+
+	// SupportsDeterminism reports whether the engine honors Determinism.
+	SupportsDeterminism() bool
+}
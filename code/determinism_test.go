@@ -0,0 +1,45 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// deterministicEngine is a mockEngine that implements DeterministicEngine.
+type deterministicEngine struct {
+	mockEngine
+}
+
+func (*deterministicEngine) SupportsDeterminism() bool { return true }
+
+func TestExecuteCode_Determinism(t *testing.T) {
+	params := ExecuteParams{Code: "x", Determinism: &Determinism{Seed: 1}}
+
+	plain := &mockEngine{}
+	exec, _ := NewDefaultExecutor(Config{Index: &mockIndex{}, Docs: &mockStore{}, Run: &mockRunner{}, Engine: plain})
+	if _, err := exec.ExecuteCode(context.Background(), params); !errors.Is(err, ErrDeterminismUnsupported) {
+		t.Errorf("ExecuteCode() error = %v, want %v", err, ErrDeterminismUnsupported)
+	}
+	if len(plain.executeCalls) != 0 {
+		t.Error("unsupported engine should not run")
+	}
+
+	engine := &deterministicEngine{}
+	exec, _ = NewDefaultExecutor(Config{Index: &mockIndex{}, Docs: &mockStore{}, Run: &mockRunner{}, Engine: engine})
+	if _, err := exec.ExecuteCode(context.Background(), params); err != nil {
+		t.Fatalf("ExecuteCode() error = %v", err)
+	}
+	if got := engine.executeCalls[0].params.Determinism; got == nil || got.Seed != 1 {
+		t.Errorf("engine Determinism = %+v, want seed 1", got)
+	}
+}
+
+func TestDeterminism_Rand(t *testing.T) {
+	a, b := Determinism{Seed: 42}.Rand(), Determinism{Seed: 42}.Rand()
+	for i := 0; i < 5; i++ {
+		if x, y := a.Float64(), b.Float64(); x != y {
+			t.Fatalf("draw %d: %v != %v for the same seed", i, x, y)
+		}
+	}
+}
@@ -10,8 +10,8 @@
 // The package defines three main interfaces:
 //
 //   - [Tools]: The metatool environment exposed to code snippets, providing
-//     SearchTools, ListNamespaces, DescribeTool, ListToolExamples, RunTool,
-//     RunChain, and Println functions.
+//     SearchTools, SearchToolsFiltered, ListNamespaces, DescribeTool,
+//     ListToolExamples, RunTool, RunChain, and Println functions.
 //
 //   - [Engine]: The pluggable code execution engine that runs snippets with
 //     access to the Tools environment.
@@ -19,12 +19,69 @@
 //   - [Executor]: The main entry point that orchestrates execution, applying
 //     defaults, enforcing limits, and collecting results.
 //
+// One executor can serve several languages: [Config].Engines maps each
+// language to its Engine, and [Config].DefaultLanguage picks one when
+// [ExecuteParams].Language is empty. Unmatched languages fail with
+// [ErrUnsupportedLanguage], which lists the supported ones.
+//
+// [DefaultExecutor.Describe] reports the [Surface] an execution would see
+// (functions, limits, engine, backend and security profile) without
+// running code; [Surface.Prompt] renders it for an LLM system prompt.
+// Engines that implement [EngineDescriber] supply their own details.
+//
+// # Concurrency
+//
+// The [Tools] passed to an Engine is safe for concurrent use, so engines
+// may run snippet goroutines that call tools and Println in parallel.
+// Limits are reserved atomically before each call and the trace and stdout
+// stay consistent; trace order follows completion order.
+//
 // # Execution Limits
 //
-// The executor enforces two types of limits:
+// The executor enforces these limits:
 //
 //   - Timeout: Applied via context deadline, returns [ErrLimitExceeded]
 //   - MaxToolCalls: Tracks tool invocations, returns [ErrLimitExceeded] when exceeded
+//   - MaxStdoutBytes: Caps captured stdout, truncating with [StdoutTruncatedMarker]
+//     and setting [ExecuteResult].Truncated
+//   - MaxConcurrentExecutions: Caps executions running at once; others queue
+//     (bounded by MaxQueuedExecutions and QueueTimeout) or fail with
+//     [ErrExecutorBusy]; session executions queue only once it is their
+//     session's turn
+//
+// [Config].NamespaceToolCalls decomposes the tool call budget by namespace:
+// listed namespaces get their own limit (or [UnlimitedToolCalls]) and do not
+// draw from MaxToolCalls.
+//
+// A RunChain call that would breach MaxChainSteps or a tool call limit is
+// rejected before any step runs. With [Config].PartialChains set, the steps
+// that fit run instead: RunChain returns their results followed by a marker
+// [run.StepResult] for the first blocked step, and an error wrapping
+// [ErrLimitExceeded], so a snippet can continue from partial progress.
+//
+// # Preflight
+//
+// A [Preflight] set in [Config] inspects each snippet before the Engine
+// runs. [NewPolicyPreflight] rejects imports outside an allowlist,
+// filesystem and network primitives, and exit-less unconditional loops,
+// returning a [PolicyError] that matches [ErrPolicyViolation]. Go snippets
+// must parse, and their uses of packages the wrapping program imports are
+// checked as imports are.
+//
+// Engines that implement [ValidatingEngine] check [ExecuteParams] before
+// anything runs; the runtime-backed engine rejects params its backend's
+// capabilities cannot honor with [ErrUnsupportedCapability].
+//
+// # WASM Compilation
+//
+// Sandboxes that run WebAssembly need a compiled module rather than
+// source. With [Config].Compiler set, the executor compiles snippets in
+// the compiler's languages first, within the execution's concurrency slot
+// and timeout, and passes the module to the Engine in
+// [ExecuteParams].Metadata under [MetadataWASMModule], where the wasm
+// runtime backend picks it up. [NewToolchainCompiler] builds Go, TinyGo,
+// and AssemblyScript programs with their toolchains and caches modules by
+// a hash of the source.
 //
 // # Tool Call Tracing
 //
@@ -34,11 +91,103 @@
 //   - Structured: The structured result from successful execution
 //   - BackendKind: The backend that executed the tool (mcp, provider, local)
 //   - Error/ErrorOp: Error information if the call failed
+//   - StartedAt: When the call started
 //   - DurationMs: Execution time in milliseconds
+//   - Deduplicated: Set when the result was reused from an identical earlier call
+//
+// With [Config].DedupToolCalls, repeated identical RunTool calls within one
+// execution reuse the first successful result instead of running again.
+//
+// Traces export without custom marshalling: [SpanEvents] converts records
+// to OpenTelemetry span events, and [WriteJSONL] and [ReadJSONL] store
+// them as JSON Lines, one record per line.
+//
+// # Streaming
+//
+// [DefaultExecutor.ExecuteCodeStream] delivers [CodeEvent] values while a
+// snippet runs: stdout lines, tool call start/finish, and partial values
+// reported by engines through [ReportPartial], ending with a done or error
+// event that carries the final [ExecuteResult].
+//
+// # Cancellation
+//
+// [DefaultExecutor.ExecuteCodeAsync] runs a snippet in the background and
+// returns an [Execution] handle. [Execution.Cancel] cancels the execution's
+// context, which engines and backends honor by stopping the snippet's
+// process, container, or VM; [Execution.Status] and
+// [DefaultExecutor.Execution] let UIs observe running executions.
+//
+// # Deterministic Replay
+//
+// [ExecuteParams].Determinism pins the clock and random seed a snippet
+// observes, so recorded executions replay identically in tests. Engines
+// opt in by implementing [DeterministicEngine]; others are rejected with
+// [ErrDeterminismUnsupported] rather than silently running
+// nondeterministically.
+//
+// # Environment and Secrets
+//
+// [ExecuteParams].Env scopes environment variables to one execution.
+// [ExecuteParams].Secrets names secret references that [Config].SecretResolver
+// resolves just before execution; resolved values reach the Engine through
+// Env and are replaced with "[REDACTED:NAME]" in stdout, stderr, the
+// returned value, and tool call traces, including their content items.
+// [ExecuteResult].EnvNames records only variable names.
+//
+// # Result Caching
+//
+// With [Config].ResultCache, successful executions are memoized by
+// language, code hash, and outcome-affecting parameters such as
+// [ExecuteParams].Metadata, so retried snippets skip engine startup.
+// [ExecuteParams].BypassCache forces a fresh run; session executions are
+// never cached.
+//
+// # Sessions
+//
+// Executions that set [ExecuteParams].SessionID share a [Session], which
+// engines reach via [SessionFromContext]. Sessions serialize their
+// executions, record their most recent [Config].MaxSessionResults results,
+// and may hold engine state such as a live interpreter. An execution
+// waiting for its session's turn gives up when its context ends. Idle
+// sessions expire after [Config].SessionTTL;
+// [DefaultExecutor.CloseSession] ends one explicitly.
+//
+// # Branching
+//
+// Every result carries a [ToolsSnapshot] of the tool call trace, stdout,
+// and consumed budget in [ExecuteResult].Snapshot. Passing it as
+// [ExecuteParams].Parent to several executions branches them: each starts
+// from the parent's state in isolation, so tree-of-thought agents can
+// explore alternatives that share a common prefix. Engines may roll back
+// within an execution through [SnapshotTools].
+//
+// # Logging
+//
+// When [Config].Logger implements [StructuredLogger], the executor emits a
+// [LogEntry] for execution start and end, every tool call (tool ID,
+// duration, backend, error code), and limit enforcement. [NewSlogLogger]
+// adapts a *slog.Logger.
 //
 // # Result Convention
 //
 // Code snippets should assign their final result to the `__out` variable.
 // The Engine is responsible for extracting this value and returning it
 // in [ExecuteResult].Value.
+//
+// When [ExecuteParams].OutputSchema is set, the value is validated with
+// [Config].SchemaValidator and the outcome is reported in
+// [ExecuteResult].Validation; non-conforming values fail with
+// [ErrOutputValidation].
+//
+// Engines that can measure what a snippet consumed report it in
+// [ExecuteResult].ResourceUsage (CPU time, peak memory, wall time in the
+// sandbox, and disk and network traffic), which callers can use to tune
+// [Config] limits.
+//
+// # Engine Contract
+//
+// [RunEngineContractTests] certifies a third-party [Engine]: given
+// snippets in the engine's language, it checks __out extraction, stdout
+// capture through Println, tool call recording, and that execution stops
+// at the context deadline.
 package code
@@ -23,3 +23,18 @@ type Engine interface {
 	// and any errors encountered.
 	Execute(ctx context.Context, params ExecuteParams, tools Tools) (ExecuteResult, error)
 }
+
+// ValidatingEngine is implemented by engines that can tell, before running
+// anything, whether they can honor ExecuteParams. The executor calls
+// ValidateParams after preflight and returns its error.
+//
+// Contract:
+//   - Concurrency: implementations must be safe for concurrent use.
+//   - Side effects: must not execute code.
+//   - Errors: rejections should wrap ErrUnsupportedCapability.
+type ValidatingEngine interface {
+	Engine
+
+	// ValidateParams reports whether params can be executed.
+	ValidateParams(ctx context.Context, params ExecuteParams) error
+}
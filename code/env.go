@@ -0,0 +1,163 @@
+package code
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jonwraymond/toolexec/run"
+)
+
+// SecretResolver resolves secret references named in
+// ExecuteParams.Secrets to their values at execution time, so secrets never
+// appear in snippet text or request payloads.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines.
+// - Errors: resolution failures abort the execution before the Engine runs.
+type SecretResolver interface {
+	// ResolveSecret returns the value of the secret identified by ref.
+	ResolveSecret(ctx context.Context, ref string) (string, error)
+}
+
+// SecretResolverFunc adapts a function into a SecretResolver.
+type SecretResolverFunc func(ctx context.Context, ref string) (string, error)
+
+// ResolveSecret implements SecretResolver.
+func (f SecretResolverFunc) ResolveSecret(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// redactedValue is the placeholder substituted for a secret value in
+// traces and captured output.
+func redactedValue(name string) string {
+	return "[REDACTED:" + name + "]"
+}
+
+// resolveEnv merges params.Env with resolved params.Secrets into the
+// environment handed to the Engine. It returns the merged environment and
+// the resolved secret values keyed by variable name.
+func (e *DefaultExecutor) resolveEnv(ctx context.Context, params ExecuteParams) (map[string]string, map[string]string, error) {
+	if len(params.Env) == 0 && len(params.Secrets) == 0 {
+		return nil, nil, nil
+	}
+	env := maps.Clone(params.Env)
+	if env == nil {
+		env = make(map[string]string, len(params.Secrets))
+	}
+	if len(params.Secrets) == 0 {
+		return env, nil, nil
+	}
+	if e.cfg.SecretResolver == nil {
+		return nil, nil, fmt.Errorf("%w: secrets requested but no SecretResolver configured", ErrConfiguration)
+	}
+
+	secrets := make(map[string]string, len(params.Secrets))
+	for _, name := range slices.Sorted(maps.Keys(params.Secrets)) {
+		value, err := e.cfg.SecretResolver.ResolveSecret(ctx, params.Secrets[name])
+		if err != nil {
+			return nil, nil, fmt.Errorf("code: resolve secret for %s: %w", name, err)
+		}
+		env[name] = value
+		secrets[name] = value
+	}
+	return env, secrets, nil
+}
+
+// redactor replaces secret values with their redacted names.
+type redactor struct {
+	replacer *strings.Replacer
+}
+
+// newRedactor returns a redactor for secrets, or nil if there are none.
+func newRedactor(secrets map[string]string) *redactor {
+	var pairs []string
+	// Replace longer values first so overlapping secrets redact fully.
+	names := slices.SortedFunc(maps.Keys(secrets), func(a, b string) int {
+		return len(secrets[b]) - len(secrets[a])
+	})
+	for _, name := range names {
+		if v := secrets[name]; v != "" {
+			pairs = append(pairs, v, redactedValue(name))
+		}
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+	return &redactor{replacer: strings.NewReplacer(pairs...)}
+}
+
+// apply redacts secret values from the captured output, the returned
+// value, and the tool call trace.
+func (r *redactor) apply(result *ExecuteResult) {
+	if r == nil {
+		return
+	}
+	result.Stdout = r.replacer.Replace(result.Stdout)
+	result.Stderr = r.replacer.Replace(result.Stderr)
+	result.Value = r.value(result.Value)
+	for i := range result.ToolCalls {
+		tc := &result.ToolCalls[i]
+		if tc.Args != nil {
+			tc.Args, _ = r.value(tc.Args).(map[string]any)
+		}
+		tc.Structured = r.value(tc.Structured)
+		tc.Contents = r.contents(tc.Contents)
+		tc.Error = r.replacer.Replace(tc.Error)
+	}
+}
+
+// contents redacts secret values from the text of content items. The
+// items are copied, since the runner or a cache may share them.
+func (r *redactor) contents(items []run.ContentItem) []run.ContentItem {
+	if items == nil {
+		return nil
+	}
+	out := slices.Clone(items)
+	for i := range out {
+		out[i].Text = r.replacer.Replace(out[i].Text)
+	}
+	return out
+}
+
+// sink wraps emit so streamed events are redacted too.
+func (r *redactor) sink(emit eventSink) eventSink {
+	if r == nil || emit == nil {
+		return emit
+	}
+	return func(ev CodeEvent) {
+		ev.Line = r.replacer.Replace(ev.Line)
+		ev.Value = r.value(ev.Value)
+		if ev.ToolCall != nil {
+			single := ExecuteResult{ToolCalls: []ToolCallRecord{*ev.ToolCall}}
+			r.apply(&single)
+			ev.ToolCall = &single.ToolCalls[0]
+		}
+		emit(ev)
+	}
+}
+
+// value redacts secret values from strings nested in v.
+func (r *redactor) value(v any) any {
+	switch val := v.(type) {
+	case string:
+		return r.replacer.Replace(val)
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = r.value(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = r.value(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
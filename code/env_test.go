@@ -0,0 +1,89 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jonwraymond/toolexec/run"
+)
+
+func TestExecuteCode_EnvAndSecrets(t *testing.T) {
+	var seenEnv map[string]string
+	engine := engineFunc(func(ctx context.Context, params ExecuteParams, tools Tools) (ExecuteResult, error) {
+		seenEnv = params.Env
+		tools.Println("token is", params.Env["API_TOKEN"])
+		_, err := tools.RunTool(ctx, "api:call", map[string]any{"auth": "Bearer " + params.Env["API_TOKEN"]})
+		return ExecuteResult{Value: map[string]any{"token": params.Env["API_TOKEN"]}}, err
+	})
+	resolver := SecretResolverFunc(func(_ context.Context, ref string) (string, error) {
+		if ref == "vault://api" {
+			return "s3cr3t", nil
+		}
+		return "", errors.New("unknown secret")
+	})
+	contents := []run.ContentItem{{Kind: run.ContentText, Text: "echo s3cr3t"}}
+	exec, err := NewDefaultExecutor(Config{
+		Index:          &mockIndex{},
+		Docs:           &mockStore{},
+		Run:            &mockRunner{runResult: run.RunResult{Structured: "ok", Contents: contents}},
+		Engine:         engine,
+		SecretResolver: resolver,
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+
+	result, err := exec.ExecuteCode(context.Background(), ExecuteParams{
+		Code:    "x",
+		Env:     map[string]string{"REGION": "eu"},
+		Secrets: map[string]string{"API_TOKEN": "vault://api"},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteCode() error = %v", err)
+	}
+	if seenEnv["REGION"] != "eu" || seenEnv["API_TOKEN"] != "s3cr3t" {
+		t.Errorf("engine env = %v", seenEnv)
+	}
+	if strings.Contains(result.Stdout, "s3cr3t") || !strings.Contains(result.Stdout, "[REDACTED:API_TOKEN]") {
+		t.Errorf("Stdout = %q, want secret redacted", result.Stdout)
+	}
+	if got := result.ToolCalls[0].Args["auth"]; got != "Bearer [REDACTED:API_TOKEN]" {
+		t.Errorf("trace auth arg = %v, want redacted", got)
+	}
+	if got := result.ToolCalls[0].Contents; len(got) != 1 || got[0].Text != "echo [REDACTED:API_TOKEN]" {
+		t.Errorf("trace contents = %+v, want redacted", got)
+	}
+	if contents[0].Text != "echo s3cr3t" {
+		t.Error("redaction modified the runner's contents")
+	}
+	if got, _ := result.Value.(map[string]any); got["token"] != "[REDACTED:API_TOKEN]" {
+		t.Errorf("Value = %v, want secret redacted", result.Value)
+	}
+	if len(result.EnvNames) != 2 || result.EnvNames[0] != "API_TOKEN" || result.EnvNames[1] != "REGION" {
+		t.Errorf("EnvNames = %v", result.EnvNames)
+	}
+}
+
+func TestExecuteCode_SecretErrors(t *testing.T) {
+	base := Config{
+		Index:  &mockIndex{},
+		Docs:   &mockStore{},
+		Run:    &mockRunner{},
+		Engine: &mockEngine{},
+	}
+	params := ExecuteParams{Code: "x", Secrets: map[string]string{"TOKEN": "missing"}}
+
+	exec, _ := NewDefaultExecutor(base)
+	if _, err := exec.ExecuteCode(context.Background(), params); !errors.Is(err, ErrConfiguration) {
+		t.Errorf("without resolver error = %v, want %v", err, ErrConfiguration)
+	}
+
+	notFound := errors.New("not found")
+	base.SecretResolver = SecretResolverFunc(func(context.Context, string) (string, error) { return "", notFound })
+	exec, _ = NewDefaultExecutor(base)
+	if _, err := exec.ExecuteCode(context.Background(), params); !errors.Is(err, notFound) {
+		t.Errorf("resolver failure error = %v, want %v", err, notFound)
+	}
+}
@@ -17,6 +17,15 @@ var (
 	// ErrLimitExceeded indicates that an execution limit was reached,
 	// such as timeout or maximum tool calls.
 	ErrLimitExceeded = errors.New("limit exceeded")
+
+	// ErrUnsupportedLanguage indicates that no configured Engine handles
+	// the requested language.
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+
+	// ErrUnsupportedCapability indicates that the engine cannot honor the
+	// execution parameters, such as a workspace its backend does not
+	// provide. It is reported before the snippet runs.
+	ErrUnsupportedCapability = errors.New("unsupported capability")
 )
 
 // CodeError represents an error that occurred during code snippet execution.
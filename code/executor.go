@@ -4,7 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"sync"
 	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
 )
 
 // Executor is the main entry point for executing code snippets.
@@ -24,7 +29,12 @@ type Executor interface {
 
 // DefaultExecutor is the standard implementation of Executor.
 type DefaultExecutor struct {
-	cfg Config
+	cfg       Config
+	sessions  *sessionStore
+	admission *admission
+
+	runningMu sync.Mutex
+	running   map[string]*Execution
 }
 
 // NewDefaultExecutor creates a new DefaultExecutor with the given configuration.
@@ -34,11 +44,21 @@ func NewDefaultExecutor(cfg Config) (*DefaultExecutor, error) {
 		return nil, err
 	}
 	cfg.applyDefaults()
-	return &DefaultExecutor{cfg: cfg}, nil
+	return &DefaultExecutor{
+		cfg:       cfg,
+		sessions:  newSessionStore(cfg.SessionTTL, cfg.MaxSessionResults),
+		admission: newAdmission(&cfg),
+	}, nil
 }
 
 // ExecuteCode runs a code snippet with the given parameters.
 func (e *DefaultExecutor) ExecuteCode(ctx context.Context, params ExecuteParams) (ExecuteResult, error) {
+	return e.execute(ctx, params, nil)
+}
+
+// execute runs a snippet, streaming events to emit when it is non-nil.
+func (e *DefaultExecutor) execute(ctx context.Context, params ExecuteParams, emit eventSink) (ExecuteResult, error) {
+	ctx, executionID := withExecutionID(ctx)
 	// Apply defaults from config
 	if params.Language == "" {
 		params.Language = e.cfg.DefaultLanguage
@@ -46,17 +66,88 @@ func (e *DefaultExecutor) ExecuteCode(ctx context.Context, params ExecuteParams)
 	if params.Timeout == 0 {
 		params.Timeout = e.cfg.DefaultTimeout
 	}
+	engine, err := e.cfg.engineFor(params.Language)
+	if err != nil {
+		return ExecuteResult{}, err
+	}
+	if params.Determinism != nil {
+		if d, ok := engine.(DeterministicEngine); !ok || !d.SupportsDeterminism() {
+			return ExecuteResult{}, fmt.Errorf("%w: language %q", ErrDeterminismUnsupported, params.Language)
+		}
+	}
+
+	if e.cfg.Preflight != nil {
+		if err := e.cfg.Preflight.Check(ctx, params); err != nil {
+			return ExecuteResult{}, err
+		}
+	}
+	if v, ok := engine.(ValidatingEngine); ok {
+		if err := v.ValidateParams(ctx, params); err != nil {
+			return ExecuteResult{}, err
+		}
+	}
+
+	// Secrets are resolved before the cache lookup, so a rotated secret
+	// misses results computed with its old value.
+	env, secrets, err := e.resolveEnv(ctx, params)
+	if err != nil {
+		return ExecuteResult{}, err
+	}
 
-	// Resolve MaxToolCalls (params capped by config)
-	maxCalls := params.MaxToolCalls
-	if e.cfg.MaxToolCalls > 0 {
-		if maxCalls == 0 || maxCalls > e.cfg.MaxToolCalls {
-			maxCalls = e.cfg.MaxToolCalls
+	var cacheKey string
+	if e.cfg.ResultCache != nil && !params.BypassCache && params.SessionID == "" && params.Parent == nil {
+		if key, ok := resultCacheKey(params, secrets); ok {
+			cacheKey = key
+			if cached, hit := e.cfg.ResultCache.get(key); hit {
+				cached.Cached = true
+				cached.DurationMs = 0
+				logEntry(e.cfg.Logger, LogEntry{Event: LogEventExecuteEnd, ExecutionID: executionID, Language: params.Language, ToolCalls: len(cached.ToolCalls)})
+				return cached, nil
+			}
 		}
 	}
 
+	params.Env = env
+	params.Secrets = nil
+	redact := newRedactor(secrets)
+	emit = redact.sink(emit)
+	if emit != nil {
+		ctx = context.WithValue(ctx, eventSinkKey{}, emit)
+	}
+
+	// Resolve limits (params capped by config)
+	maxCalls := capLimit(params.MaxToolCalls, e.cfg.MaxToolCalls)
+	maxStdout := capLimit(params.MaxStdoutBytes, e.cfg.MaxStdoutBytes)
+	// Engines see the effective cap, so they can stop capturing there.
+	params.MaxStdoutBytes = maxStdout
+
 	// Create tools environment
 	tools := newTools(&e.cfg, maxCalls, e.cfg.MaxChainSteps)
+	tools.emit = emit
+	tools.maxStdout = maxStdout
+	tools.executionID = executionID
+	if params.Parent != nil {
+		tools.Restore(params.Parent)
+	}
+
+	// Join the session, serializing executions within it. Its turn comes
+	// before a concurrency slot, so waiting on a session holds no slot.
+	var session *Session
+	if params.SessionID != "" {
+		session = e.sessions.acquire(params.SessionID)
+		if err := session.lock(ctx); err != nil {
+			return ExecuteResult{}, err
+		}
+		defer session.unlock()
+		ctx = withSession(ctx, session)
+	}
+
+	// Wait for a concurrency slot
+	release, err := e.admission.acquire(ctx)
+	if err != nil {
+		return ExecuteResult{}, err
+	}
+	defer release()
 
 	// Create context with timeout
 	var cancel context.CancelFunc
@@ -65,24 +156,86 @@ func (e *DefaultExecutor) ExecuteCode(ctx context.Context, params ExecuteParams)
 		defer cancel()
 	}
 
+	// Compile within the slot and the timeout, so builds are bounded too
+	params, err = e.compile(ctx, params)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("%w: timeout after %v", ErrLimitExceeded, params.Timeout)
+		}
+		return ExecuteResult{}, err
+	}
+
+	logEntry(e.cfg.Logger, LogEntry{Event: LogEventExecuteStart, ExecutionID: executionID, Language: params.Language, SessionID: params.SessionID})
+
 	start := time.Now()
-	result, err := e.cfg.Engine.Execute(ctx, params, tools)
+	result, err := engine.Execute(ctx, params, tools)
 	duration := time.Since(start).Milliseconds()
 
 	// Collect captured data from tools
 	result.ToolCalls = tools.GetToolCalls()
 	result.Stdout = tools.GetStdout()
+	result.Truncated = tools.stdoutTruncated()
 	result.DurationMs = duration
+	if err == nil && params.OutputSchema != nil {
+		err = e.validateOutput(params.OutputSchema, &result)
+	}
+	if len(env) > 0 {
+		result.EnvNames = slices.Sorted(maps.Keys(env))
+	}
+	redact.apply(&result)
+	result.Snapshot = tools.Snapshot()
+	if redact != nil {
+		redactSnapshot(result.Snapshot, result)
+	}
 
-	// Log execution summary if logger present
-	if e.cfg.Logger != nil {
-		e.cfg.Logger.Logf("executed %d tool calls in %dms", len(result.ToolCalls), duration)
+	if session != nil {
+		session.record(result, time.Now())
+	}
+	if cacheKey != "" && err == nil {
+		e.cfg.ResultCache.set(cacheKey, result)
 	}
 
 	// Wrap timeout errors
 	if err != nil && errors.Is(err, context.DeadlineExceeded) {
-		return result, fmt.Errorf("%w: timeout after %v", ErrLimitExceeded, params.Timeout)
+		err = fmt.Errorf("%w: timeout after %v", ErrLimitExceeded, params.Timeout)
+		logEntry(e.cfg.Logger, LogEntry{Event: LogEventLimitExceeded, ExecutionID: executionID, ErrorCode: "limit_exceeded", Error: err.Error()})
+	}
+
+	// Log execution summary if logger present
+	if _, ok := e.cfg.Logger.(StructuredLogger); ok {
+		errCode, errMsg := errorFields(err)
+		logEntry(e.cfg.Logger, LogEntry{
+			Event:       LogEventExecuteEnd,
+			ExecutionID: executionID,
+			Language:    params.Language,
+			SessionID:   params.SessionID,
+			ToolCalls:   len(result.ToolCalls),
+			DurationMs:  duration,
+			ErrorCode:   errCode,
+			Error:       errMsg,
+		})
+	} else if e.cfg.Logger != nil {
+		e.cfg.Logger.Logf("executed %d tool calls in %dms", len(result.ToolCalls), duration)
 	}
 
 	return result, err
 }
+
+// capLimit resolves a per-execution limit against the configured ceiling,
+// where zero means unlimited on either side.
+func capLimit(requested, ceiling int) int {
+	if ceiling > 0 && (requested == 0 || requested > ceiling) {
+		return ceiling
+	}
+	return requested
+}
+
+// withExecutionID returns ctx with an execution ID, keeping the one it
+// carries (see runtime.WithExecutionID) or adding a new one, and the ID.
+func withExecutionID(ctx context.Context) (context.Context, string) {
+	if id := runtime.ExecutionIDFromContext(ctx); id != "" {
+		return ctx, id
+	}
+	id := runtime.NewExecutionID()
+	return runtime.WithExecutionID(ctx, id), id
+}
@@ -3,6 +3,8 @@ package code
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -86,6 +88,58 @@ func TestExecuteCode_AppliesDefaultLanguage(t *testing.T) {
 	}
 }
 
+func TestExecuteCode_DispatchesByLanguage(t *testing.T) {
+	goEngine := &mockEngine{executeResult: ExecuteResult{Value: "go"}}
+	pyEngine := &mockEngine{executeResult: ExecuteResult{Value: "python"}}
+	exec, err := NewDefaultExecutor(Config{
+		Index:           &mockIndex{},
+		Docs:            &mockStore{},
+		Run:             &mockRunner{},
+		Engines:         map[string]Engine{"go": goEngine, "python": pyEngine},
+		DefaultLanguage: "python",
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+	ctx := context.Background()
+
+	result, err := exec.ExecuteCode(ctx, ExecuteParams{Code: "x"})
+	if err != nil || result.Value != "python" {
+		t.Errorf("ExecuteCode(default) = %v, %v; want python engine", result.Value, err)
+	}
+	result, err = exec.ExecuteCode(ctx, ExecuteParams{Language: "go", Code: "x"})
+	if err != nil || result.Value != "go" {
+		t.Errorf("ExecuteCode(go) = %v, %v; want go engine", result.Value, err)
+	}
+
+	_, err = exec.ExecuteCode(ctx, ExecuteParams{Language: "ruby", Code: "x"})
+	if !errors.Is(err, ErrUnsupportedLanguage) {
+		t.Fatalf("ExecuteCode(ruby) error = %v, want %v", err, ErrUnsupportedLanguage)
+	}
+	if !containsStr(err.Error(), "go, python") {
+		t.Errorf("error %q should list supported languages", err)
+	}
+	if len(goEngine.executeCalls)+len(pyEngine.executeCalls) != 2 {
+		t.Errorf("engines ran %d times, want 2", len(goEngine.executeCalls)+len(pyEngine.executeCalls))
+	}
+}
+
+func TestExecuteCode_EnginesFallBackToEngine(t *testing.T) {
+	fallback := &mockEngine{executeResult: ExecuteResult{Value: "fallback"}}
+	exec, _ := NewDefaultExecutor(Config{
+		Index:   &mockIndex{},
+		Docs:    &mockStore{},
+		Run:     &mockRunner{},
+		Engine:  fallback,
+		Engines: map[string]Engine{"python": &mockEngine{}},
+	})
+
+	result, err := exec.ExecuteCode(context.Background(), ExecuteParams{Language: "ruby", Code: "x"})
+	if err != nil || result.Value != "fallback" {
+		t.Errorf("ExecuteCode(ruby) = %v, %v; want fallback engine", result.Value, err)
+	}
+}
+
 func TestExecuteCode_AppliesDefaultTimeout(t *testing.T) {
 	engine := &mockEngine{
 		executeResult: ExecuteResult{Value: "ok"},
@@ -547,3 +601,64 @@ func (e *slowEngine) Execute(ctx context.Context, _ ExecuteParams, _ Tools) (Exe
 		return ExecuteResult{}, ctx.Err()
 	}
 }
+
+func TestExecuteCode_MaxStdoutBytes(t *testing.T) {
+	engine := engineFunc(func(_ context.Context, _ ExecuteParams, tools Tools) (ExecuteResult, error) {
+		for i := 0; i < 100; i++ {
+			tools.Println("0123456789")
+		}
+		return ExecuteResult{}, nil
+	})
+	exec, err := NewDefaultExecutor(Config{
+		Index:          &mockIndex{},
+		Docs:           &mockStore{},
+		Run:            &mockRunner{},
+		Engine:         engine,
+		MaxStdoutBytes: 100,
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+
+	// Params may lower the configured limit but not raise it.
+	for _, tt := range []struct{ param, want int }{{0, 100}, {25, 25}, {1000, 100}} {
+		result, err := exec.ExecuteCode(context.Background(), ExecuteParams{Code: "x", MaxStdoutBytes: tt.param})
+		if err != nil {
+			t.Fatalf("ExecuteCode() error = %v", err)
+		}
+		if !result.Truncated {
+			t.Errorf("param %d: Truncated = false", tt.param)
+		}
+		kept := strings.TrimSuffix(result.Stdout, StdoutTruncatedMarker)
+		if len(kept) != tt.want || kept == result.Stdout {
+			t.Errorf("param %d: kept %d bytes (marker present: %v), want %d", tt.param, len(kept), kept != result.Stdout, tt.want)
+		}
+	}
+}
+
+// validatingEngine is a mockEngine that rejects params with validateErr.
+type validatingEngine struct {
+	mockEngine
+	validateErr error
+}
+
+func (e *validatingEngine) ValidateParams(context.Context, ExecuteParams) error {
+	return e.validateErr
+}
+
+func TestExecuteCode_ValidatingEngine(t *testing.T) {
+	engine := &validatingEngine{validateErr: fmt.Errorf("%w: workspace", ErrUnsupportedCapability)}
+	exec, _ := NewDefaultExecutor(Config{Index: &mockIndex{}, Docs: &mockStore{}, Run: &mockRunner{}, Engine: engine})
+
+	if _, err := exec.ExecuteCode(context.Background(), ExecuteParams{Code: "x"}); !errors.Is(err, ErrUnsupportedCapability) {
+		t.Errorf("ExecuteCode() error = %v, want %v", err, ErrUnsupportedCapability)
+	}
+	if len(engine.executeCalls) != 0 {
+		t.Error("rejected params should not run")
+	}
+
+	engine.validateErr = nil
+	if _, err := exec.ExecuteCode(context.Background(), ExecuteParams{Code: "x"}); err != nil {
+		t.Errorf("ExecuteCode() error = %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package code
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SpanEventToolCall is the name of the span events produced by SpanEvents.
+const SpanEventToolCall = "tool.call"
+
+// Span event attribute keys, following OpenTelemetry naming conventions.
+const (
+	AttrToolID           = "tool.id"
+	AttrToolNamespace    = "tool.namespace"
+	AttrToolBackendKind  = "tool.backend.kind"
+	AttrToolDurationMs   = "tool.duration_ms"
+	AttrToolDeduplicated = "tool.deduplicated"
+	AttrErrorMessage     = "error.message"
+	AttrErrorOp          = "error.op"
+)
+
+// SpanEvent is an OpenTelemetry span event built from a ToolCallRecord.
+// Attribute values are only string, int64, or bool, so they map directly
+// onto attribute.KeyValue without reflection:
+//
+//	for _, ev := range code.SpanEvents(result.ToolCalls) {
+//		span.AddEvent(ev.Name, trace.WithTimestamp(ev.Time), trace.WithAttributes(toKeyValues(ev.Attributes)...))
+//	}
+//
+// Args and results are not exported, since they may carry sensitive data.
+type SpanEvent struct {
+	// Name is the event name, SpanEventToolCall.
+	Name string
+
+	// Time is when the tool call started; zero if unknown.
+	Time time.Time
+
+	// Attributes describe the call, keyed by the Attr* constants.
+	Attributes map[string]any
+}
+
+// SpanEvents converts a tool call trace into span events, one per record,
+// in trace order.
+func SpanEvents(records []ToolCallRecord) []SpanEvent {
+	events := make([]SpanEvent, len(records))
+	for i, rec := range records {
+		attrs := map[string]any{
+			AttrToolID:         rec.ToolID,
+			AttrToolDurationMs: rec.DurationMs,
+		}
+		if ns := namespaceOf(rec.ToolID); ns != "" {
+			attrs[AttrToolNamespace] = ns
+		}
+		if rec.BackendKind != "" {
+			attrs[AttrToolBackendKind] = rec.BackendKind
+		}
+		if rec.Deduplicated {
+			attrs[AttrToolDeduplicated] = true
+		}
+		if rec.Error != "" {
+			attrs[AttrErrorMessage] = rec.Error
+			attrs[AttrErrorOp] = rec.ErrorOp
+		}
+		events[i] = SpanEvent{Name: SpanEventToolCall, Time: rec.StartedAt, Attributes: attrs}
+	}
+	return events
+}
+
+// WriteJSONL writes a tool call trace as JSON Lines: one record per line,
+// in the same JSON form as ExecuteResult.ToolCalls.
+func WriteJSONL(w io.Writer, records []ToolCallRecord) error {
+	enc := json.NewEncoder(w)
+	for i, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return fmt.Errorf("code: write trace record %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ReadJSONL reads a tool call trace written by WriteJSONL. Blank lines
+// are skipped.
+func ReadJSONL(r io.Reader) ([]ToolCallRecord, error) {
+	var records []ToolCallRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec ToolCallRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return records, fmt.Errorf("code: read trace line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,70 @@
+package code
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleTrace() []ToolCallRecord {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []ToolCallRecord{
+		{ToolID: "weather:get", Args: map[string]any{"city": "Oslo"}, BackendKind: "local", StartedAt: start, DurationMs: 12},
+		{ToolID: "comms:send", Error: "boom", ErrorOp: "run", StartedAt: start.Add(time.Second), DurationMs: 3},
+		{ToolID: "weather:get", Deduplicated: true},
+	}
+}
+
+func TestSpanEvents(t *testing.T) {
+	records := sampleTrace()
+	events := SpanEvents(records)
+	if len(events) != len(records) {
+		t.Fatalf("SpanEvents() returned %d events, want %d", len(events), len(records))
+	}
+
+	ok := events[0]
+	if ok.Name != SpanEventToolCall || !ok.Time.Equal(records[0].StartedAt) {
+		t.Errorf("event[0] = %q at %v", ok.Name, ok.Time)
+	}
+	if ok.Attributes[AttrToolID] != "weather:get" || ok.Attributes[AttrToolNamespace] != "weather" ||
+		ok.Attributes[AttrToolBackendKind] != "local" || ok.Attributes[AttrToolDurationMs] != int64(12) {
+		t.Errorf("event[0].Attributes = %v", ok.Attributes)
+	}
+	if _, leaked := ok.Attributes["city"]; leaked {
+		t.Error("span events must not include args")
+	}
+
+	if failed := events[1].Attributes; failed[AttrErrorMessage] != "boom" || failed[AttrErrorOp] != "run" {
+		t.Errorf("event[1].Attributes = %v, want error fields", failed)
+	}
+	if events[2].Attributes[AttrToolDeduplicated] != true {
+		t.Errorf("event[2].Attributes = %v, want deduplicated", events[2].Attributes)
+	}
+}
+
+func TestJSONL_RoundTrip(t *testing.T) {
+	records := sampleTrace()
+	var buf bytes.Buffer
+	if err := WriteJSONL(&buf, records); err != nil {
+		t.Fatalf("WriteJSONL() error = %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != len(records) {
+		t.Fatalf("WriteJSONL() wrote %d lines, want %d", lines, len(records))
+	}
+	if strings.Contains(strings.Split(buf.String(), "\n")[2], "startedAt") {
+		t.Error("zero StartedAt should be omitted")
+	}
+
+	got, err := ReadJSONL(&buf)
+	if err != nil {
+		t.Fatalf("ReadJSONL() error = %v", err)
+	}
+	if len(got) != len(records) || got[0].Args["city"] != "Oslo" || !got[1].StartedAt.Equal(records[1].StartedAt) || !got[2].Deduplicated {
+		t.Errorf("ReadJSONL() = %+v", got)
+	}
+
+	if _, err := ReadJSONL(strings.NewReader("{\"toolId\":\"a:b\"}\nnot json\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("ReadJSONL(bad) error = %v, want line 2 error", err)
+	}
+}
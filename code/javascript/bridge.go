@@ -0,0 +1,227 @@
+package javascript
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dop251/goja"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/code"
+	"github.com/jonwraymond/toolexec/run"
+)
+
+// bridge exposes code.Tools to the interpreter as host functions and
+// enforces the per-execution tool call budget.
+type bridge struct {
+	ctx          context.Context
+	vm           *goja.Runtime
+	tools        code.Tools
+	maxToolCalls int
+	calls        int
+}
+
+// install defines the global tools and console objects.
+func (b *bridge) install() error {
+	tools := b.vm.NewObject()
+	for name, fn := range map[string]func(goja.FunctionCall) goja.Value{
+		"searchTools":      b.searchTools,
+		"listNamespaces":   b.listNamespaces,
+		"describeTool":     b.describeTool,
+		"listToolExamples": b.listToolExamples,
+		"runTool":          b.runTool,
+		"runChain":         b.runChain,
+		"println":          b.println,
+		"partial":          b.partial,
+	} {
+		if err := tools.Set(name, fn); err != nil {
+			return err
+		}
+	}
+	console := b.vm.NewObject()
+	if err := console.Set("log", b.println); err != nil {
+		return err
+	}
+	if err := b.vm.Set("tools", tools); err != nil {
+		return err
+	}
+	return b.vm.Set("console", console)
+}
+
+// charge reserves n tool calls, interrupting the interpreter when the
+// budget is exhausted so the snippet cannot swallow the limit error.
+func (b *bridge) charge(n int) {
+	if b.maxToolCalls <= 0 {
+		return
+	}
+	if b.calls+n > b.maxToolCalls {
+		err := fmt.Errorf("%w: max tool calls (%d) exceeded", code.ErrLimitExceeded, b.maxToolCalls)
+		b.vm.Interrupt(err)
+		panic(b.vm.NewGoError(err))
+	}
+	b.calls += n
+}
+
+// throw raises err as a catchable JavaScript error.
+func (b *bridge) throw(err error) {
+	panic(b.vm.NewGoError(err))
+}
+
+// searchTools accepts either a numeric limit or a filter object
+// {namespaces, tags, limit} as its second argument.
+func (b *bridge) searchTools(call goja.FunctionCall) goja.Value {
+	var (
+		summaries []index.Summary
+		err       error
+	)
+	query := call.Argument(0).String()
+	if opts, ok := call.Argument(1).Export().(map[string]any); ok {
+		filter := code.SearchFilter{
+			Namespaces: exportStrings(opts["namespaces"]),
+			Tags:       exportStrings(opts["tags"]),
+		}
+		if limit, ok := opts["limit"].(int64); ok {
+			filter.Limit = int(limit)
+		}
+		summaries, err = b.tools.SearchToolsFiltered(b.ctx, query, filter)
+	} else {
+		limit := 10
+		if len(call.Arguments) > 1 {
+			limit = int(call.Argument(1).ToInteger())
+		}
+		summaries, err = b.tools.SearchTools(b.ctx, query, limit)
+	}
+	if err != nil {
+		b.throw(err)
+	}
+	out := make([]any, len(summaries))
+	for i, s := range summaries {
+		out[i] = map[string]any{
+			"id":               s.ID,
+			"name":             s.Name,
+			"namespace":        s.Namespace,
+			"shortDescription": s.ShortDescription,
+			"tags":             s.Tags,
+		}
+	}
+	return b.vm.ToValue(out)
+}
+
+func (b *bridge) listNamespaces(goja.FunctionCall) goja.Value {
+	namespaces, err := b.tools.ListNamespaces(b.ctx)
+	if err != nil {
+		b.throw(err)
+	}
+	return b.vm.ToValue(namespaces)
+}
+
+func (b *bridge) describeTool(call goja.FunctionCall) goja.Value {
+	level := tooldoc.DetailLevel("summary")
+	if len(call.Arguments) > 1 {
+		level = tooldoc.DetailLevel(call.Argument(1).String())
+	}
+	doc, err := b.tools.DescribeTool(b.ctx, call.Argument(0).String(), level)
+	if err != nil {
+		b.throw(err)
+	}
+	return b.vm.ToValue(map[string]any{
+		"summary":  doc.Summary,
+		"notes":    doc.Notes,
+		"examples": encodeExamples(doc.Examples),
+	})
+}
+
+func (b *bridge) listToolExamples(call goja.FunctionCall) goja.Value {
+	maxExamples := 5
+	if len(call.Arguments) > 1 {
+		maxExamples = int(call.Argument(1).ToInteger())
+	}
+	examples, err := b.tools.ListToolExamples(b.ctx, call.Argument(0).String(), maxExamples)
+	if err != nil {
+		b.throw(err)
+	}
+	return b.vm.ToValue(encodeExamples(examples))
+}
+
+func (b *bridge) runTool(call goja.FunctionCall) goja.Value {
+	b.charge(1)
+	args, _ := exportArgs(call.Argument(1))
+	result, err := b.tools.RunTool(b.ctx, call.Argument(0).String(), args)
+	if err != nil {
+		b.throw(err)
+	}
+	return b.vm.ToValue(result.Structured)
+}
+
+func (b *bridge) runChain(call goja.FunctionCall) goja.Value {
+	raw, _ := call.Argument(0).Export().([]any)
+	steps := make([]run.ChainStep, 0, len(raw))
+	for _, s := range raw {
+		m, _ := s.(map[string]any)
+		toolID, _ := m["toolId"].(string)
+		args, _ := m["args"].(map[string]any)
+		usePrevious, _ := m["usePrevious"].(bool)
+		steps = append(steps, run.ChainStep{ToolID: toolID, Args: args, UsePrevious: usePrevious})
+	}
+	b.charge(len(steps))
+
+	result, stepResults, err := b.tools.RunChain(b.ctx, steps)
+	if err != nil {
+		b.throw(err)
+	}
+	encoded := make([]any, len(stepResults))
+	for i, sr := range stepResults {
+		encoded[i] = map[string]any{"toolId": sr.ToolID, "structured": sr.Result.Structured}
+	}
+	return b.vm.ToValue(map[string]any{"structured": result.Structured, "stepResults": encoded})
+}
+
+func (b *bridge) println(call goja.FunctionCall) goja.Value {
+	args := make([]any, len(call.Arguments))
+	for i, a := range call.Arguments {
+		args[i] = a.String()
+	}
+	b.tools.Println(args...)
+	return goja.Undefined()
+}
+
+func (b *bridge) partial(call goja.FunctionCall) goja.Value {
+	code.ReportPartial(b.ctx, call.Argument(0).Export())
+	return goja.Undefined()
+}
+
+// exportStrings converts an exported JavaScript array to strings.
+func exportStrings(v any) []string {
+	items, _ := v.([]any)
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// exportArgs converts a JavaScript object argument to a tool args map.
+func exportArgs(v goja.Value) (map[string]any, bool) {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil, true
+	}
+	m, ok := v.Export().(map[string]any)
+	return m, ok
+}
+
+func encodeExamples(examples []tooldoc.ToolExample) []any {
+	out := make([]any, len(examples))
+	for i, ex := range examples {
+		out[i] = map[string]any{
+			"id":          ex.ID,
+			"title":       ex.Title,
+			"description": ex.Description,
+			"args":        ex.Args,
+			"resultHint":  ex.ResultHint,
+		}
+	}
+	return out
+}
@@ -0,0 +1,33 @@
+// Package javascript provides a code.Engine backed by the embedded goja
+// JavaScript interpreter.
+//
+// Snippets run in a fresh interpreter per execution with a global `tools`
+// object exposing the code.Tools surface as host functions:
+//
+//	const hits = tools.searchTools("weather", 5);
+//	__out = tools.runTool(hits[0].id, {city: "Oslo"});
+//
+// tools.searchTools also accepts a filter object in place of the limit,
+// e.g. tools.searchTools("send", {namespaces: ["comms"], tags: ["email"]}).
+//
+// tools.println and console.log write to the captured stdout, tools.partial
+// reports an intermediate value to ExecuteCodeStream consumers, and the value
+// assigned to the global __out becomes ExecuteResult.Value. Tool failures
+// are thrown as JavaScript errors that snippets may catch.
+//
+// Limits are enforced in the bridge: context cancellation and deadlines
+// interrupt the interpreter, and exceeding ExecuteParams.MaxToolCalls
+// interrupts it with an error matching code.ErrLimitExceeded that the
+// snippet cannot catch.
+//
+// Executions that belong to a code.Session share one interpreter, so
+// globals declared by earlier snippets remain visible to later ones.
+//
+// TypeScript runs only with a Config.Transpiler, such as an esbuild
+// wrapper, which converts it to JavaScript before execution; without one,
+// TypeScript returns ErrUnsupportedLanguage. Line numbers in errors refer
+// to the transpiled JavaScript.
+//
+// The engine lives in its own module so that programs which do not run
+// JavaScript do not depend on goja.
+package javascript
@@ -0,0 +1,26 @@
+module github.com/jonwraymond/toolexec/code/javascript
+
+go 1.25.7
+
+require (
+	github.com/dop251/goja v0.0.0-20260106131823-651366fbe6e3
+	github.com/jonwraymond/tooldiscovery v0.3.0
+	github.com/jonwraymond/toolexec v0.3.0
+)
+
+require (
+	github.com/dlclark/regexp2 v1.11.4 // indirect
+	github.com/go-sourcemap/sourcemap v2.1.3+incompatible // indirect
+	github.com/google/jsonschema-go v0.4.2 // indirect
+	github.com/google/pprof v0.0.0-20230207041349-798e818bf904 // indirect
+	github.com/jonwraymond/toolfoundation v0.3.0 // indirect
+	github.com/modelcontextprotocol/go-sdk v1.2.0 // indirect
+	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
+	golang.org/x/oauth2 v0.34.0 // indirect
+	golang.org/x/text v0.33.0 // indirect
+)
+
+// Build against the enclosing checkout of toolexec. Consumers ignore this
+// replace and need the toolexec release required above, so tag it before
+// tagging this module.
+replace github.com/jonwraymond/toolexec => ../..
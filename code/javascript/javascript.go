@@ -0,0 +1,241 @@
+package javascript
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/parser"
+
+	"github.com/jonwraymond/toolexec/code"
+)
+
+// snippetName is the script name reported in stack traces.
+const snippetName = "snippet"
+
+var (
+	// ErrUnsupportedLanguage is returned when ExecuteParams.Language is not
+	// JavaScript, or is TypeScript without a Transpiler.
+	ErrUnsupportedLanguage = errors.New("javascript: unsupported language")
+
+	// ErrTranspileFailed is returned when TypeScript transpilation fails.
+	ErrTranspileFailed = errors.New("javascript: typescript transpilation failed")
+)
+
+// Transpiler converts TypeScript to JavaScript, such as an esbuild or
+// swc wrapper.
+type Transpiler interface {
+	// Transpile strips types from source and returns JavaScript.
+	Transpile(ctx context.Context, source string) (string, error)
+}
+
+// Config configures an Engine.
+type Config struct {
+	// MaxCallStackSize bounds JavaScript recursion depth.
+	// Zero uses the interpreter default.
+	MaxCallStackSize int
+
+	// Transpiler converts TypeScript snippets to JavaScript.
+	// If nil, TypeScript returns ErrUnsupportedLanguage.
+	Transpiler Transpiler
+}
+
+// Engine implements code.Engine with an embedded JavaScript interpreter.
+type Engine struct {
+	maxCallStackSize int
+	transpiler       Transpiler
+}
+
+// New creates a new Engine with the given configuration.
+func New(cfg Config) *Engine {
+	return &Engine{maxCallStackSize: cfg.MaxCallStackSize, transpiler: cfg.Transpiler}
+}
+
+// DescribeEngine implements code.EngineDescriber.
+func (e *Engine) DescribeEngine(string) code.EngineInfo {
+	return code.EngineInfo{
+		Name: "javascript",
+		Signatures: map[string]string{
+			"SearchTools":         "tools.searchTools(query, limit = 10)",
+			"SearchToolsFiltered": "tools.searchTools(query, {namespaces, tags, limit})",
+			"ListNamespaces":      "tools.listNamespaces()",
+			"DescribeTool":        `tools.describeTool(id, level = "summary")`,
+			"ListToolExamples":    "tools.listToolExamples(id, max = 5)",
+			"RunTool":             "tools.runTool(id, args)",
+			"RunChain":            "tools.runChain(steps)",
+			"Println":             "tools.println(...args)",
+		},
+	}
+}
+
+// Execute implements code.Engine.
+func (e *Engine) Execute(ctx context.Context, params code.ExecuteParams, tools code.Tools) (code.ExecuteResult, error) {
+	if err := ctx.Err(); err != nil {
+		return code.ExecuteResult{}, err
+	}
+	source, err := e.source(ctx, params)
+	if err != nil {
+		return code.ExecuteResult{}, err
+	}
+
+	// Parse separately from compiling: goja.Compile discards the
+	// parser's error positions.
+	ast, err := parser.ParseFile(nil, snippetName, source, 0)
+	if err != nil {
+		return code.ExecuteResult{}, compileError(err)
+	}
+	program, err := goja.CompileAST(ast, false)
+	if err != nil {
+		return code.ExecuteResult{}, compileError(err)
+	}
+
+	vm := e.runtime(ctx)
+	pin(vm, params.Determinism)
+
+	b := &bridge{ctx: ctx, vm: vm, tools: tools, maxToolCalls: params.MaxToolCalls}
+	if err := b.install(); err != nil {
+		return code.ExecuteResult{}, fmt.Errorf("javascript: install host functions: %w", err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			vm.Interrupt(ctx.Err())
+		case <-done:
+		}
+	}()
+
+	_, runErr := vm.RunProgram(program)
+
+	var result code.ExecuteResult
+	if out := vm.Get("__out"); out != nil && !goja.IsUndefined(out) {
+		result.Value = out.Export()
+	}
+	if runErr != nil {
+		return result, runtimeError(runErr)
+	}
+	return result, nil
+}
+
+// source returns the JavaScript to run for params, transpiling TypeScript.
+func (e *Engine) source(ctx context.Context, params code.ExecuteParams) (string, error) {
+	switch strings.ToLower(params.Language) {
+	case "", "javascript", "js":
+		return params.Code, nil
+	case "typescript", "ts":
+		if e.transpiler == nil {
+			return "", fmt.Errorf("%w: %q requires a transpiler", ErrUnsupportedLanguage, params.Language)
+		}
+		source, err := e.transpiler.Transpile(ctx, params.Code)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrTranspileFailed, err)
+		}
+		return source, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedLanguage, params.Language)
+	}
+}
+
+// runtime returns the interpreter for an execution. Executions in a
+// code.Session reuse the session's interpreter so globals persist between
+// snippets; all others get a fresh one.
+func (e *Engine) runtime(ctx context.Context) *goja.Runtime {
+	session, ok := code.SessionFromContext(ctx)
+	if ok {
+		if vm, ok := session.EngineState().(*goja.Runtime); ok {
+			// A previous execution may have been interrupted.
+			vm.ClearInterrupt()
+			_ = vm.Set("__out", goja.Undefined())
+			return vm
+		}
+	}
+
+	vm := goja.New()
+	vm.SetFieldNameMapper(goja.TagFieldNameMapper("json", true))
+	if e.maxCallStackSize > 0 {
+		vm.SetMaxCallStackSize(e.maxCallStackSize)
+	}
+	if ok {
+		session.SetEngineState(vm)
+	}
+	return vm
+}
+
+// pin makes Date and Math.random inside vm follow d, or the real clock
+// and an unseeded source when d is nil. Session interpreters are re-pinned
+// on every execution.
+func pin(vm *goja.Runtime, d *code.Determinism) {
+	vm.SetTimeSource(time.Now)
+	vm.SetRandSource(rand.Float64)
+	if d == nil {
+		return
+	}
+	if !d.Now.IsZero() {
+		now := d.Now
+		vm.SetTimeSource(func() time.Time { return now })
+	}
+	vm.SetRandSource(d.Rand().Float64)
+}
+
+// SupportsDeterminism implements code.DeterministicEngine.
+func (e *Engine) SupportsDeterminism() bool {
+	return true
+}
+
+// compileError converts a goja compilation failure into a CodeError.
+func compileError(err error) error {
+	var list parser.ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		first := list[0]
+		return &code.CodeError{Message: "SyntaxError: " + first.Message, Line: first.Position.Line, Column: first.Position.Column, Err: err}
+	}
+	var syntaxErr *goja.CompilerSyntaxError
+	if errors.As(err, &syntaxErr) && syntaxErr.File != nil {
+		pos := syntaxErr.File.Position(syntaxErr.Offset)
+		return &code.CodeError{Message: "SyntaxError: " + syntaxErr.Message, Line: pos.Line, Column: pos.Column, Err: err}
+	}
+	return &code.CodeError{Message: err.Error(), Err: err}
+}
+
+// runtimeError converts a goja run failure. Interrupts carry the context or
+// limit error that caused them; uncaught exceptions become CodeErrors.
+func runtimeError(err error) error {
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		if cause, ok := interrupted.Value().(error); ok {
+			return cause
+		}
+		return fmt.Errorf("%w: interrupted", code.ErrCodeExecution)
+	}
+
+	var exc *goja.Exception
+	if errors.As(err, &exc) {
+		codeErr := &code.CodeError{Message: exceptionMessage(exc), Err: err}
+		for _, frame := range exc.Stack() {
+			if frame.SrcName() == snippetName {
+				pos := frame.Position()
+				codeErr.Line, codeErr.Column = pos.Line, pos.Column
+				break
+			}
+		}
+		return codeErr
+	}
+	return &code.CodeError{Message: err.Error(), Err: err}
+}
+
+// exceptionMessage returns the thrown value's string form without the stack.
+func exceptionMessage(exc *goja.Exception) string {
+	if v := exc.Value(); v != nil {
+		return v.String()
+	}
+	return exc.Error()
+}
+
+// Ensure Engine implements code.Engine.
+var _ code.Engine = (*Engine)(nil)
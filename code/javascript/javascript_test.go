@@ -0,0 +1,283 @@
+package javascript
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/code"
+	"github.com/jonwraymond/toolexec/run"
+)
+
+// fakeTools is a minimal code.Tools backed by in-memory handlers.
+type fakeTools struct {
+	stdout strings.Builder
+	calls  []string
+}
+
+func (f *fakeTools) SearchTools(_ context.Context, query string, _ int) ([]index.Summary, error) {
+	return []index.Summary{{ID: "weather:get", Name: "get", Namespace: "weather", ShortDescription: query}}, nil
+}
+
+func (f *fakeTools) SearchToolsFiltered(ctx context.Context, query string, _ code.SearchFilter) ([]index.Summary, error) {
+	return f.SearchTools(ctx, query, 0)
+}
+
+func (f *fakeTools) ListNamespaces(context.Context) ([]string, error) {
+	return []string{"weather"}, nil
+}
+
+func (f *fakeTools) DescribeTool(_ context.Context, id string, _ tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	return tooldoc.ToolDoc{Summary: "describes " + id}, nil
+}
+
+func (f *fakeTools) ListToolExamples(context.Context, string, int) ([]tooldoc.ToolExample, error) {
+	return nil, nil
+}
+
+func (f *fakeTools) RunTool(_ context.Context, id string, args map[string]any) (run.RunResult, error) {
+	f.calls = append(f.calls, id)
+	if id == "fail:tool" {
+		return run.RunResult{}, errors.New("tool exploded")
+	}
+	return run.RunResult{Structured: map[string]any{"city": args["city"], "temp": 21.5}}, nil
+}
+
+func (f *fakeTools) RunChain(_ context.Context, steps []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	var results []run.StepResult
+	for _, s := range steps {
+		results = append(results, run.StepResult{ToolID: s.ToolID, Result: run.RunResult{Structured: s.ToolID}})
+	}
+	return run.RunResult{Structured: "chained"}, results, nil
+}
+
+func (f *fakeTools) Println(args ...any) {
+	fmt.Fprintln(&f.stdout, args...)
+}
+
+func TestEngine_Execute_ToolsAndOut(t *testing.T) {
+	tools := &fakeTools{}
+	src := strings.Join([]string{
+		`const found = tools.searchTools("forecast", 3);`,
+		`console.log("found", found[0].id);`,
+		`const w = tools.runTool(found[0].id, {city: "Oslo"});`,
+		`const chain = tools.runChain([{toolId: "a:b"}, {toolId: "c:d", usePrevious: true}]);`,
+		`__out = {temp: w.temp, chain: chain.structured, steps: chain.stepResults.length, ns: tools.listNamespaces()};`,
+	}, "\n")
+
+	result, err := New(Config{}).Execute(context.Background(), code.ExecuteParams{Language: "javascript", Code: src}, tools)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	out, ok := result.Value.(map[string]any)
+	if !ok {
+		t.Fatalf("Value = %T, want map", result.Value)
+	}
+	if out["temp"] != 21.5 || out["chain"] != "chained" || out["steps"] != int64(2) {
+		t.Errorf("Value = %+v", out)
+	}
+	if got := tools.stdout.String(); got != "found weather:get\n" {
+		t.Errorf("stdout = %q", got)
+	}
+}
+
+func TestEngine_Execute_ToolErrorCatchable(t *testing.T) {
+	src := `try { tools.runTool("fail:tool", {}); } catch (e) { __out = String(e.message || e); }`
+
+	result, err := New(Config{}).Execute(context.Background(), code.ExecuteParams{Code: src}, &fakeTools{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if s, _ := result.Value.(string); !strings.Contains(s, "tool exploded") {
+		t.Errorf("Value = %v, want caught tool error", result.Value)
+	}
+}
+
+func TestEngine_Execute_MaxToolCalls(t *testing.T) {
+	tools := &fakeTools{}
+	src := `for (let i = 0; i < 5; i++) { try { tools.runTool("weather:get", {}); } catch (e) {} }`
+
+	_, err := New(Config{}).Execute(context.Background(), code.ExecuteParams{Code: src, MaxToolCalls: 2}, tools)
+	if !errors.Is(err, code.ErrLimitExceeded) {
+		t.Fatalf("Execute() error = %v, want %v", err, code.ErrLimitExceeded)
+	}
+	if len(tools.calls) != 2 {
+		t.Errorf("tool calls = %d, want 2", len(tools.calls))
+	}
+}
+
+func TestEngine_Execute_Timeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := New(Config{}).Execute(ctx, code.ExecuteParams{Code: `while (true) {}`}, &fakeTools{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Execute() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestEngine_Execute_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		wantLine int
+	}{
+		{name: "syntax", src: "let a = 1;\nlet = ;", wantLine: 2},
+		{name: "throw", src: "const a = 1;\n\nthrow new Error('bad');", wantLine: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(Config{}).Execute(context.Background(), code.ExecuteParams{Code: tt.src}, &fakeTools{})
+			var codeErr *code.CodeError
+			if !errors.As(err, &codeErr) {
+				t.Fatalf("Execute() error = %v, want *code.CodeError", err)
+			}
+			if codeErr.Line != tt.wantLine {
+				t.Errorf("Line = %d, want %d (%s)", codeErr.Line, tt.wantLine, codeErr.Message)
+			}
+		})
+	}
+}
+
+func TestEngine_Execute_UnsupportedLanguage(t *testing.T) {
+	_, err := New(Config{}).Execute(context.Background(), code.ExecuteParams{Language: "python", Code: "x"}, &fakeTools{})
+	if !errors.Is(err, ErrUnsupportedLanguage) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrUnsupportedLanguage)
+	}
+}
+
+// stripTypes is a Transpiler that drops ": number" annotations.
+type stripTypes struct{}
+
+func (stripTypes) Transpile(_ context.Context, source string) (string, error) {
+	if strings.Contains(source, "!") {
+		return "", errors.New("unexpected token")
+	}
+	return strings.ReplaceAll(source, ": number", ""), nil
+}
+
+func TestEngine_Execute_TypeScript(t *testing.T) {
+	params := code.ExecuteParams{Language: "typescript", Code: "const x: number = 2; __out = x * 21"}
+	if _, err := New(Config{}).Execute(context.Background(), params, &fakeTools{}); !errors.Is(err, ErrUnsupportedLanguage) {
+		t.Errorf("Execute() without a transpiler error = %v, want %v", err, ErrUnsupportedLanguage)
+	}
+
+	engine := New(Config{Transpiler: stripTypes{}})
+	result, err := engine.Execute(context.Background(), params, &fakeTools{})
+	if err != nil || result.Value != int64(42) {
+		t.Errorf("Execute() = %v, %v; want 42", result.Value, err)
+	}
+	params.Code = "!"
+	if _, err := engine.Execute(context.Background(), params, &fakeTools{}); !errors.Is(err, ErrTranspileFailed) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrTranspileFailed)
+	}
+}
+
+func TestEngine_Execute_SessionRetainsGlobals(t *testing.T) {
+	idx := index.NewInMemoryIndex()
+	executor, err := code.NewDefaultExecutor(code.Config{
+		Index:           idx,
+		Docs:            tooldoc.NewInMemoryStore(tooldoc.StoreOptions{Index: idx}),
+		Run:             run.ExecutorFunc(func(context.Context, string, map[string]any) (run.RunResult, error) { return run.RunResult{}, nil }),
+		Engine:          New(Config{}),
+		DefaultLanguage: "javascript",
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := executor.ExecuteCode(ctx, code.ExecuteParams{SessionID: "s1", Code: "var counter = 41;"}); err != nil {
+		t.Fatalf("ExecuteCode() #1 error = %v", err)
+	}
+	result, err := executor.ExecuteCode(ctx, code.ExecuteParams{SessionID: "s1", Code: "counter++; __out = counter;"})
+	if err != nil {
+		t.Fatalf("ExecuteCode() #2 error = %v", err)
+	}
+	if result.Value != int64(42) {
+		t.Errorf("Value = %v, want 42", result.Value)
+	}
+
+	_, err = executor.ExecuteCode(ctx, code.ExecuteParams{Code: "__out = counter;"})
+	var codeErr *code.CodeError
+	if !errors.As(err, &codeErr) {
+		t.Errorf("ExecuteCode() without session error = %v, want ReferenceError", err)
+	}
+
+	if err := executor.CloseSession(ctx, "s1"); err != nil {
+		t.Fatalf("CloseSession() error = %v", err)
+	}
+	result, err = executor.ExecuteCode(ctx, code.ExecuteParams{SessionID: "s1", Code: "__out = typeof counter;"})
+	if err != nil {
+		t.Fatalf("ExecuteCode() after close error = %v", err)
+	}
+	if result.Value != "undefined" {
+		t.Errorf("counter after CloseSession = %v, want undefined", result.Value)
+	}
+}
+
+func TestEngine_DescribeEngine(t *testing.T) {
+	idx := index.NewInMemoryIndex()
+	executor, err := code.NewDefaultExecutor(code.Config{
+		Index:   idx,
+		Docs:    tooldoc.NewInMemoryStore(tooldoc.StoreOptions{Index: idx}),
+		Run:     run.ExecutorFunc(func(context.Context, string, map[string]any) (run.RunResult, error) { return run.RunResult{}, nil }),
+		Engines: map[string]code.Engine{"javascript": New(Config{})},
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+
+	surface, err := executor.Describe(context.Background(), code.ExecuteParams{})
+	if err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+	for _, fn := range surface.Functions {
+		if !strings.HasPrefix(fn.Signature, "tools.") || strings.Contains(fn.Signature, "ctx") {
+			t.Errorf("%s signature = %q, want JavaScript form", fn.Name, fn.Signature)
+		}
+	}
+}
+
+func TestEngine_Execute_Determinism(t *testing.T) {
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	params := code.ExecuteParams{
+		Language:    "javascript",
+		Code:        `__out = [Date.now(), new Date().toISOString(), Math.random()];`,
+		Determinism: &code.Determinism{Now: now, Seed: 7},
+	}
+	engine := New(Config{})
+
+	first, err := engine.Execute(context.Background(), params, &fakeTools{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	second, err := engine.Execute(context.Background(), params, &fakeTools{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	got := first.Value.([]any)
+	if got[0] != now.UnixMilli() || got[1] != "2026-03-04T05:06:07.000Z" {
+		t.Errorf("clock = %v, want pinned to %v", got[:2], now)
+	}
+	if !reflect.DeepEqual(first.Value, second.Value) {
+		t.Errorf("replay differs: %v vs %v", first.Value, second.Value)
+	}
+}
+
+func TestEngineContract(t *testing.T) {
+	code.RunEngineContractTests(t, code.EngineContract{
+		NewEngine:   func() code.Engine { return New(Config{}) },
+		Language:    "javascript",
+		OutCode:     `__out = "contract";`,
+		PrintlnCode: `console.log("contract");`,
+		RunToolCode: `__out = tools.runTool("contract:echo", {msg: "contract"}).msg;`,
+		LoopCode:    `while (true) {}`,
+	})
+}
@@ -1,5 +1,14 @@
 package code
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jonwraymond/toolexec/run"
+)
+
 // Logger is an optional interface for observability during code execution.
 // Implementations can log tool calls, timing information, and other events.
 //
@@ -11,3 +20,151 @@ type Logger interface {
 	// Logf logs a formatted message.
 	Logf(format string, args ...any)
 }
+
+// LogEvent identifies the kind of a structured log entry.
+type LogEvent string
+
+const (
+	// LogEventExecuteStart is logged when an execution begins.
+	LogEventExecuteStart LogEvent = "execute_start"
+
+	// LogEventExecuteEnd is logged when an execution finishes.
+	LogEventExecuteEnd LogEvent = "execute_end"
+
+	// LogEventToolCall is logged for every tool call, including
+	// deduplicated calls and individual chain steps.
+	LogEventToolCall LogEvent = "tool_call"
+
+	// LogEventLimitExceeded is logged when a limit rejects a call or
+	// ends an execution.
+	LogEventLimitExceeded LogEvent = "limit_exceeded"
+)
+
+// LogEntry is a structured log record emitted during code execution.
+// Fields irrelevant to the event are left at their zero values.
+type LogEntry struct {
+	// Event is the kind of entry.
+	Event LogEvent
+
+	// ExecutionID is the runtime execution ID of the execution.
+	ExecutionID string
+
+	// Language is the snippet language (execute events).
+	Language string
+
+	// SessionID is the session the execution belongs to, if any.
+	SessionID string
+
+	// ToolID is the tool called (tool call and limit events).
+	ToolID string
+
+	// Backend is the backend kind that executed the tool call.
+	Backend string
+
+	// ToolCalls is the number of tool calls made (execute end).
+	ToolCalls int
+
+	// DurationMs is the elapsed time in milliseconds.
+	DurationMs int64
+
+	// ErrorCode classifies a failure (see run.CodeOf); empty on success.
+	ErrorCode string
+
+	// Error is the failure message; empty on success.
+	Error string
+
+	// Deduplicated is set for tool calls served from an earlier result.
+	Deduplicated bool
+}
+
+// StructuredLogger is a Logger that also accepts structured entries.
+// When Config.Logger implements it, the executor emits a LogEntry for
+// execution start and end, every tool call, and limit enforcement instead
+// of formatted messages.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Errors: logging must be best-effort; Log should not panic.
+// - Ownership: entries are passed by value.
+type StructuredLogger interface {
+	Logger
+
+	// Log records a structured entry.
+	Log(entry LogEntry)
+}
+
+// SlogLogger adapts a *slog.Logger to StructuredLogger.
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a StructuredLogger writing to logger.
+// If logger is nil, slog.Default() is used.
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogLogger{logger: logger}
+}
+
+// Logf implements Logger.
+func (l *SlogLogger) Logf(format string, args ...any) {
+	l.logger.Info(fmt.Sprintf(format, args...))
+}
+
+// Log implements StructuredLogger. Failed tool calls and limit events are
+// logged at warn level; everything else at info or debug.
+func (l *SlogLogger) Log(entry LogEntry) {
+	attrs := []slog.Attr{slog.String("event", string(entry.Event))}
+	addString := func(key, value string) {
+		if value != "" {
+			attrs = append(attrs, slog.String(key, value))
+		}
+	}
+	addString("execution_id", entry.ExecutionID)
+	addString("language", entry.Language)
+	addString("session_id", entry.SessionID)
+	addString("tool_id", entry.ToolID)
+	addString("backend", entry.Backend)
+	if entry.Event == LogEventExecuteEnd {
+		attrs = append(attrs, slog.Int("tool_calls", entry.ToolCalls))
+	}
+	if entry.Event == LogEventExecuteEnd || entry.Event == LogEventToolCall {
+		attrs = append(attrs, slog.Int64("duration_ms", entry.DurationMs))
+	}
+	if entry.Deduplicated {
+		attrs = append(attrs, slog.Bool("deduplicated", true))
+	}
+	addString("error_code", entry.ErrorCode)
+	addString("error", entry.Error)
+
+	level := slog.LevelInfo
+	switch {
+	case entry.Event == LogEventLimitExceeded || entry.Error != "":
+		level = slog.LevelWarn
+	case entry.Event == LogEventToolCall:
+		level = slog.LevelDebug
+	}
+	l.logger.LogAttrs(context.Background(), level, "code: "+string(entry.Event), attrs...)
+}
+
+// logEntry sends entry to logger if it is a StructuredLogger.
+func logEntry(logger Logger, entry LogEntry) {
+	if sl, ok := logger.(StructuredLogger); ok {
+		sl.Log(entry)
+	}
+}
+
+// errorFields returns the code and message for err, or empty strings.
+func errorFields(err error) (code, message string) {
+	if err == nil {
+		return "", ""
+	}
+	if errors.Is(err, ErrLimitExceeded) {
+		return "limit_exceeded", err.Error()
+	}
+	return string(run.CodeOf(err)), err.Error()
+}
+
+// Ensure SlogLogger implements StructuredLogger.
+var _ StructuredLogger = (*SlogLogger)(nil)
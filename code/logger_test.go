@@ -1,6 +1,18 @@
 package code
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolfoundation/model"
+)
 
 func TestLogger_Interface(t *testing.T) {
 	t.Helper()
@@ -15,3 +27,86 @@ type testLogger struct {
 func (l *testLogger) Logf(_ string, _ ...any) {
 	// Implementation for testing
 }
+
+// recordingLogger is a StructuredLogger that records entries.
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+func (l *recordingLogger) Logf(string, ...any) {}
+
+func (l *recordingLogger) Log(entry LogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+}
+
+func TestExecuteCode_StructuredLogging(t *testing.T) {
+	logger := &recordingLogger{}
+	engine := engineFunc(func(ctx context.Context, _ ExecuteParams, tools Tools) (ExecuteResult, error) {
+		_, _ = tools.RunTool(ctx, "ns:ok", nil)
+		_, err := tools.RunTool(ctx, "ns:again", nil)
+		return ExecuteResult{}, err
+	})
+	exec, err := NewDefaultExecutor(Config{
+		Index:        &mockIndex{},
+		Docs:         &mockStore{},
+		Run:          &mockRunner{runResult: run.RunResult{Backend: model.ToolBackend{Kind: model.BackendKindLocal}}},
+		Engine:       engine,
+		MaxToolCalls: 1,
+		Logger:       logger,
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+
+	ctx := runtime.WithExecutionID(context.Background(), "exec-log")
+	result, err := exec.ExecuteCode(ctx, ExecuteParams{Language: "go", Code: "x"})
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("ExecuteCode() error = %v, want %v", err, ErrLimitExceeded)
+	}
+	for _, entry := range logger.entries {
+		if entry.ExecutionID != "exec-log" {
+			t.Errorf("%s entry ExecutionID = %q, want exec-log", entry.Event, entry.ExecutionID)
+		}
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].ExecutionID != "exec-log" {
+		t.Errorf("ToolCalls = %+v, want one call from exec-log", result.ToolCalls)
+	}
+
+	want := []LogEvent{LogEventExecuteStart, LogEventToolCall, LogEventLimitExceeded, LogEventExecuteEnd}
+	if len(logger.entries) != len(want) {
+		t.Fatalf("entries = %+v, want events %v", logger.entries, want)
+	}
+	for i, ev := range want {
+		if logger.entries[i].Event != ev {
+			t.Errorf("entry %d event = %s, want %s", i, logger.entries[i].Event, ev)
+		}
+	}
+	if call := logger.entries[1]; call.ToolID != "ns:ok" || call.Backend != "local" || call.Error != "" {
+		t.Errorf("tool call entry = %+v", call)
+	}
+	if limit := logger.entries[2]; limit.ToolID != "ns:again" || limit.ErrorCode != "limit_exceeded" {
+		t.Errorf("limit entry = %+v", limit)
+	}
+	if end := logger.entries[3]; end.ToolCalls != 1 || end.ErrorCode != "limit_exceeded" || end.Language != "go" {
+		t.Errorf("end entry = %+v", end)
+	}
+}
+
+func TestSlogLogger_Log(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	logger.Log(LogEntry{Event: LogEventToolCall, ExecutionID: "exec-1", ToolID: "ns:tool", Backend: "mcp", DurationMs: 7, ErrorCode: "execution", Error: "boom"})
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON log %q: %v", buf.String(), err)
+	}
+	if got["level"] != "WARN" || got["event"] != "tool_call" || got["tool_id"] != "ns:tool" || got["execution_id"] != "exec-1" ||
+		got["duration_ms"] != float64(7) || got["error_code"] != "execution" {
+		t.Errorf("log record = %v", got)
+	}
+}
@@ -0,0 +1,33 @@
+package code
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrOutputValidation indicates that the value a snippet produced does not
+// conform to ExecuteParams.OutputSchema.
+var ErrOutputValidation = errors.New("code: output validation failed")
+
+// ValidationReport describes the outcome of validating an execution's
+// value against ExecuteParams.OutputSchema.
+type ValidationReport struct {
+	// Valid is true when the value conforms to the schema.
+	Valid bool `json:"valid"`
+
+	// Errors lists validation failures; empty when Valid.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// validateOutput checks result.Value against schema, attaching a report
+// to result. It returns an error wrapping ErrOutputValidation on failure.
+func (e *DefaultExecutor) validateOutput(schema any, result *ExecuteResult) error {
+	// Validate the JSON-shaped value a caller would receive on the wire.
+	err := e.cfg.SchemaValidator.Validate(schema, deepCopyValue(result.Value))
+	if err == nil {
+		result.Validation = &ValidationReport{Valid: true}
+		return nil
+	}
+	result.Validation = &ValidationReport{Errors: []string{err.Error()}}
+	return fmt.Errorf("%w: %w", ErrOutputValidation, err)
+}
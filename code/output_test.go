@@ -0,0 +1,70 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+// typeValidator checks only the top-level "type" keyword of a schema.
+type typeValidator struct {
+	model.SchemaValidator
+}
+
+func (typeValidator) Validate(schema any, instance any) error {
+	want, _ := schema.(map[string]any)["type"].(string)
+	switch instance.(type) {
+	case map[string]any:
+		if want == "object" {
+			return nil
+		}
+	case string:
+		if want == "string" {
+			return nil
+		}
+	}
+	return errors.New("value does not match type " + want)
+}
+
+func TestExecuteCode_OutputSchema(t *testing.T) {
+	newExec := func(value any) *DefaultExecutor {
+		exec, err := NewDefaultExecutor(Config{
+			Index:           &mockIndex{},
+			Docs:            &mockStore{},
+			Run:             &mockRunner{},
+			Engine:          &mockEngine{executeResult: ExecuteResult{Value: value}},
+			SchemaValidator: typeValidator{},
+		})
+		if err != nil {
+			t.Fatalf("NewDefaultExecutor() error = %v", err)
+		}
+		return exec
+	}
+	schema := map[string]any{"type": "object"}
+
+	result, err := newExec(map[string]any{"ok": true}).ExecuteCode(context.Background(), ExecuteParams{Code: "x", OutputSchema: schema})
+	if err != nil {
+		t.Fatalf("ExecuteCode() error = %v", err)
+	}
+	if result.Validation == nil || !result.Validation.Valid {
+		t.Errorf("Validation = %+v, want valid", result.Validation)
+	}
+
+	result, err = newExec("not an object").ExecuteCode(context.Background(), ExecuteParams{Code: "x", OutputSchema: schema})
+	if !errors.Is(err, ErrOutputValidation) {
+		t.Fatalf("ExecuteCode() error = %v, want %v", err, ErrOutputValidation)
+	}
+	if result.Validation == nil || result.Validation.Valid || len(result.Validation.Errors) != 1 {
+		t.Errorf("Validation = %+v, want one error", result.Validation)
+	}
+	if result.Value != "not an object" {
+		t.Errorf("Value = %v, want value preserved", result.Value)
+	}
+
+	result, err = newExec("anything").ExecuteCode(context.Background(), ExecuteParams{Code: "x"})
+	if err != nil || result.Validation != nil {
+		t.Errorf("without schema: err = %v, Validation = %+v", err, result.Validation)
+	}
+}
@@ -0,0 +1,168 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPolicyViolation indicates a snippet was rejected by preflight analysis
+// before execution. Callers use errors.Is to match it.
+var ErrPolicyViolation = errors.New("code: policy violation")
+
+// Preflight rule identifiers reported in Finding.Rule.
+const (
+	RuleImport       = "import"
+	RuleFilesystem   = "filesystem"
+	RuleNetwork      = "network"
+	RuleInfiniteLoop = "infinite_loop"
+	RuleSyntax       = "syntax"
+)
+
+// Preflight inspects a snippet before it is handed to the Engine, so that
+// disallowed code is rejected without paying for a sandbox spin-up.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines.
+// - Errors: rejections should return a *PolicyError (matching ErrPolicyViolation).
+// - Ownership: params are read-only.
+type Preflight interface {
+	// Check returns nil if the snippet may run.
+	Check(ctx context.Context, params ExecuteParams) error
+}
+
+// PreflightFunc adapts a function into a Preflight.
+type PreflightFunc func(ctx context.Context, params ExecuteParams) error
+
+// Check implements Preflight.
+func (f PreflightFunc) Check(ctx context.Context, params ExecuteParams) error {
+	return f(ctx, params)
+}
+
+// Finding describes one policy violation found in a snippet.
+type Finding struct {
+	// Rule is the violated rule (RuleImport, RuleFilesystem, ...).
+	Rule string `json:"rule"`
+
+	// Message describes the violation.
+	Message string `json:"message"`
+
+	// Line is the 1-based snippet line of the violation, or zero if unknown.
+	Line int `json:"line,omitempty"`
+}
+
+// PolicyError reports every violation found by preflight analysis.
+type PolicyError struct {
+	Findings []Finding
+}
+
+// Error implements the error interface.
+func (e *PolicyError) Error() string {
+	parts := make([]string, len(e.Findings))
+	for i, f := range e.Findings {
+		if f.Line > 0 {
+			parts[i] = fmt.Sprintf("line %d: %s", f.Line, f.Message)
+		} else {
+			parts[i] = f.Message
+		}
+	}
+	return fmt.Sprintf("%v: %s", ErrPolicyViolation, strings.Join(parts, "; "))
+}
+
+// Unwrap returns ErrPolicyViolation so errors.Is matches.
+func (e *PolicyError) Unwrap() error {
+	return ErrPolicyViolation
+}
+
+// Policy describes constructs a snippet may not use.
+// Analysis is static and best-effort: it catches common cases cheaply and
+// complements, rather than replaces, runtime sandboxing.
+type Policy struct {
+	// AllowedImports lists the modules or packages a snippet may import.
+	// Nil allows any import; a non-nil empty slice allows none.
+	// For Python, an entry also allows its submodules.
+	AllowedImports []string
+
+	// DenyFilesystem rejects filesystem primitives (os, io/ioutil, open(), fs, ...).
+	DenyFilesystem bool
+
+	// DenyNetwork rejects network primitives (net, socket, fetch, ...).
+	DenyNetwork bool
+
+	// DenyInfiniteLoops rejects unconditional loops with no exit
+	// (break, return) in their body.
+	DenyInfiniteLoops bool
+}
+
+// NewPolicyPreflight returns a Preflight that enforces policy using a
+// per-language analyzer. Go is parsed with go/parser; Python and
+// JavaScript use lexical heuristics. Go snippets that do not parse are
+// rejected with RuleSyntax; snippets in other languages pass through to
+// the engine unchecked.
+func NewPolicyPreflight(policy Policy) Preflight {
+	return PreflightFunc(func(ctx context.Context, params ExecuteParams) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var findings []Finding
+		switch strings.ToLower(params.Language) {
+		case "go", "golang":
+			findings = analyzeGo(policy, params.Code)
+		case "python", "py":
+			findings = analyzePython(policy, params.Code)
+		case "javascript", "js", "typescript", "ts":
+			findings = analyzeJavaScript(policy, params.Code)
+		}
+		if len(findings) > 0 {
+			return &PolicyError{Findings: findings}
+		}
+		return nil
+	})
+}
+
+// moduleRule classifies an imported module into a denied capability.
+type moduleRule struct {
+	rule    string
+	enabled bool
+	modules map[string]bool
+}
+
+// checkImport appends findings for an import of module at line, described
+// as what ("import", "use of package"). matches reports whether an
+// allowlist entry or denied module covers it.
+func checkImport(policy Policy, findings []Finding, what, module string, line int, matches func(entry, module string) bool, rules []moduleRule) []Finding {
+	if policy.AllowedImports != nil {
+		allowed := false
+		for _, entry := range policy.AllowedImports {
+			if matches(entry, module) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			findings = append(findings, Finding{Rule: RuleImport, Message: fmt.Sprintf("%s %q is not allowed", what, module), Line: line})
+		}
+	}
+	for _, r := range rules {
+		if !r.enabled {
+			continue
+		}
+		for denied := range r.modules {
+			if matches(denied, module) {
+				findings = append(findings, Finding{Rule: r.rule, Message: fmt.Sprintf("%s %q provides %s access", what, module, r.rule), Line: line})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+func setOf(items ...string) map[string]bool {
+	m := make(map[string]bool, len(items))
+	for _, s := range items {
+		m[s] = true
+	}
+	return m
+}
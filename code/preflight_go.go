@@ -0,0 +1,161 @@
+package code
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	pathpkg "path"
+	"strconv"
+)
+
+var (
+	goFilesystemPackages = setOf("os", "os/exec", "io/ioutil", "io/fs", "syscall", "path/filepath", "embed", "plugin")
+	goNetworkPackages    = setOf("net", "net/http", "net/http/httputil", "net/rpc", "net/smtp", "net/mail", "net/url", "crypto/tls")
+
+	// goPackageNames maps the names of the denied packages to their paths.
+	// Engines wrap snippets in a program whose imports a snippet can use
+	// without importing them, so selectors on these names are checked as
+	// imports are.
+	goPackageNames = map[string]string{
+		"os": "os", "exec": "os/exec", "ioutil": "io/ioutil", "fs": "io/fs", "syscall": "syscall",
+		"filepath": "path/filepath", "embed": "embed", "plugin": "plugin",
+		"net": "net", "http": "net/http", "httputil": "net/http/httputil", "rpc": "net/rpc",
+		"smtp": "net/smtp", "mail": "net/mail", "url": "net/url", "tls": "crypto/tls",
+	}
+)
+
+// analyzeGo parses a Go snippet and checks it against policy.
+// Snippets may be a full file, top-level declarations, or bare statements;
+// a snippet that parses as none of them is rejected.
+func analyzeGo(policy Policy, src string) []Finding {
+	fset := token.NewFileSet()
+	file, offset, err := parseGoSnippet(fset, src)
+	line := func(pos token.Pos) int {
+		return fset.Position(pos).Line - offset
+	}
+	if err != nil {
+		f := Finding{Rule: RuleSyntax, Message: "snippet does not parse: " + err.Error()}
+		var list scanner.ErrorList
+		if errors.As(err, &list) && len(list) > 0 {
+			f.Message = "snippet does not parse: " + list[0].Msg
+			f.Line = max(list[0].Pos.Line-offset, 0)
+		}
+		return []Finding{f}
+	}
+
+	rules := []moduleRule{
+		{rule: RuleFilesystem, enabled: policy.DenyFilesystem, modules: goFilesystemPackages},
+		{rule: RuleNetwork, enabled: policy.DenyNetwork, modules: goNetworkPackages},
+	}
+	matches := func(entry, pkg string) bool { return entry == pkg }
+
+	var findings []Finding
+	imported := make(map[string]bool)
+	for _, imp := range file.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := pathpkg.Base(path)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		imported[name] = true
+		findings = checkImport(policy, findings, "import", path, line(imp.Pos()), matches, rules)
+	}
+
+	// A selector on an unresolved name refers to a package, imported by
+	// the snippet or by the program that wraps it. Each package is
+	// reported once, where it is first used.
+	used := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok || ident.Obj != nil || imported[ident.Name] || used[ident.Name] {
+			return true
+		}
+		if path, ok := goPackageNames[ident.Name]; ok {
+			used[ident.Name] = true
+			findings = checkImport(policy, findings, "use of package", path, line(sel.Pos()), matches, rules)
+		}
+		return true
+	})
+
+	if policy.DenyInfiniteLoops {
+		ast.Inspect(file, func(n ast.Node) bool {
+			loop, ok := n.(*ast.ForStmt)
+			if !ok || !alwaysTrue(loop.Cond) || goLoopExits(loop.Body) {
+				return true
+			}
+			findings = append(findings, Finding{Rule: RuleInfiniteLoop, Message: "loop has no condition and no exit", Line: line(loop.Pos())})
+			return true
+		})
+	}
+	return findings
+}
+
+// parseGoSnippet parses src as a file, then as declarations, then as
+// statements, returning the file and the number of lines prepended. When
+// src parses as none of them, the error is that of the statements.
+func parseGoSnippet(fset *token.FileSet, src string) (*ast.File, int, error) {
+	attempts := []struct {
+		prefix, suffix string
+		offset         int
+	}{
+		{"", "", 0},
+		{"package snippet\n", "", 1},
+		{"package snippet\nfunc _() {\n", "\n}", 2},
+	}
+	var err error
+	for _, a := range attempts {
+		var file *ast.File
+		// Object resolution tells package names from local identifiers.
+		file, err = parser.ParseFile(fset, "snippet.go", a.prefix+src+a.suffix, 0)
+		if err == nil {
+			return file, a.offset, nil
+		}
+	}
+	return nil, attempts[len(attempts)-1].offset, err
+}
+
+// alwaysTrue reports whether a loop condition is absent or the literal true.
+func alwaysTrue(cond ast.Expr) bool {
+	if cond == nil {
+		return true
+	}
+	ident, ok := cond.(*ast.Ident)
+	return ok && ident.Name == "true"
+}
+
+// goLoopExits reports whether body contains a statement that can leave
+// the loop: return, break, goto, panic, or os.Exit.
+func goLoopExits(body *ast.BlockStmt) bool {
+	exits := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.ReturnStmt:
+			exits = true
+		case *ast.BranchStmt:
+			if s.Tok == token.BREAK || s.Tok == token.GOTO {
+				exits = true
+			}
+		case *ast.CallExpr:
+			switch fn := s.Fun.(type) {
+			case *ast.Ident:
+				exits = exits || fn.Name == "panic"
+			case *ast.SelectorExpr:
+				exits = exits || fn.Sel.Name == "Exit"
+			}
+		case *ast.FuncLit:
+			// Returns inside closures do not leave the loop.
+			return false
+		}
+		return !exits
+	})
+	return exits
+}
@@ -0,0 +1,146 @@
+package code
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	pyImportRe     = regexp.MustCompile(`^\s*import\s+(.+)$`)
+	pyFromImportRe = regexp.MustCompile(`^\s*from\s+([\w.]+)\s+import\b`)
+	pyOpenRe       = regexp.MustCompile(`(^|[^\w.])open\s*\(`)
+	pyLoopRe       = regexp.MustCompile(`^(\s*)while\s+(True|1)\s*:`)
+	pyExitRe       = regexp.MustCompile(`\b(break|return|raise)\b|sys\.exit\s*\(`)
+
+	pyFilesystemModules = setOf("os", "shutil", "pathlib", "io", "glob", "tempfile", "subprocess")
+	pyNetworkModules    = setOf("socket", "ssl", "urllib", "http", "requests", "httpx", "ftplib", "smtplib", "asyncio")
+
+	jsRequireRe = regexp.MustCompile(`\brequire\s*\(\s*['"]([^'"]+)['"]\s*\)`)
+	jsImportRe  = regexp.MustCompile(`\bimport\s*(?:[\w*{}\s,]+\s*from\s*)?\(?\s*['"]([^'"]+)['"]`)
+	jsNetworkRe = regexp.MustCompile(`\b(fetch|XMLHttpRequest|WebSocket|EventSource)\b`)
+	jsLoopRe    = regexp.MustCompile(`\b(?:while\s*\(\s*(?:true|1)\s*\)|for\s*\(\s*;\s*;\s*\))\s*\{`)
+	jsExitRe    = regexp.MustCompile(`\b(break|return|throw)\b`)
+
+	jsFilesystemModules = setOf("fs", "fs/promises", "node:fs", "node:fs/promises", "child_process", "node:child_process")
+	jsNetworkModules    = setOf("http", "https", "net", "dgram", "tls", "node:http", "node:https", "node:net", "node:dgram", "node:tls")
+)
+
+// analyzePython checks a Python snippet against policy line by line.
+func analyzePython(policy Policy, src string) []Finding {
+	rules := []moduleRule{
+		{rule: RuleFilesystem, enabled: policy.DenyFilesystem, modules: pyFilesystemModules},
+		{rule: RuleNetwork, enabled: policy.DenyNetwork, modules: pyNetworkModules},
+	}
+	// An entry covers the module itself and its submodules.
+	matches := func(entry, module string) bool {
+		return module == entry || strings.HasPrefix(module, entry+".")
+	}
+
+	lines := strings.Split(src, "\n")
+	var findings []Finding
+	for i, raw := range lines {
+		line := stripPythonComment(raw)
+		var modules []string
+		if m := pyFromImportRe.FindStringSubmatch(line); m != nil {
+			modules = append(modules, m[1])
+		} else if m := pyImportRe.FindStringSubmatch(line); m != nil {
+			for _, part := range strings.Split(m[1], ",") {
+				if fields := strings.Fields(part); len(fields) > 0 {
+					modules = append(modules, fields[0])
+				}
+			}
+		}
+		for _, module := range modules {
+			findings = checkImport(policy, findings, "import", module, i+1, matches, rules)
+		}
+
+		if policy.DenyFilesystem && pyOpenRe.MatchString(line) {
+			findings = append(findings, Finding{Rule: RuleFilesystem, Message: "open() provides filesystem access", Line: i + 1})
+		}
+		if policy.DenyInfiniteLoops {
+			if m := pyLoopRe.FindStringSubmatch(line); m != nil && !pythonBlockExits(lines[i+1:], len(m[1])) {
+				findings = append(findings, Finding{Rule: RuleInfiniteLoop, Message: "while loop has a constant condition and no exit", Line: i + 1})
+			}
+		}
+	}
+	return findings
+}
+
+// pythonBlockExits reports whether the block indented deeper than indent
+// at the start of lines contains break, return, raise, or sys.exit.
+func pythonBlockExits(lines []string, indent int) bool {
+	for _, raw := range lines {
+		line := stripPythonComment(raw)
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if len(line)-len(strings.TrimLeft(line, " \t")) <= indent {
+			return false
+		}
+		if pyExitRe.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+func stripPythonComment(line string) string {
+	if i := strings.Index(line, "#"); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+// analyzeJavaScript checks a JavaScript snippet against policy using
+// lexical patterns.
+func analyzeJavaScript(policy Policy, src string) []Finding {
+	rules := []moduleRule{
+		{rule: RuleFilesystem, enabled: policy.DenyFilesystem, modules: jsFilesystemModules},
+		{rule: RuleNetwork, enabled: policy.DenyNetwork, modules: jsNetworkModules},
+	}
+	matches := func(entry, module string) bool { return entry == module }
+
+	var findings []Finding
+	for i, line := range strings.Split(src, "\n") {
+		for _, re := range []*regexp.Regexp{jsRequireRe, jsImportRe} {
+			for _, m := range re.FindAllStringSubmatch(line, -1) {
+				findings = checkImport(policy, findings, "import", m[1], i+1, matches, rules)
+			}
+		}
+		if policy.DenyNetwork {
+			if m := jsNetworkRe.FindStringSubmatch(line); m != nil {
+				findings = append(findings, Finding{Rule: RuleNetwork, Message: fmt.Sprintf("%s provides network access", m[1]), Line: i + 1})
+			}
+		}
+	}
+
+	if policy.DenyInfiniteLoops {
+		for _, loc := range jsLoopRe.FindAllStringIndex(src, -1) {
+			body := braceBlock(src[loc[1]-1:])
+			if !jsExitRe.MatchString(body) {
+				line := strings.Count(src[:loc[0]], "\n") + 1
+				findings = append(findings, Finding{Rule: RuleInfiniteLoop, Message: "loop has a constant condition and no exit", Line: line})
+			}
+		}
+	}
+	return findings
+}
+
+// braceBlock returns the text of the brace-delimited block starting at
+// s[0], or the rest of s if the braces are unbalanced.
+func braceBlock(s string) string {
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return s[:i+1]
+			}
+		}
+	}
+	return s
+}
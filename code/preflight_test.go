@@ -0,0 +1,163 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPolicyPreflight(t *testing.T) {
+	strict := Policy{
+		AllowedImports:    []string{"fmt", "strings", "json", "collections"},
+		DenyFilesystem:    true,
+		DenyNetwork:       true,
+		DenyInfiniteLoops: true,
+	}
+
+	tests := []struct {
+		name      string
+		language  string
+		code      string
+		wantRules []string
+		wantLine  int
+	}{
+		{
+			name:     "go clean statements",
+			language: "go",
+			code:     "x := 1\nfor i := 0; i < 3; i++ { x += i }\n__out = x",
+		},
+		{
+			name:      "go imports",
+			language:  "go",
+			code:      "import (\n\t\"fmt\"\n\t\"net/http\"\n)\nvar _ = fmt.Sprint\nvar _ = http.Get",
+			wantRules: []string{RuleImport, RuleNetwork},
+			wantLine:  3,
+		},
+		{
+			name:      "go infinite loop",
+			language:  "go",
+			code:      "x := 0\nfor {\n\tx++\n}",
+			wantRules: []string{RuleInfiniteLoop},
+			wantLine:  2,
+		},
+		{
+			name:      "go wrapper package use",
+			language:  "go",
+			code:      "data, _ := os.ReadFile(\"/etc/passwd\")\n__out = string(data)",
+			wantRules: []string{RuleImport, RuleFilesystem},
+			wantLine:  1,
+		},
+		{
+			name:     "go local shadows package",
+			language: "go",
+			code:     "url := struct{ Host string }{\"x\"}\n__out = url.Host",
+		},
+		{
+			name:      "go does not parse",
+			language:  "go",
+			code:      "x := 1\nx +=\n}",
+			wantRules: []string{RuleSyntax},
+			wantLine:  3,
+		},
+		{
+			name:     "go loop with break",
+			language: "go",
+			code:     "for {\n\tbreak\n}",
+		},
+		{
+			name:      "python imports and open",
+			language:  "python",
+			code:      "import json, os.path\nfrom collections import Counter\nf = open('x')",
+			wantRules: []string{RuleImport, RuleFilesystem, RuleFilesystem},
+			wantLine:  1,
+		},
+		{
+			name:      "python infinite loop",
+			language:  "python",
+			code:      "n = 0\nwhile True:\n    n += 1\nprint(n)",
+			wantRules: []string{RuleInfiniteLoop},
+			wantLine:  2,
+		},
+		{
+			name:     "python loop with break",
+			language: "python",
+			code:     "while True:\n    break",
+		},
+		{
+			name:      "javascript network",
+			language:  "javascript",
+			code:      "const r = fetch('https://example.com');",
+			wantRules: []string{RuleNetwork},
+			wantLine:  1,
+		},
+		{
+			name:      "javascript require",
+			language:  "js",
+			code:      "// read config\nconst fs = require('fs');",
+			wantRules: []string{RuleImport, RuleFilesystem},
+			wantLine:  2,
+		},
+		{
+			name:      "javascript infinite loop",
+			language:  "javascript",
+			code:      "let i = 0;\nwhile (true) {\n  i++;\n}",
+			wantRules: []string{RuleInfiniteLoop},
+			wantLine:  2,
+		},
+		{
+			name:     "unknown language passes",
+			language: "ruby",
+			code:     "require 'socket'",
+		},
+	}
+
+	preflight := NewPolicyPreflight(strict)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := preflight.Check(context.Background(), ExecuteParams{Language: tt.language, Code: tt.code})
+			if len(tt.wantRules) == 0 {
+				if err != nil {
+					t.Fatalf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+			var policyErr *PolicyError
+			if !errors.As(err, &policyErr) || !errors.Is(err, ErrPolicyViolation) {
+				t.Fatalf("Check() error = %v, want *PolicyError", err)
+			}
+			if len(policyErr.Findings) != len(tt.wantRules) {
+				t.Fatalf("findings = %+v, want rules %v", policyErr.Findings, tt.wantRules)
+			}
+			for i, rule := range tt.wantRules {
+				if policyErr.Findings[i].Rule != rule {
+					t.Errorf("finding %d rule = %s, want %s", i, policyErr.Findings[i].Rule, rule)
+				}
+			}
+			if policyErr.Findings[0].Line != tt.wantLine {
+				t.Errorf("first finding line = %d, want %d", policyErr.Findings[0].Line, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestExecuteCode_PreflightRejects(t *testing.T) {
+	engine := &mockEngine{}
+	exec, err := NewDefaultExecutor(Config{
+		Index:     &mockIndex{},
+		Docs:      &mockStore{},
+		Run:       &mockRunner{},
+		Engine:    engine,
+		Preflight: NewPolicyPreflight(Policy{DenyNetwork: true}),
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+
+	_, err = exec.ExecuteCode(context.Background(), ExecuteParams{Code: "import \"net\"\nvar _ = net.Dial"})
+	if !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("ExecuteCode() error = %v, want %v", err, ErrPolicyViolation)
+	}
+	if len(engine.executeCalls) != 0 {
+		t.Error("engine ran despite preflight rejection")
+	}
+}
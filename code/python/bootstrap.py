@@ -0,0 +1,200 @@
+# Bootstrap for the toolexec Python code engine.
+#
+# The host writes one "execute" message on stdin, then answers gateway
+# requests (search_tools, run_tool, ...) written by this process on stdout.
+# Every message is a single JSON line: {"type": ..., "id": ..., "payload": {...}}.
+# The snippet's own stdout is captured, up to the execution's stdout cap, and
+# sent in "stdout" chunks before the final "result", so no line outgrows the
+# host's message size limit.
+import io
+import json
+import sys
+import traceback
+
+_proto_in = sys.stdin
+_proto_out = sys.stdout
+_next_id = 0
+
+# Characters of stdout per "stdout" message. JSON escapes a character to at
+# most 12 bytes, so a chunk stays well under the host's 16 MiB line limit.
+_STDOUT_CHUNK = 1 << 20
+
+
+class ToolError(Exception):
+    """Raised when a gateway request fails on the host.
+
+    code is the error's run.ErrorCode, if any, and step_results holds the
+    results of the steps a failed run_chain ran, ending with a limit marker
+    when a limit cut the chain short.
+    """
+
+    def __init__(self, message, code=None, step_results=None):
+        super().__init__(message)
+        self.code = code
+        self.step_results = step_results or []
+
+
+def _send(msg_type, msg_id, payload):
+    _proto_out.write(json.dumps({"type": msg_type, "id": msg_id, "payload": payload}, default=str) + "\n")
+    _proto_out.flush()
+
+
+def _request(msg_type, payload):
+    global _next_id
+    _next_id += 1
+    msg_id = str(_next_id)
+    _send(msg_type, msg_id, payload)
+    line = _proto_in.readline()
+    if not line:
+        raise ToolError("gateway connection closed")
+    msg = json.loads(line)
+    body = msg.get("payload") or {}
+    if msg.get("type") == "error":
+        raise ToolError(body.get("error") or "unknown error", body.get("code"), body.get("stepResults"))
+    return body
+
+
+class _Capture(io.StringIO):
+    """Captured snippet stdout, keeping at most limit characters (0 keeps all)."""
+
+    def __init__(self, limit):
+        super().__init__()
+        self._room = limit or None
+
+    def write(self, s):
+        if self._room is not None:
+            if self._room <= 0:
+                return len(s)
+            kept = s[:self._room]
+            self._room -= len(kept)
+            super().write(kept)
+            return len(s)
+        return super().write(s)
+
+
+class Tools:
+    """The metatool surface available to snippets as `tools`."""
+
+    def search_tools(self, query, limit=10, namespaces=None, tags=None):
+        payload = {"query": query, "limit": limit}
+        if namespaces:
+            payload["namespaces"] = list(namespaces)
+        if tags:
+            payload["tags"] = list(tags)
+        return _request("search_tools", payload).get("results") or []
+
+    def list_namespaces(self):
+        return _request("list_namespaces", {}).get("namespaces") or []
+
+    def describe_tool(self, id, level="summary"):
+        return _request("describe_tool", {"id": id, "level": level})
+
+    def list_tool_examples(self, id, max=5):
+        return _request("list_tool_examples", {"id": id, "max": max}).get("examples") or []
+
+    def run_tool(self, id, args=None):
+        return _request("run_tool", {"id": id, "args": args or {}}).get("structured")
+
+    def run_chain(self, steps):
+        body = _request("run_chain", {"steps": steps})
+        return body.get("structured"), body.get("stepResults") or []
+
+    def println(self, *args):
+        print(*args)
+
+
+def _snippet_line(exc):
+    line = 0
+    if isinstance(exc, SyntaxError) and exc.filename == "<snippet>":
+        return exc.lineno or 0
+    for frame in traceback.extract_tb(exc.__traceback__):
+        if frame.filename == "<snippet>":
+            line = frame.lineno
+    return line
+
+
+def _pin(determinism):
+    """Seed randomness and freeze the clock for replayable executions."""
+    import os
+    import random
+    import uuid
+
+    seed = determinism.get("seed", 0)
+    random.seed(seed)
+    # OS entropy comes from its own stream, so drawing it does not shift
+    # the random module's sequence.
+    entropy = random.Random(seed)
+    os.urandom = entropy.randbytes
+
+    class _SeededSystemRandom(random.Random):
+        def __init__(self, x=None):
+            super().__init__(entropy.getrandbits(64))
+
+    random.SystemRandom = _SeededSystemRandom
+    uuid.uuid4 = lambda: uuid.UUID(bytes=entropy.randbytes(16), version=4)
+
+    now = determinism.get("now")
+    if now is None:
+        return
+    import datetime
+    import time
+
+    time.time = lambda: now
+    time.time_ns = lambda: int(now * 1e9)
+    # Elapsed time reads as zero.
+    time.monotonic = time.perf_counter = lambda: now
+    time.monotonic_ns = time.perf_counter_ns = lambda: int(now * 1e9)
+
+    class _FrozenDatetime(datetime.datetime):
+        @classmethod
+        def now(cls, tz=None):
+            return cls.fromtimestamp(now, tz)
+
+        @classmethod
+        def utcnow(cls):
+            return cls.fromtimestamp(now, datetime.timezone.utc).replace(tzinfo=None)
+
+        @classmethod
+        def today(cls):
+            return cls.fromtimestamp(now)
+
+    datetime.datetime = _FrozenDatetime
+
+
+def _finish(msg, captured, result):
+    """Restores the protocol stream, then sends the captured stdout and result."""
+    sys.stdout = _proto_out
+    out = captured.getvalue()
+    for i in range(0, len(out), _STDOUT_CHUNK):
+        _send("stdout", msg.get("id", ""), {"data": out[i:i + _STDOUT_CHUNK]})
+    _send("result", msg.get("id", ""), result)
+
+
+def main():
+    first = _proto_in.readline()
+    if not first:
+        return
+    msg = json.loads(first)
+    payload = msg.get("payload") or {}
+    source = payload.get("code", "")
+    if payload.get("determinism") is not None:
+        _pin(payload["determinism"])
+
+    # One character past the cap is at least one byte past it, so the host
+    # still sees that the output was truncated.
+    limit = payload.get("max_stdout") or 0
+    captured = _Capture(limit + 1 if limit > 0 else 0)
+    sys.stdout = captured
+    scope = {"__name__": "__main__", "tools": Tools(), "ToolError": ToolError}
+    try:
+        exec(compile(source, "<snippet>", "exec"), scope)
+    except BaseException as exc:  # report every failure, including SystemExit
+        _finish(msg, captured, {
+            "error": "%s: %s" % (type(exc).__name__, exc),
+            "line": _snippet_line(exc),
+        })
+        return
+    _finish(msg, captured, {"value": scope.get("__out")})
+
+
+main()
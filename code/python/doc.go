@@ -0,0 +1,29 @@
+// Package python provides a code.Engine that runs Python snippets in a
+// subprocess.
+//
+// The engine starts the configured interpreter with an embedded bootstrap
+// and drives it over stdin/stdout using the proxy gateway protocol
+// (newline-delimited proxy.Message values). Snippets receive a `tools`
+// object mirroring code.Tools:
+//
+//	results = tools.search_tools("weather", 5)
+//	__out = tools.run_tool("weather:get", {"city": "Oslo"})
+//
+// Requests are answered in the payload shapes of the proxy gateway:
+// run_tool returns the result's structured value, run_chain returns it
+// with the step results in run.StepResult's JSON form, and a failed
+// request raises ToolError with the error's code and, for run_chain, the
+// step results so far, including any PartialChains limit marker.
+//
+// A run_chain step may carry a "retry" policy in run.RetryPolicy's JSON
+// form, with backoffs in nanoseconds:
+//
+//	tools.run_chain([{"toolId": "weather:get", "retry": {"maxAttempts": 3}}])
+//
+// As with other engines, the value assigned to __out becomes
+// ExecuteResult.Value, and print output is captured as stdout. Uncaught
+// exceptions are returned as *code.CodeError with the snippet line number.
+//
+// The subprocess is not a sandbox: it runs with the host's privileges.
+// Use a runtime backend via toolcodeengine when isolation is required.
+package python
@@ -0,0 +1,246 @@
+package python
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/toolexec/code"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
+)
+
+// DefaultInterpreter is the Python interpreter used when Config.Interpreter is empty.
+const DefaultInterpreter = "python3"
+
+// Protocol messages exchanged with the bootstrap in addition to the
+// gateway request types.
+const (
+	// MsgExecute carries the snippet from the host to the interpreter.
+	MsgExecute proxy.MessageType = "execute"
+
+	// MsgStdout carries a chunk of the snippet's captured stdout to the
+	// host, ahead of MsgResult.
+	MsgStdout proxy.MessageType = "stdout"
+
+	// MsgResult carries the snippet outcome back to the host.
+	MsgResult proxy.MessageType = "result"
+)
+
+// maxMessageSize bounds a single protocol line from the interpreter. The
+// bootstrap splits stdout into MsgStdout chunks that stay below it.
+const maxMessageSize = 16 << 20
+
+// ErrUnsupportedLanguage is returned when ExecuteParams.Language is not Python.
+var ErrUnsupportedLanguage = errors.New("python: unsupported language")
+
+//go:embed bootstrap.py
+var bootstrap string
+
+// Config configures an Engine.
+type Config struct {
+	// Interpreter is the Python executable. Defaults to DefaultInterpreter.
+	Interpreter string
+
+	// Env is the environment of the interpreter process.
+	// If nil, the host environment is inherited.
+	Env []string
+
+	// Dir is the working directory of the interpreter process.
+	// If empty, the host's current directory is used.
+	Dir string
+}
+
+// Engine implements code.Engine by running Python in a subprocess.
+type Engine struct {
+	interpreter string
+	env         []string
+	dir         string
+}
+
+// New creates a new Engine with the given configuration.
+func New(cfg Config) *Engine {
+	interpreter := cfg.Interpreter
+	if interpreter == "" {
+		interpreter = DefaultInterpreter
+	}
+	return &Engine{
+		interpreter: interpreter,
+		env:         cfg.Env,
+		dir:         cfg.Dir,
+	}
+}
+
+// SupportsDeterminism implements code.DeterministicEngine. The bootstrap
+// seeds the random module, os.urandom, random.SystemRandom (and so the
+// secrets module), and uuid.uuid4, and freezes time.time, time.monotonic,
+// time.perf_counter, and datetime.now. Entropy a snippet reads some other
+// way, such as from /dev/urandom, is not pinned.
+func (e *Engine) SupportsDeterminism() bool {
+	return true
+}
+
+// DescribeEngine implements code.EngineDescriber.
+func (e *Engine) DescribeEngine(string) code.EngineInfo {
+	return code.EngineInfo{
+		Name: "python",
+		Signatures: map[string]string{
+			"SearchTools":         "tools.search_tools(query, limit=10)",
+			"SearchToolsFiltered": "tools.search_tools(query, limit=10, namespaces=None, tags=None)",
+			"ListNamespaces":      "tools.list_namespaces()",
+			"DescribeTool":        `tools.describe_tool(id, level="summary")`,
+			"ListToolExamples":    "tools.list_tool_examples(id, max=5)",
+			"RunTool":             "tools.run_tool(id, args=None)",
+			"RunChain":            "tools.run_chain(steps) -> (result, step_results)",
+			"Println":             "tools.println(*args)",
+		},
+	}
+}
+
+// Execute implements code.Engine.
+func (e *Engine) Execute(ctx context.Context, params code.ExecuteParams, tools code.Tools) (code.ExecuteResult, error) {
+	if err := ctx.Err(); err != nil {
+		return code.ExecuteResult{}, err
+	}
+	switch strings.ToLower(params.Language) {
+	case "", "python", "python3", "py":
+	default:
+		return code.ExecuteResult{}, fmt.Errorf("%w: %q", ErrUnsupportedLanguage, params.Language)
+	}
+
+	cmd := exec.CommandContext(ctx, e.interpreter, "-u", "-c", bootstrap)
+	cmd.Env = e.env
+	if len(params.Env) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		for k, v := range params.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	cmd.Dir = e.dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return code.ExecuteResult{}, fmt.Errorf("python: stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return code.ExecuteResult{}, fmt.Errorf("python: stdout: %w", err)
+	}
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return code.ExecuteResult{}, fmt.Errorf("python: start %s: %w", e.interpreter, err)
+	}
+
+	result, sessErr := session(ctx, stdin, stdout, params, tools)
+	_ = stdin.Close()
+	waitErr := cmd.Wait()
+	result.Stderr = stderr.String()
+	result.ResourceUsage = processUsage(cmd.ProcessState, time.Since(start))
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	if sessErr != nil {
+		if waitErr != nil {
+			return result, fmt.Errorf("python: %w (%v): %s", sessErr, waitErr, strings.TrimSpace(result.Stderr))
+		}
+		return result, sessErr
+	}
+	return result, nil
+}
+
+// session sends the snippet and serves gateway requests until the
+// interpreter reports a result.
+func session(ctx context.Context, w io.Writer, r io.Reader, params code.ExecuteParams, tools code.Tools) (code.ExecuteResult, error) {
+	enc := json.NewEncoder(w)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+
+	payload := map[string]any{"code": params.Code}
+	if params.MaxStdoutBytes > 0 {
+		payload["max_stdout"] = params.MaxStdoutBytes
+	}
+	if d := params.Determinism; d != nil {
+		pinned := map[string]any{"seed": d.Seed}
+		if !d.Now.IsZero() {
+			pinned["now"] = float64(d.Now.UnixNano()) / 1e9
+		}
+		payload["determinism"] = pinned
+	}
+	if err := enc.Encode(proxy.Message{
+		Type:    MsgExecute,
+		ID:      "0",
+		Payload: payload,
+	}); err != nil {
+		return code.ExecuteResult{}, fmt.Errorf("python: send snippet: %w", err)
+	}
+
+	var stdout strings.Builder
+	for scanner.Scan() {
+		var msg proxy.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return code.ExecuteResult{}, fmt.Errorf("%w: %v", proxy.ErrProtocol, err)
+		}
+		switch msg.Type {
+		case MsgStdout:
+			data, _ := msg.Payload["data"].(string)
+			stdout.WriteString(data)
+			continue
+		case MsgResult:
+			return decodeResult(msg, stdout.String(), tools)
+		}
+		if err := enc.Encode(serve(ctx, tools, msg)); err != nil {
+			return code.ExecuteResult{}, fmt.Errorf("python: send response: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return code.ExecuteResult{}, fmt.Errorf("python: read: %w", err)
+	}
+	return code.ExecuteResult{}, fmt.Errorf("%w: interpreter exited without a result", proxy.ErrConnectionClosed)
+}
+
+// decodeResult converts the final result message, forwarding the captured
+// stdout to tools so executors collect and cap it like any other engine
+// output.
+func decodeResult(msg proxy.Message, stdout string, tools code.Tools) (code.ExecuteResult, error) {
+	if stdout != "" {
+		for _, line := range strings.Split(strings.TrimSuffix(stdout, "\n"), "\n") {
+			tools.Println(line)
+		}
+	}
+	result := code.ExecuteResult{
+		Value:  msg.Payload["value"],
+		Stdout: stdout,
+	}
+	if errMsg, ok := msg.Payload["error"].(string); ok && errMsg != "" {
+		line, _ := msg.Payload["line"].(float64)
+		return result, &code.CodeError{Message: errMsg, Line: int(line)}
+	}
+	return result, nil
+}
+
+// processUsage reports the interpreter process's resource usage.
+func processUsage(ps *os.ProcessState, wall time.Duration) *code.ResourceUsage {
+	u := runtime.ProcessUsage(ps, wall)
+	return &code.ResourceUsage{
+		CPUMillis:       u.CPUTime.Milliseconds(),
+		PeakMemoryBytes: u.PeakMemoryBytes,
+		WallTimeMs:      u.WallTime.Milliseconds(),
+	}
+}
+
+// Ensure Engine implements code.Engine.
+var _ code.Engine = (*Engine)(nil)
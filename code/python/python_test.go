@@ -0,0 +1,298 @@
+package python
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/code"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
+)
+
+// fakeTools is a minimal code.Tools backed by in-memory handlers.
+type fakeTools struct {
+	stdout strings.Builder
+	calls  []string
+	chains [][]run.ChainStep
+}
+
+func (f *fakeTools) SearchTools(_ context.Context, query string, _ int) ([]index.Summary, error) {
+	return []index.Summary{{ID: "weather:get", Name: "get", Namespace: "weather", ShortDescription: query}}, nil
+}
+
+func (f *fakeTools) SearchToolsFiltered(ctx context.Context, query string, _ code.SearchFilter) ([]index.Summary, error) {
+	return f.SearchTools(ctx, query, 0)
+}
+
+func (f *fakeTools) ListNamespaces(context.Context) ([]string, error) {
+	return []string{"weather"}, nil
+}
+
+func (f *fakeTools) DescribeTool(_ context.Context, id string, _ tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	return tooldoc.ToolDoc{
+		Summary:    "describes " + id,
+		SchemaInfo: &tooldoc.SchemaInfo{Required: []string{"city"}},
+	}, nil
+}
+
+func (f *fakeTools) ListToolExamples(context.Context, string, int) ([]tooldoc.ToolExample, error) {
+	return nil, nil
+}
+
+func (f *fakeTools) RunTool(_ context.Context, id string, args map[string]any) (run.RunResult, error) {
+	f.calls = append(f.calls, id)
+	if id == "fail:tool" {
+		return run.RunResult{}, errors.New("tool exploded")
+	}
+	return run.RunResult{Structured: map[string]any{"city": args["city"], "temp": 21.5}}, nil
+}
+
+func (f *fakeTools) RunChain(_ context.Context, steps []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	f.chains = append(f.chains, steps)
+	var results []run.StepResult
+	for _, s := range steps {
+		if s.ToolID == "limit:hit" {
+			// Mirror a PartialChains cut: the steps that ran, then a marker.
+			err := fmt.Errorf("%w: max chain steps", code.ErrLimitExceeded)
+			results = append(results, run.StepResult{ToolID: s.ToolID, Err: err})
+			return run.RunResult{}, results, err
+		}
+		results = append(results, run.StepResult{ToolID: s.ToolID, Result: run.RunResult{Structured: s.ToolID}})
+	}
+	return run.RunResult{Structured: "chained"}, results, nil
+}
+
+func (f *fakeTools) Println(args ...any) {
+	fmt.Fprintln(&f.stdout, args...)
+}
+
+func requirePython(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath(DefaultInterpreter); err != nil {
+		t.Skipf("%s not available: %v", DefaultInterpreter, err)
+	}
+}
+
+func TestEngine_Execute_ToolsAndOut(t *testing.T) {
+	requirePython(t)
+	tools := &fakeTools{}
+	src := strings.Join([]string{
+		`found = tools.search_tools("forecast", 3)`,
+		`print("found", found[0]["id"])`,
+		`w = tools.run_tool(found[0]["id"], {"city": "Oslo"})`,
+		`final, steps = tools.run_chain([{"toolId": "a:b"}, {"toolId": "c:d", "usePrevious": True}])`,
+		`__out = {"temp": w["temp"], "chain": final, "steps": len(steps), "ns": tools.list_namespaces()}`,
+	}, "\n")
+
+	result, err := New(Config{}).Execute(context.Background(), code.ExecuteParams{Language: "python", Code: src}, tools)
+	if err != nil {
+		t.Fatalf("Execute() error = %v (stderr: %s)", err, result.Stderr)
+	}
+	out, ok := result.Value.(map[string]any)
+	if !ok {
+		t.Fatalf("Value = %#v, want map", result.Value)
+	}
+	if out["temp"] != 21.5 || out["chain"] != "chained" || out["steps"] != float64(2) {
+		t.Errorf("Value = %v", out)
+	}
+	if got := tools.stdout.String(); got != "found weather:get\n" {
+		t.Errorf("stdout = %q", got)
+	}
+	if len(tools.calls) != 1 || tools.calls[0] != "weather:get" {
+		t.Errorf("RunTool calls = %v", tools.calls)
+	}
+}
+
+func TestServe_RunChainRetry(t *testing.T) {
+	tools := &fakeTools{}
+	req := proxy.Message{Type: proxy.MsgRunChain, ID: "1", Payload: map[string]any{
+		"steps": []any{map[string]any{
+			"toolId": "a:b",
+			"retry":  map[string]any{"maxAttempts": float64(3), "backoff": float64(time.Millisecond), "retryOn": []any{"execution"}},
+		}},
+	}}
+	if resp := serve(context.Background(), tools, req); resp.Type != proxy.MsgResponse {
+		t.Fatalf("serve() = %+v, want a response", resp)
+	}
+	want := &run.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, RetryOn: []run.ErrorCode{run.ErrorCodeExecution}}
+	if len(tools.chains) != 1 || !reflect.DeepEqual(tools.chains[0][0].Retry, want) {
+		t.Errorf("RunChain steps = %+v, want retry %+v", tools.chains, want)
+	}
+}
+
+func TestServe_MatchesProxy(t *testing.T) {
+	ctx := context.Background()
+	for _, req := range []proxy.Message{
+		{Type: proxy.MsgRunTool, ID: "1", Payload: map[string]any{"id": "weather:get", "args": map[string]any{"city": "Oslo"}}},
+		{Type: proxy.MsgRunTool, ID: "2", Payload: map[string]any{"id": "fail:tool"}},
+		{Type: proxy.MsgRunChain, ID: "3", Payload: map[string]any{"steps": []any{map[string]any{"toolId": "a:b"}}}},
+		{Type: proxy.MsgRunChain, ID: "4", Payload: map[string]any{"steps": []any{map[string]any{"toolId": "a:b"}, map[string]any{"toolId": "limit:hit"}}}},
+		{Type: proxy.MsgSearchTools, ID: "5", Payload: map[string]any{"query": "weather"}},
+		{Type: proxy.MsgDescribeTool, ID: "7", Payload: map[string]any{"id": "weather:get", "level": "schema"}},
+		{Type: "format_disk", ID: "6"},
+	} {
+		got := serve(ctx, &fakeTools{}, req)
+		want := proxy.NewGatewayHandler(&fakeTools{}).ServeMessage(ctx, req)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("serve(%s %s) = %+v, want the proxy's %+v", req.Type, req.ID, got, want)
+		}
+	}
+}
+
+func TestEngine_Execute_DescribeToolSchema(t *testing.T) {
+	requirePython(t)
+	src := `__out = tools.describe_tool("weather:get", "schema")["schemaInfo"]["required"]`
+
+	result, err := New(Config{}).Execute(context.Background(), code.ExecuteParams{Code: src}, &fakeTools{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v (stderr: %s)", err, result.Stderr)
+	}
+	if want := []any{"city"}; !reflect.DeepEqual(result.Value, want) {
+		t.Errorf("Value = %#v, want %#v", result.Value, want)
+	}
+}
+
+func TestEngine_Execute_ChainLimitMarker(t *testing.T) {
+	requirePython(t)
+	src := strings.Join([]string{
+		`try:`,
+		`    tools.run_chain([{"toolId": "a:b"}, {"toolId": "limit:hit"}])`,
+		`except ToolError as e:`,
+		`    __out = [s["toolId"] for s in e.step_results] + [e.step_results[-1]["error"]["error"]]`,
+	}, "\n")
+
+	result, err := New(Config{}).Execute(context.Background(), code.ExecuteParams{Code: src}, &fakeTools{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v (stderr: %s)", err, result.Stderr)
+	}
+	out, _ := result.Value.([]any)
+	if len(out) != 3 || out[0] != "a:b" || out[1] != "limit:hit" || !strings.Contains(fmt.Sprint(out[2]), code.ErrLimitExceeded.Error()) {
+		t.Errorf("Value = %#v, want both steps and the limit marker's error", result.Value)
+	}
+}
+
+func TestEngine_Execute_ToolErrorBecomesCodeError(t *testing.T) {
+	requirePython(t)
+	src := "x = 1\ntools.run_tool(\"fail:tool\")\n"
+
+	_, err := New(Config{}).Execute(context.Background(), code.ExecuteParams{Code: src}, &fakeTools{})
+	var codeErr *code.CodeError
+	if !errors.As(err, &codeErr) {
+		t.Fatalf("Execute() error = %v, want *code.CodeError", err)
+	}
+	if !errors.Is(err, code.ErrCodeExecution) {
+		t.Error("error should match code.ErrCodeExecution")
+	}
+	if codeErr.Line != 2 || !strings.Contains(codeErr.Message, "tool exploded") {
+		t.Errorf("CodeError = %+v, want line 2 with tool message", codeErr)
+	}
+}
+
+func TestEngine_Execute_SyntaxError(t *testing.T) {
+	requirePython(t)
+	_, err := New(Config{}).Execute(context.Background(), code.ExecuteParams{Code: "ok = 1\ndef broken(:\n"}, &fakeTools{})
+	var codeErr *code.CodeError
+	if !errors.As(err, &codeErr) || codeErr.Line != 2 {
+		t.Fatalf("Execute() error = %v, want CodeError on line 2", err)
+	}
+}
+
+func TestEngine_Execute_Timeout(t *testing.T) {
+	requirePython(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := New(Config{}).Execute(ctx, code.ExecuteParams{Code: "while True:\n    pass\n"}, &fakeTools{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Execute() error = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestEngine_Execute_LargeStdout(t *testing.T) {
+	requirePython(t)
+	// Output past the protocol's message size arrives in chunks.
+	tools := &fakeTools{}
+	src := fmt.Sprintf("print('x' * %d)", maxMessageSize+1)
+	if _, err := New(Config{}).Execute(context.Background(), code.ExecuteParams{Code: src}, tools); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := tools.stdout.Len(); got != maxMessageSize+2 {
+		t.Errorf("stdout is %d bytes, want %d", got, maxMessageSize+2)
+	}
+
+	// With a cap, the bootstrap keeps one byte past it, so tools still
+	// see the truncation.
+	tools = &fakeTools{}
+	if _, err := New(Config{}).Execute(context.Background(), code.ExecuteParams{Code: src, MaxStdoutBytes: 10}, tools); err != nil {
+		t.Fatalf("Execute() with MaxStdoutBytes error = %v", err)
+	}
+	if got := tools.stdout.String(); got != strings.Repeat("x", 11)+"\n" {
+		t.Errorf("stdout = %q, want 11 bytes", got)
+	}
+}
+
+func TestEngine_Execute_UnsupportedLanguage(t *testing.T) {
+	_, err := New(Config{}).Execute(context.Background(), code.ExecuteParams{Language: "go", Code: "x"}, &fakeTools{})
+	if !errors.Is(err, ErrUnsupportedLanguage) {
+		t.Errorf("Execute() error = %v, want ErrUnsupportedLanguage", err)
+	}
+}
+
+func TestEngine_Execute_MissingInterpreter(t *testing.T) {
+	_, err := New(Config{Interpreter: "definitely-not-python"}).Execute(context.Background(), code.ExecuteParams{Code: "x"}, &fakeTools{})
+	if err == nil {
+		t.Fatal("Execute() error = nil, want start failure")
+	}
+}
+
+func TestEngine_Execute_Determinism(t *testing.T) {
+	requirePython(t)
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	params := code.ExecuteParams{
+		Language: "python",
+		Code: strings.Join([]string{
+			`import random, time, datetime, os, uuid, secrets`,
+			`__out = [time.time(), datetime.datetime.now(datetime.timezone.utc).isoformat(), random.random(),`,
+			`    time.monotonic() - time.monotonic(), time.perf_counter(), os.urandom(4).hex(),`,
+			`    random.SystemRandom().random(), str(uuid.uuid4()), secrets.token_hex(4)]`,
+		}, "\n"),
+		Determinism: &code.Determinism{Now: now, Seed: 7},
+	}
+	engine := New(Config{})
+
+	first, err := engine.Execute(context.Background(), params, &fakeTools{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v (stderr: %s)", err, first.Stderr)
+	}
+	second, err := engine.Execute(context.Background(), params, &fakeTools{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v (stderr: %s)", err, second.Stderr)
+	}
+	got := first.Value.([]any)
+	if got[0] != float64(now.Unix()) || got[1] != "2026-03-04T05:06:07+00:00" {
+		t.Errorf("clock = %v, want pinned to %v", got[:2], now)
+	}
+	if !reflect.DeepEqual(first.Value, second.Value) {
+		t.Errorf("replay differs: %v vs %v", first.Value, second.Value)
+	}
+}
+
+func TestEngineContract(t *testing.T) {
+	requirePython(t)
+	code.RunEngineContractTests(t, code.EngineContract{
+		NewEngine:   func() code.Engine { return New(Config{}) },
+		Language:    "python",
+		OutCode:     `__out = "contract"`,
+		PrintlnCode: `print("contract")`,
+		RunToolCode: `__out = tools.run_tool("contract:echo", {"msg": "contract"})["msg"]`,
+		LoopCode:    "while True:\n    pass",
+	})
+}
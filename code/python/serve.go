@@ -0,0 +1,67 @@
+package python
+
+import (
+	"context"
+
+	"github.com/jonwraymond/toolexec/code"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
+)
+
+// serve answers one gateway request from the interpreter using tools.
+// Requests are answered by proxy.NewGatewayHandler, so results, chain step
+// results, and errors have the payload shapes proxy.Gateway decodes.
+// Only filtered searches, which the handler does not support, are
+// answered here.
+func serve(ctx context.Context, tools code.Tools, req proxy.Message) proxy.Message {
+	p := req.Payload
+	namespaces, tags := getStrings(p, "namespaces"), getStrings(p, "tags")
+	if req.Type != proxy.MsgSearchTools || len(namespaces) == 0 && len(tags) == 0 {
+		return proxy.NewGatewayHandler(tools).ServeMessage(ctx, req)
+	}
+
+	summaries, err := tools.SearchToolsFiltered(ctx, getString(p, "query"), code.SearchFilter{
+		Namespaces: namespaces,
+		Tags:       tags,
+		Limit:      getInt(p, "limit"),
+	})
+	if err != nil {
+		return proxy.ErrorResponse(req.ID, err)
+	}
+	results := make([]any, len(summaries))
+	for i, s := range summaries {
+		results[i] = map[string]any{
+			"id":               s.ID,
+			"name":             s.Name,
+			"namespace":        s.Namespace,
+			"shortDescription": s.ShortDescription,
+			"tags":             s.Tags,
+		}
+	}
+	return proxy.Message{Type: proxy.MsgResponse, ID: req.ID, Payload: map[string]any{"results": results}}
+}
+
+func getString(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+func getStrings(m map[string]any, key string) []string {
+	items, _ := m[key].([]any)
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func getInt(m map[string]any, key string) int {
+	switch v := m[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
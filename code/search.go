@@ -0,0 +1,74 @@
+package code
+
+import (
+	"context"
+	"slices"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+)
+
+// searchPageSize is the page size used when scanning the index for
+// SearchToolsFiltered.
+const searchPageSize = 50
+
+// SearchFilter scopes SearchToolsFiltered results.
+type SearchFilter struct {
+	// Namespaces restricts results to tools in any of these namespaces.
+	// Empty allows every namespace.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Tags restricts results to tools carrying every one of these tags.
+	// Empty allows any tags.
+	Tags []string `json:"tags,omitempty"`
+
+	// Limit is the maximum number of results. Zero uses the same default
+	// as SearchTools callers typically pass (10).
+	Limit int `json:"limit,omitempty"`
+}
+
+// matches reports whether s satisfies the filter.
+func (f SearchFilter) matches(s index.Summary) bool {
+	if len(f.Namespaces) > 0 && !slices.Contains(f.Namespaces, s.Namespace) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !slices.Contains(s.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *toolsImpl) SearchToolsFiltered(ctx context.Context, query string, filter SearchFilter) ([]index.Summary, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	var (
+		out    []index.Summary
+		cursor string
+	)
+	// Page through the ranked results so filtering never truncates
+	// matches that rank below unrelated tools.
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, next, err := t.index.SearchPage(query, searchPageSize, cursor)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range page {
+			if filter.matches(s) {
+				out = append(out, s)
+				if len(out) == limit {
+					return out, nil
+				}
+			}
+		}
+		if next == "" || len(page) == 0 {
+			return out, nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,49 @@
+package code
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+)
+
+func TestTools_SearchToolsFiltered(t *testing.T) {
+	idx := &mockIndex{
+		searchResult: []index.Summary{
+			{ID: "web:fetch", Namespace: "web", Tags: []string{"http", "read"}},
+			{ID: "math:add", Namespace: "math", Tags: []string{"pure"}},
+			{ID: "web:post", Namespace: "web", Tags: []string{"http", "write"}},
+			{ID: "comms:email", Namespace: "comms", Tags: []string{"write"}},
+		},
+	}
+	tools := newTools(&Config{Index: idx, Docs: &mockStore{}, Run: &mockRunner{}, Engine: &mockEngine{}}, 0, 0)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		filter SearchFilter
+		want   []string
+	}{
+		{name: "namespace", filter: SearchFilter{Namespaces: []string{"web"}}, want: []string{"web:fetch", "web:post"}},
+		{name: "tags all required", filter: SearchFilter{Tags: []string{"http", "write"}}, want: []string{"web:post"}},
+		{name: "namespaces and tags", filter: SearchFilter{Namespaces: []string{"web", "comms"}, Tags: []string{"write"}}, want: []string{"web:post", "comms:email"}},
+		{name: "limit", filter: SearchFilter{Tags: []string{"write"}, Limit: 1}, want: []string{"web:post"}},
+		{name: "no filter", filter: SearchFilter{}, want: []string{"web:fetch", "math:add", "web:post", "comms:email"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tools.SearchToolsFiltered(ctx, "q", tt.filter)
+			if err != nil {
+				t.Fatalf("SearchToolsFiltered() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d results %v, want %v", len(got), got, tt.want)
+			}
+			for i, id := range tt.want {
+				if got[i].ID != id {
+					t.Errorf("result %d = %s, want %s", i, got[i].ID, id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrSessionNotFound is returned by CloseSession when no live session has
+// the given ID.
+var ErrSessionNotFound = errors.New("code: session not found")
+
+// DefaultSessionTTL is how long an idle session is retained when
+// Config.SessionTTL is zero.
+const DefaultSessionTTL = 30 * time.Minute
+
+// DefaultMaxSessionResults is how many results a session keeps when
+// Config.MaxSessionResults is zero.
+const DefaultMaxSessionResults = 100
+
+// Session is state retained across executions that share an
+// ExecuteParams.SessionID, enabling REPL-style agent loops.
+//
+// Executions within a session are serialized. Engines reach the session
+// through SessionFromContext and may keep interpreter state in it via
+// SetEngineState; engines that cannot retain interpreter state can still
+// persist values with Set and read prior results with Results.
+type Session struct {
+	id string
+
+	// run holds a token while an execution runs, serializing executions
+	// within the session.
+	run chan struct{}
+
+	mu          sync.Mutex
+	vars        map[string]any
+	results     []ExecuteResult
+	maxResults  int
+	engineState any
+	lastUsed    time.Time
+}
+
+func newSession(id string, now time.Time, maxResults int) *Session {
+	return &Session{id: id, run: make(chan struct{}, 1), vars: make(map[string]any), maxResults: maxResults, lastUsed: now}
+}
+
+// lock waits for the session's in-flight execution to finish, returning
+// ctx.Err() if ctx ends first.
+func (s *Session) lock(ctx context.Context) error {
+	select {
+	case s.run <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// unlock lets the next execution in the session run.
+func (s *Session) unlock() {
+	<-s.run
+}
+
+// ID returns the session identifier.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Get returns the value stored under key.
+func (s *Session) Get(key string) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.vars[key]
+	return v, ok
+}
+
+// Set stores value under key for later executions in the session.
+func (s *Session) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vars[key] = value
+}
+
+// Vars returns a snapshot of all stored values.
+func (s *Session) Vars() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.vars)
+}
+
+// Results returns the results of prior executions in the session, oldest
+// first. Only the most recent Config.MaxSessionResults are kept.
+func (s *Session) Results() []ExecuteResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ExecuteResult(nil), s.results...)
+}
+
+// EngineState returns the engine-owned state stored with SetEngineState.
+func (s *Session) EngineState() any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.engineState
+}
+
+// SetEngineState stores engine-owned state, such as a live interpreter.
+// If the state implements io.Closer it is closed when the session ends.
+func (s *Session) SetEngineState(state any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.engineState = state
+}
+
+func (s *Session) record(result ExecuteResult, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if over := len(s.results) + 1 - s.maxResults; over > 0 {
+		s.results = slices.Delete(s.results, 0, over)
+	}
+	s.results = append(s.results, result)
+	s.lastUsed = now
+}
+
+func (s *Session) touch(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastUsed = now
+}
+
+// expired reports whether the session has sat idle for longer than ttl.
+// A session with an execution in flight is never idle.
+func (s *Session) expired(now time.Time, ttl time.Duration) bool {
+	if len(s.run) > 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return now.Sub(s.lastUsed) > ttl
+}
+
+// close releases engine-owned state.
+func (s *Session) close() {
+	s.mu.Lock()
+	state := s.engineState
+	s.engineState = nil
+	s.mu.Unlock()
+	if c, ok := state.(io.Closer); ok {
+		_ = c.Close()
+	}
+}
+
+type sessionKey struct{}
+
+// withSession returns a context carrying s.
+func withSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// SessionFromContext returns the session of the execution running with ctx,
+// if the execution was started with an ExecuteParams.SessionID.
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(sessionKey{}).(*Session)
+	return s, ok
+}
+
+// sessionStore holds live sessions and expires idle ones lazily.
+type sessionStore struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxResults int
+	sessions   map[string]*Session
+}
+
+func newSessionStore(ttl time.Duration, maxResults int) *sessionStore {
+	return &sessionStore{ttl: ttl, maxResults: maxResults, sessions: make(map[string]*Session)}
+}
+
+// acquire returns the session for id, creating it if needed, after
+// sweeping sessions idle for longer than the TTL. Acquiring a session
+// counts as using it.
+func (st *sessionStore) acquire(id string) *Session {
+	now := time.Now()
+	st.mu.Lock()
+	var expired []*Session
+	for key, s := range st.sessions {
+		if s.expired(now, st.ttl) {
+			expired = append(expired, s)
+			delete(st.sessions, key)
+		}
+	}
+	s, ok := st.sessions[id]
+	if ok {
+		s.touch(now)
+	} else {
+		s = newSession(id, now, st.maxResults)
+		st.sessions[id] = s
+	}
+	st.mu.Unlock()
+
+	for _, e := range expired {
+		e.close()
+	}
+	return s
+}
+
+// remove deletes and closes the session for id once its in-flight
+// execution, if any, finishes.
+func (st *sessionStore) remove(ctx context.Context, id string) error {
+	st.mu.Lock()
+	s, ok := st.sessions[id]
+	st.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	st.mu.Lock()
+	if st.sessions[id] == s {
+		delete(st.sessions, id)
+	}
+	st.mu.Unlock()
+	s.close()
+	return nil
+}
+
+// CloseSession ends the session with the given ID, releasing any engine
+// state. It waits for an in-flight execution in the session to finish,
+// returning ctx.Err() if ctx ends first.
+// Returns ErrSessionNotFound if the session does not exist or has expired.
+func (e *DefaultExecutor) CloseSession(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return e.sessions.remove(ctx, id)
+}
@@ -0,0 +1,163 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// engineFunc adapts a function into an Engine.
+type engineFunc func(ctx context.Context, params ExecuteParams, tools Tools) (ExecuteResult, error)
+
+func (f engineFunc) Execute(ctx context.Context, params ExecuteParams, tools Tools) (ExecuteResult, error) {
+	return f(ctx, params, tools)
+}
+
+// closerState records whether a session closed its engine state.
+type closerState struct{ closed bool }
+
+func (c *closerState) Close() error {
+	c.closed = true
+	return nil
+}
+
+func newSessionExecutor(t *testing.T, engine Engine, ttl time.Duration) *DefaultExecutor {
+	t.Helper()
+	exec, err := NewDefaultExecutor(Config{
+		Index:      &mockIndex{},
+		Docs:       &mockStore{},
+		Run:        &mockRunner{},
+		Engine:     engine,
+		SessionTTL: ttl,
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+	return exec
+}
+
+func TestExecuteCode_SessionSharesState(t *testing.T) {
+	state := &closerState{}
+	var seen []int
+	engine := engineFunc(func(ctx context.Context, _ ExecuteParams, _ Tools) (ExecuteResult, error) {
+		s, ok := SessionFromContext(ctx)
+		if !ok {
+			return ExecuteResult{}, errors.New("no session")
+		}
+		n, _ := s.Get("n")
+		count, _ := n.(int)
+		s.Set("n", count+1)
+		s.SetEngineState(state)
+		seen = append(seen, len(s.Results()))
+		return ExecuteResult{Value: count + 1}, nil
+	})
+	exec := newSessionExecutor(t, engine, 0)
+	ctx := context.Background()
+
+	for i := 1; i <= 2; i++ {
+		result, err := exec.ExecuteCode(ctx, ExecuteParams{SessionID: "s", Code: "x"})
+		if err != nil {
+			t.Fatalf("ExecuteCode() #%d error = %v", i, err)
+		}
+		if result.Value != i {
+			t.Errorf("Value #%d = %v, want %d", i, result.Value, i)
+		}
+	}
+	if len(seen) != 2 || seen[0] != 0 || seen[1] != 1 {
+		t.Errorf("prior results seen = %v, want [0 1]", seen)
+	}
+
+	if err := exec.CloseSession(ctx, "s"); err != nil {
+		t.Fatalf("CloseSession() error = %v", err)
+	}
+	if !state.closed {
+		t.Error("engine state not closed by CloseSession")
+	}
+	if err := exec.CloseSession(ctx, "s"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("CloseSession() again error = %v, want %v", err, ErrSessionNotFound)
+	}
+}
+
+func TestExecuteCode_SessionTTL(t *testing.T) {
+	engine := engineFunc(func(ctx context.Context, _ ExecuteParams, _ Tools) (ExecuteResult, error) {
+		s, _ := SessionFromContext(ctx)
+		return ExecuteResult{Value: len(s.Results())}, nil
+	})
+	exec := newSessionExecutor(t, engine, 10*time.Millisecond)
+	ctx := context.Background()
+
+	if _, err := exec.ExecuteCode(ctx, ExecuteParams{SessionID: "s", Code: "x"}); err != nil {
+		t.Fatalf("ExecuteCode() error = %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	result, err := exec.ExecuteCode(ctx, ExecuteParams{SessionID: "s", Code: "x"})
+	if err != nil {
+		t.Fatalf("ExecuteCode() error = %v", err)
+	}
+	if result.Value != 0 {
+		t.Errorf("prior results after expiry = %v, want 0", result.Value)
+	}
+}
+
+func TestExecuteCode_NoSession(t *testing.T) {
+	engine := engineFunc(func(ctx context.Context, _ ExecuteParams, _ Tools) (ExecuteResult, error) {
+		_, ok := SessionFromContext(ctx)
+		return ExecuteResult{Value: ok}, nil
+	})
+	exec := newSessionExecutor(t, engine, 0)
+
+	result, err := exec.ExecuteCode(context.Background(), ExecuteParams{Code: "x"})
+	if err != nil {
+		t.Fatalf("ExecuteCode() error = %v", err)
+	}
+	if result.Value != false {
+		t.Error("execution without SessionID saw a session")
+	}
+}
+
+func TestExecuteCode_SessionLockHonorsContext(t *testing.T) {
+	started, finish := make(chan struct{}), make(chan struct{})
+	engine := engineFunc(func(ctx context.Context, params ExecuteParams, _ Tools) (ExecuteResult, error) {
+		if params.Code == "block" {
+			close(started)
+			<-finish
+		}
+		return ExecuteResult{}, nil
+	})
+	exec := newSessionExecutor(t, engine, 0)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := exec.ExecuteCode(context.Background(), ExecuteParams{SessionID: "s", Code: "block"})
+		done <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := exec.ExecuteCode(ctx, ExecuteParams{SessionID: "s", Code: "x"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ExecuteCode() behind a running execution error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := exec.CloseSession(ctx, "s"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CloseSession() behind a running execution error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(finish)
+	if err := <-done; err != nil {
+		t.Fatalf("ExecuteCode() error = %v", err)
+	}
+	if err := exec.CloseSession(context.Background(), "s"); err != nil {
+		t.Errorf("CloseSession() once idle error = %v", err)
+	}
+}
+
+func TestSession_MaxResults(t *testing.T) {
+	s := newSession("s", time.Now(), 2)
+	for i := range 3 {
+		s.record(ExecuteResult{Value: i}, time.Now())
+	}
+	if results := s.Results(); len(results) != 2 || results[0].Value != 1 || results[1].Value != 2 {
+		t.Errorf("Results() = %+v, want the last 2", results)
+	}
+}
@@ -0,0 +1,115 @@
+package code
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jonwraymond/toolexec/run"
+)
+
+// ToolsSnapshot is an immutable copy of the Tools state at a point in an
+// execution: the tool call trace, captured stdout, and the tool call
+// budget consumed so far. It is safe to share between goroutines.
+//
+// Snapshots let agents branch executions, for example to explore several
+// continuations tree-of-thought style: pass the same snapshot as
+// ExecuteParams.Parent to several executions and each starts from the
+// parent's state in isolation.
+type ToolsSnapshot struct {
+	records        []ToolCallRecord
+	stdout         string
+	truncated      bool
+	callCount      int
+	namespaceCalls map[string]int
+	seen           map[string]run.RunResult
+}
+
+// ToolCalls returns a copy of the tool call trace at the snapshot.
+func (s *ToolsSnapshot) ToolCalls() []ToolCallRecord {
+	return slices.Clone(s.records)
+}
+
+// Stdout returns the stdout captured at the snapshot.
+func (s *ToolsSnapshot) Stdout() string {
+	return s.stdout
+}
+
+// CallCount returns the number of tool calls charged against the
+// budget at the snapshot.
+func (s *ToolsSnapshot) CallCount() int {
+	return s.callCount
+}
+
+// SnapshotTools is implemented by Tools that can capture and restore
+// their state. Engines may use it to roll back within an execution; the
+// executor uses it for ExecuteParams.Parent and ExecuteResult.Snapshot.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Ownership: snapshots are immutable; Restore must not alias them.
+type SnapshotTools interface {
+	// Snapshot captures the current state.
+	Snapshot() *ToolsSnapshot
+
+	// Restore replaces the current state with the snapshot's.
+	Restore(snap *ToolsSnapshot)
+}
+
+// Snapshot captures the current state.
+func (t *toolsImpl) Snapshot() *ToolsSnapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &ToolsSnapshot{
+		// Clipping makes appends to either copy reallocate, so the
+		// snapshot and the live trace share the prefix but never alias.
+		records:        slices.Clip(t.toolCalls),
+		stdout:         t.stdout.String(),
+		truncated:      t.truncated,
+		callCount:      t.callCount,
+		namespaceCalls: maps.Clone(t.namespaceCalls),
+		seen:           maps.Clone(t.seen),
+	}
+}
+
+// Restore replaces the current state with the snapshot's. Limits are
+// kept, so calls recorded in the snapshot count against them and stdout
+// beyond the stdout cap is truncated.
+func (t *toolsImpl) Restore(snap *ToolsSnapshot) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.toolCalls = slices.Clip(snap.records)
+	t.stdout = strings.Builder{}
+	t.truncated = snap.truncated
+	if t.maxStdout > 0 && len(snap.stdout) > t.maxStdout {
+		t.stdout.WriteString(snap.stdout[:runeCut(snap.stdout, t.maxStdout)])
+		t.stdout.WriteString(StdoutTruncatedMarker)
+		t.truncated = true
+	} else {
+		t.stdout.WriteString(snap.stdout)
+	}
+	t.callCount = snap.callCount
+	if t.namespaceLimits != nil {
+		t.namespaceCalls = maps.Clone(snap.namespaceCalls)
+		if t.namespaceCalls == nil {
+			t.namespaceCalls = make(map[string]int, len(t.namespaceLimits))
+		}
+	}
+	if t.seen != nil {
+		t.seen = maps.Clone(snap.seen)
+		if t.seen == nil {
+			t.seen = make(map[string]run.RunResult)
+		}
+	}
+}
+
+// redactSnapshot replaces the trace and stdout of snap with their
+// redacted forms from result and drops deduplicated results, so secrets
+// never leak into a branch.
+func redactSnapshot(snap *ToolsSnapshot, result ExecuteResult) {
+	snap.records = slices.Clip(result.ToolCalls)
+	snap.stdout = result.Stdout
+	snap.seen = nil
+}
+
+var _ SnapshotTools = (*toolsImpl)(nil)
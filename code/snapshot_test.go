@@ -0,0 +1,154 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jonwraymond/toolexec/run"
+)
+
+func TestTools_SnapshotRestore(t *testing.T) {
+	tools := newTools(&Config{Run: &mockRunner{}}, 0, 0)
+	ctx := context.Background()
+
+	_, _ = tools.RunTool(ctx, "a:one", nil)
+	tools.Println("one")
+	snap := tools.Snapshot()
+
+	_, _ = tools.RunTool(ctx, "a:two", nil)
+	tools.Println("two")
+	if calls := snap.ToolCalls(); len(calls) != 1 || calls[0].ToolID != "a:one" {
+		t.Errorf("snapshot ToolCalls = %+v, want only a:one", calls)
+	}
+	if snap.Stdout() != "one\n" || snap.CallCount() != 1 {
+		t.Errorf("snapshot = %q/%d, want one\\n/1", snap.Stdout(), snap.CallCount())
+	}
+
+	tools.Restore(snap)
+	_, _ = tools.RunTool(ctx, "a:three", nil)
+	calls := tools.GetToolCalls()
+	if len(calls) != 2 || calls[1].ToolID != "a:three" {
+		t.Errorf("restored ToolCalls = %+v, want a:one then a:three", calls)
+	}
+	if got := tools.GetStdout(); got != "one\n" {
+		t.Errorf("restored stdout = %q, want one\\n", got)
+	}
+	if got := snap.ToolCalls(); len(got) != 1 {
+		t.Errorf("snapshot mutated by restored tools: %+v", got)
+	}
+}
+
+func TestExecuteCode_BranchFromParent(t *testing.T) {
+	runner := &mockRunner{runResult: run.RunResult{Structured: "ok"}}
+	engine := engineFunc(func(ctx context.Context, params ExecuteParams, tools Tools) (ExecuteResult, error) {
+		tools.Println(params.Code)
+		_, err := tools.RunTool(ctx, "t:"+params.Code, nil)
+		return ExecuteResult{}, err
+	})
+	exec, err := NewDefaultExecutor(Config{
+		Index:        &mockIndex{},
+		Docs:         &mockStore{},
+		Run:          runner,
+		Engine:       engine,
+		MaxToolCalls: 2,
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+	ctx := context.Background()
+
+	parent, err := exec.ExecuteCode(ctx, ExecuteParams{Code: "root"})
+	if err != nil {
+		t.Fatalf("ExecuteCode(root) error = %v", err)
+	}
+	if parent.Snapshot == nil {
+		t.Fatal("Snapshot = nil")
+	}
+
+	for _, branch := range []string{"left", "right"} {
+		result, err := exec.ExecuteCode(ctx, ExecuteParams{Code: branch, Parent: parent.Snapshot})
+		if err != nil {
+			t.Fatalf("ExecuteCode(%s) error = %v", branch, err)
+		}
+		if len(result.ToolCalls) != 2 || result.ToolCalls[0].ToolID != "t:root" || result.ToolCalls[1].ToolID != "t:"+branch {
+			t.Errorf("%s ToolCalls = %+v, want t:root then t:%s", branch, result.ToolCalls, branch)
+		}
+		if want := "root\n" + branch + "\n"; result.Stdout != want {
+			t.Errorf("%s Stdout = %q, want %q", branch, result.Stdout, want)
+		}
+
+		// The parent's call counts against the limit of 2.
+		_, err = exec.ExecuteCode(ctx, ExecuteParams{Code: "deeper", Parent: result.Snapshot})
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("ExecuteCode(deeper) error = %v, want %v", err, ErrLimitExceeded)
+		}
+	}
+}
+
+func TestExecuteCode_SnapshotRedactsSecrets(t *testing.T) {
+	engine := engineFunc(func(_ context.Context, params ExecuteParams, tools Tools) (ExecuteResult, error) {
+		tools.Println("token", params.Env["TOKEN"])
+		return ExecuteResult{}, nil
+	})
+	exec, err := NewDefaultExecutor(Config{
+		Index:  &mockIndex{},
+		Docs:   &mockStore{},
+		Run:    &mockRunner{},
+		Engine: engine,
+		SecretResolver: SecretResolverFunc(func(context.Context, string) (string, error) {
+			return "s3cret", nil
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+
+	result, err := exec.ExecuteCode(context.Background(), ExecuteParams{
+		Code:    "x",
+		Secrets: map[string]string{"TOKEN": "vault://token"},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteCode() error = %v", err)
+	}
+	if got := result.Snapshot.Stdout(); containsStr(got, "s3cret") {
+		t.Errorf("snapshot stdout = %q, leaks the secret", got)
+	}
+}
+
+func TestExecuteCode_BranchTruncatesParentStdout(t *testing.T) {
+	engine := engineFunc(func(_ context.Context, params ExecuteParams, tools Tools) (ExecuteResult, error) {
+		tools.Println(params.Code)
+		return ExecuteResult{}, nil
+	})
+	exec, err := NewDefaultExecutor(Config{
+		Index:  &mockIndex{},
+		Docs:   &mockStore{},
+		Run:    &mockRunner{},
+		Engine: engine,
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+	ctx := context.Background()
+
+	parent, err := exec.ExecuteCode(ctx, ExecuteParams{Code: "0123456789abcdé"})
+	if err != nil {
+		t.Fatalf("ExecuteCode(parent) error = %v", err)
+	}
+
+	for _, limit := range []int{4, 15} {
+		result, err := exec.ExecuteCode(ctx, ExecuteParams{Code: "child", Parent: parent.Snapshot, MaxStdoutBytes: limit})
+		if err != nil {
+			t.Fatalf("ExecuteCode(child, %d) error = %v", limit, err)
+		}
+		// A limit of 15 falls inside é, so the cut backs off to 14.
+		want := "0123"
+		if limit == 15 {
+			want = "0123456789abcd"
+		}
+		if want += StdoutTruncatedMarker; result.Stdout != want || !result.Truncated {
+			t.Errorf("limit %d: Stdout = %q, Truncated = %v, want %q, true", limit, result.Stdout, result.Truncated, want)
+		}
+	}
+}
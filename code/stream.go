@@ -0,0 +1,123 @@
+package code
+
+import (
+	"context"
+	"strings"
+)
+
+// CodeEventKind identifies the type of a streamed code execution event.
+type CodeEventKind string
+
+const (
+	// CodeEventStdout carries one line written via Println.
+	CodeEventStdout CodeEventKind = "stdout"
+
+	// CodeEventToolCallStarted is emitted before a tool is invoked.
+	// For RunChain it is emitted for every step up front; steps that never
+	// run because an earlier step failed get no finished event.
+	CodeEventToolCallStarted CodeEventKind = "tool_call_started"
+
+	// CodeEventToolCallFinished is emitted after a tool call completes,
+	// successfully or not.
+	CodeEventToolCallFinished CodeEventKind = "tool_call_finished"
+
+	// CodeEventPartial carries an intermediate value reported by the engine
+	// via ReportPartial.
+	CodeEventPartial CodeEventKind = "partial"
+
+	// CodeEventDone indicates the execution completed successfully.
+	// It is always the final event of a successful execution.
+	CodeEventDone CodeEventKind = "done"
+
+	// CodeEventError indicates the execution failed.
+	// It is always the final event of a failed execution.
+	CodeEventError CodeEventKind = "error"
+)
+
+// CodeEvent is an incremental event from ExecuteCodeStream.
+type CodeEvent struct {
+	// Kind indicates the type of event.
+	Kind CodeEventKind `json:"kind"`
+
+	// Line is the stdout line for CodeEventStdout, without the trailing newline.
+	Line string `json:"line,omitempty"`
+
+	// ToolID is the tool being called for tool call events.
+	ToolID string `json:"toolId,omitempty"`
+
+	// ToolCall is the completed trace record for CodeEventToolCallFinished.
+	ToolCall *ToolCallRecord `json:"toolCall,omitempty"`
+
+	// Value is the intermediate value for CodeEventPartial.
+	Value any `json:"value,omitempty"`
+
+	// Result is the final result for CodeEventDone and CodeEventError.
+	Result *ExecuteResult `json:"result,omitempty"`
+
+	// Err is set when Kind is CodeEventError.
+	// Not serialized to JSON.
+	Err error `json:"-"`
+}
+
+// eventSink receives streamed events; nil when not streaming.
+type eventSink func(CodeEvent)
+
+type eventSinkKey struct{}
+
+// ReportPartial publishes an intermediate value from a running snippet.
+// Engines call it with the context passed to Execute; it is a no-op unless
+// the execution was started with ExecuteCodeStream.
+func ReportPartial(ctx context.Context, value any) {
+	if emit, ok := ctx.Value(eventSinkKey{}).(eventSink); ok {
+		emit(CodeEvent{Kind: CodeEventPartial, Value: value})
+	}
+}
+
+// emitStdout splits printed text into one event per line.
+func (s eventSink) emitStdout(text string) {
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		s(CodeEvent{Kind: CodeEventStdout, Line: line})
+	}
+}
+
+// ExecuteCodeStream runs a code snippet like ExecuteCode but delivers
+// stdout lines, tool call start/finish, and partial values as they happen.
+// The final event is CodeEventDone or CodeEventError carrying the same
+// ExecuteResult ExecuteCode would return, after which the channel is closed.
+//
+// The execution waits for the caller to receive each event; canceling ctx
+// stops delivery and aborts the execution, and the terminal event is then
+// delivered only if the channel has buffer space.
+func (e *DefaultExecutor) ExecuteCodeStream(ctx context.Context, params ExecuteParams) (<-chan CodeEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	events := make(chan CodeEvent, 16)
+	emit := eventSink(func(ev CodeEvent) {
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+		}
+	})
+
+	go func() {
+		defer close(events)
+		result, err := e.execute(ctx, params, emit)
+		final := CodeEvent{Kind: CodeEventDone, Result: &result}
+		if err != nil {
+			final.Kind, final.Err = CodeEventError, err
+		}
+		select {
+		case events <- final:
+		case <-ctx.Done():
+			// The caller may have stopped receiving; deliver only if
+			// buffer space remains.
+			select {
+			case events <- final:
+			default:
+			}
+		}
+	}()
+	return events, nil
+}
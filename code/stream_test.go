@@ -0,0 +1,94 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jonwraymond/toolexec/run"
+)
+
+func collectEvents(t *testing.T, ch <-chan CodeEvent) []CodeEvent {
+	t.Helper()
+	var events []CodeEvent
+	for ev := range ch {
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestExecuteCodeStream_Events(t *testing.T) {
+	engine := engineFunc(func(ctx context.Context, _ ExecuteParams, tools Tools) (ExecuteResult, error) {
+		tools.Println("hello\nworld")
+		ReportPartial(ctx, 1)
+		if _, err := tools.RunTool(ctx, "ns:tool", nil); err != nil {
+			return ExecuteResult{}, err
+		}
+		return ExecuteResult{Value: "final"}, nil
+	})
+	exec, err := NewDefaultExecutor(Config{
+		Index:  &mockIndex{},
+		Docs:   &mockStore{},
+		Run:    &mockRunner{runResult: run.RunResult{Structured: "ok"}},
+		Engine: engine,
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+
+	ch, err := exec.ExecuteCodeStream(context.Background(), ExecuteParams{Code: "x"})
+	if err != nil {
+		t.Fatalf("ExecuteCodeStream() error = %v", err)
+	}
+	events := collectEvents(t, ch)
+
+	want := []CodeEventKind{
+		CodeEventStdout, CodeEventStdout, CodeEventPartial,
+		CodeEventToolCallStarted, CodeEventToolCallFinished, CodeEventDone,
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events %+v, want %d", len(events), events, len(want))
+	}
+	for i, kind := range want {
+		if events[i].Kind != kind {
+			t.Errorf("event %d kind = %s, want %s", i, events[i].Kind, kind)
+		}
+	}
+	if events[1].Line != "world" {
+		t.Errorf("second stdout line = %q, want %q", events[1].Line, "world")
+	}
+	if rec := events[4].ToolCall; rec == nil || rec.Structured != "ok" {
+		t.Errorf("finished ToolCall = %+v", rec)
+	}
+	done := events[5].Result
+	if done == nil || done.Value != "final" || done.Stdout != "hello\nworld\n" || len(done.ToolCalls) != 1 {
+		t.Errorf("done Result = %+v", done)
+	}
+}
+
+func TestExecuteCodeStream_Error(t *testing.T) {
+	boom := errors.New("boom")
+	exec, err := NewDefaultExecutor(Config{
+		Index:  &mockIndex{},
+		Docs:   &mockStore{},
+		Run:    &mockRunner{},
+		Engine: &mockEngine{executeErr: boom},
+	})
+	if err != nil {
+		t.Fatalf("NewDefaultExecutor() error = %v", err)
+	}
+
+	ch, err := exec.ExecuteCodeStream(context.Background(), ExecuteParams{Code: "x"})
+	if err != nil {
+		t.Fatalf("ExecuteCodeStream() error = %v", err)
+	}
+	events := collectEvents(t, ch)
+	if len(events) != 1 || events[0].Kind != CodeEventError || !errors.Is(events[0].Err, boom) {
+		t.Errorf("events = %+v, want single error event", events)
+	}
+}
+
+func TestReportPartial_NoStream(t *testing.T) {
+	// Must not panic when no stream is attached.
+	ReportPartial(context.Background(), 1)
+}
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/jonwraymond/tooldiscovery/index"
 	"github.com/jonwraymond/tooldiscovery/tooldoc"
@@ -27,6 +29,10 @@ type Tools interface {
 	// SearchTools searches for tools matching the query, returning up to limit results.
 	SearchTools(ctx context.Context, query string, limit int) ([]index.Summary, error)
 
+	// SearchToolsFiltered searches for tools matching the query, returning
+	// only those that satisfy filter, up to filter.Limit results.
+	SearchToolsFiltered(ctx context.Context, query string, filter SearchFilter) ([]index.Summary, error)
+
 	// ListNamespaces returns all available tool namespaces.
 	ListNamespaces(ctx context.Context) ([]string, error)
 
@@ -49,31 +55,73 @@ type Tools interface {
 	Println(args ...any)
 }
 
+// StdoutTruncatedMarker is appended to stdout when it exceeds the
+// MaxStdoutBytes limit.
+const StdoutTruncatedMarker = "\n...[stdout truncated]\n"
+
 // toolsImpl is the internal implementation of Tools that tracks tool calls
-// and enforces limits.
+// and enforces limits. It is safe for concurrent use, so engines may call
+// it from multiple goroutines: mu guards the trace, stdout, counters, and
+// dedup cache, while tool execution itself runs unlocked.
 type toolsImpl struct {
-	index         index.Index
-	docs          tooldoc.Store
-	runner        run.Runner
-	logger        Logger
+	index  index.Index
+	docs   tooldoc.Store
+	runner run.Executor
+	logger Logger
+
+	// executionID is stamped on tool call records and log entries.
+	executionID string
+
+	mu            sync.Mutex
 	toolCalls     []ToolCallRecord
 	stdout        strings.Builder
 	maxToolCalls  int
 	maxChainSteps int
 	callCount     int
+
+	// maxStdout caps stdout bytes (zero is unlimited); truncated is set
+	// once output has been cut off.
+	maxStdout int
+	truncated bool
+
+	// namespaceLimits holds per-namespace call limits; namespaceCalls
+	// counts calls charged to those namespaces.
+	namespaceLimits map[string]int
+	namespaceCalls  map[string]int
+
+	// emit receives streamed events when executing via
+	// ExecuteCodeStream; nil otherwise.
+	emit eventSink
+
+	// partialChains runs the steps of a limit-blocked chain that fit
+	// within the limits instead of rejecting the whole chain.
+	partialChains bool
+
+	// seen caches successful RunTool results by run.CallKey when
+	// deduplication is enabled; nil otherwise.
+	seen map[string]run.RunResult
 }
 
 // newTools creates a new Tools implementation with the given configuration
 // and limits. If a limit is 0, it is treated as unlimited.
 func newTools(cfg *Config, maxToolCalls int, maxChainSteps int) *toolsImpl {
-	return &toolsImpl{
+	t := &toolsImpl{
 		index:         cfg.Index,
 		docs:          cfg.Docs,
 		runner:        cfg.Run,
 		logger:        cfg.Logger,
 		maxToolCalls:  maxToolCalls,
 		maxChainSteps: maxChainSteps,
+		partialChains: cfg.PartialChains,
+	}
+	if len(cfg.NamespaceToolCalls) > 0 {
+		t.namespaceLimits = cfg.NamespaceToolCalls
+		t.namespaceCalls = make(map[string]int, len(cfg.NamespaceToolCalls))
+	}
+	if cfg.DedupToolCalls {
+		t.seen = make(map[string]run.RunResult)
 	}
+	return t
 }
 
 func (t *toolsImpl) SearchTools(ctx context.Context, query string, limit int) ([]index.Summary, error) {
@@ -105,12 +153,36 @@ func (t *toolsImpl) ListToolExamples(ctx context.Context, id string, maxExamples
 }
 
 func (t *toolsImpl) RunTool(ctx context.Context, id string, args map[string]any) (run.RunResult, error) {
-	if t.maxToolCalls > 0 && t.callCount >= t.maxToolCalls {
-		return run.RunResult{}, fmt.Errorf("%w: max tool calls (%d) exceeded",
-			ErrLimitExceeded, t.maxToolCalls)
+	key, keyOK := "", false
+	if t.seen != nil {
+		key, keyOK = run.CallKey(id, args)
+	}
+
+	// Dedup lookup, budget check, and charge happen atomically so
+	// concurrent calls cannot overrun a limit.
+	t.mu.Lock()
+	if cached, hit := t.seen[key]; keyOK && hit {
+		t.mu.Unlock()
+		t.record(ToolCallRecord{
+			ToolID:       id,
+			Args:         deepCopyArgs(args),
+			Structured:   cached.Structured,
+			Contents:     cached.Contents,
+			BackendKind:  string(cached.Backend.Kind),
+			StartedAt:    time.Now(),
+			Deduplicated: true,
+		}, nil)
+		return cached, nil
+	}
+	if err := t.checkBudget([]string{id}); err != nil {
+		t.mu.Unlock()
+		t.limitExceeded(id, err)
+		return run.RunResult{}, err
 	}
-	t.callCount++
+	t.charge(id)
+	t.mu.Unlock()
 
+	t.started(id)
 	start := time.Now()
 	result, err := t.runner.Run(ctx, id, args)
 	duration := time.Since(start).Milliseconds()
@@ -118,6 +190,7 @@ func (t *toolsImpl) RunTool(ctx context.Context, id string, args map[string]any)
 	record := ToolCallRecord{
 		ToolID:     id,
 		Args:       deepCopyArgs(args),
+		StartedAt:  start,
 		DurationMs: duration,
 	}
 	if err != nil {
@@ -129,28 +202,93 @@ func (t *toolsImpl) RunTool(ctx context.Context, id string, args map[string]any)
 		}
 	} else {
 		record.Structured = result.Structured
+		record.Contents = result.Contents
 		record.BackendKind = string(result.Backend.Kind)
+		if keyOK {
+			t.mu.Lock()
+			t.seen[key] = result
+			t.mu.Unlock()
+		}
 	}
-	t.toolCalls = append(t.toolCalls, record)
+	t.record(record, err)
 
 	return result, err
 }
 
 func (t *toolsImpl) RunChain(ctx context.Context, steps []run.ChainStep) (run.RunResult, []run.StepResult, error) {
-	if t.maxChainSteps > 0 && len(steps) > t.maxChainSteps {
-		return run.RunResult{}, nil, fmt.Errorf("%w: max chain steps (%d) exceeded (got %d)",
+	n, limitErr := t.reserveChain(steps)
+	if limitErr != nil {
+		t.limitExceeded("", limitErr)
+		if n == 0 {
+			if !t.partialChains {
+				return run.RunResult{}, nil, limitErr
+			}
+			return run.RunResult{}, []run.StepResult{{ToolID: steps[0].ToolID, Err: limitErr}},
+				fmt.Errorf("%w (ran 0 of %d steps)", limitErr, len(steps))
+		}
+	}
+
+	result, stepResults, err := t.runChain(ctx, steps[:n])
+	// A step that failed stopped the chain before the limit did, so its
+	// error is returned without a marker.
+	if limitErr == nil || err != nil {
+		return result, stepResults, err
+	}
+	// Report the steps that ran, then mark where the limit stopped the chain.
+	stepResults = append(stepResults, run.StepResult{ToolID: steps[n].ToolID, Err: limitErr})
+	return result, stepResults, fmt.Errorf("%w (ran %d of %d steps)", limitErr, n, len(steps))
+}
+
+// reserveChain charges the budget for the steps of a chain that will run
+// and returns how many that is. When a limit blocks the chain it returns
+// the limit error, and with partialChains the longest prefix that fits;
+// otherwise nothing is charged.
+func (t *toolsImpl) reserveChain(steps []run.ChainStep) (int, error) {
+	ids := make([]string, len(steps))
+	for i, step := range steps {
+		ids[i] = step.ToolID
+	}
+
+	var limitErr error
+	n := len(ids)
+	if t.maxChainSteps > 0 && n > t.maxChainSteps {
+		limitErr = fmt.Errorf("%w: max chain steps (%d) exceeded (got %d)",
 			ErrLimitExceeded, t.maxChainSteps, len(steps))
+		n = t.maxChainSteps
 	}
 
-	// Check if we have enough room for all steps
-	if t.maxToolCalls > 0 {
-		stepsNeeded := len(steps)
-		if t.callCount+stepsNeeded > t.maxToolCalls {
-			return run.RunResult{}, nil, fmt.Errorf("%w: max tool calls (%d) exceeded (need %d, have %d remaining)",
-				ErrLimitExceeded, t.maxToolCalls, stepsNeeded, t.maxToolCalls-t.callCount)
+	// Budget check and charge happen atomically so concurrent calls
+	// cannot overrun a limit.
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.checkBudget(ids[:n]); err != nil {
+		if limitErr == nil {
+			limitErr = err
 		}
+		for n > 0 && t.checkBudget(ids[:n]) != nil {
+			n--
+		}
+	}
+	if limitErr != nil && !t.partialChains {
+		return 0, limitErr
+	}
+	for _, id := range ids[:n] {
+		t.charge(id)
+	}
+	return n, limitErr
+}
+
+// runChain executes steps whose budget reserveChain has charged, refunding
+// steps that never ran, and records each executed step.
+func (t *toolsImpl) runChain(ctx context.Context, steps []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	ids := make([]string, len(steps))
+	for i, step := range steps {
+		ids[i] = step.ToolID
 	}
 
+	for _, step := range steps {
+		t.started(step.ToolID)
+	}
 	start := time.Now()
 	result, stepResults, err := t.runner.RunChain(ctx, steps)
 	totalDuration := time.Since(start).Milliseconds()
@@ -166,13 +304,19 @@ func (t *toolsImpl) RunChain(ctx context.Context, steps []run.ChainStep) (run.Ru
 	if denom == 0 {
 		denom = 1
 	}
+	if executed < len(steps) {
+		t.mu.Lock()
+		for _, id := range ids[executed:] {
+			t.refund(id)
+		}
+		t.mu.Unlock()
+	}
 
 	// Record each executed step, reconstructing the effective args
 	// (including previous injection) and normalizing to MCP-native shapes.
 	var previous any
 	for i := 0; i < executed; i++ {
 		step := steps[i]
-		t.callCount++
 
 		effectiveArgs := make(map[string]any, len(step.Args)+1)
 		for k, v := range step.Args {
@@ -185,6 +329,7 @@ func (t *toolsImpl) RunChain(ctx context.Context, steps []run.ChainStep) (run.Ru
 		record := ToolCallRecord{
 			ToolID:     step.ToolID,
 			Args:       deepCopyArgs(effectiveArgs),
+			StartedAt:  start.Add(time.Duration(int64(i)*totalDuration/denom) * time.Millisecond),
 			DurationMs: totalDuration / denom,
 		}
 
@@ -200,27 +345,113 @@ func (t *toolsImpl) RunChain(ctx context.Context, steps []run.ChainStep) (run.Ru
 				record.ErrorOp = "chain"
 			} else {
 				record.Structured = sr.Result.Structured
+				record.Contents = sr.Result.Contents
+				record.Deduplicated = sr.Deduplicated
 				previous = sr.Result.Structured
 			}
 		}
 
-		t.toolCalls = append(t.toolCalls, record)
+		var stepErr error
+		if i < len(stepResults) {
+			stepErr = stepResults[i].Err
+		}
+		t.record(record, stepErr)
 	}
 
 	return result, stepResults, err
 }
 
 func (t *toolsImpl) Println(args ...any) {
-	fmt.Fprintln(&t.stdout, args...)
+	line := fmt.Sprintln(args...)
+	t.mu.Lock()
+	if t.truncated {
+		t.mu.Unlock()
+		return
+	}
+	if t.maxStdout > 0 && t.stdout.Len()+len(line) > t.maxStdout {
+		line = line[:runeCut(line, t.maxStdout-t.stdout.Len())]
+		t.stdout.WriteString(line)
+		t.stdout.WriteString(StdoutTruncatedMarker)
+		t.truncated = true
+	} else {
+		t.stdout.WriteString(line)
+	}
+	t.mu.Unlock()
+	if t.emit != nil && line != "" {
+		t.emit.emitStdout(line)
+	}
+}
+
+// runeCut returns the largest length no greater than n at which s can
+// be cut without splitting a rune, so stdout stays valid UTF-8. A
+// non-positive n yields zero.
+func runeCut(s string, n int) int {
+	if n <= 0 {
+		return 0
+	}
+	if n >= len(s) {
+		return len(s)
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return n
+}
+
+// started emits a tool call started event when streaming.
+func (t *toolsImpl) started(toolID string) {
+	if t.emit != nil {
+		t.emit(CodeEvent{Kind: CodeEventToolCallStarted, ToolID: toolID})
+	}
+}
+
+// record appends a tool call to the trace, logs it, and emits it when
+// streaming. err is the call's failure, if any.
+func (t *toolsImpl) record(rec ToolCallRecord, err error) {
+	rec.ExecutionID = t.executionID
+	t.mu.Lock()
+	t.toolCalls = append(t.toolCalls, rec)
+	t.mu.Unlock()
+	if t.emit != nil {
+		t.emit(CodeEvent{Kind: CodeEventToolCallFinished, ToolID: rec.ToolID, ToolCall: &rec})
+	}
+	errCode, errMsg := errorFields(err)
+	logEntry(t.logger, LogEntry{
+		Event:        LogEventToolCall,
+		ExecutionID:  rec.ExecutionID,
+		ToolID:       rec.ToolID,
+		Backend:      rec.BackendKind,
+		DurationMs:   rec.DurationMs,
+		ErrorCode:    errCode,
+		Error:        errMsg,
+		Deduplicated: rec.Deduplicated,
+	})
+}
+
+// limitExceeded logs a call rejected by a limit.
+func (t *toolsImpl) limitExceeded(toolID string, err error) {
+	errCode, errMsg := errorFields(err)
+	logEntry(t.logger, LogEntry{Event: LogEventLimitExceeded, ExecutionID: t.executionID, ToolID: toolID, ErrorCode: errCode, Error: errMsg})
 }
 
 // GetToolCalls returns a copy of all recorded tool calls.
 func (t *toolsImpl) GetToolCalls() []ToolCallRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return append([]ToolCallRecord(nil), t.toolCalls...)
 }
 
+// stdoutTruncated reports whether stdout hit the size limit.
+func (t *toolsImpl) stdoutTruncated() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.truncated
+}
+
 // GetStdout returns the captured stdout output.
 func (t *toolsImpl) GetStdout() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return t.stdout.String()
 }
 
@@ -3,6 +3,9 @@ package code
 import (
 	"context"
 	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/jonwraymond/tooldiscovery/index"
@@ -256,6 +259,80 @@ func TestTools_RunTool_RecordsToolCall(t *testing.T) {
 	}
 }
 
+func TestTools_RunTool_RecordsContents(t *testing.T) {
+	contents := []run.ContentItem{
+		{Kind: run.ContentText, Text: "summary"},
+		{Kind: run.ContentImage, Data: []byte{1, 2}, MIMEType: "image/png"},
+	}
+	runner := &mockRunner{
+		runResult: run.RunResult{
+			Structured: "summary",
+			Contents:   contents,
+			Backend:    model.ToolBackend{Kind: model.BackendKindMCP},
+		},
+	}
+	tools := newTools(&Config{
+		Index:  &mockIndex{},
+		Docs:   &mockStore{},
+		Run:    runner,
+		Engine: &mockEngine{},
+	}, 0, 0)
+
+	if _, err := tools.RunTool(context.Background(), "ns:tool", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records := tools.GetToolCalls()
+	if len(records) != 1 || len(records[0].Contents) != 2 {
+		t.Fatalf("expected 1 record with 2 contents, got %+v", records)
+	}
+	if records[0].Contents[1].Kind != run.ContentImage {
+		t.Errorf("Contents[1].Kind = %q, want %q", records[0].Contents[1].Kind, run.ContentImage)
+	}
+}
+
+func TestTools_RunTool_Dedup(t *testing.T) {
+	runner := &mockRunner{
+		runResult: run.RunResult{
+			Structured: "weather",
+			Backend:    model.ToolBackend{Kind: model.BackendKindLocal},
+		},
+	}
+	tools := newTools(&Config{
+		Index:          &mockIndex{},
+		Docs:           &mockStore{},
+		Run:            runner,
+		Engine:         &mockEngine{},
+		DedupToolCalls: true,
+	}, 2, 0)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		result, err := tools.RunTool(ctx, "ns:weather", map[string]any{"city": "Oslo", "units": "c"})
+		if err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i, err)
+		}
+		if result.Structured != "weather" {
+			t.Errorf("call %d: Structured = %v", i, result.Structured)
+		}
+	}
+	if _, err := tools.RunTool(ctx, "ns:weather", map[string]any{"city": "Rome"}); err != nil {
+		t.Fatalf("distinct call: unexpected error: %v", err)
+	}
+
+	if len(runner.runCalls) != 2 {
+		t.Errorf("runner calls = %d, want 2", len(runner.runCalls))
+	}
+	records := tools.GetToolCalls()
+	if len(records) != 4 {
+		t.Fatalf("records = %d, want 4", len(records))
+	}
+	if records[0].Deduplicated || !records[1].Deduplicated || !records[2].Deduplicated || records[3].Deduplicated {
+		t.Errorf("Deduplicated flags = %v %v %v %v", records[0].Deduplicated,
+			records[1].Deduplicated, records[2].Deduplicated, records[3].Deduplicated)
+	}
+}
+
 func TestDeepCopyArgs_CustomStructPointer(t *testing.T) {
 	input := map[string]any{
 		"custom": &customStruct{
@@ -919,6 +996,118 @@ func TestTools_MaxChainSteps_Enforced(t *testing.T) {
 	}
 }
 
+func TestTools_PartialChains_MaxChainSteps(t *testing.T) {
+	runner := &mockRunner{
+		chainResult: run.RunResult{Structured: "one"},
+		chainSteps:  []run.StepResult{{ToolID: "tool1", Result: run.RunResult{Structured: "one"}}},
+	}
+	tools := newTools(&Config{
+		Index:         &mockIndex{},
+		Docs:          &mockStore{},
+		Run:           runner,
+		Engine:        &mockEngine{},
+		PartialChains: true,
+	}, 0, 1) // Max 1 step per chain
+
+	steps := []run.ChainStep{
+		{ToolID: "tool1"},
+		{ToolID: "tool2"},
+		{ToolID: "tool3"},
+	}
+	result, stepResults, err := tools.RunChain(context.Background(), steps)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected ErrLimitExceeded, got %v", err)
+	}
+	if result.Structured != "one" {
+		t.Errorf("result = %v, want the last executed step's result", result.Structured)
+	}
+	if len(runner.chainCalls) != 1 || len(runner.chainCalls[0]) != 1 {
+		t.Fatalf("runner chain calls = %v, want one call with 1 step", runner.chainCalls)
+	}
+	if len(stepResults) != 2 {
+		t.Fatalf("expected 2 step results (1 executed + marker), got %d", len(stepResults))
+	}
+	if stepResults[0].Err != nil {
+		t.Errorf("executed step error = %v", stepResults[0].Err)
+	}
+	marker := stepResults[1]
+	if marker.ToolID != "tool2" || !errors.Is(marker.Err, ErrLimitExceeded) {
+		t.Errorf("marker = %+v, want tool2 with ErrLimitExceeded", marker)
+	}
+	if calls := tools.GetToolCalls(); len(calls) != 1 {
+		t.Errorf("expected 1 recorded call, got %d", len(calls))
+	}
+}
+
+func TestTools_PartialChains_MaxToolCalls(t *testing.T) {
+	runner := &mockRunner{
+		chainSteps: []run.StepResult{{ToolID: "tool1"}, {ToolID: "tool2"}},
+	}
+	tools := newTools(&Config{
+		Index:         &mockIndex{},
+		Docs:          &mockStore{},
+		Run:           runner,
+		Engine:        &mockEngine{},
+		PartialChains: true,
+	}, 3, 0) // Max 3 tool calls
+
+	ctx := context.Background()
+	if _, err := tools.RunTool(ctx, "tool0", nil); err != nil {
+		t.Fatalf("RunTool() error = %v", err)
+	}
+
+	steps := []run.ChainStep{
+		{ToolID: "tool1"},
+		{ToolID: "tool2"},
+		{ToolID: "tool3"},
+	}
+	_, stepResults, err := tools.RunChain(ctx, steps)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected ErrLimitExceeded, got %v", err)
+	}
+	if len(runner.chainCalls) != 1 || len(runner.chainCalls[0]) != 2 {
+		t.Fatalf("runner chain calls = %v, want one call with 2 steps", runner.chainCalls)
+	}
+	if len(stepResults) != 3 || stepResults[2].ToolID != "tool3" || !errors.Is(stepResults[2].Err, ErrLimitExceeded) {
+		t.Errorf("step results = %+v, want 2 executed and a tool3 marker", stepResults)
+	}
+
+	// The budget is now exhausted, so nothing runs and only the marker
+	// is returned.
+	_, stepResults, err = tools.RunChain(ctx, steps[:2])
+	if !errors.Is(err, ErrLimitExceeded) || !strings.Contains(err.Error(), "ran 0 of 2 steps") {
+		t.Errorf("expected ErrLimitExceeded after 0 of 2 steps, got %v", err)
+	}
+	if len(runner.chainCalls) != 1 {
+		t.Errorf("expected no execution once the budget is exhausted, got %v", runner.chainCalls)
+	}
+	if len(stepResults) != 1 || stepResults[0].ToolID != "tool1" || !errors.Is(stepResults[0].Err, ErrLimitExceeded) {
+		t.Errorf("step results = %+v, want only a tool1 marker", stepResults)
+	}
+}
+
+func TestTools_PartialChains_FailedStepDropsMarker(t *testing.T) {
+	runner := &mockRunner{
+		chainSteps: []run.StepResult{{ToolID: "tool1", Err: errors.New("boom")}},
+		chainErr:   errors.New("boom"),
+	}
+	tools := newTools(&Config{
+		Index:         &mockIndex{},
+		Docs:          &mockStore{},
+		Run:           runner,
+		Engine:        &mockEngine{},
+		PartialChains: true,
+	}, 0, 1)
+
+	_, stepResults, err := tools.RunChain(context.Background(), []run.ChainStep{{ToolID: "tool1"}, {ToolID: "tool2"}})
+	if err == nil || errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("error = %v, want the step's failure", err)
+	}
+	if len(stepResults) != 1 || stepResults[0].ToolID != "tool1" {
+		t.Errorf("step results = %+v, want only the failed tool1", stepResults)
+	}
+}
+
 func TestTools_MaxToolCalls_ZeroIsUnlimited(t *testing.T) {
 	runner := &mockRunner{
 		runResult: run.RunResult{},
@@ -1010,3 +1199,88 @@ func TestTools_RunChain_CountsAgainstMaxToolCalls(t *testing.T) {
 		t.Errorf("expected ErrLimitExceeded, got %v", err)
 	}
 }
+
+func TestTools_NamespaceToolCalls(t *testing.T) {
+	runner := &mockRunner{
+		chainSteps: []run.StepResult{{}, {}},
+	}
+	tools := newTools(&Config{
+		Index:  &mockIndex{},
+		Docs:   &mockStore{},
+		Run:    runner,
+		Engine: &mockEngine{},
+		NamespaceToolCalls: map[string]int{
+			"math":  UnlimitedToolCalls,
+			"comms": 2,
+		},
+	}, 1, 0) // Max 1 call outside listed namespaces
+
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if _, err := tools.RunTool(ctx, "math:add", nil); err != nil {
+			t.Fatalf("math:add #%d error = %v, want unlimited", i+1, err)
+		}
+	}
+	if _, err := tools.RunTool(ctx, "web:fetch", nil); err != nil {
+		t.Fatalf("web:fetch error = %v", err)
+	}
+	if _, err := tools.RunTool(ctx, "web:fetch", nil); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("second web:fetch error = %v, want %v", err, ErrLimitExceeded)
+	}
+
+	if _, _, err := tools.RunChain(ctx, []run.ChainStep{{ToolID: "comms:email"}, {ToolID: "comms:sms"}}); err != nil {
+		t.Fatalf("comms chain error = %v", err)
+	}
+	_, err := tools.RunTool(ctx, "comms:email", nil)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("third comms call error = %v, want %v", err, ErrLimitExceeded)
+	}
+	if !strings.Contains(err.Error(), `namespace "comms"`) {
+		t.Errorf("error %q does not name the namespace", err)
+	}
+}
+
+func TestTools_ConcurrentUse(t *testing.T) {
+	runner := &mockRunner{
+		runResult:  run.RunResult{Structured: "ok"},
+		chainSteps: []run.StepResult{{}, {}},
+	}
+	tools := newTools(&Config{
+		Index:  &mockIndex{},
+		Docs:   &mockStore{},
+		Run:    runner,
+		Engine: &mockEngine{},
+	}, 50, 0)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	var limited atomic.Int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 2; j++ {
+				if _, err := tools.RunTool(ctx, "ns:tool", nil); errors.Is(err, ErrLimitExceeded) {
+					limited.Add(1)
+				}
+				tools.Println("line")
+			}
+			if _, _, err := tools.RunChain(ctx, []run.ChainStep{{ToolID: "a"}, {ToolID: "b"}}); errors.Is(err, ErrLimitExceeded) {
+				limited.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	calls := tools.GetToolCalls()
+	if len(calls) > 50 {
+		t.Errorf("recorded %d tool calls, limit is 50", len(calls))
+	}
+	if limited.Load() == 0 {
+		t.Error("expected some calls to hit the limit")
+	}
+	if got := strings.Count(tools.GetStdout(), "line\n"); got != 40 {
+		t.Errorf("stdout lines = %d, want 40", got)
+	}
+}
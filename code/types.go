@@ -1,6 +1,10 @@
 package code
 
-import "time"
+import (
+	"time"
+
+	"github.com/jonwraymond/toolexec/run"
+)
 
 // ToolCallRecord captures information about a single tool invocation during
 // code execution. It records the tool identifier, arguments, result, and
@@ -9,12 +13,19 @@ type ToolCallRecord struct {
 	// ToolID is the canonical identifier of the tool that was called.
 	ToolID string `json:"toolId"`
 
+	// ExecutionID is the runtime execution ID of the execution that made
+	// the call (see runtime.ExecutionIDFromContext).
+	ExecutionID string `json:"executionId,omitempty"`
+
 	// Args contains the arguments passed to the tool.
 	Args map[string]any `json:"args,omitempty"`
 
 	// Structured contains the structured result from a successful tool execution.
 	Structured any `json:"structured,omitempty"`
 
+	// Contents lists every content item returned by a successful tool execution.
+	Contents []run.ContentItem `json:"contents,omitempty"`
+
 	// BackendKind indicates which backend executed the tool (mcp, provider, local).
 	BackendKind string `json:"backendKind,omitempty"`
 
@@ -24,8 +35,16 @@ type ToolCallRecord struct {
 	// ErrorOp indicates the operation that failed (e.g., "run", "chain").
 	ErrorOp string `json:"errorOp,omitempty"`
 
+	// StartedAt is when the call started. For RunChain steps it is
+	// estimated from the chain start and the average step duration.
+	StartedAt time.Time `json:"startedAt,omitzero"`
+
 	// DurationMs is the execution time in milliseconds.
 	DurationMs int64 `json:"durationMs"`
+
+	// Deduplicated is true when the result was reused from an earlier
+	// identical call instead of executing the tool again.
+	Deduplicated bool `json:"deduplicated,omitempty"`
 }
 
 // ExecuteParams specifies the parameters for executing a code snippet.
@@ -44,6 +63,90 @@ type ExecuteParams struct {
 	// MaxToolCalls limits the number of tool invocations allowed.
 	// If zero, the executor's configured limit applies (or unlimited if none).
 	MaxToolCalls int `json:"maxToolCalls,omitempty"`
+
+	// MaxStdoutBytes caps captured stdout for this execution. If zero, the
+	// executor's configured limit applies (or unlimited if none).
+	MaxStdoutBytes int `json:"maxStdoutBytes,omitempty"`
+
+	// SessionID joins the execution to a persistent session so that
+	// successive snippets share state. If empty, the execution is isolated.
+	SessionID string `json:"sessionId,omitempty"`
+
+	// Env provides environment variables scoped to this execution.
+	// Engines and runtime backends that support environments expose them
+	// to the snippet.
+	Env map[string]string `json:"env,omitempty"`
+
+	// Secrets maps environment variable names to secret references that
+	// Config.SecretResolver resolves at execution time. Resolved values
+	// are merged into Env for the Engine and redacted from the result.
+	Secrets map[string]string `json:"secrets,omitempty"`
+
+	// OutputSchema is an optional JSON Schema the final value must satisfy.
+	// When set, a successful execution's Value is validated and the outcome
+	// is reported in ExecuteResult.Validation.
+	OutputSchema any `json:"outputSchema,omitempty"`
+
+	// Metadata is caller-defined context for the execution. It is part of
+	// the result cache key, so differing metadata never shares a result.
+	Metadata map[string]any `json:"metadata,omitempty"`
+
+	// BypassCache skips Config.ResultCache for this execution: the
+	// snippet always runs and its result is not stored.
+	BypassCache bool `json:"bypassCache,omitempty"`
+
+	// Workspace, if set, gives the snippet a scratch directory for
+	// intermediate files, removed when the execution ends. Engines backed
+	// by a sandbox runtime pass it to the backend; engines without a
+	// filesystem ignore it.
+	Workspace *Workspace `json:"workspace,omitempty"`
+
+	// Determinism, if set, pins the clock and random seed inside the
+	// sandbox for replayable executions. The engine must implement
+	// DeterministicEngine.
+	Determinism *Determinism `json:"determinism,omitempty"`
+
+	// Parent, if set, starts the execution from a snapshot of an earlier
+	// execution's Tools state, taken from ExecuteResult.Snapshot. The
+	// result's trace and stdout then begin with the parent's, and the
+	// parent's tool calls count against this execution's limits.
+	// Executions with a Parent bypass Config.ResultCache.
+	Parent *ToolsSnapshot `json:"-"`
+}
+
+// Workspace describes a per-execution scratch directory. Inside the
+// sandbox its location is in the TOOLEXEC_WORKSPACE environment variable.
+type Workspace struct {
+	// Path is the absolute path of the workspace inside the sandbox.
+	// If empty, the backend default ("/workspace") is used.
+	Path string `json:"path,omitempty"`
+
+	// MaxBytes caps the total size of files in the workspace.
+	// Zero means unlimited, subject to backend defaults.
+	MaxBytes int64 `json:"maxBytes,omitempty"`
+}
+
+// ResourceUsage reports resources consumed by a code execution, as
+// observed by the engine. Callers can use it to tune Limits. Zero fields
+// mean the engine could not measure that resource.
+type ResourceUsage struct {
+	// CPUMillis is user plus system CPU time in milliseconds.
+	CPUMillis int64 `json:"cpuMillis,omitempty"`
+
+	// PeakMemoryBytes is the peak resident memory in bytes.
+	PeakMemoryBytes int64 `json:"peakMemoryBytes,omitempty"`
+
+	// WallTimeMs is the time spent inside the sandbox in milliseconds,
+	// excluding setup such as image pulls.
+	WallTimeMs int64 `json:"wallTimeMs,omitempty"`
+
+	// DiskWriteBytes is the number of bytes written to disk.
+	DiskWriteBytes int64 `json:"diskWriteBytes,omitempty"`
+
+	// NetworkRxBytes and NetworkTxBytes count bytes received and sent,
+	// when the execution had network access.
+	NetworkRxBytes int64 `json:"networkRxBytes,omitempty"`
+	NetworkTxBytes int64 `json:"networkTxBytes,omitempty"`
 }
 
 // ExecuteResult contains the outcome of executing a code snippet.
@@ -58,9 +161,33 @@ type ExecuteResult struct {
 	// Stderr contains any error output from the execution.
 	Stderr string `json:"stderr,omitempty"`
 
+	// Truncated is true when Stdout exceeded the MaxStdoutBytes limit and
+	// was cut off; Stdout then ends with StdoutTruncatedMarker.
+	Truncated bool `json:"truncated,omitempty"`
+
 	// ToolCalls records all tool invocations made during execution.
 	ToolCalls []ToolCallRecord `json:"toolCalls,omitempty"`
 
 	// DurationMs is the total execution time in milliseconds.
 	DurationMs int64 `json:"durationMs"`
+
+	// ResourceUsage reports what the engine observed the snippet consume;
+	// nil when the engine does not measure usage.
+	ResourceUsage *ResourceUsage `json:"resourceUsage,omitempty"`
+
+	// EnvNames lists the names of the environment variables provided to
+	// the execution, including secret-backed ones. Values are never recorded.
+	EnvNames []string `json:"envNames,omitempty"`
+
+	// Validation reports the result of checking Value against
+	// ExecuteParams.OutputSchema; nil when no schema was given.
+	Validation *ValidationReport `json:"validation,omitempty"`
+
+	// Cached is true when the result was served from Config.ResultCache
+	// instead of running the snippet; DurationMs is then zero.
+	Cached bool `json:"cached,omitempty"`
+
+	// Snapshot captures the Tools state at the end of the execution, for
+	// branching further executions with ExecuteParams.Parent.
+	Snapshot *ToolsSnapshot `json:"-"`
 }
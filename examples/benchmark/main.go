@@ -0,0 +1,47 @@
+// Package main benchmarks a runtime backend for capacity planning.
+//
+// This example shows how to:
+// - Measure cold and warm starts, overhead, and streaming latency
+// - Print the backend's capability report
+//
+// It benchmarks the unsafe host backend, which needs the Go toolchain; swap
+// in any configured backend to measure it instead.
+//
+// Run with: go run ./examples/benchmark -n 5
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/unsafe"
+	"github.com/jonwraymond/toolexec/runtime/gateway/direct"
+)
+
+func main() {
+	iterations := flag.Int("n", runtime.DefaultBenchmarkIterations, "warm executions to measure")
+	flag.Parse()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	backend := unsafe.New(unsafe.Config{Mode: unsafe.ModeSubprocess})
+	report, err := runtime.Benchmark(ctx, backend, runtime.BenchmarkSpec{
+		Request: runtime.ExecuteRequest{
+			Code:    "fmt.Println(\"ready\")\n\t__out = \"ok\"",
+			Gateway: direct.New(direct.Config{}),
+			Timeout: time.Minute,
+		},
+		Iterations: *iterations,
+	})
+	if err != nil {
+		log.Fatalf("Benchmark failed: %v", err)
+	}
+	if err := report.WriteText(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
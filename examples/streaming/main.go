@@ -12,6 +12,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -120,6 +121,8 @@ func main() {
 			fmt.Printf("[DONE]\n%v\n", event.Data)
 		case run.StreamEventError:
 			fmt.Printf("[ERROR] %v\n", event.Err)
+		case run.StreamEventCancelled, run.StreamEventTimeout:
+			fmt.Printf("[%s] %+v\n", strings.ToUpper(string(event.Kind)), event.Data)
 		}
 	}
 
@@ -62,6 +62,39 @@
 //	    {ToolID: "ns:tool2", UsePrevious: true}, // receives tool1's result
 //	})
 //
+// # Quotas
+//
+// Rolling-window quotas limit chain and code executions per principal.
+// They are checked before a request is admitted; the principal is taken
+// from the context, and requests without one share a single allowance:
+//
+//	executor, err := exec.New(exec.Options{
+//	    Index: idx,
+//	    Docs:  docs,
+//	    Quotas: []exec.Quota{
+//	        {Kind: exec.QuotaChainExecutions, Limit: 100, Window: time.Hour},
+//	        {Kind: exec.QuotaSandboxSeconds, Limit: 600, Window: 24 * time.Hour},
+//	    },
+//	})
+//	ctx = exec.WithPrincipal(ctx, "user-123")
+//	status, _ := executor.QuotaStatus(ctx, "user-123") // remaining allowance
+//
+// Usage is tracked by a pluggable QuotaStore (in-memory by default), whose
+// Reserve checks and records a request's usage atomically. Sandbox seconds
+// are committed to the reservation once RunCode returns, even when its
+// context was cancelled, so a run admitted under the limit may end past it.
+//
+// # Execution-Only Deployments
+//
+// Set DegradeDocs to run without a documentation store. GetToolDoc then
+// returns minimal documentation derived from the indexed tool definition,
+// and the same fallback is used when a configured store returns an error:
+//
+//	executor, err := exec.New(exec.Options{
+//	    Index:       idx,
+//	    DegradeDocs: true,
+//	})
+//
 // # Integration
 //
 // The exec package integrates with:
@@ -2,11 +2,14 @@ package exec
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/jonwraymond/tooldiscovery/index"
 	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/code"
 	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
 )
 
 // Exec is the unified facade for tool execution.
@@ -38,6 +41,9 @@ func New(opts Options) (*Exec, error) {
 		run.WithMCPExecutor(opts.MCPExecutor),
 		run.WithProviderExecutor(opts.ProviderExecutor),
 		run.WithValidation(opts.ValidateInput, opts.ValidateOutput),
+		run.WithHooks(opts.Hooks...),
+		run.WithMaxTotalCost(opts.MaxTotalCost),
+		run.WithContextArgInjector(opts.ContextArgInjector),
 	)
 
 	return &Exec{
@@ -65,6 +71,7 @@ func (e *Exec) RunTool(ctx context.Context, toolID string, args map[string]any)
 
 	return Result{
 		Value:    runResult.Structured,
+		Contents: runResult.Contents,
 		ToolID:   toolID,
 		Duration: duration,
 	}, nil
@@ -72,7 +79,13 @@ func (e *Exec) RunTool(ctx context.Context, toolID string, args map[string]any)
 
 // RunChain executes a sequence of tools.
 // Returns the final result, a slice of step results, and any error.
+// Returns ErrQuotaExceeded without running any step when the caller's
+// QuotaChainExecutions allowance is exhausted.
 func (e *Exec) RunChain(ctx context.Context, steps []Step) (Result, []StepResult, error) {
+	if _, err := e.admit(ctx, QuotaChainExecutions, QuotaChainExecutions); err != nil {
+		return Result{Error: err}, nil, err
+	}
+
 	start := time.Now()
 
 	// Convert exec.Step to run.ChainStep
@@ -82,6 +95,7 @@ func (e *Exec) RunChain(ctx context.Context, steps []Step) (Result, []StepResult
 			ToolID:      s.ToolID,
 			Args:        s.Args,
 			UsePrevious: s.UsePrevious,
+			Retry:       s.Retry,
 		}
 	}
 
@@ -98,6 +112,7 @@ func (e *Exec) RunChain(ctx context.Context, steps []Step) (Result, []StepResult
 			Value:     rs.Result.Structured,
 			Duration:  0, // run.StepResult doesn't track duration per step
 			Skipped:   false,
+			Retries:   rs.Retries,
 		}
 		if rs.Err != nil {
 			stepResults[i].Error = rs.Err
@@ -120,11 +135,93 @@ func (e *Exec) RunChain(ctx context.Context, steps []Step) (Result, []StepResult
 
 	return Result{
 		Value:    runResult.Structured,
+		Contents: runResult.Contents,
 		ToolID:   finalToolID,
 		Duration: duration,
 	}, stepResults, nil
 }
 
+// RunCode executes a code snippet via the configured CodeExecutor.
+// Returns ErrCodeExecutionDisabled unless Options.EnableCodeExecution is set,
+// ErrCodeExecutorRequired when no CodeExecutor is configured,
+// and ErrQuotaExceeded without executing when the caller's
+// QuotaCodeExecutions or QuotaSandboxSeconds allowance is exhausted.
+// Elapsed time is charged to QuotaSandboxSeconds after execution.
+func (e *Exec) RunCode(ctx context.Context, params CodeParams) (CodeResult, error) {
+	if !e.opts.EnableCodeExecution {
+		return CodeResult{Error: ErrCodeExecutionDisabled}, ErrCodeExecutionDisabled
+	}
+	if e.opts.CodeExecutor == nil {
+		return CodeResult{Error: ErrCodeExecutorRequired}, ErrCodeExecutorRequired
+	}
+	reservations, err := e.admit(ctx, QuotaCodeExecutions, QuotaCodeExecutions, QuotaSandboxSeconds)
+	if err != nil {
+		return CodeResult{Error: err}, err
+	}
+
+	execParams := code.ExecuteParams{
+		Language:     params.Language,
+		Code:         params.Code,
+		Timeout:      params.Timeout,
+		MaxToolCalls: params.MaxToolCalls,
+		SessionID:    params.SessionID,
+		Env:          params.Env,
+		Secrets:      params.Secrets,
+		OutputSchema: params.OutputSchema,
+		Workspace:    params.Workspace,
+		Determinism:  params.Determinism,
+	}
+	if execParams.Language == "" {
+		execParams.Language = e.opts.DefaultLanguage
+	}
+	if execParams.Timeout == 0 {
+		execParams.Timeout = e.opts.DefaultTimeout
+	}
+	if execParams.MaxToolCalls == 0 {
+		execParams.MaxToolCalls = e.opts.MaxToolCalls
+	}
+
+	// Correlate the engine's backends, gateways, and logs with the result
+	id := runtime.ExecutionIDFromContext(ctx)
+	if id == "" {
+		id = runtime.NewExecutionID()
+		ctx = runtime.WithExecutionID(ctx, id)
+	}
+
+	start := time.Now()
+	execResult, err := e.opts.CodeExecutor.ExecuteCode(ctx, execParams)
+	duration := time.Since(start)
+
+	if qErr := e.commitUsage(ctx, reservations, QuotaSandboxSeconds, duration.Seconds()); qErr != nil && err == nil {
+		err = qErr
+	}
+
+	result := CodeResult{
+		ExecutionID:   id,
+		Value:         execResult.Value,
+		ToolCalls:     make([]ToolCall, len(execResult.ToolCalls)),
+		Duration:      duration,
+		Stdout:        execResult.Stdout,
+		Stderr:        execResult.Stderr,
+		Validation:    execResult.Validation,
+		ResourceUsage: execResult.ResourceUsage,
+		Error:         err,
+	}
+	for i, tc := range execResult.ToolCalls {
+		result.ToolCalls[i] = ToolCall{
+			ToolID:   tc.ToolID,
+			Args:     tc.Args,
+			Result:   tc.Structured,
+			Contents: tc.Contents,
+			Duration: time.Duration(tc.DurationMs) * time.Millisecond,
+		}
+		if tc.Error != "" {
+			result.ToolCalls[i].Error = errors.New(tc.Error)
+		}
+	}
+	return result, err
+}
+
 // SearchTools finds tools matching a query.
 func (e *Exec) SearchTools(ctx context.Context, query string, limit int) ([]ToolSummary, error) {
 	_ = ctx // reserved for future context-aware search
@@ -132,9 +229,42 @@ func (e *Exec) SearchTools(ctx context.Context, query string, limit int) ([]Tool
 }
 
 // GetToolDoc retrieves tool documentation at the specified detail level.
+//
+// When Options.DegradeDocs is set and the documentation store is missing or
+// fails, a minimal document derived from the indexed tool is returned instead.
 func (e *Exec) GetToolDoc(ctx context.Context, toolID string, level tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
 	_ = ctx // reserved for future context-aware doc retrieval
-	return e.docs.DescribeTool(toolID, level)
+	if e.docs != nil {
+		doc, err := e.docs.DescribeTool(toolID, level)
+		if err == nil || !e.opts.DegradeDocs {
+			return doc, err
+		}
+		fallback, fbErr := e.schemaDoc(toolID, level)
+		if fbErr != nil {
+			// Prefer the store's error; it is the more informative failure.
+			return tooldoc.ToolDoc{}, err
+		}
+		return fallback, nil
+	}
+	return e.schemaDoc(toolID, level)
+}
+
+// schemaDoc builds minimal documentation from the tool definition in the index.
+// The summary comes from the tool description; the full tool definition
+// (including input and output schemas) is attached above DetailSummary.
+func (e *Exec) schemaDoc(toolID string, level tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	tool, _, err := e.index.GetTool(toolID)
+	if err != nil {
+		return tooldoc.ToolDoc{}, err
+	}
+
+	doc := tooldoc.ToolDoc{
+		Summary: tool.Description,
+	}
+	if level != tooldoc.DetailSummary {
+		doc.Tool = &tool
+	}
+	return doc, nil
 }
 
 // Index returns the underlying tool index.
@@ -144,6 +274,7 @@ func (e *Exec) Index() index.Index {
 }
 
 // DocStore returns the underlying documentation store.
+// Returns nil when running with DegradeDocs and no store configured.
 func (e *Exec) DocStore() tooldoc.Store {
 	return e.docs
 }
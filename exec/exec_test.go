@@ -11,6 +11,7 @@ import (
 	"github.com/jonwraymond/tooldiscovery/index"
 	"github.com/jonwraymond/tooldiscovery/search"
 	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
 	"github.com/jonwraymond/toolfoundation/model"
 )
 
@@ -254,6 +255,40 @@ func TestExec_RunTool_ContextCanceled(t *testing.T) {
 	}
 }
 
+func TestExec_RunTool_Hooks(t *testing.T) {
+	idx, docs, tool := testSetup(t)
+	if err := idx.RegisterTool(tool, model.NewLocalBackend("greet-handler")); err != nil {
+		t.Fatalf("RegisterTool() error = %v", err)
+	}
+
+	exec, err := New(Options{
+		Index: idx,
+		Docs:  docs,
+		LocalHandlers: map[string]Handler{
+			"greet-handler": func(_ context.Context, args map[string]any) (any, error) {
+				name, _ := args["name"].(string)
+				return "Hello, " + name + "!", nil
+			},
+		},
+		Hooks: []run.Hooks{{
+			BeforeRun: func(_ context.Context, _ model.Tool, _ map[string]any) (map[string]any, error) {
+				return map[string]any{"name": "Hooked"}, nil
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	result, err := exec.RunTool(context.Background(), "test:greet", map[string]any{"name": "World"})
+	if err != nil {
+		t.Fatalf("RunTool() error = %v", err)
+	}
+	if result.Value != "Hello, Hooked!" {
+		t.Errorf("Result.Value = %v, want %q", result.Value, "Hello, Hooked!")
+	}
+}
+
 func TestExec_RunChain(t *testing.T) {
 	idx, docs, tool := testSetup(t)
 
@@ -369,6 +404,88 @@ func TestExec_GetToolDoc(t *testing.T) {
 	}
 }
 
+// failingDocStore is a tooldoc.Store whose lookups always fail.
+type failingDocStore struct{}
+
+func (failingDocStore) DescribeTool(string, tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	return tooldoc.ToolDoc{}, errors.New("docs unavailable")
+}
+
+func (failingDocStore) ListExamples(string, int) ([]tooldoc.ToolExample, error) {
+	return nil, errors.New("docs unavailable")
+}
+
+func TestNew_DegradeDocsWithoutStore(t *testing.T) {
+	idx, _, tool := testSetup(t)
+
+	if err := idx.RegisterTool(tool, model.NewLocalBackend("greet-handler")); err != nil {
+		t.Fatalf("RegisterTool() error = %v", err)
+	}
+
+	exec, err := New(Options{
+		Index:       idx,
+		DegradeDocs: true,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v, want nil", err)
+	}
+	if exec.DocStore() != nil {
+		t.Error("DocStore() should be nil when no store is configured")
+	}
+
+	ctx := context.Background()
+	doc, err := exec.GetToolDoc(ctx, "test:greet", tooldoc.DetailSchema)
+	if err != nil {
+		t.Fatalf("GetToolDoc() error = %v", err)
+	}
+	if doc.Summary != "Greets a user by name" {
+		t.Errorf("doc.Summary = %q, want tool description", doc.Summary)
+	}
+	if doc.Tool == nil || doc.Tool.InputSchema == nil {
+		t.Error("doc.Tool should carry the input schema above summary level")
+	}
+
+	summary, err := exec.GetToolDoc(ctx, "test:greet", tooldoc.DetailSummary)
+	if err != nil {
+		t.Fatalf("GetToolDoc(summary) error = %v", err)
+	}
+	if summary.Tool != nil {
+		t.Error("summary doc should not include the tool definition")
+	}
+
+	if _, err := exec.GetToolDoc(ctx, "test:missing", tooldoc.DetailSummary); !errors.Is(err, index.ErrNotFound) {
+		t.Errorf("GetToolDoc(missing) error = %v, want %v", err, index.ErrNotFound)
+	}
+}
+
+func TestExec_GetToolDoc_DegradesOnStoreError(t *testing.T) {
+	idx, _, tool := testSetup(t)
+
+	if err := idx.RegisterTool(tool, model.NewLocalBackend("greet-handler")); err != nil {
+		t.Fatalf("RegisterTool() error = %v", err)
+	}
+
+	strict, err := New(Options{Index: idx, Docs: failingDocStore{}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := strict.GetToolDoc(context.Background(), "test:greet", tooldoc.DetailFull); err == nil {
+		t.Error("GetToolDoc() should return the store error without DegradeDocs")
+	}
+
+	degraded, err := New(Options{Index: idx, Docs: failingDocStore{}, DegradeDocs: true})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	doc, err := degraded.GetToolDoc(context.Background(), "test:greet", tooldoc.DetailFull)
+	if err != nil {
+		t.Fatalf("GetToolDoc() error = %v", err)
+	}
+	if doc.Tool == nil || doc.Tool.Name != "greet" {
+		t.Errorf("doc.Tool = %v, want greet", doc.Tool)
+	}
+}
+
 func TestResult_OK(t *testing.T) {
 	tests := []struct {
 		name   string
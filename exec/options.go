@@ -2,10 +2,12 @@ package exec
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/jonwraymond/tooldiscovery/index"
 	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/code"
 	"github.com/jonwraymond/toolexec/run"
 	"github.com/jonwraymond/toolexec/runtime"
 )
@@ -21,8 +23,17 @@ const (
 var (
 	ErrIndexRequired = errors.New("exec: Index is required")
 	ErrDocsRequired  = errors.New("exec: Docs store is required")
+	ErrInvalidQuota  = errors.New("exec: quota limit and window must be positive")
 )
 
+// ErrCodeExecutionDisabled is returned by RunCode when
+// Options.EnableCodeExecution is false.
+var ErrCodeExecutionDisabled = errors.New("exec: code execution is disabled")
+
+// ErrCodeExecutorRequired is returned by RunCode when code execution is
+// enabled but Options.CodeExecutor is nil.
+var ErrCodeExecutorRequired = errors.New("exec: CodeExecutor is required when code execution is enabled")
+
 // Options configures an Exec instance.
 type Options struct {
 	// Index provides tool discovery and registration.
@@ -30,9 +41,16 @@ type Options struct {
 	Index index.Index
 
 	// Docs provides tool documentation.
-	// Required.
+	// Required unless DegradeDocs is set.
 	Docs tooldoc.Store
 
+	// DegradeDocs allows documentation features to degrade gracefully.
+	// When true, Docs may be nil, and GetToolDoc falls back to minimal
+	// documentation derived from the indexed tool definition whenever
+	// no store is configured or the store returns an error.
+	// Default: false (Docs is required and store errors are returned)
+	DegradeDocs bool
+
 	// LocalHandlers maps handler names to handler functions.
 	// These are used when a tool's backend is a local backend
 	// referencing the handler by name.
@@ -46,6 +64,18 @@ type Options struct {
 	// Optional; if nil, provider tools cannot be executed.
 	ProviderExecutor run.ProviderExecutor
 
+	// Hooks run around every tool execution, including chain steps.
+	// They are installed on the underlying runner in order.
+	Hooks []run.Hooks
+
+	// MaxTotalCost caps the summed tool cost weights (see run.ToolCost)
+	// of a single RunChain call. Zero means unlimited.
+	MaxTotalCost float64
+
+	// ContextArgInjector passes per-request context values to every tool
+	// under args[run.ContextArgsKey]. Optional.
+	ContextArgInjector run.ContextArgInjector
+
 	// SecurityProfile determines the runtime backend for code execution.
 	// Default: runtime.ProfileDev
 	SecurityProfile runtime.SecurityProfile
@@ -54,6 +84,10 @@ type Options struct {
 	// Default: false (tool execution only)
 	EnableCodeExecution bool
 
+	// CodeExecutor runs code snippets for RunCode, which returns
+	// ErrCodeExecutorRequired without one.
+	CodeExecutor code.Executor
+
 	// MaxToolCalls limits tool calls in code execution.
 	// Default: 100
 	MaxToolCalls int
@@ -73,6 +107,15 @@ type Options struct {
 	// ValidateOutput enables output validation after execution.
 	// Default: true
 	ValidateOutput bool
+
+	// Quotas are rolling-window allowances enforced per principal
+	// (see WithPrincipal) before RunChain and RunCode are admitted.
+	// Default: none (unlimited)
+	Quotas []Quota
+
+	// QuotaStore tracks usage for Quotas.
+	// Default: an in-memory store retaining the longest quota window.
+	QuotaStore QuotaStore
 }
 
 // validate checks that required fields are set.
@@ -80,9 +123,14 @@ func (o *Options) validate() error {
 	if o.Index == nil {
 		return ErrIndexRequired
 	}
-	if o.Docs == nil {
+	if o.Docs == nil && !o.DegradeDocs {
 		return ErrDocsRequired
 	}
+	for _, q := range o.Quotas {
+		if q.Limit <= 0 || q.Window <= 0 {
+			return fmt.Errorf("%w: %s", ErrInvalidQuota, q.Kind)
+		}
+	}
 	return nil
 }
 
@@ -100,6 +148,15 @@ func (o *Options) applyDefaults() {
 	if o.DefaultTimeout == 0 {
 		o.DefaultTimeout = DefaultTimeout
 	}
+	if len(o.Quotas) > 0 && o.QuotaStore == nil {
+		var retention time.Duration
+		for _, q := range o.Quotas {
+			if q.Window > retention {
+				retention = q.Window
+			}
+		}
+		o.QuotaStore = NewMemoryQuotaStore(retention)
+	}
 	// Note: ValidateInput and ValidateOutput default to false (zero value),
 	// but we want them to default to true. This is handled in New().
 }
@@ -123,6 +180,10 @@ type Step struct {
 	// StopOnError determines whether chain execution should
 	// stop if this step fails. Default is true.
 	StopOnError *bool
+
+	// Retry, when set, retries this step on retryable failures
+	// with jittered exponential backoff.
+	Retry *run.RetryPolicy
 }
 
 // shouldStopOnError returns whether to stop on error for this step.
@@ -156,4 +217,24 @@ type CodeParams struct {
 
 	// Env provides environment variables for the execution.
 	Env map[string]string
+
+	// Secrets maps environment variable names to secret references
+	// resolved by the code executor. See code.ExecuteParams.Secrets.
+	Secrets map[string]string
+
+	// SessionID joins the execution to a persistent code session so that
+	// successive snippets share state. See code.ExecuteParams.SessionID.
+	SessionID string
+
+	// OutputSchema is a JSON Schema the final value must satisfy.
+	// See code.ExecuteParams.OutputSchema.
+	OutputSchema any
+
+	// Workspace requests a scratch directory for the execution.
+	// See code.ExecuteParams.Workspace.
+	Workspace *code.Workspace
+
+	// Determinism pins the sandbox clock and random seed for replay.
+	// See code.ExecuteParams.Determinism.
+	Determinism *code.Determinism
 }
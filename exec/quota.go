@@ -0,0 +1,335 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned when a principal has exhausted a quota
+// for the current window. Callers use errors.Is to match it.
+var ErrQuotaExceeded = errors.New("exec: quota exceeded")
+
+// DefaultQuotaRetention is how long MemoryQuotaStore keeps usage records
+// when no retention is configured.
+const DefaultQuotaRetention = 24 * time.Hour
+
+// QuotaKind identifies what a quota measures.
+type QuotaKind string
+
+const (
+	// QuotaChainExecutions counts RunChain calls.
+	QuotaChainExecutions QuotaKind = "chain_executions"
+
+	// QuotaCodeExecutions counts RunCode calls.
+	QuotaCodeExecutions QuotaKind = "code_executions"
+
+	// QuotaSandboxSeconds accumulates wall-clock seconds spent in RunCode.
+	// Seconds are charged when a run ends and checked only at admission,
+	// so the run that crosses the limit may overshoot it by up to its
+	// timeout.
+	QuotaSandboxSeconds QuotaKind = "sandbox_seconds"
+)
+
+// Quota is a rolling-window allowance for a single kind of usage.
+// For example, {Kind: QuotaChainExecutions, Limit: 100, Window: time.Hour}
+// allows each principal 100 chain executions in any trailing hour.
+// Executions whose context carries no principal (see WithPrincipal) all
+// share the quota of the empty principal.
+type Quota struct {
+	// Kind is the usage being limited.
+	Kind QuotaKind
+
+	// Limit is the maximum usage allowed within Window.
+	Limit float64
+
+	// Window is the trailing duration over which usage is summed.
+	Window time.Duration
+}
+
+// QuotaStatus reports a principal's standing against one quota.
+type QuotaStatus struct {
+	// Kind is the usage being limited.
+	Kind QuotaKind `json:"kind"`
+
+	// Limit is the maximum usage allowed within Window.
+	Limit float64 `json:"limit"`
+
+	// Window is the trailing duration over which usage is summed.
+	Window time.Duration `json:"window"`
+
+	// Used is the usage recorded within the current window.
+	Used float64 `json:"used"`
+
+	// Remaining is Limit minus Used, floored at zero.
+	Remaining float64 `json:"remaining"`
+}
+
+// QuotaStore tracks usage for quota enforcement.
+// Implementations may be backed by memory, a database, or a shared cache
+// so that quotas hold across multiple Exec instances. Exec reserves one
+// unit per execution and zero sandbox-seconds, committing the measured
+// seconds once a run ends.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines.
+// - Errors: store failures are returned to callers and block admission;
+// Reserve wraps ErrQuotaExceeded when a quota is exhausted.
+// - Atomicity: Reserve checks and records in one step, so concurrent
+// reservations cannot together overrun a quota.
+type QuotaStore interface {
+	// Usage returns the total amount recorded for principal and kind
+	// at or after since.
+	Usage(ctx context.Context, principal string, kind QuotaKind, since time.Time) (float64, error)
+
+	// Record adds amount to the usage of principal and kind at time at.
+	Record(ctx context.Context, principal string, kind QuotaKind, amount float64, at time.Time) error
+
+	// Reserve records amount against principal and kind at time at,
+	// provided the usage within each of quotas' windows ending at at is
+	// below its Limit.
+	Reserve(ctx context.Context, principal string, kind QuotaKind, amount float64, at time.Time, quotas []Quota) (Reservation, error)
+
+	// Commit replaces the amount recorded by r, such as with the measured
+	// cost once an execution ends. Committing zero releases r.
+	Commit(ctx context.Context, r Reservation, amount float64) error
+}
+
+// Reservation identifies usage recorded by QuotaStore.Reserve.
+type Reservation struct {
+	// Principal is the principal the usage is attributed to.
+	Principal string
+
+	// Kind is the usage reserved.
+	Kind QuotaKind
+
+	// ID identifies the record within the store.
+	ID string
+}
+
+// principalKey is the context key for the calling principal.
+type principalKey struct{}
+
+// WithPrincipal returns a context that attributes executions to principal
+// for quota accounting.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal set by WithPrincipal,
+// or an empty string when none is set.
+func PrincipalFromContext(ctx context.Context) string {
+	p, _ := ctx.Value(principalKey{}).(string)
+	return p
+}
+
+// MemoryQuotaStore is an in-process QuotaStore.
+// Records older than the retention period are discarded as new usage arrives.
+type MemoryQuotaStore struct {
+	mu        sync.Mutex
+	retention time.Duration
+	records   map[quotaKey][]quotaRecord
+	nextID    uint64
+}
+
+type quotaKey struct {
+	principal string
+	kind      QuotaKind
+}
+
+type quotaRecord struct {
+	id     uint64
+	at     time.Time
+	amount float64
+}
+
+// NewMemoryQuotaStore creates an in-memory QuotaStore.
+// Retention should be at least the longest configured quota window;
+// if zero, DefaultQuotaRetention is used.
+func NewMemoryQuotaStore(retention time.Duration) *MemoryQuotaStore {
+	if retention <= 0 {
+		retention = DefaultQuotaRetention
+	}
+	return &MemoryQuotaStore{
+		retention: retention,
+		records:   make(map[quotaKey][]quotaRecord),
+	}
+}
+
+// Usage implements QuotaStore.
+func (s *MemoryQuotaStore) Usage(ctx context.Context, principal string, kind QuotaKind, since time.Time) (float64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage(quotaKey{principal, kind}, since), nil
+}
+
+// usage sums the amounts recorded for key at or after since. s.mu must
+// be held.
+func (s *MemoryQuotaStore) usage(key quotaKey, since time.Time) float64 {
+	var total float64
+	for _, r := range s.records[key] {
+		if !r.at.Before(since) {
+			total += r.amount
+		}
+	}
+	return total
+}
+
+// Record implements QuotaStore.
+func (s *MemoryQuotaStore) Record(ctx context.Context, principal string, kind QuotaKind, amount float64, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record(quotaKey{principal, kind}, amount, at)
+	return nil
+}
+
+// Reserve implements QuotaStore.
+func (s *MemoryQuotaStore) Reserve(ctx context.Context, principal string, kind QuotaKind, amount float64, at time.Time, quotas []Quota) (Reservation, error) {
+	if err := ctx.Err(); err != nil {
+		return Reservation{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := quotaKey{principal, kind}
+	for _, q := range quotas {
+		if s.usage(key, at.Add(-q.Window)) >= q.Limit {
+			return Reservation{}, fmt.Errorf("%w: %s limit %g per %v reached", ErrQuotaExceeded, q.Kind, q.Limit, q.Window)
+		}
+	}
+	id := s.record(key, amount, at)
+	return Reservation{Principal: principal, Kind: kind, ID: strconv.FormatUint(id, 10)}, nil
+}
+
+// Commit implements QuotaStore. Reservations older than the retention
+// period are already discarded, so committing them does nothing.
+func (s *MemoryQuotaStore) Commit(ctx context.Context, r Reservation, amount float64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	id, err := strconv.ParseUint(r.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("exec: unknown reservation %q", r.ID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := s.records[quotaKey{r.Principal, r.Kind}]
+	for i := range records {
+		if records[i].id == id {
+			records[i].amount = amount
+			break
+		}
+	}
+	return nil
+}
+
+// record appends a record of amount at at to key, discarding those older
+// than the retention period, and returns its ID. s.mu must be held.
+func (s *MemoryQuotaStore) record(key quotaKey, amount float64, at time.Time) uint64 {
+	cutoff := at.Add(-s.retention)
+	kept := s.records[key][:0]
+	for _, r := range s.records[key] {
+		if !r.at.Before(cutoff) {
+			kept = append(kept, r)
+		}
+	}
+	s.nextID++
+	s.records[key] = append(kept, quotaRecord{id: s.nextID, at: at, amount: amount})
+	return s.nextID
+}
+
+// QuotaStatus reports the principal's standing against every configured quota.
+// Returns nil when no quotas are configured.
+func (e *Exec) QuotaStatus(ctx context.Context, principal string) ([]QuotaStatus, error) {
+	if len(e.opts.Quotas) == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	statuses := make([]QuotaStatus, 0, len(e.opts.Quotas))
+	for _, q := range e.opts.Quotas {
+		used, err := e.opts.QuotaStore.Usage(ctx, principal, q.Kind, now.Add(-q.Window))
+		if err != nil {
+			return nil, err
+		}
+		remaining := q.Limit - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		statuses = append(statuses, QuotaStatus{
+			Kind:      q.Kind,
+			Limit:     q.Limit,
+			Window:    q.Window,
+			Used:      used,
+			Remaining: remaining,
+		})
+	}
+	return statuses, nil
+}
+
+// admit reserves one unit of countKind, and nothing yet of the other
+// kinds, against the context principal's quotas of kinds, returning the
+// reservations made. Each reservation checks and records atomically, so
+// concurrent requests cannot overrun a quota.
+func (e *Exec) admit(ctx context.Context, countKind QuotaKind, kinds ...QuotaKind) ([]Reservation, error) {
+	if len(e.opts.Quotas) == 0 {
+		return nil, nil
+	}
+	principal := PrincipalFromContext(ctx)
+	now := time.Now()
+	var reservations []Reservation
+	for _, kind := range kinds {
+		var quotas []Quota
+		for _, q := range e.opts.Quotas {
+			if q.Kind == kind {
+				quotas = append(quotas, q)
+			}
+		}
+		if len(quotas) == 0 {
+			continue
+		}
+		var amount float64
+		if kind == countKind {
+			amount = 1
+		}
+		r, err := e.opts.QuotaStore.Reserve(ctx, principal, kind, amount, now, quotas)
+		if err != nil {
+			e.release(ctx, reservations)
+			if !errors.Is(err, ErrQuotaExceeded) {
+				err = fmt.Errorf("exec: quota reservation: %w", err)
+			}
+			return nil, err
+		}
+		reservations = append(reservations, r)
+	}
+	return reservations, nil
+}
+
+// release returns the reservations of a request that was not admitted.
+func (e *Exec) release(ctx context.Context, reservations []Reservation) {
+	for _, r := range reservations {
+		_ = e.opts.QuotaStore.Commit(context.WithoutCancel(ctx), r, 0)
+	}
+}
+
+// commitUsage commits amount to the reservation of kind, if any. Usage is
+// committed even when ctx was cancelled, since the execution it measures
+// has already run.
+func (e *Exec) commitUsage(ctx context.Context, reservations []Reservation, kind QuotaKind, amount float64) error {
+	for _, r := range reservations {
+		if r.Kind == kind {
+			return e.opts.QuotaStore.Commit(context.WithoutCancel(ctx), r, amount)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/code"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+// fakeCodeExecutor is a code.Executor that returns a fixed result.
+type fakeCodeExecutor struct {
+	calls      int
+	lastParams code.ExecuteParams
+	result     code.ExecuteResult
+	err        error
+}
+
+func (f *fakeCodeExecutor) ExecuteCode(_ context.Context, params code.ExecuteParams) (code.ExecuteResult, error) {
+	f.calls++
+	f.lastParams = params
+	return f.result, f.err
+}
+
+func TestMemoryQuotaStore_UsageWindow(t *testing.T) {
+	store := NewMemoryQuotaStore(time.Hour)
+	ctx := context.Background()
+	now := time.Now()
+
+	_ = store.Record(ctx, "alice", QuotaChainExecutions, 1, now.Add(-2*time.Hour))
+	_ = store.Record(ctx, "alice", QuotaChainExecutions, 1, now.Add(-30*time.Minute))
+	_ = store.Record(ctx, "alice", QuotaChainExecutions, 2, now)
+	_ = store.Record(ctx, "bob", QuotaChainExecutions, 5, now)
+
+	used, err := store.Usage(ctx, "alice", QuotaChainExecutions, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Usage() error = %v", err)
+	}
+	if used != 3 {
+		t.Errorf("Usage(alice) = %v, want 3", used)
+	}
+
+	used, _ = store.Usage(ctx, "alice", QuotaSandboxSeconds, now.Add(-time.Hour))
+	if used != 0 {
+		t.Errorf("Usage(alice, sandbox) = %v, want 0", used)
+	}
+}
+
+func TestMemoryQuotaStore_Reserve(t *testing.T) {
+	store := NewMemoryQuotaStore(time.Hour)
+	ctx := context.Background()
+	quotas := []Quota{{Kind: QuotaCodeExecutions, Limit: 5, Window: time.Hour}}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var admitted []Reservation
+	for range 20 {
+		wg.Go(func() {
+			r, err := store.Reserve(ctx, "alice", QuotaCodeExecutions, 1, time.Now(), quotas)
+			if err == nil {
+				mu.Lock()
+				admitted = append(admitted, r)
+				mu.Unlock()
+			} else if !errors.Is(err, ErrQuotaExceeded) {
+				t.Errorf("Reserve() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	if len(admitted) != 5 {
+		t.Fatalf("%d concurrent reservations admitted, want 5", len(admitted))
+	}
+
+	// Commit replaces the reserved amount; zero releases it.
+	if err := store.Commit(ctx, admitted[0], 0); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := store.Commit(ctx, admitted[1], 0.5); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if used, _ := store.Usage(ctx, "alice", QuotaCodeExecutions, time.Now().Add(-time.Hour)); used != 3.5 {
+		t.Errorf("Usage() after commits = %v, want 3.5", used)
+	}
+}
+
+func TestNew_InvalidQuota(t *testing.T) {
+	idx, docs, _ := testSetup(t)
+
+	_, err := New(Options{
+		Index:  idx,
+		Docs:   docs,
+		Quotas: []Quota{{Kind: QuotaChainExecutions, Limit: 10}},
+	})
+	if !errors.Is(err, ErrInvalidQuota) {
+		t.Errorf("New() error = %v, want %v", err, ErrInvalidQuota)
+	}
+}
+
+func TestExec_RunChain_QuotaPerPrincipal(t *testing.T) {
+	idx, docs, tool := testSetup(t)
+	if err := idx.RegisterTool(tool, model.NewLocalBackend("greet-handler")); err != nil {
+		t.Fatalf("RegisterTool() error = %v", err)
+	}
+
+	exec, err := New(Options{
+		Index: idx,
+		Docs:  docs,
+		LocalHandlers: map[string]Handler{
+			"greet-handler": func(_ context.Context, _ map[string]any) (any, error) {
+				return "hi", nil
+			},
+		},
+		Quotas: []Quota{{Kind: QuotaChainExecutions, Limit: 2, Window: time.Hour}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	steps := []Step{{ToolID: "test:greet", Args: map[string]any{"name": "x"}}}
+	alice := WithPrincipal(context.Background(), "alice")
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := exec.RunChain(alice, steps); err != nil {
+			t.Fatalf("RunChain() #%d error = %v", i+1, err)
+		}
+	}
+	if _, _, err := exec.RunChain(alice, steps); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("RunChain() error = %v, want %v", err, ErrQuotaExceeded)
+	}
+
+	bob := WithPrincipal(context.Background(), "bob")
+	if _, _, err := exec.RunChain(bob, steps); err != nil {
+		t.Fatalf("RunChain(bob) error = %v, want nil", err)
+	}
+
+	status, err := exec.QuotaStatus(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("QuotaStatus() error = %v", err)
+	}
+	if len(status) != 1 || status[0].Used != 2 || status[0].Remaining != 0 {
+		t.Errorf("QuotaStatus(alice) = %+v, want used 2 remaining 0", status)
+	}
+}
+
+func TestExec_RunCode(t *testing.T) {
+	idx, docs, _ := testSetup(t)
+
+	fake := &fakeCodeExecutor{
+		result: code.ExecuteResult{
+			Value:  42,
+			Stdout: "done\n",
+			ToolCalls: []code.ToolCallRecord{
+				{ToolID: "test:greet", DurationMs: 5},
+				{ToolID: "test:fail", Error: "boom"},
+			},
+		},
+	}
+
+	exec, err := New(Options{
+		Index:               idx,
+		Docs:                docs,
+		EnableCodeExecution: true,
+		CodeExecutor:        fake,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	result, err := exec.RunCode(context.Background(), CodeParams{Code: "__out = 42"})
+	if err != nil {
+		t.Fatalf("RunCode() error = %v", err)
+	}
+	if result.Value != 42 || result.Stdout != "done\n" || result.ExecutionID == "" {
+		t.Errorf("RunCode() = %+v", result)
+	}
+	ctx := runtime.WithExecutionID(context.Background(), "exec-1")
+	if result, _ := exec.RunCode(ctx, CodeParams{Code: "x"}); result.ExecutionID != "exec-1" {
+		t.Errorf("RunCode().ExecutionID = %q, want the context's", result.ExecutionID)
+	}
+	if fake.lastParams.Language != DefaultLanguage || fake.lastParams.Timeout != DefaultTimeout {
+		t.Errorf("defaults not applied: %+v", fake.lastParams)
+	}
+	if len(result.ToolCalls) != 2 || result.ToolCalls[1].Error == nil {
+		t.Errorf("ToolCalls = %+v, want 2 with second failed", result.ToolCalls)
+	}
+}
+
+func TestExec_RunCode_Disabled(t *testing.T) {
+	idx, docs, _ := testSetup(t)
+
+	exec, err := New(Options{Index: idx, Docs: docs})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := exec.RunCode(context.Background(), CodeParams{Code: "x"}); !errors.Is(err, ErrCodeExecutionDisabled) {
+		t.Errorf("RunCode() error = %v, want %v", err, ErrCodeExecutionDisabled)
+	}
+
+	// A missing executor fails RunCode, not New, with or without quotas.
+	for _, quotas := range [][]Quota{nil, {{Kind: QuotaCodeExecutions, Limit: 1, Window: time.Hour}}} {
+		exec, err := New(Options{Index: idx, Docs: docs, EnableCodeExecution: true, Quotas: quotas})
+		if err != nil {
+			t.Fatalf("New() with %d quotas error = %v", len(quotas), err)
+		}
+		if _, err := exec.RunCode(context.Background(), CodeParams{Code: "x"}); !errors.Is(err, ErrCodeExecutorRequired) {
+			t.Errorf("RunCode() with %d quotas error = %v, want %v", len(quotas), err, ErrCodeExecutorRequired)
+		}
+	}
+}
+
+func TestExec_RunCode_SandboxSecondsQuota(t *testing.T) {
+	idx, docs, _ := testSetup(t)
+	store := NewMemoryQuotaStore(0)
+	fake := &fakeCodeExecutor{}
+
+	exec, err := New(Options{
+		Index:               idx,
+		Docs:                docs,
+		EnableCodeExecution: true,
+		CodeExecutor:        fake,
+		QuotaStore:          store,
+		Quotas: []Quota{
+			{Kind: QuotaCodeExecutions, Limit: 100, Window: time.Hour},
+			{Kind: QuotaSandboxSeconds, Limit: 60, Window: 24 * time.Hour},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := WithPrincipal(context.Background(), "alice")
+	if _, err := exec.RunCode(ctx, CodeParams{Code: "x"}); err != nil {
+		t.Fatalf("RunCode() error = %v", err)
+	}
+
+	// Simulate a long-running snippet earlier in the day.
+	_ = store.Record(ctx, "alice", QuotaSandboxSeconds, 60, time.Now().Add(-time.Hour))
+
+	if _, err := exec.RunCode(ctx, CodeParams{Code: "x"}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("RunCode() error = %v, want %v", err, ErrQuotaExceeded)
+	}
+	if fake.calls != 1 {
+		t.Errorf("executor calls = %d, want 1 (second call must not be admitted)", fake.calls)
+	}
+
+	status, err := exec.QuotaStatus(ctx, "alice")
+	if err != nil {
+		t.Fatalf("QuotaStatus() error = %v", err)
+	}
+	if status[0].Kind != QuotaCodeExecutions || status[0].Used != 1 {
+		t.Errorf("code executions status = %+v, want used 1", status[0])
+	}
+	if status[1].Remaining != 0 {
+		t.Errorf("sandbox seconds remaining = %v, want 0", status[1].Remaining)
+	}
+}
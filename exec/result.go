@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/toolexec/code"
+	"github.com/jonwraymond/toolexec/run"
 )
 
 // Handler is the function signature for local tool handlers.
@@ -16,6 +18,10 @@ type Result struct {
 	// Value is the return value from the tool.
 	Value any
 
+	// Contents lists every content item returned by the tool
+	// (text, images, resources). Set for MCP tools only.
+	Contents []run.ContentItem
+
 	// ToolID is the canonical ID of the executed tool.
 	ToolID string
 
@@ -56,6 +62,9 @@ type StepResult struct {
 
 	// Skipped is true if this step was skipped due to a prior failure.
 	Skipped bool
+
+	// Retries is the number of retries made after the first attempt.
+	Retries int
 }
 
 // OK returns true if the step completed successfully.
@@ -65,6 +74,11 @@ func (s StepResult) OK() bool {
 
 // CodeResult represents the outcome of code execution with tool access.
 type CodeResult struct {
+	// ExecutionID identifies the execution in runtime backend labels,
+	// gateway messages, tool call records, and logs. It is taken from the
+	// context (see runtime.WithExecutionID) or generated.
+	ExecutionID string
+
 	// Value is the final return value from the code.
 	Value any
 
@@ -80,6 +94,13 @@ type CodeResult struct {
 	// Stderr contains captured standard error.
 	Stderr string
 
+	// Validation reports the output schema check, if OutputSchema was set.
+	Validation *code.ValidationReport
+
+	// ResourceUsage reports what the engine observed the code consume,
+	// or nil when the engine does not measure usage.
+	ResourceUsage *code.ResourceUsage
+
 	// Error is non-nil if execution failed.
 	Error error
 }
@@ -100,6 +121,9 @@ type ToolCall struct {
 	// Result is the tool's return value.
 	Result any
 
+	// Contents lists every content item returned by the tool.
+	Contents []run.ContentItem
+
 	// Duration is how long the tool call took.
 	Duration time.Duration
 
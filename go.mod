@@ -6,7 +6,9 @@ require (
 	github.com/jonwraymond/tooldiscovery v0.3.0
 	github.com/jonwraymond/toolfoundation v0.3.0
 	github.com/modelcontextprotocol/go-sdk v1.2.0
+	golang.org/x/sys v0.40.0
 	golang.org/x/text v0.33.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -39,6 +41,5 @@ require (
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
 	go.etcd.io/bbolt v1.4.3 // indirect
 	golang.org/x/oauth2 v0.34.0 // indirect
-	golang.org/x/sys v0.40.0 // indirect
 	google.golang.org/protobuf v1.36.11 // indirect
 )
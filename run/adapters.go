@@ -0,0 +1,66 @@
+package run
+
+import (
+	"context"
+
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+// ExecutorFunc adapts a single-tool execution function into an Executor.
+// RunChain executes steps sequentially through the function with the same
+// UsePrevious semantics as DefaultRunner; RunStream returns
+// ErrStreamNotSupported. It lets gateways and tests provide execution
+// without resolver or registry wiring.
+type ExecutorFunc func(ctx context.Context, toolID string, args map[string]any) (RunResult, error)
+
+// Run calls f.
+func (f ExecutorFunc) Run(ctx context.Context, toolID string, args map[string]any) (RunResult, error) {
+	if toolID == "" {
+		return RunResult{}, WrapError(toolID, nil, "validate_tool_id", ErrInvalidToolID)
+	}
+	return f(ctx, toolID, args)
+}
+
+// RunStream always returns ErrStreamNotSupported.
+func (f ExecutorFunc) RunStream(_ context.Context, toolID string, _ map[string]any) (<-chan StreamEvent, error) {
+	return nil, WrapError(toolID, nil, "stream", ErrStreamNotSupported)
+}
+
+// RunChain executes steps sequentially, stopping on the first error.
+func (f ExecutorFunc) RunChain(ctx context.Context, steps []ChainStep) (RunResult, []StepResult, error) {
+	var results []StepResult
+	var last RunResult
+	var previous any
+	for _, step := range steps {
+		if err := ctx.Err(); err != nil {
+			return RunResult{}, results, err
+		}
+		result, err := f.Run(ctx, step.ToolID, buildChainArgs(step, previous))
+		results = append(results, StepResult{
+			ToolID:  step.ToolID,
+			Backend: result.Backend,
+			Result:  result,
+			Err:     err,
+		})
+		if err != nil {
+			return RunResult{}, results, err
+		}
+		last = result
+		previous = result.Structured
+	}
+	return last, results, nil
+}
+
+// ResolverFunc adapts a lookup function into a Resolver.
+type ResolverFunc func(ctx context.Context, toolID string) (model.Tool, []model.ToolBackend, error)
+
+// Resolve calls f.
+func (f ResolverFunc) Resolve(ctx context.Context, toolID string) (model.Tool, []model.ToolBackend, error) {
+	return f(ctx, toolID)
+}
+
+// Ensure adapters implement their interfaces.
+var (
+	_ Executor = ExecutorFunc(nil)
+	_ Resolver = ResolverFunc(nil)
+)
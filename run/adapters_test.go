@@ -0,0 +1,72 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+func TestExecutorFunc_RunChain(t *testing.T) {
+	var gotArgs []map[string]any
+	exec := ExecutorFunc(func(_ context.Context, toolID string, args map[string]any) (RunResult, error) {
+		gotArgs = append(gotArgs, args)
+		if toolID == "fail" {
+			return RunResult{}, errors.New("boom")
+		}
+		return RunResult{Structured: toolID + "-out"}, nil
+	})
+
+	final, results, err := exec.RunChain(context.Background(), []ChainStep{
+		{ToolID: "a"},
+		{ToolID: "b", UsePrevious: true},
+	})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if final.Structured != "b-out" || len(results) != 2 {
+		t.Errorf("RunChain() = %v, %d results", final.Structured, len(results))
+	}
+	if gotArgs[1]["previous"] != "a-out" {
+		t.Errorf("step 2 previous = %v, want a-out", gotArgs[1]["previous"])
+	}
+
+	_, results, err = exec.RunChain(context.Background(), []ChainStep{{ToolID: "fail"}, {ToolID: "a"}})
+	if err == nil || len(results) != 1 {
+		t.Errorf("RunChain() err = %v, results = %d, want error after 1 step", err, len(results))
+	}
+}
+
+func TestExecutorFunc_StreamAndInvalidID(t *testing.T) {
+	exec := ExecutorFunc(func(context.Context, string, map[string]any) (RunResult, error) {
+		return RunResult{}, nil
+	})
+	if _, err := exec.RunStream(context.Background(), "a", nil); !errors.Is(err, ErrStreamNotSupported) {
+		t.Errorf("RunStream() error = %v, want ErrStreamNotSupported", err)
+	}
+	if _, err := exec.Run(context.Background(), "", nil); !errors.Is(err, ErrInvalidToolID) {
+		t.Errorf("Run(\"\") error = %v, want ErrInvalidToolID", err)
+	}
+}
+
+func TestDefaultRunner_Resolve(t *testing.T) {
+	idx := newMockIndex()
+	backend := testLocalBackend("h")
+	mustRegisterTool(t, idx, testTool("mytool"), backend)
+	idx.DefaultBackends["mytool"] = backend
+
+	var resolver Resolver = NewRunner(WithIndex(idx))
+
+	tool, backends, err := resolver.Resolve(context.Background(), "mytool")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if tool.Name != "mytool" || len(backends) != 1 || backends[0].Kind != model.BackendKindLocal {
+		t.Errorf("Resolve() = %v, %v", tool.Name, backends)
+	}
+
+	if _, _, err := resolver.Resolve(context.Background(), "missing"); !errors.Is(err, ErrToolNotFound) {
+		t.Errorf("Resolve(missing) error = %v, want ErrToolNotFound", err)
+	}
+}
@@ -0,0 +1,157 @@
+package run
+
+import (
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+// ResolutionCache memoizes tool and backend resolution by tool ID so hot
+// tools skip the Index and resolver lookups on every call.
+//
+// Entries expire after the configured TTL. NewRunner subscribes the cache
+// to its Index's change notifications when the Index implements
+// index.ChangeNotifier, so changed tools are dropped as they change. For
+// other registries, call Watch, Invalidate, or InvalidateAll so stale
+// definitions are not served.
+//
+// Contract:
+// - Concurrency: safe for concurrent use; a cache may be shared by runners.
+// - Ownership: the backends slice is copied on read and write, but tools
+// and backends are copied shallowly: their schemas, tags, and other
+// reference fields are shared with the cache and must not be modified.
+type ResolutionCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[string]resolutionEntry
+	watched []index.ChangeNotifier
+
+	// gen counts invalidations, so a resolution that began before one is
+	// not stored after it.
+	gen uint64
+}
+
+type resolutionEntry struct {
+	tool     model.Tool
+	backends []model.ToolBackend
+	expires  time.Time
+}
+
+// NewResolutionCache creates a resolution cache whose entries expire after ttl.
+// A ttl of zero disables expiry; entries then live until invalidated.
+func NewResolutionCache(ttl time.Duration) *ResolutionCache {
+	return &ResolutionCache{
+		ttl:     ttl,
+		entries: make(map[string]resolutionEntry),
+	}
+}
+
+// Watch invalidates cached resolutions as notifier reports changes: the
+// changed tool's entry, or every entry on a refresh. Watching a notifier
+// the cache already watches adds no second subscription. The returned
+// function stops watching.
+func (c *ResolutionCache) Watch(notifier index.ChangeNotifier) (unsubscribe func()) {
+	dedupe := reflect.TypeOf(notifier).Comparable()
+	c.mu.Lock()
+	if dedupe && slices.Contains(c.watched, notifier) {
+		c.mu.Unlock()
+		return func() {}
+	}
+	if dedupe {
+		c.watched = append(c.watched, notifier)
+	}
+	c.mu.Unlock()
+
+	stop := notifier.OnChange(func(event index.ChangeEvent) {
+		if event.Type == index.ChangeRefreshed || event.ToolID == "" {
+			c.InvalidateAll()
+			return
+		}
+		c.Invalidate(event.ToolID)
+	})
+	return sync.OnceFunc(func() {
+		stop()
+		if dedupe {
+			c.mu.Lock()
+			c.watched = slices.DeleteFunc(c.watched, func(n index.ChangeNotifier) bool { return n == notifier })
+			c.mu.Unlock()
+		}
+	})
+}
+
+// Invalidate removes the cached resolution for toolID, if any.
+func (c *ResolutionCache) Invalidate(toolID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	delete(c.entries, toolID)
+}
+
+// InvalidateAll removes every cached resolution.
+func (c *ResolutionCache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.entries = make(map[string]resolutionEntry)
+}
+
+// Len returns the number of cached resolutions, including expired entries
+// that have not yet been evicted.
+func (c *ResolutionCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// get returns a cached resolution for toolID when present and unexpired,
+// and the generation to pass to put on a miss.
+func (c *ResolutionCache) get(toolID string) (*resolveResult, uint64, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[toolID]
+	gen := c.gen
+	c.mu.RUnlock()
+	if !ok {
+		return nil, gen, false
+	}
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.evict(toolID)
+		return nil, gen, false
+	}
+	return &resolveResult{
+		tool:     entry.tool,
+		backends: append([]model.ToolBackend(nil), entry.backends...),
+	}, gen, true
+}
+
+// evict removes toolID's entry if it has expired. Unlike Invalidate, it
+// leaves the generation alone, so the lookup that found it can still store
+// a fresh resolution.
+func (c *ResolutionCache) evict(toolID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[toolID]; ok && !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		delete(c.entries, toolID)
+	}
+}
+
+// put stores a resolution for toolID, unless the cache was invalidated
+// since get returned gen: the resolution may predate the change.
+func (c *ResolutionCache) put(toolID string, res *resolveResult, gen uint64) {
+	entry := resolutionEntry{
+		tool:     res.tool,
+		backends: append([]model.ToolBackend(nil), res.backends...),
+	}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen != gen {
+		return
+	}
+	c.entries[toolID] = entry
+}
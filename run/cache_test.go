@@ -0,0 +1,176 @@
+package run
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+)
+
+func newCachedRunner(t *testing.T, cache *ResolutionCache) (*DefaultRunner, *mockIndex) {
+	t.Helper()
+	idx := newMockIndex()
+	mustRegisterTool(t, idx, testTool("mytool"), testLocalBackend("myhandler"))
+
+	localReg := newMockLocalRegistry()
+	localReg.Register("myhandler", func(_ context.Context, _ map[string]any) (any, error) {
+		return "ok", nil
+	})
+
+	runner := NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithValidation(false, false),
+		WithResolutionCache(cache),
+	)
+	return runner, idx
+}
+
+func TestResolutionCache_ServesHotTools(t *testing.T) {
+	cache := NewResolutionCache(0)
+	runner, idx := newCachedRunner(t, cache)
+
+	if _, err := runner.Run(context.Background(), "mytool", nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("cache.Len() = %d, want 1", cache.Len())
+	}
+
+	// Subsequent calls must not touch the index.
+	idx.GetToolErr = errTest
+	if _, err := runner.Run(context.Background(), "mytool", nil); err != nil {
+		t.Fatalf("Run() with cached resolution error = %v", err)
+	}
+
+	cache.Invalidate("mytool")
+	if _, err := runner.Run(context.Background(), "mytool", nil); err == nil {
+		t.Fatal("Run() after Invalidate should resolve against the index again")
+	}
+}
+
+func TestResolutionCache_DoesNotCacheFailures(t *testing.T) {
+	cache := NewResolutionCache(0)
+	runner, _ := newCachedRunner(t, cache)
+
+	if _, err := runner.Run(context.Background(), "missing", nil); err == nil {
+		t.Fatal("Run() should fail for unknown tool")
+	}
+	if cache.Len() != 0 {
+		t.Errorf("cache.Len() = %d, want 0 after failed resolution", cache.Len())
+	}
+}
+
+func TestResolutionCache_TTLExpiry(t *testing.T) {
+	cache := NewResolutionCache(10 * time.Millisecond)
+	runner, idx := newCachedRunner(t, cache)
+
+	if _, err := runner.Run(context.Background(), "mytool", nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	idx.GetToolErr = errTest
+	if _, err := runner.Run(context.Background(), "mytool", nil); err == nil {
+		t.Fatal("Run() should re-resolve after the TTL expires")
+	}
+}
+
+func TestResolutionCache_InvalidateAll(t *testing.T) {
+	cache := NewResolutionCache(time.Hour)
+	runner, _ := newCachedRunner(t, cache)
+
+	if _, err := runner.Run(context.Background(), "mytool", nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	cache.InvalidateAll()
+	if cache.Len() != 0 {
+		t.Errorf("cache.Len() = %d, want 0", cache.Len())
+	}
+}
+
+func TestResolutionCache_SkipsStaleResolutions(t *testing.T) {
+	cache := NewResolutionCache(0)
+	res := &resolveResult{tool: testTool("mytool")}
+
+	// A resolution that started before an invalidation is not stored.
+	_, gen, _ := cache.get("mytool")
+	cache.Invalidate("mytool")
+	cache.put("mytool", res, gen)
+	if cache.Len() != 0 {
+		t.Errorf("cache.Len() = %d after a stale put, want 0", cache.Len())
+	}
+
+	_, gen, _ = cache.get("mytool")
+	cache.put("mytool", res, gen)
+	if cache.Len() != 1 {
+		t.Errorf("cache.Len() = %d, want 1", cache.Len())
+	}
+}
+
+// notifyingIndex is a mockIndex implementing index.ChangeNotifier.
+type notifyingIndex struct {
+	*mockIndex
+	mu        sync.Mutex
+	listeners map[int]index.ChangeListener
+	next      int
+}
+
+func (n *notifyingIndex) OnChange(listener index.ChangeListener) func() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	id := n.next
+	n.next++
+	n.listeners[id] = listener
+	return func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		delete(n.listeners, id)
+	}
+}
+
+func (n *notifyingIndex) notify(event index.ChangeEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, l := range n.listeners {
+		l(event)
+	}
+}
+
+func TestResolutionCache_WatchesIndexChanges(t *testing.T) {
+	idx := &notifyingIndex{mockIndex: newMockIndex(), listeners: make(map[int]index.ChangeListener)}
+	mustRegisterTool(t, idx.mockIndex, testTool("mytool"), testLocalBackend("myhandler"))
+	localReg := newMockLocalRegistry()
+	localReg.Register("myhandler", func(_ context.Context, _ map[string]any) (any, error) {
+		return "ok", nil
+	})
+	cache := NewResolutionCache(0)
+	var runner *DefaultRunner
+	for range 2 {
+		runner = NewRunner(WithIndex(idx), WithLocalRegistry(localReg), WithValidation(false, false), WithResolutionCache(cache))
+	}
+	if len(idx.listeners) != 1 {
+		t.Fatalf("index has %d listeners, want the cache subscribed once", len(idx.listeners))
+	}
+
+	for _, event := range []index.ChangeEvent{
+		{Type: index.ChangeUpdated, ToolID: "mytool"},
+		{Type: index.ChangeRefreshed},
+	} {
+		if _, err := runner.Run(context.Background(), "mytool", nil); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		idx.notify(event)
+		if cache.Len() != 0 {
+			t.Errorf("after a %s event, cache.Len() = %d, want 0", event.Type, cache.Len())
+		}
+	}
+
+	unsubscribe := cache.Watch(idx)
+	unsubscribe()
+	if len(idx.listeners) != 1 {
+		t.Errorf("stopping a repeated Watch left %d listeners, want 1", len(idx.listeners))
+	}
+}
@@ -508,3 +508,54 @@ func TestRunChain_BackendInStepResult_OnError(t *testing.T) {
 		t.Errorf("results[0].Backend.Local = %#v, want myhandler", results[0].Backend.Local)
 	}
 }
+
+func TestRunChain_Dedup(t *testing.T) {
+	idx := newMockIndex()
+	for _, name := range []string{"fetch", "store"} {
+		backend := testLocalBackend(name + "-handler")
+		mustRegisterTool(t, idx, testTool(name), backend)
+		idx.DefaultBackends[name] = backend
+	}
+
+	calls := map[string]int{}
+	localReg := newMockLocalRegistry()
+	localReg.Register("fetch-handler", func(_ context.Context, args map[string]any) (any, error) {
+		calls["fetch"]++
+		return args["url"], nil
+	})
+	localReg.Register("store-handler", func(_ context.Context, _ map[string]any) (any, error) {
+		calls["store"]++
+		return "stored", nil
+	})
+
+	runner := NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithValidation(false, false),
+		WithChainDedup(true),
+	)
+
+	steps := []ChainStep{
+		{ToolID: "fetch", Args: map[string]any{"url": "a"}},
+		{ToolID: "fetch", Args: map[string]any{"url": "a"}},
+		{ToolID: "store", UsePrevious: true},
+		{ToolID: "fetch", Args: map[string]any{"url": "b"}},
+	}
+
+	final, results, err := runner.RunChain(context.Background(), steps)
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if calls["fetch"] != 2 || calls["store"] != 1 {
+		t.Errorf("calls = %v, want fetch 2, store 1", calls)
+	}
+	if !results[1].Deduplicated || results[0].Deduplicated || results[3].Deduplicated {
+		t.Errorf("Deduplicated = %v %v %v", results[0].Deduplicated, results[1].Deduplicated, results[3].Deduplicated)
+	}
+	if results[1].Result.Structured != "a" {
+		t.Errorf("deduplicated result = %v, want a", results[1].Result.Structured)
+	}
+	if final.Structured != "b" {
+		t.Errorf("final = %v, want b", final.Structured)
+	}
+}
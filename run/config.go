@@ -24,6 +24,14 @@ type Config struct {
 	// Defaults to index.DefaultBackendSelector (local > provider > mcp).
 	BackendSelector index.BackendSelector
 
+	// ResolutionCache caches resolved tools and backends by tool ID.
+	// NewRunner subscribes it to Index's change notifications when Index
+	// implements index.ChangeNotifier. The subscription is never removed,
+	// so the cache lives as long as Index; share one cache per Index
+	// rather than creating one per runner.
+	// Optional; when nil, every call resolves against Index and resolvers.
+	ResolutionCache *ResolutionCache
+
 	// Validation
 
 	// Validator validates tool inputs and outputs against JSON Schema.
@@ -38,6 +46,41 @@ type Config struct {
 	// Defaults to true.
 	ValidateOutput bool
 
+	// Validators apply custom rules to arguments before execution and to
+	// results after execution. They run in order after schema validation.
+	Validators []Validator
+
+	// Hooks run around each execution. See Hooks for ordering.
+	Hooks []Hooks
+
+	// ContextArgInjector supplies per-request values that are merged into
+	// every tool call's args under ContextArgsKey after input validation.
+	ContextArgInjector ContextArgInjector
+
+	// Chains
+
+	// MaxTotalCost caps the summed cost weights (see ToolCost) of the steps
+	// in a single RunChain call. Zero means unlimited.
+	MaxTotalCost float64
+
+	// Scheduling
+
+	// MaxConcurrency bounds the number of runs and streams executing at
+	// once. When saturated, waiting runs are admitted by Priority
+	// (see WithPriority). Zero means unlimited.
+	MaxConcurrency int
+
+	// PreemptStreams lets a waiting run cancel an active streaming run of
+	// strictly lower priority, whose stream then ends with ErrPreempted.
+	PreemptStreams bool
+
+	// Health enables per-backend health tracking for Health when non-nil.
+	Health *HealthConfig
+
+	// DedupChainCalls reuses the result of an earlier successful step when a
+	// later step in the same chain has identical (toolID, args).
+	DedupChainCalls bool
+
 	// Executors
 
 	// MCP is the executor for MCP backend tools.
@@ -126,3 +169,65 @@ func WithBackendsResolver(resolver func(id string) ([]model.ToolBackend, error))
 		c.BackendsResolver = resolver
 	}
 }
+
+// WithResolutionCache sets a cache for tool and backend resolution.
+// Changes reported by an index.ChangeNotifier Index invalidate it; for
+// other registries, invalidate the cache when they change.
+func WithResolutionCache(cache *ResolutionCache) ConfigOption {
+	return func(c *Config) {
+		c.ResolutionCache = cache
+	}
+}
+
+// WithValidators appends custom validators to the run pipeline.
+func WithValidators(validators ...Validator) ConfigOption {
+	return func(c *Config) {
+		c.Validators = append(c.Validators, validators...)
+	}
+}
+
+// WithHooks appends execution hooks to the runner.
+func WithHooks(hooks ...Hooks) ConfigOption {
+	return func(c *Config) {
+		c.Hooks = append(c.Hooks, hooks...)
+	}
+}
+
+// WithMaxTotalCost caps the total cost weight of each chain.
+func WithMaxTotalCost(limit float64) ConfigOption {
+	return func(c *Config) {
+		c.MaxTotalCost = limit
+	}
+}
+
+// WithContextArgInjector sets the injector used to pass per-request
+// context values to tools under ContextArgsKey.
+func WithContextArgInjector(injector ContextArgInjector) ConfigOption {
+	return func(c *Config) {
+		c.ContextArgInjector = injector
+	}
+}
+
+// WithChainDedup enables reuse of identical step results within a chain.
+func WithChainDedup(enabled bool) ConfigOption {
+	return func(c *Config) {
+		c.DedupChainCalls = enabled
+	}
+}
+
+// WithMaxConcurrency bounds concurrent runs and enables priority scheduling.
+// When preempt is true, higher-priority runs may cancel lower-priority streams.
+func WithMaxConcurrency(limit int, preempt bool) ConfigOption {
+	return func(c *Config) {
+		c.MaxConcurrency = limit
+		c.PreemptStreams = preempt
+	}
+}
+
+// WithHealthTracking records dispatch outcomes per backend so that
+// DefaultRunner.Health can report status. Zero fields use defaults.
+func WithHealthTracking(cfg HealthConfig) ConfigOption {
+	return func(c *Config) {
+		c.Health = &cfg
+	}
+}
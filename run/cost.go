@@ -0,0 +1,78 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+// ErrCostLimitExceeded is returned by RunChain when the next step would
+// push the chain's accumulated cost above Config.MaxTotalCost.
+var ErrCostLimitExceeded = errors.New("chain cost limit exceeded")
+
+// CostMetaKey is the tool metadata (_meta) key holding a tool's cost weight.
+const CostMetaKey = "toolexec/cost"
+
+// DefaultToolCost is the weight of a tool that declares no cost.
+const DefaultToolCost = 1.0
+
+// ToolCost returns the cost weight declared in tool.Meta[CostMetaKey].
+// Tools without a valid, non-negative weight cost DefaultToolCost.
+func ToolCost(tool model.Tool) float64 {
+	raw, ok := tool.Meta[CostMetaKey]
+	if !ok {
+		return DefaultToolCost
+	}
+	var cost float64
+	switch v := raw.(type) {
+	case float64:
+		cost = v
+	case float32:
+		cost = float64(v)
+	case int:
+		cost = float64(v)
+	case int64:
+		cost = float64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return DefaultToolCost
+		}
+		cost = f
+	default:
+		return DefaultToolCost
+	}
+	if cost < 0 {
+		return DefaultToolCost
+	}
+	return cost
+}
+
+// stepCost resolves toolID and returns its cost weight.
+// Unresolvable tools cost DefaultToolCost; the resolution error itself
+// surfaces when the step runs.
+func (r *DefaultRunner) stepCost(ctx context.Context, toolID string) float64 {
+	resolved, err := r.resolveTool(ctx, toolID)
+	if err != nil {
+		return DefaultToolCost
+	}
+	return ToolCost(resolved.tool)
+}
+
+// chargeStep adds the step's cost to spent, failing if the total would
+// exceed Config.MaxTotalCost. It is a no-op when no limit is configured.
+func (r *DefaultRunner) chargeStep(ctx context.Context, toolID string, spent *float64) (float64, error) {
+	if r.cfg.MaxTotalCost <= 0 {
+		return 0, nil
+	}
+	cost := r.stepCost(ctx, toolID)
+	if *spent+cost > r.cfg.MaxTotalCost {
+		return cost, WrapError(toolID, nil, "cost_limit", fmt.Errorf("%w: spent %g + step %g > limit %g",
+			ErrCostLimitExceeded, *spent, cost, r.cfg.MaxTotalCost))
+	}
+	*spent += cost
+	return cost, nil
+}
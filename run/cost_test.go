@@ -0,0 +1,91 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestToolCost(t *testing.T) {
+	tests := []struct {
+		name string
+		meta mcp.Meta
+		want float64
+	}{
+		{"no meta", nil, DefaultToolCost},
+		{"float", mcp.Meta{CostMetaKey: 2.5}, 2.5},
+		{"int", mcp.Meta{CostMetaKey: 10}, 10},
+		{"json number", mcp.Meta{CostMetaKey: json.Number("4")}, 4},
+		{"zero", mcp.Meta{CostMetaKey: 0.0}, 0},
+		{"negative", mcp.Meta{CostMetaKey: -3.0}, DefaultToolCost},
+		{"wrong type", mcp.Meta{CostMetaKey: "high"}, DefaultToolCost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := testTool("t")
+			tool.Meta = tt.meta
+			if got := ToolCost(tool); got != tt.want {
+				t.Errorf("ToolCost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func newCostChainRunner(t *testing.T, limit float64, calls *[]string) *DefaultRunner {
+	t.Helper()
+	idx := newMockIndex()
+	localReg := newMockLocalRegistry()
+	for name, cost := range map[string]float64{"cheap": 1, "llm": 5} {
+		tool := testTool(name)
+		tool.Meta = mcp.Meta{CostMetaKey: cost}
+		backend := testLocalBackend(name + "-handler")
+		mustRegisterTool(t, idx, tool, backend)
+		idx.DefaultBackends[name] = backend
+		localReg.Register(name+"-handler", func(_ context.Context, _ map[string]any) (any, error) {
+			*calls = append(*calls, name)
+			return name, nil
+		})
+	}
+	return NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithValidation(false, false),
+		WithMaxTotalCost(limit),
+	)
+}
+
+func TestRunChain_MaxTotalCost(t *testing.T) {
+	var calls []string
+	runner := newCostChainRunner(t, 7, &calls)
+
+	steps := []ChainStep{{ToolID: "cheap"}, {ToolID: "llm"}, {ToolID: "llm"}}
+	_, results, err := runner.RunChain(context.Background(), steps)
+	if !errors.Is(err, ErrCostLimitExceeded) {
+		t.Fatalf("RunChain() error = %v, want ErrCostLimitExceeded", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("len(results) = %d, want 2", len(results))
+	}
+	if results[0].Cost != 1 || results[1].Cost != 5 {
+		t.Errorf("costs = %v, %v, want 1, 5", results[0].Cost, results[1].Cost)
+	}
+	if len(calls) != 2 {
+		t.Errorf("calls = %v, want the third step not to run", calls)
+	}
+}
+
+func TestRunChain_MaxTotalCost_WithinLimit(t *testing.T) {
+	var calls []string
+	runner := newCostChainRunner(t, 6, &calls)
+
+	steps := []ChainStep{{ToolID: "cheap"}, {ToolID: "llm"}}
+	if _, _, err := runner.RunChain(context.Background(), steps); err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if len(calls) != 2 {
+		t.Errorf("calls = %v, want 2", calls)
+	}
+}
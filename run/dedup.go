@@ -0,0 +1,15 @@
+package run
+
+import "encoding/json"
+
+// CallKey returns a canonical key identifying a (toolID, args) call.
+// Identical calls produce identical keys regardless of map ordering.
+// The second return value is false when args cannot be encoded, in which
+// case the call must not be deduplicated.
+func CallKey(toolID string, args map[string]any) (string, bool) {
+	b, err := json.Marshal(args)
+	if err != nil {
+		return "", false
+	}
+	return toolID + "\x00" + string(b), true
+}
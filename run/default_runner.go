@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/jonwraymond/tooldiscovery/index"
 	"github.com/jonwraymond/toolfoundation/model"
 )
 
@@ -12,7 +14,9 @@ import (
 // It uses the configured Index, resolvers, validators, and executors
 // to resolve, validate, and execute tools.
 type DefaultRunner struct {
-	cfg Config
+	cfg    Config
+	sched  *scheduler
+	health *healthTracker
 }
 
 // NewRunner creates a new DefaultRunner with the given options.
@@ -26,11 +30,42 @@ func NewRunner(opts ...ConfigOption) *DefaultRunner {
 		opt(&cfg)
 	}
 	cfg.applyDefaults()
-	return &DefaultRunner{cfg: cfg}
+	r := &DefaultRunner{cfg: cfg}
+	if cfg.MaxConcurrency > 0 {
+		r.sched = newScheduler(cfg.MaxConcurrency, cfg.PreemptStreams)
+	}
+	if cfg.Health != nil {
+		r.health = newHealthTracker(*cfg.Health)
+	}
+	if notifier, ok := cfg.Index.(index.ChangeNotifier); ok && cfg.ResolutionCache != nil {
+		cfg.ResolutionCache.Watch(notifier)
+	}
+	return r
 }
 
 // Run executes a single tool and returns the normalized result.
 func (r *DefaultRunner) Run(ctx context.Context, toolID string, args map[string]any) (RunResult, error) {
+	return r.run(ctx, toolID, args, nil)
+}
+
+// run implements Run, forwarding backend progress notifications to onProgress.
+func (r *DefaultRunner) run(ctx context.Context, toolID string, args map[string]any, onProgress ProgressCallback) (RunResult, error) {
+	if r.sched != nil {
+		if err := r.sched.acquire(ctx, PriorityFromContext(ctx)); err != nil {
+			r.onError(ctx, toolID, err)
+			return RunResult{}, err
+		}
+		defer r.sched.release()
+	}
+	result, err := r.execute(ctx, toolID, args, onProgress)
+	if err != nil {
+		r.onError(ctx, toolID, err)
+	}
+	return result, err
+}
+
+// execute performs resolution, hooks, validation, dispatch and normalization.
+func (r *DefaultRunner) execute(ctx context.Context, toolID string, args map[string]any, onProgress ProgressCallback) (RunResult, error) {
 	if err := ctx.Err(); err != nil {
 		return RunResult{}, err
 	}
@@ -49,39 +84,64 @@ func (r *DefaultRunner) Run(ctx context.Context, toolID string, args map[string]
 		return RunResult{}, WrapError(toolID, nil, "select_backend", err)
 	}
 
-	// 3. Validate input
+	// 3. Before hooks
+	args, err = r.beforeRun(ctx, resolved.tool, args)
+	if err != nil {
+		return RunResult{}, WrapError(toolID, &backend, "before_run", fmt.Errorf("%w: %w", ErrVetoed, err))
+	}
+
+	// 4. Validate input
 	if r.cfg.ValidateInput {
 		if err := r.cfg.Validator.ValidateInput(&resolved.tool, args); err != nil {
 			return RunResult{}, WrapError(toolID, &backend, "validate_input", fmt.Errorf("%w: %v", ErrValidation, err))
 		}
 	}
+	if err := r.validateArgs(ctx, resolved.tool, args); err != nil {
+		return RunResult{}, WrapError(toolID, &backend, "validate_input", fmt.Errorf("%w: %v", ErrValidation, err))
+	}
 
-	// 4. Dispatch
-	dispatchResult, err := r.dispatch(ctx, resolved.tool, backend, args)
+	// 5. Dispatch
+	args = r.injectContextArgs(ctx, args)
+	dispatchStart := time.Now()
+	dispatchResult, err := r.dispatchWithProgress(ctx, resolved.tool, backend, args, onProgress)
+	if r.health != nil {
+		r.health.record(backend, time.Since(dispatchStart), err, time.Now())
+	}
 	if err != nil {
 		return RunResult{}, WrapError(toolID, &backend, "execute", fmt.Errorf("%w: %v", ErrExecution, err))
 	}
 
-	// 5. Normalize
+	// 6. Normalize
 	result := r.normalize(resolved.tool, backend, dispatchResult)
 
-	// 6. Validate output
+	// 7. Validate output
 	if r.cfg.ValidateOutput {
 		if err := r.cfg.Validator.ValidateOutput(&resolved.tool, result.Structured); err != nil {
 			return RunResult{}, WrapError(toolID, &backend, "validate_output", fmt.Errorf("%w: %v", ErrOutputValidation, err))
 		}
 	}
+	if err := r.validateResult(ctx, resolved.tool, result); err != nil {
+		return RunResult{}, WrapError(toolID, &backend, "validate_output", fmt.Errorf("%w: %v", ErrOutputValidation, err))
+	}
+
+	// 8. After hooks
+	result, err = r.afterRun(ctx, resolved.tool, result)
+	if err != nil {
+		return RunResult{}, WrapError(toolID, &backend, "after_run", err)
+	}
 
 	return result, nil
 }
 
 // RunWithProgress executes a tool and emits coarse progress updates.
+// Progress notifications reported by MCP backends (see MCPProgressExecutor)
+// are forwarded between the "started" and final events.
 func (r *DefaultRunner) RunWithProgress(ctx context.Context, toolID string, args map[string]any, onProgress ProgressCallback) (RunResult, error) {
 	if onProgress != nil {
 		onProgress(ProgressEvent{Progress: 0, Total: 1, Message: "started"})
 	}
 
-	result, err := r.Run(ctx, toolID, args)
+	result, err := r.run(ctx, toolID, args, onProgress)
 
 	if onProgress != nil {
 		msg := "completed"
@@ -96,6 +156,38 @@ func (r *DefaultRunner) RunWithProgress(ctx context.Context, toolID string, args
 
 // RunStream executes a tool with streaming support.
 func (r *DefaultRunner) RunStream(ctx context.Context, toolID string, args map[string]any) (<-chan StreamEvent, error) {
+	if r.sched == nil {
+		events, err := r.startStream(ctx, toolID, args, nil)
+		if err != nil {
+			r.onError(ctx, toolID, err)
+		}
+		return events, err
+	}
+
+	priority := PriorityFromContext(ctx)
+	if err := r.sched.acquire(ctx, priority); err != nil {
+		r.onError(ctx, toolID, err)
+		return nil, err
+	}
+	streamCtx, cancel := context.WithCancelCause(ctx)
+	slot := r.sched.addStream(priority, cancel)
+	done := func() {
+		r.sched.removeStream(slot)
+		cancel(nil)
+		r.sched.release()
+	}
+
+	events, err := r.startStream(streamCtx, toolID, args, done)
+	if err != nil {
+		done()
+		r.onError(ctx, toolID, err)
+	}
+	return events, err
+}
+
+// startStream resolves, validates and dispatches a streaming execution.
+// onDone, if non-nil, is called once the returned channel has been closed.
+func (r *DefaultRunner) startStream(ctx context.Context, toolID string, args map[string]any, onDone func()) (<-chan StreamEvent, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
@@ -114,14 +206,24 @@ func (r *DefaultRunner) RunStream(ctx context.Context, toolID string, args map[s
 		return nil, WrapError(toolID, nil, "select_backend", err)
 	}
 
-	// 3. Validate input
+	// 3. Before hooks
+	args, err = r.beforeRun(ctx, resolved.tool, args)
+	if err != nil {
+		return nil, WrapError(toolID, &backend, "before_run", fmt.Errorf("%w: %w", ErrVetoed, err))
+	}
+
+	// 4. Validate input
 	if r.cfg.ValidateInput {
 		if err := r.cfg.Validator.ValidateInput(&resolved.tool, args); err != nil {
 			return nil, WrapError(toolID, &backend, "validate_input", fmt.Errorf("%w: %v", ErrValidation, err))
 		}
 	}
+	if err := r.validateArgs(ctx, resolved.tool, args); err != nil {
+		return nil, WrapError(toolID, &backend, "validate_input", fmt.Errorf("%w: %v", ErrValidation, err))
+	}
 
-	// 4. Dispatch stream
+	// 5. Dispatch stream
+	args = r.injectContextArgs(ctx, args)
 	rawChan, err := r.dispatchStream(ctx, resolved.tool, backend, args)
 	if err != nil {
 		return nil, WrapError(toolID, &backend, "stream", err)
@@ -131,30 +233,8 @@ func (r *DefaultRunner) RunStream(ctx context.Context, toolID string, args map[s
 		return nil, WrapError(toolID, &backend, "stream", ErrStreamNotSupported)
 	}
 
-	// 5. Wrap channel to stamp ToolID on events when missing
-	out := make(chan StreamEvent)
-	go func() {
-		defer close(out)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case ev, ok := <-rawChan:
-				if !ok {
-					return
-				}
-				if ev.ToolID == "" {
-					ev.ToolID = toolID
-				}
-				select {
-				case out <- ev:
-				case <-ctx.Done():
-					return
-				}
-			}
-		}
-	}()
-	return out, nil
+	// 6. Wrap channel to stamp ToolID and report interruptions
+	return forwardStream(ctx, toolID, rawChan, onDone), nil
 }
 
 // RunChain executes a sequence of tool steps.
@@ -178,16 +258,50 @@ func (r *DefaultRunner) runChainWithProgress(ctx context.Context, steps []ChainS
 
 	var results []StepResult
 	var previous any
+	var spent float64
+	var seen map[string]RunResult
+	if r.cfg.DedupChainCalls {
+		seen = make(map[string]RunResult)
+	}
 
 	for i, step := range steps {
 		if err := ctx.Err(); err != nil {
 			return RunResult{}, results, err
 		}
+
 		// Build args with previous injection
-		args := r.buildChainArgs(step, previous)
+		args := buildChainArgs(step, previous)
+
+		// Reuse an identical earlier step's result
+		key, keyOK := "", false
+		if seen != nil {
+			key, keyOK = CallKey(step.ToolID, args)
+		}
+		if cached, hit := seen[key]; keyOK && hit {
+			results = append(results, StepResult{
+				ToolID:       step.ToolID,
+				Backend:      cached.Backend,
+				Result:       cached,
+				Deduplicated: true,
+			})
+			if onProgress != nil {
+				onProgress(ProgressEvent{
+					Progress: float64(i + 1),
+					Total:    float64(len(steps)),
+					Message:  "step_completed",
+				})
+			}
+			previous = cached.Structured
+			continue
+		}
+
+		cost, err := r.chargeStep(ctx, step.ToolID, &spent)
+		if err != nil {
+			return RunResult{}, results, err
+		}
 
-		// Execute the step
-		result, err := r.Run(ctx, step.ToolID, args)
+		// Execute the step, retrying per its policy
+		result, retries, err := r.runWithRetry(ctx, step, args)
 
 		// Resolve backend for StepResult (we need to resolve again to get it)
 		var backend model.ToolBackend
@@ -205,6 +319,8 @@ func (r *DefaultRunner) runChainWithProgress(ctx context.Context, steps []ChainS
 			Backend: backend,
 			Result:  result,
 			Err:     err,
+			Retries: retries,
+			Cost:    cost,
 		}
 		results = append(results, stepResult)
 
@@ -225,6 +341,10 @@ func (r *DefaultRunner) runChainWithProgress(ctx context.Context, steps []ChainS
 			return RunResult{}, results, err
 		}
 
+		if keyOK {
+			seen[key] = result
+		}
+
 		// Update previous for next step
 		previous = result.Structured
 	}
@@ -236,7 +356,7 @@ func (r *DefaultRunner) runChainWithProgress(ctx context.Context, steps []ChainS
 
 // buildChainArgs builds the args map for a chain step.
 // If UsePrevious is true, injects previous result at args["previous"].
-func (r *DefaultRunner) buildChainArgs(step ChainStep, previous any) map[string]any {
+func buildChainArgs(step ChainStep, previous any) map[string]any {
 	args := make(map[string]any)
 	for k, v := range step.Args {
 		args[k] = v
@@ -247,5 +367,21 @@ func (r *DefaultRunner) buildChainArgs(step ChainStep, previous any) map[string]
 	return args
 }
 
-// Ensure DefaultRunner implements Runner.
-var _ Runner = (*DefaultRunner)(nil)
+// Resolve returns the tool definition and available backends for toolID
+// without executing it.
+func (r *DefaultRunner) Resolve(ctx context.Context, toolID string) (model.Tool, []model.ToolBackend, error) {
+	if toolID == "" {
+		return model.Tool{}, nil, WrapError(toolID, nil, "validate_tool_id", ErrInvalidToolID)
+	}
+	resolved, err := r.resolveTool(ctx, toolID)
+	if err != nil {
+		return model.Tool{}, nil, WrapError(toolID, nil, "resolve", err)
+	}
+	return resolved.tool, append([]model.ToolBackend(nil), resolved.backends...), nil
+}
+
+// Ensure DefaultRunner implements Runner and Resolver.
+var (
+	_ Runner   = (*DefaultRunner)(nil)
+	_ Resolver = (*DefaultRunner)(nil)
+)
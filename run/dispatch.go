@@ -22,9 +22,15 @@ type dispatchResult struct {
 
 // dispatch executes a tool via the appropriate backend.
 func (r *DefaultRunner) dispatch(ctx context.Context, tool model.Tool, backend model.ToolBackend, args map[string]any) (*dispatchResult, error) {
+	return r.dispatchWithProgress(ctx, tool, backend, args, nil)
+}
+
+// dispatchWithProgress executes a tool via the appropriate backend,
+// forwarding backend progress notifications to onProgress when supported.
+func (r *DefaultRunner) dispatchWithProgress(ctx context.Context, tool model.Tool, backend model.ToolBackend, args map[string]any, onProgress ProgressCallback) (*dispatchResult, error) {
 	switch backend.Kind {
 	case model.BackendKindMCP:
-		return r.dispatchMCP(ctx, tool, backend, args)
+		return r.dispatchMCP(ctx, tool, backend, args, onProgress)
 	case model.BackendKindProvider:
 		return r.dispatchProvider(ctx, tool, backend, args)
 	case model.BackendKindLocal:
@@ -35,7 +41,9 @@ func (r *DefaultRunner) dispatch(ctx context.Context, tool model.Tool, backend m
 }
 
 // dispatchMCP executes a tool via an MCP server.
-func (r *DefaultRunner) dispatchMCP(ctx context.Context, tool model.Tool, backend model.ToolBackend, args map[string]any) (*dispatchResult, error) {
+// When onProgress is set and the executor implements MCPProgressExecutor,
+// server progress notifications are forwarded to it.
+func (r *DefaultRunner) dispatchMCP(ctx context.Context, tool model.Tool, backend model.ToolBackend, args map[string]any, onProgress ProgressCallback) (*dispatchResult, error) {
 	if r.cfg.MCP == nil {
 		return nil, fmt.Errorf("MCP executor not configured")
 	}
@@ -50,7 +58,13 @@ func (r *DefaultRunner) dispatchMCP(ctx context.Context, tool model.Tool, backen
 		Arguments: args,
 	}
 
-	result, err := r.cfg.MCP.CallTool(ctx, backend.MCP.ServerName, params)
+	var result *mcp.CallToolResult
+	var err error
+	if pe, ok := r.cfg.MCP.(MCPProgressExecutor); ok && onProgress != nil {
+		result, err = pe.CallToolWithProgress(ctx, backend.MCP.ServerName, params, onProgress)
+	} else {
+		result, err = r.cfg.MCP.CallTool(ctx, backend.MCP.ServerName, params)
+	}
 	if err != nil {
 		return nil, err
 	}
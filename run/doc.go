@@ -19,6 +19,18 @@
 //  1. Attempt Index.GetTool(id) when Index is configured
 //  2. If not found, fall back to injected resolvers (ToolResolver, BackendsResolver)
 //
+// WithResolutionCache memoizes successful resolutions for hot tools. Entries
+// expire after a TTL, are dropped as an index.ChangeNotifier Index reports
+// changes, and can be dropped explicitly via Invalidate/InvalidateAll when
+// another registry changes.
+//
+// # Interfaces
+//
+// Runner is composed of the narrow Executor interface (Run, RunChain,
+// RunStream). Read-only clients depend on Resolver, which DefaultRunner
+// also implements. ExecutorFunc and ResolverFunc adapt plain functions
+// for integrations that have no resolver or registry wiring.
+//
 // # Backend Selection
 //
 // When multiple backends exist for the same tool, a configurable BackendSelector
@@ -31,12 +43,57 @@
 // Output validation is performed after execution when tool.OutputSchema is present.
 // Both can be configured via ValidateInput and ValidateOutput options.
 //
+// Custom business rules (path allowlists, PII checks) plug in via
+// WithValidators. Each Validator sees (tool, args) before dispatch and
+// (tool, result) after normalization, independent of schema validation.
+//
+// # Hooks
+//
+// WithHooks registers BeforeRun, AfterRun and OnError callbacks that let
+// infrastructure code rewrite args, veto execution (ErrVetoed), enrich
+// results, or observe failures. Multiple Hooks nest like middleware.
+//
+// WithContextArgInjector passes per-request values from the context to
+// every tool under args[ContextArgsKey], after input validation. The
+// reserved key always overrides caller-supplied values.
+//
+// # Scheduling
+//
+// WithMaxConcurrency bounds concurrent runs and streams. When saturated,
+// waiting calls are admitted highest Priority first (see WithPriority),
+// FIFO within a priority. With preemption enabled, a waiting call cancels
+// an active stream of strictly lower priority; that stream ends with a
+// StreamEventCancelled whose Err matches ErrPreempted.
+//
+// # Health
+//
+// WithHealthTracking records dispatch latency and outcome per backend.
+// DefaultRunner.Health (HealthReporter) returns reachability, last error,
+// p95 latency and an advisory circuit state for each backend, suitable for
+// a /healthz endpoint.
+//
 // # Chains
 //
 // Chains execute steps sequentially with explicit data passing.
 // If UsePrevious is true, the prior step's structured result is injected
 // at args["previous"] (overwriting any existing value).
-// Chains stop on first error (v1 policy).
+// Chains stop on first error (v1 policy). A step with a RetryPolicy is
+// retried with jittered exponential backoff on the configured error codes
+// (see CodeOf) before the chain stops; StepResult.Retries records how many
+// retries were made.
+//
+// Tools may declare a cost weight in their _meta under CostMetaKey
+// (default DefaultToolCost). With WithMaxTotalCost, RunChain refuses to
+// start a step that would push the chain's total above the limit and
+// returns ErrCostLimitExceeded.
+//
+// WithChainDedup reuses the result of an earlier successful step when a
+// later step has identical (toolID, args), marking it Deduplicated.
+//
+// # Fan-out
+//
+// RunMany (FanOutRunner) executes one tool over many argument sets with
+// bounded concurrency and returns per-item results in input order.
 //
 // # Example
 //
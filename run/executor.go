@@ -25,6 +25,24 @@ type MCPExecutor interface {
 	CallToolStream(ctx context.Context, serverName string, params *mcp.CallToolParams) (<-chan StreamEvent, error)
 }
 
+// MCPProgressExecutor is an optional extension of MCPExecutor for servers
+// that emit progress notifications while a tool call is in flight.
+// When an MCPExecutor also implements this interface, the runner forwards
+// those notifications to RunWithProgress callbacks and, when CallToolStream
+// returns ErrStreamNotSupported, to RunStream as StreamEventProgress events.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines and return ctx.Err() when canceled.
+// - Progress: onProgress must be invoked in-order and never after the call returns;
+// a nil onProgress is allowed.
+// - Ownership: params are read-only; returned results are caller-owned.
+type MCPProgressExecutor interface {
+	// CallToolWithProgress executes a tool call, requesting progress
+	// notifications from the server and delivering each to onProgress.
+	CallToolWithProgress(ctx context.Context, serverName string, params *mcp.CallToolParams, onProgress ProgressCallback) (*mcp.CallToolResult, error)
+}
+
 // ProviderExecutor executes provider-bound tools.
 // It is intentionally generic but uses canonical tool IDs and args.
 //
@@ -0,0 +1,107 @@
+package run
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultFanOutConcurrency bounds RunMany when FanOutOptions.Concurrency is unset.
+const DefaultFanOutConcurrency = 4
+
+// FanOutOptions controls RunMany.
+type FanOutOptions struct {
+	// Concurrency is the maximum number of executions in flight.
+	// Defaults to DefaultFanOutConcurrency when <= 0.
+	Concurrency int
+
+	// StopOnError cancels outstanding items after the first failure and
+	// makes RunMany return that failure.
+	StopOnError bool
+}
+
+// FanOutResult is the outcome of one argument set passed to RunMany.
+type FanOutResult struct {
+	// Index is the position of the argument set in the input slice.
+	Index int `json:"index"`
+
+	// Result is the normalized result when Err is nil.
+	Result RunResult `json:"result"`
+
+	// Err is set if the item failed or was not run.
+	// Not serialized to JSON - callers should check this field explicitly.
+	Err error `json:"-"`
+}
+
+// FanOutRunner is an optional interface for executing one tool over many
+// argument sets, a common map-style agent pattern.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines; items not started when ctx
+// ends report ctx.Err().
+// - Ordering: results are returned in input order, one per argument set.
+// - Errors: per-item failures are reported in FanOutResult.Err; the returned
+// error is non-nil only when ctx ends or StopOnError trips.
+type FanOutRunner interface {
+	// RunMany executes toolID once per argument set with bounded concurrency.
+	RunMany(ctx context.Context, toolID string, argSets []map[string]any, opts FanOutOptions) ([]FanOutResult, error)
+}
+
+// RunMany executes toolID once per argument set with bounded concurrency.
+// Each item goes through the full Run pipeline (hooks, validation, dispatch).
+func (r *DefaultRunner) RunMany(ctx context.Context, toolID string, argSets []map[string]any, opts FanOutOptions) ([]FanOutResult, error) {
+	if toolID == "" {
+		return nil, WrapError(toolID, nil, "validate_tool_id", ErrInvalidToolID)
+	}
+	limit := opts.Concurrency
+	if limit <= 0 {
+		limit = DefaultFanOutConcurrency
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]FanOutResult, len(argSets))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for i, args := range argSets {
+		results[i].Index = i
+		select {
+		case sem <- struct{}{}:
+		case <-runCtx.Done():
+			results[i].Err = runCtx.Err()
+			continue
+		}
+		if err := runCtx.Err(); err != nil {
+			<-sem
+			results[i].Err = err
+			continue
+		}
+		wg.Add(1)
+		go func(i int, args map[string]any) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result, err := r.Run(runCtx, toolID, args)
+			results[i].Result = result
+			results[i].Err = err
+			if err != nil && opts.StopOnError {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i, args)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	return results, firstErr
+}
+
+// Ensure DefaultRunner implements FanOutRunner.
+var _ FanOutRunner = (*DefaultRunner)(nil)
@@ -0,0 +1,108 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newFanOutRunner(t *testing.T, handler func(context.Context, map[string]any) (any, error)) *DefaultRunner {
+	t.Helper()
+	idx := newMockIndex()
+	backend := testLocalBackend("myhandler")
+	mustRegisterTool(t, idx, testTool("mytool"), backend)
+	idx.DefaultBackends["mytool"] = backend
+
+	localReg := newMockLocalRegistry()
+	localReg.Register("myhandler", handler)
+	return NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithValidation(false, false),
+	)
+}
+
+func TestRunMany_BoundedConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	runner := newFanOutRunner(t, func(_ context.Context, args map[string]any) (any, error) {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		inFlight.Add(-1)
+		if args["n"] == 3 {
+			return nil, errors.New("bad item")
+		}
+		return args["n"], nil
+	})
+
+	argSets := make([]map[string]any, 8)
+	for i := range argSets {
+		argSets[i] = map[string]any{"n": i}
+	}
+
+	results, err := runner.RunMany(context.Background(), "mytool", argSets, FanOutOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatalf("RunMany() error = %v", err)
+	}
+	if len(results) != 8 {
+		t.Fatalf("len(results) = %d, want 8", len(results))
+	}
+	if peak.Load() > 2 {
+		t.Errorf("peak concurrency = %d, want <= 2", peak.Load())
+	}
+	for i, r := range results {
+		if r.Index != i {
+			t.Errorf("results[%d].Index = %d", i, r.Index)
+		}
+		if i == 3 {
+			if !errors.Is(r.Err, ErrExecution) {
+				t.Errorf("results[3].Err = %v, want ErrExecution", r.Err)
+			}
+			continue
+		}
+		if r.Err != nil || r.Result.Structured != i {
+			t.Errorf("results[%d] = %v, %v", i, r.Result.Structured, r.Err)
+		}
+	}
+}
+
+func TestRunMany_StopOnError(t *testing.T) {
+	var calls atomic.Int32
+	runner := newFanOutRunner(t, func(_ context.Context, args map[string]any) (any, error) {
+		calls.Add(1)
+		if args["n"] == 0 {
+			return nil, errors.New("first fails")
+		}
+		return "ok", nil
+	})
+
+	argSets := make([]map[string]any, 20)
+	for i := range argSets {
+		argSets[i] = map[string]any{"n": i}
+	}
+
+	results, err := runner.RunMany(context.Background(), "mytool", argSets, FanOutOptions{Concurrency: 1, StopOnError: true})
+	if !errors.Is(err, ErrExecution) {
+		t.Fatalf("RunMany() error = %v, want ErrExecution", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+	if !errors.Is(results[19].Err, context.Canceled) {
+		t.Errorf("unstarted item Err = %v, want context.Canceled", results[19].Err)
+	}
+}
+
+func TestRunMany_InvalidToolID(t *testing.T) {
+	runner := NewRunner()
+	if _, err := runner.RunMany(context.Background(), "", nil, FanOutOptions{}); !errors.Is(err, ErrInvalidToolID) {
+		t.Errorf("RunMany() error = %v, want ErrInvalidToolID", err)
+	}
+}
@@ -0,0 +1,247 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+// Default health tracking settings.
+const (
+	DefaultHealthWindow           = 100
+	DefaultHealthFailureThreshold = 5
+	DefaultHealthCooldown         = 30 * time.Second
+)
+
+// HealthConfig controls how the runner aggregates backend health from
+// recent executions.
+type HealthConfig struct {
+	// Window is the number of recent calls per backend used for latency
+	// percentiles and failure counts. Defaults to DefaultHealthWindow.
+	Window int
+
+	// FailureThreshold is the number of consecutive failures that opens a
+	// backend's circuit. Defaults to DefaultHealthFailureThreshold.
+	FailureThreshold int
+
+	// Cooldown is how long an open circuit stays open after the last
+	// failure. Defaults to DefaultHealthCooldown.
+	Cooldown time.Duration
+}
+
+// BackendHealth summarizes recent executions against one backend.
+type BackendHealth struct {
+	// Key identifies the backend (e.g. "mcp:github", "provider:openai",
+	// "local:echo").
+	Key string `json:"key"`
+
+	// Kind is the backend kind.
+	Kind model.BackendKind `json:"kind"`
+
+	// Reachable is false while the circuit is open.
+	Reachable bool `json:"reachable"`
+
+	// CircuitOpen reports that the backend has failed FailureThreshold
+	// times in a row within Cooldown. The state is advisory; the runner
+	// still dispatches calls to the backend.
+	CircuitOpen bool `json:"circuitOpen"`
+
+	// Calls and Failures count executions within the window.
+	Calls    int `json:"calls"`
+	Failures int `json:"failures"`
+
+	// ConsecutiveFailures counts failures since the last success.
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+
+	// P95Latency is the 95th percentile dispatch latency within the window.
+	P95Latency time.Duration `json:"p95Latency"`
+
+	// LastError is the most recent failure message, if any.
+	LastError string `json:"lastError,omitempty"`
+
+	// LastErrorAt and LastSuccessAt are zero when no such call was seen.
+	LastErrorAt   time.Time `json:"lastErrorAt,omitzero"`
+	LastSuccessAt time.Time `json:"lastSuccessAt,omitzero"`
+}
+
+// HealthReport is the aggregated health of every backend the runner has
+// dispatched to, suitable for exposing on a /healthz endpoint.
+type HealthReport struct {
+	// Healthy is true when no backend has an open circuit.
+	Healthy bool `json:"healthy"`
+
+	// Backends is sorted by Key.
+	Backends []BackendHealth `json:"backends"`
+
+	// CheckedAt is when the report was built.
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// HealthReporter is an optional interface for runners that expose
+// backend health.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines.
+// - Ownership: the returned report is a caller-owned snapshot.
+type HealthReporter interface {
+	// Health returns per-backend status aggregated from recent executions.
+	Health(ctx context.Context) (HealthReport, error)
+}
+
+// healthTracker records dispatch outcomes per backend.
+type healthTracker struct {
+	mu       sync.Mutex
+	cfg      HealthConfig
+	backends map[string]*backendStats
+}
+
+// backendStats holds a ring buffer of recent calls for one backend.
+type backendStats struct {
+	kind                model.BackendKind
+	samples             []healthSample
+	next                int
+	consecutiveFailures int
+	lastError           string
+	lastErrorAt         time.Time
+	lastSuccessAt       time.Time
+}
+
+type healthSample struct {
+	latency time.Duration
+	failed  bool
+}
+
+func newHealthTracker(cfg HealthConfig) *healthTracker {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultHealthWindow
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = DefaultHealthFailureThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = DefaultHealthCooldown
+	}
+	return &healthTracker{cfg: cfg, backends: make(map[string]*backendStats)}
+}
+
+// backendKey returns a stable identifier for the backend instance.
+func backendKey(b model.ToolBackend) string {
+	switch {
+	case b.Kind == model.BackendKindMCP && b.MCP != nil:
+		return "mcp:" + b.MCP.ServerName
+	case b.Kind == model.BackendKindProvider && b.Provider != nil:
+		return "provider:" + b.Provider.ProviderID
+	case b.Kind == model.BackendKindLocal && b.Local != nil:
+		return "local:" + b.Local.Name
+	}
+	return string(b.Kind)
+}
+
+// record adds one dispatch outcome. Caller cancellations are ignored
+// because they say nothing about backend health.
+func (h *healthTracker) record(backend model.ToolBackend, latency time.Duration, err error, now time.Time) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	key := backendKey(backend)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st, ok := h.backends[key]
+	if !ok {
+		st = &backendStats{kind: backend.Kind, samples: make([]healthSample, 0, h.cfg.Window)}
+		h.backends[key] = st
+	}
+	sample := healthSample{latency: latency, failed: err != nil}
+	if len(st.samples) < h.cfg.Window {
+		st.samples = append(st.samples, sample)
+	} else {
+		st.samples[st.next] = sample
+	}
+	st.next = (st.next + 1) % h.cfg.Window
+
+	if err != nil {
+		st.consecutiveFailures++
+		st.lastError = err.Error()
+		st.lastErrorAt = now
+	} else {
+		st.consecutiveFailures = 0
+		st.lastSuccessAt = now
+	}
+}
+
+// report builds a snapshot of every tracked backend.
+func (h *healthTracker) report(now time.Time) HealthReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	report := HealthReport{Healthy: true, CheckedAt: now}
+	for key, st := range h.backends {
+		bh := BackendHealth{
+			Key:                 key,
+			Kind:                st.kind,
+			Calls:               len(st.samples),
+			ConsecutiveFailures: st.consecutiveFailures,
+			LastError:           st.lastError,
+			LastErrorAt:         st.lastErrorAt,
+			LastSuccessAt:       st.lastSuccessAt,
+		}
+		latencies := make([]time.Duration, 0, len(st.samples))
+		for _, s := range st.samples {
+			if s.failed {
+				bh.Failures++
+			}
+			latencies = append(latencies, s.latency)
+		}
+		bh.P95Latency = percentile(latencies, 0.95)
+		bh.CircuitOpen = st.consecutiveFailures >= h.cfg.FailureThreshold &&
+			now.Sub(st.lastErrorAt) < h.cfg.Cooldown
+		bh.Reachable = !bh.CircuitOpen
+		if bh.CircuitOpen {
+			report.Healthy = false
+		}
+		report.Backends = append(report.Backends, bh)
+	}
+	slices.SortFunc(report.Backends, func(a, b BackendHealth) int {
+		switch {
+		case a.Key < b.Key:
+			return -1
+		case a.Key > b.Key:
+			return 1
+		}
+		return 0
+	})
+	return report
+}
+
+// percentile returns the nearest-rank percentile p (0..1) of values.
+func percentile(values []time.Duration, p float64) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+	slices.Sort(values)
+	rank := int(p*float64(len(values))+0.5) - 1
+	rank = max(0, min(rank, len(values)-1))
+	return values[rank]
+}
+
+// Health returns per-backend status aggregated from recent executions.
+// It returns an empty, healthy report when health tracking is disabled.
+func (r *DefaultRunner) Health(ctx context.Context) (HealthReport, error) {
+	if err := ctx.Err(); err != nil {
+		return HealthReport{}, err
+	}
+	now := time.Now()
+	if r.health == nil {
+		return HealthReport{Healthy: true, CheckedAt: now}, nil
+	}
+	return r.health.report(now), nil
+}
+
+// Ensure DefaultRunner implements HealthReporter.
+var _ HealthReporter = (*DefaultRunner)(nil)
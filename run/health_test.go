@@ -0,0 +1,103 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+func TestHealth_Disabled(t *testing.T) {
+	report, err := NewRunner().Health(context.Background())
+	if err != nil {
+		t.Fatalf("Health() error = %v", err)
+	}
+	if !report.Healthy || len(report.Backends) != 0 {
+		t.Errorf("Health() = %+v, want healthy and empty", report)
+	}
+}
+
+func TestHealth_AggregatesExecutions(t *testing.T) {
+	idx := newMockIndex()
+	for _, name := range []string{"good", "bad"} {
+		backend := testLocalBackend(name)
+		mustRegisterTool(t, idx, testTool(name), backend)
+		idx.DefaultBackends[name] = backend
+	}
+	localReg := newMockLocalRegistry()
+	localReg.Register("good", func(_ context.Context, _ map[string]any) (any, error) {
+		return "ok", nil
+	})
+	localReg.Register("bad", func(_ context.Context, _ map[string]any) (any, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	runner := NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithValidation(false, false),
+		WithHealthTracking(HealthConfig{FailureThreshold: 3}),
+	)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, _ = runner.Run(ctx, "good", nil)
+		_, _ = runner.Run(ctx, "bad", nil)
+	}
+
+	report, err := runner.Health(ctx)
+	if err != nil {
+		t.Fatalf("Health() error = %v", err)
+	}
+	if report.Healthy {
+		t.Error("Healthy = true, want false with an open circuit")
+	}
+	if len(report.Backends) != 2 {
+		t.Fatalf("len(Backends) = %d, want 2", len(report.Backends))
+	}
+
+	bad, good := report.Backends[0], report.Backends[1]
+	if bad.Key != "local:bad" || good.Key != "local:good" {
+		t.Fatalf("keys = %q, %q", bad.Key, good.Key)
+	}
+	if !bad.CircuitOpen || bad.Reachable || bad.Failures != 3 || bad.LastError == "" {
+		t.Errorf("bad = %+v, want open circuit with 3 failures", bad)
+	}
+	if good.CircuitOpen || !good.Reachable || good.Calls != 3 || good.Failures != 0 {
+		t.Errorf("good = %+v, want reachable with 3 calls", good)
+	}
+	if good.LastSuccessAt.IsZero() {
+		t.Error("good.LastSuccessAt is zero")
+	}
+}
+
+func TestHealthTracker_WindowAndCooldown(t *testing.T) {
+	h := newHealthTracker(HealthConfig{Window: 4, FailureThreshold: 2, Cooldown: time.Minute})
+	backend := model.ToolBackend{Kind: model.BackendKindMCP, MCP: &model.MCPBackend{ServerName: "srv"}}
+	now := time.Now()
+
+	for i := 1; i <= 6; i++ {
+		h.record(backend, time.Duration(i)*time.Millisecond, nil, now)
+	}
+	h.record(backend, time.Millisecond, context.Canceled, now)
+
+	report := h.report(now)
+	b := report.Backends[0]
+	if b.Key != "mcp:srv" || b.Calls != 4 {
+		t.Errorf("backend = %+v, want key mcp:srv with 4 calls (window)", b)
+	}
+	if b.P95Latency != 6*time.Millisecond {
+		t.Errorf("P95Latency = %v, want 6ms", b.P95Latency)
+	}
+
+	h.record(backend, time.Millisecond, errors.New("x"), now)
+	h.record(backend, time.Millisecond, errors.New("y"), now)
+	if !h.report(now).Backends[0].CircuitOpen {
+		t.Error("circuit should be open after threshold failures")
+	}
+	if h.report(now.Add(2 * time.Minute)).Backends[0].CircuitOpen {
+		t.Error("circuit should close after cooldown")
+	}
+}
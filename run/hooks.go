@@ -0,0 +1,88 @@
+package run
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+// ErrVetoed is returned when a BeforeRun hook rejects an execution.
+var ErrVetoed = errors.New("execution vetoed")
+
+// BeforeRunHook is called after resolution and before validation and dispatch.
+// It may return replacement args (nil keeps the current args) or an error
+// to veto the execution.
+type BeforeRunHook func(ctx context.Context, tool model.Tool, args map[string]any) (map[string]any, error)
+
+// AfterRunHook is called after a successful execution and output validation.
+// It may return an enriched result, or an error to fail the execution.
+type AfterRunHook func(ctx context.Context, tool model.Tool, result RunResult) (RunResult, error)
+
+// ErrorHook observes any error returned by Run, including errors from
+// other hooks. It cannot change the error.
+type ErrorHook func(ctx context.Context, toolID string, err error)
+
+// Hooks bundles optional callbacks around tool execution so infrastructure
+// code (auth, auditing, argument defaults) can act centrally.
+//
+// Multiple Hooks compose like middleware: BeforeRun hooks run in
+// registration order, while AfterRun and OnError hooks run in reverse,
+// so the first registered Hooks wraps all later ones.
+//
+// Contract:
+// - Concurrency: hooks must be safe for concurrent use.
+// - Ownership: hooks must not mutate args in place; return a copy instead.
+// - Streaming: RunStream applies BeforeRun and OnError only.
+type Hooks struct {
+	// BeforeRun may replace args or veto execution.
+	BeforeRun BeforeRunHook
+
+	// AfterRun may enrich or reject a successful result.
+	AfterRun AfterRunHook
+
+	// OnError observes failed executions.
+	OnError ErrorHook
+}
+
+// beforeRun applies every BeforeRun hook in registration order.
+func (r *DefaultRunner) beforeRun(ctx context.Context, tool model.Tool, args map[string]any) (map[string]any, error) {
+	for _, h := range r.cfg.Hooks {
+		if h.BeforeRun == nil {
+			continue
+		}
+		next, err := h.BeforeRun(ctx, tool, args)
+		if err != nil {
+			return nil, err
+		}
+		if next != nil {
+			args = next
+		}
+	}
+	return args, nil
+}
+
+// afterRun applies every AfterRun hook in reverse registration order.
+func (r *DefaultRunner) afterRun(ctx context.Context, tool model.Tool, result RunResult) (RunResult, error) {
+	for i := len(r.cfg.Hooks) - 1; i >= 0; i-- {
+		h := r.cfg.Hooks[i]
+		if h.AfterRun == nil {
+			continue
+		}
+		var err error
+		result, err = h.AfterRun(ctx, tool, result)
+		if err != nil {
+			return RunResult{}, err
+		}
+	}
+	return result, nil
+}
+
+// onError notifies every OnError hook in reverse registration order.
+func (r *DefaultRunner) onError(ctx context.Context, toolID string, err error) {
+	for i := len(r.cfg.Hooks) - 1; i >= 0; i-- {
+		if h := r.cfg.Hooks[i]; h.OnError != nil {
+			h.OnError(ctx, toolID, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+func newHookTestRunner(t *testing.T, handlerErr error, hooks ...Hooks) (*DefaultRunner, *map[string]any) {
+	t.Helper()
+	idx := newMockIndex()
+	backend := testLocalBackend("myhandler")
+	mustRegisterTool(t, idx, testTool("mytool"), backend)
+	idx.DefaultBackends["mytool"] = backend
+
+	var seen map[string]any
+	localReg := newMockLocalRegistry()
+	localReg.Register("myhandler", func(_ context.Context, args map[string]any) (any, error) {
+		seen = args
+		if handlerErr != nil {
+			return nil, handlerErr
+		}
+		return map[string]any{"ok": true}, nil
+	})
+
+	runner := NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithValidation(false, false),
+		WithHooks(hooks...),
+	)
+	return runner, &seen
+}
+
+func TestHooks_BeforeRunMutatesArgs(t *testing.T) {
+	runner, seen := newHookTestRunner(t, nil, Hooks{
+		BeforeRun: func(_ context.Context, tool model.Tool, args map[string]any) (map[string]any, error) {
+			next := map[string]any{"tenant": "acme"}
+			for k, v := range args {
+				next[k] = v
+			}
+			return next, nil
+		},
+	})
+
+	if _, err := runner.Run(context.Background(), "mytool", map[string]any{"q": "x"}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if (*seen)["tenant"] != "acme" || (*seen)["q"] != "x" {
+		t.Errorf("handler args = %v, want tenant and q", *seen)
+	}
+}
+
+func TestHooks_BeforeRunVeto(t *testing.T) {
+	denied := errors.New("not allowed")
+	var observed error
+	runner, seen := newHookTestRunner(t, nil, Hooks{
+		BeforeRun: func(context.Context, model.Tool, map[string]any) (map[string]any, error) {
+			return nil, denied
+		},
+		OnError: func(_ context.Context, _ string, err error) {
+			observed = err
+		},
+	})
+
+	_, err := runner.Run(context.Background(), "mytool", nil)
+	if !errors.Is(err, ErrVetoed) || !errors.Is(err, denied) {
+		t.Fatalf("Run() error = %v, want ErrVetoed wrapping hook error", err)
+	}
+	if *seen != nil {
+		t.Error("handler ran despite veto")
+	}
+	if observed != err {
+		t.Errorf("OnError saw %v, want %v", observed, err)
+	}
+}
+
+func TestHooks_AfterRunEnrichesResult(t *testing.T) {
+	runner, _ := newHookTestRunner(t, nil, Hooks{
+		AfterRun: func(_ context.Context, _ model.Tool, result RunResult) (RunResult, error) {
+			result.Structured = map[string]any{"wrapped": result.Structured}
+			return result, nil
+		},
+	})
+
+	result, err := runner.Run(context.Background(), "mytool", nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	m, ok := result.Structured.(map[string]any)
+	if !ok || m["wrapped"] == nil {
+		t.Errorf("Structured = %v, want wrapped result", result.Structured)
+	}
+}
+
+func TestHooks_OnErrorObservesExecutionFailure(t *testing.T) {
+	var observedID string
+	runner, _ := newHookTestRunner(t, errors.New("boom"), Hooks{
+		AfterRun: func(context.Context, model.Tool, RunResult) (RunResult, error) {
+			t.Error("AfterRun called on failure")
+			return RunResult{}, nil
+		},
+		OnError: func(_ context.Context, toolID string, _ error) {
+			observedID = toolID
+		},
+	})
+
+	if _, err := runner.Run(context.Background(), "mytool", nil); !errors.Is(err, ErrExecution) {
+		t.Fatalf("Run() error = %v, want ErrExecution", err)
+	}
+	if observedID != "mytool" {
+		t.Errorf("OnError toolID = %q, want mytool", observedID)
+	}
+}
+
+func TestHooks_MiddlewareOrder(t *testing.T) {
+	var order []string
+	layer := func(name string) Hooks {
+		return Hooks{
+			BeforeRun: func(context.Context, model.Tool, map[string]any) (map[string]any, error) {
+				order = append(order, "before:"+name)
+				return nil, nil
+			},
+			AfterRun: func(_ context.Context, _ model.Tool, r RunResult) (RunResult, error) {
+				order = append(order, "after:"+name)
+				return r, nil
+			},
+		}
+	}
+	runner, _ := newHookTestRunner(t, nil, layer("outer"), layer("inner"))
+
+	if _, err := runner.Run(context.Background(), "mytool", nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := []string{"before:outer", "before:inner", "after:inner", "after:outer"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package run
+
+import "context"
+
+// ContextArgsKey is the reserved argument key under which values from a
+// ContextArgInjector are passed to tools.
+const ContextArgsKey = "_context"
+
+// ContextArgInjector extracts per-request values (user ID, locale,
+// workspace path) from ctx for injection into every tool call's args.
+// Returning an empty map skips injection.
+type ContextArgInjector func(ctx context.Context) map[string]any
+
+// injectContextArgs returns a copy of args with the injector's values set
+// under ContextArgsKey. Any caller-supplied value at that key is dropped,
+// even when the injector returns nothing, so callers cannot spoof
+// injected values.
+func (r *DefaultRunner) injectContextArgs(ctx context.Context, args map[string]any) map[string]any {
+	if r.cfg.ContextArgInjector == nil {
+		return args
+	}
+	values := r.cfg.ContextArgInjector(ctx)
+	if _, spoofed := args[ContextArgsKey]; !spoofed && len(values) == 0 {
+		return args
+	}
+	merged := make(map[string]any, len(args)+1)
+	for k, v := range args {
+		merged[k] = v
+	}
+	delete(merged, ContextArgsKey)
+	if len(values) > 0 {
+		merged[ContextArgsKey] = values
+	}
+	return merged
+}
@@ -0,0 +1,72 @@
+package run
+
+import (
+	"context"
+	"testing"
+)
+
+type userKey struct{}
+
+func newInjectTestRunner(t *testing.T, opts ...ConfigOption) (*DefaultRunner, *map[string]any) {
+	t.Helper()
+	idx := newMockIndex()
+	backend := testLocalBackend("myhandler")
+	mustRegisterTool(t, idx, testTool("mytool"), backend)
+	idx.DefaultBackends["mytool"] = backend
+
+	var seen map[string]any
+	localReg := newMockLocalRegistry()
+	localReg.Register("myhandler", func(_ context.Context, args map[string]any) (any, error) {
+		seen = args
+		return "ok", nil
+	})
+
+	opts = append([]ConfigOption{
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithValidation(false, false),
+	}, opts...)
+	return NewRunner(opts...), &seen
+}
+
+func TestRun_ContextArgInjector(t *testing.T) {
+	runner, seen := newInjectTestRunner(t, WithContextArgInjector(func(ctx context.Context) map[string]any {
+		user, _ := ctx.Value(userKey{}).(string)
+		if user == "" {
+			return nil
+		}
+		return map[string]any{"userId": user}
+	}))
+
+	ctx := context.WithValue(context.Background(), userKey{}, "u-42")
+	args := map[string]any{"q": "x", ContextArgsKey: "spoofed"}
+	if _, err := runner.Run(ctx, "mytool", args); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	injected, ok := (*seen)[ContextArgsKey].(map[string]any)
+	if !ok || injected["userId"] != "u-42" {
+		t.Errorf("args[%q] = %v, want injected userId", ContextArgsKey, (*seen)[ContextArgsKey])
+	}
+	if (*seen)["q"] != "x" {
+		t.Errorf("args[q] = %v, want x", (*seen)["q"])
+	}
+	if args[ContextArgsKey] != "spoofed" {
+		t.Error("caller args were mutated")
+	}
+
+	if _, err := runner.Run(context.Background(), "mytool", map[string]any{"q": "y"}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if _, ok := (*seen)[ContextArgsKey]; ok {
+		t.Error("empty injection should leave args untouched")
+	}
+
+	// An empty injection still drops a spoofed value.
+	if _, err := runner.Run(context.Background(), "mytool", args); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if v, ok := (*seen)[ContextArgsKey]; ok {
+		t.Errorf("args[%q] = %v after an empty injection, want it dropped", ContextArgsKey, v)
+	}
+}
@@ -20,6 +20,7 @@ func (r *DefaultRunner) normalize(tool model.Tool, backend model.ToolBackend, dr
 	if dr.mcpResult != nil {
 		result.MCPResult = dr.mcpResult
 		result.Structured = extractStructured(dr.mcpResult)
+		result.Contents = extractContents(dr.mcpResult.Content)
 	} else {
 		result.Structured = dr.structured
 	}
@@ -83,6 +84,39 @@ func bestEffortStructured(content []mcp.Content) any {
 	return nil
 }
 
+// extractContents converts MCP content into ContentItems, preserving order.
+// Unknown content types are skipped.
+func extractContents(content []mcp.Content) []ContentItem {
+	if len(content) == 0 {
+		return nil
+	}
+	items := make([]ContentItem, 0, len(content))
+	for _, c := range content {
+		switch v := c.(type) {
+		case *mcp.TextContent:
+			items = append(items, ContentItem{Kind: ContentText, Text: v.Text})
+		case *mcp.ImageContent:
+			items = append(items, ContentItem{Kind: ContentImage, Data: v.Data, MIMEType: v.MIMEType})
+		case *mcp.AudioContent:
+			items = append(items, ContentItem{Kind: ContentAudio, Data: v.Data, MIMEType: v.MIMEType})
+		case *mcp.ResourceLink:
+			items = append(items, ContentItem{Kind: ContentResourceLink, URI: v.URI, Name: v.Name, MIMEType: v.MIMEType})
+		case *mcp.EmbeddedResource:
+			if v.Resource == nil {
+				continue
+			}
+			items = append(items, ContentItem{
+				Kind:     ContentResource,
+				URI:      v.Resource.URI,
+				MIMEType: v.Resource.MIMEType,
+				Text:     v.Resource.Text,
+				Data:     v.Resource.Blob,
+			})
+		}
+	}
+	return items
+}
+
 // extractTextFromContent extracts text from an MCP Content item.
 func extractTextFromContent(c mcp.Content) string {
 	// MCP Content is an interface; we need to type-switch
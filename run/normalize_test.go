@@ -150,3 +150,50 @@ func TestNormalize_EmptyMCPResult(t *testing.T) {
 		t.Errorf("Structured = %v, want nil", result.Structured)
 	}
 }
+
+func TestNormalize_MCP_Contents_PreservesAllItems(t *testing.T) {
+	runner := NewRunner()
+
+	dr := &dispatchResult{
+		mcpResult: &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "caption"},
+				&mcp.ImageContent{Data: []byte{0x89, 0x50}, MIMEType: "image/png"},
+				&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: "file:///a.txt", MIMEType: "text/plain", Text: "body"}},
+				&mcp.ResourceLink{URI: "file:///b.bin", Name: "b.bin"},
+			},
+		},
+	}
+
+	result := runner.normalize(testTool("mytool"), testMCPBackend("server1"), dr)
+
+	if len(result.Contents) != 4 {
+		t.Fatalf("len(Contents) = %d, want 4", len(result.Contents))
+	}
+	wantKinds := []ContentKind{ContentText, ContentImage, ContentResource, ContentResourceLink}
+	for i, want := range wantKinds {
+		if result.Contents[i].Kind != want {
+			t.Errorf("Contents[%d].Kind = %q, want %q", i, result.Contents[i].Kind, want)
+		}
+	}
+	if result.Contents[1].MIMEType != "image/png" || len(result.Contents[1].Data) != 2 {
+		t.Errorf("image item = %+v", result.Contents[1])
+	}
+	if result.Contents[2].URI != "file:///a.txt" || result.Contents[2].Text != "body" {
+		t.Errorf("resource item = %+v", result.Contents[2])
+	}
+	if result.Structured != "caption" {
+		t.Errorf("Structured = %v, want %q", result.Structured, "caption")
+	}
+}
+
+func TestNormalize_Local_NoContents(t *testing.T) {
+	runner := NewRunner()
+
+	dr := &dispatchResult{structured: "value"}
+	result := runner.normalize(testTool("mytool"), testLocalBackend("h"), dr)
+
+	if result.Contents != nil {
+		t.Errorf("Contents = %v, want nil for local backends", result.Contents)
+	}
+}
@@ -2,9 +2,33 @@ package run
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
+// progressMCPExecutor is an MCP executor that reports server progress
+// notifications and does not support native streaming.
+type progressMCPExecutor struct {
+	*mockMCPExecutor
+	notifications []ProgressEvent
+}
+
+func (p *progressMCPExecutor) CallToolStream(_ context.Context, _ string, _ *mcp.CallToolParams) (<-chan StreamEvent, error) {
+	return nil, ErrStreamNotSupported
+}
+
+func (p *progressMCPExecutor) CallToolWithProgress(ctx context.Context, serverName string, params *mcp.CallToolParams, onProgress ProgressCallback) (*mcp.CallToolResult, error) {
+	for _, n := range p.notifications {
+		if onProgress != nil {
+			onProgress(n)
+		}
+	}
+	return p.CallTool(ctx, serverName, params)
+}
+
 func TestRunWithProgress_EmitsStartAndEnd(t *testing.T) {
 	idx := newMockIndex()
 	tool := testTool("mytool")
@@ -91,3 +115,116 @@ func TestRunChainWithProgress_EmitsStepProgress(t *testing.T) {
 		t.Errorf("total = %v, want 2", events[2].Total)
 	}
 }
+
+func TestRunWithProgress_ForwardsMCPNotifications(t *testing.T) {
+	idx := newMockIndex()
+	tool := testTool("mytool")
+	mustRegisterTool(t, idx, tool, testMCPBackend("server1"))
+
+	mcpExec := &progressMCPExecutor{
+		mockMCPExecutor: newMockMCPExecutor(),
+		notifications: []ProgressEvent{
+			{Progress: 1, Total: 3, Message: "fetching"},
+			{Progress: 2, Total: 3, Message: "parsing"},
+		},
+	}
+	mcpExec.CallToolResult = testMCPResultStructured(map[string]any{"ok": true})
+
+	runner := NewRunner(
+		WithIndex(idx),
+		WithMCPExecutor(mcpExec),
+		WithValidation(false, false),
+	)
+
+	var events []ProgressEvent
+	_, err := runner.RunWithProgress(context.Background(), "mytool", nil, func(ev ProgressEvent) {
+		events = append(events, ev)
+	})
+	if err != nil {
+		t.Fatalf("RunWithProgress() error = %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("expected 4 progress events, got %d: %+v", len(events), events)
+	}
+	if events[1].Message != "fetching" || events[2].Message != "parsing" {
+		t.Errorf("forwarded messages = [%q %q], want [fetching parsing]", events[1].Message, events[2].Message)
+	}
+	if events[3].Message != "completed" {
+		t.Errorf("final message = %q, want completed", events[3].Message)
+	}
+
+	// Plain Run must not require a callback.
+	if _, err := runner.Run(context.Background(), "mytool", nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+}
+
+func TestRunStream_MCPProgressFallback(t *testing.T) {
+	idx := newMockIndex()
+	tool := testTool("mytool")
+	mustRegisterTool(t, idx, tool, testMCPBackend("server1"))
+
+	mcpExec := &progressMCPExecutor{
+		mockMCPExecutor: newMockMCPExecutor(),
+		notifications:   []ProgressEvent{{Progress: 50, Total: 100}},
+	}
+	mcpExec.CallToolResult = testMCPResultStructured(map[string]any{"ok": true})
+
+	runner := NewRunner(
+		WithIndex(idx),
+		WithMCPExecutor(mcpExec),
+		WithValidation(false, false),
+	)
+
+	ch, err := runner.RunStream(context.Background(), "mytool", nil)
+	if err != nil {
+		t.Fatalf("RunStream() error = %v", err)
+	}
+
+	var kinds []StreamEventKind
+	var last StreamEvent
+	for ev := range ch {
+		kinds = append(kinds, ev.Kind)
+		last = ev
+	}
+	if len(kinds) != 2 || kinds[0] != StreamEventProgress || kinds[1] != StreamEventDone {
+		t.Fatalf("event kinds = %v, want [progress done]", kinds)
+	}
+	if m, ok := last.Data.(map[string]any); !ok || m["ok"] != true {
+		t.Errorf("done data = %v, want structured result", last.Data)
+	}
+}
+
+func TestRunStream_MCPProgressFallbackToolError(t *testing.T) {
+	idx := newMockIndex()
+	mustRegisterTool(t, idx, testTool("mytool"), testMCPBackend("server1"))
+
+	mcpExec := &progressMCPExecutor{mockMCPExecutor: newMockMCPExecutor()}
+	mcpExec.CallToolResult = testMCPResult("rate limited")
+	mcpExec.CallToolResult.IsError = true
+
+	runner := NewRunner(
+		WithIndex(idx),
+		WithMCPExecutor(mcpExec),
+		WithValidation(false, false),
+	)
+
+	ch, err := runner.RunStream(context.Background(), "mytool", nil)
+	if err != nil {
+		t.Fatalf("RunStream() error = %v", err)
+	}
+	var events []StreamEvent
+	for ev := range ch {
+		events = append(events, ev)
+	}
+	if len(events) != 1 || events[0].Kind != StreamEventError {
+		t.Fatalf("events = %+v, want a single error event", events)
+	}
+	if err := events[0].Err; !errors.Is(err, ErrExecution) || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("error event Err = %v, want %v with the tool's message", err, ErrExecution)
+	}
+	var toolErr *ToolError
+	if !errors.As(events[0].Err, &toolErr) || toolErr.Op != "execute" {
+		t.Errorf("error event Err = %#v, want a ToolError for the execute op", events[0].Err)
+	}
+}
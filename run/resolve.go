@@ -14,15 +14,37 @@ type resolveResult struct {
 	backends []model.ToolBackend
 }
 
-// resolveTool resolves a tool ID to its definition and available backends.
-// Resolution order:
-//  1. If Index is configured, try Index.GetTool(id) and Index.GetAllBackends(id)
-//  2. If not found (or Index not configured), try ToolResolver and BackendsResolver
-//  3. Return ErrToolNotFound if all sources fail
+// resolveTool resolves a tool ID to its definition and available backends,
+// consulting the ResolutionCache first when one is configured.
 func (r *DefaultRunner) resolveTool(ctx context.Context, toolID string) (*resolveResult, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
+	cache := r.cfg.ResolutionCache
+	var gen uint64
+	if cache != nil {
+		res, g, ok := cache.get(toolID)
+		if ok {
+			return res, nil
+		}
+		gen = g
+	}
+	res, err := r.resolveUncached(ctx, toolID)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		cache.put(toolID, res, gen)
+	}
+	return res, nil
+}
+
+// resolveUncached resolves a tool ID to its definition and available backends.
+// Resolution order:
+//  1. If Index is configured, try Index.GetTool(id) and Index.GetAllBackends(id)
+//  2. If not found (or Index not configured), try ToolResolver and BackendsResolver
+//  3. Return ErrToolNotFound if all sources fail
+func (r *DefaultRunner) resolveUncached(ctx context.Context, toolID string) (*resolveResult, error) {
 	var tool model.Tool
 	var backends []model.ToolBackend
 	var toolFound, backendsFound bool
@@ -0,0 +1,158 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// ErrorCode is a stable, serializable classification of a run error.
+// It is used to select which failures a RetryPolicy retries.
+type ErrorCode string
+
+const (
+	// ErrorCodeToolNotFound classifies ErrToolNotFound.
+	ErrorCodeToolNotFound ErrorCode = "tool_not_found"
+
+	// ErrorCodeInvalidToolID classifies ErrInvalidToolID.
+	ErrorCodeInvalidToolID ErrorCode = "invalid_tool_id"
+
+	// ErrorCodeNoBackends classifies ErrNoBackends.
+	ErrorCodeNoBackends ErrorCode = "no_backends"
+
+	// ErrorCodeValidation classifies ErrValidation.
+	ErrorCodeValidation ErrorCode = "validation"
+
+	// ErrorCodeOutputValidation classifies ErrOutputValidation.
+	ErrorCodeOutputValidation ErrorCode = "output_validation"
+
+	// ErrorCodeExecution classifies ErrExecution and any unclassified
+	// failure raised while executing a tool.
+	ErrorCodeExecution ErrorCode = "execution"
+
+	// ErrorCodeTimeout classifies context.DeadlineExceeded. Run reports a
+	// deadline hit inside a backend as ErrorCodeExecution, so this code
+	// means the run's own context expired.
+	ErrorCodeTimeout ErrorCode = "timeout"
+
+	// ErrorCodeCancelled classifies context.Canceled.
+	ErrorCodeCancelled ErrorCode = "cancelled"
+
+	// ErrorCodeUnknown classifies any other error.
+	ErrorCodeUnknown ErrorCode = "unknown"
+)
+
+// CodeOf returns the ErrorCode for err, or an empty code when err is nil.
+func CodeOf(err error) ErrorCode {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrToolNotFound):
+		return ErrorCodeToolNotFound
+	case errors.Is(err, ErrInvalidToolID):
+		return ErrorCodeInvalidToolID
+	case errors.Is(err, ErrNoBackends):
+		return ErrorCodeNoBackends
+	case errors.Is(err, ErrValidation):
+		return ErrorCodeValidation
+	case errors.Is(err, ErrOutputValidation):
+		return ErrorCodeOutputValidation
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorCodeTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorCodeCancelled
+	case errors.Is(err, ErrExecution):
+		return ErrorCodeExecution
+	}
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) && toolErr.Op == "execute" {
+		return ErrorCodeExecution
+	}
+	return ErrorCodeUnknown
+}
+
+// DefaultRetryOn is the set of codes retried when RetryPolicy.RetryOn is empty.
+// Validation and resolution failures are deterministic and never retried
+// by default. Timeouts are left out: a step whose context has expired
+// cannot be retried, and backend deadlines are execution failures.
+var DefaultRetryOn = []ErrorCode{ErrorCodeExecution}
+
+// RetryPolicy controls how a chain step is retried after a failure.
+// Delays grow exponentially from Backoff and are jittered to avoid
+// synchronized retries across callers.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Values <= 1 disable retries.
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+
+	// Backoff is the base delay before the first retry.
+	// Each subsequent retry doubles the delay.
+	Backoff time.Duration `json:"backoff,omitempty"`
+
+	// MaxBackoff caps the delay between attempts. Zero means no cap.
+	MaxBackoff time.Duration `json:"maxBackoff,omitempty"`
+
+	// RetryOn lists the error codes that trigger a retry.
+	// If empty, DefaultRetryOn is used.
+	RetryOn []ErrorCode `json:"retryOn,omitempty"`
+}
+
+// shouldRetry reports whether err is retryable under the policy.
+func (p *RetryPolicy) shouldRetry(err error) bool {
+	codes := p.RetryOn
+	if len(codes) == 0 {
+		codes = DefaultRetryOn
+	}
+	return slices.Contains(codes, CodeOf(err))
+}
+
+// delay returns the jittered wait before the given retry (1-based).
+// The result lies in [d/2, d] where d is the capped exponential delay.
+func (p *RetryPolicy) delay(retry int) time.Duration {
+	if p.Backoff <= 0 {
+		return 0
+	}
+	d := p.Backoff
+	for i := 1; i < retry; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// runWithRetry executes a chain step, retrying according to its policy.
+// It returns the final result and error along with the number of retries made.
+func (r *DefaultRunner) runWithRetry(ctx context.Context, step ChainStep, args map[string]any) (RunResult, int, error) {
+	result, err := r.Run(ctx, step.ToolID, args)
+	policy := step.Retry
+	if policy == nil {
+		return result, 0, err
+	}
+
+	retries := 0
+	for err != nil && retries+1 < policy.MaxAttempts && policy.shouldRetry(err) {
+		if ctx.Err() != nil {
+			break
+		}
+		retries++
+		if wait := policy.delay(retries); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return result, retries - 1, err
+			case <-timer.C:
+			}
+		}
+		result, err = r.Run(ctx, step.ToolID, args)
+	}
+	return result, retries, err
+}
@@ -0,0 +1,176 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newFlakyChainRunner(t *testing.T, failures int, failErr error) (*DefaultRunner, *int) {
+	t.Helper()
+	idx := newMockIndex()
+	for _, name := range []string{"fetch", "flaky", "store"} {
+		backend := testLocalBackend(name + "-handler")
+		mustRegisterTool(t, idx, testTool(name), backend)
+		idx.DefaultBackends[name] = backend
+	}
+
+	calls := 0
+	localReg := newMockLocalRegistry()
+	localReg.Register("fetch-handler", func(_ context.Context, _ map[string]any) (any, error) {
+		return "data", nil
+	})
+	localReg.Register("flaky-handler", func(_ context.Context, _ map[string]any) (any, error) {
+		calls++
+		if calls <= failures {
+			return nil, failErr
+		}
+		return "ok", nil
+	})
+	localReg.Register("store-handler", func(_ context.Context, _ map[string]any) (any, error) {
+		return "stored", nil
+	})
+
+	runner := NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithValidation(false, false),
+	)
+	return runner, &calls
+}
+
+func TestRunChain_RetrySucceeds(t *testing.T) {
+	runner, calls := newFlakyChainRunner(t, 2, errors.New("transient"))
+
+	steps := []ChainStep{
+		{ToolID: "fetch"},
+		{ToolID: "flaky", Retry: &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}},
+		{ToolID: "store"},
+	}
+
+	_, results, err := runner.RunChain(context.Background(), steps)
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("len(results) = %d, want 3", len(results))
+	}
+	if results[1].Retries != 2 {
+		t.Errorf("results[1].Retries = %d, want 2", results[1].Retries)
+	}
+	if results[0].Retries != 0 || results[2].Retries != 0 {
+		t.Errorf("unexpected retries on other steps: %d, %d", results[0].Retries, results[2].Retries)
+	}
+	if *calls != 3 {
+		t.Errorf("flaky calls = %d, want 3", *calls)
+	}
+}
+
+func TestRunChain_RetriesBackendDeadline(t *testing.T) {
+	runner, calls := newFlakyChainRunner(t, 1, context.DeadlineExceeded)
+
+	steps := []ChainStep{
+		{ToolID: "flaky", Retry: &RetryPolicy{MaxAttempts: 2}},
+	}
+
+	_, results, err := runner.RunChain(context.Background(), steps)
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Retries != 1 {
+		t.Errorf("results = %+v, want one step with 1 retry", results)
+	}
+	if *calls != 2 {
+		t.Errorf("flaky calls = %d, want 2", *calls)
+	}
+}
+
+func TestRunChain_RetryExhausted(t *testing.T) {
+	runner, calls := newFlakyChainRunner(t, 5, errors.New("transient"))
+
+	steps := []ChainStep{
+		{ToolID: "flaky", Retry: &RetryPolicy{MaxAttempts: 2}},
+		{ToolID: "store"},
+	}
+
+	_, results, err := runner.RunChain(context.Background(), steps)
+	if err == nil {
+		t.Fatal("RunChain() error = nil, want error")
+	}
+	if len(results) != 1 || results[0].Retries != 1 {
+		t.Errorf("results = %+v, want one step with 1 retry", results)
+	}
+	if *calls != 2 {
+		t.Errorf("flaky calls = %d, want 2", *calls)
+	}
+}
+
+func TestRunChain_RetryOnFiltersCodes(t *testing.T) {
+	runner, calls := newFlakyChainRunner(t, 1, errors.New("transient"))
+
+	steps := []ChainStep{
+		{ToolID: "flaky", Retry: &RetryPolicy{MaxAttempts: 3, RetryOn: []ErrorCode{ErrorCodeTimeout}}},
+	}
+
+	if _, _, err := runner.RunChain(context.Background(), steps); err == nil {
+		t.Fatal("RunChain() error = nil, want error")
+	}
+	if *calls != 1 {
+		t.Errorf("flaky calls = %d, want 1 (execution errors not in RetryOn)", *calls)
+	}
+}
+
+func TestRunChain_RetryStopsOnCancel(t *testing.T) {
+	runner, calls := newFlakyChainRunner(t, 5, errors.New("transient"))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	steps := []ChainStep{
+		{ToolID: "flaky", Retry: &RetryPolicy{MaxAttempts: 5, Backoff: time.Hour}},
+	}
+
+	start := time.Now()
+	if _, _, err := runner.RunChain(ctx, steps); err == nil {
+		t.Fatal("RunChain() error = nil, want error")
+	}
+	if time.Since(start) > time.Second {
+		t.Error("RunChain() did not stop waiting when the context ended")
+	}
+	if *calls != 1 {
+		t.Errorf("flaky calls = %d, want 1", *calls)
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorCode
+	}{
+		{nil, ""},
+		{WrapError("t", nil, "resolve", ErrToolNotFound), ErrorCodeToolNotFound},
+		{WrapError("t", nil, "validate_input", fmt.Errorf("%w: bad", ErrValidation)), ErrorCodeValidation},
+		{WrapError("t", nil, "execute", errors.New("boom")), ErrorCodeExecution},
+		{WrapError("t", nil, "execute", context.DeadlineExceeded), ErrorCodeTimeout},
+		{context.Canceled, ErrorCodeCancelled},
+		{errors.New("other"), ErrorCodeUnknown},
+	}
+	for _, tt := range tests {
+		if got := CodeOf(tt.err); got != tt.want {
+			t.Errorf("CodeOf(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicy_DelayJitterBounds(t *testing.T) {
+	p := &RetryPolicy{Backoff: 10 * time.Millisecond, MaxBackoff: 30 * time.Millisecond}
+	for i := 0; i < 50; i++ {
+		if d := p.delay(1); d < 5*time.Millisecond || d > 10*time.Millisecond {
+			t.Fatalf("delay(1) = %v, want within [5ms, 10ms]", d)
+		}
+		if d := p.delay(4); d < 15*time.Millisecond || d > 30*time.Millisecond {
+			t.Fatalf("delay(4) = %v, want within [15ms, 30ms] (capped)", d)
+		}
+	}
+}
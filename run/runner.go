@@ -1,10 +1,14 @@
 package run
 
-import "context"
+import (
+	"context"
 
-// Runner is the main execution interface for running tools.
-// It provides methods for single tool execution, streaming execution,
-// and sequential chain execution.
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+// Executor is the narrow execution-only view of a Runner. Components that
+// only need to execute tools (gateways, code-mode sandboxes) should depend
+// on Executor rather than on Runner or its resolver/registry wiring.
 //
 // Contract:
 //   - Concurrency: implementations must be safe for concurrent use.
@@ -15,7 +19,7 @@ import "context"
 //   - Ownership: args are treated as read-only; results are caller-owned snapshots.
 //   - Determinism: for identical inputs/backends, results should be stable.
 //   - Nil/zero: empty toolID must return ErrInvalidToolID; nil args treated as empty.
-type Runner interface {
+type Executor interface {
 	// Run executes a single tool and returns the normalized result.
 	// It resolves the tool, validates input, executes via the appropriate backend,
 	// normalizes the result, and validates output.
@@ -24,6 +28,8 @@ type Runner interface {
 	// RunStream executes a tool with streaming support.
 	// Returns a channel that receives streaming events.
 	// May return ErrStreamNotSupported if the backend doesn't support streaming.
+	// If ctx ends mid-stream, a final StreamEventCancelled or StreamEventTimeout
+	// carrying StreamStats is emitted before the channel closes.
 	RunStream(ctx context.Context, toolID string, args map[string]any) (<-chan StreamEvent, error)
 
 	// RunChain executes a sequence of tool steps.
@@ -35,6 +41,26 @@ type Runner interface {
 	RunChain(ctx context.Context, steps []ChainStep) (RunResult, []StepResult, error)
 }
 
+// Resolver is the read-only view of a Runner: it looks up tool definitions
+// and backends without executing anything.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines.
+// - Errors: unknown tools return an error matching ErrToolNotFound.
+// - Ownership: returned values are caller-owned copies.
+type Resolver interface {
+	// Resolve returns the tool definition and its available backends.
+	Resolve(ctx context.Context, toolID string) (model.Tool, []model.ToolBackend, error)
+}
+
+// Runner is the main execution interface for running tools.
+// It provides methods for single tool execution, streaming execution,
+// and sequential chain execution; see Executor for the method contract.
+type Runner interface {
+	Executor
+}
+
 // ProgressCallback receives progress updates during execution.
 // Implementations should be fast and non-blocking.
 type ProgressCallback func(ProgressEvent)
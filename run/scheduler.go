@@ -0,0 +1,192 @@
+package run
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ErrPreempted is the cancellation cause of a streaming run that was
+// preempted to make room for a higher-priority run. It wraps
+// context.Canceled.
+var ErrPreempted = fmt.Errorf("preempted by higher-priority run: %w", context.Canceled)
+
+// Priority orders runs waiting for a slot when Config.MaxConcurrency is
+// saturated. Higher values run first; equal priorities run in arrival order.
+type Priority int
+
+const (
+	// PriorityLow is for background work that may be preempted.
+	PriorityLow Priority = -10
+
+	// PriorityNormal is the default priority.
+	PriorityNormal Priority = 0
+
+	// PriorityHigh is for latency-sensitive, interactive work.
+	PriorityHigh Priority = 10
+)
+
+// priorityKey is the context key for a run's Priority.
+type priorityKey struct{}
+
+// WithPriority returns a context that schedules runs at priority p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority set by WithPriority,
+// or PriorityNormal when none is set.
+func PriorityFromContext(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
+
+// scheduler bounds concurrent runs and grants free slots to the
+// highest-priority waiter first.
+type scheduler struct {
+	mu      sync.Mutex
+	limit   int
+	preempt bool
+	running int
+	seq     uint64
+	waiters waiterQueue
+	streams map[*streamSlot]struct{}
+}
+
+// streamSlot tracks an active streaming run that may be preempted.
+type streamSlot struct {
+	priority  Priority
+	cancel    context.CancelCauseFunc
+	preempted bool
+}
+
+func newScheduler(limit int, preempt bool) *scheduler {
+	return &scheduler{
+		limit:   limit,
+		preempt: preempt,
+		streams: make(map[*streamSlot]struct{}),
+	}
+}
+
+// acquire blocks until a slot is available for a run at priority p,
+// or ctx ends.
+func (s *scheduler) acquire(ctx context.Context, p Priority) error {
+	s.mu.Lock()
+	if s.running < s.limit && s.waiters.Len() == 0 {
+		s.running++
+		s.mu.Unlock()
+		return nil
+	}
+	s.seq++
+	w := &waiter{priority: p, seq: s.seq, ready: make(chan struct{})}
+	heap.Push(&s.waiters, w)
+	if s.preempt {
+		s.preemptLocked(p)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		if w.index >= 0 {
+			heap.Remove(&s.waiters, w.index)
+			s.mu.Unlock()
+		} else {
+			// The slot was granted concurrently; hand it on.
+			s.mu.Unlock()
+			s.release()
+		}
+		return ctx.Err()
+	}
+}
+
+// release frees a slot, handing it directly to the next waiter if any.
+func (s *scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.waiters.Len() > 0 {
+		w := heap.Pop(&s.waiters).(*waiter)
+		close(w.ready)
+		return
+	}
+	s.running--
+}
+
+// addStream registers an active stream so it can be preempted.
+func (s *scheduler) addStream(p Priority, cancel context.CancelCauseFunc) *streamSlot {
+	slot := &streamSlot{priority: p, cancel: cancel}
+	s.mu.Lock()
+	s.streams[slot] = struct{}{}
+	s.mu.Unlock()
+	return slot
+}
+
+// removeStream unregisters a finished stream.
+func (s *scheduler) removeStream(slot *streamSlot) {
+	s.mu.Lock()
+	delete(s.streams, slot)
+	s.mu.Unlock()
+}
+
+// preemptLocked cancels the lowest-priority active stream whose priority is
+// below p. The stream's slot is released once its channel drains.
+func (s *scheduler) preemptLocked(p Priority) {
+	var victim *streamSlot
+	for slot := range s.streams {
+		if slot.preempted || slot.priority >= p {
+			continue
+		}
+		if victim == nil || slot.priority < victim.priority {
+			victim = slot
+		}
+	}
+	if victim != nil {
+		victim.preempted = true
+		victim.cancel(ErrPreempted)
+	}
+}
+
+// waiter is a run blocked in acquire.
+type waiter struct {
+	priority Priority
+	seq      uint64
+	ready    chan struct{}
+	index    int
+}
+
+// waiterQueue is a max-heap on priority, FIFO within a priority.
+type waiterQueue []*waiter
+
+func (q waiterQueue) Len() int { return len(q) }
+
+func (q waiterQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waiterQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waiterQueue) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waiterQueue) Pop() any {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*q = old[:n-1]
+	return w
+}
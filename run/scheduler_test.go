@@ -0,0 +1,182 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForWaiters blocks until the scheduler has n queued waiters.
+func waitForWaiters(t *testing.T, s *scheduler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		got := s.waiters.Len()
+		s.mu.Unlock()
+		if got == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d waiters", n)
+}
+
+func TestWithPriority(t *testing.T) {
+	if got := PriorityFromContext(context.Background()); got != PriorityNormal {
+		t.Errorf("PriorityFromContext() = %v, want PriorityNormal", got)
+	}
+	ctx := WithPriority(context.Background(), PriorityHigh)
+	if got := PriorityFromContext(ctx); got != PriorityHigh {
+		t.Errorf("PriorityFromContext() = %v, want PriorityHigh", got)
+	}
+}
+
+func TestRun_PrioritySchedulingOrder(t *testing.T) {
+	idx := newMockIndex()
+	backend := testLocalBackend("h")
+	mustRegisterTool(t, idx, testTool("mytool"), backend)
+	idx.DefaultBackends["mytool"] = backend
+
+	gate := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	localReg := newMockLocalRegistry()
+	localReg.Register("h", func(_ context.Context, args map[string]any) (any, error) {
+		name, _ := args["name"].(string)
+		if name == "blocker" {
+			<-gate
+		}
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+		return name, nil
+	})
+
+	runner := NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithValidation(false, false),
+		WithMaxConcurrency(1, false),
+	)
+
+	var wg sync.WaitGroup
+	start := func(ctx context.Context, name string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := runner.Run(ctx, "mytool", map[string]any{"name": name}); err != nil {
+				t.Errorf("Run(%s) error = %v", name, err)
+			}
+		}()
+	}
+
+	start(context.Background(), "blocker")
+	time.Sleep(10 * time.Millisecond)
+	start(WithPriority(context.Background(), PriorityLow), "low")
+	waitForWaiters(t, runner.sched, 1)
+	start(context.Background(), "normal")
+	waitForWaiters(t, runner.sched, 2)
+	start(WithPriority(context.Background(), PriorityHigh), "high")
+	waitForWaiters(t, runner.sched, 3)
+
+	close(gate)
+	wg.Wait()
+
+	want := []string{"blocker", "high", "normal", "low"}
+	for i := range want {
+		if i >= len(order) || order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestRun_SchedulerWaitHonorsContext(t *testing.T) {
+	s := newScheduler(1, false)
+	if err := s.acquire(context.Background(), PriorityNormal); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.acquire(ctx, PriorityHigh); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquire() error = %v, want DeadlineExceeded", err)
+	}
+	if s.waiters.Len() != 0 {
+		t.Errorf("waiters = %d, want 0 after timeout", s.waiters.Len())
+	}
+	s.release()
+	if s.running != 0 {
+		t.Errorf("running = %d, want 0", s.running)
+	}
+}
+
+func TestRunStream_PreemptedByHigherPriority(t *testing.T) {
+	idx := newMockIndex()
+	streamBackend := testProviderBackend("prov", "tail")
+	mustRegisterTool(t, idx, testTool("tail"), streamBackend)
+	idx.DefaultBackends["tail"] = streamBackend
+	quickBackend := testLocalBackend("quick")
+	mustRegisterTool(t, idx, testTool("quick"), quickBackend)
+	idx.DefaultBackends["quick"] = quickBackend
+
+	provExec := newMockProviderExecutor()
+	provExec.CallToolStreamChan = make(chan StreamEvent) // never closes
+	localReg := newMockLocalRegistry()
+	localReg.Register("quick", func(_ context.Context, _ map[string]any) (any, error) {
+		return "done", nil
+	})
+
+	runner := NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithProviderExecutor(provExec),
+		WithValidation(false, false),
+		WithMaxConcurrency(1, true),
+	)
+
+	events, err := runner.RunStream(WithPriority(context.Background(), PriorityLow), "tail", nil)
+	if err != nil {
+		t.Fatalf("RunStream() error = %v", err)
+	}
+
+	lastc := make(chan StreamEvent, 1)
+	go func() {
+		var last StreamEvent
+		for ev := range events {
+			last = ev
+		}
+		lastc <- last
+	}()
+
+	result, err := runner.Run(WithPriority(context.Background(), PriorityHigh), "quick", nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Structured != "done" {
+		t.Errorf("Structured = %v, want done", result.Structured)
+	}
+
+	last := <-lastc
+	if last.Kind != StreamEventCancelled || !errors.Is(last.Err, ErrPreempted) {
+		t.Errorf("last event = %+v, want cancelled with ErrPreempted", last)
+	}
+	if !errors.Is(last.Err, context.Canceled) {
+		t.Error("ErrPreempted should match context.Canceled")
+	}
+}
+
+func TestRunStream_NotPreemptedByEqualPriority(t *testing.T) {
+	s := newScheduler(1, true)
+	_, cancel := context.WithCancelCause(context.Background())
+	slot := s.addStream(PriorityNormal, cancel)
+	s.preemptLocked(PriorityNormal)
+	if slot.preempted {
+		t.Error("stream preempted by equal-priority run")
+	}
+	s.preemptLocked(PriorityHigh)
+	if !slot.preempted {
+		t.Error("stream not preempted by higher-priority run")
+	}
+}
@@ -2,7 +2,10 @@ package run
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
@@ -39,7 +42,63 @@ func (r *DefaultRunner) dispatchStreamMCP(ctx context.Context, tool model.Tool,
 		Arguments: args,
 	}
 
-	return r.cfg.MCP.CallToolStream(ctx, backend.MCP.ServerName, params)
+	ch, err := r.cfg.MCP.CallToolStream(ctx, backend.MCP.ServerName, params)
+	if errors.Is(err, ErrStreamNotSupported) {
+		if pe, ok := r.cfg.MCP.(MCPProgressExecutor); ok {
+			return streamMCPProgress(ctx, pe, tool.ToolID(), backend, params), nil
+		}
+	}
+	return ch, err
+}
+
+// streamMCPProgress adapts a non-streaming MCP call into a stream.
+// Server progress notifications become StreamEventProgress events carrying a
+// ProgressEvent, followed by StreamEventDone with the structured result or
+// StreamEventError on failure. A result flagged IsError is a failure, with
+// the error Run reports for a failed call and the structured result as
+// Data.
+func streamMCPProgress(ctx context.Context, pe MCPProgressExecutor, toolID string, backend model.ToolBackend, params *mcp.CallToolParams) <-chan StreamEvent {
+	out := make(chan StreamEvent)
+	send := func(ev StreamEvent) {
+		select {
+		case out <- ev:
+		case <-ctx.Done():
+		}
+	}
+	go func() {
+		defer close(out)
+		result, err := pe.CallToolWithProgress(ctx, backend.MCP.ServerName, params, func(ev ProgressEvent) {
+			send(StreamEvent{Kind: StreamEventProgress, Data: ev})
+		})
+		if err != nil {
+			send(StreamEvent{Kind: StreamEventError, Err: WrapError(toolID, &backend, "execute", fmt.Errorf("%w: %v", ErrExecution, err))})
+			return
+		}
+		if result != nil && result.IsError {
+			send(StreamEvent{
+				Kind: StreamEventError,
+				Data: extractStructured(result),
+				Err:  WrapError(toolID, &backend, "execute", fmt.Errorf("%w: %s", ErrExecution, toolErrorText(result))),
+			})
+			return
+		}
+		send(StreamEvent{Kind: StreamEventDone, Data: extractStructured(result)})
+	}()
+	return out
+}
+
+// toolErrorText returns the text an MCP tool reported its error with.
+func toolErrorText(result *mcp.CallToolResult) string {
+	var texts []string
+	for _, c := range result.Content {
+		if text, ok := c.(*mcp.TextContent); ok && text.Text != "" {
+			texts = append(texts, text.Text)
+		}
+	}
+	if len(texts) == 0 {
+		return "tool reported an error"
+	}
+	return strings.Join(texts, "; ")
 }
 
 // dispatchStreamProvider executes a tool via a provider with streaming.
@@ -54,3 +113,75 @@ func (r *DefaultRunner) dispatchStreamProvider(ctx context.Context, _ model.Tool
 
 	return r.cfg.Provider.CallToolStream(ctx, backend.Provider.ProviderID, backend.Provider.ToolID, args)
 }
+
+// terminalEventGrace bounds how long an interrupted stream waits for the
+// caller to receive the final cancelled/timeout event before closing.
+const terminalEventGrace = time.Second
+
+// forwardStream relays events from raw to the returned channel, stamping
+// toolID on events that lack one. If ctx ends before raw is drained, a final
+// StreamEventCancelled or StreamEventTimeout carrying StreamStats is emitted
+// before the channel is closed. onDone, if non-nil, runs after close.
+func forwardStream(ctx context.Context, toolID string, raw <-chan StreamEvent, onDone func()) <-chan StreamEvent {
+	out := make(chan StreamEvent)
+	go func() {
+		if onDone != nil {
+			defer onDone()
+		}
+		defer close(out)
+		start := time.Now()
+		var stats StreamStats
+		for {
+			select {
+			case <-ctx.Done():
+				emitInterrupted(ctx, out, toolID, stats, start)
+				return
+			case ev, ok := <-raw:
+				if !ok {
+					return
+				}
+				if ev.ToolID == "" {
+					ev.ToolID = toolID
+				}
+				select {
+				case out <- ev:
+					stats.Events++
+					switch ev.Kind {
+					case StreamEventChunk:
+						stats.Chunks++
+					case StreamEventProgress:
+						stats.Progress++
+						stats.LastProgress = ev.Data
+					}
+				case <-ctx.Done():
+					emitInterrupted(ctx, out, toolID, stats, start)
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// emitInterrupted sends the terminal event for a stream whose context ended.
+// The send is bounded by terminalEventGrace so abandoned streams do not leak.
+func emitInterrupted(ctx context.Context, out chan<- StreamEvent, toolID string, stats StreamStats, start time.Time) {
+	stats.Elapsed = time.Since(start)
+	kind := StreamEventCancelled
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		kind = StreamEventTimeout
+	}
+	ev := StreamEvent{
+		Kind:   kind,
+		ToolID: toolID,
+		Data:   stats,
+		Err:    context.Cause(ctx),
+	}
+
+	timer := time.NewTimer(terminalEventGrace)
+	defer timer.Stop()
+	select {
+	case out <- ev:
+	case <-timer.C:
+	}
+}
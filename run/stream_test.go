@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 )
 
 func TestRunStream_ValidatesInput(t *testing.T) {
@@ -274,3 +275,104 @@ func TestRunStream_NilChannel_IsError(t *testing.T) {
 		t.Errorf("RunStream() error = %v, want ErrStreamNotSupported", err)
 	}
 }
+
+func TestRunStream_CancelledEmitsTerminalEvent(t *testing.T) {
+	idx := newMockIndex()
+	tool := testTool("mytool")
+	backend := testMCPBackend("server1")
+	mustRegisterTool(t, idx, tool, backend)
+
+	// Executor emits one chunk and then stalls without closing.
+	eventChan := make(chan StreamEvent, 1)
+	eventChan <- StreamEvent{Kind: StreamEventChunk, Data: "partial"}
+
+	mcpExec := newMockMCPExecutor()
+	mcpExec.CallToolStreamChan = eventChan
+
+	runner := NewRunner(
+		WithIndex(idx),
+		WithMCPExecutor(mcpExec),
+		WithValidation(false, false),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := runner.RunStream(ctx, "mytool", nil)
+	if err != nil {
+		t.Fatalf("RunStream() error = %v", err)
+	}
+
+	if ev := <-ch; ev.Kind != StreamEventChunk {
+		t.Fatalf("first event kind = %q, want %q", ev.Kind, StreamEventChunk)
+	}
+	cancel()
+
+	var events []StreamEvent
+	for ev := range ch {
+		events = append(events, ev)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 terminal event after cancel, got %d", len(events))
+	}
+	last := events[0]
+	if last.Kind != StreamEventCancelled {
+		t.Errorf("terminal kind = %q, want %q", last.Kind, StreamEventCancelled)
+	}
+	if !errors.Is(last.Err, context.Canceled) {
+		t.Errorf("terminal Err = %v, want context.Canceled", last.Err)
+	}
+	if last.ToolID != "mytool" {
+		t.Errorf("terminal ToolID = %q, want %q", last.ToolID, "mytool")
+	}
+	stats, ok := last.Data.(StreamStats)
+	if !ok {
+		t.Fatalf("terminal Data = %T, want StreamStats", last.Data)
+	}
+	if stats.Events != 1 || stats.Chunks != 1 {
+		t.Errorf("stats = %+v, want 1 event and 1 chunk", stats)
+	}
+}
+
+func TestRunStream_DeadlineEmitsTimeoutEvent(t *testing.T) {
+	idx := newMockIndex()
+	tool := testTool("mytool")
+	backend := testProviderBackend("provider1", "remote-tool")
+	mustRegisterTool(t, idx, tool, backend)
+
+	eventChan := make(chan StreamEvent, 1)
+	eventChan <- StreamEvent{Kind: StreamEventProgress, Data: ProgressEvent{Progress: 1, Total: 4}}
+
+	provExec := newMockProviderExecutor()
+	provExec.CallToolStreamChan = eventChan
+
+	runner := NewRunner(
+		WithIndex(idx),
+		WithProviderExecutor(provExec),
+		WithValidation(false, false),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	ch, err := runner.RunStream(ctx, "mytool", nil)
+	if err != nil {
+		t.Fatalf("RunStream() error = %v", err)
+	}
+
+	var last StreamEvent
+	for ev := range ch {
+		last = ev
+	}
+	if last.Kind != StreamEventTimeout {
+		t.Fatalf("terminal kind = %q, want %q", last.Kind, StreamEventTimeout)
+	}
+	if !errors.Is(last.Err, context.DeadlineExceeded) {
+		t.Errorf("terminal Err = %v, want context.DeadlineExceeded", last.Err)
+	}
+	stats := last.Data.(StreamStats)
+	if stats.Progress != 1 {
+		t.Errorf("stats.Progress = %d, want 1", stats.Progress)
+	}
+	if _, ok := stats.LastProgress.(ProgressEvent); !ok {
+		t.Errorf("stats.LastProgress = %T, want ProgressEvent", stats.LastProgress)
+	}
+}
@@ -1,6 +1,8 @@
 package run
 
 import (
+	"time"
+
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
 	"github.com/jonwraymond/toolfoundation/model"
@@ -21,6 +23,14 @@ const (
 
 	// StreamEventError indicates an error occurred during streaming.
 	StreamEventError StreamEventKind = "error"
+
+	// StreamEventCancelled indicates the stream was interrupted because
+	// the caller's context was canceled. It is always the final event.
+	StreamEventCancelled StreamEventKind = "cancelled"
+
+	// StreamEventTimeout indicates the stream was interrupted because
+	// the caller's context deadline expired. It is always the final event.
+	StreamEventTimeout StreamEventKind = "timeout"
 )
 
 // StreamEvent is a transport-agnostic streaming envelope.
@@ -38,12 +48,33 @@ type StreamEvent struct {
 	// For chunk events, this contains partial result data.
 	Data any `json:"data,omitempty"`
 
-	// Err is set when Kind is StreamEventError.
+	// Err is set when Kind is StreamEventError, StreamEventCancelled,
+	// or StreamEventTimeout.
 	// Not serialized to JSON - callers should extract error information
 	// from Data if needed for transmission.
 	Err error `json:"-"`
 }
 
+// StreamStats summarizes what a stream delivered before it ended.
+// It is the Data payload of StreamEventCancelled and StreamEventTimeout events,
+// letting callers render a final state for interrupted executions.
+type StreamStats struct {
+	// Events is the number of events delivered to the caller.
+	Events int `json:"events"`
+
+	// Chunks is the number of StreamEventChunk events delivered.
+	Chunks int `json:"chunks"`
+
+	// Progress is the number of StreamEventProgress events delivered.
+	Progress int `json:"progress"`
+
+	// LastProgress is the data of the most recent progress event, if any.
+	LastProgress any `json:"lastProgress,omitempty"`
+
+	// Elapsed is the time between stream start and interruption.
+	Elapsed time.Duration `json:"elapsed"`
+}
+
 // ProgressEvent represents coarse-grained progress during execution.
 // Progress and Total are optional; when Total is zero, Progress should be treated
 // as a best-effort signal rather than a precise fraction.
@@ -65,6 +96,10 @@ type ChainStep struct {
 	// UsePrevious, when true, injects the previous step's structured result
 	// into args["previous"], overwriting any existing value.
 	UsePrevious bool `json:"usePrevious,omitempty"`
+
+	// Retry, when set, retries this step on retryable failures before
+	// the chain is stopped.
+	Retry *RetryPolicy `json:"retry,omitempty"`
 }
 
 // StepResult captures what happened at a single chain step.
@@ -82,6 +117,17 @@ type StepResult struct {
 	// Err is set if the step failed.
 	// Not serialized to JSON - callers should check this field explicitly.
 	Err error `json:"-"`
+
+	// Retries is the number of retries made after the first attempt.
+	Retries int `json:"retries,omitempty"`
+
+	// Cost is the step's cost weight charged against MaxTotalCost.
+	// Zero when no cost limit is configured.
+	Cost float64 `json:"cost,omitempty"`
+
+	// Deduplicated is true when Result was reused from an earlier identical
+	// step instead of executing the tool again.
+	Deduplicated bool `json:"deduplicated,omitempty"`
 }
 
 // RunResult is the normalized result of a tool execution.
@@ -99,7 +145,55 @@ type RunResult struct {
 	// For provider/local backends, this is the executor/handler return value.
 	Structured any `json:"structured,omitempty"`
 
+	// Contents lists every content item returned by the tool, in order.
+	// MCP tools may return several items (text, images, resources);
+	// Structured is derived from them but cannot represent all of them.
+	// Empty for provider and local backends.
+	Contents []ContentItem `json:"contents,omitempty"`
+
 	// MCPResult is the raw MCP CallToolResult when the backend was MCP.
 	// Nil for provider and local backends unless they return MCP-native results.
 	MCPResult *mcp.CallToolResult `json:"mcpResult,omitempty"`
 }
+
+// ContentKind identifies the type of a ContentItem.
+type ContentKind string
+
+const (
+	// ContentText is plain text content.
+	ContentText ContentKind = "text"
+
+	// ContentImage is binary image data with a MIME type.
+	ContentImage ContentKind = "image"
+
+	// ContentAudio is binary audio data with a MIME type.
+	ContentAudio ContentKind = "audio"
+
+	// ContentResource is an embedded resource with inline text or blob data.
+	ContentResource ContentKind = "resource"
+
+	// ContentResourceLink is a reference to a resource by URI.
+	ContentResourceLink ContentKind = "resource_link"
+)
+
+// ContentItem is a transport-agnostic view of one item of tool output.
+// Only the fields relevant to Kind are set.
+type ContentItem struct {
+	// Kind indicates the type of content.
+	Kind ContentKind `json:"kind"`
+
+	// Text is set for text content and text resources.
+	Text string `json:"text,omitempty"`
+
+	// Data holds raw bytes for image, audio, and blob resource content.
+	Data []byte `json:"data,omitempty"`
+
+	// MIMEType describes Data or the referenced resource.
+	MIMEType string `json:"mimeType,omitempty"`
+
+	// URI identifies the resource for resource and resource link content.
+	URI string `json:"uri,omitempty"`
+
+	// Name is the resource name for resource link content.
+	Name string `json:"name,omitempty"`
+}
@@ -0,0 +1,73 @@
+package run
+
+import (
+	"context"
+
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+// Validator applies custom business rules around tool execution, in
+// addition to JSON Schema validation. Typical uses include path allowlists
+// on arguments or PII checks on results.
+//
+// Validators run regardless of the ValidateInput/ValidateOutput settings,
+// which control schema validation only.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Ownership: args and results are read-only.
+// - Errors: a non-nil error vetoes the call; the runner wraps it with
+// ErrValidation (pre-exec) or ErrOutputValidation (post-exec).
+type Validator interface {
+	// ValidateArgs is called after input schema validation and before dispatch.
+	ValidateArgs(ctx context.Context, tool model.Tool, args map[string]any) error
+
+	// ValidateResult is called after output schema validation.
+	ValidateResult(ctx context.Context, tool model.Tool, result RunResult) error
+}
+
+// ArgsValidatorFunc adapts a function into a Validator that checks arguments only.
+type ArgsValidatorFunc func(ctx context.Context, tool model.Tool, args map[string]any) error
+
+// ValidateArgs calls f.
+func (f ArgsValidatorFunc) ValidateArgs(ctx context.Context, tool model.Tool, args map[string]any) error {
+	return f(ctx, tool, args)
+}
+
+// ValidateResult accepts every result.
+func (f ArgsValidatorFunc) ValidateResult(context.Context, model.Tool, RunResult) error {
+	return nil
+}
+
+// ResultValidatorFunc adapts a function into a Validator that checks results only.
+type ResultValidatorFunc func(ctx context.Context, tool model.Tool, result RunResult) error
+
+// ValidateArgs accepts every argument set.
+func (f ResultValidatorFunc) ValidateArgs(context.Context, model.Tool, map[string]any) error {
+	return nil
+}
+
+// ValidateResult calls f.
+func (f ResultValidatorFunc) ValidateResult(ctx context.Context, tool model.Tool, result RunResult) error {
+	return f(ctx, tool, result)
+}
+
+// validateArgs runs every configured Validator against args, stopping at the first error.
+func (r *DefaultRunner) validateArgs(ctx context.Context, tool model.Tool, args map[string]any) error {
+	for _, v := range r.cfg.Validators {
+		if err := v.ValidateArgs(ctx, tool, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateResult runs every configured Validator against result, stopping at the first error.
+func (r *DefaultRunner) validateResult(ctx context.Context, tool model.Tool, result RunResult) error {
+	for _, v := range r.cfg.Validators {
+		if err := v.ValidateResult(ctx, tool, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+func newValidatorTestRunner(t *testing.T, validators ...Validator) (*DefaultRunner, *int) {
+	t.Helper()
+	idx := newMockIndex()
+	backend := testLocalBackend("myhandler")
+	mustRegisterTool(t, idx, testTool("mytool"), backend)
+	idx.DefaultBackends["mytool"] = backend
+
+	calls := 0
+	localReg := newMockLocalRegistry()
+	localReg.Register("myhandler", func(_ context.Context, args map[string]any) (any, error) {
+		calls++
+		return map[string]any{"echo": args["path"]}, nil
+	})
+
+	runner := NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithValidation(false, false),
+		WithValidators(validators...),
+	)
+	return runner, &calls
+}
+
+func TestWithValidators_Appends(t *testing.T) {
+	a := ArgsValidatorFunc(func(context.Context, model.Tool, map[string]any) error { return nil })
+	b := ResultValidatorFunc(func(context.Context, model.Tool, RunResult) error { return nil })
+
+	cfg := Config{}
+	WithValidators(a)(&cfg)
+	WithValidators(b)(&cfg)
+
+	if len(cfg.Validators) != 2 {
+		t.Errorf("len(Validators) = %d, want 2", len(cfg.Validators))
+	}
+}
+
+func TestRun_Validators_RejectArgs(t *testing.T) {
+	allowlist := ArgsValidatorFunc(func(_ context.Context, tool model.Tool, args map[string]any) error {
+		if p, _ := args["path"].(string); !strings.HasPrefix(p, "/srv/") {
+			return errors.New("path outside allowlist")
+		}
+		return nil
+	})
+	runner, calls := newValidatorTestRunner(t, allowlist)
+
+	_, err := runner.Run(context.Background(), "mytool", map[string]any{"path": "/etc/passwd"})
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("Run() error = %v, want ErrValidation", err)
+	}
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || toolErr.Op != "validate_input" {
+		t.Errorf("Run() error = %v, want ToolError with op validate_input", err)
+	}
+	if *calls != 0 {
+		t.Errorf("handler called %d times, want 0", *calls)
+	}
+
+	if _, err := runner.Run(context.Background(), "mytool", map[string]any{"path": "/srv/data"}); err != nil {
+		t.Fatalf("Run() allowed path error = %v", err)
+	}
+}
+
+func TestRun_Validators_RejectResult(t *testing.T) {
+	var seen RunResult
+	pii := ResultValidatorFunc(func(_ context.Context, tool model.Tool, result RunResult) error {
+		seen = result
+		if m, ok := result.Structured.(map[string]any); ok && m["echo"] == "ssn" {
+			return errors.New("result contains PII")
+		}
+		return nil
+	})
+	runner, _ := newValidatorTestRunner(t, pii)
+
+	_, err := runner.Run(context.Background(), "mytool", map[string]any{"path": "ssn"})
+	if !errors.Is(err, ErrOutputValidation) {
+		t.Fatalf("Run() error = %v, want ErrOutputValidation", err)
+	}
+	if seen.Structured == nil {
+		t.Error("result validator did not see the normalized result")
+	}
+}
+
+func TestRun_Validators_RunInOrder(t *testing.T) {
+	var order []string
+	first := ArgsValidatorFunc(func(context.Context, model.Tool, map[string]any) error {
+		order = append(order, "first")
+		return errors.New("stop")
+	})
+	second := ArgsValidatorFunc(func(context.Context, model.Tool, map[string]any) error {
+		order = append(order, "second")
+		return nil
+	})
+	runner, _ := newValidatorTestRunner(t, first, second)
+
+	if _, err := runner.Run(context.Background(), "mytool", nil); err == nil {
+		t.Fatal("Run() error = nil, want error")
+	}
+	if len(order) != 1 || order[0] != "first" {
+		t.Errorf("validator order = %v, want [first]", order)
+	}
+}
+
+func TestRunStream_Validators_RejectArgs(t *testing.T) {
+	deny := ArgsValidatorFunc(func(context.Context, model.Tool, map[string]any) error {
+		return errors.New("denied")
+	})
+	runner, _ := newValidatorTestRunner(t, deny)
+
+	if _, err := runner.RunStream(context.Background(), "mytool", nil); !errors.Is(err, ErrValidation) {
+		t.Errorf("RunStream() error = %v, want ErrValidation", err)
+	}
+}
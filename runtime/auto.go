@@ -0,0 +1,268 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultRanking orders backend kinds from most to least preferred for each
+// security profile. Hardened prefers VM-level isolation, standard prefers
+// containers and falls back to stronger sandboxes, and dev prefers the
+// fastest local execution. Kinds missing from a profile's list are never
+// selected for it; only dev lists BackendUnsafeHost.
+var DefaultRanking = map[SecurityProfile][]BackendKind{
+	ProfileHardened: {
+		BackendFirecracker, BackendKata, BackendGVisor, BackendWASM,
+	},
+	ProfileStandard: {
+		BackendDocker, BackendPodman, BackendContainerd, BackendKubernetes,
+		BackendNspawn, BackendWindows, BackendGVisor, BackendKata,
+		BackendFirecracker, BackendRemote, BackendProxmoxLXC,
+		BackendServerless, BackendWASM, BackendIsolate,
+	},
+	ProfileDev: {
+		BackendUnsafeHost, BackendDocker, BackendPodman, BackendContainerd,
+		BackendWASM, BackendIsolate,
+	},
+}
+
+// BackendCandidate is a backend NewAutoRuntime may select.
+type BackendCandidate struct {
+	// Backend is the configured backend. Its Kind places it in the ranking.
+	Backend Backend
+
+	// Probe checks that the backend can run on this host.
+	// Default: DefaultProbe(Backend.Kind()); candidates without a probe
+	// count as available
+	Probe Probe
+}
+
+// ProbeResult is the outcome of probing one candidate.
+type ProbeResult struct {
+	// Kind is the candidate's backend kind.
+	Kind BackendKind
+
+	// Err is nil when the backend is available.
+	Err error
+
+	// Duration is how long the probe took.
+	Duration time.Duration
+}
+
+// AutoSelection records the backend NewAutoRuntime chose for a profile.
+type AutoSelection struct {
+	// Profile is the security profile.
+	Profile SecurityProfile
+
+	// Kind is the selected backend's kind.
+	Kind BackendKind
+
+	// Rank is the selected candidate's position among the profile's
+	// candidates, in ranking order; 0 means the first choice was available.
+	Rank int
+
+	// Skipped lists the better-ranked candidates that were unavailable.
+	Skipped []ProbeResult
+}
+
+// AutoRuntimeConfig configures NewAutoRuntime.
+type AutoRuntimeConfig struct {
+	// Candidates are the backends to probe. Several candidates of the
+	// same kind are ranked in the order given.
+	Candidates []BackendCandidate
+
+	// Ranking orders backend kinds per security profile.
+	// Default: DefaultRanking
+	Ranking map[SecurityProfile][]BackendKind
+
+	// ProbeTimeout bounds each probe.
+	// Default: 2s
+	ProbeTimeout time.Duration
+
+	// DenyUnsafeProfiles and DefaultProfile behave as in RuntimeConfig.
+	DenyUnsafeProfiles []SecurityProfile
+	DefaultProfile     SecurityProfile
+
+	// Logger is an optional logger for runtime events, including probe
+	// failures.
+	Logger Logger
+}
+
+// AutoRuntime is a DefaultRuntime whose backends were chosen by probing.
+// Results from a selected backend carry the decision in
+// BackendInfo.Details: autoSelected (true), autoRank, and autoSkipped (the
+// better-ranked kinds that were unavailable, with the reason).
+type AutoRuntime struct {
+	*DefaultRuntime
+
+	mu         sync.RWMutex
+	selections map[SecurityProfile]AutoSelection
+	probes     []ProbeResult
+}
+
+// NewAutoRuntime probes every candidate concurrently and, for each profile
+// in the ranking, registers the best-ranked available backend. Profiles
+// without an available backend are left empty. It returns
+// ErrRuntimeUnavailable, joined with the probe failures, when no profile
+// gets a backend.
+//
+// Probing happens once; call NewAutoRuntime again to re-probe.
+func NewAutoRuntime(ctx context.Context, cfg AutoRuntimeConfig) (*AutoRuntime, error) {
+	ranking := cfg.Ranking
+	if ranking == nil {
+		ranking = DefaultRanking
+	}
+	timeout := cfg.ProbeTimeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	probes := probeCandidates(ctx, cfg.Candidates, timeout)
+	if cfg.Logger != nil {
+		for _, p := range probes {
+			if p.Err != nil {
+				cfg.Logger.Info("backend unavailable", "backend", p.Kind, "error", p.Err)
+			}
+		}
+	}
+
+	backends := make(map[SecurityProfile]Backend)
+	selections := make(map[SecurityProfile]AutoSelection)
+	for profile, kinds := range ranking {
+		sel, idx, ok := selectCandidate(profile, kinds, cfg.Candidates, probes)
+		if !ok {
+			continue
+		}
+		backends[profile] = cfg.Candidates[idx].Backend
+		selections[profile] = sel
+		if cfg.Logger != nil {
+			cfg.Logger.Info("backend selected", "profile", profile, "backend", sel.Kind, "rank", sel.Rank)
+		}
+	}
+	if len(backends) == 0 {
+		var errs []error
+		for _, p := range probes {
+			if p.Err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", p.Kind, p.Err))
+			}
+		}
+		return nil, fmt.Errorf("%w: no candidate backend is available: %w", ErrRuntimeUnavailable, errors.Join(errs...))
+	}
+
+	return &AutoRuntime{
+		DefaultRuntime: NewDefaultRuntime(RuntimeConfig{
+			Backends:           backends,
+			DenyUnsafeProfiles: cfg.DenyUnsafeProfiles,
+			DefaultProfile:     cfg.DefaultProfile,
+			Logger:             cfg.Logger,
+		}),
+		selections: selections,
+		probes:     probes,
+	}, nil
+}
+
+func probeCandidates(ctx context.Context, candidates []BackendCandidate, timeout time.Duration) []ProbeResult {
+	results := make([]ProbeResult, len(candidates))
+	var wg sync.WaitGroup
+	for i, c := range candidates {
+		results[i].Kind = c.Backend.Kind()
+		probe := c.Probe
+		if probe == nil {
+			probe = DefaultProbe(results[i].Kind)
+		}
+		if probe == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			results[i].Err = probe(pctx)
+			results[i].Duration = time.Since(start)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// selectCandidate walks the profile's ranking and returns the first
+// available candidate, with its index into candidates.
+func selectCandidate(profile SecurityProfile, kinds []BackendKind, candidates []BackendCandidate, probes []ProbeResult) (AutoSelection, int, bool) {
+	sel := AutoSelection{Profile: profile}
+	for _, kind := range kinds {
+		for i := range candidates {
+			if probes[i].Kind != kind {
+				continue
+			}
+			if probes[i].Err != nil {
+				sel.Skipped = append(sel.Skipped, probes[i])
+				sel.Rank++
+				continue
+			}
+			sel.Kind = kind
+			return sel, i, true
+		}
+	}
+	return AutoSelection{}, 0, false
+}
+
+// Execute implements Runtime and records the selection in the result's
+// BackendInfo.Details.
+func (r *AutoRuntime) Execute(ctx context.Context, req ExecuteRequest) (ExecuteResult, error) {
+	result, err := r.DefaultRuntime.Execute(ctx, req)
+	if err != nil && result.Backend.Kind == "" {
+		return result, err
+	}
+	profile := req.Profile
+	if profile == "" {
+		profile = r.defaultProfile
+	}
+	r.mu.RLock()
+	sel, ok := r.selections[profile]
+	r.mu.RUnlock()
+	// A backend registered after probing replaces the selection.
+	if kind, registered := r.BackendKind(profile); !ok || !registered || kind != sel.Kind {
+		return result, err
+	}
+	details := maps.Clone(result.Backend.Details)
+	if details == nil {
+		details = make(map[string]any)
+	}
+	details["autoSelected"] = true
+	details["autoRank"] = sel.Rank
+	if len(sel.Skipped) > 0 {
+		skipped := make([]string, len(sel.Skipped))
+		for i, p := range sel.Skipped {
+			skipped[i] = fmt.Sprintf("%s: %v", p.Kind, p.Err)
+		}
+		details["autoSkipped"] = skipped
+	}
+	result.Backend.Details = details
+	return result, err
+}
+
+// Selection reports the backend chosen for profile, or false when no
+// candidate was available for it.
+func (r *AutoRuntime) Selection(profile SecurityProfile) (AutoSelection, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sel, ok := r.selections[profile]
+	if ok {
+		sel.Skipped = slices.Clone(sel.Skipped)
+	}
+	return sel, ok
+}
+
+// Probes returns the probe result for every candidate, in candidate order.
+func (r *AutoRuntime) Probes() []ProbeResult {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.probes)
+}
@@ -0,0 +1,139 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func available(context.Context) error { return nil }
+
+func unavailable(context.Context) error { return ErrProbeFailed }
+
+func TestNewAutoRuntimeRanksPerProfile(t *testing.T) {
+	firecracker := &mockBackend{kind: BackendFirecracker, result: ExecuteResult{Value: "firecracker"}}
+	gvisor := &mockBackend{kind: BackendGVisor, result: ExecuteResult{Value: "gvisor", Backend: BackendInfo{Kind: BackendGVisor}}}
+	docker := &mockBackend{kind: BackendDocker, result: ExecuteResult{Value: "docker", Backend: BackendInfo{Kind: BackendDocker}}}
+	unsafe := &mockBackend{kind: BackendUnsafeHost, result: ExecuteResult{Value: "unsafe", Backend: BackendInfo{Kind: BackendUnsafeHost}}}
+
+	rt, err := NewAutoRuntime(context.Background(), AutoRuntimeConfig{
+		Candidates: []BackendCandidate{
+			{Backend: unsafe, Probe: available},
+			{Backend: docker, Probe: available},
+			{Backend: gvisor, Probe: available},
+			{Backend: firecracker, Probe: unavailable},
+		},
+		DefaultProfile: ProfileStandard,
+	})
+	if err != nil {
+		t.Fatalf("NewAutoRuntime() error = %v", err)
+	}
+
+	for profile, want := range map[SecurityProfile]BackendKind{
+		ProfileHardened: BackendGVisor,
+		ProfileStandard: BackendDocker,
+		ProfileDev:      BackendUnsafeHost,
+	} {
+		if kind, ok := rt.BackendKind(profile); !ok || kind != want {
+			t.Errorf("BackendKind(%s) = %q, %v; want %q", profile, kind, ok, want)
+		}
+	}
+
+	sel, ok := rt.Selection(ProfileHardened)
+	if !ok || sel.Rank != 1 || len(sel.Skipped) != 1 || sel.Skipped[0].Kind != BackendFirecracker {
+		t.Errorf("Selection(hardened) = %+v, %v", sel, ok)
+	}
+
+	result, err := rt.Execute(context.Background(), ExecuteRequest{
+		Code:    "x",
+		Gateway: &mockToolGateway{},
+		Profile: ProfileHardened,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	details := result.Backend.Details
+	if details["autoSelected"] != true || details["autoRank"] != 1 {
+		t.Errorf("Details = %v, want autoSelected and autoRank 1", details)
+	}
+	if skipped, _ := details["autoSkipped"].([]string); len(skipped) != 1 {
+		t.Errorf("Details[autoSkipped] = %v, want one entry", details["autoSkipped"])
+	}
+
+	// A backend registered after probing is not annotated.
+	rt.RegisterBackend(ProfileStandard, firecracker)
+	result, err = rt.Execute(context.Background(), ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Value != "firecracker" || result.Backend.Details["autoSelected"] != nil {
+		t.Errorf("Execute() = %v with details %v, want the registered backend unannotated", result.Value, result.Backend.Details)
+	}
+}
+
+func TestNewAutoRuntimeNoneAvailable(t *testing.T) {
+	_, err := NewAutoRuntime(context.Background(), AutoRuntimeConfig{
+		Candidates: []BackendCandidate{
+			{Backend: &mockBackend{kind: BackendKata}, Probe: unavailable},
+			// Unsafe is only ranked for dev, which this ranking omits.
+			{Backend: &mockBackend{kind: BackendUnsafeHost}},
+		},
+		Ranking: map[SecurityProfile][]BackendKind{
+			ProfileHardened: {BackendKata},
+		},
+	})
+	if !errors.Is(err, ErrRuntimeUnavailable) || !errors.Is(err, ErrProbeFailed) {
+		t.Errorf("NewAutoRuntime() error = %v, want %v joined with %v", err, ErrRuntimeUnavailable, ErrProbeFailed)
+	}
+}
+
+func TestNewAutoRuntimeDefaultProbe(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	rt, err := NewAutoRuntime(context.Background(), AutoRuntimeConfig{
+		Candidates: []BackendCandidate{
+			{Backend: &mockBackend{kind: BackendFirecracker}},
+			{Backend: &mockBackend{kind: BackendWASM}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAutoRuntime() error = %v", err)
+	}
+	probes := rt.Probes()
+	if !errors.Is(probes[0].Err, ErrProbeFailed) {
+		t.Errorf("firecracker probe error = %v, want %v", probes[0].Err, ErrProbeFailed)
+	}
+	if probes[1].Err != nil {
+		t.Errorf("wasm probe error = %v, want nil (no default probe)", probes[1].Err)
+	}
+	if kind, _ := rt.BackendKind(ProfileHardened); kind != BackendWASM {
+		t.Errorf("BackendKind(hardened) = %q, want %q", kind, BackendWASM)
+	}
+}
+
+func TestProbeURL(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	sock := filepath.Join(t.TempDir(), "s.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	ctx := context.Background()
+	for _, url := range []string{srv.URL, "tcp://" + srv.Listener.Addr().String(), "unix://" + sock} {
+		if err := ProbeURL(url)(ctx); err != nil {
+			t.Errorf("ProbeURL(%s) error = %v", url, err)
+		}
+	}
+	for _, url := range []string{"unix://" + sock + ".missing", "ftp://example.com"} {
+		if err := ProbeURL(url)(ctx); !errors.Is(err, ErrProbeFailed) {
+			t.Errorf("ProbeURL(%s) error = %v, want %v", url, err, ErrProbeFailed)
+		}
+	}
+}
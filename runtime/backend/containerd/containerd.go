@@ -28,7 +28,7 @@ var (
 	ErrClientNotConfigured = errors.New("containerd client not configured")
 
 	// ErrDaemonUnavailable is returned when the containerd daemon is not reachable.
-	ErrDaemonUnavailable = errors.New("containerd daemon unavailable")
+	ErrDaemonUnavailable = fmt.Errorf("containerd daemon unavailable: %w", runtime.ErrRuntimeUnavailable)
 
 	// ErrSecurityViolation is returned when a security policy is violated.
 	ErrSecurityViolation = errors.New("security policy violation")
@@ -64,6 +64,11 @@ type Config struct {
 	// Optional.
 	Runtime string
 
+	// Snapshotter is the containerd snapshotter images are unpacked with,
+	// e.g. "overlayfs", or "devmapper" for VM runtimes that need block
+	// devices. Optional; runners use their default.
+	Snapshotter string
+
 	// SeccompPath is the path to a seccomp profile for hardened mode.
 	SeccompPath string
 
@@ -87,6 +92,7 @@ type Backend struct {
 	namespace  string
 	socketPath string
 	runtime    string
+	snapshot   string
 	seccomp    string
 	client     ContainerRunner
 	resolver   ImageResolver
@@ -116,6 +122,7 @@ func New(cfg Config) *Backend {
 		namespace:  namespace,
 		socketPath: socketPath,
 		runtime:    cfg.Runtime,
+		snapshot:   cfg.Snapshotter,
 		seccomp:    cfg.SeccompPath,
 		client:     cfg.Client,
 		resolver:   cfg.ImageResolver,
@@ -129,6 +136,17 @@ func (b *Backend) Kind() runtime.BackendKind {
 	return runtime.BackendContainerd
 }
 
+// Capabilities implements runtime.CapabilityReporter.
+func (b *Backend) Capabilities() runtime.Capabilities {
+	return runtime.Capabilities{
+		Streaming:    true,
+		Workspace:    true,
+		FileStaging:  true,
+		NetworkModes: []string{"none", "bridge"},
+		GPU:          true,
+	}
+}
+
 // Execute runs code via containerd with security isolation.
 func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
 	if err := ctx.Err(); err != nil {
@@ -193,18 +211,24 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 		}, err
 	}
 
+	usage := containerResult.Usage
+	usage.WallTime = containerResult.Duration
 	return runtime.ExecuteResult{
-		Value:    extractOutValue(containerResult.Stdout),
-		Stdout:   containerResult.Stdout,
-		Stderr:   containerResult.Stderr,
-		Duration: containerResult.Duration,
-		Backend:  b.backendInfo(profile),
+		Value:     extractOutValue(containerResult.Stdout),
+		Stdout:    containerResult.Stdout,
+		Stderr:    containerResult.Stderr,
+		Truncated: containerResult.Truncated,
+		Duration:  containerResult.Duration,
+		Backend:   b.backendInfo(profile),
+		Usage:     usage,
+		Artifacts: containerResult.Artifacts,
 		LimitsEnforced: runtime.LimitsEnforced{
 			Timeout:    true,
 			Memory:     req.Limits.MemoryBytes > 0,
 			CPU:        req.Limits.CPUQuotaMillis > 0,
 			Pids:       req.Limits.PidsMax > 0,
 			Disk:       req.Limits.DiskBytes > 0,
+			Output:     req.MaxOutputBytes > 0,
 			ToolCalls:  true,
 			ChainSteps: true,
 		},
@@ -214,7 +238,7 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 var _ runtime.Backend = (*Backend)(nil)
 
 func (b *Backend) backendInfo(profile runtime.SecurityProfile) runtime.BackendInfo {
-	return runtime.BackendInfo{
+	info := runtime.BackendInfo{
 		Kind:      runtime.BackendContainerd,
 		Readiness: runtime.ReadinessBeta,
 		Details: map[string]any{
@@ -224,19 +248,31 @@ func (b *Backend) backendInfo(profile runtime.SecurityProfile) runtime.BackendIn
 			"profile":   string(profile),
 		},
 	}
+	if b.snapshot != "" {
+		info.Details["snapshotter"] = b.snapshot
+	}
+	return info
 }
 
 func (b *Backend) buildSpec(image string, req runtime.ExecuteRequest, profile runtime.SecurityProfile) (ContainerSpec, error) {
 	opts := b.containerOptions(profile, req.Limits)
+	staging, err := req.Staging()
+	if err != nil {
+		return ContainerSpec{}, err
+	}
 
 	spec := ContainerSpec{
-		Image:   image,
-		Runtime: b.runtime,
+		Image:       image,
+		Namespace:   b.namespace,
+		Runtime:     b.runtime,
+		Snapshotter: b.snapshot,
 		Resources: ResourceSpec{
 			MemoryBytes: opts.MemoryLimit,
 			CPUQuota:    opts.CPUQuota,
 			PidsLimit:   opts.PidsLimit,
 			DiskBytes:   opts.DiskBytes,
+			GPUs:        opts.GPUs,
+			GPUClass:    opts.GPUClass,
 		},
 		Security: SecuritySpec{
 			User:           opts.User,
@@ -244,11 +280,21 @@ func (b *Backend) buildSpec(image string, req runtime.ExecuteRequest, profile ru
 			NetworkMode:    opts.NetworkMode,
 			SeccompProfile: opts.SeccompProfile,
 		},
-		Timeout: req.Timeout,
+		Timeout:        req.Timeout,
+		MaxOutputBytes: req.MaxOutputBytes,
 		Labels: map[string]string{
-			"runtime.profile": string(profile),
-			"runtime.backend": string(runtime.BackendContainerd),
+			"runtime.profile":        string(profile),
+			"runtime.backend":        string(runtime.BackendContainerd),
+			runtime.LabelExecutionID: req.EnsureExecutionID(),
 		},
+		LogStreamer: req.LogStreamer,
+	}
+	if staging != nil {
+		spec.Staging = staging
+		spec.WorkingDir = staging.Dir
+		spec.Env = append(spec.Env,
+			runtime.WorkspaceEnv+"="+staging.Dir,
+			runtime.OutputEnv+"="+staging.OutputPath())
 	}
 
 	if err := spec.Validate(); err != nil {
@@ -264,6 +310,8 @@ type containerOptions struct {
 	CPUQuota       int64
 	PidsLimit      int64
 	DiskBytes      int64
+	GPUs           int
+	GPUClass       string
 	SeccompProfile string
 	User           string
 }
@@ -300,6 +348,10 @@ func (b *Backend) containerOptions(profile runtime.SecurityProfile, limits runti
 	if limits.DiskBytes > 0 {
 		opts.DiskBytes = limits.DiskBytes
 	}
+	if limits.GPUs.Count > 0 {
+		opts.GPUs = limits.GPUs.Count
+		opts.GPUClass = limits.GPUs.DeviceClass()
+	}
 
 	return opts
 }
@@ -3,6 +3,8 @@ package containerd
 import (
 	"context"
 	"errors"
+	"reflect"
+	"slices"
 	"testing"
 
 	"github.com/jonwraymond/tooldiscovery/index"
@@ -120,6 +122,73 @@ func TestBackendWithMockClient(t *testing.T) {
 	}
 }
 
+func TestBackendGPUs(t *testing.T) {
+	var got ResourceSpec
+	mockRunner := &mockContainerRunner{
+		runFunc: func(_ context.Context, spec ContainerSpec) (ContainerResult, error) {
+			got = spec.Resources
+			return ContainerResult{}, nil
+		},
+	}
+	b := New(Config{Client: mockRunner})
+
+	req := runtime.ExecuteRequest{
+		Code:    "test",
+		Gateway: &mockGateway{},
+		Limits:  runtime.Limits{GPUs: runtime.GPURequest{Count: 1}},
+	}
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got.GPUs != 1 || got.GPUClass != runtime.DefaultGPUClass {
+		t.Errorf("GPUs = %d/%q, want 1/%s", got.GPUs, got.GPUClass, runtime.DefaultGPUClass)
+	}
+}
+
+func TestBackendNamespaceAndSnapshotter(t *testing.T) {
+	var got ContainerSpec
+	mockRunner := &mockContainerRunner{
+		runFunc: func(_ context.Context, spec ContainerSpec) (ContainerResult, error) {
+			got = spec
+			return ContainerResult{}, nil
+		},
+	}
+	b := New(Config{Client: mockRunner, Namespace: "tenant-a", Runtime: "kata", Snapshotter: "devmapper"})
+
+	result, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got.Namespace != "tenant-a" || got.Runtime != "kata" || got.Snapshotter != "devmapper" {
+		t.Errorf("spec = %q/%q/%q, want the configured namespace, runtime, and snapshotter", got.Namespace, got.Runtime, got.Snapshotter)
+	}
+	if result.Backend.Details["snapshotter"] != "devmapper" {
+		t.Errorf("Details = %v, want the snapshotter", result.Backend.Details)
+	}
+}
+
+func TestBackendMaxOutputBytes(t *testing.T) {
+	var got int64
+	mockRunner := &mockContainerRunner{
+		runFunc: func(_ context.Context, spec ContainerSpec) (ContainerResult, error) {
+			got = spec.MaxOutputBytes
+			return ContainerResult{Stdout: "abc" + runtime.OutputTruncatedMarker, Truncated: true}, nil
+		},
+	}
+	b := New(Config{Client: mockRunner})
+
+	result, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}, MaxOutputBytes: 3})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got != 3 {
+		t.Errorf("spec MaxOutputBytes = %d, want 3", got)
+	}
+	if !result.Truncated || !result.LimitsEnforced.Output {
+		t.Errorf("Truncated = %v, LimitsEnforced.Output = %v; want both", result.Truncated, result.LimitsEnforced.Output)
+	}
+}
+
 type mockContainerRunner struct {
 	runFunc func(ctx context.Context, spec ContainerSpec) (ContainerResult, error)
 }
@@ -130,3 +199,35 @@ func (m *mockContainerRunner) Run(ctx context.Context, spec ContainerSpec) (Cont
 	}
 	return ContainerResult{}, nil
 }
+
+func TestBackendStagesFiles(t *testing.T) {
+	artifacts := []runtime.Artifact{{Name: "report.txt", Data: []byte("done")}}
+	var got ContainerSpec
+	b := New(Config{Client: &mockContainerRunner{
+		runFunc: func(_ context.Context, spec ContainerSpec) (ContainerResult, error) {
+			got = spec
+			return ContainerResult{Artifacts: artifacts}, nil
+		},
+	}})
+	req := runtime.ExecuteRequest{
+		Code:      "test",
+		Gateway:   &mockGateway{},
+		Workspace: &runtime.Workspace{Path: "/scratch", MaxBytes: 1 << 20},
+		Files:     map[string]runtime.File{"input.txt": {Data: []byte("data")}},
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got.Staging == nil || got.Staging.Dir != "/scratch" || got.Staging.MaxBytes != 1<<20 || string(got.Staging.Files["input.txt"]) != "data" {
+		t.Errorf("Staging = %+v, want /scratch with input.txt", got.Staging)
+	}
+	wantEnv := []string{runtime.WorkspaceEnv + "=/scratch", runtime.OutputEnv + "=/scratch/out"}
+	if got.WorkingDir != "/scratch" || !slices.Equal(got.Env, wantEnv) {
+		t.Errorf("WorkingDir = %q, Env = %v", got.WorkingDir, got.Env)
+	}
+	if !reflect.DeepEqual(result.Artifacts, artifacts) {
+		t.Errorf("Artifacts = %+v, want %+v", result.Artifacts, artifacts)
+	}
+}
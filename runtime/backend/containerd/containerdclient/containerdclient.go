@@ -0,0 +1,439 @@
+// Package containerdclient provides a containerd.ContainerRunner backed by
+// the containerd Go client.
+//
+// It is a separate module so that the core toolexec module does not
+// depend on containerd; import it only when running the containerd
+// backend:
+//
+//	runner, err := containerdclient.New(containerdclient.Config{})
+//	if err != nil {
+//		return err
+//	}
+//	defer runner.Close()
+//	backend := containerd.New(containerd.Config{
+//		Runtime:       "runsc",
+//		Client:        runner,
+//		ImageResolver: runner,
+//		HealthChecker: runner,
+//	})
+//
+// # Namespaces, Runtimes, and Snapshotters
+//
+// Containers are created in ContainerSpec.Namespace, or Config.Namespace
+// when it is empty. ContainerSpec.Runtime may be a full runtime type such
+// as "io.containerd.kata.v2" or a handler name: "runc", "runsc" (or
+// "gvisor"), and "kata" are built in, and Config.Runtimes adds more, such
+// as "kata-fc" for Kata with Firecracker. Images are unpacked with
+// ContainerSpec.Snapshotter, or Config.Snapshotter, so VM runtimes can use
+// a block-device snapshotter such as devmapper.
+//
+// Image references are looked up as containerd stores them, so they should
+// be fully qualified, e.g. "docker.io/library/python:3.12". Resolve pulls
+// images that are missing.
+//
+// # Container Spec
+//
+// Every field of containerd.ContainerSpec is applied to the container:
+//
+//   - Security.NetworkMode "none" gives the container its own network
+//     namespace with only loopback. "bridge" joins Config.NetworkNamespace
+//     when it is set, such as one prepared by CNI, and otherwise also runs
+//     without a network.
+//   - Security.SeccompProfile is read from the host path.
+//   - Security.ReadOnlyRootfs and User map to the OCI spec.
+//   - Resources.CPUQuota is applied per 100ms period.
+//   - Resources.GPUs becomes CDI devices "<GPUClass>=<index>".
+//   - Resources.DiskBytes is not enforced; snapshotters have no portable
+//     size limit.
+//
+// When Command is empty, the image's entrypoint and command run.
+//
+// # File Staging
+//
+// When ContainerSpec.Staging is set, Run writes its files to a host
+// directory under Config.WorkspaceDir, bind-mounts it at Staging.Dir, and
+// reads the output directory back as artifacts after the task exits. Host
+// directories have no size limit, so Staging.MaxBytes is checked against
+// the workspace afterwards, failing with runtime.ErrResourceLimit.
+//
+// Containers, their snapshots, and staged workspaces are always removed
+// when Run returns. Tasks that exceed their timeout or whose context is
+// canceled are killed first.
+//
+// # Resource Usage
+//
+// Before deleting a task, Run reads its CPU time, peak memory, and bytes
+// written from the container's cgroup under Config.CgroupRoot. Only cgroup
+// v2 hosts using the cgroupfs driver report usage; network traffic is not
+// measured.
+package containerdclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/pkg/cio"
+	"github.com/containerd/containerd/v2/pkg/namespaces"
+	"github.com/containerd/errdefs"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/containerd"
+)
+
+// Runtime types for the built-in handler names.
+const (
+	RuntimeRunc  = "io.containerd.runc.v2"
+	RuntimeRunsc = "io.containerd.runsc.v1"
+	RuntimeKata  = "io.containerd.kata.v2"
+)
+
+// Defaults for Config.
+const (
+	DefaultAddress    = "/run/containerd/containerd.sock"
+	DefaultNamespace  = "default"
+	DefaultCgroupRoot = "/sys/fs/cgroup"
+)
+
+// API is the subset of the containerd client used by Runner.
+// *client.Client implements it.
+type API interface {
+	Version(ctx context.Context) (client.Version, error)
+	GetImage(ctx context.Context, ref string) (client.Image, error)
+	Pull(ctx context.Context, ref string, opts ...client.RemoteOpt) (client.Image, error)
+	NewContainer(ctx context.Context, id string, opts ...client.NewContainerOpts) (client.Container, error)
+}
+
+// Config configures a Runner.
+type Config struct {
+	// Client is the containerd client. If nil, one is connected at
+	// Address.
+	Client API
+
+	// Address is the containerd socket. Ignored when Client is set.
+	// Default: /run/containerd/containerd.sock
+	Address string
+
+	// Namespace is used for specs without a namespace.
+	// Default: default
+	Namespace string
+
+	// Snapshotter is used for specs without a snapshotter.
+	// Default: containerd's default snapshotter
+	Snapshotter string
+
+	// DefaultRuntime is used for specs without a runtime.
+	// Default: runc
+	DefaultRuntime string
+
+	// Runtimes maps additional handler names to runtime types, e.g.
+	// "kata-fc" to "io.containerd.kata-fc.v2". Entries override the
+	// built-in names.
+	Runtimes map[string]string
+
+	// NetworkNamespace is the path of a network namespace joined by
+	// containers with NetworkMode "bridge". Empty gives them no network.
+	NetworkNamespace string
+
+	// WorkspaceDir is where staged workspaces are created on the host.
+	// Default: os.TempDir()
+	WorkspaceDir string
+
+	// CgroupRoot is the host's cgroup v2 mount, where resource usage is
+	// read from before a task is deleted.
+	// Default: /sys/fs/cgroup
+	CgroupRoot string
+}
+
+// Runner runs containers through containerd. It implements
+// containerd.ContainerRunner, containerd.ImageResolver, and
+// containerd.HealthChecker, and is safe for concurrent use.
+type Runner struct {
+	api            API
+	closer         io.Closer
+	namespace      string
+	snapshotter    string
+	defaultRuntime string
+	runtimes       map[string]string
+	netns          string
+	workspaceDir   string
+	cgroupRoot     string
+}
+
+// New creates a Runner.
+func New(cfg Config) (*Runner, error) {
+	r := &Runner{
+		api:          cfg.Client,
+		namespace:    cfg.Namespace,
+		snapshotter:  cfg.Snapshotter,
+		netns:        cfg.NetworkNamespace,
+		workspaceDir: cfg.WorkspaceDir,
+		cgroupRoot:   cfg.CgroupRoot,
+		runtimes: map[string]string{
+			"runc":   RuntimeRunc,
+			"runsc":  RuntimeRunsc,
+			"gvisor": RuntimeRunsc,
+			"kata":   RuntimeKata,
+		},
+	}
+	if r.namespace == "" {
+		r.namespace = DefaultNamespace
+	}
+	if r.cgroupRoot == "" {
+		r.cgroupRoot = DefaultCgroupRoot
+	}
+	maps.Copy(r.runtimes, cfg.Runtimes)
+	r.defaultRuntime = RuntimeRunc
+	if cfg.DefaultRuntime != "" {
+		rt, err := r.runtimeFor(cfg.DefaultRuntime)
+		if err != nil {
+			return nil, err
+		}
+		r.defaultRuntime = rt
+	}
+	if r.api == nil {
+		address := cfg.Address
+		if address == "" {
+			address = DefaultAddress
+		}
+		cli, err := client.New(address, client.WithDefaultNamespace(r.namespace))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", containerd.ErrContainerdNotAvailable, err)
+		}
+		r.api = cli
+		r.closer = cli
+	}
+	return r, nil
+}
+
+// Close releases the client created by New. A Config.Client is left open.
+func (r *Runner) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// Ping implements containerd.HealthChecker.
+func (r *Runner) Ping(ctx context.Context) error {
+	if _, err := r.api.Version(ctx); err != nil {
+		return fmt.Errorf("%w: %v", containerd.ErrDaemonUnavailable, err)
+	}
+	return nil
+}
+
+// Resolve implements containerd.ImageResolver. It pulls and unpacks ref
+// into Config.Namespace with Config.Snapshotter when it is not present.
+func (r *Runner) Resolve(ctx context.Context, ref string) (string, error) {
+	img, err := r.image(namespaces.WithNamespace(ctx, r.namespace), ref, r.snapshotter)
+	if err != nil {
+		return "", err
+	}
+	return img.Name(), nil
+}
+
+// Run implements containerd.ContainerRunner.
+func (r *Runner) Run(ctx context.Context, spec containerd.ContainerSpec) (containerd.ContainerResult, error) {
+	if err := spec.Validate(); err != nil {
+		return containerd.ContainerResult{}, err
+	}
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spec.Timeout)
+		defer cancel()
+	}
+	ns := spec.Namespace
+	if ns == "" {
+		ns = r.namespace
+	}
+	ctx = namespaces.WithNamespace(ctx, ns)
+	cleanupCtx := context.WithoutCancel(ctx)
+	start := time.Now()
+
+	snapshotter := spec.Snapshotter
+	if snapshotter == "" {
+		snapshotter = r.snapshotter
+	}
+	img, err := r.image(ctx, spec.Image, snapshotter)
+	if err != nil {
+		return containerd.ContainerResult{}, err
+	}
+
+	var workspace string
+	if spec.Staging != nil {
+		if workspace, err = r.stage(spec.Staging); err != nil {
+			return containerd.ContainerResult{}, fmt.Errorf("%w: stage: %v", containerd.ErrContainerFailed, err)
+		}
+		defer func() { _ = os.RemoveAll(workspace) }()
+	}
+	runtimeType, err := r.runtimeFor(spec.Runtime)
+	if err != nil {
+		return containerd.ContainerResult{}, err
+	}
+	specOpts, err := r.specOpts(img, spec, workspace)
+	if err != nil {
+		return containerd.ContainerResult{}, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return containerd.ContainerResult{}, fmt.Errorf("%w: %v", containerd.ErrContainerFailed, err)
+	}
+	opts := []client.NewContainerOpts{client.WithImage(img)}
+	if snapshotter != "" {
+		opts = append(opts, client.WithSnapshotter(snapshotter))
+	}
+	opts = append(opts,
+		client.WithNewSnapshot(id+"-snapshot", img),
+		client.WithRuntime(runtimeType, nil),
+		client.WithNewSpec(specOpts...),
+		client.WithContainerLabels(spec.Labels),
+	)
+	ctr, err := r.api.NewContainer(ctx, id, opts...)
+	if err != nil {
+		return containerd.ContainerResult{}, fmt.Errorf("%w: create: %v", containerd.ErrContainerFailed, err)
+	}
+	defer func() { _ = ctr.Delete(cleanupCtx, client.WithSnapshotCleanup) }()
+
+	stdout := runtime.NewOutputBuffer(spec.MaxOutputBytes)
+	stderr := runtime.NewOutputBuffer(spec.MaxOutputBytes)
+	stdoutW, stderrW := io.Writer(stdout), io.Writer(stderr)
+	if spec.LogStreamer != nil {
+		stdoutLines := runtime.NewLineWriter(spec.LogStreamer, runtime.LogStdout)
+		stderrLines := runtime.NewLineWriter(spec.LogStreamer, runtime.LogStderr)
+		defer func() {
+			_ = stdoutLines.Close()
+			_ = stderrLines.Close()
+		}()
+		stdoutW = io.MultiWriter(stdout, stdoutLines)
+		stderrW = io.MultiWriter(stderr, stderrLines)
+	}
+
+	task, err := ctr.NewTask(ctx, cio.NewCreator(cio.WithStreams(nil, stdoutW, stderrW)))
+	if err != nil {
+		return containerd.ContainerResult{}, fmt.Errorf("%w: task: %v", containerd.ErrContainerFailed, err)
+	}
+	deleted := false
+	defer func() {
+		if !deleted {
+			_, _ = task.Delete(cleanupCtx, client.WithProcessKill)
+		}
+	}()
+	// Wait outlives ctx so the exit of a killed task is still observed.
+	exited, err := task.Wait(cleanupCtx)
+	if err != nil {
+		return containerd.ContainerResult{}, fmt.Errorf("%w: wait: %v", containerd.ErrContainerFailed, err)
+	}
+	if err := task.Start(ctx); err != nil {
+		return containerd.ContainerResult{}, fmt.Errorf("%w: start: %v", containerd.ErrContainerFailed, err)
+	}
+
+	var status client.ExitStatus
+	select {
+	case status = <-exited:
+	case <-ctx.Done():
+		_ = task.Kill(cleanupCtx, syscall.SIGKILL)
+		status = <-exited
+	}
+	// Deleting the task removes its cgroup, so read usage first.
+	// containerd places containers at /<namespace>/<id>; runtimes using
+	// the systemd cgroup driver, or a VM, report none.
+	usage, _ := runtime.CgroupUsage(filepath.Join(r.cgroupRoot, ns, id))
+	// Deleting the task waits for its output to be copied.
+	_, deleteErr := task.Delete(cleanupCtx)
+	deleted = deleteErr == nil
+
+	code, _, waitErr := status.Result()
+	result := containerd.ContainerResult{
+		ExitCode:  int(code),
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Truncated: stdout.Truncated() || stderr.Truncated(),
+		Duration:  time.Since(start),
+		Usage:     usage,
+	}
+	if err := ctx.Err(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return result, fmt.Errorf("%w: %v", runtime.ErrTimeout, err)
+		}
+		return result, err
+	}
+	if waitErr != nil {
+		return result, fmt.Errorf("%w: wait: %v", containerd.ErrContainerFailed, waitErr)
+	}
+	if spec.Staging != nil {
+		artifacts, err := collectWorkspace(workspace, spec.Staging)
+		if errors.Is(err, runtime.ErrResourceLimit) {
+			return result, err
+		}
+		if err != nil {
+			return result, fmt.Errorf("%w: collect: %v", containerd.ErrContainerFailed, err)
+		}
+		result.Artifacts = artifacts
+	}
+	return result, nil
+}
+
+// image returns ref, pulling it when it is missing and unpacking it for
+// snapshotter when needed.
+func (r *Runner) image(ctx context.Context, ref, snapshotter string) (client.Image, error) {
+	img, err := r.api.GetImage(ctx, ref)
+	if errdefs.IsNotFound(err) {
+		opts := []client.RemoteOpt{client.WithPullUnpack}
+		if snapshotter != "" {
+			opts = append(opts, client.WithPullSnapshotter(snapshotter))
+		}
+		img, err = r.api.Pull(ctx, ref, opts...)
+		if errdefs.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", containerd.ErrImageNotFound, ref)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: pull %s: %v", containerd.ErrContainerFailed, ref, err)
+		}
+		return img, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: image %s: %v", containerd.ErrContainerFailed, ref, err)
+	}
+	unpacked, err := img.IsUnpacked(ctx, snapshotter)
+	if err == nil && !unpacked {
+		err = img.Unpack(ctx, snapshotter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: unpack %s: %v", containerd.ErrContainerFailed, ref, err)
+	}
+	return img, nil
+}
+
+// runtimeFor maps a handler name to its runtime type, and an empty name
+// to the default runtime. Full runtime types, which contain a dot, are
+// returned unchanged.
+func (r *Runner) runtimeFor(name string) (string, error) {
+	if name == "" {
+		return r.defaultRuntime, nil
+	}
+	if rt, ok := r.runtimes[name]; ok {
+		return rt, nil
+	}
+	if strings.Contains(name, ".") {
+		return name, nil
+	}
+	return "", fmt.Errorf("%w: unknown runtime %q", containerd.ErrContainerFailed, name)
+}
+
+func newID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return "toolexec-" + hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,384 @@
+package containerdclient
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/pkg/cio"
+	"github.com/containerd/containerd/v2/pkg/namespaces"
+	"github.com/containerd/containerd/v2/pkg/oci"
+	"github.com/containerd/errdefs"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/containerd"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// fakeAPI serves images from a fixed set and records containers.
+type fakeAPI struct {
+	mu         sync.Mutex
+	images     map[string]*fakeImage
+	pullable   map[string]bool
+	pulls      []string
+	namespaces []string
+	containers []*fakeContainer
+	exitCode   uint32
+	hang       bool
+	cgroupRoot string // if set, containers get a cgroup with usage here
+}
+
+func (f *fakeAPI) Version(context.Context) (client.Version, error) {
+	return client.Version{Version: "v2.1.4"}, nil
+}
+
+func (f *fakeAPI) GetImage(_ context.Context, ref string) (client.Image, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if img, ok := f.images[ref]; ok {
+		return img, nil
+	}
+	return nil, errdefs.ErrNotFound
+}
+
+func (f *fakeAPI) Pull(ctx context.Context, ref string, _ ...client.RemoteOpt) (client.Image, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pulls = append(f.pulls, ref)
+	if !f.pullable[ref] {
+		return nil, errdefs.ErrNotFound
+	}
+	img := &fakeImage{name: ref, unpacked: true}
+	f.images[ref] = img
+	return img, nil
+}
+
+func (f *fakeAPI) NewContainer(ctx context.Context, id string, opts ...client.NewContainerOpts) (client.Container, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ns, _ := namespaces.Namespace(ctx)
+	f.namespaces = append(f.namespaces, ns)
+	if f.cgroupRoot != "" {
+		dir := filepath.Join(f.cgroupRoot, ns, id)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cpu.stat"), []byte("usage_usec 2500\n"), 0o644); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "memory.peak"), []byte("4096\n"), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	c := &fakeContainer{id: id, task: &fakeTask{code: f.exitCode, hang: f.hang, exited: make(chan client.ExitStatus, 1)}}
+	f.containers = append(f.containers, c)
+	return c, nil
+}
+
+type fakeImage struct {
+	client.Image
+	name        string
+	unpacked    bool
+	unpackedFor []string
+}
+
+func (i *fakeImage) Name() string { return i.name }
+
+func (i *fakeImage) IsUnpacked(context.Context, string) (bool, error) { return i.unpacked, nil }
+
+func (i *fakeImage) Unpack(_ context.Context, snapshotter string, _ ...client.UnpackOpt) error {
+	i.unpackedFor = append(i.unpackedFor, snapshotter)
+	i.unpacked = true
+	return nil
+}
+
+type fakeContainer struct {
+	client.Container
+	id      string
+	task    *fakeTask
+	deleted bool
+}
+
+func (c *fakeContainer) ID() string { return c.id }
+
+func (c *fakeContainer) NewTask(context.Context, cio.Creator, ...client.NewTaskOpts) (client.Task, error) {
+	return c.task, nil
+}
+
+func (c *fakeContainer) Delete(context.Context, ...client.DeleteOpts) error {
+	c.deleted = true
+	return nil
+}
+
+// fakeTask exits with code when started, or only when killed if hang is
+// set.
+type fakeTask struct {
+	client.Task
+	code    uint32
+	hang    bool
+	exited  chan client.ExitStatus
+	killed  bool
+	deleted bool
+}
+
+func (t *fakeTask) Wait(context.Context) (<-chan client.ExitStatus, error) { return t.exited, nil }
+
+func (t *fakeTask) Start(context.Context) error {
+	if !t.hang {
+		t.exited <- *client.NewExitStatus(t.code, time.Now(), nil)
+	}
+	return nil
+}
+
+func (t *fakeTask) Kill(_ context.Context, sig syscall.Signal, _ ...client.KillOpts) error {
+	t.killed = true
+	t.exited <- *client.NewExitStatus(128+uint32(sig), time.Now(), nil)
+	return nil
+}
+
+func (t *fakeTask) Delete(context.Context, ...client.ProcessDeleteOpts) (*client.ExitStatus, error) {
+	t.deleted = true
+	return nil, nil
+}
+
+func newRunner(t *testing.T, api *fakeAPI, cfg Config) *Runner {
+	t.Helper()
+	if api.images == nil {
+		api.images = map[string]*fakeImage{"docker.io/library/sandbox:latest": {name: "docker.io/library/sandbox:latest", unpacked: true}}
+	}
+	cfg.Client = api
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return r
+}
+
+func TestRunExitCode(t *testing.T) {
+	api := &fakeAPI{exitCode: 3}
+	r := newRunner(t, api, Config{})
+
+	result, err := r.Run(context.Background(), containerd.ContainerSpec{Image: "docker.io/library/sandbox:latest", Namespace: "tenant-a"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", result.ExitCode)
+	}
+	c := api.containers[0]
+	if !c.deleted || !c.task.deleted {
+		t.Errorf("container deleted = %v, task deleted = %v; want both", c.deleted, c.task.deleted)
+	}
+	if api.namespaces[0] != "tenant-a" {
+		t.Errorf("namespace = %q, want the spec's", api.namespaces[0])
+	}
+
+	if _, err := r.Run(context.Background(), containerd.ContainerSpec{Image: "docker.io/library/sandbox:latest"}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if api.namespaces[1] != DefaultNamespace {
+		t.Errorf("namespace = %q, want %q", api.namespaces[1], DefaultNamespace)
+	}
+}
+
+func TestRunReadsCgroupUsage(t *testing.T) {
+	api := &fakeAPI{cgroupRoot: t.TempDir()}
+	r := newRunner(t, api, Config{CgroupRoot: api.cgroupRoot})
+
+	result, err := r.Run(context.Background(), containerd.ContainerSpec{Image: "docker.io/library/sandbox:latest"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := runtime.ResourceUsage{CPUTime: 2500 * time.Microsecond, PeakMemoryBytes: 4096}
+	if result.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", result.Usage, want)
+	}
+
+	// Without a readable cgroup, usage is left empty.
+	r = newRunner(t, &fakeAPI{}, Config{CgroupRoot: t.TempDir()})
+	result, err = r.Run(context.Background(), containerd.ContainerSpec{Image: "docker.io/library/sandbox:latest"})
+	if err != nil || result.Usage != (runtime.ResourceUsage{}) {
+		t.Errorf("Run() = %+v, %v; want no usage", result.Usage, err)
+	}
+}
+
+func TestRunTimeoutKills(t *testing.T) {
+	api := &fakeAPI{hang: true}
+	r := newRunner(t, api, Config{})
+
+	_, err := r.Run(context.Background(), containerd.ContainerSpec{Image: "docker.io/library/sandbox:latest", Timeout: 20 * time.Millisecond})
+	if !errors.Is(err, runtime.ErrTimeout) {
+		t.Fatalf("Run() error = %v, want runtime.ErrTimeout", err)
+	}
+	c := api.containers[0]
+	if !c.task.killed || !c.deleted {
+		t.Errorf("task killed = %v, container deleted = %v; want both", c.task.killed, c.deleted)
+	}
+}
+
+func TestRunRejectsInvalidSpec(t *testing.T) {
+	api := &fakeAPI{}
+	r := newRunner(t, api, Config{})
+	spec := containerd.ContainerSpec{Image: "docker.io/library/sandbox:latest", Security: containerd.SecuritySpec{Privileged: true}}
+	if _, err := r.Run(context.Background(), spec); !errors.Is(err, containerd.ErrSecurityViolation) {
+		t.Errorf("Run() error = %v, want ErrSecurityViolation", err)
+	}
+	spec = containerd.ContainerSpec{Image: "docker.io/library/sandbox:latest", Runtime: "xen"}
+	if _, err := r.Run(context.Background(), spec); !errors.Is(err, containerd.ErrContainerFailed) {
+		t.Errorf("Run() error = %v, want ErrContainerFailed for an unknown runtime", err)
+	}
+	if len(api.containers) != 0 {
+		t.Errorf("created %d containers for invalid specs", len(api.containers))
+	}
+}
+
+func TestResolve(t *testing.T) {
+	api := &fakeAPI{images: map[string]*fakeImage{}, pullable: map[string]bool{"docker.io/library/python:3.12": true}}
+	r := newRunner(t, api, Config{})
+
+	name, err := r.Resolve(context.Background(), "docker.io/library/python:3.12")
+	if err != nil || name != "docker.io/library/python:3.12" {
+		t.Fatalf("Resolve() = %q, %v", name, err)
+	}
+	if _, err := r.Resolve(context.Background(), "docker.io/library/python:3.12"); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if !slices.Equal(api.pulls, []string{"docker.io/library/python:3.12"}) {
+		t.Errorf("pulls = %v, want one pull", api.pulls)
+	}
+	if _, err := r.Resolve(context.Background(), "docker.io/library/missing:1"); !errors.Is(err, containerd.ErrImageNotFound) {
+		t.Errorf("Resolve() error = %v, want ErrImageNotFound", err)
+	}
+}
+
+func TestImageUnpacksForSnapshotter(t *testing.T) {
+	img := &fakeImage{name: "docker.io/library/sandbox:latest"}
+	api := &fakeAPI{images: map[string]*fakeImage{img.name: img}}
+	r := newRunner(t, api, Config{Snapshotter: "overlayfs"})
+
+	spec := containerd.ContainerSpec{Image: img.name, Snapshotter: "devmapper", Runtime: "kata"}
+	if _, err := r.Run(context.Background(), spec); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !slices.Equal(img.unpackedFor, []string{"devmapper"}) {
+		t.Errorf("unpacked for %v, want the spec's snapshotter", img.unpackedFor)
+	}
+}
+
+func TestRuntimeFor(t *testing.T) {
+	r := newRunner(t, &fakeAPI{}, Config{Runtimes: map[string]string{"kata-fc": "io.containerd.kata-fc.v2"}})
+	for name, want := range map[string]string{
+		"":                RuntimeRunc,
+		"runsc":           RuntimeRunsc,
+		"gvisor":          RuntimeRunsc,
+		"kata":            RuntimeKata,
+		"kata-fc":         "io.containerd.kata-fc.v2",
+		"aws.firecracker": "aws.firecracker",
+	} {
+		if got, err := r.runtimeFor(name); err != nil || got != want {
+			t.Errorf("runtimeFor(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := r.runtimeFor("xen"); err == nil {
+		t.Error("runtimeFor(xen) succeeded")
+	}
+
+	r = newRunner(t, &fakeAPI{}, Config{DefaultRuntime: "runsc"})
+	if got, _ := r.runtimeFor(""); got != RuntimeRunsc {
+		t.Errorf("default runtime = %q, want %q", got, RuntimeRunsc)
+	}
+	if _, err := New(Config{Client: &fakeAPI{}, DefaultRuntime: "xen"}); err == nil {
+		t.Error("New() accepted an unknown default runtime")
+	}
+}
+
+func TestSandboxOpts(t *testing.T) {
+	dir := t.TempDir()
+	seccomp := filepath.Join(dir, "seccomp.json")
+	if err := os.WriteFile(seccomp, []byte(`{"defaultAction":"SCMP_ACT_ERRNO"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r := newRunner(t, &fakeAPI{}, Config{NetworkNamespace: "/var/run/netns/cni-1"})
+	spec := containerd.ContainerSpec{
+		Command:    []string{"python", "-c", "print(1)"},
+		WorkingDir: "/workspace",
+		Env:        []string{"A=1"},
+		Resources:  containerd.ResourceSpec{MemoryBytes: 64 << 20, CPUQuota: 50_000, PidsLimit: 32},
+		Security:   containerd.SecuritySpec{ReadOnlyRootfs: true, NetworkMode: "bridge", SeccompProfile: seccomp},
+		Staging:    &runtime.Staging{Dir: "/workspace"},
+	}
+	opts, err := r.sandboxOpts(spec, dir)
+	if err != nil {
+		t.Fatalf("sandboxOpts() error = %v", err)
+	}
+	s := &oci.Spec{Process: &specs.Process{}, Root: &specs.Root{}, Linux: &specs.Linux{}}
+	for _, opt := range opts {
+		if err := opt(context.Background(), nil, nil, s); err != nil {
+			t.Fatalf("apply error = %v", err)
+		}
+	}
+
+	if !slices.Equal(s.Process.Args, spec.Command) || s.Process.Cwd != "/workspace" || !slices.Contains(s.Process.Env, "A=1") {
+		t.Errorf("Process = %+v", s.Process)
+	}
+	res := s.Linux.Resources
+	if res == nil || *res.Memory.Limit != 64<<20 || *res.CPU.Quota != 50_000 || *res.CPU.Period != cpuPeriod || res.Pids == nil {
+		t.Errorf("Resources = %+v", res)
+	}
+	if !s.Root.Readonly {
+		t.Error("root filesystem is writable")
+	}
+	if s.Linux.Seccomp == nil || s.Linux.Seccomp.DefaultAction != "SCMP_ACT_ERRNO" {
+		t.Errorf("Seccomp = %+v", s.Linux.Seccomp)
+	}
+	if !slices.Contains(s.Linux.Namespaces, specs.LinuxNamespace{Type: specs.NetworkNamespace, Path: "/var/run/netns/cni-1"}) {
+		t.Errorf("Namespaces = %+v, want the bridge network namespace", s.Linux.Namespaces)
+	}
+	if !slices.ContainsFunc(s.Mounts, func(m specs.Mount) bool { return m.Destination == "/workspace" && m.Source == dir }) {
+		t.Errorf("Mounts = %+v, want the workspace bind mount", s.Mounts)
+	}
+
+	spec.Security.SeccompProfile = filepath.Join(dir, "missing.json")
+	if _, err := r.sandboxOpts(spec, dir); !errors.Is(err, containerd.ErrContainerFailed) {
+		t.Errorf("sandboxOpts() error = %v, want ErrContainerFailed for a missing profile", err)
+	}
+}
+
+func TestStage(t *testing.T) {
+	r := newRunner(t, &fakeAPI{}, Config{WorkspaceDir: t.TempDir()})
+	s := &runtime.Staging{Dir: "/workspace", OutputDir: "out/results", Files: map[string][]byte{"data/in.txt": []byte("input")}}
+
+	dir, err := r.stage(s)
+	if err != nil {
+		t.Fatalf("stage() error = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "data", "in.txt")); err != nil || string(data) != "input" {
+		t.Errorf("staged file = %q, %v", data, err)
+	}
+	info, err := os.Stat(hostPath(dir, s.OutputDir))
+	if err != nil || info.Mode().Perm() != 0o777 || info.Mode()&os.ModeSticky == 0 {
+		t.Errorf("output dir = %v, %v; want world-writable and sticky", info, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(hostPath(dir, s.OutputDir), "r.txt"), []byte("result"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	artifacts, err := collectWorkspace(dir, s)
+	if err != nil || len(artifacts) != 1 || artifacts[0].Name != "r.txt" {
+		t.Errorf("artifacts = %+v, %v", artifacts, err)
+	}
+	s.MaxBytes = 8
+	if _, err := collectWorkspace(dir, s); !errors.Is(err, runtime.ErrResourceLimit) {
+		t.Errorf("collectWorkspace() over MaxBytes error = %v, want %v", err, runtime.ErrResourceLimit)
+	}
+
+	if _, err := r.stage(&runtime.Staging{Dir: "/workspace", Files: map[string][]byte{"../escape": nil}}); err == nil {
+		t.Error("stage() accepted a path outside the workspace")
+	}
+}
@@ -0,0 +1,172 @@
+package containerdclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/core/containers"
+	"github.com/containerd/containerd/v2/pkg/cdi"
+	"github.com/containerd/containerd/v2/pkg/oci"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/containerd"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// cpuPeriod is the CFS period ResourceSpec.CPUQuota is measured against.
+const cpuPeriod = 100_000
+
+// specOpts builds the OCI spec options for spec: the image's defaults
+// followed by the sandbox settings. workspace is the staged host
+// directory, if any.
+func (r *Runner) specOpts(img client.Image, spec containerd.ContainerSpec, workspace string) ([]oci.SpecOpts, error) {
+	opts := []oci.SpecOpts{oci.WithImageConfig(img)}
+	sandbox, err := r.sandboxOpts(spec, workspace)
+	if err != nil {
+		return nil, err
+	}
+	return append(opts, sandbox...), nil
+}
+
+// sandboxOpts applies the spec's command, environment, limits, and
+// security settings. They do not depend on the image, so they can be
+// applied to any base spec.
+func (r *Runner) sandboxOpts(spec containerd.ContainerSpec, workspace string) ([]oci.SpecOpts, error) {
+	var opts []oci.SpecOpts
+	if len(spec.Command) > 0 {
+		opts = append(opts, oci.WithProcessArgs(spec.Command...))
+	}
+	if spec.WorkingDir != "" {
+		opts = append(opts, oci.WithProcessCwd(spec.WorkingDir))
+	}
+	if len(spec.Env) > 0 {
+		opts = append(opts, oci.WithEnv(spec.Env))
+	}
+	if spec.Security.User != "" {
+		opts = append(opts, oci.WithUser(spec.Security.User))
+	}
+	if spec.Security.ReadOnlyRootfs {
+		opts = append(opts, oci.WithRootFSReadonly())
+	}
+	if spec.Security.SeccompProfile != "" {
+		profile, err := seccompProfile(spec.Security.SeccompProfile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: seccomp: %v", containerd.ErrContainerFailed, err)
+		}
+		opts = append(opts, withSeccomp(profile))
+	}
+	if spec.Security.NetworkMode == "bridge" && r.netns != "" {
+		opts = append(opts, oci.WithLinuxNamespace(specs.LinuxNamespace{Type: specs.NetworkNamespace, Path: r.netns}))
+	}
+
+	res := spec.Resources
+	if res.MemoryBytes > 0 {
+		opts = append(opts, oci.WithMemoryLimit(uint64(res.MemoryBytes)))
+	}
+	if res.CPUQuota > 0 {
+		opts = append(opts, oci.WithCPUCFS(res.CPUQuota, cpuPeriod))
+	}
+	if res.PidsLimit > 0 {
+		opts = append(opts, oci.WithPidsLimit(res.PidsLimit))
+	}
+	if res.GPUs > 0 {
+		devices := make([]string, res.GPUs)
+		for i := range devices {
+			devices[i] = res.GPUClass + "=" + strconv.Itoa(i)
+		}
+		opts = append(opts, cdi.WithCDIDevices(devices...))
+	}
+
+	if workspace != "" {
+		opts = append(opts, oci.WithMounts([]specs.Mount{{
+			Destination: spec.Staging.Dir,
+			Type:        "bind",
+			Source:      workspace,
+			Options:     []string{"rbind", "rw"},
+		}}))
+	}
+	return opts, nil
+}
+
+// seccompProfile reads an OCI seccomp profile from the host.
+func seccompProfile(path string) (*specs.LinuxSeccomp, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var profile specs.LinuxSeccomp
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func withSeccomp(profile *specs.LinuxSeccomp) oci.SpecOpts {
+	return func(_ context.Context, _ oci.Client, _ *containers.Container, s *oci.Spec) error {
+		if s.Linux == nil {
+			s.Linux = &specs.Linux{}
+		}
+		s.Linux.Seccomp = profile
+		return nil
+	}
+}
+
+// stage writes the staged files and an empty output directory to a new
+// host directory and returns it. The workspace root and output directory
+// are world-writable with the sticky bit, as a tmpfs mount would be, so
+// the unprivileged container user can write to them.
+func (r *Runner) stage(s *runtime.Staging) (string, error) {
+	dir, err := os.MkdirTemp(r.workspaceDir, "toolexec-workspace-")
+	if err != nil {
+		return "", err
+	}
+	if err := stageDir(dir, s); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+func stageDir(dir string, s *runtime.Staging) error {
+	if err := runtime.WriteFiles(dir, s.Files); err != nil {
+		return err
+	}
+	out := hostPath(dir, s.OutputDir)
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return err
+	}
+	for p := out; ; p = filepath.Dir(p) {
+		if err := os.Chmod(p, 0o777|os.ModeSticky); err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+	}
+}
+
+// hostPath returns the host path of rel, a slash-separated path inside
+// the workspace at dir.
+func hostPath(dir, rel string) string {
+	return filepath.Join(dir, filepath.FromSlash(rel))
+}
+
+// collectWorkspace checks the workspace at dir against s.MaxBytes and
+// returns the files in its output directory. Host directories have no
+// size limit, so the workspace is checked after the fact.
+func collectWorkspace(dir string, s *runtime.Staging) ([]runtime.Artifact, error) {
+	if limit := s.MaxBytes; limit > 0 {
+		used, err := runtime.DirSize(dir)
+		if err != nil {
+			return nil, err
+		}
+		if used > limit {
+			return nil, fmt.Errorf("%w: workspace used %d bytes, limit %d", runtime.ErrResourceLimit, used, limit)
+		}
+	}
+	return runtime.CollectArtifacts(hostPath(dir, s.OutputDir), s.MaxBytes)
+}
@@ -1,6 +1,10 @@
 package containerd
 
-import "time"
+import (
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
 
 // ResourceSpec defines container resource limits.
 type ResourceSpec struct {
@@ -19,6 +23,13 @@ type ResourceSpec struct {
 	// DiskBytes is the disk limit in bytes.
 	// Zero means unlimited. Not all runtimes support this.
 	DiskBytes int64
+
+	// GPUs is the number of GPUs to attach. Zero attaches none.
+	GPUs int
+
+	// GPUClass is the CDI device kind providing the GPUs, e.g.
+	// "nvidia.com/gpu"; runners inject devices named "<GPUClass>=<index>".
+	GPUClass string
 }
 
 // SecuritySpec defines container security settings.
@@ -47,9 +58,16 @@ type ContainerSpec struct {
 	// Image is the container image reference (required).
 	Image string
 
+	// Namespace is the containerd namespace to create the container in.
+	Namespace string
+
 	// Runtime is the containerd runtime to use (e.g., "io.containerd.runc.v2").
 	Runtime string
 
+	// Snapshotter is the snapshotter to unpack the image with.
+	// Empty uses the runner's default.
+	Snapshotter string
+
 	// Command is the command to execute.
 	Command []string
 
@@ -68,8 +86,24 @@ type ContainerSpec struct {
 	// Timeout is the maximum execution duration.
 	Timeout time.Duration
 
+	// MaxOutputBytes, if set, caps the stdout and the stderr collected
+	// from the task, each. ContainerRunner implementations collect
+	// through runtime.OutputBuffer and report the cut in
+	// ContainerResult.Truncated.
+	MaxOutputBytes int64
+
 	// Labels are container labels for tracking.
 	Labels map[string]string
+
+	// LogStreamer, if set, receives stdout and stderr line by line while
+	// the task runs. ContainerRunner implementations should call it as
+	// output arrives, for example through runtime.NewLineWriter.
+	LogStreamer runtime.LogStreamer
+
+	// Staging, if set, asks the runner to provide a writable workspace
+	// at Staging.Dir, write Staging.Files into it before the task
+	// starts, and collect the output directory after the task exits.
+	Staging *runtime.Staging
 }
 
 // ContainerResult captures the output of container execution.
@@ -83,6 +117,17 @@ type ContainerResult struct {
 	// Stderr contains the container's stderr output.
 	Stderr string
 
+	// Truncated is true when Stdout or Stderr exceeded
+	// ContainerSpec.MaxOutputBytes and was cut off.
+	Truncated bool
+
 	// Duration is the execution time.
 	Duration time.Duration
+
+	// Usage reports the resources the container consumed, read from its cgroup
+	// where the runner can. WallTime is filled in from Duration.
+	Usage runtime.ResourceUsage
+
+	// Artifacts holds the files collected from Staging's output directory.
+	Artifacts []runtime.Artifact
 }
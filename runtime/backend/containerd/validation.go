@@ -44,5 +44,8 @@ func (r ResourceSpec) Validate() error {
 	if r.DiskBytes < 0 {
 		return errors.New("disk limit cannot be negative")
 	}
+	if r.GPUs < 0 {
+		return errors.New("gpu count cannot be negative")
+	}
 	return nil
 }
@@ -3,6 +3,8 @@ package docker
 import (
 	"maps"
 	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
 )
 
 // SpecBuilder constructs ContainerSpec with validation.
@@ -89,6 +91,13 @@ func (b *SpecBuilder) WithPidsLimit(limit int64) *SpecBuilder {
 	return b
 }
 
+// WithGPUs attaches count GPUs provided by driver, e.g. "nvidia".
+func (b *SpecBuilder) WithGPUs(count int, driver string) *SpecBuilder {
+	b.spec.Resources.GPUs = count
+	b.spec.Resources.GPUDriver = driver
+	return b
+}
+
 // WithSecurity sets the security specification.
 func (b *SpecBuilder) WithSecurity(s SecuritySpec) *SpecBuilder {
 	b.spec.Security = s
@@ -130,6 +139,39 @@ func (b *SpecBuilder) WithTimeout(d time.Duration) *SpecBuilder {
 	return b
 }
 
+// WithGraceTimeout sets how long the container has to exit after being
+// stopped at its timeout.
+func (b *SpecBuilder) WithGraceTimeout(d time.Duration) *SpecBuilder {
+	b.spec.GraceTimeout = d
+	return b
+}
+
+// WithMaxOutputBytes caps the stdout and the stderr collected from the
+// container.
+func (b *SpecBuilder) WithMaxOutputBytes(n int64) *SpecBuilder {
+	b.spec.MaxOutputBytes = n
+	return b
+}
+
+// WithLogStreamer sets the receiver of streamed output lines.
+func (b *SpecBuilder) WithLogStreamer(streamer runtime.LogStreamer) *SpecBuilder {
+	b.spec.LogStreamer = streamer
+	return b
+}
+
+// WithStaging sets the files to stage into the workspace and the output
+// directory to collect.
+func (b *SpecBuilder) WithStaging(staging *runtime.Staging) *SpecBuilder {
+	b.spec.Staging = staging
+	return b
+}
+
+// WithEgress sets the egress policy the runner enforces.
+func (b *SpecBuilder) WithEgress(policy *runtime.EgressPolicy) *SpecBuilder {
+	b.spec.Egress = policy
+	return b
+}
+
 // WithLabel adds a container label.
 func (b *SpecBuilder) WithLabel(key, value string) *SpecBuilder {
 	if b.spec.Labels == nil {
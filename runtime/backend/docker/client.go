@@ -21,7 +21,8 @@ type ContainerRunner interface {
 	// The implementation must:
 	//   - Validate the spec before execution
 	//   - Respect ctx cancellation
-	//   - Respect spec.Timeout if set
+	//   - Respect spec.Timeout if set, first asking the container to
+	//     stop when spec.GraceTimeout is set
 	//   - Capture stdout and stderr
 	//   - Return the exit code
 	//   - Clean up the container on completion or error
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"testing"
 	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
 )
 
 // MockContainerRunner is a test double for ContainerRunner.
@@ -19,6 +21,31 @@ func (m *MockContainerRunner) Run(ctx context.Context, spec ContainerSpec) (Cont
 	return ContainerResult{}, nil
 }
 
+// MockEgressRunner is a MockContainerRunner that enforces egress modes.
+type MockEgressRunner struct {
+	MockContainerRunner
+	Modes []runtime.EgressMode
+}
+
+func (m *MockEgressRunner) EgressModes() []runtime.EgressMode { return m.Modes }
+
+// MockStreamRunner is a test double for StreamRunner that replays events.
+type MockStreamRunner struct {
+	MockContainerRunner
+	Events []StreamEvent
+	Spec   ContainerSpec
+}
+
+func (m *MockStreamRunner) RunStream(_ context.Context, spec ContainerSpec) (<-chan StreamEvent, error) {
+	m.Spec = spec
+	ch := make(chan StreamEvent, len(m.Events))
+	for _, ev := range m.Events {
+		ch <- ev
+	}
+	close(ch)
+	return ch, nil
+}
+
 // MockImageResolver is a test double for ImageResolver.
 type MockImageResolver struct {
 	ResolveFunc func(ctx context.Context, image string) (string, error)
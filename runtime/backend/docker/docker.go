@@ -7,10 +7,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
 )
 
 // Errors for Docker backend operations.
@@ -40,7 +43,7 @@ var (
 	ErrImagePull = errors.New("image pull failed")
 
 	// ErrDaemonUnavailable is returned when the Docker daemon is not reachable.
-	ErrDaemonUnavailable = errors.New("docker daemon unavailable")
+	ErrDaemonUnavailable = fmt.Errorf("docker daemon unavailable: %w", runtime.ErrRuntimeUnavailable)
 
 	// ErrResourceLimit is returned when a resource limit is exceeded.
 	ErrResourceLimit = errors.New("resource limit exceeded")
@@ -95,6 +98,12 @@ type ContainerOptions struct {
 
 	// User is the user to run as (non-root).
 	User string
+
+	// GPUs is the number of GPUs to attach.
+	GPUs int
+
+	// GPUDriver is the device driver that provides the GPUs.
+	GPUDriver string
 }
 
 // Config configures a Docker backend.
@@ -106,7 +115,15 @@ type Config struct {
 	// SeccompPath is the path to a custom seccomp profile for hardened mode.
 	SeccompPath string
 
-	// Client is the container runner implementation.
+	// GatewaySocket is the host path of a Unix socket serving the tool
+	// gateway, such as one from proxy.ListenUnix. If set, it is mounted
+	// into each container at proxy.DefaultGatewaySocketPath, named by
+	// proxy.GatewaySocketEnv, so code reaches the gateway with
+	// proxy.DialGatewaySocket even when networking is disabled.
+	GatewaySocket string
+
+	// Client is the container runner implementation, such as the Docker
+	// SDK Runner in the runtime/backend/docker/dockerclient module.
 	// If nil, Execute() returns ErrClientNotConfigured.
 	Client ContainerRunner
 
@@ -137,6 +154,7 @@ type Logger interface {
 type Backend struct {
 	imageName     string
 	seccompPath   string
+	gatewaySocket string
 	client        ContainerRunner
 	imageResolver ImageResolver
 	healthChecker HealthChecker
@@ -153,6 +171,7 @@ func New(cfg Config) *Backend {
 	return &Backend{
 		imageName:     imageName,
 		seccompPath:   cfg.SeccompPath,
+		gatewaySocket: cfg.GatewaySocket,
 		client:        cfg.Client,
 		imageResolver: cfg.ImageResolver,
 		healthChecker: cfg.HealthChecker,
@@ -165,6 +184,22 @@ func (b *Backend) Kind() runtime.BackendKind {
 	return runtime.BackendDocker
 }
 
+// Capabilities implements runtime.CapabilityReporter. Output streams only
+// when the client is a StreamRunner, and egress modes other than deny-all
+// are enforced only when it is a runtime.EgressEnforcer.
+func (b *Backend) Capabilities() runtime.Capabilities {
+	_, streams := b.client.(StreamRunner)
+	return runtime.Capabilities{
+		Streaming:    streams,
+		GracefulStop: true,
+		Workspace:    true,
+		FileStaging:  true,
+		NetworkModes: []string{"none", "bridge"},
+		EgressModes:  runtime.EnforcedEgressModes(b.client),
+		GPU:          true,
+	}
+}
+
 // Execute runs code in a Docker container with security isolation.
 func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
 	// Validate request
@@ -176,14 +211,17 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 	if b.client == nil {
 		return runtime.ExecuteResult{}, ErrClientNotConfigured
 	}
+	if err := runtime.CheckEgress(req.Egress, runtime.EnforcedEgressModes(b.client)); err != nil {
+		return runtime.ExecuteResult{}, err
+	}
 
-	// Apply timeout
-	timeout := req.Timeout
-	if timeout == 0 {
-		timeout = 30 * time.Second
+	// Apply timeout, leaving the runner the grace period to stop the
+	// container in
+	if req.Timeout == 0 {
+		req.Timeout = 30 * time.Second
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+	ctx, cancel := context.WithTimeout(ctx, req.Timeout+req.GraceTimeout)
 	defer cancel()
 
 	select {
@@ -232,52 +270,157 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 			"readOnlyRootfs", spec.Security.ReadOnlyRootfs)
 	}
 
-	// Execute via client
-	containerResult, err := b.client.Run(ctx, spec)
+	// Execute via client, streaming output when a streamer is set
+	var containerResult ContainerResult
+	if streamer, ok := b.client.(StreamRunner); ok && spec.LogStreamer != nil {
+		containerResult, err = runStreaming(ctx, streamer, spec)
+	} else {
+		containerResult, err = b.client.Run(ctx, spec)
+	}
 	if err != nil {
+		// A container stopped at its timeout may have flushed output.
 		return runtime.ExecuteResult{
-			Duration: time.Since(start),
-			Backend:  b.backendInfo(profile),
+			Value:     extractOutValue(containerResult.Stdout),
+			Stdout:    containerResult.Stdout,
+			Stderr:    containerResult.Stderr,
+			Truncated: containerResult.Truncated,
+			Duration:  time.Since(start),
+			Backend:   b.backendInfo(profile),
 		}, err
 	}
 
 	// Convert to ExecuteResult
+	usage := containerResult.Usage
+	usage.WallTime = containerResult.Duration
 	return runtime.ExecuteResult{
-		Value:    extractOutValue(containerResult.Stdout),
-		Stdout:   containerResult.Stdout,
-		Stderr:   containerResult.Stderr,
-		Duration: containerResult.Duration,
-		Backend:  b.backendInfo(profile),
+		Value:     extractOutValue(containerResult.Stdout),
+		Stdout:    containerResult.Stdout,
+		Stderr:    containerResult.Stderr,
+		Truncated: containerResult.Truncated,
+		Duration:  containerResult.Duration,
+		Backend:   b.backendInfo(profile),
+		Usage:     usage,
+		Artifacts: containerResult.Artifacts,
 		LimitsEnforced: runtime.LimitsEnforced{
 			Timeout:    true,
 			Memory:     req.Limits.MemoryBytes > 0,
 			CPU:        req.Limits.CPUQuotaMillis > 0,
 			Pids:       req.Limits.PidsMax > 0,
+			Output:     req.MaxOutputBytes > 0,
 			ToolCalls:  true, // Enforced by gateway
 			ChainSteps: true, // Enforced by gateway
 		},
 	}, nil
 }
 
+// runStreaming runs spec through RunStream, forwarding output lines to
+// spec.LogStreamer while collecting the complete result.
+func runStreaming(ctx context.Context, runner StreamRunner, spec ContainerSpec) (ContainerResult, error) {
+	start := time.Now()
+	streamer := spec.LogStreamer
+	spec.LogStreamer = nil // delivered here, not by the runner
+	events, err := runner.RunStream(ctx, spec)
+	if err != nil {
+		return ContainerResult{}, err
+	}
+
+	stdout := runtime.NewOutputBuffer(spec.MaxOutputBytes)
+	stderr := runtime.NewOutputBuffer(spec.MaxOutputBytes)
+	stdoutLines := runtime.NewLineWriter(streamer, runtime.LogStdout)
+	stderrLines := runtime.NewLineWriter(streamer, runtime.LogStderr)
+	var result ContainerResult
+	var runErr error
+	for ev := range events {
+		switch ev.Type {
+		case StreamEventStdout:
+			stdout.Write(ev.Data)
+			_, _ = stdoutLines.Write(ev.Data)
+		case StreamEventStderr:
+			stderr.Write(ev.Data)
+			_, _ = stderrLines.Write(ev.Data)
+		case StreamEventExit:
+			result.ExitCode = ev.ExitCode
+			result.Artifacts = ev.Artifacts
+			result.Usage = ev.Usage
+		case StreamEventError:
+			runErr = ev.Error
+		}
+	}
+	_ = stdoutLines.Close()
+	_ = stderrLines.Close()
+
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	result.Truncated = stdout.Truncated() || stderr.Truncated()
+	result.Duration = time.Since(start)
+	return result, runErr
+}
+
 // buildSpec creates a ContainerSpec from an ExecuteRequest.
 func (b *Backend) buildSpec(image string, req runtime.ExecuteRequest, profile runtime.SecurityProfile) (ContainerSpec, error) {
 	opts := b.containerOptions(profile, req.Limits)
+	staging, err := req.Staging()
+	if err != nil {
+		return ContainerSpec{}, err
+	}
+
+	network := b.networkMode(opts)
+	env := req.Env
+	if egress := req.Egress; egress != nil {
+		// The policy replaces the profile's network mode.
+		network = "none"
+		if egress.Mode != runtime.EgressDenyAll {
+			network = "bridge"
+			env = maps.Clone(env)
+			if env == nil {
+				env = make(map[string]string)
+			}
+			maps.Copy(env, egress.ProxyEnv())
+		}
+	}
 
 	builder := NewSpecBuilder(image).
 		WithTimeout(req.Timeout).
+		WithGraceTimeout(req.GraceTimeout).
+		WithMaxOutputBytes(req.MaxOutputBytes).
 		WithSecurity(SecuritySpec{
 			User:           opts.User,
 			ReadOnlyRootfs: opts.ReadOnlyRootfs,
-			NetworkMode:    b.networkMode(opts),
+			NetworkMode:    network,
 			SeccompProfile: opts.SeccompProfile,
 		}).
 		WithResources(ResourceSpec{
 			MemoryBytes: opts.MemoryLimit,
 			CPUQuota:    opts.CPUQuota,
 			PidsLimit:   opts.PidsLimit,
+			GPUs:        opts.GPUs,
+			GPUDriver:   opts.GPUDriver,
 		}).
 		WithLabel("runtime.profile", string(profile)).
-		WithLabel("runtime.backend", string(runtime.BackendDocker))
+		WithLabel("runtime.backend", string(runtime.BackendDocker)).
+		WithLabel(runtime.LabelExecutionID, req.EnsureExecutionID()).
+		WithLogStreamer(req.LogStreamer)
+	for _, key := range slices.Sorted(maps.Keys(env)) {
+		builder.WithEnv(key, env[key])
+	}
+	if req.Egress != nil && req.Egress.Mode != runtime.EgressDenyAll {
+		builder.WithEgress(req.Egress)
+	}
+	if ws := req.Workspace; ws != nil {
+		// tmpfs keeps the workspace writable under a read-only rootfs
+		// and disappears with the container.
+		path := ws.MountPath()
+		builder.WithMount(Mount{Type: MountTypeTmpfs, Target: path, SizeBytes: ws.MaxBytes}).
+			WithWorkingDir(path).
+			WithEnv(runtime.WorkspaceEnv, path).
+			WithEnv(runtime.OutputEnv, staging.OutputPath()).
+			WithStaging(staging)
+	}
+	if b.gatewaySocket != "" {
+		// Connecting needs no write access to the mount.
+		builder.WithBindMount(b.gatewaySocket, proxy.DefaultGatewaySocketPath, true).
+			WithEnv(proxy.GatewaySocketEnv, proxy.DefaultGatewaySocketPath)
+	}
 
 	return builder.Build()
 }
@@ -368,6 +511,10 @@ func (b *Backend) containerOptions(profile runtime.SecurityProfile, limits runti
 	if limits.PidsMax > 0 {
 		opts.PidsLimit = limits.PidsMax
 	}
+	if limits.GPUs.Count > 0 {
+		opts.GPUs = limits.GPUs.Count
+		opts.GPUDriver = limits.GPUs.Vendor()
+	}
 
 	return opts
 }
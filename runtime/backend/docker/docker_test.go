@@ -3,13 +3,18 @@ package docker
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"slices"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/jonwraymond/tooldiscovery/index"
 	"github.com/jonwraymond/tooldiscovery/tooldoc"
 	"github.com/jonwraymond/toolexec/run"
 	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
 )
 
 // mockGateway implements runtime.ToolGateway for testing
@@ -224,6 +229,112 @@ func TestBackendWithMockClient(t *testing.T) {
 	}
 }
 
+func TestBackendReportsUsage(t *testing.T) {
+	usage := runtime.ResourceUsage{CPUTime: 300 * time.Millisecond, PeakMemoryBytes: 32 << 20, DiskWriteBytes: 512}
+	b := New(Config{Client: &MockContainerRunner{
+		RunFunc: func(context.Context, ContainerSpec) (ContainerResult, error) {
+			return ContainerResult{Duration: time.Second, Usage: usage}, nil
+		},
+	}})
+
+	result, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	usage.WallTime = time.Second
+	if result.Usage != usage {
+		t.Errorf("Usage = %+v, want %+v", result.Usage, usage)
+	}
+}
+
+func TestBackendGraceTimeout(t *testing.T) {
+	b := New(Config{Client: &MockContainerRunner{
+		RunFunc: func(ctx context.Context, spec ContainerSpec) (ContainerResult, error) {
+			if spec.Timeout != time.Second || spec.GraceTimeout != 5*time.Second {
+				t.Errorf("Timeout/GraceTimeout = %v/%v, want 1s/5s", spec.Timeout, spec.GraceTimeout)
+			}
+			if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) < 5*time.Second {
+				t.Errorf("ctx deadline leaves no grace period")
+			}
+			return ContainerResult{Stdout: "__OUT__:1\n"}, fmt.Errorf("%w: stopped at its timeout", runtime.ErrTimeout)
+		},
+	}})
+
+	result, err := b.Execute(context.Background(), runtime.ExecuteRequest{
+		Code:         "x",
+		Gateway:      &mockGateway{},
+		Timeout:      time.Second,
+		GraceTimeout: 5 * time.Second,
+	})
+	if !errors.Is(err, runtime.ErrTimeout) {
+		t.Fatalf("Execute() error = %v, want %v", err, runtime.ErrTimeout)
+	}
+	if result.Value != float64(1) {
+		t.Errorf("Value = %v, want the __out flushed before the stop", result.Value)
+	}
+}
+
+func TestBackendStreamsLogs(t *testing.T) {
+	runner := &MockStreamRunner{Events: []StreamEvent{
+		{Type: StreamEventStdout, Data: []byte("step 1\nst")},
+		{Type: StreamEventStderr, Data: []byte("warn\n")},
+		{Type: StreamEventStdout, Data: []byte("ep 2\n__OUT__:3")},
+		{Type: StreamEventExit, ExitCode: 0},
+	}}
+	b := New(Config{Client: runner})
+
+	var mu sync.Mutex
+	var lines []string
+	req := runtime.ExecuteRequest{
+		Code:    "print('hello')",
+		Gateway: &mockGateway{},
+		LogStreamer: runtime.LogStreamerFunc(func(stream runtime.LogStream, line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			lines = append(lines, string(stream)+":"+line)
+		}),
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := []string{"stdout:step 1", "stderr:warn", "stdout:step 2", "stdout:__OUT__:3"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("streamed lines = %q, want %q", lines, want)
+	}
+	if result.Stdout != "step 1\nstep 2\n__OUT__:3" || result.Value != float64(3) {
+		t.Errorf("result = %q/%v, want the full stdout and value 3", result.Stdout, result.Value)
+	}
+	if runner.Spec.LogStreamer != nil {
+		t.Error("RunStream received the LogStreamer; lines would be delivered twice")
+	}
+}
+
+func TestBackendStreamMaxOutputBytes(t *testing.T) {
+	runner := &MockStreamRunner{Events: []StreamEvent{
+		{Type: StreamEventStdout, Data: []byte("0123456789")},
+		{Type: StreamEventExit, ExitCode: 0},
+	}}
+	b := New(Config{Client: runner})
+
+	result, err := b.Execute(context.Background(), runtime.ExecuteRequest{
+		Code:           "x",
+		Gateway:        &mockGateway{},
+		LogStreamer:    runtime.LogStreamerFunc(func(runtime.LogStream, string) {}),
+		MaxOutputBytes: 4,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "0123"+runtime.OutputTruncatedMarker || !result.Truncated || !result.LimitsEnforced.Output {
+		t.Errorf("result = %q, Truncated = %v; want 4 bytes and the marker", result.Stdout, result.Truncated)
+	}
+	if runner.Spec.MaxOutputBytes != 4 {
+		t.Errorf("spec MaxOutputBytes = %d, want 4", runner.Spec.MaxOutputBytes)
+	}
+}
+
 func TestBackendWithHealthChecker(t *testing.T) {
 	t.Run("healthy daemon", func(t *testing.T) {
 		mockRunner := &MockContainerRunner{
@@ -315,6 +426,48 @@ func TestBackendWithImageResolver(t *testing.T) {
 	}
 }
 
+func TestBackendEgress(t *testing.T) {
+	var got ContainerSpec
+	runner := &MockEgressRunner{MockContainerRunner: MockContainerRunner{
+		RunFunc: func(_ context.Context, spec ContainerSpec) (ContainerResult, error) {
+			got = spec
+			return ContainerResult{}, nil
+		},
+	}}
+	allowlist := &runtime.EgressPolicy{Mode: runtime.EgressAllowlist, AllowCIDRs: []string{"10.0.0.0/8"}}
+	proxy := &runtime.EgressPolicy{Mode: runtime.EgressProxyOnly, Proxy: "http://proxy:3128"}
+
+	// Without an enforcing runner only deny-all is accepted.
+	b := New(Config{Client: runner})
+	req := runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}, Profile: runtime.ProfileDev, Egress: allowlist}
+	if _, err := b.Execute(context.Background(), req); !errors.Is(err, runtime.ErrUnsupportedCapability) {
+		t.Fatalf("Execute(allowlist) error = %v, want %v", err, runtime.ErrUnsupportedCapability)
+	}
+	req.Egress = &runtime.EgressPolicy{Mode: runtime.EgressDenyAll}
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute(deny-all) error = %v", err)
+	}
+	if got.Security.NetworkMode != "none" || got.Egress != nil {
+		t.Errorf("deny-all spec network = %q, egress = %v; want none and no runner policy", got.Security.NetworkMode, got.Egress)
+	}
+
+	runner.Modes = []runtime.EgressMode{runtime.EgressAllowlist, runtime.EgressProxyOnly}
+	if modes := b.Capabilities().EgressModes; len(modes) != 3 {
+		t.Errorf("Capabilities().EgressModes = %v, want all three", modes)
+	}
+	// The policy opens the standard profile's network to its destinations.
+	req = runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}, Egress: proxy, Env: map[string]string{"HTTP_PROXY": "http://evil"}}
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute(proxy-only) error = %v", err)
+	}
+	if got.Security.NetworkMode != "bridge" || got.Egress != proxy {
+		t.Errorf("proxy-only spec network = %q, egress = %v; want bridge and the policy", got.Security.NetworkMode, got.Egress)
+	}
+	if !slices.Contains(got.Env, "HTTPS_PROXY=http://proxy:3128") || !slices.Contains(got.Env, "HTTP_PROXY=http://proxy:3128") {
+		t.Errorf("Env = %v, want the proxy variables to override the request's", got.Env)
+	}
+}
+
 func TestBackendBuildSpec(t *testing.T) {
 	b := New(Config{
 		SeccompPath: "/path/to/seccomp.json",
@@ -329,6 +482,7 @@ func TestBackendBuildSpec(t *testing.T) {
 			CPUQuotaMillis: 1000,
 			PidsMax:        100,
 		},
+		Env: map[string]string{"B": "2", "A": "1"},
 	}
 
 	spec, err := b.buildSpec("test-image:latest", req, runtime.ProfileHardened)
@@ -370,6 +524,101 @@ func TestBackendBuildSpec(t *testing.T) {
 	if spec.Labels["runtime.backend"] != "docker" {
 		t.Errorf("Labels[runtime.backend] = %q, want %q", spec.Labels["runtime.backend"], "docker")
 	}
+
+	// Verify env, sorted by key
+	if len(spec.Env) != 2 || spec.Env[0] != "A=1" || spec.Env[1] != "B=2" {
+		t.Errorf("Env = %v, want [A=1 B=2]", spec.Env)
+	}
+}
+
+func TestBackendBuildSpecWorkspace(t *testing.T) {
+	b := New(Config{})
+	req := runtime.ExecuteRequest{
+		Code:      "print('hello')",
+		Gateway:   &mockGateway{},
+		Workspace: &runtime.Workspace{MaxBytes: 64 << 20},
+	}
+
+	spec, err := b.buildSpec("test-image:latest", req, runtime.ProfileStandard)
+	if err != nil {
+		t.Fatalf("buildSpec() error = %v", err)
+	}
+	want := Mount{Type: MountTypeTmpfs, Target: runtime.DefaultWorkspacePath, SizeBytes: 64 << 20}
+	if len(spec.Mounts) != 1 || spec.Mounts[0] != want {
+		t.Errorf("Mounts = %+v, want [%+v]", spec.Mounts, want)
+	}
+	if spec.WorkingDir != runtime.DefaultWorkspacePath {
+		t.Errorf("WorkingDir = %q, want %q", spec.WorkingDir, runtime.DefaultWorkspacePath)
+	}
+	wantEnv := []string{
+		runtime.WorkspaceEnv + "=" + runtime.DefaultWorkspacePath,
+		runtime.OutputEnv + "=" + runtime.DefaultWorkspacePath + "/" + runtime.DefaultOutputDir,
+	}
+	if !slices.Equal(spec.Env, wantEnv) {
+		t.Errorf("Env = %v, want %v", spec.Env, wantEnv)
+	}
+}
+
+func TestBackendStagesFiles(t *testing.T) {
+	artifacts := []runtime.Artifact{{Name: "report.txt", Data: []byte("done")}}
+	var got *runtime.Staging
+	b := New(Config{Client: &MockContainerRunner{
+		RunFunc: func(_ context.Context, spec ContainerSpec) (ContainerResult, error) {
+			got = spec.Staging
+			return ContainerResult{Artifacts: artifacts}, nil
+		},
+	}})
+	req := runtime.ExecuteRequest{
+		Code:      "print('hello')",
+		Gateway:   &mockGateway{},
+		Workspace: &runtime.Workspace{},
+		Files:     map[string]runtime.File{"input.txt": {Data: []byte("data")}},
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got == nil || string(got.Files["input.txt"]) != "data" || got.OutputPath() != "/workspace/out" {
+		t.Errorf("Staging = %+v, want input.txt staged with /workspace/out collected", got)
+	}
+	if !reflect.DeepEqual(result.Artifacts, artifacts) {
+		t.Errorf("Artifacts = %+v, want %+v", result.Artifacts, artifacts)
+	}
+}
+
+func TestBackendBuildSpecGPUs(t *testing.T) {
+	b := New(Config{})
+	req := runtime.ExecuteRequest{
+		Code:    "print('hello')",
+		Gateway: &mockGateway{},
+		Limits:  runtime.Limits{GPUs: runtime.GPURequest{Count: 2}},
+	}
+
+	spec, err := b.buildSpec("test-image:latest", req, runtime.ProfileStandard)
+	if err != nil {
+		t.Fatalf("buildSpec() error = %v", err)
+	}
+	if spec.Resources.GPUs != 2 || spec.Resources.GPUDriver != "nvidia" {
+		t.Errorf("GPUs = %d/%q, want 2/nvidia", spec.Resources.GPUs, spec.Resources.GPUDriver)
+	}
+}
+
+func TestBackendBuildSpecGatewaySocket(t *testing.T) {
+	b := New(Config{GatewaySocket: "/var/run/toolexec/gw.sock"})
+	req := runtime.ExecuteRequest{Code: "print('hello')", Gateway: &mockGateway{}}
+
+	spec, err := b.buildSpec("test-image:latest", req, runtime.ProfileHardened)
+	if err != nil {
+		t.Fatalf("buildSpec() error = %v", err)
+	}
+	want := Mount{Type: MountTypeBind, Source: "/var/run/toolexec/gw.sock", Target: proxy.DefaultGatewaySocketPath, ReadOnly: true}
+	if !slices.Contains(spec.Mounts, want) {
+		t.Errorf("Mounts = %+v, want the gateway socket bound read-only", spec.Mounts)
+	}
+	if !slices.Contains(spec.Env, proxy.GatewaySocketEnvValue(proxy.DefaultGatewaySocketPath)) {
+		t.Errorf("Env = %v, want %s set", spec.Env, proxy.GatewaySocketEnv)
+	}
 }
 
 func TestClientError(t *testing.T) {
@@ -410,3 +659,15 @@ func TestClientError(t *testing.T) {
 		}
 	})
 }
+
+func TestBackendCapabilities(t *testing.T) {
+	var _ runtime.CapabilityReporter = (*Backend)(nil)
+
+	caps := New(Config{Client: &MockContainerRunner{}}).Capabilities()
+	if caps.Streaming || !caps.FileStaging || !caps.GPU {
+		t.Errorf("Capabilities() = %+v, want staging and GPUs without streaming", caps)
+	}
+	if !New(Config{Client: &MockStreamRunner{}}).Capabilities().Streaming {
+		t.Error("Capabilities().Streaming = false with a StreamRunner client")
+	}
+}
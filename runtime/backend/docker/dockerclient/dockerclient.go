@@ -0,0 +1,622 @@
+// Package dockerclient provides a docker.ContainerRunner backed by the
+// Docker Engine SDK.
+//
+// It is a separate module so that the core toolexec module does not
+// depend on the Docker SDK; import it only when running the docker backend:
+//
+//	runner, err := dockerclient.New(dockerclient.Config{})
+//	if err != nil {
+//		return err
+//	}
+//	backend := docker.New(docker.Config{
+//		Client:        runner,
+//		ImageResolver: runner,
+//		HealthChecker: runner,
+//	})
+//
+// Runner connects using the standard DOCKER_HOST, DOCKER_API_VERSION,
+// DOCKER_CERT_PATH, and DOCKER_TLS_VERIFY environment variables unless
+// Config.Host is set, and negotiates the API version with the daemon.
+//
+// # Container Spec
+//
+// Every field of docker.ContainerSpec is applied to the container:
+//
+//   - Security.NetworkMode "none" also disables networking in the
+//     container config, so no interface but loopback exists.
+//   - Security.SeccompProfile is read from the host path and sent inline.
+//   - Security.ReadOnlyRootfs, User, and Privileged map to the host config.
+//   - Resources.MemoryBytes also caps swap, so the limit cannot be bypassed.
+//   - Resources.CPUQuota is applied per 100ms period.
+//   - Resources.DiskBytes sets the "size" storage option, which only some
+//     storage drivers support.
+//   - Resources.GPUs becomes a device request for GPU-capable devices of
+//     Resources.GPUDriver, as with "docker run --gpus".
+//   - Egress attaches the container to Config.Egress.Network and
+//     restricts it with Config.Egress.Firewall; see Egress below.
+//
+// Run streams output to ContainerSpec.LogStreamer line by line when it is
+// set, and RunStream streams raw output as events. Run keeps at most
+// ContainerSpec.MaxOutputBytes of each stream; the rest is read from the
+// daemon and discarded.
+//
+// # File Staging
+//
+// When ContainerSpec.Staging is set, its files are copied into the
+// workspace through the archive API after the container is created, and
+// the output directory is copied back as artifacts after it exits. A
+// tmpfs is only mounted while the container runs, so a tmpfs mount at
+// the workspace path becomes an anonymous volume instead; its size is
+// not enforced by the local volume driver, but artifacts beyond
+// Staging.MaxBytes are rejected with docker.ErrResourceLimit.
+//
+// Containers are always removed, along with their anonymous volumes, when
+// Run returns or a stream ends. Containers that exceed their timeout or
+// whose context is canceled are killed first. With
+// ContainerSpec.GraceTimeout set, a container is sent SIGTERM at its
+// timeout and only killed if it is still running when the grace period
+// ends; its output up to then is returned with an error wrapping
+// runtime.ErrTimeout.
+//
+// # Egress
+//
+// With Config.Egress set, Runner implements runtime.EgressEnforcer for
+// the allowlist and proxy-only modes. Before creating a container with an
+// Egress policy, Run resolves the policy's destinations, gives the
+// container a random MAC address on the egress network, and has the
+// Firewall drop its forwarded traffic except to those destinations. The
+// default IPTables firewall adds the rules to the DOCKER-USER chain and
+// removes them after the container is removed:
+//
+//	runner, err := dockerclient.New(dockerclient.Config{
+//		Egress: &dockerclient.EgressConfig{Network: "sandbox-egress"},
+//	})
+//
+// Containers run without CAP_NET_ADMIN, so code cannot change its MAC
+// address to escape the rules.
+//
+// # Orphaned Containers
+//
+// Containers left behind when the process crashes keep the
+// runtime.LabelExecutionID label the docker backend sets. Runner
+// implements runtime.Reaper over them, so a runtime.Janitor removes them
+// once they outlive its TTL:
+//
+//	janitor := runtime.NewJanitor(runtime.JanitorConfig{Reapers: []runtime.Reaper{runner}})
+//	go janitor.Run(ctx)
+//
+// Firewall rules added for a crashed container's egress policy are not
+// reaped; they match only its random MAC address.
+package dockerclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/docker"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// cpuPeriod is the CFS period ResourceSpec.CPUQuota is measured against.
+const cpuPeriod = 100_000
+
+// APIClient is the subset of the Docker SDK client used by Runner.
+// *client.Client implements it.
+type APIClient interface {
+	ClientVersion() string
+	Ping(ctx context.Context) (types.Ping, error)
+	Info(ctx context.Context) (system.Info, error)
+	ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error)
+	ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error)
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error)
+	ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error
+	ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error)
+	ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error)
+	ContainerKill(ctx context.Context, containerID, signal string) error
+	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+	ContainerStats(ctx context.Context, containerID string, stream bool) (container.StatsResponseReader, error)
+	ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error
+	ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error)
+	CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader, options container.CopyToContainerOptions) error
+	CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, container.PathStat, error)
+}
+
+// Config configures a Runner.
+type Config struct {
+	// Client is the Docker API client. If nil, a client is created from
+	// the environment, or from Host when set.
+	Client APIClient
+
+	// Host overrides DOCKER_HOST, e.g. "unix:///var/run/docker.sock".
+	// Ignored when Client is set.
+	Host string
+
+	// RegistryAuth is the base64-encoded auth configuration sent when
+	// pulling images. Empty pulls anonymously.
+	RegistryAuth string
+
+	// Egress, if set, enables the allowlist and proxy-only egress modes.
+	// Without it, specs with an Egress policy fail.
+	Egress *EgressConfig
+}
+
+// Runner runs containers through the Docker Engine API. It implements
+// docker.ContainerRunner, docker.StreamRunner, docker.ImageResolver,
+// docker.HealthChecker, and runtime.Reaper, and is safe for concurrent use.
+type Runner struct {
+	api          APIClient
+	closer       io.Closer
+	registryAuth string
+	egress       *EgressConfig
+}
+
+// New creates a Runner.
+func New(cfg Config) (*Runner, error) {
+	egress, err := newEgress(cfg.Egress)
+	if err != nil {
+		return nil, err
+	}
+	r := &Runner{api: cfg.Client, registryAuth: cfg.RegistryAuth, egress: egress}
+	if r.api == nil {
+		opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+		if cfg.Host != "" {
+			opts = append(opts, client.WithHost(cfg.Host))
+		}
+		cli, err := client.NewClientWithOpts(opts...)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", docker.ErrDockerNotAvailable, err)
+		}
+		r.api = cli
+		r.closer = cli
+	}
+	return r, nil
+}
+
+// Close releases the client created by New. A Config.Client is left open.
+func (r *Runner) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// Run implements docker.ContainerRunner.
+func (r *Runner) Run(ctx context.Context, spec docker.ContainerSpec) (docker.ContainerResult, error) {
+	ctx, stop, cancel := deadline(ctx, spec)
+	defer cancel()
+	start := time.Now()
+	c, err := r.start(ctx, spec)
+	if err != nil {
+		return docker.ContainerResult{}, err
+	}
+	defer c.release()
+	defer r.remove(ctx, c.id)
+	r.stopOn(ctx, stop, &c)
+	stats := r.sampleStats(ctx, c.id)
+
+	stdout := runtime.NewOutputBuffer(spec.MaxOutputBytes)
+	stderr := runtime.NewOutputBuffer(spec.MaxOutputBytes)
+	var logErr error
+	if spec.LogStreamer != nil {
+		// Follow the output until the container exits.
+		stdoutLines := runtime.NewLineWriter(spec.LogStreamer, runtime.LogStdout)
+		stderrLines := runtime.NewLineWriter(spec.LogStreamer, runtime.LogStderr)
+		logErr = r.copyLogs(ctx, c.id, true, io.MultiWriter(stdout, stdoutLines), io.MultiWriter(stderr, stderrLines))
+		_ = stdoutLines.Close()
+		_ = stderrLines.Close()
+	}
+
+	exitCode, waitErr := r.wait(ctx, c)
+	if spec.LogStreamer == nil {
+		logErr = r.copyLogs(context.WithoutCancel(ctx), c.id, false, stdout, stderr)
+	}
+	result := docker.ContainerResult{
+		ExitCode:  exitCode,
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Truncated: stdout.Truncated() || stderr.Truncated(),
+		Duration:  time.Since(start),
+		Usage:     stats(),
+	}
+	if waitErr != nil {
+		return result, waitErr
+	}
+	if logErr != nil {
+		return result, &docker.ClientError{Op: "logs", Image: spec.Image, ContainerID: c.id, Err: logErr}
+	}
+	if err := r.checkOOM(ctx, c); err != nil {
+		return result, err
+	}
+	if spec.Staging != nil {
+		artifacts, err := r.collectArtifacts(ctx, c.id, spec.Staging)
+		if err != nil {
+			return result, &docker.ClientError{Op: "collect", Image: spec.Image, ContainerID: c.id, Err: err}
+		}
+		result.Artifacts = artifacts
+	}
+	return result, nil
+}
+
+// RunStream implements docker.StreamRunner. Output is streamed as the
+// container writes it; the channel ends with an exit or error event.
+func (r *Runner) RunStream(ctx context.Context, spec docker.ContainerSpec) (<-chan docker.StreamEvent, error) {
+	ctx, stop, cancel := deadline(ctx, spec)
+	c, err := r.start(ctx, spec)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	r.stopOn(ctx, stop, &c)
+
+	events := make(chan docker.StreamEvent, 16)
+	go func() {
+		defer close(events)
+		defer cancel()
+		defer c.release()
+		defer r.remove(ctx, c.id)
+		stats := r.sampleStats(ctx, c.id)
+
+		send := func(ev docker.StreamEvent) {
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+			}
+		}
+		stdout := &eventWriter{typ: docker.StreamEventStdout, send: send}
+		stderr := &eventWriter{typ: docker.StreamEventStderr, send: send}
+		logErr := r.copyLogs(ctx, c.id, true, stdout, stderr)
+
+		exitCode, err := r.wait(ctx, c)
+		usage := stats()
+		if err == nil && logErr != nil && ctx.Err() == nil {
+			err = &docker.ClientError{Op: "logs", Image: spec.Image, ContainerID: c.id, Err: logErr}
+		}
+		if err == nil {
+			err = r.checkOOM(ctx, c)
+		}
+		var artifacts []runtime.Artifact
+		if err == nil && spec.Staging != nil {
+			if artifacts, err = r.collectArtifacts(ctx, c.id, spec.Staging); err != nil {
+				err = &docker.ClientError{Op: "collect", Image: spec.Image, ContainerID: c.id, Err: err}
+			}
+		}
+		if err != nil {
+			events <- docker.StreamEvent{Type: docker.StreamEventError, Error: err}
+			return
+		}
+		events <- docker.StreamEvent{Type: docker.StreamEventExit, ExitCode: exitCode, Artifacts: artifacts, Usage: usage}
+	}()
+	return events, nil
+}
+
+// Resolve implements docker.ImageResolver. It pulls image when it is not
+// present locally and returns its digest reference when the image has one.
+func (r *Runner) Resolve(ctx context.Context, ref string) (string, error) {
+	inspect, err := r.api.ImageInspect(ctx, ref)
+	if client.IsErrNotFound(err) {
+		if err := r.pull(ctx, ref); err != nil {
+			return "", err
+		}
+		inspect, err = r.api.ImageInspect(ctx, ref)
+	}
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return "", fmt.Errorf("%w: %s", docker.ErrImageNotFound, ref)
+		}
+		return "", &docker.ClientError{Op: "inspect", Image: ref, Err: err}
+	}
+	if len(inspect.RepoDigests) > 0 {
+		return inspect.RepoDigests[0], nil
+	}
+	return ref, nil
+}
+
+// Ping implements docker.HealthChecker.
+func (r *Runner) Ping(ctx context.Context) error {
+	if _, err := r.api.Ping(ctx); err != nil {
+		return fmt.Errorf("%w: %v", docker.ErrDaemonUnavailable, err)
+	}
+	return nil
+}
+
+// Info implements docker.HealthChecker.
+func (r *Runner) Info(ctx context.Context) (docker.DaemonInfo, error) {
+	info, err := r.api.Info(ctx)
+	if err != nil {
+		return docker.DaemonInfo{}, fmt.Errorf("%w: %v", docker.ErrDaemonUnavailable, err)
+	}
+	return docker.DaemonInfo{
+		Version:      info.ServerVersion,
+		APIVersion:   r.api.ClientVersion(),
+		OS:           info.OSType,
+		Architecture: info.Architecture,
+		RootDir:      info.DockerRootDir,
+	}, nil
+}
+
+// started is a running container and its exit notification.
+type started struct {
+	id     string
+	image  string
+	status <-chan container.WaitResponse
+	errs   <-chan error
+	// release removes the container's firewall rules, if any.
+	release func()
+	// stopped is set once the container is sent SIGTERM at its timeout.
+	stopped *atomic.Bool
+}
+
+// deadline bounds ctx by spec's Timeout plus its GraceTimeout. With a grace
+// period, stop ends at Timeout, when the container is asked to exit;
+// otherwise stop is nil and ctx itself ends at Timeout.
+func deadline(ctx context.Context, spec docker.ContainerSpec) (_, stop context.Context, cancel context.CancelFunc) {
+	if spec.Timeout <= 0 {
+		return ctx, nil, func() {}
+	}
+	ctx, cancelRun := context.WithTimeout(ctx, spec.Timeout+spec.GraceTimeout)
+	if spec.GraceTimeout <= 0 {
+		return ctx, nil, cancelRun
+	}
+	stop, cancelStop := context.WithTimeout(ctx, spec.Timeout)
+	return ctx, stop, func() {
+		cancelStop()
+		cancelRun()
+	}
+}
+
+// stopOn sends c SIGTERM when stop's deadline passes while ctx is live, so
+// the container can flush its output before ctx ends and it is killed.
+func (r *Runner) stopOn(ctx, stop context.Context, c *started) {
+	if stop == nil {
+		return
+	}
+	c.stopped = new(atomic.Bool)
+	context.AfterFunc(stop, func() {
+		if ctx.Err() == nil && errors.Is(stop.Err(), context.DeadlineExceeded) {
+			c.stopped.Store(true)
+			_ = r.api.ContainerKill(context.WithoutCancel(ctx), c.id, "TERM")
+		}
+	})
+}
+
+// start creates and starts a container for spec. The caller removes it.
+func (r *Runner) start(ctx context.Context, spec docker.ContainerSpec) (_ started, err error) {
+	if err := spec.Validate(); err != nil {
+		return started{}, err
+	}
+	cfg, hostCfg, err := containerConfig(spec)
+	if err != nil {
+		return started{}, err
+	}
+	release := func() {}
+	var netCfg *network.NetworkingConfig
+	if spec.Egress != nil {
+		if netCfg, release, err = r.restrictEgress(ctx, spec.Egress, hostCfg); err != nil {
+			return started{}, &docker.ClientError{Op: "egress", Image: spec.Image, Err: err}
+		}
+		defer func() {
+			if err != nil {
+				release()
+			}
+		}()
+	}
+	created, err := r.api.ContainerCreate(ctx, cfg, hostCfg, netCfg, nil, "")
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			err = fmt.Errorf("%w: %v", docker.ErrImageNotFound, err)
+		}
+		return started{}, &docker.ClientError{Op: "create", Image: spec.Image, Err: fmt.Errorf("%w: %w", docker.ErrContainerCreate, err)}
+	}
+	c := started{id: created.ID, image: spec.Image, release: release}
+
+	if spec.Staging != nil {
+		if err := r.stageFiles(ctx, c.id, spec.Staging); err != nil {
+			r.remove(ctx, c.id)
+			return started{}, &docker.ClientError{Op: "stage", Image: spec.Image, ContainerID: c.id, Err: err}
+		}
+	}
+
+	// Wait before starting so a fast exit is not missed.
+	c.status, c.errs = r.api.ContainerWait(ctx, c.id, container.WaitConditionNotRunning)
+	if err := r.api.ContainerStart(ctx, c.id, container.StartOptions{}); err != nil {
+		r.remove(ctx, c.id)
+		return started{}, &docker.ClientError{Op: "start", Image: spec.Image, ContainerID: c.id, Err: fmt.Errorf("%w: %w", docker.ErrContainerStart, err)}
+	}
+	return c, nil
+}
+
+// wait blocks until the container exits, killing it if ctx ends first.
+func (r *Runner) wait(ctx context.Context, c started) (int, error) {
+	select {
+	case resp := <-c.status:
+		if resp.Error != nil && resp.Error.Message != "" {
+			return int(resp.StatusCode), &docker.ClientError{Op: "wait", Image: c.image, ContainerID: c.id, Err: fmt.Errorf("%w: %s", docker.ErrContainerWait, resp.Error.Message)}
+		}
+		if c.stopped != nil && c.stopped.Load() {
+			return int(resp.StatusCode), &docker.ClientError{Op: "wait", Image: c.image, ContainerID: c.id, Err: fmt.Errorf("%w: stopped at its timeout", runtime.ErrTimeout)}
+		}
+		return int(resp.StatusCode), nil
+	case err := <-c.errs:
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			r.kill(ctx, c.id)
+			return -1, ctxErr
+		}
+		return -1, &docker.ClientError{Op: "wait", Image: c.image, ContainerID: c.id, Err: fmt.Errorf("%w: %w", docker.ErrContainerWait, err)}
+	case <-ctx.Done():
+		r.kill(ctx, c.id)
+		return -1, ctx.Err()
+	}
+}
+
+// copyLogs demultiplexes the container's output into stdout and stderr.
+func (r *Runner) copyLogs(ctx context.Context, id string, follow bool, stdout, stderr io.Writer) error {
+	logs, err := r.api.ContainerLogs(ctx, id, container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: follow})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = logs.Close() }()
+	_, err = stdcopy.StdCopy(stdout, stderr, logs)
+	return err
+}
+
+// checkOOM reports a container killed for exceeding its memory limit.
+func (r *Runner) checkOOM(ctx context.Context, c started) error {
+	inspect, err := r.api.ContainerInspect(context.WithoutCancel(ctx), c.id)
+	if err != nil || inspect.ContainerJSONBase == nil || inspect.State == nil {
+		return nil
+	}
+	if inspect.State.OOMKilled {
+		return &docker.ClientError{Op: "wait", Image: c.image, ContainerID: c.id, Err: fmt.Errorf("%w: out of memory", docker.ErrResourceLimit)}
+	}
+	return nil
+}
+
+func (r *Runner) kill(ctx context.Context, id string) {
+	_ = r.api.ContainerKill(context.WithoutCancel(ctx), id, "KILL")
+}
+
+// remove deletes the container even when ctx has ended.
+func (r *Runner) remove(ctx context.Context, id string) {
+	_ = r.api.ContainerRemove(context.WithoutCancel(ctx), id, container.RemoveOptions{Force: true, RemoveVolumes: true})
+}
+
+// pull fetches ref, reporting errors the daemon embeds in the progress stream.
+func (r *Runner) pull(ctx context.Context, ref string) error {
+	progress, err := r.api.ImagePull(ctx, ref, image.PullOptions{RegistryAuth: r.registryAuth})
+	if err != nil {
+		return &docker.ClientError{Op: "pull", Image: ref, Err: fmt.Errorf("%w: %w", docker.ErrImagePull, err)}
+	}
+	defer func() { _ = progress.Close() }()
+
+	dec := json.NewDecoder(progress)
+	for {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return &docker.ClientError{Op: "pull", Image: ref, Err: fmt.Errorf("%w: %w", docker.ErrImagePull, err)}
+		}
+		if msg.Error != "" {
+			return &docker.ClientError{Op: "pull", Image: ref, Err: fmt.Errorf("%w: %s", docker.ErrImagePull, msg.Error)}
+		}
+	}
+}
+
+// containerConfig translates spec into Docker create options.
+func containerConfig(spec docker.ContainerSpec) (*container.Config, *container.HostConfig, error) {
+	cfg := &container.Config{
+		Image:           spec.Image,
+		Cmd:             spec.Command,
+		WorkingDir:      spec.WorkingDir,
+		Env:             spec.Env,
+		User:            spec.Security.User,
+		Labels:          spec.Labels,
+		NetworkDisabled: spec.Security.NetworkMode == "none",
+		AttachStdout:    true,
+		AttachStderr:    true,
+	}
+
+	hostCfg := &container.HostConfig{
+		NetworkMode:    container.NetworkMode(spec.Security.NetworkMode),
+		Privileged:     spec.Security.Privileged,
+		ReadonlyRootfs: spec.Security.ReadOnlyRootfs,
+	}
+	if path := spec.Security.SeccompProfile; path != "" {
+		profile, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("seccomp profile: %w", err)
+		}
+		hostCfg.SecurityOpt = append(hostCfg.SecurityOpt, "seccomp="+string(profile))
+	}
+
+	res := spec.Resources
+	if res.MemoryBytes > 0 {
+		hostCfg.Memory = res.MemoryBytes
+		hostCfg.MemorySwap = res.MemoryBytes
+	}
+	if res.CPUQuota > 0 {
+		hostCfg.CPUPeriod = cpuPeriod
+		hostCfg.CPUQuota = res.CPUQuota
+	}
+	if res.PidsLimit > 0 {
+		pids := res.PidsLimit
+		hostCfg.PidsLimit = &pids
+	}
+	if res.DiskBytes > 0 {
+		hostCfg.StorageOpt = map[string]string{"size": strconv.FormatInt(res.DiskBytes, 10)}
+	}
+	if res.GPUs > 0 {
+		hostCfg.DeviceRequests = []container.DeviceRequest{{
+			Driver:       res.GPUDriver,
+			Count:        res.GPUs,
+			Capabilities: [][]string{{"gpu"}},
+		}}
+	}
+
+	for _, m := range spec.Mounts {
+		dm := mount.Mount{
+			Source:      m.Source,
+			Target:      m.Target,
+			ReadOnly:    m.ReadOnly,
+			Consistency: mount.Consistency(m.Consistency),
+		}
+		switch m.Type {
+		case docker.MountTypeBind:
+			dm.Type = mount.TypeBind
+		case docker.MountTypeVolume:
+			dm.Type = mount.TypeVolume
+		case docker.MountTypeTmpfs:
+			dm.Type = mount.TypeTmpfs
+			dm.Source = ""
+			if spec.Staging != nil && m.Target == spec.Staging.Dir {
+				// Staged files must outlive the running container.
+				dm.Type = mount.TypeVolume
+				break
+			}
+			if m.SizeBytes > 0 {
+				dm.TmpfsOptions = &mount.TmpfsOptions{SizeBytes: m.SizeBytes}
+			}
+		}
+		hostCfg.Mounts = append(hostCfg.Mounts, dm)
+	}
+	return cfg, hostCfg, nil
+}
+
+// eventWriter emits each write as a stream event.
+type eventWriter struct {
+	typ  docker.StreamEventType
+	send func(docker.StreamEvent)
+}
+
+func (w *eventWriter) Write(p []byte) (int, error) {
+	w.send(docker.StreamEvent{Type: w.typ, Data: bytes.Clone(p)})
+	return len(p), nil
+}
+
+var (
+	_ docker.ContainerRunner = (*Runner)(nil)
+	_ docker.StreamRunner    = (*Runner)(nil)
+	_ docker.ImageResolver   = (*Runner)(nil)
+	_ docker.HealthChecker   = (*Runner)(nil)
+	_ APIClient              = (*client.Client)(nil)
+)
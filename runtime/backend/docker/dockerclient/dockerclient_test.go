@@ -0,0 +1,554 @@
+package dockerclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/docker"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string { return "not found" }
+func (notFoundError) NotFound()     {}
+
+// fakeAPI is an in-memory APIClient running a single container.
+type fakeAPI struct {
+	stdout, stderr string
+	exitCode       int64
+	oomKilled      bool
+	hang           bool
+	exitOnTerm     bool // a hanging container exits on SIGTERM
+	hasImage       bool
+	pullStream     string
+	outputs        map[string]string // artifact tar contents; nil if absent
+	stats          []container.StatsResponse
+	containers     []container.Summary
+
+	mu       sync.Mutex
+	config   *container.Config
+	host     *container.HostConfig
+	netCfg   *network.NetworkingConfig
+	started  bool
+	killed   bool
+	signals  []string
+	removed  bool
+	pulled   bool
+	listOpts container.ListOptions
+	staged   []byte
+	stagedAt string
+	exitedCh chan struct{}
+}
+
+func (f *fakeAPI) ClientVersion() string { return "1.47" }
+
+func (f *fakeAPI) Ping(context.Context) (types.Ping, error) { return types.Ping{}, nil }
+
+func (f *fakeAPI) Info(context.Context) (system.Info, error) {
+	return system.Info{ServerVersion: "28.5.2", OSType: "linux", Architecture: "x86_64"}, nil
+}
+
+func (f *fakeAPI) ImageInspect(_ context.Context, ref string, _ ...client.ImageInspectOption) (image.InspectResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.hasImage {
+		return image.InspectResponse{}, notFoundError{}
+	}
+	return image.InspectResponse{RepoDigests: []string{ref + "@sha256:abc"}}, nil
+}
+
+func (f *fakeAPI) ImagePull(context.Context, string, image.PullOptions) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pulled = true
+	if !strings.Contains(f.pullStream, "error") {
+		f.hasImage = true
+	}
+	return io.NopCloser(strings.NewReader(f.pullStream)), nil
+}
+
+func (f *fakeAPI) ContainerCreate(_ context.Context, cfg *container.Config, host *container.HostConfig, netCfg *network.NetworkingConfig, _ *ocispec.Platform, _ string) (container.CreateResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.config, f.host, f.netCfg = cfg, host, netCfg
+	f.exitedCh = make(chan struct{})
+	return container.CreateResponse{ID: "c1"}, nil
+}
+
+func (f *fakeAPI) ContainerStart(context.Context, string, container.StartOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started = true
+	if !f.hang {
+		close(f.exitedCh)
+	}
+	return nil
+}
+
+func (f *fakeAPI) ContainerWait(ctx context.Context, _ string, _ container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+	status := make(chan container.WaitResponse, 1)
+	errs := make(chan error, 1)
+	go func() {
+		select {
+		case <-f.exitedCh:
+			status <- container.WaitResponse{StatusCode: f.exitCode}
+		case <-ctx.Done():
+			errs <- ctx.Err()
+		}
+	}()
+	return status, errs
+}
+
+func (f *fakeAPI) ContainerLogs(ctx context.Context, _ string, opts container.LogsOptions) (io.ReadCloser, error) {
+	var buf bytes.Buffer
+	_, _ = stdcopy.NewStdWriter(&buf, stdcopy.Stdout).Write([]byte(f.stdout))
+	_, _ = stdcopy.NewStdWriter(&buf, stdcopy.Stderr).Write([]byte(f.stderr))
+	if opts.Follow {
+		select {
+		case <-f.exitedCh:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return io.NopCloser(&buf), nil
+}
+
+func (f *fakeAPI) ContainerKill(_ context.Context, _ string, signal string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.signals = append(f.signals, signal)
+	if signal == "TERM" {
+		if f.exitOnTerm {
+			close(f.exitedCh)
+		}
+		return nil
+	}
+	f.killed = true
+	return nil
+}
+
+func (f *fakeAPI) ContainerInspect(context.Context, string) (container.InspectResponse, error) {
+	return container.InspectResponse{ContainerJSONBase: &container.ContainerJSONBase{
+		State: &container.State{OOMKilled: f.oomKilled},
+	}}, nil
+}
+
+// ContainerStats streams f.stats and ends, as the daemon does when the
+// container exits.
+func (f *fakeAPI) ContainerStats(context.Context, string, bool) (container.StatsResponseReader, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, s := range f.stats {
+		_ = enc.Encode(s)
+	}
+	return container.StatsResponseReader{Body: io.NopCloser(&buf), OSType: "linux"}, nil
+}
+
+func (f *fakeAPI) ContainerRemove(context.Context, string, container.RemoveOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removed = true
+	return nil
+}
+
+func (f *fakeAPI) ContainerList(_ context.Context, opts container.ListOptions) ([]container.Summary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listOpts = opts
+	return f.containers, nil
+}
+
+func (f *fakeAPI) CopyToContainer(_ context.Context, _, dstPath string, content io.Reader, _ container.CopyToContainerOptions) error {
+	data, err := io.ReadAll(content)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.staged, f.stagedAt = data, dstPath
+	return err
+}
+
+func (f *fakeAPI) CopyFromContainer(_ context.Context, _, srcPath string) (io.ReadCloser, container.PathStat, error) {
+	if f.outputs == nil {
+		return nil, container.PathStat{}, notFoundError{}
+	}
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	base := path.Base(srcPath)
+	_ = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: base + "/", Mode: 0o755})
+	for _, name := range slices.Sorted(maps.Keys(f.outputs)) {
+		data := f.outputs[name]
+		_ = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: base + "/" + name, Mode: 0o644, Size: int64(len(data))})
+		_, _ = tw.Write([]byte(data))
+	}
+	_ = tw.Close()
+	return io.NopCloser(&buf), container.PathStat{Name: base}, nil
+}
+
+func newRunner(t *testing.T, api *fakeAPI) *Runner {
+	t.Helper()
+	r, err := New(Config{Client: api})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return r
+}
+
+func TestContainerConfig(t *testing.T) {
+	seccomp := filepath.Join(t.TempDir(), "seccomp.json")
+	if err := os.WriteFile(seccomp, []byte(`{"defaultAction":"SCMP_ACT_ERRNO"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	spec := docker.ContainerSpec{
+		Image:   "sandbox:latest",
+		Command: []string{"run"},
+		Env:     []string{"A=1"},
+		Mounts:  []docker.Mount{{Type: docker.MountTypeTmpfs, Target: "/work", SizeBytes: 1 << 20}},
+		Resources: docker.ResourceSpec{
+			MemoryBytes: 64 << 20,
+			CPUQuota:    50_000,
+			PidsLimit:   32,
+			DiskBytes:   1 << 30,
+			GPUs:        2,
+			GPUDriver:   "nvidia",
+		},
+		Security: docker.SecuritySpec{
+			User:           "nobody",
+			ReadOnlyRootfs: true,
+			NetworkMode:    "none",
+			SeccompProfile: seccomp,
+		},
+	}
+
+	cfg, host, err := containerConfig(spec)
+	if err != nil {
+		t.Fatalf("containerConfig() error = %v", err)
+	}
+	if !cfg.NetworkDisabled || host.NetworkMode != "none" {
+		t.Errorf("network = %v/%q, want disabled", cfg.NetworkDisabled, host.NetworkMode)
+	}
+	if cfg.User != "nobody" || !host.ReadonlyRootfs || host.Privileged {
+		t.Errorf("security = %q/%v/%v, want nobody, read-only, unprivileged", cfg.User, host.ReadonlyRootfs, host.Privileged)
+	}
+	if len(host.SecurityOpt) != 1 || host.SecurityOpt[0] != `seccomp={"defaultAction":"SCMP_ACT_ERRNO"}` {
+		t.Errorf("SecurityOpt = %v, want the inline seccomp profile", host.SecurityOpt)
+	}
+	if host.Memory != 64<<20 || host.MemorySwap != 64<<20 {
+		t.Errorf("memory = %d/%d, want 64MiB with no extra swap", host.Memory, host.MemorySwap)
+	}
+	if host.CPUQuota != 50_000 || host.CPUPeriod != cpuPeriod {
+		t.Errorf("cpu = %d/%d, want 50000/%d", host.CPUQuota, host.CPUPeriod, cpuPeriod)
+	}
+	if host.PidsLimit == nil || *host.PidsLimit != 32 {
+		t.Errorf("PidsLimit = %v, want 32", host.PidsLimit)
+	}
+	if host.StorageOpt["size"] != "1073741824" {
+		t.Errorf("StorageOpt = %v, want size 1073741824", host.StorageOpt)
+	}
+	if len(host.DeviceRequests) != 1 || host.DeviceRequests[0].Count != 2 || host.DeviceRequests[0].Driver != "nvidia" {
+		t.Errorf("DeviceRequests = %+v, want 2 nvidia GPUs", host.DeviceRequests)
+	}
+	if len(host.Mounts) != 1 || host.Mounts[0].Type != mount.TypeTmpfs || host.Mounts[0].TmpfsOptions.SizeBytes != 1<<20 {
+		t.Errorf("Mounts = %+v, want a 1MiB tmpfs", host.Mounts)
+	}
+
+	spec.Security.SeccompProfile = filepath.Join(t.TempDir(), "missing.json")
+	if _, _, err := containerConfig(spec); err == nil {
+		t.Error("containerConfig() with a missing seccomp profile succeeded")
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	api := &fakeAPI{stdout: "out\n", stderr: "err\n", exitCode: 3}
+	r := newRunner(t, api)
+
+	result, err := r.Run(context.Background(), docker.ContainerSpec{Image: "sandbox"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Stdout != "out\n" || result.Stderr != "err\n" || result.ExitCode != 3 {
+		t.Errorf("Run() = %+v, want out/err/3", result)
+	}
+	if !api.removed {
+		t.Error("container not removed")
+	}
+}
+
+func TestRunner_RunMaxOutputBytes(t *testing.T) {
+	api := &fakeAPI{stdout: strings.Repeat("x", 100), stderr: "err"}
+	r := newRunner(t, api)
+
+	result, err := r.Run(context.Background(), docker.ContainerSpec{Image: "sandbox", MaxOutputBytes: 10})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := strings.Repeat("x", 10) + runtime.OutputTruncatedMarker; result.Stdout != want || !result.Truncated {
+		t.Errorf("Stdout = %q, Truncated = %v; want 10 bytes and the marker", result.Stdout, result.Truncated)
+	}
+	if result.Stderr != "err" {
+		t.Errorf("Stderr = %q, want it whole", result.Stderr)
+	}
+}
+
+func TestRunner_RunUsage(t *testing.T) {
+	sample := func(cpu, mem, written, rx uint64) container.StatsResponse {
+		return container.StatsResponse{
+			CPUStats:    container.CPUStats{CPUUsage: container.CPUUsage{TotalUsage: cpu}},
+			MemoryStats: container.MemoryStats{Usage: mem},
+			BlkioStats: container.BlkioStats{IoServiceBytesRecursive: []container.BlkioStatEntry{
+				{Op: "read", Value: 999},
+				{Op: "write", Value: written},
+			}},
+			Networks: map[string]container.NetworkStats{"eth0": {RxBytes: rx, TxBytes: rx / 2}},
+		}
+	}
+	api := &fakeAPI{stats: []container.StatsResponse{
+		sample(uint64(time.Second), 8<<20, 100, 40),
+		sample(uint64(2*time.Second), 4<<20, 300, 60),
+		{}, // the final sample after exit is empty
+	}}
+	r := newRunner(t, api)
+
+	result, err := r.Run(context.Background(), docker.ContainerSpec{Image: "sandbox"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := runtime.ResourceUsage{CPUTime: 2 * time.Second, PeakMemoryBytes: 8 << 20, DiskWriteBytes: 300, NetworkRxBytes: 60, NetworkTxBytes: 30}
+	if result.Usage != want {
+		t.Errorf("Run().Usage = %+v, want %+v", result.Usage, want)
+	}
+
+	events, err := r.RunStream(context.Background(), docker.ContainerSpec{Image: "sandbox"})
+	if err != nil {
+		t.Fatalf("RunStream() error = %v", err)
+	}
+	var last docker.StreamEvent
+	for ev := range events {
+		last = ev
+	}
+	if last.Usage != want {
+		t.Errorf("RunStream() exit Usage = %+v, want %+v", last.Usage, want)
+	}
+}
+
+func TestRunner_RunLogStreamer(t *testing.T) {
+	api := &fakeAPI{stdout: "a\nb\n", stderr: "oops"}
+	r := newRunner(t, api)
+
+	var mu sync.Mutex
+	var lines []string
+	spec := docker.ContainerSpec{
+		Image: "sandbox",
+		LogStreamer: runtime.LogStreamerFunc(func(stream runtime.LogStream, line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			lines = append(lines, string(stream)+":"+line)
+		}),
+	}
+	result, err := r.Run(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := "stdout:a,stdout:b,stderr:oops"; strings.Join(lines, ",") != want {
+		t.Errorf("streamed lines = %v, want %s", lines, want)
+	}
+	if result.Stdout != "a\nb\n" || result.Stderr != "oops" {
+		t.Errorf("Run() = %+v, want the complete output", result)
+	}
+}
+
+func TestRunner_RunValidates(t *testing.T) {
+	api := &fakeAPI{}
+	r := newRunner(t, api)
+
+	spec := docker.ContainerSpec{Image: "sandbox", Security: docker.SecuritySpec{Privileged: true}}
+	if _, err := r.Run(context.Background(), spec); !errors.Is(err, docker.ErrSecurityViolation) {
+		t.Errorf("Run() error = %v, want %v", err, docker.ErrSecurityViolation)
+	}
+	if api.config != nil {
+		t.Error("container created for an invalid spec")
+	}
+}
+
+func TestRunner_RunTimeout(t *testing.T) {
+	api := &fakeAPI{hang: true}
+	r := newRunner(t, api)
+
+	_, err := r.Run(context.Background(), docker.ContainerSpec{Image: "sandbox", Timeout: 20 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if !api.killed || !api.removed {
+		t.Errorf("killed/removed = %v/%v, want both", api.killed, api.removed)
+	}
+}
+
+func TestRunner_RunGraceTimeout(t *testing.T) {
+	api := &fakeAPI{hang: true, exitOnTerm: true, stdout: "flushed"}
+	r := newRunner(t, api)
+
+	spec := docker.ContainerSpec{Image: "sandbox", Timeout: 20 * time.Millisecond, GraceTimeout: time.Minute}
+	result, err := r.Run(context.Background(), spec)
+	if !errors.Is(err, runtime.ErrTimeout) {
+		t.Fatalf("Run() error = %v, want %v", err, runtime.ErrTimeout)
+	}
+	if result.Stdout != "flushed" {
+		t.Errorf("Stdout = %q, want the output flushed after SIGTERM", result.Stdout)
+	}
+	if api.killed || !slices.Equal(api.signals, []string{"TERM"}) {
+		t.Errorf("signals = %v, want only SIGTERM", api.signals)
+	}
+
+	api = &fakeAPI{hang: true}
+	r = newRunner(t, api)
+	spec.GraceTimeout = 20 * time.Millisecond
+	if _, err := r.Run(context.Background(), spec); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if !slices.Equal(api.signals, []string{"TERM", "KILL"}) {
+		t.Errorf("signals = %v, want SIGTERM then SIGKILL", api.signals)
+	}
+}
+
+func TestRunner_RunOOMKilled(t *testing.T) {
+	r := newRunner(t, &fakeAPI{exitCode: 137, oomKilled: true})
+	if _, err := r.Run(context.Background(), docker.ContainerSpec{Image: "sandbox"}); !errors.Is(err, docker.ErrResourceLimit) {
+		t.Errorf("Run() error = %v, want %v", err, docker.ErrResourceLimit)
+	}
+}
+
+func TestRunner_RunStream(t *testing.T) {
+	api := &fakeAPI{stdout: "out", stderr: "err", exitCode: 1}
+	r := newRunner(t, api)
+
+	events, err := r.RunStream(context.Background(), docker.ContainerSpec{Image: "sandbox"})
+	if err != nil {
+		t.Fatalf("RunStream() error = %v", err)
+	}
+	var got []string
+	var last docker.StreamEvent
+	for ev := range events {
+		got = append(got, string(ev.Type)+":"+string(ev.Data))
+		last = ev
+	}
+	if want := "stdout:out,stderr:err,exit:"; strings.Join(got, ",") != want {
+		t.Errorf("events = %v, want %s", got, want)
+	}
+	if last.ExitCode != 1 {
+		t.Errorf("exit code = %d, want 1", last.ExitCode)
+	}
+	if !api.removed {
+		t.Error("container not removed")
+	}
+}
+
+func TestRunner_Resolve(t *testing.T) {
+	api := &fakeAPI{pullStream: `{"status":"Pulling"}` + "\n" + `{"status":"Done"}`}
+	r := newRunner(t, api)
+
+	ref, err := r.Resolve(context.Background(), "sandbox:latest")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if !api.pulled || ref != "sandbox:latest@sha256:abc" {
+		t.Errorf("Resolve() = %q (pulled %v), want the pulled digest", ref, api.pulled)
+	}
+
+	api = &fakeAPI{pullStream: `{"error":"manifest unknown"}`}
+	if _, err := newRunner(t, api).Resolve(context.Background(), "missing"); !errors.Is(err, docker.ErrImagePull) {
+		t.Errorf("Resolve() error = %v, want %v", err, docker.ErrImagePull)
+	}
+}
+
+func TestRunner_Info(t *testing.T) {
+	info, err := newRunner(t, &fakeAPI{}).Info(context.Background())
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	if info.Version != "28.5.2" || info.APIVersion != "1.47" || info.OS != "linux" {
+		t.Errorf("Info() = %+v", info)
+	}
+}
+
+func TestRunner_RunStaging(t *testing.T) {
+	api := &fakeAPI{outputs: map[string]string{"b.txt": "bee", "sub/a.txt": "ay"}}
+	r := newRunner(t, api)
+	spec := docker.ContainerSpec{
+		Image:  "sandbox",
+		Mounts: []docker.Mount{{Type: docker.MountTypeTmpfs, Target: "/workspace", SizeBytes: 1 << 20}},
+		Staging: &runtime.Staging{
+			Dir:       "/workspace",
+			MaxBytes:  1 << 20,
+			Files:     map[string][]byte{"data/in.csv": []byte("a,b")},
+			OutputDir: "out",
+		},
+	}
+
+	result, err := r.Run(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if m := api.host.Mounts[0]; m.Type != mount.TypeVolume || m.Source != "" || m.TmpfsOptions != nil {
+		t.Errorf("workspace mount = %+v, want an anonymous volume", m)
+	}
+
+	if api.stagedAt != "/workspace" {
+		t.Errorf("staged at %q, want /workspace", api.stagedAt)
+	}
+	modes := map[string]int64{}
+	contents := map[string]string{}
+	tr := tar.NewReader(bytes.NewReader(api.staged))
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("staged archive: %v", err)
+		}
+		modes[hdr.Name] = hdr.Mode
+		data, _ := io.ReadAll(tr)
+		contents[hdr.Name] = string(data)
+	}
+	wantModes := map[string]int64{"./": 0o1777, "data/": 0o755, "out/": 0o1777, "data/in.csv": 0o644}
+	if !maps.Equal(modes, wantModes) || contents["data/in.csv"] != "a,b" {
+		t.Errorf("staged archive modes = %v, contents = %v", modes, contents)
+	}
+
+	want := []runtime.Artifact{{Name: "b.txt", Data: []byte("bee")}, {Name: "sub/a.txt", Data: []byte("ay")}}
+	if len(result.Artifacts) != 2 || result.Artifacts[0].Name != want[0].Name || string(result.Artifacts[1].Data) != "ay" {
+		t.Errorf("Artifacts = %+v, want %+v", result.Artifacts, want)
+	}
+
+	api.outputs = nil
+	if result, err := r.Run(context.Background(), spec); err != nil || result.Artifacts != nil {
+		t.Errorf("Run() without output dir = %+v, %v; want no artifacts", result.Artifacts, err)
+	}
+
+	api.outputs = map[string]string{"big": strings.Repeat("x", 64)}
+	spec.Staging.MaxBytes = 32
+	if _, err := r.Run(context.Background(), spec); !errors.Is(err, docker.ErrResourceLimit) {
+		t.Errorf("Run() with oversized artifacts error = %v, want %v", err, docker.ErrResourceLimit)
+	}
+}
@@ -0,0 +1,193 @@
+package dockerclient
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// EgressConfig enables the allowlist and proxy-only egress modes.
+type EgressConfig struct {
+	// Network is the user-defined bridge network that containers with an
+	// egress policy join. Its name resolution goes through Docker's
+	// embedded DNS server, which runs on the host and is not filtered.
+	// Required.
+	Network string
+
+	// Firewall installs the rules that restrict each container.
+	// Default: IPTables{}
+	Firewall Firewall
+
+	// Resolver resolves EgressPolicy.AllowDomains and the proxy host.
+	// Default: net.DefaultResolver
+	Resolver runtime.Resolver
+}
+
+// Firewall installs host packet filter rules restricting one container.
+//
+// Contract:
+//   - Concurrency: implementations must be safe for concurrent use.
+//   - Errors: Restrict must leave no rules behind when it fails.
+type Firewall interface {
+	// Restrict drops traffic forwarded from the interface with hardware
+	// address mac, except to allow, and returns a func that removes the
+	// rules. It is called before the container is created, so no traffic
+	// escapes before the rules are in place.
+	Restrict(ctx context.Context, mac net.HardwareAddr, allow []netip.Prefix) (release func(), err error)
+}
+
+// IPTables is a Firewall that inserts rules into a chain Docker consults
+// for forwarded traffic, using iptables and ip6tables. The runner must
+// share the Docker host's network namespace and have CAP_NET_ADMIN.
+type IPTables struct {
+	// Chain is the chain rules are inserted into.
+	// Default: DOCKER-USER
+	Chain string
+
+	// IPv6 also filters IPv6 traffic with ip6tables. Set it when the
+	// egress network has IPv6 enabled; without it, IPv6 destinations are
+	// rejected.
+	IPv6 bool
+
+	// run executes a command; nil runs it with os/exec.
+	run func(ctx context.Context, name string, args ...string) error
+}
+
+// Restrict implements Firewall. Each container gets a DROP rule matching
+// its source MAC address, with an ACCEPT rule per destination above it.
+func (f IPTables) Restrict(ctx context.Context, mac net.HardwareAddr, allow []netip.Prefix) (func(), error) {
+	chain := f.Chain
+	if chain == "" {
+		chain = "DOCKER-USER"
+	}
+	run := f.run
+	if run == nil {
+		run = runCommand
+	}
+	commands := []string{"iptables"}
+	if f.IPv6 {
+		commands = append(commands, "ip6tables")
+	}
+	for _, p := range allow {
+		if p.Addr().Is6() && !f.IPv6 {
+			return nil, fmt.Errorf("IPv6 destination %s needs IPTables.IPv6", p)
+		}
+	}
+
+	match := []string{"-m", "mac", "--mac-source", mac.String()}
+	type rule struct {
+		command string
+		spec    []string
+	}
+	var installed []rule
+	release := func() {
+		cleanup := context.WithoutCancel(ctx)
+		for i := len(installed) - 1; i >= 0; i-- {
+			r := installed[i]
+			_ = run(cleanup, r.command, append([]string{"-w", "-D", chain}, r.spec...)...)
+		}
+	}
+	// Rules are inserted at the top, so the DROP rules go in first and end
+	// up below the ACCEPT rules.
+	insert := func(command string, spec ...string) error {
+		if err := run(ctx, command, append([]string{"-w", "-I", chain, "1"}, spec...)...); err != nil {
+			return err
+		}
+		installed = append(installed, rule{command, spec})
+		return nil
+	}
+	for _, command := range commands {
+		if err := insert(command, slices.Concat(match, []string{"-j", "DROP"})...); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	for _, p := range allow {
+		command := "iptables"
+		if p.Addr().Is6() {
+			command = "ip6tables"
+		}
+		if err := insert(command, slices.Concat(match, []string{"-d", p.String(), "-j", "ACCEPT"})...); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+func runCommand(ctx context.Context, name string, args ...string) error {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// EgressModes implements runtime.EgressEnforcer. The allowlist and
+// proxy-only modes are enforced when Config.Egress is set.
+func (r *Runner) EgressModes() []runtime.EgressMode {
+	if r.egress == nil {
+		return nil
+	}
+	return []runtime.EgressMode{runtime.EgressAllowlist, runtime.EgressProxyOnly}
+}
+
+// restrictEgress attaches the container to the egress network with a
+// fresh MAC address and installs the firewall rules for policy. The
+// caller runs the returned release func after removing the container.
+func (r *Runner) restrictEgress(ctx context.Context, policy *runtime.EgressPolicy, hostCfg *container.HostConfig) (*network.NetworkingConfig, func(), error) {
+	if r.egress == nil {
+		return nil, nil, fmt.Errorf("%w: egress mode %q needs Config.Egress", runtime.ErrUnsupportedCapability, policy.Mode)
+	}
+	allow, err := policy.Destinations(ctx, r.egress.Resolver)
+	if err != nil {
+		return nil, nil, err
+	}
+	mac, err := randomMAC()
+	if err != nil {
+		return nil, nil, err
+	}
+	release, err := r.egress.Firewall.Restrict(ctx, mac, allow)
+	if err != nil {
+		return nil, nil, fmt.Errorf("firewall: %w", err)
+	}
+	hostCfg.NetworkMode = container.NetworkMode(r.egress.Network)
+	netCfg := &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{
+		r.egress.Network: {MacAddress: mac.String()},
+	}}
+	return netCfg, release, nil
+}
+
+// randomMAC returns a random locally administered unicast address.
+func randomMAC() (net.HardwareAddr, error) {
+	mac := make(net.HardwareAddr, 6)
+	if _, err := rand.Read(mac); err != nil {
+		return nil, err
+	}
+	mac[0] = mac[0]&^0x01 | 0x02
+	return mac, nil
+}
+
+func newEgress(cfg *EgressConfig) (*EgressConfig, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.Network == "" {
+		return nil, errors.New("dockerclient: Egress.Network is required")
+	}
+	egress := *cfg
+	if egress.Firewall == nil {
+		egress.Firewall = IPTables{}
+	}
+	return &egress, nil
+}
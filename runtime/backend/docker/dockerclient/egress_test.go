@@ -0,0 +1,137 @@
+package dockerclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/docker"
+)
+
+// fakeFirewall records the rules it is asked to install.
+type fakeFirewall struct {
+	mu       sync.Mutex
+	mac      net.HardwareAddr
+	allow    []netip.Prefix
+	released bool
+}
+
+func (f *fakeFirewall) Restrict(_ context.Context, mac net.HardwareAddr, allow []netip.Prefix) (func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mac, f.allow = mac, allow
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.released = true
+	}, nil
+}
+
+type staticResolver map[string][]netip.Addr
+
+func (r staticResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	return r[host], nil
+}
+
+func TestRunner_RunEgress(t *testing.T) {
+	api := &fakeAPI{}
+	fw := &fakeFirewall{}
+	r, err := New(Config{Client: api, Egress: &EgressConfig{
+		Network:  "sandbox-egress",
+		Firewall: fw,
+		Resolver: staticResolver{"pypi.org": {netip.MustParseAddr("151.101.0.223")}},
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if modes := r.EgressModes(); len(modes) != 2 {
+		t.Errorf("EgressModes() = %v, want allowlist and proxy-only", modes)
+	}
+
+	spec := docker.ContainerSpec{
+		Image:    "sandbox",
+		Security: docker.SecuritySpec{NetworkMode: "bridge"},
+		Egress:   &runtime.EgressPolicy{Mode: runtime.EgressAllowlist, AllowCIDRs: []string{"10.0.0.0/8"}, AllowDomains: []string{"pypi.org"}},
+	}
+	if _, err := r.Run(context.Background(), spec); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if api.host.NetworkMode != "sandbox-egress" {
+		t.Errorf("NetworkMode = %q, want the egress network", api.host.NetworkMode)
+	}
+	endpoint := api.netCfg.EndpointsConfig["sandbox-egress"]
+	if endpoint == nil || endpoint.MacAddress != fw.mac.String() {
+		t.Errorf("endpoint = %+v, want the firewalled MAC %s", endpoint, fw.mac)
+	}
+	want := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("151.101.0.223/32")}
+	if !slices.Equal(fw.allow, want) {
+		t.Errorf("allowed = %v, want %v", fw.allow, want)
+	}
+	if !fw.released || !api.removed {
+		t.Errorf("firewall released = %v, container removed = %v; want both", fw.released, api.removed)
+	}
+
+	// Without an EgressConfig the policy cannot be enforced.
+	r = newRunner(t, &fakeAPI{})
+	if _, err := r.Run(context.Background(), spec); !errors.Is(err, runtime.ErrUnsupportedCapability) {
+		t.Errorf("Run() without Egress error = %v, want %v", err, runtime.ErrUnsupportedCapability)
+	}
+	if _, err := New(Config{Client: &fakeAPI{}, Egress: &EgressConfig{}}); err == nil {
+		t.Error("New() without Egress.Network error = nil")
+	}
+}
+
+func TestIPTablesRestrict(t *testing.T) {
+	var commands []string
+	failOn := ""
+	fw := IPTables{IPv6: true, run: func(_ context.Context, name string, args ...string) error {
+		cmd := name + " " + strings.Join(args, " ")
+		commands = append(commands, cmd)
+		if failOn != "" && strings.Contains(cmd, failOn) {
+			return errors.New("iptables failed")
+		}
+		return nil
+	}}
+	mac, _ := net.ParseMAC("02:00:00:00:00:01")
+	allow := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8::/32")}
+
+	release, err := fw.Restrict(context.Background(), mac, allow)
+	if err != nil {
+		t.Fatalf("Restrict() error = %v", err)
+	}
+	match := "-m mac --mac-source 02:00:00:00:00:01"
+	want := []string{
+		"iptables -w -I DOCKER-USER 1 " + match + " -j DROP",
+		"ip6tables -w -I DOCKER-USER 1 " + match + " -j DROP",
+		"iptables -w -I DOCKER-USER 1 " + match + " -d 10.0.0.0/8 -j ACCEPT",
+		"ip6tables -w -I DOCKER-USER 1 " + match + " -d 2001:db8::/32 -j ACCEPT",
+	}
+	if !slices.Equal(commands, want) {
+		t.Errorf("commands = %q, want %q", commands, want)
+	}
+
+	commands = nil
+	release()
+	if len(commands) != 4 || commands[0] != "ip6tables -w -D DOCKER-USER "+match+" -d 2001:db8::/32 -j ACCEPT" {
+		t.Errorf("release commands = %q, want the rules deleted in reverse", commands)
+	}
+
+	// A failure removes the rules already installed.
+	commands, failOn = nil, "10.0.0.0/8"
+	if _, err := fw.Restrict(context.Background(), mac, allow); err == nil {
+		t.Fatal("Restrict() error = nil, want the iptables failure")
+	}
+	if got := commands[len(commands)-2:]; !strings.Contains(got[0], "ip6tables -w -D") || !strings.Contains(got[1], "iptables -w -D") {
+		t.Errorf("commands after failure = %q, want both DROP rules deleted", commands)
+	}
+
+	if _, err := (IPTables{run: fw.run}).Restrict(context.Background(), mac, allow); err == nil {
+		t.Error("Restrict() with an IPv6 destination and IPv6 unset error = nil")
+	}
+}
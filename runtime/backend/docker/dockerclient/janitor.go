@@ -0,0 +1,44 @@
+package dockerclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/docker"
+)
+
+// Orphans implements runtime.Reaper. It lists every container, running or
+// not, that carries runtime.LabelExecutionID.
+func (r *Runner) Orphans(ctx context.Context) ([]runtime.Orphan, error) {
+	containers, err := r.api.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", runtime.LabelExecutionID)),
+	})
+	if err != nil {
+		return nil, &docker.ClientError{Op: "list", Err: err}
+	}
+	orphans := make([]runtime.Orphan, 0, len(containers))
+	for _, c := range containers {
+		orphans = append(orphans, runtime.Orphan{
+			Kind:        "container",
+			ID:          c.ID,
+			ExecutionID: c.Labels[runtime.LabelExecutionID],
+			Created:     time.Unix(c.Created, 0),
+		})
+	}
+	return orphans, nil
+}
+
+// Reap implements runtime.Reaper. It force-removes the container and its
+// anonymous volumes.
+func (r *Runner) Reap(ctx context.Context, o runtime.Orphan) error {
+	err := r.api.ContainerRemove(ctx, o.ID, container.RemoveOptions{Force: true, RemoveVolumes: true})
+	if err != nil && !client.IsErrNotFound(err) {
+		return &docker.ClientError{Op: "remove", ContainerID: o.ID, Err: err}
+	}
+	return nil
+}
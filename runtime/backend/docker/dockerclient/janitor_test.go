@@ -0,0 +1,39 @@
+package dockerclient
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+func TestRunnerReaper(t *testing.T) {
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	api := &fakeAPI{containers: []container.Summary{{
+		ID:      "c1",
+		Created: created.Unix(),
+		Labels:  map[string]string{runtime.LabelExecutionID: "exec-1"},
+	}}}
+	r, err := New(Config{Client: api})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orphans, err := r.Orphans(context.Background())
+	if err != nil {
+		t.Fatalf("Orphans() error = %v", err)
+	}
+	if len(orphans) != 1 || orphans[0].ID != "c1" || orphans[0].ExecutionID != "exec-1" || orphans[0].Kind != "container" || !orphans[0].Created.Equal(created) {
+		t.Fatalf("Orphans() = %v, want container c1 of exec-1 created at %v", orphans, created)
+	}
+	if !api.listOpts.All || !slices.Equal(api.listOpts.Filters.Get("label"), []string{runtime.LabelExecutionID}) {
+		t.Errorf("list options = %+v, want all containers with the execution label", api.listOpts)
+	}
+
+	if err := r.Reap(context.Background(), orphans[0]); err != nil || !api.removed {
+		t.Errorf("Reap() error = %v, removed = %v", err, api.removed)
+	}
+}
@@ -0,0 +1,123 @@
+package dockerclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/docker"
+)
+
+// stageFiles copies the staged files and an empty output directory into
+// the workspace of a created container.
+func (r *Runner) stageFiles(ctx context.Context, id string, s *runtime.Staging) error {
+	archive, err := stagingArchive(s)
+	if err != nil {
+		return err
+	}
+	return r.api.CopyToContainer(ctx, id, s.Dir, archive, container.CopyToContainerOptions{})
+}
+
+// collectArtifacts copies the output directory out of an exited container.
+// A missing directory yields no artifacts.
+func (r *Runner) collectArtifacts(ctx context.Context, id string, s *runtime.Staging) ([]runtime.Artifact, error) {
+	rc, _, err := r.api.CopyFromContainer(context.WithoutCancel(ctx), id, s.OutputPath())
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	return readArtifacts(rc, s.MaxBytes)
+}
+
+// stagingArchive builds the tar stream extracted at Staging.Dir. The
+// workspace root and output directory are world-writable with the sticky
+// bit, as a tmpfs mount would be, so the unprivileged container user can
+// write to them.
+func stagingArchive(s *runtime.Staging) (io.Reader, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	dirs := map[string]int64{".": 0o1777}
+	for dir := s.OutputDir; dir != "."; dir = path.Dir(dir) {
+		dirs[dir] = 0o1777
+	}
+	for name := range s.Files {
+		if !runtime.ValidRelPath(name) {
+			return nil, fmt.Errorf("%w: %q", runtime.ErrInvalidFile, name)
+		}
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if _, ok := dirs[dir]; !ok {
+				dirs[dir] = 0o755
+			}
+		}
+	}
+	// Sorted names put every directory before its contents.
+	for _, dir := range slices.Sorted(maps.Keys(dirs)) {
+		hdr := &tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: dirs[dir]}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.Files)) {
+		data := s.Files[name]
+		hdr := &tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644, Size: int64(len(data))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// readArtifacts reads the regular files of a tar stream whose entries are
+// prefixed with the output directory's base name, as the archive API
+// returns them. A positive maxBytes caps their total size.
+func readArtifacts(r io.Reader, maxBytes int64) ([]runtime.Artifact, error) {
+	var artifacts []runtime.Artifact
+	var total int64
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		_, name, ok := strings.Cut(hdr.Name, "/")
+		if !ok || !runtime.ValidRelPath(name) {
+			continue
+		}
+		total += hdr.Size
+		if maxBytes > 0 && total > maxBytes {
+			return nil, fmt.Errorf("%w: artifacts exceed %d bytes", docker.ErrResourceLimit, maxBytes)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, runtime.Artifact{Name: name, Data: data})
+	}
+	slices.SortFunc(artifacts, func(a, b runtime.Artifact) int { return strings.Compare(a.Name, b.Name) })
+	return artifacts, nil
+}
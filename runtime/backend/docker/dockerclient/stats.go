@@ -0,0 +1,80 @@
+package dockerclient
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// sampleStats follows the container's stats stream until the returned
+// func is called, which stops sampling and reports the usage seen. The
+// daemon samples roughly once a second and stops reporting when the
+// container exits, so counters keep their largest value and executions
+// shorter than one sample may report nothing. Errors leave the usage zero.
+func (r *Runner) sampleStats(ctx context.Context, id string) func() runtime.ResourceUsage {
+	ctx, cancel := context.WithCancel(ctx)
+	var (
+		mu    sync.Mutex
+		usage runtime.ResourceUsage
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stats, err := r.api.ContainerStats(ctx, id, true)
+		if err != nil {
+			return
+		}
+		defer func() { _ = stats.Body.Close() }()
+		go func() {
+			// Unblock the decoder when sampling stops first.
+			<-ctx.Done()
+			_ = stats.Body.Close()
+		}()
+		dec := json.NewDecoder(stats.Body)
+		for {
+			var s container.StatsResponse
+			if err := dec.Decode(&s); err != nil {
+				return
+			}
+			mu.Lock()
+			mergeStats(&usage, s)
+			mu.Unlock()
+		}
+	}()
+	return func() runtime.ResourceUsage {
+		cancel()
+		<-done
+		mu.Lock()
+		defer mu.Unlock()
+		return usage
+	}
+}
+
+// mergeStats folds one stats sample into usage. Every counter is
+// cumulative, so the largest value seen is the total.
+func mergeStats(usage *runtime.ResourceUsage, s container.StatsResponse) {
+	usage.CPUTime = max(usage.CPUTime, time.Duration(s.CPUStats.CPUUsage.TotalUsage))
+	// MaxUsage is only reported on cgroup v1.
+	usage.PeakMemoryBytes = max(usage.PeakMemoryBytes, int64(s.MemoryStats.MaxUsage), int64(s.MemoryStats.Usage))
+
+	var written int64
+	for _, e := range s.BlkioStats.IoServiceBytesRecursive {
+		if strings.EqualFold(e.Op, "write") {
+			written += int64(e.Value)
+		}
+	}
+	usage.DiskWriteBytes = max(usage.DiskWriteBytes, written)
+
+	var rx, tx int64
+	for _, n := range s.Networks {
+		rx += int64(n.RxBytes)
+		tx += int64(n.TxBytes)
+	}
+	usage.NetworkRxBytes = max(usage.NetworkRxBytes, rx)
+	usage.NetworkTxBytes = max(usage.NetworkTxBytes, tx)
+}
@@ -1,6 +1,10 @@
 package docker
 
-import "time"
+import (
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
 
 // MountType defines the type of volume mount.
 type MountType string
@@ -34,6 +38,10 @@ type Mount struct {
 	// Consistency is the mount consistency mode: "consistent", "cached", "delegated".
 	// Only relevant for bind mounts on macOS.
 	Consistency string
+
+	// SizeBytes caps the size of a tmpfs mount.
+	// Zero uses the runtime default. Ignored for other mount types.
+	SizeBytes int64
 }
 
 // ResourceSpec defines container resource limits.
@@ -53,6 +61,12 @@ type ResourceSpec struct {
 	// DiskBytes is the disk limit in bytes.
 	// Zero means unlimited. Not all runtimes support this.
 	DiskBytes int64
+
+	// GPUs is the number of GPUs to attach. Zero attaches none.
+	GPUs int
+
+	// GPUDriver is the device driver that provides the GPUs, e.g. "nvidia".
+	GPUDriver string
 }
 
 // SecuritySpec defines container security settings.
@@ -102,8 +116,36 @@ type ContainerSpec struct {
 	// Timeout is the maximum execution duration.
 	Timeout time.Duration
 
+	// GraceTimeout, if set, is how long the container has to exit after
+	// being sent SIGTERM at Timeout before it is killed.
+	GraceTimeout time.Duration
+
+	// MaxOutputBytes, if set, caps the stdout and the stderr collected
+	// from the container, each. ContainerRunner implementations collect
+	// through runtime.OutputBuffer and report the cut in
+	// ContainerResult.Truncated.
+	MaxOutputBytes int64
+
 	// Labels are container labels for tracking.
 	Labels map[string]string
+
+	// LogStreamer, if set, receives stdout and stderr line by line while
+	// the container runs. ContainerRunner implementations that can follow
+	// output should call it; the backend streams through RunStream instead
+	// when the client is a StreamRunner.
+	LogStreamer runtime.LogStreamer
+
+	// Staging, if set, lists files to copy into the workspace mount
+	// before the container starts and the output directory to copy out
+	// of it after the container exits.
+	Staging *runtime.Staging
+
+	// Egress, if set, is an allowlist or proxy-only policy restricting
+	// the container's outbound traffic; the backend turns deny-all into
+	// NetworkMode "none" instead. ContainerRunner implementations that do
+	// not enforce it must fail rather than run the container unrestricted,
+	// and advertise the modes they enforce as runtime.EgressEnforcer.
+	Egress *runtime.EgressPolicy
 }
 
 // ContainerResult captures the output of container execution.
@@ -117,8 +159,19 @@ type ContainerResult struct {
 	// Stderr contains the container's stderr output.
 	Stderr string
 
+	// Truncated is true when Stdout or Stderr exceeded
+	// ContainerSpec.MaxOutputBytes and was cut off.
+	Truncated bool
+
 	// Duration is the execution time.
 	Duration time.Duration
+
+	// Usage reports the resources the container consumed, read from its cgroup
+	// where the runner can. WallTime is filled in from Duration.
+	Usage runtime.ResourceUsage
+
+	// Artifacts holds the files collected from Staging's output directory.
+	Artifacts []runtime.Artifact
 }
 
 // StreamEventType identifies the type of streaming event.
@@ -149,6 +202,13 @@ type StreamEvent struct {
 	// ExitCode is set when Type is StreamEventExit.
 	ExitCode int
 
+	// Artifacts is set when Type is StreamEventExit and the spec had
+	// Staging.
+	Artifacts []runtime.Artifact
+
+	// Usage is set when Type is StreamEventExit.
+	Usage runtime.ResourceUsage
+
 	// Error is set when Type is StreamEventError.
 	Error error
 }
@@ -3,6 +3,8 @@ package docker
 import (
 	"errors"
 	"fmt"
+
+	"github.com/jonwraymond/toolexec/runtime"
 )
 
 // Validate checks ContainerSpec for errors before execution.
@@ -21,6 +23,17 @@ func (s ContainerSpec) Validate() error {
 			return fmt.Errorf("mount[%d]: %w", i, err)
 		}
 	}
+	if s.Egress != nil {
+		if err := s.Egress.Validate(); err != nil {
+			return fmt.Errorf("egress: %w", err)
+		}
+		if s.Egress.Mode == runtime.EgressDenyAll {
+			return errors.New(`egress: deny-all is NetworkMode "none", not a runner policy`)
+		}
+		if s.Security.NetworkMode == "none" {
+			return fmt.Errorf("egress: %s policy needs a network", s.Egress.Mode)
+		}
+	}
 	return nil
 }
 
@@ -49,6 +62,9 @@ func (r ResourceSpec) Validate() error {
 	if r.DiskBytes < 0 {
 		return errors.New("disk limit cannot be negative")
 	}
+	if r.GPUs < 0 {
+		return errors.New("gpu count cannot be negative")
+	}
 	return nil
 }
 
@@ -68,6 +84,9 @@ func (m Mount) Validate() error {
 		}
 	case MountTypeTmpfs:
 		// tmpfs doesn't require source
+		if m.SizeBytes < 0 {
+			return errors.New("tmpfs size cannot be negative")
+		}
 	case "":
 		return errors.New("mount type is required")
 	default:
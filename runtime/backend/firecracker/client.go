@@ -8,11 +8,51 @@ import "context"
 // - Concurrency: Implementations must be safe for concurrent use.
 // - Context: Run must honor cancellation and deadlines.
 // - Ownership: Implementations must not mutate the provided spec.
+// - Snapshots: When spec.Snapshot is set, restore and resume it instead of booting; return ErrSnapshotInvalid if it cannot be loaded.
 type MicroVMRunner interface {
 	Run(ctx context.Context, spec MicroVMSpec) (MicroVMResult, error)
 }
 
+// Snapshotter creates the golden snapshots that MicroVMRunner restores.
+// A MicroVMRunner that also implements Snapshotter enables snapshot
+// restore when Config.SnapshotDir is set.
+//
+// Contract:
+// - Concurrency: Implementations must be safe for concurrent use.
+// - Context: CreateSnapshot must honor cancellation and deadlines.
+// - Ownership: Implementations must not mutate the provided spec or snapshot.
+// - Files: CreateSnapshot boots spec until the guest is ready to run commands, pauses it, and writes dst.StatePath and dst.MemPath.
+type Snapshotter interface {
+	CreateSnapshot(ctx context.Context, spec MicroVMSpec, dst Snapshot) error
+}
+
 // HealthChecker can verify Firecracker availability.
 type HealthChecker interface {
 	Ping(ctx context.Context) error
 }
+
+// MicroVMStarter starts microVMs that serve several executions. A
+// MicroVMRunner that also implements MicroVMStarter enables pooling when
+// Config.Pool is set.
+//
+// Contract:
+// - Concurrency: Implementations must be safe for concurrent use.
+// - Context: Start must honor cancellation and deadlines.
+// - Ownership: Implementations must not mutate the provided spec.
+// - Snapshots: Start honors spec.Snapshot like MicroVMRunner.Run.
+type MicroVMStarter interface {
+	Start(ctx context.Context, spec MicroVMSpec) (MicroVM, error)
+}
+
+// MicroVM is a running microVM driven by an in-guest agent.
+//
+// Contract:
+// - Concurrency: Exec, Reset, and Stop are never called concurrently on one microVM.
+// - Context: methods must honor cancellation and deadlines.
+// - Errors: Exec returns an error only when the agent failed; a non-zero exit is reported in MicroVMResult.
+// - Reset: Reset kills leftover guest processes and restores the guest filesystem and environment, so the next execution observes nothing from the previous one.
+type MicroVM interface {
+	Exec(ctx context.Context, spec MicroVMSpec) (MicroVMResult, error)
+	Reset(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
@@ -12,8 +12,14 @@ import (
 	"time"
 
 	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
 )
 
+// DefaultGuestCID is the vsock context ID given to microVMs. CIDs 0-2
+// are reserved, and each microVM has its own vsock device, so every
+// guest can use the same one.
+const DefaultGuestCID uint32 = 3
+
 // Errors for Firecracker backend operations.
 var (
 	// ErrFirecrackerNotAvailable is returned when Firecracker is not available.
@@ -29,7 +35,15 @@ var (
 	ErrClientNotConfigured = errors.New("firecracker runner not configured")
 
 	// ErrDaemonUnavailable is returned when Firecracker is not reachable.
-	ErrDaemonUnavailable = errors.New("firecracker daemon unavailable")
+	ErrDaemonUnavailable = fmt.Errorf("firecracker daemon unavailable: %w", runtime.ErrRuntimeUnavailable)
+
+	// ErrSnapshotFailed is returned when a golden snapshot cannot be created.
+	ErrSnapshotFailed = errors.New("microvm snapshot failed")
+
+	// ErrSnapshotInvalid is returned by runners when a snapshot cannot be
+	// restored, for example after a Firecracker upgrade. The backend then
+	// deletes the snapshot and boots the microVM instead.
+	ErrSnapshotInvalid = errors.New("microvm snapshot invalid")
 )
 
 // Logger is the interface for logging.
@@ -61,6 +75,18 @@ type Config struct {
 	// Default: auto-generated per VM
 	SocketPath string
 
+	// VsockPath is the host-side Unix socket of the microVM's vsock
+	// device. When set, guests reach the tool gateway over vsock without a
+	// network: their environment gets proxy.GatewayVsockEnv, and the host
+	// serves the gateway on proxy.FirecrackerVsockPath(VsockPath,
+	// GatewayVsockPort).
+	// Default: "" (no vsock device)
+	VsockPath string
+
+	// GatewayVsockPort is the vsock port guests dial for the gateway.
+	// Default: proxy.DefaultGatewayVsockPort
+	GatewayVsockPort uint32
+
 	// VCPUCount is the number of virtual CPUs.
 	// Default: 1
 	VCPUCount int
@@ -77,6 +103,20 @@ type Config struct {
 	// If nil, Execute() returns ErrClientNotConfigured.
 	Client MicroVMRunner
 
+	// SnapshotDir enables snapshot restore when Client also implements
+	// Snapshotter. The first execution boots a golden microVM and
+	// snapshots it under SnapshotDir; later executions restore it. A new
+	// snapshot is taken when the kernel, rootfs, or machine size changes.
+	// Default: "" (boot every microVM)
+	SnapshotDir string
+
+	// Pool enables microVM reuse when Client also implements
+	// MicroVMStarter. Executions then run in pooled microVMs that the
+	// in-guest agent resets in between, and new pooled microVMs start
+	// from the golden snapshot when snapshots are enabled.
+	// Default: nil (one microVM per execution)
+	Pool *PoolConfig
+
 	// HealthChecker optionally verifies Firecracker availability.
 	HealthChecker HealthChecker
 
@@ -90,10 +130,14 @@ type Backend struct {
 	kernelPath string
 	rootfsPath string
 	socketPath string
+	vsockPath  string
+	vsockPort  uint32
 	vcpuCount  int
 	memSizeMB  int
 	image      string
 	client     MicroVMRunner
+	snapshots  *snapshotStore
+	pool       *vmPool
 	health     HealthChecker
 	logger     Logger
 }
@@ -120,15 +164,33 @@ func New(cfg Config) *Backend {
 		image = "toolruntime-sandbox:latest"
 	}
 
+	vsockPort := cfg.GatewayVsockPort
+	if vsockPort == 0 {
+		vsockPort = proxy.DefaultGatewayVsockPort
+	}
+
+	var snapshots *snapshotStore
+	if creator, ok := cfg.Client.(Snapshotter); ok && cfg.SnapshotDir != "" {
+		snapshots = &snapshotStore{dir: cfg.SnapshotDir, creator: creator}
+	}
+	var pool *vmPool
+	if starter, ok := cfg.Client.(MicroVMStarter); ok && cfg.Pool != nil {
+		pool = newVMPool(starter, *cfg.Pool)
+	}
+
 	return &Backend{
 		binaryPath: binaryPath,
 		kernelPath: cfg.KernelPath,
 		rootfsPath: cfg.RootfsPath,
 		socketPath: cfg.SocketPath,
+		vsockPath:  cfg.VsockPath,
+		vsockPort:  vsockPort,
 		vcpuCount:  vcpuCount,
 		memSizeMB:  memSizeMB,
 		image:      image,
 		client:     cfg.Client,
+		snapshots:  snapshots,
+		pool:       pool,
 		health:     cfg.HealthChecker,
 		logger:     cfg.Logger,
 	}
@@ -139,6 +201,14 @@ func (b *Backend) Kind() runtime.BackendKind {
 	return runtime.BackendFirecracker
 }
 
+// Capabilities implements runtime.CapabilityReporter. MicroVMs have no
+// network interface.
+func (b *Backend) Capabilities() runtime.Capabilities {
+	return runtime.Capabilities{
+		NetworkModes: []string{"none"},
+	}
+}
+
 // Execute runs code in a Firecracker microVM.
 func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
 	if err := ctx.Err(); err != nil {
@@ -184,20 +254,31 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 			"rootfsPath", b.rootfsPath)
 	}
 
-	runResult, err := b.client.Run(ctx, spec)
+	run := b.run
+	if b.pool != nil {
+		run = b.runPooled
+	}
+	runResult, snap, err := run(ctx, spec)
 	if err != nil {
 		return runtime.ExecuteResult{
 			Duration: time.Since(start),
 			Backend:  b.backendInfo(profile),
 		}, err
 	}
+	info := b.backendInfo(profile)
+	if snap != nil {
+		info.Details["snapshot"] = snap.Key
+	}
 
+	usage := runResult.Usage
+	usage.WallTime = runResult.Duration
 	return runtime.ExecuteResult{
 		Value:    extractOutValue(runResult.Stdout),
 		Stdout:   runResult.Stdout,
 		Stderr:   runResult.Stderr,
 		Duration: runResult.Duration,
-		Backend:  b.backendInfo(profile),
+		Backend:  info,
+		Usage:    usage,
 		LimitsEnforced: runtime.LimitsEnforced{
 			Timeout:    true,
 			Memory:     req.Limits.MemoryBytes > 0,
@@ -212,8 +293,110 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 
 var _ runtime.Backend = (*Backend)(nil)
 
+// InvalidateSnapshots deletes the backend's snapshots so the next
+// execution boots and snapshots a fresh golden microVM. Changes to the
+// kernel or rootfs are detected automatically; call it after changes
+// they do not reveal, such as a guest agent update in place.
+func (b *Backend) InvalidateSnapshots() error {
+	if b.snapshots == nil {
+		return nil
+	}
+	return b.snapshots.invalidate("")
+}
+
+// PoolStats returns a snapshot of the microVM pool, or zero stats when
+// pooling is disabled.
+func (b *Backend) PoolStats() PoolStats {
+	if b.pool == nil {
+		return PoolStats{}
+	}
+	return b.pool.snapshot()
+}
+
+// Close stops the pool's idle microVMs. MicroVMs running an execution
+// are stopped when it finishes. The backend keeps working without the
+// pool, booting one microVM per execution.
+func (b *Backend) Close() error {
+	if b.pool == nil {
+		return nil
+	}
+	return b.pool.close()
+}
+
+// runPooled runs spec in a pooled microVM, starting one when none is
+// idle, and boots a one-shot microVM when the pool is full. It reports
+// the snapshot a new microVM was restored from, if any.
+func (b *Backend) runPooled(ctx context.Context, spec MicroVMSpec) (MicroVMResult, *Snapshot, error) {
+	key, err := fingerprint(spec)
+	if err != nil {
+		// Without a fingerprint, idle microVMs cannot be matched to the
+		// current kernel and rootfs.
+		return b.run(ctx, spec)
+	}
+	vm, reserved := b.pool.acquire(key)
+	var snap *Snapshot
+	if vm == nil {
+		if !reserved {
+			return b.run(ctx, spec)
+		}
+		var started MicroVM
+		snap, err = b.withSnapshot(ctx, spec, func(spec MicroVMSpec) error {
+			var err error
+			started, err = b.pool.starter.Start(ctx, spec)
+			return err
+		})
+		if err != nil {
+			b.pool.unreserve()
+			return MicroVMResult{}, nil, err
+		}
+		vm = &pooledVM{vm: started, key: key}
+	}
+	result, err := vm.vm.Exec(ctx, spec)
+	b.pool.release(ctx, vm, err)
+	return result, snap, err
+}
+
+// run boots spec once, from the golden snapshot when snapshots are
+// enabled. It reports the snapshot it restored, if any.
+func (b *Backend) run(ctx context.Context, spec MicroVMSpec) (MicroVMResult, *Snapshot, error) {
+	var result MicroVMResult
+	snap, err := b.withSnapshot(ctx, spec, func(spec MicroVMSpec) error {
+		var err error
+		result, err = b.client.Run(ctx, spec)
+		return err
+	})
+	return result, snap, err
+}
+
+// withSnapshot calls boot with spec set to restore the golden snapshot
+// when snapshots are enabled. It boots without one when the snapshot
+// cannot be created, or is invalid, and reports the snapshot restored.
+func (b *Backend) withSnapshot(ctx context.Context, spec MicroVMSpec, boot func(MicroVMSpec) error) (*Snapshot, error) {
+	if b.snapshots == nil {
+		return nil, boot(spec)
+	}
+	snap, err := b.snapshots.ensure(ctx, spec)
+	if err != nil {
+		if b.logger != nil {
+			b.logger.Warn("firecracker snapshot unavailable, booting", "error", err)
+		}
+		return nil, boot(spec)
+	}
+	restored := spec
+	restored.Snapshot = snap
+	err = boot(restored)
+	if errors.Is(err, ErrSnapshotInvalid) {
+		if b.logger != nil {
+			b.logger.Warn("firecracker snapshot invalid, booting", "snapshot", snap.Key, "error", err)
+		}
+		_ = b.snapshots.invalidate(snap.Key)
+		return nil, boot(spec)
+	}
+	return snap, err
+}
+
 func (b *Backend) backendInfo(profile runtime.SecurityProfile) runtime.BackendInfo {
-	return runtime.BackendInfo{
+	info := runtime.BackendInfo{
 		Kind:      runtime.BackendFirecracker,
 		Readiness: runtime.ReadinessBeta,
 		Details: map[string]any{
@@ -222,6 +405,14 @@ func (b *Backend) backendInfo(profile runtime.SecurityProfile) runtime.BackendIn
 			"profile":   string(profile),
 		},
 	}
+	if b.pool != nil {
+		stats := b.pool.snapshot()
+		info.Details["poolSize"] = stats.Size
+		info.Details["poolIdle"] = stats.Idle
+		info.Details["poolHits"] = stats.Hits
+		info.Details["poolMisses"] = stats.Misses
+	}
+	return info
 }
 
 func (b *Backend) buildSpec(req runtime.ExecuteRequest) (MicroVMSpec, error) {
@@ -230,7 +421,12 @@ func (b *Backend) buildSpec(req runtime.ExecuteRequest) (MicroVMSpec, error) {
 		Resources: VMResourceSpec{VCPUCount: b.vcpuCount, MemSizeMB: b.memSizeMB},
 		Config:    VMConfig{KernelPath: b.kernelPath, RootfsPath: b.rootfsPath, SocketPath: b.socketPath},
 		Timeout:   req.Timeout,
-		Labels:    map[string]string{"runtime.backend": string(runtime.BackendFirecracker)},
+		Labels:    map[string]string{"runtime.backend": string(runtime.BackendFirecracker), runtime.LabelExecutionID: req.EnsureExecutionID()},
+	}
+	if b.vsockPath != "" {
+		spec.Config.VsockPath = b.vsockPath
+		spec.Config.GuestCID = DefaultGuestCID
+		spec.Env = append(spec.Env, proxy.GatewayVsockEnvValue(b.vsockPort))
 	}
 	if err := spec.Validate(); err != nil {
 		return MicroVMSpec{}, err
@@ -3,11 +3,37 @@ package firecracker
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
 
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
 	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
 )
 
+type mockGateway struct{}
+
+func (m *mockGateway) SearchTools(_ context.Context, _ string, _ int) ([]index.Summary, error) {
+	return nil, nil
+}
+func (m *mockGateway) ListNamespaces(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+func (m *mockGateway) DescribeTool(_ context.Context, _ string, _ tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	return tooldoc.ToolDoc{}, nil
+}
+func (m *mockGateway) ListToolExamples(_ context.Context, _ string, _ int) ([]tooldoc.ToolExample, error) {
+	return nil, nil
+}
+func (m *mockGateway) RunTool(_ context.Context, _ string, _ map[string]any) (run.RunResult, error) {
+	return run.RunResult{}, nil
+}
+func (m *mockGateway) RunChain(_ context.Context, _ []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	return run.RunResult{}, nil, nil
+}
+
 func TestBackendImplementsInterface(t *testing.T) {
 	t.Helper()
 	var _ runtime.Backend = (*Backend)(nil)
@@ -45,3 +71,22 @@ func TestBackendRequiresGateway(t *testing.T) {
 		t.Errorf("Execute() without gateway error = %v, want %v", err, runtime.ErrMissingGateway)
 	}
 }
+
+func TestBackendGatewayVsock(t *testing.T) {
+	b := New(Config{KernelPath: "/vmlinux", RootfsPath: "/rootfs.ext4", VsockPath: "/run/v.sock"})
+	spec, err := b.buildSpec(runtime.ExecuteRequest{Code: "test"})
+	if err != nil {
+		t.Fatalf("buildSpec() error = %v", err)
+	}
+	if spec.Config.VsockPath != "/run/v.sock" || spec.Config.GuestCID != DefaultGuestCID {
+		t.Errorf("Config = %+v, want a vsock device with the default guest CID", spec.Config)
+	}
+	if want := proxy.GatewayVsockEnvValue(proxy.DefaultGatewayVsockPort); !slices.Contains(spec.Env, want) {
+		t.Errorf("Env = %v, want %q", spec.Env, want)
+	}
+
+	spec.Config.GuestCID = 2
+	if err := spec.Validate(); err == nil {
+		t.Error("Validate() accepted the host CID as guest CID")
+	}
+}
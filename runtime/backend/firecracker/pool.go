@@ -0,0 +1,227 @@
+package firecracker
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// Pool defaults.
+const (
+	DefaultPoolMaxSize       = 4
+	DefaultPoolMaxExecutions = 100
+	DefaultPoolIdleTTL       = 5 * time.Minute
+)
+
+// resetTimeout bounds the in-guest reset between executions.
+const resetTimeout = 10 * time.Second
+
+// PoolConfig bounds the microVMs kept running for reuse.
+type PoolConfig struct {
+	// MaxSize caps the pooled microVMs, busy or idle. Executions beyond
+	// it run in a one-shot microVM.
+	// Default: DefaultPoolMaxSize
+	MaxSize int
+
+	// MaxExecutionsPerVM retires a microVM after this many executions.
+	// Default: DefaultPoolMaxExecutions
+	MaxExecutionsPerVM int
+
+	// IdleTTL stops microVMs that stay idle for longer.
+	// Default: DefaultPoolIdleTTL
+	IdleTTL time.Duration
+
+	// Events, if set, receives runtime.EventPoolResized whenever a
+	// microVM joins or leaves the pool.
+	Events *runtime.Events
+}
+
+// PoolStats reports the pool's microVMs and how often they were reused.
+type PoolStats struct {
+	// Size counts pooled microVMs, busy or idle; Idle counts those
+	// waiting for an execution.
+	Size int
+	Idle int
+	// Hits counts executions served by an idle microVM; Misses counts
+	// executions that started one or, at MaxSize, ran one-shot.
+	Hits   uint64
+	Misses uint64
+	// Retired counts microVMs stopped after an error, a failed reset,
+	// MaxExecutionsPerVM, IdleTTL, or a rootfs change.
+	Retired uint64
+}
+
+// vmPool keeps reset microVMs for reuse. All pooled microVMs of a
+// backend share one fingerprint; idle microVMs with another are stale.
+type vmPool struct {
+	starter  MicroVMStarter
+	maxSize  int
+	maxExecs int
+	idleTTL  time.Duration
+	events   *runtime.Events
+
+	mu     sync.Mutex
+	idle   []*pooledVM
+	stats  PoolStats
+	closed bool
+}
+
+type pooledVM struct {
+	vm    MicroVM
+	key   string
+	execs int
+	timer *time.Timer
+}
+
+func newVMPool(starter MicroVMStarter, cfg PoolConfig) *vmPool {
+	p := &vmPool{
+		starter:  starter,
+		maxSize:  cfg.MaxSize,
+		maxExecs: cfg.MaxExecutionsPerVM,
+		idleTTL:  cfg.IdleTTL,
+		events:   cfg.Events,
+	}
+	if p.maxSize <= 0 {
+		p.maxSize = DefaultPoolMaxSize
+	}
+	if p.maxExecs <= 0 {
+		p.maxExecs = DefaultPoolMaxExecutions
+	}
+	if p.idleTTL <= 0 {
+		p.idleTTL = DefaultPoolIdleTTL
+	}
+	return p
+}
+
+// acquire takes an idle microVM with fingerprint key. Without one, it
+// reports whether a slot was reserved for starting a new microVM; the
+// caller must then add it with release or give the slot back with
+// unreserve.
+func (p *vmPool) acquire(key string) (vm *pooledVM, reserved bool) {
+	p.mu.Lock()
+	var stale []*pooledVM
+	p.idle = slices.DeleteFunc(p.idle, func(v *pooledVM) bool {
+		if v.key != key {
+			stale = append(stale, v)
+			return true
+		}
+		return false
+	})
+	p.retireLocked(stale)
+	defer func() {
+		p.mu.Unlock()
+		stop(stale)
+	}()
+
+	if n := len(p.idle); n > 0 {
+		vm = p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		vm.timer.Stop()
+		p.stats.Hits++
+		return vm, false
+	}
+	p.stats.Misses++
+	if p.closed || p.stats.Size >= p.maxSize {
+		return nil, false
+	}
+	p.stats.Size++
+	p.resizedLocked()
+	return nil, true
+}
+
+// unreserve gives back a slot reserved by acquire.
+func (p *vmPool) unreserve() {
+	p.mu.Lock()
+	p.stats.Size--
+	p.resizedLocked()
+	p.mu.Unlock()
+}
+
+// release returns vm after an execution. The microVM is reset and kept
+// idle, or retired when the execution failed or timed out, its reset
+// failed, or it reached MaxExecutionsPerVM.
+func (p *vmPool) release(ctx context.Context, vm *pooledVM, execErr error) {
+	vm.execs++
+	retire := execErr != nil || ctx.Err() != nil || vm.execs >= p.maxExecs
+	if !retire {
+		resetCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), resetTimeout)
+		retire = vm.vm.Reset(resetCtx) != nil
+		cancel()
+	}
+
+	p.mu.Lock()
+	if retire || p.closed {
+		p.retireLocked([]*pooledVM{vm})
+		p.mu.Unlock()
+		stop([]*pooledVM{vm})
+		return
+	}
+	vm.timer = time.AfterFunc(p.idleTTL, func() { p.expire(vm) })
+	p.idle = append(p.idle, vm)
+	p.mu.Unlock()
+}
+
+// expire retires vm if it is still idle when its IdleTTL passes.
+func (p *vmPool) expire(vm *pooledVM) {
+	p.mu.Lock()
+	i := slices.Index(p.idle, vm)
+	if i < 0 {
+		p.mu.Unlock()
+		return
+	}
+	p.idle = slices.Delete(p.idle, i, i+1)
+	p.retireLocked([]*pooledVM{vm})
+	p.mu.Unlock()
+	stop([]*pooledVM{vm})
+}
+
+// close stops the idle microVMs; busy ones are stopped when released.
+func (p *vmPool) close() error {
+	p.mu.Lock()
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.retireLocked(idle)
+	p.mu.Unlock()
+	return stop(idle)
+}
+
+func (p *vmPool) snapshot() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	stats.Idle = len(p.idle)
+	return stats
+}
+
+// retireLocked removes vms from the pool's count. p.mu must be held.
+func (p *vmPool) retireLocked(vms []*pooledVM) {
+	for _, vm := range vms {
+		if vm.timer != nil {
+			vm.timer.Stop()
+		}
+		p.stats.Size--
+		p.stats.Retired++
+	}
+	if len(vms) > 0 {
+		p.resizedLocked()
+	}
+}
+
+// resizedLocked publishes the pool's new size. p.mu must be held, so
+// events arrive in order.
+func (p *vmPool) resizedLocked() {
+	p.events.Publish(runtime.Event{Type: runtime.EventPoolResized, Backend: runtime.BackendFirecracker, PoolSize: p.stats.Size})
+}
+
+func stop(vms []*pooledVM) error {
+	var errs []error
+	for _, vm := range vms {
+		errs = append(errs, vm.vm.Stop(context.Background()))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,235 @@
+package firecracker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// poolRunner starts fakeVMs and counts one-shot runs.
+type poolRunner struct {
+	mu       sync.Mutex
+	vms      []*fakeVM
+	oneShots int
+	execErr  error
+	resetErr error
+	block    chan struct{}
+}
+
+func (r *poolRunner) Run(context.Context, MicroVMSpec) (MicroVMResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.oneShots++
+	return MicroVMResult{Stdout: "one-shot"}, nil
+}
+
+func (r *poolRunner) Start(context.Context, MicroVMSpec) (MicroVM, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	vm := &fakeVM{runner: r}
+	r.vms = append(r.vms, vm)
+	return vm, nil
+}
+
+func (r *poolRunner) started() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.vms)
+}
+
+type fakeVM struct {
+	runner  *poolRunner
+	mu      sync.Mutex
+	execs   int
+	resets  int
+	stopped bool
+}
+
+func (v *fakeVM) Exec(context.Context, MicroVMSpec) (MicroVMResult, error) {
+	if v.runner.block != nil {
+		<-v.runner.block
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.execs++
+	return MicroVMResult{Stdout: "pooled"}, v.runner.execErr
+}
+
+func (v *fakeVM) Reset(context.Context) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.resets++
+	return v.runner.resetErr
+}
+
+func (v *fakeVM) Stop(context.Context) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.stopped = true
+	return nil
+}
+
+func (v *fakeVM) isStopped() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.stopped
+}
+
+func newPoolBackend(t *testing.T, runner *poolRunner, cfg PoolConfig) (*Backend, string) {
+	t.Helper()
+	dir := t.TempDir()
+	kernel := filepath.Join(dir, "vmlinux")
+	rootfs := filepath.Join(dir, "rootfs.ext4")
+	for _, path := range []string{kernel, rootfs} {
+		if err := os.WriteFile(path, []byte("image"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b := New(Config{KernelPath: kernel, RootfsPath: rootfs, Client: runner, Pool: &cfg})
+	t.Cleanup(func() { _ = b.Close() })
+	return b, rootfs
+}
+
+func TestBackendPoolReuse(t *testing.T) {
+	runner := &poolRunner{}
+	b, _ := newPoolBackend(t, runner, PoolConfig{})
+
+	execute(t, b)
+	result := execute(t, b)
+	if runner.started() != 1 || runner.vms[0].execs != 2 || runner.vms[0].resets != 2 {
+		t.Fatalf("started %d microVMs, want one reused and reset after each execution", runner.started())
+	}
+	if result.Stdout != "pooled" {
+		t.Errorf("Stdout = %q, want the pooled microVM's output", result.Stdout)
+	}
+	d := result.Backend.Details
+	if d["poolSize"] != 1 || d["poolIdle"] != 1 || d["poolHits"] != uint64(1) || d["poolMisses"] != uint64(1) {
+		t.Errorf("Details = %v, want one idle microVM with one hit and one miss", d)
+	}
+}
+
+func TestBackendPoolRetires(t *testing.T) {
+	t.Run("max executions", func(t *testing.T) {
+		runner := &poolRunner{}
+		b, _ := newPoolBackend(t, runner, PoolConfig{MaxExecutionsPerVM: 2})
+		for range 3 {
+			execute(t, b)
+		}
+		if runner.started() != 2 || !runner.vms[0].isStopped() || runner.vms[0].resets != 1 {
+			t.Errorf("started %d microVMs, want the first retired after 2 executions", runner.started())
+		}
+	})
+
+	t.Run("exec error", func(t *testing.T) {
+		runner := &poolRunner{execErr: errors.New("agent gone")}
+		b, _ := newPoolBackend(t, runner, PoolConfig{})
+		if _, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}}); err == nil {
+			t.Fatal("Execute() succeeded with a failing agent")
+		}
+		if !runner.vms[0].isStopped() || b.PoolStats().Size != 0 {
+			t.Errorf("PoolStats() = %+v, want the failed microVM retired", b.PoolStats())
+		}
+	})
+
+	t.Run("reset error", func(t *testing.T) {
+		runner := &poolRunner{resetErr: errors.New("reset failed")}
+		b, _ := newPoolBackend(t, runner, PoolConfig{})
+		execute(t, b)
+		execute(t, b)
+		if runner.started() != 2 || !runner.vms[0].isStopped() {
+			t.Errorf("started %d microVMs, want a new one after a failed reset", runner.started())
+		}
+	})
+
+	t.Run("idle ttl", func(t *testing.T) {
+		runner := &poolRunner{}
+		b, _ := newPoolBackend(t, runner, PoolConfig{IdleTTL: 10 * time.Millisecond})
+		execute(t, b)
+		deadline := time.Now().Add(2 * time.Second)
+		for !runner.vms[0].isStopped() && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if stats := b.PoolStats(); !runner.vms[0].isStopped() || stats.Size != 0 || stats.Retired != 1 {
+			t.Errorf("PoolStats() = %+v, want the idle microVM expired", stats)
+		}
+	})
+
+	t.Run("rootfs change", func(t *testing.T) {
+		runner := &poolRunner{}
+		b, rootfs := newPoolBackend(t, runner, PoolConfig{})
+		execute(t, b)
+		later := time.Now().Add(time.Hour)
+		if err := os.Chtimes(rootfs, later, later); err != nil {
+			t.Fatal(err)
+		}
+		execute(t, b)
+		if runner.started() != 2 || !runner.vms[0].isStopped() {
+			t.Errorf("started %d microVMs, want the stale one replaced", runner.started())
+		}
+	})
+}
+
+func TestBackendPoolFullRunsOneShot(t *testing.T) {
+	runner := &poolRunner{block: make(chan struct{})}
+	b, _ := newPoolBackend(t, runner, PoolConfig{MaxSize: 1})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}})
+	}()
+	for runner.started() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	result := execute(t, b)
+	close(runner.block)
+	<-done
+	if result.Stdout != "one-shot" || runner.oneShots != 1 {
+		t.Errorf("Stdout = %q, want a one-shot microVM while the pool is full", result.Stdout)
+	}
+}
+
+func TestBackendPoolClose(t *testing.T) {
+	runner := &poolRunner{}
+	b, _ := newPoolBackend(t, runner, PoolConfig{})
+	execute(t, b)
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !runner.vms[0].isStopped() {
+		t.Error("Close() left the idle microVM running")
+	}
+	result := execute(t, b)
+	if result.Stdout != "one-shot" {
+		t.Errorf("Stdout = %q, want one-shot microVMs after Close", result.Stdout)
+	}
+}
+
+func TestBackendPoolEvents(t *testing.T) {
+	events := runtime.NewEvents()
+	resized, cancel := events.Subscribe(0, runtime.EventPoolResized)
+	defer cancel()
+	b, _ := newPoolBackend(t, &poolRunner{}, PoolConfig{Events: events})
+
+	execute(t, b)
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var sizes []int
+	for range 2 {
+		ev := <-resized
+		if ev.Backend != runtime.BackendFirecracker {
+			t.Errorf("Backend = %q, want %q", ev.Backend, runtime.BackendFirecracker)
+		}
+		sizes = append(sizes, ev.PoolSize)
+	}
+	if sizes[0] != 1 || sizes[1] != 0 {
+		t.Errorf("pool sizes = %v, want the microVM added then retired", sizes)
+	}
+}
@@ -0,0 +1,164 @@
+package firecracker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Snapshot file names inside a snapshot directory.
+const (
+	SnapshotStateFile = "vmstate"
+	SnapshotMemFile   = "memory"
+)
+
+// Snapshot locates the files of a paused microVM snapshot.
+type Snapshot struct {
+	// Key fingerprints the kernel, rootfs, and machine configuration the
+	// snapshot was taken from.
+	Key       string
+	StatePath string
+	MemPath   string
+}
+
+// snapshotStore keeps one golden snapshot per fingerprint under dir and
+// removes snapshots whose fingerprint no longer matches.
+type snapshotStore struct {
+	dir     string
+	creator Snapshotter
+
+	mu sync.Mutex
+}
+
+// ensure returns the snapshot for spec, creating it from a golden boot
+// when it does not exist yet.
+func (s *snapshotStore) ensure(ctx context.Context, spec MicroVMSpec) (*Snapshot, error) {
+	key, err := fingerprint(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotFailed, err)
+	}
+	snap := s.snapshot(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if exists(snap.StatePath) && exists(snap.MemPath) {
+		return snap, nil
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("%w: snapshot dir: %v", ErrSnapshotFailed, err)
+	}
+	tmp, err := os.MkdirTemp(s.dir, key+".tmp-")
+	if err != nil {
+		return nil, fmt.Errorf("%w: snapshot dir: %v", ErrSnapshotFailed, err)
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+	staged := Snapshot{
+		Key:       key,
+		StatePath: filepath.Join(tmp, SnapshotStateFile),
+		MemPath:   filepath.Join(tmp, SnapshotMemFile),
+	}
+	if err := s.creator.CreateSnapshot(ctx, spec, staged); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotFailed, err)
+	}
+	if err := os.Rename(tmp, filepath.Dir(snap.StatePath)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotFailed, err)
+	}
+	s.prune(key)
+	return snap, nil
+}
+
+// invalidate deletes the snapshot with key, or every snapshot when key
+// is empty.
+func (s *snapshotStore) invalidate(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key != "" {
+		return os.RemoveAll(filepath.Join(s.dir, key))
+	}
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, e := range entries {
+		errs = append(errs, os.RemoveAll(filepath.Join(s.dir, e.Name())))
+	}
+	return errors.Join(errs...)
+}
+
+// prune removes snapshots other than key, which are stale once the
+// kernel, rootfs, or machine configuration changed. s.mu must be held.
+func (s *snapshotStore) prune(key string) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != key && !strings.Contains(e.Name(), ".tmp-") {
+			_ = os.RemoveAll(filepath.Join(s.dir, e.Name()))
+		}
+	}
+}
+
+func (s *snapshotStore) snapshot(key string) *Snapshot {
+	dir := filepath.Join(s.dir, key)
+	return &Snapshot{
+		Key:       key,
+		StatePath: filepath.Join(dir, SnapshotStateFile),
+		MemPath:   filepath.Join(dir, SnapshotMemFile),
+	}
+}
+
+// fingerprint identifies the guest a spec boots: its kernel, rootfs, and
+// machine size. Kernel and rootfs are identified by path, size, and
+// modification time, so replacing or rewriting either changes the
+// fingerprint without hashing the image. Snapshots and pooled microVMs
+// are only reused while it matches.
+func fingerprint(spec MicroVMSpec) (string, error) {
+	type file struct {
+		Path    string
+		Size    int64
+		ModTime time.Time
+	}
+	stat := func(path string) (file, error) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return file{}, err
+		}
+		return file{Path: path, Size: info.Size(), ModTime: info.ModTime().UTC()}, nil
+	}
+	kernel, err := stat(spec.Config.KernelPath)
+	if err != nil {
+		return "", err
+	}
+	rootfs, err := stat(spec.Config.RootfsPath)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(struct {
+		Kernel, Rootfs file
+		Image          string
+		Resources      VMResourceSpec
+	}{kernel, rootfs, spec.Image, spec.Resources})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
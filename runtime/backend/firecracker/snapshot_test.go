@@ -0,0 +1,168 @@
+package firecracker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// snapshotRunner records runs and writes snapshot files on request.
+type snapshotRunner struct {
+	mu        sync.Mutex
+	created   int
+	runs      []*Snapshot
+	invalid   bool
+	createErr error
+}
+
+func (r *snapshotRunner) Run(_ context.Context, spec MicroVMSpec) (MicroVMResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, spec.Snapshot)
+	if spec.Snapshot != nil && r.invalid {
+		r.invalid = false
+		return MicroVMResult{}, ErrSnapshotInvalid
+	}
+	return MicroVMResult{Stdout: "ok"}, nil
+}
+
+func (r *snapshotRunner) CreateSnapshot(_ context.Context, _ MicroVMSpec, dst Snapshot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.createErr != nil {
+		return r.createErr
+	}
+	r.created++
+	if err := os.WriteFile(dst.StatePath, []byte("state"), 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(dst.MemPath, []byte("mem"), 0o600)
+}
+
+func newSnapshotBackend(t *testing.T, runner *snapshotRunner) (*Backend, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	kernel := filepath.Join(dir, "vmlinux")
+	rootfs := filepath.Join(dir, "rootfs.ext4")
+	for _, path := range []string{kernel, rootfs} {
+		if err := os.WriteFile(path, []byte("image"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapDir := filepath.Join(dir, "snapshots")
+	return New(Config{KernelPath: kernel, RootfsPath: rootfs, Client: runner, SnapshotDir: snapDir}), rootfs, snapDir
+}
+
+func execute(t *testing.T, b *Backend) runtime.ExecuteResult {
+	t.Helper()
+	result, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	return result
+}
+
+func TestBackendSnapshotRestore(t *testing.T) {
+	runner := &snapshotRunner{}
+	b, rootfs, snapDir := newSnapshotBackend(t, runner)
+
+	first := execute(t, b)
+	second := execute(t, b)
+	if runner.created != 1 {
+		t.Errorf("created %d snapshots, want 1", runner.created)
+	}
+	snap := runner.runs[1]
+	if snap == nil || filepath.Dir(snap.StatePath) != filepath.Join(snapDir, snap.Key) {
+		t.Fatalf("second run snapshot = %+v, want one under %s", snap, snapDir)
+	}
+	if first.Backend.Details["snapshot"] != snap.Key || second.Backend.Details["snapshot"] != snap.Key {
+		t.Errorf("Details = %v, want snapshot %s", second.Backend.Details, snap.Key)
+	}
+
+	// Rewriting the rootfs takes a new snapshot and drops the old one.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(rootfs, later, later); err != nil {
+		t.Fatal(err)
+	}
+	execute(t, b)
+	if runner.created != 2 {
+		t.Errorf("created %d snapshots after a rootfs change, want 2", runner.created)
+	}
+	if runner.runs[2].Key == snap.Key {
+		t.Error("rootfs change kept the snapshot key")
+	}
+	if _, err := os.Stat(filepath.Join(snapDir, snap.Key)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("stale snapshot not removed: %v", err)
+	}
+}
+
+func TestBackendSnapshotInvalidBoots(t *testing.T) {
+	runner := &snapshotRunner{invalid: true}
+	b, _, snapDir := newSnapshotBackend(t, runner)
+
+	result := execute(t, b)
+	if len(runner.runs) != 2 || runner.runs[1] != nil {
+		t.Errorf("runs = %v, want a failed restore followed by a boot", runner.runs)
+	}
+	if _, ok := result.Backend.Details["snapshot"]; ok {
+		t.Error("Details report a snapshot for a booted microVM")
+	}
+	if entries, _ := os.ReadDir(snapDir); len(entries) != 0 {
+		t.Errorf("invalid snapshot kept: %v", entries)
+	}
+	execute(t, b)
+	if runner.created != 2 {
+		t.Errorf("created %d snapshots, want a fresh one after invalidation", runner.created)
+	}
+}
+
+func TestBackendSnapshotCreateFailureBoots(t *testing.T) {
+	runner := &snapshotRunner{createErr: errors.New("no kvm")}
+	b, _, _ := newSnapshotBackend(t, runner)
+
+	execute(t, b)
+	if len(runner.runs) != 1 || runner.runs[0] != nil {
+		t.Errorf("runs = %v, want one boot without a snapshot", runner.runs)
+	}
+}
+
+func TestBackendInvalidateSnapshots(t *testing.T) {
+	runner := &snapshotRunner{}
+	b, _, snapDir := newSnapshotBackend(t, runner)
+
+	execute(t, b)
+	if err := b.InvalidateSnapshots(); err != nil {
+		t.Fatalf("InvalidateSnapshots() error = %v", err)
+	}
+	if entries, _ := os.ReadDir(snapDir); len(entries) != 0 {
+		t.Errorf("snapshots left after InvalidateSnapshots: %v", entries)
+	}
+	execute(t, b)
+	if runner.created != 2 {
+		t.Errorf("created %d snapshots, want 2", runner.created)
+	}
+}
+
+func TestBackendSnapshotConcurrentCreate(t *testing.T) {
+	runner := &snapshotRunner{}
+	b, _, _ := newSnapshotBackend(t, runner)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}})
+		}()
+	}
+	wg.Wait()
+	if runner.created != 1 {
+		t.Errorf("created %d snapshots concurrently, want 1", runner.created)
+	}
+}
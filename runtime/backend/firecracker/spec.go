@@ -1,6 +1,10 @@
 package firecracker
 
-import "time"
+import (
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
 
 // VMResourceSpec defines resource limits for microVMs.
 type VMResourceSpec struct {
@@ -13,6 +17,10 @@ type VMConfig struct {
 	KernelPath string
 	RootfsPath string
 	SocketPath string
+	// VsockPath, if set, adds a vsock device with guest CID GuestCID
+	// whose host side is this Unix socket.
+	VsockPath string
+	GuestCID  uint32
 }
 
 // MicroVMSpec defines what to run inside a Firecracker microVM.
@@ -25,6 +33,9 @@ type MicroVMSpec struct {
 	Config     VMConfig
 	Timeout    time.Duration
 	Labels     map[string]string
+	// Snapshot, if set, is restored instead of booting the kernel. The
+	// backend sets it when snapshot restore is enabled.
+	Snapshot *Snapshot
 }
 
 // MicroVMResult captures the output of a microVM execution.
@@ -33,4 +44,8 @@ type MicroVMResult struct {
 	Stdout   string
 	Stderr   string
 	Duration time.Duration
+	// Usage reports the resources the microVM consumed, read from the
+	// jailer's cgroup and the tap device where the runner can. WallTime
+	// is filled in from Duration.
+	Usage runtime.ResourceUsage
 }
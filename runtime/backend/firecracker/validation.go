@@ -18,5 +18,24 @@ func (s MicroVMSpec) Validate() error {
 	if s.Resources.MemSizeMB <= 0 {
 		return errors.New("memSizeMB must be positive")
 	}
+	if s.Config.VsockPath != "" && s.Config.GuestCID < 3 {
+		return errors.New("guestCID must be at least 3")
+	}
+	if s.Snapshot != nil {
+		if err := s.Snapshot.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks that the snapshot locates both of its files.
+func (s Snapshot) Validate() error {
+	if s.StatePath == "" {
+		return errors.New("snapshot statePath is required")
+	}
+	if s.MemPath == "" {
+		return errors.New("snapshot memPath is required")
+	}
 	return nil
 }
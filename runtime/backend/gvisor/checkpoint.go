@@ -0,0 +1,233 @@
+package gvisor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Checkpoint file names inside a checkpoint directory.
+const (
+	// CheckpointImageDir is the directory passed to runsc checkpoint and
+	// runsc restore as --image-path.
+	CheckpointImageDir = "image"
+
+	// CheckpointMetaFile records the checkpoint's metadata as JSON.
+	CheckpointMetaFile = "checkpoint.json"
+)
+
+// Checkpoint describes a runsc checkpoint of a sandbox that is ready to
+// run commands.
+type Checkpoint struct {
+	// Key fingerprints the image, platform, resources, and security
+	// settings the checkpoint was taken with.
+	Key string `json:"key"`
+
+	// Image is the container image the checkpointed sandbox runs.
+	Image string `json:"image"`
+
+	// ImagePath is the runsc --image-path directory.
+	ImagePath string `json:"imagePath"`
+
+	// CreatedAt is when the checkpoint was taken.
+	CreatedAt time.Time `json:"createdAt"`
+
+	// ExpiresAt is when the checkpoint is deleted, or zero if it does not
+	// expire.
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+}
+
+// Expired reports whether the checkpoint has expired at now.
+func (c Checkpoint) Expired(now time.Time) bool {
+	return !c.ExpiresAt.IsZero() && !now.Before(c.ExpiresAt)
+}
+
+// checkpointStore keeps one checkpoint per fingerprint under dir and
+// deletes checkpoints older than ttl.
+type checkpointStore struct {
+	dir     string
+	ttl     time.Duration
+	creator Checkpointer
+	now     func() time.Time
+
+	mu sync.Mutex
+}
+
+// ensure returns the checkpoint for spec, taking it when it does not exist
+// yet or has expired.
+func (s *checkpointStore) ensure(ctx context.Context, spec SandboxSpec) (*Checkpoint, error) {
+	key, err := checkpointKey(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCheckpointFailed, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if cp, err := s.load(key); err == nil {
+		if !cp.Expired(now) {
+			return cp, nil
+		}
+		_ = os.RemoveAll(filepath.Join(s.dir, key))
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("%w: checkpoint dir: %v", ErrCheckpointFailed, err)
+	}
+	tmp, err := os.MkdirTemp(s.dir, key+".tmp-")
+	if err != nil {
+		return nil, fmt.Errorf("%w: checkpoint dir: %v", ErrCheckpointFailed, err)
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	staged := Checkpoint{Key: key, Image: spec.Image, ImagePath: filepath.Join(tmp, CheckpointImageDir), CreatedAt: now}
+	if err := os.Mkdir(staged.ImagePath, 0o700); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCheckpointFailed, err)
+	}
+	if err := s.creator.Checkpoint(ctx, spec, staged); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCheckpointFailed, err)
+	}
+
+	cp := staged
+	cp.ImagePath = filepath.Join(s.dir, key, CheckpointImageDir)
+	if s.ttl > 0 {
+		cp.ExpiresAt = now.Add(s.ttl)
+	}
+	data, err := json.Marshal(cp)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(tmp, CheckpointMetaFile), data, 0o600)
+	}
+	if err == nil {
+		err = os.Rename(tmp, filepath.Join(s.dir, key))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCheckpointFailed, err)
+	}
+	_, _ = s.pruneLocked(false)
+	return &cp, nil
+}
+
+// load reads the metadata of the checkpoint with key.
+func (s *checkpointStore) load(key string) (*Checkpoint, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, key, CheckpointMetaFile))
+	if err != nil {
+		return nil, err
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// list returns the stored checkpoints ordered by key.
+func (s *checkpointStore) list() ([]Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys, err := s.keys()
+	if err != nil {
+		return nil, err
+	}
+	var out []Checkpoint
+	for _, key := range keys {
+		if cp, err := s.load(key); err == nil {
+			out = append(out, *cp)
+		}
+	}
+	return out, nil
+}
+
+// remove deletes the checkpoint with key.
+func (s *checkpointStore) remove(key string) error {
+	if key == "" || strings.ContainsAny(key, `/\`) || key == "." || key == ".." {
+		return fmt.Errorf("%w: %q", ErrCheckpointNotFound, key)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir := filepath.Join(s.dir, key)
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %q", ErrCheckpointNotFound, key)
+	}
+	return os.RemoveAll(dir)
+}
+
+// prune deletes expired checkpoints, and every checkpoint when all is
+// set, along with unreadable and abandoned ones. It returns how many
+// checkpoints it deleted.
+func (s *checkpointStore) prune(all bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pruneLocked(all)
+}
+
+// pruneLocked is prune with s.mu held.
+func (s *checkpointStore) pruneLocked(all bool) (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	now := s.now()
+	var n int
+	var errs []error
+	for _, e := range entries {
+		cp, err := s.load(e.Name())
+		if !all && err == nil && !cp.Expired(now) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(s.dir, e.Name())); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if cp != nil {
+			n++
+		}
+	}
+	return n, errors.Join(errs...)
+}
+
+func (s *checkpointStore) keys() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.Contains(e.Name(), ".tmp-") {
+			keys = append(keys, e.Name())
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+// checkpointKey fingerprints the parts of spec that shape the sandbox
+// before any command runs. Commands, environment, labels, and timeouts
+// vary per execution and are applied after restore.
+func checkpointKey(spec SandboxSpec) (string, error) {
+	data, err := json.Marshal(struct {
+		Image     string
+		Platform  string
+		RunscPath string
+		Resources ResourceSpec
+		Security  SecuritySpec
+	}{spec.Image, spec.Platform, spec.RunscPath, spec.Resources, spec.Security})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), nil
+}
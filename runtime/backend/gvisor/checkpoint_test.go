@@ -0,0 +1,171 @@
+package gvisor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// checkpointRunner records runs and writes checkpoint images on request.
+type checkpointRunner struct {
+	mu      sync.Mutex
+	created int
+	runs    []*Checkpoint
+	invalid bool
+}
+
+func (r *checkpointRunner) Run(_ context.Context, spec SandboxSpec) (SandboxResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, spec.Checkpoint)
+	if spec.Checkpoint != nil && r.invalid {
+		r.invalid = false
+		return SandboxResult{}, ErrCheckpointInvalid
+	}
+	return SandboxResult{Stdout: "ok"}, nil
+}
+
+func (r *checkpointRunner) Checkpoint(_ context.Context, _ SandboxSpec, dst Checkpoint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.created++
+	return os.WriteFile(filepath.Join(dst.ImagePath, "checkpoint.img"), []byte("state"), 0o600)
+}
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newCheckpointBackend(t *testing.T, runner *checkpointRunner, ttl time.Duration) (*Backend, *fakeClock) {
+	t.Helper()
+	b := New(Config{Client: runner, CheckpointDir: filepath.Join(t.TempDir(), "checkpoints"), CheckpointTTL: ttl})
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	b.checkpoints.now = clock.Now
+	return b, clock
+}
+
+func execute(t *testing.T, b *Backend, req runtime.ExecuteRequest) runtime.ExecuteResult {
+	t.Helper()
+	req.Gateway = &mockGateway{}
+	if req.Code == "" {
+		req.Code = "test"
+	}
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	return result
+}
+
+func TestBackendCheckpointRestore(t *testing.T) {
+	runner := &checkpointRunner{}
+	b, clock := newCheckpointBackend(t, runner, time.Hour)
+
+	first := execute(t, b, runtime.ExecuteRequest{})
+	execute(t, b, runtime.ExecuteRequest{})
+	if runner.created != 1 {
+		t.Errorf("created %d checkpoints, want 1", runner.created)
+	}
+	cp := runner.runs[1]
+	if cp == nil || !exists(filepath.Join(cp.ImagePath, "checkpoint.img")) {
+		t.Fatalf("second run checkpoint = %+v, want a stored image", cp)
+	}
+	if first.Backend.Details["checkpoint"] != cp.Key {
+		t.Errorf("Details = %v, want checkpoint %s", first.Backend.Details, cp.Key)
+	}
+	if !cp.ExpiresAt.Equal(clock.now.Add(time.Hour)) {
+		t.Errorf("ExpiresAt = %v, want CreatedAt plus the TTL", cp.ExpiresAt)
+	}
+
+	// A different sandbox shape gets its own checkpoint.
+	execute(t, b, runtime.ExecuteRequest{Limits: runtime.Limits{MemoryBytes: 64 << 20}})
+	if list, _ := b.Checkpoints(); len(list) != 2 {
+		t.Errorf("Checkpoints() = %v, want one per shape", list)
+	}
+
+	// An expired checkpoint is taken again on use.
+	clock.now = clock.now.Add(2 * time.Hour)
+	execute(t, b, runtime.ExecuteRequest{})
+	if runner.created != 3 {
+		t.Errorf("created %d checkpoints after expiry, want 3", runner.created)
+	}
+	if list, _ := b.Checkpoints(); len(list) != 1 {
+		t.Errorf("Checkpoints() = %v, want the expired shape pruned", list)
+	}
+}
+
+func TestBackendCheckpointInvalidStarts(t *testing.T) {
+	runner := &checkpointRunner{invalid: true}
+	b, _ := newCheckpointBackend(t, runner, 0)
+
+	result := execute(t, b, runtime.ExecuteRequest{})
+	if len(runner.runs) != 2 || runner.runs[0] == nil || runner.runs[1] != nil {
+		t.Errorf("runs = %v, want a failed restore followed by a new sandbox", runner.runs)
+	}
+	if _, ok := result.Backend.Details["checkpoint"]; ok {
+		t.Error("Details report a checkpoint for a new sandbox")
+	}
+	if list, _ := b.Checkpoints(); len(list) != 0 {
+		t.Errorf("invalid checkpoint kept: %v", list)
+	}
+}
+
+func TestBackendCheckpointSkipsStaging(t *testing.T) {
+	runner := &checkpointRunner{}
+	b, _ := newCheckpointBackend(t, runner, 0)
+
+	execute(t, b, runtime.ExecuteRequest{Workspace: &runtime.Workspace{}})
+	if runner.created != 0 || runner.runs[0] != nil {
+		t.Errorf("staged execution used a checkpoint: created=%d runs=%v", runner.created, runner.runs)
+	}
+}
+
+func TestBackendCheckpointManagement(t *testing.T) {
+	runner := &checkpointRunner{}
+	b, clock := newCheckpointBackend(t, runner, time.Minute)
+
+	execute(t, b, runtime.ExecuteRequest{})
+	execute(t, b, runtime.ExecuteRequest{Limits: runtime.Limits{PidsMax: 10}})
+	list, err := b.Checkpoints()
+	if err != nil || len(list) != 2 {
+		t.Fatalf("Checkpoints() = %v, %v; want 2", list, err)
+	}
+
+	if err := b.DeleteCheckpoint(list[0].Key); err != nil {
+		t.Fatalf("DeleteCheckpoint() error = %v", err)
+	}
+	for _, key := range []string{list[0].Key, "", "../x"} {
+		if err := b.DeleteCheckpoint(key); !errors.Is(err, ErrCheckpointNotFound) {
+			t.Errorf("DeleteCheckpoint(%q) error = %v, want ErrCheckpointNotFound", key, err)
+		}
+	}
+
+	if n, err := b.PruneCheckpoints(false); err != nil || n != 0 {
+		t.Errorf("PruneCheckpoints(false) = %d, %v; want nothing expired", n, err)
+	}
+	clock.now = clock.now.Add(time.Minute)
+	if n, err := b.PruneCheckpoints(false); err != nil || n != 1 {
+		t.Errorf("PruneCheckpoints(false) = %d, %v; want the expired checkpoint", n, err)
+	}
+
+	execute(t, b, runtime.ExecuteRequest{})
+	if n, err := b.PruneCheckpoints(true); err != nil || n != 1 {
+		t.Errorf("PruneCheckpoints(true) = %d, %v; want 1", n, err)
+	}
+
+	disabled := New(Config{Client: runner})
+	if list, err := disabled.Checkpoints(); list != nil || err != nil {
+		t.Errorf("Checkpoints() without CheckpointDir = %v, %v", list, err)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -12,6 +12,19 @@ type SandboxRunner interface {
 	Run(ctx context.Context, spec SandboxSpec) (SandboxResult, error)
 }
 
+// Checkpointer takes the runsc checkpoints that SandboxRunner restores. A
+// SandboxRunner that also implements Checkpointer enables checkpoint
+// restore when Config.CheckpointDir is set.
+//
+// Contract:
+// - Concurrency: Implementations must be safe for concurrent use.
+// - Context: Checkpoint must honor cancellation and deadlines.
+// - Ownership: Implementations must not mutate the provided spec or checkpoint.
+// - Files: Checkpoint starts a sandbox for spec, waits until it is ready to run commands, and runs runsc checkpoint --image-path=dst.ImagePath.
+type Checkpointer interface {
+	Checkpoint(ctx context.Context, spec SandboxSpec, dst Checkpoint) error
+}
+
 // HealthChecker can verify gVisor/runsc availability.
 type HealthChecker interface {
 	Ping(ctx context.Context) error
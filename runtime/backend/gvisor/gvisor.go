@@ -7,6 +7,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
@@ -28,10 +30,21 @@ var (
 	ErrClientNotConfigured = errors.New("gvisor runner not configured")
 
 	// ErrDaemonUnavailable is returned when runsc is not reachable.
-	ErrDaemonUnavailable = errors.New("gvisor daemon unavailable")
+	ErrDaemonUnavailable = fmt.Errorf("gvisor daemon unavailable: %w", runtime.ErrRuntimeUnavailable)
 
 	// ErrSecurityViolation is returned when a security policy is violated.
 	ErrSecurityViolation = errors.New("security policy violation")
+
+	// ErrCheckpointFailed is returned when a checkpoint cannot be taken.
+	ErrCheckpointFailed = errors.New("sandbox checkpoint failed")
+
+	// ErrCheckpointInvalid is returned by runners when a checkpoint cannot
+	// be restored, for example after a runsc upgrade. The backend deletes
+	// the checkpoint and starts the sandbox instead.
+	ErrCheckpointInvalid = errors.New("sandbox checkpoint invalid")
+
+	// ErrCheckpointNotFound is returned when a checkpoint does not exist.
+	ErrCheckpointNotFound = errors.New("sandbox checkpoint not found")
 )
 
 // Logger is the interface for logging.
@@ -73,6 +86,17 @@ type Config struct {
 	// If nil, Execute() returns ErrClientNotConfigured.
 	Client SandboxRunner
 
+	// CheckpointDir enables checkpoint restore when Client also implements
+	// Checkpointer. The first execution for each image and sandbox shape
+	// checkpoints a ready sandbox under CheckpointDir; later executions
+	// restore it. Executions that stage files always start a new sandbox.
+	CheckpointDir string
+
+	// CheckpointTTL is how long a checkpoint is restored before it is
+	// deleted and taken again. Zero keeps checkpoints until they are
+	// deleted with DeleteCheckpoint or PruneCheckpoints.
+	CheckpointTTL time.Duration
+
 	// ImageResolver optionally resolves/pulls images before execution.
 	ImageResolver ImageResolver
 
@@ -91,6 +115,7 @@ type Backend struct {
 	networkMode string
 	image       string
 	client      SandboxRunner
+	checkpoints *checkpointStore
 	resolver    ImageResolver
 	health      HealthChecker
 	logger      Logger
@@ -123,6 +148,11 @@ func New(cfg Config) *Backend {
 		networkMode = "none"
 	}
 
+	var checkpoints *checkpointStore
+	if creator, ok := cfg.Client.(Checkpointer); ok && cfg.CheckpointDir != "" {
+		checkpoints = &checkpointStore{dir: cfg.CheckpointDir, ttl: cfg.CheckpointTTL, creator: creator, now: time.Now}
+	}
+
 	return &Backend{
 		runscPath:   runscPath,
 		rootDir:     rootDir,
@@ -130,6 +160,7 @@ func New(cfg Config) *Backend {
 		networkMode: networkMode,
 		image:       image,
 		client:      cfg.Client,
+		checkpoints: checkpoints,
 		resolver:    cfg.ImageResolver,
 		health:      cfg.HealthChecker,
 		logger:      cfg.Logger,
@@ -141,6 +172,24 @@ func (b *Backend) Kind() runtime.BackendKind {
 	return runtime.BackendGVisor
 }
 
+// Capabilities implements runtime.CapabilityReporter. Dev executions get
+// Config.NetworkMode; the others get no network. Egress modes other than
+// deny-all are enforced only when the client is a runtime.EgressEnforcer,
+// and files are staged only when it is a runtime.FileStager.
+func (b *Backend) Capabilities() runtime.Capabilities {
+	modes := []string{"none"}
+	if b.networkMode != "none" {
+		modes = append(modes, b.networkMode)
+	}
+	return runtime.Capabilities{
+		Streaming:    true,
+		Workspace:    true,
+		FileStaging:  runtime.StagesFiles(b.client),
+		NetworkModes: modes,
+		EgressModes:  runtime.EnforcedEgressModes(b.client),
+	}
+}
+
 // Execute runs code with gVisor isolation.
 func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
 	if err := req.Validate(); err != nil {
@@ -149,6 +198,12 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 	if b.client == nil {
 		return runtime.ExecuteResult{}, ErrClientNotConfigured
 	}
+	if err := runtime.CheckEgress(req.Egress, runtime.EnforcedEgressModes(b.client)); err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+	if len(req.Files) > 0 && !runtime.StagesFiles(b.client) {
+		return runtime.ExecuteResult{}, fmt.Errorf("%w: file staging", runtime.ErrUnsupportedCapability)
+	}
 
 	timeout := req.Timeout
 	if timeout == 0 {
@@ -192,26 +247,36 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 			"networkMode", spec.Security.NetworkMode)
 	}
 
-	runResult, err := b.client.Run(ctx, spec)
+	runResult, cp, err := b.run(ctx, spec)
 	if err != nil {
 		return runtime.ExecuteResult{
 			Duration: time.Since(start),
 			Backend:  b.backendInfo(profile),
 		}, err
 	}
+	info := b.backendInfo(profile)
+	if cp != nil {
+		info.Details["checkpoint"] = cp.Key
+	}
 
+	usage := runResult.Usage
+	usage.WallTime = runResult.Duration
 	return runtime.ExecuteResult{
-		Value:    extractOutValue(runResult.Stdout),
-		Stdout:   runResult.Stdout,
-		Stderr:   runResult.Stderr,
-		Duration: runResult.Duration,
-		Backend:  b.backendInfo(profile),
+		Value:     extractOutValue(runResult.Stdout),
+		Stdout:    runResult.Stdout,
+		Stderr:    runResult.Stderr,
+		Truncated: runResult.Truncated,
+		Duration:  runResult.Duration,
+		Backend:   info,
+		Usage:     usage,
+		Artifacts: runResult.Artifacts,
 		LimitsEnforced: runtime.LimitsEnforced{
 			Timeout:    true,
 			Memory:     req.Limits.MemoryBytes > 0,
 			CPU:        req.Limits.CPUQuotaMillis > 0,
 			Pids:       req.Limits.PidsMax > 0,
 			Disk:       req.Limits.DiskBytes > 0,
+			Output:     req.MaxOutputBytes > 0,
 			ToolCalls:  true,
 			ChainSteps: true,
 		},
@@ -220,6 +285,67 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 
 var _ runtime.Backend = (*Backend)(nil)
 
+// Checkpoints lists the stored checkpoints, including expired ones not yet
+// pruned. It returns nil when checkpoints are disabled.
+func (b *Backend) Checkpoints() ([]Checkpoint, error) {
+	if b.checkpoints == nil {
+		return nil, nil
+	}
+	return b.checkpoints.list()
+}
+
+// DeleteCheckpoint deletes the checkpoint with key, so the next matching
+// execution takes a fresh one. It returns ErrCheckpointNotFound if there
+// is no such checkpoint.
+func (b *Backend) DeleteCheckpoint(key string) error {
+	if b.checkpoints == nil {
+		return fmt.Errorf("%w: %q", ErrCheckpointNotFound, key)
+	}
+	return b.checkpoints.remove(key)
+}
+
+// PruneCheckpoints deletes expired checkpoints, or every checkpoint when
+// all is set, and reports how many it deleted. Expired checkpoints are
+// also replaced on use and pruned whenever a new checkpoint is taken;
+// call it periodically to reclaim storage from images no longer in use.
+func (b *Backend) PruneCheckpoints(all bool) (int, error) {
+	if b.checkpoints == nil {
+		return 0, nil
+	}
+	return b.checkpoints.prune(all)
+}
+
+// run executes spec, restoring it from a checkpoint when checkpoints are
+// enabled and spec stages no files. It starts a new sandbox when the
+// checkpoint cannot be taken or restored, and reports the checkpoint
+// restored, if any.
+func (b *Backend) run(ctx context.Context, spec SandboxSpec) (SandboxResult, *Checkpoint, error) {
+	if b.checkpoints == nil || spec.Staging != nil {
+		result, err := b.client.Run(ctx, spec)
+		return result, nil, err
+	}
+	cp, err := b.checkpoints.ensure(ctx, spec)
+	if err != nil {
+		if b.logger != nil {
+			b.logger.Warn("gvisor checkpoint unavailable, starting", "error", err)
+		}
+		result, err := b.client.Run(ctx, spec)
+		return result, nil, err
+	}
+	restored := spec
+	restored.Checkpoint = cp
+	result, err := b.client.Run(ctx, restored)
+	if errors.Is(err, ErrCheckpointInvalid) {
+		if b.logger != nil {
+			b.logger.Warn("gvisor checkpoint invalid, starting", "checkpoint", cp.Key, "error", err)
+		}
+		_ = b.checkpoints.remove(cp.Key)
+		result, err := b.client.Run(ctx, spec)
+		return result, nil, err
+	}
+	return result, cp, err
+}
+
 func (b *Backend) backendInfo(profile runtime.SecurityProfile) runtime.BackendInfo {
 	return runtime.BackendInfo{
 		Kind:      runtime.BackendGVisor,
@@ -234,16 +360,42 @@ func (b *Backend) backendInfo(profile runtime.SecurityProfile) runtime.BackendIn
 
 func (b *Backend) buildSpec(image string, req runtime.ExecuteRequest, profile runtime.SecurityProfile) (SandboxSpec, error) {
 	opts := b.sandboxOptions(profile, req.Limits)
+	staging, err := req.Staging()
+	if err != nil {
+		return SandboxSpec{}, err
+	}
 
 	spec := SandboxSpec{
-		Image:     image,
-		Platform:  b.platform,
-		RunscPath: b.runscPath,
-		RootDir:   b.rootDir,
-		Resources: ResourceSpec{MemoryBytes: opts.MemoryLimit, CPUQuota: opts.CPUQuota, PidsLimit: opts.PidsLimit, DiskBytes: opts.DiskBytes},
-		Security:  SecuritySpec{User: opts.User, ReadOnlyRootfs: opts.ReadOnlyRootfs, NetworkMode: opts.NetworkMode},
-		Timeout:   req.Timeout,
-		Labels:    map[string]string{"runtime.profile": string(profile), "runtime.backend": string(runtime.BackendGVisor)},
+		Image:          image,
+		Platform:       b.platform,
+		RunscPath:      b.runscPath,
+		RootDir:        b.rootDir,
+		Resources:      ResourceSpec{MemoryBytes: opts.MemoryLimit, CPUQuota: opts.CPUQuota, PidsLimit: opts.PidsLimit, DiskBytes: opts.DiskBytes},
+		Security:       SecuritySpec{User: opts.User, ReadOnlyRootfs: opts.ReadOnlyRootfs, NetworkMode: opts.NetworkMode},
+		Timeout:        req.Timeout,
+		Labels:         map[string]string{"runtime.profile": string(profile), "runtime.backend": string(runtime.BackendGVisor), runtime.LabelExecutionID: req.EnsureExecutionID()},
+		MaxOutputBytes: req.MaxOutputBytes,
+		LogStreamer:    req.LogStreamer,
+	}
+	if egress := req.Egress; egress != nil {
+		// The policy replaces the profile's network mode; the runner
+		// filters the sandbox netstack's traffic.
+		spec.Security.NetworkMode = "none"
+		if egress.Mode != runtime.EgressDenyAll {
+			spec.Security.NetworkMode = "sandbox"
+			spec.Egress = egress
+			proxyEnv := egress.ProxyEnv()
+			for _, k := range slices.Sorted(maps.Keys(proxyEnv)) {
+				spec.Env = append(spec.Env, k+"="+proxyEnv[k])
+			}
+		}
+	}
+	if staging != nil {
+		spec.Staging = staging
+		spec.WorkingDir = staging.Dir
+		spec.Env = append(spec.Env,
+			runtime.WorkspaceEnv+"="+staging.Dir,
+			runtime.OutputEnv+"="+staging.OutputPath())
 	}
 
 	if err := spec.Validate(); err != nil {
@@ -3,11 +3,36 @@ package gvisor
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
 
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
 	"github.com/jonwraymond/toolexec/runtime"
 )
 
+type mockGateway struct{}
+
+func (m *mockGateway) SearchTools(_ context.Context, _ string, _ int) ([]index.Summary, error) {
+	return nil, nil
+}
+func (m *mockGateway) ListNamespaces(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+func (m *mockGateway) DescribeTool(_ context.Context, _ string, _ tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	return tooldoc.ToolDoc{}, nil
+}
+func (m *mockGateway) ListToolExamples(_ context.Context, _ string, _ int) ([]tooldoc.ToolExample, error) {
+	return nil, nil
+}
+func (m *mockGateway) RunTool(_ context.Context, _ string, _ map[string]any) (run.RunResult, error) {
+	return run.RunResult{}, nil
+}
+func (m *mockGateway) RunChain(_ context.Context, _ []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	return run.RunResult{}, nil, nil
+}
+
 func TestBackendImplementsInterface(t *testing.T) {
 	t.Helper()
 	var _ runtime.Backend = (*Backend)(nil)
@@ -45,3 +70,82 @@ func TestBackendRequiresGateway(t *testing.T) {
 		t.Errorf("Execute() without gateway error = %v, want %v", err, runtime.ErrMissingGateway)
 	}
 }
+
+func TestBackendBuildSpecStaging(t *testing.T) {
+	b := New(Config{})
+	req := runtime.ExecuteRequest{
+		Code:      "test",
+		Workspace: &runtime.Workspace{OutputDir: "results"},
+		Files:     map[string]runtime.File{"input.txt": {Data: []byte("data")}},
+	}
+
+	spec, err := b.buildSpec("img", req, runtime.ProfileStandard)
+	if err != nil {
+		t.Fatalf("buildSpec() error = %v", err)
+	}
+	if spec.Staging == nil || string(spec.Staging.Files["input.txt"]) != "data" || spec.Staging.OutputPath() != "/workspace/results" {
+		t.Errorf("Staging = %+v, want input.txt staged with /workspace/results collected", spec.Staging)
+	}
+	if spec.WorkingDir != runtime.DefaultWorkspacePath {
+		t.Errorf("WorkingDir = %q, want %q", spec.WorkingDir, runtime.DefaultWorkspacePath)
+	}
+
+	req.Workspace = nil
+	if spec, err := b.buildSpec("img", req, runtime.ProfileStandard); err != nil || spec.Staging != nil {
+		t.Errorf("buildSpec() without workspace = %+v, %v; want no staging", spec.Staging, err)
+	}
+}
+
+func TestBackendBuildSpecMaxOutputBytes(t *testing.T) {
+	b := New(Config{})
+	spec, err := b.buildSpec("img", runtime.ExecuteRequest{Code: "test", MaxOutputBytes: 1 << 20}, runtime.ProfileStandard)
+	if err != nil {
+		t.Fatalf("buildSpec() error = %v", err)
+	}
+	if spec.MaxOutputBytes != 1<<20 {
+		t.Errorf("MaxOutputBytes = %d, want %d", spec.MaxOutputBytes, 1<<20)
+	}
+}
+
+// egressRunner records the spec it runs and enforces Modes.
+type egressRunner struct {
+	got   SandboxSpec
+	Modes []runtime.EgressMode
+}
+
+func (r *egressRunner) Run(_ context.Context, spec SandboxSpec) (SandboxResult, error) {
+	r.got = spec
+	return SandboxResult{}, nil
+}
+
+func (r *egressRunner) EgressModes() []runtime.EgressMode { return r.Modes }
+
+func TestBackendEgress(t *testing.T) {
+	runner := &egressRunner{}
+	b := New(Config{Client: runner})
+	req := runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}, Egress: &runtime.EgressPolicy{Mode: runtime.EgressAllowlist, AllowCIDRs: []string{"10.0.0.0/8"}}}
+	if _, err := b.Execute(context.Background(), req); !errors.Is(err, runtime.ErrUnsupportedCapability) {
+		t.Fatalf("Execute(allowlist) error = %v, want %v", err, runtime.ErrUnsupportedCapability)
+	}
+
+	runner.Modes = []runtime.EgressMode{runtime.EgressAllowlist, runtime.EgressProxyOnly}
+	if modes := b.Capabilities().EgressModes; len(modes) != 3 {
+		t.Errorf("Capabilities().EgressModes = %v, want all three", modes)
+	}
+	req.Egress = &runtime.EgressPolicy{Mode: runtime.EgressProxyOnly, Proxy: "http://proxy:3128"}
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute(proxy-only) error = %v", err)
+	}
+	if got := runner.got; got.Security.NetworkMode != "sandbox" || got.Egress != req.Egress || !slices.Contains(got.Env, "HTTP_PROXY=http://proxy:3128") {
+		t.Errorf("proxy-only spec = %+v, want the sandbox network, the policy, and the proxy variables", got)
+	}
+
+	req.Profile = runtime.ProfileDev
+	req.Egress = &runtime.EgressPolicy{Mode: runtime.EgressDenyAll}
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute(deny-all) error = %v", err)
+	}
+	if got := runner.got; got.Security.NetworkMode != "none" || got.Egress != nil {
+		t.Errorf("deny-all spec network = %q, egress = %v; want none and no runner policy", got.Security.NetworkMode, got.Egress)
+	}
+}
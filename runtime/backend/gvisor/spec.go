@@ -1,6 +1,10 @@
 package gvisor
 
-import "time"
+import (
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
 
 // ResourceSpec defines sandbox resource limits.
 type ResourceSpec struct {
@@ -32,6 +36,32 @@ type SandboxSpec struct {
 	RootDir    string
 	Timeout    time.Duration
 	Labels     map[string]string
+	// MaxOutputBytes, if positive, caps the stdout and stderr kept from
+	// the sandbox. SandboxRunner implementations collect each stream
+	// through runtime.OutputBuffer and report the cut in
+	// SandboxResult.Truncated.
+	MaxOutputBytes int64
+	// LogStreamer, if set, receives stdout and stderr line by line while
+	// the sandbox runs. SandboxRunner implementations should call it as
+	// output arrives, for example through runtime.NewLineWriter.
+	LogStreamer runtime.LogStreamer
+	// Staging, if set, asks the runner to provide a writable workspace
+	// at Staging.Dir, write Staging.Files into it before the sandbox
+	// starts, and collect the output directory after it exits. Backends
+	// send Files only to runners that are runtime.FileStagers.
+	Staging *runtime.Staging
+	// Checkpoint, if set, asks the runner to runsc restore the sandbox
+	// from Checkpoint.ImagePath and run Command in it instead of starting
+	// a new sandbox. Runners return ErrCheckpointInvalid when the
+	// checkpoint cannot be restored.
+	Checkpoint *Checkpoint
+	// Egress, if set, is an allowlist or proxy-only policy restricting
+	// the sandbox's outbound traffic through its netstack (runsc
+	// --network=sandbox); the backend turns deny-all into NetworkMode
+	// "none" instead. SandboxRunner implementations that do not enforce
+	// it must fail rather than run the sandbox unrestricted, and
+	// advertise the modes they enforce as runtime.EgressEnforcer.
+	Egress *runtime.EgressPolicy
 }
 
 // SandboxResult captures the output of a gVisor execution.
@@ -40,4 +70,11 @@ type SandboxResult struct {
 	Stdout   string
 	Stderr   string
 	Duration time.Duration
+	// Truncated reports that output past MaxOutputBytes was discarded.
+	Truncated bool
+	// Usage reports the resources the sandbox consumed, read from its cgroup
+	// where the runner can. WallTime is filled in from Duration.
+	Usage runtime.ResourceUsage
+	// Artifacts holds the files collected from Staging's output directory.
+	Artifacts []runtime.Artifact
 }
@@ -3,6 +3,8 @@ package gvisor
 import (
 	"errors"
 	"fmt"
+
+	"github.com/jonwraymond/toolexec/runtime"
 )
 
 // Validate checks SandboxSpec for errors before execution.
@@ -16,6 +18,30 @@ func (s SandboxSpec) Validate() error {
 	if err := s.Resources.Validate(); err != nil {
 		return fmt.Errorf("resources: %w", err)
 	}
+	if s.Checkpoint != nil {
+		if err := s.Checkpoint.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Egress != nil {
+		if err := s.Egress.Validate(); err != nil {
+			return fmt.Errorf("egress: %w", err)
+		}
+		if s.Egress.Mode == runtime.EgressDenyAll {
+			return errors.New(`egress: deny-all is NetworkMode "none", not a runner policy`)
+		}
+		if s.Security.NetworkMode == "none" {
+			return fmt.Errorf("egress: %s policy needs a network", s.Egress.Mode)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the checkpoint locates its image.
+func (c Checkpoint) Validate() error {
+	if c.ImagePath == "" {
+		return errors.New("checkpoint imagePath is required")
+	}
 	return nil
 }
 
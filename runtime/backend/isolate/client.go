@@ -0,0 +1,44 @@
+package isolate
+
+import "context"
+
+// Runner is the primary interface for isolate execution.
+// Implementations may embed an engine, such as the V8 Runner in the
+// runtime/backend/isolate/v8runner module, or call a remote isolate
+// service.
+//
+// Implementations are expected to:
+//   - Run each execution in a fresh isolate and context
+//   - Install HostCallFunction and run Prelude before spec.Code
+//   - Enforce spec.Resources with a Watchdog
+//   - Return the __out value and captured output
+type Runner interface {
+	// Run executes code in an isolate and returns the result.
+	// The isolate lifecycle (create, run, dispose) is atomic.
+	//
+	// The implementation must:
+	//   - Validate the spec before execution
+	//   - Respect ctx cancellation
+	//   - Respect spec.Timeout if set
+	//   - Report exceeded limits as ErrHeapLimitExceeded or ErrCPUBudgetExceeded
+	//   - Report uncaught exceptions as ErrScriptFailed
+	Run(ctx context.Context, spec Spec) (Result, error)
+}
+
+// HealthChecker verifies isolate engine availability.
+// This is an optional interface - backends may skip health checks.
+type HealthChecker interface {
+	// Ping checks if the engine is operational.
+	Ping(ctx context.Context) error
+
+	// Info returns engine information.
+	Info(ctx context.Context) (RuntimeInfo, error)
+}
+
+// Transpiler converts TypeScript to JavaScript, such as an esbuild or
+// swc wrapper. This is an optional interface - without one, the backend
+// rejects TypeScript.
+type Transpiler interface {
+	// Transpile strips types from source and returns JavaScript.
+	Transpile(ctx context.Context, source string) (string, error)
+}
@@ -0,0 +1,232 @@
+package isolate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// HostCallFunction is the global function a Runner installs in each
+// context before running Prelude. It takes a host function name and a
+// JSON request string and returns the JSON response string from
+// HostBridge.Call. Prelude removes it from the global scope.
+const HostCallFunction = "__toolexec_call"
+
+// OutExpression evaluates to the JSON encoding of the script's __out
+// global, or undefined when it is unset. Runners evaluate it after the
+// script completes.
+const OutExpression = "JSON.stringify(globalThis.__out)"
+
+// Host functions callable through HostCallFunction. Failures, including
+// denied calls, are reported in the response as {"error": "..."}; Prelude
+// rethrows them as JavaScript errors the script can catch.
+const (
+	// HostSearchTools takes {"query", "limit"} and returns {"results"}.
+	HostSearchTools = "searchTools"
+
+	// HostListNamespaces takes {} and returns {"namespaces"}.
+	HostListNamespaces = "listNamespaces"
+
+	// HostDescribeTool takes {"id", "level"} and returns {"doc"}.
+	HostDescribeTool = "describeTool"
+
+	// HostListToolExamples takes {"id", "max"} and returns {"examples"}.
+	HostListToolExamples = "listToolExamples"
+
+	// HostRunTool takes {"id", "args"} and returns {"structured"}.
+	HostRunTool = "runTool"
+
+	// HostRunChain takes {"steps"} as run.ChainStep values and returns
+	// {"structured", "stepResults"}.
+	HostRunChain = "runChain"
+
+	// HostPrintln takes {"line"}, writes it to stdout, and returns {}.
+	HostPrintln = "println"
+)
+
+// HostFunctionNames lists every host function, in a stable order.
+func HostFunctionNames() []string {
+	return []string{
+		HostSearchTools, HostListNamespaces, HostDescribeTool, HostListToolExamples,
+		HostRunTool, HostRunChain, HostPrintln,
+	}
+}
+
+// Prelude defines the tools and console globals on top of
+// HostCallFunction, with the same signatures as the code/javascript
+// engine so snippets run unchanged on either.
+const Prelude = `(function (global) {
+  "use strict";
+  var call = global.` + HostCallFunction + `;
+  delete global.` + HostCallFunction + `;
+  function invoke(name, request) {
+    var response = JSON.parse(call(name, JSON.stringify(request)));
+    if (response.error) {
+      throw new Error(response.error);
+    }
+    return response;
+  }
+  function println() {
+    var parts = [];
+    for (var i = 0; i < arguments.length; i++) {
+      var arg = arguments[i];
+      parts.push(typeof arg === "string" ? arg : JSON.stringify(arg));
+    }
+    invoke("println", {line: parts.join(" ")});
+  }
+  global.tools = Object.freeze({
+    searchTools: function (query, limit) {
+      return invoke("searchTools", {query: query, limit: limit === undefined ? 10 : limit}).results;
+    },
+    listNamespaces: function () {
+      return invoke("listNamespaces", {}).namespaces;
+    },
+    describeTool: function (id, level) {
+      return invoke("describeTool", {id: id, level: level || "summary"}).doc;
+    },
+    listToolExamples: function (id, max) {
+      return invoke("listToolExamples", {id: id, max: max === undefined ? 5 : max}).examples;
+    },
+    runTool: function (id, args) {
+      return invoke("runTool", {id: id, args: args || {}}).structured;
+    },
+    runChain: function (steps) {
+      var response = invoke("runChain", {steps: steps});
+      return {structured: response.structured, stepResults: response.stepResults};
+    },
+    println: println
+  });
+  global.console = Object.freeze({log: println});
+})(globalThis);
+`
+
+// HostBridge serves host function calls from a script against a
+// ToolGateway. Runner implementations expose Call to scripts as
+// HostCallFunction.
+type HostBridge struct {
+	gateway runtime.ToolGateway
+	allowed []string
+	stdout  io.Writer
+}
+
+// NewHostBridge creates a bridge for spec, writing println output to
+// stdout. Only functions listed in spec.Security.AllowedHostFunctions may
+// be called.
+func NewHostBridge(spec Spec, stdout io.Writer) *HostBridge {
+	return &HostBridge{
+		gateway: spec.Gateway,
+		allowed: spec.Security.AllowedHostFunctions,
+		stdout:  stdout,
+	}
+}
+
+// Allowed reports whether the script may call the named host function.
+func (b *HostBridge) Allowed(name string) bool {
+	return slices.Contains(b.allowed, name)
+}
+
+// Call runs the named host function with a JSON request and returns the
+// JSON response.
+func (b *HostBridge) Call(ctx context.Context, name string, request []byte) []byte {
+	payload, err := b.call(ctx, name, request)
+	if err != nil {
+		payload = map[string]any{"error": err.Error()}
+	}
+	resp, err := json.Marshal(payload)
+	if err != nil {
+		resp, _ = json.Marshal(map[string]any{"error": fmt.Sprintf("encode response: %v", err)})
+	}
+	return resp
+}
+
+func (b *HostBridge) call(ctx context.Context, name string, request []byte) (map[string]any, error) {
+	if !slices.Contains(HostFunctionNames(), name) {
+		return nil, fmt.Errorf("unknown host function %q", name)
+	}
+	if !b.Allowed(name) {
+		return nil, fmt.Errorf("%w: %s", ErrHostFunctionDenied, name)
+	}
+	if name != HostPrintln && b.gateway == nil {
+		return nil, runtime.ErrMissingGateway
+	}
+
+	var req struct {
+		Query string          `json:"query"`
+		Limit int             `json:"limit"`
+		ID    string          `json:"id"`
+		Level string          `json:"level"`
+		Max   int             `json:"max"`
+		Args  map[string]any  `json:"args"`
+		Steps []run.ChainStep `json:"steps"`
+		Line  string          `json:"line"`
+	}
+	if err := json.Unmarshal(request, &req); err != nil {
+		return nil, fmt.Errorf("decode request: %w", err)
+	}
+
+	switch name {
+	case HostSearchTools:
+		results, err := b.gateway.SearchTools(ctx, req.Query, req.Limit)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"results": results}, nil
+
+	case HostListNamespaces:
+		namespaces, err := b.gateway.ListNamespaces(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"namespaces": namespaces}, nil
+
+	case HostDescribeTool:
+		doc, err := b.gateway.DescribeTool(ctx, req.ID, tooldoc.DetailLevel(req.Level))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"doc": doc}, nil
+
+	case HostListToolExamples:
+		examples, err := b.gateway.ListToolExamples(ctx, req.ID, req.Max)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"examples": examples}, nil
+
+	case HostRunTool:
+		result, err := b.gateway.RunTool(ctx, req.ID, req.Args)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"structured": result.Structured}, nil
+
+	case HostRunChain:
+		result, steps, err := b.gateway.RunChain(ctx, req.Steps)
+		stepResults := make([]map[string]any, len(steps))
+		for i, s := range steps {
+			stepResults[i] = map[string]any{"toolId": s.ToolID, "structured": s.Result.Structured}
+			if s.Err != nil {
+				stepResults[i]["error"] = s.Err.Error()
+			}
+		}
+		resp := map[string]any{"structured": result.Structured, "stepResults": stepResults}
+		if err != nil {
+			resp["error"] = err.Error()
+		}
+		return resp, nil
+
+	default: // HostPrintln
+		if b.stdout != nil {
+			if _, err := io.WriteString(b.stdout, req.Line+"\n"); err != nil {
+				return nil, err
+			}
+		}
+		return map[string]any{}, nil
+	}
+}
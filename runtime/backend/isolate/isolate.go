@@ -0,0 +1,336 @@
+// Package isolate provides a backend that executes JavaScript and
+// TypeScript in V8-style isolates: lightweight, in-process heaps that
+// start in milliseconds, without containers.
+//
+// Each execution gets a fresh isolate from a Runner, such as the V8
+// Runner in the runtime/backend/isolate/v8runner module. The isolate sees
+// only the tools and console globals defined by Prelude, backed by a
+// HostBridge to the request's gateway. A Watchdog enforces a per-isolate
+// heap limit and CPU budget by terminating the isolate.
+//
+//	runner := v8runner.New(v8runner.Config{Warm: 4})
+//	defer runner.Close()
+//	backend := isolate.New(isolate.Config{
+//		Client:         runner,
+//		HeapLimitBytes: 64 << 20,
+//	})
+//
+// Snippets use the code/javascript conventions: tools.runTool and friends
+// are synchronous, and the result is the value assigned to __out.
+package isolate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// Errors for isolate backend operations.
+var (
+	// ErrIsolateNotAvailable is returned when the isolate engine is not available.
+	ErrIsolateNotAvailable = fmt.Errorf("isolate engine not available: %w", runtime.ErrRuntimeUnavailable)
+
+	// ErrClientNotConfigured is returned when no Runner is configured.
+	ErrClientNotConfigured = errors.New("isolate client not configured")
+
+	// ErrUnsupportedLanguage is returned when the language is not
+	// JavaScript, or is TypeScript without a Transpiler.
+	ErrUnsupportedLanguage = errors.New("language not supported by isolate backend")
+
+	// ErrTranspileFailed is returned when TypeScript transpilation fails.
+	ErrTranspileFailed = errors.New("typescript transpilation failed")
+
+	// ErrScriptFailed is returned when the script throws an uncaught exception.
+	ErrScriptFailed = errors.New("script execution failed")
+
+	// ErrHeapLimitExceeded is returned when the isolate exceeds its heap limit.
+	ErrHeapLimitExceeded = errors.New("isolate heap limit exceeded")
+
+	// ErrCPUBudgetExceeded is returned when the isolate exceeds its CPU budget.
+	ErrCPUBudgetExceeded = errors.New("isolate cpu budget exceeded")
+
+	// ErrHostFunctionDenied is reported when a script calls a host function
+	// that SecuritySpec.AllowedHostFunctions does not list.
+	ErrHostFunctionDenied = errors.New("host function not allowed")
+)
+
+// DefaultHeapLimitBytes is the heap limit used when neither
+// Config.HeapLimitBytes nor Limits.MemoryBytes is set.
+const DefaultHeapLimitBytes int64 = 64 << 20
+
+// Logger is the interface for logging.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Errors: logging must be best-effort and must not panic.
+type Logger interface {
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// Config configures an isolate backend.
+type Config struct {
+	// Engine names the isolate engine for reporting.
+	// Default: v8
+	Engine string
+
+	// HeapLimitBytes is the per-isolate heap limit. Limits.MemoryBytes
+	// overrides it per execution.
+	// Default: DefaultHeapLimitBytes
+	HeapLimitBytes int64
+
+	// WatchdogInterval is how often isolates are sampled for heap and
+	// CPU usage.
+	// Default: DefaultWatchdogInterval
+	WatchdogInterval time.Duration
+
+	// AllowedHostFunctions lists host functions scripts can call.
+	// Default: all of HostFunctionNames. The hardened profile allows
+	// only HostRunTool, HostRunChain, and HostPrintln.
+	AllowedHostFunctions []string
+
+	// Transpiler converts TypeScript to JavaScript.
+	// If nil, TypeScript requests return ErrUnsupportedLanguage.
+	Transpiler Transpiler
+
+	// Client is the isolate runner implementation.
+	// If nil, Execute() returns ErrClientNotConfigured.
+	Client Runner
+
+	// HealthChecker optionally verifies engine health.
+	// If nil, health checks are skipped.
+	HealthChecker HealthChecker
+
+	// Logger is an optional logger for backend events.
+	Logger Logger
+}
+
+// Backend executes JavaScript in isolates.
+type Backend struct {
+	engine               string
+	heapLimitBytes       int64
+	watchdogInterval     time.Duration
+	allowedHostFunctions []string
+	transpiler           Transpiler
+	client               Runner
+	healthChecker        HealthChecker
+	logger               Logger
+}
+
+// New creates a new isolate backend with the given configuration.
+func New(cfg Config) *Backend {
+	engine := cfg.Engine
+	if engine == "" {
+		engine = "v8"
+	}
+
+	heapLimitBytes := cfg.HeapLimitBytes
+	if heapLimitBytes <= 0 {
+		heapLimitBytes = DefaultHeapLimitBytes
+	}
+
+	allowed := cfg.AllowedHostFunctions
+	if allowed == nil {
+		allowed = HostFunctionNames()
+	}
+
+	return &Backend{
+		engine:               engine,
+		heapLimitBytes:       heapLimitBytes,
+		watchdogInterval:     cfg.WatchdogInterval,
+		allowedHostFunctions: allowed,
+		transpiler:           cfg.Transpiler,
+		client:               cfg.Client,
+		healthChecker:        cfg.HealthChecker,
+		logger:               cfg.Logger,
+	}
+}
+
+// Kind returns the backend kind identifier.
+func (b *Backend) Kind() runtime.BackendKind {
+	return runtime.BackendIsolate
+}
+
+// Capabilities implements runtime.CapabilityReporter. TypeScript needs
+// Config.Transpiler.
+func (b *Backend) Capabilities() runtime.Capabilities {
+	languages := []string{"javascript", "js"}
+	if b.transpiler != nil {
+		languages = append(languages, "typescript", "ts")
+	}
+	return runtime.Capabilities{
+		Languages:    languages,
+		NetworkModes: []string{"none"},
+	}
+}
+
+// Execute runs JavaScript or TypeScript in a fresh isolate.
+func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
+	if err := req.Validate(); err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+	if b.client == nil {
+		return runtime.ExecuteResult{}, ErrClientNotConfigured
+	}
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	profile := req.Profile
+	if profile == "" {
+		profile = runtime.ProfileStandard
+	}
+
+	if b.healthChecker != nil {
+		if err := b.healthChecker.Ping(ctx); err != nil {
+			return runtime.ExecuteResult{}, fmt.Errorf("%w: %v", ErrIsolateNotAvailable, err)
+		}
+	}
+
+	source, err := b.source(ctx, req)
+	if err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+
+	spec := b.buildSpec(req, profile)
+	spec.Code = source
+	spec.Timeout = timeout
+
+	if b.logger != nil {
+		b.logger.Info("executing in isolate",
+			"profile", profile,
+			"engine", b.engine,
+			"heapLimitBytes", spec.Resources.HeapLimitBytes,
+			"cpuBudget", spec.Resources.CPUBudget)
+	}
+
+	start := time.Now()
+	result, err := b.client.Run(ctx, spec)
+	info := b.backendInfo(profile, spec, result)
+	usage := runtime.ResourceUsage{
+		CPUTime:         result.Usage.CPUTime,
+		PeakMemoryBytes: result.Usage.PeakHeapBytes,
+		WallTime:        result.Duration,
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, runtime.ErrTimeout):
+			err = fmt.Errorf("%w: %v", runtime.ErrTimeout, err)
+		case (errors.Is(err, ErrHeapLimitExceeded) || errors.Is(err, ErrCPUBudgetExceeded)) &&
+			!errors.Is(err, runtime.ErrResourceLimit):
+			err = fmt.Errorf("%w: %w", runtime.ErrResourceLimit, err)
+		}
+		return runtime.ExecuteResult{
+			Stdout:   result.Stdout,
+			Duration: time.Since(start),
+			Backend:  info,
+			Usage:    usage,
+		}, err
+	}
+
+	return runtime.ExecuteResult{
+		Value:    result.Value,
+		Stdout:   result.Stdout,
+		Duration: result.Duration,
+		Backend:  info,
+		Usage:    usage,
+		LimitsEnforced: runtime.LimitsEnforced{
+			Timeout:    true,
+			Memory:     spec.Resources.HeapLimitBytes > 0,
+			CPU:        spec.Resources.CPUBudget > 0,
+			Pids:       false, // Isolates have no process model
+			ToolCalls:  true,  // Enforced by gateway
+			ChainSteps: true,  // Enforced by gateway
+		},
+	}, nil
+}
+
+// source returns the request's code as JavaScript, transpiling TypeScript.
+func (b *Backend) source(ctx context.Context, req runtime.ExecuteRequest) (string, error) {
+	switch strings.ToLower(req.Language) {
+	case "", "javascript", "js":
+		return req.Code, nil
+	case "typescript", "ts":
+		if b.transpiler == nil {
+			return "", fmt.Errorf("%w: %q requires a transpiler", ErrUnsupportedLanguage, req.Language)
+		}
+		source, err := b.transpiler.Transpile(ctx, req.Code)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrTranspileFailed, err)
+		}
+		return source, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedLanguage, req.Language)
+	}
+}
+
+// buildSpec creates a Spec from an ExecuteRequest.
+func (b *Backend) buildSpec(req runtime.ExecuteRequest, profile runtime.SecurityProfile) Spec {
+	spec := Spec{
+		Gateway: req.Gateway,
+		Resources: ResourceSpec{
+			HeapLimitBytes:   b.heapLimitBytes,
+			WatchdogInterval: b.watchdogInterval,
+		},
+		Security: SecuritySpec{
+			AllowedHostFunctions: b.allowedHostFunctions,
+		},
+		Labels: map[string]string{
+			"runtime.profile": string(profile),
+			"runtime.backend": string(runtime.BackendIsolate),
+		},
+	}
+
+	// Hardened: no tool discovery, only the calls the snippet names
+	if profile == runtime.ProfileHardened {
+		var allowed []string
+		for _, name := range spec.Security.AllowedHostFunctions {
+			switch name {
+			case HostRunTool, HostRunChain, HostPrintln:
+				allowed = append(allowed, name)
+			}
+		}
+		spec.Security.AllowedHostFunctions = allowed
+	}
+
+	// Apply resource limits from request
+	if req.Limits.MemoryBytes > 0 {
+		spec.Resources.HeapLimitBytes = req.Limits.MemoryBytes
+	}
+	if req.Limits.CPUQuotaMillis > 0 {
+		spec.Resources.CPUBudget = time.Duration(req.Limits.CPUQuotaMillis) * time.Millisecond
+	}
+
+	return spec
+}
+
+// backendInfo returns BackendInfo for an execution.
+func (b *Backend) backendInfo(profile runtime.SecurityProfile, spec Spec, result Result) runtime.BackendInfo {
+	details := map[string]any{
+		"engine":         b.engine,
+		"profile":        string(profile),
+		"heapLimitBytes": spec.Resources.HeapLimitBytes,
+	}
+	if spec.Resources.CPUBudget > 0 {
+		details["cpuBudget"] = spec.Resources.CPUBudget.String()
+	}
+	if result.StartupDuration > 0 {
+		details["startup"] = result.StartupDuration.String()
+	}
+	return runtime.BackendInfo{
+		Kind:      runtime.BackendIsolate,
+		Readiness: runtime.ReadinessBeta,
+		Details:   details,
+	}
+}
+
+var _ runtime.Backend = (*Backend)(nil)
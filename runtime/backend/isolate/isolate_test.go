@@ -0,0 +1,290 @@
+package isolate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+type mockGateway struct{}
+
+func (m *mockGateway) SearchTools(_ context.Context, _ string, _ int) ([]index.Summary, error) {
+	return nil, nil
+}
+func (m *mockGateway) ListNamespaces(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+func (m *mockGateway) DescribeTool(_ context.Context, _ string, _ tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	return tooldoc.ToolDoc{}, nil
+}
+func (m *mockGateway) ListToolExamples(_ context.Context, _ string, _ int) ([]tooldoc.ToolExample, error) {
+	return nil, nil
+}
+func (m *mockGateway) RunTool(_ context.Context, _ string, _ map[string]any) (run.RunResult, error) {
+	return run.RunResult{}, nil
+}
+func (m *mockGateway) RunChain(_ context.Context, _ []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	return run.RunResult{}, nil, nil
+}
+
+// scriptRunner is a Runner whose scripts are Go functions standing in for
+// the JavaScript in spec.Code. It calls host functions through a
+// HostBridge and runs scripts under a Watchdog, the way an engine Runner
+// does.
+type scriptRunner struct {
+	scripts map[string]script
+	seen    Spec
+}
+
+// script runs a snippet: it calls host functions through call and returns
+// the value of __out, or stops once terminated is closed.
+type script func(call func(name string, request map[string]any) (map[string]any, error), terminated <-chan struct{}) (any, error)
+
+type scriptProbe struct {
+	once       sync.Once
+	terminated chan struct{}
+}
+
+func (p *scriptProbe) HeapUsed() int64 { return 0 }
+func (p *scriptProbe) Terminate()      { p.once.Do(func() { close(p.terminated) }) }
+
+func (r *scriptRunner) Run(ctx context.Context, spec Spec) (Result, error) {
+	r.seen = spec
+	if err := spec.Validate(); err != nil {
+		return Result{}, err
+	}
+	start := time.Now()
+	var stdout strings.Builder
+	bridge := NewHostBridge(spec, &stdout)
+	probe := &scriptProbe{terminated: make(chan struct{})}
+
+	wd := StartWatchdog(ctx, probe, spec.Resources)
+	call := func(name string, request map[string]any) (map[string]any, error) {
+		wd.Pause()
+		defer wd.Resume()
+		data, _ := json.Marshal(request)
+		var resp map[string]any
+		if err := json.Unmarshal(bridge.Call(ctx, name, data), &resp); err != nil {
+			return nil, err
+		}
+		if msg, ok := resp["error"].(string); ok {
+			return nil, errors.New(msg)
+		}
+		return resp, nil
+	}
+	var value any
+	fn, ok := r.scripts[spec.Code]
+	runErr := fmt.Errorf("ReferenceError: no script for %q", spec.Code)
+	if ok {
+		value, runErr = fn(call, probe.terminated)
+	}
+	// __out is returned as JSON, as OutExpression does.
+	if data, err := json.Marshal(value); runErr == nil && err == nil {
+		_ = json.Unmarshal(data, &value)
+	}
+	usage, err := wd.Stop()
+	result := Result{Value: value, Stdout: stdout.String(), Duration: time.Since(start), Usage: usage}
+	if err != nil {
+		return result, err
+	}
+	if runErr != nil {
+		return result, errors.Join(ErrScriptFailed, runErr)
+	}
+	return result, nil
+}
+
+// spin is `for (;;) {}`.
+func spin(_ func(string, map[string]any) (map[string]any, error), terminated <-chan struct{}) (any, error) {
+	<-terminated
+	return nil, errors.New("terminated")
+}
+
+// one is `__out = 1`.
+func one(func(string, map[string]any) (map[string]any, error), <-chan struct{}) (any, error) {
+	return 1, nil
+}
+
+type echoGateway struct {
+	mockGateway
+	toolID string
+}
+
+func (g *echoGateway) RunTool(_ context.Context, id string, args map[string]any) (run.RunResult, error) {
+	g.toolID = id
+	time.Sleep(20 * time.Millisecond)
+	return run.RunResult{Structured: args["x"]}, nil
+}
+
+func (g *echoGateway) ListNamespaces(_ context.Context) ([]string, error) {
+	return []string{"ns"}, nil
+}
+
+func TestBackendKind(t *testing.T) {
+	b := New(Config{})
+	if b.Kind() != runtime.BackendIsolate {
+		t.Errorf("Kind() = %v, want %v", b.Kind(), runtime.BackendIsolate)
+	}
+	_, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "1", Gateway: &mockGateway{}})
+	if !errors.Is(err, ErrClientNotConfigured) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrClientNotConfigured)
+	}
+}
+
+func TestBackendExecute(t *testing.T) {
+	gw := &echoGateway{}
+	src := `console.log("ns:", tools.listNamespaces());
+__out = {value: tools.runTool("ns:echo", {x: 42})};`
+	runner := &scriptRunner{scripts: map[string]script{
+		src: func(call func(string, map[string]any) (map[string]any, error), _ <-chan struct{}) (any, error) {
+			ns, err := call(HostListNamespaces, nil)
+			if err != nil {
+				return nil, err
+			}
+			list, _ := json.Marshal(ns["namespaces"])
+			if _, err := call(HostPrintln, map[string]any{"line": "ns: " + string(list)}); err != nil {
+				return nil, err
+			}
+			res, err := call(HostRunTool, map[string]any{"id": "ns:echo", "args": map[string]any{"x": 42}})
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"value": res["structured"]}, nil
+		},
+	}}
+	b := New(Config{Client: runner})
+	result, err := b.Execute(context.Background(), runtime.ExecuteRequest{
+		Code:    src,
+		Gateway: gw,
+		Limits:  runtime.Limits{MemoryBytes: 32 << 20, CPUQuotaMillis: 10},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if out, _ := result.Value.(map[string]any); out["value"] != 42.0 || gw.toolID != "ns:echo" {
+		t.Errorf("Value = %v (tool %q), want {value: 42} from ns:echo", result.Value, gw.toolID)
+	}
+	if result.Stdout != "ns: [\"ns\"]\n" {
+		t.Errorf("Stdout = %q", result.Stdout)
+	}
+	// The tool call sleeps past the CPU budget; the watchdog excludes it.
+	if result.Usage.CPUTime >= 20*time.Millisecond {
+		t.Errorf("Usage.CPUTime = %v, want host call time excluded", result.Usage.CPUTime)
+	}
+	if res := runner.seen.Resources; res.HeapLimitBytes != 32<<20 || res.CPUBudget != 10*time.Millisecond {
+		t.Errorf("Resources = %+v", res)
+	}
+	if !result.LimitsEnforced.Memory || !result.LimitsEnforced.CPU || result.Backend.Details["engine"] != "v8" {
+		t.Errorf("LimitsEnforced = %+v, Backend = %+v", result.LimitsEnforced, result.Backend)
+	}
+}
+
+func TestBackendCPUBudget(t *testing.T) {
+	b := New(Config{Client: &scriptRunner{scripts: map[string]script{"for (;;) {}": spin}}, WatchdogInterval: time.Millisecond})
+	_, err := b.Execute(context.Background(), runtime.ExecuteRequest{
+		Code:    `for (;;) {}`,
+		Gateway: &mockGateway{},
+		Limits:  runtime.Limits{CPUQuotaMillis: 20},
+	})
+	if !errors.Is(err, ErrCPUBudgetExceeded) || !errors.Is(err, runtime.ErrResourceLimit) {
+		t.Errorf("Execute() error = %v, want %v and %v", err, ErrCPUBudgetExceeded, runtime.ErrResourceLimit)
+	}
+
+	_, err = b.Execute(context.Background(), runtime.ExecuteRequest{
+		Code:    `for (;;) {}`,
+		Gateway: &mockGateway{},
+		Timeout: 20 * time.Millisecond,
+	})
+	if !errors.Is(err, runtime.ErrTimeout) {
+		t.Errorf("Execute() error = %v, want %v", err, runtime.ErrTimeout)
+	}
+}
+
+type upperTranspiler struct{}
+
+func (upperTranspiler) Transpile(_ context.Context, source string) (string, error) {
+	if strings.Contains(source, "!") {
+		return "", errors.New("unexpected token")
+	}
+	return strings.ReplaceAll(source, ": number", ""), nil
+}
+
+func TestBackendLanguages(t *testing.T) {
+	tests := []struct {
+		name       string
+		language   string
+		code       string
+		transpiler Transpiler
+		want       error
+	}{
+		{"javascript", "js", "__out = 1", nil, nil},
+		{"typescript", "typescript", "const x: number = 1; __out = x", upperTranspiler{}, nil},
+		{"typescript without transpiler", "ts", "__out = 1", nil, ErrUnsupportedLanguage},
+		{"transpile error", "ts", "!", upperTranspiler{}, ErrTranspileFailed},
+		{"python", "python", "print(1)", nil, ErrUnsupportedLanguage},
+		{"uncaught exception", "", `throw new Error("boom")`, nil, ErrScriptFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &scriptRunner{scripts: map[string]script{
+				"__out = 1":              one,
+				"const x = 1; __out = x": one,
+				`throw new Error("boom")`: func(func(string, map[string]any) (map[string]any, error), <-chan struct{}) (any, error) {
+					return nil, errors.New("Error: boom")
+				},
+			}}
+			b := New(Config{Client: runner, Transpiler: tt.transpiler})
+			result, err := b.Execute(context.Background(), runtime.ExecuteRequest{Language: tt.language, Code: tt.code, Gateway: &mockGateway{}})
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Errorf("Execute() error = %v, want %v", err, tt.want)
+				}
+				return
+			}
+			if err != nil || result.Value != 1.0 {
+				t.Errorf("Execute() = %v, %v; want 1", result.Value, err)
+			}
+		})
+	}
+}
+
+func TestBackendHardenedProfile(t *testing.T) {
+	runner := &scriptRunner{scripts: map[string]script{
+		`tools.searchTools("x")`: func(call func(string, map[string]any) (map[string]any, error), _ <-chan struct{}) (any, error) {
+			_, err := call(HostSearchTools, map[string]any{"query": "x", "limit": 10})
+			return nil, err
+		},
+	}}
+	b := New(Config{Client: runner})
+	_, err := b.Execute(context.Background(), runtime.ExecuteRequest{
+		Code:    `tools.searchTools("x")`,
+		Gateway: &mockGateway{},
+		Profile: runtime.ProfileHardened,
+	})
+	if err == nil || !strings.Contains(err.Error(), ErrHostFunctionDenied.Error()) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrHostFunctionDenied)
+	}
+	want := []string{HostRunTool, HostRunChain, HostPrintln}
+	if got := runner.seen.Security.AllowedHostFunctions; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("AllowedHostFunctions = %v, want %v", got, want)
+	}
+}
+
+func TestBackendCapabilities(t *testing.T) {
+	req := runtime.ExecuteRequest{Language: "typescript"}
+	if err := New(Config{}).Capabilities().Check(req); !errors.Is(err, runtime.ErrUnsupportedCapability) {
+		t.Errorf("Check() without a transpiler error = %v, want %v", err, runtime.ErrUnsupportedCapability)
+	}
+	if err := New(Config{Transpiler: upperTranspiler{}}).Capabilities().Check(req); err != nil {
+		t.Errorf("Check() with a transpiler error = %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package isolate
+
+import (
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// Spec defines what to execute in an isolate and how.
+type Spec struct {
+	// Code is the JavaScript source to run (required). TypeScript has
+	// already been transpiled by the backend.
+	Code string
+
+	// Resources defines the per-isolate limits the Watchdog enforces.
+	Resources ResourceSpec
+
+	// Security defines security settings.
+	Security SecuritySpec
+
+	// Timeout is the maximum execution duration.
+	Timeout time.Duration
+
+	// Labels are metadata labels for tracking.
+	Labels map[string]string
+
+	// Gateway serves the host functions listed in
+	// Security.AllowedHostFunctions; see HostBridge.
+	Gateway runtime.ToolGateway
+}
+
+// ResourceSpec defines isolate resource limits.
+type ResourceSpec struct {
+	// HeapLimitBytes is the maximum used heap size of the isolate.
+	// Zero means unlimited.
+	HeapLimitBytes int64
+
+	// CPUBudget is the maximum time the isolate may spend running
+	// script, excluding time blocked in host functions.
+	// Zero means unlimited.
+	CPUBudget time.Duration
+
+	// WatchdogInterval is how often the watchdog samples the isolate.
+	// Zero uses DefaultWatchdogInterval.
+	WatchdogInterval time.Duration
+}
+
+// SecuritySpec defines isolate security settings.
+type SecuritySpec struct {
+	// AllowedHostFunctions lists host functions the script can call, by
+	// name (see HostFunctionNames). Empty means no host functions allowed.
+	AllowedHostFunctions []string
+}
+
+// Result captures the output of isolate execution.
+type Result struct {
+	// Value is the JSON-decoded value of the script's __out global.
+	Value any
+
+	// Stdout contains the script's tools.println and console.log output.
+	Stdout string
+
+	// Duration is the execution time, including isolate startup.
+	Duration time.Duration
+
+	// StartupDuration is the time taken to acquire an isolate and
+	// create its context.
+	StartupDuration time.Duration
+
+	// Usage reports the CPU time and peak heap the watchdog observed.
+	Usage Usage
+}
+
+// RuntimeInfo contains isolate engine metadata.
+type RuntimeInfo struct {
+	// Name is the engine name, such as "v8".
+	Name string
+
+	// Version is the engine version.
+	Version string
+}
@@ -0,0 +1,23 @@
+module github.com/jonwraymond/toolexec/runtime/backend/isolate/v8runner
+
+go 1.25.7
+
+require (
+	github.com/jonwraymond/tooldiscovery v0.3.0
+	github.com/jonwraymond/toolexec v0.3.0
+	rogchap.com/v8go v0.9.0
+)
+
+require (
+	github.com/google/jsonschema-go v0.4.2 // indirect
+	github.com/jonwraymond/toolfoundation v0.3.0 // indirect
+	github.com/modelcontextprotocol/go-sdk v1.2.0 // indirect
+	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
+	golang.org/x/oauth2 v0.34.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// Build against the enclosing checkout of toolexec. Consumers ignore this
+// replace and need the toolexec release required above, so tag it before
+// tagging this module.
+replace github.com/jonwraymond/toolexec => ../../../..
@@ -0,0 +1,255 @@
+// Package v8runner provides an isolate.Runner that embeds V8 through
+// v8go.
+//
+// It is a separate module so that the core toolexec module does not
+// require cgo or the V8 static library; import it only when running the
+// isolate backend:
+//
+//	runner := v8runner.New(v8runner.Config{Warm: 4})
+//	defer runner.Close()
+//	backend := isolate.New(isolate.Config{Client: runner})
+//
+// Every execution runs in its own isolate, which is disposed afterwards;
+// isolates are never reused across executions. Creating an isolate takes
+// a few milliseconds, so Config.Warm keeps that many created ahead of
+// time to take it off the execution path.
+//
+// v8go cannot configure V8's heap limit, so the isolate.Watchdog samples
+// the used heap size and terminates the isolate once it exceeds
+// ResourceSpec.HeapLimitBytes. A script can overshoot the limit by what
+// it allocates within one watchdog interval.
+package v8runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime/backend/isolate"
+	v8 "rogchap.com/v8go"
+)
+
+// Config configures a Runner.
+type Config struct {
+	// Warm is the number of idle isolates kept ready for executions.
+	// Zero creates each isolate when an execution starts.
+	Warm int
+}
+
+// Runner runs scripts in V8 isolates. It implements isolate.Runner and
+// isolate.HealthChecker.
+//
+// Contract:
+// - Concurrency: safe for concurrent use; each Run uses its own isolate.
+// - Lifecycle: Close stops the warm pool and disposes idle isolates.
+type Runner struct {
+	warm chan *v8.Isolate
+	stop chan struct{}
+	wg   sync.WaitGroup
+
+	closeOnce sync.Once
+}
+
+// New creates a Runner and, if cfg.Warm is positive, starts filling its
+// warm pool.
+func New(cfg Config) *Runner {
+	r := &Runner{stop: make(chan struct{})}
+	if cfg.Warm > 0 {
+		r.warm = make(chan *v8.Isolate, cfg.Warm)
+		r.wg.Add(1)
+		go r.fill()
+	}
+	return r
+}
+
+// fill keeps the warm pool full until Close.
+func (r *Runner) fill() {
+	defer r.wg.Done()
+	for {
+		iso := v8.NewIsolate()
+		select {
+		case r.warm <- iso:
+		case <-r.stop:
+			iso.Dispose()
+			return
+		}
+	}
+}
+
+// acquire takes a warm isolate, or creates one if the pool is empty.
+func (r *Runner) acquire() *v8.Isolate {
+	select {
+	case iso := <-r.warm:
+		return iso
+	default:
+		return v8.NewIsolate()
+	}
+}
+
+// Close stops refilling the warm pool and disposes idle isolates.
+// Executions in progress are unaffected.
+func (r *Runner) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		r.wg.Wait()
+		for r.warm != nil {
+			select {
+			case iso := <-r.warm:
+				iso.Dispose()
+			default:
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// Run executes spec.Code in a fresh isolate.
+func (r *Runner) Run(ctx context.Context, spec isolate.Spec) (isolate.Result, error) {
+	if err := spec.Validate(); err != nil {
+		return isolate.Result{}, fmt.Errorf("invalid spec: %w", err)
+	}
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spec.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	iso := r.acquire()
+	defer iso.Dispose()
+
+	var stdout strings.Builder
+	bridge := isolate.NewHostBridge(spec, &stdout)
+
+	// The watchdog starts once the prelude has run; the prelude makes no
+	// host calls, so the callback never sees a nil watchdog.
+	var wd *isolate.Watchdog
+	host := v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		args := info.Args()
+		if len(args) != 2 {
+			return throw(iso, isolate.HostCallFunction+" takes a name and a request")
+		}
+		wd.Pause()
+		resp := bridge.Call(ctx, args[0].String(), []byte(args[1].String()))
+		wd.Resume()
+		value, err := v8.NewValue(iso, string(resp))
+		if err != nil {
+			return throw(iso, err.Error())
+		}
+		return value
+	})
+	global := v8.NewObjectTemplate(iso)
+	if err := global.Set(isolate.HostCallFunction, host); err != nil {
+		return isolate.Result{}, fmt.Errorf("%w: install host function: %v", isolate.ErrIsolateNotAvailable, err)
+	}
+	v8ctx := v8.NewContext(iso, global)
+	defer v8ctx.Close()
+	if _, err := v8ctx.RunScript(isolate.Prelude, "prelude.js"); err != nil {
+		return isolate.Result{}, fmt.Errorf("%w: install prelude: %v", isolate.ErrIsolateNotAvailable, err)
+	}
+	startup := time.Since(start)
+
+	wd = isolate.StartWatchdog(ctx, probe{iso}, spec.Resources)
+	value, runErr := runScript(v8ctx, spec.Code)
+	usage, err := wd.Stop()
+
+	result := isolate.Result{
+		Value:           value,
+		Stdout:          stdout.String(),
+		Duration:        time.Since(start),
+		StartupDuration: startup,
+		Usage:           usage,
+	}
+	if err != nil {
+		// The watchdog terminated the script; its reason is the error.
+		result.Value = nil
+		return result, err
+	}
+	if runErr != nil {
+		return result, scriptError(runErr)
+	}
+	return result, nil
+}
+
+// runScript runs code and returns its decoded __out value.
+func runScript(v8ctx *v8.Context, code string) (any, error) {
+	if _, err := v8ctx.RunScript(code, "snippet.js"); err != nil {
+		return nil, err
+	}
+	out, err := v8ctx.RunScript(isolate.OutExpression, "out.js")
+	if err != nil || out.IsUndefined() {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal([]byte(out.String()), &value); err != nil {
+		return nil, fmt.Errorf("decode __out: %w", err)
+	}
+	return value, nil
+}
+
+// scriptError wraps an uncaught exception in isolate.ErrScriptFailed.
+func scriptError(err error) error {
+	var jsErr *v8.JSError
+	if errors.As(err, &jsErr) {
+		if jsErr.Location != "" {
+			return fmt.Errorf("%w: %s at %s", isolate.ErrScriptFailed, jsErr.Message, jsErr.Location)
+		}
+		return fmt.Errorf("%w: %s", isolate.ErrScriptFailed, jsErr.Message)
+	}
+	return fmt.Errorf("%w: %v", isolate.ErrScriptFailed, err)
+}
+
+// throw raises msg as a JavaScript error and returns the value the
+// callback must return.
+func throw(iso *v8.Isolate, msg string) *v8.Value {
+	value, err := v8.NewValue(iso, msg)
+	if err != nil {
+		return nil
+	}
+	return iso.ThrowException(value)
+}
+
+// probe exposes an isolate to the watchdog. V8 allows heap statistics
+// and termination requests from other threads.
+type probe struct {
+	iso *v8.Isolate
+}
+
+func (p probe) HeapUsed() int64 {
+	used := p.iso.GetHeapStatistics().UsedHeapSize
+	if used > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	// #nosec G115 -- used is bounded to MaxInt64.
+	return int64(used)
+}
+
+func (p probe) Terminate() {
+	p.iso.TerminateExecution()
+}
+
+// Ping reports whether the runner accepts executions.
+func (r *Runner) Ping(_ context.Context) error {
+	select {
+	case <-r.stop:
+		return fmt.Errorf("%w: runner closed", isolate.ErrIsolateNotAvailable)
+	default:
+		return nil
+	}
+}
+
+// Info returns the embedded V8 version.
+func (r *Runner) Info(_ context.Context) (isolate.RuntimeInfo, error) {
+	return isolate.RuntimeInfo{Name: "v8", Version: v8.Version()}, nil
+}
+
+var (
+	_ isolate.Runner        = (*Runner)(nil)
+	_ isolate.HealthChecker = (*Runner)(nil)
+)
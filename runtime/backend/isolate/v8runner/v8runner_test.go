@@ -0,0 +1,119 @@
+package v8runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime/backend/isolate"
+)
+
+type echoGateway struct{}
+
+func (echoGateway) SearchTools(_ context.Context, query string, _ int) ([]index.Summary, error) {
+	return []index.Summary{{ID: "ns:" + query}}, nil
+}
+func (echoGateway) ListNamespaces(_ context.Context) ([]string, error) {
+	return []string{"ns"}, nil
+}
+func (echoGateway) DescribeTool(_ context.Context, _ string, _ tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	return tooldoc.ToolDoc{}, nil
+}
+func (echoGateway) ListToolExamples(_ context.Context, _ string, _ int) ([]tooldoc.ToolExample, error) {
+	return nil, nil
+}
+func (echoGateway) RunTool(_ context.Context, _ string, args map[string]any) (run.RunResult, error) {
+	return run.RunResult{Structured: args["x"]}, nil
+}
+func (echoGateway) RunChain(_ context.Context, _ []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	return run.RunResult{}, nil, nil
+}
+
+func spec(code string) isolate.Spec {
+	return isolate.Spec{
+		Code:     code,
+		Gateway:  echoGateway{},
+		Security: isolate.SecuritySpec{AllowedHostFunctions: isolate.HostFunctionNames()},
+		Resources: isolate.ResourceSpec{
+			HeapLimitBytes:   32 << 20,
+			WatchdogInterval: time.Millisecond,
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	r := New(Config{Warm: 2})
+	defer func() { _ = r.Close() }()
+
+	res, err := r.Run(context.Background(), spec(`
+console.log("found", tools.searchTools("q").length);
+__out = {x: tools.runTool("ns:echo", {x: 42})};`))
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out, _ := res.Value.(map[string]any); out["x"] != 42.0 {
+		t.Errorf("Value = %v, want {x: 42}", res.Value)
+	}
+	if res.Stdout != "found 1\n" {
+		t.Errorf("Stdout = %q", res.Stdout)
+	}
+	if res.Usage.PeakHeapBytes == 0 {
+		t.Error("Usage.PeakHeapBytes = 0, want the sampled heap")
+	}
+}
+
+func TestRunLimits(t *testing.T) {
+	r := New(Config{})
+	defer func() { _ = r.Close() }()
+
+	s := spec(`const a = []; for (;;) { a.push(new Array(1024).fill(0)); }`)
+	if _, err := r.Run(context.Background(), s); !errors.Is(err, isolate.ErrHeapLimitExceeded) {
+		t.Errorf("Run() error = %v, want %v", err, isolate.ErrHeapLimitExceeded)
+	}
+
+	s = spec(`for (;;) {}`)
+	s.Resources.CPUBudget = 20 * time.Millisecond
+	if _, err := r.Run(context.Background(), s); !errors.Is(err, isolate.ErrCPUBudgetExceeded) {
+		t.Errorf("Run() error = %v, want %v", err, isolate.ErrCPUBudgetExceeded)
+	}
+
+	s = spec(`for (;;) {}`)
+	s.Timeout = 20 * time.Millisecond
+	if _, err := r.Run(context.Background(), s); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRunScriptError(t *testing.T) {
+	r := New(Config{})
+	defer func() { _ = r.Close() }()
+
+	_, err := r.Run(context.Background(), spec(`throw new Error("boom")`))
+	if !errors.Is(err, isolate.ErrScriptFailed) || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Run() error = %v, want %v with boom", err, isolate.ErrScriptFailed)
+	}
+
+	s := spec(`tools.runTool("ns:echo", {})`)
+	s.Security.AllowedHostFunctions = nil
+	_, err = r.Run(context.Background(), s)
+	if !errors.Is(err, isolate.ErrScriptFailed) || !strings.Contains(err.Error(), isolate.ErrHostFunctionDenied.Error()) {
+		t.Errorf("Run() error = %v, want a denied host function", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	r := New(Config{Warm: 1})
+	if err := r.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	_ = r.Close()
+	_ = r.Close()
+	if err := r.Ping(context.Background()); !errors.Is(err, isolate.ErrIsolateNotAvailable) {
+		t.Errorf("Ping() after Close error = %v, want %v", err, isolate.ErrIsolateNotAvailable)
+	}
+}
@@ -0,0 +1,45 @@
+package isolate
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Validate checks Spec for errors before execution.
+func (s Spec) Validate() error {
+	if s.Code == "" {
+		return errors.New("code is required")
+	}
+	if err := s.Security.Validate(); err != nil {
+		return fmt.Errorf("security: %w", err)
+	}
+	if err := s.Resources.Validate(); err != nil {
+		return fmt.Errorf("resources: %w", err)
+	}
+	return nil
+}
+
+// Validate checks SecuritySpec for unknown host functions.
+func (s SecuritySpec) Validate() error {
+	for _, name := range s.AllowedHostFunctions {
+		if !slices.Contains(HostFunctionNames(), name) {
+			return fmt.Errorf("unknown host function %q", name)
+		}
+	}
+	return nil
+}
+
+// Validate checks ResourceSpec for invalid values.
+func (r ResourceSpec) Validate() error {
+	if r.HeapLimitBytes < 0 {
+		return errors.New("heap limit cannot be negative")
+	}
+	if r.CPUBudget < 0 {
+		return errors.New("cpu budget cannot be negative")
+	}
+	if r.WatchdogInterval < 0 {
+		return errors.New("watchdog interval cannot be negative")
+	}
+	return nil
+}
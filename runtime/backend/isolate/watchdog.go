@@ -0,0 +1,161 @@
+package isolate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultWatchdogInterval is the sampling interval used when
+// ResourceSpec.WatchdogInterval is zero.
+const DefaultWatchdogInterval = 5 * time.Millisecond
+
+// Probe exposes a running isolate to a Watchdog.
+//
+// Contract:
+// - Concurrency: both methods are called from the watchdog goroutine
+// while the isolate runs script on another.
+type Probe interface {
+	// HeapUsed returns the isolate's used heap size in bytes.
+	HeapUsed() int64
+
+	// Terminate stops script execution in the isolate.
+	Terminate()
+}
+
+// Usage reports what a Watchdog observed.
+type Usage struct {
+	// CPUTime is the time spent running script, excluding time paused
+	// in host functions. Isolates run script on one thread, so this
+	// approximates CPU time without measuring it.
+	CPUTime time.Duration
+
+	// PeakHeapBytes is the largest used heap size sampled.
+	PeakHeapBytes int64
+}
+
+// Watchdog terminates an isolate that exceeds its heap limit or CPU
+// budget, or whose context ends. Runners start one when script begins,
+// pause it around host function calls, and stop it when script returns.
+//
+// Contract:
+// - Concurrency: Pause and Resume are called from the isolate's thread;
+// Stop must be called exactly once.
+type Watchdog struct {
+	probe  Probe
+	limits ResourceSpec
+
+	mu      sync.Mutex
+	cpu     time.Duration
+	running time.Time // start of the current script span; zero while paused
+	peak    int64
+	err     error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// StartWatchdog starts watching probe against limits until Stop is
+// called. The CPU clock starts running immediately.
+func StartWatchdog(ctx context.Context, probe Probe, limits ResourceSpec) *Watchdog {
+	if limits.WatchdogInterval <= 0 {
+		limits.WatchdogInterval = DefaultWatchdogInterval
+	}
+	w := &Watchdog{
+		probe:   probe,
+		limits:  limits,
+		running: time.Now(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.watch(ctx)
+	return w
+}
+
+func (w *Watchdog) watch(ctx context.Context) {
+	defer close(w.done)
+	ticker := time.NewTicker(w.limits.WatchdogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ctx.Done():
+			w.terminate(ctx.Err())
+			return
+		case <-ticker.C:
+			if err := w.check(); err != nil {
+				w.terminate(err)
+				return
+			}
+		}
+	}
+}
+
+// check samples the isolate and reports the first exceeded limit.
+func (w *Watchdog) check() error {
+	heap := w.probe.HeapUsed()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.peak = max(w.peak, heap)
+	if limit := w.limits.HeapLimitBytes; limit > 0 && heap > limit {
+		return fmt.Errorf("%w: %d bytes used, limit %d", ErrHeapLimitExceeded, heap, limit)
+	}
+	if budget := w.limits.CPUBudget; budget > 0 {
+		if cpu := w.cpuLocked(); cpu > budget {
+			return fmt.Errorf("%w: %s used, budget %s", ErrCPUBudgetExceeded, cpu.Round(time.Millisecond), budget)
+		}
+	}
+	return nil
+}
+
+func (w *Watchdog) terminate(err error) {
+	w.mu.Lock()
+	w.err = err
+	w.mu.Unlock()
+	w.probe.Terminate()
+}
+
+func (w *Watchdog) cpuLocked() time.Duration {
+	if w.running.IsZero() {
+		return w.cpu
+	}
+	return w.cpu + time.Since(w.running)
+}
+
+// Pause stops the CPU clock while the isolate waits on a host function.
+func (w *Watchdog) Pause() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.running.IsZero() {
+		w.cpu += time.Since(w.running)
+		w.running = time.Time{}
+	}
+}
+
+// Resume restarts the CPU clock when a host function returns.
+func (w *Watchdog) Resume() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.running.IsZero() {
+		w.running = time.Now()
+	}
+}
+
+// Stop stops the watchdog and returns the usage it observed. The error
+// is the reason the watchdog terminated the isolate: ErrHeapLimitExceeded,
+// ErrCPUBudgetExceeded, or the context's error. It is nil if the isolate
+// was not terminated.
+func (w *Watchdog) Stop() (Usage, error) {
+	w.Pause()
+	close(w.stop)
+	<-w.done
+
+	heap := w.probe.HeapUsed()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.peak = max(w.peak, heap)
+	return Usage{CPUTime: w.cpu, PeakHeapBytes: w.peak}, w.err
+}
@@ -0,0 +1,75 @@
+package isolate
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeProbe struct {
+	heap       atomic.Int64
+	terminated chan struct{}
+}
+
+func newFakeProbe(heap int64) *fakeProbe {
+	p := &fakeProbe{terminated: make(chan struct{})}
+	p.heap.Store(heap)
+	return p
+}
+
+func (p *fakeProbe) HeapUsed() int64 { return p.heap.Load() }
+func (p *fakeProbe) Terminate()      { close(p.terminated) }
+
+func TestWatchdogHeapLimit(t *testing.T) {
+	probe := newFakeProbe(1 << 20)
+	wd := StartWatchdog(context.Background(), probe, ResourceSpec{HeapLimitBytes: 4 << 20, WatchdogInterval: time.Millisecond})
+	time.Sleep(5 * time.Millisecond)
+	probe.heap.Store(8 << 20)
+
+	select {
+	case <-probe.terminated:
+	case <-time.After(time.Second):
+		t.Fatal("watchdog did not terminate the isolate")
+	}
+	usage, err := wd.Stop()
+	if !errors.Is(err, ErrHeapLimitExceeded) {
+		t.Errorf("Stop() error = %v, want %v", err, ErrHeapLimitExceeded)
+	}
+	if usage.PeakHeapBytes != 8<<20 {
+		t.Errorf("PeakHeapBytes = %d, want %d", usage.PeakHeapBytes, 8<<20)
+	}
+}
+
+func TestWatchdogPauseExcludesHostCalls(t *testing.T) {
+	probe := newFakeProbe(0)
+	wd := StartWatchdog(context.Background(), probe, ResourceSpec{CPUBudget: 20 * time.Millisecond, WatchdogInterval: time.Millisecond})
+	wd.Pause()
+	time.Sleep(40 * time.Millisecond)
+	wd.Resume()
+
+	usage, err := wd.Stop()
+	if err != nil {
+		t.Fatalf("Stop() error = %v, want paused time excluded from the budget", err)
+	}
+	if usage.CPUTime >= 20*time.Millisecond {
+		t.Errorf("CPUTime = %v, want under 20ms", usage.CPUTime)
+	}
+}
+
+func TestWatchdogContext(t *testing.T) {
+	probe := newFakeProbe(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	wd := StartWatchdog(ctx, probe, ResourceSpec{})
+	cancel()
+
+	select {
+	case <-probe.terminated:
+	case <-time.After(time.Second):
+		t.Fatal("watchdog did not terminate the isolate")
+	}
+	if _, err := wd.Stop(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Stop() error = %v, want %v", err, context.Canceled)
+	}
+}
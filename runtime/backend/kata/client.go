@@ -21,3 +21,15 @@ type HealthChecker interface {
 type ImageResolver interface {
 	Resolve(ctx context.Context, image string) (string, error)
 }
+
+// HypervisorProber checks whether a hypervisor can run Kata sandboxes.
+// HostProber probes the local host.
+//
+// Contract:
+// - Concurrency: Implementations must be safe for concurrent use.
+// - Context: Probe must honor cancellation and deadlines.
+// - Errors: an unavailable hypervisor is reported in the status; errors
+// mean the probe itself failed.
+type HypervisorProber interface {
+	Probe(ctx context.Context, hypervisor string) (HypervisorStatus, error)
+}
@@ -7,10 +7,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
 )
 
 // Errors for Kata backend operations.
@@ -28,10 +31,14 @@ var (
 	ErrClientNotConfigured = errors.New("kata runner not configured")
 
 	// ErrDaemonUnavailable is returned when kata-runtime is not reachable.
-	ErrDaemonUnavailable = errors.New("kata runtime unavailable")
+	ErrDaemonUnavailable = fmt.Errorf("kata runtime unavailable: %w", runtime.ErrRuntimeUnavailable)
 
 	// ErrSecurityViolation is returned when a security policy is violated.
 	ErrSecurityViolation = errors.New("security policy violation")
+
+	// ErrHypervisorUnavailable is returned when no configured hypervisor is
+	// installed and compatible.
+	ErrHypervisorUnavailable = fmt.Errorf("no compatible hypervisor available: %w", runtime.ErrRuntimeUnavailable)
 )
 
 // Logger is the interface for logging.
@@ -57,15 +64,31 @@ type Config struct {
 
 	// Hypervisor specifies the hypervisor to use.
 	// Options: qemu, cloud-hypervisor, firecracker
-	// Default: qemu
+	// Default: qemu, or the first of HypervisorPreference
 	Hypervisor string
 
+	// HypervisorPreference lists hypervisors to fall back to, in order,
+	// when Hypervisor is unavailable. It only takes effect with a Prober.
+	HypervisorPreference []string
+
+	// Prober optionally checks hypervisors before the first execution.
+	// The first available one in Hypervisor, HypervisorPreference order is
+	// used, and the CapabilityReport appears in BackendInfo.Details.
+	// If nil, Hypervisor is used unchecked.
+	Prober HypervisorProber
+
 	// KernelPath is the path to the guest kernel.
 	KernelPath string
 
 	// ImagePath is the path to the guest image/rootfs.
 	ImagePath string
 
+	// GatewayVsockPort, if set, lets guests reach the tool gateway over
+	// vsock without a network: their environment gets
+	// proxy.GatewayVsockEnv for this port, and the host accepts the
+	// connections with proxy.ListenVsock.
+	GatewayVsockPort uint32
+
 	// Client executes sandbox specs.
 	// If nil, Execute() returns ErrClientNotConfigured.
 	Client SandboxRunner
@@ -83,14 +106,20 @@ type Config struct {
 // Backend executes code in Kata Containers for VM-level isolation.
 type Backend struct {
 	runtimePath string
-	hypervisor  string
+	hypervisors []string
+	prober      HypervisorProber
 	kernelPath  string
 	imagePath   string
 	image       string
+	vsockPort   uint32
 	client      SandboxRunner
 	resolver    ImageResolver
 	health      HealthChecker
 	logger      Logger
+
+	mu         sync.Mutex
+	hypervisor string
+	report     *CapabilityReport
 }
 
 // New creates a new Kata backend with the given configuration.
@@ -100,9 +129,14 @@ func New(cfg Config) *Backend {
 		runtimePath = "kata-runtime"
 	}
 
-	hypervisor := cfg.Hypervisor
-	if hypervisor == "" {
-		hypervisor = "qemu"
+	var hypervisors []string
+	for _, h := range append([]string{cfg.Hypervisor}, cfg.HypervisorPreference...) {
+		if h != "" && !slices.Contains(hypervisors, h) {
+			hypervisors = append(hypervisors, h)
+		}
+	}
+	if len(hypervisors) == 0 {
+		hypervisors = []string{HypervisorQEMU}
 	}
 
 	image := cfg.Image
@@ -112,10 +146,13 @@ func New(cfg Config) *Backend {
 
 	return &Backend{
 		runtimePath: runtimePath,
-		hypervisor:  hypervisor,
+		hypervisors: hypervisors,
+		prober:      cfg.Prober,
+		hypervisor:  hypervisors[0],
 		kernelPath:  cfg.KernelPath,
 		imagePath:   cfg.ImagePath,
 		image:       image,
+		vsockPort:   cfg.GatewayVsockPort,
 		client:      cfg.Client,
 		resolver:    cfg.ImageResolver,
 		health:      cfg.HealthChecker,
@@ -128,6 +165,13 @@ func (b *Backend) Kind() runtime.BackendKind {
 	return runtime.BackendKata
 }
 
+// Capabilities implements runtime.CapabilityReporter.
+func (b *Backend) Capabilities() runtime.Capabilities {
+	return runtime.Capabilities{
+		NetworkModes: []string{"none", "bridge"},
+	}
+}
+
 // Execute runs code in a Kata Container with VM-level isolation.
 func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
 	if err := ctx.Err(); err != nil {
@@ -156,6 +200,10 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 		}
 	}
 
+	if err := b.ensureProbed(ctx); err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+
 	image := b.image
 	if b.resolver != nil {
 		resolved, err := b.resolver.Resolve(ctx, image)
@@ -179,7 +227,7 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 		b.logger.Info("executing in kata",
 			"profile", profile,
 			"image", image,
-			"hypervisor", b.hypervisor)
+			"hypervisor", spec.Hypervisor)
 	}
 
 	runResult, err := b.client.Run(ctx, spec)
@@ -210,8 +258,57 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 
 var _ runtime.Backend = (*Backend)(nil)
 
+// Probe checks the configured hypervisors with Config.Prober and selects
+// the first available one for later executions. If none is available, it
+// returns the report and ErrHypervisorUnavailable, and executions keep
+// failing until a later probe succeeds. Without a Prober, it reports the
+// configured hypervisor as selected without checking it.
+func (b *Backend) Probe(ctx context.Context) (CapabilityReport, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.probeLocked(ctx)
+}
+
+// ensureProbed probes once, before the first execution, unless a probe
+// has already succeeded.
+func (b *Backend) ensureProbed(ctx context.Context) error {
+	if b.prober == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.report != nil && b.report.Selected != "" {
+		return nil
+	}
+	_, err := b.probeLocked(ctx)
+	return err
+}
+
+func (b *Backend) probeLocked(ctx context.Context) (CapabilityReport, error) {
+	if b.prober == nil {
+		return CapabilityReport{Selected: b.hypervisor}, nil
+	}
+	report, err := probe(ctx, b.prober, b.hypervisors)
+	if err != nil && report.Hypervisors == nil {
+		return CapabilityReport{}, err
+	}
+	b.report = &report
+	if err != nil {
+		return report, err
+	}
+	if report.Selected != b.hypervisors[0] && b.logger != nil {
+		b.logger.Warn("kata hypervisor unavailable, falling back",
+			"preferred", b.hypervisors[0],
+			"selected", report.Selected)
+	}
+	b.hypervisor = report.Selected
+	return report, nil
+}
+
 func (b *Backend) backendInfo(profile runtime.SecurityProfile) runtime.BackendInfo {
-	return runtime.BackendInfo{
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	info := runtime.BackendInfo{
 		Kind:      runtime.BackendKata,
 		Readiness: runtime.ReadinessBeta,
 		Details: map[string]any{
@@ -219,6 +316,16 @@ func (b *Backend) backendInfo(profile runtime.SecurityProfile) runtime.BackendIn
 			"profile":    string(profile),
 		},
 	}
+	if b.report != nil {
+		info.Details["capabilities"] = *b.report
+	}
+	return info
+}
+
+func (b *Backend) selectedHypervisor() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.hypervisor
 }
 
 func (b *Backend) buildSpec(image string, req runtime.ExecuteRequest, profile runtime.SecurityProfile) (SandboxSpec, error) {
@@ -227,13 +334,16 @@ func (b *Backend) buildSpec(image string, req runtime.ExecuteRequest, profile ru
 	spec := SandboxSpec{
 		Image:      image,
 		Runtime:    b.runtimePath,
-		Hypervisor: b.hypervisor,
+		Hypervisor: b.selectedHypervisor(),
 		KernelPath: b.kernelPath,
 		ImagePath:  b.imagePath,
 		Resources:  ResourceSpec{MemoryBytes: opts.MemoryLimit, CPUQuota: opts.CPUQuota, PidsLimit: opts.PidsLimit, DiskBytes: opts.DiskBytes},
 		Security:   SecuritySpec{User: opts.User, ReadOnlyRootfs: opts.ReadOnlyRootfs, NetworkMode: opts.NetworkMode},
 		Timeout:    req.Timeout,
-		Labels:     map[string]string{"runtime.profile": string(profile), "runtime.backend": string(runtime.BackendKata)},
+		Labels:     map[string]string{"runtime.profile": string(profile), "runtime.backend": string(runtime.BackendKata), runtime.LabelExecutionID: req.EnsureExecutionID()},
+	}
+	if b.vsockPort != 0 {
+		spec.Env = append(spec.Env, proxy.GatewayVsockEnvValue(b.vsockPort))
 	}
 
 	if err := spec.Validate(); err != nil {
@@ -3,11 +3,73 @@ package kata
 import (
 	"context"
 	"errors"
+	"slices"
+	"sync"
 	"testing"
 
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
 	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
 )
 
+type mockGateway struct{}
+
+func (m *mockGateway) SearchTools(_ context.Context, _ string, _ int) ([]index.Summary, error) {
+	return nil, nil
+}
+func (m *mockGateway) ListNamespaces(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+func (m *mockGateway) DescribeTool(_ context.Context, _ string, _ tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	return tooldoc.ToolDoc{}, nil
+}
+func (m *mockGateway) ListToolExamples(_ context.Context, _ string, _ int) ([]tooldoc.ToolExample, error) {
+	return nil, nil
+}
+func (m *mockGateway) RunTool(_ context.Context, _ string, _ map[string]any) (run.RunResult, error) {
+	return run.RunResult{}, nil
+}
+func (m *mockGateway) RunChain(_ context.Context, _ []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	return run.RunResult{}, nil, nil
+}
+
+type mockRunner struct {
+	mu    sync.Mutex
+	specs []SandboxSpec
+}
+
+func (m *mockRunner) Run(_ context.Context, spec SandboxSpec) (SandboxResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.specs = append(m.specs, spec)
+	return SandboxResult{}, nil
+}
+
+// mockProber reports the hypervisors in available as usable.
+type mockProber struct {
+	mu        sync.Mutex
+	available map[string]bool
+	probes    int
+}
+
+func (m *mockProber) Probe(_ context.Context, hypervisor string) (HypervisorStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.probes++
+	if !m.available[hypervisor] {
+		return HypervisorStatus{Reason: "binary not found"}, nil
+	}
+	return HypervisorStatus{Available: true, Binary: "/usr/bin/" + hypervisor}, nil
+}
+
+func (m *mockProber) set(hypervisor string, available bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.available[hypervisor] = available
+}
+
 func TestBackendImplementsInterface(t *testing.T) {
 	t.Helper()
 	var _ runtime.Backend = (*Backend)(nil)
@@ -42,3 +104,89 @@ func TestBackendRequiresGateway(t *testing.T) {
 		t.Errorf("Execute() without gateway error = %v, want %v", err, runtime.ErrMissingGateway)
 	}
 }
+
+func TestBackendGatewayVsock(t *testing.T) {
+	req := runtime.ExecuteRequest{Code: "test"}
+	spec, err := New(Config{}).buildSpec("img", req, runtime.ProfileStandard)
+	if err != nil {
+		t.Fatalf("buildSpec() error = %v", err)
+	}
+	if len(spec.Env) != 0 {
+		t.Errorf("Env = %v, want none without a gateway port", spec.Env)
+	}
+
+	spec, err = New(Config{GatewayVsockPort: 5000}).buildSpec("img", req, runtime.ProfileStandard)
+	if err != nil {
+		t.Fatalf("buildSpec() error = %v", err)
+	}
+	if !slices.Contains(spec.Env, proxy.GatewayVsockEnv+"=2:5000") {
+		t.Errorf("Env = %v, want the gateway vsock address", spec.Env)
+	}
+}
+
+func TestBackendHypervisorFallback(t *testing.T) {
+	runner := &mockRunner{}
+	prober := &mockProber{available: map[string]bool{HypervisorFirecracker: true, HypervisorCloudHypervisor: true}}
+	b := New(Config{
+		Hypervisor:           HypervisorQEMU,
+		HypervisorPreference: []string{HypervisorCloudHypervisor, HypervisorFirecracker},
+		Prober:               prober,
+		Client:               runner,
+	})
+	req := runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := runner.specs[0].Hypervisor; got != HypervisorCloudHypervisor {
+		t.Errorf("spec.Hypervisor = %q, want the first available fallback", got)
+	}
+	if got := result.Backend.Details["hypervisor"]; got != HypervisorCloudHypervisor {
+		t.Errorf("Details[hypervisor] = %v", got)
+	}
+	report, ok := result.Backend.Details["capabilities"].(CapabilityReport)
+	if !ok || len(report.Hypervisors) != 3 || report.Hypervisors[0].Available || report.Hypervisors[0].Reason == "" {
+		t.Errorf("Details[capabilities] = %+v, want a status per hypervisor", result.Backend.Details["capabilities"])
+	}
+
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if prober.probes != 3 {
+		t.Errorf("probes = %d, want one probe per hypervisor across executions", prober.probes)
+	}
+}
+
+func TestBackendNoHypervisor(t *testing.T) {
+	runner := &mockRunner{}
+	prober := &mockProber{available: map[string]bool{}}
+	b := New(Config{HypervisorPreference: []string{HypervisorFirecracker, "xen"}, Prober: prober, Client: runner})
+	req := runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}}
+
+	if _, err := b.Execute(context.Background(), req); !errors.Is(err, ErrHypervisorUnavailable) {
+		t.Fatalf("Execute() error = %v, want ErrHypervisorUnavailable", err)
+	}
+	if len(runner.specs) != 0 {
+		t.Errorf("runner called %d times, want none", len(runner.specs))
+	}
+	report, err := b.Probe(context.Background())
+	if !errors.Is(err, ErrHypervisorUnavailable) || len(report.Hypervisors) != 2 || report.Hypervisors[1].Reason != "unknown hypervisor" {
+		t.Errorf("Probe() = %+v, %v", report, err)
+	}
+
+	// A failed probe is retried, so installing a hypervisor takes effect.
+	prober.set(HypervisorFirecracker, true)
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := runner.specs[0].Hypervisor; got != HypervisorFirecracker {
+		t.Errorf("spec.Hypervisor = %q, want %q", got, HypervisorFirecracker)
+	}
+}
+
+func TestSandboxSpecUnknownHypervisor(t *testing.T) {
+	if err := (SandboxSpec{Image: "img", Hypervisor: "xen"}).Validate(); err == nil {
+		t.Error("Validate() accepted an unknown hypervisor")
+	}
+}
@@ -0,0 +1,216 @@
+package kata
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"slices"
+	"strings"
+)
+
+// Hypervisors Kata Containers can run sandboxes under.
+const (
+	HypervisorQEMU            = "qemu"
+	HypervisorCloudHypervisor = "cloud-hypervisor"
+	HypervisorFirecracker     = "firecracker"
+)
+
+// KnownHypervisors lists the hypervisors the backend accepts, in the
+// default preference order.
+var KnownHypervisors = []string{HypervisorQEMU, HypervisorCloudHypervisor, HypervisorFirecracker}
+
+// HypervisorStatus reports whether one hypervisor can run Kata sandboxes on
+// this host.
+type HypervisorStatus struct {
+	// Name is the hypervisor, e.g. "qemu".
+	Name string `json:"name"`
+
+	// Available reports whether the hypervisor is installed and compatible.
+	Available bool `json:"available"`
+
+	// Binary is the resolved hypervisor binary, if found.
+	Binary string `json:"binary,omitempty"`
+
+	// ConfigPath is the Kata configuration file for the hypervisor, if found.
+	ConfigPath string `json:"configPath,omitempty"`
+
+	// Reason explains why the hypervisor is unavailable.
+	Reason string `json:"reason,omitempty"`
+}
+
+// CapabilityReport is the result of probing the configured hypervisors.
+type CapabilityReport struct {
+	// Selected is the first available hypervisor in preference order, or
+	// empty if none is available.
+	Selected string `json:"selected,omitempty"`
+
+	// Hypervisors holds one status per probed hypervisor, in preference
+	// order.
+	Hypervisors []HypervisorStatus `json:"hypervisors"`
+}
+
+// HostProber probes the local host for Kata hypervisors. A hypervisor is
+// available when its binary is on PATH or in SearchDirs, the KVM device
+// exists, and the host architecture supports it.
+//
+// Contract:
+// - Concurrency: safe for concurrent use.
+// - Errors: a missing or incompatible hypervisor is reported in the
+// status, not as an error.
+type HostProber struct {
+	// SearchDirs are searched for hypervisor binaries after PATH.
+	// Default: /opt/kata/bin
+	SearchDirs []string
+
+	// ConfigDirs are searched for configuration-<hypervisor>.toml files.
+	// Default: /etc/kata-containers, /opt/kata/share/defaults/kata-containers,
+	// /usr/share/defaults/kata-containers
+	ConfigDirs []string
+
+	// KVMPath is the KVM device every Kata hypervisor requires.
+	// Default: /dev/kvm
+	KVMPath string
+}
+
+var _ HypervisorProber = (*HostProber)(nil)
+
+// Probe reports whether hypervisor is installed and compatible.
+func (p *HostProber) Probe(ctx context.Context, hypervisor string) (HypervisorStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return HypervisorStatus{}, err
+	}
+	status := HypervisorStatus{Name: hypervisor}
+	binaries, ok := hypervisorBinaries(hypervisor)
+	if !ok {
+		status.Reason = "unknown hypervisor"
+		return status, nil
+	}
+	if hypervisor != HypervisorQEMU && goruntime.GOARCH != "amd64" && goruntime.GOARCH != "arm64" {
+		status.Reason = "unsupported on " + goruntime.GOARCH
+		return status, nil
+	}
+
+	status.Binary = p.findBinary(binaries)
+	status.ConfigPath = p.findConfig(hypervisor)
+	kvm := p.KVMPath
+	if kvm == "" {
+		kvm = "/dev/kvm"
+	}
+	switch {
+	case status.Binary == "":
+		status.Reason = "binary not found: " + binaries[0]
+	case !exists(kvm):
+		status.Reason = "kvm unavailable: " + kvm
+	default:
+		status.Available = true
+	}
+	return status, nil
+}
+
+func (p *HostProber) findBinary(names []string) string {
+	dirs := p.SearchDirs
+	if dirs == nil {
+		dirs = []string{"/opt/kata/bin"}
+	}
+	for _, name := range names {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+		for _, dir := range dirs {
+			if path := filepath.Join(dir, name); exists(path) {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+func (p *HostProber) findConfig(hypervisor string) string {
+	dirs := p.ConfigDirs
+	if dirs == nil {
+		dirs = []string{
+			"/etc/kata-containers",
+			"/opt/kata/share/defaults/kata-containers",
+			"/usr/share/defaults/kata-containers",
+		}
+	}
+	name := "configuration-" + hypervisorConfigName(hypervisor) + ".toml"
+	for _, dir := range dirs {
+		if path := filepath.Join(dir, name); exists(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+// hypervisorBinaries returns the binaries that provide hypervisor on this
+// architecture, most specific first.
+func hypervisorBinaries(hypervisor string) ([]string, bool) {
+	switch hypervisor {
+	case HypervisorQEMU:
+		arch := map[string]string{"amd64": "x86_64", "arm64": "aarch64", "ppc64le": "ppc64", "s390x": "s390x"}[goruntime.GOARCH]
+		if arch == "" {
+			arch = goruntime.GOARCH
+		}
+		return []string{"qemu-system-" + arch, "qemu-kvm"}, true
+	case HypervisorCloudHypervisor:
+		return []string{"cloud-hypervisor"}, true
+	case HypervisorFirecracker:
+		return []string{"firecracker"}, true
+	}
+	return nil, false
+}
+
+// hypervisorConfigName maps a hypervisor to the suffix Kata uses for its
+// configuration file.
+func hypervisorConfigName(hypervisor string) string {
+	switch hypervisor {
+	case HypervisorCloudHypervisor:
+		return "clh"
+	case HypervisorFirecracker:
+		return "fc"
+	}
+	return hypervisor
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// probe checks each hypervisor in order and selects the first available
+// one. It returns ErrHypervisorUnavailable, with the full report, when none
+// is.
+func probe(ctx context.Context, prober HypervisorProber, order []string) (CapabilityReport, error) {
+	report := CapabilityReport{Hypervisors: make([]HypervisorStatus, 0, len(order))}
+	var reasons []string
+	for _, name := range order {
+		status := HypervisorStatus{Name: name, Reason: "unknown hypervisor"}
+		if slices.Contains(KnownHypervisors, name) {
+			var err error
+			status, err = prober.Probe(ctx, name)
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return CapabilityReport{}, ctxErr
+				}
+				status = HypervisorStatus{Reason: err.Error()}
+			}
+			status.Name = name
+		}
+		report.Hypervisors = append(report.Hypervisors, status)
+		if status.Available {
+			if report.Selected == "" {
+				report.Selected = name
+			}
+		} else {
+			reasons = append(reasons, name+": "+status.Reason)
+		}
+	}
+	if report.Selected == "" {
+		return report, fmt.Errorf("%w: %s", ErrHypervisorUnavailable, strings.Join(reasons, "; "))
+	}
+	return report, nil
+}
@@ -0,0 +1,48 @@
+package kata
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHostProber(t *testing.T) {
+	dir := t.TempDir()
+	binDir := filepath.Join(dir, "bin")
+	confDir := filepath.Join(dir, "conf")
+	kvm := filepath.Join(dir, "kvm")
+	for _, d := range []string{binDir, confDir} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{filepath.Join(binDir, "cloud-hypervisor"), filepath.Join(confDir, "configuration-clh.toml"), kvm} {
+		if err := os.WriteFile(f, nil, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", "")
+	p := &HostProber{SearchDirs: []string{binDir}, ConfigDirs: []string{confDir}, KVMPath: kvm}
+	ctx := context.Background()
+
+	status, err := p.Probe(ctx, HypervisorCloudHypervisor)
+	if err != nil || !status.Available {
+		t.Fatalf("Probe(cloud-hypervisor) = %+v, %v; want available", status, err)
+	}
+	if status.Binary != filepath.Join(binDir, "cloud-hypervisor") || status.ConfigPath != filepath.Join(confDir, "configuration-clh.toml") {
+		t.Errorf("Probe(cloud-hypervisor) = %+v", status)
+	}
+
+	if status, _ := p.Probe(ctx, HypervisorFirecracker); status.Available || status.Reason == "" {
+		t.Errorf("Probe(firecracker) = %+v, want unavailable without a binary", status)
+	}
+	if status, _ := p.Probe(ctx, "xen"); status.Available || status.Reason != "unknown hypervisor" {
+		t.Errorf("Probe(xen) = %+v, want unknown", status)
+	}
+
+	p.KVMPath = filepath.Join(dir, "missing")
+	if status, _ := p.Probe(ctx, HypervisorCloudHypervisor); status.Available {
+		t.Errorf("Probe() = %+v, want unavailable without kvm", status)
+	}
+}
@@ -3,6 +3,7 @@ package kata
 import (
 	"errors"
 	"fmt"
+	"slices"
 )
 
 // Validate checks SandboxSpec for errors before execution.
@@ -10,6 +11,9 @@ func (s SandboxSpec) Validate() error {
 	if s.Image == "" && s.ImagePath == "" {
 		return errors.New("image or imagePath is required")
 	}
+	if s.Hypervisor != "" && !slices.Contains(KnownHypervisors, s.Hypervisor) {
+		return fmt.Errorf("unknown hypervisor %q", s.Hypervisor)
+	}
 	if err := s.Security.Validate(); err != nil {
 		return fmt.Errorf("security: %w", err)
 	}
@@ -8,6 +8,7 @@ import "context"
 // - Concurrency: Implementations must be safe for concurrent use.
 // - Context: Run must honor cancellation and deadlines.
 // - Ownership: Implementations must not mutate the provided spec.
+// - Jobs: When spec.Job is set, run the pod as a batch/v1 Job template and set PodResult.Job.
 type PodRunner interface {
 	Run(ctx context.Context, spec PodSpec) (PodResult, error)
 }
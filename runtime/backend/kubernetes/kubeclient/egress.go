@@ -0,0 +1,129 @@
+package kubeclient
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/netip"
+
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// EgressLabel is set to the execution's name on pods with an egress
+// policy; the execution's NetworkPolicy selects it.
+const EgressLabel = "toolexec.io/egress"
+
+// DefaultDNSNamespace is the namespace of the cluster DNS service when
+// EgressConfig.DNSNamespace is empty.
+const DefaultDNSNamespace = "kube-system"
+
+// EgressConfig enables the allowlist and proxy-only egress modes. The
+// cluster's network plugin must enforce egress NetworkPolicies; the
+// runner cannot tell when it does not.
+type EgressConfig struct {
+	// DNSNamespace is the namespace whose pods executions with a policy
+	// may reach on port 53, so that allowed domains resolve.
+	// Default: DefaultDNSNamespace
+	DNSNamespace string
+
+	// Resolver resolves EgressPolicy.AllowDomains and the proxy host.
+	// Default: net.DefaultResolver
+	Resolver runtime.Resolver
+}
+
+// EgressPolicyRules returns the RBAC rules a runner with Config.Egress
+// needs in each namespace it runs in: those of PolicyRules plus managing
+// NetworkPolicies.
+func EgressPolicyRules() []rbacv1.PolicyRule {
+	return append(PolicyRules(),
+		rbacv1.PolicyRule{APIGroups: []string{"networking.k8s.io"}, Resources: []string{"networkpolicies"}, Verbs: []string{"create", "list", "delete"}})
+}
+
+// EgressModes implements runtime.EgressEnforcer. The allowlist and
+// proxy-only modes are enforced when Config.Egress is set.
+func (r *Runner) EgressModes() []runtime.EgressMode {
+	if r.egress == nil {
+		return nil
+	}
+	return []runtime.EgressMode{runtime.EgressAllowlist, runtime.EgressProxyOnly}
+}
+
+// restrictEgress creates the NetworkPolicy for spec.Egress and labels
+// spec's pods for it to select. The caller runs the returned release func
+// after the pod or Job is deleted.
+func (r *Runner) restrictEgress(ctx context.Context, name string, spec *kubernetes.PodSpec) (func(), error) {
+	if r.egress == nil {
+		return nil, fmt.Errorf("%w: egress mode %q needs Config.Egress", runtime.ErrUnsupportedCapability, spec.Egress.Mode)
+	}
+	allow, err := spec.Egress.Destinations(ctx, r.egress.Resolver)
+	if err != nil {
+		return nil, err
+	}
+	policies := r.client.NetworkingV1().NetworkPolicies(spec.Namespace)
+	policy := newNetworkPolicy(name, spec.Namespace, allow, r.egress.DNSNamespace)
+	if id := spec.Labels[runtime.LabelExecutionID]; id != "" {
+		policy.Labels = map[string]string{runtime.LabelExecutionID: id}
+	}
+	if _, err := policies.Create(ctx, policy, metav1.CreateOptions{}); err != nil {
+		return nil, apiError(kubernetes.ErrPodCreationFailed, err, "create", "networkpolicies", spec.Namespace)
+	}
+	spec.Labels = maps.Clone(spec.Labels)
+	if spec.Labels == nil {
+		spec.Labels = make(map[string]string, 1)
+	}
+	spec.Labels[EgressLabel] = name
+	return func() {
+		_ = policies.Delete(context.WithoutCancel(ctx), name, metav1.DeleteOptions{})
+	}, nil
+}
+
+// newNetworkPolicy builds the policy limiting the pods labeled for name
+// to allow and to DNS in dnsNamespace. Policies are additive, so other
+// policies in the namespace selecting the same pods widen what they reach.
+func newNetworkPolicy(name, namespace string, allow []netip.Prefix, dnsNamespace string) *networkingv1.NetworkPolicy {
+	var peers []networkingv1.NetworkPolicyPeer
+	for _, p := range allow {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: p.String()}})
+	}
+	udp, tcp := corev1.ProtocolUDP, corev1.ProtocolTCP
+	dnsPort := intstr.FromInt32(53)
+	rules := []networkingv1.NetworkPolicyEgressRule{{
+		To: []networkingv1.NetworkPolicyPeer{{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+				corev1.LabelMetadataName: dnsNamespace,
+			}},
+		}},
+		Ports: []networkingv1.NetworkPolicyPort{
+			{Protocol: &udp, Port: &dnsPort},
+			{Protocol: &tcp, Port: &dnsPort},
+		},
+	}}
+	if len(peers) > 0 {
+		rules = append(rules, networkingv1.NetworkPolicyEgressRule{To: peers})
+	}
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{EgressLabel: name}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      rules,
+		},
+	}
+}
+
+func newEgress(cfg *EgressConfig) *EgressConfig {
+	if cfg == nil {
+		return nil
+	}
+	egress := *cfg
+	if egress.DNSNamespace == "" {
+		egress.DNSNamespace = DefaultDNSNamespace
+	}
+	return &egress
+}
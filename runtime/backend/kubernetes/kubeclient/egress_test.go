@@ -0,0 +1,84 @@
+package kubeclient
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+type staticResolver map[string][]netip.Addr
+
+func (r staticResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	return r[host], nil
+}
+
+func TestRunner_RunEgress(t *testing.T) {
+	cs := fake.NewClientset()
+	r, err := New(Config{Client: cs, PollInterval: 5 * time.Millisecond, Egress: &EgressConfig{
+		Resolver: staticResolver{"pypi.org": {netip.MustParseAddr("151.101.0.223")}},
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if modes := r.EgressModes(); len(modes) != 2 {
+		t.Errorf("EgressModes() = %v, want allowlist and proxy-only", modes)
+	}
+	var policy *networkingv1.NetworkPolicy
+	cs.PrependReactor("create", "networkpolicies", func(a k8stesting.Action) (bool, k8sruntime.Object, error) {
+		policy = a.(k8stesting.CreateAction).GetObject().(*networkingv1.NetworkPolicy)
+		return false, nil, nil
+	})
+	pods := make(chan *corev1.Pod, 1)
+	go func() {
+		pods <- firstPod(t, cs)
+		setStatus(t, cs, corev1.PodSucceeded, terminated(0, "Completed"))
+	}()
+
+	spec := testSpec()
+	spec.Security.NetworkMode = "default"
+	spec.Labels = map[string]string{runtime.LabelExecutionID: "exec-1"}
+	spec.Egress = &runtime.EgressPolicy{Mode: runtime.EgressAllowlist, AllowCIDRs: []string{"10.0.0.0/8"}, AllowDomains: []string{"pypi.org"}}
+	if _, err := r.Run(context.Background(), spec); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if policy == nil {
+		t.Fatal("no NetworkPolicy created")
+	}
+	if policy.Labels[runtime.LabelExecutionID] != "exec-1" {
+		t.Errorf("policy labels = %v, want the execution ID for reaping", policy.Labels)
+	}
+	if pod := <-pods; pod == nil || pod.Labels[EgressLabel] != policy.Name || policy.Spec.PodSelector.MatchLabels[EgressLabel] != policy.Name {
+		t.Errorf("policy selector = %v, want it to select the pod", policy.Spec.PodSelector)
+	}
+	rules := policy.Spec.Egress
+	if len(rules) != 2 || rules[0].To[0].NamespaceSelector.MatchLabels[corev1.LabelMetadataName] != DefaultDNSNamespace {
+		t.Fatalf("egress rules = %+v, want DNS then the destinations", rules)
+	}
+	var cidrs []string
+	for _, peer := range rules[1].To {
+		cidrs = append(cidrs, peer.IPBlock.CIDR)
+	}
+	if len(cidrs) != 2 || cidrs[0] != "10.0.0.0/8" || cidrs[1] != "151.101.0.223/32" {
+		t.Errorf("allowed CIDRs = %v, want 10.0.0.0/8 and 151.101.0.223/32", cidrs)
+	}
+	list, _ := cs.NetworkingV1().NetworkPolicies(testNamespace).List(context.Background(), metav1.ListOptions{})
+	if len(list.Items) != 0 {
+		t.Errorf("%d policies left, want the policy deleted", len(list.Items))
+	}
+
+	// Without an EgressConfig the policy cannot be enforced.
+	r, _ = newRunner(t)
+	if _, err := r.Run(context.Background(), spec); !errors.Is(err, runtime.ErrUnsupportedCapability) {
+		t.Errorf("Run() without Egress error = %v, want %v", err, runtime.ErrUnsupportedCapability)
+	}
+}
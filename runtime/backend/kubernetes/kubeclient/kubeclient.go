@@ -0,0 +1,479 @@
+// Package kubeclient provides a kubernetes.PodRunner backed by client-go.
+//
+// It is a separate module so that the core toolexec module does not
+// depend on client-go; import it only when running the kubernetes backend:
+//
+//	runner, err := kubeclient.New(kubeclient.Config{})
+//	if err != nil {
+//		return err
+//	}
+//	backend := kubernetes.New(kubernetes.Config{
+//		Namespace: "sandbox",
+//		Client:    runner,
+//	})
+//
+// Runner loads the kubeconfig named by Config.Kubeconfig, KUBECONFIG, or
+// ~/.kube/config, and falls back to the in-cluster service account when
+// none exists. It also implements kubernetes.HealthChecker, which the
+// backend picks up automatically.
+//
+// # Execution
+//
+// Each Run creates a pod named "toolexec-<random>" with a single
+// ContainerName container that never restarts, or a batch/v1 Job with
+// that pod as its template when PodSpec.Job is set. Run waits for the
+// container to start, failing fast when its image cannot be pulled, then
+// waits for it to terminate and reports its exit code. A container killed
+// for exceeding its memory limit fails with runtime.ErrResourceLimit.
+// The pod or Job is deleted when Run returns, unless a Job has a TTL, in
+// which case the TTL controller deletes it.
+//
+// Pod logs interleave stdout and stderr, so PodResult.Stdout holds all
+// output and PodResult.Stderr is empty. In pod mode PodSpec.LogStreamer
+// follows the logs while the container runs; Jobs report the logs of
+// their last attempt after they finish. PodSpec.MaxOutputBytes caps the
+// logs read, and the API server stops sending them just past the cap.
+//
+// Pods run with all capabilities dropped, no privilege escalation, the
+// RuntimeDefault seccomp profile, no service account token unless
+// PodSpec.ServiceAccount is set, and the PodSpec resource limits.
+// Kubernetes cannot disable a pod's network, so pods whose spec asks for
+// network mode "none" are labeled with NetworkLabel for a default-deny
+// NetworkPolicy to select. Pods have no pid limit field, so
+// PodSpec.Resources.PidsLimit is left to the kubelet's podPidsLimit.
+//
+// Workspace staging is limited to the empty Scratch volume: Runner is not
+// a runtime.FileStager, so the backend rejects requests with files, Run
+// rejects specs with Staging.Files, and no artifacts are returned.
+//
+// PodSpec.Egress policies are enforced when Config.Egress is set: Run
+// creates a NetworkPolicy named after the execution before the pod,
+// selecting it by EgressLabel, that permits only the policy's
+// destinations and DNS, and deletes it afterwards. Domains are resolved
+// when the execution starts.
+//
+// Runner.Reaper returns a runtime.Reaper for the pods, Jobs, and
+// NetworkPolicies a crashed process left in a namespace; they carry the
+// runtime.LabelExecutionID label the kubernetes backend sets:
+//
+//	janitor := runtime.NewJanitor(runtime.JanitorConfig{
+//		Reapers: []runtime.Reaper{runner.Reaper("sandbox")},
+//	})
+//
+// PodResult.Usage is left empty: per-pod CPU and memory counters come from
+// the kubelet summary API, which needs nodes/proxy access that PolicyRules
+// deliberately does not grant. The backend still reports wall time.
+//
+// # Warm pool
+//
+// Scheduling a pod and starting its image takes seconds. Pool wraps a
+// Runner and keeps PoolConfig.Size idle pods running per spec shape, then
+// runs each execution in one of them with exec and deletes it afterwards:
+//
+//	pool, err := kubeclient.NewPool(kubeclient.PoolConfig{
+//		Runner:     runner,
+//		Size:       3,
+//		Entrypoint: []string{"/usr/local/bin/sandbox"},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer pool.Close()
+//	backend := kubernetes.New(kubernetes.Config{Client: pool})
+//
+// Exec keeps stdout and stderr apart, so warm runs fill both
+// PodResult.Stdout and PodResult.Stderr. Runs the pool cannot serve fall
+// back to the Runner. With PoolConfig.Events set, the pool publishes
+// runtime.EventPoolResized when its idle pod count changes. Pools need
+// PoolPolicyRules, which add "create" on "pods/exec" to PolicyRules.
+//
+// # RBAC
+//
+// The runner's identity needs the rules returned by PolicyRules in each
+// namespace it runs in, for example:
+//
+//	apiVersion: rbac.authorization.k8s.io/v1
+//	kind: Role
+//	metadata:
+//	  name: toolexec-runner
+//	  namespace: sandbox
+//	rules:
+//	- apiGroups: [""]
+//	  resources: ["pods"]
+//	  verbs: ["create", "get", "list", "delete"]
+//	- apiGroups: [""]
+//	  resources: ["pods/log"]
+//	  verbs: ["get"]
+//	- apiGroups: ["batch"]
+//	  resources: ["jobs"]
+//	  verbs: ["create", "get", "list", "delete"]
+//
+// Runners with Config.Egress need EgressPolicyRules, which add "create",
+// "list", and "delete" on "networkpolicies" in the "networking.k8s.io"
+// group.
+//
+// Requests the API server forbids fail with an error naming the missing
+// verb, resource, and namespace.
+package kubeclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/kubernetes"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// DefaultPollInterval is how often Run checks pod and Job status when
+// Config.PollInterval is zero.
+const DefaultPollInterval = 500 * time.Millisecond
+
+// jobNameLabel is set by the Job controller on the pods it creates.
+const jobNameLabel = "batch.kubernetes.io/job-name"
+
+// ErrStagingUnsupported is returned for specs with files to stage.
+var ErrStagingUnsupported = errors.New("kubeclient: file staging not supported")
+
+// Fatal container waiting reasons: the container will not start without
+// intervention, so Run fails instead of waiting for its timeout.
+var fatalWaitingReasons = []string{
+	"ErrImagePull",
+	"ImagePullBackOff",
+	"InvalidImageName",
+	"CreateContainerConfigError",
+	"CreateContainerError",
+}
+
+// Config configures a Runner.
+type Config struct {
+	// Client is the Kubernetes clientset. If nil, one is created from
+	// RESTConfig, or from the kubeconfig when RESTConfig is nil too.
+	Client clientset.Interface
+
+	// RESTConfig configures the clientset created when Client is nil.
+	RESTConfig *rest.Config
+
+	// Kubeconfig is the kubeconfig path used when Client and RESTConfig
+	// are nil. If empty, the standard loading rules apply.
+	Kubeconfig string
+
+	// PollInterval is how often pod and Job status is checked.
+	// Default: DefaultPollInterval
+	PollInterval time.Duration
+
+	// Egress, if set, enforces allowlist and proxy-only egress policies
+	// with a NetworkPolicy per execution. The runner then needs
+	// EgressPolicyRules.
+	Egress *EgressConfig
+}
+
+// Runner runs pods and Jobs through the Kubernetes API. It implements
+// kubernetes.PodRunner and kubernetes.HealthChecker and is safe for
+// concurrent use.
+type Runner struct {
+	client     clientset.Interface
+	restConfig *rest.Config
+	interval   time.Duration
+	egress     *EgressConfig
+}
+
+// New creates a Runner.
+func New(cfg Config) (*Runner, error) {
+	cs := cfg.Client
+	restCfg := cfg.RESTConfig
+	if cs == nil {
+		if restCfg == nil {
+			rules := clientcmd.NewDefaultClientConfigLoadingRules()
+			rules.ExplicitPath = cfg.Kubeconfig
+			loaded, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", kubernetes.ErrKubernetesNotAvailable, err)
+			}
+			restCfg = loaded
+		}
+		created, err := clientset.NewForConfig(restCfg)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", kubernetes.ErrKubernetesNotAvailable, err)
+		}
+		cs = created
+	}
+	interval := cfg.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	return &Runner{client: cs, restConfig: restCfg, interval: interval, egress: newEgress(cfg.Egress)}, nil
+}
+
+// PolicyRules returns the RBAC rules the runner needs in each namespace
+// it runs in.
+func PolicyRules() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"create", "get", "list", "delete"}},
+		{APIGroups: []string{""}, Resources: []string{"pods/log"}, Verbs: []string{"get"}},
+		{APIGroups: []string{"batch"}, Resources: []string{"jobs"}, Verbs: []string{"create", "get", "list", "delete"}},
+	}
+}
+
+// Run implements kubernetes.PodRunner.
+func (r *Runner) Run(ctx context.Context, spec kubernetes.PodSpec) (kubernetes.PodResult, error) {
+	if err := spec.Validate(); err != nil {
+		return kubernetes.PodResult{}, err
+	}
+	if spec.Staging != nil && len(spec.Staging.Files) > 0 {
+		return kubernetes.PodResult{}, ErrStagingUnsupported
+	}
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spec.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	name, err := newName()
+	if err != nil {
+		return kubernetes.PodResult{}, err
+	}
+
+	if spec.Egress != nil {
+		release, err := r.restrictEgress(ctx, name, &spec)
+		if err != nil {
+			return kubernetes.PodResult{}, err
+		}
+		defer release()
+	}
+
+	var result kubernetes.PodResult
+	if spec.Job != nil {
+		result, err = r.runJob(ctx, name, spec)
+	} else {
+		result, err = r.runPod(ctx, name, spec)
+	}
+	result.Duration = time.Since(start)
+	return result, err
+}
+
+// Ping implements kubernetes.HealthChecker by querying the server version.
+func (r *Runner) Ping(context.Context) error {
+	if _, err := r.client.Discovery().ServerVersion(); err != nil {
+		return fmt.Errorf("%w: %v", kubernetes.ErrClusterUnavailable, err)
+	}
+	return nil
+}
+
+func (r *Runner) runPod(ctx context.Context, name string, spec kubernetes.PodSpec) (kubernetes.PodResult, error) {
+	pod, err := newPod(name, spec)
+	if err != nil {
+		return kubernetes.PodResult{}, fmt.Errorf("%w: %v", kubernetes.ErrPodCreationFailed, err)
+	}
+	pods := r.client.CoreV1().Pods(spec.Namespace)
+	if _, err := pods.Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return kubernetes.PodResult{}, apiError(kubernetes.ErrPodCreationFailed, err, "create", "pods", spec.Namespace)
+	}
+	defer func() {
+		_ = pods.Delete(context.WithoutCancel(ctx), name, deleteOptions())
+	}()
+
+	if _, err := r.waitPod(ctx, spec.Namespace, name, started); err != nil {
+		return kubernetes.PodResult{}, err
+	}
+	logs := runtime.NewOutputBuffer(spec.MaxOutputBytes)
+	var logErr error
+	if spec.LogStreamer != nil {
+		lines := runtime.NewLineWriter(spec.LogStreamer, runtime.LogStdout)
+		logErr = r.copyLogs(ctx, spec.Namespace, name, true, 0, io.MultiWriter(logs, lines))
+		_ = lines.Close()
+	}
+	pod, err = r.waitPod(ctx, spec.Namespace, name, finished)
+	if err != nil {
+		return kubernetes.PodResult{Stdout: logs.String(), Truncated: logs.Truncated()}, err
+	}
+	if spec.LogStreamer == nil {
+		logErr = r.copyLogs(context.WithoutCancel(ctx), spec.Namespace, name, false, spec.MaxOutputBytes, logs)
+	}
+	return podResult(pod, logs, logErr)
+}
+
+func (r *Runner) runJob(ctx context.Context, name string, spec kubernetes.PodSpec) (kubernetes.PodResult, error) {
+	job, err := newJob(name, spec)
+	if err != nil {
+		return kubernetes.PodResult{}, fmt.Errorf("%w: %v", kubernetes.ErrPodCreationFailed, err)
+	}
+	jobs := r.client.BatchV1().Jobs(spec.Namespace)
+	if _, err := jobs.Create(ctx, job, metav1.CreateOptions{}); err != nil {
+		return kubernetes.PodResult{}, apiError(kubernetes.ErrPodCreationFailed, err, "create", "jobs", spec.Namespace)
+	}
+	if spec.Job.TTLAfterFinished <= 0 {
+		defer func() {
+			_ = jobs.Delete(context.WithoutCancel(ctx), name, deleteOptions())
+		}()
+	}
+
+	var status *kubernetes.JobStatus
+	err = r.poll(ctx, func() (bool, error) {
+		job, err := jobs.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, apiError(kubernetes.ErrPodExecutionFailed, err, "get", "jobs", spec.Namespace)
+		}
+		status = jobStatus(job)
+		return status.Complete() || status.Reason != "", nil
+	})
+	if err != nil {
+		return kubernetes.PodResult{Job: status}, err
+	}
+
+	list, err := r.client.CoreV1().Pods(spec.Namespace).List(ctx, metav1.ListOptions{LabelSelector: jobNameLabel + "=" + name})
+	if err != nil {
+		return kubernetes.PodResult{Job: status}, apiError(kubernetes.ErrPodExecutionFailed, err, "list", "pods", spec.Namespace)
+	}
+	if len(list.Items) == 0 {
+		return kubernetes.PodResult{Job: status}, nil
+	}
+	last := slices.MaxFunc(list.Items, func(a, b corev1.Pod) int {
+		return a.CreationTimestamp.Compare(b.CreationTimestamp.Time)
+	})
+	logs := runtime.NewOutputBuffer(spec.MaxOutputBytes)
+	logErr := r.copyLogs(context.WithoutCancel(ctx), spec.Namespace, last.Name, false, spec.MaxOutputBytes, logs)
+	result, err := podResult(&last, logs, logErr)
+	result.Job = status
+	return result, err
+}
+
+// waitPod polls the pod until done reports true.
+func (r *Runner) waitPod(ctx context.Context, namespace, name string, done func(*corev1.Pod) (bool, error)) (*corev1.Pod, error) {
+	var pod *corev1.Pod
+	err := r.poll(ctx, func() (bool, error) {
+		var err error
+		pod, err = r.client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, apiError(kubernetes.ErrPodExecutionFailed, err, "get", "pods", namespace)
+		}
+		return done(pod)
+	})
+	return pod, err
+}
+
+// poll calls check every poll interval until it reports done, fails, or
+// ctx ends.
+func (r *Runner) poll(ctx context.Context, check func() (bool, error)) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// copyLogs writes the container's logs to w. A positive limit asks the
+// API server for one byte past it, enough for w to see the overflow
+// without transferring the rest.
+func (r *Runner) copyLogs(ctx context.Context, namespace, name string, follow bool, limit int64, w io.Writer) error {
+	opts := &corev1.PodLogOptions{Container: ContainerName, Follow: follow}
+	if limit > 0 {
+		limit++
+		opts.LimitBytes = &limit
+	}
+	stream, err := r.client.CoreV1().Pods(namespace).GetLogs(name, opts).Stream(ctx)
+	if err != nil {
+		return apiError(kubernetes.ErrPodExecutionFailed, err, "get", "pods/log", namespace)
+	}
+	defer func() { _ = stream.Close() }()
+	_, err = io.Copy(w, stream)
+	return err
+}
+
+// started reports whether the pod's container has started, failing when
+// it cannot.
+func started(pod *corev1.Pod) (bool, error) {
+	switch pod.Status.Phase {
+	case corev1.PodRunning, corev1.PodSucceeded, corev1.PodFailed:
+		return true, nil
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if w := cs.State.Waiting; w != nil && slices.Contains(fatalWaitingReasons, w.Reason) {
+			return false, fmt.Errorf("%w: %s: %s", kubernetes.ErrPodCreationFailed, w.Reason, w.Message)
+		}
+	}
+	return false, nil
+}
+
+// finished reports whether the pod has terminated.
+func finished(pod *corev1.Pod) (bool, error) {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed, nil
+}
+
+// podResult reads the exit code of a terminated pod's container.
+func podResult(pod *corev1.Pod, logs *runtime.OutputBuffer, logErr error) (kubernetes.PodResult, error) {
+	result := kubernetes.PodResult{Stdout: logs.String(), Truncated: logs.Truncated()}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name != ContainerName || cs.State.Terminated == nil {
+			continue
+		}
+		term := cs.State.Terminated
+		result.ExitCode = int(term.ExitCode)
+		if term.Reason == "OOMKilled" {
+			return result, fmt.Errorf("%w: pod %s out of memory", runtime.ErrResourceLimit, pod.Name)
+		}
+	}
+	if logErr != nil {
+		return result, logErr
+	}
+	return result, nil
+}
+
+// jobStatus summarizes the Job's progress and failure condition.
+func jobStatus(job *batchv1.Job) *kubernetes.JobStatus {
+	s := &kubernetes.JobStatus{
+		Name:      job.Name,
+		Attempts:  job.Status.Active + job.Status.Succeeded + job.Status.Failed,
+		Succeeded: job.Status.Succeeded,
+		Failed:    job.Status.Failed,
+	}
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			s.Reason = c.Reason
+		}
+	}
+	return s
+}
+
+// apiError wraps err in sentinel. When the API server forbids the
+// request, it names the RBAC permission the runner lacks.
+func apiError(sentinel, err error, verb, resource, namespace string) error {
+	if apierrors.IsForbidden(err) {
+		return fmt.Errorf("%w: %v; grant the runner %q on %q in namespace %q (see kubeclient.PolicyRules)", sentinel, err, verb, resource, namespace)
+	}
+	return fmt.Errorf("%w: %v", sentinel, err)
+}
+
+func deleteOptions() metav1.DeleteOptions {
+	propagation := metav1.DeletePropagationBackground
+	return metav1.DeleteOptions{PropagationPolicy: &propagation}
+}
+
+func newName() (string, error) {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return "toolexec-" + hex.EncodeToString(b[:]), nil
+}
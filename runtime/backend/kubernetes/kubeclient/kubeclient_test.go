@@ -0,0 +1,380 @@
+package kubeclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/kubernetes"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testNamespace = "sandbox"
+
+func newRunner(t *testing.T) (*Runner, *fake.Clientset) {
+	t.Helper()
+	cs := fake.NewClientset()
+	r, err := New(Config{Client: cs, PollInterval: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return r, cs
+}
+
+func testSpec() kubernetes.PodSpec {
+	return kubernetes.PodSpec{
+		Namespace: testNamespace,
+		Image:     "sandbox:latest",
+		Command:   []string{"run"},
+		Env:       []string{"A=1"},
+		Security:  kubernetes.SecuritySpec{User: "65534", ReadOnlyRootfs: true, NetworkMode: "none"},
+		Labels:    map[string]string{"runtime.backend": "kubernetes"},
+	}
+}
+
+// firstPod waits for the runner to create a pod.
+func firstPod(t *testing.T, cs *fake.Clientset) *corev1.Pod {
+	t.Helper()
+	for range 1000 {
+		list, err := cs.CoreV1().Pods(testNamespace).List(context.Background(), metav1.ListOptions{})
+		if err == nil && len(list.Items) > 0 {
+			return &list.Items[0]
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("no pod created")
+	return nil
+}
+
+// setStatus moves the runner's pod to phase with the given container state.
+func setStatus(t *testing.T, cs *fake.Clientset, phase corev1.PodPhase, state corev1.ContainerState) {
+	t.Helper()
+	pod := firstPod(t, cs)
+	if pod == nil {
+		return
+	}
+	pod.Status.Phase = phase
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: ContainerName, State: state}}
+	if _, err := cs.CoreV1().Pods(testNamespace).UpdateStatus(context.Background(), pod, metav1.UpdateOptions{}); err != nil {
+		t.Errorf("UpdateStatus() error = %v", err)
+	}
+}
+
+func terminated(code int32, reason string) corev1.ContainerState {
+	return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: code, Reason: reason}}
+}
+
+func TestNewPod(t *testing.T) {
+	spec := testSpec()
+	spec.RuntimeClassName = "gvisor"
+	spec.Resources = kubernetes.ResourceSpec{MemoryBytes: 64 << 20, CPUQuota: 500, DiskBytes: 1 << 30, GPUs: 1, GPUResource: "nvidia.com/gpu"}
+	spec.Scratch = &kubernetes.ScratchSpec{MountPath: "/workspace", SizeLimitBytes: 1 << 20}
+
+	pod, err := newPod("toolexec-1", spec)
+	if err != nil {
+		t.Fatalf("newPod() error = %v", err)
+	}
+	if pod.Labels[NetworkLabel] != "none" || pod.Labels["runtime.backend"] != "kubernetes" {
+		t.Errorf("Labels = %v, want the spec labels and %s=none", pod.Labels, NetworkLabel)
+	}
+	ps := pod.Spec
+	if ps.RestartPolicy != corev1.RestartPolicyNever || *ps.RuntimeClassName != "gvisor" || *ps.AutomountServiceAccountToken {
+		t.Errorf("pod spec = %+v, want no restarts, gvisor, and no token", ps)
+	}
+	c := ps.Containers[0]
+	if c.Name != ContainerName || c.Env[0] != (corev1.EnvVar{Name: "A", Value: "1"}) {
+		t.Errorf("container = %+v", c)
+	}
+	sc := c.SecurityContext
+	if *sc.RunAsUser != 65534 || !*sc.RunAsNonRoot || !*sc.ReadOnlyRootFilesystem || *sc.AllowPrivilegeEscalation {
+		t.Errorf("SecurityContext = %+v, want uid 65534, non-root, read-only, no escalation", sc)
+	}
+	limits := c.Resources.Limits
+	if limits.Memory().Value() != 64<<20 || limits.Cpu().MilliValue() != 500 ||
+		limits.StorageEphemeral().Value() != 1<<30 || limits.Name("nvidia.com/gpu", "").Value() != 1 {
+		t.Errorf("Limits = %v", limits)
+	}
+	if len(ps.Volumes) != 1 || ps.Volumes[0].EmptyDir.SizeLimit.Value() != 1<<20 || c.VolumeMounts[0].MountPath != "/workspace" {
+		t.Errorf("workspace = %+v / %+v, want a 1MiB emptyDir at /workspace", ps.Volumes, c.VolumeMounts)
+	}
+
+	spec.Security.User = "nobody"
+	if _, err := newPod("toolexec-1", spec); err == nil {
+		t.Error("newPod() accepted a non-numeric user")
+	}
+}
+
+func TestNewPodTemplate(t *testing.T) {
+	spec := testSpec()
+	spec.Template = kubernetes.PodTemplate{
+		NodeSelector: map[string]string{"pool": "sandbox"},
+		Tolerations: []kubernetes.Toleration{{
+			Key: "sandbox", Operator: kubernetes.TolerationOpExists, Effect: kubernetes.TaintEffectNoSchedule,
+		}},
+		Affinity: &kubernetes.Affinity{
+			RequiredNodeTerms: []kubernetes.NodeSelectorTerm{{MatchExpressions: []kubernetes.NodeSelectorRequirement{
+				{Key: "zone", Operator: kubernetes.NodeSelectorOpIn, Values: []string{"a", "b"}},
+			}}},
+			PreferredNodeTerms: []kubernetes.PreferredNodeTerm{{Weight: 50, Term: kubernetes.NodeSelectorTerm{
+				MatchExpressions: []kubernetes.NodeSelectorRequirement{{Key: "ssd", Operator: kubernetes.NodeSelectorOpExists}},
+			}}},
+		},
+		Annotations:       map[string]string{"owner": "ops"},
+		PriorityClassName: "sandbox-low",
+	}
+
+	pod, err := newPod("toolexec-1", spec)
+	if err != nil {
+		t.Fatalf("newPod() error = %v", err)
+	}
+	ps := pod.Spec
+	if pod.Annotations["owner"] != "ops" || ps.NodeSelector["pool"] != "sandbox" || ps.PriorityClassName != "sandbox-low" {
+		t.Errorf("pod = %+v, want the template's annotations, node selector, and priority class", pod)
+	}
+	if len(ps.Tolerations) != 1 || ps.Tolerations[0].Operator != corev1.TolerationOpExists || ps.Tolerations[0].Effect != corev1.TaintEffectNoSchedule {
+		t.Errorf("Tolerations = %+v", ps.Tolerations)
+	}
+	na := ps.Affinity.NodeAffinity
+	req := na.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(req) != 1 || req[0].MatchExpressions[0].Operator != corev1.NodeSelectorOpIn || len(req[0].MatchExpressions[0].Values) != 2 {
+		t.Errorf("required terms = %+v", req)
+	}
+	pref := na.PreferredDuringSchedulingIgnoredDuringExecution
+	if len(pref) != 1 || pref[0].Weight != 50 || pref[0].Preference.MatchExpressions[0].Key != "ssd" {
+		t.Errorf("preferred terms = %+v", pref)
+	}
+
+	spec.Job = &kubernetes.JobSpec{}
+	job, err := newJob("toolexec-1", spec)
+	if err != nil {
+		t.Fatalf("newJob() error = %v", err)
+	}
+	if job.Spec.Template.Annotations["owner"] != "ops" || job.Spec.Template.Spec.NodeSelector["pool"] != "sandbox" {
+		t.Errorf("job template = %+v, want the pod template applied", job.Spec.Template)
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	r, cs := newRunner(t)
+	go setStatus(t, cs, corev1.PodSucceeded, terminated(3, "Completed"))
+
+	result, err := r.Run(context.Background(), testSpec())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.ExitCode != 3 || result.Stdout != "fake logs" {
+		t.Errorf("Run() = %+v, want exit 3 with the pod logs", result)
+	}
+	list, _ := cs.CoreV1().Pods(testNamespace).List(context.Background(), metav1.ListOptions{})
+	if len(list.Items) != 0 {
+		t.Errorf("%d pods left, want the pod deleted", len(list.Items))
+	}
+}
+
+func TestRunner_RunMaxOutputBytes(t *testing.T) {
+	r, cs := newRunner(t)
+	go setStatus(t, cs, corev1.PodSucceeded, terminated(0, "Completed"))
+
+	spec := testSpec()
+	spec.MaxOutputBytes = 4
+	result, err := r.Run(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !result.Truncated || result.Stdout != "fake"+runtime.OutputTruncatedMarker {
+		t.Errorf("Run() Stdout = %q, Truncated = %v; want the logs cut at 4 bytes", result.Stdout, result.Truncated)
+	}
+}
+
+func TestRunner_RunLogStreamer(t *testing.T) {
+	r, cs := newRunner(t)
+	go setStatus(t, cs, corev1.PodRunning, corev1.ContainerState{Running: &corev1.ContainerStateRunning{}})
+
+	var mu sync.Mutex
+	var lines []string
+	spec := testSpec()
+	spec.LogStreamer = runtime.LogStreamerFunc(func(_ runtime.LogStream, line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, line)
+		if len(lines) == 1 {
+			go setStatus(t, cs, corev1.PodSucceeded, terminated(0, "Completed"))
+		}
+	})
+
+	result, err := r.Run(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(lines) != 1 || lines[0] != "fake logs" || result.Stdout != "fake logs" {
+		t.Errorf("streamed %q, Stdout %q; want the pod logs", lines, result.Stdout)
+	}
+}
+
+func TestRunner_RunFailures(t *testing.T) {
+	tests := []struct {
+		name  string
+		phase corev1.PodPhase
+		state corev1.ContainerState
+		want  error
+	}{
+		{"image pull", corev1.PodPending, corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}, kubernetes.ErrPodCreationFailed},
+		{"oom killed", corev1.PodFailed, terminated(137, "OOMKilled"), runtime.ErrResourceLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, cs := newRunner(t)
+			go setStatus(t, cs, tt.phase, tt.state)
+			if _, err := r.Run(context.Background(), testSpec()); !errors.Is(err, tt.want) {
+				t.Errorf("Run() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunner_RunTimeout(t *testing.T) {
+	r, cs := newRunner(t)
+	spec := testSpec()
+	spec.Timeout = 50 * time.Millisecond
+
+	if _, err := r.Run(context.Background(), spec); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	list, _ := cs.CoreV1().Pods(testNamespace).List(context.Background(), metav1.ListOptions{})
+	if len(list.Items) != 0 {
+		t.Errorf("%d pods left, want the pod deleted", len(list.Items))
+	}
+}
+
+func TestRunner_RunForbidden(t *testing.T) {
+	r, cs := newRunner(t)
+	cs.PrependReactor("create", "pods", func(k8stesting.Action) (bool, k8sruntime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "", errors.New("no RBAC policy matched"))
+	})
+
+	_, err := r.Run(context.Background(), testSpec())
+	if !errors.Is(err, kubernetes.ErrPodCreationFailed) {
+		t.Fatalf("Run() error = %v, want %v", err, kubernetes.ErrPodCreationFailed)
+	}
+	for _, want := range []string{`"create"`, `"pods"`, `"sandbox"`, "PolicyRules"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestRunner_RunStagingUnsupported(t *testing.T) {
+	r, _ := newRunner(t)
+	spec := testSpec()
+	spec.Staging = &runtime.Staging{Dir: "/workspace", Files: map[string][]byte{"a": nil}}
+	if _, err := r.Run(context.Background(), spec); !errors.Is(err, ErrStagingUnsupported) {
+		t.Errorf("Run() error = %v, want %v", err, ErrStagingUnsupported)
+	}
+}
+
+// finishJob waits for the runner's Job, adds a pod for it, and marks the
+// Job with the given status.
+func finishJob(t *testing.T, cs *fake.Clientset, status batchv1.JobStatus, pod bool) {
+	t.Helper()
+	ctx := context.Background()
+	var job *batchv1.Job
+	for range 1000 {
+		list, err := cs.BatchV1().Jobs(testNamespace).List(ctx, metav1.ListOptions{})
+		if err == nil && len(list.Items) > 0 {
+			job = &list.Items[0]
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if job == nil {
+		t.Error("no job created")
+		return
+	}
+	if pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: job.Name + "-abc", Namespace: testNamespace, Labels: map[string]string{jobNameLabel: job.Name}},
+			Status: corev1.PodStatus{
+				Phase:             corev1.PodSucceeded,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: ContainerName, State: terminated(0, "Completed")}},
+			},
+		}
+		if _, err := cs.CoreV1().Pods(testNamespace).Create(ctx, p, metav1.CreateOptions{}); err != nil {
+			t.Errorf("create pod: %v", err)
+		}
+	}
+	job.Status = status
+	if _, err := cs.BatchV1().Jobs(testNamespace).UpdateStatus(ctx, job, metav1.UpdateOptions{}); err != nil {
+		t.Errorf("UpdateStatus() error = %v", err)
+	}
+}
+
+func TestRunner_RunJob(t *testing.T) {
+	r, cs := newRunner(t)
+	spec := testSpec()
+	spec.Job = &kubernetes.JobSpec{BackoffLimit: 1, ActiveDeadline: 90 * time.Second}
+	go finishJob(t, cs, batchv1.JobStatus{Succeeded: 1, Failed: 1}, true)
+
+	result, err := r.Run(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Stdout != "fake logs" || result.Job == nil || !result.Job.Complete() || result.Job.Attempts != 2 {
+		t.Errorf("Run() = %+v, job %+v; want the last pod's logs after 2 attempts", result, result.Job)
+	}
+
+	var created *batchv1.Job
+	for _, a := range cs.Actions() {
+		if c, ok := a.(k8stesting.CreateAction); ok && a.GetResource().Resource == "jobs" {
+			created = c.GetObject().(*batchv1.Job)
+		}
+	}
+	if created == nil || *created.Spec.BackoffLimit != 1 || *created.Spec.ActiveDeadlineSeconds != 90 || created.Spec.TTLSecondsAfterFinished != nil {
+		t.Errorf("created job = %+v", created)
+	}
+	jobs, _ := cs.BatchV1().Jobs(testNamespace).List(context.Background(), metav1.ListOptions{})
+	if len(jobs.Items) != 0 {
+		t.Errorf("%d jobs left, want the job deleted", len(jobs.Items))
+	}
+}
+
+func TestRunner_RunJobDeadlineExceeded(t *testing.T) {
+	r, cs := newRunner(t)
+	spec := testSpec()
+	spec.Job = &kubernetes.JobSpec{TTLAfterFinished: time.Minute}
+	go finishJob(t, cs, batchv1.JobStatus{
+		Failed:     1,
+		Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: kubernetes.JobReasonDeadlineExceeded}},
+	}, false)
+
+	result, err := r.Run(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Job == nil || result.Job.Reason != kubernetes.JobReasonDeadlineExceeded {
+		t.Errorf("Job = %+v, want %s", result.Job, kubernetes.JobReasonDeadlineExceeded)
+	}
+	jobs, _ := cs.BatchV1().Jobs(testNamespace).List(context.Background(), metav1.ListOptions{})
+	if len(jobs.Items) != 1 {
+		t.Errorf("%d jobs left, want the job kept for its TTL", len(jobs.Items))
+	}
+}
+
+func TestRunner_Ping(t *testing.T) {
+	r, _ := newRunner(t)
+	if err := r.Ping(context.Background()); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package kubeclient
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jonwraymond/toolexec/runtime/backend/kubernetes"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ContainerName is the name of the container that runs the code.
+const ContainerName = "sandbox"
+
+// NetworkLabel is set to "none" on pods whose spec disables networking.
+// Pods cannot drop their network interface, so the namespace needs a
+// default-deny NetworkPolicy selecting this label to enforce it.
+const NetworkLabel = "toolexec.io/network"
+
+// workspaceVolume names the emptyDir volume backing PodSpec.Scratch.
+const workspaceVolume = "workspace"
+
+// newPod builds the pod that runs spec.
+func newPod(name string, spec kubernetes.PodSpec) (*corev1.Pod, error) {
+	podSpec, err := newPodSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.Pod{
+		ObjectMeta: objectMeta(name, spec),
+		Spec:       podSpec,
+	}, nil
+}
+
+// newJob builds the Job whose template runs spec.
+func newJob(name string, spec kubernetes.PodSpec) (*batchv1.Job, error) {
+	podSpec, err := newPodSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	backoff := spec.Job.BackoffLimit
+	return &batchv1.Job{
+		ObjectMeta: objectMeta(name, spec),
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoff,
+			ActiveDeadlineSeconds:   spec.Job.ActiveDeadlineSeconds(),
+			TTLSecondsAfterFinished: spec.Job.TTLSecondsAfterFinished(),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels(spec), Annotations: annotations(spec)},
+				Spec:       podSpec,
+			},
+		},
+	}, nil
+}
+
+func objectMeta(name string, spec kubernetes.PodSpec) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   spec.Namespace,
+		Labels:      labels(spec),
+		Annotations: annotations(spec),
+	}
+}
+
+func annotations(spec kubernetes.PodSpec) map[string]string {
+	if len(spec.Template.Annotations) == 0 {
+		return nil
+	}
+	return maps.Clone(spec.Template.Annotations)
+}
+
+func labels(spec kubernetes.PodSpec) map[string]string {
+	out := make(map[string]string, len(spec.Labels)+1)
+	maps.Copy(out, spec.Labels)
+	if spec.Security.NetworkMode == "none" {
+		out[NetworkLabel] = "none"
+	}
+	return out
+}
+
+// newPodSpec translates spec into a pod spec that never restarts.
+func newPodSpec(spec kubernetes.PodSpec) (corev1.PodSpec, error) {
+	sc, err := securityContext(spec.Security)
+	if err != nil {
+		return corev1.PodSpec{}, err
+	}
+	c := corev1.Container{
+		Name:            ContainerName,
+		Image:           spec.Image,
+		Command:         spec.Command,
+		Args:            spec.Args,
+		WorkingDir:      spec.WorkingDir,
+		Env:             envVars(spec.Env),
+		Resources:       resources(spec.Resources),
+		SecurityContext: sc,
+	}
+	automount := spec.ServiceAccount != ""
+	ps := corev1.PodSpec{
+		RestartPolicy:                corev1.RestartPolicyNever,
+		ServiceAccountName:           spec.ServiceAccount,
+		AutomountServiceAccountToken: &automount,
+		EnableServiceLinks:           new(bool),
+	}
+	if spec.RuntimeClassName != "" {
+		rc := spec.RuntimeClassName
+		ps.RuntimeClassName = &rc
+	}
+	applyTemplate(&ps, spec.Template)
+	if s := spec.Scratch; s != nil {
+		emptyDir := &corev1.EmptyDirVolumeSource{}
+		if s.SizeLimitBytes > 0 {
+			emptyDir.SizeLimit = resource.NewQuantity(s.SizeLimitBytes, resource.BinarySI)
+		}
+		ps.Volumes = append(ps.Volumes, corev1.Volume{
+			Name:         workspaceVolume,
+			VolumeSource: corev1.VolumeSource{EmptyDir: emptyDir},
+		})
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: workspaceVolume, MountPath: s.MountPath})
+	}
+	ps.Containers = []corev1.Container{c}
+	return ps, nil
+}
+
+// applyTemplate copies the scheduling fields of t onto ps.
+func applyTemplate(ps *corev1.PodSpec, t kubernetes.PodTemplate) {
+	if len(t.NodeSelector) > 0 {
+		ps.NodeSelector = maps.Clone(t.NodeSelector)
+	}
+	for _, tol := range t.Tolerations {
+		ps.Tolerations = append(ps.Tolerations, corev1.Toleration{
+			Key:               tol.Key,
+			Operator:          corev1.TolerationOperator(tol.Operator),
+			Value:             tol.Value,
+			Effect:            corev1.TaintEffect(tol.Effect),
+			TolerationSeconds: tol.TolerationSeconds,
+		})
+	}
+	ps.PriorityClassName = t.PriorityClassName
+	if a := t.Affinity; a != nil && (len(a.RequiredNodeTerms) > 0 || len(a.PreferredNodeTerms) > 0) {
+		na := &corev1.NodeAffinity{}
+		if len(a.RequiredNodeTerms) > 0 {
+			sel := &corev1.NodeSelector{}
+			for _, term := range a.RequiredNodeTerms {
+				sel.NodeSelectorTerms = append(sel.NodeSelectorTerms, nodeSelectorTerm(term))
+			}
+			na.RequiredDuringSchedulingIgnoredDuringExecution = sel
+		}
+		for _, p := range a.PreferredNodeTerms {
+			na.PreferredDuringSchedulingIgnoredDuringExecution = append(
+				na.PreferredDuringSchedulingIgnoredDuringExecution,
+				corev1.PreferredSchedulingTerm{Weight: p.Weight, Preference: nodeSelectorTerm(p.Term)},
+			)
+		}
+		ps.Affinity = &corev1.Affinity{NodeAffinity: na}
+	}
+}
+
+func nodeSelectorTerm(t kubernetes.NodeSelectorTerm) corev1.NodeSelectorTerm {
+	var out corev1.NodeSelectorTerm
+	for _, r := range t.MatchExpressions {
+		out.MatchExpressions = append(out.MatchExpressions, corev1.NodeSelectorRequirement{
+			Key:      r.Key,
+			Operator: corev1.NodeSelectorOperator(r.Operator),
+			Values:   slices.Clone(r.Values),
+		})
+	}
+	return out
+}
+
+// resources sets limits only; the API server defaults requests to them.
+func resources(r kubernetes.ResourceSpec) corev1.ResourceRequirements {
+	limits := corev1.ResourceList{}
+	if r.MemoryBytes > 0 {
+		limits[corev1.ResourceMemory] = *resource.NewQuantity(r.MemoryBytes, resource.BinarySI)
+	}
+	if r.CPUQuota > 0 {
+		limits[corev1.ResourceCPU] = *resource.NewMilliQuantity(r.CPUQuota, resource.DecimalSI)
+	}
+	if r.DiskBytes > 0 {
+		limits[corev1.ResourceEphemeralStorage] = *resource.NewQuantity(r.DiskBytes, resource.BinarySI)
+	}
+	if r.GPUs > 0 {
+		limits[corev1.ResourceName(r.GPUResource)] = *resource.NewQuantity(r.GPUs, resource.DecimalSI)
+	}
+	if len(limits) == 0 {
+		return corev1.ResourceRequirements{}
+	}
+	return corev1.ResourceRequirements{Limits: limits}
+}
+
+// securityContext drops all capabilities and privilege escalation and
+// applies the RuntimeDefault seccomp profile. User must be numeric, as
+// "uid" or "uid:gid", because the kubelet cannot resolve names.
+func securityContext(s kubernetes.SecuritySpec) (*corev1.SecurityContext, error) {
+	sc := &corev1.SecurityContext{
+		ReadOnlyRootFilesystem:   &s.ReadOnlyRootfs,
+		AllowPrivilegeEscalation: new(bool),
+		Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		SeccompProfile:           &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+	if s.User == "" {
+		return sc, nil
+	}
+	uidStr, gidStr, hasGID := strings.Cut(s.User, ":")
+	uid, err := strconv.ParseInt(uidStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("user %q must be a numeric uid", s.User)
+	}
+	sc.RunAsUser = &uid
+	nonRoot := uid != 0
+	sc.RunAsNonRoot = &nonRoot
+	if hasGID {
+		gid, err := strconv.ParseInt(gidStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("group %q must be a numeric gid", gidStr)
+		}
+		sc.RunAsGroup = &gid
+	}
+	return sc, nil
+}
+
+func envVars(env []string) []corev1.EnvVar {
+	var out []corev1.EnvVar
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		out = append(out, corev1.EnvVar{Name: k, Value: v})
+	}
+	return out
+}
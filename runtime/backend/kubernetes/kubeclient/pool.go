@@ -0,0 +1,618 @@
+package kubeclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// DefaultResyncInterval is how often a Pool reconciles its idle pods when
+// PoolConfig.ResyncInterval is zero.
+const DefaultResyncInterval = 5 * time.Second
+
+// Pool labels. PoolLabel identifies the pods of one Pool; ShapeLabel
+// groups them by the spec they were created for.
+const (
+	PoolLabel  = "toolexec.io/pool"
+	ShapeLabel = "toolexec.io/pool-shape"
+)
+
+// ErrExecUnavailable is returned by NewPool when no Executor is given and
+// the Runner has no REST config to exec with.
+var ErrExecUnavailable = errors.New("kubeclient: pod exec requires a REST config")
+
+// Executor runs a command in the ContainerName container of a running pod.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: Exec must honor cancellation and deadlines.
+// - Errors: a command that ran returns its exit code and a nil error, even when the code is non-zero.
+type Executor interface {
+	Exec(ctx context.Context, namespace, pod string, command []string, stdout, stderr io.Writer) (int, error)
+}
+
+// PoolConfig configures a Pool.
+type PoolConfig struct {
+	// Runner creates the warm pods and runs the executions the pool
+	// cannot serve. Required.
+	Runner *Runner
+
+	// Size is the number of idle pods kept ready for each spec shape.
+	// Default: 1
+	Size int
+
+	// Entrypoint is exec'd for specs without a Command, since exec cannot
+	// start the image's own entrypoint. Set it to the image's entrypoint;
+	// if empty, such specs run cold.
+	Entrypoint []string
+
+	// IdleCommand keeps warm pods running until they are claimed.
+	// Default: sleep infinity
+	IdleCommand []string
+
+	// Executor runs executions inside warm pods.
+	// Default: exec through the API server with the Runner's REST config
+	Executor Executor
+
+	// ResyncInterval is how often idle pods are reconciled with the
+	// cluster. Claims and misses also trigger a reconcile.
+	// Default: DefaultResyncInterval
+	ResyncInterval time.Duration
+
+	// Events, if set, receives runtime.EventPoolResized when a reconcile
+	// finds a different number of idle pods, ready or warming.
+	Events *runtime.Events
+}
+
+// PoolStats reports a Pool's idle pods and how often it served a run.
+type PoolStats struct {
+	// Ready counts running idle pods; Warming counts idle pods that have
+	// not started yet.
+	Ready   int
+	Warming int
+	// Hits counts runs served by a warm pod; Misses counts runs that fell
+	// back to a cold pod.
+	Hits   uint64
+	Misses uint64
+	// LastError is the error of the last reconcile, or nil.
+	LastError error
+}
+
+// Pool keeps idle sandbox pods running and executes runs in them with
+// exec, skipping pod scheduling and image startup. It implements
+// kubernetes.PodRunner and kubernetes.HealthChecker and is safe for
+// concurrent use.
+//
+// Warm pods are grouped by shape: the spec they were created for, minus
+// the per-execution Command, Args, Env, Labels, Timeout, and LogStreamer.
+// A run is served warm when an idle pod of its shape is ready. Each pod
+// serves one run and is then deleted and replaced, so runs never share a
+// container. Runs with a Job, an egress policy, or files to stage, runs
+// whose shape has no ready pod, and runs without a Command when
+// Entrypoint is empty go to the Runner instead.
+type Pool struct {
+	runner      *Runner
+	exec        Executor
+	size        int
+	entrypoint  []string
+	idleCommand []string
+	resync      time.Duration
+	id          string
+	events      *runtime.Events
+
+	mu      sync.Mutex
+	shapes  map[string]*warmShape
+	claimed map[string]bool
+	stats   PoolStats
+	closed  bool
+
+	kick   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// warmShape tracks the idle pods of one shape.
+type warmShape struct {
+	spec    kubernetes.PodSpec
+	ready   []string
+	warming int
+}
+
+// NewPool creates a Pool and starts reconciling its idle pods. Shapes are
+// learned from the runs the pool sees, or registered ahead with Warm.
+// Call Close to stop the pool and delete its pods.
+func NewPool(cfg PoolConfig) (*Pool, error) {
+	if cfg.Runner == nil {
+		return nil, fmt.Errorf("%w: pool requires a Runner", kubernetes.ErrClientNotConfigured)
+	}
+	exec := cfg.Executor
+	if exec == nil {
+		if cfg.Runner.restConfig == nil {
+			return nil, ErrExecUnavailable
+		}
+		exec = spdyExecutor{client: cfg.Runner.client, config: cfg.Runner.restConfig}
+	}
+	size := cfg.Size
+	if size <= 0 {
+		size = 1
+	}
+	idle := cfg.IdleCommand
+	if len(idle) == 0 {
+		idle = []string{"sleep", "infinity"}
+	}
+	resync := cfg.ResyncInterval
+	if resync <= 0 {
+		resync = DefaultResyncInterval
+	}
+	id, err := newName()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		runner:      cfg.Runner,
+		exec:        exec,
+		size:        size,
+		entrypoint:  slices.Clone(cfg.Entrypoint),
+		idleCommand: slices.Clone(idle),
+		resync:      resync,
+		id:          id,
+		events:      cfg.Events,
+		shapes:      make(map[string]*warmShape),
+		claimed:     make(map[string]bool),
+		kick:        make(chan struct{}, 1),
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+	go p.loop(ctx)
+	return p, nil
+}
+
+// PoolPolicyRules returns the RBAC rules a Pool needs in each namespace it
+// runs in: those of PolicyRules plus exec into pods.
+func PoolPolicyRules() []rbacv1.PolicyRule {
+	return append(PolicyRules(),
+		rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods/exec"}, Verbs: []string{"create"}})
+}
+
+// Warm registers the shape of spec so that idle pods are provisioned for
+// it before the first run.
+func (p *Pool) Warm(spec kubernetes.PodSpec) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+	key, ok := shapeKey(spec)
+	if !ok {
+		return fmt.Errorf("%w: spec cannot run in a warm pod", kubernetes.ErrPodCreationFailed)
+	}
+	p.mu.Lock()
+	p.register(key, spec)
+	p.mu.Unlock()
+	p.trigger()
+	return nil
+}
+
+// Run implements kubernetes.PodRunner.
+func (p *Pool) Run(ctx context.Context, spec kubernetes.PodSpec) (kubernetes.PodResult, error) {
+	if err := spec.Validate(); err != nil {
+		return kubernetes.PodResult{}, err
+	}
+	command := p.command(spec)
+	key, ok := shapeKey(spec)
+	if !ok || len(command) == 0 {
+		p.mu.Lock()
+		p.stats.Misses++
+		p.mu.Unlock()
+		return p.runner.Run(ctx, spec)
+	}
+	name := p.claim(key, spec)
+	if name == "" {
+		return p.runner.Run(ctx, spec)
+	}
+	defer func() {
+		_ = p.runner.client.CoreV1().Pods(spec.Namespace).Delete(context.WithoutCancel(ctx), name, deleteOptions())
+		p.trigger()
+	}()
+
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spec.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	stdout := runtime.NewOutputBuffer(spec.MaxOutputBytes)
+	stderr := runtime.NewOutputBuffer(spec.MaxOutputBytes)
+	var outW, errW io.Writer = stdout, stderr
+	if spec.LogStreamer != nil {
+		outLines := runtime.NewLineWriter(spec.LogStreamer, runtime.LogStdout)
+		errLines := runtime.NewLineWriter(spec.LogStreamer, runtime.LogStderr)
+		defer func() {
+			_ = outLines.Close()
+			_ = errLines.Close()
+		}()
+		outW = io.MultiWriter(stdout, outLines)
+		errW = io.MultiWriter(stderr, errLines)
+	}
+	code, err := p.exec.Exec(ctx, spec.Namespace, name, command, outW, errW)
+	result := kubernetes.PodResult{
+		ExitCode:  code,
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Duration:  time.Since(start),
+		Truncated: stdout.Truncated() || stderr.Truncated(),
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return result, ctxErr
+	}
+	if err != nil {
+		return result, apiError(kubernetes.ErrPodExecutionFailed, err, "create", "pods/exec", spec.Namespace)
+	}
+	return result, nil
+}
+
+// Ping implements kubernetes.HealthChecker.
+func (p *Pool) Ping(ctx context.Context) error {
+	return p.runner.Ping(ctx)
+}
+
+// EgressModes implements runtime.EgressEnforcer. Runs with an egress
+// policy go to the Runner, which enforces them.
+func (p *Pool) EgressModes() []runtime.EgressMode {
+	return p.runner.EgressModes()
+}
+
+// Stats returns a snapshot of the pool's state.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	for _, s := range p.shapes {
+		stats.Ready += len(s.ready)
+		stats.Warming += s.warming
+	}
+	return stats
+}
+
+// Close stops reconciling and deletes the pool's idle pods. Runs in
+// progress finish and delete their own pods.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	namespaces := make(map[string]bool)
+	for _, s := range p.shapes {
+		namespaces[s.spec.Namespace] = true
+	}
+	p.mu.Unlock()
+
+	p.cancel()
+	<-p.done
+	var errs []error
+	for ns := range namespaces {
+		pods := p.runner.client.CoreV1().Pods(ns)
+		list, err := pods.List(context.Background(), metav1.ListOptions{LabelSelector: PoolLabel + "=" + p.id})
+		if err != nil {
+			errs = append(errs, apiError(kubernetes.ErrPodExecutionFailed, err, "list", "pods", ns))
+			continue
+		}
+		for _, pod := range list.Items {
+			if p.isClaimed(pod.Name) {
+				continue
+			}
+			if err := pods.Delete(context.Background(), pod.Name, deleteOptions()); err != nil {
+				errs = append(errs, apiError(kubernetes.ErrPodExecutionFailed, err, "delete", "pods", ns))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// command returns the command exec'd for spec, or nil when it has none.
+func (p *Pool) command(spec kubernetes.PodSpec) []string {
+	command := spec.Command
+	if len(command) == 0 {
+		command = p.entrypoint
+	}
+	if len(command) == 0 {
+		return nil
+	}
+	var out []string
+	if len(spec.Env) > 0 {
+		out = append(append([]string{"env"}, spec.Env...), command...)
+	} else {
+		out = slices.Clone(command)
+	}
+	return append(out, spec.Args...)
+}
+
+// claim takes a ready pod of the shape, registering the shape when it is
+// new. It returns "" on a miss.
+func (p *Pool) claim(key string, spec kubernetes.PodSpec) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.register(key, spec)
+	if p.closed || len(s.ready) == 0 {
+		p.stats.Misses++
+		p.trigger()
+		return ""
+	}
+	name := s.ready[0]
+	s.ready = s.ready[1:]
+	p.claimed[name] = true
+	p.stats.Hits++
+	p.trigger()
+	return name
+}
+
+// register returns the shape for key, creating it from spec if needed.
+// p.mu must be held.
+func (p *Pool) register(key string, spec kubernetes.PodSpec) *warmShape {
+	s, ok := p.shapes[key]
+	if !ok {
+		s = &warmShape{spec: p.idleSpec(key, spec)}
+		p.shapes[key] = s
+	}
+	return s
+}
+
+func (p *Pool) isClaimed(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.claimed[name]
+}
+
+// idleSpec returns the spec of the shape's idle pods.
+func (p *Pool) idleSpec(key string, spec kubernetes.PodSpec) kubernetes.PodSpec {
+	spec.Command = slices.Clone(p.idleCommand)
+	spec.Args = nil
+	spec.Env = nil
+	spec.Timeout = 0
+	spec.MaxOutputBytes = 0
+	spec.LogStreamer = nil
+	spec.Staging = nil
+	spec.Labels = maps.Clone(spec.Labels)
+	if spec.Labels == nil {
+		spec.Labels = make(map[string]string, 2)
+	}
+	// Idle pods outlive the run that created their shape.
+	delete(spec.Labels, runtime.LabelExecutionID)
+	spec.Labels[PoolLabel] = p.id
+	spec.Labels[ShapeLabel] = key
+	return spec
+}
+
+// trigger asks the loop to reconcile without blocking.
+func (p *Pool) trigger() {
+	select {
+	case p.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Pool) loop(ctx context.Context) {
+	defer close(p.done)
+	ticker := time.NewTicker(p.resync)
+	defer ticker.Stop()
+	var size int
+	for {
+		err := p.reconcile(ctx)
+		p.mu.Lock()
+		p.stats.LastError = err
+		p.mu.Unlock()
+		if stats := p.Stats(); stats.Ready+stats.Warming != size {
+			size = stats.Ready + stats.Warming
+			p.events.Publish(runtime.Event{
+				Type:     runtime.EventPoolResized,
+				Backend:  runtime.BackendKubernetes,
+				PoolSize: size,
+				Details:  map[string]any{"pool": p.id},
+			})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.kick:
+		}
+	}
+}
+
+// reconcile lists the pool's pods, tracks which are ready, deletes the
+// ones that failed, and creates pods until each shape has Size idle.
+func (p *Pool) reconcile(ctx context.Context) error {
+	p.mu.Lock()
+	namespaces := make(map[string]bool)
+	for _, s := range p.shapes {
+		namespaces[s.spec.Namespace] = true
+	}
+	p.mu.Unlock()
+
+	var errs []error
+	for ns := range namespaces {
+		if err := p.reconcileNamespace(ctx, ns); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Pool) reconcileNamespace(ctx context.Context, namespace string) error {
+	pods := p.runner.client.CoreV1().Pods(namespace)
+	list, err := pods.List(ctx, metav1.ListOptions{LabelSelector: PoolLabel + "=" + p.id})
+	if err != nil {
+		return apiError(kubernetes.ErrPodCreationFailed, err, "list", "pods", namespace)
+	}
+
+	var stale []string
+	var create []kubernetes.PodSpec
+	p.mu.Lock()
+	byShape := make(map[string][]corev1.Pod)
+	listed := make(map[string]bool, len(list.Items))
+	for _, pod := range list.Items {
+		listed[pod.Name] = true
+		if pod.DeletionTimestamp != nil || p.claimed[pod.Name] {
+			continue
+		}
+		key := pod.Labels[ShapeLabel]
+		if _, ok := p.shapes[key]; !ok || failed(&pod) {
+			stale = append(stale, pod.Name)
+			continue
+		}
+		byShape[key] = append(byShape[key], pod)
+	}
+	// A claimed pod absent from a list taken after the claim is gone.
+	for name := range p.claimed {
+		if !listed[name] {
+			delete(p.claimed, name)
+		}
+	}
+	for key, s := range p.shapes {
+		if s.spec.Namespace != namespace {
+			continue
+		}
+		// Keep known pods first so the oldest ready pods are claimed first.
+		var ready, fresh []string
+		s.warming = 0
+		for _, pod := range byShape[key] {
+			switch {
+			case !running(&pod):
+				s.warming++
+			case slices.Contains(s.ready, pod.Name):
+				ready = append(ready, pod.Name)
+			default:
+				fresh = append(fresh, pod.Name)
+			}
+		}
+		s.ready = append(ready, fresh...)
+		for range p.size - len(s.ready) - s.warming {
+			create = append(create, s.spec)
+		}
+	}
+	closed := p.closed
+	p.mu.Unlock()
+
+	var errs []error
+	for _, name := range stale {
+		if err := pods.Delete(ctx, name, deleteOptions()); err != nil {
+			errs = append(errs, apiError(kubernetes.ErrPodExecutionFailed, err, "delete", "pods", namespace))
+		}
+	}
+	if closed {
+		return errors.Join(errs...)
+	}
+	for _, spec := range create {
+		if err := p.createIdle(ctx, spec); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Pool) createIdle(ctx context.Context, spec kubernetes.PodSpec) error {
+	name, err := newName()
+	if err != nil {
+		return err
+	}
+	pod, err := newPod(name, spec)
+	if err != nil {
+		return fmt.Errorf("%w: %v", kubernetes.ErrPodCreationFailed, err)
+	}
+	if _, err := p.runner.client.CoreV1().Pods(spec.Namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return apiError(kubernetes.ErrPodCreationFailed, err, "create", "pods", spec.Namespace)
+	}
+	return nil
+}
+
+// shapeKey identifies the warm pods that can serve spec. It reports false
+// for specs that cannot run in a warm pod.
+func shapeKey(spec kubernetes.PodSpec) (string, bool) {
+	if spec.Job != nil || spec.Egress != nil || (spec.Staging != nil && len(spec.Staging.Files) > 0) {
+		return "", false
+	}
+	spec.Command = nil
+	spec.Args = nil
+	spec.Env = nil
+	spec.Labels = nil
+	spec.Timeout = 0
+	spec.MaxOutputBytes = 0
+	spec.LogStreamer = nil
+	spec.Staging = nil
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), true
+}
+
+// running reports whether the pod's container is running.
+func running(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name == ContainerName {
+			return cs.State.Running != nil
+		}
+	}
+	return false
+}
+
+// failed reports whether the pod exited or cannot start.
+func failed(pod *corev1.Pod) bool {
+	if done, _ := finished(pod); done {
+		return true
+	}
+	_, err := started(pod)
+	return err != nil
+}
+
+// spdyExecutor execs through the API server's pods/exec subresource.
+type spdyExecutor struct {
+	client clientset.Interface
+	config *rest.Config
+}
+
+func (e spdyExecutor) Exec(ctx context.Context, namespace, pod string, command []string, stdout, stderr io.Writer) (int, error) {
+	req := e.client.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(namespace).Name(pod).SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: ContainerName,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	ex, err := remotecommand.NewSPDYExecutor(e.config, http.MethodPost, req.URL())
+	if err != nil {
+		return 0, err
+	}
+	err = ex.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: stdout, Stderr: stderr})
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		return exitErr.ExitStatus(), nil
+	}
+	return 0, err
+}
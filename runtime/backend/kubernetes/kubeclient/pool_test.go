@@ -0,0 +1,252 @@
+package kubeclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeExecutor records exec calls and replies with fixed output.
+type fakeExecutor struct {
+	mu    sync.Mutex
+	calls []execCall
+	code  int
+	err   error
+}
+
+type execCall struct {
+	pod     string
+	command []string
+}
+
+func (e *fakeExecutor) Exec(_ context.Context, _, pod string, command []string, stdout, stderr io.Writer) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = append(e.calls, execCall{pod: pod, command: command})
+	_, _ = io.WriteString(stdout, "out\n")
+	_, _ = io.WriteString(stderr, "err\n")
+	return e.code, e.err
+}
+
+func newPool(t *testing.T, exec Executor) (*Pool, *fake.Clientset) {
+	t.Helper()
+	r, cs := newRunner(t)
+	p, err := NewPool(PoolConfig{Runner: r, Size: 2, Executor: exec, ResyncInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewPool() error = %v", err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+	return p, cs
+}
+
+// poolPods lists the pods of all pools in the test namespace.
+func poolPods(t *testing.T, cs *fake.Clientset) []corev1.Pod {
+	t.Helper()
+	list, err := cs.CoreV1().Pods(testNamespace).List(context.Background(), metav1.ListOptions{LabelSelector: PoolLabel})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	return list.Items
+}
+
+// warmUp waits for the pool to create n pods, starts them, and waits until
+// the pool sees them ready.
+func warmUp(t *testing.T, p *Pool, cs *fake.Clientset, n int) {
+	t.Helper()
+	waitFor(t, func() bool { return len(poolPods(t, cs)) >= n })
+	for _, pod := range poolPods(t, cs) {
+		if running(&pod) {
+			continue
+		}
+		pod.Status.Phase = corev1.PodRunning
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:  ContainerName,
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		}}
+		if _, err := cs.CoreV1().Pods(testNamespace).UpdateStatus(context.Background(), &pod, metav1.UpdateOptions{}); err != nil {
+			t.Fatalf("UpdateStatus() error = %v", err)
+		}
+	}
+	p.trigger()
+	waitFor(t, func() bool { return p.Stats().Ready >= n })
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for range 2000 {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("condition not met")
+}
+
+func TestPool_RunWarm(t *testing.T) {
+	exec := &fakeExecutor{code: 3}
+	p, cs := newPool(t, exec)
+	spec := testSpec()
+	spec.Args = []string{"main.py"}
+	if err := p.Warm(spec); err != nil {
+		t.Fatalf("Warm() error = %v", err)
+	}
+	warmUp(t, p, cs, 2)
+	idle := poolPods(t, cs)
+	if idle[0].Spec.Containers[0].Command[0] != "sleep" || idle[0].Labels["runtime.backend"] != "kubernetes" {
+		t.Errorf("idle pod = %+v, want the idle command and the spec labels", idle[0])
+	}
+
+	var lines []string
+	var mu sync.Mutex
+	spec.LogStreamer = runtime.LogStreamerFunc(func(stream runtime.LogStream, line string) {
+		mu.Lock()
+		lines = append(lines, fmt.Sprintf("%s:%s", stream, line))
+		mu.Unlock()
+	})
+	result, err := p.Run(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.ExitCode != 3 || result.Stdout != "out\n" || result.Stderr != "err\n" {
+		t.Errorf("Run() = %+v, want exit 3 with separate stdout and stderr", result)
+	}
+	call := exec.calls[0]
+	if want := []string{"env", "A=1", "run", "main.py"}; !slices.Equal(call.command, want) {
+		t.Errorf("exec command = %v, want %v", call.command, want)
+	}
+	if !slices.ContainsFunc(idle, func(pod corev1.Pod) bool { return pod.Name == call.pod }) {
+		t.Errorf("exec pod %q is not one of the idle pods", call.pod)
+	}
+	if len(lines) != 2 {
+		t.Errorf("streamed lines = %v, want stdout and stderr", lines)
+	}
+
+	// The used pod is deleted and replaced.
+	waitFor(t, func() bool {
+		pods := poolPods(t, cs)
+		return len(pods) == 2 && !slices.ContainsFunc(pods, func(pod corev1.Pod) bool { return pod.Name == call.pod })
+	})
+	if stats := p.Stats(); stats.Hits != 1 || stats.Misses != 0 {
+		t.Errorf("Stats() = %+v, want one hit", stats)
+	}
+}
+
+func TestPool_Events(t *testing.T) {
+	r, cs := newRunner(t)
+	events := runtime.NewEvents()
+	resized, cancel := events.Subscribe(0, runtime.EventPoolResized)
+	defer cancel()
+	p, err := NewPool(PoolConfig{Runner: r, Size: 2, Executor: &fakeExecutor{}, ResyncInterval: time.Hour, Events: events})
+	if err != nil {
+		t.Fatalf("NewPool() error = %v", err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+	if err := p.Warm(testSpec()); err != nil {
+		t.Fatalf("Warm() error = %v", err)
+	}
+	warmUp(t, p, cs, 2)
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-resized:
+			if ev.Backend != runtime.BackendKubernetes || ev.Details["pool"] != p.id {
+				t.Errorf("event = %+v, want the pool's kubernetes event", ev)
+			}
+			if ev.PoolSize == 2 {
+				return
+			}
+		case <-timeout:
+			t.Fatal("no EventPoolResized with two idle pods")
+		}
+	}
+}
+
+func TestPool_RunExecError(t *testing.T) {
+	p, cs := newPool(t, &fakeExecutor{err: errors.New("stream reset")})
+	spec := testSpec()
+	if err := p.Warm(spec); err != nil {
+		t.Fatalf("Warm() error = %v", err)
+	}
+	warmUp(t, p, cs, 2)
+	if _, err := p.Run(context.Background(), spec); !errors.Is(err, kubernetes.ErrPodExecutionFailed) {
+		t.Errorf("Run() error = %v, want ErrPodExecutionFailed", err)
+	}
+}
+
+func TestPool_RunMissFallsBack(t *testing.T) {
+	p, cs := newPool(t, &fakeExecutor{})
+	spec := testSpec()
+	spec.Staging = &runtime.Staging{Files: map[string][]byte{"a": nil}}
+	// Specs with files go to the Runner, which rejects them.
+	if _, err := p.Run(context.Background(), spec); !errors.Is(err, ErrStagingUnsupported) {
+		t.Errorf("Run() error = %v, want ErrStagingUnsupported from the runner", err)
+	}
+	if stats := p.Stats(); stats.Misses != 1 {
+		t.Errorf("Stats() = %+v, want one miss", stats)
+	}
+	if pods := poolPods(t, cs); len(pods) != 0 {
+		t.Errorf("pool created %d pods for a spec it cannot serve", len(pods))
+	}
+}
+
+func TestPool_Close(t *testing.T) {
+	p, cs := newPool(t, &fakeExecutor{})
+	if err := p.Warm(testSpec()); err != nil {
+		t.Fatalf("Warm() error = %v", err)
+	}
+	waitFor(t, func() bool { return len(poolPods(t, cs)) == 2 })
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if pods := poolPods(t, cs); len(pods) != 0 {
+		t.Errorf("Close() left %d pods", len(pods))
+	}
+}
+
+func TestNewPoolRequiresExec(t *testing.T) {
+	r, _ := newRunner(t)
+	if _, err := NewPool(PoolConfig{Runner: r}); !errors.Is(err, ErrExecUnavailable) {
+		t.Errorf("NewPool() error = %v, want ErrExecUnavailable", err)
+	}
+}
+
+func TestShapeKey(t *testing.T) {
+	base := testSpec()
+	key, ok := shapeKey(base)
+	if !ok {
+		t.Fatal("shapeKey() rejected a plain spec")
+	}
+
+	perRun := base
+	perRun.Command = []string{"other"}
+	perRun.Env = []string{"B=2"}
+	perRun.Labels = map[string]string{"runtime.profile": "dev"}
+	perRun.Timeout = time.Minute
+	if k, _ := shapeKey(perRun); k != key {
+		t.Error("per-execution fields changed the shape")
+	}
+
+	image := base
+	image.Image = "other:latest"
+	if k, _ := shapeKey(image); k == key {
+		t.Error("a different image has the same shape")
+	}
+
+	job := base
+	job.Job = &kubernetes.JobSpec{}
+	if _, ok := shapeKey(job); ok {
+		t.Error("shapeKey() accepted a Job spec")
+	}
+}
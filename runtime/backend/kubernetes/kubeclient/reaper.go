@@ -0,0 +1,95 @@
+package kubeclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/kubernetes"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Orphan kinds reported by the Reaper.
+const (
+	KindPod           = "pod"
+	KindJob           = "job"
+	KindNetworkPolicy = "networkpolicy"
+)
+
+// Reaper returns a runtime.Reaper for the pods, Jobs, and egress
+// NetworkPolicies in namespace that carry runtime.LabelExecutionID. Pods
+// a Job created are left to the Job. NetworkPolicies are only listed when
+// Config.Egress is set.
+func (r *Runner) Reaper(namespace string) runtime.Reaper {
+	return &reaper{runner: r, namespace: namespace}
+}
+
+type reaper struct {
+	runner    *Runner
+	namespace string
+}
+
+func (p *reaper) Orphans(ctx context.Context) ([]runtime.Orphan, error) {
+	opts := metav1.ListOptions{LabelSelector: runtime.LabelExecutionID}
+	var orphans []runtime.Orphan
+	add := func(kind string, meta metav1.ObjectMeta) {
+		orphans = append(orphans, runtime.Orphan{
+			Kind:        kind,
+			ID:          meta.Name,
+			ExecutionID: meta.Labels[runtime.LabelExecutionID],
+			Created:     meta.CreationTimestamp.Time,
+		})
+	}
+
+	client := p.runner.client
+	pods, err := client.CoreV1().Pods(p.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, apiError(kubernetes.ErrPodExecutionFailed, err, "list", "pods", p.namespace)
+	}
+	for _, pod := range pods.Items {
+		if _, owned := pod.Labels[jobNameLabel]; !owned {
+			add(KindPod, pod.ObjectMeta)
+		}
+	}
+	jobs, err := client.BatchV1().Jobs(p.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, apiError(kubernetes.ErrPodExecutionFailed, err, "list", "jobs", p.namespace)
+	}
+	for _, job := range jobs.Items {
+		add(KindJob, job.ObjectMeta)
+	}
+	if p.runner.egress != nil {
+		policies, err := client.NetworkingV1().NetworkPolicies(p.namespace).List(ctx, opts)
+		if err != nil {
+			return nil, apiError(kubernetes.ErrPodExecutionFailed, err, "list", "networkpolicies", p.namespace)
+		}
+		for _, policy := range policies.Items {
+			add(KindNetworkPolicy, policy.ObjectMeta)
+		}
+	}
+	return orphans, nil
+}
+
+func (p *reaper) Reap(ctx context.Context, o runtime.Orphan) error {
+	client := p.runner.client
+	var err error
+	var resource string
+	switch o.Kind {
+	case KindPod:
+		resource = "pods"
+		err = client.CoreV1().Pods(p.namespace).Delete(ctx, o.ID, deleteOptions())
+	case KindJob:
+		resource = "jobs"
+		err = client.BatchV1().Jobs(p.namespace).Delete(ctx, o.ID, deleteOptions())
+	case KindNetworkPolicy:
+		resource = "networkpolicies"
+		err = client.NetworkingV1().NetworkPolicies(p.namespace).Delete(ctx, o.ID, metav1.DeleteOptions{})
+	default:
+		return fmt.Errorf("kubeclient: unknown orphan kind %q", o.Kind)
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return apiError(kubernetes.ErrPodExecutionFailed, err, "delete", resource, p.namespace)
+	}
+	return nil
+}
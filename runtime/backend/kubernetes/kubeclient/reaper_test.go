@@ -0,0 +1,71 @@
+package kubeclient
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRunner_Reaper(t *testing.T) {
+	created := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	meta := func(name string, labels map[string]string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: testNamespace, Labels: labels, CreationTimestamp: created}
+	}
+	execution := map[string]string{runtime.LabelExecutionID: "exec-1"}
+	cs := fake.NewClientset(
+		&corev1.Pod{ObjectMeta: meta("toolexec-pod", execution)},
+		&corev1.Pod{ObjectMeta: meta("toolexec-job-x1", map[string]string{runtime.LabelExecutionID: "exec-2", jobNameLabel: "toolexec-job"})},
+		&corev1.Pod{ObjectMeta: meta("unrelated", nil)},
+		&batchv1.Job{ObjectMeta: meta("toolexec-job", map[string]string{runtime.LabelExecutionID: "exec-2"})},
+		&networkingv1.NetworkPolicy{ObjectMeta: meta("toolexec-pod", execution)},
+	)
+	r, err := New(Config{Client: cs, Egress: &EgressConfig{}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	reaper := r.Reaper(testNamespace)
+	ctx := context.Background()
+
+	orphans, err := reaper.Orphans(ctx)
+	if err != nil {
+		t.Fatalf("Orphans() error = %v", err)
+	}
+	var got []string
+	for _, o := range orphans {
+		if !o.Created.Equal(created.Time) {
+			t.Errorf("%v created at %v, want %v", o, o.Created, created.Time)
+		}
+		got = append(got, o.String())
+	}
+	want := []string{
+		"pod toolexec-pod (execution exec-1)",
+		"job toolexec-job (execution exec-2)",
+		"networkpolicy toolexec-pod (execution exec-1)",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Orphans() = %q, want %q", got, want)
+	}
+
+	for _, o := range orphans {
+		if err := reaper.Reap(ctx, o); err != nil {
+			t.Errorf("Reap(%v) error = %v", o, err)
+		}
+	}
+	if pods, _ := cs.CoreV1().Pods(testNamespace).List(ctx, metav1.ListOptions{}); len(pods.Items) != 2 {
+		t.Errorf("%d pods left, want the Job's pod and the unrelated pod", len(pods.Items))
+	}
+	if orphans, err := reaper.Orphans(ctx); err != nil || len(orphans) != 0 {
+		t.Errorf("Orphans() after Reap = %v, %v; want none", orphans, err)
+	}
+	if err := reaper.Reap(ctx, orphans[0]); err != nil {
+		t.Errorf("Reap() of a deleted pod error = %v, want nil", err)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
@@ -22,7 +24,7 @@ var (
 	ErrClientNotConfigured = errors.New("kubernetes client not configured")
 
 	// ErrClusterUnavailable is returned when the API server cannot be reached.
-	ErrClusterUnavailable = errors.New("kubernetes cluster unavailable")
+	ErrClusterUnavailable = fmt.Errorf("kubernetes cluster unavailable: %w", runtime.ErrRuntimeUnavailable)
 
 	// ErrPodCreationFailed is returned when pod creation fails.
 	ErrPodCreationFailed = errors.New("pod creation failed")
@@ -32,6 +34,29 @@ var (
 
 	// ErrSecurityViolation is returned when a security policy is violated.
 	ErrSecurityViolation = errors.New("security policy violation")
+
+	// ErrInvalidMode is returned when Config.Mode is not a known Mode.
+	ErrInvalidMode = errors.New("invalid execution mode")
+)
+
+// defaultTimeout applies when a request has no Timeout.
+const defaultTimeout = 60 * time.Second
+
+// DefaultJobTTL is how long finished Jobs are kept in ModeJob when
+// Config.JobTTL is zero.
+const DefaultJobTTL = 5 * time.Minute
+
+// Mode selects the workload object each execution runs as.
+type Mode string
+
+const (
+	// ModePod runs each execution as a bare pod. This is the default.
+	ModePod Mode = "pod"
+
+	// ModeJob runs each execution as a batch/v1 Job. The cluster then
+	// enforces the deadline, retries failed pods up to the backoff limit,
+	// and garbage-collects finished executions.
+	ModeJob Mode = "job"
 )
 
 // Logger is the interface for logging.
@@ -62,8 +87,27 @@ type Config struct {
 	// ServiceAccount is the service account for execution pods.
 	ServiceAccount string
 
+	// Mode selects between bare pods and Jobs.
+	// Default: ModePod
+	Mode Mode
+
+	// BackoffLimit is the number of pod retries in ModeJob. The default
+	// of zero never retries, since code may have side effects through
+	// its tool calls.
+	BackoffLimit int32
+
+	// JobTTL is how long finished Jobs are kept in ModeJob before the TTL
+	// controller deletes them.
+	// Default: DefaultJobTTL
+	JobTTL time.Duration
+
+	// PodTemplate applies node selection, tolerations, affinity, labels,
+	// annotations, and priority to every execution pod.
+	PodTemplate PodTemplate
+
 	// Client executes pod specs.
-	// Required. Provide a PodRunner from an integration package.
+	// Required. runtime/backend/kubernetes/kubeclient provides one built
+	// on client-go.
 	Client PodRunner
 
 	// ImageResolver optionally resolves images before execution.
@@ -82,6 +126,10 @@ type Backend struct {
 	image            string
 	runtimeClassName string
 	serviceAccount   string
+	mode             Mode
+	backoffLimit     int32
+	jobTTL           time.Duration
+	template         PodTemplate
 	client           PodRunner
 	resolver         ImageResolver
 	health           HealthChecker
@@ -100,11 +148,25 @@ func New(cfg Config) *Backend {
 		image = "toolruntime-sandbox:latest"
 	}
 
+	mode := cfg.Mode
+	if mode == "" {
+		mode = ModePod
+	}
+
+	jobTTL := cfg.JobTTL
+	if jobTTL == 0 {
+		jobTTL = DefaultJobTTL
+	}
+
 	return &Backend{
 		namespace:        namespace,
 		image:            image,
 		runtimeClassName: cfg.RuntimeClassName,
 		serviceAccount:   cfg.ServiceAccount,
+		mode:             mode,
+		backoffLimit:     cfg.BackoffLimit,
+		jobTTL:           jobTTL,
+		template:         cfg.PodTemplate,
 		client:           cfg.Client,
 		resolver:         cfg.ImageResolver,
 		health:           cfg.HealthChecker,
@@ -117,6 +179,21 @@ func (b *Backend) Kind() runtime.BackendKind {
 	return runtime.BackendKubernetes
 }
 
+// Capabilities implements runtime.CapabilityReporter. Egress modes other
+// than deny-all are enforced only when the client is a
+// runtime.EgressEnforcer, and files are staged only when it is a
+// runtime.FileStager.
+func (b *Backend) Capabilities() runtime.Capabilities {
+	return runtime.Capabilities{
+		Streaming:    true,
+		Workspace:    true,
+		FileStaging:  runtime.StagesFiles(b.client),
+		NetworkModes: []string{"none", "default"},
+		EgressModes:  runtime.EnforcedEgressModes(b.client),
+		GPU:          true,
+	}
+}
+
 // Execute runs code in a Kubernetes pod.
 func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
 	if err := req.Validate(); err != nil {
@@ -127,10 +204,16 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 	if err != nil {
 		return runtime.ExecuteResult{}, err
 	}
+	if err := runtime.CheckEgress(req.Egress, runtime.EnforcedEgressModes(client)); err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+	if len(req.Files) > 0 && !runtime.StagesFiles(client) {
+		return runtime.ExecuteResult{}, fmt.Errorf("%w: file staging", runtime.ErrUnsupportedCapability)
+	}
 
 	timeout := req.Timeout
 	if timeout == 0 {
-		timeout = 60 * time.Second
+		timeout = defaultTimeout
 	}
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
@@ -176,19 +259,39 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 			Backend:  b.backendInfo(profile),
 		}, err
 	}
+	info := b.backendInfo(profile)
+	if job := runResult.Job; job != nil {
+		info.Details["job"] = job.Name
+		info.Details["jobAttempts"] = job.Attempts
+		if job.Reason == JobReasonDeadlineExceeded {
+			return runtime.ExecuteResult{
+				Stdout:    runResult.Stdout,
+				Stderr:    runResult.Stderr,
+				Truncated: runResult.Truncated,
+				Duration:  time.Since(start),
+				Backend:   info,
+			}, fmt.Errorf("%w: job %s exceeded its active deadline", runtime.ErrTimeout, job.Name)
+		}
+	}
 
+	usage := runResult.Usage
+	usage.WallTime = runResult.Duration
 	return runtime.ExecuteResult{
-		Value:    extractOutValue(runResult.Stdout),
-		Stdout:   runResult.Stdout,
-		Stderr:   runResult.Stderr,
-		Duration: runResult.Duration,
-		Backend:  b.backendInfo(profile),
+		Value:     extractOutValue(runResult.Stdout),
+		Stdout:    runResult.Stdout,
+		Stderr:    runResult.Stderr,
+		Truncated: runResult.Truncated,
+		Duration:  runResult.Duration,
+		Backend:   info,
+		Usage:     usage,
+		Artifacts: runResult.Artifacts,
 		LimitsEnforced: runtime.LimitsEnforced{
 			Timeout:    true,
 			Memory:     req.Limits.MemoryBytes > 0,
 			CPU:        req.Limits.CPUQuotaMillis > 0,
 			Pids:       false,
 			Disk:       req.Limits.DiskBytes > 0,
+			Output:     req.MaxOutputBytes > 0,
 			ToolCalls:  true,
 			ChainSteps: true,
 		},
@@ -217,6 +320,7 @@ func (b *Backend) backendInfo(profile runtime.SecurityProfile) runtime.BackendIn
 			"namespace":        b.namespace,
 			"image":            b.image,
 			"runtimeClassName": b.runtimeClassName,
+			"mode":             string(b.mode),
 			"profile":          string(profile),
 		},
 	}
@@ -224,6 +328,10 @@ func (b *Backend) backendInfo(profile runtime.SecurityProfile) runtime.BackendIn
 
 func (b *Backend) buildSpec(image string, req runtime.ExecuteRequest, profile runtime.SecurityProfile) (PodSpec, error) {
 	opts := b.podOptions(profile, req.Limits)
+	staging, err := req.Staging()
+	if err != nil {
+		return PodSpec{}, err
+	}
 	spec := PodSpec{
 		Namespace:        b.namespace,
 		Image:            image,
@@ -234,17 +342,59 @@ func (b *Backend) buildSpec(image string, req runtime.ExecuteRequest, profile ru
 			CPUQuota:    opts.CPUQuota,
 			PidsLimit:   opts.PidsLimit,
 			DiskBytes:   opts.DiskBytes,
+			GPUs:        opts.GPUs,
+			GPUResource: opts.GPUResource,
 		},
 		Security: SecuritySpec{
 			User:           opts.User,
 			ReadOnlyRootfs: opts.ReadOnlyRootfs,
 			NetworkMode:    opts.NetworkMode,
 		},
-		Timeout: req.Timeout,
-		Labels: map[string]string{
-			"runtime.profile": string(profile),
-			"runtime.backend": string(runtime.BackendKubernetes),
-		},
+		Timeout:        req.Timeout,
+		Labels:         make(map[string]string, len(b.template.Labels)+3),
+		MaxOutputBytes: req.MaxOutputBytes,
+		LogStreamer:    req.LogStreamer,
+		Template:       b.template,
+	}
+	maps.Copy(spec.Labels, b.template.Labels)
+	spec.Labels["runtime.profile"] = string(profile)
+	spec.Labels["runtime.backend"] = string(runtime.BackendKubernetes)
+	spec.Labels[runtime.LabelExecutionID] = req.EnsureExecutionID()
+	if ws := req.Workspace; ws != nil {
+		path := ws.MountPath()
+		spec.Scratch = &ScratchSpec{MountPath: path, SizeLimitBytes: ws.MaxBytes}
+		spec.WorkingDir = path
+		spec.Env = append(spec.Env,
+			runtime.WorkspaceEnv+"="+path,
+			runtime.OutputEnv+"="+staging.OutputPath())
+		spec.Staging = staging
+	}
+	if egress := req.Egress; egress != nil {
+		// The policy replaces the profile's network mode.
+		spec.Security.NetworkMode = "none"
+		if egress.Mode != runtime.EgressDenyAll {
+			spec.Security.NetworkMode = "default"
+			spec.Egress = egress
+			proxyEnv := egress.ProxyEnv()
+			for _, k := range slices.Sorted(maps.Keys(proxyEnv)) {
+				spec.Env = append(spec.Env, k+"="+proxyEnv[k])
+			}
+		}
+	}
+	switch b.mode {
+	case ModePod:
+	case ModeJob:
+		deadline := req.Timeout
+		if deadline == 0 {
+			deadline = defaultTimeout
+		}
+		spec.Job = &JobSpec{
+			BackoffLimit:     b.backoffLimit,
+			ActiveDeadline:   deadline,
+			TTLAfterFinished: b.jobTTL,
+		}
+	default:
+		return PodSpec{}, fmt.Errorf("%w: %q", ErrInvalidMode, b.mode)
 	}
 	if err := spec.Validate(); err != nil {
 		return PodSpec{}, err
@@ -259,6 +409,8 @@ type podOptions struct {
 	CPUQuota       int64
 	PidsLimit      int64
 	DiskBytes      int64
+	GPUs           int64
+	GPUResource    string
 	User           string
 }
 
@@ -291,6 +443,10 @@ func (b *Backend) podOptions(profile runtime.SecurityProfile, limits runtime.Lim
 	if limits.DiskBytes > 0 {
 		opts.DiskBytes = limits.DiskBytes
 	}
+	if limits.GPUs.Count > 0 {
+		opts.GPUs = int64(limits.GPUs.Count)
+		opts.GPUResource = limits.GPUs.DeviceClass()
+	}
 	return opts
 }
 
@@ -3,7 +3,9 @@ package kubernetes
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/jonwraymond/tooldiscovery/index"
 	"github.com/jonwraymond/tooldiscovery/tooldoc"
@@ -79,3 +81,267 @@ func TestBackendRequiresClient(t *testing.T) {
 		t.Errorf("Execute() without client error = %v, want %v", err, ErrClientNotConfigured)
 	}
 }
+
+func TestBackendBuildSpecWorkspace(t *testing.T) {
+	b := New(Config{})
+	req := runtime.ExecuteRequest{
+		Code:      "test",
+		Gateway:   &mockGateway{},
+		Workspace: &runtime.Workspace{Path: "/scratch", MaxBytes: 1 << 30},
+		Files:     map[string]runtime.File{"input.txt": {Data: []byte("data")}},
+	}
+
+	spec, err := b.buildSpec("img", req, runtime.ProfileStandard)
+	if err != nil {
+		t.Fatalf("buildSpec() error = %v", err)
+	}
+	if spec.Scratch == nil || *spec.Scratch != (ScratchSpec{MountPath: "/scratch", SizeLimitBytes: 1 << 30}) {
+		t.Errorf("Scratch = %+v, want /scratch limited to 1GiB", spec.Scratch)
+	}
+	wantEnv := []string{runtime.WorkspaceEnv + "=/scratch", runtime.OutputEnv + "=/scratch/out"}
+	if spec.WorkingDir != "/scratch" || !slices.Equal(spec.Env, wantEnv) {
+		t.Errorf("WorkingDir = %q, Env = %v", spec.WorkingDir, spec.Env)
+	}
+	if spec.Staging == nil || string(spec.Staging.Files["input.txt"]) != "data" {
+		t.Errorf("Staging = %+v, want input.txt staged", spec.Staging)
+	}
+
+	req.Workspace = &runtime.Workspace{Path: "relative"}
+	if _, err := b.Execute(context.Background(), req); !errors.Is(err, runtime.ErrInvalidWorkspace) {
+		t.Errorf("Execute() with relative workspace error = %v, want %v", err, runtime.ErrInvalidWorkspace)
+	}
+}
+
+func TestBackendBuildSpecGPUs(t *testing.T) {
+	b := New(Config{})
+	req := runtime.ExecuteRequest{
+		Code:    "test",
+		Gateway: &mockGateway{},
+		Limits:  runtime.Limits{GPUs: runtime.GPURequest{Count: 1, Class: "amd.com/gpu"}},
+	}
+
+	spec, err := b.buildSpec("img", req, runtime.ProfileStandard)
+	if err != nil {
+		t.Fatalf("buildSpec() error = %v", err)
+	}
+	if spec.Resources.GPUs != 1 || spec.Resources.GPUResource != "amd.com/gpu" {
+		t.Errorf("GPUs = %d/%q, want 1/amd.com/gpu", spec.Resources.GPUs, spec.Resources.GPUResource)
+	}
+}
+
+type podRunnerFunc func(ctx context.Context, spec PodSpec) (PodResult, error)
+
+func (f podRunnerFunc) Run(ctx context.Context, spec PodSpec) (PodResult, error) {
+	return f(ctx, spec)
+}
+
+func TestBackendJobMode(t *testing.T) {
+	var got PodSpec
+	b := New(Config{
+		Mode:         ModeJob,
+		BackoffLimit: 2,
+		Client: podRunnerFunc(func(_ context.Context, spec PodSpec) (PodResult, error) {
+			got = spec
+			return PodResult{Stdout: "ok", Job: &JobStatus{Name: "exec-1", Attempts: 2, Succeeded: 1, Failed: 1}}, nil
+		}),
+	})
+	req := runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}, Timeout: 1500 * time.Millisecond}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := JobSpec{BackoffLimit: 2, ActiveDeadline: 1500 * time.Millisecond, TTLAfterFinished: DefaultJobTTL}
+	if got.Job == nil || *got.Job != want {
+		t.Errorf("Job = %+v, want %+v", got.Job, want)
+	}
+	if secs := got.Job.ActiveDeadlineSeconds(); secs == nil || *secs != 2 {
+		t.Errorf("ActiveDeadlineSeconds() = %v, want 2", secs)
+	}
+	if secs := got.Job.TTLSecondsAfterFinished(); secs == nil || *secs != 300 {
+		t.Errorf("TTLSecondsAfterFinished() = %v, want 300", secs)
+	}
+	d := result.Backend.Details
+	if d["mode"] != "job" || d["job"] != "exec-1" || d["jobAttempts"] != int32(2) {
+		t.Errorf("Details = %v, want job exec-1 with 2 attempts", d)
+	}
+}
+
+// stagingRunner is a PodRunner that stages files.
+type stagingRunner struct{ podRunnerFunc }
+
+func (stagingRunner) StagesFiles() bool { return true }
+
+func TestBackendFileStaging(t *testing.T) {
+	var got PodSpec
+	run := podRunnerFunc(func(_ context.Context, spec PodSpec) (PodResult, error) {
+		got = spec
+		return PodResult{Artifacts: []runtime.Artifact{{Name: "r.txt"}}}, nil
+	})
+	req := runtime.ExecuteRequest{
+		Code:      "x",
+		Gateway:   &mockGateway{},
+		Workspace: &runtime.Workspace{},
+		Files:     map[string]runtime.File{"in.txt": {Data: []byte("in")}},
+	}
+
+	b := New(Config{Client: run})
+	if b.Capabilities().FileStaging {
+		t.Error("Capabilities().FileStaging = true for a runner that does not stage files")
+	}
+	if _, err := b.Execute(context.Background(), req); !errors.Is(err, runtime.ErrUnsupportedCapability) {
+		t.Fatalf("Execute() with files error = %v, want %v", err, runtime.ErrUnsupportedCapability)
+	}
+
+	b = New(Config{Client: stagingRunner{run}})
+	if !b.Capabilities().FileStaging {
+		t.Error("Capabilities().FileStaging = false for a runtime.FileStager")
+	}
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got.Staging == nil || len(result.Artifacts) != 1 {
+		t.Errorf("Staging = %+v, Artifacts = %+v; want files staged and artifacts returned", got.Staging, result.Artifacts)
+	}
+}
+
+// egressRunner is a PodRunner enforcing Modes.
+type egressRunner struct {
+	podRunnerFunc
+	Modes []runtime.EgressMode
+}
+
+func (r egressRunner) EgressModes() []runtime.EgressMode { return r.Modes }
+
+func TestBackendEgress(t *testing.T) {
+	var got PodSpec
+	run := podRunnerFunc(func(_ context.Context, spec PodSpec) (PodResult, error) {
+		got = spec
+		return PodResult{}, nil
+	})
+	allowlist := &runtime.EgressPolicy{Mode: runtime.EgressAllowlist, AllowDomains: []string{"pypi.org"}}
+
+	// Without an enforcing runner only deny-all is accepted.
+	b := New(Config{Client: run})
+	req := runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}, Profile: runtime.ProfileDev, Egress: allowlist}
+	if _, err := b.Execute(context.Background(), req); !errors.Is(err, runtime.ErrUnsupportedCapability) {
+		t.Fatalf("Execute(allowlist) error = %v, want %v", err, runtime.ErrUnsupportedCapability)
+	}
+	req.Egress = &runtime.EgressPolicy{Mode: runtime.EgressDenyAll}
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute(deny-all) error = %v", err)
+	}
+	if got.Security.NetworkMode != "none" || got.Egress != nil {
+		t.Errorf("deny-all spec network = %q, egress = %v; want none and no runner policy", got.Security.NetworkMode, got.Egress)
+	}
+
+	b = New(Config{Client: egressRunner{run, []runtime.EgressMode{runtime.EgressAllowlist, runtime.EgressProxyOnly}}})
+	if modes := b.Capabilities().EgressModes; len(modes) != 3 {
+		t.Errorf("Capabilities().EgressModes = %v, want all three", modes)
+	}
+	req = runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}, Egress: &runtime.EgressPolicy{Mode: runtime.EgressProxyOnly, Proxy: "http://proxy:3128"}}
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute(proxy-only) error = %v", err)
+	}
+	if got.Security.NetworkMode != "default" || got.Egress != req.Egress {
+		t.Errorf("proxy-only spec network = %q, egress = %v; want default and the policy", got.Security.NetworkMode, got.Egress)
+	}
+	if !slices.Contains(got.Env, "HTTPS_PROXY=http://proxy:3128") {
+		t.Errorf("Env = %v, want the proxy variables", got.Env)
+	}
+}
+
+func TestBackendJobDeadlineExceeded(t *testing.T) {
+	b := New(Config{
+		Mode: ModeJob,
+		Client: podRunnerFunc(func(context.Context, PodSpec) (PodResult, error) {
+			return PodResult{Job: &JobStatus{Name: "exec-1", Attempts: 1, Failed: 1, Reason: JobReasonDeadlineExceeded}}, nil
+		}),
+	})
+	req := runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}}
+	if _, err := b.Execute(context.Background(), req); !errors.Is(err, runtime.ErrTimeout) {
+		t.Errorf("Execute() error = %v, want %v", err, runtime.ErrTimeout)
+	}
+}
+
+func TestBackendPodModeAndInvalidMode(t *testing.T) {
+	req := runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}}
+	spec, err := New(Config{}).buildSpec("img", req, runtime.ProfileStandard)
+	if err != nil || spec.Job != nil {
+		t.Errorf("buildSpec() in pod mode = %+v, %v; want no job", spec.Job, err)
+	}
+	if _, err := New(Config{Mode: "deployment"}).buildSpec("img", req, runtime.ProfileStandard); !errors.Is(err, ErrInvalidMode) {
+		t.Errorf("buildSpec() with unknown mode error = %v, want %v", err, ErrInvalidMode)
+	}
+	if err := (JobSpec{BackoffLimit: -1}).Validate(); err == nil {
+		t.Error("JobSpec.Validate() accepted a negative backoff limit")
+	}
+}
+
+func TestBackendPodTemplate(t *testing.T) {
+	tmpl := PodTemplate{
+		NodeSelector: map[string]string{"pool": "sandbox"},
+		Tolerations:  []Toleration{{Key: "sandbox", Operator: TolerationOpExists, Effect: TaintEffectNoSchedule}},
+		Affinity: &Affinity{RequiredNodeTerms: []NodeSelectorTerm{{
+			MatchExpressions: []NodeSelectorRequirement{{Key: "zone", Operator: NodeSelectorOpIn, Values: []string{"a"}}},
+		}}},
+		Labels:            map[string]string{"team": "tools", "runtime.backend": "spoofed"},
+		Annotations:       map[string]string{"owner": "ops"},
+		PriorityClassName: "sandbox-low",
+	}
+	b := New(Config{PodTemplate: tmpl})
+	req := runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}}
+
+	spec, err := b.buildSpec("img", req, runtime.ProfileStandard)
+	if err != nil {
+		t.Fatalf("buildSpec() error = %v", err)
+	}
+	if spec.Labels["team"] != "tools" || spec.Labels["runtime.backend"] != string(runtime.BackendKubernetes) {
+		t.Errorf("Labels = %v, want template labels under the backend's", spec.Labels)
+	}
+	if spec.Template.NodeSelector["pool"] != "sandbox" || spec.Template.PriorityClassName != "sandbox-low" {
+		t.Errorf("Template = %+v, want the configured template", spec.Template)
+	}
+
+	b = New(Config{PodTemplate: PodTemplate{Tolerations: []Toleration{{Key: "k", Operator: "Matches"}}}})
+	if _, err := b.buildSpec("img", req, runtime.ProfileStandard); err == nil {
+		t.Error("buildSpec() accepted an unknown toleration operator")
+	}
+}
+
+func TestPodTemplateValidate(t *testing.T) {
+	secs := int64(30)
+	tests := []struct {
+		name string
+		tmpl PodTemplate
+		ok   bool
+	}{
+		{"empty", PodTemplate{}, true},
+		{"equal toleration", PodTemplate{Tolerations: []Toleration{{Key: "k", Value: "v"}}}, true},
+		{"no execute seconds", PodTemplate{Tolerations: []Toleration{{Key: "k", Operator: TolerationOpExists, Effect: TaintEffectNoExecute, TolerationSeconds: &secs}}}, true},
+		{"equal without key", PodTemplate{Tolerations: []Toleration{{Value: "v"}}}, false},
+		{"exists with value", PodTemplate{Tolerations: []Toleration{{Key: "k", Operator: TolerationOpExists, Value: "v"}}}, false},
+		{"unknown effect", PodTemplate{Tolerations: []Toleration{{Key: "k", Effect: "Evict"}}}, false},
+		{"seconds without no execute", PodTemplate{Tolerations: []Toleration{{Key: "k", TolerationSeconds: &secs}}}, false},
+		{"empty term", PodTemplate{Affinity: &Affinity{RequiredNodeTerms: []NodeSelectorTerm{{}}}}, false},
+		{"in without values", PodTemplate{Affinity: &Affinity{RequiredNodeTerms: []NodeSelectorTerm{{
+			MatchExpressions: []NodeSelectorRequirement{{Key: "zone", Operator: NodeSelectorOpIn}},
+		}}}}, false},
+		{"weight out of range", PodTemplate{Affinity: &Affinity{PreferredNodeTerms: []PreferredNodeTerm{{
+			Weight: 0,
+			Term:   NodeSelectorTerm{MatchExpressions: []NodeSelectorRequirement{{Key: "gpu", Operator: NodeSelectorOpExists}}},
+		}}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.tmpl.Validate()
+			if tt.ok && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("Validate() succeeded, want error")
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package kubernetes
 
-import "time"
+import (
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
 
 // ResourceSpec defines Kubernetes resource limits.
 type ResourceSpec struct {
@@ -8,6 +12,10 @@ type ResourceSpec struct {
 	CPUQuota    int64
 	PidsLimit   int64
 	DiskBytes   int64
+	// GPUs is requested as the GPUResource extended resource, e.g.
+	// "nvidia.com/gpu", so the pod schedules onto a node with devices.
+	GPUs        int64
+	GPUResource string
 }
 
 // SecuritySpec defines Kubernetes security settings.
@@ -17,6 +25,13 @@ type SecuritySpec struct {
 	NetworkMode    string
 }
 
+// ScratchSpec defines an emptyDir volume mounted into the pod as a
+// per-execution scratch directory. It is deleted with the pod.
+type ScratchSpec struct {
+	MountPath      string
+	SizeLimitBytes int64
+}
+
 // PodSpec defines what to run inside a Kubernetes pod/job.
 type PodSpec struct {
 	Namespace        string
@@ -31,6 +46,86 @@ type PodSpec struct {
 	Security         SecuritySpec
 	Timeout          time.Duration
 	Labels           map[string]string
+	Scratch          *ScratchSpec
+	// MaxOutputBytes, if positive, caps the output kept from the pod.
+	// PodRunner implementations collect it through runtime.OutputBuffer
+	// and report the cut in PodResult.Truncated.
+	MaxOutputBytes int64
+	// Staging, if set, lists files to place in the Scratch volume before
+	// the main container starts (for example from an init container) and
+	// the output directory to read back after it exits. Backends send
+	// Files only to runners that are runtime.FileStagers.
+	Staging *runtime.Staging
+	// Job, if set, asks the runner to create a batch/v1 Job with this
+	// pod as its template instead of a bare pod.
+	Job *JobSpec
+	// LogStreamer, if set, receives output line by line while the pod
+	// runs. PodRunner implementations that can follow logs should call it.
+	LogStreamer runtime.LogStreamer
+	// Template carries the operator's scheduling and metadata overrides.
+	// Its labels are already merged into Labels.
+	Template PodTemplate
+	// Egress, if set, is an allowlist or proxy-only policy restricting
+	// the pod's outbound traffic; the backend turns deny-all into
+	// NetworkMode "none" instead. PodRunner implementations that do not
+	// enforce it must fail rather than run the pod unrestricted, and
+	// advertise the modes they enforce as runtime.EgressEnforcer.
+	Egress *runtime.EgressPolicy
+}
+
+// Job failure reasons reported in JobStatus.Reason, matching the reasons
+// of the Job's Failed condition.
+const (
+	JobReasonBackoffLimitExceeded = "BackoffLimitExceeded"
+	JobReasonDeadlineExceeded     = "DeadlineExceeded"
+)
+
+// JobSpec defines the batch/v1 Job wrapping an execution pod.
+type JobSpec struct {
+	// BackoffLimit is the number of times a failed pod is retried before
+	// the Job fails.
+	BackoffLimit int32
+	// ActiveDeadline bounds the Job's total runtime across retries.
+	ActiveDeadline time.Duration
+	// TTLAfterFinished lets the TTL controller delete the finished Job.
+	// Zero leaves deletion to the runner.
+	TTLAfterFinished time.Duration
+}
+
+// ActiveDeadlineSeconds returns ActiveDeadline as the Job's
+// activeDeadlineSeconds, rounded up, or nil when it is unset.
+func (s JobSpec) ActiveDeadlineSeconds() *int64 {
+	if s.ActiveDeadline <= 0 {
+		return nil
+	}
+	secs := int64((s.ActiveDeadline + time.Second - 1) / time.Second)
+	return &secs
+}
+
+// TTLSecondsAfterFinished returns TTLAfterFinished as the Job's
+// ttlSecondsAfterFinished, rounded up, or nil when it is unset.
+func (s JobSpec) TTLSecondsAfterFinished() *int32 {
+	if s.TTLAfterFinished <= 0 {
+		return nil
+	}
+	secs := int32((s.TTLAfterFinished + time.Second - 1) / time.Second)
+	return &secs
+}
+
+// JobStatus tracks the completion of a Job execution.
+type JobStatus struct {
+	Name string
+	// Attempts counts the pods the Job started, including retries.
+	Attempts  int32
+	Succeeded int32
+	Failed    int32
+	// Reason is set when the Job failed, e.g. JobReasonDeadlineExceeded.
+	Reason string
+}
+
+// Complete reports whether the Job finished successfully.
+func (s JobStatus) Complete() bool {
+	return s.Succeeded > 0
 }
 
 // PodResult captures the output of pod execution.
@@ -39,4 +134,14 @@ type PodResult struct {
 	Stdout   string
 	Stderr   string
 	Duration time.Duration
+	// Truncated reports that output past MaxOutputBytes was discarded.
+	Truncated bool
+	// Usage reports the resources the pod consumed, read from its cgroup
+	// where the runner can. WallTime is filled in from Duration.
+	Usage runtime.ResourceUsage
+	// Artifacts holds the files collected from Staging's output directory.
+	Artifacts []runtime.Artifact
+	// Job reports completion tracking when the spec requested a Job.
+	// Output and exit code are those of the last pod attempt.
+	Job *JobStatus
 }
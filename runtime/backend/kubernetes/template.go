@@ -0,0 +1,79 @@
+package kubernetes
+
+// PodTemplate holds scheduling and metadata overrides applied to every
+// execution pod, so operators can steer sandboxes to dedicated node pools.
+// The fields mirror their core/v1 Pod counterparts.
+type PodTemplate struct {
+	// NodeSelector restricts pods to nodes with all of these labels.
+	NodeSelector map[string]string
+	// Tolerations let pods schedule onto tainted nodes.
+	Tolerations []Toleration
+	// Affinity adds node affinity rules.
+	Affinity *Affinity
+	// Labels are merged into PodSpec.Labels. The backend's own
+	// "runtime.*" labels take precedence.
+	Labels map[string]string
+	// Annotations are added to pods.
+	Annotations map[string]string
+	// PriorityClassName sets the pods' PriorityClass.
+	PriorityClassName string
+}
+
+// Toleration operators and taint effects.
+const (
+	TolerationOpEqual  = "Equal"
+	TolerationOpExists = "Exists"
+
+	TaintEffectNoSchedule       = "NoSchedule"
+	TaintEffectPreferNoSchedule = "PreferNoSchedule"
+	TaintEffectNoExecute        = "NoExecute"
+)
+
+// Toleration tolerates taints matching Key, Operator, Value, and Effect.
+// An empty Operator means TolerationOpEqual; an empty Effect matches all
+// effects.
+type Toleration struct {
+	Key      string
+	Operator string
+	Value    string
+	Effect   string
+	// TolerationSeconds bounds how long a NoExecute taint is tolerated.
+	TolerationSeconds *int64
+}
+
+// Affinity holds node affinity rules.
+type Affinity struct {
+	// RequiredNodeTerms schedule pods only onto nodes matching at least
+	// one term.
+	RequiredNodeTerms []NodeSelectorTerm
+	// PreferredNodeTerms favor nodes matching the terms, by weight.
+	PreferredNodeTerms []PreferredNodeTerm
+}
+
+// NodeSelectorTerm matches nodes satisfying all of its requirements.
+type NodeSelectorTerm struct {
+	MatchExpressions []NodeSelectorRequirement
+}
+
+// Node selector operators.
+const (
+	NodeSelectorOpIn           = "In"
+	NodeSelectorOpNotIn        = "NotIn"
+	NodeSelectorOpExists       = "Exists"
+	NodeSelectorOpDoesNotExist = "DoesNotExist"
+	NodeSelectorOpGt           = "Gt"
+	NodeSelectorOpLt           = "Lt"
+)
+
+// NodeSelectorRequirement compares the node label Key with Values.
+type NodeSelectorRequirement struct {
+	Key      string
+	Operator string
+	Values   []string
+}
+
+// PreferredNodeTerm weights a NodeSelectorTerm from 1 to 100.
+type PreferredNodeTerm struct {
+	Weight int32
+	Term   NodeSelectorTerm
+}
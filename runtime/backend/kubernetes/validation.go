@@ -3,6 +3,8 @@ package kubernetes
 import (
 	"errors"
 	"fmt"
+
+	"github.com/jonwraymond/toolexec/runtime"
 )
 
 // Validate checks PodSpec for errors before execution.
@@ -16,6 +18,55 @@ func (s PodSpec) Validate() error {
 	if err := s.Resources.Validate(); err != nil {
 		return fmt.Errorf("resources: %w", err)
 	}
+	if s.Scratch != nil {
+		if err := s.Scratch.Validate(); err != nil {
+			return fmt.Errorf("scratch: %w", err)
+		}
+	}
+	if s.Job != nil {
+		if err := s.Job.Validate(); err != nil {
+			return fmt.Errorf("job: %w", err)
+		}
+	}
+	if err := s.Template.Validate(); err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	if s.Egress != nil {
+		if err := s.Egress.Validate(); err != nil {
+			return fmt.Errorf("egress: %w", err)
+		}
+		if s.Egress.Mode == runtime.EgressDenyAll {
+			return errors.New(`egress: deny-all is NetworkMode "none", not a runner policy`)
+		}
+		if s.Security.NetworkMode == "none" {
+			return fmt.Errorf("egress: %s policy needs a network", s.Egress.Mode)
+		}
+	}
+	return nil
+}
+
+// Validate checks JobSpec for invalid values.
+func (s JobSpec) Validate() error {
+	if s.BackoffLimit < 0 {
+		return errors.New("backoff limit cannot be negative")
+	}
+	if s.ActiveDeadline < 0 {
+		return errors.New("active deadline cannot be negative")
+	}
+	if s.TTLAfterFinished < 0 {
+		return errors.New("ttl after finished cannot be negative")
+	}
+	return nil
+}
+
+// Validate checks ScratchSpec for required fields.
+func (s ScratchSpec) Validate() error {
+	if s.MountPath == "" {
+		return errors.New("mount path is required")
+	}
+	if s.SizeLimitBytes < 0 {
+		return errors.New("size limit cannot be negative")
+	}
 	return nil
 }
 
@@ -41,5 +92,87 @@ func (r ResourceSpec) Validate() error {
 	if r.DiskBytes < 0 {
 		return errors.New("disk limit cannot be negative")
 	}
+	if r.GPUs < 0 {
+		return errors.New("gpu count cannot be negative")
+	}
+	return nil
+}
+
+// Validate checks PodTemplate for invalid tolerations and affinity rules.
+func (t PodTemplate) Validate() error {
+	for i, tol := range t.Tolerations {
+		if err := tol.Validate(); err != nil {
+			return fmt.Errorf("toleration[%d]: %w", i, err)
+		}
+	}
+	if a := t.Affinity; a != nil {
+		for i, term := range a.RequiredNodeTerms {
+			if err := term.Validate(); err != nil {
+				return fmt.Errorf("required node term[%d]: %w", i, err)
+			}
+		}
+		for i, p := range a.PreferredNodeTerms {
+			if p.Weight < 1 || p.Weight > 100 {
+				return fmt.Errorf("preferred node term[%d]: weight %d must be between 1 and 100", i, p.Weight)
+			}
+			if err := p.Term.Validate(); err != nil {
+				return fmt.Errorf("preferred node term[%d]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks Toleration for a known operator and effect.
+func (t Toleration) Validate() error {
+	switch t.Operator {
+	case "", TolerationOpEqual:
+		if t.Key == "" {
+			return errors.New("key is required with the Equal operator")
+		}
+	case TolerationOpExists:
+		if t.Value != "" {
+			return errors.New("value must be empty with the Exists operator")
+		}
+	default:
+		return fmt.Errorf("unknown operator %q", t.Operator)
+	}
+	switch t.Effect {
+	case "", TaintEffectNoSchedule, TaintEffectPreferNoSchedule, TaintEffectNoExecute:
+	default:
+		return fmt.Errorf("unknown effect %q", t.Effect)
+	}
+	if t.TolerationSeconds != nil && t.Effect != TaintEffectNoExecute {
+		return errors.New("toleration seconds require the NoExecute effect")
+	}
+	return nil
+}
+
+// Validate checks NodeSelectorTerm requirements against their operators.
+func (t NodeSelectorTerm) Validate() error {
+	if len(t.MatchExpressions) == 0 {
+		return errors.New("at least one match expression is required")
+	}
+	for _, r := range t.MatchExpressions {
+		if r.Key == "" {
+			return errors.New("key is required")
+		}
+		switch r.Operator {
+		case NodeSelectorOpIn, NodeSelectorOpNotIn:
+			if len(r.Values) == 0 {
+				return fmt.Errorf("%s %s requires values", r.Key, r.Operator)
+			}
+		case NodeSelectorOpExists, NodeSelectorOpDoesNotExist:
+			if len(r.Values) > 0 {
+				return fmt.Errorf("%s %s takes no values", r.Key, r.Operator)
+			}
+		case NodeSelectorOpGt, NodeSelectorOpLt:
+			if len(r.Values) != 1 {
+				return fmt.Errorf("%s %s takes exactly one value", r.Key, r.Operator)
+			}
+		default:
+			return fmt.Errorf("%s: unknown operator %q", r.Key, r.Operator)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,315 @@
+package nspawn
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// ClientConfig configures a Client.
+type ClientConfig struct {
+	// NspawnPath is the systemd-nspawn binary.
+	// Default: systemd-nspawn
+	NspawnPath string
+
+	// MachinectlPath is the machinectl binary, used to terminate machines
+	// that outlive a cancelled run.
+	// Default: machinectl
+	MachinectlPath string
+
+	// StopTimeout is how long a cancelled machine gets to exit after
+	// SIGTERM before it is killed.
+	// Default: 5s
+	StopTimeout time.Duration
+}
+
+// Client runs machines by invoking systemd-nspawn. It implements
+// MachineRunner and HealthChecker.
+//
+// Contract:
+// - Concurrency: safe for concurrent use; each run registers a uniquely named machine.
+// - Privileges: the process must be root, as systemd-nspawn requires.
+// - Errors: timeouts wrap runtime.ErrTimeout; failures to start are *ClientError.
+type Client struct {
+	nspawnPath     string
+	machinectlPath string
+	stopTimeout    time.Duration
+}
+
+// NewClient creates a Client.
+func NewClient(cfg ClientConfig) *Client {
+	nspawnPath := cfg.NspawnPath
+	if nspawnPath == "" {
+		nspawnPath = "systemd-nspawn"
+	}
+	machinectlPath := cfg.MachinectlPath
+	if machinectlPath == "" {
+		machinectlPath = "machinectl"
+	}
+	stopTimeout := cfg.StopTimeout
+	if stopTimeout <= 0 {
+		stopTimeout = 5 * time.Second
+	}
+	return &Client{
+		nspawnPath:     nspawnPath,
+		machinectlPath: machinectlPath,
+		stopTimeout:    stopTimeout,
+	}
+}
+
+// Run implements MachineRunner.
+func (c *Client) Run(ctx context.Context, spec MachineSpec) (MachineResult, error) {
+	if err := spec.Validate(); err != nil {
+		return MachineResult{}, err
+	}
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spec.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	name := machineName()
+
+	// The workspace is a host directory bound into the machine, so its
+	// files are staged and collected directly.
+	var workspace string
+	if spec.Staging != nil {
+		dir, err := stage(spec.Staging)
+		if err != nil {
+			return MachineResult{}, &ClientError{Op: "stage", Machine: name, Err: err}
+		}
+		defer func() { _ = os.RemoveAll(dir) }()
+		workspace = dir
+		spec.Binds = append(spec.Binds, Bind{Source: dir, Target: spec.Staging.Dir})
+	}
+
+	cmd := exec.CommandContext(ctx, c.nspawnPath, nspawnArgs(name, spec)...)
+	cmd.Stdin = bytes.NewReader(spec.Stdin)
+	// systemd-nspawn shuts the machine down on SIGTERM.
+	cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+	cmd.WaitDelay = c.stopTimeout
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	var lines []io.Closer
+	if spec.LogStreamer != nil {
+		stdoutLines := runtime.NewLineWriter(spec.LogStreamer, runtime.LogStdout)
+		stderrLines := runtime.NewLineWriter(spec.LogStreamer, runtime.LogStderr)
+		lines = append(lines, stdoutLines, stderrLines)
+		cmd.Stdout = io.MultiWriter(&stdout, stdoutLines)
+		cmd.Stderr = io.MultiWriter(&stderr, stderrLines)
+	}
+
+	runErr := cmd.Run()
+	for _, l := range lines {
+		_ = l.Close()
+	}
+	result := MachineResult{
+		MachineName: name,
+		Stdout:      stdout.String(),
+		Stderr:      stderr.String(),
+		Duration:    time.Since(start),
+	}
+	if err := ctx.Err(); err != nil {
+		c.terminate(name)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return result, fmt.Errorf("%w: %v", runtime.ErrTimeout, err)
+		}
+		return result, err
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(runErr, &exitErr):
+		// systemd-nspawn exits with the command's status.
+		result.ExitCode = exitErr.ExitCode()
+	case runErr != nil:
+		return result, &ClientError{Op: "run", Machine: name, Err: fmt.Errorf("%w: %w", ErrMachineFailed, runErr)}
+	}
+
+	if workspace != "" {
+		// Host directories have no size limit, so the workspace is
+		// checked after the fact.
+		if limit := spec.Staging.MaxBytes; limit > 0 {
+			used, err := runtime.DirSize(workspace)
+			if err != nil {
+				return result, &ClientError{Op: "collect", Machine: name, Err: err}
+			}
+			if used > limit {
+				return result, fmt.Errorf("%w: workspace used %d bytes, limit %d", runtime.ErrResourceLimit, used, limit)
+			}
+		}
+		artifacts, err := runtime.CollectArtifacts(filepath.Join(workspace, filepath.FromSlash(spec.Staging.OutputDir)), spec.Staging.MaxBytes)
+		if err != nil {
+			return result, &ClientError{Op: "collect", Machine: name, Err: err}
+		}
+		result.Artifacts = artifacts
+	}
+	return result, nil
+}
+
+// stage creates a host workspace holding the staged files and an empty
+// output directory. Directories are world-writable with the sticky bit
+// because the machine's users map to an unprivileged host UID range.
+func stage(s *runtime.Staging) (string, error) {
+	dir, err := os.MkdirTemp("", "toolruntime-nspawn-*")
+	if err != nil {
+		return "", err
+	}
+	output := filepath.Join(dir, filepath.FromSlash(s.OutputDir))
+	err = runtime.WriteFiles(dir, s.Files)
+	if err == nil {
+		err = os.MkdirAll(output, 0o755)
+	}
+	if err == nil {
+		err = os.Chmod(dir, 0o777|os.ModeSticky)
+	}
+	if err == nil {
+		err = os.Chmod(output, 0o777|os.ModeSticky)
+	}
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// terminate stops a machine that outlived its run, best-effort.
+func (c *Client) terminate(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.stopTimeout)
+	defer cancel()
+	_ = exec.CommandContext(ctx, c.machinectlPath, "terminate", name).Run()
+}
+
+// nspawnArgs returns the systemd-nspawn arguments that run spec as machine
+// name. The machine is registered with systemd-machined so the resource
+// properties apply to its scope unit, and host settings files and
+// journal linking are ignored so only spec decides the sandbox.
+func nspawnArgs(name string, spec MachineSpec) []string {
+	args := []string{
+		"--quiet",
+		"--register=yes",
+		"--machine=" + name,
+		"--settings=no",
+		"--link-journal=no",
+		"--console=pipe",
+	}
+	if spec.Image != "" {
+		args = append(args, "--image="+spec.Image)
+	} else {
+		args = append(args, "--directory="+spec.Directory)
+	}
+
+	sec := spec.Security
+	if sec.ReadOnly {
+		args = append(args, "--read-only")
+	} else {
+		args = append(args, "--volatile=overlay")
+	}
+	if sec.PrivateNetwork {
+		args = append(args, "--private-network")
+	}
+	if sec.PrivateUsers != "" {
+		args = append(args, "--private-users="+sec.PrivateUsers)
+		if sec.PrivateUsers != "no" {
+			// Idmapped mounts, so the read-only tree needs no chown.
+			args = append(args, "--private-users-ownership=auto")
+		}
+	}
+	if sec.User != "" {
+		args = append(args, "--user="+sec.User)
+	}
+	if sec.NoNewPrivileges {
+		args = append(args, "--no-new-privileges=yes")
+	}
+	if sec.SystemCallFilter != "" {
+		args = append(args, "--system-call-filter="+sec.SystemCallFilter)
+	}
+
+	res := spec.Resources
+	if res.MemoryBytes > 0 {
+		args = append(args,
+			"--property=MemoryMax="+strconv.FormatInt(res.MemoryBytes, 10),
+			"--property=MemorySwapMax=0")
+	}
+	if res.CPUQuotaPercent > 0 {
+		args = append(args, "--property=CPUQuota="+strconv.FormatInt(res.CPUQuotaPercent, 10)+"%")
+	}
+	if res.TasksMax > 0 {
+		args = append(args, "--property=TasksMax="+strconv.FormatInt(res.TasksMax, 10))
+	}
+	for _, p := range res.Properties {
+		args = append(args, "--property="+p)
+	}
+
+	for _, b := range spec.Binds {
+		flag := "--bind="
+		if b.ReadOnly {
+			flag = "--bind-ro="
+		}
+		args = append(args, flag+b.Source+":"+b.Target)
+	}
+	if spec.WorkingDir != "" {
+		args = append(args, "--chdir="+spec.WorkingDir)
+	}
+	for _, env := range spec.Env {
+		args = append(args, "--setenv="+env)
+	}
+
+	args = append(args, "--")
+	return append(args, spec.Command...)
+}
+
+// machineName returns a unique machine name.
+func machineName() string {
+	var b [6]byte
+	_, _ = rand.Read(b[:])
+	return "toolexec-" + hex.EncodeToString(b[:])
+}
+
+// Ping implements HealthChecker.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Info(ctx)
+	return err
+}
+
+// Info implements HealthChecker. It parses the output of
+// systemd-nspawn --version:
+//
+//	systemd 255 (255.4-1ubuntu8)
+//	+PAM +AUDIT +SELINUX ...
+func (c *Client) Info(ctx context.Context) (HostInfo, error) {
+	out, err := exec.CommandContext(ctx, c.nspawnPath, "--version").Output()
+	if err != nil {
+		return HostInfo{}, fmt.Errorf("%w: %v", ErrNspawnNotAvailable, err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Scan()
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 2 || fields[0] != "systemd" {
+		return HostInfo{}, fmt.Errorf("%w: unexpected version output %q", ErrNspawnNotAvailable, scanner.Text())
+	}
+	info := HostInfo{SystemdVersion: fields[1]}
+	if scanner.Scan() {
+		info.Features = strings.Fields(scanner.Text())
+	}
+	return info, nil
+}
+
+var (
+	_ MachineRunner = (*Client)(nil)
+	_ HealthChecker = (*Client)(nil)
+)
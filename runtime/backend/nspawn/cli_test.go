@@ -0,0 +1,160 @@
+package nspawn
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// fakeNspawn writes a shell script standing in for systemd-nspawn. It
+// records its arguments, writes result.txt to the output directory of
+// any read-write bind, echoes stdin, and exits with the given status.
+func fakeNspawn(t *testing.T, body string) (path, argsFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	argsFile = filepath.Join(dir, "args")
+	script := `#!/bin/sh
+if [ "$1" = "--version" ]; then
+  echo "systemd 255 (255.4-1ubuntu8)"
+  echo "+PAM +AUDIT +SECCOMP"
+  exit 0
+fi
+printf '%s\n' "$@" > ` + argsFile + `
+for arg in "$@"; do
+  case "$arg" in
+    --bind=*) src=${arg#--bind=}; src=${src%%:*}; echo done > "$src/out/result.txt" ;;
+  esac
+done
+` + body
+	path = filepath.Join(dir, "systemd-nspawn")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path, argsFile
+}
+
+func TestNspawnArgs(t *testing.T) {
+	spec := MachineSpec{
+		Directory:  "/var/lib/machines/sandbox",
+		Command:    []string{"/usr/local/bin/toolruntime-sandbox"},
+		WorkingDir: "/workspace",
+		Env:        []string{"A=1"},
+		Binds:      []Bind{{Source: "/srv/data", Target: "/data", ReadOnly: true}},
+		Resources:  ResourceSpec{MemoryBytes: 1 << 20, CPUQuotaPercent: 50, TasksMax: 32, Properties: []string{"IOWeight=50"}},
+		Security: SecuritySpec{
+			User: "nobody", PrivateNetwork: true, PrivateUsers: "pick", ReadOnly: true, NoNewPrivileges: true,
+			SystemCallFilter: "~@mount",
+		},
+	}
+	want := []string{
+		"--quiet", "--register=yes", "--machine=m", "--settings=no", "--link-journal=no", "--console=pipe",
+		"--directory=/var/lib/machines/sandbox",
+		"--read-only", "--private-network", "--private-users=pick", "--private-users-ownership=auto",
+		"--user=nobody", "--no-new-privileges=yes", "--system-call-filter=~@mount",
+		"--property=MemoryMax=1048576", "--property=MemorySwapMax=0", "--property=CPUQuota=50%",
+		"--property=TasksMax=32", "--property=IOWeight=50",
+		"--bind-ro=/srv/data:/data", "--chdir=/workspace", "--setenv=A=1",
+		"--", "/usr/local/bin/toolruntime-sandbox",
+	}
+	if got := nspawnArgs("m", spec); !reflect.DeepEqual(got, want) {
+		t.Errorf("nspawnArgs() =\n%q\nwant\n%q", got, want)
+	}
+
+	spec = MachineSpec{Image: "/srv/sandbox.raw", Command: []string{"sh"}}
+	got := strings.Join(nspawnArgs("m", spec), " ")
+	if !strings.Contains(got, "--image=/srv/sandbox.raw --volatile=overlay") || strings.Contains(got, "--private-network") {
+		t.Errorf("nspawnArgs() = %s", got)
+	}
+}
+
+func TestClientRun(t *testing.T) {
+	path, argsFile := fakeNspawn(t, "cat\necho warn >&2\nexit 3\n")
+	c := NewClient(ClientConfig{NspawnPath: path})
+
+	var (
+		mu       sync.Mutex
+		streamed []string
+	)
+	result, err := c.Run(context.Background(), MachineSpec{
+		Directory: "/var/lib/machines/sandbox",
+		Command:   []string{"/usr/local/bin/toolruntime-sandbox"},
+		Stdin:     []byte("print(1)\n"),
+		Staging:   &runtime.Staging{Dir: "/workspace", OutputDir: "out", Files: map[string][]byte{"in.txt": []byte("hi")}},
+		LogStreamer: runtime.LogStreamerFunc(func(stream runtime.LogStream, line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			streamed = append(streamed, string(stream)+":"+line)
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.ExitCode != 3 || result.Stdout != "print(1)\n" || result.Stderr != "warn\n" {
+		t.Errorf("Run() = %+v", result)
+	}
+	if !strings.HasPrefix(result.MachineName, "toolexec-") {
+		t.Errorf("MachineName = %q", result.MachineName)
+	}
+	if len(result.Artifacts) != 1 || result.Artifacts[0].Name != "result.txt" || string(result.Artifacts[0].Data) != "done\n" {
+		t.Errorf("Artifacts = %+v", result.Artifacts)
+	}
+	slices.Sort(streamed)
+	if !reflect.DeepEqual(streamed, []string{"stderr:warn", "stdout:print(1)"}) {
+		t.Errorf("streamed = %q", streamed)
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), "--machine="+result.MachineName+"\n") || !strings.Contains(string(args), ":/workspace\n") {
+		t.Errorf("args = %s", args)
+	}
+}
+
+func TestClientRunTimeout(t *testing.T) {
+	path, _ := fakeNspawn(t, "exec sleep 10\n")
+	c := NewClient(ClientConfig{NspawnPath: path, MachinectlPath: "true", StopTimeout: time.Second})
+
+	_, err := c.Run(context.Background(), MachineSpec{
+		Directory: "/var/lib/machines/sandbox",
+		Command:   []string{"sh"},
+		Timeout:   50 * time.Millisecond,
+	})
+	if !errors.Is(err, runtime.ErrTimeout) {
+		t.Errorf("Run() error = %v, want %v", err, runtime.ErrTimeout)
+	}
+
+	c = NewClient(ClientConfig{NspawnPath: filepath.Join(t.TempDir(), "missing")})
+	_, err = c.Run(context.Background(), MachineSpec{Directory: "/srv/tree", Command: []string{"sh"}})
+	var clientErr *ClientError
+	if !errors.As(err, &clientErr) || !errors.Is(err, ErrMachineFailed) {
+		t.Errorf("Run() error = %v, want a *ClientError wrapping %v", err, ErrMachineFailed)
+	}
+}
+
+func TestClientInfo(t *testing.T) {
+	path, _ := fakeNspawn(t, "")
+	c := NewClient(ClientConfig{NspawnPath: path})
+	info, err := c.Info(context.Background())
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	if info.SystemdVersion != "255" || !reflect.DeepEqual(info.Features, []string{"+PAM", "+AUDIT", "+SECCOMP"}) {
+		t.Errorf("Info() = %+v", info)
+	}
+
+	c = NewClient(ClientConfig{NspawnPath: filepath.Join(t.TempDir(), "missing")})
+	if err := c.Ping(context.Background()); !errors.Is(err, ErrNspawnNotAvailable) {
+		t.Errorf("Ping() error = %v, want %v", err, ErrNspawnNotAvailable)
+	}
+}
@@ -0,0 +1,29 @@
+package nspawn
+
+import "context"
+
+// MachineRunner is the primary interface for machine execution.
+// Implementations may invoke systemd-nspawn directly, go through
+// systemd-machined over D-Bus, or mock the host in tests.
+//
+// Implementations are expected to:
+//   - Start an ephemeral machine from the spec's tree or image
+//   - Apply resource limits as unit properties
+//   - Capture stdout/stderr
+//   - Respect context cancellation and spec timeout
+//   - Terminate and unregister the machine when done
+type MachineRunner interface {
+	// Run executes a command in an ephemeral machine and returns the result.
+	// The machine lifecycle (start, run, terminate) is atomic.
+	Run(ctx context.Context, spec MachineSpec) (MachineResult, error)
+}
+
+// HealthChecker verifies that the host can run machines.
+// This is an optional interface - backends may skip health checks.
+type HealthChecker interface {
+	// Ping checks if systemd-nspawn is usable.
+	Ping(ctx context.Context) error
+
+	// Info returns host systemd information.
+	Info(ctx context.Context) (HostInfo, error)
+}
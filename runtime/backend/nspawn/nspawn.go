@@ -0,0 +1,392 @@
+// Package nspawn provides a backend that executes code in ephemeral
+// systemd-nspawn machines, for bare-metal Linux hosts that standardize on
+// systemd rather than a container daemon.
+//
+// Each execution starts a machine from a prepared OS tree (or raw disk
+// image), registered with systemd-machined so resource limits apply as
+// properties of its scope unit: MemoryMax=, CPUQuota=, and TasksMax=.
+// The machine's network is private, its users are mapped to an unused
+// host UID range, and nothing it writes outside the workspace survives.
+//
+// The package includes Client, a MachineRunner and HealthChecker that
+// invokes the systemd-nspawn binary; it must run as root:
+//
+//	client := nspawn.NewClient(nspawn.ClientConfig{})
+//	backend := nspawn.New(nspawn.Config{
+//		Directory:     "/var/lib/machines/toolruntime-sandbox",
+//		Client:        client,
+//		HealthChecker: client,
+//	})
+//
+// The sandbox entrypoint inside the tree receives the code on stdin.
+package nspawn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// Errors for systemd-nspawn backend operations.
+var (
+	// ErrNspawnNotAvailable is returned when systemd-nspawn is not usable.
+	ErrNspawnNotAvailable = fmt.Errorf("systemd-nspawn not available: %w", runtime.ErrRuntimeUnavailable)
+
+	// ErrClientNotConfigured is returned when no MachineRunner is configured.
+	ErrClientNotConfigured = errors.New("nspawn client not configured")
+
+	// ErrMachineFailed is returned when the machine cannot be started.
+	ErrMachineFailed = errors.New("machine execution failed")
+
+	// ErrSecurityViolation is returned when a security policy is violated.
+	ErrSecurityViolation = errors.New("security policy violation")
+)
+
+// DefaultDirectory is the OS tree used when Config.Directory and
+// Config.Image are empty.
+const DefaultDirectory = "/var/lib/machines/toolruntime-sandbox"
+
+// DefaultCommand is the sandbox entrypoint used when Config.Command is
+// empty.
+var DefaultCommand = []string{"/usr/local/bin/toolruntime-sandbox"}
+
+// DefaultSystemCallFilter is the hardened profile's system call filter
+// when Config.SystemCallFilter is empty. It denies the groups a snippet
+// has no use for, on top of systemd-nspawn's default filter.
+const DefaultSystemCallFilter = "~@clock @cpu-emulation @debug @module @mount @obsolete @raw-io @reboot @swap"
+
+// ClientError wraps client operation errors with context.
+type ClientError struct {
+	// Op is the operation that failed: "stage", "run", "collect".
+	Op string
+
+	// Machine is the machine name.
+	Machine string
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ClientError) Error() string {
+	return fmt.Sprintf("nspawn %s %s: %v", e.Op, e.Machine, e.Err)
+}
+
+func (e *ClientError) Unwrap() error { return e.Err }
+
+// Logger is the interface for logging.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Errors: logging must be best-effort and must not panic.
+type Logger interface {
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// Config configures a systemd-nspawn backend.
+type Config struct {
+	// Directory is the OS tree machines start from. The tree is never
+	// modified: it is mounted read-only or behind a tmpfs overlay.
+	// Default: DefaultDirectory, unless Image is set
+	Directory string
+
+	// Image is a raw disk image machines start from instead of Directory.
+	Image string
+
+	// Command is the sandbox entrypoint inside the tree. It receives the
+	// code on stdin.
+	// Default: DefaultCommand
+	Command []string
+
+	// PrivateUsers is the user namespace mode, e.g. "pick" or "identity".
+	// Default: pick
+	PrivateUsers string
+
+	// SystemCallFilter is the system call filter for hardened mode.
+	// Default: DefaultSystemCallFilter
+	SystemCallFilter string
+
+	// Properties are additional unit properties applied to every
+	// machine, such as "IOWeight=50".
+	Properties []string
+
+	// Client is the machine runner implementation, such as a *Client.
+	// If nil, Execute() returns ErrClientNotConfigured.
+	Client MachineRunner
+
+	// HealthChecker optionally verifies the host before execution.
+	// If nil, health checks are skipped.
+	HealthChecker HealthChecker
+
+	// Logger is an optional logger for backend events.
+	Logger Logger
+}
+
+// Backend executes code in ephemeral systemd-nspawn machines.
+type Backend struct {
+	directory        string
+	image            string
+	command          []string
+	privateUsers     string
+	systemCallFilter string
+	properties       []string
+	client           MachineRunner
+	health           HealthChecker
+	logger           Logger
+}
+
+// New creates a new systemd-nspawn backend with the given configuration.
+func New(cfg Config) *Backend {
+	directory := cfg.Directory
+	if directory == "" && cfg.Image == "" {
+		directory = DefaultDirectory
+	}
+
+	command := cfg.Command
+	if len(command) == 0 {
+		command = DefaultCommand
+	}
+
+	privateUsers := cfg.PrivateUsers
+	if privateUsers == "" {
+		privateUsers = "pick"
+	}
+
+	systemCallFilter := cfg.SystemCallFilter
+	if systemCallFilter == "" {
+		systemCallFilter = DefaultSystemCallFilter
+	}
+
+	return &Backend{
+		directory:        directory,
+		image:            cfg.Image,
+		command:          command,
+		privateUsers:     privateUsers,
+		systemCallFilter: systemCallFilter,
+		properties:       cfg.Properties,
+		client:           cfg.Client,
+		health:           cfg.HealthChecker,
+		logger:           cfg.Logger,
+	}
+}
+
+// Kind returns the backend kind identifier.
+func (b *Backend) Kind() runtime.BackendKind {
+	return runtime.BackendNspawn
+}
+
+// Capabilities implements runtime.CapabilityReporter. Only dev executions
+// share the host network.
+func (b *Backend) Capabilities() runtime.Capabilities {
+	return runtime.Capabilities{
+		Streaming:    true,
+		Workspace:    true,
+		FileStaging:  true,
+		NetworkModes: []string{"none", "host"},
+	}
+}
+
+// Execute runs code in an ephemeral systemd-nspawn machine.
+func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
+	if err := ctx.Err(); err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+	if err := req.Validate(); err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+
+	if b.client == nil {
+		return runtime.ExecuteResult{}, ErrClientNotConfigured
+	}
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	if b.health != nil {
+		if err := b.health.Ping(ctx); err != nil {
+			return runtime.ExecuteResult{}, fmt.Errorf("%w: %v", ErrNspawnNotAvailable, err)
+		}
+	}
+
+	profile := req.Profile
+	if profile == "" {
+		profile = runtime.ProfileStandard
+	}
+
+	spec, err := b.buildSpec(req, profile)
+	if err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+
+	if b.logger != nil {
+		b.logger.Info("executing in systemd-nspawn machine",
+			"profile", profile,
+			"directory", spec.Directory,
+			"image", spec.Image,
+			"privateNetwork", spec.Security.PrivateNetwork,
+			"privateUsers", spec.Security.PrivateUsers)
+	}
+
+	machineResult, err := b.client.Run(ctx, spec)
+	info := b.backendInfo(profile, machineResult.MachineName)
+	if err != nil {
+		return runtime.ExecuteResult{
+			Stdout:   machineResult.Stdout,
+			Stderr:   machineResult.Stderr,
+			Duration: time.Since(start),
+			Backend:  info,
+		}, err
+	}
+
+	return runtime.ExecuteResult{
+		Value:     extractOutValue(machineResult.Stdout),
+		Stdout:    machineResult.Stdout,
+		Stderr:    machineResult.Stderr,
+		Duration:  machineResult.Duration,
+		Backend:   info,
+		Usage:     runtime.ResourceUsage{WallTime: machineResult.Duration},
+		Artifacts: machineResult.Artifacts,
+		LimitsEnforced: runtime.LimitsEnforced{
+			Timeout:    true,
+			Memory:     spec.Resources.MemoryBytes > 0,
+			CPU:        spec.Resources.CPUQuotaPercent > 0,
+			Pids:       spec.Resources.TasksMax > 0,
+			ToolCalls:  true,
+			ChainSteps: true,
+		},
+	}, nil
+}
+
+var _ runtime.Backend = (*Backend)(nil)
+
+func (b *Backend) backendInfo(profile runtime.SecurityProfile, machine string) runtime.BackendInfo {
+	details := map[string]any{
+		"profile":      string(profile),
+		"privateUsers": b.privateUsers,
+	}
+	if b.image != "" {
+		details["image"] = b.image
+	} else {
+		details["directory"] = b.directory
+	}
+	if machine != "" {
+		details["machine"] = machine
+	}
+	return runtime.BackendInfo{
+		Kind:      runtime.BackendNspawn,
+		Readiness: runtime.ReadinessBeta,
+		Details:   details,
+	}
+}
+
+func (b *Backend) buildSpec(req runtime.ExecuteRequest, profile runtime.SecurityProfile) (MachineSpec, error) {
+	staging, err := req.Staging()
+	if err != nil {
+		return MachineSpec{}, err
+	}
+
+	spec := MachineSpec{
+		Directory: b.directory,
+		Image:     b.image,
+		Command:   b.command,
+		Stdin:     []byte(req.Code),
+		Resources: ResourceSpec{
+			Properties: b.properties,
+		},
+		Security: SecuritySpec{
+			// The nobody user exists in every distribution's base tree.
+			User:            "nobody",
+			PrivateNetwork:  true,
+			PrivateUsers:    b.privateUsers,
+			ReadOnly:        true,
+			NoNewPrivileges: true,
+		},
+		Timeout: req.Timeout,
+		Labels: map[string]string{
+			"runtime.profile":        string(profile),
+			"runtime.backend":        string(runtime.BackendNspawn),
+			runtime.LabelExecutionID: req.EnsureExecutionID(),
+		},
+		LogStreamer: req.LogStreamer,
+	}
+
+	switch profile {
+	case runtime.ProfileDev:
+		// Dev mode: host network and a writable (overlaid) tree
+		spec.Security.PrivateNetwork = false
+		spec.Security.ReadOnly = false
+	case runtime.ProfileHardened:
+		spec.Security.SystemCallFilter = b.systemCallFilter
+	}
+
+	if req.Limits.MemoryBytes > 0 {
+		spec.Resources.MemoryBytes = req.Limits.MemoryBytes
+	}
+	if req.Limits.CPUQuotaMillis > 0 {
+		// CPUQuotaMillis is per 100ms period, as for the container
+		// backends, so 100ms is one full CPU.
+		spec.Resources.CPUQuotaPercent = req.Limits.CPUQuotaMillis
+	}
+	if req.Limits.PidsMax > 0 {
+		spec.Resources.TasksMax = req.Limits.PidsMax
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(req.Env)) {
+		spec.Env = append(spec.Env, key+"="+req.Env[key])
+	}
+	if staging != nil {
+		spec.WorkingDir = staging.Dir
+		spec.Env = append(spec.Env,
+			runtime.WorkspaceEnv+"="+staging.Dir,
+			runtime.OutputEnv+"="+staging.OutputPath())
+		spec.Staging = staging
+	}
+
+	if err := spec.Validate(); err != nil {
+		return MachineSpec{}, err
+	}
+	return spec, nil
+}
+
+// extractOutValue extracts the __out value from stdout if present.
+func extractOutValue(stdout string) any {
+	lines := strings.Split(stdout, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "__OUT__:") {
+			jsonStr := strings.TrimPrefix(line, "__OUT__:")
+			var value any
+			if err := json.Unmarshal([]byte(jsonStr), &value); err == nil {
+				return value
+			}
+			return jsonStr
+		}
+		if strings.HasPrefix(line, "{") && strings.HasSuffix(line, "}") {
+			var payload map[string]any
+			if err := json.Unmarshal([]byte(line), &payload); err == nil {
+				if value, ok := payload["__out"]; ok {
+					return value
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package nspawn
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// mockGateway implements runtime.ToolGateway for testing.
+type mockGateway struct{}
+
+func (m *mockGateway) SearchTools(_ context.Context, _ string, _ int) ([]index.Summary, error) {
+	return nil, nil
+}
+
+func (m *mockGateway) ListNamespaces(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockGateway) DescribeTool(_ context.Context, _ string, _ tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	return tooldoc.ToolDoc{}, nil
+}
+
+func (m *mockGateway) ListToolExamples(_ context.Context, _ string, _ int) ([]tooldoc.ToolExample, error) {
+	return nil, nil
+}
+
+func (m *mockGateway) RunTool(_ context.Context, _ string, _ map[string]any) (run.RunResult, error) {
+	return run.RunResult{}, nil
+}
+
+func (m *mockGateway) RunChain(_ context.Context, _ []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	return run.RunResult{}, nil, nil
+}
+
+type mockMachineRunner struct {
+	spec   MachineSpec
+	result MachineResult
+	err    error
+}
+
+func (m *mockMachineRunner) Run(_ context.Context, spec MachineSpec) (MachineResult, error) {
+	m.spec = spec
+	return m.result, m.err
+}
+
+type mockHealthChecker struct {
+	err error
+}
+
+func (m *mockHealthChecker) Ping(_ context.Context) error { return m.err }
+
+func (m *mockHealthChecker) Info(_ context.Context) (HostInfo, error) {
+	return HostInfo{SystemdVersion: "255"}, m.err
+}
+
+func TestBackendDefaults(t *testing.T) {
+	b := New(Config{})
+	if b.Kind() != runtime.BackendNspawn {
+		t.Errorf("Kind() = %v, want %v", b.Kind(), runtime.BackendNspawn)
+	}
+	if b.directory != DefaultDirectory || b.privateUsers != "pick" || !reflect.DeepEqual(b.command, DefaultCommand) {
+		t.Errorf("defaults = %q, %q, %v", b.directory, b.privateUsers, b.command)
+	}
+	if b := New(Config{Image: "/var/lib/machines/sandbox.raw"}); b.directory != "" {
+		t.Errorf("directory = %q, want none with an image", b.directory)
+	}
+
+	_, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}})
+	if !errors.Is(err, ErrClientNotConfigured) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrClientNotConfigured)
+	}
+}
+
+func TestBackendProfiles(t *testing.T) {
+	tests := []struct {
+		profile runtime.SecurityProfile
+		want    SecuritySpec
+	}{
+		{runtime.ProfileDev, SecuritySpec{User: "nobody", PrivateUsers: "pick", NoNewPrivileges: true}},
+		{runtime.ProfileStandard, SecuritySpec{User: "nobody", PrivateNetwork: true, PrivateUsers: "pick", ReadOnly: true, NoNewPrivileges: true}},
+		{runtime.ProfileHardened, SecuritySpec{
+			User: "nobody", PrivateNetwork: true, PrivateUsers: "pick", ReadOnly: true, NoNewPrivileges: true,
+			SystemCallFilter: DefaultSystemCallFilter,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.profile), func(t *testing.T) {
+			runner := &mockMachineRunner{}
+			b := New(Config{Client: runner})
+			if _, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}, Profile: tt.profile}); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if !reflect.DeepEqual(runner.spec.Security, tt.want) {
+				t.Errorf("Security = %+v, want %+v", runner.spec.Security, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackendExecute(t *testing.T) {
+	runner := &mockMachineRunner{result: MachineResult{MachineName: "toolexec-1", Stdout: "log\n__OUT__:{\"ok\":true}\n"}}
+	b := New(Config{Client: runner, HealthChecker: &mockHealthChecker{}, Properties: []string{"IOWeight=50"}})
+	result, err := b.Execute(context.Background(), runtime.ExecuteRequest{
+		Code:      "print(1)",
+		Gateway:   &mockGateway{},
+		Env:       map[string]string{"B": "2", "A": "1"},
+		Limits:    runtime.Limits{MemoryBytes: 256 << 20, CPUQuotaMillis: 50, PidsMax: 64},
+		Workspace: &runtime.Workspace{},
+		Files:     map[string]runtime.File{"in.txt": {Data: []byte("hi")}},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	spec := runner.spec
+	if string(spec.Stdin) != "print(1)" || spec.Directory != DefaultDirectory {
+		t.Errorf("spec = %+v", spec)
+	}
+	wantRes := ResourceSpec{MemoryBytes: 256 << 20, CPUQuotaPercent: 50, TasksMax: 64, Properties: []string{"IOWeight=50"}}
+	if !reflect.DeepEqual(spec.Resources, wantRes) {
+		t.Errorf("Resources = %+v, want %+v", spec.Resources, wantRes)
+	}
+	ws := runtime.Workspace{}
+	wantEnv := []string{"A=1", "B=2", runtime.WorkspaceEnv + "=" + ws.MountPath(), runtime.OutputEnv + "=" + spec.Staging.OutputPath()}
+	if !reflect.DeepEqual(spec.Env, wantEnv) || spec.WorkingDir != ws.MountPath() {
+		t.Errorf("Env = %v, WorkingDir = %q", spec.Env, spec.WorkingDir)
+	}
+	if string(spec.Staging.Files["in.txt"]) != "hi" {
+		t.Errorf("Staging = %+v", spec.Staging)
+	}
+
+	if out, _ := result.Value.(map[string]any); out["ok"] != true {
+		t.Errorf("Value = %v", result.Value)
+	}
+	if !result.LimitsEnforced.Memory || !result.LimitsEnforced.CPU || !result.LimitsEnforced.Pids {
+		t.Errorf("LimitsEnforced = %+v", result.LimitsEnforced)
+	}
+	if result.Backend.Details["machine"] != "toolexec-1" {
+		t.Errorf("Backend = %+v", result.Backend)
+	}
+}
+
+func TestBackendErrors(t *testing.T) {
+	b := New(Config{Client: &mockMachineRunner{}, HealthChecker: &mockHealthChecker{err: errors.New("no systemd")}})
+	_, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}})
+	if !errors.Is(err, ErrNspawnNotAvailable) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrNspawnNotAvailable)
+	}
+
+	b = New(Config{Client: &mockMachineRunner{}, Directory: "relative/tree"})
+	if _, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}}); err == nil {
+		t.Error("Execute() with a relative directory succeeded, want a validation error")
+	}
+
+	runErr := &ClientError{Op: "run", Machine: "toolexec-1", Err: ErrMachineFailed}
+	b = New(Config{Client: &mockMachineRunner{err: runErr, result: MachineResult{Stderr: "boom"}}})
+	result, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}})
+	if !errors.Is(err, ErrMachineFailed) || result.Stderr != "boom" {
+		t.Errorf("Execute() = %+v, %v; want %v with stderr", result, err, ErrMachineFailed)
+	}
+}
+
+func TestMachineSpecValidate(t *testing.T) {
+	valid := MachineSpec{Directory: "/srv/tree", Command: []string{"/bin/true"}}
+	tests := []struct {
+		name   string
+		modify func(*MachineSpec)
+	}{
+		{"no root", func(s *MachineSpec) { s.Directory = "" }},
+		{"directory and image", func(s *MachineSpec) { s.Image = "/srv/tree.raw" }},
+		{"no command", func(s *MachineSpec) { s.Command = nil }},
+		{"private users range", func(s *MachineSpec) { s.Security.PrivateUsers = "65536:65536" }},
+		{"negative memory", func(s *MachineSpec) { s.Resources.MemoryBytes = -1 }},
+		{"bad property", func(s *MachineSpec) { s.Resources.Properties = []string{"IOWeight"} }},
+		{"relative bind", func(s *MachineSpec) { s.Binds = []Bind{{Source: "data", Target: "/data"}} }},
+		{"colon bind", func(s *MachineSpec) { s.Binds = []Bind{{Source: "/a:b", Target: "/data"}} }},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := valid
+			tt.modify(&spec)
+			if err := spec.Validate(); err == nil {
+				t.Error("Validate() succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package nspawn
+
+import (
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// Bind defines a host directory bind-mounted into the machine.
+type Bind struct {
+	// Source is the absolute host path.
+	Source string
+
+	// Target is the absolute path inside the machine.
+	Target string
+
+	// ReadOnly mounts the directory read-only.
+	ReadOnly bool
+}
+
+// ResourceSpec defines resource limits, applied as systemd properties of
+// the machine's scope unit.
+type ResourceSpec struct {
+	// MemoryBytes is the MemoryMax= limit. Swap is disabled when set.
+	// Zero means unlimited.
+	MemoryBytes int64
+
+	// CPUQuotaPercent is the CPUQuota= limit as a percentage of one CPU;
+	// 200 allows two CPUs. Zero means unlimited.
+	CPUQuotaPercent int64
+
+	// TasksMax is the TasksMax= limit on processes and threads.
+	// Zero means the systemd default.
+	TasksMax int64
+
+	// Properties are additional unit properties in Name=Value form,
+	// such as "IOWeight=50".
+	Properties []string
+}
+
+// SecuritySpec defines machine security settings.
+type SecuritySpec struct {
+	// User is the user the command runs as inside the machine.
+	// Empty runs as root inside the machine.
+	User string
+
+	// PrivateNetwork disconnects the machine from the host network,
+	// leaving only a loopback device.
+	PrivateNetwork bool
+
+	// PrivateUsers is the --private-users= mode, e.g. "pick" to map the
+	// machine's users to an unused host UID range. Empty leaves users
+	// unmapped, so root inside is root on the host.
+	PrivateUsers string
+
+	// ReadOnly mounts the OS tree read-only. When false, the tree is
+	// overlaid with a tmpfs and writes are discarded on exit.
+	ReadOnly bool
+
+	// NoNewPrivileges sets PR_SET_NO_NEW_PRIVS for the command.
+	NoNewPrivileges bool
+
+	// SystemCallFilter is a --system-call-filter= list, such as
+	// "~@mount @module". Empty keeps systemd-nspawn's default filter.
+	SystemCallFilter string
+}
+
+// MachineSpec defines what to run in an ephemeral machine and how.
+// Exactly one of Directory and Image is required.
+type MachineSpec struct {
+	// Directory is the OS tree the machine boots from.
+	Directory string
+
+	// Image is a raw disk image the machine boots from.
+	Image string
+
+	// Command is the command to execute (required).
+	Command []string
+
+	// Stdin is passed to the command's standard input.
+	Stdin []byte
+
+	// WorkingDir is the working directory inside the machine.
+	WorkingDir string
+
+	// Env contains environment variables in KEY=value format.
+	Env []string
+
+	// Binds defines host directories mounted into the machine.
+	Binds []Bind
+
+	// Resources defines resource limits.
+	Resources ResourceSpec
+
+	// Security defines security settings.
+	Security SecuritySpec
+
+	// Timeout is the maximum execution duration.
+	Timeout time.Duration
+
+	// Labels are metadata labels for tracking.
+	Labels map[string]string
+
+	// LogStreamer, if set, receives stdout and stderr line by line while
+	// the machine runs.
+	LogStreamer runtime.LogStreamer
+
+	// Staging, if set, lists files to write into the workspace before the
+	// command starts and the output directory to collect after it exits.
+	// MachineRunner implementations bind a host directory at Staging.Dir.
+	Staging *runtime.Staging
+}
+
+// MachineResult captures the output of a machine run.
+type MachineResult struct {
+	// MachineName is the name the machine was registered under.
+	MachineName string
+
+	// ExitCode is the command's exit code.
+	ExitCode int
+
+	// Stdout contains the command's stdout output.
+	Stdout string
+
+	// Stderr contains the command's stderr output.
+	Stderr string
+
+	// Duration is the execution time.
+	Duration time.Duration
+
+	// Artifacts holds the files collected from Staging's output directory.
+	Artifacts []runtime.Artifact
+}
+
+// HostInfo contains systemd metadata for the host.
+type HostInfo struct {
+	// SystemdVersion is the systemd version, such as "255".
+	SystemdVersion string
+
+	// Features lists compile-time features, such as "+SECCOMP".
+	Features []string
+}
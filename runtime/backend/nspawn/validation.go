@@ -0,0 +1,86 @@
+package nspawn
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Validate checks MachineSpec for errors before execution.
+func (s MachineSpec) Validate() error {
+	switch {
+	case s.Directory == "" && s.Image == "":
+		return errors.New("directory or image is required")
+	case s.Directory != "" && s.Image != "":
+		return errors.New("directory and image are mutually exclusive")
+	case s.Directory != "" && !path.IsAbs(s.Directory):
+		return fmt.Errorf("directory %q must be absolute", s.Directory)
+	case s.Image != "" && !path.IsAbs(s.Image):
+		return fmt.Errorf("image %q must be absolute", s.Image)
+	}
+	if len(s.Command) == 0 {
+		return errors.New("command is required")
+	}
+	if err := s.Security.Validate(); err != nil {
+		return fmt.Errorf("security: %w", err)
+	}
+	if err := s.Resources.Validate(); err != nil {
+		return fmt.Errorf("resources: %w", err)
+	}
+	for i, b := range s.Binds {
+		if err := b.Validate(); err != nil {
+			return fmt.Errorf("bind[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks SecuritySpec for policy violations.
+func (s SecuritySpec) Validate() error {
+	switch s.PrivateUsers {
+	case "", "no", "yes", "pick", "identity":
+	default:
+		// A UID range ("65536:65536") is also valid for nspawn, but
+		// picking one is left to systemd.
+		return fmt.Errorf("%w: unsupported private users mode %q", ErrSecurityViolation, s.PrivateUsers)
+	}
+	if strings.ContainsAny(s.User, ":/") {
+		return fmt.Errorf("invalid user %q", s.User)
+	}
+	return nil
+}
+
+// Validate checks ResourceSpec for invalid values.
+func (r ResourceSpec) Validate() error {
+	if r.MemoryBytes < 0 {
+		return errors.New("memory cannot be negative")
+	}
+	if r.CPUQuotaPercent < 0 {
+		return errors.New("cpu quota cannot be negative")
+	}
+	if r.TasksMax < 0 {
+		return errors.New("tasks max cannot be negative")
+	}
+	for _, p := range r.Properties {
+		if name, _, ok := strings.Cut(p, "="); !ok || name == "" {
+			return fmt.Errorf("property %q must be Name=Value", p)
+		}
+	}
+	return nil
+}
+
+// Validate checks Bind for errors.
+func (b Bind) Validate() error {
+	if !path.IsAbs(b.Source) {
+		return fmt.Errorf("source %q must be absolute", b.Source)
+	}
+	if !path.IsAbs(b.Target) {
+		return fmt.Errorf("target %q must be absolute", b.Target)
+	}
+	// nspawn separates bind fields with colons.
+	if strings.Contains(b.Source, ":") || strings.Contains(b.Target, ":") {
+		return fmt.Errorf("bind paths cannot contain ':'")
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package podman
+
+import "context"
+
+// ContainerRunner executes a Podman container for a given spec.
+//
+// Contract:
+// - Concurrency: Implementations must be safe for concurrent use.
+// - Context: Run must honor cancellation, deadlines, and spec.Timeout.
+// - Ownership: Implementations must not mutate the provided spec.
+// - Cleanup: Implementations must remove the container when Run returns.
+type ContainerRunner interface {
+	Run(ctx context.Context, spec ContainerSpec) (ContainerResult, error)
+}
+
+// ImageResolver ensures an image exists locally, pulling it if needed, and
+// returns the resolved reference (which may include a digest).
+type ImageResolver interface {
+	Resolve(ctx context.Context, image string) (string, error)
+}
+
+// HealthChecker verifies Podman service availability.
+type HealthChecker interface {
+	// Ping checks if the Podman service is responsive.
+	Ping(ctx context.Context) error
+
+	// Info returns service information (version, OS, rootless mode, etc.).
+	Info(ctx context.Context) (ServiceInfo, error)
+}
@@ -0,0 +1,49 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// Orphans implements runtime.Reaper. It lists every container, running or
+// not, that carries runtime.LabelExecutionID.
+func (c *Client) Orphans(ctx context.Context) ([]runtime.Orphan, error) {
+	filters, err := json.Marshal(map[string][]string{"label": {runtime.LabelExecutionID}})
+	if err != nil {
+		return nil, err
+	}
+	var containers []struct {
+		ID      string            `json:"Id"`
+		Created time.Time         `json:"Created"`
+		Labels  map[string]string `json:"Labels"`
+	}
+	query := url.Values{"all": {"true"}, "filters": {string(filters)}}
+	if err := c.getJSON(ctx, "/containers/json", query, &containers); err != nil {
+		return nil, &ClientError{Op: "list", Err: err}
+	}
+	orphans := make([]runtime.Orphan, 0, len(containers))
+	for _, ctr := range containers {
+		orphans = append(orphans, runtime.Orphan{
+			Kind:        "container",
+			ID:          ctr.ID,
+			ExecutionID: ctr.Labels[runtime.LabelExecutionID],
+			Created:     ctr.Created,
+		})
+	}
+	return orphans, nil
+}
+
+// Reap implements runtime.Reaper. It force-removes the container and its
+// anonymous volumes.
+func (c *Client) Reap(ctx context.Context, o runtime.Orphan) error {
+	err := c.call(ctx, http.MethodDelete, "/containers/"+o.ID, url.Values{"force": {"true"}, "v": {"true"}}, nil)
+	if err != nil && !isNotFound(err) {
+		return &ClientError{Op: "remove", ContainerID: o.ID, Err: err}
+	}
+	return nil
+}
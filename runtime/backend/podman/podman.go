@@ -0,0 +1,402 @@
+// Package podman provides a backend that executes code in Podman
+// containers. It mirrors the docker backend's container and security specs
+// but talks to the Podman REST service, so it runs on hosts that disallow
+// the Docker daemon, including rootless ones.
+//
+// The package includes Client, a ContainerRunner, ImageResolver, and
+// HealthChecker that speaks the libpod REST API over the Podman unix
+// socket using only the standard library:
+//
+//	client := podman.NewClient(podman.ClientConfig{}) // rootless socket when not root
+//	backend := podman.New(podman.Config{
+//		Client:        client,
+//		ImageResolver: client,
+//		HealthChecker: client,
+//	})
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// Errors for Podman backend operations.
+var (
+	// ErrPodmanNotAvailable is returned when the Podman service is not
+	// available.
+	ErrPodmanNotAvailable = errors.New("podman not available")
+
+	// ErrImageNotFound is returned when the execution image is not found.
+	ErrImageNotFound = errors.New("podman image not found")
+
+	// ErrContainerFailed is returned when container creation/execution fails.
+	ErrContainerFailed = errors.New("container execution failed")
+
+	// ErrClientNotConfigured is returned when no ContainerRunner is configured.
+	ErrClientNotConfigured = errors.New("podman client not configured")
+
+	// ErrImagePull is returned when image pull fails.
+	ErrImagePull = errors.New("image pull failed")
+
+	// ErrServiceUnavailable is returned when the Podman service is not
+	// reachable.
+	ErrServiceUnavailable = fmt.Errorf("podman service unavailable: %w", runtime.ErrRuntimeUnavailable)
+
+	// ErrResourceLimit is returned when a resource limit is exceeded.
+	ErrResourceLimit = errors.New("resource limit exceeded")
+
+	// ErrSecurityViolation is returned when a security policy is violated.
+	ErrSecurityViolation = errors.New("security policy violation")
+)
+
+// ClientError wraps client operation errors with context.
+type ClientError struct {
+	// Op is the operation that failed: "create", "start", "wait", "pull".
+	Op string
+
+	// Image is the image reference.
+	Image string
+
+	// ContainerID is the container ID if available.
+	ContainerID string
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ClientError) Error() string {
+	if e.ContainerID != "" {
+		return fmt.Sprintf("podman %s %s (%s): %v", e.Op, e.Image, e.ContainerID, e.Err)
+	}
+	return fmt.Sprintf("podman %s %s: %v", e.Op, e.Image, e.Err)
+}
+
+func (e *ClientError) Unwrap() error { return e.Err }
+
+// Logger is the interface for logging.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Errors: logging must be best-effort and must not panic.
+type Logger interface {
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// Config configures a Podman backend.
+type Config struct {
+	// ImageRef is the image reference to use for execution.
+	// Default: toolruntime-sandbox:latest
+	ImageRef string
+
+	// SeccompPath is the path to a seccomp profile on the Podman host for
+	// hardened mode.
+	SeccompPath string
+
+	// UserNS is the user namespace mode for containers, e.g. "auto" or
+	// "keep-id". Empty uses the Podman default.
+	UserNS string
+
+	// Client is the container runner implementation, such as a *Client
+	// connected to the Podman socket.
+	// If nil, Execute() returns ErrClientNotConfigured.
+	Client ContainerRunner
+
+	// ImageResolver optionally resolves/pulls images before execution.
+	// If nil, images are assumed to exist locally.
+	ImageResolver ImageResolver
+
+	// HealthChecker optionally verifies service health before execution.
+	// If nil, health checks are skipped.
+	HealthChecker HealthChecker
+
+	// Logger is an optional logger for backend events.
+	Logger Logger
+}
+
+// Backend executes code in Podman containers with security isolation.
+type Backend struct {
+	imageRef string
+	seccomp  string
+	userNS   string
+	client   ContainerRunner
+	resolver ImageResolver
+	health   HealthChecker
+	logger   Logger
+}
+
+// New creates a new Podman backend with the given configuration.
+func New(cfg Config) *Backend {
+	imageRef := cfg.ImageRef
+	if imageRef == "" {
+		imageRef = "toolruntime-sandbox:latest"
+	}
+
+	return &Backend{
+		imageRef: imageRef,
+		seccomp:  cfg.SeccompPath,
+		userNS:   cfg.UserNS,
+		client:   cfg.Client,
+		resolver: cfg.ImageResolver,
+		health:   cfg.HealthChecker,
+		logger:   cfg.Logger,
+	}
+}
+
+// Kind returns the backend kind identifier.
+func (b *Backend) Kind() runtime.BackendKind {
+	return runtime.BackendPodman
+}
+
+// Capabilities implements runtime.CapabilityReporter.
+func (b *Backend) Capabilities() runtime.Capabilities {
+	return runtime.Capabilities{
+		Streaming:    true,
+		Workspace:    true,
+		FileStaging:  true,
+		NetworkModes: []string{"none", "bridge"},
+		GPU:          true,
+	}
+}
+
+// Execute runs code in a Podman container with security isolation.
+func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
+	if err := ctx.Err(); err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+	if err := req.Validate(); err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+
+	if b.client == nil {
+		return runtime.ExecuteResult{}, ErrClientNotConfigured
+	}
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	if b.health != nil {
+		if err := b.health.Ping(ctx); err != nil {
+			return runtime.ExecuteResult{}, fmt.Errorf("%w: %v", ErrServiceUnavailable, err)
+		}
+	}
+
+	image := b.imageRef
+	if b.resolver != nil {
+		resolved, err := b.resolver.Resolve(ctx, image)
+		if err != nil {
+			return runtime.ExecuteResult{}, err
+		}
+		image = resolved
+	}
+
+	profile := req.Profile
+	if profile == "" {
+		profile = runtime.ProfileStandard
+	}
+
+	spec, err := b.buildSpec(image, req, profile)
+	if err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+
+	if b.logger != nil {
+		b.logger.Info("executing in Podman container",
+			"profile", profile,
+			"image", image,
+			"networkMode", spec.Security.NetworkMode,
+			"readOnlyRootfs", spec.Security.ReadOnlyRootfs)
+	}
+
+	containerResult, err := b.client.Run(ctx, spec)
+	if err != nil {
+		return runtime.ExecuteResult{
+			Duration: time.Since(start),
+			Backend:  b.backendInfo(profile),
+		}, err
+	}
+
+	usage := containerResult.Usage
+	usage.WallTime = containerResult.Duration
+	return runtime.ExecuteResult{
+		Value:     extractOutValue(containerResult.Stdout),
+		Stdout:    containerResult.Stdout,
+		Stderr:    containerResult.Stderr,
+		Duration:  containerResult.Duration,
+		Backend:   b.backendInfo(profile),
+		Usage:     usage,
+		Artifacts: containerResult.Artifacts,
+		LimitsEnforced: runtime.LimitsEnforced{
+			Timeout:    true,
+			Memory:     req.Limits.MemoryBytes > 0,
+			CPU:        req.Limits.CPUQuotaMillis > 0,
+			Pids:       req.Limits.PidsMax > 0,
+			ToolCalls:  true,
+			ChainSteps: true,
+		},
+	}, nil
+}
+
+var _ runtime.Backend = (*Backend)(nil)
+
+func (b *Backend) backendInfo(profile runtime.SecurityProfile) runtime.BackendInfo {
+	info := runtime.BackendInfo{
+		Kind:      runtime.BackendPodman,
+		Readiness: runtime.ReadinessBeta,
+		Details: map[string]any{
+			"imageRef": b.imageRef,
+			"profile":  string(profile),
+		},
+	}
+	if b.userNS != "" {
+		info.Details["userns"] = b.userNS
+	}
+	return info
+}
+
+func (b *Backend) buildSpec(image string, req runtime.ExecuteRequest, profile runtime.SecurityProfile) (ContainerSpec, error) {
+	opts := b.containerOptions(profile, req.Limits)
+	staging, err := req.Staging()
+	if err != nil {
+		return ContainerSpec{}, err
+	}
+
+	spec := ContainerSpec{
+		Image: image,
+		Resources: ResourceSpec{
+			MemoryBytes: opts.MemoryLimit,
+			CPUQuota:    opts.CPUQuota,
+			PidsLimit:   opts.PidsLimit,
+			GPUs:        opts.GPUs,
+			GPUClass:    opts.GPUClass,
+		},
+		Security: SecuritySpec{
+			User:           opts.User,
+			ReadOnlyRootfs: opts.ReadOnlyRootfs,
+			NetworkMode:    opts.NetworkMode,
+			UserNS:         b.userNS,
+			SeccompProfile: opts.SeccompProfile,
+		},
+		Timeout: req.Timeout,
+		Labels: map[string]string{
+			"runtime.profile":        string(profile),
+			"runtime.backend":        string(runtime.BackendPodman),
+			runtime.LabelExecutionID: req.EnsureExecutionID(),
+		},
+		LogStreamer: req.LogStreamer,
+	}
+	for _, key := range slices.Sorted(maps.Keys(req.Env)) {
+		spec.Env = append(spec.Env, key+"="+req.Env[key])
+	}
+	if ws := req.Workspace; ws != nil {
+		// tmpfs keeps the workspace writable under a read-only rootfs
+		// and disappears with the container.
+		path := ws.MountPath()
+		spec.Mounts = append(spec.Mounts, Mount{Type: MountTypeTmpfs, Target: path, SizeBytes: ws.MaxBytes})
+		spec.WorkingDir = path
+		spec.Env = append(spec.Env,
+			runtime.WorkspaceEnv+"="+path,
+			runtime.OutputEnv+"="+staging.OutputPath())
+		spec.Staging = staging
+	}
+
+	if err := spec.Validate(); err != nil {
+		return ContainerSpec{}, err
+	}
+	return spec, nil
+}
+
+type containerOptions struct {
+	NetworkMode    string
+	ReadOnlyRootfs bool
+	MemoryLimit    int64
+	CPUQuota       int64
+	PidsLimit      int64
+	GPUs           int
+	GPUClass       string
+	SeccompProfile string
+	User           string
+}
+
+func (b *Backend) containerOptions(profile runtime.SecurityProfile, limits runtime.Limits) containerOptions {
+	opts := containerOptions{
+		// Numeric IDs: the nobody group is "nogroup" on Debian but
+		// "nobody" on Fedora-based images.
+		User: "65534:65534",
+	}
+
+	switch profile {
+	case runtime.ProfileDev:
+		opts.NetworkMode = "bridge"
+		opts.ReadOnlyRootfs = false
+	case runtime.ProfileStandard:
+		opts.NetworkMode = "none"
+		opts.ReadOnlyRootfs = true
+	case runtime.ProfileHardened:
+		opts.NetworkMode = "none"
+		opts.ReadOnlyRootfs = true
+		if b.seccomp != "" {
+			opts.SeccompProfile = b.seccomp
+		}
+	}
+
+	if limits.MemoryBytes > 0 {
+		opts.MemoryLimit = limits.MemoryBytes
+	}
+	if limits.CPUQuotaMillis > 0 {
+		opts.CPUQuota = limits.CPUQuotaMillis * 1000
+	}
+	if limits.PidsMax > 0 {
+		opts.PidsLimit = limits.PidsMax
+	}
+	if limits.GPUs.Count > 0 {
+		opts.GPUs = limits.GPUs.Count
+		opts.GPUClass = limits.GPUs.DeviceClass()
+	}
+
+	return opts
+}
+
+// extractOutValue extracts the __out value from stdout if present.
+func extractOutValue(stdout string) any {
+	lines := strings.Split(stdout, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "__OUT__:") {
+			jsonStr := strings.TrimPrefix(line, "__OUT__:")
+			var value any
+			if err := json.Unmarshal([]byte(jsonStr), &value); err == nil {
+				return value
+			}
+			return jsonStr
+		}
+		if strings.HasPrefix(line, "{") && strings.HasSuffix(line, "}") {
+			var payload map[string]any
+			if err := json.Unmarshal([]byte(line), &payload); err == nil {
+				if value, ok := payload["__out"]; ok {
+					return value
+				}
+			}
+		}
+	}
+	return nil
+}
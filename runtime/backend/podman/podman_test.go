@@ -0,0 +1,195 @@
+package podman
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// mockGateway implements runtime.ToolGateway for testing.
+type mockGateway struct{}
+
+func (m *mockGateway) SearchTools(_ context.Context, _ string, _ int) ([]index.Summary, error) {
+	return nil, nil
+}
+
+func (m *mockGateway) ListNamespaces(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockGateway) DescribeTool(_ context.Context, _ string, _ tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	return tooldoc.ToolDoc{}, nil
+}
+
+func (m *mockGateway) ListToolExamples(_ context.Context, _ string, _ int) ([]tooldoc.ToolExample, error) {
+	return nil, nil
+}
+
+func (m *mockGateway) RunTool(_ context.Context, _ string, _ map[string]any) (run.RunResult, error) {
+	return run.RunResult{}, nil
+}
+
+func (m *mockGateway) RunChain(_ context.Context, _ []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	return run.RunResult{}, nil, nil
+}
+
+type mockContainerRunner struct {
+	runFunc func(ctx context.Context, spec ContainerSpec) (ContainerResult, error)
+}
+
+func (m *mockContainerRunner) Run(ctx context.Context, spec ContainerSpec) (ContainerResult, error) {
+	if m.runFunc != nil {
+		return m.runFunc(ctx, spec)
+	}
+	return ContainerResult{}, nil
+}
+
+type mockHealthChecker struct {
+	err error
+}
+
+func (m *mockHealthChecker) Ping(_ context.Context) error { return m.err }
+
+func (m *mockHealthChecker) Info(_ context.Context) (ServiceInfo, error) {
+	return ServiceInfo{Rootless: true}, m.err
+}
+
+func TestBackendKind(t *testing.T) {
+	b := New(Config{})
+	if b.Kind() != runtime.BackendPodman {
+		t.Errorf("Kind() = %v, want %v", b.Kind(), runtime.BackendPodman)
+	}
+	if b.imageRef != "toolruntime-sandbox:latest" {
+		t.Errorf("imageRef = %q, want %q", b.imageRef, "toolruntime-sandbox:latest")
+	}
+}
+
+func TestBackendRequiresClient(t *testing.T) {
+	b := New(Config{})
+	_, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}})
+	if !errors.Is(err, ErrClientNotConfigured) {
+		t.Errorf("Execute() without client error = %v, want %v", err, ErrClientNotConfigured)
+	}
+}
+
+func TestBackendServiceUnavailable(t *testing.T) {
+	b := New(Config{
+		Client:        &mockContainerRunner{},
+		HealthChecker: &mockHealthChecker{err: errors.New("connection refused")},
+	})
+	_, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}})
+	if !errors.Is(err, ErrServiceUnavailable) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrServiceUnavailable)
+	}
+}
+
+func TestBackendProfiles(t *testing.T) {
+	tests := []struct {
+		profile  runtime.SecurityProfile
+		network  string
+		readOnly bool
+		seccomp  string
+	}{
+		{runtime.ProfileDev, "bridge", false, ""},
+		{runtime.ProfileStandard, "none", true, ""},
+		{runtime.ProfileHardened, "none", true, "/etc/podman/seccomp.json"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.profile), func(t *testing.T) {
+			var got ContainerSpec
+			b := New(Config{
+				SeccompPath: "/etc/podman/seccomp.json",
+				UserNS:      "auto",
+				Client: &mockContainerRunner{runFunc: func(_ context.Context, spec ContainerSpec) (ContainerResult, error) {
+					got = spec
+					return ContainerResult{Stdout: "__OUT__:42"}, nil
+				}},
+			})
+			result, err := b.Execute(context.Background(), runtime.ExecuteRequest{
+				Code:    "test",
+				Gateway: &mockGateway{},
+				Profile: tt.profile,
+				Limits:  runtime.Limits{MemoryBytes: 64 << 20, CPUQuotaMillis: 50},
+			})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			want := SecuritySpec{User: "65534:65534", ReadOnlyRootfs: tt.readOnly, NetworkMode: tt.network, UserNS: "auto", SeccompProfile: tt.seccomp}
+			if got.Security != want {
+				t.Errorf("Security = %+v, want %+v", got.Security, want)
+			}
+			if got.Resources.MemoryBytes != 64<<20 || got.Resources.CPUQuota != 50_000 {
+				t.Errorf("Resources = %+v", got.Resources)
+			}
+			if result.Value != float64(42) {
+				t.Errorf("Value = %v, want 42", result.Value)
+			}
+			if result.Backend.Kind != runtime.BackendPodman || result.Backend.Details["userns"] != "auto" {
+				t.Errorf("Backend = %+v", result.Backend)
+			}
+		})
+	}
+}
+
+func TestBackendStagesFiles(t *testing.T) {
+	artifacts := []runtime.Artifact{{Name: "report.txt", Data: []byte("done")}}
+	var got ContainerSpec
+	b := New(Config{Client: &mockContainerRunner{
+		runFunc: func(_ context.Context, spec ContainerSpec) (ContainerResult, error) {
+			got = spec
+			return ContainerResult{Artifacts: artifacts}, nil
+		},
+	}})
+	req := runtime.ExecuteRequest{
+		Code:      "test",
+		Gateway:   &mockGateway{},
+		Workspace: &runtime.Workspace{Path: "/scratch", MaxBytes: 1 << 20},
+		Files:     map[string]runtime.File{"input.txt": {Data: []byte("data")}},
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got.Staging == nil || got.Staging.Dir != "/scratch" || string(got.Staging.Files["input.txt"]) != "data" {
+		t.Errorf("Staging = %+v, want /scratch with input.txt", got.Staging)
+	}
+	wantMounts := []Mount{{Type: MountTypeTmpfs, Target: "/scratch", SizeBytes: 1 << 20}}
+	if !reflect.DeepEqual(got.Mounts, wantMounts) || got.WorkingDir != "/scratch" {
+		t.Errorf("Mounts = %+v, WorkingDir = %q", got.Mounts, got.WorkingDir)
+	}
+	if !reflect.DeepEqual(result.Artifacts, artifacts) {
+		t.Errorf("Artifacts = %+v, want %+v", result.Artifacts, artifacts)
+	}
+}
+
+func TestSecuritySpecValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		spec SecuritySpec
+		ok   bool
+	}{
+		{"default", SecuritySpec{}, true},
+		{"rootless keep-id", SecuritySpec{UserNS: "keep-id", NetworkMode: "pasta"}, true},
+		{"privileged", SecuritySpec{Privileged: true}, false},
+		{"host network", SecuritySpec{NetworkMode: "host"}, false},
+		{"host userns", SecuritySpec{UserNS: "host"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.Validate()
+			if tt.ok && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrSecurityViolation) {
+				t.Errorf("Validate() error = %v, want %v", err, ErrSecurityViolation)
+			}
+		})
+	}
+}
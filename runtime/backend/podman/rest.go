@@ -0,0 +1,594 @@
+package podman
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// APIVersion is the libpod REST API version Client requests. Podman 4.0
+// and later serve it.
+const APIVersion = "v4.0.0"
+
+// cpuPeriod is the CFS period ResourceSpec.CPUQuota is measured against.
+const cpuPeriod = 100_000
+
+// DefaultSocketPath returns the Podman socket to connect to: the unix path
+// in CONTAINER_HOST when set, the rootless socket under XDG_RUNTIME_DIR
+// when not running as root, and /run/podman/podman.sock otherwise.
+func DefaultSocketPath() string {
+	if host, ok := strings.CutPrefix(os.Getenv("CONTAINER_HOST"), "unix://"); ok && host != "" {
+		return host
+	}
+	if os.Geteuid() != 0 {
+		if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+			return filepath.Join(dir, "podman", "podman.sock")
+		}
+	}
+	return "/run/podman/podman.sock"
+}
+
+// ClientConfig configures a Client.
+type ClientConfig struct {
+	// SocketPath is the Podman service socket, with or without a
+	// "unix://" prefix.
+	// Default: DefaultSocketPath()
+	SocketPath string
+
+	// RegistryAuth is the base64-encoded auth configuration sent when
+	// pulling images. Empty pulls anonymously.
+	RegistryAuth string
+}
+
+// Client runs containers through the libpod REST API served by
+// "podman system service". It implements ContainerRunner, ImageResolver,
+// HealthChecker, and runtime.Reaper, which lists the containers the
+// podman backend labeled with runtime.LabelExecutionID.
+//
+// Every field of ContainerSpec is applied to the container:
+//   - Security.SeccompProfile is a path on the Podman host.
+//   - Security.UserNS selects the user namespace mode.
+//   - Resources.MemoryBytes also caps swap, so the limit cannot be bypassed.
+//   - Resources.CPUQuota is applied per 100ms period.
+//   - Resources.GPUs become CDI devices "<GPUClass>=<index>".
+//
+// When ContainerSpec.Staging is set, its files are copied into the
+// workspace through the archive API after the container is created, and
+// the output directory is copied back as artifacts after it exits. A tmpfs
+// is only mounted while the container runs, so the workspace becomes an
+// anonymous volume instead; artifacts beyond Staging.MaxBytes are rejected
+// with ErrResourceLimit.
+//
+// Contract:
+// - Concurrency: safe for concurrent use.
+// - Cleanup: Run kills timed-out containers and always removes them.
+// - Errors: timeouts wrap runtime.ErrTimeout; API failures are *ClientError.
+type Client struct {
+	http         *http.Client
+	socketPath   string
+	registryAuth string
+}
+
+// NewClient creates a Client. It does not connect until first used.
+func NewClient(cfg ClientConfig) *Client {
+	socketPath := strings.TrimPrefix(cfg.SocketPath, "unix://")
+	if socketPath == "" {
+		socketPath = DefaultSocketPath()
+	}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}
+	return &Client{
+		http:         &http.Client{Transport: transport},
+		socketPath:   socketPath,
+		registryAuth: cfg.RegistryAuth,
+	}
+}
+
+// SocketPath returns the socket the client connects to.
+func (c *Client) SocketPath() string {
+	return c.socketPath
+}
+
+// Close releases idle connections to the service.
+func (c *Client) Close() error {
+	c.http.CloseIdleConnections()
+	return nil
+}
+
+// Run implements ContainerRunner.
+func (c *Client) Run(ctx context.Context, spec ContainerSpec) (ContainerResult, error) {
+	if err := spec.Validate(); err != nil {
+		return ContainerResult{}, err
+	}
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spec.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+
+	id, err := c.create(ctx, spec)
+	if err != nil {
+		return ContainerResult{}, err
+	}
+	defer c.remove(ctx, id)
+
+	if spec.Staging != nil {
+		if err := c.stageFiles(ctx, id, spec.Staging); err != nil {
+			return ContainerResult{}, &ClientError{Op: "stage", Image: spec.Image, ContainerID: id, Err: err}
+		}
+	}
+	if err := c.call(ctx, http.MethodPost, "/containers/"+id+"/start", nil, nil); err != nil {
+		return ContainerResult{}, &ClientError{Op: "start", Image: spec.Image, ContainerID: id, Err: fmt.Errorf("%w: %w", ErrContainerFailed, err)}
+	}
+
+	var stdout, stderr bytes.Buffer
+	var logErr error
+	if spec.LogStreamer != nil {
+		// Follow the output until the container exits.
+		stdoutLines := runtime.NewLineWriter(spec.LogStreamer, runtime.LogStdout)
+		stderrLines := runtime.NewLineWriter(spec.LogStreamer, runtime.LogStderr)
+		logErr = c.copyLogs(ctx, id, true, io.MultiWriter(&stdout, stdoutLines), io.MultiWriter(&stderr, stderrLines))
+		_ = stdoutLines.Close()
+		_ = stderrLines.Close()
+	}
+
+	exitCode, waitErr := c.wait(ctx, id)
+	if spec.LogStreamer == nil {
+		logErr = c.copyLogs(context.WithoutCancel(ctx), id, false, &stdout, &stderr)
+	}
+	result := ContainerResult{
+		ExitCode: exitCode,
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Duration: time.Since(start),
+	}
+	if err := ctx.Err(); err != nil {
+		c.kill(ctx, id)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return result, fmt.Errorf("%w: %v", runtime.ErrTimeout, err)
+		}
+		return result, err
+	}
+	if waitErr != nil {
+		return result, &ClientError{Op: "wait", Image: spec.Image, ContainerID: id, Err: fmt.Errorf("%w: %w", ErrContainerFailed, waitErr)}
+	}
+	if logErr != nil {
+		return result, &ClientError{Op: "logs", Image: spec.Image, ContainerID: id, Err: logErr}
+	}
+	if err := c.checkOOM(ctx, id); err != nil {
+		return result, &ClientError{Op: "wait", Image: spec.Image, ContainerID: id, Err: err}
+	}
+	if spec.Staging != nil {
+		artifacts, err := c.collectArtifacts(ctx, id, spec.Staging)
+		if err != nil {
+			return result, &ClientError{Op: "collect", Image: spec.Image, ContainerID: id, Err: err}
+		}
+		result.Artifacts = artifacts
+	}
+	return result, nil
+}
+
+// Resolve implements ImageResolver. It pulls image when it is not present
+// locally and returns its digest reference when the image has one.
+func (c *Client) Resolve(ctx context.Context, ref string) (string, error) {
+	var inspect struct {
+		RepoDigests []string `json:"RepoDigests"`
+	}
+	err := c.getJSON(ctx, "/images/"+ref+"/json", nil, &inspect)
+	if isNotFound(err) {
+		if err := c.pull(ctx, ref); err != nil {
+			return "", err
+		}
+		err = c.getJSON(ctx, "/images/"+ref+"/json", nil, &inspect)
+	}
+	if err != nil {
+		if isNotFound(err) {
+			return "", fmt.Errorf("%w: %s", ErrImageNotFound, ref)
+		}
+		return "", &ClientError{Op: "inspect", Image: ref, Err: err}
+	}
+	if len(inspect.RepoDigests) > 0 {
+		return inspect.RepoDigests[0], nil
+	}
+	return ref, nil
+}
+
+// Ping implements HealthChecker.
+func (c *Client) Ping(ctx context.Context) error {
+	if err := c.call(ctx, http.MethodGet, "/_ping", nil, nil); err != nil {
+		return fmt.Errorf("%w: %v", ErrServiceUnavailable, err)
+	}
+	return nil
+}
+
+// Info implements HealthChecker.
+func (c *Client) Info(ctx context.Context) (ServiceInfo, error) {
+	var info struct {
+		Host struct {
+			OS       string `json:"os"`
+			Arch     string `json:"arch"`
+			Security struct {
+				Rootless bool `json:"rootless"`
+			} `json:"security"`
+		} `json:"host"`
+		Store struct {
+			GraphRoot string `json:"graphRoot"`
+		} `json:"store"`
+		Version struct {
+			Version    string `json:"Version"`
+			APIVersion string `json:"APIVersion"`
+		} `json:"version"`
+	}
+	if err := c.getJSON(ctx, "/info", nil, &info); err != nil {
+		return ServiceInfo{}, fmt.Errorf("%w: %v", ErrServiceUnavailable, err)
+	}
+	return ServiceInfo{
+		Version:      info.Version.Version,
+		APIVersion:   info.Version.APIVersion,
+		OS:           info.Host.OS,
+		Architecture: info.Host.Arch,
+		GraphRoot:    info.Store.GraphRoot,
+		Rootless:     info.Host.Security.Rootless,
+	}, nil
+}
+
+// create creates a container for spec and returns its ID.
+func (c *Client) create(ctx context.Context, spec ContainerSpec) (string, error) {
+	body, err := json.Marshal(specGenerator(spec))
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/containers/create", nil, bytes.NewReader(body), nil)
+	if err != nil {
+		if isNotFound(err) {
+			err = fmt.Errorf("%w: %v", ErrImageNotFound, err)
+		}
+		return "", &ClientError{Op: "create", Image: spec.Image, Err: fmt.Errorf("%w: %w", ErrContainerFailed, err)}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil || created.ID == "" {
+		return "", &ClientError{Op: "create", Image: spec.Image, Err: fmt.Errorf("%w: no container id in response", ErrContainerFailed)}
+	}
+	return created.ID, nil
+}
+
+// wait blocks until the container exits and returns its exit code.
+func (c *Client) wait(ctx context.Context, id string) (int, error) {
+	var code int
+	if err := c.postJSON(ctx, "/containers/"+id+"/wait", url.Values{"condition": {"exited"}}, &code); err != nil {
+		return -1, err
+	}
+	return code, nil
+}
+
+// copyLogs demultiplexes the container's output into stdout and stderr.
+func (c *Client) copyLogs(ctx context.Context, id string, follow bool, stdout, stderr io.Writer) error {
+	query := url.Values{"stdout": {"true"}, "stderr": {"true"}, "follow": {strconv.FormatBool(follow)}}
+	resp, err := c.do(ctx, http.MethodGet, "/containers/"+id+"/logs", query, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return demux(resp.Body, stdout, stderr)
+}
+
+// checkOOM reports a container killed for exceeding its memory limit.
+func (c *Client) checkOOM(ctx context.Context, id string) error {
+	var inspect struct {
+		State struct {
+			OOMKilled bool `json:"OOMKilled"`
+		} `json:"State"`
+	}
+	if err := c.getJSON(context.WithoutCancel(ctx), "/containers/"+id+"/json", nil, &inspect); err != nil {
+		return nil
+	}
+	if inspect.State.OOMKilled {
+		return fmt.Errorf("%w: out of memory", ErrResourceLimit)
+	}
+	return nil
+}
+
+func (c *Client) kill(ctx context.Context, id string) {
+	_ = c.call(context.WithoutCancel(ctx), http.MethodPost, "/containers/"+id+"/kill", url.Values{"signal": {"KILL"}}, nil)
+}
+
+// remove deletes the container and its anonymous volumes even when ctx
+// has ended.
+func (c *Client) remove(ctx context.Context, id string) {
+	_ = c.call(context.WithoutCancel(ctx), http.MethodDelete, "/containers/"+id, url.Values{"force": {"true"}, "v": {"true"}}, nil)
+}
+
+// pull fetches ref, reporting errors the service embeds in the progress
+// stream.
+func (c *Client) pull(ctx context.Context, ref string) error {
+	var header http.Header
+	if c.registryAuth != "" {
+		header = http.Header{"X-Registry-Auth": {c.registryAuth}}
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/images/pull", url.Values{"reference": {ref}}, nil, header)
+	if err != nil {
+		return &ClientError{Op: "pull", Image: ref, Err: fmt.Errorf("%w: %w", ErrImagePull, err)}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return &ClientError{Op: "pull", Image: ref, Err: fmt.Errorf("%w: %w", ErrImagePull, err)}
+		}
+		if msg.Error != "" {
+			return &ClientError{Op: "pull", Image: ref, Err: fmt.Errorf("%w: %s", ErrImagePull, msg.Error)}
+		}
+	}
+}
+
+// call sends a request and discards the response body.
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body io.Reader) error {
+	resp, err := c.do(ctx, method, path, query, body, nil)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, out any) error {
+	return c.doJSON(ctx, http.MethodGet, path, query, out)
+}
+
+func (c *Client) postJSON(ctx context.Context, path string, query url.Values, out any) error {
+	return c.doJSON(ctx, http.MethodPost, path, query, out)
+}
+
+func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, out any) error {
+	resp, err := c.do(ctx, method, path, query, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// do sends a libpod API request. Responses with an error status are
+// returned as *apiError with the body closed.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader, header http.Header) (*http.Response, error) {
+	u := url.URL{Scheme: "http", Host: "podman", Path: "/" + APIVersion + "/libpod" + path, RawQuery: query.Encode()}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("%w: %v", ErrPodmanNotAvailable, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer func() { _ = resp.Body.Close() }()
+		apiErr := &apiError{status: resp.StatusCode}
+		var msg struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(data, &msg) == nil && msg.Message != "" {
+			apiErr.message = msg.Message
+		} else {
+			apiErr.message = strings.TrimSpace(string(data))
+		}
+		return nil, apiErr
+	}
+	return resp, nil
+}
+
+// apiError is an error response from the libpod API.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	if e.message == "" {
+		return http.StatusText(e.status)
+	}
+	return fmt.Sprintf("%s (status %d)", e.message, e.status)
+}
+
+func isNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.status == http.StatusNotFound
+}
+
+// demux splits a multiplexed log stream: each frame is an 8-byte header
+// holding the stream (1 stdout, 2 stderr) and a big-endian payload length.
+func demux(r io.Reader, stdout, stderr io.Writer) error {
+	var hdr [8]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		w := io.Discard
+		switch hdr[0] {
+		case 1:
+			w = stdout
+		case 2:
+			w = stderr
+		}
+		if _, err := io.CopyN(w, r, int64(binary.BigEndian.Uint32(hdr[4:]))); err != nil {
+			return err
+		}
+	}
+}
+
+// createRequest is the subset of the libpod SpecGenerator Client sends.
+type createRequest struct {
+	Image              string            `json:"image"`
+	Command            []string          `json:"command,omitempty"`
+	WorkDir            string            `json:"work_dir,omitempty"`
+	Env                map[string]string `json:"env,omitempty"`
+	User               string            `json:"user,omitempty"`
+	Labels             map[string]string `json:"labels,omitempty"`
+	ReadOnlyFilesystem bool              `json:"read_only_filesystem,omitempty"`
+	Privileged         bool              `json:"privileged,omitempty"`
+	SeccompProfilePath string            `json:"seccomp_profile_path,omitempty"`
+	NetNS              *namespace        `json:"netns,omitempty"`
+	UserNS             *namespace        `json:"userns,omitempty"`
+	ResourceLimits     *resourceLimits   `json:"resource_limits,omitempty"`
+	Mounts             []specMount       `json:"mounts,omitempty"`
+	Volumes            []namedVolume     `json:"volumes,omitempty"`
+	Devices            []device          `json:"devices,omitempty"`
+}
+
+type namespace struct {
+	NSMode string `json:"nsmode"`
+}
+
+type resourceLimits struct {
+	Memory *memoryLimits `json:"memory,omitempty"`
+	CPU    *cpuLimits    `json:"cpu,omitempty"`
+	Pids   *pidsLimits   `json:"pids,omitempty"`
+}
+
+type memoryLimits struct {
+	Limit int64 `json:"limit"`
+	Swap  int64 `json:"swap"`
+}
+
+type cpuLimits struct {
+	Quota  int64  `json:"quota"`
+	Period uint64 `json:"period"`
+}
+
+type pidsLimits struct {
+	Limit int64 `json:"limit"`
+}
+
+type specMount struct {
+	Destination string   `json:"destination"`
+	Type        string   `json:"type"`
+	Source      string   `json:"source,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}
+
+type namedVolume struct {
+	Name        string
+	Dest        string
+	Options     []string
+	IsAnonymous bool
+}
+
+type device struct {
+	Path string `json:"path"`
+}
+
+// specGenerator translates spec into a libpod create request.
+func specGenerator(spec ContainerSpec) createRequest {
+	req := createRequest{
+		Image:              spec.Image,
+		Command:            spec.Command,
+		WorkDir:            spec.WorkingDir,
+		User:               spec.Security.User,
+		Labels:             spec.Labels,
+		ReadOnlyFilesystem: spec.Security.ReadOnlyRootfs,
+		Privileged:         spec.Security.Privileged,
+		SeccompProfilePath: spec.Security.SeccompProfile,
+	}
+	if len(spec.Env) > 0 {
+		req.Env = make(map[string]string, len(spec.Env))
+		for _, kv := range spec.Env {
+			k, v, _ := strings.Cut(kv, "=")
+			req.Env[k] = v
+		}
+	}
+	if mode := spec.Security.NetworkMode; mode != "" {
+		req.NetNS = &namespace{NSMode: mode}
+	}
+	if mode := spec.Security.UserNS; mode != "" {
+		req.UserNS = &namespace{NSMode: mode}
+	}
+
+	res := spec.Resources
+	var limits resourceLimits
+	if res.MemoryBytes > 0 {
+		limits.Memory = &memoryLimits{Limit: res.MemoryBytes, Swap: res.MemoryBytes}
+	}
+	if res.CPUQuota > 0 {
+		limits.CPU = &cpuLimits{Quota: res.CPUQuota, Period: cpuPeriod}
+	}
+	if res.PidsLimit > 0 {
+		limits.Pids = &pidsLimits{Limit: res.PidsLimit}
+	}
+	if limits != (resourceLimits{}) {
+		req.ResourceLimits = &limits
+	}
+	for i := range res.GPUs {
+		req.Devices = append(req.Devices, device{Path: res.GPUClass + "=" + strconv.Itoa(i)})
+	}
+
+	for _, m := range spec.Mounts {
+		var opts []string
+		if m.ReadOnly {
+			opts = append(opts, "ro")
+		}
+		switch m.Type {
+		case MountTypeBind:
+			req.Mounts = append(req.Mounts, specMount{Destination: m.Target, Type: "bind", Source: m.Source, Options: append(opts, "rbind")})
+		case MountTypeVolume:
+			req.Volumes = append(req.Volumes, namedVolume{Name: m.Source, Dest: m.Target, Options: opts})
+		case MountTypeTmpfs:
+			if spec.Staging != nil && m.Target == spec.Staging.Dir {
+				// Staged files must outlive the running container.
+				req.Volumes = append(req.Volumes, namedVolume{Dest: m.Target, Options: opts, IsAnonymous: true})
+				continue
+			}
+			if m.SizeBytes > 0 {
+				opts = append(opts, "size="+strconv.FormatInt(m.SizeBytes, 10))
+			}
+			req.Mounts = append(req.Mounts, specMount{Destination: m.Target, Type: "tmpfs", Source: "tmpfs", Options: opts})
+		}
+	}
+	return req
+}
+
+var (
+	_ ContainerRunner = (*Client)(nil)
+	_ ImageResolver   = (*Client)(nil)
+	_ HealthChecker   = (*Client)(nil)
+)
@@ -0,0 +1,318 @@
+package podman
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// fakeService is an in-memory libpod API served on a unix socket.
+type fakeService struct {
+	mu       sync.Mutex
+	created  createRequest
+	staged   map[string]string
+	pulled   bool
+	removed  bool
+	killed   bool
+	exitCode int
+	block    bool
+	oom      bool
+
+	listQuery url.Values
+}
+
+func newFakeService(t *testing.T) (*fakeService, *Client) {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "podman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "podman.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeService{staged: map[string]string{}}
+	srv := &http.Server{Handler: http.StripPrefix("/"+APIVersion+"/libpod", f)}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	c := NewClient(ClientConfig{SocketPath: "unix://" + socket})
+	t.Cleanup(func() { _ = c.Close() })
+	return f, c
+}
+
+func (f *fakeService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := r.URL.Path
+	switch {
+	case path == "/_ping":
+		_, _ = io.WriteString(w, "OK")
+	case path == "/info":
+		_, _ = io.WriteString(w, `{"host":{"os":"linux","arch":"amd64","security":{"rootless":true}},"store":{"graphRoot":"/home/u/.local/share/containers/storage"},"version":{"Version":"5.2.0","APIVersion":"5.2.0"}}`)
+	case path == "/images/pull":
+		f.pulled = true
+		_, _ = io.WriteString(w, `{"stream":"Pulling"}`+"\n"+`{"images":["sha256:abc"],"id":"sha256:abc"}`)
+	case strings.HasPrefix(path, "/images/"):
+		if !f.pulled {
+			writeError(w, http.StatusNotFound, "image not known")
+			return
+		}
+		_, _ = io.WriteString(w, `{"RepoDigests":["docker.io/library/alpine@sha256:abc"]}`)
+	case path == "/containers/json":
+		f.listQuery = r.URL.Query()
+		_, _ = io.WriteString(w, `[{"Id":"c1","Created":"2024-05-01T10:00:00Z","Labels":{"runtime.execution-id":"exec-1"}}]`)
+	case path == "/containers/create":
+		_ = json.NewDecoder(r.Body).Decode(&f.created)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"Id":"c1","Warnings":[]}`)
+	case path == "/containers/c1/archive" && r.Method == http.MethodPut:
+		tr := tar.NewReader(r.Body)
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				break
+			}
+			data, _ := io.ReadAll(tr)
+			f.staged[hdr.Name] = string(data)
+		}
+	case path == "/containers/c1/archive":
+		if r.URL.Query().Get("path") != "/workspace/out" {
+			writeError(w, http.StatusNotFound, "no such file")
+			return
+		}
+		tw := tar.NewWriter(w)
+		_ = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "out/", Mode: 0o755})
+		_ = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "out/report.txt", Mode: 0o644, Size: 4})
+		_, _ = tw.Write([]byte("done"))
+		_ = tw.Close()
+	case path == "/containers/c1/start":
+		w.WriteHeader(http.StatusNoContent)
+	case path == "/containers/c1/wait":
+		if f.block {
+			f.mu.Unlock()
+			<-r.Context().Done()
+			f.mu.Lock()
+			return
+		}
+		_ = json.NewEncoder(w).Encode(f.exitCode)
+	case path == "/containers/c1/logs":
+		writeFrame(w, 1, "hello\n")
+		writeFrame(w, 2, "warn\n")
+	case path == "/containers/c1/json":
+		_ = json.NewEncoder(w).Encode(map[string]any{"State": map[string]any{"OOMKilled": f.oom}})
+	case path == "/containers/c1/kill":
+		f.killed = true
+		w.WriteHeader(http.StatusNoContent)
+	case path == "/containers/c1" && r.Method == http.MethodDelete:
+		f.removed = r.URL.Query().Get("v") == "true"
+		_, _ = io.WriteString(w, `[]`)
+	default:
+		writeError(w, http.StatusNotFound, "no such container")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"cause": msg, "message": msg, "response": status})
+}
+
+func writeFrame(w io.Writer, stream byte, data string) {
+	hdr := [8]byte{stream}
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(data)))
+	_, _ = w.Write(hdr[:])
+	_, _ = io.WriteString(w, data)
+}
+
+func TestClientRun(t *testing.T) {
+	f, c := newFakeService(t)
+	f.exitCode = 3
+	spec := ContainerSpec{
+		Image:   "alpine",
+		Command: []string{"sh", "-c", "true"},
+		Env:     []string{"A=1", runtime.WorkspaceEnv + "=/workspace"},
+		Mounts:  []Mount{{Type: MountTypeTmpfs, Target: "/workspace", SizeBytes: 1 << 20}, {Type: MountTypeTmpfs, Target: "/tmp", SizeBytes: 4096}},
+		Resources: ResourceSpec{
+			MemoryBytes: 64 << 20,
+			CPUQuota:    50_000,
+			PidsLimit:   32,
+			GPUs:        1,
+			GPUClass:    "nvidia.com/gpu",
+		},
+		Security: SecuritySpec{User: "65534:65534", ReadOnlyRootfs: true, NetworkMode: "none", UserNS: "keep-id"},
+		Staging: &runtime.Staging{
+			Dir:       "/workspace",
+			OutputDir: "out",
+			Files:     map[string][]byte{"input.txt": []byte("data")},
+		},
+	}
+
+	result, err := c.Run(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.ExitCode != 3 || result.Stdout != "hello\n" || result.Stderr != "warn\n" {
+		t.Errorf("result = %+v", result)
+	}
+	want := []runtime.Artifact{{Name: "report.txt", Data: []byte("done")}}
+	if !reflect.DeepEqual(result.Artifacts, want) {
+		t.Errorf("Artifacts = %+v, want %+v", result.Artifacts, want)
+	}
+	if f.staged["input.txt"] != "data" {
+		t.Errorf("staged = %v, want input.txt", f.staged)
+	}
+	if !f.removed {
+		t.Error("container not removed with its volumes")
+	}
+
+	got := f.created
+	if got.Env["A"] != "1" || got.NetNS.NSMode != "none" || got.UserNS.NSMode != "keep-id" || !got.ReadOnlyFilesystem {
+		t.Errorf("create = %+v", got)
+	}
+	limits := resourceLimits{
+		Memory: &memoryLimits{Limit: 64 << 20, Swap: 64 << 20},
+		CPU:    &cpuLimits{Quota: 50_000, Period: cpuPeriod},
+		Pids:   &pidsLimits{Limit: 32},
+	}
+	if got.ResourceLimits == nil || !reflect.DeepEqual(*got.ResourceLimits, limits) {
+		t.Errorf("ResourceLimits = %+v", got.ResourceLimits)
+	}
+	if len(got.Devices) != 1 || got.Devices[0].Path != "nvidia.com/gpu=0" {
+		t.Errorf("Devices = %+v", got.Devices)
+	}
+	// The staged workspace becomes an anonymous volume; other tmpfs stay.
+	if len(got.Volumes) != 1 || got.Volumes[0].Dest != "/workspace" || !got.Volumes[0].IsAnonymous {
+		t.Errorf("Volumes = %+v", got.Volumes)
+	}
+	wantMounts := []specMount{{Destination: "/tmp", Type: "tmpfs", Source: "tmpfs", Options: []string{"size=4096"}}}
+	if !reflect.DeepEqual(got.Mounts, wantMounts) {
+		t.Errorf("Mounts = %+v, want %+v", got.Mounts, wantMounts)
+	}
+}
+
+func TestClientRunTimeout(t *testing.T) {
+	f, c := newFakeService(t)
+	f.block = true
+
+	_, err := c.Run(context.Background(), ContainerSpec{Image: "alpine", Timeout: 50 * time.Millisecond})
+	if !errors.Is(err, runtime.ErrTimeout) {
+		t.Fatalf("Run() error = %v, want %v", err, runtime.ErrTimeout)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.killed || !f.removed {
+		t.Errorf("killed = %v, removed = %v, want both", f.killed, f.removed)
+	}
+}
+
+func TestClientRunOOM(t *testing.T) {
+	f, c := newFakeService(t)
+	f.oom = true
+
+	_, err := c.Run(context.Background(), ContainerSpec{Image: "alpine"})
+	var clientErr *ClientError
+	if !errors.Is(err, ErrResourceLimit) || !errors.As(err, &clientErr) || clientErr.ContainerID != "c1" {
+		t.Errorf("Run() error = %v, want %v from container c1", err, ErrResourceLimit)
+	}
+}
+
+func TestClientResolvePullsMissingImage(t *testing.T) {
+	f, c := newFakeService(t)
+
+	ref, err := c.Resolve(context.Background(), "docker.io/library/alpine:3")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if !f.pulled || ref != "docker.io/library/alpine@sha256:abc" {
+		t.Errorf("Resolve() = %q, pulled = %v", ref, f.pulled)
+	}
+}
+
+func TestClientHealth(t *testing.T) {
+	_, c := newFakeService(t)
+
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	info, err := c.Info(context.Background())
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	if !info.Rootless || info.Version != "5.2.0" || info.OS != "linux" {
+		t.Errorf("Info() = %+v", info)
+	}
+
+	down := NewClient(ClientConfig{SocketPath: filepath.Join(t.TempDir(), "missing.sock")})
+	if err := down.Ping(context.Background()); !errors.Is(err, ErrServiceUnavailable) {
+		t.Errorf("Ping() on missing socket error = %v, want %v", err, ErrServiceUnavailable)
+	}
+}
+
+func TestClientReaper(t *testing.T) {
+	f, c := newFakeService(t)
+	orphans, err := c.Orphans(context.Background())
+	if err != nil {
+		t.Fatalf("Orphans() error = %v", err)
+	}
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	if len(orphans) != 1 || orphans[0].ID != "c1" || orphans[0].ExecutionID != "exec-1" || !orphans[0].Created.Equal(created) {
+		t.Fatalf("Orphans() = %v, want container c1 of exec-1", orphans)
+	}
+	if q := f.listQuery; q.Get("all") != "true" || q.Get("filters") != `{"label":["runtime.execution-id"]}` {
+		t.Errorf("list query = %v, want all containers with the execution label", q)
+	}
+	if err := c.Reap(context.Background(), orphans[0]); err != nil || !f.removed {
+		t.Errorf("Reap() error = %v, removed = %v", err, f.removed)
+	}
+	if err := c.Reap(context.Background(), runtime.Orphan{ID: "gone"}); err != nil {
+		t.Errorf("Reap() of a removed container error = %v, want nil", err)
+	}
+}
+
+func TestDefaultSocketPath(t *testing.T) {
+	t.Setenv("CONTAINER_HOST", "unix:///run/user/1000/podman/custom.sock")
+	if got := DefaultSocketPath(); got != "/run/user/1000/podman/custom.sock" {
+		t.Errorf("DefaultSocketPath() = %q, want CONTAINER_HOST", got)
+	}
+
+	t.Setenv("CONTAINER_HOST", "")
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	want := "/run/podman/podman.sock"
+	if os.Geteuid() != 0 {
+		want = "/run/user/1000/podman/podman.sock"
+	}
+	if got := DefaultSocketPath(); got != want {
+		t.Errorf("DefaultSocketPath() = %q, want %q", got, want)
+	}
+}
+
+func TestDemux(t *testing.T) {
+	var in, stdout, stderr bytes.Buffer
+	writeFrame(&in, 1, "out")
+	writeFrame(&in, 2, "err")
+	writeFrame(&in, 1, "put")
+	if err := demux(&in, &stdout, &stderr); err != nil {
+		t.Fatalf("demux() error = %v", err)
+	}
+	if stdout.String() != "output" || stderr.String() != "err" {
+		t.Errorf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+}
@@ -0,0 +1,173 @@
+package podman
+
+import (
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// MountType defines the type of volume mount.
+type MountType string
+
+const (
+	// MountTypeBind mounts a host path into the container.
+	MountTypeBind MountType = "bind"
+
+	// MountTypeVolume mounts a Podman named volume.
+	MountTypeVolume MountType = "volume"
+
+	// MountTypeTmpfs mounts a tmpfs (in-memory) filesystem.
+	MountTypeTmpfs MountType = "tmpfs"
+)
+
+// Mount defines a volume mount for a container.
+type Mount struct {
+	// Type specifies the mount type: "bind", "volume", or "tmpfs".
+	Type MountType
+
+	// Source is the host path (bind) or volume name (volume).
+	// Ignored for tmpfs mounts.
+	Source string
+
+	// Target is the path inside the container.
+	Target string
+
+	// ReadOnly mounts the volume as read-only.
+	ReadOnly bool
+
+	// SizeBytes caps the size of a tmpfs mount.
+	// Zero uses the runtime default. Ignored for other mount types.
+	SizeBytes int64
+}
+
+// ResourceSpec defines container resource limits.
+type ResourceSpec struct {
+	// MemoryBytes is the memory limit in bytes.
+	// Zero means unlimited.
+	MemoryBytes int64
+
+	// CPUQuota is the CPU quota in microseconds per 100ms period.
+	// Zero means unlimited.
+	CPUQuota int64
+
+	// PidsLimit is the maximum number of processes.
+	// Zero means unlimited.
+	PidsLimit int64
+
+	// GPUs is the number of GPUs to attach. Zero attaches none.
+	GPUs int
+
+	// GPUClass is the CDI device kind providing the GPUs, e.g.
+	// "nvidia.com/gpu"; runners inject devices named "<GPUClass>=<index>".
+	GPUClass string
+}
+
+// SecuritySpec defines container security settings.
+type SecuritySpec struct {
+	// User is the user to run as (e.g., "65534:65534").
+	User string
+
+	// ReadOnlyRootfs mounts the root filesystem as read-only.
+	ReadOnlyRootfs bool
+
+	// NetworkMode is the network mode: "none", "bridge", "slirp4netns",
+	// "pasta", or "host". "host" is not allowed in sandbox contexts.
+	NetworkMode string
+
+	// UserNS is the user namespace mode, e.g. "auto", "keep-id", or
+	// "nomap". Empty uses the Podman default, which for rootless Podman
+	// maps container root to the invoking user. "host" is not allowed in
+	// sandbox contexts.
+	UserNS string
+
+	// SeccompProfile is the path to a seccomp profile on the Podman host.
+	// Empty uses the runtime's default profile.
+	SeccompProfile string
+
+	// Privileged grants extended privileges to the container.
+	// Must always be false in sandbox contexts.
+	Privileged bool
+}
+
+// ContainerSpec defines what to run in a container and how.
+type ContainerSpec struct {
+	// Image is the container image reference (required).
+	Image string
+
+	// Command is the command to execute.
+	Command []string
+
+	// WorkingDir is the working directory inside the container.
+	WorkingDir string
+
+	// Env contains environment variables in KEY=value format.
+	Env []string
+
+	// Mounts defines volume mounts for the container.
+	Mounts []Mount
+
+	// Resources defines resource limits.
+	Resources ResourceSpec
+
+	// Security defines security settings.
+	Security SecuritySpec
+
+	// Timeout is the maximum execution duration.
+	Timeout time.Duration
+
+	// Labels are container labels for tracking.
+	Labels map[string]string
+
+	// LogStreamer, if set, receives stdout and stderr line by line while
+	// the container runs. ContainerRunner implementations should call it
+	// as output arrives, for example through runtime.NewLineWriter.
+	LogStreamer runtime.LogStreamer
+
+	// Staging, if set, lists files to copy into the workspace mount
+	// before the container starts and the output directory to copy out
+	// of it after the container exits.
+	Staging *runtime.Staging
+}
+
+// ContainerResult captures the output of container execution.
+type ContainerResult struct {
+	// ExitCode is the container's exit code.
+	ExitCode int
+
+	// Stdout contains the container's stdout output.
+	Stdout string
+
+	// Stderr contains the container's stderr output.
+	Stderr string
+
+	// Duration is the execution time.
+	Duration time.Duration
+
+	// Usage reports the resources the container consumed, read from its cgroup
+	// where the runner can. WallTime is filled in from Duration.
+	Usage runtime.ResourceUsage
+
+	// Artifacts holds the files collected from Staging's output directory.
+	Artifacts []runtime.Artifact
+}
+
+// ServiceInfo contains Podman service metadata.
+type ServiceInfo struct {
+	// Version is the Podman version.
+	Version string
+
+	// APIVersion is the REST API version.
+	APIVersion string
+
+	// OS is the host operating system.
+	OS string
+
+	// Architecture is the host CPU architecture.
+	Architecture string
+
+	// GraphRoot is the container storage root directory.
+	GraphRoot string
+
+	// Rootless reports whether the service runs without root privileges.
+	Rootless bool
+}
@@ -13,3 +13,22 @@ type APIClient interface {
 type LXCStatus struct {
 	Status string `json:"status"`
 }
+
+// SnapshotClient is implemented by API clients that manage LXC snapshots.
+// Config.RollbackSnapshot requires it.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: methods must honor cancellation and deadlines.
+// - Tasks: CreateSnapshot and RollbackSnapshot return once the Proxmox task has finished.
+type SnapshotClient interface {
+	ListSnapshots(ctx context.Context, node string, vmid int) ([]LXCSnapshot, error)
+	CreateSnapshot(ctx context.Context, node string, vmid int, name string) error
+	RollbackSnapshot(ctx context.Context, node string, vmid int, name string) error
+}
+
+// LXCSnapshot describes a container snapshot.
+type LXCSnapshot struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
@@ -1,12 +1,19 @@
 // Package proxmox provides a backend that executes code via a Proxmox LXC runtime.
 // The backend ensures an LXC container is running and delegates execution to a
 // runtime service inside the container via the remote backend.
+//
+// With Config.RollbackSnapshot, executions are serialized and the container
+// is rolled back to the named snapshot after each one, so every run starts
+// from the same state. A rollback that fails is retried before the next
+// execution, which is refused until it succeeds.
 package proxmox
 
 import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"sync"
 	"time"
 
 	"github.com/jonwraymond/toolexec/runtime"
@@ -29,8 +36,19 @@ var (
 
 	// ErrLXCNotRunning is returned when the LXC container is not running.
 	ErrLXCNotRunning = errors.New("lxc container not running")
+
+	// ErrSnapshotNotSupported is returned when RollbackSnapshot is set but
+	// the API client does not implement SnapshotClient.
+	ErrSnapshotNotSupported = errors.New("proxmox client does not support snapshots")
+
+	// ErrRollbackFailed is returned when the container cannot be restored
+	// to the rollback snapshot.
+	ErrRollbackFailed = errors.New("lxc snapshot rollback failed")
 )
 
+// snapshotName matches the snapshot names Proxmox accepts.
+var snapshotName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{1,39}$`)
+
 // Logger is the interface for logging.
 //
 // Contract:
@@ -78,6 +96,19 @@ type Config struct {
 	// RuntimeGatewayToken is an optional token for the tool gateway.
 	RuntimeGatewayToken string
 
+	// RollbackSnapshot names a snapshot the container is rolled back to
+	// after every execution. If it does not exist, it is taken before the
+	// first execution; take it yourself after provisioning to pin a known
+	// state. Executions are serialized while it is set, and Client must
+	// implement SnapshotClient. A rollback leaves the container stopped,
+	// so keep AutoStart enabled.
+	// Optional.
+	RollbackSnapshot string
+
+	// RollbackTimeout bounds the rollback after an execution.
+	// Default: 2m
+	RollbackTimeout time.Duration
+
 	// Logger is an optional logger for backend events.
 	Logger Logger
 }
@@ -95,7 +126,16 @@ type Backend struct {
 	autoStop               bool
 	startTimeout           time.Duration
 	pollInterval           time.Duration
+	rollbackSnapshot       string
+	rollbackTimeout        time.Duration
 	logger                 Logger
+
+	// mu serializes executions when rollbackSnapshot is set.
+	mu sync.Mutex
+	// snapshotReady is set once rollbackSnapshot is known to exist.
+	snapshotReady bool
+	// dirty is set when a rollback failed and must be retried.
+	dirty bool
 }
 
 // New creates a new Proxmox LXC backend with the given configuration.
@@ -110,6 +150,10 @@ func New(cfg Config) *Backend {
 	if poll == 0 {
 		poll = 2 * time.Second
 	}
+	rollbackTimeout := cfg.RollbackTimeout
+	if rollbackTimeout == 0 {
+		rollbackTimeout = 2 * time.Minute
+	}
 
 	return &Backend{
 		client:                 cfg.Client,
@@ -122,6 +166,8 @@ func New(cfg Config) *Backend {
 		autoStop:               autoStop,
 		startTimeout:           startTimeout,
 		pollInterval:           poll,
+		rollbackSnapshot:       cfg.RollbackSnapshot,
+		rollbackTimeout:        rollbackTimeout,
 		logger:                 cfg.Logger,
 	}
 }
@@ -145,6 +191,18 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 		return runtime.ExecuteResult{}, err
 	}
 
+	var snapshots SnapshotClient
+	if b.rollbackSnapshot != "" {
+		if snapshots, err = b.snapshotClient(client); err != nil {
+			return runtime.ExecuteResult{}, err
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if err := b.prepareSnapshot(ctx, snapshots); err != nil {
+			return runtime.ExecuteResult{}, err
+		}
+	}
+
 	if b.autoStart {
 		if err := b.ensureRunning(ctx, client); err != nil {
 			return runtime.ExecuteResult{}, err
@@ -164,6 +222,18 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 	result, err := b.runtime.Execute(ctx, req)
 	result.Backend = b.backendInfo(req.Profile)
 
+	if snapshots != nil {
+		rollbackCtx, cancel := context.WithTimeout(context.Background(), b.rollbackTimeout)
+		rollbackErr := snapshots.RollbackSnapshot(rollbackCtx, b.node, b.vmid, b.rollbackSnapshot)
+		cancel()
+		// The run's result stands; the next execution retries the rollback.
+		b.dirty = rollbackErr != nil
+		result.Backend.Details["rolledBack"] = !b.dirty
+		if rollbackErr != nil && b.logger != nil {
+			b.logger.Error("proxmox lxc rollback failed", "node", b.node, "vmid", b.vmid, "snapshot", b.rollbackSnapshot, "error", rollbackErr)
+		}
+	}
+
 	if b.autoStop {
 		stopCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		_ = client.Stop(stopCtx, b.node, b.vmid)
@@ -182,6 +252,53 @@ func (b *Backend) ensureClient() (APIClient, error) {
 	return nil, ErrClientNotConfigured
 }
 
+func (b *Backend) snapshotClient(client APIClient) (SnapshotClient, error) {
+	if !snapshotName.MatchString(b.rollbackSnapshot) {
+		return nil, fmt.Errorf("%w: invalid snapshot name %q", ErrRollbackFailed, b.rollbackSnapshot)
+	}
+	snapshots, ok := client.(SnapshotClient)
+	if !ok {
+		return nil, ErrSnapshotNotSupported
+	}
+	return snapshots, nil
+}
+
+// prepareSnapshot makes sure the container is at the rollback snapshot:
+// it takes the snapshot if it does not exist yet and retries a failed
+// rollback. b.mu must be held.
+func (b *Backend) prepareSnapshot(ctx context.Context, snapshots SnapshotClient) error {
+	if b.dirty {
+		if err := snapshots.RollbackSnapshot(ctx, b.node, b.vmid, b.rollbackSnapshot); err != nil {
+			return fmt.Errorf("%w: %v", ErrRollbackFailed, err)
+		}
+		b.dirty = false
+	}
+	if b.snapshotReady {
+		return nil
+	}
+	if b.node == "" || b.vmid == 0 {
+		return ErrProxmoxNotAvailable
+	}
+	list, err := snapshots.ListSnapshots(ctx, b.node, b.vmid)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrProxmoxNotAvailable, err)
+	}
+	for _, snap := range list {
+		if snap.Name == b.rollbackSnapshot {
+			b.snapshotReady = true
+			return nil
+		}
+	}
+	if b.logger != nil {
+		b.logger.Info("creating proxmox lxc snapshot", "node", b.node, "vmid", b.vmid, "snapshot", b.rollbackSnapshot)
+	}
+	if err := snapshots.CreateSnapshot(ctx, b.node, b.vmid, b.rollbackSnapshot); err != nil {
+		return fmt.Errorf("%w: create snapshot: %v", ErrProxmoxNotAvailable, err)
+	}
+	b.snapshotReady = true
+	return nil
+}
+
 func (b *Backend) ensureRunning(ctx context.Context, client APIClient) error {
 	if b.node == "" || b.vmid == 0 {
 		return ErrProxmoxNotAvailable
@@ -231,6 +348,9 @@ func (b *Backend) backendInfo(profile runtime.SecurityProfile) runtime.BackendIn
 		"vmid":    b.vmid,
 		"profile": string(profile),
 	}
+	if b.rollbackSnapshot != "" {
+		details["snapshot"] = b.rollbackSnapshot
+	}
 	if provider, ok := b.runtimeClient.(interface{ Endpoint() string }); ok {
 		if endpoint := provider.Endpoint(); endpoint != "" {
 			details["endpoint"] = endpoint
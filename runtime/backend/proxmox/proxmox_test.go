@@ -3,6 +3,7 @@ package proxmox
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/jonwraymond/tooldiscovery/index"
@@ -93,3 +94,130 @@ type stubRemoteClient struct{}
 func (s stubRemoteClient) Execute(_ context.Context, _ remote.RemoteRequest) (remote.RemoteResponse, error) {
 	return remote.RemoteResponse{}, nil
 }
+
+// snapshotAPI is an APIClient and SnapshotClient that records calls.
+type snapshotAPI struct {
+	status      string
+	snapshots   []LXCSnapshot
+	rollbackErr error
+	calls       []string
+}
+
+func (a *snapshotAPI) Status(_ context.Context, _ string, _ int) (LXCStatus, error) {
+	return LXCStatus{Status: a.status}, nil
+}
+
+func (a *snapshotAPI) Start(_ context.Context, _ string, _ int) error {
+	a.calls = append(a.calls, "start")
+	a.status = "running"
+	return nil
+}
+
+func (a *snapshotAPI) Stop(_ context.Context, _ string, _ int) error {
+	a.calls = append(a.calls, "stop")
+	a.status = "stopped"
+	return nil
+}
+
+func (a *snapshotAPI) ListSnapshots(_ context.Context, _ string, _ int) ([]LXCSnapshot, error) {
+	a.calls = append(a.calls, "list")
+	return a.snapshots, nil
+}
+
+func (a *snapshotAPI) CreateSnapshot(_ context.Context, _ string, _ int, name string) error {
+	a.calls = append(a.calls, "snapshot "+name)
+	a.snapshots = append(a.snapshots, LXCSnapshot{Name: name})
+	return nil
+}
+
+func (a *snapshotAPI) RollbackSnapshot(_ context.Context, _ string, _ int, name string) error {
+	a.calls = append(a.calls, "rollback "+name)
+	if a.rollbackErr != nil {
+		return a.rollbackErr
+	}
+	a.status = "stopped"
+	return nil
+}
+
+type resultRemoteClient struct{}
+
+func (resultRemoteClient) Execute(_ context.Context, _ remote.RemoteRequest) (remote.RemoteResponse, error) {
+	return remote.RemoteResponse{Result: &remote.ExecuteResultPayload{Value: "ok"}}, nil
+}
+
+func TestBackendRollbackSnapshot(t *testing.T) {
+	api := &snapshotAPI{status: "stopped"}
+	b := New(Config{
+		Node:             "node-1",
+		VMID:             100,
+		Client:           api,
+		RuntimeClient:    resultRemoteClient{},
+		RollbackSnapshot: "pristine",
+	})
+	req := runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}}
+
+	for range 2 {
+		result, err := b.Execute(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if result.Backend.Details["rolledBack"] != true || result.Backend.Details["snapshot"] != "pristine" {
+			t.Errorf("details = %v", result.Backend.Details)
+		}
+	}
+	want := "list,snapshot pristine,start,rollback pristine,start,rollback pristine"
+	if got := strings.Join(api.calls, ","); got != want {
+		t.Errorf("calls = %s, want %s", got, want)
+	}
+
+	// An existing snapshot is reused.
+	api = &snapshotAPI{status: "running", snapshots: []LXCSnapshot{{Name: "pristine"}}}
+	b = New(Config{Node: "node-1", VMID: 100, Client: api, RuntimeClient: resultRemoteClient{}, RollbackSnapshot: "pristine"})
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := strings.Join(api.calls, ","); got != "list,rollback pristine" {
+		t.Errorf("calls = %s", got)
+	}
+}
+
+func TestBackendRollbackFailure(t *testing.T) {
+	api := &snapshotAPI{status: "running", snapshots: []LXCSnapshot{{Name: "pristine"}}, rollbackErr: errors.New("storage busy")}
+	b := New(Config{Node: "node-1", VMID: 100, Client: api, RuntimeClient: resultRemoteClient{}, RollbackSnapshot: "pristine"})
+	req := runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}}
+
+	// The run that left the container dirty still returns its result.
+	result, err := b.Execute(context.Background(), req)
+	if err != nil || result.Value != "ok" || result.Backend.Details["rolledBack"] != false {
+		t.Fatalf("Execute() = %+v, %v", result, err)
+	}
+	// The next run is refused until the rollback succeeds.
+	if _, err := b.Execute(context.Background(), req); !errors.Is(err, ErrRollbackFailed) {
+		t.Errorf("Execute() error = %v, want ErrRollbackFailed", err)
+	}
+	api.rollbackErr = nil
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Errorf("Execute() after recovery error = %v", err)
+	}
+}
+
+func TestBackendRollbackConfig(t *testing.T) {
+	req := runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}}
+	b := New(Config{Node: "node-1", VMID: 100, Client: plainAPI{}, RuntimeClient: resultRemoteClient{}, RollbackSnapshot: "pristine"})
+	if _, err := b.Execute(context.Background(), req); !errors.Is(err, ErrSnapshotNotSupported) {
+		t.Errorf("Execute() error = %v, want ErrSnapshotNotSupported", err)
+	}
+	b = New(Config{Node: "node-1", VMID: 100, Client: &snapshotAPI{}, RuntimeClient: resultRemoteClient{}, RollbackSnapshot: "1 bad"})
+	if _, err := b.Execute(context.Background(), req); err == nil {
+		t.Error("Execute() with an invalid snapshot name succeeded")
+	}
+}
+
+// plainAPI is an APIClient without snapshot support.
+type plainAPI struct{}
+
+func (plainAPI) Status(_ context.Context, _ string, _ int) (LXCStatus, error) {
+	return LXCStatus{Status: "running"}, nil
+}
+func (plainAPI) Start(_ context.Context, _ string, _ int) error { return nil }
+func (plainAPI) Stop(_ context.Context, _ string, _ int) error  { return nil }
@@ -0,0 +1,267 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPClientConfig configures an HTTPClient.
+type HTTPClientConfig struct {
+	// URL is the http:// or https:// endpoint that accepts a POSTed
+	// RemoteRequest (required).
+	URL string
+
+	// Client sends the requests. Its Transport must be an *http.Transport
+	// for ConfigureTLS to apply.
+	// Default: a client with a copy of http.DefaultTransport, pooled as
+	// configured below
+	Client *http.Client
+
+	// MaxIdleConnsPerHost is how many idle connections are kept for reuse.
+	// Used only when Client is nil.
+	// Default: 16
+	MaxIdleConnsPerHost int
+
+	// MaxConnsPerHost bounds the connections to the endpoint, active and
+	// idle. Used only when Client is nil.
+	// Zero means no limit.
+	MaxConnsPerHost int
+
+	// IdleConnTimeout closes connections idle for this long. Used only
+	// when Client is nil.
+	// Default: 90s
+	IdleConnTimeout time.Duration
+
+	// Header is added to every request.
+	Header http.Header
+
+	// Authorize, if set, signs or authorizes each request.
+	Authorize func(ctx context.Context, req *http.Request) error
+}
+
+// HTTPClient executes requests by POSTing a RemoteRequest as JSON and
+// decoding the RemoteResponse. It implements RemoteClient,
+// EndpointProvider, TLSConfigurer and PayloadConfigurer.
+//
+// Requests are compressed with the codec's preferred encoding, and
+// Accept-Encoding advertises every configured encoding for the response.
+// A server that answers 415 Unsupported Media Type is retried once with an
+// encoding from the Accept-Encoding header of the 415 response (RFC 7694),
+// or uncompressed, and later requests use that encoding.
+//
+// When a request sets Stream, the server may answer with server-sent
+// events carrying StreamMessage values. HTTPClient reads the stream to its
+// StreamResult message; the output events are not forwarded.
+//
+// Contract:
+// - Concurrency: safe for concurrent use if Authorize is.
+// - Context: requests honor cancellation and deadlines.
+// - Errors: transport failures wrap ErrConnectionFailed; non-2xx responses without a RemoteResponse body wrap ErrRemoteExecutionFailed; oversized payloads are *PayloadTooLargeError.
+type HTTPClient struct {
+	url       string
+	client    *http.Client
+	header    http.Header
+	authorize func(ctx context.Context, req *http.Request) error
+
+	mu              sync.RWMutex
+	codec           PayloadCodec
+	requestEncoding string
+}
+
+// NewHTTPClient creates an HTTPClient.
+func NewHTTPClient(cfg HTTPClientConfig) (*HTTPClient, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("url %q must use http or https", cfg.URL)
+	}
+	client := cfg.Client
+	if client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+		if transport.MaxIdleConnsPerHost <= 0 {
+			transport.MaxIdleConnsPerHost = 16
+		}
+		transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+		if cfg.IdleConnTimeout > 0 {
+			transport.IdleConnTimeout = cfg.IdleConnTimeout
+		}
+		client = &http.Client{Transport: transport}
+	}
+	return &HTTPClient{
+		url:       cfg.URL,
+		client:    client,
+		header:    cfg.Header,
+		authorize: cfg.Authorize,
+	}, nil
+}
+
+// Endpoint implements EndpointProvider.
+func (c *HTTPClient) Endpoint() string {
+	return c.url
+}
+
+// ConfigureTLS implements TLSConfigurer.
+func (c *HTTPClient) ConfigureTLS(cfg *tls.Config) error {
+	var transport *http.Transport
+	switch t := c.client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return fmt.Errorf("transport %T does not accept TLS settings", t)
+	}
+	transport.TLSClientConfig = cfg.Clone()
+	client := *c.client
+	client.Transport = transport
+	c.client = &client
+	return nil
+}
+
+// ConfigurePayload implements PayloadConfigurer.
+func (c *HTTPClient) ConfigurePayload(codec PayloadCodec) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.codec = codec
+	c.requestEncoding = ""
+	if len(codec.Compressors) > 0 {
+		c.requestEncoding = codec.Compressors[0].Encoding()
+	}
+}
+
+// Execute implements RemoteClient.
+func (c *HTTPClient) Execute(ctx context.Context, req RemoteRequest) (RemoteResponse, error) {
+	c.mu.RLock()
+	codec, encoding := c.codec, c.requestEncoding
+	c.mu.RUnlock()
+
+	resp, used, err := c.post(ctx, codec, req, encoding)
+	if err != nil {
+		return RemoteResponse{}, err
+	}
+	if resp.StatusCode == http.StatusUnsupportedMediaType && used != "" {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		fallback := codec.Negotiate(resp.Header.Get("Accept-Encoding"))
+		if fallback == used {
+			fallback = ""
+		}
+		c.mu.Lock()
+		if c.requestEncoding == encoding {
+			c.requestEncoding = fallback
+		}
+		c.mu.Unlock()
+		if resp, _, err = c.post(ctx, codec, req, fallback); err != nil {
+			return RemoteResponse{}, err
+		}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusRequestEntityTooLarge {
+		return RemoteResponse{}, fmt.Errorf("%w: %s", ErrPayloadTooLarge, resp.Status)
+	}
+	if resp.StatusCode < http.StatusMultipleChoices && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return readEventStream(resp.Body, codec.MaxPayloadBytes)
+	}
+	response, err := codec.DecodeResponse(resp.Body, resp.Header.Get("Content-Encoding"))
+	if resp.StatusCode >= http.StatusMultipleChoices && (err != nil || response.Error == nil) {
+		return RemoteResponse{}, fmt.Errorf("%w: %s", ErrRemoteExecutionFailed, resp.Status)
+	}
+	if err != nil {
+		return RemoteResponse{}, fmt.Errorf("decode response: %w", err)
+	}
+	return response, nil
+}
+
+// post sends req compressed with encoding and returns the response and
+// the encoding actually used.
+func (c *HTTPClient) post(ctx context.Context, codec PayloadCodec, req RemoteRequest, encoding string) (*http.Response, string, error) {
+	body, used, err := codec.EncodeRequest(req, encoding)
+	if err != nil {
+		return nil, "", err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	for k, v := range c.header {
+		httpReq.Header[k] = v
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if used != "" {
+		httpReq.Header.Set("Content-Encoding", used)
+	}
+	if accept := codec.AcceptEncoding(); accept != "" {
+		httpReq.Header.Set("Accept-Encoding", accept)
+	}
+	if c.authorize != nil {
+		if err := c.authorize(ctx, httpReq); err != nil {
+			return nil, "", fmt.Errorf("authorize: %w", err)
+		}
+	}
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+	return resp, used, nil
+}
+
+// readEventStream reads server-sent events up to the StreamResult message.
+// limit bounds one event; zero means 16 MiB.
+func readEventStream(r io.Reader, limit int64) (RemoteResponse, error) {
+	if limit <= 0 {
+		limit = 16 << 20
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), int(limit))
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(value, " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+		var msg StreamMessage
+		if err := json.Unmarshal([]byte(data.String()), &msg); err != nil {
+			return RemoteResponse{}, fmt.Errorf("%w: %v", ErrStreamProtocol, err)
+		}
+		data.Reset()
+		if msg.Type == StreamResult {
+			if msg.Response == nil {
+				return RemoteResponse{}, fmt.Errorf("%w: result without a response", ErrStreamProtocol)
+			}
+			return *msg.Response, nil
+		}
+	}
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return RemoteResponse{}, &PayloadTooLargeError{Direction: "response", Size: limit + 1, Limit: limit}
+	}
+	if err := scanner.Err(); err != nil {
+		return RemoteResponse{}, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+	return RemoteResponse{}, fmt.Errorf("%w: event stream ended without a result", ErrStreamProtocol)
+}
+
+var (
+	_ RemoteClient      = (*HTTPClient)(nil)
+	_ EndpointProvider  = (*HTTPClient)(nil)
+	_ TLSConfigurer     = (*HTTPClient)(nil)
+	_ PayloadConfigurer = (*HTTPClient)(nil)
+)
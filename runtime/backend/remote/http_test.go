@@ -0,0 +1,160 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// encodingServer is a remote runtime that decodes requests with codec and
+// records the Content-Encoding of each.
+type encodingServer struct {
+	codec    PayloadCodec
+	status   int
+	response RemoteResponse
+
+	mu        sync.Mutex
+	encodings []string
+}
+
+func (s *encodingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.encodings = append(s.encodings, r.Header.Get("Content-Encoding"))
+	s.mu.Unlock()
+	if !s.codec.Supports(r.Header.Get("Content-Encoding")) {
+		w.Header().Set("Accept-Encoding", s.codec.AcceptEncoding())
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	req, err := s.codec.DecodeRequest(r.Body, r.Header.Get("Content-Encoding"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	resp := s.response
+	if resp.Result == nil && resp.Error == nil {
+		resp.Result = &ExecuteResultPayload{Value: len(req.Request.Code), Stdout: req.Request.Code}
+	}
+	body, encoding, err := s.codec.EncodeResponse(resp, s.codec.Negotiate(r.Header.Get("Accept-Encoding")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+	}
+	_, _ = w.Write(body)
+}
+
+func (s *encodingServer) seen() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.encodings...)
+}
+
+func newHTTPBackend(t *testing.T, srv *encodingServer, cfg Config) *Backend {
+	t.Helper()
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	client, err := NewHTTPClient(HTTPClientConfig{URL: ts.URL})
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	cfg.Client = client
+	return New(cfg)
+}
+
+func TestHTTPClientCompression(t *testing.T) {
+	code := strings.Repeat("print('hello')\n", 200)
+	req := runtime.ExecuteRequest{Code: code, Gateway: &mockGateway{}}
+
+	srv := &encodingServer{codec: PayloadCodec{Compressors: []Compressor{Gzip}}}
+	b := newHTTPBackend(t, srv, Config{Compression: []Compressor{Gzip}})
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != code {
+		t.Error("Execute() did not round-trip stdout")
+	}
+	if got := srv.seen(); len(got) != 1 || got[0] != "gzip" {
+		t.Errorf("request encodings = %q, want [gzip]", got)
+	}
+
+	// A server without gzip answers 415; the client falls back to identity
+	// and keeps using it.
+	srv = &encodingServer{}
+	b = newHTTPBackend(t, srv, Config{Compression: []Compressor{Gzip}})
+	for range 2 {
+		if _, err := b.Execute(context.Background(), req); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+	if got := srv.seen(); len(got) != 3 || got[0] != "gzip" || got[1] != "" || got[2] != "" {
+		t.Errorf("request encodings = %q, want [gzip, identity, identity]", got)
+	}
+}
+
+func TestHTTPClientPayloadLimit(t *testing.T) {
+	srv := &encodingServer{
+		codec:    PayloadCodec{Compressors: []Compressor{Gzip}},
+		response: RemoteResponse{Result: &ExecuteResultPayload{Stdout: strings.Repeat("a", 1<<20)}},
+	}
+	b := newHTTPBackend(t, srv, Config{Compression: []Compressor{Gzip}, MaxPayloadBytes: 64 << 10})
+
+	var tooLarge *PayloadTooLargeError
+	_, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}})
+	if !errors.As(err, &tooLarge) || tooLarge.Direction != "response" {
+		t.Errorf("Execute() error = %v, want a response PayloadTooLargeError", err)
+	}
+	_, err = b.Execute(context.Background(), runtime.ExecuteRequest{Code: strings.Repeat("a", 128<<10), Gateway: &mockGateway{}})
+	if !errors.As(err, &tooLarge) || tooLarge.Direction != "request" {
+		t.Errorf("Execute() error = %v, want a request PayloadTooLargeError", err)
+	}
+	if got := srv.seen(); len(got) != 1 {
+		t.Errorf("server saw %d requests, want the oversized one rejected before sending", len(got))
+	}
+
+	// Clients that do not take the codec are still guarded on the request.
+	b = New(Config{Client: &stubClient{}, MaxPayloadBytes: 1 << 10})
+	_, err = b.Execute(context.Background(), runtime.ExecuteRequest{Code: strings.Repeat("a", 2<<10), Gateway: &mockGateway{}})
+	if !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("Execute() error = %v, want ErrPayloadTooLarge", err)
+	}
+}
+
+func TestHTTPClientErrors(t *testing.T) {
+	if _, err := NewHTTPClient(HTTPClientConfig{URL: "ws://runtime"}); err == nil {
+		t.Error("NewHTTPClient() with a ws URL succeeded, want an error")
+	}
+
+	srv := &encodingServer{status: http.StatusInternalServerError, response: RemoteResponse{Error: &RemoteError{Code: "oom", Message: "out of memory"}}}
+	b := newHTTPBackend(t, srv, Config{})
+	_, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}})
+	if !errors.Is(err, ErrRemoteExecutionFailed) || !strings.Contains(err.Error(), "out of memory") {
+		t.Errorf("Execute() error = %v, want the remote error", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	t.Cleanup(ts.Close)
+	client, _ := NewHTTPClient(HTTPClientConfig{URL: ts.URL})
+	if _, err := client.Execute(context.Background(), RemoteRequest{}); !errors.Is(err, ErrRemoteExecutionFailed) {
+		t.Errorf("Execute() error = %v, want ErrRemoteExecutionFailed", err)
+	}
+
+	ts.Close()
+	if _, err := client.Execute(context.Background(), RemoteRequest{}); !errors.Is(err, ErrConnectionFailed) {
+		t.Errorf("Execute() error = %v, want ErrConnectionFailed", err)
+	}
+}
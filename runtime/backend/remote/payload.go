@@ -0,0 +1,231 @@
+package remote
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Errors for remote payload encoding.
+var (
+	// ErrPayloadTooLarge is matched by PayloadTooLargeError.
+	ErrPayloadTooLarge = errors.New("remote payload too large")
+
+	// ErrUnsupportedEncoding is returned for a content-coding with no
+	// configured Compressor.
+	ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+)
+
+// PayloadTooLargeError reports a request or response that exceeds
+// MaxPayloadBytes.
+type PayloadTooLargeError struct {
+	// Direction is "request" or "response".
+	Direction string
+
+	// Size is the uncompressed size. When reading stopped at the limit it
+	// is Limit+1.
+	Size int64
+
+	// Limit is the configured MaxPayloadBytes.
+	Limit int64
+}
+
+func (e *PayloadTooLargeError) Error() string {
+	return fmt.Sprintf("remote %s payload of %d bytes exceeds %d", e.Direction, e.Size, e.Limit)
+}
+
+func (e *PayloadTooLargeError) Unwrap() error { return ErrPayloadTooLarge }
+
+// Compressor compresses payloads in one HTTP content-coding.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Ownership: callers close the returned writers and readers.
+type Compressor interface {
+	// Encoding is the content-coding name, e.g. "gzip".
+	Encoding() string
+	Compress(w io.Writer) (io.WriteCloser, error)
+	Decompress(r io.Reader) (io.ReadCloser, error)
+}
+
+// Gzip is the gzip Compressor. zstd is provided by the zstdcodec module.
+var Gzip Compressor = gzipCompressor{}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Encoding() string { return "gzip" }
+
+func (gzipCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCompressor) Decompress(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// PayloadCodec encodes and decodes wire payloads as JSON with optional
+// compression and a size limit. The zero value sends identity-encoded
+// payloads of any size.
+type PayloadCodec struct {
+	// Compressors lists the supported encodings in order of preference.
+	Compressors []Compressor
+
+	// MaxPayloadBytes bounds the uncompressed size of payloads in either
+	// direction, so a small compressed body cannot expand without bound.
+	// Zero means no limit.
+	MaxPayloadBytes int64
+
+	// MinCompressBytes is the smallest payload worth compressing.
+	// Default: 1 KiB
+	MinCompressBytes int
+}
+
+// AcceptEncoding returns the Accept-Encoding header value advertising
+// the supported encodings, or "" when there are none.
+func (c PayloadCodec) AcceptEncoding() string {
+	names := make([]string, len(c.Compressors))
+	for i, comp := range c.Compressors {
+		names[i] = comp.Encoding()
+	}
+	return strings.Join(names, ", ")
+}
+
+// Negotiate returns the preferred encoding that the peer accepts, given its
+// Accept-Encoding header, or "" for identity.
+func (c PayloadCodec) Negotiate(accept string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				continue
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	for _, comp := range c.Compressors {
+		if accepted[comp.Encoding()] || accepted["*"] {
+			return comp.Encoding()
+		}
+	}
+	return ""
+}
+
+// Supports reports whether encoding can be decoded. Identity always can.
+func (c PayloadCodec) Supports(encoding string) bool {
+	_, err := c.compressor(encoding)
+	return err == nil
+}
+
+// EncodeRequest marshals req and compresses it with encoding unless it is
+// smaller than MinCompressBytes. It returns the body and the encoding
+// used, "" for identity.
+func (c PayloadCodec) EncodeRequest(req RemoteRequest, encoding string) ([]byte, string, error) {
+	return c.encode("request", req, encoding)
+}
+
+// DecodeRequest decompresses and unmarshals a request body.
+func (c PayloadCodec) DecodeRequest(r io.Reader, encoding string) (RemoteRequest, error) {
+	var req RemoteRequest
+	err := c.decode("request", r, encoding, &req)
+	return req, err
+}
+
+// EncodeResponse is EncodeRequest for responses.
+func (c PayloadCodec) EncodeResponse(resp RemoteResponse, encoding string) ([]byte, string, error) {
+	return c.encode("response", resp, encoding)
+}
+
+// DecodeResponse decompresses and unmarshals a response body.
+func (c PayloadCodec) DecodeResponse(r io.Reader, encoding string) (RemoteResponse, error) {
+	var resp RemoteResponse
+	err := c.decode("response", r, encoding, &resp)
+	return resp, err
+}
+
+func (c PayloadCodec) encode(direction string, v any, encoding string) ([]byte, string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, "", err
+	}
+	if limit := c.MaxPayloadBytes; limit > 0 && int64(len(data)) > limit {
+		return nil, "", &PayloadTooLargeError{Direction: direction, Size: int64(len(data)), Limit: limit}
+	}
+	comp, err := c.compressor(encoding)
+	if err != nil {
+		return nil, "", err
+	}
+	minBytes := c.MinCompressBytes
+	if minBytes <= 0 {
+		minBytes = 1 << 10
+	}
+	if comp == nil || len(data) < minBytes {
+		return data, "", nil
+	}
+	var buf bytes.Buffer
+	w, err := comp.Compress(&buf)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, "", err
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), comp.Encoding(), nil
+}
+
+func (c PayloadCodec) decode(direction string, r io.Reader, encoding string, v any) error {
+	comp, err := c.compressor(encoding)
+	if err != nil {
+		return err
+	}
+	if comp != nil {
+		zr, err := comp.Decompress(r)
+		if err != nil {
+			return fmt.Errorf("%s: %w", encoding, err)
+		}
+		defer func() { _ = zr.Close() }()
+		r = zr
+	}
+	if limit := c.MaxPayloadBytes; limit > 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", encoding, err)
+	}
+	if limit := c.MaxPayloadBytes; limit > 0 && int64(len(data)) > limit {
+		return &PayloadTooLargeError{Direction: direction, Size: int64(len(data)), Limit: limit}
+	}
+	return json.Unmarshal(data, v)
+}
+
+// compressor returns the compressor for encoding, or nil for identity.
+func (c PayloadCodec) compressor(encoding string) (Compressor, error) {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	if encoding == "" || encoding == "identity" {
+		return nil, nil
+	}
+	for _, comp := range c.Compressors {
+		if comp.Encoding() == encoding {
+			return comp, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedEncoding, encoding)
+}
+
+// PayloadConfigurer is implemented by clients that compress payloads and
+// bound response sizes, such as HTTPClient. New passes it the codec built
+// from Config.
+type PayloadConfigurer interface {
+	// ConfigurePayload sets the codec for later requests. It is called
+	// before the client is used.
+	ConfigurePayload(codec PayloadCodec)
+}
@@ -0,0 +1,101 @@
+package remote
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPayloadCodecRoundTrip(t *testing.T) {
+	codec := PayloadCodec{Compressors: []Compressor{Gzip}}
+	req := RemoteRequest{Request: ExecutePayload{Code: strings.Repeat("print('hello')\n", 200)}}
+
+	body, encoding, err := codec.EncodeRequest(req, "gzip")
+	if err != nil {
+		t.Fatalf("EncodeRequest() error = %v", err)
+	}
+	if encoding != "gzip" || len(body) >= len(req.Request.Code) {
+		t.Errorf("EncodeRequest() = %d bytes, %q; want a smaller gzip body", len(body), encoding)
+	}
+	got, err := codec.DecodeRequest(bytes.NewReader(body), encoding)
+	if err != nil {
+		t.Fatalf("DecodeRequest() error = %v", err)
+	}
+	if got.Request.Code != req.Request.Code {
+		t.Error("DecodeRequest() did not round-trip the code")
+	}
+
+	// Small payloads are not worth compressing.
+	_, encoding, err = codec.EncodeResponse(RemoteResponse{Result: &ExecuteResultPayload{Value: 1}}, "gzip")
+	if err != nil || encoding != "" {
+		t.Errorf("EncodeResponse() encoding = %q, %v; want identity", encoding, err)
+	}
+
+	if _, err := codec.DecodeResponse(strings.NewReader("{}"), "br"); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("DecodeResponse(br) error = %v, want ErrUnsupportedEncoding", err)
+	}
+	if !codec.Supports("identity") || !codec.Supports("GZIP") || codec.Supports("br") {
+		t.Error("Supports() mismatch")
+	}
+}
+
+func TestPayloadCodecLimit(t *testing.T) {
+	codec := PayloadCodec{Compressors: []Compressor{Gzip}, MaxPayloadBytes: 4 << 10}
+	large := RemoteRequest{Request: ExecutePayload{Code: strings.Repeat("a", 8<<10)}}
+
+	var tooLarge *PayloadTooLargeError
+	if _, _, err := codec.EncodeRequest(large, "gzip"); !errors.As(err, &tooLarge) || tooLarge.Direction != "request" {
+		t.Errorf("EncodeRequest() error = %v, want a request PayloadTooLargeError", err)
+	}
+
+	// A compressed body under the limit must not expand past it.
+	body, encoding, err := PayloadCodec{Compressors: []Compressor{Gzip}}.EncodeResponse(
+		RemoteResponse{Result: &ExecuteResultPayload{Stdout: strings.Repeat("a", 1<<20)}}, "gzip")
+	if err != nil {
+		t.Fatalf("EncodeResponse() error = %v", err)
+	}
+	if len(body) > 4<<10 {
+		t.Fatalf("compressed body is %d bytes, want under the limit", len(body))
+	}
+	_, err = codec.DecodeResponse(bytes.NewReader(body), encoding)
+	if !errors.As(err, &tooLarge) || tooLarge.Direction != "response" || tooLarge.Limit != 4<<10 {
+		t.Errorf("DecodeResponse() error = %v, want a response PayloadTooLargeError", err)
+	}
+	if !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("DecodeResponse() error = %v, want ErrPayloadTooLarge", err)
+	}
+}
+
+func TestPayloadCodecNegotiate(t *testing.T) {
+	zstd := namedCompressor{Compressor: Gzip, name: "zstd"}
+	codec := PayloadCodec{Compressors: []Compressor{zstd, Gzip}}
+	tests := map[string]string{
+		"":                  "",
+		"identity":          "",
+		"gzip":              "gzip",
+		"gzip, zstd":        "zstd",
+		"zstd;q=0, gzip":    "gzip",
+		"zstd;q=0.0, gzip":  "gzip",
+		"ZSTD;q=0.5":        "zstd",
+		"*":                 "zstd",
+		"br, deflate":       "",
+		" gzip ; q=1 , br ": "gzip",
+	}
+	for accept, want := range tests {
+		if got := codec.Negotiate(accept); got != want {
+			t.Errorf("Negotiate(%q) = %q, want %q", accept, got, want)
+		}
+	}
+	if got := codec.AcceptEncoding(); got != "zstd, gzip" {
+		t.Errorf("AcceptEncoding() = %q", got)
+	}
+}
+
+// namedCompressor renames a Compressor.
+type namedCompressor struct {
+	Compressor
+	name string
+}
+
+func (c namedCompressor) Encoding() string { return c.name }
@@ -0,0 +1,188 @@
+package remote
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// SelectionPolicy chooses the endpoint that serves an execution.
+type SelectionPolicy string
+
+// Selection policies.
+const (
+	// SelectRoundRobin rotates through the endpoints.
+	SelectRoundRobin SelectionPolicy = "round_robin"
+
+	// SelectLeastInFlight picks the endpoint with the fewest executions in
+	// flight, rotating among ties.
+	SelectLeastInFlight SelectionPolicy = "least_in_flight"
+
+	// SelectPriority always tries endpoints in the configured order; later
+	// endpoints serve only hedges and failover.
+	SelectPriority SelectionPolicy = "priority"
+)
+
+// Endpoint is one remote runtime among several.
+type Endpoint struct {
+	// Name identifies the endpoint in errors and diagnostics.
+	// Default: the client's Endpoint() when it is an EndpointProvider
+	Name string
+
+	// Client executes requests on this endpoint (required).
+	Client RemoteClient
+}
+
+// endpointPool spreads executions over several endpoints, hedging slow
+// ones and failing over when an endpoint cannot be reached.
+type endpointPool struct {
+	endpoints  []*poolEndpoint
+	policy     SelectionPolicy
+	hedgeAfter time.Duration
+	maxHedges  int
+	next       atomic.Uint64
+}
+
+type poolEndpoint struct {
+	Endpoint
+	inFlight atomic.Int64
+}
+
+func newEndpointPool(cfg Config) (*endpointPool, error) {
+	policy := cfg.Selection
+	switch policy {
+	case "":
+		policy = SelectRoundRobin
+	case SelectRoundRobin, SelectLeastInFlight, SelectPriority:
+	default:
+		return nil, fmt.Errorf("unknown selection policy %q", policy)
+	}
+	maxHedges := cfg.MaxHedges
+	if maxHedges <= 0 {
+		maxHedges = 1
+	}
+	p := &endpointPool{policy: policy, hedgeAfter: cfg.HedgeAfter, maxHedges: maxHedges}
+	for i, ep := range cfg.Endpoints {
+		if ep.Client == nil {
+			return nil, fmt.Errorf("%w: endpoint %d", ErrClientNotConfigured, i)
+		}
+		if ep.Name == "" {
+			if provider, ok := ep.Client.(EndpointProvider); ok {
+				ep.Name = provider.Endpoint()
+			}
+			if ep.Name == "" {
+				ep.Name = fmt.Sprintf("endpoint %d", i)
+			}
+		}
+		p.endpoints = append(p.endpoints, &poolEndpoint{Endpoint: ep})
+	}
+	return p, nil
+}
+
+// Endpoint implements EndpointProvider.
+func (p *endpointPool) Endpoint() string {
+	names := make([]string, len(p.endpoints))
+	for i, ep := range p.endpoints {
+		names[i] = ep.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Execute implements RemoteClient. The first endpoint chosen by the policy
+// serves the execution. If it has not answered after hedgeAfter, the next
+// one is sent the same request, up to maxHedges times, and the first
+// success wins; the others are cancelled. An endpoint that cannot be
+// reached is replaced by the next one at once.
+func (p *endpointPool) Execute(ctx context.Context, req RemoteRequest) (RemoteResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type attempt struct {
+		endpoint *poolEndpoint
+		response RemoteResponse
+		err      error
+	}
+	order := p.order()
+	results := make(chan attempt, len(order))
+	launched, pending := 0, 0
+	launch := func() {
+		ep := order[launched]
+		launched++
+		pending++
+		ep.inFlight.Add(1)
+		go func() {
+			defer ep.inFlight.Add(-1)
+			resp, err := ep.Client.Execute(ctx, req)
+			results <- attempt{endpoint: ep, response: resp, err: err}
+		}()
+	}
+	launch()
+
+	var hedge <-chan time.Time
+	var timer *time.Timer
+	if p.hedgeAfter > 0 && len(order) > 1 {
+		timer = time.NewTimer(p.hedgeAfter)
+		defer timer.Stop()
+		hedge = timer.C
+	}
+	hedges := 0
+	var errs []error
+	for pending > 0 {
+		select {
+		case a := <-results:
+			pending--
+			if a.err == nil {
+				return a.response, nil
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", a.endpoint.Name, a.err))
+			if unreachable(a.err) && launched < len(order) && ctx.Err() == nil {
+				launch()
+			}
+		case <-hedge:
+			hedge = nil
+			if hedges < p.maxHedges && launched < len(order) {
+				launch()
+				hedges++
+				timer.Reset(p.hedgeAfter)
+				hedge = timer.C
+			}
+		}
+	}
+	return RemoteResponse{}, errors.Join(errs...)
+}
+
+// order returns the endpoints in the order one execution tries them.
+func (p *endpointPool) order() []*poolEndpoint {
+	if p.policy == SelectPriority {
+		return slices.Clone(p.endpoints)
+	}
+	n := len(p.endpoints)
+	start := int(p.next.Add(1)-1) % n
+	order := make([]*poolEndpoint, 0, n)
+	for i := range n {
+		order = append(order, p.endpoints[(start+i)%n])
+	}
+	if p.policy == SelectLeastInFlight {
+		// Stable, so ties keep the rotation.
+		slices.SortStableFunc(order, func(a, b *poolEndpoint) int {
+			return cmp.Compare(a.inFlight.Load(), b.inFlight.Load())
+		})
+	}
+	return order
+}
+
+// unreachable reports whether err means the endpoint could not be reached,
+// so another endpoint should run the request.
+func unreachable(err error) bool {
+	return errors.Is(err, ErrConnectionFailed) || errors.Is(err, ErrRemoteNotAvailable)
+}
+
+var (
+	_ RemoteClient     = (*endpointPool)(nil)
+	_ EndpointProvider = (*endpointPool)(nil)
+)
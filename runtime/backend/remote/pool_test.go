@@ -0,0 +1,171 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// endpointClient answers after delay, or fails with err.
+type endpointClient struct {
+	name      string
+	delay     time.Duration
+	err       error
+	calls     atomic.Int32
+	cancelled atomic.Int32
+}
+
+func (c *endpointClient) Execute(ctx context.Context, _ RemoteRequest) (RemoteResponse, error) {
+	c.calls.Add(1)
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		c.cancelled.Add(1)
+		return RemoteResponse{}, ctx.Err()
+	}
+	if c.err != nil {
+		return RemoteResponse{}, c.err
+	}
+	return RemoteResponse{Result: &ExecuteResultPayload{Value: c.name}}, nil
+}
+
+func (c *endpointClient) Endpoint() string { return "http://" + c.name }
+
+func executePool(t *testing.T, cfg Config) (any, error) {
+	t.Helper()
+	result, err := New(cfg).Execute(context.Background(), runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}})
+	return result.Value, err
+}
+
+func TestEndpointPoolSelection(t *testing.T) {
+	a, b := &endpointClient{name: "a"}, &endpointClient{name: "b"}
+	endpoints := []Endpoint{{Client: a}, {Client: b}}
+
+	backend := New(Config{Endpoints: endpoints})
+	for range 4 {
+		if _, err := backend.Execute(context.Background(), runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}}); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+	if a.calls.Load() != 2 || b.calls.Load() != 2 {
+		t.Errorf("round robin calls = %d, %d; want 2, 2", a.calls.Load(), b.calls.Load())
+	}
+
+	backend = New(Config{Endpoints: endpoints, Selection: SelectPriority})
+	for range 3 {
+		result, err := backend.Execute(context.Background(), runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}})
+		if err != nil || result.Value != "a" {
+			t.Fatalf("Execute() = %v, %v; want a", result.Value, err)
+		}
+	}
+	if got := backend.backendInfo().Details["endpoint"]; got != "http://a, http://b" {
+		t.Errorf("endpoint detail = %v", got)
+	}
+
+	// A busy endpoint is skipped by least in flight.
+	slow := &endpointClient{name: "slow", delay: time.Second}
+	pool, err := newEndpointPool(Config{Endpoints: []Endpoint{{Client: slow}, {Client: a}}, Selection: SelectLeastInFlight})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = pool.Execute(ctx, RemoteRequest{})
+	}()
+	for pool.endpoints[0].inFlight.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for range 3 {
+		if order := pool.order(); order[0].Name != "http://a" {
+			t.Errorf("order()[0] = %s, want the idle endpoint", order[0].Name)
+		}
+	}
+	cancel()
+	wg.Wait()
+}
+
+func TestEndpointPoolHedging(t *testing.T) {
+	slow := &endpointClient{name: "slow", delay: 5 * time.Second}
+	fast := &endpointClient{name: "fast", delay: 10 * time.Millisecond}
+	start := time.Now()
+	value, err := executePool(t, Config{
+		Endpoints:  []Endpoint{{Client: slow}, {Client: fast}},
+		Selection:  SelectPriority,
+		HedgeAfter: 20 * time.Millisecond,
+	})
+	if err != nil || value != "fast" {
+		t.Fatalf("Execute() = %v, %v; want the hedge to win", value, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Execute() took %v", elapsed)
+	}
+	deadline := time.Now().Add(time.Second)
+	for slow.cancelled.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if slow.cancelled.Load() != 1 {
+		t.Error("the slower attempt was not cancelled")
+	}
+
+	// Without hedging the primary is waited for.
+	primary := &endpointClient{name: "primary", delay: 50 * time.Millisecond}
+	secondary := &endpointClient{name: "secondary"}
+	value, err = executePool(t, Config{Endpoints: []Endpoint{{Client: primary}, {Client: secondary}}, Selection: SelectPriority})
+	if err != nil || value != "primary" || secondary.calls.Load() != 0 {
+		t.Errorf("Execute() = %v, %v with %d secondary calls", value, err, secondary.calls.Load())
+	}
+}
+
+func TestEndpointPoolFailover(t *testing.T) {
+	down := &endpointClient{name: "down", err: ErrConnectionFailed}
+	up := &endpointClient{name: "up"}
+	value, err := executePool(t, Config{Endpoints: []Endpoint{{Client: down}, {Client: up}}, Selection: SelectPriority})
+	if err != nil || value != "up" {
+		t.Errorf("Execute() = %v, %v; want failover", value, err)
+	}
+
+	// Other errors are returned, not retried elsewhere.
+	failing := &endpointClient{name: "failing", err: errors.New("boom")}
+	up = &endpointClient{name: "up"}
+	_, err = executePool(t, Config{Endpoints: []Endpoint{{Client: failing}, {Client: up}}, Selection: SelectPriority})
+	if err == nil || up.calls.Load() != 0 {
+		t.Errorf("Execute() error = %v with %d failover calls", err, up.calls.Load())
+	}
+
+	_, err = executePool(t, Config{Endpoints: []Endpoint{{Client: down}, {Name: "other", Client: down}}})
+	if !errors.Is(err, ErrConnectionFailed) {
+		t.Errorf("Execute() error = %v, want ErrConnectionFailed", err)
+	}
+}
+
+func TestEndpointPoolConfigErrors(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"client and endpoints": {Client: &stubClient{}, Endpoints: []Endpoint{{Client: &stubClient{}}}},
+		"nil endpoint client":  {Endpoints: []Endpoint{{Name: "a"}}},
+		"unknown selection":    {Endpoints: []Endpoint{{Client: &stubClient{}}}, Selection: "fastest"},
+	} {
+		if _, err := executePool(t, cfg); err == nil {
+			t.Errorf("%s: Execute() succeeded, want an error", name)
+		}
+	}
+}
+
+func TestHTTPClientPooling(t *testing.T) {
+	client, err := NewHTTPClient(HTTPClientConfig{URL: "http://runtime", MaxConnsPerHost: 8, IdleConnTimeout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := client.client.Transport.(*http.Transport)
+	if transport.MaxIdleConnsPerHost != 16 || transport.MaxConnsPerHost != 8 || transport.IdleConnTimeout != time.Minute {
+		t.Errorf("transport pool = %d idle, %d max, %v", transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost, transport.IdleConnTimeout)
+	}
+}
@@ -1,9 +1,23 @@
 // Package remote provides a backend that executes code on a remote runtime service.
 // Generic target for dedicated runtime services, batch systems, or job runners.
+//
+// StreamClient carries executions over a bidirectional message stream, such
+// as the WebSocket dialer in the wsclient module: output arrives as it is
+// written, the remote code's tool calls are served by the request's gateway,
+// and a dropped connection is resumed rather than failing the execution.
+//
+// HTTPClient POSTs requests to a runtime's HTTP endpoint, such as the
+// reference server in runtime/remote/server. Config.Compression and
+// Config.MaxPayloadBytes compress payloads with gzip or zstd and reject
+// oversized ones with a PayloadTooLargeError. Config.TLS adds custom
+// certificate authorities, a client certificate and SPKI pins to clients
+// that implement TLSConfigurer. Config.Endpoints spreads executions over
+// several runtimes, failing over and optionally hedging slow executions.
 package remote
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -14,7 +28,7 @@ import (
 // Errors for remote backend operations.
 var (
 	// ErrRemoteNotAvailable is returned when the remote service is not available.
-	ErrRemoteNotAvailable = errors.New("remote service not available")
+	ErrRemoteNotAvailable = fmt.Errorf("remote service not available: %w", runtime.ErrRuntimeUnavailable)
 
 	// ErrConnectionFailed is returned when connection to remote service fails.
 	ErrConnectionFailed = errors.New("connection to remote service failed")
@@ -54,9 +68,30 @@ type EndpointProvider interface {
 // Config configures a remote backend.
 type Config struct {
 	// Client executes remote requests.
-	// Required. Provide a RemoteClient from an integration package.
+	// Required unless Endpoints is set. Provide HTTPClient, StreamClient,
+	// or a RemoteClient from an integration package.
 	Client RemoteClient
 
+	// Endpoints lists several remote runtimes to spread executions over,
+	// instead of Client. Executions go through RemoteClient.Execute, so
+	// StreamingClient endpoints do not stream.
+	Endpoints []Endpoint
+
+	// Selection chooses the endpoint for each execution.
+	// Default: SelectRoundRobin
+	Selection SelectionPolicy
+
+	// HedgeAfter, if positive, sends an execution that has not completed
+	// after this long to the next endpoint as well; the first to succeed
+	// wins and the other is cancelled. Hedged code may run twice, including
+	// its tool calls, so enable it only for idempotent workloads. An
+	// endpoint that cannot be reached is always replaced by the next one.
+	HedgeAfter time.Duration
+
+	// MaxHedges bounds the extra endpoints one execution is sent to.
+	// Default: 1
+	MaxHedges int
+
 	// GatewayEndpoint is the URL of the tool gateway available to the remote runtime.
 	// Optional, but recommended when remote code needs tool access.
 	GatewayEndpoint string
@@ -69,8 +104,30 @@ type Config struct {
 	TimeoutOverhead time.Duration
 
 	// EnableStreaming enables SSE streaming when supported by the remote service.
+	// Clients that implement StreamingClient, such as StreamClient, always
+	// stream.
 	EnableStreaming bool
 
+	// TLS configures certificate authorities, a client certificate for
+	// mutual TLS, and public key pins for the connection to the remote
+	// runtime. The client must implement TLSConfigurer; otherwise Execute
+	// fails with ErrTLSConfig rather than connecting without them.
+	// Optional.
+	TLS *TLSConfig
+
+	// Compression lists the encodings, in order of preference, that
+	// clients implementing PayloadConfigurer, such as HTTPClient, use to
+	// compress requests and accept compressed responses. Use Gzip or the
+	// zstd compressor from the zstdcodec module.
+	// Default: none
+	Compression []Compressor
+
+	// MaxPayloadBytes bounds the uncompressed size of the request and,
+	// for clients implementing PayloadConfigurer, of the response.
+	// Oversized payloads fail with a *PayloadTooLargeError.
+	// Zero means no limit.
+	MaxPayloadBytes int64
+
 	// Logger is an optional logger for backend events.
 	Logger Logger
 }
@@ -82,6 +139,9 @@ type Backend struct {
 	gatewayToken    string
 	timeoutOverhead time.Duration
 	enableStreaming bool
+	maxPayloadBytes int64
+	mutualTLS       bool
+	configErr       error
 	logger          Logger
 }
 
@@ -92,14 +152,60 @@ func New(cfg Config) *Backend {
 		timeoutOverhead = 5 * time.Second
 	}
 
-	return &Backend{
+	b := &Backend{
 		client:          cfg.Client,
 		gatewayEndpoint: cfg.GatewayEndpoint,
 		gatewayToken:    cfg.GatewayToken,
 		timeoutOverhead: timeoutOverhead,
 		enableStreaming: cfg.EnableStreaming,
+		maxPayloadBytes: cfg.MaxPayloadBytes,
 		logger:          cfg.Logger,
 	}
+	clients := []RemoteClient{cfg.Client}
+	if len(cfg.Endpoints) > 0 {
+		pool, err := newEndpointPool(cfg)
+		switch {
+		case cfg.Client != nil:
+			b.configErr = fmt.Errorf("%w: set Client or Endpoints, not both", ErrClientNotConfigured)
+		case err != nil:
+			b.configErr = err
+		default:
+			b.client = pool
+		}
+		clients = clients[:0]
+		for _, ep := range cfg.Endpoints {
+			clients = append(clients, ep.Client)
+		}
+	}
+	for _, client := range clients {
+		if configurer, ok := client.(PayloadConfigurer); ok {
+			configurer.ConfigurePayload(PayloadCodec{
+				Compressors:     cfg.Compression,
+				MaxPayloadBytes: cfg.MaxPayloadBytes,
+			})
+		}
+		if cfg.TLS != nil && client != nil && b.configErr == nil {
+			b.mutualTLS = cfg.TLS.CertFile != ""
+			b.configErr = configureTLS(client, *cfg.TLS)
+		}
+	}
+	return b
+}
+
+// configureTLS applies tlsCfg to client. The error is reported by Execute.
+func configureTLS(client RemoteClient, tlsCfg TLSConfig) error {
+	configurer, ok := client.(TLSConfigurer)
+	if !ok {
+		return fmt.Errorf("%w: client %T does not accept TLS settings", ErrTLSConfig, client)
+	}
+	cfg, err := tlsCfg.Build()
+	if err != nil {
+		return err
+	}
+	if err := configurer.ConfigureTLS(cfg); err != nil {
+		return fmt.Errorf("%w: %v", ErrTLSConfig, err)
+	}
+	return nil
 }
 
 // Kind returns the backend kind identifier.
@@ -112,6 +218,9 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 	if err := req.Validate(); err != nil {
 		return runtime.ExecuteResult{}, err
 	}
+	if b.configErr != nil {
+		return runtime.ExecuteResult{}, b.configErr
+	}
 	if b.client == nil {
 		return runtime.ExecuteResult{}, ErrClientNotConfigured
 	}
@@ -121,18 +230,29 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 		timeout = 30 * time.Second
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, timeout+b.timeoutOverhead)
+	ctx, cancel := context.WithTimeout(ctx, timeout+req.GraceTimeout+b.timeoutOverhead)
 	defer cancel()
 
 	start := time.Now()
 
-	payload := RemoteRequest{
-		Request: buildExecutePayload(req),
-		Gateway: buildGatewayDescriptor(b.gatewayEndpoint, b.gatewayToken),
-		Stream:  b.enableStreaming,
+	payload := NewRequest(req, NewGatewayDescriptor(b.gatewayEndpoint, b.gatewayToken))
+	payload.Stream = b.enableStreaming
+	if err := b.checkPayloadSize(payload); err != nil {
+		return runtime.ExecuteResult{}, err
 	}
 
-	response, err := b.client.Execute(ctx, payload)
+	var response RemoteResponse
+	var err error
+	if streamer, ok := b.client.(StreamingClient); ok {
+		// Output and tool calls come back over the stream, so the
+		// remote code needs no route to GatewayEndpoint.
+		response, err = streamer.ExecuteStream(ctx, payload, StreamHandler{
+			LogStreamer: req.LogStreamer,
+			Gateway:     req.Gateway,
+		})
+	} else {
+		response, err = b.client.Execute(ctx, payload)
+	}
 	if err != nil {
 		return runtime.ExecuteResult{
 			Duration: time.Since(start),
@@ -152,7 +272,7 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 		}, fmt.Errorf("%w: missing result", ErrRemoteExecutionFailed)
 	}
 
-	result := mapRemoteResult(*response.Result)
+	result := response.Result.ExecuteResult()
 	if result.Duration == 0 {
 		result.Duration = time.Since(start)
 	}
@@ -160,6 +280,22 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 	return result, nil
 }
 
+// checkPayloadSize enforces MaxPayloadBytes for clients that do not
+// enforce it themselves.
+func (b *Backend) checkPayloadSize(payload RemoteRequest) error {
+	if _, ok := b.client.(PayloadConfigurer); ok || b.maxPayloadBytes <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if size := int64(len(data)); size > b.maxPayloadBytes {
+		return &PayloadTooLargeError{Direction: "request", Size: size, Limit: b.maxPayloadBytes}
+	}
+	return nil
+}
+
 var _ runtime.Backend = (*Backend)(nil)
 
 // RemoteRequest is the wire request to a remote runtime.
@@ -181,11 +317,23 @@ type ExecutePayload struct {
 	Language       string         `json:"language,omitempty"`
 	Code           string         `json:"code"`
 	TimeoutMillis  int64          `json:"timeout_ms,omitempty"`
+	GraceMillis    int64          `json:"grace_ms,omitempty"`
+	MaxOutputBytes int64          `json:"max_output_bytes,omitempty"`
 	Limits         LimitsPayload  `json:"limits,omitempty"`
 	Profile        string         `json:"profile,omitempty"`
 	Metadata       map[string]any `json:"metadata,omitempty"`
 	EnableTracing  bool           `json:"enable_tracing,omitempty"`
 	RequestedScope string         `json:"requested_scope,omitempty"`
+	Egress         *EgressPayload `json:"egress,omitempty"`
+	ExecutionID    string         `json:"execution_id,omitempty"`
+}
+
+// EgressPayload encodes an egress policy for remote runtimes.
+type EgressPayload struct {
+	Mode         string   `json:"mode"`
+	AllowCIDRs   []string `json:"allow_cidrs,omitempty"`
+	AllowDomains []string `json:"allow_domains,omitempty"`
+	Proxy        string   `json:"proxy,omitempty"`
 }
 
 // LimitsPayload encodes execution limits for remote runtimes.
@@ -215,29 +363,50 @@ type ExecuteResultPayload struct {
 	Value          any                    `json:"value,omitempty"`
 	Stdout         string                 `json:"stdout,omitempty"`
 	Stderr         string                 `json:"stderr,omitempty"`
+	Truncated      bool                   `json:"truncated,omitempty"`
 	ToolCalls      []ToolCallPayload      `json:"tool_calls,omitempty"`
 	DurationMillis int64                  `json:"duration_ms,omitempty"`
 	LimitsEnforced runtime.LimitsEnforced `json:"limits_enforced,omitempty"`
+	Artifacts      []ArtifactPayload      `json:"artifacts,omitempty"`
+}
+
+// ArtifactPayload carries a file collected from the workspace output
+// directory. Data is base64-encoded on the wire.
+type ArtifactPayload struct {
+	Name string `json:"name"`
+	Data []byte `json:"data"`
 }
 
 // ToolCallPayload records tool call metadata from a remote execution.
 type ToolCallPayload struct {
 	ToolID      string `json:"tool_id"`
+	ExecutionID string `json:"execution_id,omitempty"`
 	BackendKind string `json:"backend_kind"`
 	DurationMs  int64  `json:"duration_ms"`
 	ErrorOp     string `json:"error_op,omitempty"`
 }
 
+// NewRequest packages req as the wire request a remote runtime accepts.
+// gateway may be nil when the runtime needs no tool access.
+func NewRequest(req runtime.ExecuteRequest, gateway *GatewayDescriptor) RemoteRequest {
+	return RemoteRequest{Request: buildExecutePayload(req), Gateway: gateway}
+}
+
 func buildExecutePayload(req runtime.ExecuteRequest) ExecutePayload {
 	payload := ExecutePayload{
-		Language: req.Language,
-		Code:     req.Code,
-		Profile:  string(req.Profile),
-		Metadata: req.Metadata,
+		Language:       req.Language,
+		Code:           req.Code,
+		MaxOutputBytes: req.MaxOutputBytes,
+		Profile:        string(req.Profile),
+		Metadata:       req.Metadata,
+		ExecutionID:    req.ExecutionID,
 	}
 	if req.Timeout > 0 {
 		payload.TimeoutMillis = req.Timeout.Milliseconds()
 	}
+	if req.GraceTimeout > 0 {
+		payload.GraceMillis = req.GraceTimeout.Milliseconds()
+	}
 	payload.Limits = LimitsPayload{
 		MaxToolCalls:   req.Limits.MaxToolCalls,
 		MaxChainSteps:  req.Limits.MaxChainSteps,
@@ -246,10 +415,85 @@ func buildExecutePayload(req runtime.ExecuteRequest) ExecutePayload {
 		PidsMax:        req.Limits.PidsMax,
 		DiskBytes:      req.Limits.DiskBytes,
 	}
+	if e := req.Egress; e != nil {
+		payload.Egress = &EgressPayload{
+			Mode:         string(e.Mode),
+			AllowCIDRs:   e.AllowCIDRs,
+			AllowDomains: e.AllowDomains,
+			Proxy:        e.Proxy,
+		}
+	}
 	return payload
 }
 
-func buildGatewayDescriptor(endpoint, token string) *GatewayDescriptor {
+// ExecuteRequest converts the payload back to the runtime.ExecuteRequest a
+// remote runtime executes. Gateway and LogStreamer are left for the caller
+// to fill in.
+func (p ExecutePayload) ExecuteRequest() runtime.ExecuteRequest {
+	req := runtime.ExecuteRequest{
+		Language:       p.Language,
+		Code:           p.Code,
+		Timeout:        time.Duration(p.TimeoutMillis) * time.Millisecond,
+		GraceTimeout:   time.Duration(p.GraceMillis) * time.Millisecond,
+		MaxOutputBytes: p.MaxOutputBytes,
+		Limits: runtime.Limits{
+			MaxToolCalls:   p.Limits.MaxToolCalls,
+			MaxChainSteps:  p.Limits.MaxChainSteps,
+			CPUQuotaMillis: p.Limits.CPUQuotaMillis,
+			MemoryBytes:    p.Limits.MemoryBytes,
+			PidsMax:        p.Limits.PidsMax,
+			DiskBytes:      p.Limits.DiskBytes,
+		},
+		Profile:     runtime.SecurityProfile(p.Profile),
+		Metadata:    p.Metadata,
+		ExecutionID: p.ExecutionID,
+	}
+	if e := p.Egress; e != nil {
+		req.Egress = &runtime.EgressPolicy{
+			Mode:         runtime.EgressMode(e.Mode),
+			AllowCIDRs:   e.AllowCIDRs,
+			AllowDomains: e.AllowDomains,
+			Proxy:        e.Proxy,
+		}
+	}
+	return req
+}
+
+// NewResultPayload packages result as the wire result a remote runtime
+// returns.
+func NewResultPayload(result runtime.ExecuteResult) ExecuteResultPayload {
+	payload := ExecuteResultPayload{
+		Value:          result.Value,
+		Stdout:         result.Stdout,
+		Stderr:         result.Stderr,
+		Truncated:      result.Truncated,
+		DurationMillis: result.Duration.Milliseconds(),
+		LimitsEnforced: result.LimitsEnforced,
+	}
+	if len(result.ToolCalls) > 0 {
+		payload.ToolCalls = make([]ToolCallPayload, len(result.ToolCalls))
+		for i, call := range result.ToolCalls {
+			payload.ToolCalls[i] = ToolCallPayload{
+				ToolID:      call.ToolID,
+				ExecutionID: call.ExecutionID,
+				BackendKind: call.BackendKind,
+				DurationMs:  call.Duration.Milliseconds(),
+				ErrorOp:     call.ErrorOp,
+			}
+		}
+	}
+	if len(result.Artifacts) > 0 {
+		payload.Artifacts = make([]ArtifactPayload, len(result.Artifacts))
+		for i, a := range result.Artifacts {
+			payload.Artifacts[i] = ArtifactPayload{Name: a.Name, Data: a.Data}
+		}
+	}
+	return payload
+}
+
+// NewGatewayDescriptor describes the tool gateway at endpoint, or returns
+// nil when endpoint is empty.
+func NewGatewayDescriptor(endpoint, token string) *GatewayDescriptor {
 	if endpoint == "" {
 		return nil
 	}
@@ -260,40 +504,63 @@ func buildGatewayDescriptor(endpoint, token string) *GatewayDescriptor {
 	}
 }
 
-func mapRemoteResult(payload ExecuteResultPayload) runtime.ExecuteResult {
+// ExecuteResult converts the payload to a runtime.ExecuteResult. Backend
+// is left for the caller to fill in.
+func (p ExecuteResultPayload) ExecuteResult() runtime.ExecuteResult {
 	result := runtime.ExecuteResult{
-		Value:    payload.Value,
-		Stdout:   payload.Stdout,
-		Stderr:   payload.Stderr,
-		Duration: time.Duration(payload.DurationMillis) * time.Millisecond,
+		Value:     p.Value,
+		Stdout:    p.Stdout,
+		Stderr:    p.Stderr,
+		Truncated: p.Truncated,
+		Duration:  time.Duration(p.DurationMillis) * time.Millisecond,
 		LimitsEnforced: runtime.LimitsEnforced{
-			Timeout:    payload.LimitsEnforced.Timeout,
-			ToolCalls:  payload.LimitsEnforced.ToolCalls,
-			ChainSteps: payload.LimitsEnforced.ChainSteps,
-			Memory:     payload.LimitsEnforced.Memory,
-			CPU:        payload.LimitsEnforced.CPU,
-			Pids:       payload.LimitsEnforced.Pids,
-			Disk:       payload.LimitsEnforced.Disk,
+			Timeout:    p.LimitsEnforced.Timeout,
+			ToolCalls:  p.LimitsEnforced.ToolCalls,
+			ChainSteps: p.LimitsEnforced.ChainSteps,
+			Memory:     p.LimitsEnforced.Memory,
+			CPU:        p.LimitsEnforced.CPU,
+			Pids:       p.LimitsEnforced.Pids,
+			Disk:       p.LimitsEnforced.Disk,
+			Output:     p.LimitsEnforced.Output,
 		},
 	}
 
-	if len(payload.ToolCalls) > 0 {
-		result.ToolCalls = make([]runtime.ToolCallRecord, len(payload.ToolCalls))
-		for i, call := range payload.ToolCalls {
+	if len(p.ToolCalls) > 0 {
+		result.ToolCalls = make([]runtime.ToolCallRecord, len(p.ToolCalls))
+		for i, call := range p.ToolCalls {
 			result.ToolCalls[i] = runtime.ToolCallRecord{
 				ToolID:      call.ToolID,
+				ExecutionID: call.ExecutionID,
 				BackendKind: call.BackendKind,
 				Duration:    time.Duration(call.DurationMs) * time.Millisecond,
 				ErrorOp:     call.ErrorOp,
 			}
 		}
 	}
+	if len(p.Artifacts) > 0 {
+		result.Artifacts = make([]runtime.Artifact, len(p.Artifacts))
+		for i, a := range p.Artifacts {
+			result.Artifacts[i] = runtime.Artifact{Name: a.Name, Data: a.Data}
+		}
+	}
 
 	return result
 }
 
 func (b *Backend) backendInfo() runtime.BackendInfo {
 	details := map[string]any{}
+	if _, ok := b.client.(StreamingClient); ok {
+		details["streaming"] = true
+	}
+	if b.mutualTLS {
+		details["mtls"] = true
+	}
+	if pool, ok := b.client.(*endpointPool); ok {
+		details["selection"] = string(pool.policy)
+		if pool.hedgeAfter > 0 {
+			details["hedgeAfter"] = pool.hedgeAfter.String()
+		}
+	}
 	if provider, ok := b.client.(EndpointProvider); ok {
 		if endpoint := provider.Endpoint(); endpoint != "" {
 			details["endpoint"] = endpoint
@@ -3,6 +3,7 @@ package remote
 import (
 	"context"
 	"errors"
+	"reflect"
 	"testing"
 	"time"
 
@@ -99,6 +100,76 @@ func TestBackendExecuteSuccess(t *testing.T) {
 	}
 }
 
+func TestBackendExecuteEgress(t *testing.T) {
+	client := &stubClient{response: RemoteResponse{Result: &ExecuteResultPayload{}}}
+	b := New(Config{Client: client})
+	policy := &runtime.EgressPolicy{Mode: runtime.EgressAllowlist, AllowCIDRs: []string{"10.0.0.0/8"}, AllowDomains: []string{"pypi.org"}}
+
+	if _, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}, Egress: policy}); err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if client.seen.Request.Egress == nil || client.seen.Request.Egress.Mode != "allowlist" {
+		t.Fatalf("Egress payload = %+v, want the allowlist", client.seen.Request.Egress)
+	}
+	if got := client.seen.Request.ExecuteRequest().Egress; !reflect.DeepEqual(got, policy) {
+		t.Errorf("round-tripped Egress = %+v, want %+v", got, policy)
+	}
+}
+
+func TestBackendExecuteGraceTimeout(t *testing.T) {
+	client := &stubClient{response: RemoteResponse{Result: &ExecuteResultPayload{}}}
+	b := New(Config{Client: client})
+
+	req := runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}, Timeout: time.Second, GraceTimeout: 3 * time.Second}
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if client.seen.Request.GraceMillis != 3000 {
+		t.Errorf("GraceMillis = %d, want 3000", client.seen.Request.GraceMillis)
+	}
+	if got := client.seen.Request.ExecuteRequest().GraceTimeout; got != 3*time.Second {
+		t.Errorf("round-tripped GraceTimeout = %v, want 3s", got)
+	}
+}
+
+func TestBackendExecuteMaxOutputBytes(t *testing.T) {
+	client := &stubClient{response: RemoteResponse{Result: &ExecuteResultPayload{
+		Stdout:         "abc" + runtime.OutputTruncatedMarker,
+		Truncated:      true,
+		LimitsEnforced: runtime.LimitsEnforced{Output: true},
+	}}}
+	b := New(Config{Client: client})
+
+	result, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}, MaxOutputBytes: 3})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if got := client.seen.Request.ExecuteRequest().MaxOutputBytes; got != 3 {
+		t.Errorf("round-tripped MaxOutputBytes = %d, want 3", got)
+	}
+	if !result.Truncated || !result.LimitsEnforced.Output {
+		t.Errorf("Truncated = %v, LimitsEnforced.Output = %v; want both", result.Truncated, result.LimitsEnforced.Output)
+	}
+}
+
+func TestBackendExecuteExecutionID(t *testing.T) {
+	client := &stubClient{response: RemoteResponse{Result: &ExecuteResultPayload{
+		ToolCalls: []ToolCallPayload{{ToolID: "t:a", ExecutionID: "exec-1"}},
+	}}}
+	b := New(Config{Client: client})
+
+	result, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}, ExecutionID: "exec-1"})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if got := client.seen.Request.ExecuteRequest().ExecutionID; got != "exec-1" {
+		t.Errorf("round-tripped ExecutionID = %q, want exec-1", got)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].ExecutionID != "exec-1" {
+		t.Errorf("ToolCalls = %+v, want the call attributed to exec-1", result.ToolCalls)
+	}
+}
+
 func TestBackendExecuteErrorResponse(t *testing.T) {
 	client := &stubClient{
 		response: RemoteResponse{Error: &RemoteError{Code: "unauthorized", Message: "nope"}},
@@ -0,0 +1,403 @@
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
+)
+
+// ErrStreamProtocol is returned when a stream peer violates the protocol.
+var ErrStreamProtocol = errors.New("remote stream protocol error")
+
+// StreamMessageType identifies a message of the streaming protocol.
+type StreamMessageType string
+
+// Streaming protocol messages. An execution starts with StreamExecute and
+// ends with StreamResult; everything between flows in both directions:
+//   - client to server: StreamExecute, StreamResume, StreamCancel, StreamToolResult
+//   - server to client: StreamAccepted, StreamStdout, StreamStderr, StreamToolCall, StreamResult
+//
+// Every server message carries a Seq, increasing by one per message of the
+// execution. After a lost connection the client reconnects and sends
+// StreamResume with the resume token from StreamAccepted and the last Seq
+// it received; the server replays later messages and re-sends the tool
+// calls it has no result for. Tool calls are identified by their ID, so a
+// repeated call is answered again without running the tool twice.
+const (
+	StreamExecute    StreamMessageType = "execute"
+	StreamResume     StreamMessageType = "resume"
+	StreamCancel     StreamMessageType = "cancel"
+	StreamToolResult StreamMessageType = "tool_result"
+
+	StreamAccepted StreamMessageType = "accepted"
+	StreamStdout   StreamMessageType = "stdout"
+	StreamStderr   StreamMessageType = "stderr"
+	StreamToolCall StreamMessageType = "tool_call"
+	StreamResult   StreamMessageType = "result"
+)
+
+// StreamMessage is the envelope of the streaming protocol.
+type StreamMessage struct {
+	Type StreamMessageType `json:"type"`
+
+	// Seq orders server messages; see StreamMessageType.
+	Seq uint64 `json:"seq,omitempty"`
+
+	// ResumeToken identifies the execution in StreamAccepted and
+	// StreamResume.
+	ResumeToken string `json:"resume_token,omitempty"`
+
+	// Request is the execution to start, in StreamExecute.
+	Request *RemoteRequest `json:"request,omitempty"`
+
+	// Data is a chunk of output, in StreamStdout and StreamStderr.
+	Data string `json:"data,omitempty"`
+
+	// ToolCall is a gateway request from the remote code, in
+	// StreamToolCall. It uses the proxy gateway's message format.
+	ToolCall *proxy.Message `json:"tool_call,omitempty"`
+
+	// ToolResult answers the tool call with the same ID, in
+	// StreamToolResult.
+	ToolResult *proxy.Message `json:"tool_result,omitempty"`
+
+	// Response is the outcome of the execution, in StreamResult.
+	Response *RemoteResponse `json:"response,omitempty"`
+}
+
+// StreamConn is one connection carrying the streaming protocol.
+//
+// Contract:
+// - Concurrency: Send and Receive may be called concurrently with each other.
+// - Context: Send/Receive must honor cancellation and deadlines.
+// - Errors: a failed Send or Receive means the connection is unusable.
+type StreamConn interface {
+	Send(ctx context.Context, msg StreamMessage) error
+	Receive(ctx context.Context) (StreamMessage, error)
+	Close() error
+}
+
+// StreamDialer opens connections to a remote runtime that speaks the
+// streaming protocol, such as a WebSocket dialer.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: DialStream must honor cancellation and deadlines.
+type StreamDialer interface {
+	DialStream(ctx context.Context) (StreamConn, error)
+}
+
+// StreamHandler receives what a streamed execution sends back before its
+// result. Both fields are optional.
+type StreamHandler struct {
+	// LogStreamer receives stdout and stderr line by line.
+	LogStreamer runtime.LogStreamer
+
+	// Gateway serves the tool calls of the remote code. If nil, tool
+	// calls fail.
+	Gateway runtime.ToolGateway
+}
+
+// StreamingClient is implemented by clients that stream executions. The
+// backend prefers it over RemoteClient.Execute, passing the request's log
+// streamer and gateway so the remote code needs no route back to the
+// host.
+type StreamingClient interface {
+	ExecuteStream(ctx context.Context, req RemoteRequest, handler StreamHandler) (RemoteResponse, error)
+}
+
+// StreamClientConfig configures a StreamClient.
+type StreamClientConfig struct {
+	// Dialer opens connections to the remote runtime (required).
+	Dialer StreamDialer
+
+	// MaxReconnects is how many times a lost connection is re-dialed
+	// before the execution fails.
+	// Default: 5
+	MaxReconnects int
+
+	// ReconnectBackoff is the delay before the first reconnect attempt.
+	// It doubles with each attempt, up to 5s.
+	// Default: 100ms
+	ReconnectBackoff time.Duration
+
+	// CancelTimeout bounds sending StreamCancel when ctx is done.
+	// Default: 1s
+	CancelTimeout time.Duration
+}
+
+// StreamClient executes requests over the streaming protocol, reconnecting
+// and resuming when a connection drops. It implements RemoteClient and
+// StreamingClient.
+//
+// Contract:
+// - Concurrency: safe for concurrent use; each execution uses its own connections.
+// - Context: cancellation sends StreamCancel to the server, best-effort.
+// - Errors: dial and reconnect failures wrap ErrConnectionFailed; a connection lost before StreamAccepted is not resumed.
+type StreamClient struct {
+	dialer           StreamDialer
+	maxReconnects    int
+	reconnectBackoff time.Duration
+	cancelTimeout    time.Duration
+}
+
+// NewStreamClient creates a StreamClient.
+func NewStreamClient(cfg StreamClientConfig) *StreamClient {
+	maxReconnects := cfg.MaxReconnects
+	if maxReconnects == 0 {
+		maxReconnects = 5
+	}
+	reconnectBackoff := cfg.ReconnectBackoff
+	if reconnectBackoff <= 0 {
+		reconnectBackoff = 100 * time.Millisecond
+	}
+	cancelTimeout := cfg.CancelTimeout
+	if cancelTimeout <= 0 {
+		cancelTimeout = time.Second
+	}
+	return &StreamClient{
+		dialer:           cfg.Dialer,
+		maxReconnects:    maxReconnects,
+		reconnectBackoff: reconnectBackoff,
+		cancelTimeout:    cancelTimeout,
+	}
+}
+
+// Execute implements RemoteClient. Output is not streamed and tool calls
+// fail.
+func (c *StreamClient) Execute(ctx context.Context, req RemoteRequest) (RemoteResponse, error) {
+	return c.ExecuteStream(ctx, req, StreamHandler{})
+}
+
+// ExecuteStream implements StreamingClient.
+func (c *StreamClient) ExecuteStream(ctx context.Context, req RemoteRequest, handler StreamHandler) (RemoteResponse, error) {
+	if c.dialer == nil {
+		return RemoteResponse{}, ErrClientNotConfigured
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req.Stream = true
+	s := &streamSession{
+		client:   c,
+		gateway:  handler.Gateway,
+		answered: make(map[string]*proxy.Message),
+		results:  make(chan proxy.Message),
+	}
+	if handler.LogStreamer != nil {
+		stdout := runtime.NewLineWriter(handler.LogStreamer, runtime.LogStdout)
+		stderr := runtime.NewLineWriter(handler.LogStreamer, runtime.LogStderr)
+		defer func() {
+			_ = stdout.Close()
+			_ = stderr.Close()
+		}()
+		s.stdout, s.stderr = stdout, stderr
+	}
+
+	conn, err := s.dial(ctx, StreamMessage{Type: StreamExecute, Request: &req})
+	if err != nil {
+		return RemoteResponse{}, err
+	}
+	for {
+		response, done, err := s.serve(ctx, conn)
+		_ = conn.Close()
+		if done {
+			return response, err
+		}
+		if s.token == "" {
+			return RemoteResponse{}, fmt.Errorf("%w: connection lost before the execution was accepted: %v", ErrConnectionFailed, err)
+		}
+		if conn, err = s.reconnect(ctx, err); err != nil {
+			return RemoteResponse{}, err
+		}
+	}
+}
+
+// Endpoint implements EndpointProvider when the dialer does.
+func (c *StreamClient) Endpoint() string {
+	if provider, ok := c.dialer.(EndpointProvider); ok {
+		return provider.Endpoint()
+	}
+	return ""
+}
+
+// ConfigureTLS implements TLSConfigurer when the dialer does.
+func (c *StreamClient) ConfigureTLS(cfg *tls.Config) error {
+	configurer, ok := c.dialer.(TLSConfigurer)
+	if !ok {
+		return fmt.Errorf("dialer %T does not accept TLS settings", c.dialer)
+	}
+	return configurer.ConfigureTLS(cfg)
+}
+
+// streamSession is the client side of one streamed execution.
+type streamSession struct {
+	client  *StreamClient
+	gateway runtime.ToolGateway
+	stdout  io.Writer
+	stderr  io.Writer
+
+	token   string
+	lastSeq uint64
+
+	// answered holds tool results by call ID; a nil entry is a call in
+	// progress. It is only used by serve.
+	answered map[string]*proxy.Message
+	results  chan proxy.Message
+}
+
+// dial opens a connection and sends its first message.
+func (s *streamSession) dial(ctx context.Context, first StreamMessage) (StreamConn, error) {
+	conn, err := s.client.dialer.DialStream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+	if err := conn.Send(ctx, first); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+	return conn, nil
+}
+
+// reconnect resumes the execution on a new connection after lost.
+func (s *streamSession) reconnect(ctx context.Context, lost error) (StreamConn, error) {
+	backoff := s.client.reconnectBackoff
+	lastErr := lost
+	for range s.client.maxReconnects {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, 5*time.Second)
+
+		conn, err := s.dial(ctx, StreamMessage{Type: StreamResume, ResumeToken: s.token, Seq: s.lastSeq})
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("%w: %d reconnect attempts failed: %v", ErrConnectionFailed, s.client.maxReconnects, lastErr)
+}
+
+// serve handles messages on conn until the execution ends (done) or the
+// connection fails.
+func (s *streamSession) serve(ctx context.Context, conn StreamConn) (response RemoteResponse, done bool, err error) {
+	readCtx, stopReading := context.WithCancel(ctx)
+	defer stopReading()
+	messages := make(chan StreamMessage)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := conn.Receive(readCtx)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case messages <- msg:
+			case <-readCtx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.cancel(ctx, conn)
+			return RemoteResponse{}, true, ctx.Err()
+		case err := <-readErr:
+			if ctx.Err() != nil {
+				s.cancel(ctx, conn)
+				return RemoteResponse{}, true, ctx.Err()
+			}
+			return RemoteResponse{}, false, err
+		case result := <-s.results:
+			s.answered[result.ID] = &result
+			if err := conn.Send(ctx, StreamMessage{Type: StreamToolResult, ToolResult: &result}); err != nil {
+				// The server re-sends the call after the resume.
+				return RemoteResponse{}, false, err
+			}
+		case msg := <-messages:
+			if msg.Seq != 0 {
+				// Re-sent tool calls keep their Seq and are deduplicated
+				// by ID instead.
+				if msg.Seq <= s.lastSeq && msg.Type != StreamToolCall {
+					continue
+				}
+				s.lastSeq = max(s.lastSeq, msg.Seq)
+			}
+			switch msg.Type {
+			case StreamAccepted:
+				s.token = msg.ResumeToken
+			case StreamStdout:
+				s.write(s.stdout, msg.Data)
+			case StreamStderr:
+				s.write(s.stderr, msg.Data)
+			case StreamToolCall:
+				if err := s.toolCall(ctx, conn, msg.ToolCall); err != nil {
+					return RemoteResponse{}, false, err
+				}
+			case StreamResult:
+				if msg.Response == nil {
+					return RemoteResponse{}, true, fmt.Errorf("%w: result without response", ErrStreamProtocol)
+				}
+				return *msg.Response, true, nil
+			}
+		}
+	}
+}
+
+// toolCall starts serving call, or answers it from the cache when the
+// server repeats it.
+func (s *streamSession) toolCall(ctx context.Context, conn StreamConn, call *proxy.Message) error {
+	if call == nil || call.ID == "" {
+		return nil
+	}
+	result, seen := s.answered[call.ID]
+	switch {
+	case result != nil:
+		return conn.Send(ctx, StreamMessage{Type: StreamToolResult, ToolResult: result})
+	case seen:
+		// Still running; its result is sent when it is ready.
+		return nil
+	}
+	s.answered[call.ID] = nil
+	go func() {
+		result := serveToolCall(ctx, s.gateway, *call)
+		select {
+		case s.results <- result:
+		case <-ctx.Done():
+		}
+	}()
+	return nil
+}
+
+func (s *streamSession) write(w io.Writer, data string) {
+	if w != nil {
+		_, _ = io.WriteString(w, data)
+	}
+}
+
+// cancel tells the server to stop the execution, best-effort.
+func (s *streamSession) cancel(ctx context.Context, conn StreamConn) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.client.cancelTimeout)
+	defer cancel()
+	_ = conn.Send(ctx, StreamMessage{Type: StreamCancel, ResumeToken: s.token})
+}
+
+var (
+	_ RemoteClient     = (*StreamClient)(nil)
+	_ StreamingClient  = (*StreamClient)(nil)
+	_ EndpointProvider = (*StreamClient)(nil)
+	_ TLSConfigurer    = (*StreamClient)(nil)
+)
@@ -0,0 +1,283 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
+)
+
+// pipeConn is one end of an in-memory StreamConn pair.
+type pipeConn struct {
+	in     <-chan StreamMessage
+	out    chan<- StreamMessage
+	closed chan struct{}
+	once   *sync.Once
+}
+
+func newPipe() (client, server *pipeConn) {
+	toServer := make(chan StreamMessage)
+	toClient := make(chan StreamMessage)
+	closed := make(chan struct{})
+	once := &sync.Once{}
+	return &pipeConn{in: toClient, out: toServer, closed: closed, once: once},
+		&pipeConn{in: toServer, out: toClient, closed: closed, once: once}
+}
+
+func (p *pipeConn) Send(ctx context.Context, msg StreamMessage) error {
+	select {
+	case p.out <- msg:
+		return nil
+	case <-p.closed:
+		return errors.New("pipe closed")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *pipeConn) Receive(ctx context.Context) (StreamMessage, error) {
+	select {
+	case msg := <-p.in:
+		return msg, nil
+	case <-p.closed:
+		return StreamMessage{}, errors.New("pipe closed")
+	case <-ctx.Done():
+		return StreamMessage{}, ctx.Err()
+	}
+}
+
+func (p *pipeConn) Close() error {
+	p.once.Do(func() { close(p.closed) })
+	return nil
+}
+
+// pipeDialer hands the server end of each dialed pipe to the test. Dials
+// after the first maxDials fail.
+type pipeDialer struct {
+	servers  chan *pipeConn
+	maxDials int32
+	dials    atomic.Int32
+}
+
+func newPipeDialer(maxDials int32) *pipeDialer {
+	return &pipeDialer{servers: make(chan *pipeConn, 4), maxDials: maxDials}
+}
+
+func (d *pipeDialer) DialStream(context.Context) (StreamConn, error) {
+	if d.dials.Add(1) > d.maxDials {
+		return nil, errors.New("connection refused")
+	}
+	client, server := newPipe()
+	d.servers <- server
+	return client, nil
+}
+
+func (d *pipeDialer) Endpoint() string { return "ws://pipe" }
+
+// countingGateway counts RunTool calls.
+type countingGateway struct {
+	mockGateway
+	runs atomic.Int32
+}
+
+func (g *countingGateway) SearchTools(context.Context, string, int) ([]index.Summary, error) {
+	return []index.Summary{{ID: "ns:echo", Name: "echo", Namespace: "ns"}}, nil
+}
+
+func (g *countingGateway) RunTool(_ context.Context, id string, args map[string]any) (run.RunResult, error) {
+	g.runs.Add(1)
+	return run.RunResult{Structured: map[string]any{"tool": id, "args": args}}, nil
+}
+
+func receive(t *testing.T, conn *pipeConn, want StreamMessageType) StreamMessage {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg, err := conn.Receive(ctx)
+	if err != nil {
+		t.Fatalf("Receive(%s) error = %v", want, err)
+	}
+	if msg.Type != want {
+		t.Fatalf("Receive() type = %s, want %s", msg.Type, want)
+	}
+	return msg
+}
+
+func send(t *testing.T, conn *pipeConn, msg StreamMessage) {
+	t.Helper()
+	if err := conn.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send(%s) error = %v", msg.Type, err)
+	}
+}
+
+func TestStreamClientResume(t *testing.T) {
+	dialer := newPipeDialer(2)
+	client := NewStreamClient(StreamClientConfig{Dialer: dialer, ReconnectBackoff: time.Millisecond})
+	gw := &countingGateway{}
+	toolCall := &proxy.Message{Type: proxy.MsgRunTool, ID: "1", Payload: map[string]any{"id": "ns:echo", "args": map[string]any{"x": 1.0}}}
+
+	go func() {
+		conn := <-dialer.servers
+		msg := receive(t, conn, StreamExecute)
+		if msg.Request == nil || !msg.Request.Stream || msg.Request.Request.Code != "run()" {
+			t.Errorf("execute request = %+v", msg.Request)
+		}
+		send(t, conn, StreamMessage{Type: StreamAccepted, Seq: 1, ResumeToken: "t1"})
+		send(t, conn, StreamMessage{Type: StreamStdout, Seq: 2, Data: "hello\nwor"})
+		send(t, conn, StreamMessage{Type: StreamStdout, Seq: 3, Data: "ld\n"})
+		send(t, conn, StreamMessage{Type: StreamToolCall, Seq: 4, ToolCall: toolCall})
+		result := receive(t, conn, StreamToolResult)
+		if result.ToolResult == nil || result.ToolResult.Type != proxy.MsgResponse {
+			t.Errorf("tool result = %+v", result.ToolResult)
+		}
+		_ = conn.Close()
+
+		conn = <-dialer.servers
+		msg = receive(t, conn, StreamResume)
+		if msg.ResumeToken != "t1" || msg.Seq != 4 {
+			t.Errorf("resume = %+v", msg)
+		}
+		// A replayed message and a re-sent call must not repeat.
+		send(t, conn, StreamMessage{Type: StreamStdout, Seq: 3, Data: "ld\n"})
+		send(t, conn, StreamMessage{Type: StreamToolCall, Seq: 4, ToolCall: toolCall})
+		receive(t, conn, StreamToolResult)
+		send(t, conn, StreamMessage{Type: StreamResult, Seq: 5, Response: &RemoteResponse{
+			Result: &ExecuteResultPayload{Value: 42.0, Stdout: "hello\nworld\n"},
+		}})
+	}()
+
+	var streamed []string
+	resp, err := client.ExecuteStream(context.Background(), RemoteRequest{Request: ExecutePayload{Code: "run()"}}, StreamHandler{
+		LogStreamer: runtime.LogStreamerFunc(func(stream runtime.LogStream, line string) {
+			streamed = append(streamed, string(stream)+":"+line)
+		}),
+		Gateway: gw,
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	if resp.Result == nil || resp.Result.Value != 42.0 {
+		t.Errorf("response = %+v", resp)
+	}
+	if want := []string{"stdout:hello", "stdout:world"}; !reflect.DeepEqual(streamed, want) {
+		t.Errorf("streamed = %q, want %q", streamed, want)
+	}
+	if n := gw.runs.Load(); n != 1 {
+		t.Errorf("RunTool called %d times, want 1", n)
+	}
+}
+
+func TestStreamClientCancel(t *testing.T) {
+	dialer := newPipeDialer(1)
+	client := NewStreamClient(StreamClientConfig{Dialer: dialer})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cancelled := make(chan StreamMessage, 1)
+	go func() {
+		conn := <-dialer.servers
+		receive(t, conn, StreamExecute)
+		send(t, conn, StreamMessage{Type: StreamAccepted, Seq: 1, ResumeToken: "t1"})
+		cancel()
+		cancelled <- receive(t, conn, StreamCancel)
+	}()
+
+	_, err := client.ExecuteStream(ctx, RemoteRequest{Request: ExecutePayload{Code: "loop()"}}, StreamHandler{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ExecuteStream() error = %v, want %v", err, context.Canceled)
+	}
+	if msg := <-cancelled; msg.ResumeToken != "t1" {
+		t.Errorf("cancel = %+v", msg)
+	}
+}
+
+func TestStreamClientConnectionFailures(t *testing.T) {
+	// Lost before acceptance: the execution may not have started, so it
+	// is not resumed.
+	dialer := newPipeDialer(5)
+	client := NewStreamClient(StreamClientConfig{Dialer: dialer, ReconnectBackoff: time.Millisecond})
+	go func() {
+		conn := <-dialer.servers
+		receive(t, conn, StreamExecute)
+		_ = conn.Close()
+	}()
+	if _, err := client.Execute(context.Background(), RemoteRequest{}); !errors.Is(err, ErrConnectionFailed) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrConnectionFailed)
+	}
+
+	dialer = newPipeDialer(1)
+	client = NewStreamClient(StreamClientConfig{Dialer: dialer, MaxReconnects: 2, ReconnectBackoff: time.Millisecond})
+	go func() {
+		conn := <-dialer.servers
+		receive(t, conn, StreamExecute)
+		send(t, conn, StreamMessage{Type: StreamAccepted, Seq: 1, ResumeToken: "t1"})
+		_ = conn.Close()
+	}()
+	if _, err := client.Execute(context.Background(), RemoteRequest{}); !errors.Is(err, ErrConnectionFailed) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrConnectionFailed)
+	}
+	if n := dialer.dials.Load(); n != 3 {
+		t.Errorf("dials = %d, want 3", n)
+	}
+
+	if _, err := NewStreamClient(StreamClientConfig{}).Execute(context.Background(), RemoteRequest{}); !errors.Is(err, ErrClientNotConfigured) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrClientNotConfigured)
+	}
+}
+
+func TestServeToolCall(t *testing.T) {
+	gw := &countingGateway{}
+	resp := serveToolCall(context.Background(), gw, proxy.Message{Type: proxy.MsgSearchTools, ID: "7", Payload: map[string]any{"query": "echo", "limit": 5.0}})
+	results, _ := resp.Payload["results"].([]any)
+	if resp.Type != proxy.MsgResponse || resp.ID != "7" || len(results) != 1 {
+		t.Errorf("search response = %+v", resp)
+	}
+
+	resp = serveToolCall(context.Background(), gw, proxy.Message{Type: "unknown", ID: "8"})
+	if resp.Type != proxy.MsgError || resp.ID != "8" {
+		t.Errorf("unknown response = %+v", resp)
+	}
+	resp = serveToolCall(context.Background(), nil, proxy.Message{Type: proxy.MsgRunTool, ID: "9"})
+	if resp.Type != proxy.MsgError {
+		t.Errorf("nil gateway response = %+v", resp)
+	}
+}
+
+// stubStreamingClient records the handler the backend passes.
+type stubStreamingClient struct {
+	stubClient
+	handler StreamHandler
+}
+
+func (s *stubStreamingClient) ExecuteStream(_ context.Context, req RemoteRequest, handler StreamHandler) (RemoteResponse, error) {
+	s.seen = req
+	s.handler = handler
+	return s.response, s.err
+}
+
+func TestBackendExecuteStreaming(t *testing.T) {
+	client := &stubStreamingClient{stubClient: stubClient{response: RemoteResponse{Result: &ExecuteResultPayload{Stdout: "ok"}}}}
+	gw := &mockGateway{}
+	streamer := runtime.LogStreamerFunc(func(runtime.LogStream, string) {})
+	result, err := New(Config{Client: client}).Execute(context.Background(), runtime.ExecuteRequest{
+		Code:        "return 1",
+		Gateway:     gw,
+		LogStreamer: streamer,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if client.handler.Gateway != gw || client.handler.LogStreamer == nil {
+		t.Errorf("handler = %+v", client.handler)
+	}
+	if result.Stdout != "ok" || result.Backend.Details["streaming"] != true {
+		t.Errorf("result = %+v", result)
+	}
+}
@@ -0,0 +1,143 @@
+package remote
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Errors for remote TLS configuration.
+var (
+	// ErrTLSConfig is returned when the TLS configuration is invalid or
+	// the client cannot apply it.
+	ErrTLSConfig = errors.New("invalid remote TLS configuration")
+
+	// ErrCertificatePinMismatch is returned when no certificate presented
+	// by the remote runtime matches a pinned public key.
+	ErrCertificatePinMismatch = errors.New("remote certificate does not match any pin")
+)
+
+// TLSConfig configures TLS for connections to the remote runtime.
+type TLSConfig struct {
+	// CAFile is a PEM bundle of certificate authorities trusted for the
+	// server certificate. It replaces the system roots.
+	CAFile string
+
+	// CAPEM holds PEM certificate authorities inline. It may be combined
+	// with CAFile.
+	CAPEM []byte
+
+	// CertFile and KeyFile are the PEM client certificate and key
+	// presented for mutual TLS. Both or neither must be set.
+	CertFile string
+	KeyFile  string
+
+	// ServerName overrides the host name verified against the server
+	// certificate.
+	ServerName string
+
+	// PinnedSPKI lists the SHA-256 hashes of trusted public keys, each as
+	// "sha256/" followed by the base64 hash of the DER SubjectPublicKeyInfo.
+	// When set, a connection fails unless a certificate in the verified
+	// chain matches one of them. Pin a backup key to survive rotation.
+	PinnedSPKI []string
+
+	// MinVersion is the minimum TLS version.
+	// Default: tls.VersionTLS12
+	MinVersion uint16
+}
+
+// TLSConfigurer is implemented by clients whose transport accepts TLS
+// settings, such as StreamClient. New passes Config.TLS to it.
+type TLSConfigurer interface {
+	// ConfigureTLS applies cfg to later connections. It is called before
+	// the client is used.
+	ConfigureTLS(cfg *tls.Config) error
+}
+
+// Build returns the crypto/tls configuration described by c.
+func (c TLSConfig) Build() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName: c.ServerName,
+		MinVersion: c.MinVersion,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+
+	if c.CAFile != "" || len(c.CAPEM) > 0 {
+		pool := x509.NewCertPool()
+		if c.CAFile != "" {
+			data, err := os.ReadFile(c.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("%w: ca file: %v", ErrTLSConfig, err)
+			}
+			if !pool.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("%w: no certificates in ca file %s", ErrTLSConfig, c.CAFile)
+			}
+		}
+		if len(c.CAPEM) > 0 && !pool.AppendCertsFromPEM(c.CAPEM) {
+			return nil, fmt.Errorf("%w: no certificates in ca pem", ErrTLSConfig)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, fmt.Errorf("%w: cert file and key file must be set together", ErrTLSConfig)
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: client certificate: %v", ErrTLSConfig, err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(c.PinnedSPKI) > 0 {
+		pins := make([][]byte, len(c.PinnedSPKI))
+		for i, pin := range c.PinnedSPKI {
+			hash, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256/"))
+			if err != nil || len(hash) != sha256.Size || !strings.HasPrefix(pin, "sha256/") {
+				return nil, fmt.Errorf("%w: pin %q must be sha256/ and a base64 SHA-256 hash", ErrTLSConfig, pin)
+			}
+			pins[i] = hash
+		}
+		// VerifyConnection runs after chain verification, on resumed
+		// sessions too, so the verified chains include the trusted root.
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			for _, chain := range cs.VerifiedChains {
+				if matchesPin(chain, pins) {
+					return nil
+				}
+			}
+			return ErrCertificatePinMismatch
+		}
+	}
+	return cfg, nil
+}
+
+// matchesPin reports whether a certificate of chain matches a pin.
+func matchesPin(chain []*x509.Certificate, pins [][]byte) bool {
+	for _, cert := range chain {
+		hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, pin := range pins {
+			if subtle.ConstantTimeCompare(hash[:], pin) == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SPKIPin returns the pin of cert's public key in the format PinnedSPKI
+// expects.
+func SPKIPin(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(hash[:])
+}
@@ -0,0 +1,254 @@
+package remote
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// testCert is a certificate and key issued by a test CA.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func (c testCert) certPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+}
+
+func (c testCert) keyPEM(t *testing.T) []byte {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func (c testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+// issue creates a certificate signed by parent, or a self-signed CA when
+// parent is nil.
+func issue(t *testing.T, parent *testCert, tmpl *x509.Certificate) testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	} else {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testCert{cert: cert, key: key}
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTLSConfigMutualAndPinning(t *testing.T) {
+	ca := issue(t, nil, &x509.Certificate{Subject: pkix.Name{CommonName: "test ca"}})
+	server := issue(t, &ca, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "runtime"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	client := issue(t, &ca, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "toolexec"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	other := issue(t, nil, &x509.Certificate{Subject: pkix.Name{CommonName: "other ca"}})
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{server.tlsCertificate()},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	caFile := writeFile(t, "ca.pem", ca.certPEM())
+	certFile := writeFile(t, "client.pem", client.certPEM())
+	keyFile := writeFile(t, "client.key", client.keyPEM(t))
+
+	get := func(t *testing.T, cfg TLSConfig) error {
+		t.Helper()
+		tlsCfg, err := cfg.Build()
+		if err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg}}
+		resp, err := httpClient.Get(srv.URL)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		return nil
+	}
+
+	tests := []struct {
+		name    string
+		cfg     TLSConfig
+		wantErr error
+	}{
+		{
+			name: "mutual tls",
+			cfg:  TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile},
+		},
+		{
+			name: "pinned ca key",
+			cfg: TLSConfig{CAPEM: ca.certPEM(), CertFile: certFile, KeyFile: keyFile,
+				PinnedSPKI: []string{SPKIPin(other.cert), SPKIPin(ca.cert)}},
+		},
+		{
+			name: "pinned leaf key",
+			cfg: TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile,
+				PinnedSPKI: []string{SPKIPin(server.cert)}},
+		},
+		{
+			name: "pin mismatch",
+			cfg: TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile,
+				PinnedSPKI: []string{SPKIPin(other.cert)}},
+			wantErr: ErrCertificatePinMismatch,
+		},
+		{
+			name:    "no client certificate",
+			cfg:     TLSConfig{CAFile: caFile},
+			wantErr: errAny,
+		},
+		{
+			name:    "untrusted server",
+			cfg:     TLSConfig{CAPEM: other.certPEM(), CertFile: certFile, KeyFile: keyFile},
+			wantErr: errAny,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := get(t, tt.cfg)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Errorf("Get() error = %v", err)
+			case tt.wantErr == errAny && err == nil:
+				t.Error("Get() succeeded, want error")
+			case tt.wantErr != nil && tt.wantErr != errAny && !errors.Is(err, tt.wantErr):
+				t.Errorf("Get() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// errAny marks a test case that expects some error.
+var errAny = errors.New("any error")
+
+func TestTLSConfigBuild(t *testing.T) {
+	ca := issue(t, nil, &x509.Certificate{Subject: pkix.Name{CommonName: "test ca"}})
+
+	cfg, err := TLSConfig{CAPEM: ca.certPEM(), ServerName: "runtime.internal"}.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if cfg.MinVersion != tls.VersionTLS12 || cfg.ServerName != "runtime.internal" || cfg.RootCAs == nil {
+		t.Errorf("Build() = %+v", cfg)
+	}
+	if cfg.VerifyConnection != nil {
+		t.Error("VerifyConnection set without pins")
+	}
+
+	for name, bad := range map[string]TLSConfig{
+		"missing ca file":  {CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		"empty ca pem":     {CAPEM: []byte("not pem")},
+		"cert without key": {CertFile: writeFile(t, "cert.pem", ca.certPEM())},
+		"bad key pair": {
+			CertFile: writeFile(t, "cert.pem", ca.certPEM()),
+			KeyFile:  writeFile(t, "key.pem", []byte("not a key")),
+		},
+		"pin without prefix": {PinnedSPKI: []string{SPKIPin(ca.cert)[len("sha256/"):]}},
+		"short pin":          {PinnedSPKI: []string{"sha256/AAAA"}},
+	} {
+		if _, err := bad.Build(); !errors.Is(err, ErrTLSConfig) {
+			t.Errorf("%s: Build() error = %v, want ErrTLSConfig", name, err)
+		}
+	}
+}
+
+// tlsStubClient is a stubClient that accepts TLS settings.
+type tlsStubClient struct {
+	stubClient
+	tlsConfig *tls.Config
+}
+
+func (c *tlsStubClient) ConfigureTLS(cfg *tls.Config) error {
+	c.tlsConfig = cfg
+	return nil
+}
+
+func TestBackendTLS(t *testing.T) {
+	ca := issue(t, nil, &x509.Certificate{Subject: pkix.Name{CommonName: "test ca"}})
+	req := runtime.ExecuteRequest{Code: "return 1", Gateway: &mockGateway{}}
+
+	client := &tlsStubClient{stubClient: stubClient{response: RemoteResponse{Result: &ExecuteResultPayload{Value: 1}}}}
+	b := New(Config{Client: client, TLS: &TLSConfig{CAPEM: ca.certPEM(), PinnedSPKI: []string{SPKIPin(ca.cert)}}})
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if client.tlsConfig == nil || client.tlsConfig.VerifyConnection == nil {
+		t.Errorf("ConfigureTLS() got %+v", client.tlsConfig)
+	}
+
+	// A client that cannot apply the settings must not connect without them.
+	b = New(Config{Client: &stubClient{}, TLS: &TLSConfig{CAPEM: ca.certPEM()}})
+	if _, err := b.Execute(context.Background(), req); !errors.Is(err, ErrTLSConfig) {
+		t.Errorf("Execute() error = %v, want ErrTLSConfig", err)
+	}
+
+	b = New(Config{Client: &tlsStubClient{}, TLS: &TLSConfig{PinnedSPKI: []string{"sha256/AAAA"}}})
+	if _, err := b.Execute(context.Background(), req); !errors.Is(err, ErrTLSConfig) {
+		t.Errorf("Execute() error = %v, want ErrTLSConfig", err)
+	}
+}
@@ -0,0 +1,19 @@
+package remote
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
+)
+
+// serveToolCall runs a gateway request from the remote code against gw
+// and returns the response in the payload shapes proxy.Gateway decodes.
+// The call's execution ID is used when ctx carries none.
+func serveToolCall(ctx context.Context, gw runtime.ToolGateway, call proxy.Message) proxy.Message {
+	if gw == nil {
+		return proxy.ErrorResponse(call.ID, errors.New("no tool gateway for this execution"))
+	}
+	return proxy.NewGatewayHandler(gw).ServeMessage(ctx, call)
+}
@@ -0,0 +1,24 @@
+module github.com/jonwraymond/toolexec/runtime/backend/remote/wsclient
+
+go 1.25.7
+
+require (
+	github.com/gorilla/websocket v1.5.3
+	github.com/jonwraymond/toolexec v0.3.0
+)
+
+require (
+	github.com/google/jsonschema-go v0.4.2 // indirect
+	github.com/jonwraymond/tooldiscovery v0.3.0 // indirect
+	github.com/jonwraymond/toolfoundation v0.3.0 // indirect
+	github.com/modelcontextprotocol/go-sdk v1.2.0 // indirect
+	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
+	golang.org/x/oauth2 v0.34.0 // indirect
+	golang.org/x/sys v0.40.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// Build against the enclosing checkout of toolexec. Consumers ignore this
+// replace and need the toolexec release required above, so tag it before
+// tagging this module.
+replace github.com/jonwraymond/toolexec => ../../../..
@@ -0,0 +1,266 @@
+// Package wsclient provides a remote.StreamDialer that carries the remote
+// streaming protocol over WebSocket connections.
+//
+// It is a separate module so that the core toolexec module does not
+// depend on a WebSocket library; import it only when the remote runtime
+// serves the streaming protocol:
+//
+//	dialer, err := wsclient.New(wsclient.Config{
+//		URL:    "wss://runtime.internal/v1/stream",
+//		Header: http.Header{"Authorization": {"Bearer " + token}},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	backend := remote.New(remote.Config{
+//		Client: remote.NewStreamClient(remote.StreamClientConfig{Dialer: dialer}),
+//	})
+//
+// Each StreamMessage travels as one JSON text message. The client pings
+// the server every PingInterval and treats a connection that stays silent
+// for twice that long as lost, so remote.StreamClient can resume the
+// execution on a new connection.
+package wsclient
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jonwraymond/toolexec/runtime/backend/remote"
+)
+
+// Config configures a Dialer.
+type Config struct {
+	// URL is the ws:// or wss:// endpoint of the remote runtime (required).
+	URL string
+
+	// Header is sent with the opening handshake, e.g. for authorization.
+	Header http.Header
+
+	// TLSConfig configures wss:// connections. If nil, the default
+	// configuration is used. remote.Config.TLS replaces it through
+	// ConfigureTLS.
+	TLSConfig *tls.Config
+
+	// HandshakeTimeout bounds the opening handshake.
+	// Default: 10s
+	HandshakeTimeout time.Duration
+
+	// PingInterval is how often the connection is pinged. A connection
+	// that receives nothing, not even a pong, for twice as long is lost.
+	// Default: 15s
+	PingInterval time.Duration
+
+	// WriteTimeout bounds each write.
+	// Default: 10s
+	WriteTimeout time.Duration
+
+	// MaxMessageBytes bounds a single received message.
+	// Default: 16 MiB
+	MaxMessageBytes int64
+}
+
+// Dialer opens WebSocket connections to a remote runtime. It implements
+// remote.StreamDialer, remote.EndpointProvider and remote.TLSConfigurer
+// and is safe for concurrent use.
+type Dialer struct {
+	url             string
+	header          http.Header
+	dialer          *websocket.Dialer
+	pingInterval    time.Duration
+	writeTimeout    time.Duration
+	maxMessageBytes int64
+}
+
+// New creates a Dialer.
+func New(cfg Config) (*Dialer, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return nil, fmt.Errorf("url %q must use ws or wss", cfg.URL)
+	}
+
+	handshakeTimeout := cfg.HandshakeTimeout
+	if handshakeTimeout <= 0 {
+		handshakeTimeout = 10 * time.Second
+	}
+	pingInterval := cfg.PingInterval
+	if pingInterval <= 0 {
+		pingInterval = 15 * time.Second
+	}
+	writeTimeout := cfg.WriteTimeout
+	if writeTimeout <= 0 {
+		writeTimeout = 10 * time.Second
+	}
+	maxMessageBytes := cfg.MaxMessageBytes
+	if maxMessageBytes <= 0 {
+		maxMessageBytes = 16 << 20
+	}
+
+	return &Dialer{
+		url:    cfg.URL,
+		header: cfg.Header,
+		dialer: &websocket.Dialer{
+			Proxy:            http.ProxyFromEnvironment,
+			TLSClientConfig:  cfg.TLSConfig,
+			HandshakeTimeout: handshakeTimeout,
+		},
+		pingInterval:    pingInterval,
+		writeTimeout:    writeTimeout,
+		maxMessageBytes: maxMessageBytes,
+	}, nil
+}
+
+// Endpoint implements remote.EndpointProvider.
+func (d *Dialer) Endpoint() string {
+	return d.url
+}
+
+// ConfigureTLS implements remote.TLSConfigurer. It must be called before
+// the first DialStream.
+func (d *Dialer) ConfigureTLS(cfg *tls.Config) error {
+	d.dialer.TLSClientConfig = cfg.Clone()
+	return nil
+}
+
+// DialStream implements remote.StreamDialer.
+func (d *Dialer) DialStream(ctx context.Context) (remote.StreamConn, error) {
+	ws, resp, err := d.dialer.DialContext(ctx, d.url, d.header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("websocket handshake: %s: %w", resp.Status, err)
+		}
+		return nil, fmt.Errorf("websocket dial: %w", err)
+	}
+	ws.SetReadLimit(d.maxMessageBytes)
+	c := &conn{
+		ws:           ws,
+		writeTimeout: d.writeTimeout,
+		idleTimeout:  2 * d.pingInterval,
+		done:         make(chan struct{}),
+	}
+	_ = ws.SetReadDeadline(time.Now().Add(c.idleTimeout))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(c.idleTimeout))
+	})
+	go c.ping(d.pingInterval)
+	return c, nil
+}
+
+// conn is a remote.StreamConn over one WebSocket connection. Gorilla
+// connections support one concurrent reader and one concurrent writer, so
+// reads and writes are each serialized.
+type conn struct {
+	ws           *websocket.Conn
+	writeTimeout time.Duration
+	idleTimeout  time.Duration
+
+	readMu  sync.Mutex
+	writeMu sync.Mutex
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// Send implements remote.StreamConn.
+func (c *conn) Send(ctx context.Context, msg remote.StreamMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(c.writeTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = c.ws.SetWriteDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { _ = c.ws.SetWriteDeadline(time.Unix(1, 0)) })
+	defer stop()
+	if err := c.ws.WriteJSON(msg); err != nil {
+		return c.err(ctx, err)
+	}
+	return nil
+}
+
+// Receive implements remote.StreamConn. Cancelling ctx interrupts the read
+// and leaves the connection unusable, as a timed-out WebSocket read does.
+func (c *conn) Receive(ctx context.Context) (remote.StreamMessage, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return remote.StreamMessage{}, err
+	}
+	stop := context.AfterFunc(ctx, func() { _ = c.ws.SetReadDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	var msg remote.StreamMessage
+	for {
+		kind, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return remote.StreamMessage{}, c.err(ctx, err)
+		}
+		// Any message proves the peer alive.
+		_ = c.ws.SetReadDeadline(time.Now().Add(c.idleTimeout))
+		if kind != websocket.TextMessage {
+			continue
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return remote.StreamMessage{}, fmt.Errorf("%w: decode: %v", remote.ErrStreamProtocol, err)
+		}
+		return msg, nil
+	}
+}
+
+// Close implements remote.StreamConn. It sends a close frame, best-effort,
+// before closing the connection.
+func (c *conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		_ = c.ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(time.Second))
+		err = c.ws.Close()
+	})
+	return err
+}
+
+// ping keeps the connection alive until it is closed.
+func (c *conn) ping(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			// WriteControl may be called concurrently with other methods.
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.writeTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// err reports ctx's error when ctx interrupted the operation.
+func (c *conn) err(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+var (
+	_ remote.StreamDialer     = (*Dialer)(nil)
+	_ remote.EndpointProvider = (*Dialer)(nil)
+	_ remote.TLSConfigurer    = (*Dialer)(nil)
+)
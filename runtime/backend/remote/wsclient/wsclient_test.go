@@ -0,0 +1,189 @@
+package wsclient
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/remote"
+)
+
+// newServer starts a WebSocket server that runs serve for each connection
+// and returns its ws:// URL.
+func newServer(t *testing.T, serve func(*websocket.Conn)) string {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		serve(ws)
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestDialerStream(t *testing.T) {
+	url := newServer(t, func(ws *websocket.Conn) {
+		var msg remote.StreamMessage
+		if err := ws.ReadJSON(&msg); err != nil || msg.Type != remote.StreamExecute {
+			t.Errorf("ReadJSON() = %+v, %v", msg, err)
+			return
+		}
+		// Keep reading, which answers pings, and outlive a few ping
+		// intervals so the keepalive is exercised.
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				if _, _, err := ws.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+		time.Sleep(50 * time.Millisecond)
+		for _, m := range []remote.StreamMessage{
+			{Type: remote.StreamAccepted, Seq: 1, ResumeToken: "t1"},
+			{Type: remote.StreamStdout, Seq: 2, Data: "hello\n"},
+			{Type: remote.StreamResult, Seq: 3, Response: &remote.RemoteResponse{
+				Result: &remote.ExecuteResultPayload{Value: "done", Stdout: "hello\n"},
+			}},
+		} {
+			if err := ws.WriteJSON(m); err != nil {
+				t.Errorf("WriteJSON() error = %v", err)
+				return
+			}
+		}
+		<-done
+	})
+
+	dialer, err := New(Config{
+		URL:          url,
+		Header:       http.Header{"Authorization": {"Bearer token"}},
+		PingInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if dialer.Endpoint() != url {
+		t.Errorf("Endpoint() = %q, want %q", dialer.Endpoint(), url)
+	}
+
+	var lines []string
+	client := remote.NewStreamClient(remote.StreamClientConfig{Dialer: dialer})
+	resp, err := client.ExecuteStream(context.Background(), remote.RemoteRequest{Request: remote.ExecutePayload{Code: "run()"}}, remote.StreamHandler{
+		LogStreamer: runtime.LogStreamerFunc(func(_ runtime.LogStream, line string) {
+			lines = append(lines, line)
+		}),
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	if resp.Result == nil || resp.Result.Value != "done" {
+		t.Errorf("response = %+v", resp)
+	}
+	if len(lines) != 1 || lines[0] != "hello" {
+		t.Errorf("lines = %q", lines)
+	}
+}
+
+func TestDialerErrors(t *testing.T) {
+	if _, err := New(Config{URL: "http://runtime"}); err == nil {
+		t.Error("New() with an http URL succeeded, want an error")
+	}
+
+	url := newServer(t, func(*websocket.Conn) {})
+	dialer, err := New(Config{URL: url})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := dialer.DialStream(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("DialStream() error = %v, want a 401 handshake error", err)
+	}
+
+	// A server that goes silent is detected by the missing pongs.
+	silent := newServer(t, func(ws *websocket.Conn) {
+		ws.SetPingHandler(func(string) error { return nil })
+		_, _, _ = ws.ReadMessage()
+	})
+	dialer, _ = New(Config{URL: silent, Header: http.Header{"Authorization": {"Bearer token"}}, PingInterval: 10 * time.Millisecond})
+	conn, err := dialer.DialStream(context.Background())
+	if err != nil {
+		t.Fatalf("DialStream() error = %v", err)
+	}
+	defer conn.Close()
+	start := time.Now()
+	if _, err := conn.Receive(context.Background()); err == nil {
+		t.Error("Receive() from a silent server succeeded, want an error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Receive() took %v to detect a silent server", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := conn.Receive(ctx); err != context.Canceled {
+		t.Errorf("Receive() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestDialerTLS(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		var msg remote.StreamMessage
+		if err := ws.ReadJSON(&msg); err != nil {
+			return
+		}
+		_ = ws.WriteJSON(remote.StreamMessage{Type: remote.StreamAccepted, Seq: 1, ResumeToken: "t1"})
+		_ = ws.WriteJSON(remote.StreamMessage{Type: remote.StreamResult, Seq: 2, Response: &remote.RemoteResponse{
+			Result: &remote.ExecuteResultPayload{Value: "done"},
+		}})
+		_, _, _ = ws.ReadMessage()
+	}))
+	t.Cleanup(srv.Close)
+	url := "wss" + strings.TrimPrefix(srv.URL, "https")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	execute := func(tlsCfg remote.TLSConfig) error {
+		dialer, err := New(Config{URL: url})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		cfg, err := tlsCfg.Build()
+		if err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+		client := remote.NewStreamClient(remote.StreamClientConfig{Dialer: dialer})
+		if err := client.ConfigureTLS(cfg); err != nil {
+			t.Fatalf("ConfigureTLS() error = %v", err)
+		}
+		_, err = client.ExecuteStream(context.Background(), remote.RemoteRequest{Request: remote.ExecutePayload{Code: "run()"}}, remote.StreamHandler{})
+		return err
+	}
+
+	if err := execute(remote.TLSConfig{CAPEM: caPEM, PinnedSPKI: []string{remote.SPKIPin(srv.Certificate())}}); err != nil {
+		t.Errorf("ExecuteStream() error = %v", err)
+	}
+	if err := execute(remote.TLSConfig{CAPEM: caPEM, PinnedSPKI: []string{"sha256/" + strings.Repeat("A", 43) + "="}}); !errors.Is(err, remote.ErrConnectionFailed) ||
+		!strings.Contains(err.Error(), remote.ErrCertificatePinMismatch.Error()) {
+		t.Errorf("ExecuteStream() error = %v, want a pin mismatch", err)
+	}
+}
@@ -0,0 +1,72 @@
+// Package zstdcodec provides a zstd remote.Compressor for the remote
+// backend's payloads.
+//
+// It is a separate module so that the core toolexec module does not
+// depend on a zstd library:
+//
+//	backend := remote.New(remote.Config{
+//		Client:      client,
+//		Compression: []remote.Compressor{zstdcodec.New(zstdcodec.Config{}), remote.Gzip},
+//	})
+package zstdcodec
+
+import (
+	"io"
+
+	"github.com/jonwraymond/toolexec/runtime/backend/remote"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Config configures a Compressor.
+type Config struct {
+	// Level is the encoder level.
+	// Default: zstd.SpeedDefault
+	Level zstd.EncoderLevel
+
+	// MaxWindowBytes bounds the window a received frame may declare, and so
+	// the memory one decompression may use.
+	// Default: 8 MiB
+	MaxWindowBytes uint64
+}
+
+// Compressor implements remote.Compressor with zstd. It is safe for
+// concurrent use.
+type Compressor struct {
+	level          zstd.EncoderLevel
+	maxWindowBytes uint64
+}
+
+// New creates a Compressor.
+func New(cfg Config) *Compressor {
+	level := cfg.Level
+	if level == 0 {
+		level = zstd.SpeedDefault
+	}
+	maxWindowBytes := cfg.MaxWindowBytes
+	if maxWindowBytes == 0 {
+		maxWindowBytes = 8 << 20
+	}
+	return &Compressor{level: level, maxWindowBytes: maxWindowBytes}
+}
+
+// Encoding implements remote.Compressor.
+func (c *Compressor) Encoding() string { return "zstd" }
+
+// Compress implements remote.Compressor.
+func (c *Compressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(c.level), zstd.WithEncoderConcurrency(1))
+}
+
+// Decompress implements remote.Compressor.
+func (c *Compressor) Decompress(r io.Reader) (io.ReadCloser, error) {
+	dec, err := zstd.NewReader(r,
+		zstd.WithDecoderConcurrency(1),
+		zstd.WithDecoderMaxWindow(c.maxWindowBytes),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return dec.IOReadCloser(), nil
+}
+
+var _ remote.Compressor = (*Compressor)(nil)
@@ -0,0 +1,48 @@
+package zstdcodec
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jonwraymond/toolexec/runtime/backend/remote"
+)
+
+func TestCompressorRoundTrip(t *testing.T) {
+	codec := remote.PayloadCodec{Compressors: []remote.Compressor{New(Config{}), remote.Gzip}}
+	if got := codec.Negotiate("gzip, zstd"); got != "zstd" {
+		t.Errorf("Negotiate() = %q, want zstd", got)
+	}
+
+	code := strings.Repeat("print('hello')\n", 200)
+	body, encoding, err := codec.EncodeRequest(remote.RemoteRequest{Request: remote.ExecutePayload{Code: code}}, "zstd")
+	if err != nil {
+		t.Fatalf("EncodeRequest() error = %v", err)
+	}
+	if encoding != "zstd" || len(body) >= len(code) {
+		t.Errorf("EncodeRequest() = %d bytes, %q; want a smaller zstd body", len(body), encoding)
+	}
+	req, err := codec.DecodeRequest(bytes.NewReader(body), "zstd")
+	if err != nil {
+		t.Fatalf("DecodeRequest() error = %v", err)
+	}
+	if req.Request.Code != code {
+		t.Error("DecodeRequest() did not round-trip the code")
+	}
+}
+
+func TestCompressorLimit(t *testing.T) {
+	codec := remote.PayloadCodec{Compressors: []remote.Compressor{New(Config{})}}
+	body, encoding, err := codec.EncodeResponse(remote.RemoteResponse{
+		Result: &remote.ExecuteResultPayload{Stdout: strings.Repeat("a", 4<<20)},
+	}, "zstd")
+	if err != nil {
+		t.Fatalf("EncodeResponse() error = %v", err)
+	}
+
+	codec.MaxPayloadBytes = 1 << 20
+	if _, err := codec.DecodeResponse(bytes.NewReader(body), encoding); !errors.Is(err, remote.ErrPayloadTooLarge) {
+		t.Errorf("DecodeResponse() error = %v, want ErrPayloadTooLarge", err)
+	}
+}
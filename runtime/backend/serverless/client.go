@@ -0,0 +1,56 @@
+package serverless
+
+import (
+	"context"
+	"time"
+)
+
+// Invoker calls a cloud function synchronously and returns its response.
+// Implementations exist per provider: lambdaclient for the AWS Lambda Invoke
+// API, and HTTPInvoker for functions behind an HTTPS trigger.
+//
+// Contract:
+// - Concurrency: Implementations must be safe for concurrent use.
+// - Context: Invoke must honor cancellation and deadlines.
+// - Errors: a function that ran and failed is reported in
+// InvokeResult.FunctionError, not as an error.
+type Invoker interface {
+	Invoke(ctx context.Context, inv Invocation) (InvokeResult, error)
+}
+
+// Invocation is one synchronous function call.
+type Invocation struct {
+	// Function is the function selected for the execution's limits.
+	Function Function
+
+	// Payload is the JSON-encoded remote.RemoteRequest for the sandbox agent.
+	Payload []byte
+
+	// Timeout is the execution timeout the agent enforces. The function's
+	// own timeout is at least this long.
+	Timeout time.Duration
+}
+
+// InvokeResult is the outcome of a function call.
+type InvokeResult struct {
+	// Payload is the function's response, a JSON-encoded
+	// remote.RemoteResponse when FunctionError is empty.
+	Payload []byte
+
+	// FunctionError is set when the function itself failed, e.g. Lambda's
+	// "Unhandled". Payload then holds the provider's error document.
+	FunctionError string
+
+	// RequestID is the provider's identifier for the call, if known.
+	RequestID string
+
+	// BilledDuration is the duration the provider bills, if reported.
+	BilledDuration time.Duration
+
+	// InitDuration is the cold-start initialization time, or zero when a
+	// warm instance served the call.
+	InitDuration time.Duration
+
+	// MaxMemoryBytes is the peak memory the function used, if reported.
+	MaxMemoryBytes int64
+}
@@ -0,0 +1,93 @@
+package serverless
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Cloud function providers.
+const (
+	ProviderAWSLambda         = "aws-lambda"
+	ProviderGCPCloudFunctions = "gcp-cloud-functions"
+	ProviderAzureFunctions    = "azure-functions"
+)
+
+// mib is the unit function memory is configured in.
+const mib = 1 << 20
+
+// Function is a deployed function running the sandbox agent. Providers fix
+// memory and timeout per function, so deploy one function per size tier
+// and list them all; each execution runs on the smallest that fits its
+// limits.
+type Function struct {
+	// Name identifies the function: a Lambda name or ARN (optionally with
+	// a ":qualifier"), or the HTTPS trigger URL for HTTPInvoker.
+	Name string
+
+	// MemoryMB is the memory configured for the function.
+	MemoryMB int
+
+	// Timeout is the timeout configured for the function.
+	Timeout time.Duration
+}
+
+// ProviderLimits bounds the function configuration a provider supports.
+type ProviderLimits struct {
+	// MinMemoryMB and MaxMemoryMB bound a function's memory.
+	MinMemoryMB int
+	MaxMemoryMB int
+
+	// MaxTimeout is the longest synchronous invocation.
+	MaxTimeout time.Duration
+
+	// MaxPayloadBytes is the largest synchronous request payload.
+	MaxPayloadBytes int
+}
+
+// LimitsFor returns the documented limits of provider, or zero limits
+// (unchecked) for providers it does not know.
+func LimitsFor(provider string) ProviderLimits {
+	switch provider {
+	case ProviderAWSLambda:
+		return ProviderLimits{MinMemoryMB: 128, MaxMemoryMB: 10240, MaxTimeout: 15 * time.Minute, MaxPayloadBytes: 6 * mib}
+	case ProviderGCPCloudFunctions:
+		return ProviderLimits{MinMemoryMB: 128, MaxMemoryMB: 32768, MaxTimeout: 60 * time.Minute, MaxPayloadBytes: 32 * mib}
+	case ProviderAzureFunctions:
+		// HTTP triggers are cut off by the front-end load balancer at 230s.
+		return ProviderLimits{MinMemoryMB: 128, MaxMemoryMB: 14336, MaxTimeout: 230 * time.Second, MaxPayloadBytes: 100 * mib}
+	}
+	return ProviderLimits{}
+}
+
+// merge returns l with its zero fields taken from defaults.
+func (l ProviderLimits) merge(defaults ProviderLimits) ProviderLimits {
+	l.MinMemoryMB = cmp.Or(l.MinMemoryMB, defaults.MinMemoryMB)
+	l.MaxMemoryMB = cmp.Or(l.MaxMemoryMB, defaults.MaxMemoryMB)
+	l.MaxTimeout = cmp.Or(l.MaxTimeout, defaults.MaxTimeout)
+	l.MaxPayloadBytes = cmp.Or(l.MaxPayloadBytes, defaults.MaxPayloadBytes)
+	return l
+}
+
+// memoryMB converts a memory limit in bytes to whole megabytes, rounding up.
+func memoryMB(bytes int64) int {
+	return int((bytes + mib - 1) / mib)
+}
+
+// selectFunction returns the function with the least memory, then the
+// shortest timeout, that provides at least memMB and timeout.
+func selectFunction(functions []Function, memMB int, timeout time.Duration) (Function, error) {
+	var fits []Function
+	for _, fn := range functions {
+		if fn.MemoryMB >= memMB && fn.Timeout >= timeout {
+			fits = append(fits, fn)
+		}
+	}
+	if len(fits) == 0 {
+		return Function{}, fmt.Errorf("%w: need %d MB for %s", ErrNoFunction, memMB, timeout)
+	}
+	return slices.MinFunc(fits, func(a, b Function) int {
+		return cmp.Or(cmp.Compare(a.MemoryMB, b.MemoryMB), cmp.Compare(a.Timeout, b.Timeout))
+	}), nil
+}
@@ -0,0 +1,81 @@
+package serverless
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTPInvoker invokes functions through their HTTPS trigger: a Lambda
+// function URL, a GCP Cloud Functions or Cloud Run URL, or an Azure
+// Functions HTTP trigger. Function.Name is the URL.
+//
+// Contract:
+// - Concurrency: safe for concurrent use if Authorize is.
+// - Errors: 5xx responses are reported as InvokeResult.FunctionError;
+// other non-2xx responses are errors.
+type HTTPInvoker struct {
+	// Client sends the requests. Default: http.DefaultClient
+	Client *http.Client
+
+	// Header is added to every request, e.g. Azure's "x-functions-key".
+	Header http.Header
+
+	// Authorize, if set, signs or authorizes each request, e.g. by adding
+	// a GCP identity token.
+	Authorize func(ctx context.Context, req *http.Request) error
+}
+
+var _ Invoker = (*HTTPInvoker)(nil)
+
+// requestIDHeaders are the response headers providers report the
+// invocation ID in.
+var requestIDHeaders = []string{"X-Amzn-Requestid", "Function-Execution-Id", "X-Cloud-Trace-Context"}
+
+// Invoke POSTs the payload to the function URL.
+func (h *HTTPInvoker) Invoke(ctx context.Context, inv Invocation) (InvokeResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inv.Function.Name, bytes.NewReader(inv.Payload))
+	if err != nil {
+		return InvokeResult{}, err
+	}
+	for k, v := range h.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.Authorize != nil {
+		if err := h.Authorize(ctx, req); err != nil {
+			return InvokeResult{}, fmt.Errorf("authorize: %w", err)
+		}
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return InvokeResult{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return InvokeResult{}, err
+	}
+
+	result := InvokeResult{Payload: body}
+	for _, name := range requestIDHeaders {
+		if id := resp.Header.Get(name); id != "" {
+			result.RequestID = id
+			break
+		}
+	}
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		result.FunctionError = resp.Status
+	case resp.StatusCode >= http.StatusMultipleChoices:
+		return InvokeResult{}, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package serverless
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPInvoker(t *testing.T) {
+	var gotKey, gotAuth, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("X-Functions-Key")
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.Header().Set("Function-Execution-Id", "exec-1")
+		switch r.URL.Path {
+		case "/crash":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		case "/denied":
+			http.Error(w, "forbidden", http.StatusForbidden)
+		default:
+			_, _ = io.WriteString(w, `{"result":{}}`)
+		}
+	}))
+	defer srv.Close()
+
+	h := &HTTPInvoker{
+		Header: http.Header{"X-Functions-Key": {"key"}},
+		Authorize: func(_ context.Context, req *http.Request) error {
+			req.Header.Set("Authorization", "Bearer token")
+			return nil
+		},
+	}
+	res, err := h.Invoke(context.Background(), Invocation{Function: Function{Name: srv.URL + "/agent"}, Payload: []byte(`{"request":{}}`)})
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if string(res.Payload) != `{"result":{}}` || res.RequestID != "exec-1" || res.FunctionError != "" {
+		t.Errorf("Invoke() = %+v", res)
+	}
+	if gotKey != "key" || gotAuth != "Bearer token" || gotBody != `{"request":{}}` {
+		t.Errorf("request key = %q, auth = %q, body = %q", gotKey, gotAuth, gotBody)
+	}
+
+	res, err = h.Invoke(context.Background(), Invocation{Function: Function{Name: srv.URL + "/crash"}})
+	if err != nil || res.FunctionError == "" {
+		t.Errorf("Invoke() on 500 = %+v, %v, want a function error", res, err)
+	}
+	if _, err := h.Invoke(context.Background(), Invocation{Function: Function{Name: srv.URL + "/denied"}}); err == nil {
+		t.Error("Invoke() on 403 succeeded, want an error")
+	}
+
+	h.Authorize = func(context.Context, *http.Request) error { return errors.New("no credentials") }
+	if _, err := h.Invoke(context.Background(), Invocation{Function: Function{Name: srv.URL}}); err == nil {
+		t.Error("Invoke() with failing Authorize succeeded, want an error")
+	}
+}
@@ -0,0 +1,166 @@
+// Package lambdaclient provides a serverless.Invoker backed by the AWS
+// Lambda Invoke API.
+//
+// It is a separate module so that the core toolexec module does not
+// depend on the AWS SDK; import it only when running the serverless
+// backend against Lambda:
+//
+//	invoker, err := lambdaclient.New(ctx, lambdaclient.Config{Region: "us-east-1"})
+//	if err != nil {
+//		return err
+//	}
+//	backend := serverless.New(serverless.Config{
+//		Invoker:   invoker,
+//		Functions: functions,
+//	})
+//
+// Invoker loads credentials through the SDK's default chain (environment,
+// shared config, and instance or task roles) unless Config.Client is set.
+// Calls are synchronous (RequestResponse) and request the log tail, so
+// each InvokeResult carries the request ID, billed duration, cold-start
+// init duration, and peak memory from Lambda's REPORT line. The caller
+// needs lambda:InvokeFunction on every configured function.
+package lambdaclient
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/jonwraymond/toolexec/runtime/backend/serverless"
+)
+
+// API is the subset of the Lambda client used by Invoker.
+// *lambda.Client implements it.
+type API interface {
+	Invoke(ctx context.Context, params *lambda.InvokeInput, optFns ...func(*lambda.Options)) (*lambda.InvokeOutput, error)
+}
+
+// Config configures an Invoker.
+type Config struct {
+	// Client is the Lambda API client. If nil, a client is created from
+	// the default AWS configuration.
+	Client API
+
+	// Region overrides the region of the default configuration.
+	// Ignored when Client is set.
+	Region string
+
+	// Qualifier is the version or alias invoked when a function name has
+	// none, e.g. "live". Empty invokes $LATEST.
+	Qualifier string
+}
+
+// Invoker invokes Lambda functions. It implements serverless.Invoker and
+// is safe for concurrent use.
+type Invoker struct {
+	api       API
+	qualifier string
+}
+
+// New creates an Invoker.
+func New(ctx context.Context, cfg Config) (*Invoker, error) {
+	api := cfg.Client
+	if api == nil {
+		var opts []func(*config.LoadOptions) error
+		if cfg.Region != "" {
+			opts = append(opts, config.WithRegion(cfg.Region))
+		}
+		awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("%w: load aws config: %v", serverless.ErrInvocationFailed, err)
+		}
+		api = lambda.NewFromConfig(awsCfg)
+	}
+	return &Invoker{api: api, qualifier: cfg.Qualifier}, nil
+}
+
+// Invoke implements serverless.Invoker.
+func (i *Invoker) Invoke(ctx context.Context, inv serverless.Invocation) (serverless.InvokeResult, error) {
+	input := &lambda.InvokeInput{
+		FunctionName:   aws.String(inv.Function.Name),
+		InvocationType: types.InvocationTypeRequestResponse,
+		LogType:        types.LogTypeTail,
+		Payload:        inv.Payload,
+	}
+	if i.qualifier != "" && !hasQualifier(inv.Function.Name) {
+		input.Qualifier = aws.String(i.qualifier)
+	}
+	out, err := i.api.Invoke(ctx, input)
+	if err != nil {
+		return serverless.InvokeResult{}, err
+	}
+	if out.StatusCode != 0 && (out.StatusCode < 200 || out.StatusCode > 299) {
+		return serverless.InvokeResult{}, fmt.Errorf("lambda returned status %d", out.StatusCode)
+	}
+	result := serverless.InvokeResult{
+		Payload:       out.Payload,
+		FunctionError: aws.ToString(out.FunctionError),
+	}
+	if logs, err := base64.StdEncoding.DecodeString(aws.ToString(out.LogResult)); err == nil {
+		parseReport(string(logs), &result)
+	}
+	return result, nil
+}
+
+// hasQualifier reports whether a function name or ARN already names a
+// version or alias: "fn:alias" or "arn:aws:lambda:region:account:function:fn:alias".
+func hasQualifier(name string) bool {
+	parts := strings.Split(name, ":")
+	if parts[0] == "arn" {
+		return len(parts) > 7
+	}
+	return len(parts) > 1
+}
+
+// parseReport fills result from the REPORT line Lambda appends to the log
+// tail, e.g.
+//
+//	REPORT RequestId: 8f5c…	Duration: 102.35 ms	Billed Duration: 103 ms	Memory Size: 512 MB	Max Memory Used: 70 MB	Init Duration: 150.23 ms
+func parseReport(logs string, result *serverless.InvokeResult) {
+	var report string
+	for line := range strings.Lines(logs) {
+		if strings.HasPrefix(line, "REPORT ") {
+			report = strings.TrimPrefix(strings.TrimSpace(line), "REPORT ")
+		}
+	}
+	for field := range strings.SplitSeq(report, "\t") {
+		key, value, ok := strings.Cut(field, ": ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "RequestId":
+			result.RequestID = value
+		case "Billed Duration":
+			result.BilledDuration = parseMillis(value)
+		case "Init Duration":
+			result.InitDuration = parseMillis(value)
+		case "Max Memory Used":
+			if mb, err := strconv.ParseInt(strings.TrimSuffix(value, " MB"), 10, 64); err == nil {
+				result.MaxMemoryBytes = mb << 20
+			}
+		}
+	}
+}
+
+// parseMillis parses a "<n> ms" duration.
+func parseMillis(value string) time.Duration {
+	ms, err := strconv.ParseFloat(strings.TrimSuffix(value, " ms"), 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+var (
+	_ serverless.Invoker = (*Invoker)(nil)
+	_ API                = (*lambda.Client)(nil)
+)
@@ -0,0 +1,103 @@
+package lambdaclient
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/jonwraymond/toolexec/runtime/backend/serverless"
+)
+
+type fakeAPI struct {
+	input *lambda.InvokeInput
+	out   *lambda.InvokeOutput
+	err   error
+}
+
+func (f *fakeAPI) Invoke(_ context.Context, params *lambda.InvokeInput, _ ...func(*lambda.Options)) (*lambda.InvokeOutput, error) {
+	f.input = params
+	return f.out, f.err
+}
+
+const report = "START RequestId: req-1 Version: $LATEST\n" +
+	"END RequestId: req-1\n" +
+	"REPORT RequestId: req-1\tDuration: 102.35 ms\tBilled Duration: 103 ms\tMemory Size: 512 MB\tMax Memory Used: 70 MB\tInit Duration: 150.5 ms\t\n"
+
+func TestInvoke(t *testing.T) {
+	api := &fakeAPI{out: &lambda.InvokeOutput{
+		StatusCode: 200,
+		Payload:    []byte(`{"result":{}}`),
+		LogResult:  aws.String(base64.StdEncoding.EncodeToString([]byte(report))),
+	}}
+	inv, err := New(context.Background(), Config{Client: api, Qualifier: "live"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := inv.Invoke(context.Background(), serverless.Invocation{
+		Function: serverless.Function{Name: "sandbox-agent-512"},
+		Payload:  []byte(`{"request":{}}`),
+	})
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if got := api.input; aws.ToString(got.FunctionName) != "sandbox-agent-512" || aws.ToString(got.Qualifier) != "live" ||
+		got.InvocationType != types.InvocationTypeRequestResponse || got.LogType != types.LogTypeTail {
+		t.Errorf("input = %+v", got)
+	}
+	want := serverless.InvokeResult{
+		Payload:        []byte(`{"result":{}}`),
+		RequestID:      "req-1",
+		BilledDuration: 103 * time.Millisecond,
+		InitDuration:   150500 * time.Microsecond,
+		MaxMemoryBytes: 70 << 20,
+	}
+	if string(res.Payload) != string(want.Payload) || res.RequestID != want.RequestID || res.BilledDuration != want.BilledDuration ||
+		res.InitDuration != want.InitDuration || res.MaxMemoryBytes != want.MaxMemoryBytes {
+		t.Errorf("Invoke() = %+v, want %+v", res, want)
+	}
+}
+
+func TestInvokeFunctionError(t *testing.T) {
+	api := &fakeAPI{out: &lambda.InvokeOutput{
+		StatusCode:    200,
+		FunctionError: aws.String("Unhandled"),
+		Payload:       []byte(`{"errorMessage":"boom"}`),
+	}}
+	inv, _ := New(context.Background(), Config{Client: api, Qualifier: "live"})
+
+	res, err := inv.Invoke(context.Background(), serverless.Invocation{Function: serverless.Function{Name: "agent:canary"}})
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if res.FunctionError != "Unhandled" {
+		t.Errorf("FunctionError = %q, want Unhandled", res.FunctionError)
+	}
+	if api.input.Qualifier != nil {
+		t.Errorf("Qualifier = %q, want none for a qualified name", aws.ToString(api.input.Qualifier))
+	}
+
+	api.err = errors.New("TooManyRequestsException")
+	if _, err := inv.Invoke(context.Background(), serverless.Invocation{Function: serverless.Function{Name: "agent"}}); err == nil {
+		t.Error("Invoke() succeeded, want the API error")
+	}
+}
+
+func TestHasQualifier(t *testing.T) {
+	tests := map[string]bool{
+		"agent":      false,
+		"agent:live": true,
+		"arn:aws:lambda:us-east-1:123456789012:function:agent":      false,
+		"arn:aws:lambda:us-east-1:123456789012:function:agent:live": true,
+	}
+	for name, want := range tests {
+		if got := hasQualifier(name); got != want {
+			t.Errorf("hasQualifier(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
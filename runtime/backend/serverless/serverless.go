@@ -0,0 +1,283 @@
+// Package serverless provides a backend that executes code by invoking a
+// cloud function running the sandbox agent: AWS Lambda, or a GCP or Azure
+// equivalent through a pluggable Invoker. Functions scale to zero between
+// calls, which suits bursty multi-tenant workloads.
+//
+// Each execution is packaged as the remote backend's wire request
+// (remote.RemoteRequest) and the function answers with a
+// remote.RemoteResponse, so the same agent serves both backends.
+//
+// Providers fix memory and timeout when a function is deployed, so the
+// backend maps an execution's limits onto function configuration by
+// choosing among Config.Functions: the smallest function whose memory
+// covers Limits.MemoryBytes and whose timeout covers the execution timeout.
+//
+//	invoker, err := lambdaclient.New(ctx, lambdaclient.Config{})
+//	if err != nil {
+//		return err
+//	}
+//	backend := serverless.New(serverless.Config{
+//		Invoker: invoker,
+//		Functions: []serverless.Function{
+//			{Name: "sandbox-agent-512", MemoryMB: 512, Timeout: time.Minute},
+//			{Name: "sandbox-agent-2048", MemoryMB: 2048, Timeout: 5 * time.Minute},
+//		},
+//	})
+package serverless
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/remote"
+)
+
+// Errors for serverless backend operations.
+var (
+	// ErrClientNotConfigured is returned when no Invoker is configured.
+	ErrClientNotConfigured = errors.New("serverless invoker not configured")
+
+	// ErrInvalidFunction is returned when a configured function is invalid
+	// or outside the provider's limits.
+	ErrInvalidFunction = errors.New("invalid function configuration")
+
+	// ErrNoFunction is returned when no configured function provides the
+	// memory and timeout an execution needs.
+	ErrNoFunction = errors.New("no function satisfies the execution limits")
+
+	// ErrPayloadTooLarge is returned when the packaged request exceeds the
+	// provider's synchronous payload limit.
+	ErrPayloadTooLarge = errors.New("execute payload too large")
+
+	// ErrInvocationFailed is returned when the function cannot be invoked.
+	ErrInvocationFailed = errors.New("function invocation failed")
+
+	// ErrFunctionFailed is returned when the function or the agent in it
+	// reports an error.
+	ErrFunctionFailed = errors.New("function execution failed")
+)
+
+// Logger is the interface for logging.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Errors: logging must be best-effort and must not panic.
+type Logger interface {
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// Config configures a serverless backend.
+type Config struct {
+	// Provider names the cloud provider, e.g. ProviderAWSLambda. It
+	// selects the limits functions and payloads are checked against.
+	// Default: ProviderAWSLambda
+	Provider string
+
+	// Limits overrides the provider's limits; zero fields keep
+	// LimitsFor(Provider).
+	Limits ProviderLimits
+
+	// Functions are the deployed functions to choose from. Required.
+	Functions []Function
+
+	// Invoker calls the functions.
+	// If nil, Execute() returns ErrClientNotConfigured.
+	Invoker Invoker
+
+	// GatewayEndpoint is the URL of the tool gateway available to the agent.
+	// Optional, but required when code needs tool access.
+	GatewayEndpoint string
+
+	// GatewayToken is an optional token to authorize gateway access.
+	GatewayToken string
+
+	// TimeoutOverhead is added to the execution timeout to allow for
+	// network latency and cold starts.
+	// Default: 10s
+	TimeoutOverhead time.Duration
+
+	// Logger is an optional logger for backend events.
+	Logger Logger
+}
+
+// Backend executes code by invoking cloud functions.
+type Backend struct {
+	provider        string
+	limits          ProviderLimits
+	functions       []Function
+	invoker         Invoker
+	gatewayEndpoint string
+	gatewayToken    string
+	timeoutOverhead time.Duration
+	logger          Logger
+}
+
+// New creates a new serverless backend with the given configuration.
+func New(cfg Config) *Backend {
+	provider := cfg.Provider
+	if provider == "" {
+		provider = ProviderAWSLambda
+	}
+
+	timeoutOverhead := cfg.TimeoutOverhead
+	if timeoutOverhead == 0 {
+		timeoutOverhead = 10 * time.Second
+	}
+
+	return &Backend{
+		provider:        provider,
+		limits:          cfg.Limits.merge(LimitsFor(provider)),
+		functions:       cfg.Functions,
+		invoker:         cfg.Invoker,
+		gatewayEndpoint: cfg.GatewayEndpoint,
+		gatewayToken:    cfg.GatewayToken,
+		timeoutOverhead: timeoutOverhead,
+		logger:          cfg.Logger,
+	}
+}
+
+// Kind returns the backend kind identifier.
+func (b *Backend) Kind() runtime.BackendKind {
+	return runtime.BackendServerless
+}
+
+// Capabilities implements runtime.CapabilityReporter. The memory cap is
+// that of the largest configured function.
+func (b *Backend) Capabilities() runtime.Capabilities {
+	var maxMB int
+	for _, fn := range b.functions {
+		maxMB = max(maxMB, fn.MemoryMB)
+	}
+	return runtime.Capabilities{
+		MaxMemoryBytes: int64(maxMB) << 20,
+	}
+}
+
+// Execute runs code in the smallest function that fits the request's
+// limits.
+func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
+	if err := req.Validate(); err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+	if b.invoker == nil {
+		return runtime.ExecuteResult{}, ErrClientNotConfigured
+	}
+	for i, fn := range b.functions {
+		if err := fn.Validate(b.limits); err != nil {
+			return runtime.ExecuteResult{}, fmt.Errorf("%w: function[%d]: %v", ErrInvalidFunction, i, err)
+		}
+	}
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	fn, err := selectFunction(b.functions, memoryMB(req.Limits.MemoryBytes), timeout)
+	if err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+
+	payload, err := json.Marshal(remote.NewRequest(req, remote.NewGatewayDescriptor(b.gatewayEndpoint, b.gatewayToken)))
+	if err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+	if limit := b.limits.MaxPayloadBytes; limit > 0 && len(payload) > limit {
+		return runtime.ExecuteResult{}, fmt.Errorf("%w: %d bytes exceeds %d", ErrPayloadTooLarge, len(payload), limit)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout+b.timeoutOverhead)
+	defer cancel()
+
+	if b.logger != nil {
+		b.logger.Info("invoking function",
+			"provider", b.provider,
+			"function", fn.Name,
+			"memoryMB", fn.MemoryMB)
+	}
+
+	start := time.Now()
+	inv, err := b.invoker.Invoke(ctx, Invocation{Function: fn, Payload: payload, Timeout: timeout})
+	info := b.backendInfo(fn, inv)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("%w: %v", runtime.ErrTimeout, err)
+		} else {
+			err = fmt.Errorf("%w: %w", ErrInvocationFailed, err)
+		}
+		return runtime.ExecuteResult{Duration: time.Since(start), Backend: info}, err
+	}
+	if inv.FunctionError != "" {
+		return runtime.ExecuteResult{Duration: time.Since(start), Backend: info},
+			fmt.Errorf("%w: %s: %s", ErrFunctionFailed, inv.FunctionError, functionErrorMessage(inv.Payload))
+	}
+
+	var response remote.RemoteResponse
+	if err := json.Unmarshal(inv.Payload, &response); err != nil {
+		return runtime.ExecuteResult{Duration: time.Since(start), Backend: info},
+			fmt.Errorf("%w: decode response: %v", ErrFunctionFailed, err)
+	}
+	if response.Error != nil {
+		return runtime.ExecuteResult{Duration: time.Since(start), Backend: info},
+			fmt.Errorf("%w: %s", ErrFunctionFailed, response.Error.Message)
+	}
+	if response.Result == nil {
+		return runtime.ExecuteResult{Duration: time.Since(start), Backend: info},
+			fmt.Errorf("%w: missing result", ErrFunctionFailed)
+	}
+
+	result := response.Result.ExecuteResult()
+	if result.Duration == 0 {
+		result.Duration = time.Since(start)
+	}
+	result.Backend = info
+	result.Usage = runtime.ResourceUsage{WallTime: result.Duration, PeakMemoryBytes: inv.MaxMemoryBytes}
+	// The provider stops the function at its timeout.
+	result.LimitsEnforced.Timeout = true
+	return result, nil
+}
+
+var _ runtime.Backend = (*Backend)(nil)
+
+func (b *Backend) backendInfo(fn Function, inv InvokeResult) runtime.BackendInfo {
+	details := map[string]any{
+		"provider":  b.provider,
+		"function":  fn.Name,
+		"memoryMB":  fn.MemoryMB,
+		"coldStart": inv.InitDuration > 0,
+	}
+	if inv.RequestID != "" {
+		details["requestId"] = inv.RequestID
+	}
+	if inv.BilledDuration > 0 {
+		details["billedDuration"] = inv.BilledDuration.String()
+	}
+	return runtime.BackendInfo{
+		Kind:      runtime.BackendServerless,
+		Readiness: runtime.ReadinessBeta,
+		Details:   details,
+	}
+}
+
+// functionErrorMessage extracts the message from a provider error
+// document such as Lambda's {"errorMessage": "...", "errorType": "..."}.
+func functionErrorMessage(payload []byte) string {
+	var doc struct {
+		ErrorMessage string `json:"errorMessage"`
+		Message      string `json:"message"`
+	}
+	if err := json.Unmarshal(payload, &doc); err == nil {
+		if doc.ErrorMessage != "" {
+			return doc.ErrorMessage
+		}
+		if doc.Message != "" {
+			return doc.Message
+		}
+	}
+	return string(payload)
+}
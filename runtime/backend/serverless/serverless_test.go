@@ -0,0 +1,201 @@
+package serverless
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/remote"
+)
+
+type mockGateway struct{}
+
+func (m *mockGateway) SearchTools(_ context.Context, _ string, _ int) ([]index.Summary, error) {
+	return nil, nil
+}
+func (m *mockGateway) ListNamespaces(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+func (m *mockGateway) DescribeTool(_ context.Context, _ string, _ tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	return tooldoc.ToolDoc{}, nil
+}
+func (m *mockGateway) ListToolExamples(_ context.Context, _ string, _ int) ([]tooldoc.ToolExample, error) {
+	return nil, nil
+}
+func (m *mockGateway) RunTool(_ context.Context, _ string, _ map[string]any) (run.RunResult, error) {
+	return run.RunResult{}, nil
+}
+func (m *mockGateway) RunChain(_ context.Context, _ []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	return run.RunResult{}, nil, nil
+}
+
+type stubInvoker struct {
+	result InvokeResult
+	err    error
+	seen   Invocation
+}
+
+func (s *stubInvoker) Invoke(ctx context.Context, inv Invocation) (InvokeResult, error) {
+	s.seen = inv
+	if s.err != nil {
+		return InvokeResult{}, s.err
+	}
+	return s.result, nil
+}
+
+func respond(t *testing.T, resp remote.RemoteResponse) []byte {
+	t.Helper()
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+var tiers = []Function{
+	{Name: "agent-2048", MemoryMB: 2048, Timeout: 5 * time.Minute},
+	{Name: "agent-512", MemoryMB: 512, Timeout: time.Minute},
+	{Name: "agent-512-long", MemoryMB: 512, Timeout: 10 * time.Minute},
+}
+
+func TestBackendRequiresInvoker(t *testing.T) {
+	b := New(Config{Functions: tiers})
+	if b.Kind() != runtime.BackendServerless {
+		t.Errorf("Kind() = %v, want %v", b.Kind(), runtime.BackendServerless)
+	}
+	_, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "1", Gateway: &mockGateway{}})
+	if !errors.Is(err, ErrClientNotConfigured) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrClientNotConfigured)
+	}
+}
+
+func TestBackendSelectsFunctionForLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		memory  int64
+		timeout time.Duration
+		want    string
+		err     error
+	}{
+		{"defaults", 0, 0, "agent-512", nil},
+		{"memory rounds up", 512<<20 + 1, 0, "agent-2048", nil},
+		{"long timeout", 256 << 20, 2 * time.Minute, "agent-512-long", nil},
+		{"too much memory", 4 << 30, 0, "", ErrNoFunction},
+		{"too long", 0, time.Hour, "", ErrNoFunction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := &stubInvoker{result: InvokeResult{
+				Payload:        respond(t, remote.RemoteResponse{Result: &remote.ExecuteResultPayload{Value: 42.0, DurationMillis: 7}}),
+				RequestID:      "req-1",
+				InitDuration:   200 * time.Millisecond,
+				MaxMemoryBytes: 70 << 20,
+			}}
+			b := New(Config{Invoker: inv, Functions: tiers, GatewayEndpoint: "https://gw"})
+			result, err := b.Execute(context.Background(), runtime.ExecuteRequest{
+				Code:    "1",
+				Gateway: &mockGateway{},
+				Timeout: tt.timeout,
+				Limits:  runtime.Limits{MemoryBytes: tt.memory},
+			})
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Execute() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if inv.seen.Function.Name != tt.want {
+				t.Errorf("function = %q, want %q", inv.seen.Function.Name, tt.want)
+			}
+			var req remote.RemoteRequest
+			if err := json.Unmarshal(inv.seen.Payload, &req); err != nil {
+				t.Fatalf("payload: %v", err)
+			}
+			if req.Request.Code != "1" || req.Gateway == nil || req.Gateway.URL != "https://gw" {
+				t.Errorf("payload = %+v", req)
+			}
+			if result.Value != 42.0 || result.Duration != 7*time.Millisecond || result.Usage.PeakMemoryBytes != 70<<20 {
+				t.Errorf("result = %+v", result)
+			}
+			d := result.Backend.Details
+			if d["function"] != tt.want || d["coldStart"] != true || d["requestId"] != "req-1" || !result.LimitsEnforced.Timeout {
+				t.Errorf("Backend = %+v, LimitsEnforced = %+v", result.Backend, result.LimitsEnforced)
+			}
+		})
+	}
+}
+
+func TestBackendFunctionErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		result InvokeResult
+		err    error
+		want   error
+		msg    string
+	}{
+		{
+			name:   "unhandled",
+			result: InvokeResult{FunctionError: "Unhandled", Payload: []byte(`{"errorMessage":"out of memory","errorType":"Runtime.ExitError"}`)},
+			want:   ErrFunctionFailed,
+			msg:    "out of memory",
+		},
+		{
+			name:   "agent error",
+			result: InvokeResult{Payload: []byte(`{"error":{"code":"sandbox","message":"denied"}}`)},
+			want:   ErrFunctionFailed,
+			msg:    "denied",
+		},
+		{
+			name: "invoke",
+			err:  errors.New("throttled"),
+			want: ErrInvocationFailed,
+			msg:  "throttled",
+		},
+		{
+			name: "deadline",
+			err:  context.DeadlineExceeded,
+			want: runtime.ErrTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(Config{Invoker: &stubInvoker{result: tt.result, err: tt.err}, Functions: tiers})
+			_, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "1", Gateway: &mockGateway{}})
+			if !errors.Is(err, tt.want) || !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("Execute() error = %v, want %v with %q", err, tt.want, tt.msg)
+			}
+		})
+	}
+}
+
+func TestBackendChecksProviderLimits(t *testing.T) {
+	inv := &stubInvoker{}
+	b := New(Config{Invoker: inv, Functions: []Function{{Name: "huge", MemoryMB: 20480, Timeout: time.Minute}}})
+	_, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "1", Gateway: &mockGateway{}})
+	if !errors.Is(err, ErrInvalidFunction) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrInvalidFunction)
+	}
+
+	b = New(Config{Invoker: inv, Functions: tiers, Limits: ProviderLimits{MaxPayloadBytes: 64}})
+	_, err = b.Execute(context.Background(), runtime.ExecuteRequest{Code: strings.Repeat("x", 100), Gateway: &mockGateway{}})
+	if !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrPayloadTooLarge)
+	}
+
+	// GCP allows more memory than Lambda.
+	b = New(Config{Provider: ProviderGCPCloudFunctions, Invoker: inv, Functions: []Function{{Name: "huge", MemoryMB: 20480, Timeout: time.Minute}}})
+	inv.result = InvokeResult{Payload: respond(t, remote.RemoteResponse{Result: &remote.ExecuteResultPayload{}})}
+	if _, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "1", Gateway: &mockGateway{}}); err != nil {
+		t.Errorf("Execute() error = %v", err)
+	}
+}
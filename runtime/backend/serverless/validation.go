@@ -0,0 +1,30 @@
+package serverless
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate checks that fn is within limits. Zero limit fields are not
+// checked.
+func (fn Function) Validate(limits ProviderLimits) error {
+	if fn.Name == "" {
+		return errors.New("name is required")
+	}
+	if fn.MemoryMB <= 0 {
+		return errors.New("memory must be positive")
+	}
+	if fn.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	if limits.MinMemoryMB > 0 && fn.MemoryMB < limits.MinMemoryMB {
+		return fmt.Errorf("memory %d MB below provider minimum %d MB", fn.MemoryMB, limits.MinMemoryMB)
+	}
+	if limits.MaxMemoryMB > 0 && fn.MemoryMB > limits.MaxMemoryMB {
+		return fmt.Errorf("memory %d MB above provider maximum %d MB", fn.MemoryMB, limits.MaxMemoryMB)
+	}
+	if limits.MaxTimeout > 0 && fn.Timeout > limits.MaxTimeout {
+		return fmt.Errorf("timeout %s above provider maximum %s", fn.Timeout, limits.MaxTimeout)
+	}
+	return nil
+}
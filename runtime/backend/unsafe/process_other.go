@@ -0,0 +1,16 @@
+//go:build !unix
+
+package unsafe
+
+import (
+	"os/exec"
+	"time"
+)
+
+// gracefulStop reports whether configureProcess can stop a process
+// before killing it.
+const gracefulStop = false
+
+// configureProcess keeps the default cancellation, which kills only the
+// direct child; there is no signal to stop it with first.
+func configureProcess(*exec.Cmd, time.Duration) func() { return func() {} }
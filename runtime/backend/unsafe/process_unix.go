@@ -0,0 +1,38 @@
+//go:build unix
+
+package unsafe
+
+import (
+	"math"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// gracefulStop reports whether configureProcess can stop a process
+// before killing it.
+const gracefulStop = true
+
+// configureProcess starts cmd in its own process group and makes
+// cancellation kill the whole group. With a grace period, cancellation
+// sends the group SIGTERM and only kills it once grace passes. The caller
+// runs the returned func after cmd is waited for.
+func configureProcess(cmd *exec.Cmd, grace time.Duration) func() {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if grace <= 0 {
+		cmd.Cancel = func() error {
+			return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+		return func() {}
+	}
+	// Armed by Cancel, so the group is never killed after the caller
+	// has released it.
+	kill := time.AfterFunc(time.Duration(math.MaxInt64), func() {
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	})
+	cmd.Cancel = func() error {
+		kill.Reset(grace)
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	return func() { kill.Stop() }
+}
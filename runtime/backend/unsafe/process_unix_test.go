@@ -0,0 +1,128 @@
+//go:build unix
+
+package unsafe
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+func TestBackendTimeoutKillsProcessGroup(t *testing.T) {
+	b := New(Config{Mode: ModeSubprocess, EnvAllowlist: DefaultEnvAllowlist})
+
+	src := `package main
+
+import (
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+func main() {
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		panic(err)
+	}
+	fmt.Println(cmd.Process.Pid)
+	time.Sleep(time.Hour)
+}
+`
+	req := runtime.ExecuteRequest{
+		Code:    src,
+		Gateway: &mockGateway{},
+		Timeout: 5 * time.Second,
+	}
+
+	start := time.Now()
+	result, err := b.Execute(context.Background(), req)
+	if errors.Is(err, ErrSubprocessFailed) {
+		t.Skipf("Execute() error = %v (go toolchain may not be available)", err)
+	}
+	if !errors.Is(err, runtime.ErrTimeout) {
+		t.Fatalf("Execute() error = %v, want %v", err, runtime.ErrTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Execute() took %v, leftover processes held it open", elapsed)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(result.Stdout))
+	if err != nil {
+		t.Skipf("program did not start within the timeout: %q", result.Stdout)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("grandchild %d survived the timeout", pid)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestBackendGraceTimeoutFlushesOut(t *testing.T) {
+	b := New(Config{Mode: ModeSubprocess, EnvAllowlist: DefaultEnvAllowlist})
+	req := runtime.ExecuteRequest{
+		Code:         "__out = 42\n\tselect {}",
+		Gateway:      &mockGateway{},
+		Timeout:      5 * time.Second,
+		GraceTimeout: 5 * time.Second,
+	}
+
+	start := time.Now()
+	result, err := b.Execute(context.Background(), req)
+	if errors.Is(err, ErrSubprocessFailed) {
+		t.Skipf("Execute() error = %v (go toolchain may not be available)", err)
+	}
+	if !errors.Is(err, runtime.ErrTimeout) {
+		t.Fatalf("Execute() error = %v, want %v", err, runtime.ErrTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 9*time.Second {
+		t.Errorf("Execute() took %v, want the program to exit on SIGTERM", elapsed)
+	}
+	if result.Value == nil {
+		t.Skipf("program did not start within the timeout: %q", result.Stderr)
+	}
+	if result.Value != float64(42) {
+		t.Errorf("Value = %v, want the __out flushed on SIGTERM", result.Value)
+	}
+}
+
+func TestWrapCodeGrace(t *testing.T) {
+	code := "if true {\n\t\t__out = 1\n\t}\n\t__out, _ = 2, 3"
+	if _, graceSrc := wrapCode(code, 0); graceSrc != "" {
+		t.Error("wrapCode() without a grace period returned a grace file")
+	}
+
+	mainSrc, graceSrc := wrapCode(code, time.Second)
+	if graceSrc == "" {
+		t.Fatal("wrapCode() with a grace period returned no grace file")
+	}
+	if got := strings.Count(mainSrc, "__publish(__out)"); got != 2 {
+		t.Errorf("main.go publishes __out %d times, want after both assignments:\n%s", got, mainSrc)
+	}
+	for _, pkg := range []string{`"os"`, `"os/signal"`, `"syscall"`} {
+		if strings.Contains(mainSrc, pkg) {
+			t.Errorf("main.go imports %s, which the snippet could then use", pkg)
+		}
+		if !strings.Contains(graceSrc, "__"+strings.Trim(pkg[strings.LastIndex(pkg, "/")+1:], `"`)+" ") {
+			t.Errorf("grace.go does not alias %s", pkg)
+		}
+	}
+}
+
+// processAlive reports whether pid is running. A killed process whose new
+// parent has not reaped it yet counts as dead.
+func processAlive(pid int) bool {
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return false
+	}
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	return err != nil || !strings.Contains(string(stat), ") Z ")
+}
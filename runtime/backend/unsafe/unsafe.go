@@ -1,5 +1,11 @@
 // Package unsafe provides a backend that executes code directly on the host.
 // WARNING: This backend provides no isolation. Use only for trusted code in development.
+//
+// Config can still keep executions least-privilege on a dev machine.
+// EnvAllowlist passes only the named host variables to the subprocess. ScopeDir
+// runs each execution in a fresh directory that is also its HOME and TMPDIR.
+// On Unix the subprocess gets its own process group, and the timeout kills the
+// whole group, so programs started by `go run` cannot outlive it.
 package unsafe
 
 import (
@@ -8,6 +14,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -65,6 +75,30 @@ type Config struct {
 	// RequireOptIn requires explicit opt-in via request metadata.
 	// When true, requests must include metadata["unsafeOptIn"] = true.
 	RequireOptIn bool
+
+	// EnvAllowlist names the host environment variables the subprocess
+	// inherits; a trailing "*" matches a prefix. The request's Env is
+	// always passed. DefaultEnvAllowlist is enough for the Go toolchain.
+	// Default: nil, which passes the whole host environment
+	EnvAllowlist []string
+
+	// ScopeDir, if set, runs each execution in a fresh directory created
+	// under it and removed afterwards. The directory is the working
+	// directory, HOME, and TMPDIR, and holds the workspace, so code and the
+	// tools it runs write there by default. The Go build and module caches
+	// stay at their host locations. This is a convention, not a security
+	// boundary: code can still reach any path the host user can.
+	// The directory is created if missing.
+	// Default: the system temp dir, with the host HOME
+	ScopeDir string
+}
+
+// DefaultEnvAllowlist is an EnvAllowlist that keeps the Go toolchain working
+// and passes no credentials.
+var DefaultEnvAllowlist = []string{
+	"PATH", "HOME", "USER", "LANG", "LC_*", "TZ", "TMPDIR",
+	"GOROOT", "GOPATH", "GOCACHE", "GOMODCACHE", "GOFLAGS", "GOPROXY",
+	"GONOSUMDB", "GOPRIVATE", "GOTOOLCHAIN",
 }
 
 // Backend executes code directly on the host without isolation.
@@ -73,6 +107,8 @@ type Backend struct {
 	mode         ExecutionMode
 	logger       Logger
 	requireOptIn bool
+	envAllowlist []string
+	scopeDir     string
 }
 
 // New creates a new unsafe backend with the given configuration.
@@ -82,10 +118,19 @@ func New(cfg Config) *Backend {
 		mode = ModeInterpreter
 	}
 
+	scopeDir := cfg.ScopeDir
+	if scopeDir != "" {
+		if abs, err := filepath.Abs(scopeDir); err == nil {
+			scopeDir = abs
+		}
+	}
+
 	return &Backend{
 		mode:         mode,
 		logger:       cfg.Logger,
 		requireOptIn: cfg.RequireOptIn,
+		envAllowlist: cfg.EnvAllowlist,
+		scopeDir:     scopeDir,
 	}
 }
 
@@ -94,6 +139,18 @@ func (b *Backend) Kind() runtime.BackendKind {
 	return runtime.BackendUnsafeHost
 }
 
+// Capabilities implements runtime.CapabilityReporter. Code runs as a Go
+// program with the host's network, and is stopped with SIGTERM before it
+// is killed on Unix.
+func (b *Backend) Capabilities() runtime.Capabilities {
+	return runtime.Capabilities{
+		Languages:    []string{"go"},
+		GracefulStop: gracefulStop,
+		Workspace:    true,
+		NetworkModes: []string{"host"},
+	}
+}
+
 // Execute runs code on the host without isolation.
 func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
 	// Validate request
@@ -140,12 +197,19 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 	}
 
 	result.Duration = time.Since(start)
+	details := map[string]any{
+		"mode": string(b.mode),
+	}
+	if b.scopeDir != "" {
+		details["scoped"] = true
+	}
+	if b.envAllowlist != nil {
+		details["envFiltered"] = true
+	}
 	result.Backend = runtime.BackendInfo{
 		Kind:      runtime.BackendUnsafeHost,
 		Readiness: runtime.ReadinessProd,
-		Details: map[string]any{
-			"mode": string(b.mode),
-		},
+		Details:   details,
 	}
 
 	return result, err
@@ -166,23 +230,40 @@ func (b *Backend) executeInterpreter(ctx context.Context, req runtime.ExecuteReq
 
 // executeSubprocess executes code using `go run`.
 func (b *Backend) executeSubprocess(ctx context.Context, req runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
+	if b.scopeDir != "" {
+		if err := os.MkdirAll(b.scopeDir, 0o700); err != nil {
+			return runtime.ExecuteResult{}, fmt.Errorf("%w: failed to create scope dir: %v", ErrSubprocessFailed, err)
+		}
+	}
+
 	// Create a temporary directory for the code
-	tmpDir, err := os.MkdirTemp("", "toolruntime-unsafe-*")
+	tmpDir, err := os.MkdirTemp(b.scopeDir, "toolruntime-unsafe-*")
 	if err != nil {
 		return runtime.ExecuteResult{}, fmt.Errorf("%w: failed to create temp dir: %v", ErrSubprocessFailed, err)
 	}
 	defer func() {
 		_ = os.RemoveAll(tmpDir)
 	}()
+	env := b.hostEnv()
+	if b.scopeDir != "" {
+		if env, err = scopeEnv(env, tmpDir); err != nil {
+			return runtime.ExecuteResult{}, fmt.Errorf("%w: %v", ErrSubprocessFailed, err)
+		}
+	}
 
 	// Wrap the code in a main function
-	wrappedCode := wrapCode(req.Code)
+	wrappedCode, graceCode := wrapCode(req.Code, req.GraceTimeout)
 
 	// Write the code to a file
 	mainFile := filepath.Join(tmpDir, "main.go")
 	if err := os.WriteFile(mainFile, []byte(wrappedCode), 0600); err != nil {
 		return runtime.ExecuteResult{}, fmt.Errorf("%w: failed to write code: %v", ErrSubprocessFailed, err)
 	}
+	if graceCode != "" {
+		if err := os.WriteFile(filepath.Join(tmpDir, "grace.go"), []byte(graceCode), 0600); err != nil {
+			return runtime.ExecuteResult{}, fmt.Errorf("%w: failed to write code: %v", ErrSubprocessFailed, err)
+		}
+	}
 
 	// Create go.mod
 	goMod := `module toolruntime_exec
@@ -196,25 +277,64 @@ go 1.21
 	// Run the code
 	cmd := exec.CommandContext(ctx, "go", "run", ".")
 	cmd.Dir = tmpDir
+	for k, v := range req.Env {
+		env = append(env, k+"="+v)
+	}
+	cmd.Env = env
+	// Stop or kill the whole process tree at the deadline, and stop
+	// waiting for output that a leftover grandchild might still hold open.
+	release := configureProcess(cmd, req.GraceTimeout)
+	defer release()
+	cmd.WaitDelay = req.GraceTimeout + time.Second
+
+	// The workspace is a host temp dir; its Path is meaningless without a
+	// mount namespace, so code finds it through the environment.
+	var workspace string
+	if req.Workspace != nil {
+		workspace, err = os.MkdirTemp(b.scopeDir, "toolruntime-workspace-*")
+		if err != nil {
+			return runtime.ExecuteResult{}, fmt.Errorf("%w: failed to create workspace: %v", ErrSubprocessFailed, err)
+		}
+		defer func() {
+			_ = os.RemoveAll(workspace)
+		}()
+		cmd.Env = append(cmd.Env, runtime.WorkspaceEnv+"="+workspace)
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
+	runStart := time.Now()
 	err = cmd.Run()
 
 	result := runtime.ExecuteResult{
 		Stdout: stdout.String(),
 		Stderr: stderr.String(),
+		Usage:  runtime.ProcessUsage(cmd.ProcessState, time.Since(runStart)),
 	}
 
 	if err != nil {
 		if ctx.Err() != nil {
+			// Code stopped with a grace period may have flushed __out.
+			result.Value = extractOutValue(stdout.String())
 			return result, fmt.Errorf("%w: %v", runtime.ErrTimeout, ctx.Err())
 		}
 		return result, fmt.Errorf("%w: %v\nstderr: %s", ErrSubprocessFailed, err, stderr.String())
 	}
 
+	// Host directories have no size limit, so the workspace is checked
+	// after the fact.
+	if workspace != "" && req.Workspace.MaxBytes > 0 {
+		used, err := runtime.DirSize(workspace)
+		if err != nil {
+			return result, fmt.Errorf("%w: measure workspace: %v", ErrSubprocessFailed, err)
+		}
+		if used > req.Workspace.MaxBytes {
+			return result, fmt.Errorf("%w: workspace used %d bytes, limit %d", runtime.ErrResourceLimit, used, req.Workspace.MaxBytes)
+		}
+	}
+
 	// Extract __out value from stdout
 	// The wrapped code prints "__OUT__:<value>" at the end
 	result.Value = extractOutValue(stdout.String())
@@ -222,19 +342,80 @@ go 1.21
 	return result, nil
 }
 
-// wrapCode wraps user code in a main function with output capture.
-func wrapCode(code string) string {
+// hostEnv returns the host environment filtered by the allowlist.
+func (b *Backend) hostEnv() []string {
+	if b.envAllowlist == nil {
+		return os.Environ()
+	}
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if envAllowed(name, b.envAllowlist) {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+func envAllowed(name string, allowlist []string) bool {
+	for _, pattern := range allowlist {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeEnv points HOME and the temp dir variables into dir. The Go caches
+// default to paths under HOME, so they are pinned to the host locations
+// first to keep builds cached.
+func scopeEnv(env []string, dir string) ([]string, error) {
+	home := filepath.Join(dir, ".home")
+	tmp := filepath.Join(dir, ".tmp")
+	for _, d := range []string{home, tmp} {
+		if err := os.Mkdir(d, 0o700); err != nil {
+			return nil, fmt.Errorf("create scope dir: %v", err)
+		}
+	}
+	set := map[string]bool{}
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		set[name] = true
+	}
+	if !set["GOCACHE"] {
+		if cache, err := os.UserCacheDir(); err == nil {
+			env = append(env, "GOCACHE="+filepath.Join(cache, "go-build"))
+		}
+	}
+	if !set["GOPATH"] {
+		if hostHome, err := os.UserHomeDir(); err == nil {
+			env = append(env, "GOPATH="+filepath.Join(hostHome, "go"))
+		}
+	}
+	// Later entries win, so these override any inherited values.
+	return append(env, "HOME="+home, "TMPDIR="+tmp, "TMP="+tmp, "TEMP="+tmp, "GOTMPDIR="+tmp), nil
+}
+
+// wrapCode wraps user code in a main function with output capture. When
+// the program may be stopped with a grace period, it also returns the
+// source of a second file that flushes __out on SIGTERM. That file keeps
+// its imports to itself, so the snippet sees only encoding/json and fmt.
+func wrapCode(code string, grace time.Duration) (mainSrc, graceSrc string) {
 	// Check if code already has package/imports
 	hasPackage := strings.Contains(code, "package ")
 	hasMain := strings.Contains(code, "func main()")
 
 	if hasPackage && hasMain {
 		// Code is already complete
-		return code
+		return code, ""
 	}
 
 	// Wrap in main function with __out capture
-	return fmt.Sprintf(`package main
+	mainSrc = fmt.Sprintf(`package main
 
 import (
 	"encoding/json"
@@ -255,6 +436,118 @@ func main() {
 	}
 }
 `, code)
+	if !gracefulStop || grace <= 0 {
+		return mainSrc, ""
+	}
+	published, ok := publishOut(mainSrc)
+	if !ok {
+		// The program will not compile; go run reports why.
+		return mainSrc, ""
+	}
+	return published, graceFile
+}
+
+// graceFile flushes the value last published from __out when the program
+// gets SIGTERM. The snippet's goroutines keep running meanwhile, so it
+// reads a snapshot taken under a mutex rather than __out itself.
+const graceFile = `package main
+
+import (
+	__json "encoding/json"
+	__fmt "fmt"
+	__os "os"
+	__signal "os/signal"
+	__sync "sync"
+	__syscall "syscall"
+)
+
+var (
+	__lastMu __sync.Mutex
+	__last   []byte
+)
+
+// __publish records the JSON of out, just assigned to __out.
+func __publish(out any) {
+	var data []byte
+	if out != nil {
+		data, _ = __json.Marshal(out)
+	}
+	__lastMu.Lock()
+	__last = data
+	__lastMu.Unlock()
+}
+
+func init() {
+	stop := make(chan __os.Signal, 1)
+	__signal.Notify(stop, __syscall.SIGTERM)
+	go func() {
+		<-stop
+		__lastMu.Lock()
+		if __last != nil {
+			__fmt.Printf("__OUT__:%s\n", __last)
+		}
+		__os.Exit(143)
+	}()
+}
+`
+
+// publishOut rewrites the wrapped program src so that every statement
+// assigning __out is followed by __publish(__out). ok is false when src
+// does not parse.
+func publishOut(src string) (string, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", src, parser.ParseComments)
+	if err != nil {
+		return "", false
+	}
+	publish := func(list []ast.Stmt) []ast.Stmt {
+		var out []ast.Stmt
+		for _, stmt := range list {
+			out = append(out, stmt)
+			if assignsOut(stmt) {
+				// Positioned at the assignment's end, so that comments
+				// stay where they were.
+				pos := stmt.End()
+				out = append(out, &ast.ExprStmt{X: &ast.CallExpr{
+					Fun:    &ast.Ident{NamePos: pos, Name: "__publish"},
+					Lparen: pos,
+					Args:   []ast.Expr{&ast.Ident{NamePos: pos, Name: "__out"}},
+					Rparen: pos,
+				}})
+			}
+		}
+		return out
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			n.List = publish(n.List)
+		case *ast.CaseClause:
+			n.Body = publish(n.Body)
+		case *ast.CommClause:
+			n.Body = publish(n.Body)
+		}
+		return true
+	})
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return "", false
+	}
+	return buf.String(), true
+}
+
+// assignsOut reports whether stmt assigns to __out.
+func assignsOut(stmt ast.Stmt) bool {
+	assign, ok := stmt.(*ast.AssignStmt)
+	if !ok || assign.Tok != token.ASSIGN {
+		return false
+	}
+	for _, lhs := range assign.Lhs {
+		if ident, ok := lhs.(*ast.Ident); ok && ident.Name == "__out" {
+			return true
+		}
+	}
+	return false
 }
 
 // extractOutValue extracts the __out value from stdout.
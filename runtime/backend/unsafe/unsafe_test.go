@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -227,6 +229,124 @@ func TestBackendCapturesStdout(t *testing.T) {
 	}
 }
 
+func TestBackendWorkspace(t *testing.T) {
+	b := New(Config{Mode: ModeSubprocess})
+
+	src := `package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"path/filepath"
+)
+
+func main() {
+	dir := os.Getenv("TOOLEXEC_WORKSPACE")
+	fmt.Println(dir)
+	if err := os.WriteFile(filepath.Join(dir, "data.bin"), make([]byte, 2048), 0o600); err != nil {
+		panic(err)
+	}
+}
+`
+	req := runtime.ExecuteRequest{
+		Code:      src,
+		Gateway:   &mockGateway{},
+		Workspace: &runtime.Workspace{MaxBytes: 1024},
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if errors.Is(err, ErrSubprocessFailed) {
+		t.Skipf("Execute() error = %v (go toolchain may not be available)", err)
+	}
+	if !errors.Is(err, runtime.ErrResourceLimit) {
+		t.Fatalf("Execute() error = %v, want %v", err, runtime.ErrResourceLimit)
+	}
+	dir := strings.TrimSpace(result.Stdout)
+	if dir == "" {
+		t.Fatal("workspace path was not exposed to the code")
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("workspace %s should be removed after execution (stat error = %v)", dir, err)
+	}
+}
+
+func TestEnvAllowlist(t *testing.T) {
+	t.Setenv("TOOLEXEC_TEST_SECRET", "s3cret")
+	t.Setenv("LC_TOOLEXEC_TEST", "1")
+
+	b := New(Config{EnvAllowlist: DefaultEnvAllowlist})
+	env := strings.Join(b.hostEnv(), "\n")
+	if strings.Contains(env, "TOOLEXEC_TEST_SECRET") {
+		t.Error("variable outside the allowlist was passed")
+	}
+	if !strings.Contains(env, "LC_TOOLEXEC_TEST=1") {
+		t.Error("prefix pattern LC_* did not match")
+	}
+
+	all := New(Config{})
+	if !strings.Contains(strings.Join(all.hostEnv(), "\n"), "TOOLEXEC_TEST_SECRET=s3cret") {
+		t.Error("nil allowlist should pass the whole host environment")
+	}
+}
+
+func TestBackendScopeDir(t *testing.T) {
+	scope := filepath.Join(t.TempDir(), "scope")
+	t.Setenv("TOOLEXEC_TEST_SECRET", "s3cret")
+	b := New(Config{Mode: ModeSubprocess, ScopeDir: scope, EnvAllowlist: DefaultEnvAllowlist})
+
+	src := `package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	wd, _ := os.Getwd()
+	home, _ := os.UserHomeDir()
+	fmt.Println(wd)
+	fmt.Println(home)
+	fmt.Println(os.TempDir())
+	fmt.Println(os.Getenv("TOOLEXEC_WORKSPACE"))
+	fmt.Println(os.Getenv("TOOLEXEC_TEST_SECRET") + os.Getenv("ALLOWED"))
+}
+`
+	req := runtime.ExecuteRequest{
+		Code:      src,
+		Gateway:   &mockGateway{},
+		Env:       map[string]string{"ALLOWED": "yes"},
+		Workspace: &runtime.Workspace{},
+	}
+
+	result, err := b.Execute(context.Background(), req)
+	if errors.Is(err, ErrSubprocessFailed) {
+		t.Skipf("Execute() error = %v (go toolchain may not be available)", err)
+	}
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(result.Stdout), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Stdout = %q, want 5 lines", result.Stdout)
+	}
+	for _, dir := range lines[:4] {
+		if !strings.HasPrefix(dir, scope) {
+			t.Errorf("path %q is outside the scope dir %q", dir, scope)
+		}
+	}
+	if lines[4] != "yes" {
+		t.Errorf("env line = %q, want only the request's variable", lines[4])
+	}
+	if entries, _ := os.ReadDir(scope); len(entries) != 0 {
+		t.Errorf("scope dir should be empty after execution, has %d entries", len(entries))
+	}
+	if result.Backend.Details["scoped"] != true {
+		t.Errorf("Details[scoped] = %v, want true", result.Backend.Details["scoped"])
+	}
+}
+
 func TestBackendModeSelection(t *testing.T) {
 	tests := []struct {
 		mode ExecutionMode
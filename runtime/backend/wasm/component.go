@@ -0,0 +1,195 @@
+package wasm
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jonwraymond/toolexec/run"
+)
+
+// WorldWIT is the WIT definition of the toolexec:gateway package. Its
+// sandbox world imports the tools interface, served by HostBridge, and
+// exports run, whose ok value is the execution's result as JSON.
+// Components built against it, in any language with component tooling,
+// call tools through typed functions instead of the stdout conventions of
+// core modules.
+//
+//go:embed wit/toolexec.wit
+var WorldWIT string
+
+// ComponentRunExport is the function of the sandbox world a ComponentRunner
+// calls.
+const ComponentRunExport = "run"
+
+// componentVersion is the version and layer field following the magic
+// number of a component binary; core modules have 0x01 0x00 0x00 0x00.
+var componentVersion = []byte{0x0d, 0x00, 0x01, 0x00}
+
+// IsComponent reports whether binary is a WebAssembly component (WASI
+// preview 2) rather than a core module.
+func IsComponent(binary []byte) bool {
+	return isWasmModule(binary) && len(binary) >= 8 && bytes.Equal(binary[4:8], componentVersion)
+}
+
+// ComponentRunner executes WebAssembly components targeting the sandbox
+// world of WorldWIT. This is an optional extension to Runner; the backend
+// rejects components with ErrComponentUnsupported when its Runner does
+// not implement it.
+//
+// Contract:
+//   - RunComponent follows the Runner contract for spec.Module, a
+//     component binary.
+//   - Imports of the tools interface are served by the typed methods of
+//     NewHostBridge(spec, stdout), and WASI preview 2 only when
+//     spec.Security.EnableWASI is set.
+//   - The ok value of the run export is returned in Result.Output. Its
+//     error value fails the run with ErrModuleExecutionFailed.
+type ComponentRunner interface {
+	Runner
+
+	// RunComponent instantiates the component and calls its run export.
+	RunComponent(ctx context.Context, spec Spec) (Result, error)
+}
+
+// ToolSummary is the tool-summary record of the tools interface.
+type ToolSummary struct {
+	ID        string
+	Name      string
+	Namespace string
+	Summary   string
+	Tags      []string
+}
+
+// ChainStep is the chain-step record of the tools interface.
+type ChainStep struct {
+	ToolID string
+	// Args is a JSON object.
+	Args        string
+	UsePrevious bool
+}
+
+// StepResult is the step-result record of the tools interface.
+type StepResult struct {
+	ToolID string
+	// Structured is the step's result as JSON.
+	Structured string
+	// Error is set when the step failed.
+	Error *string
+}
+
+// ChainResult is the chain-result record of the tools interface.
+type ChainResult struct {
+	Structured string
+	Steps      []StepResult
+	// Error is set when a step stopped the chain.
+	Error *string
+}
+
+// SearchTools serves search-tools, gated by HostSearchTools.
+func (b *HostBridge) SearchTools(ctx context.Context, query string, limit uint32) ([]ToolSummary, error) {
+	if err := b.check(HostSearchTools); err != nil {
+		return nil, err
+	}
+	results, err := b.gateway.SearchTools(ctx, query, int(limit))
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]ToolSummary, len(results))
+	for i, r := range results {
+		summary := r.Summary
+		if summary == "" {
+			summary = r.ShortDescription
+		}
+		summaries[i] = ToolSummary{ID: r.ID, Name: r.Name, Namespace: r.Namespace, Summary: summary, Tags: r.Tags}
+	}
+	return summaries, nil
+}
+
+// RunTool serves run-tool, gated by HostRunTool. args and the returned
+// result are JSON.
+func (b *HostBridge) RunTool(ctx context.Context, id, args string) (string, error) {
+	if err := b.check(HostRunTool); err != nil {
+		return "", err
+	}
+	decoded, err := decodeArgs(args)
+	if err != nil {
+		return "", err
+	}
+	result, err := b.gateway.RunTool(ctx, id, decoded)
+	if err != nil {
+		return "", err
+	}
+	return encodeJSON(result.Structured)
+}
+
+// RunChain serves run-chain, gated by HostRunChain. A step that stops the
+// chain is reported in the result's Error, with the steps that ran.
+func (b *HostBridge) RunChain(ctx context.Context, steps []ChainStep) (ChainResult, error) {
+	if err := b.check(HostRunChain); err != nil {
+		return ChainResult{}, err
+	}
+	chain := make([]run.ChainStep, len(steps))
+	for i, s := range steps {
+		args, err := decodeArgs(s.Args)
+		if err != nil {
+			return ChainResult{}, fmt.Errorf("step %d: %w", i, err)
+		}
+		chain[i] = run.ChainStep{ToolID: s.ToolID, Args: args, UsePrevious: s.UsePrevious}
+	}
+	result, stepResults, chainErr := b.gateway.RunChain(ctx, chain)
+	out := ChainResult{Steps: make([]StepResult, len(stepResults))}
+	var err error
+	if out.Structured, err = encodeJSON(result.Structured); err != nil {
+		return ChainResult{}, err
+	}
+	for i, s := range stepResults {
+		out.Steps[i].ToolID = s.ToolID
+		if out.Steps[i].Structured, err = encodeJSON(s.Result.Structured); err != nil {
+			return ChainResult{}, err
+		}
+		if s.Err != nil {
+			msg := s.Err.Error()
+			out.Steps[i].Error = &msg
+		}
+	}
+	if chainErr != nil {
+		msg := chainErr.Error()
+		out.Error = &msg
+	}
+	return out, nil
+}
+
+// Println serves println, gated by HostPrintln.
+func (b *HostBridge) Println(line string) error {
+	if err := b.check(HostPrintln); err != nil {
+		return err
+	}
+	if b.stdout == nil {
+		return nil
+	}
+	_, err := fmt.Fprintln(b.stdout, line)
+	return err
+}
+
+// decodeArgs decodes a JSON object of tool arguments; empty means none.
+func decodeArgs(args string) (map[string]any, error) {
+	if args == "" {
+		return nil, nil
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(args), &decoded); err != nil {
+		return nil, fmt.Errorf("decode args: %w", err)
+	}
+	return decoded, nil
+}
+
+func encodeJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("encode result: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,105 @@
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+var minimalComponent = []byte{0x00, 0x61, 0x73, 0x6d, 0x0d, 0x00, 0x01, 0x00}
+
+// mockComponentRunner records whether it ran a component or a module.
+type mockComponentRunner struct {
+	mockWasmRunner
+	output     string
+	components int
+}
+
+func (m *mockComponentRunner) RunComponent(_ context.Context, _ Spec) (Result, error) {
+	m.components++
+	return Result{Output: m.output}, nil
+}
+
+var _ ComponentRunner = (*mockComponentRunner)(nil)
+
+func TestIsComponent(t *testing.T) {
+	if !IsComponent(minimalComponent) {
+		t.Error("IsComponent(component) = false")
+	}
+	if IsComponent(minimalWasmModule) || IsComponent([]byte("\x00asm")) {
+		t.Error("IsComponent(core module) = true")
+	}
+}
+
+func TestWorldWITDeclaresHostFunctions(t *testing.T) {
+	if !strings.Contains(WorldWIT, "world sandbox") || !strings.Contains(WorldWIT, "export "+ComponentRunExport+":") {
+		t.Fatalf("WorldWIT lacks the sandbox world and its %s export", ComponentRunExport)
+	}
+	for _, name := range HostFunctionNames() {
+		if fn := strings.ReplaceAll(name, "_", "-") + ": func("; !strings.Contains(WorldWIT, fn) {
+			t.Errorf("WorldWIT does not declare %s", fn)
+		}
+	}
+}
+
+func TestBackendExecuteComponent(t *testing.T) {
+	runner := &mockComponentRunner{output: `{"ok":true}`}
+	b := New(Config{Client: runner, ModuleLoader: &mockModuleLoader{err: errors.New("not a core module")}})
+	req := runtime.ExecuteRequest{Code: "x", Gateway: &mockGateway{}, Metadata: map[string]any{"wasm_module": minimalComponent}}
+
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if runner.components != 1 {
+		t.Errorf("RunComponent calls = %d, want 1", runner.components)
+	}
+	if want := map[string]any{"ok": true}; !reflect.DeepEqual(result.Value, want) {
+		t.Errorf("Value = %#v, want %#v", result.Value, want)
+	}
+	if result.Backend.Details["component"] != true {
+		t.Errorf("Details = %v, want component set", result.Backend.Details)
+	}
+
+	b = New(Config{Client: &mockWasmRunner{}})
+	if _, err := b.Execute(context.Background(), req); !errors.Is(err, ErrComponentUnsupported) || !errors.Is(err, runtime.ErrUnsupportedCapability) {
+		t.Errorf("Execute() with a module-only runner error = %v, want %v", err, ErrComponentUnsupported)
+	}
+}
+
+func TestHostBridgeTypedCalls(t *testing.T) {
+	gw := &echoGateway{}
+	var stdout bytes.Buffer
+	bridge := NewHostBridge(Spec{
+		Gateway:  gw,
+		Security: SecuritySpec{AllowedHostFunctions: HostFunctionNames()},
+	}, &stdout)
+	ctx := context.Background()
+
+	if out, err := bridge.RunTool(ctx, "ns:tool", `{"x":"v"}`); err != nil || out != `"v"` || gw.toolID != "ns:tool" {
+		t.Errorf("RunTool() = %q, %v (tool %q); want \"v\" from ns:tool", out, err, gw.toolID)
+	}
+	if _, err := bridge.RunTool(ctx, "ns:tool", "not json"); err == nil {
+		t.Error("RunTool() with bad args succeeded")
+	}
+	if tools, err := bridge.SearchTools(ctx, "q", 1); err != nil || len(tools) != 1 || tools[0].ID != "ns:q" {
+		t.Errorf("SearchTools() = %+v, %v; want ns:q", tools, err)
+	}
+	chain, err := bridge.RunChain(ctx, []ChainStep{{ToolID: "ns:a"}, {ToolID: "ns:b"}})
+	if err != nil || len(chain.Steps) != 1 || chain.Error == nil || *chain.Error != "step 2 failed" {
+		t.Errorf("RunChain() = %+v, %v; want one step and the chain error", chain, err)
+	}
+	if err := bridge.Println("hello"); err != nil || stdout.String() != "hello\n" {
+		t.Errorf("Println() = %v, stdout %q; want hello", err, stdout.String())
+	}
+
+	denied := NewHostBridge(Spec{Gateway: gw, Security: SecuritySpec{AllowedHostFunctions: []string{HostPrintln}}}, nil)
+	if _, err := denied.RunTool(ctx, "ns:tool", ""); !errors.Is(err, ErrHostFunctionDenied) {
+		t.Errorf("denied RunTool() error = %v, want %v", err, ErrHostFunctionDenied)
+	}
+}
@@ -0,0 +1,174 @@
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// HostModuleName is the import module of the host functions.
+const HostModuleName = "toolexec"
+
+// HostAllocExport is the function a module must export for host functions
+// to return data: toolexec_alloc(size i32) -> ptr i32 allocates size bytes
+// in linear memory.
+const HostAllocExport = "toolexec_alloc"
+
+// Host functions importable from HostModuleName.
+//
+// Each takes (ptr i32, len i32) addressing a JSON request in linear memory
+// and returns an i64 packing the JSON response as ptr<<32 | len, written
+// to memory allocated with HostAllocExport. Failures, including denied
+// calls, are reported in the response as {"error": "..."} rather than
+// trapping, so modules can handle them.
+const (
+	// HostSearchTools takes {"query", "limit"} and returns {"results"}.
+	HostSearchTools = "search_tools"
+
+	// HostRunTool takes {"id", "args"} and returns {"structured"}.
+	HostRunTool = "run_tool"
+
+	// HostRunChain takes {"steps"} as run.ChainStep values and returns
+	// {"structured", "stepResults"}.
+	HostRunChain = "run_chain"
+
+	// HostPrintln takes {"line"}, writes it to stdout, and returns {}.
+	HostPrintln = "println"
+)
+
+// HostFunctionNames lists every host function, in a stable order.
+func HostFunctionNames() []string {
+	return []string{HostSearchTools, HostRunTool, HostRunChain, HostPrintln}
+}
+
+// HostBridge serves host function calls from a module against a
+// ToolGateway. Runner implementations expose its functions to modules
+// under HostModuleName and delegate each call to Call; ComponentRunner
+// implementations serve the tools interface of WorldWIT through its typed
+// methods instead. Both are gated by the same allow list.
+type HostBridge struct {
+	gateway runtime.ToolGateway
+	allowed []string
+	stdout  io.Writer
+}
+
+// NewHostBridge creates a bridge for spec, writing println output to
+// stdout. Only functions listed in spec.Security.AllowedHostFunctions may
+// be called; the hardened profile clears that list, denying them all.
+func NewHostBridge(spec Spec, stdout io.Writer) *HostBridge {
+	return &HostBridge{
+		gateway: spec.Gateway,
+		allowed: spec.Security.AllowedHostFunctions,
+		stdout:  stdout,
+	}
+}
+
+// Allowed reports whether the module may call the named host function.
+func (b *HostBridge) Allowed(name string) bool {
+	return slices.Contains(b.allowed, name)
+}
+
+// Call runs the named host function with a JSON request and returns the
+// JSON response.
+func (b *HostBridge) Call(ctx context.Context, name string, request []byte) []byte {
+	payload, err := b.call(ctx, name, request)
+	if err != nil {
+		payload = map[string]any{"error": err.Error()}
+	}
+	resp, err := json.Marshal(payload)
+	if err != nil {
+		resp, _ = json.Marshal(map[string]any{"error": fmt.Sprintf("encode response: %v", err)})
+	}
+	return resp
+}
+
+// check fails unless the named host function may be called and has what
+// it needs to run.
+func (b *HostBridge) check(name string) error {
+	if !b.Allowed(name) {
+		return fmt.Errorf("%w: %s", ErrHostFunctionDenied, name)
+	}
+	if name != HostPrintln && b.gateway == nil {
+		return runtime.ErrMissingGateway
+	}
+	return nil
+}
+
+func (b *HostBridge) call(ctx context.Context, name string, request []byte) (map[string]any, error) {
+	if !slices.Contains(HostFunctionNames(), name) {
+		return nil, fmt.Errorf("unknown host function %q", name)
+	}
+	if err := b.check(name); err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case HostSearchTools:
+		var req struct {
+			Query string `json:"query"`
+			Limit int    `json:"limit"`
+		}
+		if err := json.Unmarshal(request, &req); err != nil {
+			return nil, fmt.Errorf("decode request: %w", err)
+		}
+		results, err := b.gateway.SearchTools(ctx, req.Query, req.Limit)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"results": results}, nil
+
+	case HostRunTool:
+		var req struct {
+			ID   string         `json:"id"`
+			Args map[string]any `json:"args"`
+		}
+		if err := json.Unmarshal(request, &req); err != nil {
+			return nil, fmt.Errorf("decode request: %w", err)
+		}
+		result, err := b.gateway.RunTool(ctx, req.ID, req.Args)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"structured": result.Structured}, nil
+
+	case HostRunChain:
+		var req struct {
+			Steps []run.ChainStep `json:"steps"`
+		}
+		if err := json.Unmarshal(request, &req); err != nil {
+			return nil, fmt.Errorf("decode request: %w", err)
+		}
+		result, steps, err := b.gateway.RunChain(ctx, req.Steps)
+		stepResults := make([]map[string]any, len(steps))
+		for i, s := range steps {
+			stepResults[i] = map[string]any{"toolId": s.ToolID, "structured": s.Result.Structured}
+			if s.Err != nil {
+				stepResults[i]["error"] = s.Err.Error()
+			}
+		}
+		resp := map[string]any{"structured": result.Structured, "stepResults": stepResults}
+		if err != nil {
+			resp["error"] = err.Error()
+		}
+		return resp, nil
+
+	default: // HostPrintln
+		var req struct {
+			Line string `json:"line"`
+		}
+		if err := json.Unmarshal(request, &req); err != nil {
+			return nil, fmt.Errorf("decode request: %w", err)
+		}
+		if b.stdout != nil {
+			if _, err := io.WriteString(b.stdout, req.Line+"\n"); err != nil {
+				return nil, err
+			}
+		}
+		return map[string]any{}, nil
+	}
+}
@@ -0,0 +1,108 @@
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+type echoGateway struct {
+	mockGateway
+	toolID string
+}
+
+func (g *echoGateway) SearchTools(_ context.Context, query string, _ int) ([]index.Summary, error) {
+	return []index.Summary{{ID: "ns:" + query}}, nil
+}
+
+func (g *echoGateway) RunTool(_ context.Context, id string, args map[string]any) (run.RunResult, error) {
+	g.toolID = id
+	return run.RunResult{Structured: args["x"]}, nil
+}
+
+func (g *echoGateway) RunChain(_ context.Context, steps []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	results := []run.StepResult{{ToolID: steps[0].ToolID, Result: run.RunResult{Structured: 1}}}
+	return run.RunResult{Structured: 1}, results, errors.New("step 2 failed")
+}
+
+func decodeHostResponse(t *testing.T, resp []byte) map[string]any {
+	t.Helper()
+	var out map[string]any
+	if err := json.Unmarshal(resp, &out); err != nil {
+		t.Fatalf("response %s is not JSON: %v", resp, err)
+	}
+	return out
+}
+
+func TestHostBridgeCalls(t *testing.T) {
+	gw := &echoGateway{}
+	var stdout bytes.Buffer
+	bridge := NewHostBridge(Spec{
+		Gateway:  gw,
+		Security: SecuritySpec{AllowedHostFunctions: HostFunctionNames()},
+	}, &stdout)
+	ctx := context.Background()
+
+	out := decodeHostResponse(t, bridge.Call(ctx, HostRunTool, []byte(`{"id":"ns:tool","args":{"x":"v"}}`)))
+	if out["structured"] != "v" || gw.toolID != "ns:tool" {
+		t.Errorf("run_tool = %v (tool %q), want structured v from ns:tool", out, gw.toolID)
+	}
+
+	out = decodeHostResponse(t, bridge.Call(ctx, HostSearchTools, []byte(`{"query":"q","limit":1}`)))
+	if results, _ := out["results"].([]any); len(results) != 1 {
+		t.Errorf("search_tools = %v, want one result", out)
+	}
+
+	out = decodeHostResponse(t, bridge.Call(ctx, HostRunChain, []byte(`{"steps":[{"toolId":"ns:a"},{"toolId":"ns:b"}]}`)))
+	if steps, _ := out["stepResults"].([]any); len(steps) != 1 || out["error"] != "step 2 failed" {
+		t.Errorf("run_chain = %v, want one step result and the chain error", out)
+	}
+
+	out = decodeHostResponse(t, bridge.Call(ctx, HostPrintln, []byte(`{"line":"hello"}`)))
+	if len(out) != 0 || stdout.String() != "hello\n" {
+		t.Errorf("println = %v, stdout %q; want {} and hello", out, stdout.String())
+	}
+
+	out = decodeHostResponse(t, bridge.Call(ctx, HostRunTool, []byte(`not json`)))
+	if _, ok := out["error"]; !ok {
+		t.Errorf("run_tool with bad request = %v, want error", out)
+	}
+}
+
+func TestHostBridgeDenied(t *testing.T) {
+	bridge := NewHostBridge(Spec{
+		Gateway:  &echoGateway{},
+		Security: SecuritySpec{AllowedHostFunctions: []string{HostPrintln}},
+	}, nil)
+
+	if bridge.Allowed(HostRunTool) || !bridge.Allowed(HostPrintln) {
+		t.Error("Allowed() does not follow AllowedHostFunctions")
+	}
+	out := decodeHostResponse(t, bridge.Call(context.Background(), HostRunTool, []byte(`{"id":"ns:tool"}`)))
+	if msg, _ := out["error"].(string); !strings.Contains(msg, ErrHostFunctionDenied.Error()) {
+		t.Errorf("denied run_tool = %v, want %q", out, ErrHostFunctionDenied)
+	}
+}
+
+func TestHostBridgeHardenedProfile(t *testing.T) {
+	b := New(Config{AllowedHostFunctions: HostFunctionNames()})
+	req := runtime.ExecuteRequest{Code: "x", Gateway: &echoGateway{}}
+
+	standard := NewHostBridge(b.buildSpec(req, runtime.ProfileStandard), nil)
+	if !standard.Allowed(HostRunTool) {
+		t.Error("standard profile denies run_tool")
+	}
+	hardened := NewHostBridge(b.buildSpec(req, runtime.ProfileHardened), nil)
+	for _, name := range HostFunctionNames() {
+		if hardened.Allowed(name) {
+			t.Errorf("hardened profile allows %s", name)
+		}
+	}
+}
@@ -25,6 +25,15 @@ type CompiledModule interface {
 	Close(ctx context.Context) error
 }
 
+// CPULimiter reports whether a Runner stops modules once
+// Spec.Resources.CPUTime is spent.
+// This is an optional interface - without it, the backend does not report
+// CPU limits as enforced.
+type CPULimiter interface {
+	// LimitsCPU reports whether Spec.Resources.CPUTime is enforced.
+	LimitsCPU() bool
+}
+
 // HealthChecker verifies WASM runtime availability.
 // This is an optional interface - backends may skip health checks.
 type HealthChecker interface {
@@ -1,6 +1,10 @@
 package wasm
 
-import "time"
+import (
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
 
 // Spec defines what to execute in a WASM sandbox and how.
 type Spec struct {
@@ -37,6 +41,10 @@ type Spec struct {
 
 	// Labels are metadata labels for tracking.
 	Labels map[string]string
+
+	// Gateway serves the host functions listed in
+	// Security.AllowedHostFunctions; see HostBridge.
+	Gateway runtime.ToolGateway
 }
 
 // Mount defines a filesystem mount for WASI.
@@ -61,6 +69,12 @@ type ResourceSpec struct {
 	// Zero means unlimited (no metering).
 	FuelLimit uint64
 
+	// CPUTime caps the time the module executes, stopping it once spent,
+	// even in a loop without calls. A module runs on one thread, so this
+	// bounds its CPU time. Runners that honor it implement CPULimiter.
+	// Zero means no limit.
+	CPUTime time.Duration
+
 	// StackSize is the maximum call stack size in bytes.
 	// Zero uses runtime default.
 	StackSize uint32
@@ -72,8 +86,9 @@ type SecuritySpec struct {
 	// Default: true for most use cases.
 	EnableWASI bool
 
-	// AllowedHostFunctions lists host functions the module can import.
-	// Empty means no host functions allowed (maximum isolation).
+	// AllowedHostFunctions lists host functions the module can call, by
+	// name (see HostFunctionNames). Empty means no host functions allowed
+	// (maximum isolation).
 	AllowedHostFunctions []string
 
 	// EnableNetwork allows WASI network access.
@@ -104,6 +119,10 @@ type Result struct {
 
 	// MemoryUsed is peak memory usage in bytes.
 	MemoryUsed uint64
+
+	// Output is the JSON returned by a component's run export. Core
+	// modules report their result through stdout instead.
+	Output string
 }
 
 // StreamEventType identifies the type of streaming event.
@@ -1,5 +1,11 @@
 // Package wasm provides a backend that executes code compiled to WebAssembly.
 // Provides strong in-process isolation; requires constrained SDK surface.
+//
+// Core modules reach tools through the JSON host functions of HostBridge
+// and report their result on stdout. WebAssembly components (WASI preview
+// 2) instead target the sandbox world of WorldWIT, calling tools through
+// typed imports and returning their result from the run export; they need
+// a Runner that implements ComponentRunner.
 package wasm
 
 import (
@@ -18,7 +24,7 @@ import (
 // Errors for WASM backend operations.
 var (
 	// ErrWASMRuntimeNotAvailable is returned when WASM runtime is not available.
-	ErrWASMRuntimeNotAvailable = errors.New("wasm runtime not available")
+	ErrWASMRuntimeNotAvailable = fmt.Errorf("wasm runtime not available: %w", runtime.ErrRuntimeUnavailable)
 
 	// ErrModuleCompilationFailed is returned when WASM module compilation fails.
 	ErrModuleCompilationFailed = errors.New("wasm module compilation failed")
@@ -40,8 +46,28 @@ var (
 
 	// ErrFuelExhausted is returned when the fuel limit is exhausted.
 	ErrFuelExhausted = errors.New("fuel limit exhausted")
+
+	// ErrCPUTimeExceeded is returned when a module runs past
+	// ResourceSpec.CPUTime.
+	ErrCPUTimeExceeded = errors.New("cpu time exceeded")
+
+	// ErrHostFunctionDenied is reported when a module calls a host function
+	// that SecuritySpec.AllowedHostFunctions does not list.
+	ErrHostFunctionDenied = errors.New("host function not allowed")
+
+	// ErrComponentUnsupported is returned when a WebAssembly component is
+	// given to a Runner that does not implement ComponentRunner.
+	ErrComponentUnsupported = fmt.Errorf("wasm components: %w", runtime.ErrUnsupportedCapability)
 )
 
+// DefaultFuelPerMillisecond converts ExecuteRequest.Limits.CPUQuotaMillis
+// to ResourceSpec.FuelLimit when Config.FuelPerMillisecond is zero. Runners
+// meter roughly one unit of fuel per WebAssembly function call, and an
+// interpreter executes on the order of ten thousand calls per millisecond,
+// so the resulting limit approximates CPU time rather than measuring it.
+// The quota itself is enforced through ResourceSpec.CPUTime.
+const DefaultFuelPerMillisecond uint64 = 10_000
+
 // Logger is the interface for logging.
 //
 // Contract:
@@ -68,14 +94,23 @@ type Config struct {
 	// Default: true
 	EnableWASI bool
 
-	// AllowedHostFunctions lists host functions the WASM module can call.
+	// AllowedHostFunctions lists host functions the WASM module can call,
+	// such as HostRunTool. The hardened profile allows none.
 	AllowedHostFunctions []string
 
-	// Client is the WASM runner implementation.
+	// FuelPerMillisecond is the fuel granted per millisecond of
+	// Limits.CPUQuotaMillis: FuelLimit = CPUQuotaMillis * FuelPerMillisecond.
+	// Default: DefaultFuelPerMillisecond
+	FuelPerMillisecond uint64
+
+	// Client is the WASM runner implementation, such as the wazero
+	// Runner in the runtime/backend/wasm/wazero module.
 	// If nil, Execute() returns ErrClientNotConfigured.
 	Client Runner
 
-	// ModuleLoader optionally pre-compiles modules.
+	// ModuleLoader optionally pre-compiles modules before each execution,
+	// rejecting invalid modules early and warming its cache, such as the
+	// on-disk ModuleCache in the runtime/backend/wasm/wazero module.
 	// If nil, modules are compiled on-demand.
 	ModuleLoader ModuleLoader
 
@@ -93,6 +128,7 @@ type Backend struct {
 	maxMemoryPages       int
 	enableWASI           bool
 	allowedHostFunctions []string
+	fuelPerMillisecond   uint64
 	client               Runner
 	moduleLoader         ModuleLoader
 	healthChecker        HealthChecker
@@ -111,11 +147,17 @@ func New(cfg Config) *Backend {
 		maxMemoryPages = 256 // 16MB
 	}
 
+	fuelPerMillisecond := cfg.FuelPerMillisecond
+	if fuelPerMillisecond == 0 {
+		fuelPerMillisecond = DefaultFuelPerMillisecond
+	}
+
 	return &Backend{
 		runtime:              runtime,
 		maxMemoryPages:       maxMemoryPages,
 		enableWASI:           cfg.EnableWASI,
 		allowedHostFunctions: cfg.AllowedHostFunctions,
+		fuelPerMillisecond:   fuelPerMillisecond,
 		client:               cfg.Client,
 		moduleLoader:         cfg.ModuleLoader,
 		healthChecker:        cfg.HealthChecker,
@@ -128,6 +170,14 @@ func (b *Backend) Kind() runtime.BackendKind {
 	return runtime.BackendWASM
 }
 
+// Capabilities implements runtime.CapabilityReporter. Modules never get
+// network access.
+func (b *Backend) Capabilities() runtime.Capabilities {
+	return runtime.Capabilities{
+		NetworkModes: []string{"none"},
+	}
+}
+
 // Execute runs code compiled to WebAssembly.
 func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
 	// Validate request
@@ -175,6 +225,24 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 	if err != nil {
 		return runtime.ExecuteResult{}, err
 	}
+	component := IsComponent(module)
+	componentRunner, ok := b.client.(ComponentRunner)
+	if component && !ok {
+		return runtime.ExecuteResult{}, ErrComponentUnsupported
+	}
+
+	// Pre-compile through the loader, which may serve a cached compilation.
+	// Loaders compile core modules, so components skip it.
+	if b.moduleLoader != nil && !component {
+		compiled, err := b.moduleLoader.Load(ctx, module)
+		if err != nil {
+			if !errors.Is(err, ErrModuleCompilationFailed) {
+				err = fmt.Errorf("%w: %v", ErrModuleCompilationFailed, err)
+			}
+			return runtime.ExecuteResult{}, err
+		}
+		defer func() { _ = compiled.Close(context.WithoutCancel(ctx)) }()
+	}
 
 	// Build WASM spec from request
 	spec := b.buildSpec(req, profile)
@@ -186,32 +254,54 @@ func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runt
 			"profile", profile,
 			"runtime", b.runtime,
 			"enableWASI", b.enableWASI,
-			"memoryPages", b.maxMemoryPages)
+			"memoryPages", b.maxMemoryPages,
+			"component", component)
 	}
 
 	// Execute via client
-	wasmResult, err := b.client.Run(ctx, spec)
+	var wasmResult Result
+	if component {
+		wasmResult, err = componentRunner.RunComponent(ctx, spec)
+	} else {
+		wasmResult, err = b.client.Run(ctx, spec)
+	}
+	info := b.backendInfo(profile)
+	info.Details["component"] = component
+	if spec.Resources.FuelLimit > 0 {
+		info.Details["fuelLimit"] = spec.Resources.FuelLimit
+		info.Details["fuelConsumed"] = wasmResult.FuelConsumed
+	}
 	if err != nil {
+		if (errors.Is(err, ErrFuelExhausted) || errors.Is(err, ErrCPUTimeExceeded)) && !errors.Is(err, runtime.ErrResourceLimit) {
+			err = fmt.Errorf("%w: %w", runtime.ErrResourceLimit, err)
+		}
 		return runtime.ExecuteResult{
+			Stdout:   wasmResult.Stdout,
+			Stderr:   wasmResult.Stderr,
 			Duration: time.Since(start),
-			Backend:  b.backendInfo(profile),
+			Backend:  info,
 		}, err
 	}
 
+	value := extractOutValue(wasmResult.Stdout)
+	if component {
+		value = componentValue(wasmResult.Output)
+	}
+
 	// Convert to ExecuteResult
 	return runtime.ExecuteResult{
-		Value:    extractOutValue(wasmResult.Stdout),
+		Value:    value,
 		Stdout:   wasmResult.Stdout,
 		Stderr:   wasmResult.Stderr,
 		Duration: wasmResult.Duration,
-		Backend:  b.backendInfo(profile),
+		Backend:  info,
 		LimitsEnforced: runtime.LimitsEnforced{
 			Timeout:    true,
 			Memory:     spec.Resources.MemoryPages > 0,
-			CPU:        spec.Resources.FuelLimit > 0, // Fuel serves as CPU limiting
-			Pids:       false,                        // WASM doesn't have process model
-			ToolCalls:  true,                         // Enforced by gateway
-			ChainSteps: true,                         // Enforced by gateway
+			CPU:        spec.Resources.CPUTime > 0 && limitsCPU(b.client),
+			Pids:       false, // WASM doesn't have process model
+			ToolCalls:  true,  // Enforced by gateway
+			ChainSteps: true,  // Enforced by gateway
 		},
 	}, nil
 }
@@ -227,6 +317,7 @@ func (b *Backend) buildSpec(req runtime.ExecuteRequest, profile runtime.Security
 
 	spec := Spec{
 		Timeout: req.Timeout,
+		Gateway: req.Gateway,
 		Security: SecuritySpec{
 			EnableWASI:           b.enableWASI,
 			AllowedHostFunctions: b.allowedHostFunctions,
@@ -262,6 +353,11 @@ func (b *Backend) buildSpec(req runtime.ExecuteRequest, profile runtime.Security
 	}
 
 	// Apply resource limits from request
+	if req.Limits.CPUQuotaMillis > 0 {
+		spec.Resources.FuelLimit = fuelForCPU(req.Limits.CPUQuotaMillis, b.fuelPerMillisecond)
+		millis := min(req.Limits.CPUQuotaMillis, math.MaxInt64/int64(time.Millisecond))
+		spec.Resources.CPUTime = time.Duration(millis) * time.Millisecond
+	}
 	if req.Limits.MemoryBytes > 0 {
 		// Convert bytes to 64KB pages
 		pages := req.Limits.MemoryBytes / (64 * 1024)
@@ -317,6 +413,35 @@ func extractOutValue(stdout string) any {
 	return nil
 }
 
+// componentValue decodes the JSON a component's run export returned,
+// keeping it as a string when it is not JSON.
+func componentValue(output string) any {
+	if output == "" {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal([]byte(output), &value); err != nil {
+		return output
+	}
+	return value
+}
+
+// limitsCPU reports whether runner enforces ResourceSpec.CPUTime.
+func limitsCPU(runner Runner) bool {
+	l, ok := runner.(CPULimiter)
+	return ok && l.LimitsCPU()
+}
+
+// fuelForCPU converts a CPU quota to fuel, saturating on overflow.
+func fuelForCPU(millis int64, perMillisecond uint64) uint64 {
+	// #nosec G115 -- callers pass a positive quota.
+	m := uint64(millis)
+	if perMillisecond != 0 && m > math.MaxUint64/perMillisecond {
+		return math.MaxUint64
+	}
+	return m * perMillisecond
+}
+
 func clampUint32(value uint64) uint32 {
 	if value > math.MaxUint32 {
 		return math.MaxUint32
@@ -3,6 +3,7 @@ package wasm
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
@@ -250,6 +251,109 @@ func TestBackendMissingModule(t *testing.T) {
 	}
 }
 
+func TestBackendModuleLoader(t *testing.T) {
+	loader := &mockModuleLoader{}
+	b := New(Config{Client: &mockWasmRunner{}, ModuleLoader: loader})
+	req := runtime.ExecuteRequest{
+		Code:     "test",
+		Gateway:  &mockGateway{},
+		Metadata: map[string]any{"wasm_module": minimalWasmModule},
+	}
+
+	if _, err := b.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if loader.loads != 1 || loader.closed != 1 {
+		t.Errorf("loads = %d, closed = %d, want 1 and 1", loader.loads, loader.closed)
+	}
+
+	loader.err = errors.New("bad module")
+	if _, err := b.Execute(context.Background(), req); !errors.Is(err, ErrModuleCompilationFailed) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrModuleCompilationFailed)
+	}
+}
+
+func TestBuildSpecFuelFromCPUQuota(t *testing.T) {
+	req := runtime.ExecuteRequest{
+		Code:    "test",
+		Gateway: &mockGateway{},
+		Limits:  runtime.Limits{CPUQuotaMillis: 250},
+	}
+
+	spec := New(Config{}).buildSpec(req, runtime.ProfileStandard)
+	if want := 250 * DefaultFuelPerMillisecond; spec.Resources.FuelLimit != want {
+		t.Errorf("FuelLimit = %d, want %d", spec.Resources.FuelLimit, want)
+	}
+	if spec.Resources.CPUTime != 250*time.Millisecond {
+		t.Errorf("CPUTime = %v, want 250ms", spec.Resources.CPUTime)
+	}
+
+	spec = New(Config{FuelPerMillisecond: 4}).buildSpec(req, runtime.ProfileStandard)
+	if spec.Resources.FuelLimit != 1000 {
+		t.Errorf("FuelLimit = %d, want 1000", spec.Resources.FuelLimit)
+	}
+
+	req.Limits.CPUQuotaMillis = 0
+	if spec := New(Config{}).buildSpec(req, runtime.ProfileStandard); spec.Resources.FuelLimit != 0 || spec.Resources.CPUTime != 0 {
+		t.Errorf("without a CPU quota, FuelLimit = %d and CPUTime = %v, want 0", spec.Resources.FuelLimit, spec.Resources.CPUTime)
+	}
+}
+
+func TestBackendFuel(t *testing.T) {
+	req := runtime.ExecuteRequest{
+		Code:     "test",
+		Gateway:  &mockGateway{},
+		Metadata: map[string]any{"wasm_module": minimalWasmModule},
+		Limits:   runtime.Limits{CPUQuotaMillis: 10},
+	}
+
+	b := New(Config{Client: &mockWasmRunner{result: Result{FuelConsumed: 42}}, FuelPerMillisecond: 10})
+	result, err := b.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.LimitsEnforced.CPU {
+		t.Error("LimitsEnforced.CPU = true from a runner that does not limit CPU time")
+	}
+	if limited, err := New(Config{Client: &cpuLimitingRunner{}}).Execute(context.Background(), req); err != nil || !limited.LimitsEnforced.CPU {
+		t.Errorf("Execute() with a CPULimiter = %+v, %v, want CPU enforced", limited.LimitsEnforced, err)
+	}
+	if got := result.Backend.Details["fuelConsumed"]; got != uint64(42) {
+		t.Errorf("Details[fuelConsumed] = %v, want 42", got)
+	}
+	if got := result.Backend.Details["fuelLimit"]; got != uint64(100) {
+		t.Errorf("Details[fuelLimit] = %v, want 100", got)
+	}
+
+	b = New(Config{Client: &mockWasmRunner{
+		result: Result{FuelConsumed: 100},
+		err:    fmt.Errorf("%w: 100 function calls", ErrFuelExhausted),
+	}, FuelPerMillisecond: 10})
+	result, err = b.Execute(context.Background(), req)
+	if !errors.Is(err, ErrFuelExhausted) || !errors.Is(err, runtime.ErrResourceLimit) {
+		t.Fatalf("Execute() error = %v, want %v and %v", err, ErrFuelExhausted, runtime.ErrResourceLimit)
+	}
+	if got := result.Backend.Details["fuelConsumed"]; got != uint64(100) {
+		t.Errorf("Details[fuelConsumed] = %v, want 100", got)
+	}
+	b = New(Config{Client: &cpuLimitingRunner{mockWasmRunner{err: ErrCPUTimeExceeded}}})
+	if _, err = b.Execute(context.Background(), req); !errors.Is(err, ErrCPUTimeExceeded) || !errors.Is(err, runtime.ErrResourceLimit) {
+		t.Errorf("Execute() error = %v, want %v and %v", err, ErrCPUTimeExceeded, runtime.ErrResourceLimit)
+	}
+
+	req.Limits.CPUQuotaMillis = 0
+	result, err = New(Config{Client: &mockWasmRunner{}}).Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.LimitsEnforced.CPU {
+		t.Error("LimitsEnforced.CPU = true without a CPU quota")
+	}
+	if _, ok := result.Backend.Details["fuelConsumed"]; ok {
+		t.Error("Details reports fuel for an unmetered run")
+	}
+}
+
 // Mock implementations
 
 type mockGateway struct{}
@@ -298,6 +402,13 @@ func (m *mockWasmRunner) Run(ctx context.Context, _ Spec) (Result, error) {
 	return m.result, m.err
 }
 
+// cpuLimitingRunner is a mockWasmRunner that enforces CPUTime.
+type cpuLimitingRunner struct {
+	mockWasmRunner
+}
+
+func (*cpuLimitingRunner) LimitsCPU() bool { return true }
+
 type mockHealthChecker struct {
 	pingErr error
 	info    RuntimeInfo
@@ -312,8 +423,40 @@ func (m *mockHealthChecker) Info(_ context.Context) (RuntimeInfo, error) {
 	return m.info, m.infoErr
 }
 
+type mockModuleLoader struct {
+	err    error
+	loads  int
+	closed int
+}
+
+func (m *mockModuleLoader) Load(_ context.Context, _ []byte) (CompiledModule, error) {
+	m.loads++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &mockCompiledModule{loader: m}, nil
+}
+
+func (m *mockModuleLoader) Close(_ context.Context) error {
+	return nil
+}
+
+type mockCompiledModule struct {
+	loader *mockModuleLoader
+}
+
+func (m *mockCompiledModule) Name() string { return "" }
+
+func (m *mockCompiledModule) Exports() []string { return nil }
+
+func (m *mockCompiledModule) Close(_ context.Context) error {
+	m.loader.closed++
+	return nil
+}
+
 // Interface compliance checks
 var (
 	_ Runner        = (*mockWasmRunner)(nil)
 	_ HealthChecker = (*mockHealthChecker)(nil)
+	_ ModuleLoader  = (*mockModuleLoader)(nil)
 )
@@ -0,0 +1,68 @@
+package wazero
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jonwraymond/toolexec/runtime/backend/wasm"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// hostParams and hostResults are the signature of every host function:
+// (ptr i32, len i32) -> i64.
+var (
+	hostParams  = []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}
+	hostResults = []api.ValueType{api.ValueTypeI64}
+)
+
+// instantiateHost registers the wasm.HostModuleName module, serving every
+// host function through bridge. Functions the spec does not allow are
+// still importable; calling them returns a denial in the response.
+func instantiateHost(ctx context.Context, rt wazero.Runtime, bridge *wasm.HostBridge) error {
+	builder := rt.NewHostModuleBuilder(wasm.HostModuleName)
+	for _, name := range wasm.HostFunctionNames() {
+		fn := func(ctx context.Context, mod api.Module, stack []uint64) {
+			stack[0] = hostCall(ctx, mod, bridge, name, api.DecodeU32(stack[0]), api.DecodeU32(stack[1]))
+		}
+		builder = builder.NewFunctionBuilder().
+			WithGoModuleFunction(api.GoModuleFunc(fn), hostParams, hostResults).
+			WithParameterNames("ptr", "len").
+			Export(name)
+	}
+	if _, err := builder.Instantiate(ctx); err != nil {
+		return fmt.Errorf("%w: host module: %v", wasm.ErrWASMRuntimeNotAvailable, err)
+	}
+	return nil
+}
+
+// hostCall reads a request from guest memory, runs it through bridge, and
+// copies the response into memory allocated by the guest. Memory faults
+// and a missing allocator trap the module.
+func hostCall(ctx context.Context, mod api.Module, bridge *wasm.HostBridge, name string, ptr, size uint32) uint64 {
+	mem := mod.Memory()
+	if mem == nil {
+		panic(fmt.Errorf("%s: module has no memory", name))
+	}
+	view, ok := mem.Read(ptr, size)
+	if !ok {
+		panic(fmt.Errorf("%s: request out of range", name))
+	}
+	// The allocator may grow memory, invalidating view.
+	request := append([]byte(nil), view...)
+	response := bridge.Call(ctx, name, request)
+
+	alloc := mod.ExportedFunction(wasm.HostAllocExport)
+	if alloc == nil {
+		panic(fmt.Errorf("%s: module does not export %s", name, wasm.HostAllocExport))
+	}
+	results, err := alloc.Call(ctx, uint64(len(response)))
+	if err != nil {
+		panic(fmt.Errorf("%s: %s: %w", name, wasm.HostAllocExport, err))
+	}
+	out := api.DecodeU32(results[0])
+	if !mem.Write(out, response) {
+		panic(fmt.Errorf("%s: response out of range", name))
+	}
+	return uint64(out)<<32 | uint64(len(response))
+}
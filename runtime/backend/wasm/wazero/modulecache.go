@@ -0,0 +1,277 @@
+package wazero
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime/backend/wasm"
+	"github.com/tetratelabs/wazero"
+)
+
+// wazeroModulePath is the module path used to look up the wazero version.
+const wazeroModulePath = "github.com/tetratelabs/wazero"
+
+// versionDirPrefix prefixes the per-version cache directories.
+const versionDirPrefix = "wazero-"
+
+// ModuleCacheConfig configures a ModuleCache.
+type ModuleCacheConfig struct {
+	// Dir is the cache root directory (required). Entries are stored
+	// under a "wazero-<version>-<os>-<arch>" subdirectory.
+	Dir string
+
+	// MaxBytes bounds the total size of cached entries; the least
+	// recently used entries are evicted first. Zero means unbounded.
+	MaxBytes int64
+
+	// MaxAge evicts entries not used for longer than this.
+	// Zero means entries never expire.
+	MaxAge time.Duration
+}
+
+// CacheStats reports ModuleCache activity.
+type CacheStats struct {
+	// Hits counts loads served from a cached compilation.
+	Hits uint64
+
+	// Misses counts loads that compiled the module.
+	Misses uint64
+
+	// Evictions counts entries removed by size or age.
+	Evictions uint64
+
+	// Entries is the number of cached modules after the last eviction pass.
+	Entries int
+
+	// Bytes is the size of cached modules after the last eviction pass.
+	Bytes int64
+}
+
+// ModuleCache caches compiled modules on disk, keyed by a hash of the
+// module binary and the wazero version, so repeated executions of the
+// same module skip compilation, including across process restarts.
+// Eviction runs whenever a module is added; it also removes directories
+// left by other wazero versions, since they can never be hit.
+//
+// Set it as Config.Modules to serve Runner compilations from the cache,
+// and as wasm.Config.ModuleLoader to compile modules ahead of execution.
+// It is safe for concurrent use.
+type ModuleCache struct {
+	root    string
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+
+	mu    sync.Mutex
+	stats CacheStats
+}
+
+// NewModuleCache creates a ModuleCache, creating its directory if needed.
+func NewModuleCache(cfg ModuleCacheConfig) (*ModuleCache, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("wazero: module cache dir is required")
+	}
+	dir := filepath.Join(cfg.Dir, versionDirPrefix+RuntimeVersion()+"-"+goruntime.GOOS+"-"+goruntime.GOARCH)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("wazero: module cache dir: %w", err)
+	}
+	return &ModuleCache{root: cfg.Dir, dir: dir, maxSize: cfg.MaxBytes, maxAge: cfg.MaxAge}, nil
+}
+
+// RuntimeVersion returns the version of wazero linked into the binary,
+// or "devel" when it cannot be determined.
+func RuntimeVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == wazeroModulePath {
+				if dep.Replace != nil && dep.Replace.Version != "" {
+					return dep.Replace.Version
+				}
+				return dep.Version
+			}
+		}
+	}
+	return "devel"
+}
+
+// Stats returns a snapshot of cache activity.
+func (c *ModuleCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Load implements wasm.ModuleLoader. It compiles binary, reusing a cached
+// compilation when one exists. The returned module must be closed.
+func (c *ModuleCache) Load(ctx context.Context, binary []byte) (wasm.CompiledModule, error) {
+	cache, err := c.compilationCache(binary)
+	if err != nil {
+		return nil, err
+	}
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCompilationCache(cache))
+	compiled, err := rt.CompileModule(ctx, binary)
+	if err != nil {
+		_ = rt.Close(ctx)
+		_ = cache.Close(ctx)
+		return nil, fmt.Errorf("%w: %v", wasm.ErrModuleCompilationFailed, err)
+	}
+	return &compiledModule{rt: rt, cache: cache, compiled: compiled}, nil
+}
+
+// Close implements wasm.ModuleLoader. Cached entries stay on disk.
+func (c *ModuleCache) Close(context.Context) error {
+	return nil
+}
+
+// compilationCache returns a wazero compilation cache dedicated to
+// binary, recording a hit or miss and evicting stale entries. The caller
+// closes the returned cache.
+func (c *ModuleCache) compilationCache(binary []byte) (wazero.CompilationCache, error) {
+	sum := sha256.Sum256(binary)
+	entry := filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+
+	now := time.Now()
+	hit := false
+	if entries, err := os.ReadDir(entry); err == nil && len(entries) > 0 {
+		hit = true
+		_ = os.Chtimes(entry, now, now)
+	}
+
+	c.mu.Lock()
+	if hit {
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+		c.evictLocked(now, entry)
+	}
+	c.mu.Unlock()
+
+	cache, err := wazero.NewCompilationCacheWithDir(entry)
+	if err != nil {
+		return nil, fmt.Errorf("wazero: module cache entry: %w", err)
+	}
+	return cache, nil
+}
+
+// evictLocked removes entries older than maxAge, then the least recently
+// used entries until the cache fits in maxSize. keep is never evicted.
+func (c *ModuleCache) evictLocked(now time.Time, keep string) {
+	c.removeOtherVersionsLocked()
+
+	type cacheEntry struct {
+		path    string
+		size    int64
+		touched time.Time
+	}
+	dirs, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	var entries []cacheEntry
+	var total int64
+	for _, d := range dirs {
+		info, err := d.Info()
+		if err != nil || !d.IsDir() {
+			continue
+		}
+		path := filepath.Join(c.dir, d.Name())
+		if path != keep && c.maxAge > 0 && now.Sub(info.ModTime()) > c.maxAge {
+			if os.RemoveAll(path) == nil {
+				c.stats.Evictions++
+			}
+			continue
+		}
+		size := dirSize(path)
+		entries = append(entries, cacheEntry{path: path, size: size, touched: info.ModTime()})
+		total += size
+	}
+
+	if c.maxSize > 0 && total > c.maxSize {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].touched.Before(entries[j].touched) })
+		for i := 0; i < len(entries) && total > c.maxSize; {
+			if entries[i].path == keep || os.RemoveAll(entries[i].path) != nil {
+				i++
+				continue
+			}
+			c.stats.Evictions++
+			total -= entries[i].size
+			entries = slices.Delete(entries, i, i+1)
+		}
+	}
+	c.stats.Entries = len(entries)
+	c.stats.Bytes = total
+}
+
+// removeOtherVersionsLocked deletes the cache directories of other
+// runtime versions or platforms.
+func (c *ModuleCache) removeOtherVersionsLocked() {
+	dirs, err := os.ReadDir(c.root)
+	if err != nil {
+		return
+	}
+	for _, d := range dirs {
+		path := filepath.Join(c.root, d.Name())
+		if d.IsDir() && strings.HasPrefix(d.Name(), versionDirPrefix) && path != c.dir {
+			_ = os.RemoveAll(path)
+		}
+	}
+}
+
+// dirSize returns the total size of the regular files under dir.
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// compiledModule is a module compiled by a ModuleCache.
+type compiledModule struct {
+	rt       wazero.Runtime
+	cache    wazero.CompilationCache
+	compiled wazero.CompiledModule
+}
+
+func (m *compiledModule) Name() string {
+	return m.compiled.Name()
+}
+
+func (m *compiledModule) Exports() []string {
+	names := make([]string, 0, len(m.compiled.ExportedFunctions()))
+	for name := range m.compiled.ExportedFunctions() {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (m *compiledModule) Close(ctx context.Context) error {
+	return errors.Join(m.compiled.Close(ctx), m.rt.Close(ctx), m.cache.Close(ctx))
+}
+
+var (
+	_ wasm.ModuleLoader   = (*ModuleCache)(nil)
+	_ wasm.CompiledModule = (*compiledModule)(nil)
+)
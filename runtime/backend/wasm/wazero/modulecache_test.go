@@ -0,0 +1,86 @@
+package wazero
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime/backend/wasm"
+)
+
+func TestModuleCache_HitsAfterFirstCompile(t *testing.T) {
+	cache, err := NewModuleCache(ModuleCacheConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewModuleCache() error = %v", err)
+	}
+	ctx := context.Background()
+
+	for range 2 {
+		mod, err := cache.Load(ctx, emptyModule)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if exports := mod.Exports(); len(exports) != 1 || exports[0] != "_start" {
+			t.Errorf("Exports() = %v, want [_start]", exports)
+		}
+		_ = mod.Close(ctx)
+	}
+	if stats := cache.Stats(); stats.Misses != 1 || stats.Hits != 1 {
+		t.Errorf("Stats() = %+v, want 1 miss then 1 hit", stats)
+	}
+}
+
+func TestModuleCache_RunnerUsesCache(t *testing.T) {
+	cache, err := NewModuleCache(ModuleCacheConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewModuleCache() error = %v", err)
+	}
+	r := New(Config{Modules: cache})
+	for range 2 {
+		if _, err := r.Run(context.Background(), wasm.Spec{Module: emptyModule}); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+	if stats := cache.Stats(); stats.Hits != 1 {
+		t.Errorf("Stats() = %+v, want the second run to hit", stats)
+	}
+}
+
+func TestModuleCache_Eviction(t *testing.T) {
+	root := t.TempDir()
+	stale := filepath.Join(root, versionDirPrefix+"v0.0.1-plan9-386")
+	if err := os.MkdirAll(stale, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewModuleCache(ModuleCacheConfig{Dir: root, MaxAge: time.Hour})
+	if err != nil {
+		t.Fatalf("NewModuleCache() error = %v", err)
+	}
+
+	old := filepath.Join(cache.dir, "old")
+	if err := os.MkdirAll(old, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(old, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	mod, err := cache.Load(context.Background(), emptyModule)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	_ = mod.Close(context.Background())
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("expired entry was not evicted")
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("other version's cache was not removed")
+	}
+	if stats := cache.Stats(); stats.Evictions != 1 {
+		t.Errorf("Stats().Evictions = %d, want 1", stats.Evictions)
+	}
+}
@@ -0,0 +1,295 @@
+// Package wazero provides a wasm.Runner backed by wazero, a pure-Go
+// WebAssembly runtime with no cgo dependencies.
+//
+// It is a separate module so that the core toolexec module does not
+// depend on wazero; import it only when running the wasm backend:
+//
+//	backend := wasm.New(wasm.Config{
+//		EnableWASI: true,
+//		Client:     wazero.New(wazero.Config{}),
+//	})
+//
+// Each Run instantiates the module in a fresh wazero runtime, so
+// executions share no state beyond the compilation cache.
+//
+// # Module Cache
+//
+// A ModuleCache keeps compiled modules on disk, keyed by module hash and
+// wazero version, with eviction by size and age. Set it as Config.Modules
+// so repeated executions skip compilation across restarts, and read hit
+// and miss counts with ModuleCache.Stats.
+//
+// # Host Functions
+//
+// Modules may import the functions of wasm.HostModuleName ("toolexec"):
+// search_tools, run_tool, run_chain, and println. Each exchanges JSON
+// through linear memory and requires the module to export
+// wasm.HostAllocExport; see wasm.HostBridge. Only the functions listed in
+// Spec.Security.AllowedHostFunctions succeed, and println output is
+// appended to the module's stdout.
+//
+// # Components
+//
+// wazero runs core modules only. Runner does not implement
+// wasm.ComponentRunner, so the wasm backend rejects WebAssembly components
+// with wasm.ErrComponentUnsupported before running them, and Run does the
+// same when called directly.
+//
+// # Limits
+//
+//   - Spec.Resources.MemoryPages caps linear memory; growth beyond it
+//     fails inside the module.
+//   - Spec.Timeout and context cancellation stop execution, including
+//     tight loops.
+//   - Spec.Resources.CPUTime stops the module once it has executed that
+//     long, interrupting it even in a loop without calls, and Run returns
+//     wasm.ErrCPUTimeExceeded. Runner implements wasm.CPULimiter, so the
+//     wasm backend reports CPU limits as enforced.
+//   - Spec.Resources.FuelLimit meters execution: one unit of fuel is
+//     consumed per WebAssembly function call, so a loop without calls
+//     spends none. Running out stops the module and Run returns
+//     wasm.ErrFuelExhausted. Metering uses the interpreter engine, which is
+//     slower than the compiler. The wasm backend derives both limits from
+//     Limits.CPUQuotaMillis (see wasm.DefaultFuelPerMillisecond).
+package wazero
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime/backend/wasm"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// DefaultEntryPoint is the function called when Spec.EntryPoint is empty.
+const DefaultEntryPoint = "_start"
+
+// Config configures a Runner.
+type Config struct {
+	// CompilationCache is shared by every Run so each distinct module is
+	// compiled once. If nil, an in-memory cache is created.
+	CompilationCache wazero.CompilationCache
+
+	// Modules, if set, serves compilations from an on-disk ModuleCache
+	// instead of CompilationCache, so compiled code survives restarts.
+	Modules *ModuleCache
+
+	// Interpreter runs modules with the interpreter engine instead of
+	// the compiler. Metered runs (Spec.Resources.FuelLimit > 0) always
+	// use the interpreter.
+	Interpreter bool
+}
+
+// Runner executes WASM modules with wazero.
+// It is safe for concurrent use.
+type Runner struct {
+	cache       wazero.CompilationCache
+	modules     *ModuleCache
+	interpreter bool
+}
+
+// New creates a Runner.
+func New(cfg Config) *Runner {
+	cache := cfg.CompilationCache
+	if cache == nil {
+		cache = wazero.NewCompilationCache()
+	}
+	return &Runner{cache: cache, modules: cfg.Modules, interpreter: cfg.Interpreter}
+}
+
+// Run implements wasm.Runner.
+func (r *Runner) Run(ctx context.Context, spec wasm.Spec) (wasm.Result, error) {
+	if len(spec.Module) == 0 {
+		return wasm.Result{}, wasm.ErrInvalidModule
+	}
+	if wasm.IsComponent(spec.Module) {
+		return wasm.Result{}, wasm.ErrComponentUnsupported
+	}
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spec.Timeout)
+		defer cancel()
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var meter *fuelMeter
+	if limit := spec.Resources.FuelLimit; limit > 0 {
+		meter = &fuelMeter{limit: limit, cancel: cancel}
+		runCtx = experimental.WithFunctionListenerFactory(runCtx, meter)
+	}
+
+	cache := r.cache
+	if r.modules != nil {
+		moduleCache, err := r.modules.compilationCache(spec.Module)
+		if err != nil {
+			return wasm.Result{}, err
+		}
+		defer func() { _ = moduleCache.Close(context.WithoutCancel(ctx)) }()
+		cache = moduleCache
+	}
+
+	rt := wazero.NewRuntimeWithConfig(runCtx, r.runtimeConfig(spec, cache, meter != nil))
+	defer func() { _ = rt.Close(context.WithoutCancel(ctx)) }()
+
+	if spec.Security.EnableWASI {
+		if _, err := wasi_snapshot_preview1.Instantiate(runCtx, rt); err != nil {
+			return wasm.Result{}, fmt.Errorf("%w: wasi: %v", wasm.ErrWASMRuntimeNotAvailable, err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := instantiateHost(runCtx, rt, wasm.NewHostBridge(spec, &stdout)); err != nil {
+		return wasm.Result{}, err
+	}
+
+	compiled, err := rt.CompileModule(runCtx, spec.Module)
+	if err != nil {
+		return wasm.Result{}, fmt.Errorf("%w: %v", wasm.ErrModuleCompilationFailed, err)
+	}
+
+	// The runtime closes the module once execCtx is done, so CPUTime
+	// interrupts even code that makes no calls.
+	execCtx := runCtx
+	if spec.Resources.CPUTime > 0 {
+		var cancelExec context.CancelFunc
+		execCtx, cancelExec = context.WithTimeout(runCtx, spec.Resources.CPUTime)
+		defer cancelExec()
+	}
+	start := time.Now()
+	mod, err := rt.InstantiateModule(execCtx, compiled, moduleConfig(spec, &stdout, &stderr))
+	result := wasm.Result{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Duration: time.Since(start),
+	}
+	if meter != nil {
+		result.FuelConsumed = min(meter.used.Load(), meter.limit)
+	}
+	// Memory returns a typed nil for modules without one, so check the
+	// compiled module's definitions instead.
+	if mod != nil && len(compiled.ImportedMemories())+len(compiled.ExportedMemories()) > 0 {
+		result.MemoryUsed = uint64(mod.Memory().Size())
+	}
+
+	if meter != nil && meter.exhausted.Load() {
+		return result, fmt.Errorf("%w: %d function calls", wasm.ErrFuelExhausted, meter.limit)
+	}
+	if err != nil && ctx.Err() == nil && errors.Is(execCtx.Err(), context.DeadlineExceeded) {
+		return result, fmt.Errorf("%w: %v", wasm.ErrCPUTimeExceeded, spec.Resources.CPUTime)
+	}
+	var exitErr *sys.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		switch exitErr.ExitCode() {
+		case sys.ExitCodeDeadlineExceeded, sys.ExitCodeContextCanceled:
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return result, ctxErr
+			}
+		}
+		result.ExitCode = int(exitErr.ExitCode())
+	default:
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return result, ctxErr
+		}
+		return result, fmt.Errorf("%w: %v", wasm.ErrModuleExecutionFailed, err)
+	}
+	return result, nil
+}
+
+// LimitsCPU implements wasm.CPULimiter: Run enforces Spec.Resources.CPUTime.
+func (r *Runner) LimitsCPU() bool { return true }
+
+// Close releases the compilation cache.
+func (r *Runner) Close(ctx context.Context) error {
+	return r.cache.Close(ctx)
+}
+
+// runtimeConfig builds the wazero runtime configuration for spec.
+func (r *Runner) runtimeConfig(spec wasm.Spec, cache wazero.CompilationCache, metered bool) wazero.RuntimeConfig {
+	var cfg wazero.RuntimeConfig
+	if r.interpreter || metered {
+		cfg = wazero.NewRuntimeConfigInterpreter()
+	} else {
+		cfg = wazero.NewRuntimeConfig()
+	}
+	cfg = cfg.WithCompilationCache(cache).WithCloseOnContextDone(true)
+	if pages := spec.Resources.MemoryPages; pages > 0 {
+		cfg = cfg.WithMemoryLimitPages(pages)
+	}
+	return cfg
+}
+
+// moduleConfig builds the instantiation configuration for spec.
+func moduleConfig(spec wasm.Spec, stdout, stderr *bytes.Buffer) wazero.ModuleConfig {
+	entry := spec.EntryPoint
+	if entry == "" {
+		entry = DefaultEntryPoint
+	}
+	cfg := wazero.NewModuleConfig().
+		WithStartFunctions(entry).
+		WithStdin(bytes.NewReader(spec.Stdin)).
+		WithStdout(stdout).
+		WithStderr(stderr).
+		WithRandSource(rand.Reader).
+		WithArgs(append([]string{"module"}, spec.Args...)...)
+	for _, kv := range spec.Env {
+		key, value, _ := strings.Cut(kv, "=")
+		cfg = cfg.WithEnv(key, value)
+	}
+	if spec.Security.EnableClock {
+		cfg = cfg.WithSysWalltime().WithSysNanotime().WithSysNanosleep()
+	}
+	if len(spec.Mounts) > 0 {
+		fs := wazero.NewFSConfig()
+		for _, m := range spec.Mounts {
+			if m.ReadOnly {
+				fs = fs.WithReadOnlyDirMount(m.HostPath, m.GuestPath)
+			} else {
+				fs = fs.WithDirMount(m.HostPath, m.GuestPath)
+			}
+		}
+		cfg = cfg.WithFSConfig(fs)
+	}
+	return cfg
+}
+
+// fuelMeter charges one unit of fuel per function call and cancels the
+// execution once the limit is spent.
+type fuelMeter struct {
+	limit     uint64
+	cancel    context.CancelFunc
+	used      atomic.Uint64
+	exhausted atomic.Bool
+}
+
+func (m *fuelMeter) NewFunctionListener(api.FunctionDefinition) experimental.FunctionListener {
+	return m
+}
+
+func (m *fuelMeter) Before(context.Context, api.Module, api.FunctionDefinition, []uint64, experimental.StackIterator) {
+	if m.used.Add(1) > m.limit && !m.exhausted.Swap(true) {
+		m.cancel()
+	}
+}
+
+func (m *fuelMeter) After(context.Context, api.Module, api.FunctionDefinition, []uint64) {}
+
+func (m *fuelMeter) Abort(context.Context, api.Module, api.FunctionDefinition, error) {}
+
+var (
+	_ wasm.Runner                          = (*Runner)(nil)
+	_ wasm.CPULimiter                      = (*Runner)(nil)
+	_ experimental.FunctionListenerFactory = (*fuelMeter)(nil)
+)
@@ -0,0 +1,169 @@
+package wazero
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime/backend/wasm"
+)
+
+// Hand-assembled modules exporting _start.
+var (
+	// emptyModule returns immediately.
+	emptyModule = []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00, // type: () -> ()
+		0x03, 0x02, 0x01, 0x00, // func 0: type 0
+		0x07, 0x0a, 0x01, 0x06, '_', 's', 't', 'a', 'r', 't', 0x00, 0x00,
+		0x0a, 0x04, 0x01, 0x02, 0x00, 0x0b, // body: end
+	}
+
+	// loopModule spins forever.
+	loopModule = []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+		0x03, 0x02, 0x01, 0x00,
+		0x07, 0x0a, 0x01, 0x06, '_', 's', 't', 'a', 'r', 't', 0x00, 0x00,
+		0x0a, 0x09, 0x01, 0x07, 0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b, // loop br 0 end
+	}
+
+	// callLoopModule calls an empty function forever.
+	callLoopModule = []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+		0x03, 0x03, 0x02, 0x00, 0x00, // funcs 0 and 1: type 0
+		0x07, 0x0a, 0x01, 0x06, '_', 's', 't', 'a', 'r', 't', 0x00, 0x00,
+		0x0a, 0x0e, 0x02,
+		0x09, 0x00, 0x03, 0x40, 0x10, 0x01, 0x0c, 0x00, 0x0b, 0x0b, // loop call 1 br 0 end
+		0x02, 0x00, 0x0b,
+	}
+
+	// printlnModule calls toolexec.println with {"line":"hi"} and exports
+	// a toolexec_alloc that returns offset 1024.
+	printlnModule = []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x0f, 0x03, // types
+		0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e, // 0: (i32, i32) -> i64
+		0x60, 0x00, 0x00, // 1: () -> ()
+		0x60, 0x01, 0x7f, 0x01, 0x7f, // 2: (i32) -> i32
+		0x02, 0x14, 0x01, 0x08, 't', 'o', 'o', 'l', 'e', 'x', 'e', 'c',
+		0x07, 'p', 'r', 'i', 'n', 't', 'l', 'n', 0x00, 0x00, // import func 0: type 0
+		0x03, 0x03, 0x02, 0x01, 0x02, // funcs 1 and 2: types 1 and 2
+		0x05, 0x03, 0x01, 0x00, 0x01, // memory: 1 page
+		0x07, 0x24, 0x03,
+		0x06, '_', 's', 't', 'a', 'r', 't', 0x00, 0x01,
+		0x0e, 't', 'o', 'o', 'l', 'e', 'x', 'e', 'c', '_', 'a', 'l', 'l', 'o', 'c', 0x00, 0x02,
+		0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+		0x0a, 0x11, 0x02,
+		0x09, 0x00, 0x41, 0x00, 0x41, 0x0d, 0x10, 0x00, 0x1a, 0x0b, // call 0 (0, 13) drop
+		0x05, 0x00, 0x41, 0x80, 0x08, 0x0b, // i32.const 1024
+		0x0b, 0x13, 0x01, 0x00, 0x41, 0x00, 0x0b, 0x0d,
+		'{', '"', 'l', 'i', 'n', 'e', '"', ':', '"', 'h', 'i', '"', '}',
+	}
+)
+
+func TestRunner_Run(t *testing.T) {
+	r := New(Config{})
+	defer func() { _ = r.Close(context.Background()) }()
+
+	result, err := r.Run(context.Background(), wasm.Spec{Module: emptyModule})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("ExitCode = %d, want 0", result.ExitCode)
+	}
+}
+
+func TestRunner_InvalidModule(t *testing.T) {
+	r := New(Config{})
+	if _, err := r.Run(context.Background(), wasm.Spec{}); !errors.Is(err, wasm.ErrInvalidModule) {
+		t.Errorf("Run() error = %v, want %v", err, wasm.ErrInvalidModule)
+	}
+	_, err := r.Run(context.Background(), wasm.Spec{Module: []byte{0x00, 0x61, 0x73, 0x6d, 0xff}})
+	if !errors.Is(err, wasm.ErrModuleCompilationFailed) {
+		t.Errorf("Run() error = %v, want %v", err, wasm.ErrModuleCompilationFailed)
+	}
+}
+
+func TestRunner_Component(t *testing.T) {
+	r := New(Config{})
+	component := []byte{0x00, 0x61, 0x73, 0x6d, 0x0d, 0x00, 0x01, 0x00}
+	if _, err := r.Run(context.Background(), wasm.Spec{Module: component}); !errors.Is(err, wasm.ErrComponentUnsupported) {
+		t.Errorf("Run() error = %v, want %v", err, wasm.ErrComponentUnsupported)
+	}
+}
+
+func TestRunner_Timeout(t *testing.T) {
+	r := New(Config{})
+	start := time.Now()
+	_, err := r.Run(context.Background(), wasm.Spec{Module: loopModule, Timeout: 50 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Run() took %v to stop", elapsed)
+	}
+}
+
+func TestRunner_FuelExhausted(t *testing.T) {
+	r := New(Config{})
+	spec := wasm.Spec{
+		Module:    callLoopModule,
+		Timeout:   5 * time.Second,
+		Resources: wasm.ResourceSpec{FuelLimit: 1000},
+	}
+	result, err := r.Run(context.Background(), spec)
+	if !errors.Is(err, wasm.ErrFuelExhausted) {
+		t.Fatalf("Run() error = %v, want %v", err, wasm.ErrFuelExhausted)
+	}
+	if result.FuelConsumed != 1000 {
+		t.Errorf("FuelConsumed = %d, want 1000", result.FuelConsumed)
+	}
+}
+
+func TestRunner_CPUTime(t *testing.T) {
+	r := New(Config{})
+	spec := wasm.Spec{
+		// A loop without calls spends no fuel, so only CPUTime stops it.
+		Module:    loopModule,
+		Timeout:   5 * time.Second,
+		Resources: wasm.ResourceSpec{FuelLimit: 1000, CPUTime: 50 * time.Millisecond},
+	}
+	start := time.Now()
+	if _, err := r.Run(context.Background(), spec); !errors.Is(err, wasm.ErrCPUTimeExceeded) {
+		t.Errorf("Run() error = %v, want %v", err, wasm.ErrCPUTimeExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Run() took %v to stop", elapsed)
+	}
+}
+
+func TestRunner_HostFunctions(t *testing.T) {
+	r := New(Config{})
+	defer func() { _ = r.Close(context.Background()) }()
+
+	spec := wasm.Spec{
+		Module:   printlnModule,
+		Security: wasm.SecuritySpec{AllowedHostFunctions: []string{wasm.HostPrintln}},
+	}
+	result, err := r.Run(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Stdout != "hi\n" {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, "hi\n")
+	}
+
+	// Denied calls return an error response instead of trapping.
+	spec.Security.AllowedHostFunctions = nil
+	result, err = r.Run(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("Run() denied error = %v", err)
+	}
+	if result.Stdout != "" {
+		t.Errorf("Stdout = %q, want nothing from a denied println", result.Stdout)
+	}
+}
@@ -0,0 +1,27 @@
+package windows
+
+import "context"
+
+// ProcessRunner is the primary interface for Windows execution.
+// This package provides JobRunner, which confines a host process in a Job
+// Object, and SandboxRunner, which runs the command in Windows Sandbox.
+//
+// Implementations are expected to:
+//   - Apply spec.Resources before the command can spawn children
+//   - Capture stdout/stderr
+//   - Respect context cancellation and spec timeout
+//   - Terminate every process of the execution when done
+type ProcessRunner interface {
+	// Run executes the command and returns the result.
+	Run(ctx context.Context, spec ProcessSpec) (ProcessResult, error)
+}
+
+// HealthChecker verifies that the host supports the runner.
+// This is an optional interface - backends may skip health checks.
+type HealthChecker interface {
+	// Ping checks if the isolation mechanism is usable.
+	Ping(ctx context.Context) error
+
+	// Info returns host information.
+	Info(ctx context.Context) (HostInfo, error)
+}
@@ -0,0 +1,216 @@
+package windows
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// DefaultInheritEnv lists the host environment variables JobRunner passes
+// to the command when JobRunnerConfig.InheritEnv is nil: the minimum
+// Windows programs need to locate the system and resolve commands.
+var DefaultInheritEnv = []string{"SystemRoot", "SystemDrive", "windir", "ComSpec", "PATH", "PATHEXT"}
+
+// JobRunnerConfig configures a JobRunner.
+type JobRunnerConfig struct {
+	// InheritEnv lists host environment variables passed to the command,
+	// ahead of ProcessSpec.Env.
+	// Default: DefaultInheritEnv
+	InheritEnv []string
+
+	// WaitDelay bounds how long Run waits for output pipes that processes
+	// left behind by the command still hold open. They are killed with
+	// the job afterwards.
+	// Default: 1s
+	WaitDelay time.Duration
+}
+
+// JobRunner runs the command as a host process confined in a Job Object.
+// It implements ProcessRunner and HealthChecker.
+//
+// The process starts suspended and is assigned to a fresh job before its
+// first instruction, so neither it nor any process it spawns escapes the
+// limits. The job is configured to kill every remaining process when Run
+// returns. Each run gets a private host temp dir as its working directory,
+// TEMP, and workspace; network access is not restricted.
+//
+// Contract:
+// - Concurrency: safe for concurrent use; each run creates its own job.
+// - Platform: Run and Ping fail with ErrWindowsNotAvailable on other systems.
+// - Errors: timeouts wrap runtime.ErrTimeout; exhausting the job memory limit wraps runtime.ErrResourceLimit.
+type JobRunner struct {
+	inheritEnv []string
+	waitDelay  time.Duration
+}
+
+// NewJobRunner creates a JobRunner.
+func NewJobRunner(cfg JobRunnerConfig) *JobRunner {
+	inheritEnv := cfg.InheritEnv
+	if inheritEnv == nil {
+		inheritEnv = DefaultInheritEnv
+	}
+	waitDelay := cfg.WaitDelay
+	if waitDelay <= 0 {
+		waitDelay = time.Second
+	}
+	return &JobRunner{
+		inheritEnv: inheritEnv,
+		waitDelay:  waitDelay,
+	}
+}
+
+// Run implements ProcessRunner.
+func (r *JobRunner) Run(ctx context.Context, spec ProcessSpec) (ProcessResult, error) {
+	if err := spec.Validate(); err != nil {
+		return ProcessResult{}, err
+	}
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spec.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	result := ProcessResult{
+		Isolation: "job",
+		Enforced: Enforcement{
+			Memory:    spec.Resources.MemoryBytes > 0,
+			CPU:       spec.Resources.CPURatePercent > 0,
+			Processes: spec.Resources.ActiveProcesses > 0,
+			UI:        spec.Security.UIRestrictions,
+		},
+	}
+
+	j, err := newJob(spec.Resources, spec.Security)
+	if err != nil {
+		return ProcessResult{}, fmt.Errorf("%w: %v", ErrWindowsNotAvailable, err)
+	}
+	defer j.close()
+
+	dir, err := os.MkdirTemp("", "toolruntime-job-*")
+	if err != nil {
+		return ProcessResult{}, err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	env := r.environ(dir)
+	if spec.Staging != nil {
+		if err := stageWorkspace(dir, spec.Staging); err != nil {
+			return ProcessResult{}, err
+		}
+		env = append(env,
+			runtime.WorkspaceEnv+"="+dir,
+			runtime.OutputEnv+"="+filepath.Join(dir, filepath.FromSlash(spec.Staging.OutputDir)))
+	}
+
+	cmd := exec.CommandContext(ctx, spec.Command[0], spec.Command[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(env, spec.Env...)
+	cmd.Stdin = bytes.NewReader(spec.Stdin)
+	// Terminating the job also stops the processes the command spawned.
+	cmd.Cancel = j.terminate
+	cmd.WaitDelay = r.waitDelay
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	var lines []io.Closer
+	if spec.LogStreamer != nil {
+		stdoutLines := runtime.NewLineWriter(spec.LogStreamer, runtime.LogStdout)
+		stderrLines := runtime.NewLineWriter(spec.LogStreamer, runtime.LogStderr)
+		lines = append(lines, stdoutLines, stderrLines)
+		cmd.Stdout = io.MultiWriter(&stdout, stdoutLines)
+		cmd.Stderr = io.MultiWriter(&stderr, stderrLines)
+	}
+
+	if err := j.start(cmd); err != nil {
+		return ProcessResult{}, fmt.Errorf("%w: %w", ErrProcessFailed, err)
+	}
+	// Wait reports ErrWaitDelay when leftover processes held the output
+	// open; the exit status is still in ProcessState.
+	_ = cmd.Wait()
+	for _, l := range lines {
+		_ = l.Close()
+	}
+	stats := j.stats()
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	result.Duration = time.Since(start)
+	result.Usage = stats.usage
+	if err := ctx.Err(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return result, fmt.Errorf("%w: %v", runtime.ErrTimeout, err)
+		}
+		return result, err
+	}
+	result.ExitCode = cmd.ProcessState.ExitCode()
+	if stats.memoryLimitHit && result.ExitCode != 0 {
+		return result, fmt.Errorf("%w: job memory limit of %d bytes reached", runtime.ErrResourceLimit, spec.Resources.MemoryBytes)
+	}
+
+	if spec.Staging != nil {
+		artifacts, err := collectWorkspace(dir, spec.Staging)
+		if err != nil {
+			return result, err
+		}
+		result.Artifacts = artifacts
+	}
+	return result, nil
+}
+
+// environ returns the inherited host variables, with TEMP and TMP pointing
+// into the run's private directory.
+func (r *JobRunner) environ(dir string) []string {
+	var env []string
+	for _, name := range r.inheritEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return append(env, "TEMP="+dir, "TMP="+dir)
+}
+
+// jobStats is what a job reports after its command exits.
+type jobStats struct {
+	usage          runtime.ResourceUsage
+	memoryLimitHit bool
+}
+
+// cpuRate converts a percentage of one CPU into a Job Object CPU rate,
+// which is in hundredths of a percent of all CPUs, clamped to the valid
+// range of 1 to 10000.
+func cpuRate(percent int64, cpus int) uint32 {
+	rate := percent * 100 / int64(max(cpus, 1))
+	return uint32(min(max(rate, 1), 10000))
+}
+
+// Ping implements HealthChecker.
+func (r *JobRunner) Ping(ctx context.Context) error {
+	_, err := r.Info(ctx)
+	return err
+}
+
+// Info implements HealthChecker. It creates and discards an unconfigured
+// job to confirm Job Objects are usable.
+func (r *JobRunner) Info(ctx context.Context) (HostInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return HostInfo{}, err
+	}
+	j, err := newJob(ResourceSpec{}, SecuritySpec{})
+	if err != nil {
+		return HostInfo{}, fmt.Errorf("%w: %v", ErrWindowsNotAvailable, err)
+	}
+	j.close()
+	return HostInfo{Isolation: "job"}, nil
+}
+
+var (
+	_ ProcessRunner = (*JobRunner)(nil)
+	_ HealthChecker = (*JobRunner)(nil)
+)
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package windows
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// job is a placeholder: Job Objects exist only on Windows.
+type job struct{}
+
+func newJob(ResourceSpec, SecuritySpec) (*job, error) {
+	return nil, errors.New("job objects require Windows")
+}
+
+func (*job) start(*exec.Cmd) error { return errors.ErrUnsupported }
+
+func (*job) terminate() error { return errors.ErrUnsupported }
+
+func (*job) stats() jobStats { return jobStats{} }
+
+func (*job) close() {}
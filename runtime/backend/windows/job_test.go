@@ -0,0 +1,77 @@
+package windows
+
+import (
+	"context"
+	"errors"
+	goruntime "runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+func TestCPURate(t *testing.T) {
+	tests := []struct {
+		percent int64
+		cpus    int
+		want    uint32
+	}{
+		{100, 1, 10000},
+		{50, 4, 1250},
+		{200, 8, 2500},
+		{400, 2, 10000},
+		{1, 1000, 1},
+		{50, 0, 5000},
+	}
+	for _, tt := range tests {
+		if got := cpuRate(tt.percent, tt.cpus); got != tt.want {
+			t.Errorf("cpuRate(%d, %d) = %d, want %d", tt.percent, tt.cpus, got, tt.want)
+		}
+	}
+}
+
+func TestJobRunnerUnavailable(t *testing.T) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("job objects are available on Windows")
+	}
+	r := NewJobRunner(JobRunnerConfig{})
+	if err := r.Ping(context.Background()); !errors.Is(err, ErrWindowsNotAvailable) {
+		t.Errorf("Ping() error = %v, want %v", err, ErrWindowsNotAvailable)
+	}
+	_, err := r.Run(context.Background(), ProcessSpec{Command: []string{"cmd.exe"}})
+	if !errors.Is(err, ErrWindowsNotAvailable) {
+		t.Errorf("Run() error = %v, want %v", err, ErrWindowsNotAvailable)
+	}
+}
+
+func TestJobRunnerRun(t *testing.T) {
+	if goruntime.GOOS != "windows" {
+		t.Skip("job objects require Windows")
+	}
+	r := NewJobRunner(JobRunnerConfig{})
+	result, err := r.Run(context.Background(), ProcessSpec{
+		Command:   []string{"cmd.exe", "/c", "more & echo %TOOLEXEC_WORKSPACE% & exit 3"},
+		Stdin:     []byte("hello\r\n"),
+		Resources: ResourceSpec{MemoryBytes: 256 << 20, CPURatePercent: 50, ActiveProcesses: 4},
+		Security:  SecuritySpec{UIRestrictions: true},
+		Staging:   &runtime.Staging{OutputDir: "out"},
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.ExitCode != 3 || !strings.HasPrefix(result.Stdout, "hello") || !strings.Contains(result.Stdout, "toolruntime-job-") {
+		t.Errorf("Run() = %+v", result)
+	}
+	if result.Usage.PeakMemoryBytes == 0 || !result.Enforced.Memory || result.Enforced.Network {
+		t.Errorf("Usage = %+v, Enforced = %+v", result.Usage, result.Enforced)
+	}
+
+	_, err = r.Run(context.Background(), ProcessSpec{
+		Command: []string{"cmd.exe", "/c", "ping -n 10 127.0.0.1 > nul"},
+		Timeout: 100 * time.Millisecond,
+	})
+	if !errors.Is(err, runtime.ErrTimeout) {
+		t.Errorf("Run() error = %v, want %v", err, runtime.ErrTimeout)
+	}
+}
@@ -0,0 +1,244 @@
+package windows
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	goruntime "runtime"
+	"syscall"
+	"time"
+	"unsafe"
+
+	winsys "golang.org/x/sys/windows"
+)
+
+// Job Object structures and values that golang.org/x/sys/windows does not
+// define.
+const (
+	jobCPURateControlEnable  = 0x1
+	jobCPURateControlHardCap = 0x4
+
+	jobMsgProcessMemoryLimit = 9
+	jobMsgJobMemoryLimit     = 10
+
+	jobUIRestrictAll = winsys.JOB_OBJECT_UILIMIT_DESKTOP |
+		winsys.JOB_OBJECT_UILIMIT_DISPLAYSETTINGS |
+		winsys.JOB_OBJECT_UILIMIT_EXITWINDOWS |
+		winsys.JOB_OBJECT_UILIMIT_GLOBALATOMS |
+		winsys.JOB_OBJECT_UILIMIT_HANDLES |
+		winsys.JOB_OBJECT_UILIMIT_READCLIPBOARD |
+		winsys.JOB_OBJECT_UILIMIT_SYSTEMPARAMETERS |
+		winsys.JOB_OBJECT_UILIMIT_WRITECLIPBOARD
+)
+
+// jobCPURateControl is JOBOBJECT_CPU_RATE_CONTROL_INFORMATION with the
+// CpuRate member of its union.
+type jobCPURateControl struct {
+	ControlFlags uint32
+	CPURate      uint32
+}
+
+// jobBasicAccounting is JOBOBJECT_BASIC_ACCOUNTING_INFORMATION. Times are
+// in 100-nanosecond units.
+type jobBasicAccounting struct {
+	TotalUserTime             int64
+	TotalKernelTime           int64
+	ThisPeriodTotalUserTime   int64
+	ThisPeriodTotalKernelTime int64
+	TotalPageFaultCount       uint32
+	TotalProcesses            uint32
+	ActiveProcesses           uint32
+	TotalTerminatedProcesses  uint32
+}
+
+// jobAssociateCompletionPort is JOBOBJECT_ASSOCIATE_COMPLETION_PORT.
+type jobAssociateCompletionPort struct {
+	CompletionKey  uintptr
+	CompletionPort winsys.Handle
+}
+
+// job is a Job Object with a completion port that receives its limit
+// notifications.
+type job struct {
+	handle winsys.Handle
+	port   winsys.Handle
+}
+
+// newJob creates a job that kills its processes when closed and applies
+// res and sec to them.
+func newJob(res ResourceSpec, sec SecuritySpec) (*job, error) {
+	handle, err := winsys.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	j := &job{handle: handle}
+	if err := j.configure(res, sec); err != nil {
+		j.close()
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *job) configure(res ResourceSpec, sec SecuritySpec) error {
+	port, err := winsys.CreateIoCompletionPort(winsys.InvalidHandle, 0, 0, 1)
+	if err != nil {
+		return fmt.Errorf("create completion port: %w", err)
+	}
+	j.port = port
+	assoc := jobAssociateCompletionPort{CompletionPort: port}
+	if err := j.set(winsys.JobObjectAssociateCompletionPortInformation, unsafe.Pointer(&assoc), unsafe.Sizeof(assoc)); err != nil {
+		return fmt.Errorf("associate completion port: %w", err)
+	}
+
+	var limits winsys.JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+	limits.BasicLimitInformation.LimitFlags = winsys.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE |
+		winsys.JOB_OBJECT_LIMIT_DIE_ON_UNHANDLED_EXCEPTION
+	if res.MemoryBytes > 0 {
+		limits.BasicLimitInformation.LimitFlags |= winsys.JOB_OBJECT_LIMIT_JOB_MEMORY
+		limits.JobMemoryLimit = uintptr(res.MemoryBytes)
+	}
+	if res.ActiveProcesses > 0 {
+		limits.BasicLimitInformation.LimitFlags |= winsys.JOB_OBJECT_LIMIT_ACTIVE_PROCESS
+		limits.BasicLimitInformation.ActiveProcessLimit = uint32(res.ActiveProcesses)
+	}
+	if err := j.set(winsys.JobObjectExtendedLimitInformation, unsafe.Pointer(&limits), unsafe.Sizeof(limits)); err != nil {
+		return fmt.Errorf("set limits: %w", err)
+	}
+
+	if res.CPURatePercent > 0 {
+		rate := jobCPURateControl{
+			ControlFlags: jobCPURateControlEnable | jobCPURateControlHardCap,
+			CPURate:      cpuRate(res.CPURatePercent, goruntime.NumCPU()),
+		}
+		if err := j.set(winsys.JobObjectCpuRateControlInformation, unsafe.Pointer(&rate), unsafe.Sizeof(rate)); err != nil {
+			return fmt.Errorf("set cpu rate: %w", err)
+		}
+	}
+
+	if sec.UIRestrictions {
+		ui := winsys.JOBOBJECT_BASIC_UI_RESTRICTIONS{UIRestrictionsClass: jobUIRestrictAll}
+		if err := j.set(winsys.JobObjectBasicUIRestrictions, unsafe.Pointer(&ui), unsafe.Sizeof(ui)); err != nil {
+			return fmt.Errorf("set ui restrictions: %w", err)
+		}
+	}
+	return nil
+}
+
+func (j *job) set(class uint32, info unsafe.Pointer, size uintptr) error {
+	_, err := winsys.SetInformationJobObject(j.handle, class, uintptr(info), uint32(size))
+	return err
+}
+
+func (j *job) query(class int32, info unsafe.Pointer, size uintptr) error {
+	return winsys.QueryInformationJobObject(j.handle, class, uintptr(info), uint32(size), nil)
+}
+
+// start starts cmd suspended, assigns it to the job, and resumes it, so
+// the command runs no code outside the job.
+func (j *job) start(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= winsys.CREATE_SUSPENDED
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	pid := uint32(cmd.Process.Pid)
+	err := j.assign(pid)
+	if err == nil {
+		err = resumeThreads(pid)
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return err
+	}
+	return nil
+}
+
+func (j *job) assign(pid uint32) error {
+	process, err := winsys.OpenProcess(winsys.PROCESS_SET_QUOTA|winsys.PROCESS_TERMINATE, false, pid)
+	if err != nil {
+		return fmt.Errorf("open process: %w", err)
+	}
+	defer func() { _ = winsys.CloseHandle(process) }()
+	if err := winsys.AssignProcessToJobObject(j.handle, process); err != nil {
+		return fmt.Errorf("assign process to job: %w", err)
+	}
+	return nil
+}
+
+// resumeThreads resumes the threads of a process started suspended.
+// os.Process does not expose the primary thread handle, so the threads
+// are found through a snapshot.
+func resumeThreads(pid uint32) error {
+	snapshot, err := winsys.CreateToolhelp32Snapshot(winsys.TH32CS_SNAPTHREAD, 0)
+	if err != nil {
+		return fmt.Errorf("snapshot threads: %w", err)
+	}
+	defer func() { _ = winsys.CloseHandle(snapshot) }()
+
+	resumed := 0
+	entry := winsys.ThreadEntry32{Size: uint32(unsafe.Sizeof(winsys.ThreadEntry32{}))}
+	for err = winsys.Thread32First(snapshot, &entry); err == nil; err = winsys.Thread32Next(snapshot, &entry) {
+		if entry.OwnerProcessID != pid {
+			continue
+		}
+		thread, err := winsys.OpenThread(winsys.THREAD_SUSPEND_RESUME, false, entry.ThreadID)
+		if err != nil {
+			return fmt.Errorf("open thread: %w", err)
+		}
+		_, err = winsys.ResumeThread(thread)
+		_ = winsys.CloseHandle(thread)
+		if err != nil {
+			return fmt.Errorf("resume thread: %w", err)
+		}
+		resumed++
+	}
+	if !errors.Is(err, winsys.ERROR_NO_MORE_FILES) {
+		return fmt.Errorf("enumerate threads: %w", err)
+	}
+	if resumed == 0 {
+		return errors.New("no thread to resume")
+	}
+	return nil
+}
+
+// terminate stops every process in the job.
+func (j *job) terminate() error {
+	return winsys.TerminateJobObject(j.handle, 1)
+}
+
+// stats reads the job's accounting and drains its limit notifications.
+func (j *job) stats() jobStats {
+	var stats jobStats
+	var acct jobBasicAccounting
+	if j.query(winsys.JobObjectBasicAccountingInformation, unsafe.Pointer(&acct), unsafe.Sizeof(acct)) == nil {
+		stats.usage.CPUTime = time.Duration(acct.TotalUserTime+acct.TotalKernelTime) * 100
+	}
+	var limits winsys.JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+	if j.query(winsys.JobObjectExtendedLimitInformation, unsafe.Pointer(&limits), unsafe.Sizeof(limits)) == nil {
+		stats.usage.PeakMemoryBytes = int64(limits.PeakJobMemoryUsed)
+	}
+
+	for {
+		var msg uint32
+		var key uintptr
+		var overlapped *winsys.Overlapped
+		if winsys.GetQueuedCompletionStatus(j.port, &msg, &key, &overlapped, 0) != nil {
+			break
+		}
+		if msg == jobMsgJobMemoryLimit || msg == jobMsgProcessMemoryLimit {
+			stats.memoryLimitHit = true
+		}
+	}
+	return stats
+}
+
+// close releases the job, killing any processes still in it.
+func (j *job) close() {
+	if j.port != 0 {
+		_ = winsys.CloseHandle(j.port)
+	}
+	_ = winsys.CloseHandle(j.handle)
+}
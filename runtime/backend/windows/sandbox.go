@@ -0,0 +1,412 @@
+package windows
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// SandboxFolder is where SandboxRunner maps the run directory inside
+// Windows Sandbox. The workspace is its workspace subdirectory.
+const SandboxFolder = `C:\toolexec`
+
+// sandboxMinMemoryMB is the smallest memory size Windows Sandbox accepts;
+// smaller MemoryInMB values are ignored in favor of its default.
+const sandboxMinMemoryMB = 2048
+
+// Files in the run directory shared with the sandbox.
+const (
+	sandboxConfigFile = "sandbox.wsb"
+	sandboxScriptFile = "run.cmd"
+	sandboxStdinFile  = "stdin.txt"
+	sandboxStdoutFile = "stdout.txt"
+	sandboxStderrFile = "stderr.txt"
+	sandboxExitFile   = "exit.txt"
+	sandboxWorkspace  = "workspace"
+)
+
+// SandboxRunnerConfig configures a SandboxRunner.
+type SandboxRunnerConfig struct {
+	// ExecutablePath is the Windows Sandbox launcher.
+	// Default: WindowsSandbox.exe
+	ExecutablePath string
+
+	// StopCommand closes a sandbox that outlives its run.
+	// Default: taskkill /f /t /im WindowsSandbox*
+	StopCommand []string
+
+	// StopTimeout is how long a finished sandbox gets to shut down on its
+	// own before StopCommand is run.
+	// Default: 10s
+	StopTimeout time.Duration
+
+	// PollInterval is how often Run checks whether the command finished.
+	// Default: 250ms
+	PollInterval time.Duration
+}
+
+// SandboxRunner runs the command inside Windows Sandbox, a disposable
+// Hyper-V virtual machine. It implements ProcessRunner and HealthChecker.
+//
+// Each run writes a .wsb configuration that maps a host temp dir to
+// SandboxFolder and logs on with a script that runs the command, records
+// its output and exit code in that folder, and shuts the sandbox down.
+// Networking, the vGPU, audio and video input, and clipboard and printer
+// redirection are disabled according to the spec. Resources.MemoryBytes
+// sizes the whole virtual machine, rounded up to the sandbox minimum of
+// 2 GiB; CPU rate and process limits are not enforced. The sandbox boot
+// counts against the spec timeout.
+//
+// Contract:
+// - Concurrency: safe for concurrent use; Windows Sandbox allows one instance per host, so runs are serialized.
+// - Errors: timeouts wrap runtime.ErrTimeout; launcher failures wrap ErrProcessFailed.
+type SandboxRunner struct {
+	executable   string
+	stopCommand  []string
+	stopTimeout  time.Duration
+	pollInterval time.Duration
+
+	mu sync.Mutex
+}
+
+// NewSandboxRunner creates a SandboxRunner.
+func NewSandboxRunner(cfg SandboxRunnerConfig) *SandboxRunner {
+	executable := cfg.ExecutablePath
+	if executable == "" {
+		executable = "WindowsSandbox.exe"
+	}
+	stopCommand := cfg.StopCommand
+	if len(stopCommand) == 0 {
+		stopCommand = []string{"taskkill", "/f", "/t", "/im", "WindowsSandbox*"}
+	}
+	stopTimeout := cfg.StopTimeout
+	if stopTimeout <= 0 {
+		stopTimeout = 10 * time.Second
+	}
+	pollInterval := cfg.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = 250 * time.Millisecond
+	}
+	return &SandboxRunner{
+		executable:   executable,
+		stopCommand:  stopCommand,
+		stopTimeout:  stopTimeout,
+		pollInterval: pollInterval,
+	}
+}
+
+// Run implements ProcessRunner.
+func (r *SandboxRunner) Run(ctx context.Context, spec ProcessSpec) (ProcessResult, error) {
+	if err := spec.Validate(); err != nil {
+		return ProcessResult{}, err
+	}
+	script, err := sandboxScript(spec)
+	if err != nil {
+		return ProcessResult{}, err
+	}
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spec.Timeout)
+		defer cancel()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	start := time.Now()
+	result := ProcessResult{
+		Isolation: "sandbox",
+		Enforced: Enforcement{
+			Memory:  spec.Resources.MemoryBytes > 0,
+			UI:      spec.Security.UIRestrictions,
+			Network: spec.Security.DisableNetwork,
+		},
+	}
+
+	dir, err := os.MkdirTemp("", "toolruntime-sandbox-*")
+	if err != nil {
+		return ProcessResult{}, err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	workspace := filepath.Join(dir, sandboxWorkspace)
+	if err := os.Mkdir(workspace, 0o755); err != nil {
+		return ProcessResult{}, err
+	}
+	if spec.Staging != nil {
+		if err := stageWorkspace(workspace, spec.Staging); err != nil {
+			return ProcessResult{}, err
+		}
+	}
+	config, err := sandboxConfig(dir, spec)
+	if err != nil {
+		return ProcessResult{}, err
+	}
+	for name, data := range map[string][]byte{
+		sandboxConfigFile: config,
+		sandboxScriptFile: script,
+		sandboxStdinFile:  spec.Stdin,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return ProcessResult{}, err
+		}
+	}
+
+	cmd := exec.Command(r.executable, filepath.Join(dir, sandboxConfigFile))
+	if err := cmd.Start(); err != nil {
+		return ProcessResult{}, fmt.Errorf("%w: %w", ErrProcessFailed, err)
+	}
+	l := &launcher{cmd: cmd, done: make(chan struct{})}
+	go func() {
+		l.err = cmd.Wait()
+		close(l.done)
+	}()
+
+	exitCode, err := r.wait(ctx, dir, l)
+	if err != nil {
+		r.stop(l)
+		result.Duration = time.Since(start)
+		return result, err
+	}
+	r.shutdown(l)
+	result.Duration = time.Since(start)
+	result.ExitCode = exitCode
+
+	stdout, _ := os.ReadFile(filepath.Join(dir, sandboxStdoutFile))
+	stderr, _ := os.ReadFile(filepath.Join(dir, sandboxStderrFile))
+	result.Stdout, result.Stderr = string(stdout), string(stderr)
+	if spec.LogStreamer != nil {
+		// Output is only visible once the sandbox has finished.
+		streamLines(spec.LogStreamer, runtime.LogStdout, stdout)
+		streamLines(spec.LogStreamer, runtime.LogStderr, stderr)
+	}
+
+	if spec.Staging != nil {
+		artifacts, err := collectWorkspace(workspace, spec.Staging)
+		if err != nil {
+			return result, err
+		}
+		result.Artifacts = artifacts
+	}
+	return result, nil
+}
+
+// launcher tracks the Windows Sandbox launcher process. err is valid once
+// done is closed.
+type launcher struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+	err  error
+}
+
+// wait polls for the exit file until it appears, the launcher fails, or
+// ctx is done.
+func (r *SandboxRunner) wait(ctx context.Context, dir string, l *launcher) (int, error) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+	done := l.done
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, sandboxExitFile))
+		if err == nil {
+			code, err := strconv.Atoi(strings.TrimSpace(string(data)))
+			if err != nil {
+				return 0, fmt.Errorf("%w: invalid exit code %q", ErrProcessFailed, data)
+			}
+			return code, nil
+		}
+		select {
+		case <-done:
+			// The launcher may hand the sandbox off and exit cleanly;
+			// only a failure ends the run.
+			if l.err != nil {
+				return 0, fmt.Errorf("%w: %w", ErrProcessFailed, l.err)
+			}
+			done = nil
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return 0, fmt.Errorf("%w: %v", runtime.ErrTimeout, ctx.Err())
+			}
+			return 0, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// shutdown waits for the launcher to exit as the sandbox shuts itself
+// down, and stops the sandbox if it does not.
+func (r *SandboxRunner) shutdown(l *launcher) {
+	select {
+	case <-l.done:
+	case <-time.After(r.stopTimeout):
+		r.stop(l)
+	}
+}
+
+// stop runs the stop command, kills the launcher, and waits for it to
+// exit.
+func (r *SandboxRunner) stop(l *launcher) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.stopTimeout)
+	defer cancel()
+	_ = exec.CommandContext(ctx, r.stopCommand[0], r.stopCommand[1:]...).Run()
+	_ = l.cmd.Process.Kill()
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+	}
+}
+
+// streamLines sends data to streamer line by line.
+func streamLines(streamer runtime.LogStreamer, stream runtime.LogStream, data []byte) {
+	w := runtime.NewLineWriter(streamer, stream)
+	_, _ = w.Write(data)
+	_ = w.Close()
+}
+
+// wsbConfig is the Windows Sandbox configuration file format.
+type wsbConfig struct {
+	XMLName              xml.Name          `xml:"Configuration"`
+	VGPU                 string            `xml:"vGPU"`
+	Networking           string            `xml:"Networking"`
+	AudioInput           string            `xml:"AudioInput"`
+	VideoInput           string            `xml:"VideoInput"`
+	PrinterRedirection   string            `xml:"PrinterRedirection"`
+	ClipboardRedirection string            `xml:"ClipboardRedirection"`
+	ProtectedClient      string            `xml:"ProtectedClient"`
+	MemoryInMB           int64             `xml:"MemoryInMB,omitempty"`
+	MappedFolders        []wsbMappedFolder `xml:"MappedFolders>MappedFolder"`
+	LogonCommand         wsbLogonCommand   `xml:"LogonCommand"`
+}
+
+type wsbMappedFolder struct {
+	HostFolder    string `xml:"HostFolder"`
+	SandboxFolder string `xml:"SandboxFolder"`
+	ReadOnly      bool   `xml:"ReadOnly"`
+}
+
+type wsbLogonCommand struct {
+	Command string `xml:"Command"`
+}
+
+// sandboxConfig renders the .wsb configuration that maps dir to
+// SandboxFolder and runs the logon script.
+func sandboxConfig(dir string, spec ProcessSpec) ([]byte, error) {
+	cfg := wsbConfig{
+		VGPU:                 "Disable",
+		Networking:           "Default",
+		AudioInput:           "Disable",
+		VideoInput:           "Disable",
+		PrinterRedirection:   "Disable",
+		ClipboardRedirection: "Default",
+		ProtectedClient:      "Default",
+		MappedFolders: []wsbMappedFolder{
+			{HostFolder: dir, SandboxFolder: SandboxFolder},
+		},
+		LogonCommand: wsbLogonCommand{Command: SandboxFolder + `\` + sandboxScriptFile},
+	}
+	if spec.Security.DisableNetwork {
+		cfg.Networking = "Disable"
+	}
+	if spec.Security.UIRestrictions {
+		cfg.ClipboardRedirection = "Disable"
+	}
+	if spec.Security.ProtectedClient {
+		cfg.ProtectedClient = "Enable"
+	}
+	if spec.Resources.MemoryBytes > 0 {
+		cfg.MemoryInMB = max((spec.Resources.MemoryBytes+(1<<20)-1)>>20, sandboxMinMemoryMB)
+	}
+	out, err := xml.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// sandboxScript renders the logon script that runs the command inside the
+// sandbox. The exit file is written last, by rename, so the host never
+// reads a partial exit code.
+func sandboxScript(spec ProcessSpec) ([]byte, error) {
+	folder := SandboxFolder + `\`
+	workspace := folder + sandboxWorkspace
+
+	env := spec.Env
+	if spec.Staging != nil {
+		env = append(env[:len(env):len(env)],
+			runtime.WorkspaceEnv+"="+workspace,
+			runtime.OutputEnv+"="+workspace+`\`+strings.ReplaceAll(spec.Staging.OutputDir, "/", `\`))
+	}
+
+	var b bytes.Buffer
+	b.WriteString("@echo off\r\n")
+	for _, kv := range env {
+		if err := checkScriptArg(kv); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "set \"%s\"\r\n", escapePercent(kv))
+	}
+	fmt.Fprintf(&b, "cd /d %s\r\n", workspace)
+	for i, arg := range spec.Command {
+		if err := checkScriptArg(arg); err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "\"%s\"", escapePercent(arg))
+	}
+	fmt.Fprintf(&b, " < %s%s > %s%s 2> %s%s\r\n",
+		folder, sandboxStdinFile, folder, sandboxStdoutFile, folder, sandboxStderrFile)
+	fmt.Fprintf(&b, "echo %%ERRORLEVEL%% > %sexit.tmp\r\n", folder)
+	fmt.Fprintf(&b, "move /y %sexit.tmp %s%s > nul\r\n", folder, folder, sandboxExitFile)
+	b.WriteString("shutdown /s /t 0\r\n")
+	return b.Bytes(), nil
+}
+
+// checkScriptArg rejects values that cannot be quoted safely in a batch
+// file.
+func checkScriptArg(s string) error {
+	if strings.ContainsAny(s, "\"\r\n") {
+		return fmt.Errorf("%q cannot be passed to the sandbox: quotes and newlines are not supported", s)
+	}
+	return nil
+}
+
+// escapePercent escapes percent signs, which batch files expand even
+// inside quotes.
+func escapePercent(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// Ping implements HealthChecker.
+func (r *SandboxRunner) Ping(ctx context.Context) error {
+	_, err := r.Info(ctx)
+	return err
+}
+
+// Info implements HealthChecker. It resolves the launcher executable;
+// Windows Sandbox is an optional Windows feature, so a missing launcher
+// means it is not enabled.
+func (r *SandboxRunner) Info(ctx context.Context) (HostInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return HostInfo{}, err
+	}
+	path, err := exec.LookPath(r.executable)
+	if err != nil {
+		return HostInfo{}, fmt.Errorf("%w: %v", ErrWindowsNotAvailable, err)
+	}
+	return HostInfo{Isolation: "sandbox", Path: path}, nil
+}
+
+var (
+	_ ProcessRunner = (*SandboxRunner)(nil)
+	_ HealthChecker = (*SandboxRunner)(nil)
+)
@@ -0,0 +1,158 @@
+package windows
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	goruntime "runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// fakeSandbox writes a shell script standing in for WindowsSandbox.exe. It
+// receives the .wsb path and plays the sandbox's part in the run
+// directory next to it.
+func fakeSandbox(t *testing.T, body string) string {
+	t.Helper()
+	if goruntime.GOOS == "windows" {
+		t.Skip("the fake launcher is a shell script")
+	}
+	script := `#!/bin/sh
+dir=$(dirname "$1")
+` + body
+	path := filepath.Join(t.TempDir(), "WindowsSandbox.exe")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSandboxConfig(t *testing.T) {
+	config, err := sandboxConfig(`C:\Temp\run`, ProcessSpec{
+		Command:   []string{"sandbox.exe"},
+		Resources: ResourceSpec{MemoryBytes: 512 << 20},
+		Security:  SecuritySpec{UIRestrictions: true, DisableNetwork: true, ProtectedClient: true},
+	})
+	if err != nil {
+		t.Fatalf("sandboxConfig() error = %v", err)
+	}
+	for _, want := range []string{
+		"<Networking>Disable</Networking>",
+		"<vGPU>Disable</vGPU>",
+		"<ClipboardRedirection>Disable</ClipboardRedirection>",
+		"<ProtectedClient>Enable</ProtectedClient>",
+		"<MemoryInMB>2048</MemoryInMB>",
+		`<HostFolder>C:\Temp\run</HostFolder>`,
+		`<SandboxFolder>C:\toolexec</SandboxFolder>`,
+		`<Command>C:\toolexec\run.cmd</Command>`,
+	} {
+		if !strings.Contains(string(config), want) {
+			t.Errorf("config missing %s:\n%s", want, config)
+		}
+	}
+
+	config, err = sandboxConfig(`C:\Temp\run`, ProcessSpec{Command: []string{"sandbox.exe"}})
+	if err != nil {
+		t.Fatalf("sandboxConfig() error = %v", err)
+	}
+	if !strings.Contains(string(config), "<Networking>Default</Networking>") || strings.Contains(string(config), "MemoryInMB") {
+		t.Errorf("config = %s", config)
+	}
+}
+
+func TestSandboxScript(t *testing.T) {
+	script, err := sandboxScript(ProcessSpec{
+		Command: []string{`C:\tools\sandbox.exe`, "--rate=50%"},
+		Env:     []string{"A=1"},
+		Staging: &runtime.Staging{OutputDir: "out/files"},
+	})
+	if err != nil {
+		t.Fatalf("sandboxScript() error = %v", err)
+	}
+	want := "@echo off\r\n" +
+		"set \"A=1\"\r\n" +
+		"set \"TOOLEXEC_WORKSPACE=C:\\toolexec\\workspace\"\r\n" +
+		"set \"TOOLEXEC_OUTPUT=C:\\toolexec\\workspace\\out\\files\"\r\n" +
+		"cd /d C:\\toolexec\\workspace\r\n" +
+		"\"C:\\tools\\sandbox.exe\" \"--rate=50%%\" < C:\\toolexec\\stdin.txt > C:\\toolexec\\stdout.txt 2> C:\\toolexec\\stderr.txt\r\n" +
+		"echo %ERRORLEVEL% > C:\\toolexec\\exit.tmp\r\n" +
+		"move /y C:\\toolexec\\exit.tmp C:\\toolexec\\exit.txt > nul\r\n" +
+		"shutdown /s /t 0\r\n"
+	if string(script) != want {
+		t.Errorf("sandboxScript() =\n%s\nwant\n%s", script, want)
+	}
+
+	if _, err := sandboxScript(ProcessSpec{Command: []string{"sandbox.exe", `a"b`}}); err == nil {
+		t.Error("sandboxScript() with a quote succeeded, want an error")
+	}
+}
+
+func TestSandboxRunnerRun(t *testing.T) {
+	path := fakeSandbox(t, `test -f "$dir/run.cmd" || exit 1
+cat "$dir/stdin.txt" > "$dir/stdout.txt"
+printf 'line1\r\n__OUT__:42\r\n' >> "$dir/stdout.txt"
+echo warn > "$dir/stderr.txt"
+echo done > "$dir/workspace/out/result.txt"
+printf '3 \r\n' > "$dir/exit.txt"
+`)
+	r := NewSandboxRunner(SandboxRunnerConfig{ExecutablePath: path, PollInterval: 10 * time.Millisecond})
+
+	var streamed []string
+	result, err := r.Run(context.Background(), ProcessSpec{
+		Command:  []string{"sandbox.exe"},
+		Stdin:    []byte("code\n"),
+		Security: SecuritySpec{DisableNetwork: true},
+		Staging:  &runtime.Staging{OutputDir: "out", Files: map[string][]byte{"in.txt": []byte("hi")}},
+		LogStreamer: runtime.LogStreamerFunc(func(stream runtime.LogStream, line string) {
+			streamed = append(streamed, string(stream)+":"+line)
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.ExitCode != 3 || result.Stdout != "code\nline1\r\n__OUT__:42\r\n" || result.Stderr != "warn\n" {
+		t.Errorf("Run() = %+v", result)
+	}
+	if result.Isolation != "sandbox" || !result.Enforced.Network || result.Enforced.CPU {
+		t.Errorf("Isolation = %q, Enforced = %+v", result.Isolation, result.Enforced)
+	}
+	if len(result.Artifacts) != 1 || result.Artifacts[0].Name != "result.txt" {
+		t.Errorf("Artifacts = %+v", result.Artifacts)
+	}
+	want := []string{"stdout:code", "stdout:line1", "stdout:__OUT__:42", "stderr:warn"}
+	if !reflect.DeepEqual(streamed, want) {
+		t.Errorf("streamed = %q, want %q", streamed, want)
+	}
+}
+
+func TestSandboxRunnerErrors(t *testing.T) {
+	path := fakeSandbox(t, "exec sleep 10\n")
+	r := NewSandboxRunner(SandboxRunnerConfig{
+		ExecutablePath: path,
+		StopCommand:    []string{"true"},
+		StopTimeout:    time.Second,
+		PollInterval:   10 * time.Millisecond,
+	})
+	_, err := r.Run(context.Background(), ProcessSpec{Command: []string{"sandbox.exe"}, Timeout: 50 * time.Millisecond})
+	if !errors.Is(err, runtime.ErrTimeout) {
+		t.Errorf("Run() error = %v, want %v", err, runtime.ErrTimeout)
+	}
+
+	r = NewSandboxRunner(SandboxRunnerConfig{ExecutablePath: fakeSandbox(t, "exit 1\n"), PollInterval: 10 * time.Millisecond})
+	if _, err := r.Run(context.Background(), ProcessSpec{Command: []string{"sandbox.exe"}}); !errors.Is(err, ErrProcessFailed) {
+		t.Errorf("Run() error = %v, want %v", err, ErrProcessFailed)
+	}
+
+	r = NewSandboxRunner(SandboxRunnerConfig{ExecutablePath: filepath.Join(t.TempDir(), "missing.exe")})
+	if err := r.Ping(context.Background()); !errors.Is(err, ErrWindowsNotAvailable) {
+		t.Errorf("Ping() error = %v, want %v", err, ErrWindowsNotAvailable)
+	}
+	if info, err := NewSandboxRunner(SandboxRunnerConfig{ExecutablePath: path}).Info(context.Background()); err != nil || info.Path != path {
+		t.Errorf("Info() = %+v, %v", info, err)
+	}
+}
@@ -0,0 +1,130 @@
+package windows
+
+import (
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// ResourceSpec defines resource limits.
+type ResourceSpec struct {
+	// MemoryBytes is the committed memory limit for all processes of the
+	// execution together. Zero means unlimited.
+	MemoryBytes int64
+
+	// CPURatePercent caps CPU use as a percentage of one CPU; 200 allows
+	// two CPUs. Zero means unlimited.
+	CPURatePercent int64
+
+	// ActiveProcesses limits the number of processes alive at once,
+	// including the command itself. Zero means unlimited.
+	ActiveProcesses int64
+}
+
+// SecuritySpec defines Windows security settings.
+type SecuritySpec struct {
+	// UIRestrictions denies access to the desktop, clipboard, global
+	// atoms, display and system settings, and ExitWindows.
+	UIRestrictions bool
+
+	// DisableNetwork disconnects the execution from the network. Only
+	// SandboxRunner can honor it; JobRunner reports it as not enforced.
+	DisableNetwork bool
+
+	// ProtectedClient runs Windows Sandbox with AppContainer isolation
+	// on its remote session. Ignored by JobRunner.
+	ProtectedClient bool
+}
+
+// ProcessSpec defines what to run and how.
+type ProcessSpec struct {
+	// Command is the program and arguments to execute (required).
+	Command []string
+
+	// Stdin is passed to the command's standard input.
+	Stdin []byte
+
+	// Env contains environment variables in KEY=value format.
+	Env []string
+
+	// Resources defines resource limits.
+	Resources ResourceSpec
+
+	// Security defines security settings.
+	Security SecuritySpec
+
+	// Timeout is the maximum execution duration.
+	Timeout time.Duration
+
+	// Labels are metadata labels for tracking.
+	Labels map[string]string
+
+	// LogStreamer, if set, receives stdout and stderr line by line while
+	// the command runs. Runners that only see output at exit deliver it
+	// then.
+	LogStreamer runtime.LogStreamer
+
+	// Staging, if set, lists files to write into the workspace before the
+	// command starts and the output directory to collect after it exits.
+	// Windows has no mount namespace, so runners place the workspace
+	// themselves, ignore Staging.Dir, and set runtime.WorkspaceEnv and
+	// runtime.OutputEnv to where it is.
+	Staging *runtime.Staging
+}
+
+// ProcessResult captures the output of an execution.
+type ProcessResult struct {
+	// Isolation names the mechanism used: "job" or "sandbox".
+	Isolation string
+
+	// ExitCode is the command's exit code.
+	ExitCode int
+
+	// Stdout contains the command's stdout output.
+	Stdout string
+
+	// Stderr contains the command's stderr output.
+	Stderr string
+
+	// Duration is the execution time.
+	Duration time.Duration
+
+	// Usage reports the CPU time and peak memory of the job, where the
+	// runner can measure them.
+	Usage runtime.ResourceUsage
+
+	// Enforced reports which settings of the spec the runner applied.
+	Enforced Enforcement
+
+	// Artifacts holds the files collected from Staging's output directory.
+	Artifacts []runtime.Artifact
+}
+
+// Enforcement reports which settings of a ProcessSpec a runner applied.
+// A Job Object confines each process, while Windows Sandbox limits the
+// virtual machine as a whole and can only isolate its network.
+type Enforcement struct {
+	// Memory is true when Resources.MemoryBytes was applied.
+	Memory bool
+
+	// CPU is true when Resources.CPURatePercent was applied.
+	CPU bool
+
+	// Processes is true when Resources.ActiveProcesses was applied.
+	Processes bool
+
+	// UI is true when Security.UIRestrictions was applied.
+	UI bool
+
+	// Network is true when Security.DisableNetwork was applied.
+	Network bool
+}
+
+// HostInfo contains Windows host metadata.
+type HostInfo struct {
+	// Isolation names the mechanism the runner uses: "job" or "sandbox".
+	Isolation string
+
+	// Path is the resolved Windows Sandbox executable, for SandboxRunner.
+	Path string
+}
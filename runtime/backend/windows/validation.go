@@ -0,0 +1,37 @@
+package windows
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Validate checks ProcessSpec for errors before execution.
+func (s ProcessSpec) Validate() error {
+	if len(s.Command) == 0 || s.Command[0] == "" {
+		return errors.New("command is required")
+	}
+	if err := s.Resources.Validate(); err != nil {
+		return fmt.Errorf("resources: %w", err)
+	}
+	for _, env := range s.Env {
+		if name, _, ok := strings.Cut(env, "="); !ok || name == "" {
+			return fmt.Errorf("env %q must be KEY=value", env)
+		}
+	}
+	return nil
+}
+
+// Validate checks ResourceSpec for invalid values.
+func (r ResourceSpec) Validate() error {
+	if r.MemoryBytes < 0 {
+		return errors.New("memory cannot be negative")
+	}
+	if r.CPURatePercent < 0 {
+		return errors.New("cpu rate cannot be negative")
+	}
+	if r.ActiveProcesses < 0 {
+		return errors.New("active processes cannot be negative")
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+// Package windows provides a backend that executes code on Windows hosts,
+// so the runtime is not limited to Linux isolation primitives.
+//
+// Two runners are available. JobRunner starts the sandbox entrypoint as a
+// host process confined in a Job Object, which caps its committed memory,
+// CPU rate, and process count, strips its access to the desktop, and kills
+// every process it spawned when the run ends. SandboxRunner runs the
+// entrypoint inside Windows Sandbox, a disposable Hyper-V virtual machine
+// that can also be disconnected from the network.
+//
+//	runner := windows.NewJobRunner(windows.JobRunnerConfig{})
+//	backend := windows.New(windows.Config{
+//		Client:        runner,
+//		HealthChecker: runner,
+//	})
+//
+// The sandbox entrypoint receives the code on stdin.
+package windows
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// Errors for Windows backend operations.
+var (
+	// ErrWindowsNotAvailable is returned when the isolation mechanism is
+	// not usable on this host.
+	ErrWindowsNotAvailable = fmt.Errorf("windows isolation not available: %w", runtime.ErrRuntimeUnavailable)
+
+	// ErrClientNotConfigured is returned when no ProcessRunner is configured.
+	ErrClientNotConfigured = errors.New("windows client not configured")
+
+	// ErrProcessFailed is returned when the command cannot be started or
+	// confined.
+	ErrProcessFailed = errors.New("process execution failed")
+)
+
+// DefaultCommand is the sandbox entrypoint used when Config.Command is
+// empty.
+var DefaultCommand = []string{`C:\Program Files\toolruntime\toolruntime-sandbox.exe`}
+
+// Logger is the interface for logging.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Errors: logging must be best-effort and must not panic.
+type Logger interface {
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// Config configures a Windows backend.
+type Config struct {
+	// Command is the sandbox entrypoint. It receives the code on stdin.
+	// With SandboxRunner the path is resolved inside the sandbox.
+	// Default: DefaultCommand
+	Command []string
+
+	// Client is the process runner implementation, such as a *JobRunner
+	// or *SandboxRunner.
+	// If nil, Execute() returns ErrClientNotConfigured.
+	Client ProcessRunner
+
+	// HealthChecker optionally verifies the host before execution.
+	// If nil, health checks are skipped.
+	HealthChecker HealthChecker
+
+	// Logger is an optional logger for backend events.
+	Logger Logger
+}
+
+// Backend executes code in Job Objects or Windows Sandbox.
+type Backend struct {
+	command []string
+	client  ProcessRunner
+	health  HealthChecker
+	logger  Logger
+}
+
+// New creates a new Windows backend with the given configuration.
+func New(cfg Config) *Backend {
+	command := cfg.Command
+	if len(command) == 0 {
+		command = DefaultCommand
+	}
+
+	return &Backend{
+		command: command,
+		client:  cfg.Client,
+		health:  cfg.HealthChecker,
+		logger:  cfg.Logger,
+	}
+}
+
+// Kind returns the backend kind identifier.
+func (b *Backend) Kind() runtime.BackendKind {
+	return runtime.BackendWindows
+}
+
+// Capabilities implements runtime.CapabilityReporter. Only dev executions
+// keep network access.
+func (b *Backend) Capabilities() runtime.Capabilities {
+	return runtime.Capabilities{
+		Streaming:    true,
+		Workspace:    true,
+		FileStaging:  true,
+		NetworkModes: []string{"none", "host"},
+	}
+}
+
+// Execute runs code in a Job Object or Windows Sandbox.
+func (b *Backend) Execute(ctx context.Context, req runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
+	if err := ctx.Err(); err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+	if err := req.Validate(); err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+
+	if b.client == nil {
+		return runtime.ExecuteResult{}, ErrClientNotConfigured
+	}
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	if b.health != nil {
+		if err := b.health.Ping(ctx); err != nil {
+			return runtime.ExecuteResult{}, fmt.Errorf("%w: %v", ErrWindowsNotAvailable, err)
+		}
+	}
+
+	profile := req.Profile
+	if profile == "" {
+		profile = runtime.ProfileStandard
+	}
+
+	spec, err := b.buildSpec(req, profile)
+	if err != nil {
+		return runtime.ExecuteResult{}, err
+	}
+
+	if b.logger != nil {
+		b.logger.Info("executing on windows",
+			"profile", profile,
+			"uiRestrictions", spec.Security.UIRestrictions,
+			"disableNetwork", spec.Security.DisableNetwork,
+			"activeProcesses", spec.Resources.ActiveProcesses)
+	}
+
+	processResult, err := b.client.Run(ctx, spec)
+	info := b.backendInfo(profile, processResult)
+	if err != nil {
+		return runtime.ExecuteResult{
+			Stdout:   processResult.Stdout,
+			Stderr:   processResult.Stderr,
+			Duration: time.Since(start),
+			Backend:  info,
+		}, err
+	}
+
+	enforced := processResult.Enforced
+	if b.logger != nil && spec.Security.DisableNetwork && !enforced.Network {
+		b.logger.Warn("network isolation not enforced by runner",
+			"isolation", processResult.Isolation)
+	}
+
+	usage := processResult.Usage
+	usage.WallTime = processResult.Duration
+	return runtime.ExecuteResult{
+		Value:     extractOutValue(processResult.Stdout),
+		Stdout:    processResult.Stdout,
+		Stderr:    processResult.Stderr,
+		Duration:  processResult.Duration,
+		Backend:   info,
+		Usage:     usage,
+		Artifacts: processResult.Artifacts,
+		LimitsEnforced: runtime.LimitsEnforced{
+			Timeout:    true,
+			Memory:     enforced.Memory,
+			CPU:        enforced.CPU,
+			Pids:       enforced.Processes,
+			ToolCalls:  true,
+			ChainSteps: true,
+		},
+	}, nil
+}
+
+var _ runtime.Backend = (*Backend)(nil)
+
+func (b *Backend) backendInfo(profile runtime.SecurityProfile, result ProcessResult) runtime.BackendInfo {
+	details := map[string]any{
+		"profile": string(profile),
+	}
+	if result.Isolation != "" {
+		details["isolation"] = result.Isolation
+		details["networkDisabled"] = result.Enforced.Network
+	}
+	return runtime.BackendInfo{
+		Kind:      runtime.BackendWindows,
+		Readiness: runtime.ReadinessBeta,
+		Details:   details,
+	}
+}
+
+func (b *Backend) buildSpec(req runtime.ExecuteRequest, profile runtime.SecurityProfile) (ProcessSpec, error) {
+	staging, err := req.Staging()
+	if err != nil {
+		return ProcessSpec{}, err
+	}
+
+	spec := ProcessSpec{
+		Command: b.command,
+		Stdin:   []byte(req.Code),
+		Security: SecuritySpec{
+			UIRestrictions: true,
+			DisableNetwork: true,
+		},
+		Timeout: req.Timeout,
+		Labels: map[string]string{
+			"runtime.profile":        string(profile),
+			"runtime.backend":        string(runtime.BackendWindows),
+			runtime.LabelExecutionID: req.EnsureExecutionID(),
+		},
+		LogStreamer: req.LogStreamer,
+		Staging:     staging,
+	}
+
+	switch profile {
+	case runtime.ProfileDev:
+		// Dev mode: desktop and network access
+		spec.Security.UIRestrictions = false
+		spec.Security.DisableNetwork = false
+	case runtime.ProfileHardened:
+		// Hardened mode: the entrypoint cannot spawn processes, and
+		// Windows Sandbox isolates its remote session.
+		spec.Resources.ActiveProcesses = 1
+		spec.Security.ProtectedClient = true
+	}
+
+	if req.Limits.MemoryBytes > 0 {
+		spec.Resources.MemoryBytes = req.Limits.MemoryBytes
+	}
+	if req.Limits.CPUQuotaMillis > 0 {
+		// CPUQuotaMillis is per 100ms period, as for the container
+		// backends, so 100ms is one full CPU.
+		spec.Resources.CPURatePercent = req.Limits.CPUQuotaMillis
+	}
+	if req.Limits.PidsMax > 0 {
+		spec.Resources.ActiveProcesses = req.Limits.PidsMax
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(req.Env)) {
+		spec.Env = append(spec.Env, key+"="+req.Env[key])
+	}
+
+	if err := spec.Validate(); err != nil {
+		return ProcessSpec{}, err
+	}
+	return spec, nil
+}
+
+// extractOutValue extracts the __out value from stdout if present.
+func extractOutValue(stdout string) any {
+	lines := strings.Split(stdout, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "__OUT__:") {
+			jsonStr := strings.TrimPrefix(line, "__OUT__:")
+			var value any
+			if err := json.Unmarshal([]byte(jsonStr), &value); err == nil {
+				return value
+			}
+			return jsonStr
+		}
+		if strings.HasPrefix(line, "{") && strings.HasSuffix(line, "}") {
+			var payload map[string]any
+			if err := json.Unmarshal([]byte(line), &payload); err == nil {
+				if value, ok := payload["__out"]; ok {
+					return value
+				}
+			}
+		}
+	}
+	return nil
+}
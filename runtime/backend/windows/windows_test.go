@@ -0,0 +1,189 @@
+package windows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// mockGateway implements runtime.ToolGateway for testing.
+type mockGateway struct{}
+
+func (m *mockGateway) SearchTools(_ context.Context, _ string, _ int) ([]index.Summary, error) {
+	return nil, nil
+}
+
+func (m *mockGateway) ListNamespaces(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockGateway) DescribeTool(_ context.Context, _ string, _ tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	return tooldoc.ToolDoc{}, nil
+}
+
+func (m *mockGateway) ListToolExamples(_ context.Context, _ string, _ int) ([]tooldoc.ToolExample, error) {
+	return nil, nil
+}
+
+func (m *mockGateway) RunTool(_ context.Context, _ string, _ map[string]any) (run.RunResult, error) {
+	return run.RunResult{}, nil
+}
+
+func (m *mockGateway) RunChain(_ context.Context, _ []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	return run.RunResult{}, nil, nil
+}
+
+type mockProcessRunner struct {
+	spec   ProcessSpec
+	result ProcessResult
+	err    error
+}
+
+func (m *mockProcessRunner) Run(_ context.Context, spec ProcessSpec) (ProcessResult, error) {
+	m.spec = spec
+	return m.result, m.err
+}
+
+type mockHealthChecker struct {
+	err error
+}
+
+func (m *mockHealthChecker) Ping(_ context.Context) error { return m.err }
+
+func (m *mockHealthChecker) Info(_ context.Context) (HostInfo, error) {
+	return HostInfo{Isolation: "job"}, m.err
+}
+
+func TestBackendDefaults(t *testing.T) {
+	b := New(Config{})
+	if b.Kind() != runtime.BackendWindows {
+		t.Errorf("Kind() = %v, want %v", b.Kind(), runtime.BackendWindows)
+	}
+	if !reflect.DeepEqual(b.command, DefaultCommand) {
+		t.Errorf("command = %v, want %v", b.command, DefaultCommand)
+	}
+
+	_, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}})
+	if !errors.Is(err, ErrClientNotConfigured) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrClientNotConfigured)
+	}
+}
+
+func TestBackendProfiles(t *testing.T) {
+	tests := []struct {
+		profile   runtime.SecurityProfile
+		security  SecuritySpec
+		processes int64
+	}{
+		{runtime.ProfileDev, SecuritySpec{}, 0},
+		{runtime.ProfileStandard, SecuritySpec{UIRestrictions: true, DisableNetwork: true}, 0},
+		{runtime.ProfileHardened, SecuritySpec{UIRestrictions: true, DisableNetwork: true, ProtectedClient: true}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.profile), func(t *testing.T) {
+			runner := &mockProcessRunner{}
+			b := New(Config{Client: runner})
+			if _, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}, Profile: tt.profile}); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if runner.spec.Security != tt.security || runner.spec.Resources.ActiveProcesses != tt.processes {
+				t.Errorf("Security = %+v, ActiveProcesses = %d", runner.spec.Security, runner.spec.Resources.ActiveProcesses)
+			}
+		})
+	}
+}
+
+func TestBackendExecute(t *testing.T) {
+	runner := &mockProcessRunner{result: ProcessResult{
+		Isolation: "job",
+		Stdout:    "log\r\n__OUT__:{\"ok\":true}\r\n",
+		Usage:     runtime.ResourceUsage{CPUTime: 5, PeakMemoryBytes: 1 << 20},
+		Enforced:  Enforcement{Memory: true, CPU: true, Processes: true, UI: true},
+	}}
+	b := New(Config{Client: runner, HealthChecker: &mockHealthChecker{}})
+	result, err := b.Execute(context.Background(), runtime.ExecuteRequest{
+		Code:      "print(1)",
+		Gateway:   &mockGateway{},
+		Env:       map[string]string{"B": "2", "A": "1"},
+		Limits:    runtime.Limits{MemoryBytes: 256 << 20, CPUQuotaMillis: 50, PidsMax: 8},
+		Workspace: &runtime.Workspace{},
+		Files:     map[string]runtime.File{"in.txt": {Data: []byte("hi")}},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	spec := runner.spec
+	if string(spec.Stdin) != "print(1)" || !reflect.DeepEqual(spec.Env, []string{"A=1", "B=2"}) {
+		t.Errorf("spec = %+v", spec)
+	}
+	wantRes := ResourceSpec{MemoryBytes: 256 << 20, CPURatePercent: 50, ActiveProcesses: 8}
+	if spec.Resources != wantRes {
+		t.Errorf("Resources = %+v, want %+v", spec.Resources, wantRes)
+	}
+	if spec.Staging == nil || string(spec.Staging.Files["in.txt"]) != "hi" {
+		t.Errorf("Staging = %+v", spec.Staging)
+	}
+
+	if out, _ := result.Value.(map[string]any); out["ok"] != true {
+		t.Errorf("Value = %v", result.Value)
+	}
+	if result.Usage.PeakMemoryBytes != 1<<20 || result.Usage.CPUTime != 5 {
+		t.Errorf("Usage = %+v", result.Usage)
+	}
+	if !result.LimitsEnforced.Memory || !result.LimitsEnforced.CPU || !result.LimitsEnforced.Pids {
+		t.Errorf("LimitsEnforced = %+v", result.LimitsEnforced)
+	}
+	if result.Backend.Details["isolation"] != "job" || result.Backend.Details["networkDisabled"] != false {
+		t.Errorf("Backend = %+v", result.Backend)
+	}
+}
+
+func TestBackendErrors(t *testing.T) {
+	b := New(Config{Client: &mockProcessRunner{}, HealthChecker: &mockHealthChecker{err: errors.New("no job objects")}})
+	_, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}})
+	if !errors.Is(err, ErrWindowsNotAvailable) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrWindowsNotAvailable)
+	}
+
+	runErr := fmt.Errorf("%w: access denied", ErrProcessFailed)
+	b = New(Config{Client: &mockProcessRunner{err: runErr, result: ProcessResult{Stderr: "boom"}}})
+	result, err := b.Execute(context.Background(), runtime.ExecuteRequest{Code: "test", Gateway: &mockGateway{}})
+	if !errors.Is(err, ErrProcessFailed) || result.Stderr != "boom" {
+		t.Errorf("Execute() = %+v, %v; want %v with stderr", result, err, ErrProcessFailed)
+	}
+}
+
+func TestProcessSpecValidate(t *testing.T) {
+	valid := ProcessSpec{Command: []string{"cmd.exe"}}
+	tests := []struct {
+		name   string
+		modify func(*ProcessSpec)
+	}{
+		{"no command", func(s *ProcessSpec) { s.Command = nil }},
+		{"empty program", func(s *ProcessSpec) { s.Command = []string{""} }},
+		{"negative memory", func(s *ProcessSpec) { s.Resources.MemoryBytes = -1 }},
+		{"negative cpu", func(s *ProcessSpec) { s.Resources.CPURatePercent = -1 }},
+		{"negative processes", func(s *ProcessSpec) { s.Resources.ActiveProcesses = -1 }},
+		{"bad env", func(s *ProcessSpec) { s.Env = []string{"=C:"} }},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := valid
+			tt.modify(&spec)
+			if err := spec.Validate(); err == nil {
+				t.Error("Validate() succeeded, want an error")
+			}
+		})
+	}
+}
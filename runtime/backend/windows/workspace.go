@@ -0,0 +1,34 @@
+package windows
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// stageWorkspace writes the staged files and an empty output directory
+// into dir, which the runner then exposes as the workspace.
+func stageWorkspace(dir string, s *runtime.Staging) error {
+	if err := runtime.WriteFiles(dir, s.Files); err != nil {
+		return err
+	}
+	return os.MkdirAll(filepath.Join(dir, filepath.FromSlash(s.OutputDir)), 0o755)
+}
+
+// collectWorkspace checks the workspace size and returns the files in its
+// output directory. Host directories have no size limit, so the workspace
+// is checked after the fact.
+func collectWorkspace(dir string, s *runtime.Staging) ([]runtime.Artifact, error) {
+	if limit := s.MaxBytes; limit > 0 {
+		used, err := runtime.DirSize(dir)
+		if err != nil {
+			return nil, err
+		}
+		if used > limit {
+			return nil, fmt.Errorf("%w: workspace used %d bytes, limit %d", runtime.ErrResourceLimit, used, limit)
+		}
+	}
+	return runtime.CollectArtifacts(filepath.Join(dir, filepath.FromSlash(s.OutputDir)), s.MaxBytes)
+}
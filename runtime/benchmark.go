@@ -0,0 +1,216 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultBenchmarkIterations is the number of warm executions Benchmark
+// runs when BenchmarkSpec.Iterations is zero.
+const DefaultBenchmarkIterations = 10
+
+// BenchmarkSpec configures Benchmark.
+type BenchmarkSpec struct {
+	// Request is the execution measured. It should do little beyond
+	// printing a line, so the measurements reflect the backend rather than
+	// the code. Its LogStreamer is replaced.
+	Request ExecuteRequest
+
+	// Iterations is the number of warm executions after the cold one.
+	// Default: DefaultBenchmarkIterations
+	Iterations int
+}
+
+// BenchmarkReport is what Benchmark measured of a backend.
+type BenchmarkReport struct {
+	// Backend is the backend's kind.
+	Backend BackendKind
+
+	// Capabilities is what the backend advertises, when it implements
+	// CapabilityReporter.
+	Capabilities *Capabilities
+
+	// ColdStart is the wall time of the first execution, which pays for
+	// the image pulls, compilation, and pool fills later ones reuse.
+	ColdStart time.Duration
+
+	// WarmStart is the wall time of the executions after the first.
+	WarmStart LatencyStats
+
+	// Overhead is the wall time of each execution minus the time its code
+	// ran (ResourceUsage.WallTime), for executions whose backend reports
+	// it.
+	Overhead LatencyStats
+
+	// FirstOutput is the time from the start of each execution to its
+	// first streamed line, for backends reporting Capabilities.Streaming.
+	FirstOutput LatencyStats
+}
+
+// LatencyStats summarizes a set of durations.
+type LatencyStats struct {
+	Samples int
+	Min     time.Duration
+	Mean    time.Duration
+	P50     time.Duration
+	P95     time.Duration
+	Max     time.Duration
+}
+
+// Benchmark measures backend by running spec.Request once cold and then
+// spec.Iterations times warm, in sequence. It stops at the first failed
+// execution, returning what it measured so far with the error.
+func Benchmark(ctx context.Context, backend Backend, spec BenchmarkSpec) (BenchmarkReport, error) {
+	iterations := spec.Iterations
+	if iterations <= 0 {
+		iterations = DefaultBenchmarkIterations
+	}
+	report := BenchmarkReport{Backend: backend.Kind()}
+	streaming := false
+	if reporter, ok := backend.(CapabilityReporter); ok {
+		caps := reporter.Capabilities()
+		report.Capabilities = &caps
+		streaming = caps.Streaming
+	}
+
+	var warm, overhead, firstOutput []time.Duration
+	for i := range iterations + 1 {
+		sample, err := benchmarkOnce(ctx, backend, spec.Request)
+		if err != nil {
+			report.finish(warm, overhead, firstOutput)
+			return report, fmt.Errorf("benchmark execution %d: %w", i, err)
+		}
+		if i == 0 {
+			report.ColdStart = sample.wall
+		} else {
+			warm = append(warm, sample.wall)
+		}
+		if sample.ran > 0 {
+			overhead = append(overhead, max(sample.wall-sample.ran, 0))
+		}
+		if streaming && sample.firstOutput > 0 {
+			firstOutput = append(firstOutput, sample.firstOutput)
+		}
+	}
+	report.finish(warm, overhead, firstOutput)
+	return report, nil
+}
+
+// benchmarkSample is what one execution measured; zero means unmeasured.
+type benchmarkSample struct {
+	wall, ran, firstOutput time.Duration
+}
+
+func benchmarkOnce(ctx context.Context, backend Backend, req ExecuteRequest) (benchmarkSample, error) {
+	var (
+		once  sync.Once
+		first time.Duration
+	)
+	start := time.Now()
+	req.LogStreamer = LogStreamerFunc(func(LogStream, string) {
+		once.Do(func() { first = time.Since(start) })
+	})
+	result, err := backend.Execute(ctx, req)
+	sample := benchmarkSample{wall: time.Since(start), ran: result.Usage.WallTime}
+	once.Do(func() {}) // Lines streamed after Execute returned do not count.
+	sample.firstOutput = first
+	return sample, err
+}
+
+func (r *BenchmarkReport) finish(warm, overhead, firstOutput []time.Duration) {
+	r.WarmStart = NewLatencyStats(warm)
+	r.Overhead = NewLatencyStats(overhead)
+	r.FirstOutput = NewLatencyStats(firstOutput)
+}
+
+// NewLatencyStats summarizes samples, using nearest-rank percentiles.
+func NewLatencyStats(samples []time.Duration) LatencyStats {
+	if len(samples) == 0 {
+		return LatencyStats{}
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	var total time.Duration
+	for _, s := range sorted {
+		total += s
+	}
+	rank := func(p int) time.Duration {
+		return sorted[max((p*len(sorted)+99)/100-1, 0)]
+	}
+	return LatencyStats{
+		Samples: len(sorted),
+		Min:     sorted[0],
+		Mean:    total / time.Duration(len(sorted)),
+		P50:     rank(50),
+		P95:     rank(95),
+		Max:     sorted[len(sorted)-1],
+	}
+}
+
+// String formats the stats on one line, or "-" when there are none.
+func (s LatencyStats) String() string {
+	if s.Samples == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("n=%d min=%v mean=%v p50=%v p95=%v max=%v", s.Samples, s.Min, s.Mean, s.P50, s.P95, s.Max)
+}
+
+// WriteText writes the report as aligned text, for command-line tools.
+func (r BenchmarkReport) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "backend\t%s\n", r.Backend)
+	fmt.Fprintf(tw, "cold start\t%v\n", r.ColdStart)
+	fmt.Fprintf(tw, "warm start\t%v\n", r.WarmStart)
+	fmt.Fprintf(tw, "overhead\t%v\n", r.Overhead)
+	fmt.Fprintf(tw, "first output\t%v\n", r.FirstOutput)
+	if c := r.Capabilities; c != nil {
+		fmt.Fprintf(tw, "capabilities\t%s\n", c.summary())
+	}
+	return tw.Flush()
+}
+
+// summary lists the capabilities on one line.
+func (c Capabilities) summary() string {
+	var parts []string
+	flags := []struct {
+		on   bool
+		name string
+	}{
+		{c.Streaming, "streaming"},
+		{c.GracefulStop, "graceful-stop"},
+		{c.Workspace, "workspace"},
+		{c.FileStaging, "file-staging"},
+		{c.GPU, "gpu"},
+	}
+	for _, f := range flags {
+		if f.on {
+			parts = append(parts, f.name)
+		}
+	}
+	if len(c.Languages) > 0 {
+		parts = append(parts, "languages="+strings.Join(c.Languages, ","))
+	}
+	if len(c.NetworkModes) > 0 {
+		parts = append(parts, "network="+strings.Join(c.NetworkModes, ","))
+	}
+	if len(c.EgressModes) > 0 {
+		modes := make([]string, len(c.EgressModes))
+		for i, m := range c.EgressModes {
+			modes[i] = string(m)
+		}
+		parts = append(parts, "egress="+strings.Join(modes, ","))
+	}
+	if c.MaxMemoryBytes > 0 {
+		parts = append(parts, fmt.Sprintf("max-memory=%d", c.MaxMemoryBytes))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, " ")
+}
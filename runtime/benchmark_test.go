@@ -0,0 +1,94 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchBackend streams a line and reports a fixed code run time, failing
+// from execution failAt on.
+type benchBackend struct {
+	calls  int
+	failAt int
+}
+
+func (b *benchBackend) Kind() BackendKind { return BackendDocker }
+
+func (b *benchBackend) Capabilities() Capabilities { return Capabilities{Streaming: true} }
+
+func (b *benchBackend) Execute(_ context.Context, req ExecuteRequest) (ExecuteResult, error) {
+	b.calls++
+	if b.failAt > 0 && b.calls >= b.failAt {
+		return ExecuteResult{}, ErrRuntimeUnavailable
+	}
+	if b.calls == 1 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if req.LogStreamer != nil {
+		req.LogStreamer.StreamLog(LogStdout, "ready")
+	}
+	return ExecuteResult{Usage: ResourceUsage{WallTime: time.Microsecond}}, nil
+}
+
+func TestBenchmark(t *testing.T) {
+	backend := &benchBackend{}
+	spec := BenchmarkSpec{Request: ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}}, Iterations: 4}
+
+	report, err := Benchmark(context.Background(), backend, spec)
+	if err != nil {
+		t.Fatalf("Benchmark() error = %v", err)
+	}
+	if backend.calls != 5 {
+		t.Errorf("executions = %d, want 5", backend.calls)
+	}
+	if report.Backend != BackendDocker || report.Capabilities == nil || !report.Capabilities.Streaming {
+		t.Errorf("report = %+v, want the backend's kind and capabilities", report)
+	}
+	if report.ColdStart < 5*time.Millisecond || report.WarmStart.Samples != 4 || report.WarmStart.Max >= report.ColdStart {
+		t.Errorf("ColdStart = %v, WarmStart = %v; want one slow cold start and four warm ones", report.ColdStart, report.WarmStart)
+	}
+	if report.Overhead.Samples != 5 || report.FirstOutput.Samples != 5 {
+		t.Errorf("Overhead = %v, FirstOutput = %v; want 5 samples each", report.Overhead, report.FirstOutput)
+	}
+
+	var text strings.Builder
+	if err := report.WriteText(&text); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	for _, want := range []string{"backend", "docker", "warm start", "n=4", "streaming"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("WriteText() = %q, want %q", text.String(), want)
+		}
+	}
+}
+
+func TestBenchmarkStopsAtFailure(t *testing.T) {
+	backend := &benchBackend{failAt: 3}
+	spec := BenchmarkSpec{Request: ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}}}
+
+	report, err := Benchmark(context.Background(), backend, spec)
+	if !errors.Is(err, ErrRuntimeUnavailable) {
+		t.Fatalf("Benchmark() error = %v, want %v", err, ErrRuntimeUnavailable)
+	}
+	if backend.calls != 3 || report.WarmStart.Samples != 1 {
+		t.Errorf("executions = %d, WarmStart = %v; want to stop at the third with one warm sample", backend.calls, report.WarmStart)
+	}
+}
+
+func TestNewLatencyStats(t *testing.T) {
+	var samples []time.Duration
+	for i := 20; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+	got := NewLatencyStats(samples)
+	want := LatencyStats{Samples: 20, Min: time.Millisecond, Mean: 10500 * time.Microsecond, P50: 10 * time.Millisecond, P95: 19 * time.Millisecond, Max: 20 * time.Millisecond}
+	if got != want {
+		t.Errorf("NewLatencyStats() = %+v, want %+v", got, want)
+	}
+	if s := NewLatencyStats(nil); s.String() != "-" {
+		t.Errorf("empty stats String() = %q, want -", s.String())
+	}
+}
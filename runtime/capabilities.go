@@ -0,0 +1,98 @@
+package runtime
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Capabilities describes the request features a backend can honor, so a
+// request can be checked before it is dispatched rather than failing, or
+// silently degrading, mid-run.
+type Capabilities struct {
+	// Languages lists the languages the backend runs. Empty means the
+	// backend does not restrict the language (or cannot tell).
+	Languages []string
+
+	// Streaming reports whether ExecuteRequest.LogStreamer receives output
+	// while the code runs. Backends without it ignore the streamer, so
+	// Check does not reject requests that set one.
+	Streaming bool
+
+	// GracefulStop reports whether ExecuteRequest.GraceTimeout is honored.
+	// Backends without it kill code at its Timeout, so Check does not
+	// reject requests that set one.
+	GracefulStop bool
+
+	// Workspace reports whether ExecuteRequest.Workspace is honored.
+	Workspace bool
+
+	// FileStaging reports whether ExecuteRequest.Files are staged and
+	// workspace output is returned as Artifacts.
+	FileStaging bool
+
+	// NetworkModes lists the network modes the backend can give an
+	// execution, such as "none" or "bridge". Empty means unknown.
+	NetworkModes []string
+
+	// EgressModes lists the ExecuteRequest.Egress modes the backend
+	// enforces. Requests with a policy in another mode are rejected.
+	EgressModes []EgressMode
+
+	// GPU reports whether Limits.GPUs is honored.
+	GPU bool
+
+	// MaxMemoryBytes is the largest Limits.MemoryBytes the backend can
+	// grant. Zero means no known cap.
+	MaxMemoryBytes int64
+}
+
+// CapabilityReporter is implemented by backends that advertise their
+// Capabilities. DefaultRuntime skips backends whose capabilities reject a
+// request and moves on to the profile's fallbacks.
+//
+// Contract:
+//   - Concurrency: implementations must be safe for concurrent use.
+//   - Ownership: the returned value is caller-owned.
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// SupportsLanguage reports whether language is in Languages, ignoring
+// case, or Languages is empty.
+func (c Capabilities) SupportsLanguage(language string) bool {
+	if len(c.Languages) == 0 || language == "" {
+		return true
+	}
+	return slices.ContainsFunc(c.Languages, func(l string) bool {
+		return strings.EqualFold(l, language)
+	})
+}
+
+// Check returns ErrUnsupportedCapability, listing every problem, when req
+// needs something the backend cannot provide.
+func (c Capabilities) Check(req ExecuteRequest) error {
+	var problems []string
+	if !c.SupportsLanguage(req.Language) {
+		problems = append(problems, fmt.Sprintf("language %q (supported: %s)", req.Language, strings.Join(c.Languages, ", ")))
+	}
+	if req.Workspace != nil && !c.Workspace {
+		problems = append(problems, "workspace")
+	}
+	if len(req.Files) > 0 && !c.FileStaging {
+		problems = append(problems, "file staging")
+	}
+	if req.Egress != nil && !slices.Contains(c.EgressModes, req.Egress.Mode) {
+		problems = append(problems, fmt.Sprintf("egress mode %q", req.Egress.Mode))
+	}
+	if req.Limits.GPUs.Count > 0 && !c.GPU {
+		problems = append(problems, "GPUs")
+	}
+	if c.MaxMemoryBytes > 0 && req.Limits.MemoryBytes > c.MaxMemoryBytes {
+		problems = append(problems, fmt.Sprintf("memory %d bytes (max %d)", req.Limits.MemoryBytes, c.MaxMemoryBytes))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedCapability, strings.Join(problems, "; "))
+}
@@ -0,0 +1,86 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCapabilitiesCheck(t *testing.T) {
+	caps := Capabilities{
+		Languages:      []string{"javascript"},
+		Workspace:      true,
+		MaxMemoryBytes: 1 << 30,
+	}
+	tests := []struct {
+		name    string
+		req     ExecuteRequest
+		wantErr string
+	}{
+		{name: "supported", req: ExecuteRequest{Language: "JavaScript", Workspace: &Workspace{}}},
+		{name: "default language", req: ExecuteRequest{}},
+		{name: "language", req: ExecuteRequest{Language: "go"}, wantErr: `language "go"`},
+		{name: "files", req: ExecuteRequest{Files: map[string]File{"a": {Data: []byte("x")}}}, wantErr: "file staging"},
+		{name: "gpu", req: ExecuteRequest{Limits: Limits{GPUs: GPURequest{Count: 1}}}, wantErr: "GPUs"},
+		{name: "memory", req: ExecuteRequest{Limits: Limits{MemoryBytes: 2 << 30}}, wantErr: "memory"},
+		{name: "egress", req: ExecuteRequest{Egress: &EgressPolicy{Mode: EgressDenyAll}}, wantErr: `egress mode "deny-all"`},
+		// Streaming is best-effort and never rejected.
+		{name: "streaming", req: ExecuteRequest{LogStreamer: LogStreamerFunc(func(LogStream, string) {})}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := caps.Check(tt.req)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnsupportedCapability) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() error = %v, want %v mentioning %q", err, ErrUnsupportedCapability, tt.wantErr)
+			}
+		})
+	}
+}
+
+// capsBackend is a mockBackend that advertises capabilities.
+type capsBackend struct {
+	mockBackend
+	caps Capabilities
+}
+
+func (b *capsBackend) Capabilities() Capabilities { return b.caps }
+
+func TestDefaultRuntimeSkipsIncapableBackends(t *testing.T) {
+	isolate := &capsBackend{
+		mockBackend: mockBackend{kind: BackendIsolate, result: ExecuteResult{Value: "isolate"}},
+		caps:        Capabilities{Languages: []string{"javascript"}},
+	}
+	docker := &mockBackend{kind: BackendDocker, result: ExecuteResult{Value: "docker"}}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: isolate},
+		Fallbacks:      map[SecurityProfile][]Backend{ProfileStandard: {docker}},
+		DefaultProfile: ProfileStandard,
+	})
+
+	caps, ok := rt.Capabilities("")
+	if !ok || caps.Languages[0] != "javascript" {
+		t.Errorf("Capabilities() = %+v, %v", caps, ok)
+	}
+
+	ctx := context.Background()
+	result, err := rt.Execute(ctx, ExecuteRequest{Code: "x", Language: "javascript", Gateway: &mockToolGateway{}})
+	if err != nil || result.Value != "isolate" {
+		t.Errorf("Execute(javascript) = %v, %v; want isolate", result.Value, err)
+	}
+	result, err = rt.Execute(ctx, ExecuteRequest{Code: "x", Language: "go", Gateway: &mockToolGateway{}})
+	if err != nil || result.Value != "docker" {
+		t.Errorf("Execute(go) = %v, %v; want docker", result.Value, err)
+	}
+
+	rt.RegisterBackend(ProfileDev, isolate)
+	if _, err := rt.Execute(ctx, ExecuteRequest{Code: "x", Language: "go", Gateway: &mockToolGateway{}, Profile: ProfileDev}); !errors.Is(err, ErrUnsupportedCapability) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrUnsupportedCapability)
+	}
+}
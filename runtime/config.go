@@ -0,0 +1,269 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFile is the declarative form of a RuntimeConfig, read from YAML or
+// JSON by LoadConfig. Field names are camelCase in both formats.
+type ConfigFile struct {
+	// DefaultProfile is RuntimeConfig.DefaultProfile.
+	DefaultProfile SecurityProfile `json:"defaultProfile,omitempty" yaml:"defaultProfile,omitempty"`
+
+	// DenyUnsafeProfiles is RuntimeConfig.DenyUnsafeProfiles.
+	DenyUnsafeProfiles []SecurityProfile `json:"denyUnsafeProfiles,omitempty" yaml:"denyUnsafeProfiles,omitempty"`
+
+	// Concurrency and queue limits, as in RuntimeConfig.
+	MaxConcurrentExecutions int           `json:"maxConcurrentExecutions,omitempty" yaml:"maxConcurrentExecutions,omitempty"`
+	MaxConcurrentPerTenant  int           `json:"maxConcurrentPerTenant,omitempty" yaml:"maxConcurrentPerTenant,omitempty"`
+	MaxQueuedExecutions     int           `json:"maxQueuedExecutions,omitempty" yaml:"maxQueuedExecutions,omitempty"`
+	MaxQueuedPerTenant      int           `json:"maxQueuedPerTenant,omitempty" yaml:"maxQueuedPerTenant,omitempty"`
+	QueueTimeout            time.Duration `json:"queueTimeout,omitempty" yaml:"queueTimeout,omitempty"`
+
+	// Profiles configures the backend of each security profile.
+	Profiles map[SecurityProfile]ProfileSpec `json:"profiles" yaml:"profiles"`
+}
+
+// ProfileSpec configures the backends of one security profile.
+type ProfileSpec struct {
+	// Backend serves the profile.
+	Backend BackendSpec `json:"backend" yaml:"backend"`
+
+	// Fallbacks are tried in order when Backend is unavailable.
+	Fallbacks []BackendSpec `json:"fallbacks,omitempty" yaml:"fallbacks,omitempty"`
+}
+
+// BackendSpec declares one backend. The BackendFactory registered for its
+// Kind builds it, interpreting the other fields.
+type BackendSpec struct {
+	// Kind selects the factory.
+	Kind BackendKind `json:"kind" yaml:"kind"`
+
+	// Image is the sandbox image reference, for backends that run one.
+	Image string `json:"image,omitempty" yaml:"image,omitempty"`
+
+	// PoolSize is the number of warm sandboxes to keep, for backends that
+	// pool them. Zero leaves pooling to the factory.
+	PoolSize int `json:"poolSize,omitempty" yaml:"poolSize,omitempty"`
+
+	// Options holds backend-specific settings; see DecodeOptions.
+	Options map[string]any `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// DecodeOptions decodes Options into v, a pointer to a struct, through
+// JSON, so v's json tags and case-insensitive field names apply.
+func (s BackendSpec) DecodeOptions(v any) error {
+	data, err := json.Marshal(s.Options)
+	if err != nil {
+		return fmt.Errorf("%w: %s options: %v", ErrInvalidConfig, s.Kind, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %s options: %v", ErrInvalidConfig, s.Kind, err)
+	}
+	return nil
+}
+
+// BackendFactory builds a backend from its BackendSpec. Factories supply
+// what a file cannot declare, such as SDK clients and loggers.
+type BackendFactory func(spec BackendSpec) (Backend, error)
+
+// LoadConfig reads the YAML or JSON file at path and builds the
+// RuntimeConfig it declares, calling the factory for each backend's Kind.
+// ${NAME} and ${NAME:-default} in string values are replaced with
+// environment variables first; see ParseConfig. Logger, Events, and the
+// other fields a file cannot express are left for the caller to set.
+func LoadConfig(path string, factories map[BackendKind]BackendFactory) (RuntimeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RuntimeConfig{}, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	file, err := ParseConfig(data, os.LookupEnv)
+	if err != nil {
+		return RuntimeConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg, err := file.RuntimeConfig(factories)
+	if err != nil {
+		return RuntimeConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ParseConfig parses a YAML or JSON configuration, replacing ${NAME} and
+// ${NAME:-default} in its values with what lookup returns; $$ is a
+// literal $. A substituted value is typed by its content, so ${POOL_SIZE}
+// can fill a number. Variables that are unset and have no default, and
+// fields ConfigFile does not define, fail with ErrInvalidConfig.
+func ParseConfig(data []byte, lookup func(string) (string, bool)) (ConfigFile, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return ConfigFile{}, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if doc.Kind == 0 {
+		return ConfigFile{}, fmt.Errorf("%w: empty", ErrInvalidConfig)
+	}
+	var missing []string
+	interpolate(&doc, lookup, &missing)
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return ConfigFile{}, fmt.Errorf("%w: unset environment variables: %s", ErrInvalidConfig, strings.Join(slices.Compact(missing), ", "))
+	}
+	expanded, err := yaml.Marshal(&doc)
+	if err != nil {
+		return ConfigFile{}, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	var file ConfigFile
+	dec := yaml.NewDecoder(bytes.NewReader(expanded))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return ConfigFile{}, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return file, nil
+}
+
+// RuntimeConfig validates the file and builds its RuntimeConfig, calling
+// the factory for each backend's Kind. A spec listed under several
+// profiles builds a backend for each.
+func (f ConfigFile) RuntimeConfig(factories map[BackendKind]BackendFactory) (RuntimeConfig, error) {
+	cfg := RuntimeConfig{
+		Backends:                make(map[SecurityProfile]Backend, len(f.Profiles)),
+		DenyUnsafeProfiles:      f.DenyUnsafeProfiles,
+		DefaultProfile:          f.DefaultProfile,
+		MaxConcurrentExecutions: f.MaxConcurrentExecutions,
+		MaxConcurrentPerTenant:  f.MaxConcurrentPerTenant,
+		MaxQueuedExecutions:     f.MaxQueuedExecutions,
+		MaxQueuedPerTenant:      f.MaxQueuedPerTenant,
+		QueueTimeout:            f.QueueTimeout,
+	}
+	if f.DefaultProfile != "" && !f.DefaultProfile.IsValid() {
+		return RuntimeConfig{}, fmt.Errorf("%w: unknown default profile %q", ErrInvalidConfig, f.DefaultProfile)
+	}
+	for _, p := range f.DenyUnsafeProfiles {
+		if !p.IsValid() {
+			return RuntimeConfig{}, fmt.Errorf("%w: unknown profile %q in denyUnsafeProfiles", ErrInvalidConfig, p)
+		}
+	}
+	if min(f.MaxConcurrentExecutions, f.MaxConcurrentPerTenant, f.MaxQueuedExecutions, f.MaxQueuedPerTenant) < 0 || f.QueueTimeout < 0 {
+		return RuntimeConfig{}, fmt.Errorf("%w: limits cannot be negative", ErrInvalidConfig)
+	}
+
+	profiles := make([]SecurityProfile, 0, len(f.Profiles))
+	for p := range f.Profiles {
+		profiles = append(profiles, p)
+	}
+	slices.Sort(profiles)
+	for _, profile := range profiles {
+		if !profile.IsValid() {
+			return RuntimeConfig{}, fmt.Errorf("%w: unknown profile %q", ErrInvalidConfig, profile)
+		}
+		spec := f.Profiles[profile]
+		backend, err := buildBackend(spec.Backend, factories)
+		if err != nil {
+			return RuntimeConfig{}, fmt.Errorf("profile %s: %w", profile, err)
+		}
+		cfg.Backends[profile] = backend
+		for i, fallback := range spec.Fallbacks {
+			backend, err := buildBackend(fallback, factories)
+			if err != nil {
+				return RuntimeConfig{}, fmt.Errorf("profile %s fallback %d: %w", profile, i, err)
+			}
+			if cfg.Fallbacks == nil {
+				cfg.Fallbacks = make(map[SecurityProfile][]Backend)
+			}
+			cfg.Fallbacks[profile] = append(cfg.Fallbacks[profile], backend)
+		}
+	}
+	return cfg, nil
+}
+
+func buildBackend(spec BackendSpec, factories map[BackendKind]BackendFactory) (Backend, error) {
+	if spec.Kind == "" {
+		return nil, fmt.Errorf("%w: backend kind is required", ErrInvalidConfig)
+	}
+	if spec.PoolSize < 0 {
+		return nil, fmt.Errorf("%w: %s poolSize cannot be negative", ErrInvalidConfig, spec.Kind)
+	}
+	factory, ok := factories[spec.Kind]
+	if !ok {
+		return nil, fmt.Errorf("%w: no factory for backend kind %q", ErrInvalidConfig, spec.Kind)
+	}
+	backend, err := factory(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%s backend: %w", spec.Kind, err)
+	}
+	if backend == nil {
+		return nil, fmt.Errorf("%w: %s factory returned no backend", ErrInvalidConfig, spec.Kind)
+	}
+	return backend, nil
+}
+
+// interpolate expands environment references in the scalar values under
+// n, recording the names of unset variables in missing. Expanded scalars
+// lose their tag and quoting so they resolve by content.
+func interpolate(n *yaml.Node, lookup func(string) (string, bool), missing *[]string) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "$") {
+			return
+		}
+		if value := expandEnv(n.Value, lookup, missing); value != n.Value {
+			n.Value, n.Tag, n.Style = value, "", 0
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			interpolate(n.Content[i], lookup, missing)
+		}
+	default:
+		for _, c := range n.Content {
+			interpolate(c, lookup, missing)
+		}
+	}
+}
+
+// expandEnv replaces ${NAME} and ${NAME:-default} in s, and $$ with $.
+// Other uses of $ are kept as written.
+func expandEnv(s string, lookup func(string) (string, bool), missing *[]string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 || i == len(s)-1 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$')
+			s = s[i+2:]
+			continue
+		case '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				b.WriteString(s[i:])
+				return b.String()
+			}
+			name, def, hasDefault := strings.Cut(s[i+2:i+end], ":-")
+			if value, ok := lookup(name); ok && (value != "" || !hasDefault) {
+				b.WriteString(value)
+			} else if hasDefault {
+				b.WriteString(def)
+			} else {
+				*missing = append(*missing, name)
+			}
+			s = s[i+end+1:]
+		default:
+			b.WriteByte('$')
+			s = s[i+1:]
+		}
+	}
+}
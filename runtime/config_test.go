@@ -0,0 +1,154 @@
+package runtime
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// specBackend is a backend built from a BackendSpec.
+type specBackend struct {
+	mockBackend
+	spec BackendSpec
+}
+
+func specFactories() map[BackendKind]BackendFactory {
+	factory := func(spec BackendSpec) (Backend, error) {
+		return &specBackend{mockBackend: mockBackend{kind: spec.Kind}, spec: spec}, nil
+	}
+	return map[BackendKind]BackendFactory{BackendDocker: factory, BackendGVisor: factory, BackendUnsafeHost: factory}
+}
+
+const testConfigYAML = `
+defaultProfile: standard
+denyUnsafeProfiles: [standard, hardened]
+maxConcurrentExecutions: 8
+queueTimeout: 30s
+profiles:
+  standard:
+    backend:
+      kind: docker
+      image: ${SANDBOX_IMAGE}
+      poolSize: ${POOL_SIZE:-2}
+      options:
+        seccompPath: /etc/seccomp.json
+    fallbacks:
+      - kind: gvisor
+        image: "${SANDBOX_IMAGE}-gvisor"
+  dev:
+    backend:
+      kind: unsafe_host
+`
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("SANDBOX_IMAGE", "ghcr.io/acme/sandbox:1.2")
+	path := filepath.Join(t.TempDir(), "runtime.yaml")
+	if err := os.WriteFile(path, []byte(testConfigYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path, specFactories())
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.DefaultProfile != ProfileStandard || cfg.MaxConcurrentExecutions != 8 || cfg.QueueTimeout != 30*time.Second || len(cfg.DenyUnsafeProfiles) != 2 {
+		t.Errorf("cfg = %+v, want the file's runtime settings", cfg)
+	}
+	standard, ok := cfg.Backends[ProfileStandard].(*specBackend)
+	if !ok {
+		t.Fatalf("standard backend = %T, want the factory's", cfg.Backends[ProfileStandard])
+	}
+	if standard.spec.Image != "ghcr.io/acme/sandbox:1.2" || standard.spec.PoolSize != 2 {
+		t.Errorf("standard spec = %+v, want the interpolated image and default pool size", standard.spec)
+	}
+	var opts struct{ SeccompPath string }
+	if err := standard.spec.DecodeOptions(&opts); err != nil || opts.SeccompPath != "/etc/seccomp.json" {
+		t.Errorf("DecodeOptions() = %+v, %v; want the seccomp path", opts, err)
+	}
+	fallbacks := cfg.Fallbacks[ProfileStandard]
+	if len(fallbacks) != 1 || fallbacks[0].(*specBackend).spec.Image != "ghcr.io/acme/sandbox:1.2-gvisor" {
+		t.Errorf("fallbacks = %v, want the gvisor backend", fallbacks)
+	}
+	if cfg.Backends[ProfileDev].Kind() != BackendUnsafeHost {
+		t.Errorf("dev backend = %v, want unsafe_host", cfg.Backends[ProfileDev].Kind())
+	}
+}
+
+func TestParseConfigJSON(t *testing.T) {
+	data := []byte("{\n\t\"maxQueuedExecutions\": \"${QUEUE}\",\n\t\"profiles\": {\"dev\": {\"backend\": {\"kind\": \"unsafe_host\"}}}\n}")
+	lookup := func(name string) (string, bool) { return map[string]string{"QUEUE": "16"}[name], name == "QUEUE" }
+
+	file, err := ParseConfig(data, lookup)
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+	if file.MaxQueuedExecutions != 16 || file.Profiles[ProfileDev].Backend.Kind != BackendUnsafeHost {
+		t.Errorf("ParseConfig() = %+v, want the queue size and dev backend", file)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	lookup := func(string) (string, bool) { return "", false }
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"syntax", "profiles: ["},
+		{"unset variable", "defaultProfile: ${PROFILE}"},
+		{"unknown field", "maxConcurrency: 4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseConfig([]byte(tt.data), lookup); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("ParseConfig() error = %v, want %v", err, ErrInvalidConfig)
+			}
+		})
+	}
+}
+
+func TestConfigFileRuntimeConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file ConfigFile
+	}{
+		{"unknown profile", ConfigFile{Profiles: map[SecurityProfile]ProfileSpec{"fast": {Backend: BackendSpec{Kind: BackendDocker}}}}},
+		{"unknown default", ConfigFile{DefaultProfile: "fast"}},
+		{"missing kind", ConfigFile{Profiles: map[SecurityProfile]ProfileSpec{ProfileDev: {}}}},
+		{"no factory", ConfigFile{Profiles: map[SecurityProfile]ProfileSpec{ProfileHardened: {Backend: BackendSpec{Kind: BackendKata}}}}},
+		{"negative pool", ConfigFile{Profiles: map[SecurityProfile]ProfileSpec{ProfileDev: {Backend: BackendSpec{Kind: BackendDocker, PoolSize: -1}}}}},
+		{"negative limit", ConfigFile{MaxQueuedPerTenant: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.file.RuntimeConfig(specFactories()); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("RuntimeConfig() error = %v, want %v", err, ErrInvalidConfig)
+			}
+		})
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		v, ok := map[string]string{"A": "1", "EMPTY": ""}[name]
+		return v, ok
+	}
+	tests := []struct{ in, want string }{
+		{"${A}", "1"},
+		{"x-${A}-y", "x-1-y"},
+		{"${B:-2}", "2"},
+		{"${EMPTY:-3}", "3"},
+		{"${EMPTY}", ""},
+		{"$$A", "$A"},
+		{"cost $5", "cost $5"},
+		{"${A", "${A"},
+	}
+	for _, tt := range tests {
+		var missing []string
+		if got := expandEnv(tt.in, lookup, &missing); got != tt.want || len(missing) != 0 {
+			t.Errorf("expandEnv(%q) = %q (missing %v), want %q", tt.in, got, missing, tt.want)
+		}
+	}
+}
@@ -34,14 +34,19 @@
 //
 //   - BackendUnsafeHost: Direct host execution (dev only, no isolation)
 //   - BackendDocker: Docker containers with cgroups and seccomp
+//   - BackendPodman: Podman containers, rootless-friendly, via the libpod socket
 //   - BackendContainerd: Containerd for infrastructure-native deployments
+//   - BackendNspawn: Ephemeral systemd-nspawn machines on bare-metal Linux
+//   - BackendWindows: Job Objects or Windows Sandbox on Windows hosts
 //   - BackendKubernetes: Short-lived pods/jobs with scheduling
 //   - BackendGVisor: Strong isolation via gVisor/runsc
 //   - BackendKata: VM-level isolation via Kata Containers
 //   - BackendFirecracker: MicroVM isolation (strongest)
 //   - BackendWASM: WebAssembly in-process isolation
+//   - BackendIsolate: V8 isolates for JavaScript/TypeScript
 //   - BackendTemporal: Workflow orchestration (composes with sandbox backends)
 //   - BackendRemote: Generic remote execution service
+//   - BackendServerless: Cloud functions (AWS Lambda, GCP, Azure) running the agent
 //
 // # Security Requirements
 //
@@ -56,5 +61,82 @@
 //  7. Treat tool schemas/docs/annotations as untrusted input
 //
 // Backends that cannot enforce a given limit must report that clearly
-// via the LimitsEnforced field in ExecuteResult.
+// via the LimitsEnforced field in ExecuteResult. Measured resource
+// consumption is reported in its Usage field.
+//
+// Each profile maps to a backend and, optionally, a list of fallbacks.
+// DefaultRuntime tries them in order while a backend fails with an error
+// wrapping ErrRuntimeUnavailable, and records the skipped ones in
+// BackendInfo.Details.
+//
+// DefaultRuntime can cap concurrent executions globally and per tenant.
+// Executions over a cap queue per tenant and are dispatched fair-share;
+// full queues and queue timeouts fail with ErrRuntimeBusy.
+//
+// Backends that implement CapabilityReporter advertise what they support
+// (languages, workspaces, file staging, GPUs, memory). DefaultRuntime checks
+// requests against them before dispatch and skips backends that cannot run
+// a request.
+//
+// ExecuteRequest.Egress replaces the profile's network mode with an
+// EgressPolicy, which denies all egress, allows listed CIDRs and domains,
+// or allows only an HTTP proxy. Backends advertise the modes
+// they enforce in Capabilities.EgressModes and reject the others.
+//
+// Container and VM backends label the resources they create with
+// LabelExecutionID. A Janitor lists them through Reapers and removes the
+// ones older than its TTL, so containers, pods, and temporary directories
+// a crash left behind do not accumulate; call Cleanup to reap once or Run
+// to reap periodically.
+//
+// RuntimeConfig.Events publishes structured events (execution started and
+// finished, backend unhealthy, backend replaced, pool resized, limit
+// breached) to the channels of its subscribers, for alerting and
+// dashboards.
+//
+// ExecuteRequest.GraceTimeout asks backends reporting
+// Capabilities.GracefulStop to stop code at its Timeout (with SIGTERM) and
+// kill it only after the grace period, so it can flush __out and its
+// output; the result carries them along with an ErrTimeout error.
+//
+// ExecuteRequest.MaxOutputBytes caps the stdout and stderr container
+// backends keep; output past it is discarded, the kept part ends with
+// OutputTruncatedMarker, and ExecuteResult.Truncated is set.
+//
+// LoadConfig builds a RuntimeConfig from a YAML or JSON file, expanding
+// environment variables and building each declared backend with the
+// BackendFactory registered for its kind.
+//
+// DefaultRuntime.ReplaceBackend swaps a profile's backend while the
+// runtime serves requests: new executions use the replacement at once,
+// and it returns the previous backend once the executions already using
+// it have finished, for rolling upgrades of sandbox images or moving a
+// profile from one backend kind to another.
+//
+// Benchmark measures a backend's cold and warm starts, execution
+// overhead, and streaming latency, returning a BenchmarkReport for
+// capacity planning.
+//
+// Each execution has an ExecutionID, from the request, from the context
+// (see WithExecutionID), or generated. DefaultRuntime passes it to the
+// backend on the request and the context, logs it, and returns it in
+// ExecuteResult; backends label resources with it, and gateways record it
+// on tool calls, so one execution can be followed across them.
+//
+// NewAutoRuntime builds a runtime from candidate backends by probing which
+// ones this host can run (sockets, binaries, or caller-supplied probes) and
+// registering the best-ranked available backend for each profile. The
+// choice is reported in BackendInfo.Details.
+//
+// An ExecuteRequest may ask for a Workspace: a size-limited scratch
+// directory that backends create empty, expose through WorkspaceEnv, and
+// remove when the execution ends. Files listed in ExecuteRequest.Files
+// are staged into it before the code runs, and files the code writes to
+// its output directory (OutputEnv) are returned as ExecuteResult.Artifacts.
+// Backends pass both to their runners as a Staging.
+//
+// An ExecuteRequest may also carry a LogStreamer, which receives output
+// line by line while the code runs. The docker backend streams through a
+// StreamRunner client; containerd, gvisor, and kubernetes pass it to their
+// runners.
 package runtime
@@ -0,0 +1,201 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// EgressMode selects how an EgressPolicy restricts outbound traffic.
+type EgressMode string
+
+const (
+	// EgressDenyAll gives the execution no network at all.
+	EgressDenyAll EgressMode = "deny-all"
+
+	// EgressAllowlist permits connections only to EgressPolicy.AllowCIDRs
+	// and the addresses of EgressPolicy.AllowDomains.
+	EgressAllowlist EgressMode = "allowlist"
+
+	// EgressProxyOnly permits connections only to EgressPolicy.Proxy,
+	// which the code is pointed at through the standard proxy variables.
+	EgressProxyOnly EgressMode = "proxy-only"
+)
+
+// EgressPolicy restricts an execution's outbound network traffic. It
+// replaces the network mode the security profile would otherwise pick.
+type EgressPolicy struct {
+	// Mode selects the restriction.
+	Mode EgressMode
+
+	// AllowCIDRs lists the destination networks an EgressAllowlist
+	// permits, such as "10.1.0.0/16"; a bare address permits that host.
+	AllowCIDRs []string
+
+	// AllowDomains lists the host names an EgressAllowlist permits.
+	// Backends that filter by address resolve them when the execution
+	// starts, so addresses that change mid-run are not followed.
+	AllowDomains []string
+
+	// Proxy is the URL of the HTTP proxy an EgressProxyOnly permits,
+	// such as "http://egress-proxy.internal:3128".
+	Proxy string
+}
+
+// Validate checks that the policy is well formed for its mode.
+func (p EgressPolicy) Validate() error {
+	switch p.Mode {
+	case EgressDenyAll:
+		if len(p.AllowCIDRs) > 0 || len(p.AllowDomains) > 0 || p.Proxy != "" {
+			return fmt.Errorf("%w: %s allows no destinations", ErrInvalidEgress, p.Mode)
+		}
+	case EgressAllowlist:
+		if p.Proxy != "" {
+			return fmt.Errorf("%w: %s does not take a proxy", ErrInvalidEgress, p.Mode)
+		}
+		if len(p.AllowCIDRs) == 0 && len(p.AllowDomains) == 0 {
+			return fmt.Errorf("%w: %s needs AllowCIDRs or AllowDomains", ErrInvalidEgress, p.Mode)
+		}
+		for _, cidr := range p.AllowCIDRs {
+			if _, err := parsePrefix(cidr); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidEgress, err)
+			}
+		}
+		for _, domain := range p.AllowDomains {
+			if domain == "" || strings.ContainsAny(domain, "*/: ") {
+				return fmt.Errorf("%w: domain %q must be a plain host name", ErrInvalidEgress, domain)
+			}
+		}
+	case EgressProxyOnly:
+		if len(p.AllowCIDRs) > 0 || len(p.AllowDomains) > 0 {
+			return fmt.Errorf("%w: %s allows only the proxy", ErrInvalidEgress, p.Mode)
+		}
+		if _, err := p.proxyURL(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidEgress, p.Mode)
+	}
+	return nil
+}
+
+// Resolver looks up the addresses of a host name. *net.Resolver
+// implements it.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// Destinations returns the networks the policy permits: AllowCIDRs and the
+// resolved addresses of AllowDomains for EgressAllowlist, or the proxy's
+// addresses for EgressProxyOnly. EgressDenyAll permits none. A nil
+// resolver uses net.DefaultResolver.
+func (p EgressPolicy) Destinations(ctx context.Context, resolver Resolver) ([]netip.Prefix, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	hosts := p.AllowDomains
+	if p.Mode == EgressProxyOnly {
+		u, _ := p.proxyURL()
+		hosts = []string{u.Hostname()}
+	}
+
+	var prefixes []netip.Prefix
+	for _, cidr := range p.AllowCIDRs {
+		prefix, _ := parsePrefix(cidr)
+		prefixes = append(prefixes, prefix)
+	}
+	for _, host := range hosts {
+		if addr, err := netip.ParseAddr(host); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return nil, fmt.Errorf("%w: resolve %s: %v", ErrInvalidEgress, host, err)
+		}
+		for _, addr := range addrs {
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	slices.SortFunc(prefixes, func(a, b netip.Prefix) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return slices.Compact(prefixes), nil
+}
+
+// ProxyEnv returns the environment variables that point HTTP clients at
+// the proxy of an EgressProxyOnly policy, or nil for other modes.
+func (p EgressPolicy) ProxyEnv() map[string]string {
+	if p.Mode != EgressProxyOnly || p.Proxy == "" {
+		return nil
+	}
+	return map[string]string{
+		"HTTP_PROXY":  p.Proxy,
+		"HTTPS_PROXY": p.Proxy,
+		"http_proxy":  p.Proxy,
+		"https_proxy": p.Proxy,
+		"NO_PROXY":    "localhost,127.0.0.1,::1",
+		"no_proxy":    "localhost,127.0.0.1,::1",
+	}
+}
+
+func (p EgressPolicy) proxyURL() (*url.URL, error) {
+	u, err := url.Parse(p.Proxy)
+	if err != nil || u.Hostname() == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("%w: proxy %q must be an http or https URL", ErrInvalidEgress, p.Proxy)
+	}
+	return u, nil
+}
+
+func parsePrefix(cidr string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(cidr); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("CIDR %q: %v", cidr, err)
+	}
+	return prefix.Masked(), nil
+}
+
+// EgressEnforcer is implemented by backend clients that enforce egress
+// modes beyond EgressDenyAll, which backends provide themselves by
+// disabling the network.
+//
+// Contract:
+//   - Concurrency: implementations must be safe for concurrent use.
+//   - Ownership: the returned slice is caller-owned.
+type EgressEnforcer interface {
+	EgressModes() []EgressMode
+}
+
+// EnforcedEgressModes returns EgressDenyAll followed by the modes client
+// enforces when it is an EgressEnforcer.
+func EnforcedEgressModes(client any) []EgressMode {
+	modes := []EgressMode{EgressDenyAll}
+	if e, ok := client.(EgressEnforcer); ok {
+		for _, m := range e.EgressModes() {
+			if !slices.Contains(modes, m) {
+				modes = append(modes, m)
+			}
+		}
+	}
+	return modes
+}
+
+// CheckEgress returns ErrUnsupportedCapability when policy is set and its
+// mode is not among modes.
+func CheckEgress(policy *EgressPolicy, modes []EgressMode) error {
+	if policy == nil || slices.Contains(modes, policy.Mode) {
+		return nil
+	}
+	return fmt.Errorf("%w: egress mode %q", ErrUnsupportedCapability, policy.Mode)
+}
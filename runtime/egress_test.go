@@ -0,0 +1,124 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestEgressPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  EgressPolicy
+		wantErr bool
+	}{
+		{name: "deny all", policy: EgressPolicy{Mode: EgressDenyAll}},
+		{name: "deny all with destinations", policy: EgressPolicy{Mode: EgressDenyAll, AllowCIDRs: []string{"10.0.0.0/8"}}, wantErr: true},
+		{name: "allowlist", policy: EgressPolicy{Mode: EgressAllowlist, AllowCIDRs: []string{"10.0.0.0/8", "192.0.2.1"}, AllowDomains: []string{"pypi.org"}}},
+		{name: "empty allowlist", policy: EgressPolicy{Mode: EgressAllowlist}, wantErr: true},
+		{name: "bad CIDR", policy: EgressPolicy{Mode: EgressAllowlist, AllowCIDRs: []string{"10.0.0.0/33"}}, wantErr: true},
+		{name: "wildcard domain", policy: EgressPolicy{Mode: EgressAllowlist, AllowDomains: []string{"*.example.com"}}, wantErr: true},
+		{name: "proxy", policy: EgressPolicy{Mode: EgressProxyOnly, Proxy: "http://proxy.internal:3128"}},
+		{name: "proxy without URL", policy: EgressPolicy{Mode: EgressProxyOnly}, wantErr: true},
+		{name: "proxy with CIDRs", policy: EgressPolicy{Mode: EgressProxyOnly, Proxy: "http://p:1", AllowCIDRs: []string{"10.0.0.0/8"}}, wantErr: true},
+		{name: "unknown mode", policy: EgressPolicy{Mode: "open"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.wantErr != (err != nil) || (err != nil && !errors.Is(err, ErrInvalidEgress)) {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	req := ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}, Egress: &EgressPolicy{Mode: EgressAllowlist}}
+	if err := req.Validate(); !errors.Is(err, ErrInvalidEgress) {
+		t.Errorf("ExecuteRequest.Validate() error = %v, want %v", err, ErrInvalidEgress)
+	}
+}
+
+// fakeResolver resolves names from a fixed table.
+type fakeResolver map[string][]netip.Addr
+
+func (r fakeResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return addrs, nil
+}
+
+func TestEgressPolicyDestinations(t *testing.T) {
+	resolver := fakeResolver{
+		"pypi.org":       {netip.MustParseAddr("151.101.0.223"), netip.MustParseAddr("2a04:4e42::223")},
+		"proxy.internal": {netip.MustParseAddr("::ffff:10.0.0.5")},
+	}
+	ctx := context.Background()
+
+	got, err := EgressPolicy{
+		Mode:         EgressAllowlist,
+		AllowCIDRs:   []string{"10.1.2.3/16", "192.0.2.1", "10.1.0.0/16"},
+		AllowDomains: []string{"pypi.org"},
+	}.Destinations(ctx, resolver)
+	if err != nil {
+		t.Fatalf("Destinations() error = %v", err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.1.0.0/16"),
+		netip.MustParsePrefix("151.101.0.223/32"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+		netip.MustParsePrefix("2a04:4e42::223/128"),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Destinations() = %v, want %v", got, want)
+	}
+
+	got, err = EgressPolicy{Mode: EgressProxyOnly, Proxy: "http://proxy.internal:3128"}.Destinations(ctx, resolver)
+	if err != nil || !slices.Equal(got, []netip.Prefix{netip.MustParsePrefix("10.0.0.5/32")}) {
+		t.Errorf("Destinations(proxy) = %v, %v; want the proxy address", got, err)
+	}
+
+	if got, err := (EgressPolicy{Mode: EgressDenyAll}).Destinations(ctx, resolver); err != nil || len(got) != 0 {
+		t.Errorf("Destinations(deny-all) = %v, %v; want none", got, err)
+	}
+
+	_, err = EgressPolicy{Mode: EgressAllowlist, AllowDomains: []string{"unknown.invalid"}}.Destinations(ctx, resolver)
+	if !errors.Is(err, ErrInvalidEgress) {
+		t.Errorf("Destinations(unresolvable) error = %v, want %v", err, ErrInvalidEgress)
+	}
+}
+
+func TestEgressPolicyProxyEnv(t *testing.T) {
+	env := EgressPolicy{Mode: EgressProxyOnly, Proxy: "http://p:3128"}.ProxyEnv()
+	if env["HTTPS_PROXY"] != "http://p:3128" || env["http_proxy"] != "http://p:3128" || env["NO_PROXY"] == "" {
+		t.Errorf("ProxyEnv() = %v", env)
+	}
+	if env := (EgressPolicy{Mode: EgressAllowlist}).ProxyEnv(); env != nil {
+		t.Errorf("ProxyEnv(allowlist) = %v, want nil", env)
+	}
+}
+
+// egressClient enforces a fixed set of modes.
+type egressClient []EgressMode
+
+func (c egressClient) EgressModes() []EgressMode { return c }
+
+func TestEnforcedEgressModes(t *testing.T) {
+	if got := EnforcedEgressModes(nil); !slices.Equal(got, []EgressMode{EgressDenyAll}) {
+		t.Errorf("EnforcedEgressModes(nil) = %v, want deny-all only", got)
+	}
+	got := EnforcedEgressModes(egressClient{EgressAllowlist, EgressDenyAll})
+	if !slices.Equal(got, []EgressMode{EgressDenyAll, EgressAllowlist}) {
+		t.Errorf("EnforcedEgressModes() = %v", got)
+	}
+
+	if err := CheckEgress(nil, nil); err != nil {
+		t.Errorf("CheckEgress(nil) error = %v", err)
+	}
+	if err := CheckEgress(&EgressPolicy{Mode: EgressProxyOnly}, got); !errors.Is(err, ErrUnsupportedCapability) {
+		t.Errorf("CheckEgress(proxy-only) error = %v, want %v", err, ErrUnsupportedCapability)
+	}
+}
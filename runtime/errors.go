@@ -30,6 +30,32 @@ var (
 
 	// ErrInvalidLimits is returned when Limits validation fails.
 	ErrInvalidLimits = errors.New("invalid limits")
+
+	// ErrInvalidWorkspace is returned when Workspace validation fails.
+	ErrInvalidWorkspace = errors.New("invalid workspace")
+
+	// ErrInvalidFile is returned when a staged file is invalid or unreadable.
+	ErrInvalidFile = errors.New("invalid file")
+
+	// ErrInvalidEgress is returned when an EgressPolicy is malformed or
+	// its domains cannot be resolved.
+	ErrInvalidEgress = errors.New("invalid egress policy")
+
+	// ErrUnsupportedCapability is returned when a request needs a feature
+	// the selected backend does not advertise in its Capabilities.
+	ErrUnsupportedCapability = errors.New("unsupported capability")
+
+	// ErrRuntimeBusy is returned when an execution cannot be queued under
+	// the runtime's concurrency caps, or waited too long for a slot.
+	ErrRuntimeBusy = errors.New("runtime busy")
+
+	// ErrProbeFailed is returned by a Probe when the resource a backend
+	// needs is missing or unreachable.
+	ErrProbeFailed = errors.New("backend probe failed")
+
+	// ErrInvalidConfig is returned when a configuration file cannot be
+	// read, parsed, or turned into a RuntimeConfig.
+	ErrInvalidConfig = errors.New("invalid runtime config")
 )
 
 // RuntimeError wraps an error with execution context information.
@@ -0,0 +1,189 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType names a kind of runtime event.
+type EventType string
+
+const (
+	// EventExecutionStarted is published when a backend starts running a
+	// request.
+	EventExecutionStarted EventType = "execution.started"
+
+	// EventExecutionFinished is published when a backend returns, with the
+	// execution's Duration, Usage, and error.
+	EventExecutionFinished EventType = "execution.finished"
+
+	// EventBackendUnhealthy is published when a backend fails with an
+	// availability error, such as an unreachable daemon.
+	EventBackendUnhealthy EventType = "backend.unhealthy"
+
+	// EventBackendReplaced is published when ReplaceBackend registers a
+	// new backend for a profile, before the previous one drains.
+	EventBackendReplaced EventType = "backend.replaced"
+
+	// EventPoolResized is published when a pool of warm sandboxes grows or
+	// shrinks, with its new PoolSize.
+	EventPoolResized EventType = "pool.resized"
+
+	// EventLimitBreached is published when an execution is refused or
+	// stopped by a limit, named in Limit.
+	EventLimitBreached EventType = "limit.breached"
+)
+
+// Limits reported in Event.Limit.
+const (
+	// LimitQueue is a full execution queue or an expired queue wait.
+	LimitQueue = "queue"
+
+	// LimitTimeout is the execution timeout.
+	LimitTimeout = "timeout"
+
+	// LimitResource is a memory, CPU, disk, or output limit.
+	LimitResource = "resource"
+)
+
+// DefaultEventBuffer is the subscription buffer when Subscribe is given
+// none.
+const DefaultEventBuffer = 64
+
+// Event is a structured record of something the runtime or a backend did.
+// Fields that do not apply to its Type are zero.
+type Event struct {
+	// Type is the kind of event.
+	Type EventType
+
+	// Time is when the event was published.
+	Time time.Time
+
+	// Profile is the security profile of the execution.
+	Profile SecurityProfile
+
+	// Backend is the backend involved.
+	Backend BackendKind
+
+	// Tenant is the tenant the execution is accounted to.
+	Tenant string
+
+	// ExecutionID identifies the execution, for execution events.
+	ExecutionID string
+
+	// Duration is the execution's wall time, for EventExecutionFinished.
+	Duration time.Duration
+
+	// Usage is the execution's resource usage, for EventExecutionFinished.
+	Usage ResourceUsage
+
+	// Limit names the limit breached, for EventLimitBreached.
+	Limit string
+
+	// PoolSize is the pool's new size, for EventPoolResized.
+	PoolSize int
+
+	// Err is the error that caused the event, if any.
+	Err error
+
+	// Details holds event-specific data, such as a pool's name.
+	Details map[string]any
+}
+
+// Events is a publish/subscribe bus for runtime events. Operators
+// subscribe to it to drive alerting and dashboards; DefaultRuntime and
+// pools publish to it.
+//
+// Contract:
+//   - Concurrency: safe for concurrent use.
+//   - Delivery: Publish never blocks. Events for a subscriber whose buffer
+//     is full are dropped and counted in Dropped.
+//   - A nil *Events discards what is published to it.
+type Events struct {
+	mu      sync.RWMutex
+	subs    map[*subscription]struct{}
+	dropped atomic.Uint64
+	now     func() time.Time
+}
+
+type subscription struct {
+	ch    chan Event
+	types []EventType
+}
+
+// NewEvents creates an Events bus with no subscribers.
+func NewEvents() *Events {
+	return &Events{subs: make(map[*subscription]struct{}), now: time.Now}
+}
+
+// Subscribe returns a channel receiving the published events of types, or
+// of every type when types is empty, and a func that ends the
+// subscription and closes the channel. buffer sizes the channel.
+// Default buffer: DefaultEventBuffer
+func (e *Events) Subscribe(buffer int, types ...EventType) (<-chan Event, func()) {
+	if buffer <= 0 {
+		buffer = DefaultEventBuffer
+	}
+	sub := &subscription{ch: make(chan Event, buffer), types: slices.Clone(types)}
+	e.mu.Lock()
+	e.subs[sub] = struct{}{}
+	e.mu.Unlock()
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			e.mu.Lock()
+			delete(e.subs, sub)
+			e.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+}
+
+// Publish delivers ev to every subscriber of its type, stamping Time when
+// it is zero.
+func (e *Events) Publish(ev Event) {
+	if e == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = e.now()
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for sub := range e.subs {
+		if len(sub.types) > 0 && !slices.Contains(sub.types, ev.Type) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			e.dropped.Add(1)
+		}
+	}
+}
+
+// Dropped reports how many events were dropped because a subscriber fell
+// behind.
+func (e *Events) Dropped() uint64 {
+	if e == nil {
+		return 0
+	}
+	return e.dropped.Load()
+}
+
+// breachedLimit names the limit err reports, or "" when it reports none.
+func breachedLimit(err error) string {
+	switch {
+	case errors.Is(err, ErrRuntimeBusy):
+		return LimitQueue
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return LimitTimeout
+	case errors.Is(err, ErrResourceLimit):
+		return LimitResource
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestEventsSubscribe(t *testing.T) {
+	events := NewEvents()
+	all, cancelAll := events.Subscribe(1)
+	limits, cancelLimits := events.Subscribe(4, EventLimitBreached)
+
+	events.Publish(Event{Type: EventExecutionStarted})
+	events.Publish(Event{Type: EventLimitBreached, Limit: LimitQueue})
+
+	if ev := <-all; ev.Type != EventExecutionStarted || ev.Time.IsZero() {
+		t.Errorf("first event = %+v, want a timestamped EventExecutionStarted", ev)
+	}
+	if ev := <-limits; ev.Type != EventLimitBreached || ev.Limit != LimitQueue {
+		t.Errorf("filtered event = %+v, want only EventLimitBreached", ev)
+	}
+	if n := events.Dropped(); n != 1 {
+		t.Errorf("Dropped() = %d, want the event the full subscriber missed", n)
+	}
+
+	cancelAll()
+	cancelAll()
+	if _, ok := <-all; ok {
+		t.Error("channel open after cancel")
+	}
+	cancelLimits()
+
+	var nilEvents *Events
+	nilEvents.Publish(Event{Type: EventExecutionStarted})
+	if nilEvents.Dropped() != 0 {
+		t.Error("nil Events dropped events")
+	}
+}
+
+func TestDefaultRuntimeEvents(t *testing.T) {
+	firecracker := &mockBackend{kind: BackendFirecracker, executeErr: fmt.Errorf("daemon down: %w", ErrRuntimeUnavailable)}
+	kata := &mockBackend{kind: BackendKata, result: ExecuteResult{Duration: time.Second}}
+	events := NewEvents()
+	sub, cancel := events.Subscribe(16)
+	defer cancel()
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Fallbacks:      map[SecurityProfile][]Backend{ProfileHardened: {firecracker, kata}},
+		DefaultProfile: ProfileHardened,
+		Events:         events,
+	})
+	req := ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}, Metadata: map[string]any{TenantMetadataKey: "t"}}
+	if _, err := rt.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	kata.executeErr = fmt.Errorf("oom: %w", ErrResourceLimit)
+	if _, err := rt.Execute(context.Background(), req); !errors.Is(err, ErrResourceLimit) {
+		t.Fatalf("Execute() error = %v, want %v", err, ErrResourceLimit)
+	}
+
+	want := []struct {
+		typ     EventType
+		backend BackendKind
+	}{
+		{EventExecutionStarted, BackendFirecracker},
+		{EventExecutionFinished, BackendFirecracker},
+		{EventBackendUnhealthy, BackendFirecracker},
+		{EventExecutionStarted, BackendKata},
+		{EventExecutionFinished, BackendKata},
+		{EventExecutionStarted, BackendFirecracker},
+		{EventExecutionFinished, BackendFirecracker},
+		{EventBackendUnhealthy, BackendFirecracker},
+		{EventExecutionStarted, BackendKata},
+		{EventExecutionFinished, BackendKata},
+		{EventLimitBreached, BackendKata},
+	}
+	for i, w := range want {
+		ev := <-sub
+		if ev.Type != w.typ || ev.Backend != w.backend || ev.Profile != ProfileHardened || ev.Tenant != "t" {
+			t.Fatalf("event %d = %+v, want %s from %s", i, ev, w.typ, w.backend)
+		}
+		switch {
+		case i == 4 && (ev.Duration != time.Second || ev.Err != nil):
+			t.Errorf("finished event = %+v, want the result's duration", ev)
+		case i == 10 && ev.Limit != LimitResource:
+			t.Errorf("Limit = %q, want %q", ev.Limit, LimitResource)
+		}
+	}
+}
+
+func TestDefaultRuntimeEventsQueueLimit(t *testing.T) {
+	backend := &blockingBackend{started: make(chan struct{}, 1), release: make(chan struct{})}
+	events := NewEvents()
+	breached, cancel := events.Subscribe(1, EventLimitBreached)
+	defer cancel()
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:                map[SecurityProfile]Backend{ProfileStandard: backend},
+		DefaultProfile:          ProfileStandard,
+		MaxConcurrentExecutions: 1,
+		QueueTimeout:            time.Millisecond,
+		Events:                  events,
+	})
+	req := ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = rt.Execute(context.Background(), req)
+	}()
+	<-backend.started
+	if _, err := rt.Execute(context.Background(), req); !errors.Is(err, ErrRuntimeBusy) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrRuntimeBusy)
+	}
+	close(backend.release)
+	<-done
+	if ev := <-breached; ev.Limit != LimitQueue || !errors.Is(ev.Err, ErrRuntimeBusy) {
+		t.Errorf("event = %+v, want a queue limit breach", ev)
+	}
+}
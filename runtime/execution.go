@@ -0,0 +1,30 @@
+package runtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// NewExecutionID returns a random execution ID, usable as a label value on
+// every backend.
+func NewExecutionID() string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return "exec-" + hex.EncodeToString(b[:])
+}
+
+type executionIDKey struct{}
+
+// WithExecutionID returns a context carrying the execution ID id.
+// DefaultRuntime sets it for the duration of an execution, so gateways
+// can attribute tool calls; callers can set it first to choose the ID.
+func WithExecutionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, executionIDKey{}, id)
+}
+
+// ExecutionIDFromContext returns the execution ID ctx carries, or "".
+func ExecutionIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(executionIDKey{}).(string)
+	return id
+}
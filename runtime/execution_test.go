@@ -0,0 +1,75 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// idBackend records the execution ID of each request and its context.
+type idBackend struct {
+	reqIDs, ctxIDs []string
+}
+
+func (b *idBackend) Kind() BackendKind { return BackendUnsafeHost }
+
+func (b *idBackend) Execute(ctx context.Context, req ExecuteRequest) (ExecuteResult, error) {
+	b.reqIDs = append(b.reqIDs, req.ExecutionID)
+	b.ctxIDs = append(b.ctxIDs, ExecutionIDFromContext(ctx))
+	return ExecuteResult{}, nil
+}
+
+func TestNewExecutionID(t *testing.T) {
+	a, b := NewExecutionID(), NewExecutionID()
+	if a == b || !strings.HasPrefix(a, "exec-") || len(a) != 29 {
+		t.Errorf("NewExecutionID() = %q, %q; want distinct exec- IDs", a, b)
+	}
+}
+
+func TestExecuteRequestEnsureExecutionID(t *testing.T) {
+	var req ExecuteRequest
+	id := req.EnsureExecutionID()
+	if id == "" || req.ExecutionID != id {
+		t.Fatalf("EnsureExecutionID() = %q, ExecutionID = %q", id, req.ExecutionID)
+	}
+	if again := req.EnsureExecutionID(); again != id {
+		t.Errorf("EnsureExecutionID() = %q on second call, want %q", again, id)
+	}
+}
+
+func TestDefaultRuntimeExecutionID(t *testing.T) {
+	backend := &idBackend{}
+	events := NewEvents()
+	sub, cancel := events.Subscribe(8, EventExecutionStarted)
+	defer cancel()
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileDev: backend},
+		DefaultProfile: ProfileDev,
+		Events:         events,
+	})
+	req := ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}}
+
+	result, err := rt.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	id := result.ExecutionID
+	if !strings.HasPrefix(id, "exec-") {
+		t.Fatalf("ExecutionID = %q, want a generated ID", id)
+	}
+	if backend.reqIDs[0] != id || backend.ctxIDs[0] != id {
+		t.Errorf("backend saw request ID %q and context ID %q, want %q", backend.reqIDs[0], backend.ctxIDs[0], id)
+	}
+	if ev := <-sub; ev.ExecutionID != id {
+		t.Errorf("event ExecutionID = %q, want %q", ev.ExecutionID, id)
+	}
+
+	ctx := WithExecutionID(context.Background(), "exec-from-ctx")
+	if result, _ := rt.Execute(ctx, req); result.ExecutionID != "exec-from-ctx" {
+		t.Errorf("ExecutionID = %q, want the context's", result.ExecutionID)
+	}
+	req.ExecutionID = "exec-from-req"
+	if result, _ := rt.Execute(ctx, req); result.ExecutionID != "exec-from-req" || backend.ctxIDs[2] != "exec-from-req" {
+		t.Errorf("ExecutionID = %q, context ID %q; want the request's", result.ExecutionID, backend.ctxIDs[2])
+	}
+}
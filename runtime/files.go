@@ -0,0 +1,214 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+)
+
+// DefaultOutputDir is the workspace subdirectory collected as artifacts
+// when Workspace.OutputDir is empty.
+const DefaultOutputDir = "out"
+
+// OutputEnv is the environment variable through which backends tell code
+// where to write files it wants returned as artifacts.
+const OutputEnv = "TOOLEXEC_OUTPUT"
+
+// File is an input file staged into the workspace before the code runs.
+// Its content comes from Data, or from the host file at Path when Path is
+// set; setting both is an error.
+type File struct {
+	// Data is the file content.
+	Data []byte
+
+	// Path is a host file whose content is read when the request is staged.
+	Path string
+}
+
+// Artifact is a file the code left in the workspace output directory.
+type Artifact struct {
+	// Name is the slash-separated path relative to the output directory.
+	Name string
+
+	// Data is the file content.
+	Data []byte
+}
+
+// FileStager is implemented by the pluggable runners of backends such as
+// kubernetes and gvisor when they stage Staging.Files and collect
+// artifacts. Those backends advertise Capabilities.FileStaging, and
+// accept requests with Files, only when their runner is a FileStager.
+//
+// Contract:
+//   - Concurrency: implementations must be safe for concurrent use.
+type FileStager interface {
+	// StagesFiles reports whether Staging.Files and artifacts are honored.
+	StagesFiles() bool
+}
+
+// StagesFiles reports whether runner is a FileStager that stages files.
+func StagesFiles(runner any) bool {
+	s, ok := runner.(FileStager)
+	return ok && s.StagesFiles()
+}
+
+// Staging tells a runner how to move files into and out of the sandbox.
+// Backends build it from the request with ExecuteRequest.Staging.
+type Staging struct {
+	// Dir is the absolute workspace path inside the sandbox. Runners
+	// that do not already mount the workspace must create it writable
+	// for the sandbox user.
+	Dir string
+
+	// MaxBytes caps the size of the workspace, as Workspace.MaxBytes.
+	MaxBytes int64
+
+	// Files maps slash-separated paths relative to Dir to the content
+	// written there before the code starts.
+	Files map[string][]byte
+
+	// OutputDir is the slash-separated path relative to Dir that is
+	// created before the code starts and whose regular files are
+	// returned as artifacts after it exits.
+	OutputDir string
+}
+
+// OutputPath returns the absolute path of the output directory.
+func (s Staging) OutputPath() string {
+	return path.Join(s.Dir, s.OutputDir)
+}
+
+// Staging resolves the request's workspace and files for a runner,
+// reading File.Path contents from the host. It returns nil when the
+// request has no Workspace.
+func (r ExecuteRequest) Staging() (*Staging, error) {
+	if r.Workspace == nil {
+		return nil, nil
+	}
+	s := &Staging{
+		Dir:       r.Workspace.MountPath(),
+		MaxBytes:  r.Workspace.MaxBytes,
+		OutputDir: r.Workspace.OutputPath(),
+	}
+	if len(r.Files) > 0 {
+		s.Files = make(map[string][]byte, len(r.Files))
+	}
+	for _, name := range slices.Sorted(maps.Keys(r.Files)) {
+		f := r.Files[name]
+		if f.Path == "" {
+			s.Files[name] = f.Data
+			continue
+		}
+		data, err := os.ReadFile(f.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidFile, name, err)
+		}
+		s.Files[name] = data
+	}
+	return s, nil
+}
+
+// Validate checks that the file has at most one content source.
+func (f File) Validate() error {
+	if f.Path != "" && f.Data != nil {
+		return fmt.Errorf("%w: Data and Path are mutually exclusive", ErrInvalidFile)
+	}
+	return nil
+}
+
+// ValidRelPath reports whether name is a clean, slash-separated path that
+// stays inside the directory it is relative to.
+func ValidRelPath(name string) bool {
+	return name != "" && name != "." && fs.ValidPath(name)
+}
+
+// WriteFiles writes staged files below dir, creating parent directories.
+// Runners that stage through a host directory use it.
+func WriteFiles(dir string, files map[string][]byte) error {
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		if !ValidRelPath(name) {
+			return fmt.Errorf("%w: %q", ErrInvalidFile, name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, files[name], 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CollectArtifacts reads every regular file below dir, in lexical order.
+// A missing dir yields no artifacts. When maxBytes is positive and the
+// files total more, it fails with an error wrapping ErrResourceLimit
+// without reading past the limit.
+func CollectArtifacts(dir string, maxBytes int64) ([]Artifact, error) {
+	var artifacts []Artifact
+	var total int64
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir && os.IsNotExist(err) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := readLimited(p, maxBytes-total, maxBytes > 0)
+		if err != nil {
+			return err
+		}
+		total += int64(len(data))
+		if maxBytes > 0 && total > maxBytes {
+			return fmt.Errorf("%w: artifacts exceed %d bytes", ErrResourceLimit, maxBytes)
+		}
+		artifacts = append(artifacts, Artifact{Name: filepath.ToSlash(rel), Data: data})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return artifacts, nil
+}
+
+// readLimited reads the file at p, or when limited only its first
+// remaining+1 bytes, enough to tell that it is over the limit.
+func readLimited(p string, remaining int64, limited bool) ([]byte, error) {
+	if !limited {
+		return os.ReadFile(p)
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return io.ReadAll(io.LimitReader(f, remaining+1))
+}
+
+// validateFiles checks file names and sources against the workspace.
+func validateFiles(files map[string]File, ws *Workspace) error {
+	if len(files) > 0 && ws == nil {
+		return fmt.Errorf("%w: files require a Workspace", ErrInvalidFile)
+	}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		if !ValidRelPath(name) {
+			return fmt.Errorf("%w: name %q must be a relative path inside the workspace", ErrInvalidFile, name)
+		}
+		if err := files[name].Validate(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package runtime
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExecuteRequest_ValidateFiles(t *testing.T) {
+	ws := &Workspace{}
+	tests := []struct {
+		name  string
+		ws    *Workspace
+		files map[string]File
+		ok    bool
+	}{
+		{"data", ws, map[string]File{"input.csv": {Data: []byte("a,b")}}, true},
+		{"nested path", ws, map[string]File{"data/input.csv": {Path: "/etc/hosts"}}, true},
+		{"empty file", ws, map[string]File{"empty": {}}, true},
+		{"no workspace", nil, map[string]File{"input.csv": {Data: []byte("x")}}, false},
+		{"absolute name", ws, map[string]File{"/etc/passwd": {Data: []byte("x")}}, false},
+		{"escaping name", ws, map[string]File{"../x": {Data: []byte("x")}}, false},
+		{"unclean name", ws, map[string]File{"a//b": {Data: []byte("x")}}, false},
+		{"data and path", ws, map[string]File{"x": {Data: []byte("x"), Path: "/tmp/x"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}, Workspace: tt.ws, Files: tt.files}
+			err := req.Validate()
+			if tt.ok && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidFile) {
+				t.Errorf("Validate() error = %v, want %v", err, ErrInvalidFile)
+			}
+		})
+	}
+}
+
+func TestExecuteRequest_Staging(t *testing.T) {
+	if s, err := (ExecuteRequest{}).Staging(); s != nil || err != nil {
+		t.Errorf("Staging() without workspace = %+v, %v; want nil", s, err)
+	}
+
+	host := filepath.Join(t.TempDir(), "host.txt")
+	if err := os.WriteFile(host, []byte("from host"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	req := ExecuteRequest{
+		Workspace: &Workspace{Path: "/scratch", MaxBytes: 1 << 20, OutputDir: "results"},
+		Files: map[string]File{
+			"inline.txt":    {Data: []byte("inline")},
+			"data/host.txt": {Path: host},
+		},
+	}
+	s, err := req.Staging()
+	if err != nil {
+		t.Fatalf("Staging() error = %v", err)
+	}
+	want := &Staging{
+		Dir:       "/scratch",
+		MaxBytes:  1 << 20,
+		OutputDir: "results",
+		Files: map[string][]byte{
+			"inline.txt":    []byte("inline"),
+			"data/host.txt": []byte("from host"),
+		},
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("Staging() = %+v, want %+v", s, want)
+	}
+	if got := s.OutputPath(); got != "/scratch/results" {
+		t.Errorf("OutputPath() = %q, want /scratch/results", got)
+	}
+
+	req.Files["missing"] = File{Path: filepath.Join(t.TempDir(), "missing")}
+	if _, err := req.Staging(); !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("Staging() with missing host file error = %v, want %v", err, ErrInvalidFile)
+	}
+}
+
+func TestWriteFilesCollectArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"input.txt":     []byte("in"),
+		"out/b.json":    []byte("{}"),
+		"out/sub/a.txt": []byte("a"),
+	}
+	if err := WriteFiles(dir, files); err != nil {
+		t.Fatalf("WriteFiles() error = %v", err)
+	}
+
+	got, err := CollectArtifacts(filepath.Join(dir, "out"), 0)
+	if err != nil {
+		t.Fatalf("CollectArtifacts() error = %v", err)
+	}
+	want := []Artifact{
+		{Name: "b.json", Data: []byte("{}")},
+		{Name: "sub/a.txt", Data: []byte("a")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CollectArtifacts() = %+v, want %+v", got, want)
+	}
+
+	if got, err := CollectArtifacts(filepath.Join(dir, "out"), 3); !reflect.DeepEqual(got, want) || err != nil {
+		t.Errorf("CollectArtifacts(limit 3) = %+v, %v; want all", got, err)
+	}
+	if _, err := CollectArtifacts(filepath.Join(dir, "out"), 2); !errors.Is(err, ErrResourceLimit) {
+		t.Errorf("CollectArtifacts(limit 2) error = %v, want %v", err, ErrResourceLimit)
+	}
+	if got, err := CollectArtifacts(filepath.Join(dir, "missing"), 0); got != nil || err != nil {
+		t.Errorf("CollectArtifacts(missing) = %v, %v; want nil", got, err)
+	}
+	if err := WriteFiles(dir, map[string][]byte{"../escape": nil}); !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("WriteFiles(../escape) error = %v, want %v", err, ErrInvalidFile)
+	}
+}
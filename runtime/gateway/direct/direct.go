@@ -34,7 +34,7 @@ type Config struct {
 	Docs tooldoc.Store
 
 	// Runner is the tool execution runner.
-	Runner run.Runner
+	Runner run.Executor
 
 	// MaxToolCalls limits the total number of tool invocations.
 	// Zero means unlimited.
@@ -50,7 +50,7 @@ type Config struct {
 type Gateway struct {
 	index         index.Index
 	docs          tooldoc.Store
-	runner        run.Runner
+	runner        run.Executor
 	maxToolCalls  int
 	maxChainSteps int
 
@@ -124,8 +124,9 @@ func (g *Gateway) RunTool(ctx context.Context, id string, args map[string]any) (
 
 	// Record the call
 	record := runtime.ToolCallRecord{
-		ToolID:   id,
-		Duration: duration,
+		ToolID:      id,
+		ExecutionID: runtime.ExecutionIDFromContext(ctx),
+		Duration:    duration,
 	}
 	if err != nil {
 		record.ErrorOp = "run"
@@ -141,6 +142,55 @@ func (g *Gateway) RunTool(ctx context.Context, id string, args map[string]any) (
 	return result, err
 }
 
+// RunToolStream delegates to the runner's RunStream and records the call
+// when the stream ends. A stream counts against MaxToolCalls like RunTool.
+func (g *Gateway) RunToolStream(ctx context.Context, id string, args map[string]any) (<-chan run.StreamEvent, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	g.mu.Lock()
+	if g.maxToolCalls > 0 && g.callCount >= g.maxToolCalls {
+		g.mu.Unlock()
+		return nil, fmt.Errorf("%w: max %d calls exceeded", ErrToolCallLimitExceeded, g.maxToolCalls)
+	}
+	g.callCount++
+	g.mu.Unlock()
+
+	start := time.Now()
+	events, err := g.runner.RunStream(ctx, id, args)
+	record := runtime.ToolCallRecord{
+		ToolID:      id,
+		ExecutionID: runtime.ExecutionIDFromContext(ctx),
+	}
+	if err != nil {
+		record.Duration = time.Since(start)
+		record.ErrorOp = "stream"
+		g.record(record)
+		return nil, err
+	}
+
+	out := make(chan run.StreamEvent)
+	go func() {
+		defer close(out)
+		for ev := range events {
+			if ev.Kind == run.StreamEventError {
+				record.ErrorOp = "stream"
+			}
+			out <- ev
+		}
+		record.Duration = time.Since(start)
+		g.record(record)
+	}()
+	return out, nil
+}
+
+func (g *Gateway) record(record runtime.ToolCallRecord) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.toolCalls = append(g.toolCalls, record)
+}
+
 // RunChain delegates to the runner and records the calls.
 func (g *Gateway) RunChain(ctx context.Context, steps []run.ChainStep) (run.RunResult, []run.StepResult, error) {
 	if ctx.Err() != nil {
@@ -198,11 +248,13 @@ func (g *Gateway) RunChain(ctx context.Context, steps []run.ChainStep) (run.RunR
 	}
 	stepDuration := duration / time.Duration(executed)
 
+	executionID := runtime.ExecutionIDFromContext(ctx)
 	g.mu.Lock()
 	for i, step := range steps[:executed] {
 		record := runtime.ToolCallRecord{
-			ToolID:   step.ToolID,
-			Duration: stepDuration,
+			ToolID:      step.ToolID,
+			ExecutionID: executionID,
+			Duration:    stepDuration,
 		}
 		if i < len(stepResults) && stepResults[i].Err != nil {
 			record.ErrorOp = "chain"
@@ -233,3 +285,5 @@ func (g *Gateway) Reset() {
 	g.callCount = 0
 	g.toolCalls = nil
 }
+
+var _ runtime.StreamingGateway = (*Gateway)(nil)
@@ -105,6 +105,7 @@ type mockRunner struct {
 	chainResult run.RunResult
 	stepResults []run.StepResult
 	chainErr    error
+	events      []run.StreamEvent
 	callCount   int
 	mu          sync.Mutex
 }
@@ -124,7 +125,15 @@ func (m *mockRunner) Run(ctx context.Context, _ string, _ map[string]any) (run.R
 }
 
 func (m *mockRunner) RunStream(_ context.Context, _ string, _ map[string]any) (<-chan run.StreamEvent, error) {
-	return nil, errors.New("streaming not supported")
+	if m.events == nil {
+		return nil, errors.New("streaming not supported")
+	}
+	ch := make(chan run.StreamEvent, len(m.events))
+	for _, ev := range m.events {
+		ch <- ev
+	}
+	close(ch)
+	return ch, nil
 }
 
 func (m *mockRunner) RunChain(ctx context.Context, steps []run.ChainStep) (run.RunResult, []run.StepResult, error) {
@@ -145,6 +154,7 @@ func (m *mockRunner) RunChain(ctx context.Context, steps []run.ChainStep) (run.R
 func TestGatewayImplementsInterface(t *testing.T) {
 	t.Helper()
 	var _ runtime.ToolGateway = (*Gateway)(nil)
+	var _ runtime.StreamingGateway = (*Gateway)(nil)
 }
 
 func TestGatewaySearchTools(t *testing.T) {
@@ -327,6 +337,24 @@ func TestGatewayRunTool(t *testing.T) {
 			t.Errorf("GetToolCalls() returned %d records, want 2", len(records))
 		}
 	})
+
+	t.Run("records the execution ID", func(t *testing.T) {
+		gw := New(Config{
+			Index:  &mockIndex{},
+			Docs:   &mockDocs{},
+			Runner: &mockRunner{},
+		})
+
+		ctx := runtime.WithExecutionID(context.Background(), "exec-1")
+		_, _ = gw.RunTool(ctx, "tool1", nil)
+		_, _, _ = gw.RunChain(ctx, []run.ChainStep{{ToolID: "tool2"}})
+
+		for _, record := range gw.GetToolCalls() {
+			if record.ExecutionID != "exec-1" {
+				t.Errorf("record %s ExecutionID = %q, want exec-1", record.ToolID, record.ExecutionID)
+			}
+		}
+	})
 }
 
 func TestGatewayRunChain(t *testing.T) {
@@ -434,6 +462,34 @@ func TestGatewayToolCallLimits(t *testing.T) {
 	}
 }
 
+func TestGatewayRunToolStream(t *testing.T) {
+	runner := &mockRunner{events: []run.StreamEvent{
+		{Kind: run.StreamEventChunk, Data: "a"},
+		{Kind: run.StreamEventError, Err: errors.New("failed")},
+	}}
+	gw := New(Config{Runner: runner, MaxToolCalls: 1})
+	ctx := runtime.WithExecutionID(context.Background(), "exec-1")
+
+	events, err := gw.RunToolStream(ctx, "ns:tool", nil)
+	if err != nil {
+		t.Fatalf("RunToolStream() error = %v", err)
+	}
+	var kinds []run.StreamEventKind
+	for ev := range events {
+		kinds = append(kinds, ev.Kind)
+	}
+	if len(kinds) != 2 || kinds[1] != run.StreamEventError {
+		t.Errorf("events = %v, want the runner's chunk and error", kinds)
+	}
+	calls := gw.GetToolCalls()
+	if len(calls) != 1 || calls[0].ToolID != "ns:tool" || calls[0].ExecutionID != "exec-1" || calls[0].ErrorOp != "stream" {
+		t.Errorf("GetToolCalls() = %+v, want the failed stream", calls)
+	}
+	if _, err := gw.RunToolStream(ctx, "ns:tool", nil); !errors.Is(err, ErrToolCallLimitExceeded) {
+		t.Errorf("RunToolStream() over the limit error = %v, want %v", err, ErrToolCallLimitExceeded)
+	}
+}
+
 func TestGatewayChainStepLimits(t *testing.T) {
 	runner := &mockRunner{
 		stepResults: []run.StepResult{{}, {}},
@@ -0,0 +1,229 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// A MsgBatch request carries its requests as "requests", each a message
+// in the shape of Message's JSON encoding. Its answer, a MsgBatch with the
+// same ID, carries their responses as "responses", in the same order.
+
+// batchable reports whether requests of type t may be sent in a MsgBatch.
+func batchable(t MessageType) bool {
+	return idempotent(t) || t == MsgRunTool || t == MsgRunChain
+}
+
+// encodeBatch returns the payload of a MsgBatch carrying msgs under key.
+// Payload values are kept as they are, so binary codecs carry them
+// natively.
+func encodeBatch(key string, msgs []Message) map[string]any {
+	encoded := make([]any, len(msgs))
+	for i, msg := range msgs {
+		m := map[string]any{"type": string(msg.Type), "id": msg.ID}
+		if msg.ExecutionID != "" {
+			m["executionId"] = msg.ExecutionID
+		}
+		if msg.Payload != nil {
+			m["payload"] = msg.Payload
+		}
+		if msg.Token != "" {
+			m["token"] = msg.Token
+		}
+		encoded[i] = m
+	}
+	return map[string]any{key: encoded}
+}
+
+// decodeBatch decodes the messages a MsgBatch payload carries under key.
+func decodeBatch(p map[string]any, key string) ([]Message, error) {
+	raw, ok := p[key].([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: batch without %s", ErrProtocol, key)
+	}
+	msgs := make([]Message, len(raw))
+	for i, r := range raw {
+		m, ok := r.(map[string]any)
+		if !ok || getString(m, "id") == "" {
+			return nil, fmt.Errorf("%w: malformed batch message %d", ErrProtocol, i)
+		}
+		payload, _ := m["payload"].(map[string]any)
+		msgs[i] = Message{
+			Type:        MessageType(getString(m, "type")),
+			ID:          getString(m, "id"),
+			ExecutionID: getString(m, "executionId"),
+			Payload:     payload,
+			Token:       getString(m, "token"),
+		}
+	}
+	return msgs, nil
+}
+
+// serveBatched answers req, one of a batch's requests, with h.
+func serveBatched(ctx context.Context, h Handler, req Message) Message {
+	if !batchable(req.Type) {
+		return ErrorResponse(req.ID, fmt.Errorf("%w: %s requests cannot be batched", ErrProtocol, req.Type))
+	}
+	return h.ServeMessage(ctx, req)
+}
+
+// batcher gathers the requests sent within a gateway's BatchWindow into
+// MsgBatch messages.
+type batcher struct {
+	window  time.Duration
+	maxSize int
+
+	mu      sync.Mutex
+	link    *link
+	entries []*batchEntry
+	timer   *time.Timer
+}
+
+// batchEntry is a request waiting in a batcher.
+type batchEntry struct {
+	msg  Message
+	done chan error // receives the result of sending the batch
+}
+
+// send sends msg on l, after waiting for other requests to batch it with
+// when BatchWindow is set.
+func (g *Gateway) send(ctx context.Context, l *link, msg Message) error {
+	if g.batcher == nil || !batchable(msg.Type) || g.unbatched.Load() {
+		return l.conn.Send(ctx, msg)
+	}
+	e := &batchEntry{msg: msg, done: make(chan error, 1)}
+	g.add(l, e)
+	select {
+	case err := <-e.done:
+		return err
+	case <-ctx.Done():
+		if g.batcher.remove(e) {
+			return ctx.Err()
+		}
+		// The batch is on its way.
+		return <-e.done
+	}
+}
+
+// add queues e for l, flushing the queue once it is full, and before a
+// request for another link joins it.
+func (g *Gateway) add(l *link, e *batchEntry) {
+	b := g.batcher
+	b.mu.Lock()
+	if b.link != l && len(b.entries) > 0 {
+		prev, entries := b.link, b.take()
+		go g.flush(prev, entries)
+	}
+	b.link = l
+	b.entries = append(b.entries, e)
+	if len(b.entries) >= b.maxSize {
+		entries := b.take()
+		b.mu.Unlock()
+		go g.flush(l, entries)
+		return
+	}
+	if b.timer == nil {
+		var timer *time.Timer
+		timer = time.AfterFunc(b.window, func() {
+			b.mu.Lock()
+			if b.timer != timer {
+				b.mu.Unlock()
+				return
+			}
+			l, entries := b.link, b.take()
+			b.mu.Unlock()
+			g.flush(l, entries)
+		})
+		b.timer = timer
+	}
+	b.mu.Unlock()
+}
+
+// take empties the queue, returning its entries. b.mu must be held.
+func (b *batcher) take() []*batchEntry {
+	entries := b.entries
+	b.entries = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return entries
+}
+
+// remove takes e out of the queue, reporting whether it was still there.
+func (b *batcher) remove(e *batchEntry) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := slices.Index(b.entries, e)
+	if i < 0 {
+		return false
+	}
+	b.entries = slices.Delete(b.entries, i, i+1)
+	if len(b.entries) == 0 {
+		b.take()
+	}
+	return true
+}
+
+// flush sends entries on l, as a MsgBatch unless there is only one.
+func (g *Gateway) flush(l *link, entries []*batchEntry) {
+	ctx := context.Background()
+	if g.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.requestTimeout)
+		defer cancel()
+	}
+	var err error
+	if len(entries) == 1 {
+		err = l.conn.Send(ctx, entries[0].msg)
+	} else {
+		msgs := make([]Message, len(entries))
+		for i, e := range entries {
+			msgs[i] = e.msg
+		}
+		batch := Message{
+			Type:    MsgBatch,
+			ID:      fmt.Sprintf("%d", g.requestID.Add(1)),
+			Payload: encodeBatch("requests", msgs),
+		}
+		l.batches.Store(batch.ID, msgs)
+		if err = l.conn.Send(ctx, batch); err != nil {
+			l.batches.Delete(batch.ID)
+		}
+	}
+	for _, e := range entries {
+		e.done <- err
+	}
+}
+
+// deliverBatch delivers the responses of a MsgBatch answer, msg, received
+// on l. A MsgError answer comes from a host that predates MsgBatch, so the
+// batch's requests are sent again one by one, as are later ones.
+func (g *Gateway) deliverBatch(ctx context.Context, l *link, msg Message) {
+	v, ok := l.batches.LoadAndDelete(msg.ID)
+	if !ok {
+		return
+	}
+	if msg.Type == MsgError {
+		g.unbatched.Store(true)
+		go func() {
+			for _, req := range v.([]Message) {
+				_ = l.conn.Send(ctx, req)
+			}
+		}()
+		return
+	}
+	responses, err := decodeBatch(msg.Payload, "responses")
+	if err != nil {
+		for _, req := range v.([]Message) {
+			_ = g.DeliverResponse(ErrorResponse(req.ID, err))
+		}
+		return
+	}
+	for _, resp := range responses {
+		_ = g.DeliverResponse(resp)
+	}
+}
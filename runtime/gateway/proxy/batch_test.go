@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// recordingConn records the types of the messages it receives.
+type recordingConn struct {
+	Connection
+	mu       sync.Mutex
+	received []MessageType
+}
+
+func (c *recordingConn) Receive(ctx context.Context) (Message, error) {
+	msg, err := c.Connection.Receive(ctx)
+	if err == nil {
+		c.mu.Lock()
+		c.received = append(c.received, msg.Type)
+		c.mu.Unlock()
+	}
+	return msg, err
+}
+
+// runConcurrently runs n tools concurrently on g, each under its own
+// execution, and checks their results.
+func runConcurrently(t *testing.T, g *Gateway, n int) {
+	t.Helper()
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := range n {
+		wg.Go(func() {
+			ctx := runtime.WithExecutionID(context.Background(), fmt.Sprint("exec-", i))
+			result, err := g.RunTool(ctx, "ns:echo", nil)
+			if want := fmt.Sprint("ns:echo@exec-", i); err == nil && result.Structured != want {
+				err = fmt.Errorf("result = %v, want %v", result.Structured, want)
+			}
+			errs <- err
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("RunTool() error = %v", err)
+		}
+	}
+}
+
+func TestGateway_Batch(t *testing.T) {
+	guest, stdio := newStdioPair(t)
+	host := &recordingConn{Connection: stdio}
+	go func() { _ = Pump(context.Background(), host, NewGatewayHandler(hostGateway{})) }()
+	g := New(Config{Connection: guest, BatchWindow: 50 * time.Millisecond, MaxBatchSize: 4})
+	go func() { _ = g.ReceiveResponses(context.Background()) }()
+
+	runConcurrently(t, g, 10)
+	host.mu.Lock()
+	batches := 0
+	for _, typ := range host.received {
+		if typ == MsgBatch {
+			batches++
+		}
+	}
+	if batches < 2 || len(host.received) >= 10 {
+		t.Errorf("host received %v, want the requests in batches", host.received)
+	}
+	host.mu.Unlock()
+
+	// Streams are never batched.
+	events, err := g.RunToolStream(context.Background(), "ns:echo", nil)
+	if err == nil {
+		for range events {
+		}
+	}
+	host.mu.Lock()
+	defer host.mu.Unlock()
+	if got := host.received[len(host.received)-1]; got != MsgRunToolStream {
+		t.Errorf("stream sent as %s", got)
+	}
+}
+
+func TestGateway_BatchUnsupported(t *testing.T) {
+	guest, host := newStdioPair(t)
+	// A host that predates MsgBatch answers it as an unknown request.
+	go func() {
+		h := NewGatewayHandler(hostGateway{})
+		for {
+			req, err := host.Receive(context.Background())
+			if err != nil {
+				return
+			}
+			go func() { _ = host.Send(context.Background(), h.ServeMessage(context.Background(), req)) }()
+		}
+	}()
+	g := New(Config{Connection: guest, BatchWindow: 50 * time.Millisecond})
+	go func() { _ = g.ReceiveResponses(context.Background()) }()
+
+	runConcurrently(t, g, 5)
+	if !g.unbatched.Load() {
+		t.Error("gateway still batches after the host rejected a batch")
+	}
+	runConcurrently(t, g, 5)
+}
+
+func TestServeBatched(t *testing.T) {
+	h := NewGatewayHandler(hostGateway{})
+	resp := serveBatched(context.Background(), h, Message{Type: MsgRunToolStream, ID: "1"})
+	if err := decodeError(resp.Payload); resp.Type != MsgError || !strings.Contains(err.Error(), "cannot be batched") {
+		t.Errorf("serveBatched(run_tool_stream) = %+v", resp)
+	}
+
+	msgs := []Message{
+		{Type: MsgRunTool, ID: "1", ExecutionID: "exec", Payload: map[string]any{"id": "ns:echo"}, Token: "token"},
+		{Type: MsgListNamespaces, ID: "2"},
+	}
+	got, err := decodeBatch(encodeBatch("requests", msgs), "requests")
+	if err != nil || len(got) != 2 || got[0].Token != "token" || got[0].ExecutionID != "exec" || got[1].Type != MsgListNamespaces {
+		t.Errorf("decodeBatch(encodeBatch()) = %+v, %v", got, err)
+	}
+	if _, err := decodeBatch(map[string]any{"requests": []any{"x"}}, "requests"); !errors.Is(err, ErrProtocol) {
+		t.Errorf("decodeBatch() of a malformed batch error = %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// cacheable reports whether responses to requests of type t may be
+// cached. Their decoders copy what they return, so a caller cannot change
+// a cached response.
+func cacheable(t MessageType) bool {
+	switch t {
+	case MsgSearchTools, MsgListNamespaces, MsgDescribeTool:
+		return true
+	default:
+		return false
+	}
+}
+
+// responseCache holds the responses to discovery requests for a gateway's
+// CacheTTL, evicting the oldest beyond MaxCacheEntries.
+type responseCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]cacheEntry
+	order      []string
+}
+
+type cacheEntry struct {
+	resp    Message
+	expires time.Time
+}
+
+func newResponseCache(ttl time.Duration, maxEntries int) *responseCache {
+	return &responseCache{ttl: ttl, maxEntries: maxEntries, entries: make(map[string]cacheEntry)}
+}
+
+// get returns the cached response for key when present and unexpired.
+func (c *responseCache) get(key string) (Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return Message{}, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return Message{}, false
+	}
+	return entry.resp, true
+}
+
+// set stores resp under key, evicting the oldest entries over capacity.
+func (c *responseCache) set(key string, resp Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.entries[key]; !exists {
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = cacheEntry{resp: resp, expires: time.Now().Add(c.ttl)}
+
+	for len(c.entries) > c.maxEntries && len(c.order) > 0 {
+		oldest := c.order[0]
+		c.order = c.order[1:]
+		delete(c.entries, oldest)
+	}
+	// Drop order slots for keys already removed by expiry.
+	if len(c.order) > 2*len(c.entries)+16 {
+		kept := c.order[:0]
+		for _, k := range c.order {
+			if _, ok := c.entries[k]; ok {
+				kept = append(kept, k)
+			}
+		}
+		c.order = kept
+	}
+}
+
+// clear removes every cached response.
+func (c *responseCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cacheEntry)
+	c.order = nil
+}
+
+// cacheKey returns the key of a request's cached response. ok is false
+// when the request is not cached. Hosts may answer executions
+// differently, so the key includes ctx's execution ID.
+func (g *Gateway) cacheKey(ctx context.Context, msgType MessageType, payload map[string]any) (key string, ok bool) {
+	if g.cache == nil || !cacheable(msgType) {
+		return "", false
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", false
+	}
+	return string(msgType) + "\x00" + runtime.ExecutionIDFromContext(ctx) + "\x00" + string(data), true
+}
+
+// InvalidateCache drops the cached discovery responses, such as after the
+// host's tools change.
+func (g *Gateway) InvalidateCache() {
+	if g.cache != nil {
+		g.cache.clear()
+	}
+}
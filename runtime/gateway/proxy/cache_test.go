@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// countingHandler counts the requests of each type it serves.
+type countingHandler struct {
+	Handler
+	mu    sync.Mutex
+	count map[MessageType]int
+}
+
+func (h *countingHandler) ServeMessage(ctx context.Context, req Message) Message {
+	h.mu.Lock()
+	h.count[req.Type]++
+	h.mu.Unlock()
+	return h.Handler.ServeMessage(ctx, req)
+}
+
+func (h *countingHandler) served(t MessageType) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count[t]
+}
+
+func cachePair(t *testing.T, ttl time.Duration) (*Gateway, *countingHandler) {
+	t.Helper()
+	h := &countingHandler{Handler: NewGatewayHandler(hostGateway{}), count: make(map[MessageType]int)}
+	guest, host := newStdioPair(t)
+	go func() { _ = Pump(context.Background(), host, h) }()
+	g := New(Config{Connection: guest, CacheTTL: ttl})
+	go func() { _ = g.ReceiveResponses(context.Background()) }()
+	return g, h
+}
+
+func TestGateway_Cache(t *testing.T) {
+	g, h := cachePair(t, time.Minute)
+	ctx := context.Background()
+	exec := runtime.WithExecutionID(ctx, "exec-1")
+
+	for _, call := range []struct {
+		ctx   context.Context
+		query string
+		want  int
+	}{
+		{ctx, "echo", 1},
+		{ctx, "echo", 1},
+		{ctx, "other", 2},
+		{exec, "echo", 3},
+		{exec, "echo", 3},
+	} {
+		results, err := g.SearchTools(call.ctx, call.query, 5)
+		if err != nil || len(results) != 1 || results[0].ID != "ns:echo" {
+			t.Fatalf("SearchTools(%q) = %+v, %v", call.query, results, err)
+		}
+		// Callers own what they are returned.
+		results[0].ID = "changed"
+		if got := h.served(MsgSearchTools); got != call.want {
+			t.Errorf("after SearchTools(%q), host served %d searches, want %d", call.query, got, call.want)
+		}
+	}
+
+	// Errors and executions are not cached.
+	for range 2 {
+		if _, err := g.ListNamespaces(ctx); err == nil {
+			t.Error("ListNamespaces() succeeded")
+		}
+		if _, err := g.RunTool(ctx, "ns:echo", nil); err != nil {
+			t.Errorf("RunTool() error = %v", err)
+		}
+	}
+	if h.served(MsgListNamespaces) != 2 || h.served(MsgRunTool) != 2 {
+		t.Errorf("host served %d list_namespaces and %d run_tool requests, want 2 each", h.served(MsgListNamespaces), h.served(MsgRunTool))
+	}
+
+	g.InvalidateCache()
+	if _, err := g.SearchTools(ctx, "echo", 5); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.served(MsgSearchTools); got != 4 {
+		t.Errorf("after InvalidateCache, host served %d searches, want 4", got)
+	}
+}
+
+func TestGateway_CacheExpiry(t *testing.T) {
+	g, h := cachePair(t, time.Millisecond)
+	for range 2 {
+		if _, err := g.SearchTools(context.Background(), "echo", 5); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := h.served(MsgSearchTools); got != 2 {
+		t.Errorf("host served %d searches, want 2 once the first expired", got)
+	}
+}
+
+func TestResponseCache_Evicts(t *testing.T) {
+	c := newResponseCache(time.Minute, 2)
+	for _, key := range []string{"a", "b", "c"} {
+		c.set(key, Message{ID: key})
+	}
+	if _, ok := c.get("a"); ok {
+		t.Error("oldest entry was not evicted")
+	}
+	if resp, ok := c.get("c"); !ok || resp.ID != "c" {
+		t.Errorf("get(c) = %+v, %v", resp, ok)
+	}
+}
@@ -0,0 +1,88 @@
+// Package cborcodec provides a CBOR (RFC 8949) proxy.Codec for the gateway
+// protocol. CBOR messages are smaller and faster to encode than JSON, and
+// carry []byte values as byte strings rather than base64 text.
+//
+// It is a separate module so that the core toolexec module does not
+// depend on a CBOR library. Guests offer the codec in their hello, and
+// hosts that also have it switch the connection to it; others keep JSON:
+//
+//	codec := cborcodec.New(cborcodec.Config{})
+//	gw := proxy.New(proxy.Config{Connection: conn, Codecs: []proxy.NamedCodec{codec}})
+//
+//	// On the host:
+//	go func() { _ = proxy.Serve(ctx, ln, srv, nil, proxy.WithCodecs(codec)) }()
+package cborcodec
+
+import (
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
+)
+
+// Config configures a Codec.
+type Config struct {
+	// MaxNestedLevels bounds the nesting of arrays and maps in a received
+	// message. It is clamped to [4, 65535].
+	// Default: 32
+	MaxNestedLevels int
+
+	// MaxElements bounds the elements of each array, and the pairs of each
+	// map, in a received message. It is clamped to at least 16.
+	// Default: 131072
+	MaxElements int
+}
+
+// Codec implements proxy.NamedCodec with CBOR. It is safe for concurrent
+// use.
+type Codec struct {
+	enc cbor.EncMode
+	dec cbor.DecMode
+}
+
+// New creates a Codec.
+func New(cfg Config) *Codec {
+	levels := cfg.MaxNestedLevels
+	if levels == 0 {
+		levels = 32
+	}
+	elements := cfg.MaxElements
+	if elements == 0 {
+		elements = 131072
+	}
+	enc, err := cbor.EncOptions{
+		// Times are RFC 3339 strings, as in JSON.
+		Time: cbor.TimeRFC3339Nano,
+	}.EncMode()
+	if err != nil {
+		panic("cborcodec: " + err.Error())
+	}
+	dec, err := cbor.DecOptions{
+		MaxNestedLevels:  min(max(levels, 4), 65535),
+		MaxArrayElements: max(elements, 16),
+		MaxMapPairs:      max(elements, 16),
+		// Payload maps decode as JSON objects do.
+		DefaultMapType: reflect.TypeOf(map[string]any(nil)),
+	}.DecMode()
+	if err != nil {
+		panic("cborcodec: " + err.Error())
+	}
+	return &Codec{enc: enc, dec: dec}
+}
+
+// Name implements proxy.NamedCodec.
+func (c *Codec) Name() string { return "cbor" }
+
+// Encode implements proxy.Codec.
+func (c *Codec) Encode(msg proxy.Message) ([]byte, error) {
+	return c.enc.Marshal(msg)
+}
+
+// Decode implements proxy.Codec.
+func (c *Codec) Decode(data []byte) (proxy.Message, error) {
+	var msg proxy.Message
+	err := c.dec.Unmarshal(data, &msg)
+	return msg, err
+}
+
+var _ proxy.NamedCodec = (*Codec)(nil)
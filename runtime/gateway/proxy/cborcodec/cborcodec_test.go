@@ -0,0 +1,78 @@
+package cborcodec
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
+)
+
+func TestCodec_RoundTrip(t *testing.T) {
+	c := New(Config{})
+	msg := proxy.Message{
+		Type:        proxy.MsgRunTool,
+		ID:          "1",
+		ExecutionID: "exec",
+		Payload: map[string]any{
+			"id": "files:write",
+			"args": map[string]any{
+				"data":  []byte{0x00, 0xff, 0x10},
+				"mode":  "append",
+				"nest":  map[string]any{"ok": true, "ratio": 0.5},
+				"lines": []any{"a", "b"},
+			},
+		},
+		Token: "token",
+	}
+	data, err := c.Encode(msg)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	got, err := c.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, msg) {
+		t.Errorf("Decode() = %#v, want %#v", got, msg)
+	}
+
+	if _, err := c.Decode([]byte{0xff}); err == nil {
+		t.Error("Decode() of malformed data succeeded")
+	}
+	deep := New(Config{MaxNestedLevels: 4})
+	data, _ = c.Encode(proxy.Message{Payload: map[string]any{"a": map[string]any{"b": map[string]any{"c": []any{1}}}}})
+	if _, err := deep.Decode(data); err == nil {
+		t.Error("Decode() beyond MaxNestedLevels succeeded")
+	}
+}
+
+func TestCodec_Negotiated(t *testing.T) {
+	a, b := net.Pipe()
+	guest, host := proxy.NewStreamConnection(a, nil), proxy.NewStreamConnection(b, nil)
+	defer guest.Close()
+	defer host.Close()
+
+	// The host reports the Go type the guest's argument arrived as.
+	codec := New(Config{})
+	go func() {
+		_ = proxy.Pump(context.Background(), host, proxy.HandlerFunc(func(_ context.Context, req proxy.Message) proxy.Message {
+			args, _ := req.Payload["args"].(map[string]any)
+			return proxy.Message{Type: proxy.MsgResponse, ID: req.ID, Payload: map[string]any{
+				"structured": fmt.Sprintf("%T", args["data"]),
+			}}
+		}), proxy.WithCodecs(codec))
+	}()
+	g := proxy.New(proxy.Config{Connection: guest, Codecs: []proxy.NamedCodec{codec}})
+	go func() { _ = g.ReceiveResponses(context.Background()) }()
+
+	result, err := g.RunTool(context.Background(), "files:write", map[string]any{"data": []byte("raw")})
+	if err != nil {
+		t.Fatalf("RunTool() error = %v", err)
+	}
+	if result.Structured != "[]uint8" {
+		t.Errorf("host received data as %v, want []uint8", result.Structured)
+	}
+}
@@ -0,0 +1,34 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// heartbeat pings the host on l every heartbeatInterval until ctx ends or
+// l is lost. A host silent for heartbeatTimeout is given up on: l is
+// closed, which stops ReceiveResponses reading it.
+func (g *Gateway) heartbeat(ctx context.Context, l *link) {
+	ticker := time.NewTicker(g.heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-l.lost:
+			return
+		case <-ticker.C:
+		}
+
+		silent := time.Since(time.Unix(0, g.lastReceived.Load()))
+		if silent > g.heartbeatTimeout {
+			l.fail(fmt.Errorf("%w: host silent for %v", ErrConnectionClosed, silent.Round(time.Millisecond)))
+			_ = l.conn.Close()
+			return
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, g.heartbeatInterval)
+		_ = l.conn.Send(sendCtx, g.newRequest(ctx, MsgPing, nil))
+		cancel()
+	}
+}
@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/run"
+)
+
+// blockingHandler answers nothing until its request is canceled, which it
+// reports on canceled.
+func blockingHandler(canceled chan<- error) Handler {
+	return HandlerFunc(func(ctx context.Context, req Message) Message {
+		if req.Type != MsgRunTool {
+			return NewGatewayHandler(hostGateway{}).ServeMessage(ctx, req)
+		}
+		<-ctx.Done()
+		canceled <- ctx.Err()
+		return ErrorResponse(req.ID, ctx.Err())
+	})
+}
+
+// readAndIgnore receives requests on conn without answering them.
+func readAndIgnore(conn Connection) {
+	for {
+		if _, err := conn.Receive(context.Background()); err != nil {
+			return
+		}
+	}
+}
+
+func TestGateway_RequestTimeout(t *testing.T) {
+	canceled := make(chan error, 1)
+	guest, host := newStdioPair(t)
+	go func() { _ = Pump(context.Background(), host, blockingHandler(canceled)) }()
+	g := New(Config{Connection: guest, RequestTimeout: 20 * time.Millisecond})
+	go func() { _ = g.ReceiveResponses(context.Background()) }()
+
+	if _, err := g.RunTool(context.Background(), "ns:slow", nil); !errors.Is(err, ErrTimeout) {
+		t.Errorf("RunTool() error = %v, want %v", err, ErrTimeout)
+	}
+	select {
+	case err := <-canceled:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("host request error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the timed-out request was not canceled on the host")
+	}
+
+	// The caller's own deadline is reported as such.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	patient := New(Config{Connection: guest, RequestTimeout: time.Minute})
+	if _, err := patient.RunTool(ctx, "ns:slow", nil); !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrTimeout) {
+		t.Errorf("RunTool() past the caller's deadline error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestGateway_Heartbeat(t *testing.T) {
+	guest, host := newStdioPair(t)
+	go func() { _ = Pump(context.Background(), host, NewGatewayHandler(hostGateway{})) }()
+	g := New(Config{Connection: guest, HeartbeatInterval: 5 * time.Millisecond, HeartbeatTimeout: 50 * time.Millisecond})
+	go func() { _ = g.ReceiveResponses(context.Background()) }()
+
+	// Pongs keep an idle connection alive.
+	time.Sleep(150 * time.Millisecond)
+	if _, err := g.RunTool(context.Background(), "ns:echo", nil); err != nil {
+		t.Errorf("RunTool() after an idle period error = %v", err)
+	}
+
+	// A host that stops answering is detected, failing pending requests.
+	guest, host = newStdioPair(t)
+	go readAndIgnore(host)
+	silent := New(Config{Connection: guest, HeartbeatInterval: 5 * time.Millisecond, HeartbeatTimeout: 50 * time.Millisecond})
+	received := make(chan error, 1)
+	go func() { received <- silent.ReceiveResponses(context.Background()) }()
+
+	start := time.Now()
+	_, err := silent.RunTool(context.Background(), "ns:echo", nil)
+	if !errors.Is(err, ErrConnectionClosed) || !strings.Contains(err.Error(), "host silent") {
+		t.Errorf("RunTool() on a silent host error = %v, want %v", err, ErrConnectionClosed)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("RunTool() took %v to fail", elapsed)
+	}
+	if err := <-received; !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("ReceiveResponses() error = %v, want %v", err, ErrConnectionClosed)
+	}
+}
+
+func TestGateway_ConnectionLost(t *testing.T) {
+	guest, host := newStdioPair(t)
+	go readAndIgnore(host)
+	g := New(Config{Connection: guest})
+	go func() { _ = g.ReceiveResponses(context.Background()) }()
+
+	events, err := g.RunToolStream(context.Background(), "ns:echo", nil)
+	if err != nil {
+		t.Fatalf("RunToolStream() error = %v", err)
+	}
+	failed := make(chan error, 1)
+	go func() {
+		_, err := g.RunTool(context.Background(), "ns:echo", nil)
+		failed <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	_ = host.Close()
+
+	select {
+	case err := <-failed:
+		if !errors.Is(err, ErrConnectionClosed) {
+			t.Errorf("pending RunTool() error = %v, want %v", err, ErrConnectionClosed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the pending request did not fail when the connection dropped")
+	}
+	if ev := <-events; ev.Kind != run.StreamEventError || !errors.Is(ev.Err, ErrConnectionClosed) {
+		t.Errorf("stream event = %+v, want %v", ev, ErrConnectionClosed)
+	}
+	if _, err := g.ListNamespaces(context.Background()); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("ListNamespaces() after the drop error = %v, want %v", err, ErrConnectionClosed)
+	}
+}
@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// PumpOption configures Pump and Serve.
+type PumpOption func(*pumpOptions)
+
+type pumpOptions struct {
+	codecs []NamedCodec
+}
+
+// WithCodecs lets guests switch a connection to one of codecs with a
+// MsgHello, beyond the codec it was made with. The guest's preference
+// decides among them.
+func WithCodecs(codecs ...NamedCodec) PumpOption {
+	return func(o *pumpOptions) {
+		o.codecs = append(o.codecs, codecs...)
+	}
+}
+
+// hello offers the host g's codecs on l, switching l to the one the host
+// picks, before l carries requests.
+func (g *Gateway) hello(ctx context.Context, l *link) error {
+	defer func() {
+		select {
+		case <-l.ready:
+		default:
+			close(l.ready)
+		}
+	}()
+	switcher, ok := l.conn.(CodecSwitcher)
+	if !ok {
+		return nil
+	}
+	var names []any
+	for _, c := range g.codecs {
+		if switcher.AcceptsCodec(c) {
+			names = append(names, c.Name())
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	if g.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.requestTimeout)
+		defer cancel()
+	}
+	req := g.newRequest(ctx, MsgHello, map[string]any{"codecs": names})
+	if err := l.conn.Send(ctx, req); err != nil {
+		return err
+	}
+	for {
+		resp, err := l.conn.Receive(ctx)
+		if err != nil {
+			return err
+		}
+		if resp.ID != req.ID {
+			continue
+		}
+		name := getString(resp.Payload, "codec")
+		if resp.Type != MsgHello || name == "" {
+			// The host predates hello or keeps the codec.
+			return nil
+		}
+		i := slices.IndexFunc(g.codecs, func(c NamedCodec) bool { return c.Name() == name })
+		if i < 0 {
+			return fmt.Errorf("%w: host picked codec %q, which was not offered", ErrProtocol, name)
+		}
+		return switcher.SetCodec(g.codecs[i])
+	}
+}
+
+// answerHello answers req, a MsgHello, and switches conn to the codec
+// picked for it. The connection's codec is kept when busy is true, since
+// requests in flight are encoded with it.
+func answerHello(ctx context.Context, conn Connection, req Message, codecs []NamedCodec, busy bool) error {
+	resp := Message{Type: MsgHello, ID: req.ID, Payload: map[string]any{}}
+	var (
+		switcher CodecSwitcher
+		chosen   NamedCodec
+	)
+	if !busy {
+		offered, _ := req.Payload["codecs"].([]any)
+		switcher, chosen = pickCodec(conn, offered, codecs)
+	}
+	if chosen != nil {
+		resp.Payload["codec"] = chosen.Name()
+	}
+	if err := conn.Send(ctx, resp); err != nil {
+		return err
+	}
+	if chosen == nil {
+		return nil
+	}
+	return switcher.SetCodec(chosen)
+}
+
+// pickCodec returns the first of the offered codec names that is among
+// codecs and that conn can carry.
+func pickCodec(conn Connection, offered []any, codecs []NamedCodec) (CodecSwitcher, NamedCodec) {
+	switcher, ok := conn.(CodecSwitcher)
+	if !ok {
+		return nil, nil
+	}
+	for _, o := range offered {
+		name, _ := o.(string)
+		for _, c := range codecs {
+			if c.Name() == name && switcher.AcceptsCodec(c) {
+				return switcher, c
+			}
+		}
+	}
+	return nil, nil
+}
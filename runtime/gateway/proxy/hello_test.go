@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+)
+
+// markedCodec is JSON with a leading marker byte, counting the messages
+// it encodes.
+type markedCodec struct {
+	jsonCodec
+	encoded atomic.Int32
+}
+
+func (c *markedCodec) Name() string { return "marked" }
+
+func (c *markedCodec) Encode(msg Message) ([]byte, error) {
+	c.encoded.Add(1)
+	data, err := c.jsonCodec.Encode(msg)
+	return append([]byte{0}, data...), err
+}
+
+func (c *markedCodec) Decode(data []byte) (Message, error) {
+	return c.jsonCodec.Decode(bytes.TrimPrefix(data, []byte{0}))
+}
+
+// helloPair joins a guest gateway offering codecs and a host pumping
+// hostGateway with opts, over length-prefixed framing.
+func helloPair(t *testing.T, codecs []NamedCodec, opts ...PumpOption) *Gateway {
+	t.Helper()
+	a, b := net.Pipe()
+	guest, host := NewStreamConnection(a, nil), NewStreamConnection(b, nil)
+	t.Cleanup(func() {
+		_ = guest.Close()
+		_ = host.Close()
+	})
+	go func() { _ = Pump(context.Background(), host, NewGatewayHandler(hostGateway{}), opts...) }()
+	g := New(Config{Connection: guest, Codecs: codecs})
+	go func() { _ = g.ReceiveResponses(context.Background()) }()
+	return g
+}
+
+func TestGateway_Hello(t *testing.T) {
+	ctx := context.Background()
+
+	guestCodec, hostCodec := &markedCodec{}, &markedCodec{}
+	g := helloPair(t, []NamedCodec{guestCodec}, WithCodecs(hostCodec))
+	if result, err := g.RunTool(ctx, "ns:echo", nil); err != nil || result.Structured != "ns:echo@" {
+		t.Fatalf("RunTool() = %+v, %v", result, err)
+	}
+	if guestCodec.encoded.Load() != 1 || hostCodec.encoded.Load() != 1 {
+		t.Errorf("codec encoded %d guest and %d host messages, want the request and response", guestCodec.encoded.Load(), hostCodec.encoded.Load())
+	}
+
+	// Hosts without the codec keep JSON.
+	unused := &markedCodec{}
+	g = helloPair(t, []NamedCodec{unused})
+	if _, err := g.RunTool(ctx, "ns:echo", nil); err != nil {
+		t.Fatalf("RunTool() with a host lacking the codec error = %v", err)
+	}
+	if n := unused.encoded.Load(); n != 0 {
+		t.Errorf("unsupported codec encoded %d messages", n)
+	}
+
+	// Hosts that predate hello answer it with an error, as do other
+	// handlers.
+	guest, host := newStdioPair(t)
+	go func() {
+		_ = Pump(ctx, host, HandlerFunc(func(ctx context.Context, req Message) Message {
+			return NewGatewayHandler(hostGateway{}).ServeMessage(ctx, req)
+		}))
+	}()
+	old := New(Config{Connection: guest, Codecs: []NamedCodec{unused}})
+	go func() { _ = old.ReceiveResponses(ctx) }()
+	if _, err := old.RunTool(ctx, "ns:echo", nil); err != nil {
+		t.Errorf("RunTool() over newline framing error = %v", err)
+	}
+}
+
+func TestAnswerHello(t *testing.T) {
+	codec := &markedCodec{}
+	offer := Message{Type: MsgHello, ID: "1", Payload: map[string]any{"codecs": []any{"cbor", "marked"}}}
+	for _, tt := range []struct {
+		name string
+		line bool
+		busy bool
+		want string
+	}{
+		{name: "picks an offered codec", want: "marked"},
+		{name: "busy", busy: true},
+		{name: "newline framing", line: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := net.Pipe()
+			conn, peer := NewStreamConnection(a, nil), NewStreamConnection(b, nil)
+			if tt.line {
+				conn, peer = NewLineConnection(a), NewLineConnection(b)
+			}
+			defer conn.Close()
+			defer peer.Close()
+			go func() { _ = answerHello(context.Background(), conn, offer, []NamedCodec{codec}, tt.busy) }()
+			resp, err := peer.Receive(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := getString(resp.Payload, "codec"); resp.Type != MsgHello || got != tt.want {
+				t.Errorf("answer = %+v, want codec %q", resp, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+// Serve accepts gateway connections from l, such as a listener from
+// ListenUnix or ListenVsock, and pumps the requests on each with h (see
+// Pump, which opts configure), framing them as a StreamConnection with
+// codec (JSON if nil). It runs until ctx ends, when it closes l and the
+// open connections, waits for their requests, and returns ctx.Err(). If l
+// is closed otherwise, Serve returns nil once the connections finish;
+// other accept errors are returned after closing the connections.
+func Serve(ctx context.Context, l net.Listener, h Handler, codec Codec, opts ...PumpOption) error {
+	stop := context.AfterFunc(ctx, func() { _ = l.Close() })
+	defer stop()
+
+	var (
+		mu    sync.Mutex
+		conns = make(map[*StreamConnection]struct{})
+		wg    sync.WaitGroup
+	)
+	closeAll := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for c := range conns {
+			_ = c.Close()
+		}
+	}
+	defer wg.Wait()
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				closeAll()
+				return ctx.Err()
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			closeAll()
+			return err
+		}
+		conn := NewStreamConnection(nc, codec)
+		mu.Lock()
+		conns[conn] = struct{}{}
+		mu.Unlock()
+		wg.Go(func() {
+			_ = Pump(ctx, conn, h, opts...)
+			_ = conn.Close()
+			mu.Lock()
+			delete(conns, conn)
+			mu.Unlock()
+		})
+	}
+}
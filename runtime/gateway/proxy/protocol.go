@@ -1,5 +1,9 @@
 // Package proxy provides a gateway that implements ToolGateway
 // by serializing requests over a connection (for cross-process/container communication).
+// On the host, Pump answers the requests arriving on a connection with a Handler.
+// Serve does so for every connection accepted from a Unix or vsock listener.
+// The runtime/gateway/server package provides a Handler over an index, docs, and runner.
+// Messages are JSON unless a MsgHello negotiates another codec, such as the CBOR codec in cborcodec.
 package proxy
 
 import "context"
@@ -16,16 +20,54 @@ const (
 	MsgRunTool          MessageType = "run_tool"
 	MsgRunChain         MessageType = "run_chain"
 
+	// MsgRunToolStream runs a tool whose events the host streams back as
+	// MsgStreamChunk and MsgStreamProgress messages with the request's ID,
+	// ending with MsgStreamDone or MsgError.
+	MsgRunToolStream MessageType = "run_tool_stream"
+
+	// MsgCancel asks the host to cancel the request with the same ID.
+	MsgCancel MessageType = "cancel"
+
+	// MsgPing asks the host for a MsgPong with the same ID, showing the
+	// connection is alive.
+	MsgPing MessageType = "ping"
+	MsgPong MessageType = "pong"
+
+	// MsgHello offers the host the codecs the guest can switch to, most
+	// preferred first. The host answers with a MsgHello naming its choice,
+	// after which both ends use it. It is sent before any request on a
+	// connection, and a MsgError answer, from a host that predates it,
+	// leaves the connection's codec unchanged.
+	MsgHello MessageType = "hello"
+
+	// MsgBatch carries several discovery, run_tool, and run_chain requests
+	// in one message. The host handles them concurrently and answers with
+	// a MsgBatch carrying their responses in the same order. A MsgCancel
+	// with one of their IDs cancels that request.
+	MsgBatch MessageType = "batch"
+
 	// Response message type
 	MsgResponse MessageType = "response"
 	MsgError    MessageType = "error"
+
+	// Stream message types
+	MsgStreamChunk    MessageType = "stream_chunk"
+	MsgStreamProgress MessageType = "stream_progress"
+	MsgStreamDone     MessageType = "stream_done"
 )
 
 // Message is the wire protocol envelope for gateway operations.
+// ExecutionID, when set, names the execution the request belongs to (see
+// runtime.WithExecutionID).
 type Message struct {
-	Type    MessageType    `json:"type"`
-	ID      string         `json:"id"`
-	Payload map[string]any `json:"payload,omitempty"`
+	Type        MessageType    `json:"type"`
+	ID          string         `json:"id"`
+	ExecutionID string         `json:"executionId,omitempty"`
+	Payload     map[string]any `json:"payload,omitempty"`
+
+	// Token authenticates a request to a host that verifies its callers,
+	// such as a gateway server with a Verifier. Responses carry none.
+	Token string `json:"token,omitempty"`
 }
 
 // Connection defines the interface for sending and receiving messages.
@@ -57,3 +99,27 @@ type Codec interface {
 	// Decode decodes bytes to a message.
 	Decode(data []byte) (Message, error)
 }
+
+// NamedCodec is a Codec that a hello exchange can negotiate.
+type NamedCodec interface {
+	Codec
+
+	// Name identifies the codec in MsgHello messages, such as "cbor".
+	Name() string
+}
+
+// CodecSwitcher is implemented by connections whose codec a hello
+// exchange can change, such as StreamConnection.
+//
+// Contract:
+// - Concurrency: SetCodec must not be called while a Send or Receive is in progress.
+// - Errors: SetCodec fails, leaving the codec unchanged, for codecs AcceptsCodec rejects.
+type CodecSwitcher interface {
+	// AcceptsCodec reports whether the connection's framing can carry
+	// messages encoded with c.
+	AcceptsCodec(c Codec) bool
+
+	// SetCodec switches the codec of the messages sent and received
+	// afterwards.
+	SetCodec(c Codec) error
+}
@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/jonwraymond/tooldiscovery/index"
 	"github.com/jonwraymond/tooldiscovery/tooldoc"
 	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
 )
 
 // Errors for proxy gateway operations.
@@ -27,18 +29,116 @@ type Config struct {
 
 	// Codec is the message codec to use. If nil, JSON is used.
 	Codec Codec
+
+	// Codecs lists the codecs to offer the host, most preferred first, in
+	// a MsgHello exchange that ReceiveResponses starts each connection
+	// with; requests wait for it. The connection keeps its codec when it
+	// is not a CodecSwitcher, when its framing carries none of them, or
+	// when the host picks none.
+	// Empty means no hello is sent.
+	Codecs []NamedCodec
+
+	// Token is sent with every request for the host to verify. Hosts
+	// typically hand a sandbox its token in GatewayTokenEnv.
+	Token string
+
+	// RequestTimeout bounds each request, from sending it to receiving its
+	// response. A request that runs out fails with ErrTimeout and is
+	// canceled on the host. Streams are bounded only by their context.
+	// Zero means requests wait as long as their context allows.
+	RequestTimeout time.Duration
+
+	// HeartbeatInterval is how often ReceiveResponses pings the host while
+	// it runs.
+	// Zero disables heartbeats.
+	HeartbeatInterval time.Duration
+
+	// HeartbeatTimeout is how long the host may send nothing, not even a
+	// pong, before the connection is considered lost: it is closed and
+	// pending requests fail with ErrConnectionClosed.
+	// Default: 3 × HeartbeatInterval
+	HeartbeatTimeout time.Duration
+
+	// Dialer opens a new connection to the host when Connection, or a
+	// connection it dialed earlier, is lost. Discovery requests in flight
+	// are replayed on the new connection; execution requests fail with a
+	// ConnectionLostError. Reconnecting requires ReceiveResponses to run.
+	// Nil means a lost connection is final.
+	Dialer Dialer
+
+	// Reconnect controls how often and how quickly Dialer is retried.
+	Reconnect ReconnectPolicy
+
+	// BatchWindow is how long a request waits for others to share a
+	// MsgBatch with, saving round trips when many requests are sent
+	// concurrently. Streams are never batched, and a request that waits
+	// alone is sent as it is. Hosts that predate MsgBatch are detected,
+	// and later requests are sent one by one.
+	// Zero means requests are sent immediately.
+	BatchWindow time.Duration
+
+	// MaxBatchSize caps the requests in one MsgBatch. A full batch is sent
+	// without waiting out BatchWindow.
+	// Default: 32
+	MaxBatchSize int
+
+	// CacheTTL is how long the response to a search_tools,
+	// list_namespaces, or describe_tool request is reused for identical
+	// requests from the same execution, sparing the host discovery calls
+	// that code repeats. Errors are not cached. InvalidateCache drops the
+	// cached responses.
+	// Zero disables caching.
+	CacheTTL time.Duration
+
+	// MaxCacheEntries caps the cached responses, evicting the oldest
+	// first.
+	// Default: 256
+	MaxCacheEntries int
 }
 
+// GatewayTokenEnv is the environment variable in which hosts pass a
+// sandbox the token for its gateway requests.
+const GatewayTokenEnv = "TOOLEXEC_GATEWAY_TOKEN"
+
 // Gateway implements ToolGateway by serializing requests over a connection.
 // This is used when the gateway needs to communicate across process boundaries,
 // such as when code runs in a Docker container.
 type Gateway struct {
-	conn      Connection
 	codec     Codec
+	token     string
 	requestID atomic.Uint64
-	pending   sync.Map // map[string]chan Message
+	pending   sync.Map // map[string]chan Message or *streamWaiter
 	closed    atomic.Bool
 	closeMu   sync.Mutex
+
+	requestTimeout    time.Duration
+	heartbeatInterval time.Duration
+	heartbeatTimeout  time.Duration
+	lastReceived      atomic.Int64 // Unix nanoseconds
+
+	dialer          Dialer
+	reconnectPolicy ReconnectPolicy
+	codecs          []NamedCodec
+
+	// batcher is nil unless BatchWindow is set. unbatched is set once the
+	// host rejects a MsgBatch.
+	batcher   *batcher
+	unbatched atomic.Bool
+
+	// cache is nil unless CacheTTL is set.
+	cache *responseCache
+
+	// mu guards link, the connection in use, and changed, which is closed
+	// and replaced whenever link is.
+	mu      sync.Mutex
+	link    *link
+	changed chan struct{}
+
+	// lost is closed, with lostErr set, once no connection can deliver
+	// responses and none will be dialed.
+	lost     chan struct{}
+	lostOnce sync.Once
+	lostErr  error
 }
 
 // New creates a new proxy gateway with the given configuration.
@@ -48,10 +148,39 @@ func New(cfg Config) *Gateway {
 		codec = &jsonCodec{}
 	}
 
-	return &Gateway{
-		conn:  cfg.Connection,
-		codec: codec,
+	heartbeatTimeout := cfg.HeartbeatTimeout
+	if heartbeatTimeout <= 0 {
+		heartbeatTimeout = 3 * cfg.HeartbeatInterval
+	}
+
+	g := &Gateway{
+		codec:             codec,
+		token:             cfg.Token,
+		requestTimeout:    cfg.RequestTimeout,
+		heartbeatInterval: cfg.HeartbeatInterval,
+		heartbeatTimeout:  heartbeatTimeout,
+		dialer:            cfg.Dialer,
+		reconnectPolicy:   cfg.Reconnect.withDefaults(),
+		codecs:            cfg.Codecs,
+		changed:           make(chan struct{}),
+		lost:              make(chan struct{}),
+	}
+	if cfg.BatchWindow > 0 {
+		maxSize := cfg.MaxBatchSize
+		if maxSize <= 0 {
+			maxSize = 32
+		}
+		g.batcher = &batcher{window: cfg.BatchWindow, maxSize: maxSize}
+	}
+	if cfg.CacheTTL > 0 {
+		maxEntries := cfg.MaxCacheEntries
+		if maxEntries <= 0 {
+			maxEntries = 256
+		}
+		g.cache = newResponseCache(cfg.CacheTTL, maxEntries)
 	}
+	g.link = g.newLink(cfg.Connection)
+	return g
 }
 
 // SearchTools sends a search request over the connection.
@@ -124,7 +253,9 @@ func (g *Gateway) ListNamespaces(ctx context.Context) ([]string, error) {
 	return namespaces, nil
 }
 
-// DescribeTool sends a describe tool request over the connection.
+// DescribeTool sends a describe tool request over the connection. The
+// host's ToolDoc is returned whole: the tool and its schemas, examples,
+// and notes, as far as level includes them.
 func (g *Gateway) DescribeTool(ctx context.Context, id string, level tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
 	if g.closed.Load() {
 		return tooldoc.ToolDoc{}, ErrConnectionClosed
@@ -138,12 +269,10 @@ func (g *Gateway) DescribeTool(ctx context.Context, id string, level tooldoc.Det
 		return tooldoc.ToolDoc{}, err
 	}
 
-	// Decode response - simplified version
-	doc := tooldoc.ToolDoc{
-		Summary: getString(resp.Payload, "summary"),
-		Notes:   getString(resp.Payload, "notes"),
+	var doc tooldoc.ToolDoc
+	if err := fromPayload(resp.Payload, &doc); err != nil {
+		return tooldoc.ToolDoc{}, fmt.Errorf("%w: decode %s response: %v", ErrProtocol, MsgDescribeTool, err)
 	}
-
 	return doc, nil
 }
 
@@ -200,15 +329,16 @@ func (g *Gateway) RunTool(ctx context.Context, id string, args map[string]any) (
 		return run.RunResult{}, err
 	}
 
-	// Decode response - simplified version
-	result := run.RunResult{
-		Structured: resp.Payload["structured"],
+	result, err := decodeRunResult(resp.Payload)
+	if err != nil {
+		return run.RunResult{}, fmt.Errorf("%w: decode %s response: %v", ErrProtocol, MsgRunTool, err)
 	}
-
 	return result, nil
 }
 
-// RunChain sends a run chain request over the connection.
+// RunChain sends a run chain request over the connection. When the chain
+// fails on the host, the results of the steps that ran are returned with
+// the error.
 func (g *Gateway) RunChain(ctx context.Context, steps []run.ChainStep) (run.RunResult, []run.StepResult, error) {
 	if g.closed.Load() {
 		return run.RunResult{}, nil, ErrConnectionClosed
@@ -218,44 +348,25 @@ func (g *Gateway) RunChain(ctx context.Context, steps []run.ChainStep) (run.RunR
 		return run.RunResult{}, nil, nil
 	}
 
-	// Serialize steps
-	stepsData := make([]map[string]any, len(steps))
-	for i, step := range steps {
-		stepsData[i] = map[string]any{
-			"toolId":      step.ToolID,
-			"args":        step.Args,
-			"usePrevious": step.UsePrevious,
-		}
+	stepsData, err := encodeChainSteps(steps)
+	if err != nil {
+		return run.RunResult{}, nil, fmt.Errorf("%w: encode %s request: %v", ErrProtocol, MsgRunChain, err)
 	}
 
 	resp, err := g.request(ctx, MsgRunChain, map[string]any{
 		"steps": stepsData,
 	})
-	if err != nil {
+	if resp.Payload == nil {
 		return run.RunResult{}, nil, err
 	}
 
-	// Decode response - simplified version
-	result := run.RunResult{
-		Structured: resp.Payload["structured"],
-	}
-
-	// Decode step results if present
-	var stepResults []run.StepResult
-	if results, ok := resp.Payload["stepResults"].([]any); ok {
-		for _, r := range results {
-			if m, ok := r.(map[string]any); ok {
-				sr := run.StepResult{
-					ToolID: getString(m, "toolId"),
-					Result: run.RunResult{
-						Structured: m["structured"],
-					},
-				}
-				stepResults = append(stepResults, sr)
-			}
-		}
+	result, stepResults, decodeErr := decodeChainResult(resp.Payload)
+	if err != nil {
+		return result, stepResults, err
+	}
+	if decodeErr != nil {
+		return run.RunResult{}, nil, fmt.Errorf("%w: decode %s response: %v", ErrProtocol, MsgRunChain, decodeErr)
 	}
-
 	return result, stepResults, nil
 }
 
@@ -269,61 +380,213 @@ func (g *Gateway) Close() error {
 	}
 
 	g.closed.Store(true)
-	return g.conn.Close()
+	g.fail(ErrConnectionClosed)
+	l, _ := g.current()
+	return l.conn.Close()
+}
+
+// fail marks the gateway lost, failing pending and later requests with
+// err.
+func (g *Gateway) fail(err error) {
+	g.lostOnce.Do(func() {
+		g.lostErr = err
+		close(g.lost)
+	})
 }
 
-// request sends a request and waits for the response.
+// newRequest returns a request with a fresh ID.
+func (g *Gateway) newRequest(ctx context.Context, msgType MessageType, payload map[string]any) Message {
+	return Message{
+		Type:        msgType,
+		ID:          fmt.Sprintf("%d", g.requestID.Add(1)),
+		ExecutionID: runtime.ExecutionIDFromContext(ctx),
+		Payload:     payload,
+		Token:       g.token,
+	}
+}
+
+// request sends a request and waits for the response, unless an identical
+// discovery request was answered within CacheTTL.
 func (g *Gateway) request(ctx context.Context, msgType MessageType, payload map[string]any) (Message, error) {
-	id := fmt.Sprintf("%d", g.requestID.Add(1))
+	key, ok := g.cacheKey(ctx, msgType, payload)
+	if !ok {
+		return g.roundTrip(ctx, msgType, payload)
+	}
+	if resp, hit := g.cache.get(key); hit {
+		return resp, nil
+	}
+	resp, err := g.roundTrip(ctx, msgType, payload)
+	if err == nil {
+		g.cache.set(key, resp)
+	}
+	return resp, err
+}
+
+// roundTrip sends a request and waits for the response. Discovery requests
+// interrupted by a lost connection are sent again once the gateway has
+// reconnected.
+func (g *Gateway) roundTrip(ctx context.Context, msgType MessageType, payload map[string]any) (Message, error) {
+	msg := g.newRequest(ctx, msgType, payload)
 
-	msg := Message{
-		Type:    msgType,
-		ID:      id,
-		Payload: payload,
+	reqCtx := ctx
+	if g.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, g.requestTimeout)
+		defer cancel()
 	}
 
 	// Create response channel
 	respCh := make(chan Message, 1)
-	g.pending.Store(id, respCh)
-	defer g.pending.Delete(id)
+	g.pending.Store(msg.ID, respCh)
+	defer g.pending.Delete(msg.ID)
 
-	// Send request
-	if err := g.conn.Send(ctx, msg); err != nil {
-		return Message{}, err
-	}
+	for {
+		l, err := g.connected(reqCtx)
+		if err != nil {
+			return Message{}, g.requestErr(ctx, msgType, err)
+		}
 
-	// Wait for response
-	select {
-	case <-ctx.Done():
-		return Message{}, ctx.Err()
-	case resp := <-respCh:
-		if resp.Type == MsgError {
-			errMsg := getString(resp.Payload, "error")
-			if errMsg == "" {
-				errMsg = "unknown error"
+		// Send request
+		if err := g.send(reqCtx, l, msg); err != nil && !g.broken(reqCtx, l, err) {
+			return Message{}, g.requestErr(ctx, msgType, err)
+		}
+
+		// Wait for response
+		select {
+		case <-reqCtx.Done():
+			g.cancel(reqCtx, l, msg.ID)
+			return Message{}, g.requestErr(ctx, msgType, reqCtx.Err())
+		case resp := <-respCh:
+			return response(resp)
+		case <-l.lost:
+			select {
+			case resp := <-respCh:
+				return response(resp)
+			default:
+			}
+			if !idempotent(msgType) {
+				return Message{}, &ConnectionLostError{Type: msgType, RequestID: msg.ID, Err: l.err}
 			}
-			return Message{}, errors.New(errMsg)
 		}
-		return resp, nil
 	}
 }
 
+// response returns resp and, for a MsgError, the error it carries.
+func response(resp Message) (Message, error) {
+	if resp.Type == MsgError {
+		return resp, decodeError(resp.Payload)
+	}
+	return resp, nil
+}
+
+// requestErr reports a request that ran out of RequestTimeout, rather
+// than ctx, as ErrTimeout.
+func (g *Gateway) requestErr(ctx context.Context, msgType MessageType, err error) error {
+	if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: no response to %s within %v", ErrTimeout, msgType, g.requestTimeout)
+	}
+	return err
+}
+
+// cancel asks the host, best-effort, to cancel the request with ID id,
+// sent on l, after its context, ctx, has ended.
+func (g *Gateway) cancel(ctx context.Context, l *link, id string) {
+	sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), streamGrace)
+	defer cancel()
+	_ = l.conn.Send(sendCtx, Message{Type: MsgCancel, ID: id, Token: g.token})
+}
+
 // DeliverResponse delivers a response to a pending request.
 // This is called by the connection handler when a response is received.
 func (g *Gateway) DeliverResponse(msg Message) error {
-	ch, ok := g.pending.Load(msg.ID)
+	waiter, ok := g.pending.Load(msg.ID)
 	if !ok {
 		return fmt.Errorf("%w: no pending request for ID %s", ErrProtocol, msg.ID)
 	}
 
+	if s, ok := waiter.(*streamWaiter); ok {
+		// Streams apply backpressure rather than drop events.
+		select {
+		case s.messages <- msg:
+			return nil
+		case <-s.done:
+			return fmt.Errorf("%w: stream %s ended", ErrProtocol, msg.ID)
+		}
+	}
 	select {
-	case ch.(chan Message) <- msg:
+	case waiter.(chan Message) <- msg:
 		return nil
 	default:
 		return fmt.Errorf("%w: response channel full for ID %s", ErrProtocol, msg.ID)
 	}
 }
 
+// ReceiveResponses receives messages from the connection and delivers
+// each to the request awaiting it, until the connection fails or ctx ends.
+// Connections that need a reader to deliver responses run it in a
+// goroutine for the gateway's lifetime. Responses no request awaits, such
+// as ones whose context has ended, are dropped. While it runs, the host is
+// pinged every HeartbeatInterval. Each connection starts with a hello
+// exchange when Codecs are configured. When the connection fails and a Dialer
+// is configured, it reconnects and carries on. When it stops, pending and
+// later requests fail with ErrConnectionClosed rather than wait for
+// responses that cannot arrive. It returns nil once the gateway is closed.
+func (g *Gateway) ReceiveResponses(ctx context.Context) error {
+	l, _ := g.current()
+	for {
+		err := g.hello(ctx, l)
+		if err == nil {
+			err = g.receive(ctx, l)
+		}
+		if g.closed.Load() {
+			return nil
+		}
+		if !errors.Is(err, ErrConnectionClosed) {
+			err = fmt.Errorf("%w: %v", ErrConnectionClosed, err)
+		}
+		// A heartbeat failure explains the closed connection.
+		l.fail(err)
+		if g.dialer == nil || ctx.Err() != nil {
+			g.fail(l.err)
+			return g.lostErr
+		}
+		if l, err = g.reconnect(ctx, l); err != nil {
+			if g.closed.Load() {
+				return nil
+			}
+			g.fail(err)
+			return g.lostErr
+		}
+	}
+}
+
+// receive delivers the responses arriving on l until it fails.
+func (g *Gateway) receive(ctx context.Context, l *link) error {
+	g.lastReceived.Store(time.Now().UnixNano())
+	if g.heartbeatInterval > 0 {
+		hbCtx, stop := context.WithCancel(ctx)
+		defer stop()
+		go g.heartbeat(hbCtx, l)
+	}
+	for {
+		msg, err := l.conn.Receive(ctx)
+		if err != nil {
+			return err
+		}
+		g.lastReceived.Store(time.Now().UnixNano())
+		switch msg.Type {
+		case MsgPong:
+			continue
+		case MsgBatch, MsgError:
+			if _, ok := l.batches.Load(msg.ID); ok {
+				g.deliverBatch(ctx, l, msg)
+				continue
+			}
+		}
+		_ = g.DeliverResponse(msg)
+	}
+}
+
 // getString safely extracts a string from a map.
 func getString(m map[string]any, key string) string {
 	if v, ok := m[key].(string); ok {
@@ -335,6 +598,9 @@ func getString(m map[string]any, key string) string {
 // jsonCodec implements Codec using JSON encoding.
 type jsonCodec struct{}
 
+// Name implements NamedCodec.
+func (c *jsonCodec) Name() string { return "json" }
+
 func (c *jsonCodec) Encode(msg Message) ([]byte, error) {
 	return json.Marshal(msg)
 }
@@ -247,6 +247,24 @@ func TestGatewayRunTool(t *testing.T) {
 	}
 }
 
+func TestGatewayExecutionID(t *testing.T) {
+	var got string
+	conn := newAutoRespondConnection(func(msg Message) Message {
+		got = msg.ExecutionID
+		return Message{Type: MsgResponse, ID: msg.ID, Payload: map[string]any{}}
+	})
+	gw := New(Config{Connection: conn})
+	conn.SetGateway(gw)
+
+	ctx := runtime.WithExecutionID(context.Background(), "exec-1")
+	if _, err := gw.RunTool(ctx, "test:tool", nil); err != nil {
+		t.Fatalf("RunTool() error = %v", err)
+	}
+	if got != "exec-1" {
+		t.Errorf("Message.ExecutionID = %q, want exec-1", got)
+	}
+}
+
 func TestGatewayRunChain(t *testing.T) {
 	conn := newAutoRespondConnection(func(msg Message) Message {
 		return Message{
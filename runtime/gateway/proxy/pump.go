@@ -0,0 +1,304 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// Handler answers the gateway requests a Pump receives on the host.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines.
+// - Errors: failures are answered with a MsgError response carrying the
+// request's ID, not returned.
+type Handler interface {
+	// ServeMessage answers req with a MsgResponse or MsgError message.
+	ServeMessage(ctx context.Context, req Message) Message
+}
+
+// HandlerFunc adapts a function to the Handler interface.
+type HandlerFunc func(ctx context.Context, req Message) Message
+
+// ServeMessage calls f.
+func (f HandlerFunc) ServeMessage(ctx context.Context, req Message) Message {
+	return f(ctx, req)
+}
+
+// StreamHandler is implemented by Handlers that can stream results. Pump
+// passes it MsgRunToolStream requests; other Handlers answer those with a
+// MsgError.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: when ctx ends, the stream must end with a MsgError.
+// - Errors: failures are sent as the stream's final MsgError, not returned.
+type StreamHandler interface {
+	Handler
+
+	// ServeStream answers req by calling send with each message of its
+	// stream, ending with MsgStreamDone or MsgError.
+	ServeStream(ctx context.Context, req Message, send func(Message) error)
+}
+
+// ErrorResponse returns the MsgError answer to the request with ID id.
+// Besides err's message, it carries err's run.ErrorCode and any
+// *run.ToolError's context, so the error Gateway returns matches the same
+// sentinels with errors.Is and the same *run.ToolError with errors.As.
+func ErrorResponse(id string, err error) Message {
+	payload, encErr := toPayload(encodeError(err))
+	if encErr != nil {
+		payload = map[string]any{"error": err.Error()}
+	}
+	return Message{Type: MsgError, ID: id, Payload: payload}
+}
+
+// NewGatewayHandler returns a Handler that answers requests by calling
+// gw, such as a direct.Gateway over the host's index, docs, and
+// run.Runner, in the payload shapes Gateway decodes. A request's
+// ExecutionID reaches gw on the context. It streams MsgRunToolStream
+// requests when gw is a runtime.StreamingGateway.
+func NewGatewayHandler(gw runtime.ToolGateway) StreamHandler {
+	return gatewayHandler{gw: gw}
+}
+
+type gatewayHandler struct {
+	gw runtime.ToolGateway
+}
+
+func (h gatewayHandler) ServeMessage(ctx context.Context, req Message) Message {
+	payload, err := dispatch(withExecutionID(ctx, req), h.gw, req)
+	if err != nil {
+		resp := ErrorResponse(req.ID, err)
+		// A failed run_chain still reports the steps that ran.
+		for k, v := range payload {
+			if _, ok := resp.Payload[k]; !ok {
+				resp.Payload[k] = v
+			}
+		}
+		return resp
+	}
+	return Message{Type: MsgResponse, ID: req.ID, Payload: payload}
+}
+
+func (h gatewayHandler) ServeStream(ctx context.Context, req Message, send func(Message) error) {
+	serveStream(withExecutionID(ctx, req), h.gw, req, send)
+}
+
+func withExecutionID(ctx context.Context, req Message) context.Context {
+	if req.ExecutionID != "" && runtime.ExecutionIDFromContext(ctx) == "" {
+		ctx = runtime.WithExecutionID(ctx, req.ExecutionID)
+	}
+	return ctx
+}
+
+// Pump serves the requests received on conn with h until conn closes or
+// ctx ends, sending each response on conn. Requests are handled
+// concurrently, so a slow tool does not hold up the others; Pump waits
+// for those in flight before returning. MsgCancel cancels the context of
+// the request with its ID, and MsgPing is answered with MsgPong. The
+// requests of a MsgBatch are handled like others, and answered together
+// once all are done. A MsgHello can switch conn to a codec given with
+// WithCodecs. It returns nil when the peer closes the connection, such as
+// when a sandboxed process exits.
+func Pump(ctx context.Context, conn Connection, h Handler, opts ...PumpOption) error {
+	var o pumpOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		cancels = make(map[string]context.CancelFunc)
+	)
+	defer wg.Wait()
+
+	// start serves req in its own goroutine, under a context that a
+	// MsgCancel with req's ID cancels.
+	start := func(req Message, serve func(ctx context.Context)) {
+		reqCtx, cancel := context.WithCancel(ctx)
+		mu.Lock()
+		cancels[req.ID] = cancel
+		mu.Unlock()
+		wg.Go(func() {
+			defer func() {
+				mu.Lock()
+				delete(cancels, req.ID)
+				mu.Unlock()
+				cancel()
+			}()
+			serve(reqCtx)
+		})
+	}
+	for {
+		req, err := conn.Receive(ctx)
+		if err != nil {
+			if errors.Is(err, ErrConnectionClosed) {
+				return nil
+			}
+			return err
+		}
+		switch req.Type {
+		case MsgResponse, MsgError:
+			continue
+		case MsgCancel:
+			mu.Lock()
+			if cancel, ok := cancels[req.ID]; ok {
+				cancel()
+			}
+			mu.Unlock()
+			continue
+		case MsgPing:
+			wg.Go(func() { _ = conn.Send(ctx, Message{Type: MsgPong, ID: req.ID}) })
+			continue
+		case MsgHello:
+			// Answered before reading on, so that the next request is
+			// decoded with the codec picked.
+			mu.Lock()
+			busy := len(cancels) > 0
+			mu.Unlock()
+			if err := answerHello(ctx, conn, req, o.codecs, busy); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if req.Type == MsgBatch {
+			reqs, err := decodeBatch(req.Payload, "requests")
+			if err != nil {
+				wg.Go(func() { _ = conn.Send(ctx, ErrorResponse(req.ID, err)) })
+				continue
+			}
+			var batch sync.WaitGroup
+			responses := make([]Message, len(reqs))
+			for i, sub := range reqs {
+				batch.Add(1)
+				start(sub, func(subCtx context.Context) {
+					defer batch.Done()
+					responses[i] = serveBatched(subCtx, h, sub)
+				})
+			}
+			wg.Go(func() {
+				batch.Wait()
+				_ = conn.Send(ctx, Message{Type: MsgBatch, ID: req.ID, Payload: encodeBatch("responses", responses)})
+			})
+			continue
+		}
+		start(req, func(reqCtx context.Context) {
+			if sh, ok := h.(StreamHandler); ok && req.Type == MsgRunToolStream {
+				// Stream messages outlive a canceled request, so its
+				// final MsgError reaches the peer.
+				sh.ServeStream(reqCtx, req, func(msg Message) error { return conn.Send(ctx, msg) })
+				return
+			}
+			_ = conn.Send(ctx, h.ServeMessage(reqCtx, req))
+		})
+	}
+}
+
+func dispatch(ctx context.Context, gw runtime.ToolGateway, req Message) (map[string]any, error) {
+	p := req.Payload
+	switch req.Type {
+	case MsgSearchTools:
+		summaries, err := gw.SearchTools(ctx, getString(p, "query"), getInt(p, "limit"))
+		if err != nil {
+			return nil, err
+		}
+		results := make([]any, len(summaries))
+		for i, s := range summaries {
+			results[i] = map[string]any{
+				"id":               s.ID,
+				"name":             s.Name,
+				"namespace":        s.Namespace,
+				"shortDescription": s.ShortDescription,
+				"tags":             s.Tags,
+			}
+		}
+		return map[string]any{"results": results}, nil
+
+	case MsgListNamespaces:
+		namespaces, err := gw.ListNamespaces(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"namespaces": namespaces}, nil
+
+	case MsgDescribeTool:
+		doc, err := gw.DescribeTool(ctx, getString(p, "id"), tooldoc.DetailLevel(getString(p, "level")))
+		if err != nil {
+			return nil, err
+		}
+		// The doc is sent whole, in its JSON encoding; its detail level
+		// decides which fields are set.
+		payload, err := toPayload(doc)
+		if err != nil {
+			return nil, fmt.Errorf("encode tool doc: %w", err)
+		}
+		return payload, nil
+
+	case MsgListToolExamples:
+		examples, err := gw.ListToolExamples(ctx, getString(p, "id"), getInt(p, "max"))
+		if err != nil {
+			return nil, err
+		}
+		results := make([]any, len(examples))
+		for i, ex := range examples {
+			results[i] = map[string]any{
+				"id":          ex.ID,
+				"title":       ex.Title,
+				"description": ex.Description,
+				"resultHint":  ex.ResultHint,
+				"args":        ex.Args,
+			}
+		}
+		return map[string]any{"examples": results}, nil
+
+	case MsgRunTool:
+		args, _ := p["args"].(map[string]any)
+		result, err := gw.RunTool(ctx, getString(p, "id"), args)
+		if err != nil {
+			return nil, err
+		}
+		payload, err := encodeRunResult(result)
+		if err != nil {
+			return nil, fmt.Errorf("encode result: %w", err)
+		}
+		return payload, nil
+
+	case MsgRunChain:
+		steps, err := decodeChainSteps(p["steps"])
+		if err != nil {
+			return nil, err
+		}
+		result, stepResults, err := gw.RunChain(ctx, steps)
+		payload, encErr := encodeChainResult(result, stepResults)
+		if encErr != nil {
+			return nil, errors.Join(err, fmt.Errorf("encode result: %w", encErr))
+		}
+		return payload, err
+
+	default:
+		return nil, fmt.Errorf("%w: unknown request type %q", ErrProtocol, req.Type)
+	}
+}
+
+// getInt reads a number, which JSON decodes as float64 and binary
+// codecs may decode as an integer type.
+func getInt(m map[string]any, key string) int {
+	switch v := m[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case uint64:
+		return int(v)
+	}
+	return 0
+}
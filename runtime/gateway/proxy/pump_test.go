@@ -0,0 +1,171 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// hostGateway is the host's gateway: run_tool echoes the tool ID and the
+// execution ID; other calls fail.
+type hostGateway struct{}
+
+func (hostGateway) SearchTools(context.Context, string, int) ([]index.Summary, error) {
+	return []index.Summary{{ID: "ns:echo", Name: "echo", Namespace: "ns"}}, nil
+}
+
+func (hostGateway) ListNamespaces(context.Context) ([]string, error) {
+	return nil, errors.New("not served")
+}
+
+func (hostGateway) DescribeTool(context.Context, string, tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	return tooldoc.ToolDoc{}, errors.New("not served")
+}
+
+func (hostGateway) ListToolExamples(context.Context, string, int) ([]tooldoc.ToolExample, error) {
+	return nil, errors.New("not served")
+}
+
+func (hostGateway) RunTool(ctx context.Context, id string, _ map[string]any) (run.RunResult, error) {
+	return run.RunResult{Structured: id + "@" + runtime.ExecutionIDFromContext(ctx)}, nil
+}
+
+func (hostGateway) RunChain(context.Context, []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	return run.RunResult{}, nil, errors.New("not served")
+}
+
+// newStdioPair returns a guest connection and a host connection joined by
+// OS pipes, as a sandboxed process's stdin and stdout are.
+func newStdioPair(t *testing.T) (guest, host *StreamConnection) {
+	t.Helper()
+	hostR, guestW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	guestR, hostW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	guest, host = NewLineConnection(Pipe(guestR, guestW)), NewLineConnection(Pipe(hostR, hostW))
+	t.Cleanup(func() {
+		_ = guest.Close()
+		_ = host.Close()
+	})
+	return guest, host
+}
+
+func TestPump_Stdio(t *testing.T) {
+	guest, host := newStdioPair(t)
+	pumped := make(chan error, 1)
+	go func() { pumped <- Pump(context.Background(), host, NewGatewayHandler(hostGateway{})) }()
+
+	g := New(Config{Connection: guest})
+	go func() { _ = g.ReceiveResponses(context.Background()) }()
+	ctx := runtime.WithExecutionID(context.Background(), "exec-1")
+
+	result, err := g.RunTool(ctx, "ns:echo", nil)
+	if err != nil || result.Structured != "ns:echo@exec-1" {
+		t.Errorf("RunTool() = %+v, %v; want the host's echo", result, err)
+	}
+	if tools, err := g.SearchTools(ctx, "echo", 5); err != nil || len(tools) != 1 || tools[0].ID != "ns:echo" {
+		t.Errorf("SearchTools() = %+v, %v; want ns:echo", tools, err)
+	}
+	if _, err := g.ListNamespaces(ctx); err == nil || err.Error() != "not served" {
+		t.Errorf("ListNamespaces() error = %v, want the host's error", err)
+	}
+
+	// The guest exiting closes its pipes, which ends the pump.
+	_ = g.Close()
+	if err := <-pumped; err != nil {
+		t.Errorf("Pump() error = %v, want nil once the guest is gone", err)
+	}
+}
+
+func TestPump_UnknownRequest(t *testing.T) {
+	guest, host := newStdioPair(t)
+	go func() { _ = Pump(context.Background(), host, NewGatewayHandler(hostGateway{})) }()
+
+	if err := guest.Send(context.Background(), Message{Type: "format_disk", ID: "1"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	resp, err := guest.Receive(context.Background())
+	if err != nil || resp.Type != MsgError || resp.ID != "1" {
+		t.Errorf("Receive() = %+v, %v; want an error response to request 1", resp, err)
+	}
+
+	_, idle := newStdioPair(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Pump(ctx, idle, NewGatewayHandler(hostGateway{})); !errors.Is(err, context.Canceled) {
+		t.Errorf("Pump() with a canceled context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+// chainGateway is a hostGateway whose run_chain records the steps it
+// receives.
+type chainGateway struct {
+	hostGateway
+	steps chan []run.ChainStep
+}
+
+func (g chainGateway) RunChain(_ context.Context, steps []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	g.steps <- steps
+	return run.RunResult{Structured: "done"}, nil, nil
+}
+
+func TestPump_RunChainRetry(t *testing.T) {
+	guest, host := newStdioPair(t)
+	gw := chainGateway{steps: make(chan []run.ChainStep, 1)}
+	go func() { _ = Pump(context.Background(), host, NewGatewayHandler(gw)) }()
+
+	g := New(Config{Connection: guest})
+	go func() { _ = g.ReceiveResponses(context.Background()) }()
+	defer func() { _ = g.Close() }()
+
+	retry := &run.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     50 * time.Millisecond,
+		MaxBackoff:  time.Second,
+		RetryOn:     []run.ErrorCode{run.ErrorCodeExecution, run.ErrorCodeTimeout},
+	}
+	steps := []run.ChainStep{{ToolID: "ns:a", Retry: retry}, {ToolID: "ns:b", UsePrevious: true}}
+	if _, _, err := g.RunChain(context.Background(), steps); err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	got := <-gw.steps
+	if len(got) != 2 || got[0].Retry == nil || !reflect.DeepEqual(*got[0].Retry, *retry) {
+		t.Fatalf("host steps = %+v, want the first with retry %+v", got, retry)
+	}
+	if got[1].Retry != nil || !got[1].UsePrevious {
+		t.Errorf("host step 2 = %+v, want no retry and UsePrevious", got[1])
+	}
+}
+
+func TestLineConnection_Framing(t *testing.T) {
+	hostR, guestW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := NewLineConnection(Pipe(hostR, nil))
+	defer host.Close()
+
+	go func() {
+		_, _ = guestW.WriteString("\n{\"type\":\"run_tool\",\"id\":\"1\"}\r\n{\"type\":")
+		_ = guestW.Close()
+	}()
+	msg, err := host.Receive(context.Background())
+	if err != nil || msg.Type != MsgRunTool || msg.ID != "1" {
+		t.Errorf("Receive() = %+v, %v; want run_tool 1 after the blank line", msg, err)
+	}
+	if _, err := host.Receive(context.Background()); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Receive() of a truncated line error = %v, want %v", err, ErrConnectionClosed)
+	}
+}
@@ -0,0 +1,232 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Dialer opens connections to the host for a gateway to reconnect with.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines.
+// - Ownership: the gateway closes the connections it is returned.
+type Dialer interface {
+	// Dial opens a new connection to the host.
+	Dial(ctx context.Context) (Connection, error)
+}
+
+// DialerFunc adapts a function to a Dialer.
+type DialerFunc func(ctx context.Context) (Connection, error)
+
+// Dial calls f.
+func (f DialerFunc) Dial(ctx context.Context) (Connection, error) {
+	return f(ctx)
+}
+
+// ReconnectPolicy controls how a gateway re-dials a lost connection.
+// Attempts wait Backoff, doubling each time up to MaxBackoff.
+type ReconnectPolicy struct {
+	// MaxAttempts is how many times the connection is dialed before the
+	// gateway gives up and fails all requests.
+	// Default: 5
+	MaxAttempts int
+
+	// Backoff is the delay before the first attempt.
+	// Default: 100ms
+	Backoff time.Duration
+
+	// MaxBackoff caps the delay between attempts.
+	// Default: 5s
+	MaxBackoff time.Duration
+}
+
+// withDefaults returns p with zero fields set to their defaults.
+func (p ReconnectPolicy) withDefaults() ReconnectPolicy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = 5
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 5 * time.Second
+	}
+	return p
+}
+
+// ConnectionLostError reports an execution request whose connection was
+// lost after the request was sent. The host may or may not have run it, so
+// unlike a discovery request it is not replayed on a new connection; the
+// caller decides whether running it twice is safe.
+type ConnectionLostError struct {
+	// Type is the request's message type, such as MsgRunTool.
+	Type MessageType
+
+	// RequestID is the request's ID.
+	RequestID string
+
+	// Err is why the connection was lost. It wraps ErrConnectionClosed.
+	Err error
+}
+
+func (e *ConnectionLostError) Error() string {
+	return fmt.Sprintf("%s request %s interrupted: %v", e.Type, e.RequestID, e.Err)
+}
+
+func (e *ConnectionLostError) Unwrap() error { return e.Err }
+
+// idempotent reports whether requests of type t can be sent again without
+// effect on the host.
+func idempotent(t MessageType) bool {
+	switch t {
+	case MsgSearchTools, MsgListNamespaces, MsgDescribeTool, MsgListToolExamples:
+		return true
+	default:
+		return false
+	}
+}
+
+// link is one connection of a gateway.
+type link struct {
+	conn Connection
+
+	// ready is closed once conn may carry requests, after its hello
+	// exchange.
+	ready chan struct{}
+
+	// lost is closed, with err set, once conn can no longer deliver
+	// responses.
+	lost chan struct{}
+	once sync.Once
+	err  error
+
+	// batches maps the IDs of the MsgBatch messages sent on conn to the
+	// requests they carry.
+	batches sync.Map // map[string][]Message
+}
+
+// newLink returns a link over conn, ready for requests unless it needs a
+// hello exchange first.
+func (g *Gateway) newLink(conn Connection) *link {
+	l := &link{conn: conn, ready: make(chan struct{}), lost: make(chan struct{})}
+	if len(g.codecs) == 0 {
+		close(l.ready)
+	}
+	return l
+}
+
+// fail marks the link lost with err.
+func (l *link) fail(err error) {
+	l.once.Do(func() {
+		l.err = err
+		close(l.lost)
+	})
+}
+
+// current returns the gateway's link and a channel closed when it is
+// replaced.
+func (g *Gateway) current() (*link, <-chan struct{}) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.link, g.changed
+}
+
+// connected returns the gateway's link, waiting while a lost one is
+// replaced or a new one says hello.
+func (g *Gateway) connected(ctx context.Context) (*link, error) {
+	for {
+		l, changed := g.current()
+		select {
+		case <-g.lost:
+			return nil, g.lostErr
+		default:
+		}
+		ready := l.ready
+		select {
+		case <-l.lost:
+			// Wait for the replacement.
+			ready = nil
+		default:
+			select {
+			case <-l.ready:
+				return l, nil
+			default:
+			}
+		}
+		select {
+		case <-changed:
+		case <-ready:
+		case <-g.lost:
+			return nil, g.lostErr
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// broken reports whether err, from sending on l, means l's connection is
+// broken. If so, and the gateway can reconnect, l is closed and marked
+// lost so that ReceiveResponses replaces it.
+func (g *Gateway) broken(ctx context.Context, l *link, err error) bool {
+	if g.dialer == nil || ctx.Err() != nil || errors.Is(err, ErrProtocol) {
+		return false
+	}
+	if !errors.Is(err, ErrConnectionClosed) {
+		err = fmt.Errorf("%w: %v", ErrConnectionClosed, err)
+	}
+	l.fail(err)
+	_ = l.conn.Close()
+	return true
+}
+
+// reconnect closes l, which was lost, and dials its replacement under the
+// ReconnectPolicy.
+func (g *Gateway) reconnect(ctx context.Context, l *link) (*link, error) {
+	_ = l.conn.Close()
+	policy := g.reconnectPolicy
+	backoff := policy.Backoff
+	lastErr := l.err
+	for range policy.MaxAttempts {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w: %v", ErrConnectionClosed, ctx.Err())
+		case <-g.lost:
+			timer.Stop()
+			return nil, g.lostErr
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, policy.MaxBackoff)
+
+		conn, err := g.dialer.Dial(ctx)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		next := g.newLink(conn)
+		if !g.install(next) {
+			_ = conn.Close()
+			return nil, ErrConnectionClosed
+		}
+		return next, nil
+	}
+	return nil, fmt.Errorf("%w: %d reconnect attempts failed: %v", ErrConnectionClosed, policy.MaxAttempts, lastErr)
+}
+
+// install makes l the gateway's link, unless the gateway is closed.
+func (g *Gateway) install(l *link) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed.Load() {
+		return false
+	}
+	g.link = l
+	close(g.changed)
+	g.changed = make(chan struct{})
+	return true
+}
@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// servedDialer dials stdio pairs whose host end is pumped to hostGateway.
+func servedDialer(t *testing.T, dials *atomic.Int32) Dialer {
+	return DialerFunc(func(context.Context) (Connection, error) {
+		dials.Add(1)
+		guest, host := newStdioPair(t)
+		go func() { _ = Pump(context.Background(), host, NewGatewayHandler(hostGateway{})) }()
+		return guest, nil
+	})
+}
+
+func TestGateway_Reconnect(t *testing.T) {
+	guest, host := newStdioPair(t)
+	received := make(chan MessageType, 4)
+	go func() {
+		for {
+			msg, err := host.Receive(context.Background())
+			if err != nil {
+				return
+			}
+			received <- msg.Type
+		}
+	}()
+	var dials atomic.Int32
+	g := New(Config{
+		Connection: guest,
+		Dialer:     servedDialer(t, &dials),
+		Reconnect:  ReconnectPolicy{Backoff: time.Millisecond},
+	})
+	go func() { _ = g.ReceiveResponses(context.Background()) }()
+
+	searched := make(chan error, 1)
+	go func() {
+		tools, err := g.SearchTools(context.Background(), "echo", 5)
+		if err == nil && (len(tools) != 1 || tools[0].ID != "ns:echo") {
+			err = errors.New("unexpected results")
+		}
+		searched <- err
+	}()
+	ran := make(chan error, 1)
+	go func() {
+		_, err := g.RunTool(context.Background(), "ns:echo", nil)
+		ran <- err
+	}()
+	// Drop the connection once both requests are in flight.
+	<-received
+	<-received
+	_ = host.Close()
+
+	if err := <-searched; err != nil {
+		t.Errorf("SearchTools() across the reconnect error = %v", err)
+	}
+	err := <-ran
+	var lost *ConnectionLostError
+	if !errors.As(err, &lost) || lost.Type != MsgRunTool || !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("RunTool() across the reconnect error = %v, want a ConnectionLostError", err)
+	}
+	if result, err := g.RunTool(context.Background(), "ns:echo", nil); err != nil || result.Structured != "ns:echo@" {
+		t.Errorf("RunTool() after reconnecting = %+v, %v", result, err)
+	}
+	if n := dials.Load(); n != 1 {
+		t.Errorf("dialed %d times, want 1", n)
+	}
+}
+
+func TestGateway_ReconnectGivesUp(t *testing.T) {
+	guest, host := newStdioPair(t)
+	var dials atomic.Int32
+	g := New(Config{
+		Connection: guest,
+		Dialer: DialerFunc(func(context.Context) (Connection, error) {
+			dials.Add(1)
+			return nil, errors.New("host unreachable")
+		}),
+		Reconnect: ReconnectPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+	})
+	done := make(chan error, 1)
+	go func() { done <- g.ReceiveResponses(context.Background()) }()
+	_ = host.Close()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrConnectionClosed) || !strings.Contains(err.Error(), "host unreachable") {
+			t.Errorf("ReceiveResponses() error = %v, want the last dial error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReceiveResponses() did not give up")
+	}
+	if n := dials.Load(); n != 3 {
+		t.Errorf("dialed %d times, want 3", n)
+	}
+	if _, err := g.SearchTools(context.Background(), "echo", 5); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("SearchTools() after giving up error = %v, want %v", err, ErrConnectionClosed)
+	}
+}
+
+func TestGateway_CloseWhileReconnecting(t *testing.T) {
+	guest, host := newStdioPair(t)
+	g := New(Config{
+		Connection: guest,
+		Dialer: DialerFunc(func(context.Context) (Connection, error) {
+			return nil, errors.New("host unreachable")
+		}),
+		Reconnect: ReconnectPolicy{MaxAttempts: 1000, Backoff: time.Millisecond},
+	})
+	done := make(chan error, 1)
+	go func() { done <- g.ReceiveResponses(context.Background()) }()
+	_ = host.Close()
+
+	pending := make(chan error, 1)
+	go func() {
+		_, err := g.ListNamespaces(context.Background())
+		pending <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if err := g.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("ReceiveResponses() after Close = %v, want nil", err)
+	}
+	if err := <-pending; !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("pending ListNamespaces() error = %v, want %v", err, ErrConnectionClosed)
+	}
+}
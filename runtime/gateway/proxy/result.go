@@ -0,0 +1,232 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+// The wire form of a run.RunResult is its JSON encoding: the tool,
+// backend, structured value, content items, and raw MCP result. Peers that
+// read only "structured" still find it. A run_chain response adds
+// "stepResults", each a run.StepResult with its error, and a run_chain
+// that fails is answered with a MsgError that also carries the partial
+// results.
+
+// wireStepResult is the wire form of run.StepResult, whose JSON encoding
+// omits Err.
+type wireStepResult struct {
+	run.StepResult
+	Error *wireError `json:"error,omitempty"`
+}
+
+// wireError is the wire form of an error. Code lets the peer match the
+// run and context sentinels with errors.Is, and ToolError keeps what
+// errors.As needs to find a *run.ToolError.
+type wireError struct {
+	Message   string         `json:"error"`
+	Code      run.ErrorCode  `json:"code,omitempty"`
+	ToolError *wireToolError `json:"toolError,omitempty"`
+}
+
+// wireToolError is the wire form of a run.ToolError.
+type wireToolError struct {
+	ToolID  string             `json:"toolId"`
+	Op      string             `json:"op"`
+	Backend *model.ToolBackend `json:"backend,omitempty"`
+	Cause   string             `json:"cause"`
+}
+
+// encodeChainSteps returns the "steps" of a run_chain request. A step's
+// retry policy is sent in its JSON encoding, so the host retries it as an
+// in-process chain would.
+func encodeChainSteps(steps []run.ChainStep) ([]map[string]any, error) {
+	encoded := make([]map[string]any, len(steps))
+	for i, step := range steps {
+		encoded[i] = map[string]any{
+			"toolId":      step.ToolID,
+			"args":        step.Args,
+			"usePrevious": step.UsePrevious,
+		}
+		if step.Retry != nil {
+			retry, err := toPayload(step.Retry)
+			if err != nil {
+				return nil, err
+			}
+			encoded[i]["retry"] = retry
+		}
+	}
+	return encoded, nil
+}
+
+// decodeChainSteps decodes the "steps" of a run_chain request.
+func decodeChainSteps(v any) ([]run.ChainStep, error) {
+	raw, _ := v.([]any)
+	steps := make([]run.ChainStep, 0, len(raw))
+	for i, r := range raw {
+		m, _ := r.(map[string]any)
+		args, _ := m["args"].(map[string]any)
+		usePrevious, _ := m["usePrevious"].(bool)
+		step := run.ChainStep{ToolID: getString(m, "toolId"), Args: args, UsePrevious: usePrevious}
+		if m["retry"] != nil {
+			step.Retry = &run.RetryPolicy{}
+			if err := fromPayload(m["retry"], step.Retry); err != nil {
+				return nil, fmt.Errorf("%w: step %d retry: %v", ErrProtocol, i, err)
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// encodeRunResult returns the payload of a run_tool response.
+func encodeRunResult(r run.RunResult) (map[string]any, error) {
+	return toPayload(r)
+}
+
+// decodeRunResult decodes the payload of a run_tool response.
+func decodeRunResult(p map[string]any) (run.RunResult, error) {
+	var r run.RunResult
+	err := fromPayload(p, &r)
+	return r, err
+}
+
+// encodeChainResult returns the payload of a run_chain response.
+func encodeChainResult(r run.RunResult, steps []run.StepResult) (map[string]any, error) {
+	p, err := encodeRunResult(r)
+	if err != nil {
+		return nil, err
+	}
+	wire := make([]wireStepResult, len(steps))
+	for i, step := range steps {
+		wire[i] = wireStepResult{StepResult: step}
+		if step.Err != nil {
+			wire[i].Error = encodeError(step.Err)
+		}
+	}
+	var encoded []any
+	if err := fromPayload(wire, &encoded); err != nil {
+		return nil, err
+	}
+	p["stepResults"] = encoded
+	return p, nil
+}
+
+// decodeChainResult decodes the payload of a run_chain response.
+func decodeChainResult(p map[string]any) (run.RunResult, []run.StepResult, error) {
+	r, err := decodeRunResult(p)
+	if err != nil {
+		return run.RunResult{}, nil, err
+	}
+	var wire []wireStepResult
+	if err := fromPayload(p["stepResults"], &wire); err != nil {
+		return r, nil, err
+	}
+	var steps []run.StepResult
+	for _, w := range wire {
+		step := w.StepResult
+		if w.Error != nil {
+			step.Err = w.Error.err()
+		}
+		steps = append(steps, step)
+	}
+	return r, steps, nil
+}
+
+// encodeError returns the wire form of err.
+func encodeError(err error) *wireError {
+	w := &wireError{Message: err.Error(), Code: run.CodeOf(err)}
+	var toolErr *run.ToolError
+	if errors.As(err, &toolErr) && toolErr.Err != nil {
+		w.ToolError = &wireToolError{
+			ToolID:  toolErr.ToolID,
+			Op:      toolErr.Op,
+			Backend: toolErr.Backend,
+			Cause:   toolErr.Err.Error(),
+		}
+	}
+	return w
+}
+
+// decodeError decodes the error carried by a MsgError payload.
+func decodeError(p map[string]any) error {
+	var w wireError
+	if err := fromPayload(p, &w); err != nil || w.Message == "" {
+		w = wireError{Message: getString(p, "error")}
+	}
+	if w.Message == "" {
+		w.Message = "unknown error"
+	}
+	return w.err()
+}
+
+// err rebuilds the error w encodes.
+func (w *wireError) err() error {
+	if w.ToolError == nil {
+		return &remoteError{msg: w.Message, code: w.Code}
+	}
+	toolErr := &run.ToolError{
+		ToolID:  w.ToolError.ToolID,
+		Backend: w.ToolError.Backend,
+		Op:      w.ToolError.Op,
+		Err:     &remoteError{msg: w.ToolError.Cause, code: w.Code},
+	}
+	if toolErr.Error() == w.Message {
+		return toolErr
+	}
+	return &remoteError{msg: w.Message, code: w.Code, err: toolErr}
+}
+
+// codeErrors maps the codes of decoded errors to the sentinels they match.
+var codeErrors = map[run.ErrorCode]error{
+	run.ErrorCodeToolNotFound:     run.ErrToolNotFound,
+	run.ErrorCodeInvalidToolID:    run.ErrInvalidToolID,
+	run.ErrorCodeNoBackends:       run.ErrNoBackends,
+	run.ErrorCodeValidation:       run.ErrValidation,
+	run.ErrorCodeOutputValidation: run.ErrOutputValidation,
+	run.ErrorCodeExecution:        run.ErrExecution,
+	run.ErrorCodeTimeout:          context.DeadlineExceeded,
+	run.ErrorCodeCancelled:        context.Canceled,
+}
+
+// remoteError is an error decoded from the wire. It matches the sentinel
+// of its code, so run.CodeOf classifies it as the peer did.
+type remoteError struct {
+	msg  string
+	code run.ErrorCode
+	err  error // a *run.ToolError within msg
+}
+
+func (e *remoteError) Error() string { return e.msg }
+
+func (e *remoteError) Unwrap() error { return e.err }
+
+func (e *remoteError) Is(target error) bool {
+	sentinel, ok := codeErrors[e.code]
+	return ok && target == sentinel
+}
+
+// toPayload converts v to the maps, slices, and scalars of its JSON
+// encoding, which every Codec can encode.
+func toPayload(v any) (map[string]any, error) {
+	var p map[string]any
+	err := fromPayload(v, &p)
+	return p, err
+}
+
+// fromPayload decodes v, such as a decoded payload value, into dst
+// through its JSON encoding.
+func fromPayload(v, dst any) error {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+var fullResult = run.RunResult{
+	Tool: model.Tool{
+		Tool:      mcp.Tool{Name: "fetch", Description: "Fetch a page", InputSchema: map[string]any{"type": "object"}},
+		Namespace: "web",
+	},
+	Backend:    model.NewMCPBackend("browser"),
+	Structured: map[string]any{"status": 200.0, "title": "Example"},
+	Contents: []run.ContentItem{
+		{Kind: run.ContentText, Text: "Example"},
+		{Kind: run.ContentImage, Data: []byte{0x89, 'P', 'N', 'G'}, MIMEType: "image/png"},
+		{Kind: run.ContentResourceLink, URI: "https://example.com", Name: "page"},
+	},
+	MCPResult: &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "Example"}}},
+}
+
+// resultGateway returns fullResult, and runs chains whose second step
+// fails validation.
+type resultGateway struct{ hostGateway }
+
+// DescribeTool documents web:fetch fully at DetailFull and with its tool
+// at DetailSchema.
+func (resultGateway) DescribeTool(_ context.Context, _ string, level tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	doc := tooldoc.ToolDoc{Summary: "Fetch a page"}
+	if level == tooldoc.DetailSummary {
+		return doc, nil
+	}
+	doc.Tool = &fullResult.Tool
+	if level == tooldoc.DetailFull {
+		doc.Notes = "Follows redirects."
+		doc.Examples = []tooldoc.ToolExample{{
+			ID:         "home",
+			Title:      "Fetch the home page",
+			Args:       map[string]any{"url": "https://example.com", "retries": 2.0},
+			ResultHint: "status and title",
+		}}
+	}
+	return doc, nil
+}
+
+func (resultGateway) RunTool(_ context.Context, id string, _ map[string]any) (run.RunResult, error) {
+	if id == "web:missing" {
+		return run.RunResult{}, run.WrapError(id, nil, "resolve", run.ErrToolNotFound)
+	}
+	return fullResult, nil
+}
+
+func (resultGateway) RunChain(context.Context, []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	backend := model.NewLocalBackend("check")
+	stepErr := run.WrapError("web:check", &backend, "validate_input", run.ErrValidation)
+	steps := []run.StepResult{
+		{ToolID: "web:fetch", Backend: fullResult.Backend, Result: fullResult, Retries: 2, Cost: 1.5},
+		{ToolID: "web:check", Backend: backend, Err: stepErr, Deduplicated: true},
+	}
+	return fullResult, steps, stepErr
+}
+
+func TestGateway_RunResultFidelity(t *testing.T) {
+	g := pumpPair(t, NewGatewayHandler(resultGateway{}))
+	ctx := context.Background()
+
+	result, err := g.RunTool(ctx, "web:fetch", nil)
+	if err != nil {
+		t.Fatalf("RunTool() error = %v", err)
+	}
+	assertResult(t, "RunTool()", result)
+
+	_, err = g.RunTool(ctx, "web:missing", nil)
+	var toolErr *run.ToolError
+	if !errors.Is(err, run.ErrToolNotFound) || !errors.As(err, &toolErr) || toolErr.Op != "resolve" || toolErr.ToolID != "web:missing" {
+		t.Errorf("RunTool() error = %#v, want the host's *run.ToolError", err)
+	}
+
+	result, steps, err := g.RunChain(ctx, []run.ChainStep{{ToolID: "web:fetch"}, {ToolID: "web:check"}})
+	if !errors.Is(err, run.ErrValidation) || run.CodeOf(err) != run.ErrorCodeValidation {
+		t.Errorf("RunChain() error = %v, want %v", err, run.ErrValidation)
+	}
+	assertResult(t, "RunChain()", result)
+	if len(steps) != 2 {
+		t.Fatalf("RunChain() steps = %+v, want 2", steps)
+	}
+	if s := steps[0]; s.ToolID != "web:fetch" || s.Retries != 2 || s.Cost != 1.5 || s.Err != nil || s.Backend.MCP == nil {
+		t.Errorf("step 0 = %+v", s)
+	}
+	assertResult(t, "step 0", steps[0].Result)
+	s := steps[1]
+	if !s.Deduplicated || !errors.Is(s.Err, run.ErrValidation) || !errors.As(s.Err, &toolErr) || toolErr.Backend == nil || toolErr.Backend.Kind != model.BackendKindLocal {
+		t.Errorf("step 1 = %+v, want its *run.ToolError", s)
+	}
+	if want := "run: validate_input web:check [local]: validation error"; s.Err.Error() != want {
+		t.Errorf("step 1 error = %q, want %q", s.Err, want)
+	}
+}
+
+func assertResult(t *testing.T, name string, got run.RunResult) {
+	t.Helper()
+	if got.Tool.Name != "fetch" || got.Tool.Namespace != "web" || got.Backend.Kind != model.BackendKindMCP || got.Backend.MCP.ServerName != "browser" {
+		t.Errorf("%s tool and backend = %+v, %+v", name, got.Tool, got.Backend)
+	}
+	if !reflect.DeepEqual(got.Structured, fullResult.Structured) || !reflect.DeepEqual(got.Contents, fullResult.Contents) {
+		t.Errorf("%s = %+v, want %+v", name, got, fullResult)
+	}
+	if got.MCPResult == nil || len(got.MCPResult.Content) != 1 {
+		t.Fatalf("%s MCPResult = %+v", name, got.MCPResult)
+	}
+	if text, ok := got.MCPResult.Content[0].(*mcp.TextContent); !ok || text.Text != "Example" {
+		t.Errorf("%s MCPResult content = %#v", name, got.MCPResult.Content[0])
+	}
+}
+
+func TestGateway_DescribeToolFidelity(t *testing.T) {
+	g := pumpPair(t, NewGatewayHandler(resultGateway{}))
+	for _, level := range []tooldoc.DetailLevel{tooldoc.DetailSummary, tooldoc.DetailSchema, tooldoc.DetailFull} {
+		want, _ := resultGateway{}.DescribeTool(context.Background(), "web:fetch", level)
+		got, err := g.DescribeTool(context.Background(), "web:fetch", level)
+		if err != nil {
+			t.Fatalf("DescribeTool(%s) error = %v", level, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("DescribeTool(%s) = %+v, want %+v", level, got, want)
+		}
+	}
+}
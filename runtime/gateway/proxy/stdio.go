@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// Pipe joins a read and a write stream, such as a child process's stdout
+// and stdin, into one io.ReadWriteCloser for NewLineConnection or
+// NewStreamConnection. Closing it closes both streams that are
+// io.Closers. Deadlines reach streams that support them, as *os.File
+// pipes do, so Send and Receive honor cancellation on them.
+func Pipe(r io.Reader, w io.Writer) io.ReadWriteCloser {
+	return &pipe{r: r, w: w}
+}
+
+// Stdio returns the process's stdin and stdout as one stream, for code
+// in a sandbox whose host serves the gateway on its pipes:
+//
+//	g := proxy.New(proxy.Config{Connection: proxy.NewLineConnection(proxy.Stdio())})
+//	go func() { _ = g.ReceiveResponses(ctx) }()
+//
+// Output meant for people must then go to stderr.
+func Stdio() io.ReadWriteCloser {
+	return Pipe(os.Stdin, os.Stdout)
+}
+
+type pipe struct {
+	r io.Reader
+	w io.Writer
+}
+
+func (p *pipe) Read(b []byte) (int, error)  { return p.r.Read(b) }
+func (p *pipe) Write(b []byte) (int, error) { return p.w.Write(b) }
+
+func (p *pipe) Close() error {
+	var errs []error
+	if c, ok := p.w.(io.Closer); ok {
+		errs = append(errs, c.Close())
+	}
+	if c, ok := p.r.(io.Closer); ok {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (p *pipe) SetReadDeadline(t time.Time) error {
+	if d, ok := p.r.(deadliner); ok {
+		return d.SetReadDeadline(t)
+	}
+	return os.ErrNoDeadline
+}
+
+func (p *pipe) SetWriteDeadline(t time.Time) error {
+	if d, ok := p.w.(deadliner); ok {
+		return d.SetWriteDeadline(t)
+	}
+	return os.ErrNoDeadline
+}
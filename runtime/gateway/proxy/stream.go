@@ -0,0 +1,275 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// MaxFrameSize bounds a single message on a stream connection.
+const MaxFrameSize = 16 << 20
+
+// deadliner is implemented by net.Conn and pollable *os.File values.
+type deadliner interface {
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
+// StreamConnection implements Connection over a byte stream such as a
+// vsock, Unix, or TCP connection. Each message is encoded with the codec
+// and framed by a 4-byte big-endian length, or, for connections made by
+// NewLineConnection, ended by a newline.
+//
+// Send and Receive honor context cancellation when the stream supports
+// read and write deadlines, as net.Conn does; otherwise they block until
+// the stream makes progress.
+type StreamConnection struct {
+	rw    io.ReadWriteCloser
+	lines *bufio.Reader // non-nil for newline framing
+
+	codecMu sync.RWMutex
+	codec   Codec
+
+	readMu  sync.Mutex
+	writeMu sync.Mutex
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	closeErr  error
+}
+
+// NewStreamConnection wraps rw. If codec is nil, JSON is used.
+func NewStreamConnection(rw io.ReadWriteCloser, codec Codec) *StreamConnection {
+	if codec == nil {
+		codec = &jsonCodec{}
+	}
+	return &StreamConnection{rw: rw, codec: codec, closed: make(chan struct{})}
+}
+
+// NewLineConnection wraps rw with newline-delimited JSON framing
+// (NDJSON): each message is one line of JSON, as code/python's
+// interpreter speaks. It suits the stdin and stdout pipes of a sandboxed
+// process, which Pipe and Stdio join into one stream; nothing else may be
+// written to them. Blank lines are skipped.
+func NewLineConnection(rw io.ReadWriteCloser) *StreamConnection {
+	c := NewStreamConnection(rw, nil)
+	c.lines = bufio.NewReader(rw)
+	return c
+}
+
+// Send encodes and writes msg as one frame.
+func (c *StreamConnection) Send(ctx context.Context, msg Message) error {
+	data, err := c.currentCodec().Encode(msg)
+	if err != nil {
+		return fmt.Errorf("%w: encode: %v", ErrProtocol, err)
+	}
+	if len(data) > MaxFrameSize {
+		return fmt.Errorf("%w: message of %d bytes exceeds %d", ErrProtocol, len(data), MaxFrameSize)
+	}
+	var frame []byte
+	if c.lines != nil {
+		// JSON escapes newlines within strings, so a message is one line.
+		frame = append(data, '\n')
+	} else {
+		frame = make([]byte, 4+len(data))
+		binary.BigEndian.PutUint32(frame, uint32(len(data)))
+		copy(frame[4:], data)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.isClosed() {
+		return ErrConnectionClosed
+	}
+	stop := c.watch(ctx, func(d deadliner, t time.Time) error { return d.SetWriteDeadline(t) })
+	n, err := c.rw.Write(frame)
+	if err != nil && n > 0 {
+		// A partial frame leaves the peer unable to find the next one.
+		_ = c.Close()
+	}
+	return c.streamErr(ctx, stop(), err)
+}
+
+// Receive reads and decodes the next frame.
+func (c *StreamConnection) Receive(ctx context.Context) (Message, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	if c.isClosed() {
+		return Message{}, ErrConnectionClosed
+	}
+	stop := c.watch(ctx, func(d deadliner, t time.Time) error { return d.SetReadDeadline(t) })
+	var (
+		data    []byte
+		partial bool
+		err     error
+	)
+	if c.lines != nil {
+		data, partial, err = c.readLine()
+	} else {
+		data, partial, err = c.readFrame()
+	}
+	var tooLarge frameSizeError
+	if errors.As(err, &tooLarge) {
+		stop()
+		_ = c.Close()
+		return Message{}, fmt.Errorf("%w: %v", ErrProtocol, err)
+	}
+	if err != nil && partial {
+		// The rest of a partly read frame cannot be told from the next one.
+		_ = c.Close()
+	}
+	if err := c.streamErr(ctx, stop(), err); err != nil {
+		return Message{}, err
+	}
+	msg, err := c.currentCodec().Decode(data)
+	if err != nil {
+		return Message{}, fmt.Errorf("%w: decode: %v", ErrProtocol, err)
+	}
+	return msg, nil
+}
+
+// AcceptsCodec implements CodecSwitcher. Length-prefixed framing carries
+// any codec; newline framing carries only JSON, whose messages contain no
+// newlines.
+func (c *StreamConnection) AcceptsCodec(codec Codec) bool {
+	_, isJSON := codec.(*jsonCodec)
+	return c.lines == nil || isJSON
+}
+
+// SetCodec implements CodecSwitcher.
+func (c *StreamConnection) SetCodec(codec Codec) error {
+	if !c.AcceptsCodec(codec) {
+		return fmt.Errorf("%w: newline framing cannot carry codec %T", ErrProtocol, codec)
+	}
+	c.codecMu.Lock()
+	defer c.codecMu.Unlock()
+	c.codec = codec
+	return nil
+}
+
+func (c *StreamConnection) currentCodec() Codec {
+	c.codecMu.RLock()
+	defer c.codecMu.RUnlock()
+	return c.codec
+}
+
+// frameSizeError reports a frame larger than MaxFrameSize.
+type frameSizeError int
+
+func (e frameSizeError) Error() string {
+	return fmt.Sprintf("frame of %d bytes exceeds %d", int(e), MaxFrameSize)
+}
+
+// readFrame reads a length-prefixed frame, reporting whether it read part
+// of one before failing.
+func (c *StreamConnection) readFrame() (data []byte, partial bool, err error) {
+	var header [4]byte
+	n, err := io.ReadFull(c.rw, header[:])
+	if err != nil {
+		return nil, n > 0, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > MaxFrameSize {
+		return nil, true, frameSizeError(size)
+	}
+	data = make([]byte, size)
+	_, err = io.ReadFull(c.rw, data)
+	return data, true, err
+}
+
+// readLine reads the next non-blank line, without its newline, reporting
+// whether it read part of one before failing.
+func (c *StreamConnection) readLine() (line []byte, partial bool, err error) {
+	for {
+		chunk, err := c.lines.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > MaxFrameSize+1 {
+			return nil, true, frameSizeError(len(line))
+		}
+		switch {
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case err != nil:
+			return nil, len(line) > 0, err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			return line[:len(line)-1], true, nil
+		}
+		line = line[:0]
+	}
+}
+
+// Close closes the underlying stream. Pending Send and Receive calls fail
+// with ErrConnectionClosed.
+func (c *StreamConnection) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.closeErr = c.rw.Close()
+	})
+	return c.closeErr
+}
+
+func (c *StreamConnection) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// watch applies ctx's deadline to the stream and interrupts it when ctx
+// is canceled. The returned stop function clears the deadline and reports
+// whether ctx interrupted the operation.
+func (c *StreamConnection) watch(ctx context.Context, set func(deadliner, time.Time) error) (stop func() bool) {
+	d, ok := c.rw.(deadliner)
+	if !ok {
+		return func() bool { return false }
+	}
+	deadline, _ := ctx.Deadline()
+	_ = set(d, deadline)
+	interrupted := make(chan struct{})
+	cancel := context.AfterFunc(ctx, func() {
+		close(interrupted)
+		_ = set(d, time.Unix(1, 0))
+	})
+	return func() bool {
+		if !cancel() {
+			<-interrupted
+			return true
+		}
+		_ = set(d, time.Time{})
+		return false
+	}
+}
+
+// streamErr maps a stream error to the Connection contract.
+func (c *StreamConnection) streamErr(ctx context.Context, interrupted bool, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case interrupted || (errors.Is(err, os.ErrDeadlineExceeded) && ctx.Err() != nil):
+		return ctx.Err()
+	case errors.Is(err, os.ErrDeadlineExceeded) && deadlinePassed(ctx):
+		// The stream's deadline can fire just before ctx's timer does.
+		return context.DeadlineExceeded
+	case c.isClosed(), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, net.ErrClosed), errors.Is(err, io.ErrClosedPipe):
+		return fmt.Errorf("%w: %v", ErrConnectionClosed, err)
+	default:
+		return err
+	}
+}
+
+// deadlinePassed reports whether ctx's deadline, if any, has passed.
+func deadlinePassed(ctx context.Context) bool {
+	deadline, ok := ctx.Deadline()
+	return ok && !time.Now().Before(deadline)
+}
@@ -0,0 +1,181 @@
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newStreamPair(t *testing.T) (*StreamConnection, *StreamConnection) {
+	t.Helper()
+	a, b := net.Pipe()
+	ca, cb := NewStreamConnection(a, nil), NewStreamConnection(b, nil)
+	t.Cleanup(func() {
+		_ = ca.Close()
+		_ = cb.Close()
+	})
+	return ca, cb
+}
+
+func TestStreamConnection_RoundTrip(t *testing.T) {
+	client, host := newStreamPair(t)
+	ctx := context.Background()
+
+	sent := Message{Type: MsgRunTool, ID: "1", Payload: map[string]any{"id": "ns:tool"}}
+	go func() { _ = client.Send(ctx, sent) }()
+	got, err := host.Receive(ctx)
+	if err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	if got.Type != sent.Type || got.ID != sent.ID || got.Payload["id"] != "ns:tool" {
+		t.Errorf("Receive() = %+v, want %+v", got, sent)
+	}
+}
+
+func TestStreamConnection_ReceiveCanceled(t *testing.T) {
+	client, host := newStreamPair(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := host.Receive(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Receive() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// A receive canceled between frames leaves the connection usable.
+	go func() { _ = client.Send(context.Background(), Message{Type: MsgResponse, ID: "2"}) }()
+	if got, err := host.Receive(context.Background()); err != nil || got.ID != "2" {
+		t.Errorf("Receive() = %+v, %v; want the next message", got, err)
+	}
+}
+
+func TestStreamConnection_Closed(t *testing.T) {
+	client, host := newStreamPair(t)
+	ctx := context.Background()
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := client.Send(ctx, Message{Type: MsgResponse}); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Send() after Close error = %v, want ErrConnectionClosed", err)
+	}
+	if _, err := host.Receive(ctx); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Receive() after peer Close error = %v, want ErrConnectionClosed", err)
+	}
+}
+
+func TestStreamConnection_OversizedFrame(t *testing.T) {
+	a, b := net.Pipe()
+	host := NewStreamConnection(b, nil)
+	defer func() { _ = host.Close() }()
+	go func() {
+		var header [4]byte
+		binary.BigEndian.PutUint32(header[:], MaxFrameSize+1)
+		_, _ = a.Write(header[:])
+	}()
+	if _, err := host.Receive(context.Background()); !errors.Is(err, ErrProtocol) {
+		t.Errorf("Receive() error = %v, want ErrProtocol", err)
+	}
+}
+
+// TestStreamConnection_Gateway runs a Gateway over a stream, as a guest
+// would over vsock, with the host answering each request.
+func TestStreamConnection_Gateway(t *testing.T) {
+	guest, host := newStreamPair(t)
+	g := New(Config{Connection: guest})
+	go func() {
+		for {
+			msg, err := guest.Receive(context.Background())
+			if err != nil {
+				return
+			}
+			_ = g.DeliverResponse(msg)
+		}
+	}()
+	go func() {
+		for {
+			req, err := host.Receive(context.Background())
+			if err != nil {
+				return
+			}
+			_ = host.Send(context.Background(), Message{
+				Type:    MsgResponse,
+				ID:      req.ID,
+				Payload: map[string]any{"structured": req.Payload["id"]},
+			})
+		}
+	}()
+
+	result, err := g.RunTool(context.Background(), "ns:echo", nil)
+	if err != nil {
+		t.Fatalf("RunTool() error = %v", err)
+	}
+	if result.Structured != "ns:echo" {
+		t.Errorf("RunTool() = %+v, want the host's response", result)
+	}
+}
+
+func TestGateway_ReceiveResponses(t *testing.T) {
+	guest, host := newStreamPair(t)
+	g := New(Config{Connection: guest})
+	done := make(chan error, 1)
+	go func() { done <- g.ReceiveResponses(context.Background()) }()
+	go func() {
+		for {
+			req, err := host.Receive(context.Background())
+			if err != nil {
+				return
+			}
+			// A response nobody awaits is dropped without ending the loop.
+			_ = host.Send(context.Background(), Message{Type: MsgResponse, ID: "stale"})
+			_ = host.Send(context.Background(), Message{Type: MsgResponse, ID: req.ID, Payload: map[string]any{"structured": "ok"}})
+		}
+	}()
+
+	for range 2 {
+		if result, err := g.RunTool(context.Background(), "ns:tool", nil); err != nil || result.Structured != "ok" {
+			t.Fatalf("RunTool() = %+v, %v; want the host's response", result, err)
+		}
+	}
+	_ = g.Close()
+	if err := <-done; err != nil {
+		t.Errorf("ReceiveResponses() after Close = %v, want nil", err)
+	}
+}
+
+// TestFirecrackerVsockPath serves a guest connection on the Unix socket
+// Firecracker forwards guest-initiated vsock connections to.
+func TestFirecrackerVsockPath(t *testing.T) {
+	uds := filepath.Join(t.TempDir(), "v.sock")
+	path := FirecrackerVsockPath(uds, DefaultGatewayVsockPort)
+	if path != uds+"_1024" {
+		t.Fatalf("FirecrackerVsockPath() = %q", path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer func() { _ = l.Close() }()
+
+	go func() {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			return
+		}
+		guest := NewStreamConnection(conn, nil)
+		defer func() { _ = guest.Close() }()
+		_ = guest.Send(context.Background(), Message{Type: MsgListNamespaces, ID: "1"})
+	}()
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatalf("Accept() error = %v", err)
+	}
+	host := NewStreamConnection(conn, nil)
+	defer func() { _ = host.Close() }()
+	if msg, err := host.Receive(context.Background()); err != nil || msg.Type != MsgListNamespaces {
+		t.Errorf("Receive() = %+v, %v", msg, err)
+	}
+}
@@ -0,0 +1,205 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// streamBuffer is how many stream messages wait for the consumer before
+// DeliverResponse blocks.
+const streamBuffer = 16
+
+// streamGrace bounds the best-effort messages and events sent after a
+// request's context ends.
+const streamGrace = time.Second
+
+// streamWaiter receives the messages of one pending stream.
+type streamWaiter struct {
+	messages chan Message
+	done     chan struct{}
+}
+
+// RunToolStream implements runtime.StreamingGateway. It sends a
+// MsgRunToolStream request and relays the host's stream messages as
+// events; failures, including a host that cannot stream, arrive as a final
+// StreamEventError. When ctx ends, it asks the host to cancel the tool with
+// MsgCancel and ends the stream with StreamEventCancelled or
+// StreamEventTimeout. A stream whose connection is lost ends with a
+// ConnectionLostError; it is not replayed after reconnecting. The
+// connection's reader waits for the caller to take each event, so the
+// channel must be drained.
+func (g *Gateway) RunToolStream(ctx context.Context, id string, args map[string]any) (<-chan run.StreamEvent, error) {
+	if g.closed.Load() {
+		return nil, ErrConnectionClosed
+	}
+	l, err := g.connected(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := g.newRequest(ctx, MsgRunToolStream, map[string]any{
+		"id":   id,
+		"args": args,
+	})
+	w := &streamWaiter{messages: make(chan Message, streamBuffer), done: make(chan struct{})}
+	g.pending.Store(msg.ID, w)
+	if err := l.conn.Send(ctx, msg); err != nil {
+		g.pending.Delete(msg.ID)
+		g.broken(ctx, l, err)
+		return nil, err
+	}
+
+	out := make(chan run.StreamEvent)
+	go func() {
+		defer close(out)
+		defer g.pending.Delete(msg.ID)
+		defer close(w.done)
+		for {
+			select {
+			case <-ctx.Done():
+				g.interrupt(ctx, l, msg.ID, id, out)
+				return
+			case <-l.lost:
+				err := &ConnectionLostError{Type: MsgRunToolStream, RequestID: msg.ID, Err: l.err}
+				sendFinal(out, run.StreamEvent{Kind: run.StreamEventError, ToolID: id, Err: err})
+				return
+			case <-g.lost:
+				sendFinal(out, run.StreamEvent{Kind: run.StreamEventError, ToolID: id, Err: g.lostErr})
+				return
+			case m := <-w.messages:
+				ev, final := streamEvent(id, m)
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					g.interrupt(ctx, l, msg.ID, id, out)
+					return
+				}
+				if final {
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// interrupt cancels the stream with ID reqID, sent on l, on the host and
+// sends its caller the final event for ctx's end.
+func (g *Gateway) interrupt(ctx context.Context, l *link, reqID, toolID string, out chan<- run.StreamEvent) {
+	g.cancel(ctx, l, reqID)
+	ev := run.StreamEvent{Kind: run.StreamEventCancelled, ToolID: toolID, Err: context.Cause(ctx)}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		ev.Kind = run.StreamEventTimeout
+	}
+	sendFinal(out, ev)
+}
+
+// sendFinal sends a stream's final event, giving up after streamGrace so
+// an abandoned stream does not leak.
+func sendFinal(out chan<- run.StreamEvent, ev run.StreamEvent) {
+	timer := time.NewTimer(streamGrace)
+	defer timer.Stop()
+	select {
+	case out <- ev:
+	case <-timer.C:
+	}
+}
+
+// streamEvent decodes a stream message, reporting whether it ends the
+// stream.
+func streamEvent(toolID string, msg Message) (ev run.StreamEvent, final bool) {
+	ev = run.StreamEvent{ToolID: toolID, Data: msg.Payload["data"]}
+	switch msg.Type {
+	case MsgStreamChunk:
+		ev.Kind = run.StreamEventChunk
+		return ev, false
+	case MsgStreamProgress:
+		ev.Kind = run.StreamEventProgress
+		return ev, false
+	case MsgStreamDone:
+		ev.Kind = run.StreamEventDone
+		return ev, true
+	case MsgError:
+		ev.Kind = run.StreamEventKind(getString(msg.Payload, "kind"))
+		if ev.Kind != run.StreamEventCancelled && ev.Kind != run.StreamEventTimeout {
+			ev.Kind = run.StreamEventError
+		}
+		errMsg := getString(msg.Payload, "error")
+		if errMsg == "" {
+			errMsg = "unknown error"
+		}
+		ev.Err = errors.New(errMsg)
+		return ev, true
+	default:
+		ev.Kind = run.StreamEventError
+		ev.Err = fmt.Errorf("%w: unexpected %s message in stream", ErrProtocol, msg.Type)
+		return ev, true
+	}
+}
+
+// streamMessage encodes a stream event as the message answering the
+// request with ID id, reporting whether it ends the stream. Events of
+// unknown kinds are not sent.
+func streamMessage(id string, ev run.StreamEvent) (msg Message, final, ok bool) {
+	payload := map[string]any{}
+	if ev.Data != nil {
+		payload["data"] = ev.Data
+	}
+	switch ev.Kind {
+	case run.StreamEventChunk:
+		return Message{Type: MsgStreamChunk, ID: id, Payload: payload}, false, true
+	case run.StreamEventProgress:
+		return Message{Type: MsgStreamProgress, ID: id, Payload: payload}, false, true
+	case run.StreamEventDone:
+		return Message{Type: MsgStreamDone, ID: id, Payload: payload}, true, true
+	case run.StreamEventError, run.StreamEventCancelled, run.StreamEventTimeout:
+		payload["kind"] = string(ev.Kind)
+		payload["error"] = string(ev.Kind)
+		if ev.Err != nil {
+			payload["error"] = ev.Err.Error()
+		}
+		return Message{Type: MsgError, ID: id, Payload: payload}, true, true
+	default:
+		return Message{}, false, false
+	}
+}
+
+// serveStream streams the events of gw's run of the requested tool.
+func serveStream(ctx context.Context, gw runtime.ToolGateway, req Message, send func(Message) error) {
+	sg, ok := gw.(runtime.StreamingGateway)
+	if !ok {
+		_ = send(ErrorResponse(req.ID, run.ErrStreamNotSupported))
+		return
+	}
+	args, _ := req.Payload["args"].(map[string]any)
+	events, err := sg.RunToolStream(ctx, getString(req.Payload, "id"), args)
+	if err != nil {
+		_ = send(ErrorResponse(req.ID, err))
+		return
+	}
+
+	ended := false
+	for ev := range events {
+		if ended {
+			// Drain the stream so its producer can finish.
+			continue
+		}
+		msg, final, ok := streamMessage(req.ID, ev)
+		if !ok {
+			continue
+		}
+		if err := send(msg); err != nil {
+			ended = true
+			continue
+		}
+		ended = final
+	}
+	if !ended {
+		_ = send(Message{Type: MsgStreamDone, ID: req.ID})
+	}
+}
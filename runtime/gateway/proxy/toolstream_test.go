@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+// streamingGateway streams ns:echo's progress and chunks, and holds
+// ns:slow open until its context ends, reporting the end on canceled.
+type streamingGateway struct {
+	hostGateway
+	canceled chan error
+}
+
+func (g streamingGateway) RunToolStream(ctx context.Context, id string, _ map[string]any) (<-chan run.StreamEvent, error) {
+	if id == "ns:missing" {
+		return nil, errors.New("tool not found")
+	}
+	out := make(chan run.StreamEvent)
+	go func() {
+		defer close(out)
+		if id == "ns:slow" {
+			out <- run.StreamEvent{Kind: run.StreamEventProgress, Data: "started"}
+			<-ctx.Done()
+			g.canceled <- ctx.Err()
+			out <- run.StreamEvent{Kind: run.StreamEventCancelled, Err: ctx.Err()}
+			return
+		}
+		out <- run.StreamEvent{Kind: run.StreamEventProgress, Data: "50%"}
+		out <- run.StreamEvent{Kind: run.StreamEventChunk, Data: "hello "}
+		out <- run.StreamEvent{Kind: run.StreamEventChunk, Data: runtime.ExecutionIDFromContext(ctx)}
+		out <- run.StreamEvent{Kind: run.StreamEventDone, Data: "hello exec-1"}
+	}()
+	return out, nil
+}
+
+// pumpPair serves h over a stdio pair and returns a guest gateway.
+func pumpPair(t *testing.T, h Handler) *Gateway {
+	t.Helper()
+	guest, host := newStdioPair(t)
+	go func() { _ = Pump(context.Background(), host, h) }()
+	g := New(Config{Connection: guest})
+	go func() { _ = g.ReceiveResponses(context.Background()) }()
+	return g
+}
+
+func collect(events <-chan run.StreamEvent) []run.StreamEvent {
+	var all []run.StreamEvent
+	for ev := range events {
+		all = append(all, ev)
+	}
+	return all
+}
+
+func TestGateway_RunToolStream(t *testing.T) {
+	g := pumpPair(t, NewGatewayHandler(streamingGateway{}))
+	ctx := runtime.WithExecutionID(context.Background(), "exec-1")
+
+	events, err := g.RunToolStream(ctx, "ns:echo", nil)
+	if err != nil {
+		t.Fatalf("RunToolStream() error = %v", err)
+	}
+	got := collect(events)
+	want := []run.StreamEvent{
+		{Kind: run.StreamEventProgress, Data: "50%"},
+		{Kind: run.StreamEventChunk, Data: "hello "},
+		{Kind: run.StreamEventChunk, Data: "exec-1"},
+		{Kind: run.StreamEventDone, Data: "hello exec-1"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("events = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Kind != want[i].Kind || got[i].Data != want[i].Data || got[i].ToolID != "ns:echo" {
+			t.Errorf("event %d = %+v, want %+v for ns:echo", i, got[i], want[i])
+		}
+	}
+
+	// Failures to start end the stream with an error event.
+	got = collect(mustStream(t, g, "ns:missing"))
+	if len(got) != 1 || got[0].Kind != run.StreamEventError || got[0].Err == nil || got[0].Err.Error() != "tool not found" {
+		t.Errorf("events = %+v, want the host's error", got)
+	}
+	got = collect(mustStream(t, pumpPair(t, NewGatewayHandler(hostGateway{})), "ns:echo"))
+	if len(got) != 1 || got[0].Kind != run.StreamEventError || !strings.Contains(got[0].Err.Error(), run.ErrStreamNotSupported.Error()) {
+		t.Errorf("events from a non-streaming gateway = %+v, want %v", got, run.ErrStreamNotSupported)
+	}
+}
+
+func TestGateway_RunToolStreamCancel(t *testing.T) {
+	canceled := make(chan error, 1)
+	g := pumpPair(t, NewGatewayHandler(streamingGateway{canceled: canceled}))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events, err := g.RunToolStream(ctx, "ns:slow", nil)
+	if err != nil {
+		t.Fatalf("RunToolStream() error = %v", err)
+	}
+	if ev := <-events; ev.Kind != run.StreamEventProgress {
+		t.Fatalf("first event = %+v, want progress", ev)
+	}
+	cancel()
+	got := collect(events)
+	if len(got) != 1 || got[0].Kind != run.StreamEventCancelled || !errors.Is(got[0].Err, context.Canceled) {
+		t.Errorf("events after cancel = %+v, want one cancelled event", got)
+	}
+	select {
+	case err := <-canceled:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("host context error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the host's stream was not canceled")
+	}
+
+	// The connection still serves requests.
+	if result, err := g.RunTool(context.Background(), "ns:echo", nil); err != nil || result.Structured != "ns:echo@" {
+		t.Errorf("RunTool() after a canceled stream = %+v, %v", result, err)
+	}
+}
+
+func mustStream(t *testing.T, g *Gateway, id string) <-chan run.StreamEvent {
+	t.Helper()
+	events, err := g.RunToolStream(context.Background(), id, nil)
+	if err != nil {
+		t.Fatalf("RunToolStream(%s) error = %v", id, err)
+	}
+	return events
+}
@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+)
+
+// Unix socket conventions shared by container backends and guest code.
+// The host serves the gateway on a Unix socket and the backend mounts it
+// into the sandbox at DefaultGatewaySocketPath, naming it in
+// GatewaySocketEnv, so tool calls need no network interface in the
+// container.
+const (
+	// DefaultGatewaySocketPath is where sandboxes find the gateway's
+	// socket when GatewaySocketEnv is unset.
+	DefaultGatewaySocketPath = "/run/toolexec/gateway.sock"
+
+	// GatewaySocketEnv holds the path of the gateway's socket.
+	GatewaySocketEnv = "TOOLEXEC_GATEWAY_SOCKET"
+)
+
+// GatewaySocketPath returns the socket a guest should dial: the value of
+// GatewaySocketEnv, or DefaultGatewaySocketPath.
+func GatewaySocketPath() string {
+	if path := os.Getenv(GatewaySocketEnv); path != "" {
+		return path
+	}
+	return DefaultGatewaySocketPath
+}
+
+// GatewaySocketEnvValue returns the GatewaySocketEnv entry that points a
+// guest at the gateway socket mounted at path, for a sandbox's
+// environment.
+func GatewaySocketEnvValue(path string) string {
+	return GatewaySocketEnv + "=" + path
+}
+
+// DialUnix connects to the gateway socket at path and returns the
+// connection to pass as Config.Connection. If codec is nil, JSON is used.
+func DialUnix(ctx context.Context, path string, codec Codec) (*StreamConnection, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return NewStreamConnection(conn, codec), nil
+}
+
+// DialGatewaySocket connects a guest to the gateway at GatewaySocketPath.
+// If codec is nil, JSON is used.
+func DialGatewaySocket(ctx context.Context, codec Codec) (*StreamConnection, error) {
+	return DialUnix(ctx, GatewaySocketPath(), codec)
+}
+
+// ListenUnix listens for gateway connections on the socket at path,
+// replacing a socket left by an earlier process, and sets its permission
+// bits to mode. Sandboxes often run as users other than the host's, which
+// need write permission to connect; limit who reaches the socket with
+// mode and the directory holding it. Closing the listener removes the
+// socket.
+func ListenUnix(path string, mode fs.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("listen unix %s: file exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+	return l, nil
+}
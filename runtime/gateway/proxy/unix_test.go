@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+func TestGatewaySocketPath(t *testing.T) {
+	t.Setenv(GatewaySocketEnv, "")
+	if got := GatewaySocketPath(); got != DefaultGatewaySocketPath {
+		t.Errorf("GatewaySocketPath() = %q, want %q", got, DefaultGatewaySocketPath)
+	}
+	t.Setenv(GatewaySocketEnv, "/tmp/gw.sock")
+	if got := GatewaySocketPath(); got != "/tmp/gw.sock" {
+		t.Errorf("GatewaySocketPath() = %q, want /tmp/gw.sock", got)
+	}
+	if got := GatewaySocketEnvValue("/tmp/gw.sock"); got != "TOOLEXEC_GATEWAY_SOCKET=/tmp/gw.sock" {
+		t.Errorf("GatewaySocketEnvValue() = %q", got)
+	}
+}
+
+func TestServe_Unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gw.sock")
+	l, err := ListenUnix(path, 0o666)
+	if err != nil {
+		t.Fatalf("ListenUnix() error = %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o666 {
+		t.Errorf("socket mode = %v, %v; want 0666", info.Mode(), err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- Serve(ctx, l, NewGatewayHandler(hostGateway{}), nil) }()
+
+	t.Setenv(GatewaySocketEnv, path)
+	var guests []*Gateway
+	for _, id := range []string{"exec-1", "exec-2"} {
+		conn, err := DialGatewaySocket(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("DialGatewaySocket() error = %v", err)
+		}
+		g := New(Config{Connection: conn})
+		defer g.Close()
+		go func() { _ = g.ReceiveResponses(context.Background()) }()
+		guests = append(guests, g)
+
+		result, err := g.RunTool(runtime.WithExecutionID(context.Background(), id), "ns:echo", nil)
+		if err != nil || result.Structured != "ns:echo@"+id {
+			t.Errorf("RunTool() = %+v, %v; want the host's echo", result, err)
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-served:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Serve() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() did not return after cancel")
+	}
+	// The host closed the guests' connections and removed the socket.
+	if _, err := guests[0].RunTool(context.Background(), "ns:echo", nil); err == nil {
+		t.Error("RunTool() after Serve returned succeeded, want an error")
+	}
+	if _, err := os.Lstat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("socket after Serve returned: %v, want it removed", err)
+	}
+}
+
+func TestServe_ListenerClosed(t *testing.T) {
+	l, err := ListenUnix(filepath.Join(t.TempDir(), "gw.sock"), 0o600)
+	if err != nil {
+		t.Fatalf("ListenUnix() error = %v", err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- Serve(context.Background(), l, NewGatewayHandler(hostGateway{}), nil) }()
+	_ = l.Close()
+	if err := <-served; err != nil {
+		t.Errorf("Serve() error = %v, want nil after the listener closed", err)
+	}
+}
+
+func TestListenUnix_Stale(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gw.sock")
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	l, err := ListenUnix(path, 0o600)
+	if err != nil {
+		t.Fatalf("ListenUnix() over a stale socket error = %v", err)
+	}
+	_ = l.Close()
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ListenUnix(file, 0o600); err == nil {
+		t.Error("ListenUnix() over a regular file succeeded, want an error")
+	}
+}
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Vsock conventions shared by sandbox backends and guest code. A guest
+// reaches the host's gateway by dialing HostCID on the port named by
+// GatewayVsockEnv, or DefaultGatewayVsockPort when it is unset, so tool
+// calls need no network interface in the guest.
+const (
+	// HostCID is the context ID that addresses the host from a guest.
+	HostCID uint32 = 2
+
+	// DefaultGatewayVsockPort is the port the host gateway listens on.
+	DefaultGatewayVsockPort uint32 = 1024
+
+	// GatewayVsockEnv holds the gateway address as "cid:port".
+	GatewayVsockEnv = "TOOLEXEC_GATEWAY_VSOCK"
+)
+
+// ErrVsockUnsupported is returned by vsock functions on platforms without
+// AF_VSOCK.
+var ErrVsockUnsupported = errors.New("vsock not supported on this platform")
+
+// VsockAddr is a vsock address. It implements net.Addr.
+type VsockAddr struct {
+	CID  uint32
+	Port uint32
+}
+
+// Network returns "vsock".
+func (a VsockAddr) Network() string { return "vsock" }
+
+// String returns the address as "cid:port".
+func (a VsockAddr) String() string {
+	return strconv.FormatUint(uint64(a.CID), 10) + ":" + strconv.FormatUint(uint64(a.Port), 10)
+}
+
+// ParseVsockAddr parses a "cid:port" address.
+func ParseVsockAddr(s string) (VsockAddr, error) {
+	cidStr, portStr, ok := strings.Cut(s, ":")
+	if !ok {
+		return VsockAddr{}, fmt.Errorf("vsock address %q: want cid:port", s)
+	}
+	cid, err := strconv.ParseUint(cidStr, 10, 32)
+	if err != nil {
+		return VsockAddr{}, fmt.Errorf("vsock address %q: invalid cid", s)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 32)
+	if err != nil {
+		return VsockAddr{}, fmt.Errorf("vsock address %q: invalid port", s)
+	}
+	return VsockAddr{CID: uint32(cid), Port: uint32(port)}, nil
+}
+
+// GatewayVsockAddr returns the gateway address a guest should dial: the
+// value of GatewayVsockEnv, or HostCID on DefaultGatewayVsockPort.
+func GatewayVsockAddr() (VsockAddr, error) {
+	if s := os.Getenv(GatewayVsockEnv); s != "" {
+		return ParseVsockAddr(s)
+	}
+	return VsockAddr{CID: HostCID, Port: DefaultGatewayVsockPort}, nil
+}
+
+// GatewayVsockEnvValue returns the GatewayVsockEnv entry that points a
+// guest at the host gateway on port, for a sandbox's environment.
+func GatewayVsockEnvValue(port uint32) string {
+	return GatewayVsockEnv + "=" + VsockAddr{CID: HostCID, Port: port}.String()
+}
+
+// FirecrackerVsockPath returns the Unix socket on which the host accepts
+// guest connections to port. Firecracker forwards them there from the
+// vsock device whose host-side socket is udsPath, so the host listens
+// with net.Listen("unix", FirecrackerVsockPath(udsPath, port)) rather
+// than on AF_VSOCK.
+func FirecrackerVsockPath(udsPath string, port uint32) string {
+	return udsPath + "_" + strconv.FormatUint(uint64(port), 10)
+}
+
+// DialGateway connects a guest to the host gateway at GatewayVsockAddr and
+// returns the connection to pass as Config.Connection. If codec is nil,
+// JSON is used.
+func DialGateway(ctx context.Context, codec Codec) (*StreamConnection, error) {
+	addr, err := GatewayVsockAddr()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := DialVsock(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewStreamConnection(conn, codec), nil
+}
@@ -0,0 +1,198 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// DialVsock connects to addr over AF_VSOCK, honoring ctx's deadline and
+// cancellation while the connection is established.
+func DialVsock(ctx context.Context, addr VsockAddr) (net.Conn, error) {
+	f, err := vsockSocket("vsock:" + addr.String())
+	if err != nil {
+		return nil, dialError(addr, err)
+	}
+	rc, err := f.SyscallConn()
+	if err != nil {
+		_ = f.Close()
+		return nil, dialError(addr, err)
+	}
+
+	var connectErr error
+	ctlErr := rc.Control(func(fd uintptr) {
+		connectErr = unix.Connect(int(fd), &unix.SockaddrVM{CID: addr.CID, Port: addr.Port})
+	})
+	if ctlErr == nil && errors.Is(connectErr, unix.EINPROGRESS) {
+		connectErr = waitConnect(ctx, f, rc)
+	}
+	if err := errors.Join(ctlErr, connectErr); err != nil {
+		_ = f.Close()
+		if ctx.Err() != nil {
+			return nil, dialError(addr, ctx.Err())
+		}
+		return nil, dialError(addr, os.NewSyscallError("connect", err))
+	}
+	return newVsockConn(f, rc, addr)
+}
+
+// ListenVsock listens for vsock connections on port from any context ID.
+// Hosts running Kata sandboxes use it to accept guest gateway connections;
+// Firecracker hosts listen on FirecrackerVsockPath instead.
+func ListenVsock(port uint32) (net.Listener, error) {
+	f, err := vsockSocket("vsock-listener")
+	if err != nil {
+		return nil, listenError(port, err)
+	}
+	rc, err := f.SyscallConn()
+	if err != nil {
+		_ = f.Close()
+		return nil, listenError(port, err)
+	}
+	var local VsockAddr
+	var opErr error
+	ctlErr := rc.Control(func(fd uintptr) {
+		if err := unix.Bind(int(fd), &unix.SockaddrVM{CID: unix.VMADDR_CID_ANY, Port: port}); err != nil {
+			opErr = os.NewSyscallError("bind", err)
+			return
+		}
+		if err := unix.Listen(int(fd), unix.SOMAXCONN); err != nil {
+			opErr = os.NewSyscallError("listen", err)
+			return
+		}
+		local, opErr = sockname(int(fd))
+	})
+	if err := errors.Join(ctlErr, opErr); err != nil {
+		_ = f.Close()
+		return nil, listenError(port, err)
+	}
+	return &vsockListener{f: f, rc: rc, addr: local}, nil
+}
+
+// vsockSocket creates a non-blocking vsock socket, which os.NewFile
+// registers with the runtime poller so deadlines work.
+func vsockSocket(name string) (*os.File, error) {
+	fd, err := unix.Socket(unix.AF_VSOCK, unix.SOCK_STREAM|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	return os.NewFile(uintptr(fd), name), nil
+}
+
+// waitConnect waits for a non-blocking connect to finish.
+func waitConnect(ctx context.Context, f *os.File, rc syscall.RawConn) error {
+	deadline, _ := ctx.Deadline()
+	_ = f.SetWriteDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { _ = f.SetWriteDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	var connectErr error
+	err := rc.Write(func(fd uintptr) bool {
+		soErr, err := unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_ERROR)
+		switch {
+		case err != nil:
+			connectErr = err
+		case soErr != 0:
+			connectErr = syscall.Errno(soErr)
+		default:
+			// SO_ERROR is also zero while the connect is in progress.
+			if _, err := unix.Getpeername(int(fd)); errors.Is(err, unix.ENOTCONN) {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	_ = f.SetWriteDeadline(time.Time{})
+	return connectErr
+}
+
+func sockname(fd int) (VsockAddr, error) {
+	sa, err := unix.Getsockname(fd)
+	if err != nil {
+		return VsockAddr{}, os.NewSyscallError("getsockname", err)
+	}
+	vm, ok := sa.(*unix.SockaddrVM)
+	if !ok {
+		return VsockAddr{}, errors.New("getsockname: not a vsock address")
+	}
+	return VsockAddr{CID: vm.CID, Port: vm.Port}, nil
+}
+
+func newVsockConn(f *os.File, rc syscall.RawConn, remote VsockAddr) (net.Conn, error) {
+	var local VsockAddr
+	var nameErr error
+	if err := rc.Control(func(fd uintptr) { local, nameErr = sockname(int(fd)) }); err != nil {
+		nameErr = err
+	}
+	if nameErr != nil {
+		_ = f.Close()
+		return nil, nameErr
+	}
+	return &vsockConn{File: f, local: local, remote: remote}, nil
+}
+
+// vsockConn is a connected vsock socket. The embedded *os.File provides
+// Read, Write, Close, and deadlines through the runtime poller.
+type vsockConn struct {
+	*os.File
+	local  VsockAddr
+	remote VsockAddr
+}
+
+func (c *vsockConn) LocalAddr() net.Addr  { return c.local }
+func (c *vsockConn) RemoteAddr() net.Addr { return c.remote }
+
+type vsockListener struct {
+	f    *os.File
+	rc   syscall.RawConn
+	addr VsockAddr
+}
+
+func (l *vsockListener) Accept() (net.Conn, error) {
+	var nfd int
+	var sa unix.Sockaddr
+	var acceptErr error
+	err := l.rc.Read(func(fd uintptr) bool {
+		nfd, sa, acceptErr = unix.Accept4(int(fd), unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC)
+		return !errors.Is(acceptErr, unix.EAGAIN)
+	})
+	if err == nil && acceptErr != nil {
+		err = os.NewSyscallError("accept", acceptErr)
+	}
+	if err != nil {
+		if errors.Is(err, os.ErrClosed) {
+			err = net.ErrClosed
+		}
+		return nil, &net.OpError{Op: "accept", Net: "vsock", Addr: l.addr, Err: err}
+	}
+	var remote VsockAddr
+	if vm, ok := sa.(*unix.SockaddrVM); ok {
+		remote = VsockAddr{CID: vm.CID, Port: vm.Port}
+	}
+	f := os.NewFile(uintptr(nfd), "vsock:"+remote.String())
+	rc, err := f.SyscallConn()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return newVsockConn(f, rc, remote)
+}
+
+func (l *vsockListener) Close() error   { return l.f.Close() }
+func (l *vsockListener) Addr() net.Addr { return l.addr }
+
+func dialError(addr VsockAddr, err error) error {
+	return &net.OpError{Op: "dial", Net: "vsock", Addr: addr, Err: err}
+}
+
+func listenError(port uint32, err error) error {
+	return &net.OpError{Op: "listen", Net: "vsock", Addr: VsockAddr{CID: unix.VMADDR_CID_ANY, Port: port}, Err: err}
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package proxy
+
+import (
+	"context"
+	"net"
+)
+
+// DialVsock returns ErrVsockUnsupported outside Linux.
+func DialVsock(context.Context, VsockAddr) (net.Conn, error) {
+	return nil, ErrVsockUnsupported
+}
+
+// ListenVsock returns ErrVsockUnsupported outside Linux.
+func ListenVsock(uint32) (net.Listener, error) {
+	return nil, ErrVsockUnsupported
+}
@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseVsockAddr(t *testing.T) {
+	addr, err := ParseVsockAddr("2:1024")
+	if err != nil || addr != (VsockAddr{CID: 2, Port: 1024}) {
+		t.Errorf("ParseVsockAddr() = %v, %v", addr, err)
+	}
+	if addr.String() != "2:1024" || addr.Network() != "vsock" {
+		t.Errorf("addr = %s/%s", addr.Network(), addr)
+	}
+	for _, bad := range []string{"", "2", "x:1", "2:x", "2:99999999999"} {
+		if _, err := ParseVsockAddr(bad); err == nil {
+			t.Errorf("ParseVsockAddr(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestGatewayVsockAddr(t *testing.T) {
+	t.Setenv(GatewayVsockEnv, "")
+	addr, err := GatewayVsockAddr()
+	if err != nil || addr != (VsockAddr{CID: HostCID, Port: DefaultGatewayVsockPort}) {
+		t.Errorf("GatewayVsockAddr() = %v, %v; want the host default", addr, err)
+	}
+
+	t.Setenv(GatewayVsockEnv, "2:5000")
+	if addr, _ := GatewayVsockAddr(); addr.Port != 5000 {
+		t.Errorf("GatewayVsockAddr() = %v, want port 5000 from the environment", addr)
+	}
+	if got := GatewayVsockEnvValue(5000); got != GatewayVsockEnv+"=2:5000" {
+		t.Errorf("GatewayVsockEnvValue() = %q", got)
+	}
+}
+
+// TestVsockLoopback exchanges a message over vsock loopback (CID 1),
+// which needs the vsock_loopback transport.
+func TestVsockLoopback(t *testing.T) {
+	l, err := ListenVsock(0xFFFFFFFF)
+	if errors.Is(err, ErrVsockUnsupported) {
+		t.Skip("vsock unsupported on this platform")
+	}
+	if err != nil {
+		t.Skipf("vsock unavailable: %v", err)
+	}
+	defer func() { _ = l.Close() }()
+	port := l.Addr().(VsockAddr).Port
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	conn, err := DialVsock(ctx, VsockAddr{CID: 1, Port: port})
+	if err != nil {
+		t.Skipf("vsock loopback unavailable: %v", err)
+	}
+	guest := NewStreamConnection(conn, nil)
+	defer func() { _ = guest.Close() }()
+
+	accepted, err := l.Accept()
+	if err != nil {
+		t.Fatalf("Accept() error = %v", err)
+	}
+	host := NewStreamConnection(accepted, nil)
+	defer func() { _ = host.Close() }()
+
+	go func() { _ = guest.Send(context.Background(), Message{Type: MsgSearchTools, ID: "1"}) }()
+	if msg, err := host.Receive(ctx); err != nil || msg.Type != MsgSearchTools {
+		t.Errorf("Receive() = %+v, %v", msg, err)
+	}
+}
+
+func TestVsockListenerClose(t *testing.T) {
+	l, err := ListenVsock(0xFFFFFFFF)
+	if err != nil {
+		t.Skipf("vsock unavailable: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := l.Accept()
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	_ = l.Close()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Accept() after Close succeeded")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close() did not unblock Accept()")
+	}
+}
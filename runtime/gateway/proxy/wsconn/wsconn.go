@@ -0,0 +1,338 @@
+// Package wsconn provides a proxy.Connection over WebSocket, for sandboxes
+// that reach the host gateway over the network.
+//
+// It is a separate module so that the core toolexec module does not
+// depend on a WebSocket library. In the sandbox, DialGateway returns a
+// gateway that is ready for tool calls:
+//
+//	gw, err := wsconn.DialGateway(ctx, "ws://host.internal:7070/gateway", wsconn.Config{
+//		Header: http.Header{"Authorization": {"Bearer " + token}},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer gw.Close()
+//
+// On the host, Upgrade accepts a connection in an http.Handler, which
+// then receives requests and sends responses on it:
+//
+//	conn, err := wsconn.Upgrade(w, r, wsconn.Config{})
+//	if err != nil {
+//		return // Upgrade has replied with an HTTP error
+//	}
+//	defer conn.Close()
+//
+// Each Message travels as one WebSocket message: a text message with the
+// default JSON codec, a binary one with any other Codec. Both sides ping
+// the peer every PingInterval and treat a connection that receives
+// nothing, not even a pong, for twice that long as lost. Control frames
+// are handled only while reading, so each side keeps a Receive call
+// pending for the connection's lifetime, as Gateway.ReceiveResponses does.
+package wsconn
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
+)
+
+// Config configures a connection.
+type Config struct {
+	// Codec encodes messages. Both sides must use the same one.
+	// Default: JSON
+	Codec proxy.Codec
+
+	// Header is sent with Dial's opening handshake, e.g. for
+	// authorization.
+	Header http.Header
+
+	// TLSConfig configures wss:// connections made by Dial. If nil, the
+	// default configuration is used.
+	TLSConfig *tls.Config
+
+	// HandshakeTimeout bounds the opening handshake.
+	// Default: 10s
+	HandshakeTimeout time.Duration
+
+	// CheckOrigin reports whether Upgrade accepts a request's Origin
+	// header.
+	// Default: requests without an Origin header, or whose origin matches
+	// the Host header, are accepted
+	CheckOrigin func(r *http.Request) bool
+
+	// PingInterval is how often the peer is pinged. A connection that
+	// receives nothing, not even a pong, for twice as long is lost.
+	// Default: 15s
+	PingInterval time.Duration
+
+	// WriteTimeout bounds each write.
+	// Default: 10s
+	WriteTimeout time.Duration
+
+	// MaxMessageBytes bounds a single received message.
+	// Default: proxy.MaxFrameSize
+	MaxMessageBytes int64
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.HandshakeTimeout <= 0 {
+		cfg.HandshakeTimeout = 10 * time.Second
+	}
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = 15 * time.Second
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 10 * time.Second
+	}
+	if cfg.MaxMessageBytes <= 0 {
+		cfg.MaxMessageBytes = proxy.MaxFrameSize
+	}
+	return cfg
+}
+
+// Dial opens a connection to the gateway at url, a ws:// or wss://
+// endpoint.
+func Dial(ctx context.Context, url string, cfg Config) (*Conn, error) {
+	cfg = cfg.withDefaults()
+	dialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		TLSClientConfig:  cfg.TLSConfig,
+		HandshakeTimeout: cfg.HandshakeTimeout,
+	}
+	ws, resp, err := dialer.DialContext(ctx, url, cfg.Header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("websocket handshake: %s: %w", resp.Status, err)
+		}
+		return nil, fmt.Errorf("websocket dial: %w", err)
+	}
+	return NewConn(ws, cfg), nil
+}
+
+// DialGateway dials url and returns a proxy.Gateway over the connection.
+// A goroutine delivers its responses (see Gateway.ReceiveResponses) until
+// the gateway is closed or the connection is lost.
+func DialGateway(ctx context.Context, url string, cfg Config) (*proxy.Gateway, error) {
+	conn, err := Dial(ctx, url, cfg)
+	if err != nil {
+		return nil, err
+	}
+	g := proxy.New(proxy.Config{Connection: conn, Codec: cfg.Codec})
+	go func() { _ = g.ReceiveResponses(context.Background()) }()
+	return g, nil
+}
+
+// Upgrade upgrades the HTTP request r to a WebSocket connection. On
+// failure it has already replied to r with an HTTP error.
+func Upgrade(w http.ResponseWriter, r *http.Request, cfg Config) (*Conn, error) {
+	cfg = cfg.withDefaults()
+	upgrader := websocket.Upgrader{
+		HandshakeTimeout: cfg.HandshakeTimeout,
+		CheckOrigin:      cfg.CheckOrigin,
+	}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, fmt.Errorf("websocket upgrade: %w", err)
+	}
+	return NewConn(ws, cfg), nil
+}
+
+// Conn is a proxy.Connection over one WebSocket connection. Gorilla
+// connections support one concurrent reader and one concurrent writer, so
+// reads and writes are each serialized.
+type Conn struct {
+	ws           *websocket.Conn
+	codec        proxy.Codec
+	messageType  int
+	writeTimeout time.Duration
+	idleTimeout  time.Duration
+
+	readMu  sync.Mutex
+	writeMu sync.Mutex
+
+	closeOnce sync.Once
+	done      chan struct{}
+	closeErr  error
+}
+
+// NewConn wraps an established WebSocket connection, from either side,
+// and starts pinging the peer. Handshake settings in cfg are ignored.
+func NewConn(ws *websocket.Conn, cfg Config) *Conn {
+	cfg = cfg.withDefaults()
+	c := &Conn{
+		ws:           ws,
+		codec:        cfg.Codec,
+		messageType:  websocket.BinaryMessage,
+		writeTimeout: cfg.WriteTimeout,
+		idleTimeout:  2 * cfg.PingInterval,
+		done:         make(chan struct{}),
+	}
+	if c.codec == nil {
+		c.codec = jsonCodec{}
+		c.messageType = websocket.TextMessage
+	}
+	ws.SetReadLimit(cfg.MaxMessageBytes)
+	_ = ws.SetReadDeadline(time.Now().Add(c.idleTimeout))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(c.idleTimeout))
+	})
+	ws.SetPingHandler(func(data string) error {
+		_ = ws.SetReadDeadline(time.Now().Add(c.idleTimeout))
+		err := ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(c.writeTimeout))
+		var netErr net.Error
+		if errors.Is(err, websocket.ErrCloseSent) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return nil
+		}
+		return err
+	})
+	go c.ping(cfg.PingInterval)
+	return c
+}
+
+// Send implements proxy.Connection. It fails once WriteTimeout passes
+// or ctx ends, whichever is first.
+func (c *Conn) Send(ctx context.Context, msg proxy.Message) error {
+	data, err := c.codec.Encode(msg)
+	if err != nil {
+		return fmt.Errorf("%w: encode: %v", proxy.ErrProtocol, err)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.isClosed() {
+		return proxy.ErrConnectionClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(c.writeTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = c.ws.SetWriteDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { _ = c.ws.SetWriteDeadline(time.Unix(1, 0)) })
+	defer stop()
+	if err := c.ws.WriteMessage(c.messageType, data); err != nil {
+		return c.err(ctx, err)
+	}
+	return nil
+}
+
+// Receive implements proxy.Connection. Cancelling ctx interrupts the read
+// and leaves the connection unusable, as a timed-out WebSocket read does.
+func (c *Conn) Receive(ctx context.Context) (proxy.Message, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	if c.isClosed() {
+		return proxy.Message{}, proxy.ErrConnectionClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return proxy.Message{}, err
+	}
+	stop := context.AfterFunc(ctx, func() { _ = c.ws.SetReadDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	_, data, err := c.ws.ReadMessage()
+	if err != nil {
+		// websocket hides the deadline error behind a net.Error.
+		var netErr net.Error
+		if (errors.Is(err, os.ErrDeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout()) && ctx.Err() == nil {
+			return proxy.Message{}, fmt.Errorf("%w: peer silent for %v", proxy.ErrConnectionClosed, c.idleTimeout)
+		}
+		return proxy.Message{}, c.err(ctx, err)
+	}
+	// Any message proves the peer alive.
+	_ = c.ws.SetReadDeadline(time.Now().Add(c.idleTimeout))
+	msg, err := c.codec.Decode(data)
+	if err != nil {
+		return proxy.Message{}, fmt.Errorf("%w: decode: %v", proxy.ErrProtocol, err)
+	}
+	return msg, nil
+}
+
+// Close implements proxy.Connection. It sends a close frame, best-effort,
+// before closing the connection.
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		_ = c.ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(time.Second))
+		c.closeErr = c.ws.Close()
+	})
+	return c.closeErr
+}
+
+// RemoteAddr returns the peer's network address.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.ws.RemoteAddr()
+}
+
+func (c *Conn) isClosed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// ping keeps the connection alive until it is closed.
+func (c *Conn) ping(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			// WriteControl may be called concurrently with other methods.
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.writeTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// jsonCodec is the default codec, matching the proxy package's.
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(msg proxy.Message) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+func (jsonCodec) Decode(data []byte) (proxy.Message, error) {
+	var msg proxy.Message
+	err := json.Unmarshal(data, &msg)
+	return msg, err
+}
+
+// err maps a WebSocket error to the proxy.Connection contract.
+func (c *Conn) err(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	var closeErr *websocket.CloseError
+	switch {
+	case errors.Is(err, websocket.ErrReadLimit):
+		return fmt.Errorf("%w: %v", proxy.ErrProtocol, err)
+	case c.isClosed(), errors.As(err, &closeErr), errors.Is(err, websocket.ErrCloseSent),
+		errors.Is(err, net.ErrClosed), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Errorf("%w: %v", proxy.ErrConnectionClosed, err)
+	default:
+		return err
+	}
+}
+
+var _ proxy.Connection = (*Conn)(nil)
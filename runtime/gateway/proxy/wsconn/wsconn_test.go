@@ -0,0 +1,163 @@
+package wsconn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
+)
+
+// newHost starts a gateway host that upgrades each request with cfg and
+// runs serve on the connection, and returns its ws:// URL.
+func newHost(t *testing.T, cfg Config, serve func(*Conn)) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, err := Upgrade(w, r, cfg)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serve(conn)
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// echo answers run_tool requests with the tool ID until the connection
+// closes.
+func echo(conn *Conn) {
+	for {
+		req, err := conn.Receive(context.Background())
+		if err != nil {
+			return
+		}
+		_ = conn.Send(context.Background(), proxy.Message{
+			Type:    proxy.MsgResponse,
+			ID:      req.ID,
+			Payload: map[string]any{"structured": req.Payload["id"]},
+		})
+	}
+}
+
+var auth = http.Header{"Authorization": {"Bearer token"}}
+
+func TestDialGateway(t *testing.T) {
+	url := newHost(t, Config{PingInterval: 10 * time.Millisecond}, echo)
+
+	g, err := DialGateway(context.Background(), url, Config{Header: auth, PingInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("DialGateway() error = %v", err)
+	}
+	defer g.Close()
+
+	for _, id := range []string{"ns:a", "ns:b"} {
+		result, err := g.RunTool(context.Background(), id, nil)
+		if err != nil || result.Structured != id {
+			t.Errorf("RunTool(%s) = %+v, %v; want the host's echo", id, result, err)
+		}
+		// Outlive a few ping intervals so the heartbeat is exercised.
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestConnBinaryCodec(t *testing.T) {
+	cfg := Config{Codec: prefixCodec{}}
+	url := newHost(t, cfg, echo)
+	cfg.Header = auth
+	conn, err := Dial(context.Background(), url, cfg)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.Send(context.Background(), proxy.Message{Type: proxy.MsgRunTool, ID: "1", Payload: map[string]any{"id": "ns:a"}}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	resp, err := conn.Receive(context.Background())
+	if err != nil || resp.ID != "1" || resp.Payload["structured"] != "ns:a" {
+		t.Errorf("Receive() = %+v, %v; want the echo of request 1", resp, err)
+	}
+}
+
+func TestConnErrors(t *testing.T) {
+	if _, err := Dial(context.Background(), newHost(t, Config{}, echo), Config{}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Dial() without credentials error = %v, want a 401 handshake error", err)
+	}
+
+	// A peer that stops answering pings is detected.
+	silent := newHost(t, Config{}, func(conn *Conn) {
+		conn.ws.SetPingHandler(func(string) error { return nil })
+		_, _, _ = conn.ws.ReadMessage()
+	})
+	conn, err := Dial(context.Background(), silent, Config{Header: auth, PingInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	start := time.Now()
+	if _, err := conn.Receive(context.Background()); !errors.Is(err, proxy.ErrConnectionClosed) {
+		t.Errorf("Receive() from a silent peer error = %v, want %v", err, proxy.ErrConnectionClosed)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Receive() took %v to detect a silent peer", elapsed)
+	}
+	_ = conn.Close()
+	if err := conn.Send(context.Background(), proxy.Message{Type: proxy.MsgRunTool}); !errors.Is(err, proxy.ErrConnectionClosed) {
+		t.Errorf("Send() after Close error = %v, want %v", err, proxy.ErrConnectionClosed)
+	}
+
+	// Oversized messages and peers that hang up are reported as such.
+	big := newHost(t, Config{}, func(conn *Conn) {
+		_ = conn.Send(context.Background(), proxy.Message{Type: proxy.MsgResponse, ID: strings.Repeat("x", 1024)})
+	})
+	conn, err = Dial(context.Background(), big, Config{Header: auth, MaxMessageBytes: 512})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Receive(context.Background()); !errors.Is(err, proxy.ErrProtocol) {
+		t.Errorf("Receive() of an oversized message error = %v, want %v", err, proxy.ErrProtocol)
+	}
+
+	hangup := newHost(t, Config{}, func(*Conn) {})
+	conn, err = Dial(context.Background(), hangup, Config{Header: auth})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Receive(context.Background()); !errors.Is(err, proxy.ErrConnectionClosed) {
+		t.Errorf("Receive() after the peer closed error = %v, want %v", err, proxy.ErrConnectionClosed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := conn.Receive(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Receive() with a canceled context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+// prefixCodec is a non-default codec, so messages travel as binary.
+type prefixCodec struct{}
+
+func (prefixCodec) Encode(msg proxy.Message) ([]byte, error) {
+	data, err := json.Marshal(msg)
+	return append([]byte{0}, data...), err
+}
+
+func (prefixCodec) Decode(data []byte) (proxy.Message, error) {
+	var msg proxy.Message
+	if len(data) == 0 || data[0] != 0 {
+		return msg, errors.New("missing prefix")
+	}
+	err := json.Unmarshal(data[1:], &msg)
+	return msg, err
+}
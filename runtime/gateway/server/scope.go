@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+// Errors for request authorization.
+var (
+	// ErrUnauthorized is returned for requests whose token is missing,
+	// unknown, or revoked.
+	ErrUnauthorized = errors.New("gateway server: unauthorized")
+
+	// ErrForbidden is returned for requests outside the token's Scope.
+	ErrForbidden = errors.New("gateway server: forbidden")
+)
+
+// Scope is what a token allows. The zero Scope allows everything.
+type Scope struct {
+	// ExecutionID is the execution the token was granted to. Requests are
+	// accounted to it whatever ExecutionID they carry, so a sandbox cannot
+	// claim another execution's budget or a fresh one.
+	ExecutionID string
+
+	// Namespaces lists the tool namespaces the token may discover and
+	// call. Searches and namespace listings omit the others.
+	// Empty means all namespaces.
+	Namespaces []string
+
+	// MaxToolCalls limits the execution's tool invocations when lower than
+	// Config.MaxToolCalls. An execution's budget is fixed by its first
+	// request.
+	// Zero means Config.MaxToolCalls applies.
+	MaxToolCalls int
+
+	// ReadOnly allows discovery only: run_tool, run_tool_stream, and
+	// run_chain are forbidden.
+	ReadOnly bool
+}
+
+// allows reports whether namespace is within the scope.
+func (s Scope) allows(namespace string) bool {
+	return len(s.Namespaces) == 0 || slices.Contains(s.Namespaces, namespace)
+}
+
+// Verifier checks the tokens carried by requests.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines.
+// - Errors: unknown, expired, or revoked tokens return an error wrapping
+// ErrUnauthorized.
+type Verifier interface {
+	// Verify returns the scope granted to token.
+	Verify(ctx context.Context, token string) (Scope, error)
+}
+
+// TokenStore is an in-memory Verifier that issues random tokens. Only a
+// hash of each token is kept.
+type TokenStore struct {
+	mu     sync.RWMutex
+	scopes map[[sha256.Size]byte]Scope
+}
+
+// NewTokenStore creates an empty TokenStore.
+func NewTokenStore() *TokenStore {
+	return &TokenStore{scopes: make(map[[sha256.Size]byte]Scope)}
+}
+
+// Grant issues a new token with the given scope.
+func (s *TokenStore) Grant(scope Scope) (string, error) {
+	var secret [32]byte
+	if _, err := rand.Read(secret[:]); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret[:])
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scopes[sha256.Sum256([]byte(token))] = scope
+	return token, nil
+}
+
+// Revoke invalidates token. Revoking an unknown token is a no-op.
+func (s *TokenStore) Revoke(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.scopes, sha256.Sum256([]byte(token)))
+}
+
+// Verify implements Verifier.
+func (s *TokenStore) Verify(ctx context.Context, token string) (Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return Scope{}, err
+	}
+	if token == "" {
+		return Scope{}, fmt.Errorf("%w: no token", ErrUnauthorized)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	scope, ok := s.scopes[sha256.Sum256([]byte(token))]
+	if !ok {
+		return Scope{}, fmt.Errorf("%w: unknown token", ErrUnauthorized)
+	}
+	return scope, nil
+}
+
+// maxScopedSearch bounds how many results a scoped search asks the
+// underlying gateway for while filling its limit with in-scope tools.
+const maxScopedSearch = 1000
+
+// scopedGateway restricts a gateway to a Scope.
+type scopedGateway struct {
+	runtime.ToolGateway
+	scope Scope
+}
+
+// SearchTools searches within the scope's namespaces. Tools outside them
+// may outrank those inside, so the search widens until it fills limit,
+// the underlying gateway runs out of results, or it reaches
+// maxScopedSearch.
+func (g scopedGateway) SearchTools(ctx context.Context, query string, limit int) ([]index.Summary, error) {
+	outside := func(s index.Summary) bool { return !g.scope.allows(s.Namespace) }
+	if len(g.scope.Namespaces) == 0 || limit <= 0 {
+		summaries, err := g.ToolGateway.SearchTools(ctx, query, limit)
+		return slices.DeleteFunc(summaries, outside), err
+	}
+	for fetch := limit; ; fetch = min(fetch*4, maxScopedSearch) {
+		summaries, err := g.ToolGateway.SearchTools(ctx, query, fetch)
+		exhausted := len(summaries) < fetch
+		summaries = slices.DeleteFunc(summaries, outside)
+		if err != nil || exhausted || len(summaries) >= limit || fetch >= maxScopedSearch {
+			return summaries[:min(len(summaries), limit)], err
+		}
+	}
+}
+
+func (g scopedGateway) ListNamespaces(ctx context.Context) ([]string, error) {
+	namespaces, err := g.ToolGateway.ListNamespaces(ctx)
+	return slices.DeleteFunc(namespaces, func(ns string) bool { return !g.scope.allows(ns) }), err
+}
+
+func (g scopedGateway) DescribeTool(ctx context.Context, id string, level tooldoc.DetailLevel) (tooldoc.ToolDoc, error) {
+	if err := g.checkTool(id); err != nil {
+		return tooldoc.ToolDoc{}, err
+	}
+	return g.ToolGateway.DescribeTool(ctx, id, level)
+}
+
+func (g scopedGateway) ListToolExamples(ctx context.Context, id string, maxExamples int) ([]tooldoc.ToolExample, error) {
+	if err := g.checkTool(id); err != nil {
+		return nil, err
+	}
+	return g.ToolGateway.ListToolExamples(ctx, id, maxExamples)
+}
+
+func (g scopedGateway) RunTool(ctx context.Context, id string, args map[string]any) (run.RunResult, error) {
+	if err := g.checkRun(id); err != nil {
+		return run.RunResult{}, err
+	}
+	return g.ToolGateway.RunTool(ctx, id, args)
+}
+
+func (g scopedGateway) RunToolStream(ctx context.Context, id string, args map[string]any) (<-chan run.StreamEvent, error) {
+	if err := g.checkRun(id); err != nil {
+		return nil, err
+	}
+	sg, ok := g.ToolGateway.(runtime.StreamingGateway)
+	if !ok {
+		return nil, run.ErrStreamNotSupported
+	}
+	return sg.RunToolStream(ctx, id, args)
+}
+
+func (g scopedGateway) RunChain(ctx context.Context, steps []run.ChainStep) (run.RunResult, []run.StepResult, error) {
+	for _, step := range steps {
+		if err := g.checkRun(step.ToolID); err != nil {
+			return run.RunResult{}, nil, err
+		}
+	}
+	return g.ToolGateway.RunChain(ctx, steps)
+}
+
+func (g scopedGateway) checkRun(id string) error {
+	if g.scope.ReadOnly {
+		return fmt.Errorf("%w: token is read-only", ErrForbidden)
+	}
+	return g.checkTool(id)
+}
+
+func (g scopedGateway) checkTool(id string) error {
+	if len(g.scope.Namespaces) == 0 {
+		return nil
+	}
+	namespace, _, err := model.ParseToolID(id)
+	if err != nil || !g.scope.allows(namespace) {
+		return fmt.Errorf("%w: tool %q is outside the token's namespaces", ErrForbidden, id)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+func TestTokenStore(t *testing.T) {
+	tokens := NewTokenStore()
+	token, err := tokens.Grant(Scope{ExecutionID: "exec-1", ReadOnly: true})
+	if err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+	other, _ := tokens.Grant(Scope{})
+	if token == "" || token == other {
+		t.Errorf("Grant() = %q, %q; want distinct tokens", token, other)
+	}
+
+	ctx := context.Background()
+	if scope, err := tokens.Verify(ctx, token); err != nil || scope.ExecutionID != "exec-1" || !scope.ReadOnly {
+		t.Errorf("Verify() = %+v, %v; want the granted scope", scope, err)
+	}
+	tokens.Revoke(token)
+	for _, tok := range []string{token, "", "forged"} {
+		if _, err := tokens.Verify(ctx, tok); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("Verify(%q) error = %v, want %v", tok, err, ErrUnauthorized)
+		}
+	}
+}
+
+func TestServer_Scopes(t *testing.T) {
+	idx := newIndex(t)
+	tool := model.Tool{Tool: mcp.Tool{Name: "x", Description: "Test tool x", InputSchema: map[string]any{"type": "object"}}, Namespace: "other"}
+	if err := idx.RegisterTool(tool, model.NewLocalBackend("x")); err != nil {
+		t.Fatal(err)
+	}
+	tokens := NewTokenStore()
+	srv := New(Config{Index: idx, Docs: tooldoc.NewInMemoryStore(tooldoc.StoreOptions{Index: idx}), Runner: echo, Verifier: tokens})
+	token, _ := tokens.Grant(Scope{ExecutionID: "exec-1", Namespaces: []string{"test"}, MaxToolCalls: 1})
+	g := connect(t, srv, token)
+	// The token's execution applies, not the one the sandbox claims.
+	ctx := runtime.WithExecutionID(context.Background(), "exec-2")
+
+	if tools, err := g.SearchTools(ctx, "tool", 10); err != nil || len(tools) != 3 {
+		t.Errorf("SearchTools() = %+v, %v; want only the test tools", tools, err)
+	}
+	if ns, err := g.ListNamespaces(ctx); err != nil || len(ns) != 1 || ns[0] != "test" {
+		t.Errorf("ListNamespaces() = %v, %v; want [test]", ns, err)
+	}
+	for name, call := range map[string]func() error{
+		"DescribeTool": func() error { _, err := g.DescribeTool(ctx, "other:x", tooldoc.DetailSummary); return err },
+		"RunTool":      func() error { _, err := g.RunTool(ctx, "other:x", nil); return err },
+		"RunChain": func() error {
+			_, _, err := g.RunChain(ctx, []run.ChainStep{{ToolID: "test:a"}, {ToolID: "other:x"}})
+			return err
+		},
+	} {
+		if err := call(); err == nil || !strings.Contains(err.Error(), ErrForbidden.Error()) {
+			t.Errorf("%s() outside the namespaces error = %v, want %v", name, err, ErrForbidden)
+		}
+	}
+
+	if _, err := g.RunTool(ctx, "test:a", nil); err != nil {
+		t.Fatalf("RunTool() error = %v", err)
+	}
+	if _, err := g.RunTool(ctx, "test:a", nil); err == nil || !strings.Contains(err.Error(), "tool call limit exceeded") {
+		t.Errorf("second RunTool() error = %v, want the token's call limit", err)
+	}
+	if calls := srv.ToolCalls("exec-1"); len(calls) != 1 || srv.ToolCalls("exec-2") != nil {
+		t.Errorf("ToolCalls(exec-1) = %+v, want the call accounted to the token's execution", calls)
+	}
+	// A released execution stays released, so the token cannot open a
+	// fresh budget.
+	srv.Release("exec-1")
+	if _, err := g.RunTool(ctx, "test:a", nil); err == nil || !strings.Contains(err.Error(), ErrUnknownExecution.Error()) {
+		t.Errorf("RunTool() after Release error = %v, want %v", err, ErrUnknownExecution)
+	}
+	srv.Register("exec-1")
+	if _, err := g.RunTool(ctx, "test:a", nil); err != nil {
+		t.Errorf("RunTool() after Register error = %v", err)
+	}
+
+	// Tokens without an execution use the one the request carries, which
+	// the host must have registered.
+	readOnly, _ := tokens.Grant(Scope{ReadOnly: true})
+	if _, err := connect(t, srv, readOnly).ListNamespaces(ctx); err == nil || !strings.Contains(err.Error(), ErrUnknownExecution.Error()) {
+		t.Errorf("ListNamespaces() for an unregistered execution error = %v, want %v", err, ErrUnknownExecution)
+	}
+	srv.Register("exec-2")
+	g = connect(t, srv, readOnly)
+	if _, err := g.RunTool(ctx, "test:a", nil); err == nil || !strings.Contains(err.Error(), ErrForbidden.Error()) {
+		t.Errorf("RunTool() with a read-only token error = %v, want %v", err, ErrForbidden)
+	}
+	if _, err := g.DescribeTool(ctx, "other:x", tooldoc.DetailSummary); err != nil {
+		t.Errorf("DescribeTool() with a read-only token error = %v", err)
+	}
+	events, err := g.RunToolStream(ctx, "test:a", nil)
+	if err != nil {
+		t.Fatalf("RunToolStream() error = %v", err)
+	}
+	if ev := <-events; ev.Kind != run.StreamEventError || !strings.Contains(ev.Err.Error(), ErrForbidden.Error()) {
+		t.Errorf("RunToolStream() with a read-only token = %+v, want %v", ev, ErrForbidden)
+	}
+
+	tokens.Revoke(token)
+	for _, tok := range []string{token, ""} {
+		if _, err := connect(t, srv, tok).ListNamespaces(ctx); err == nil || !strings.Contains(err.Error(), ErrUnauthorized.Error()) {
+			t.Errorf("ListNamespaces() with token %q error = %v, want %v", tok, err, ErrUnauthorized)
+		}
+	}
+}
+
+// rankedGateway returns its summaries, in order, up to the search limit.
+type rankedGateway struct {
+	runtime.ToolGateway
+	summaries []index.Summary
+	limits    []int
+}
+
+func (g *rankedGateway) SearchTools(_ context.Context, _ string, limit int) ([]index.Summary, error) {
+	g.limits = append(g.limits, limit)
+	return slices.Clone(g.summaries[:min(limit, len(g.summaries))]), nil
+}
+
+func TestScopedGateway_SearchFillsLimit(t *testing.T) {
+	inner := &rankedGateway{}
+	for i := range 20 {
+		inner.summaries = append(inner.summaries, index.Summary{ID: fmt.Sprint("other:", i), Namespace: "other"})
+	}
+	inner.summaries = append(inner.summaries,
+		index.Summary{ID: "test:a", Namespace: "test"},
+		index.Summary{ID: "test:b", Namespace: "test"},
+		index.Summary{ID: "test:c", Namespace: "test"},
+	)
+	g := scopedGateway{ToolGateway: inner, scope: Scope{Namespaces: []string{"test"}}}
+
+	got, err := g.SearchTools(context.Background(), "q", 2)
+	if err != nil || len(got) != 2 || got[0].ID != "test:a" || got[1].ID != "test:b" {
+		t.Errorf("SearchTools() = %+v, %v; want the two best in-scope tools", got, err)
+	}
+	got, err = g.SearchTools(context.Background(), "q", 5)
+	if err != nil || len(got) != 3 {
+		t.Errorf("SearchTools() = %+v, %v; want every in-scope tool", got, err)
+	}
+	if want := []int{2, 8, 32, 5, 20, 80}; !slices.Equal(inner.limits, want) {
+		t.Errorf("underlying search limits = %v, want %v", inner.limits, want)
+	}
+}
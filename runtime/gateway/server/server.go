@@ -0,0 +1,360 @@
+// Package server provides the host side of the proxy protocol: a
+// proxy.Handler that answers gateway requests from a tool index, doc
+// store, and runner, enforcing limits for each execution.
+//
+// One Server can serve every sandbox on a host, for instance through
+// proxy.Serve on a Unix socket:
+//
+//	srv := server.New(server.Config{Index: idx, Docs: docs, Runner: runner, MaxToolCalls: 50})
+//	go func() { _ = proxy.Serve(ctx, ln, srv, nil) }()
+//
+// Requests are accounted to the ExecutionID they carry, so each execution
+// gets its own tool call budget and call records. The host registers an
+// execution with Register before its sandbox starts, and releases it with
+// Release once it finishes. Requests for executions the host did not
+// register fail with ErrUnknownExecution, so a sandbox cannot open a fresh
+// budget by sending another ID.
+//
+// With a Verifier, every request must carry a token, and is confined to
+// the Scope granted to it. A TokenStore issues a token per execution:
+//
+//	tokens := server.NewTokenStore()
+//	srv := server.New(server.Config{Index: idx, Docs: docs, Runner: runner, Verifier: tokens})
+//	token, err := tokens.Grant(server.Scope{ExecutionID: id, Namespaces: []string{"github"}})
+//
+// The sandbox sends it with proxy.Config.Token, typically read from
+// proxy.GatewayTokenEnv.
+//
+// Sandboxes start on the connection's codec, JSON by default. With Codecs,
+// they can switch to a binary codec, such as cborcodec's, in their hello.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/gateway/direct"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
+)
+
+var (
+	// ErrNotConfigured is returned for requests that need a component the
+	// Config leaves nil.
+	ErrNotConfigured = errors.New("gateway server: component not configured")
+
+	// ErrUnknownExecution is returned for requests whose execution was
+	// not registered, or was released.
+	ErrUnknownExecution = errors.New("gateway server: unknown execution")
+)
+
+// Config configures a Server.
+type Config struct {
+	// Index answers search_tools and list_namespaces.
+	Index index.Index
+
+	// Docs answers describe_tool and list_tool_examples.
+	Docs tooldoc.Store
+
+	// Runner answers run_tool and run_chain.
+	Runner run.Executor
+
+	// MaxToolCalls limits the tool invocations of each execution.
+	// Zero means unlimited.
+	MaxToolCalls int
+
+	// MaxChainSteps limits the number of steps in a chain.
+	// Zero means unlimited.
+	MaxChainSteps int
+
+	// MaxSearchResults caps the limit of a search_tools request; larger or
+	// missing limits are lowered to it.
+	// Zero means unlimited.
+	MaxSearchResults int
+
+	// MaxExamples caps the max of a list_tool_examples request, as
+	// MaxSearchResults does for searches.
+	// Zero means unlimited.
+	MaxExamples int
+
+	// RequestTimeout bounds the handling of each request.
+	// Zero means no timeout beyond the connection's context.
+	RequestTimeout time.Duration
+
+	// ExecutionTTL is how long an execution may go without requests
+	// before it is released, for hosts that miss a Release.
+	// Default: 1h
+	ExecutionTTL time.Duration
+
+	// Verifier checks the token of each request and scopes it. Requests
+	// it rejects are answered with ErrUnauthorized. A token whose Scope
+	// names an execution registers it, unless that execution was released
+	// or expired within the last ExecutionTTL. Ended executions are
+	// forgotten after that, so revoke an execution's token when releasing
+	// it.
+	// If nil, requests are not authenticated.
+	Verifier Verifier
+
+	// Codecs are the codecs ServeConn lets sandboxes switch to with a
+	// hello, such as a binary codec for large arguments and results.
+	// Empty means connections keep the codec they were made with.
+	Codecs []proxy.NamedCodec
+}
+
+// Server answers gateway requests. It implements proxy.StreamHandler and
+// is safe for concurrent use.
+type Server struct {
+	cfg Config
+
+	mu         sync.Mutex
+	executions map[string]*execution
+	ended      map[string]time.Time // released or expired, with when; tokens cannot register them
+	swept      time.Time
+}
+
+// execution is the state of a registered execution.
+type execution struct {
+	gw       *direct.Gateway // created by the execution's first request
+	lastUsed time.Time
+}
+
+// New creates a Server with the given configuration.
+func New(cfg Config) *Server {
+	if cfg.ExecutionTTL <= 0 {
+		cfg.ExecutionTTL = time.Hour
+	}
+	return &Server{cfg: cfg, executions: make(map[string]*execution), ended: make(map[string]time.Time), swept: time.Now()}
+}
+
+// ServeMessage implements proxy.Handler. Requests without an ExecutionID
+// belong to the execution registered as "".
+func (s *Server) ServeMessage(ctx context.Context, req proxy.Message) proxy.Message {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+	h, req, err := s.handler(ctx, req)
+	if err != nil {
+		return proxy.ErrorResponse(req.ID, err)
+	}
+	return h.ServeMessage(ctx, req)
+}
+
+// ServeStream implements proxy.StreamHandler. Streams are authorized,
+// limited, and accounted like run_tool requests.
+func (s *Server) ServeStream(ctx context.Context, req proxy.Message, send func(proxy.Message) error) {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+	h, req, err := s.handler(ctx, req)
+	if err != nil {
+		_ = send(proxy.ErrorResponse(req.ID, err))
+		return
+	}
+	h.ServeStream(ctx, req, send)
+}
+
+func (s *Server) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.cfg.RequestTimeout > 0 {
+		return context.WithTimeout(ctx, s.cfg.RequestTimeout)
+	}
+	return ctx, func() {}
+}
+
+// handler authorizes req and returns the handler for its execution, with
+// req rewritten to the execution and limits that apply.
+func (s *Server) handler(ctx context.Context, req proxy.Message) (proxy.StreamHandler, proxy.Message, error) {
+	var (
+		scope  Scope
+		scoped bool
+	)
+	if s.cfg.Verifier != nil {
+		var err error
+		if scope, err = s.cfg.Verifier.Verify(ctx, req.Token); err != nil {
+			return nil, req, err
+		}
+		if scope.ExecutionID != "" {
+			req.ExecutionID = scope.ExecutionID
+			scoped = true
+		}
+	}
+	if err := s.check(req.Type); err != nil {
+		return nil, req, err
+	}
+
+	req.Payload = s.clamp(req.Type, req.Payload)
+	dgw, err := s.gateway(req.ExecutionID, scope.MaxToolCalls, scoped)
+	if err != nil {
+		return nil, req, err
+	}
+	var gw runtime.ToolGateway = dgw
+	if s.cfg.Verifier != nil {
+		gw = scopedGateway{ToolGateway: gw, scope: scope}
+	}
+	return proxy.NewGatewayHandler(gw), req, nil
+}
+
+// ServeConn answers the requests on conn until it closes or ctx ends; see
+// proxy.Pump. Serving with proxy.Serve instead takes the codecs as
+// proxy.WithCodecs.
+func (s *Server) ServeConn(ctx context.Context, conn proxy.Connection) error {
+	return proxy.Pump(ctx, conn, s, proxy.WithCodecs(s.cfg.Codecs...))
+}
+
+// Register lets the sandbox of an execution send requests for it,
+// starting its budget. Registering an execution again keeps its budget.
+func (s *Server) Register(executionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.ended, executionID)
+	if _, ok := s.executions[executionID]; !ok {
+		s.executions[executionID] = &execution{lastUsed: time.Now()}
+	}
+}
+
+// ToolCalls returns the tool calls recorded for an execution.
+func (s *Server) ToolCalls(executionID string) []runtime.ToolCallRecord {
+	s.mu.Lock()
+	e := s.executions[executionID]
+	s.mu.Unlock()
+	if e == nil || e.gw == nil {
+		return nil
+	}
+	return e.gw.GetToolCalls()
+}
+
+// Release discards an execution's call records and budget. Later requests
+// for it fail with ErrUnknownExecution until it is registered again,
+// including requests whose token names it.
+func (s *Server) Release(executionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.end(executionID)
+}
+
+// end drops an execution and keeps a token from registering it again,
+// which would open a fresh budget. s.mu must be held.
+func (s *Server) end(executionID string) {
+	delete(s.executions, executionID)
+	s.ended[executionID] = time.Now()
+}
+
+// gateway returns the execution's gateway, which holds its budget. Only
+// registered executions have one, unless register is set and the
+// execution has not ended. A new budget
+// allows the lower of Config.MaxToolCalls and maxToolCalls, ignoring
+// zeros.
+func (s *Server) gateway(executionID string, maxToolCalls int, register bool) (*direct.Gateway, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.expire(now)
+	e, ok := s.executions[executionID]
+	if !ok {
+		if _, ended := s.ended[executionID]; !register || ended {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownExecution, executionID)
+		}
+		e = &execution{}
+		s.executions[executionID] = e
+	}
+	e.lastUsed = now
+	if e.gw == nil {
+		if maxToolCalls <= 0 || (s.cfg.MaxToolCalls > 0 && s.cfg.MaxToolCalls < maxToolCalls) {
+			maxToolCalls = s.cfg.MaxToolCalls
+		}
+		e.gw = direct.New(direct.Config{
+			Index:         s.cfg.Index,
+			Docs:          s.cfg.Docs,
+			Runner:        s.cfg.Runner,
+			MaxToolCalls:  maxToolCalls,
+			MaxChainSteps: s.cfg.MaxChainSteps,
+		})
+	}
+	return e.gw, nil
+}
+
+// expire releases the executions idle for longer than ExecutionTTL and
+// forgets those ended longer than ExecutionTTL ago, so ended does not
+// grow with every execution. It scans them at most every half TTL. s.mu
+// must be held.
+func (s *Server) expire(now time.Time) {
+	if now.Sub(s.swept) < s.cfg.ExecutionTTL/2 {
+		return
+	}
+	s.swept = now
+	for id, e := range s.executions {
+		if now.Sub(e.lastUsed) > s.cfg.ExecutionTTL {
+			s.end(id)
+		}
+	}
+	for id, at := range s.ended {
+		if now.Sub(at) > s.cfg.ExecutionTTL {
+			delete(s.ended, id)
+		}
+	}
+}
+
+// check reports whether the component a request needs is configured.
+// Unknown request types are left for the dispatcher to reject.
+func (s *Server) check(t proxy.MessageType) error {
+	var missing string
+	switch t {
+	case proxy.MsgSearchTools, proxy.MsgListNamespaces:
+		if s.cfg.Index == nil {
+			missing = "index"
+		}
+	case proxy.MsgDescribeTool, proxy.MsgListToolExamples:
+		if s.cfg.Docs == nil {
+			missing = "docs"
+		}
+	case proxy.MsgRunTool, proxy.MsgRunChain, proxy.MsgRunToolStream:
+		if s.cfg.Runner == nil {
+			missing = "runner"
+		}
+	}
+	if missing != "" {
+		return fmt.Errorf("%w: no %s for %s", ErrNotConfigured, missing, t)
+	}
+	return nil
+}
+
+// clamp lowers a request's result count to the configured maximum,
+// without modifying the caller's payload.
+func (s *Server) clamp(t proxy.MessageType, p map[string]any) map[string]any {
+	key, limit := "", 0
+	switch t {
+	case proxy.MsgSearchTools:
+		key, limit = "limit", s.cfg.MaxSearchResults
+	case proxy.MsgListToolExamples:
+		key, limit = "max", s.cfg.MaxExamples
+	}
+	if limit <= 0 {
+		return p
+	}
+	var n int
+	switch v := p[key].(type) {
+	case float64:
+		n = int(v)
+	case int:
+		n = v
+	case int64:
+		n = int(v)
+	case uint64:
+		n = int(v)
+	}
+	if n > 0 && n <= limit {
+		return p
+	}
+	p = maps.Clone(p)
+	if p == nil {
+		p = make(map[string]any, 1)
+	}
+	p[key] = float64(limit)
+	return p
+}
+
+var _ proxy.StreamHandler = (*Server)(nil)
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/jonwraymond/tooldiscovery/index"
+	"github.com/jonwraymond/tooldiscovery/tooldoc"
+	"github.com/jonwraymond/toolexec/run"
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/gateway/proxy"
+	"github.com/jonwraymond/toolfoundation/model"
+)
+
+// newIndex returns an index of the tools test:a, test:b, and test:c.
+func newIndex(t *testing.T) index.Index {
+	t.Helper()
+	idx := index.NewInMemoryIndex()
+	for _, name := range []string{"a", "b", "c"} {
+		tool := model.Tool{
+			Tool:      mcp.Tool{Name: name, Description: "Test tool " + name, InputSchema: map[string]any{"type": "object"}},
+			Namespace: "test",
+		}
+		if err := idx.RegisterTool(tool, model.NewLocalBackend(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return idx
+}
+
+// echo runs a tool by returning its ID.
+var echo = run.ExecutorFunc(func(_ context.Context, id string, _ map[string]any) (run.RunResult, error) {
+	return run.RunResult{Structured: id}, nil
+})
+
+// connect serves srv on one end of a pipe and returns a client gateway on
+// the other, sending token with its requests.
+func connect(t *testing.T, srv *Server, token string) *proxy.Gateway {
+	t.Helper()
+	guest, host := net.Pipe()
+	go func() { _ = srv.ServeConn(context.Background(), proxy.NewStreamConnection(host, nil)) }()
+	g := proxy.New(proxy.Config{Connection: proxy.NewStreamConnection(guest, nil), Token: token})
+	go func() { _ = g.ReceiveResponses(context.Background()) }()
+	t.Cleanup(func() {
+		_ = g.Close()
+		_ = host.Close()
+	})
+	return g
+}
+
+func TestServer_Routes(t *testing.T) {
+	idx := newIndex(t)
+	srv := New(Config{Index: idx, Docs: tooldoc.NewInMemoryStore(tooldoc.StoreOptions{Index: idx}), Runner: echo})
+	srv.Register("")
+	g := connect(t, srv, "")
+	ctx := context.Background()
+
+	if tools, err := g.SearchTools(ctx, "test", 10); err != nil || len(tools) != 3 {
+		t.Errorf("SearchTools() = %d tools, %v; want 3", len(tools), err)
+	}
+	if ns, err := g.ListNamespaces(ctx); err != nil || len(ns) != 1 || ns[0] != "test" {
+		t.Errorf("ListNamespaces() = %v, %v; want [test]", ns, err)
+	}
+	if doc, err := g.DescribeTool(ctx, "test:a", tooldoc.DetailSummary); err != nil || doc.Summary == "" {
+		t.Errorf("DescribeTool() = %+v, %v; want a summary", doc, err)
+	}
+	if _, err := g.DescribeTool(ctx, "test:missing", tooldoc.DetailSummary); err == nil {
+		t.Error("DescribeTool() of an unknown tool succeeded, want an error")
+	}
+	if result, err := g.RunTool(ctx, "test:b", nil); err != nil || result.Structured != "test:b" {
+		t.Errorf("RunTool() = %+v, %v; want the runner's result", result, err)
+	}
+	result, steps, err := g.RunChain(ctx, []run.ChainStep{{ToolID: "test:a"}, {ToolID: "test:c"}})
+	if err != nil || result.Structured != "test:c" || len(steps) != 2 {
+		t.Errorf("RunChain() = %+v, %d steps, %v; want the last step's result", result, len(steps), err)
+	}
+}
+
+func TestServer_Limits(t *testing.T) {
+	srv := New(Config{Index: newIndex(t), Runner: echo, MaxToolCalls: 1, MaxSearchResults: 2})
+	srv.Register("exec-1")
+	srv.Register("exec-2")
+	g := connect(t, srv, "")
+	exec1 := runtime.WithExecutionID(context.Background(), "exec-1")
+	exec2 := runtime.WithExecutionID(context.Background(), "exec-2")
+
+	if _, err := g.RunTool(exec1, "test:a", nil); err != nil {
+		t.Fatalf("RunTool() error = %v", err)
+	}
+	if _, err := g.RunTool(exec1, "test:a", nil); err == nil || !strings.Contains(err.Error(), "tool call limit exceeded") {
+		t.Errorf("second RunTool() error = %v, want the call limit", err)
+	}
+	// Each execution has its own budget.
+	if _, err := g.RunTool(exec2, "test:a", nil); err != nil {
+		t.Errorf("RunTool() for another execution error = %v", err)
+	}
+	if calls := srv.ToolCalls("exec-1"); len(calls) != 1 || calls[0].ExecutionID != "exec-1" {
+		t.Errorf("ToolCalls(exec-1) = %+v, want one call", calls)
+	}
+	srv.Release("exec-1")
+	if calls := srv.ToolCalls("exec-1"); calls != nil {
+		t.Errorf("ToolCalls() after Release = %+v, want none", calls)
+	}
+	// Released and unregistered executions get no budget.
+	for _, ctx := range []context.Context{exec1, runtime.WithExecutionID(context.Background(), "exec-3")} {
+		if _, err := g.RunTool(ctx, "test:a", nil); err == nil || !strings.Contains(err.Error(), ErrUnknownExecution.Error()) {
+			t.Errorf("RunTool() for %s error = %v, want %v", runtime.ExecutionIDFromContext(ctx), err, ErrUnknownExecution)
+		}
+	}
+
+	if tools, err := g.SearchTools(exec2, "test", 10); err != nil || len(tools) != 2 {
+		t.Errorf("SearchTools() = %d tools, %v; want MaxSearchResults", len(tools), err)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	blocked := run.ExecutorFunc(func(ctx context.Context, _ string, _ map[string]any) (run.RunResult, error) {
+		<-ctx.Done()
+		return run.RunResult{}, ctx.Err()
+	})
+	srv := New(Config{Runner: blocked, RequestTimeout: 10 * time.Millisecond})
+	srv.Register("")
+
+	resp := srv.ServeMessage(context.Background(), proxy.Message{Type: proxy.MsgSearchTools, ID: "1"})
+	if resp.Type != proxy.MsgError || resp.ID != "1" || !strings.Contains(resp.Payload["error"].(string), ErrNotConfigured.Error()) {
+		t.Errorf("ServeMessage() without an index = %+v, want %v", resp, ErrNotConfigured)
+	}
+	resp = srv.ServeMessage(context.Background(), proxy.Message{Type: proxy.MsgRunTool, ID: "2", Payload: map[string]any{"id": "test:a"}})
+	if resp.Type != proxy.MsgError || !strings.Contains(resp.Payload["error"].(string), context.DeadlineExceeded.Error()) {
+		t.Errorf("ServeMessage() of a slow tool = %+v, want %v", resp, context.DeadlineExceeded)
+	}
+	resp = srv.ServeMessage(context.Background(), proxy.Message{Type: "unknown", ID: "3"})
+	if resp.Type != proxy.MsgError || !strings.Contains(resp.Payload["error"].(string), proxy.ErrProtocol.Error()) {
+		t.Errorf("ServeMessage() of an unknown request = %+v, want %v", resp, proxy.ErrProtocol)
+	}
+	if err := srv.check(proxy.MsgDescribeTool); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("check(describe_tool) = %v, want %v", err, ErrNotConfigured)
+	}
+}
+
+func TestServer_ExecutionTTL(t *testing.T) {
+	srv := New(Config{Runner: echo, ExecutionTTL: 10 * time.Millisecond})
+	srv.Register("exec-1")
+	req := proxy.Message{Type: proxy.MsgRunTool, ID: "1", ExecutionID: "exec-1", Payload: map[string]any{"id": "test:a"}}
+	if resp := srv.ServeMessage(context.Background(), req); resp.Type != proxy.MsgResponse {
+		t.Fatalf("ServeMessage() = %+v", resp)
+	}
+	time.Sleep(30 * time.Millisecond)
+	resp := srv.ServeMessage(context.Background(), req)
+	if resp.Type != proxy.MsgError || !strings.Contains(resp.Payload["error"].(string), ErrUnknownExecution.Error()) {
+		t.Errorf("ServeMessage() for an idle execution = %+v, want %v", resp, ErrUnknownExecution)
+	}
+}
+
+func TestServer_ExpirePrunesEnded(t *testing.T) {
+	srv := New(Config{Runner: echo, ExecutionTTL: time.Minute})
+	srv.Register("exec-1")
+	srv.Release("exec-1")
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.expire(time.Now().Add(time.Minute / 2))
+	if _, ok := srv.ended["exec-1"]; !ok {
+		t.Error("ended execution forgotten within ExecutionTTL")
+	}
+	srv.expire(time.Now().Add(2 * time.Minute))
+	if len(srv.ended) != 0 {
+		t.Errorf("ended = %v after ExecutionTTL, want empty", srv.ended)
+	}
+}
@@ -0,0 +1,129 @@
+package imagekit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Builder builds images from a build context.
+//
+// Contract:
+//   - Concurrency: implementations must be safe for concurrent use.
+//   - Errors: Build wraps ErrBuildFailed.
+type Builder interface {
+	// Build builds the context in dir, tags the image with every tag, and
+	// returns its digest.
+	Build(ctx context.Context, dir string, tags []string) (digest string, err error)
+}
+
+// Inspector reports the digest of a local image.
+//
+// Contract:
+//   - Concurrency: implementations must be safe for concurrent use.
+//   - Errors: Digest wraps ErrImageNotFound when the image is absent.
+type Inspector interface {
+	Digest(ctx context.Context, ref string) (string, error)
+}
+
+// Exporter writes an image's flattened root filesystem as a tar stream.
+//
+// Contract:
+//   - Concurrency: implementations must be safe for concurrent use.
+//   - Ownership: Export does not close w.
+type Exporter interface {
+	Export(ctx context.Context, ref string, w io.Writer) error
+}
+
+// Image identifies a built sandbox image.
+type Image struct {
+	// Repository is the repository the image was tagged in.
+	Repository string `json:"repository"`
+
+	// Tag is derived from the build context, so it changes whenever the
+	// spec or the entrypoint does.
+	Tag string `json:"tag"`
+
+	// Digest is the builder's content address for the image.
+	Digest string `json:"digest"`
+
+	// Languages lists the languages the image runs.
+	Languages []string `json:"languages"`
+}
+
+// Ref returns the image's content-derived reference, repository:tag.
+func (i Image) Ref() string {
+	return i.Repository + ":" + i.Tag
+}
+
+// Build builds spec with b and tags the image in repository twice: with a
+// tag derived from the build context and with "latest". An empty
+// repository means DefaultRepository.
+func Build(ctx context.Context, b Builder, spec Spec, repository string) (Image, error) {
+	if repository == "" {
+		repository = DefaultRepository
+	}
+	dir, err := os.MkdirTemp("", "imagekit-*")
+	if err != nil {
+		return Image{}, err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	if err := spec.WriteContext(dir); err != nil {
+		return Image{}, err
+	}
+	tag, err := contextTag(dir)
+	if err != nil {
+		return Image{}, err
+	}
+
+	img := Image{Repository: repository, Tag: tag}
+	digest, err := b.Build(ctx, dir, []string{img.Ref(), repository + ":latest"})
+	if err != nil {
+		return Image{}, err
+	}
+	img.Digest = digest
+	langs, _ := spec.languages()
+	for _, lang := range langs {
+		img.Languages = append(img.Languages, lang.Name)
+	}
+	return img, nil
+}
+
+// contextTag hashes the files in dir into a 12-character tag.
+func contextTag(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	slices.Sort(names)
+	h := sha256.New()
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %d\n", name, len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12], nil
+}
+
+// normalizeDigest lower-cases digest and adds the sha256: prefix that
+// some tools omit.
+func normalizeDigest(digest string) string {
+	digest = strings.ToLower(strings.TrimSpace(digest))
+	if digest != "" && !strings.Contains(digest, ":") {
+		digest = "sha256:" + digest
+	}
+	return digest
+}
@@ -0,0 +1,101 @@
+package imagekit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// CLIConfig configures a CLI.
+type CLIConfig struct {
+	// Binary is the docker-compatible CLI to invoke, such as "podman".
+	// Default: docker
+	Binary string
+}
+
+// CLI builds, inspects, and exports images by invoking the docker CLI or
+// a compatible one. It implements Builder, Inspector, and Exporter.
+//
+// Contract:
+//   - Concurrency: safe for concurrent use; each call runs its own process.
+//   - Errors: Build wraps ErrBuildFailed and Digest ErrImageNotFound, with
+//     the command's stderr.
+type CLI struct {
+	binary string
+}
+
+// NewCLI creates a CLI.
+func NewCLI(cfg CLIConfig) *CLI {
+	binary := cfg.Binary
+	if binary == "" {
+		binary = "docker"
+	}
+	return &CLI{binary: binary}
+}
+
+// Build implements Builder with "build --quiet", which prints the image
+// ID.
+func (c *CLI) Build(ctx context.Context, dir string, tags []string) (string, error) {
+	args := []string{"build", "--quiet"}
+	for _, tag := range tags {
+		args = append(args, "--tag", tag)
+	}
+	out, err := c.output(ctx, nil, append(args, dir)...)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrBuildFailed, err)
+	}
+	// Some builders print progress before the ID on the last line.
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	digest := normalizeDigest(lines[len(lines)-1])
+	if digest == "" {
+		return "", fmt.Errorf("%w: %s build printed no image ID", ErrBuildFailed, c.binary)
+	}
+	return digest, nil
+}
+
+// Digest implements Inspector with the image ID from "image inspect".
+func (c *CLI) Digest(ctx context.Context, ref string) (string, error) {
+	out, err := c.output(ctx, nil, "image", "inspect", "--format", "{{.Id}}", ref)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrImageNotFound, ref, err)
+	}
+	return normalizeDigest(out), nil
+}
+
+// Export implements Exporter by creating a stopped container from ref and
+// exporting its filesystem.
+func (c *CLI) Export(ctx context.Context, ref string, w io.Writer) error {
+	out, err := c.output(ctx, nil, "create", ref)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrImageNotFound, ref, err)
+	}
+	id := strings.TrimSpace(out)
+	defer func() {
+		_, _ = c.output(context.WithoutCancel(ctx), nil, "rm", "--force", id)
+	}()
+	_, err = c.output(ctx, w, "export", id)
+	return err
+}
+
+// output runs the CLI with args, writing stdout to w when set and
+// returning it otherwise.
+func (c *CLI) output(ctx context.Context, w io.Writer, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.binary, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if w != nil {
+		cmd.Stdout = w
+	}
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("%s %s: %v: %s", c.binary, args[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,88 @@
+package imagekit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeDocker writes a shell script standing in for the docker CLI. It
+// appends each invocation's arguments to the returned log file.
+func fakeDocker(t *testing.T, body string) (path, logFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	logFile = filepath.Join(dir, "log")
+	script := `#!/bin/sh
+echo "$*" >> ` + logFile + `
+` + body
+	path = filepath.Join(dir, "docker")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path, logFile
+}
+
+func readLog(t *testing.T, logFile string) []string {
+	t.Helper()
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestCLIBuildAndDigest(t *testing.T) {
+	path, logFile := fakeDocker(t, `case "$1" in
+build) echo "STEP 1/2"; echo "ABC123" ;;
+image) [ "$5" = "missing" ] && { echo "no such image" >&2; exit 1; }; echo "sha256:def456" ;;
+esac
+`)
+	cli := NewCLI(CLIConfig{Binary: path})
+
+	digest, err := cli.Build(context.Background(), "/ctx", []string{"repo:abc", "repo:latest"})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if digest != "sha256:abc123" {
+		t.Errorf("Build() = %q, want the last line normalized", digest)
+	}
+	if got := readLog(t, logFile)[0]; got != "build --quiet --tag repo:abc --tag repo:latest /ctx" {
+		t.Errorf("build args = %q", got)
+	}
+
+	if digest, err := cli.Digest(context.Background(), "repo:abc"); err != nil || digest != "sha256:def456" {
+		t.Errorf("Digest() = %q, %v; want sha256:def456", digest, err)
+	}
+	_, err = cli.Digest(context.Background(), "missing")
+	if !errors.Is(err, ErrImageNotFound) || !strings.Contains(err.Error(), "no such image") {
+		t.Errorf("Digest(missing) error = %v, want %v with stderr", err, ErrImageNotFound)
+	}
+
+	failing, _ := fakeDocker(t, "echo 'apt failed' >&2; exit 1\n")
+	if _, err := NewCLI(CLIConfig{Binary: failing}).Build(context.Background(), "/ctx", nil); !errors.Is(err, ErrBuildFailed) {
+		t.Errorf("Build() error = %v, want %v", err, ErrBuildFailed)
+	}
+}
+
+func TestCLIExport(t *testing.T) {
+	path, logFile := fakeDocker(t, `case "$1" in
+create) echo "c0ffee" ;;
+export) printf 'tarball' ;;
+esac
+`)
+	var out bytes.Buffer
+	if err := NewCLI(CLIConfig{Binary: path}).Export(context.Background(), "repo:abc", &out); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if out.String() != "tarball" {
+		t.Errorf("Export() wrote %q", out.String())
+	}
+	want := []string{"create repo:abc", "export c0ffee", "rm --force c0ffee"}
+	if got := readLog(t, logFile); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
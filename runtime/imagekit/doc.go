@@ -0,0 +1,53 @@
+// Package imagekit builds, pins, and verifies the sandbox image the
+// container and VM backends run.
+//
+// Every backend defaults to toolruntime-sandbox:latest. A Spec describes
+// that image: a Debian-based root filesystem with the interpreters for the
+// selected languages, the sandbox entrypoint binary, and a manifest at
+// ManifestPath mapping each language to the command that runs it. Build
+// renders the Spec into a build context and tags the result both with a
+// content-derived tag and with "latest":
+//
+//	cli := imagekit.NewCLI(imagekit.CLIConfig{}) // docker, or Binary: "podman"
+//	img, err := imagekit.Build(ctx, cli, imagekit.Spec{
+//		Languages:  []string{"python", "javascript"},
+//		Entrypoint: "bin/toolruntime-sandbox",
+//	}, imagekit.DefaultRepository)
+//	if err != nil {
+//		return err
+//	}
+//	pins := imagekit.Pins{}
+//	pins.Add(img)
+//	if err := pins.Save("sandbox-images.json"); err != nil {
+//		return err
+//	}
+//
+// # Verification
+//
+// A Verifier checks an image's digest against its pin before each
+// execution. It implements the ImageResolver interface the container
+// backends share, and resolves the image to its digest so the execution
+// runs the verified content even if the tag moves afterwards. With CLI as
+// the Inspector the digest is the image ID, which the docker and podman
+// backends run directly:
+//
+//	pins, err := imagekit.LoadPins("sandbox-images.json")
+//	if err != nil {
+//		return err
+//	}
+//	backend := docker.New(docker.Config{
+//		Client:        runner,
+//		ImageResolver: imagekit.NewVerifier(imagekit.VerifierConfig{Inspector: cli, Pins: pins}),
+//	})
+//
+// Images missing from the pins fail with ErrUnpinned unless
+// VerifierConfig.AllowUnpinned is set, and images whose digest differs
+// fail with ErrDigestMismatch.
+//
+// # Root filesystems
+//
+// Backends that boot a directory rather than an image, such as nspawn, or
+// that build a disk image from one, such as firecracker, can start from
+// the same Spec: ExportRootfs flattens a built image into a directory.
+// Ownership is preserved only when the process runs as root.
+package imagekit
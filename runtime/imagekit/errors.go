@@ -0,0 +1,26 @@
+package imagekit
+
+import "errors"
+
+// Sentinel errors for image building and verification.
+var (
+	// ErrInvalidSpec is returned when a Spec cannot describe an image.
+	ErrInvalidSpec = errors.New("invalid image spec")
+
+	// ErrUnknownLanguage is returned for languages without a built-in
+	// definition.
+	ErrUnknownLanguage = errors.New("unknown language")
+
+	// ErrBuildFailed is returned when the image builder fails.
+	ErrBuildFailed = errors.New("image build failed")
+
+	// ErrImageNotFound is returned when an image cannot be inspected.
+	ErrImageNotFound = errors.New("image not found")
+
+	// ErrUnpinned is returned by Verifier for images without a pin.
+	ErrUnpinned = errors.New("image not pinned")
+
+	// ErrDigestMismatch is returned by Verifier when an image's digest
+	// differs from its pin.
+	ErrDigestMismatch = errors.New("image digest mismatch")
+)
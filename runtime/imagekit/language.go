@@ -0,0 +1,48 @@
+package imagekit
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Language describes how the sandbox image provides one language.
+type Language struct {
+	// Name is the ExecuteRequest.Language value, in lower case.
+	Name string `json:"name"`
+
+	// Packages are the Debian packages that install the toolchain.
+	Packages []string `json:"-"`
+
+	// Command runs a program; the entrypoint appends the path of the file
+	// holding the code.
+	Command []string `json:"command"`
+
+	// Extension is the file extension the code is written with.
+	Extension string `json:"extension"`
+}
+
+// languages are the built-in Language definitions, keyed by name.
+var languages = map[string]Language{
+	"bash":       {Name: "bash", Packages: []string{"bash"}, Command: []string{"bash"}, Extension: ".sh"},
+	"go":         {Name: "go", Packages: []string{"golang-go"}, Command: []string{"go", "run"}, Extension: ".go"},
+	"javascript": {Name: "javascript", Packages: []string{"nodejs"}, Command: []string{"node"}, Extension: ".js"},
+	"python":     {Name: "python", Packages: []string{"python3"}, Command: []string{"python3"}, Extension: ".py"},
+}
+
+// Languages returns the names of the built-in languages, sorted.
+func Languages() []string {
+	return slices.Sorted(maps.Keys(languages))
+}
+
+// LookupLanguage returns the built-in Language named name, ignoring case.
+func LookupLanguage(name string) (Language, error) {
+	lang, ok := languages[strings.ToLower(name)]
+	if !ok {
+		return Language{}, fmt.Errorf("%w: %q (built in: %s)", ErrUnknownLanguage, name, strings.Join(Languages(), ", "))
+	}
+	lang.Packages = slices.Clone(lang.Packages)
+	lang.Command = slices.Clone(lang.Command)
+	return lang, nil
+}
@@ -0,0 +1,119 @@
+package imagekit
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// ExportRootfs flattens the image ref into the directory dir, which must
+// exist. Entries are written through an os.Root, so neither paths nor
+// symlinks in the image can place files outside dir. Device nodes are
+// skipped, and ownership is preserved only when running as root.
+func ExportRootfs(ctx context.Context, e Exporter, ref, dir string) error {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = root.Close() }()
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := e.Export(ctx, ref, pw)
+		_ = pw.CloseWithError(err)
+		done <- err
+	}()
+	extractErr := extract(root, pr)
+	// Unblock the exporter when extraction stopped early.
+	_ = pr.CloseWithError(errors.New("imagekit: extraction stopped"))
+	if exportErr := <-done; exportErr != nil && extractErr == nil {
+		return exportErr
+	}
+	return extractErr
+}
+
+// extract unpacks the tar stream r into root.
+func extract(root *os.Root, r io.Reader) error {
+	chown := os.Geteuid() == 0
+	// Directory modes are applied last, so read-only directories do not
+	// block the entries below them.
+	dirModes := make(map[string]fs.FileMode)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			for name, mode := range dirModes {
+				if err := root.Chmod(name, mode); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("imagekit: read rootfs: %w", err)
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		if name == "." {
+			continue
+		}
+		if !fs.ValidPath(name) {
+			return fmt.Errorf("imagekit: rootfs entry %q escapes the root", hdr.Name)
+		}
+		if dir := path.Dir(name); dir != "." {
+			if err := root.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+		}
+		mode := hdr.FileInfo().Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := root.MkdirAll(name, 0o755); err != nil {
+				return err
+			}
+			dirModes[name] = mode
+		case tar.TypeReg:
+			_ = root.Remove(name)
+			f, err := root.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			_ = root.Remove(name)
+			if err := root.Symlink(hdr.Linkname, name); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			_ = root.Remove(name)
+			if err := root.Link(path.Clean(strings.TrimPrefix(hdr.Linkname, "/")), name); err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+		if chown {
+			if err := root.Lchown(name, hdr.Uid, hdr.Gid); err != nil {
+				return err
+			}
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			// After chown, which clears setuid and setgid bits.
+			if err := root.Chmod(name, mode); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package imagekit
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarExporter exports a fixed tar stream.
+type tarExporter []byte
+
+func (e tarExporter) Export(_ context.Context, _ string, w io.Writer) error {
+	_, err := w.Write(e)
+	return err
+}
+
+func makeTar(t *testing.T, entries ...tar.Header) tarExporter {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range entries {
+		// Regular files hold their own name.
+		var body string
+		if hdr.Typeflag == tar.TypeReg {
+			body = hdr.Name
+			hdr.Size = int64(len(body))
+		}
+		if err := tw.WriteHeader(&hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExportRootfs(t *testing.T) {
+	dir := t.TempDir()
+	e := makeTar(t,
+		tar.Header{Name: "usr/", Typeflag: tar.TypeDir, Mode: 0o755},
+		tar.Header{Name: "usr/bin/tool", Typeflag: tar.TypeReg, Mode: 0o755},
+		tar.Header{Name: "bin", Typeflag: tar.TypeSymlink, Linkname: "usr/bin"},
+		tar.Header{Name: "usr/bin/alias", Typeflag: tar.TypeLink, Linkname: "usr/bin/tool"},
+		tar.Header{Name: "proc/", Typeflag: tar.TypeDir, Mode: 0o555},
+		tar.Header{Name: "dev/null", Typeflag: tar.TypeChar, Mode: 0o666},
+	)
+	if err := ExportRootfs(context.Background(), e, "img", dir); err != nil {
+		t.Fatalf("ExportRootfs() error = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "bin", "alias")); err != nil || string(data) != "usr/bin/tool" {
+		t.Errorf("bin/alias = %q, %v; want the linked file through the symlink", data, err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "usr/bin/tool")); err != nil || info.Mode().Perm() != 0o755 {
+		t.Errorf("usr/bin/tool = %v, %v; want mode 0755", info, err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "proc")); err != nil || info.Mode().Perm() != 0o555 {
+		t.Errorf("proc = %v, %v; want mode 0555", info, err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "dev/null")); !os.IsNotExist(err) {
+		t.Errorf("dev/null exists, want device nodes skipped")
+	}
+}
+
+func TestExportRootfsEscapes(t *testing.T) {
+	tests := map[string]tarExporter{
+		"dot-dot": makeTar(t, tar.Header{Name: "../escape", Typeflag: tar.TypeReg, Mode: 0o644}),
+		"symlink": makeTar(t,
+			tar.Header{Name: "etc", Typeflag: tar.TypeSymlink, Linkname: "../outside"},
+			tar.Header{Name: "etc/passwd", Typeflag: tar.TypeReg, Mode: 0o644},
+		),
+	}
+	for name, e := range tests {
+		t.Run(name, func(t *testing.T) {
+			parent := t.TempDir()
+			dir := filepath.Join(parent, "rootfs")
+			if err := os.MkdirAll(filepath.Join(parent, "outside"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Mkdir(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ExportRootfs(context.Background(), e, "img", dir); err == nil {
+				t.Error("ExportRootfs() error = nil, want the escape rejected")
+			}
+			for _, p := range []string{"escape", "outside/passwd"} {
+				if _, err := os.Lstat(filepath.Join(parent, p)); err == nil {
+					t.Errorf("%s was written outside the root", p)
+				}
+			}
+		})
+	}
+}
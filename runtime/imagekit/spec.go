@@ -0,0 +1,179 @@
+package imagekit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jonwraymond/toolexec/runtime"
+)
+
+const (
+	// DefaultRepository is the repository backends run by default.
+	DefaultRepository = "toolruntime-sandbox"
+
+	// DefaultBase is the base image used when Spec.Base is empty. Pin it
+	// by digest for reproducible builds.
+	DefaultBase = "debian:bookworm-slim"
+
+	// DefaultUser is the user the image runs as: nobody.
+	DefaultUser = "65534:65534"
+
+	// EntrypointPath is where the sandbox entrypoint is installed.
+	EntrypointPath = "/usr/local/bin/toolruntime-sandbox"
+
+	// ManifestPath is where the language manifest is installed: a JSON
+	// array of Language, which the entrypoint reads to run code.
+	ManifestPath = "/etc/toolexec/languages.json"
+
+	// LanguagesLabel is the image label listing its languages.
+	LanguagesLabel = "io.toolexec.languages"
+)
+
+// Build context file names.
+const (
+	dockerfileName = "Dockerfile"
+	manifestName   = "languages.json"
+	entrypointName = "toolruntime-sandbox"
+)
+
+// Spec describes a sandbox image.
+type Spec struct {
+	// Base is the Debian-based image to build on.
+	// Default: DefaultBase
+	Base string
+
+	// Languages lists the built-in languages to install. Required.
+	Languages []string
+
+	// Packages lists extra Debian packages to install.
+	Packages []string
+
+	// Entrypoint is the host path of the sandbox entrypoint binary, which
+	// is installed at EntrypointPath. Required by WriteContext.
+	Entrypoint string
+
+	// User is the user:group the image runs as.
+	// Default: DefaultUser
+	User string
+}
+
+// Validate checks that the spec describes a buildable image.
+func (s Spec) Validate() error {
+	if len(s.Languages) == 0 {
+		return fmt.Errorf("%w: at least one language is required", ErrInvalidSpec)
+	}
+	if _, err := s.languages(); err != nil {
+		return err
+	}
+	for _, p := range s.Packages {
+		if p == "" || strings.ContainsAny(p, " \t\n\\;&|$`'\"") {
+			return fmt.Errorf("%w: package %q", ErrInvalidSpec, p)
+		}
+	}
+	if strings.ContainsAny(s.Base+s.User, " \t\n") {
+		return fmt.Errorf("%w: base and user must not contain whitespace", ErrInvalidSpec)
+	}
+	return nil
+}
+
+// languages returns the spec's languages, sorted and without duplicates.
+func (s Spec) languages() ([]Language, error) {
+	var out []Language
+	for _, name := range s.Languages {
+		lang, err := LookupLanguage(name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, lang)
+	}
+	slices.SortFunc(out, func(a, b Language) int { return strings.Compare(a.Name, b.Name) })
+	return slices.CompactFunc(out, func(a, b Language) bool { return a.Name == b.Name }), nil
+}
+
+// Dockerfile renders the spec as a Dockerfile. Packages are installed in
+// sorted order, so equal specs render identically.
+func (s Spec) Dockerfile() ([]byte, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	langs, _ := s.languages()
+	base := s.Base
+	if base == "" {
+		base = DefaultBase
+	}
+	user := s.User
+	if user == "" {
+		user = DefaultUser
+	}
+	packages := []string{"ca-certificates"}
+	var names []string
+	for _, lang := range langs {
+		packages = append(packages, lang.Packages...)
+		names = append(names, lang.Name)
+	}
+	packages = append(packages, s.Packages...)
+	slices.Sort(packages)
+	packages = slices.Compact(packages)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Generated by imagekit; do not edit.\n")
+	fmt.Fprintf(&b, "FROM %s\n", base)
+	fmt.Fprintf(&b, "RUN apt-get update \\\n")
+	fmt.Fprintf(&b, " && apt-get install -y --no-install-recommends %s \\\n", strings.Join(packages, " "))
+	fmt.Fprintf(&b, " && rm -rf /var/lib/apt/lists/*\n")
+	fmt.Fprintf(&b, "COPY %s %s\n", manifestName, ManifestPath)
+	fmt.Fprintf(&b, "COPY --chmod=0755 %s %s\n", entrypointName, EntrypointPath)
+	fmt.Fprintf(&b, "RUN mkdir -p %s && chown %s %s\n", runtime.DefaultWorkspacePath, user, runtime.DefaultWorkspacePath)
+	fmt.Fprintf(&b, "LABEL %s=%s\n", LanguagesLabel, strconv.Quote(strings.Join(names, ",")))
+	fmt.Fprintf(&b, "USER %s\n", user)
+	fmt.Fprintf(&b, "WORKDIR %s\n", runtime.DefaultWorkspacePath)
+	fmt.Fprintf(&b, "ENTRYPOINT [%s]\n", strconv.Quote(EntrypointPath))
+	return b.Bytes(), nil
+}
+
+// Manifest renders the language manifest installed at ManifestPath.
+func (s Spec) Manifest() ([]byte, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	langs, _ := s.languages()
+	data, err := json.MarshalIndent(langs, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// WriteContext writes the build context for the spec into dir: the
+// Dockerfile, the manifest, and a copy of the entrypoint.
+func (s Spec) WriteContext(dir string) error {
+	if s.Entrypoint == "" {
+		return fmt.Errorf("%w: entrypoint is required", ErrInvalidSpec)
+	}
+	dockerfile, err := s.Dockerfile()
+	if err != nil {
+		return err
+	}
+	manifest, _ := s.Manifest()
+	entrypoint, err := os.ReadFile(s.Entrypoint)
+	if err != nil {
+		return fmt.Errorf("%w: entrypoint: %v", ErrInvalidSpec, err)
+	}
+	files := map[string][]byte{
+		dockerfileName: dockerfile,
+		manifestName:   manifest,
+		entrypointName: entrypoint,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package imagekit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSpecDockerfile(t *testing.T) {
+	spec := Spec{Languages: []string{"Python", "javascript", "python"}, Packages: []string{"jq"}}
+	got, err := spec.Dockerfile()
+	if err != nil {
+		t.Fatalf("Dockerfile() error = %v", err)
+	}
+	want := `# Generated by imagekit; do not edit.
+FROM debian:bookworm-slim
+RUN apt-get update \
+ && apt-get install -y --no-install-recommends ca-certificates jq nodejs python3 \
+ && rm -rf /var/lib/apt/lists/*
+COPY languages.json /etc/toolexec/languages.json
+COPY --chmod=0755 toolruntime-sandbox /usr/local/bin/toolruntime-sandbox
+RUN mkdir -p /workspace && chown 65534:65534 /workspace
+LABEL io.toolexec.languages="javascript,python"
+USER 65534:65534
+WORKDIR /workspace
+ENTRYPOINT ["/usr/local/bin/toolruntime-sandbox"]
+`
+	if string(got) != want {
+		t.Errorf("Dockerfile() =\n%s\nwant\n%s", got, want)
+	}
+
+	data, err := spec.Manifest()
+	if err != nil {
+		t.Fatalf("Manifest() error = %v", err)
+	}
+	var manifest []Language
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("manifest is not JSON: %v", err)
+	}
+	if len(manifest) != 2 || manifest[1].Name != "python" || !slices.Equal(manifest[1].Command, []string{"python3"}) {
+		t.Errorf("manifest = %+v, want javascript and python with their commands", manifest)
+	}
+}
+
+func TestSpecValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		spec Spec
+		want error
+	}{
+		{"no languages", Spec{}, ErrInvalidSpec},
+		{"unknown language", Spec{Languages: []string{"cobol"}}, ErrUnknownLanguage},
+		{"shell in package", Spec{Languages: []string{"go"}, Packages: []string{"curl; rm -rf /"}}, ErrInvalidSpec},
+		{"whitespace in base", Spec{Languages: []string{"go"}, Base: "debian\nRUN x"}, ErrInvalidSpec},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.spec.Validate(); !errors.Is(err, tt.want) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if err := (Spec{Languages: []string{"go"}}).WriteContext(t.TempDir()); !errors.Is(err, ErrInvalidSpec) {
+		t.Errorf("WriteContext() without entrypoint error = %v, want %v", err, ErrInvalidSpec)
+	}
+}
+
+// recordingBuilder records its build context and tags.
+type recordingBuilder struct {
+	files map[string]string
+	tags  []string
+}
+
+func (b *recordingBuilder) Build(_ context.Context, dir string, tags []string) (string, error) {
+	b.files = make(map[string]string)
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		data, _ := os.ReadFile(filepath.Join(dir, e.Name()))
+		b.files[e.Name()] = string(data)
+	}
+	b.tags = tags
+	return "sha256:abc", nil
+}
+
+func TestBuild(t *testing.T) {
+	entrypoint := filepath.Join(t.TempDir(), "sandbox")
+	if err := os.WriteFile(entrypoint, []byte("v1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	spec := Spec{Languages: []string{"go"}, Entrypoint: entrypoint}
+	b := &recordingBuilder{}
+
+	img, err := Build(context.Background(), b, spec, "")
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if img.Repository != DefaultRepository || len(img.Tag) != 12 || img.Digest != "sha256:abc" || !slices.Equal(img.Languages, []string{"go"}) {
+		t.Errorf("Build() = %+v", img)
+	}
+	if !slices.Equal(b.tags, []string{img.Ref(), "toolruntime-sandbox:latest"}) {
+		t.Errorf("tags = %v, want the content tag and latest", b.tags)
+	}
+	if b.files[entrypointName] != "v1" || !strings.Contains(b.files[dockerfileName], "golang-go") || b.files[manifestName] == "" {
+		t.Errorf("build context = %v", b.files)
+	}
+
+	// The tag follows the context, including the entrypoint.
+	again, _ := Build(context.Background(), b, spec, "")
+	if err := os.WriteFile(entrypoint, []byte("v2"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	changed, _ := Build(context.Background(), b, spec, "")
+	if again.Tag != img.Tag || changed.Tag == img.Tag {
+		t.Errorf("tags = %s, %s, %s; want the first two equal and the third different", img.Tag, again.Tag, changed.Tag)
+	}
+}
@@ -0,0 +1,95 @@
+package imagekit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Pins maps image references to the digests they must resolve to.
+type Pins map[string]string
+
+// LoadPins reads pins saved with Pins.Save.
+func LoadPins(path string) (Pins, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pins Pins
+	if err := json.Unmarshal(data, &pins); err != nil {
+		return nil, fmt.Errorf("imagekit: pins %s: %w", path, err)
+	}
+	return pins, nil
+}
+
+// Save writes the pins to path as JSON.
+func (p Pins) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Add pins img under its content-derived reference and under
+// repository:latest.
+func (p Pins) Add(img Image) {
+	p[img.Ref()] = img.Digest
+	p[img.Repository+":latest"] = img.Digest
+}
+
+// VerifierConfig configures a Verifier.
+type VerifierConfig struct {
+	// Inspector reports local image digests. Required.
+	Inspector Inspector
+
+	// Pins lists the images the Verifier accepts and their digests.
+	Pins Pins
+
+	// AllowUnpinned resolves images without a pin unverified instead of
+	// failing with ErrUnpinned.
+	AllowUnpinned bool
+}
+
+// Verifier checks images against their pins before execution. It
+// implements the backends' ImageResolver interfaces.
+//
+// Contract:
+//   - Concurrency: safe for concurrent use; the pins are copied.
+//   - Errors: ErrUnpinned, ErrDigestMismatch, or the Inspector's error.
+type Verifier struct {
+	inspector     Inspector
+	pins          Pins
+	allowUnpinned bool
+}
+
+// NewVerifier creates a Verifier.
+func NewVerifier(cfg VerifierConfig) *Verifier {
+	pins := make(Pins, len(cfg.Pins))
+	for ref, digest := range cfg.Pins {
+		pins[ref] = normalizeDigest(digest)
+	}
+	return &Verifier{inspector: cfg.Inspector, pins: pins, allowUnpinned: cfg.AllowUnpinned}
+}
+
+// Resolve returns the digest of image after checking it against its pin,
+// so the execution runs the verified content even if the tag moves.
+// Unpinned images allowed by AllowUnpinned resolve to themselves.
+func (v *Verifier) Resolve(ctx context.Context, image string) (string, error) {
+	want, ok := v.pins[image]
+	if !ok {
+		if v.allowUnpinned {
+			return image, nil
+		}
+		return "", fmt.Errorf("%w: %s", ErrUnpinned, image)
+	}
+	got, err := v.inspector.Digest(ctx, image)
+	if err != nil {
+		return "", err
+	}
+	if got = normalizeDigest(got); got != want {
+		return "", fmt.Errorf("%w: %s is %s, pinned to %s", ErrDigestMismatch, image, got, want)
+	}
+	return got, nil
+}
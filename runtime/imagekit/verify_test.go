@@ -0,0 +1,50 @@
+package imagekit
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+type staticInspector map[string]string
+
+func (s staticInspector) Digest(_ context.Context, ref string) (string, error) {
+	digest, ok := s[ref]
+	if !ok {
+		return "", ErrImageNotFound
+	}
+	return digest, nil
+}
+
+func TestVerifier(t *testing.T) {
+	pins := Pins{}
+	pins.Add(Image{Repository: "sandbox", Tag: "0123456789ab", Digest: "sha256:aaa"})
+	path := filepath.Join(t.TempDir(), "pins.json")
+	if err := pins.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := LoadPins(path)
+	if err != nil || loaded["sandbox:latest"] != "sha256:aaa" || loaded["sandbox:0123456789ab"] != "sha256:aaa" {
+		t.Fatalf("LoadPins() = %v, %v", loaded, err)
+	}
+
+	inspector := staticInspector{"sandbox:latest": "AAA", "sandbox:0123456789ab": "sha256:bbb"}
+	v := NewVerifier(VerifierConfig{Inspector: inspector, Pins: loaded})
+	ctx := context.Background()
+
+	if got, err := v.Resolve(ctx, "sandbox:latest"); err != nil || got != "sha256:aaa" {
+		t.Errorf("Resolve(latest) = %q, %v; want the verified digest", got, err)
+	}
+	if _, err := v.Resolve(ctx, "sandbox:0123456789ab"); !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("Resolve(retagged) error = %v, want %v", err, ErrDigestMismatch)
+	}
+	if _, err := v.Resolve(ctx, "other:latest"); !errors.Is(err, ErrUnpinned) {
+		t.Errorf("Resolve(unpinned) error = %v, want %v", err, ErrUnpinned)
+	}
+
+	v = NewVerifier(VerifierConfig{Inspector: inspector, Pins: loaded, AllowUnpinned: true})
+	if got, err := v.Resolve(ctx, "other:latest"); err != nil || got != "other:latest" {
+		t.Errorf("Resolve(unpinned) with AllowUnpinned = %q, %v; want the image unchanged", got, err)
+	}
+}
@@ -0,0 +1,224 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LabelExecutionID is the label backends set on the containers, pods, and
+// sandboxes they create, naming the execution that owns them. Reapers find
+// leftovers by it.
+const LabelExecutionID = "runtime.execution-id"
+
+const (
+	// DefaultJanitorTTL is JanitorConfig.TTL when unset.
+	DefaultJanitorTTL = 15 * time.Minute
+
+	// DefaultJanitorInterval is JanitorConfig.Interval when unset.
+	DefaultJanitorInterval = time.Minute
+)
+
+// Orphan is a resource an execution may have left behind.
+type Orphan struct {
+	// Kind names the resource type, such as "container" or "pod".
+	Kind string
+
+	// ID identifies the resource to its Reaper.
+	ID string
+
+	// ExecutionID is the resource's LabelExecutionID, if it has one.
+	ExecutionID string
+
+	// Created is when the resource was created.
+	Created time.Time
+}
+
+func (o Orphan) String() string {
+	if o.ExecutionID == "" {
+		return o.Kind + " " + o.ID
+	}
+	return fmt.Sprintf("%s %s (execution %s)", o.Kind, o.ID, o.ExecutionID)
+}
+
+// Reaper finds and removes the resources one backend's runner creates.
+// Runners remove them when executions finish; a Reaper catches those a
+// crash or a lost connection left behind.
+//
+// Contract:
+//   - Concurrency: implementations must be safe for concurrent use.
+//   - Orphans lists every managed resource, running or not; the Janitor
+//     decides which are old enough to reap.
+//   - Reap of a resource that is already gone returns nil.
+type Reaper interface {
+	Orphans(ctx context.Context) ([]Orphan, error)
+	Reap(ctx context.Context, o Orphan) error
+}
+
+// JanitorConfig configures a Janitor.
+type JanitorConfig struct {
+	// Reapers find and remove the resources to clean up.
+	Reapers []Reaper
+
+	// TTL is the age past which resources are reaped. It must exceed the
+	// longest execution timeout, or running executions lose their
+	// resources.
+	// Default: DefaultJanitorTTL
+	TTL time.Duration
+
+	// Interval is how often Run cleans up.
+	// Default: DefaultJanitorInterval
+	Interval time.Duration
+
+	// Active, if set, reports whether an execution is still running; its
+	// resources are kept regardless of age.
+	Active func(executionID string) bool
+
+	// Logger is an optional logger for reaped resources and failures.
+	Logger Logger
+}
+
+// Janitor reaps resources that executions left behind, such as stopped
+// containers, pods, sockets, snapshots, and temporary directories.
+//
+// Contract:
+//   - Concurrency: safe for concurrent use; Cleanup calls may overlap.
+//   - Errors: Cleanup returns the reaper errors joined, after trying every
+//     resource.
+type Janitor struct {
+	reapers  []Reaper
+	ttl      time.Duration
+	interval time.Duration
+	active   func(string) bool
+	logger   Logger
+	now      func() time.Time
+}
+
+// NewJanitor creates a Janitor. Call Cleanup to reap once, or Run to reap
+// periodically.
+func NewJanitor(cfg JanitorConfig) *Janitor {
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = DefaultJanitorTTL
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = DefaultJanitorInterval
+	}
+	return &Janitor{
+		reapers:  cfg.Reapers,
+		ttl:      ttl,
+		interval: interval,
+		active:   cfg.Active,
+		logger:   cfg.Logger,
+		now:      time.Now,
+	}
+}
+
+// Cleanup reaps every resource older than the TTL whose execution is not
+// active, and returns the ones it removed.
+func (j *Janitor) Cleanup(ctx context.Context) ([]Orphan, error) {
+	cutoff := j.now().Add(-j.ttl)
+	var reaped []Orphan
+	var errs []error
+	for _, r := range j.reapers {
+		orphans, err := r.Orphans(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, o := range orphans {
+			if !o.Created.Before(cutoff) || (o.ExecutionID != "" && j.active != nil && j.active(o.ExecutionID)) {
+				continue
+			}
+			if err := r.Reap(ctx, o); err != nil {
+				errs = append(errs, fmt.Errorf("reap %s: %w", o, err))
+				continue
+			}
+			reaped = append(reaped, o)
+			if j.logger != nil {
+				j.logger.Info("reaped orphaned resource", "kind", o.Kind, "id", o.ID, "executionID", o.ExecutionID, "age", j.now().Sub(o.Created))
+			}
+		}
+	}
+	err := errors.Join(errs...)
+	if err != nil && j.logger != nil {
+		j.logger.Warn("cleanup failed", "error", err)
+	}
+	return reaped, err
+}
+
+// Run cleans up immediately and then every Interval until ctx is done,
+// and returns ctx.Err(). Cleanup errors are logged, not returned.
+func (j *Janitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		_, _ = j.Cleanup(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// PathReaper is a Reaper for the files, sockets, and directories runners
+// create on the host, such as staging directories and VM sockets, by
+// modification time. It implements Reaper.
+type PathReaper struct {
+	// Dir is the directory to search.
+	// Default: os.TempDir()
+	Dir string
+
+	// Pattern is the filepath.Match pattern entries in Dir must match,
+	// such as "toolruntime-nspawn-*". Required.
+	Pattern string
+}
+
+// Orphans implements Reaper. Entries are reported with Kind "path".
+func (p PathReaper) Orphans(context.Context) ([]Orphan, error) {
+	glob, err := p.glob()
+	if err != nil {
+		return nil, err
+	}
+	matches, err := filepath.Glob(glob)
+	if err != nil {
+		return nil, fmt.Errorf("path reaper: %w", err)
+	}
+	orphans := make([]Orphan, 0, len(matches))
+	for _, m := range matches {
+		info, err := os.Lstat(m)
+		if err != nil {
+			continue
+		}
+		orphans = append(orphans, Orphan{Kind: "path", ID: m, Created: info.ModTime()})
+	}
+	return orphans, nil
+}
+
+// Reap implements Reaper. It refuses paths that do not match Pattern.
+func (p PathReaper) Reap(_ context.Context, o Orphan) error {
+	glob, err := p.glob()
+	if err != nil {
+		return err
+	}
+	if ok, _ := filepath.Match(glob, o.ID); !ok {
+		return fmt.Errorf("path reaper: %s does not match %s", o.ID, glob)
+	}
+	return os.RemoveAll(o.ID)
+}
+
+func (p PathReaper) glob() (string, error) {
+	if p.Pattern == "" {
+		return "", errors.New("path reaper: pattern is required")
+	}
+	dir := p.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, p.Pattern), nil
+}
@@ -0,0 +1,124 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeReaper serves a fixed list of orphans and records what it reaps.
+type fakeReaper struct {
+	mu      sync.Mutex
+	orphans []Orphan
+	listErr error
+	reapErr map[string]error
+	reaped  []string
+}
+
+func (f *fakeReaper) Orphans(context.Context) ([]Orphan, error) {
+	return f.orphans, f.listErr
+}
+
+func (f *fakeReaper) Reap(_ context.Context, o Orphan) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.reapErr[o.ID]; err != nil {
+		return err
+	}
+	f.reaped = append(f.reaped, o.ID)
+	return nil
+}
+
+func TestJanitorCleanup(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-time.Hour)
+	containers := &fakeReaper{
+		orphans: []Orphan{
+			{Kind: "container", ID: "stale", ExecutionID: "exec-1", Created: old},
+			{Kind: "container", ID: "fresh", ExecutionID: "exec-2", Created: now.Add(-time.Minute)},
+			{Kind: "container", ID: "running", ExecutionID: "exec-3", Created: old},
+			{Kind: "container", ID: "stuck", ExecutionID: "exec-4", Created: old},
+		},
+		reapErr: map[string]error{"stuck": errors.New("device busy")},
+	}
+	broken := &fakeReaper{listErr: errors.New("daemon down")}
+	j := NewJanitor(JanitorConfig{
+		Reapers: []Reaper{containers, broken},
+		TTL:     10 * time.Minute,
+		Active:  func(id string) bool { return id == "exec-3" },
+	})
+
+	reaped, err := j.Cleanup(context.Background())
+	if len(reaped) != 1 || reaped[0].ID != "stale" || !slices.Equal(containers.reaped, []string{"stale"}) {
+		t.Errorf("Cleanup() reaped %v, want only the stale container", reaped)
+	}
+	for _, want := range []string{"daemon down", "device busy", "container stuck (execution exec-4)"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Cleanup() error = %v, want it to mention %q", err, want)
+		}
+	}
+}
+
+func TestJanitorRun(t *testing.T) {
+	r := &fakeReaper{orphans: []Orphan{{Kind: "container", ID: "stale", Created: time.Now().Add(-time.Hour)}}}
+	j := NewJanitor(JanitorConfig{Reapers: []Reaper{r}, Interval: time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- j.Run(ctx) }()
+	for range 1000 {
+		r.mu.Lock()
+		n := len(r.reaped)
+		r.mu.Unlock()
+		if n >= 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+	if len(r.reaped) < 2 {
+		t.Errorf("reaped %d times, want repeated cleanups", len(r.reaped))
+	}
+}
+
+func TestPathReaper(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "toolruntime-job-1")
+	fresh := filepath.Join(dir, "toolruntime-job-2")
+	other := filepath.Join(dir, "keep")
+	for _, p := range []string{stale, fresh, other} {
+		if err := os.Mkdir(p, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	p := PathReaper{Dir: dir, Pattern: "toolruntime-job-*"}
+	j := NewJanitor(JanitorConfig{Reapers: []Reaper{p}})
+	reaped, err := j.Cleanup(context.Background())
+	if err != nil || len(reaped) != 1 || reaped[0].ID != stale {
+		t.Fatalf("Cleanup() = %v, %v; want the stale directory", reaped, err)
+	}
+	for path, want := range map[string]bool{stale: false, fresh: true, other: true} {
+		if _, err := os.Stat(path); (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", path, err == nil, want)
+		}
+	}
+	if err := p.Reap(context.Background(), Orphan{Kind: "path", ID: other}); err == nil {
+		t.Error("Reap() of a path outside the pattern error = nil")
+	}
+	if _, err := (PathReaper{}).Orphans(context.Background()); err == nil {
+		t.Error("Orphans() without a pattern error = nil")
+	}
+}
@@ -0,0 +1,83 @@
+package runtime
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// LogStream names an output stream of executed code.
+type LogStream string
+
+const (
+	// LogStdout is the standard output stream.
+	LogStdout LogStream = "stdout"
+
+	// LogStderr is the standard error stream.
+	LogStderr LogStream = "stderr"
+)
+
+// LogStreamer receives the output of executed code line by line while it
+// runs, so callers can report progress on long executions. Backends that
+// support streaming still return the complete output in ExecuteResult.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use; stdout
+// and stderr lines may be delivered from different goroutines.
+// - Ordering: lines of one stream arrive in order; no order is guaranteed
+// between streams.
+// - Errors: StreamLog must not block for long or panic, as it runs on the
+// backend's output path.
+type LogStreamer interface {
+	// StreamLog receives one line of output, without its trailing newline.
+	StreamLog(stream LogStream, line string)
+}
+
+// LogStreamerFunc adapts a function to LogStreamer.
+type LogStreamerFunc func(stream LogStream, line string)
+
+// StreamLog implements LogStreamer.
+func (f LogStreamerFunc) StreamLog(stream LogStream, line string) {
+	f(stream, line)
+}
+
+// NewLineWriter returns a writer that splits what is written to it into
+// lines and passes each to streamer. Close delivers a final line that has
+// no trailing newline. Backends use it to adapt raw output to LogStreamer.
+func NewLineWriter(streamer LogStreamer, stream LogStream) io.WriteCloser {
+	return &lineWriter{streamer: streamer, stream: stream}
+}
+
+type lineWriter struct {
+	streamer LogStreamer
+	stream   LogStream
+
+	mu      sync.Mutex
+	pending []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		line := bytes.TrimSuffix(w.pending[:i], []byte{'\r'})
+		w.streamer.StreamLog(w.stream, string(line))
+		w.pending = w.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) > 0 {
+		w.streamer.StreamLog(w.stream, string(w.pending))
+		w.pending = nil
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package runtime
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := NewLineWriter(LogStreamerFunc(func(stream LogStream, line string) {
+		lines = append(lines, string(stream)+":"+line)
+	}), LogStderr)
+
+	for _, chunk := range []string{"one\ntw", "o\r\n", "", "\nthree"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if want := []string{"stderr:one", "stderr:two", "stderr:"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines before Close = %q, want %q", lines, want)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := lines[len(lines)-1]; got != "stderr:three" {
+		t.Errorf("Close() delivered %q, want stderr:three", got)
+	}
+}
@@ -0,0 +1,69 @@
+package runtime
+
+import (
+	"bytes"
+	"sync"
+	"unicode/utf8"
+)
+
+// OutputTruncatedMarker is appended to stdout or stderr when it exceeds
+// ExecuteRequest.MaxOutputBytes.
+const OutputTruncatedMarker = "\n...[output truncated]\n"
+
+// OutputBuffer collects one stream of a sandbox's output, keeping at most
+// max bytes and discarding the rest, so a snippet that floods its output
+// cannot exhaust the memory of the process collecting it. Writes never
+// fail, so a copy from the sandbox keeps draining it. Runners use it in
+// place of a bytes.Buffer when a spec sets MaxOutputBytes.
+type OutputBuffer struct {
+	max int64
+
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	truncated bool
+}
+
+// NewOutputBuffer returns a buffer keeping at most max bytes. Zero or less
+// keeps everything.
+func NewOutputBuffer(max int64) *OutputBuffer {
+	return &OutputBuffer{max: max}
+}
+
+// Write implements io.Writer. It keeps what fits under the limit, cut at a
+// rune boundary, and always reports p as written.
+func (b *OutputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.truncated {
+		return len(p), nil
+	}
+	if b.max > 0 && int64(b.buf.Len()+len(p)) > b.max {
+		n := int(b.max) - b.buf.Len()
+		for n > 0 && !utf8.RuneStart(p[n]) {
+			n--
+		}
+		b.buf.Write(p[:n])
+		b.truncated = true
+		return len(p), nil
+	}
+	b.buf.Write(p)
+	return len(p), nil
+}
+
+// String returns the kept output, ending with OutputTruncatedMarker when
+// some was discarded.
+func (b *OutputBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.truncated {
+		return b.buf.String() + OutputTruncatedMarker
+	}
+	return b.buf.String()
+}
+
+// Truncated reports whether output was discarded.
+func (b *OutputBuffer) Truncated() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.truncated
+}
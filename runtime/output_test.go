@@ -0,0 +1,45 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestOutputBuffer(t *testing.T) {
+	tests := []struct {
+		max       int64
+		writes    []string
+		want      string
+		truncated bool
+	}{
+		{max: 0, writes: []string{"abc", "def"}, want: "abcdef"},
+		{max: 6, writes: []string{"abc", "def"}, want: "abcdef"},
+		{max: 4, writes: []string{"abc", "def", "ghi"}, want: "abcd" + OutputTruncatedMarker, truncated: true},
+		{max: 2, writes: []string{"aé"}, want: "a" + OutputTruncatedMarker, truncated: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.max, tt.writes), func(t *testing.T) {
+			b := NewOutputBuffer(tt.max)
+			for _, w := range tt.writes {
+				if n, err := io.WriteString(b, w); n != len(w) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v; want everything reported written", w, n, err)
+				}
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			if b.Truncated() != tt.truncated {
+				t.Errorf("Truncated() = %v, want %v", b.Truncated(), tt.truncated)
+			}
+		})
+	}
+}
+
+func TestExecuteRequestValidateMaxOutputBytes(t *testing.T) {
+	req := ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}, MaxOutputBytes: -1}
+	if err := req.Validate(); err == nil || !strings.Contains(err.Error(), "MaxOutputBytes") {
+		t.Errorf("Validate() error = %v, want a negative MaxOutputBytes error", err)
+	}
+}
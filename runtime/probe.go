@@ -0,0 +1,142 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Probe reports whether a backend can run on this host. It returns nil
+// when the backend is available and an error describing what is missing
+// otherwise.
+//
+// Contract:
+//   - Concurrency: probes may run concurrently with each other.
+//   - Context: must honor cancellation/deadlines.
+//   - Side effects: must not start executions or change host state.
+type Probe func(ctx context.Context) error
+
+// ProbeExecutable returns a Probe that succeeds when any of the named
+// executables is on PATH.
+func ProbeExecutable(names ...string) Probe {
+	return func(context.Context) error {
+		for _, name := range names {
+			if _, err := exec.LookPath(name); err == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: none of %v found on PATH", ErrProbeFailed, names)
+	}
+}
+
+// ProbeDial returns a Probe that succeeds when address accepts a
+// connection on network ("unix", "tcp", ...).
+func ProbeDial(network, address string) Probe {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, network, address)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrProbeFailed, err)
+		}
+		_ = conn.Close()
+		return nil
+	}
+}
+
+// ProbeURL returns a Probe that dials the endpoint named by rawURL. The
+// unix:// scheme dials the socket path; tcp://, http://, https://,
+// ws://, and wss:// dial the host, using the scheme's default port when
+// none is given. Nothing is sent over the connection.
+func ProbeURL(rawURL string) Probe {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return func(context.Context) error {
+			return fmt.Errorf("%w: %v", ErrProbeFailed, err)
+		}
+	}
+	switch u.Scheme {
+	case "unix":
+		path := u.Path
+		if path == "" {
+			path = u.Opaque
+		}
+		return ProbeDial("unix", path)
+	case "tcp":
+		return ProbeDial("tcp", u.Host)
+	case "http", "ws":
+		return ProbeDial("tcp", hostPort(u, "80"))
+	case "https", "wss":
+		return ProbeDial("tcp", hostPort(u, "443"))
+	default:
+		return func(context.Context) error {
+			return fmt.Errorf("%w: unsupported scheme %q", ErrProbeFailed, u.Scheme)
+		}
+	}
+}
+
+func hostPort(u *url.URL, defaultPort string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort)
+}
+
+// DefaultProbe returns the probe used for candidates of kind that do not
+// set one, or nil for backends that run in-process or whose availability
+// cannot be checked from the host alone (remote, serverless, temporal,
+// and so on); such candidates count as available.
+//
+// The defaults look for the docker socket (DOCKER_HOST, or
+// /var/run/docker.sock), the podman socket (CONTAINER_HOST, or the rootless
+// and rootful service sockets), the containerd socket, and the runsc,
+// kata-runtime, firecracker, and systemd-nspawn executables.
+func DefaultProbe(kind BackendKind) Probe {
+	switch kind {
+	case BackendDocker:
+		if host := os.Getenv("DOCKER_HOST"); host != "" {
+			return ProbeURL(host)
+		}
+		return ProbeDial("unix", "/var/run/docker.sock")
+	case BackendPodman:
+		if host := os.Getenv("CONTAINER_HOST"); host != "" {
+			return ProbeURL(host)
+		}
+		sockets := []string{"/run/podman/podman.sock"}
+		if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+			sockets = append([]string{filepath.Join(dir, "podman", "podman.sock")}, sockets...)
+		}
+		return probeAny(sockets)
+	case BackendContainerd:
+		return ProbeDial("unix", "/run/containerd/containerd.sock")
+	case BackendGVisor:
+		return ProbeExecutable("runsc")
+	case BackendKata:
+		return ProbeExecutable("kata-runtime", "containerd-shim-kata-v2")
+	case BackendFirecracker:
+		return ProbeExecutable("firecracker")
+	case BackendNspawn:
+		return ProbeExecutable("systemd-nspawn")
+	default:
+		return nil
+	}
+}
+
+// probeAny succeeds when any of the unix sockets accepts a connection.
+func probeAny(sockets []string) Probe {
+	return func(ctx context.Context) error {
+		var errs []error
+		for _, path := range sockets {
+			err := ProbeDial("unix", path)(ctx)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	}
+}
@@ -0,0 +1,352 @@
+// Package server is a reference implementation of the remote runtime
+// protocol that the remote backend speaks. Its Handler accepts a POSTed
+// remote.RemoteRequest, runs it on a local runtime.Runtime, and answers
+// with a remote.RemoteResponse, or with server-sent events when the
+// request sets Stream:
+//
+//	handler, err := server.New(server.Config{
+//		Runtime: rt,
+//		Gateway: func(ctx context.Context, desc *remote.GatewayDescriptor) (runtime.ToolGateway, error) {
+//			return gateway, nil
+//		},
+//		Authorize: func(r *http.Request) error {
+//			return checkToken(r.Header.Get("Authorization"))
+//		},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	http.Handle("/v1/execute", handler)
+//
+// New refuses a Config without Authorize unless Insecure is set, and
+// requests may only ask for the security profiles in AllowedProfiles.
+//
+// Request and response bodies may be compressed with the configured
+// compressors. A request in another encoding is answered with 415 and an
+// Accept-Encoding header, which remote.HTTPClient uses to fall back.
+//
+// A streamed response is a sequence of events named after their
+// remote.StreamMessage type: stdout and stderr events carry output as it
+// is written, and a final result event carries the response.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/remote"
+)
+
+// ErrInvalidConfig is returned by New for an incomplete Config.
+var ErrInvalidConfig = errors.New("invalid remote server config")
+
+// Logger is the interface for logging.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Errors: logging must be best-effort and must not panic.
+type Logger interface {
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// GatewayFunc returns the tool gateway for a request. desc is the
+// request's gateway descriptor, or nil when it has none.
+type GatewayFunc func(ctx context.Context, desc *remote.GatewayDescriptor) (runtime.ToolGateway, error)
+
+// Config configures a Handler.
+type Config struct {
+	// Runtime executes the requests (required).
+	Runtime runtime.Runtime
+
+	// Gateway provides each execution's tool gateway (required).
+	Gateway GatewayFunc
+
+	// Authorize rejects a request by returning an error, which is answered
+	// with 401. Required unless Insecure is set.
+	Authorize func(r *http.Request) error
+
+	// Insecure allows a nil Authorize, serving every request without
+	// authentication. Use it only for tests and trusted networks.
+	Insecure bool
+
+	// AllowedProfiles lists the security profiles requests may ask for;
+	// others are answered with 403. Requests without a profile run under
+	// the first.
+	// Default: runtime.ProfileStandard, runtime.ProfileHardened
+	AllowedProfiles []runtime.SecurityProfile
+
+	// Compression lists the encodings accepted for requests and offered
+	// for responses, in order of preference.
+	// Default: remote.Gzip
+	Compression []remote.Compressor
+
+	// MaxPayloadBytes bounds the uncompressed size of a request or
+	// response.
+	// Default: 16 MiB
+	MaxPayloadBytes int64
+
+	// MaxTimeout caps the timeout a request may ask for, and is used for
+	// requests without one. It caps the grace period too.
+	// Default: 5m
+	MaxTimeout time.Duration
+
+	// Logger is an optional logger for server events.
+	Logger Logger
+}
+
+// Handler serves the remote runtime protocol over HTTP.
+//
+// Contract:
+// - Concurrency: safe for concurrent use if Runtime, Gateway and Authorize are.
+// - Context: executions end when the client disconnects.
+// - Errors: failures are answered as a RemoteResponse with a RemoteError.
+type Handler struct {
+	runtime    runtime.Runtime
+	gateway    GatewayFunc
+	authorize  func(r *http.Request) error
+	profiles   []runtime.SecurityProfile
+	codec      remote.PayloadCodec
+	maxTimeout time.Duration
+	logger     Logger
+}
+
+// New creates a Handler.
+func New(cfg Config) (*Handler, error) {
+	if cfg.Runtime == nil {
+		return nil, fmt.Errorf("%w: runtime is required", ErrInvalidConfig)
+	}
+	if cfg.Gateway == nil {
+		return nil, fmt.Errorf("%w: gateway is required", ErrInvalidConfig)
+	}
+	if cfg.Authorize == nil && !cfg.Insecure {
+		return nil, fmt.Errorf("%w: authorize is required unless Insecure is set", ErrInvalidConfig)
+	}
+	profiles := cfg.AllowedProfiles
+	if len(profiles) == 0 {
+		profiles = []runtime.SecurityProfile{runtime.ProfileStandard, runtime.ProfileHardened}
+	}
+	for _, p := range profiles {
+		if !p.IsValid() {
+			return nil, fmt.Errorf("%w: unknown profile %q", ErrInvalidConfig, p)
+		}
+	}
+	compression := cfg.Compression
+	if compression == nil {
+		compression = []remote.Compressor{remote.Gzip}
+	}
+	maxPayloadBytes := cfg.MaxPayloadBytes
+	if maxPayloadBytes <= 0 {
+		maxPayloadBytes = 16 << 20
+	}
+	maxTimeout := cfg.MaxTimeout
+	if maxTimeout <= 0 {
+		maxTimeout = 5 * time.Minute
+	}
+	return &Handler{
+		runtime:    cfg.Runtime,
+		gateway:    cfg.Gateway,
+		authorize:  cfg.Authorize,
+		profiles:   slices.Clone(profiles),
+		codec:      remote.PayloadCodec{Compressors: compression, MaxPayloadBytes: maxPayloadBytes},
+		maxTimeout: maxTimeout,
+		logger:     cfg.Logger,
+	}, nil
+}
+
+// Error codes of the RemoteError answers.
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeUnauthorized       = "unauthorized"
+	CodeProfileDenied      = "profile_denied"
+	CodePayloadTooLarge    = "payload_too_large"
+	CodeGatewayUnavailable = "gateway_unavailable"
+	CodeTimeout            = "timeout"
+	CodeResourceLimit      = "resource_limit"
+	CodeSandboxViolation   = "sandbox_violation"
+	CodeRuntimeUnavailable = "runtime_unavailable"
+	CodeRuntimeBusy        = "runtime_busy"
+	CodeExecutionFailed    = "execution_failed"
+)
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		h.writeError(w, r, http.StatusMethodNotAllowed, CodeInvalidRequest, "method must be POST")
+		return
+	}
+	if h.authorize != nil {
+		if err := h.authorize(r); err != nil {
+			h.writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, err.Error())
+			return
+		}
+	}
+	encoding := r.Header.Get("Content-Encoding")
+	if !h.codec.Supports(encoding) {
+		w.Header().Set("Accept-Encoding", h.codec.AcceptEncoding())
+		h.writeError(w, r, http.StatusUnsupportedMediaType, CodeInvalidRequest, fmt.Sprintf("unsupported content encoding %q", encoding))
+		return
+	}
+	payload, err := h.codec.DecodeRequest(r.Body, encoding)
+	if err != nil {
+		if errors.Is(err, remote.ErrPayloadTooLarge) {
+			h.writeError(w, r, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, err.Error())
+			return
+		}
+		h.writeError(w, r, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	req := payload.Request.ExecuteRequest()
+	if req.Profile == "" {
+		req.Profile = h.profiles[0]
+	}
+	if !slices.Contains(h.profiles, req.Profile) {
+		h.writeError(w, r, http.StatusForbidden, CodeProfileDenied, fmt.Sprintf("profile %q is not allowed", req.Profile))
+		return
+	}
+
+	ctx := r.Context()
+	gateway, err := h.gateway(ctx, payload.Gateway)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadGateway, CodeGatewayUnavailable, err.Error())
+		return
+	}
+	req.Gateway = gateway
+	if req.Timeout <= 0 || req.Timeout > h.maxTimeout {
+		req.Timeout = h.maxTimeout
+	}
+	req.GraceTimeout = min(req.GraceTimeout, h.maxTimeout)
+
+	if payload.Stream {
+		h.stream(w, r, req)
+		return
+	}
+	result, err := h.runtime.Execute(ctx, req)
+	if err != nil {
+		status, code := classify(err)
+		h.writeError(w, r, status, code, err.Error())
+		return
+	}
+	resultPayload := remote.NewResultPayload(result)
+	h.writeResponse(w, r, http.StatusOK, remote.RemoteResponse{Result: &resultPayload})
+}
+
+// stream executes req and answers with server-sent events.
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request, req runtime.ExecuteRequest) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.writeError(w, r, http.StatusInternalServerError, CodeInvalidRequest, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// Backends may stream from several goroutines.
+	var mu sync.Mutex
+	var seq uint64
+	send := func(msg remote.StreamMessage) {
+		mu.Lock()
+		defer mu.Unlock()
+		seq++
+		msg.Seq = seq
+		data, err := json.Marshal(msg)
+		if err != nil {
+			h.logError("encode stream message", err)
+			return
+		}
+		_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Type, data)
+		flusher.Flush()
+	}
+	req.LogStreamer = runtime.LogStreamerFunc(func(stream runtime.LogStream, line string) {
+		msgType := remote.StreamStdout
+		if stream == runtime.LogStderr {
+			msgType = remote.StreamStderr
+		}
+		send(remote.StreamMessage{Type: msgType, Data: line + "\n"})
+	})
+
+	result, err := h.runtime.Execute(r.Context(), req)
+	response := remote.RemoteResponse{}
+	if err != nil {
+		_, code := classify(err)
+		response.Error = &remote.RemoteError{Code: code, Message: err.Error()}
+	} else {
+		resultPayload := remote.NewResultPayload(result)
+		response.Result = &resultPayload
+	}
+	send(remote.StreamMessage{Type: remote.StreamResult, Response: &response})
+}
+
+// classify maps an execution error to an HTTP status and error code.
+func classify(err error) (int, string) {
+	switch {
+	case errors.Is(err, runtime.ErrMissingCode), errors.Is(err, runtime.ErrInvalidLimits),
+		errors.Is(err, runtime.ErrInvalidWorkspace), errors.Is(err, runtime.ErrInvalidFile),
+		errors.Is(err, runtime.ErrInvalidEgress), errors.Is(err, runtime.ErrUnsupportedCapability):
+		return http.StatusBadRequest, CodeInvalidRequest
+	case errors.Is(err, runtime.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, CodeTimeout
+	case errors.Is(err, runtime.ErrResourceLimit):
+		return http.StatusUnprocessableEntity, CodeResourceLimit
+	case errors.Is(err, runtime.ErrSandboxViolation), errors.Is(err, runtime.ErrBackendDenied):
+		return http.StatusForbidden, CodeSandboxViolation
+	case errors.Is(err, runtime.ErrRuntimeBusy):
+		return http.StatusTooManyRequests, CodeRuntimeBusy
+	case errors.Is(err, runtime.ErrRuntimeUnavailable):
+		return http.StatusServiceUnavailable, CodeRuntimeUnavailable
+	default:
+		return http.StatusInternalServerError, CodeExecutionFailed
+	}
+}
+
+// writeError answers with a RemoteError. Errors are small and exempt
+// from MaxPayloadBytes.
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	codec := h.codec
+	codec.MaxPayloadBytes = 0
+	h.write(w, r, codec, status, remote.RemoteResponse{Error: &remote.RemoteError{Code: code, Message: message}})
+}
+
+func (h *Handler) writeResponse(w http.ResponseWriter, r *http.Request, status int, resp remote.RemoteResponse) {
+	h.write(w, r, h.codec, status, resp)
+}
+
+// write encodes resp in the encoding the client prefers.
+func (h *Handler) write(w http.ResponseWriter, r *http.Request, codec remote.PayloadCodec, status int, resp remote.RemoteResponse) {
+	body, encoding, err := codec.EncodeResponse(resp, codec.Negotiate(r.Header.Get("Accept-Encoding")))
+	if err != nil {
+		if errors.Is(err, remote.ErrPayloadTooLarge) {
+			h.writeError(w, r, http.StatusInternalServerError, CodePayloadTooLarge, err.Error())
+			return
+		}
+		h.logError("encode response", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+func (h *Handler) logError(msg string, err error) {
+	if h.logger != nil {
+		h.logger.Error(msg, "error", err)
+	}
+}
+
+var _ http.Handler = (*Handler)(nil)
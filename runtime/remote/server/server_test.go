@@ -0,0 +1,265 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolexec/runtime"
+	"github.com/jonwraymond/toolexec/runtime/backend/remote"
+	"github.com/jonwraymond/toolexec/runtime/gateway/direct"
+)
+
+// echoRuntime prints the code line by line and returns its length.
+type echoRuntime struct {
+	err error
+
+	mu   sync.Mutex
+	seen runtime.ExecuteRequest
+}
+
+func (e *echoRuntime) Execute(_ context.Context, req runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
+	e.mu.Lock()
+	e.seen = req
+	e.mu.Unlock()
+	if e.err != nil {
+		return runtime.ExecuteResult{}, e.err
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(req.Code, "\n"), "\n") {
+		if req.LogStreamer != nil {
+			req.LogStreamer.StreamLog(runtime.LogStdout, line)
+		}
+	}
+	return runtime.ExecuteResult{
+		Value:     len(req.Code),
+		Stdout:    req.Code,
+		Duration:  time.Millisecond,
+		ToolCalls: []runtime.ToolCallRecord{{ToolID: "ns:tool", Duration: time.Millisecond}},
+		Artifacts: []runtime.Artifact{{Name: "out.bin", Data: []byte{0, 1, 2}}},
+	}, nil
+}
+
+func (e *echoRuntime) request() runtime.ExecuteRequest {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.seen
+}
+
+func newServer(t *testing.T, rt runtime.Runtime, cfg Config) string {
+	t.Helper()
+	cfg.Runtime = rt
+	cfg.Insecure = cfg.Authorize == nil
+	if cfg.Gateway == nil {
+		cfg.Gateway = func(context.Context, *remote.GatewayDescriptor) (runtime.ToolGateway, error) {
+			return direct.New(direct.Config{}), nil
+		}
+	}
+	handler, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func newBackend(t *testing.T, url string, cfg remote.Config) *remote.Backend {
+	t.Helper()
+	client, err := remote.NewHTTPClient(remote.HTTPClientConfig{URL: url})
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	cfg.Client = client
+	return remote.New(cfg)
+}
+
+func executeRequest(code string) runtime.ExecuteRequest {
+	return runtime.ExecuteRequest{Code: code, Gateway: direct.New(direct.Config{}), Timeout: time.Hour}
+}
+
+func TestHandlerExecute(t *testing.T) {
+	rt := &echoRuntime{}
+	var gotDesc *remote.GatewayDescriptor
+	url := newServer(t, rt, Config{
+		MaxTimeout: time.Minute,
+		Gateway: func(_ context.Context, desc *remote.GatewayDescriptor) (runtime.ToolGateway, error) {
+			gotDesc = desc
+			return direct.New(direct.Config{}), nil
+		},
+	})
+	code := strings.Repeat("print('hello')\n", 200)
+
+	for _, streaming := range []bool{false, true} {
+		b := newBackend(t, url, remote.Config{
+			Compression:     []remote.Compressor{remote.Gzip},
+			GatewayEndpoint: "http://gateway",
+			EnableStreaming: streaming,
+		})
+		result, err := b.Execute(context.Background(), executeRequest(code))
+		if err != nil {
+			t.Fatalf("streaming=%v: Execute() error = %v", streaming, err)
+		}
+		if result.Stdout != code || result.Value != float64(len(code)) || len(result.ToolCalls) != 1 {
+			t.Errorf("streaming=%v: result = %+v", streaming, result)
+		}
+		if len(result.Artifacts) != 1 || result.Artifacts[0].Name != "out.bin" || !bytes.Equal(result.Artifacts[0].Data, []byte{0, 1, 2}) {
+			t.Errorf("streaming=%v: Artifacts = %+v", streaming, result.Artifacts)
+		}
+		if got := rt.request().Profile; got != runtime.ProfileStandard {
+			t.Errorf("streaming=%v: profile = %q, want the first allowed", streaming, got)
+		}
+		if gotDesc == nil || gotDesc.URL != "http://gateway" {
+			t.Errorf("streaming=%v: gateway descriptor = %+v", streaming, gotDesc)
+		}
+		if got := rt.request().Timeout; got != time.Minute {
+			t.Errorf("streaming=%v: timeout = %v, want the MaxTimeout cap", streaming, got)
+		}
+		if got := rt.request().LogStreamer != nil; got != streaming {
+			t.Errorf("streaming=%v: LogStreamer set = %v", streaming, got)
+		}
+	}
+}
+
+func TestHandlerStreamEvents(t *testing.T) {
+	url := newServer(t, &echoRuntime{}, Config{})
+	resp, err := http.Post(url, "application/json", strings.NewReader(`{"request":{"code":"a\nb"},"stream":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if name, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			events = append(events, name)
+		}
+	}
+	if got := strings.Join(events, ","); got != "stdout,stdout,result" {
+		t.Errorf("events = %s", got)
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		rt         *echoRuntime
+		cfg        Config
+		method     string
+		header     http.Header
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "method", method: http.MethodGet, wantStatus: http.StatusMethodNotAllowed, wantCode: CodeInvalidRequest},
+		{
+			name:       "unauthorized",
+			cfg:        Config{Authorize: func(*http.Request) error { return errors.New("no token") }},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeUnauthorized,
+		},
+		{name: "malformed", body: "{", wantStatus: http.StatusBadRequest, wantCode: CodeInvalidRequest},
+		{
+			name:       "profile",
+			body:       `{"request":{"code":"return 1","profile":"dev"}}`,
+			wantStatus: http.StatusForbidden,
+			wantCode:   CodeProfileDenied,
+		},
+		{
+			name:       "too large",
+			cfg:        Config{MaxPayloadBytes: 16},
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   CodePayloadTooLarge,
+		},
+		{
+			name: "gateway",
+			cfg: Config{Gateway: func(context.Context, *remote.GatewayDescriptor) (runtime.ToolGateway, error) {
+				return nil, errors.New("unknown gateway")
+			}},
+			wantStatus: http.StatusBadGateway,
+			wantCode:   CodeGatewayUnavailable,
+		},
+		{name: "timeout", rt: &echoRuntime{err: fmt.Errorf("run: %w", runtime.ErrTimeout)}, wantStatus: http.StatusGatewayTimeout, wantCode: CodeTimeout},
+		{name: "failure", rt: &echoRuntime{err: errors.New("crashed")}, wantStatus: http.StatusInternalServerError, wantCode: CodeExecutionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := tt.rt
+			if rt == nil {
+				rt = &echoRuntime{}
+			}
+			url := newServer(t, rt, tt.cfg)
+			method, body := tt.method, tt.body
+			if method == "" {
+				method = http.MethodPost
+			}
+			if body == "" {
+				body = `{"request":{"code":"return 1"}}`
+			}
+			req, _ := http.NewRequest(method, url, strings.NewReader(body))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			got, err := remote.PayloadCodec{}.DecodeResponse(resp.Body, "")
+			if err != nil || got.Error == nil || got.Error.Code != tt.wantCode {
+				t.Errorf("response = %+v, %v; want code %s", got.Error, err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestHandlerEncodingFallback(t *testing.T) {
+	url := newServer(t, &echoRuntime{}, Config{Compression: []remote.Compressor{}})
+	body, _, err := remote.PayloadCodec{Compressors: []remote.Compressor{remote.Gzip}, MinCompressBytes: 1}.
+		EncodeRequest(remote.RemoteRequest{Request: remote.ExecutePayload{Code: "return 1"}}, "gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("status = %d, want 415", resp.StatusCode)
+	}
+
+	// The client falls back to an uncompressed request.
+	b := newBackend(t, url, remote.Config{Compression: []remote.Compressor{remote.Gzip}})
+	if _, err := b.Execute(context.Background(), executeRequest(strings.Repeat("x", 4<<10))); err != nil {
+		t.Errorf("Execute() error = %v", err)
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	if _, err := New(Config{}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("New() error = %v, want ErrInvalidConfig", err)
+	}
+	if _, err := New(Config{Runtime: &echoRuntime{}}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("New() without a gateway error = %v, want ErrInvalidConfig", err)
+	}
+	gateway := func(context.Context, *remote.GatewayDescriptor) (runtime.ToolGateway, error) { return nil, nil }
+	if _, err := New(Config{Runtime: &echoRuntime{}, Gateway: gateway}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("New() without Authorize error = %v, want ErrInvalidConfig", err)
+	}
+	if _, err := New(Config{Runtime: &echoRuntime{}, Gateway: gateway, Insecure: true, AllowedProfiles: []runtime.SecurityProfile{"root"}}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("New() with an unknown profile error = %v, want ErrInvalidConfig", err)
+	}
+}
@@ -2,8 +2,11 @@ package runtime
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"sync"
+	"time"
 )
 
 // Runtime is the main interface for code execution.
@@ -28,6 +31,18 @@ type RuntimeConfig struct {
 	// Backends maps security profiles to their backend implementations.
 	Backends map[SecurityProfile]Backend
 
+	// Fallbacks lists, per profile, the backends to try in order after the
+	// profile's entry in Backends fails with an availability error. A
+	// profile may have fallbacks without a Backends entry.
+	Fallbacks map[SecurityProfile][]Backend
+
+	// FallbackOn reports whether an execution error means the next backend
+	// in the chain should be tried. It must only accept errors returned
+	// before the code ran.
+	// Default: errors.Is(err, ErrRuntimeUnavailable); backends wrap it in
+	// their health-check errors (e.g. docker.ErrDaemonUnavailable)
+	FallbackOn func(error) bool
+
 	// DenyUnsafeProfiles lists profiles that cannot use the unsafe backend.
 	// If a profile is listed here and only the unsafe backend is available,
 	// execution will be denied.
@@ -37,8 +52,36 @@ type RuntimeConfig struct {
 	// If empty and no profile is specified, execution will fail.
 	DefaultProfile SecurityProfile
 
+	// MaxConcurrentExecutions caps executions running at once across all
+	// tenants; others queue. Zero means no cap.
+	MaxConcurrentExecutions int
+
+	// MaxConcurrentPerTenant caps executions running at once per tenant, so
+	// one caller cannot take every slot. Zero means no cap.
+	MaxConcurrentPerTenant int
+
+	// MaxQueuedExecutions and MaxQueuedPerTenant cap how many executions may
+	// wait for a slot, in total and per tenant; beyond them Execute fails
+	// immediately with ErrRuntimeBusy. Zero means no cap.
+	MaxQueuedExecutions int
+	MaxQueuedPerTenant  int
+
+	// QueueTimeout bounds how long an execution waits for a slot before
+	// failing with ErrRuntimeBusy. Zero waits until the context ends.
+	QueueTimeout time.Duration
+
+	// TenantKey identifies the caller a request is accounted to. Freed slots
+	// go to the queued tenant with the fewest running executions.
+	// Default: the string in Metadata[TenantMetadataKey]; requests without
+	// one share the "" tenant
+	TenantKey func(ExecuteRequest) string
+
 	// Logger is an optional logger for runtime events.
 	Logger Logger
+
+	// Events, if set, receives execution, backend health, and limit
+	// events for operators to subscribe to.
+	Events *Events
 }
 
 // Logger is an optional interface for logging.
@@ -57,9 +100,18 @@ type Logger interface {
 type DefaultRuntime struct {
 	mu                 sync.RWMutex
 	backends           map[SecurityProfile]Backend
+	fallbacks          map[SecurityProfile][]Backend
+	fallbackOn         func(error) bool
 	denyUnsafeProfiles map[SecurityProfile]bool
 	defaultProfile     SecurityProfile
+	scheduler          *scheduler
+	tenantKey          func(ExecuteRequest) string
 	logger             Logger
+	events             *Events
+
+	// inflight maps each profile to the *sync.WaitGroup of executions
+	// that resolved its backend chain since the last ReplaceBackend.
+	inflight sync.Map
 }
 
 // toolCallRecorder is an optional interface implemented by gateways that
@@ -79,14 +131,74 @@ func NewDefaultRuntime(cfg RuntimeConfig) *DefaultRuntime {
 		denyMap[p] = true
 	}
 
+	fallbackOn := cfg.FallbackOn
+	if fallbackOn == nil {
+		fallbackOn = func(err error) bool { return errors.Is(err, ErrRuntimeUnavailable) }
+	}
+
+	tenantKey := cfg.TenantKey
+	if tenantKey == nil {
+		tenantKey = defaultTenantKey
+	}
+
 	return &DefaultRuntime{
 		backends:           cfg.Backends,
+		fallbacks:          cfg.Fallbacks,
+		fallbackOn:         fallbackOn,
 		denyUnsafeProfiles: denyMap,
 		defaultProfile:     cfg.DefaultProfile,
+		scheduler:          newScheduler(cfg),
+		tenantKey:          tenantKey,
 		logger:             cfg.Logger,
+		events:             cfg.Events,
 	}
 }
 
+// BackendKind reports the kind of the first backend in profile's chain, or
+// false when no backend is registered for it. An empty profile resolves to
+// the default profile.
+func (r *DefaultRuntime) BackendKind(profile SecurityProfile) (BackendKind, bool) {
+	if profile == "" {
+		profile = r.defaultProfile
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	chain := r.chainLocked(profile)
+	if len(chain) == 0 {
+		return "", false
+	}
+	return chain[0].Kind(), true
+}
+
+// Capabilities reports the capabilities of the first backend in profile's
+// chain, or false when there is none or it does not implement
+// CapabilityReporter. An empty profile resolves to the default profile.
+func (r *DefaultRuntime) Capabilities(profile SecurityProfile) (Capabilities, bool) {
+	if profile == "" {
+		profile = r.defaultProfile
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	chain := r.chainLocked(profile)
+	if len(chain) == 0 {
+		return Capabilities{}, false
+	}
+	reporter, ok := chain[0].(CapabilityReporter)
+	if !ok {
+		return Capabilities{}, false
+	}
+	return reporter.Capabilities(), true
+}
+
+// chainLocked returns profile's backend followed by its fallbacks.
+func (r *DefaultRuntime) chainLocked(profile SecurityProfile) []Backend {
+	var chain []Backend
+	if backend, ok := r.backends[profile]; ok {
+		chain = append(chain, backend)
+	}
+	return append(chain, r.fallbacks[profile]...)
+}
+
 // Execute implements the Runtime interface.
 func (r *DefaultRuntime) Execute(ctx context.Context, req ExecuteRequest) (ExecuteResult, error) {
 	// Check context first
@@ -105,33 +217,97 @@ func (r *DefaultRuntime) Execute(ctx context.Context, req ExecuteRequest) (Execu
 		profile = r.defaultProfile
 	}
 
-	// Get backend for profile
+	// Get the backend chain for profile
 	r.mu.RLock()
-	backend, ok := r.backends[profile]
+	chain := r.chainLocked(profile)
 	isDenied := r.denyUnsafeProfiles[profile]
+	inflight := r.inflightLocked(profile)
+	inflight.Add(1)
 	r.mu.RUnlock()
+	defer inflight.Done()
 
-	if !ok {
+	if len(chain) == 0 {
 		return ExecuteResult{}, fmt.Errorf("%w: no backend for profile %q", ErrRuntimeUnavailable, profile)
 	}
 
-	// Check if unsafe backend is denied for this profile
-	if isDenied && backend.Kind() == BackendUnsafeHost {
-		return ExecuteResult{}, fmt.Errorf("%w: unsafe backend denied for profile %q", ErrBackendDenied, profile)
+	// Identify the execution to backends, gateways, events, and logs
+	if req.ExecutionID == "" {
+		req.ExecutionID = ExecutionIDFromContext(ctx)
 	}
+	id := req.EnsureExecutionID()
+	ctx = WithExecutionID(ctx, id)
 
-	// Log execution start
-	if r.logger != nil {
-		r.logger.Info("executing code", "profile", profile, "backend", backend.Kind())
+	// Wait for a slot under the concurrency caps
+	tenant := r.tenantKey(req)
+	event := Event{Profile: profile, Tenant: tenant, ExecutionID: id}
+	release, err := r.scheduler.acquire(ctx, tenant)
+	if err != nil {
+		if errors.Is(err, ErrRuntimeBusy) {
+			r.publish(event, EventLimitBreached, LimitQueue, err)
+		}
+		return ExecuteResult{ExecutionID: id}, err
 	}
+	defer release()
 
-	// Delegate to backend
-	result, err := backend.Execute(ctx, req)
+	// Try each backend until one runs the code. Denied backends, backends
+	// whose capabilities reject the request, and availability errors fall
+	// through; any other error ends the chain.
+	var (
+		result  ExecuteResult
+		skipped []string
+		errs    []error
+	)
+	for i, backend := range chain {
+		if i > 0 && ctx.Err() != nil {
+			return ExecuteResult{ExecutionID: id}, ctx.Err()
+		}
+		if isDenied && backend.Kind() == BackendUnsafeHost {
+			err = fmt.Errorf("%w: unsafe backend denied for profile %q", ErrBackendDenied, profile)
+		} else {
+			err = checkCapabilities(backend, req)
+		}
+		if err == nil {
+			if r.logger != nil {
+				r.logger.Info("executing code", "profile", profile, "backend", backend.Kind(), "executionID", id)
+			}
+			event.Backend = backend.Kind()
+			r.publish(event, EventExecutionStarted, "", nil)
+			result, err = backend.Execute(ctx, req)
+			result.ExecutionID = id
+			r.publishFinished(event, result, err)
+			if err == nil {
+				break
+			}
+			if !r.fallbackOn(err) {
+				if r.logger != nil {
+					r.logger.Error("execution failed", "profile", profile, "executionID", id, "error", err)
+				}
+				return result, err
+			}
+		}
+		skipped = append(skipped, fmt.Sprintf("%s: %v", backend.Kind(), err))
+		errs = append(errs, err)
+		if r.logger != nil && i+1 < len(chain) {
+			r.logger.Warn("backend unavailable, falling back", "profile", profile, "backend", backend.Kind(), "executionID", id, "error", err)
+		}
+	}
 	if err != nil {
 		if r.logger != nil {
-			r.logger.Error("execution failed", "profile", profile, "error", err)
+			r.logger.Error("execution failed", "profile", profile, "executionID", id, "error", err)
+		}
+		result.ExecutionID = id
+		if len(errs) == 1 {
+			return result, err
 		}
-		return result, err
+		return result, fmt.Errorf("%w: every backend for profile %q failed: %w", ErrRuntimeUnavailable, profile, errors.Join(errs...))
+	}
+	if len(skipped) > 0 {
+		details := maps.Clone(result.Backend.Details)
+		if details == nil {
+			details = make(map[string]any)
+		}
+		details["fallbackFrom"] = skipped
+		result.Backend.Details = details
 	}
 
 	// If the backend did not populate tool calls but the gateway can, capture them.
@@ -142,13 +318,60 @@ func (r *DefaultRuntime) Execute(ctx context.Context, req ExecuteRequest) (Execu
 	}
 
 	if r.logger != nil {
-		r.logger.Info("execution completed", "profile", profile, "duration", result.Duration)
+		r.logger.Info("execution completed", "profile", profile, "executionID", id, "duration", result.Duration)
 	}
 
 	return result, nil
 }
 
-// RegisterBackend registers a backend for a security profile.
+// inflightLocked returns the group tracking profile's executions. Adding
+// to it while holding r.mu ensures ReplaceBackend waits for the addition.
+func (r *DefaultRuntime) inflightLocked(profile SecurityProfile) *sync.WaitGroup {
+	if wg, ok := r.inflight.Load(profile); ok {
+		return wg.(*sync.WaitGroup)
+	}
+	wg, _ := r.inflight.LoadOrStore(profile, new(sync.WaitGroup))
+	return wg.(*sync.WaitGroup)
+}
+
+// publish publishes event as typ, with the limit and error that caused it.
+func (r *DefaultRuntime) publish(event Event, typ EventType, limit string, err error) {
+	event.Type = typ
+	event.Limit = limit
+	event.Err = err
+	r.events.Publish(event)
+}
+
+// publishFinished publishes the end of event's execution, and the
+// unhealthy backend or breached limit its error reports.
+func (r *DefaultRuntime) publishFinished(event Event, result ExecuteResult, err error) {
+	if r.events == nil {
+		return
+	}
+	finished := event
+	finished.Duration = result.Duration
+	finished.Usage = result.Usage
+	r.publish(finished, EventExecutionFinished, "", err)
+	switch {
+	case err == nil:
+	case r.fallbackOn(err):
+		r.publish(event, EventBackendUnhealthy, "", err)
+	case breachedLimit(err) != "":
+		r.publish(event, EventLimitBreached, breachedLimit(err), err)
+	}
+}
+
+// checkCapabilities checks req against backend's advertised capabilities.
+func checkCapabilities(backend Backend, req ExecuteRequest) error {
+	reporter, ok := backend.(CapabilityReporter)
+	if !ok {
+		return nil
+	}
+	return reporter.Capabilities().Check(req)
+}
+
+// RegisterBackend registers a backend for a security profile, replacing the
+// first backend in its chain; fallbacks are kept.
 // This is thread-safe and can be called at runtime.
 func (r *DefaultRuntime) RegisterBackend(profile SecurityProfile, backend Backend) {
 	r.mu.Lock()
@@ -159,6 +382,50 @@ func (r *DefaultRuntime) RegisterBackend(profile SecurityProfile, backend Backen
 	r.backends[profile] = backend
 }
 
+// ReplaceBackend registers backend for profile as RegisterBackend does,
+// then waits for the executions that had already chosen the profile's
+// previous backend to finish, so a sandbox image can be upgraded or a
+// profile moved to another backend kind without failing executions. New
+// executions use backend as soon as ReplaceBackend is called.
+//
+// It returns the previous backend, or nil when the profile had none, for
+// the caller to close once drained. When ctx ends first, backend stays
+// registered and ReplaceBackend returns the previous backend with
+// ctx.Err(); executions still running on it are left to finish.
+func (r *DefaultRuntime) ReplaceBackend(ctx context.Context, profile SecurityProfile, backend Backend) (Backend, error) {
+	r.mu.Lock()
+	if r.backends == nil {
+		r.backends = make(map[SecurityProfile]Backend)
+	}
+	old := r.backends[profile]
+	r.backends[profile] = backend
+	draining, _ := r.inflight.Swap(profile, new(sync.WaitGroup))
+	r.mu.Unlock()
+
+	if r.logger != nil {
+		r.logger.Info("backend replaced", "profile", profile, "backend", backend.Kind())
+	}
+	r.publish(Event{Profile: profile, Backend: backend.Kind()}, EventBackendReplaced, "", nil)
+	if draining == nil {
+		return old, nil
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		draining.(*sync.WaitGroup).Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return old, nil
+	case <-ctx.Done():
+		if r.logger != nil {
+			r.logger.Warn("backend drain interrupted", "profile", profile, "error", ctx.Err())
+		}
+		return old, ctx.Err()
+	}
+}
+
 // UnregisterBackend removes a backend for a security profile.
 // This is thread-safe and can be called at runtime.
 func (r *DefaultRuntime) UnregisterBackend(profile SecurityProfile) {
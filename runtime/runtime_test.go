@@ -3,8 +3,11 @@ package runtime
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 // RuntimeContract defines tests that any Runtime implementation must pass.
@@ -143,6 +146,86 @@ func TestDefaultRuntimeDenyUnsafe(t *testing.T) {
 	}
 }
 
+func TestDefaultRuntimeFallbackChain(t *testing.T) {
+	firecracker := &mockBackend{
+		kind:       BackendFirecracker,
+		executeErr: fmt.Errorf("firecracker daemon unavailable: %w", ErrRuntimeUnavailable),
+	}
+	gvisor := &mockBackend{
+		kind:       BackendGVisor,
+		executeErr: fmt.Errorf("gvisor daemon unavailable: %w", ErrRuntimeUnavailable),
+	}
+	kata := &mockBackend{
+		kind:   BackendKata,
+		result: ExecuteResult{Value: "kata", Backend: BackendInfo{Kind: BackendKata}},
+	}
+
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileHardened: firecracker},
+		Fallbacks:      map[SecurityProfile][]Backend{ProfileHardened: {gvisor, kata}},
+		DefaultProfile: ProfileHardened,
+	})
+	if kind, _ := rt.BackendKind(ProfileHardened); kind != BackendFirecracker {
+		t.Errorf("BackendKind() = %q, want %q", kind, BackendFirecracker)
+	}
+
+	ctx := context.Background()
+	req := ExecuteRequest{Code: "test", Gateway: &mockToolGateway{}}
+	result, err := rt.Execute(ctx, req)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Value != "kata" {
+		t.Errorf("Execute().Value = %v, want kata", result.Value)
+	}
+	attempted, _ := result.Backend.Details["fallbackFrom"].([]string)
+	if len(attempted) != 2 || !strings.HasPrefix(attempted[0], "firecracker: ") || !strings.HasPrefix(attempted[1], "gvisor: ") {
+		t.Errorf("Details[fallbackFrom] = %v, want firecracker then gvisor", attempted)
+	}
+
+	// Errors other than availability end the chain.
+	gvisor.executeErr = ErrSandboxViolation
+	if _, err := rt.Execute(ctx, req); !errors.Is(err, ErrSandboxViolation) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrSandboxViolation)
+	}
+
+	// When the whole chain is unavailable, every failure is reported.
+	gvisor.executeErr = fmt.Errorf("gvisor daemon unavailable: %w", ErrRuntimeUnavailable)
+	rt = NewDefaultRuntime(RuntimeConfig{
+		Fallbacks:      map[SecurityProfile][]Backend{ProfileHardened: {firecracker, gvisor}},
+		FallbackOn:     func(error) bool { return true },
+		DefaultProfile: ProfileHardened,
+	})
+	_, err = rt.Execute(ctx, req)
+	if !errors.Is(err, ErrRuntimeUnavailable) || !strings.Contains(err.Error(), "firecracker") || !strings.Contains(err.Error(), "gvisor") {
+		t.Errorf("Execute() error = %v, want both failures", err)
+	}
+}
+
+func TestDefaultRuntimeFallbackSkipsDeniedUnsafe(t *testing.T) {
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends: map[SecurityProfile]Backend{
+			ProfileStandard: &mockBackend{kind: BackendUnsafeHost, result: ExecuteResult{Value: "unsafe"}},
+		},
+		Fallbacks: map[SecurityProfile][]Backend{
+			ProfileStandard: {&mockBackend{kind: BackendDocker, result: ExecuteResult{Value: "docker"}}},
+		},
+		DenyUnsafeProfiles: []SecurityProfile{ProfileStandard},
+	})
+
+	result, err := rt.Execute(context.Background(), ExecuteRequest{
+		Code:    "test",
+		Gateway: &mockToolGateway{},
+		Profile: ProfileStandard,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Value != "docker" {
+		t.Errorf("Execute().Value = %v, want docker", result.Value)
+	}
+}
+
 func TestDefaultRuntimeThreadSafety(t *testing.T) {
 	backend := &mockBackend{
 		kind:   BackendUnsafeHost,
@@ -266,3 +349,95 @@ func TestDefaultRuntimeContract(t *testing.T) {
 		},
 	})
 }
+
+func TestDefaultRuntime_BackendKind(t *testing.T) {
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: &mockBackend{kind: BackendDocker}},
+		DefaultProfile: ProfileStandard,
+	})
+
+	if kind, ok := rt.BackendKind(""); !ok || kind != BackendDocker {
+		t.Errorf("BackendKind(default) = %v, %v; want %v, true", kind, ok, BackendDocker)
+	}
+	if _, ok := rt.BackendKind(ProfileHardened); ok {
+		t.Error("BackendKind(hardened) ok = true, want false")
+	}
+}
+
+func TestDefaultRuntimeReplaceBackend(t *testing.T) {
+	old := &blockingBackend{started: make(chan struct{}, 1), release: make(chan struct{})}
+	events := NewEvents()
+	replacedEvents, unsubscribe := events.Subscribe(1, EventBackendReplaced)
+	defer unsubscribe()
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:       map[SecurityProfile]Backend{ProfileStandard: old},
+		DefaultProfile: ProfileStandard,
+		Events:         events,
+	})
+	req := ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}}
+
+	running := make(chan error, 1)
+	go func() {
+		_, err := rt.Execute(context.Background(), req)
+		running <- err
+	}()
+	<-old.started
+
+	replaced := make(chan error, 1)
+	next := &mockBackend{kind: BackendGVisor, result: ExecuteResult{Value: "gvisor"}}
+	go func() {
+		prev, err := rt.ReplaceBackend(context.Background(), ProfileStandard, next)
+		if err == nil && prev != old {
+			err = fmt.Errorf("previous backend = %v, want the blocking one", prev)
+		}
+		replaced <- err
+	}()
+	for kind, _ := rt.BackendKind(""); kind != BackendGVisor; kind, _ = rt.BackendKind("") {
+		time.Sleep(time.Millisecond)
+	}
+
+	result, err := rt.Execute(context.Background(), req)
+	if err != nil || result.Value != "gvisor" {
+		t.Errorf("Execute() during drain = %v, %v; want the new backend", result.Value, err)
+	}
+	select {
+	case err := <-replaced:
+		t.Fatalf("ReplaceBackend() returned %v before the old backend drained", err)
+	default:
+	}
+
+	close(old.release)
+	if err := <-running; err != nil {
+		t.Errorf("in-flight Execute() error = %v", err)
+	}
+	if err := <-replaced; err != nil {
+		t.Errorf("ReplaceBackend() error = %v", err)
+	}
+	if ev := <-replacedEvents; ev.Type != EventBackendReplaced || ev.Backend != BackendGVisor {
+		t.Errorf("first event = %+v, want %s for gvisor", ev, EventBackendReplaced)
+	}
+}
+
+func TestDefaultRuntimeReplaceBackendDrainTimeout(t *testing.T) {
+	old := &blockingBackend{started: make(chan struct{}, 1), release: make(chan struct{})}
+	defer close(old.release)
+	rt := NewDefaultRuntime(RuntimeConfig{Backends: map[SecurityProfile]Backend{ProfileDev: old}})
+	go func() {
+		_, _ = rt.Execute(context.Background(), ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}, Profile: ProfileDev})
+	}()
+	<-old.started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	prev, err := rt.ReplaceBackend(ctx, ProfileDev, &mockBackend{kind: BackendGVisor})
+	if !errors.Is(err, context.Canceled) || prev != old {
+		t.Errorf("ReplaceBackend() = %v, %v; want the old backend and %v", prev, err, context.Canceled)
+	}
+	if kind, _ := rt.BackendKind(ProfileDev); kind != BackendGVisor {
+		t.Errorf("BackendKind() = %v, want the replacement registered", kind)
+	}
+
+	if prev, err := rt.ReplaceBackend(context.Background(), ProfileHardened, &mockBackend{kind: BackendGVisor}); prev != nil || err != nil {
+		t.Errorf("ReplaceBackend(new profile) = %v, %v; want nil, nil", prev, err)
+	}
+}
@@ -0,0 +1,193 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TenantMetadataKey is the ExecuteRequest.Metadata key the default
+// RuntimeConfig.TenantKey reads the caller's tenant from.
+const TenantMetadataKey = "tenant"
+
+// defaultTenantKey returns Metadata[TenantMetadataKey] when it is a string.
+func defaultTenantKey(req ExecuteRequest) string {
+	tenant, _ := req.Metadata[TenantMetadataKey].(string)
+	return tenant
+}
+
+// scheduler caps running executions globally and per tenant. Executions
+// over a cap wait in per-tenant FIFO queues; when a slot frees, it goes to
+// the waiting tenant with the fewest running executions, ties going to the
+// tenant served least recently, so a tenant with a deep queue cannot starve
+// the others. A nil scheduler admits everything.
+type scheduler struct {
+	maxRunning       int
+	maxTenantRunning int
+	maxQueued        int
+	maxTenantQueued  int
+	timeout          time.Duration
+	mu               sync.Mutex
+	running          int
+	queued           int
+	tenants          map[string]*tenantState
+	seq              uint64
+}
+
+type tenantState struct {
+	running    int
+	waiting    []*waiter
+	lastServed uint64
+}
+
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func newScheduler(cfg RuntimeConfig) *scheduler {
+	if cfg.MaxConcurrentExecutions <= 0 && cfg.MaxConcurrentPerTenant <= 0 {
+		return nil
+	}
+	return &scheduler{
+		maxRunning:       cfg.MaxConcurrentExecutions,
+		maxTenantRunning: cfg.MaxConcurrentPerTenant,
+		maxQueued:        cfg.MaxQueuedExecutions,
+		maxTenantQueued:  cfg.MaxQueuedPerTenant,
+		timeout:          cfg.QueueTimeout,
+		tenants:          make(map[string]*tenantState),
+	}
+}
+
+// acquire takes a slot for tenant, waiting in its queue if none is free.
+// It returns a release func, or ErrRuntimeBusy when a queue is full or the
+// wait times out, or ctx.Err() when ctx ends first.
+func (s *scheduler) acquire(ctx context.Context, tenant string) (func(), error) {
+	if s == nil {
+		return func() {}, nil
+	}
+
+	s.mu.Lock()
+	ts := s.tenants[tenant]
+	if ts == nil {
+		ts = &tenantState{}
+		s.tenants[tenant] = ts
+	}
+	// Waiters are dispatched as soon as slots free, so a tenant that can
+	// run now has no one eligible ahead of it.
+	if s.canRunLocked(ts) {
+		s.startLocked(ts)
+		s.mu.Unlock()
+		return s.releaser(tenant), nil
+	}
+	if s.maxQueued > 0 && s.queued >= s.maxQueued {
+		err := fmt.Errorf("%w: %d executions queued", ErrRuntimeBusy, s.queued)
+		s.mu.Unlock()
+		return nil, err
+	}
+	if s.maxTenantQueued > 0 && len(ts.waiting) >= s.maxTenantQueued {
+		err := fmt.Errorf("%w: tenant %q has %d executions queued", ErrRuntimeBusy, tenant, len(ts.waiting))
+		s.mu.Unlock()
+		return nil, err
+	}
+	w := &waiter{ready: make(chan struct{})}
+	ts.waiting = append(ts.waiting, w)
+	s.queued++
+	s.mu.Unlock()
+
+	var expired <-chan time.Time
+	if s.timeout > 0 {
+		timer := time.NewTimer(s.timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	var err error
+	select {
+	case <-w.ready:
+		return s.releaser(tenant), nil
+	case <-expired:
+		err = fmt.Errorf("%w: no slot free after %v", ErrRuntimeBusy, s.timeout)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w.granted {
+		// The slot arrived while giving up; hand it on.
+		s.finishLocked(tenant)
+		return nil, err
+	}
+	for i, q := range ts.waiting {
+		if q == w {
+			ts.waiting = append(ts.waiting[:i], ts.waiting[i+1:]...)
+			break
+		}
+	}
+	s.queued--
+	s.pruneLocked(tenant, ts)
+	return nil, err
+}
+
+func (s *scheduler) releaser(tenant string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.finishLocked(tenant)
+		})
+	}
+}
+
+func (s *scheduler) canRunLocked(ts *tenantState) bool {
+	return (s.maxRunning <= 0 || s.running < s.maxRunning) &&
+		(s.maxTenantRunning <= 0 || ts.running < s.maxTenantRunning)
+}
+
+func (s *scheduler) startLocked(ts *tenantState) {
+	s.running++
+	ts.running++
+	s.seq++
+	ts.lastServed = s.seq
+}
+
+// finishLocked frees tenant's slot and dispatches waiters into any slots
+// that are now free.
+func (s *scheduler) finishLocked(tenant string) {
+	ts := s.tenants[tenant]
+	s.running--
+	ts.running--
+	s.pruneLocked(tenant, ts)
+
+	for {
+		var next *tenantState
+		for _, cand := range s.tenants {
+			if len(cand.waiting) == 0 || !s.canRunLocked(cand) {
+				continue
+			}
+			if next == nil || cand.running < next.running ||
+				(cand.running == next.running && cand.lastServed < next.lastServed) {
+				next = cand
+			}
+		}
+		if next == nil {
+			return
+		}
+		w := next.waiting[0]
+		next.waiting = next.waiting[1:]
+		s.queued--
+		s.startLocked(next)
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+// pruneLocked forgets idle tenants so the map stays bounded by the number
+// of active ones.
+func (s *scheduler) pruneLocked(tenant string, ts *tenantState) {
+	if ts.running == 0 && len(ts.waiting) == 0 {
+		delete(s.tenants, tenant)
+	}
+}
@@ -0,0 +1,152 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitQueued blocks until n executions are queued in s.
+func waitQueued(t *testing.T, s *scheduler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		queued := s.queued
+		s.mu.Unlock()
+		if queued == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queued = %d, want %d", queued, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerFairShare(t *testing.T) {
+	s := newScheduler(RuntimeConfig{MaxConcurrentExecutions: 1})
+	ctx := context.Background()
+
+	release, err := s.acquire(ctx, "noisy")
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	order := make(chan string, 4)
+	enqueue := func(tenant string) {
+		go func() {
+			rel, err := s.acquire(ctx, tenant)
+			if err != nil {
+				t.Errorf("acquire(%s) error = %v", tenant, err)
+				return
+			}
+			order <- tenant
+			rel()
+		}()
+	}
+	enqueue("noisy")
+	waitQueued(t, s, 1)
+	enqueue("noisy")
+	waitQueued(t, s, 2)
+	enqueue("quiet")
+	waitQueued(t, s, 3)
+
+	release()
+	got := []string{<-order, <-order, <-order}
+	// The quiet tenant arrived last but has not been served yet.
+	want := []string{"quiet", "noisy", "noisy"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("dispatch order = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSchedulerPerTenantCap(t *testing.T) {
+	s := newScheduler(RuntimeConfig{MaxConcurrentPerTenant: 1, MaxQueuedPerTenant: 1, QueueTimeout: 20 * time.Millisecond})
+	ctx := context.Background()
+
+	release, err := s.acquire(ctx, "a")
+	if err != nil {
+		t.Fatalf("acquire(a) error = %v", err)
+	}
+	defer release()
+
+	// Another tenant is not held back by a's cap.
+	relB, err := s.acquire(ctx, "b")
+	if err != nil {
+		t.Fatalf("acquire(b) error = %v", err)
+	}
+	relB()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.acquire(ctx, "a")
+		done <- err
+	}()
+	waitQueued(t, s, 1)
+	if _, err := s.acquire(ctx, "a"); !errors.Is(err, ErrRuntimeBusy) {
+		t.Errorf("acquire() over the tenant queue cap error = %v, want %v", err, ErrRuntimeBusy)
+	}
+	if err := <-done; !errors.Is(err, ErrRuntimeBusy) {
+		t.Errorf("acquire() after QueueTimeout error = %v, want %v", err, ErrRuntimeBusy)
+	}
+}
+
+// blockingBackend runs until its release channel is closed.
+type blockingBackend struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingBackend) Kind() BackendKind { return BackendDocker }
+
+func (b *blockingBackend) Execute(ctx context.Context, _ ExecuteRequest) (ExecuteResult, error) {
+	b.started <- struct{}{}
+	select {
+	case <-b.release:
+		return ExecuteResult{}, nil
+	case <-ctx.Done():
+		return ExecuteResult{}, ctx.Err()
+	}
+}
+
+func TestDefaultRuntimeQueueLimits(t *testing.T) {
+	backend := &blockingBackend{started: make(chan struct{}, 2), release: make(chan struct{})}
+	rt := NewDefaultRuntime(RuntimeConfig{
+		Backends:                map[SecurityProfile]Backend{ProfileStandard: backend},
+		DefaultProfile:          ProfileStandard,
+		MaxConcurrentExecutions: 1,
+		MaxQueuedExecutions:     1,
+	})
+	req := ExecuteRequest{Code: "x", Gateway: &mockToolGateway{}, Metadata: map[string]any{TenantMetadataKey: "t"}}
+
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := rt.Execute(context.Background(), req)
+			errs <- err
+		}()
+	}
+	<-backend.started
+	waitQueued(t, rt.scheduler, 1)
+
+	if _, err := rt.Execute(context.Background(), req); !errors.Is(err, ErrRuntimeBusy) {
+		t.Errorf("Execute() with full queue error = %v, want %v", err, ErrRuntimeBusy)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := rt.Execute(ctx, req); !errors.Is(err, context.Canceled) {
+		t.Errorf("Execute() with canceled context error = %v, want %v", err, context.Canceled)
+	}
+
+	close(backend.release)
+	for range 2 {
+		if err := <-errs; err != nil {
+			t.Errorf("Execute() error = %v", err)
+		}
+	}
+}
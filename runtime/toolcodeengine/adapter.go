@@ -43,6 +43,27 @@ func New(cfg Config) (*Engine, error) {
 	}, nil
 }
 
+// backendKinder is implemented by runtimes that can report which backend
+// serves a profile, such as runtime.DefaultRuntime.
+type backendKinder interface {
+	BackendKind(profile runtime.SecurityProfile) (runtime.BackendKind, bool)
+}
+
+// DescribeEngine implements code.EngineDescriber, reporting the security
+// profile and, when the runtime can tell, the backend that serves it.
+func (e *Engine) DescribeEngine(language string) code.EngineInfo {
+	info := code.EngineInfo{
+		Name:            language,
+		SecurityProfile: string(e.profile),
+	}
+	if r, ok := e.runtime.(backendKinder); ok {
+		if kind, ok := r.BackendKind(e.profile); ok {
+			info.Backend = string(kind)
+		}
+	}
+	return info
+}
+
 // Execute implements code.Engine by delegating to the underlying runtime.
 func (e *Engine) Execute(ctx context.Context, params code.ExecuteParams, tools code.Tools) (code.ExecuteResult, error) {
 	if e.runtime == nil {
@@ -50,19 +71,7 @@ func (e *Engine) Execute(ctx context.Context, params code.ExecuteParams, tools c
 	}
 
 	// Wrap Tools into a ToolGateway
-	gateway := WrapTools(tools)
-
-	// Map code.ExecuteParams to runtime.ExecuteRequest
-	req := runtime.ExecuteRequest{
-		Language: params.Language,
-		Code:     params.Code,
-		Timeout:  params.Timeout,
-		Limits: runtime.Limits{
-			MaxToolCalls: params.MaxToolCalls,
-		},
-		Profile: e.profile,
-		Gateway: gateway,
-	}
+	req := e.request(params, WrapTools(tools))
 
 	// Execute via the runtime
 	result, err := e.runtime.Execute(ctx, req)
@@ -75,6 +84,48 @@ func (e *Engine) Execute(ctx context.Context, params code.ExecuteParams, tools c
 	return mapResult(result), nil
 }
 
+// capabilityReporter is implemented by runtimes that can report the
+// capabilities of the backend serving a profile, such as
+// runtime.DefaultRuntime.
+type capabilityReporter interface {
+	Capabilities(profile runtime.SecurityProfile) (runtime.Capabilities, bool)
+}
+
+// ValidateParams implements code.ValidatingEngine. It checks params against
+// the capabilities of the backend serving the engine's profile, when the
+// runtime reports them, and wraps rejections in code.ErrUnsupportedCapability.
+func (e *Engine) ValidateParams(_ context.Context, params code.ExecuteParams) error {
+	r, ok := e.runtime.(capabilityReporter)
+	if !ok {
+		return nil
+	}
+	caps, ok := r.Capabilities(e.profile)
+	if !ok {
+		return nil
+	}
+	return mapError(caps.Check(e.request(params, nil)))
+}
+
+// request maps code.ExecuteParams to a runtime.ExecuteRequest.
+func (e *Engine) request(params code.ExecuteParams, gateway runtime.ToolGateway) runtime.ExecuteRequest {
+	req := runtime.ExecuteRequest{
+		Language: params.Language,
+		Code:     params.Code,
+		Timeout:  params.Timeout,
+		Limits: runtime.Limits{
+			MaxToolCalls: params.MaxToolCalls,
+		},
+		Profile:  e.profile,
+		Gateway:  gateway,
+		Env:      params.Env,
+		Metadata: params.Metadata,
+	}
+	if ws := params.Workspace; ws != nil {
+		req.Workspace = &runtime.Workspace{Path: ws.Path, MaxBytes: ws.MaxBytes}
+	}
+	return req
+}
+
 // mapResult converts runtime.ExecuteResult to code.ExecuteResult.
 func mapResult(r runtime.ExecuteResult) code.ExecuteResult {
 	toolCalls := make([]code.ToolCallRecord, len(r.ToolCalls))
@@ -88,11 +139,28 @@ func mapResult(r runtime.ExecuteResult) code.ExecuteResult {
 	}
 
 	return code.ExecuteResult{
-		Value:      r.Value,
-		Stdout:     r.Stdout,
-		Stderr:     r.Stderr,
-		ToolCalls:  toolCalls,
-		DurationMs: r.Duration.Milliseconds(),
+		Value:         r.Value,
+		Stdout:        r.Stdout,
+		Stderr:        r.Stderr,
+		ToolCalls:     toolCalls,
+		DurationMs:    r.Duration.Milliseconds(),
+		ResourceUsage: mapUsage(r.Usage),
+	}
+}
+
+// mapUsage converts runtime usage to code usage, returning nil when the
+// backend measured nothing.
+func mapUsage(u runtime.ResourceUsage) *code.ResourceUsage {
+	if u == (runtime.ResourceUsage{}) {
+		return nil
+	}
+	return &code.ResourceUsage{
+		CPUMillis:       u.CPUTime.Milliseconds(),
+		PeakMemoryBytes: u.PeakMemoryBytes,
+		WallTimeMs:      u.WallTime.Milliseconds(),
+		DiskWriteBytes:  u.DiskWriteBytes,
+		NetworkRxBytes:  u.NetworkRxBytes,
+		NetworkTxBytes:  u.NetworkTxBytes,
 	}
 }
 
@@ -110,6 +178,10 @@ func mapError(err error) error {
 		return fmt.Errorf("%w: %v", code.ErrLimitExceeded, err)
 	}
 
+	if errors.Is(err, runtime.ErrUnsupportedCapability) {
+		return fmt.Errorf("%w: %v", code.ErrUnsupportedCapability, err)
+	}
+
 	// Map sandbox violation to ErrCodeExecution
 	if errors.Is(err, runtime.ErrSandboxViolation) {
 		return fmt.Errorf("%w: %v", code.ErrCodeExecution, err)
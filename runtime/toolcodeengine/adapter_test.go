@@ -3,6 +3,7 @@ package toolcodeengine
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -55,6 +56,10 @@ func (m *mockTools) SearchTools(_ context.Context, _ string, _ int) ([]index.Sum
 	return m.searchResults, nil
 }
 
+func (m *mockTools) SearchToolsFiltered(_ context.Context, _ string, _ code.SearchFilter) ([]index.Summary, error) {
+	return m.searchResults, nil
+}
+
 func (m *mockTools) ListNamespaces(_ context.Context) ([]string, error) {
 	return m.namespaces, nil
 }
@@ -119,6 +124,139 @@ func TestEngineExecute(t *testing.T) {
 	}
 }
 
+func TestEngineExecuteMapsResourceUsage(t *testing.T) {
+	rt := &mockRuntime{
+		result: runtime.ExecuteResult{
+			Usage: runtime.ResourceUsage{
+				CPUTime:         250 * time.Millisecond,
+				PeakMemoryBytes: 64 << 20,
+				WallTime:        400 * time.Millisecond,
+				DiskWriteBytes:  4096,
+				NetworkRxBytes:  10,
+				NetworkTxBytes:  20,
+			},
+		},
+	}
+	engine := newEngine(t, rt, runtime.ProfileDev)
+
+	result, err := engine.Execute(context.Background(), code.ExecuteParams{Code: "x"}, &mockTools{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := code.ResourceUsage{CPUMillis: 250, PeakMemoryBytes: 64 << 20, WallTimeMs: 400, DiskWriteBytes: 4096, NetworkRxBytes: 10, NetworkTxBytes: 20}
+	if result.ResourceUsage == nil || *result.ResourceUsage != want {
+		t.Errorf("Execute().ResourceUsage = %+v, want %+v", result.ResourceUsage, want)
+	}
+
+	rt.result = runtime.ExecuteResult{}
+	result, err = engine.Execute(context.Background(), code.ExecuteParams{Code: "x"}, &mockTools{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ResourceUsage != nil {
+		t.Errorf("Execute().ResourceUsage = %+v, want nil when unmeasured", result.ResourceUsage)
+	}
+}
+
+func TestEngineExecuteMapsWorkspace(t *testing.T) {
+	rt := &mockRuntime{}
+	engine := newEngine(t, rt, runtime.ProfileStandard)
+
+	params := code.ExecuteParams{Code: "x", Workspace: &code.Workspace{Path: "/scratch", MaxBytes: 1 << 20}}
+	if _, err := engine.Execute(context.Background(), params, &mockTools{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := runtime.Workspace{Path: "/scratch", MaxBytes: 1 << 20}
+	if ws := rt.capturedReq.Workspace; ws == nil || *ws != want {
+		t.Errorf("request Workspace = %+v, want %+v", ws, want)
+	}
+}
+
+func TestEngineExecutePassesMetadata(t *testing.T) {
+	rt := &mockRuntime{}
+	engine := newEngine(t, rt, runtime.ProfileStandard)
+
+	module := []byte("\x00asm\x01\x00\x00\x00")
+	params := code.ExecuteParams{Code: "x", Metadata: map[string]any{code.MetadataWASMModule: module}}
+	if _, err := engine.Execute(context.Background(), params, &mockTools{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got, _ := rt.capturedReq.Metadata[code.MetadataWASMModule].([]byte); string(got) != string(module) {
+		t.Errorf("request Metadata[%q] = %v, want the module", code.MetadataWASMModule, got)
+	}
+}
+
+func TestEngineDescribeEngine(t *testing.T) {
+	rt := runtime.NewDefaultRuntime(runtime.RuntimeConfig{
+		Backends: map[runtime.SecurityProfile]runtime.Backend{
+			runtime.ProfileStandard: &kindBackend{kind: runtime.BackendDocker},
+		},
+	})
+	engine := newEngine(t, rt, runtime.ProfileStandard)
+
+	info := engine.DescribeEngine("go")
+	if info.Name != "go" || info.Backend != "docker" || info.SecurityProfile != "standard" {
+		t.Errorf("DescribeEngine() = %+v, want go on docker with standard profile", info)
+	}
+
+	info = newEngine(t, &mockRuntime{}, runtime.ProfileDev).DescribeEngine("go")
+	if info.Backend != "" || info.SecurityProfile != "dev" {
+		t.Errorf("DescribeEngine() = %+v, want dev profile and no backend", info)
+	}
+}
+
+// kindBackend is a runtime.Backend that only reports its kind.
+type kindBackend struct {
+	kind runtime.BackendKind
+}
+
+func (b *kindBackend) Kind() runtime.BackendKind { return b.kind }
+
+func (b *kindBackend) Execute(context.Context, runtime.ExecuteRequest) (runtime.ExecuteResult, error) {
+	return runtime.ExecuteResult{}, nil
+}
+
+// capsBackend is a kindBackend that advertises capabilities.
+type capsBackend struct {
+	kindBackend
+	caps runtime.Capabilities
+}
+
+func (b *capsBackend) Capabilities() runtime.Capabilities { return b.caps }
+
+func TestEngineValidateParams(t *testing.T) {
+	rt := runtime.NewDefaultRuntime(runtime.RuntimeConfig{
+		Backends: map[runtime.SecurityProfile]runtime.Backend{
+			runtime.ProfileHardened: &capsBackend{
+				kindBackend: kindBackend{kind: runtime.BackendFirecracker},
+				caps:        runtime.Capabilities{Languages: []string{"go"}},
+			},
+		},
+	})
+	engine := newEngine(t, rt, runtime.ProfileHardened)
+	ctx := context.Background()
+
+	if err := engine.ValidateParams(ctx, code.ExecuteParams{Language: "go", Code: "x"}); err != nil {
+		t.Errorf("ValidateParams() error = %v", err)
+	}
+	err := engine.ValidateParams(ctx, code.ExecuteParams{
+		Language:  "python",
+		Code:      "x",
+		Workspace: &code.Workspace{},
+	})
+	if !errors.Is(err, code.ErrUnsupportedCapability) {
+		t.Fatalf("ValidateParams() error = %v, want %v", err, code.ErrUnsupportedCapability)
+	}
+	if !strings.Contains(err.Error(), "python") || !strings.Contains(err.Error(), "workspace") {
+		t.Errorf("ValidateParams() error = %v, want both problems listed", err)
+	}
+
+	// Runtimes that cannot report capabilities accept everything.
+	if err := newEngine(t, &mockRuntime{}, runtime.ProfileDev).ValidateParams(ctx, code.ExecuteParams{Language: "python"}); err != nil {
+		t.Errorf("ValidateParams() without capabilities error = %v", err)
+	}
+}
+
 func TestEngineExecuteMapsParams(t *testing.T) {
 	rt := &mockRuntime{
 		result: runtime.ExecuteResult{},
@@ -42,6 +42,10 @@ func (t *testTools) SearchTools(_ context.Context, _ string, _ int) ([]index.Sum
 	return t.searchResults, nil
 }
 
+func (t *testTools) SearchToolsFiltered(_ context.Context, _ string, _ code.SearchFilter) ([]index.Summary, error) {
+	return t.searchResults, nil
+}
+
 func (t *testTools) ListNamespaces(_ context.Context) ([]string, error) {
 	return t.namespaces, nil
 }
@@ -164,6 +164,10 @@ func (c *ctxTools) SearchTools(ctx context.Context, _ string, _ int) ([]index.Su
 	return nil, ctx.Err()
 }
 
+func (c *ctxTools) SearchToolsFiltered(ctx context.Context, _ string, _ code.SearchFilter) ([]index.Summary, error) {
+	return nil, ctx.Err()
+}
+
 func (c *ctxTools) ListNamespaces(ctx context.Context) ([]string, error) {
 	return nil, ctx.Err()
 }
@@ -195,6 +199,10 @@ func (e *errTools) SearchTools(_ context.Context, _ string, _ int) ([]index.Summ
 	return nil, e.err
 }
 
+func (e *errTools) SearchToolsFiltered(_ context.Context, _ string, _ code.SearchFilter) ([]index.Summary, error) {
+	return nil, e.err
+}
+
 func (e *errTools) ListNamespaces(_ context.Context) ([]string, error) {
 	return nil, e.err
 }
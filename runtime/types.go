@@ -3,6 +3,7 @@ package runtime
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jonwraymond/tooldiscovery/index"
@@ -50,10 +51,22 @@ const (
 	// Good default isolation with cgroups, read-only rootfs, user remapping, and seccomp.
 	BackendDocker BackendKind = "docker"
 
+	// BackendPodman runs code in Podman containers through the libpod REST socket.
+	// Same isolation model as Docker, without a privileged daemon; suits rootless hosts.
+	BackendPodman BackendKind = "podman"
+
 	// BackendContainerd runs code via containerd directly.
 	// Similar to Docker but more infrastructure-native for servers/agents.
 	BackendContainerd BackendKind = "containerd"
 
+	// BackendNspawn runs code in ephemeral systemd-nspawn machines registered with machined.
+	// Container-level isolation for bare-metal Linux hosts without a container daemon.
+	BackendNspawn BackendKind = "nspawn"
+
+	// BackendWindows runs code in Windows Job Objects or Windows Sandbox.
+	// Native isolation for Windows hosts; Windows Sandbox adds a disposable VM.
+	BackendWindows BackendKind = "windows"
+
 	// BackendKubernetes executes snippets in short-lived pods/jobs.
 	// Isolation depends on configured runtime class; best for scheduling and multi-tenant controls.
 	BackendKubernetes BackendKind = "kubernetes"
@@ -74,6 +87,10 @@ const (
 	// Strong in-process isolation; requires constrained SDK surface.
 	BackendWASM BackendKind = "wasm"
 
+	// BackendIsolate runs JavaScript/TypeScript in V8 isolates with per-isolate heap and CPU limits.
+	// Millisecond startup without containers; isolation is the engine's heap sandbox.
+	BackendIsolate BackendKind = "isolate"
+
 	// BackendTemporal treats snippet execution as a Temporal workflow/activity.
 	// Useful for long-running or resumable executions.
 	// Note: Temporal is orchestration, not isolation - must compose with sandbox backends.
@@ -83,6 +100,10 @@ const (
 	// Generic target for dedicated runtime services, batch systems, or job runners.
 	BackendRemote BackendKind = "remote"
 
+	// BackendServerless invokes a cloud function (AWS Lambda, GCP, Azure) running the sandbox agent.
+	// Zero idle cost for bursty workloads; isolation is the provider's function sandbox.
+	BackendServerless BackendKind = "serverless"
+
 	// BackendProxmoxLXC executes code in a Proxmox LXC container.
 	// Requires a runtime service inside the container.
 	BackendProxmoxLXC BackendKind = "proxmox_lxc"
@@ -126,6 +147,39 @@ type Limits struct {
 	// DiskBytes limits disk usage in bytes.
 	// Zero means unlimited.
 	DiskBytes int64
+
+	// GPUs requests accelerators for the execution.
+	// A zero Count requests none. Backends without device support ignore it.
+	GPUs GPURequest
+}
+
+// DefaultGPUClass is the device class used when GPURequest.Class is empty.
+const DefaultGPUClass = "nvidia.com/gpu"
+
+// GPURequest asks for accelerator devices.
+type GPURequest struct {
+	// Count is the number of devices to attach.
+	Count int
+
+	// Class is the device class as an extended resource name, such as
+	// "nvidia.com/gpu" or "amd.com/gpu". Default: DefaultGPUClass.
+	Class string
+}
+
+// DeviceClass returns Class, or DefaultGPUClass when Class is empty.
+func (g GPURequest) DeviceClass() string {
+	if g.Class == "" {
+		return DefaultGPUClass
+	}
+	return g.Class
+}
+
+// Vendor returns the vendor of the device class: "nvidia" for
+// "nvidia.com/gpu". Container runtimes use it as the device driver name.
+func (g GPURequest) Vendor() string {
+	domain, _, _ := strings.Cut(g.DeviceClass(), "/")
+	vendor, _, _ := strings.Cut(domain, ".")
+	return vendor
 }
 
 // Validate checks that all limit values are valid (non-negative).
@@ -148,6 +202,12 @@ func (l Limits) Validate() error {
 	if l.DiskBytes < 0 {
 		return fmt.Errorf("%w: DiskBytes cannot be negative", ErrInvalidLimits)
 	}
+	if l.GPUs.Count < 0 {
+		return fmt.Errorf("%w: GPUs.Count cannot be negative", ErrInvalidLimits)
+	}
+	if l.GPUs.Class != "" && !strings.Contains(l.GPUs.Class, "/") {
+		return fmt.Errorf("%w: GPUs.Class %q must be a vendor/resource name", ErrInvalidLimits, l.GPUs.Class)
+	}
 	return nil
 }
 
@@ -165,6 +225,21 @@ type ExecuteRequest struct {
 	// If zero, the backend's default timeout is used.
 	Timeout time.Duration
 
+	// GraceTimeout is how long code has, once Timeout expires, to exit
+	// after being asked to stop, so it can flush __out and its output.
+	// It is killed when the grace period ends. Backends without
+	// Capabilities.GracefulStop kill it at Timeout.
+	// If zero, code is killed at Timeout.
+	GraceTimeout time.Duration
+
+	// MaxOutputBytes caps the stdout and the stderr collected from the
+	// code, each. Output beyond it is discarded and marked with
+	// OutputTruncatedMarker, and ExecuteResult.Truncated is set; an __out
+	// value printed after the cap is lost with it. Backends whose
+	// LimitsEnforced.Output is false collect all output.
+	// If zero, output is not capped.
+	MaxOutputBytes int64
+
 	// Limits specifies resource limits for execution.
 	Limits Limits
 
@@ -176,8 +251,46 @@ type ExecuteRequest struct {
 	// Required.
 	Gateway ToolGateway
 
+	// Env provides environment variables scoped to this execution.
+	// Backends that run code in a process or container expose them to it.
+	Env map[string]string
+
+	// Workspace, if set, requests a scratch directory the code can write
+	// intermediate files to. It is discarded when the execution ends.
+	Workspace *Workspace
+
+	// Files maps paths relative to the workspace to files staged there
+	// before the code runs. Files require a Workspace. The docker,
+	// containerd, kubernetes, and gvisor backends support staging.
+	Files map[string]File
+
+	// LogStreamer, if set, receives stdout and stderr line by line while
+	// the code runs. Backends that cannot stream ignore it.
+	LogStreamer LogStreamer
+
+	// Egress, if set, restricts the execution's outbound network traffic
+	// in place of the network mode its security profile picks. Backends
+	// list the modes they enforce in Capabilities.EgressModes.
+	Egress *EgressPolicy
+
 	// Metadata contains arbitrary metadata for the execution.
 	Metadata map[string]any
+
+	// ExecutionID identifies the execution in backend labels, gateway
+	// messages, tool call records, events, and logs. If empty,
+	// DefaultRuntime takes it from the context (see WithExecutionID) or
+	// generates one.
+	ExecutionID string
+}
+
+// EnsureExecutionID sets ExecutionID to a new ID if it is empty, and
+// returns it. Backends call it so that requests executed without a
+// DefaultRuntime are labeled too.
+func (r *ExecuteRequest) EnsureExecutionID() string {
+	if r.ExecutionID == "" {
+		r.ExecutionID = NewExecutionID()
+	}
+	return r.ExecutionID
 }
 
 // Validate checks that the request is valid.
@@ -191,6 +304,22 @@ func (r ExecuteRequest) Validate() error {
 	if err := r.Limits.Validate(); err != nil {
 		return err
 	}
+	if r.MaxOutputBytes < 0 {
+		return fmt.Errorf("%w: MaxOutputBytes cannot be negative", ErrInvalidLimits)
+	}
+	if r.Workspace != nil {
+		if err := r.Workspace.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := validateFiles(r.Files, r.Workspace); err != nil {
+		return err
+	}
+	if r.Egress != nil {
+		if err := r.Egress.Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -206,6 +335,11 @@ type ExecuteResult struct {
 	// Stderr contains any output written to stderr.
 	Stderr string
 
+	// Truncated is true when Stdout or Stderr exceeded
+	// ExecuteRequest.MaxOutputBytes and was cut off; it then ends with
+	// OutputTruncatedMarker.
+	Truncated bool
+
 	// ToolCalls records all tool invocations made during execution.
 	ToolCalls []ToolCallRecord
 
@@ -219,6 +353,17 @@ type ExecuteResult struct {
 	// Backends that cannot enforce a given limit should set that field to false.
 	// This allows callers to know when limits degraded gracefully.
 	LimitsEnforced LimitsEnforced
+
+	// Usage reports the resources the execution consumed, where the
+	// backend can measure them.
+	Usage ResourceUsage
+
+	// Artifacts holds the files the code wrote to the workspace output
+	// directory, sorted by name, for backends that support staging.
+	Artifacts []Artifact
+
+	// ExecutionID is the request's ExecutionID, set by DefaultRuntime.
+	ExecutionID string
 }
 
 // LimitsEnforced reports which resource limits were actually enforced by the backend.
@@ -244,6 +389,9 @@ type LimitsEnforced struct {
 
 	// Disk indicates whether disk limits were enforced.
 	Disk bool
+
+	// Output indicates whether MaxOutputBytes was enforced.
+	Output bool
 }
 
 // ToolCallRecord captures information about a single tool invocation.
@@ -251,6 +399,10 @@ type ToolCallRecord struct {
 	// ToolID is the canonical identifier of the tool that was called.
 	ToolID string
 
+	// ExecutionID is the execution that made the call, when the gateway
+	// knows it.
+	ExecutionID string
+
 	// BackendKind indicates which backend executed the tool.
 	BackendKind string
 
@@ -300,3 +452,23 @@ type ToolGateway interface {
 	// RunChain executes a sequence of tool calls.
 	RunChain(ctx context.Context, steps []run.ChainStep) (run.RunResult, []run.StepResult, error)
 }
+
+// StreamingGateway is implemented by gateways that can stream a tool's
+// results, so code in a sandbox sees a streaming backend's chunks and
+// progress as they happen rather than only the final result.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: the stream ends with a StreamEventCancelled or
+// StreamEventTimeout event when ctx ends.
+// - Errors: failures to start are returned (run.ErrStreamNotSupported
+// for tools that cannot stream), or, across a process boundary, delivered
+// as a final StreamEventError.
+// - Ownership: callers must drain the channel until it closes.
+type StreamingGateway interface {
+	ToolGateway
+
+	// RunToolStream executes a single tool, delivering its events on the
+	// returned channel.
+	RunToolStream(ctx context.Context, id string, args map[string]any) (<-chan run.StreamEvent, error)
+}
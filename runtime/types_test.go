@@ -56,14 +56,19 @@ func TestBackendKindConstants(t *testing.T) {
 	}{
 		{BackendUnsafeHost, "unsafe_host"},
 		{BackendDocker, "docker"},
+		{BackendPodman, "podman"},
 		{BackendContainerd, "containerd"},
+		{BackendNspawn, "nspawn"},
+		{BackendWindows, "windows"},
 		{BackendKubernetes, "kubernetes"},
 		{BackendGVisor, "gvisor"},
 		{BackendKata, "kata"},
 		{BackendFirecracker, "firecracker"},
 		{BackendWASM, "wasm"},
+		{BackendIsolate, "isolate"},
 		{BackendTemporal, "temporal"},
 		{BackendRemote, "remote"},
+		{BackendServerless, "serverless"},
 		{BackendProxmoxLXC, "proxmox_lxc"},
 	}
 
@@ -154,6 +159,21 @@ func TestLimitsValidate(t *testing.T) {
 			limits:  Limits{DiskBytes: -1},
 			wantErr: true,
 		},
+		{
+			name:    "GPU request valid",
+			limits:  Limits{GPUs: GPURequest{Count: 1, Class: "amd.com/gpu"}},
+			wantErr: false,
+		},
+		{
+			name:    "negative GPU count invalid",
+			limits:  Limits{GPUs: GPURequest{Count: -1}},
+			wantErr: true,
+		},
+		{
+			name:    "GPU class without resource name invalid",
+			limits:  Limits{GPUs: GPURequest{Count: 1, Class: "nvidia"}},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -166,6 +186,18 @@ func TestLimitsValidate(t *testing.T) {
 	}
 }
 
+func TestGPURequestClass(t *testing.T) {
+	if got := (GPURequest{Count: 1}).DeviceClass(); got != DefaultGPUClass {
+		t.Errorf("DeviceClass() = %q, want %q", got, DefaultGPUClass)
+	}
+	tests := map[string]string{"": "nvidia", "amd.com/gpu": "amd", "gpu.intel.com/i915": "gpu"}
+	for class, want := range tests {
+		if got := (GPURequest{Class: class}).Vendor(); got != want {
+			t.Errorf("GPURequest{Class: %q}.Vendor() = %q, want %q", class, got, want)
+		}
+	}
+}
+
 // Test ExecuteRequest validation
 func TestExecuteRequestValidate(t *testing.T) {
 	// Create a minimal mock gateway for valid requests
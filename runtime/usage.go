@@ -0,0 +1,156 @@
+package runtime
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResourceUsage reports resources consumed by one execution, as observed
+// by the backend. Zero fields mean the backend could not measure them.
+type ResourceUsage struct {
+	// CPUTime is user plus system CPU time consumed.
+	CPUTime time.Duration
+
+	// PeakMemoryBytes is the peak resident memory.
+	PeakMemoryBytes int64
+
+	// WallTime is the elapsed time inside the sandbox, excluding
+	// backend setup such as image pulls or workspace preparation.
+	WallTime time.Duration
+
+	// DiskWriteBytes is the number of bytes written to block devices.
+	DiskWriteBytes int64
+
+	// NetworkRxBytes and NetworkTxBytes count bytes received and sent
+	// over non-loopback interfaces. They stay zero when the execution had
+	// no network.
+	NetworkRxBytes int64
+	NetworkTxBytes int64
+}
+
+// ProcessUsage derives ResourceUsage from a finished process.
+// wall is the time the process ran. A nil ps yields only WallTime.
+func ProcessUsage(ps *os.ProcessState, wall time.Duration) ResourceUsage {
+	usage := ResourceUsage{WallTime: wall}
+	if ps == nil {
+		return usage
+	}
+	usage.CPUTime = ps.UserTime() + ps.SystemTime()
+	usage.PeakMemoryBytes = maxRSS(ps.SysUsage())
+	return usage
+}
+
+// maxRSS extracts the peak resident set size from a platform rusage value.
+// Reflection keeps this portable: only Unix rusage has a Maxrss field.
+func maxRSS(sysUsage any) int64 {
+	v := reflect.ValueOf(sysUsage)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return 0
+	}
+	field := v.Elem().FieldByName("Maxrss")
+	if !field.IsValid() || !field.CanInt() {
+		return 0
+	}
+	rss := field.Int()
+	// Darwin reports bytes; other Unix systems report kilobytes.
+	if goruntime.GOOS != "darwin" && goruntime.GOOS != "ios" {
+		rss *= 1024
+	}
+	return rss
+}
+
+// CgroupUsage reads ResourceUsage from a cgroup v2 directory such as
+// /sys/fs/cgroup/<slice>/<scope>: CPUTime from cpu.stat, PeakMemoryBytes
+// from memory.peak and DiskWriteBytes from io.stat. Read it after the
+// workload exits but before the cgroup is removed. memory.peak (Linux
+// 5.19+) and io.stat are optional; a missing cpu.stat is an error, since
+// dir is then not a cgroup v2 directory. WallTime and network counters are
+// left for the caller.
+func CgroupUsage(dir string) (ResourceUsage, error) {
+	var usage ResourceUsage
+
+	stat, err := os.ReadFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return usage, fmt.Errorf("read cgroup cpu.stat: %w", err)
+	}
+	for line := range strings.Lines(string(stat)) {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "usage_usec "); ok {
+			usec, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return usage, fmt.Errorf("parse cgroup cpu.stat: %w", err)
+			}
+			usage.CPUTime = time.Duration(usec) * time.Microsecond
+		}
+	}
+
+	peak, err := os.ReadFile(filepath.Join(dir, "memory.peak"))
+	switch {
+	case err == nil:
+		if usage.PeakMemoryBytes, err = strconv.ParseInt(strings.TrimSpace(string(peak)), 10, 64); err != nil {
+			return usage, fmt.Errorf("parse cgroup memory.peak: %w", err)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return usage, fmt.Errorf("read cgroup memory.peak: %w", err)
+	}
+
+	ioStat, err := os.ReadFile(filepath.Join(dir, "io.stat"))
+	switch {
+	case err == nil:
+		// Each line is "<major>:<minor> rbytes=N wbytes=N rios=N ...".
+		for line := range strings.Lines(string(ioStat)) {
+			for _, field := range strings.Fields(line) {
+				if v, ok := strings.CutPrefix(field, "wbytes="); ok {
+					n, err := strconv.ParseInt(v, 10, 64)
+					if err != nil {
+						return usage, fmt.Errorf("parse cgroup io.stat: %w", err)
+					}
+					usage.DiskWriteBytes += n
+				}
+			}
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return usage, fmt.Errorf("read cgroup io.stat: %w", err)
+	}
+	return usage, nil
+}
+
+// NetDevUsage sums the received and sent byte counters of every
+// non-loopback interface in r, which holds /proc/net/dev content such as
+// /proc/<pid>/net/dev for a process inside the sandbox's network
+// namespace.
+func NetDevUsage(r io.Reader) (rx, tx int64, err error) {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		name, counters, ok := strings.Cut(sc.Text(), ":")
+		if !ok || strings.TrimSpace(name) == "lo" {
+			// Header lines carry no colon.
+			continue
+		}
+		// Eight receive counters precede the transmit ones.
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			return 0, 0, fmt.Errorf("parse net/dev: short line for %q", strings.TrimSpace(name))
+		}
+		in, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("parse net/dev: %w", err)
+		}
+		out, err := strconv.ParseInt(fields[8], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("parse net/dev: %w", err)
+		}
+		rx += in
+		tx += out
+	}
+	return rx, tx, sc.Err()
+}
@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessUsage(t *testing.T) {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("cannot run true: %v", err)
+	}
+
+	usage := ProcessUsage(cmd.ProcessState, 5*time.Millisecond)
+	if usage.WallTime != 5*time.Millisecond {
+		t.Errorf("WallTime = %v, want 5ms", usage.WallTime)
+	}
+	if usage.CPUTime < 0 {
+		t.Errorf("CPUTime = %v, want >= 0", usage.CPUTime)
+	}
+	if usage.PeakMemoryBytes <= 0 {
+		t.Errorf("PeakMemoryBytes = %d, want > 0", usage.PeakMemoryBytes)
+	}
+}
+
+func TestProcessUsage_NilState(t *testing.T) {
+	usage := ProcessUsage(nil, time.Second)
+	if usage != (ResourceUsage{WallTime: time.Second}) {
+		t.Errorf("ProcessUsage(nil) = %+v, want only WallTime", usage)
+	}
+}
+
+func TestCgroupUsage(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"cpu.stat":    "usage_usec 1500000\nuser_usec 1000000\nsystem_usec 500000\n",
+		"memory.peak": "52428800\n",
+		"io.stat":     "8:0 rbytes=4096 wbytes=1024 rios=1 wios=1\n253:0 rbytes=0 wbytes=2048 rios=0 wios=2\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage, err := CgroupUsage(dir)
+	if err != nil {
+		t.Fatalf("CgroupUsage() error = %v", err)
+	}
+	want := ResourceUsage{CPUTime: 1500 * time.Millisecond, PeakMemoryBytes: 52428800, DiskWriteBytes: 3072}
+	if usage != want {
+		t.Errorf("CgroupUsage() = %+v, want %+v", usage, want)
+	}
+
+	// memory.peak and io.stat are optional.
+	for _, name := range []string{"memory.peak", "io.stat"} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if usage, err := CgroupUsage(dir); err != nil || usage != (ResourceUsage{CPUTime: 1500 * time.Millisecond}) {
+		t.Errorf("CgroupUsage() without optional files = %+v, %v", usage, err)
+	}
+
+	if _, err := CgroupUsage(t.TempDir()); err == nil {
+		t.Error("CgroupUsage() on a non-cgroup directory error = nil")
+	}
+}
+
+func TestNetDevUsage(t *testing.T) {
+	const netDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:    9000      10    0    0    0     0          0         0     9000      10    0    0    0     0       0          0
+  eth0:    1200       8    0    0    0     0          0         0      340       4    0    0    0     0       0          0
+  eth1:      30       1    0    0    0     0          0         0       60       1    0    0    0     0       0          0
+`
+	rx, tx, err := NetDevUsage(strings.NewReader(netDev))
+	if err != nil {
+		t.Fatalf("NetDevUsage() error = %v", err)
+	}
+	if rx != 1230 || tx != 400 {
+		t.Errorf("NetDevUsage() = %d, %d; want 1230, 400", rx, tx)
+	}
+
+	if _, _, err := NetDevUsage(strings.NewReader("eth0: 1 2 3\n")); err == nil {
+		t.Error("NetDevUsage() on a short line error = nil")
+	}
+}
@@ -0,0 +1,96 @@
+package runtime
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+)
+
+// DefaultWorkspacePath is where sandboxed backends mount the workspace
+// when Workspace.Path is empty.
+const DefaultWorkspacePath = "/workspace"
+
+// WorkspaceEnv is the environment variable through which backends tell
+// code where its workspace is.
+const WorkspaceEnv = "TOOLEXEC_WORKSPACE"
+
+// Workspace describes a per-execution scratch directory. Backends create
+// it empty before the code runs and remove it afterwards:
+//   - docker mounts a size-limited tmpfs at Path
+//   - kubernetes mounts a size-limited emptyDir volume at Path
+//   - containerd and gvisor hand Path to their runners as a Staging
+//   - unsafe and windows create a host temp dir and ignore Path
+//
+// In every case WorkspaceEnv holds the directory as seen by the code, and
+// container backends also use it as the working directory. Backends that
+// support file staging also write ExecuteRequest.Files into it and return
+// the contents of OutputDir as ExecuteResult.Artifacts.
+type Workspace struct {
+	// Path is the absolute path of the workspace inside the sandbox.
+	// If empty, DefaultWorkspacePath is used.
+	Path string
+
+	// MaxBytes caps the total size of files in the workspace.
+	// Zero means unlimited, subject to backend defaults.
+	MaxBytes int64
+
+	// OutputDir is the workspace subdirectory whose files are returned as
+	// ExecuteResult.Artifacts, relative to Path. Backends create it
+	// empty and expose it through OutputEnv.
+	// If empty, DefaultOutputDir is used.
+	OutputDir string
+}
+
+// MountPath returns Path, or DefaultWorkspacePath when Path is empty.
+func (w Workspace) MountPath() string {
+	if w.Path == "" {
+		return DefaultWorkspacePath
+	}
+	return w.Path
+}
+
+// OutputPath returns OutputDir, or DefaultOutputDir when OutputDir is
+// empty. It is relative to MountPath.
+func (w Workspace) OutputPath() string {
+	if w.OutputDir == "" {
+		return DefaultOutputDir
+	}
+	return w.OutputDir
+}
+
+// Validate checks that Path is absolute, OutputDir is relative, and
+// MaxBytes is non-negative.
+func (w Workspace) Validate() error {
+	if w.Path != "" && (!path.IsAbs(w.Path) || path.Clean(w.Path) == "/") {
+		return fmt.Errorf("%w: path %q must be an absolute directory below /", ErrInvalidWorkspace, w.Path)
+	}
+	if w.OutputDir != "" && !ValidRelPath(w.OutputDir) {
+		return fmt.Errorf("%w: output dir %q must be a relative path inside the workspace", ErrInvalidWorkspace, w.OutputDir)
+	}
+	if w.MaxBytes < 0 {
+		return fmt.Errorf("%w: MaxBytes cannot be negative", ErrInvalidWorkspace)
+	}
+	return nil
+}
+
+// DirSize returns the total size of the regular files under dir. Backends
+// without a size-limited filesystem use it to check Workspace.MaxBytes
+// after the code has run.
+func DirSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
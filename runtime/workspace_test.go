@@ -0,0 +1,60 @@
+package runtime
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspace_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		ws   Workspace
+		ok   bool
+	}{
+		{"default path", Workspace{}, true},
+		{"absolute path", Workspace{Path: "/scratch", MaxBytes: 1024}, true},
+		{"relative path", Workspace{Path: "scratch"}, false},
+		{"root", Workspace{Path: "/"}, false},
+		{"negative size", Workspace{MaxBytes: -1}, false},
+		{"output dir", Workspace{OutputDir: "results/final"}, true},
+		{"absolute output dir", Workspace{OutputDir: "/out"}, false},
+		{"escaping output dir", Workspace{OutputDir: "../out"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.ws.Validate()
+			if tt.ok && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidWorkspace) {
+				t.Errorf("Validate() error = %v, want %v", err, ErrInvalidWorkspace)
+			}
+		})
+	}
+
+	if got := (Workspace{}).MountPath(); got != DefaultWorkspacePath {
+		t.Errorf("MountPath() = %q, want %q", got, DefaultWorkspacePath)
+	}
+	if got := (Workspace{}).OutputPath(); got != DefaultOutputDir {
+		t.Errorf("OutputPath() = %q, want %q", got, DefaultOutputDir)
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int{"a": 100, "sub/b": 50} {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := DirSize(dir)
+	if err != nil || got != 150 {
+		t.Errorf("DirSize() = %d, %v; want 150", got, err)
+	}
+}